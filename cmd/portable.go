@@ -76,6 +76,19 @@ var (
 	portableSFTPPrefix                 string
 	portableSFTPDisableConcurrentReads bool
 	portableSFTPDBufferSize            int64
+	portableFTPEndpoint                string
+	portableFTPUsername                string
+	portableFTPPassword                string
+	portableFTPPrefix                  string
+	portableFTPTLSMode                 int
+	portableFTPSkipTLSVerify           bool
+	portableFTPDisableEPSV             bool
+	portableWebDAVFsEndpoint           string
+	portableWebDAVFsUsername           string
+	portableWebDAVFsPassword           string
+	portableWebDAVFsBearerToken        string
+	portableWebDAVFsPrefix             string
+	portableWebDAVFsSkipTLSVerify      bool
 	portableCmd                        = &cobra.Command{
 		Use:   "portable",
 		Short: "Serve a single directory/account",
@@ -212,6 +225,27 @@ Please take a look at the usage below to customize the serving parameters`,
 								BufferSize:              portableSFTPDBufferSize,
 							},
 						},
+						FTPConfig: vfs.FTPFsConfig{
+							FTPFsConfig: sdk.FTPFsConfig{
+								Endpoint:      portableFTPEndpoint,
+								Username:      portableFTPUsername,
+								Password:      kms.NewPlainSecret(portableFTPPassword),
+								Prefix:        portableFTPPrefix,
+								TLSMode:       portableFTPTLSMode,
+								SkipTLSVerify: portableFTPSkipTLSVerify,
+								DisableEPSV:   portableFTPDisableEPSV,
+							},
+						},
+						WebDAVConfig: vfs.WebDAVFsConfig{
+							WebDAVFsConfig: sdk.WebDAVFsConfig{
+								Endpoint:      portableWebDAVFsEndpoint,
+								Username:      portableWebDAVFsUsername,
+								Password:      kms.NewPlainSecret(portableWebDAVFsPassword),
+								BearerToken:   kms.NewPlainSecret(portableWebDAVFsBearerToken),
+								Prefix:        portableWebDAVFsPrefix,
+								SkipTLSVerify: portableWebDAVFsSkipTLSVerify,
+							},
+						},
 					},
 				},
 			}
@@ -278,7 +312,9 @@ s3fs => AWS S3 compatible (legacy: 1)
 gcsfs => Google Cloud Storage (legacy: 2)
 azblobfs => Azure Blob Storage (legacy: 3)
 cryptfs => Encrypted local filesystem (legacy: 4)
-sftpfs => SFTP (legacy: 5)`)
+sftpfs => SFTP (legacy: 5)
+ftpfs => FTP/FTPS (legacy: 6)
+webdavfs => WebDAV (legacy: 7)`)
 	portableCmd.Flags().StringVar(&portableS3Bucket, "s3-bucket", "", "")
 	portableCmd.Flags().StringVar(&portableS3Region, "s3-region", "", "")
 	portableCmd.Flags().StringVar(&portableS3AccessKey, "s3-access-key", "", "")
@@ -346,6 +382,35 @@ multiple concurrent requests and this
 allows data to be transferred at a
 faster rate, over high latency networks,
 by overlapping round-trip times`)
+	portableCmd.Flags().StringVar(&portableFTPEndpoint, "ftp-endpoint", "", `FTP endpoint as host:port for FTP
+provider`)
+	portableCmd.Flags().StringVar(&portableFTPUsername, "ftp-username", "", `FTP user for FTP provider`)
+	portableCmd.Flags().StringVar(&portableFTPPassword, "ftp-password", "", `FTP password for FTP provider`)
+	portableCmd.Flags().StringVar(&portableFTPPrefix, "ftp-prefix", "", `FTP prefix allows restrict all
+operations to a given path within the
+remote FTP server`)
+	portableCmd.Flags().IntVar(&portableFTPTLSMode, "ftp-tls-mode", 0, `0 means plain FTP, 1 explicit TLS,
+2 implicit TLS`)
+	portableCmd.Flags().BoolVar(&portableFTPSkipTLSVerify, "ftp-skip-tls-verify", false, `Skip the verification of the
+certificate presented by the remote
+FTP server. This is a security risk`)
+	portableCmd.Flags().BoolVar(&portableFTPDisableEPSV, "ftp-disable-epsv", false, `Data connections always use the
+passive mode, set to use PASV instead
+of EPSV`)
+	portableCmd.Flags().StringVar(&portableWebDAVFsEndpoint, "webdavfs-endpoint", "", `WebDAV share URL for WebDAV provider,
+for example:
+https://host/remote.php/dav/files/user/`)
+	portableCmd.Flags().StringVar(&portableWebDAVFsUsername, "webdavfs-username", "", `WebDAV user for basic authentication`)
+	portableCmd.Flags().StringVar(&portableWebDAVFsPassword, "webdavfs-password", "", `WebDAV password for basic
+authentication`)
+	portableCmd.Flags().StringVar(&portableWebDAVFsBearerToken, "webdavfs-bearer-token", "", `WebDAV token for bearer
+authentication`)
+	portableCmd.Flags().StringVar(&portableWebDAVFsPrefix, "webdavfs-prefix", "", `WebDAV prefix allows restrict all
+operations to a given path within the
+remote WebDAV share`)
+	portableCmd.Flags().BoolVar(&portableWebDAVFsSkipTLSVerify, "webdavfs-skip-tls-verify", false, `Skip the verification of the
+certificate presented by the remote
+WebDAV server. This is a security risk`)
 	rootCmd.AddCommand(portableCmd)
 }
 
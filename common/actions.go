@@ -30,7 +30,7 @@ var (
 
 // ProtocolActions defines the action to execute on file operations and SSH commands
 type ProtocolActions struct {
-	// Valid values are download, upload, pre-delete, delete, rename, ssh_cmd. Empty slice to disable
+	// Valid values are download, upload, pre-delete, delete, rename, copy, ssh_cmd. Empty slice to disable
 	ExecuteOn []string `json:"execute_on" mapstructure:"execute_on"`
 	// Actions to be performed synchronously.
 	// The pre-delete action is always executed synchronously while the other ones are asynchronous.
@@ -54,7 +54,10 @@ func InitializeActionHandler(handler ActionHandler) {
 func ExecutePreAction(user *dataprovider.User, operation, filePath, virtualPath, protocol, ip string, fileSize int64,
 	openFlags int,
 ) error {
-	plugin.Handler.NotifyFsEvent(time.Now().UnixNano(), operation, user.Username, filePath, "", "", protocol, ip, virtualPath, "", fileSize, nil)
+	timestamp := time.Now().UnixNano()
+	plugin.Handler.NotifyFsEvent(timestamp, operation, user.Username, filePath, "", "", protocol, ip, virtualPath, "", fileSize, nil)
+	dataprovider.AddFsEvent(timestamp, operation, user.Username, filePath, "", "", protocol, ip, virtualPath, "", fileSize,
+		getActionStatus(nil))
 	if !util.IsStringInSlice(operation, Config.Actions.ExecuteOn) {
 		// for pre-delete we execute the internal handling on error, so we must return errUnconfiguredAction.
 		// Other pre action will deny the operation on error so if we have no configuration we must return
@@ -73,8 +76,24 @@ func ExecutePreAction(user *dataprovider.User, operation, filePath, virtualPath,
 func ExecuteActionNotification(user *dataprovider.User, operation, filePath, virtualPath, target, virtualTarget, sshCmd,
 	protocol, ip string, fileSize int64, err error,
 ) {
-	plugin.Handler.NotifyFsEvent(time.Now().UnixNano(), operation, user.Username, filePath, target, sshCmd, protocol, ip, virtualPath,
+	timestamp := time.Now().UnixNano()
+	plugin.Handler.NotifyFsEvent(timestamp, operation, user.Username, filePath, target, sshCmd, protocol, ip, virtualPath,
 		virtualTarget, fileSize, err)
+	dataprovider.AddFsEvent(timestamp, operation, user.Username, filePath, target, sshCmd, protocol, ip, virtualPath,
+		virtualTarget, fileSize, getActionStatus(err))
+	eventManager.handleFsEvent(user, EventParams{
+		Name:              user.Username,
+		Event:             operation,
+		Status:            getActionStatus(err),
+		VirtualPath:       virtualPath,
+		FsPath:            filePath,
+		VirtualTargetPath: virtualTarget,
+		FsTargetPath:      target,
+		FileSize:          fileSize,
+		Protocol:          protocol,
+		IP:                ip,
+		Timestamp:         timestamp,
+	})
 	notification := newActionNotification(user, operation, filePath, virtualPath, target, virtualTarget, sshCmd, protocol,
 		ip, fileSize, 0, err)
 
@@ -119,7 +138,6 @@ func newActionNotification(
 	err error,
 ) *ActionNotification {
 	var bucket, endpoint string
-	status := 1
 
 	fsConfig := user.GetFsConfigForPath(virtualPath)
 
@@ -136,12 +154,10 @@ func newActionNotification(
 		}
 	case sdk.SFTPFilesystemProvider:
 		endpoint = fsConfig.SFTPConfig.Endpoint
-	}
-
-	if err == ErrQuotaExceeded {
-		status = 3
-	} else if err != nil {
-		status = 2
+	case sdk.FTPFilesystemProvider:
+		endpoint = fsConfig.FTPConfig.Endpoint
+	case sdk.WebDAVFilesystemProvider:
+		endpoint = fsConfig.WebDAVConfig.Endpoint
 	}
 
 	return &ActionNotification{
@@ -156,7 +172,7 @@ func newActionNotification(
 		FsProvider:        int(fsConfig.Provider),
 		Bucket:            bucket,
 		Endpoint:          endpoint,
-		Status:            status,
+		Status:            getActionStatus(err),
 		Protocol:          protocol,
 		IP:                ip,
 		OpenFlags:         openFlags,
@@ -164,6 +180,16 @@ func newActionNotification(
 	}
 }
 
+func getActionStatus(err error) int {
+	if err == ErrQuotaExceeded {
+		return 3
+	}
+	if err != nil {
+		return 2
+	}
+	return 1
+}
+
 type defaultActionHandler struct{}
 
 func (h *defaultActionHandler) Handle(notification *ActionNotification) error {
@@ -33,6 +33,8 @@ const (
 	uploadLogSender   = "Upload"
 	downloadLogSender = "Download"
 	renameLogSender   = "Rename"
+	copyLogSender     = "Copy"
+	combineLogSender  = "Combine"
 	rmdirLogSender    = "Rmdir"
 	mkdirLogSender    = "Mkdir"
 	symlinkLogSender  = "Symlink"
@@ -50,6 +52,7 @@ const (
 	OperationPreUpload = "pre-upload"
 	operationPreDelete = "pre-delete"
 	operationRename    = "rename"
+	operationCopy      = "copy"
 	operationMkdir     = "mkdir"
 	operationRmdir     = "rmdir"
 	// SSH command action name
@@ -80,7 +83,10 @@ const (
 	ProtocolFTP           = "FTP"
 	ProtocolWebDAV        = "DAV"
 	ProtocolHTTP          = "HTTP"
+	ProtocolHTTPShare     = "HTTPShare"
+	ProtocolS3            = "S3"
 	ProtocolDataRetention = "DataRetention"
+	ProtocolEventAction   = "EventAction"
 )
 
 // Upload modes
@@ -103,6 +109,7 @@ var (
 	ErrOpUnsupported        = errors.New("operation unsupported")
 	ErrGenericFailure       = errors.New("failure")
 	ErrQuotaExceeded        = errors.New("denying write due to space limit")
+	ErrReadQuotaExceeded    = errors.New("denying read due to quota limit")
 	ErrSkipPermissionsCheck = errors.New("permission check skipped")
 	ErrConnectionDenied     = errors.New("you are not allowed to connect")
 	ErrNoBinding            = errors.New("no binding configured")
@@ -122,7 +129,8 @@ var (
 	QuotaScans            ActiveScans
 	idleTimeoutTicker     *time.Ticker
 	idleTimeoutTickerDone chan bool
-	supportedProtocols    = []string{ProtocolSFTP, ProtocolSCP, ProtocolSSH, ProtocolFTP, ProtocolWebDAV, ProtocolHTTP}
+	supportedProtocols    = []string{ProtocolSFTP, ProtocolSCP, ProtocolSSH, ProtocolFTP, ProtocolWebDAV, ProtocolHTTP,
+		ProtocolHTTPShare, ProtocolS3}
 	disconnHookProtocols  = []string{ProtocolSFTP, ProtocolSCP, ProtocolSSH, ProtocolFTP}
 	// the map key is the protocol, for each protocol we can have multiple rate limiters
 	rateLimiters map[string][]*rateLimiter
@@ -921,7 +929,7 @@ func (c *ConnectionStatus) GetConnectionInfo() string {
 	switch c.Protocol {
 	case ProtocolSSH, ProtocolFTP:
 		result.WriteString(fmt.Sprintf(". Command: %#v", c.Command))
-	case ProtocolWebDAV:
+	case ProtocolWebDAV, ProtocolS3:
 		result.WriteString(fmt.Sprintf(". Method: %#v", c.Command))
 	}
 
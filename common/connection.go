@@ -3,6 +3,7 @@ package common
 import (
 	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path"
 	"strings"
@@ -288,6 +289,7 @@ func (c *BaseConnection) RemoveFile(fs vfs.Fs, fsPath, virtualPath string, info
 
 	logger.CommandLog(removeLogSender, fsPath, "", c.User.Username, "", c.ID, c.protocol, -1, -1, "", "", "", -1,
 		c.localAddr, c.remoteAddr)
+	c.deleteWebDAVProperties(virtualPath)
 	if info.Mode()&os.ModeSymlink == 0 {
 		vfolder, err := c.User.GetVirtualFolderForPath(path.Dir(virtualPath))
 		if err == nil {
@@ -305,6 +307,15 @@ func (c *BaseConnection) RemoveFile(fs vfs.Fs, fsPath, virtualPath string, info
 	return nil
 }
 
+// deleteWebDAVProperties removes the WebDAV dead properties stored for a removed path.
+// Files and directories can be removed using any protocol, so this is not done
+// within the WebDAV handlers
+func (c *BaseConnection) deleteWebDAVProperties(virtualPath string) {
+	if err := dataprovider.DeleteWebDAVProperties(c.User.Username, virtualPath); err != nil {
+		c.Log(logger.LevelWarn, "unable to remove dead properties for path %#v: %v", virtualPath, err)
+	}
+}
+
 // IsRemoveDirAllowed returns an error if removing this directory is not allowed
 func (c *BaseConnection) IsRemoveDirAllowed(fs vfs.Fs, fsPath, virtualPath string) error {
 	if fs.GetRelativePath(fsPath) == "/" {
@@ -360,6 +371,7 @@ func (c *BaseConnection) RemoveDir(virtualPath string) error {
 
 	logger.CommandLog(rmdirLogSender, fsPath, "", c.User.Username, "", c.ID, c.protocol, -1, -1, "", "", "", -1,
 		c.localAddr, c.remoteAddr)
+	c.deleteWebDAVProperties(virtualPath)
 	ExecuteActionNotification(&c.User, operationRmdir, fsPath, virtualPath, "", "", "", c.protocol, c.GetRemoteIP(), 0, nil)
 	return nil
 }
@@ -419,6 +431,10 @@ func (c *BaseConnection) Rename(virtualSourcePath, virtualTargetPath string) err
 	}
 	vfs.SetPathPermissions(fsDst, fsTargetPath, c.User.GetUID(), c.User.GetGID())
 	c.updateQuotaAfterRename(fsDst, virtualSourcePath, virtualTargetPath, fsTargetPath, initialSize) //nolint:errcheck
+	if err := dataprovider.RenameWebDAVProperties(c.User.Username, virtualSourcePath, virtualTargetPath); err != nil {
+		c.Log(logger.LevelWarn, "unable to rename dead properties from %#v to %#v: %v", virtualSourcePath,
+			virtualTargetPath, err)
+	}
 	logger.CommandLog(renameLogSender, fsSourcePath, fsTargetPath, c.User.Username, "", c.ID, c.protocol, -1, -1,
 		"", "", "", -1, c.localAddr, c.remoteAddr)
 	ExecuteActionNotification(&c.User, operationRename, fsSourcePath, virtualSourcePath, fsTargetPath, virtualTargetPath, "",
@@ -427,6 +443,253 @@ func (c *BaseConnection) Rename(virtualSourcePath, virtualTargetPath string) err
 	return nil
 }
 
+// Copy copies the regular file virtualSourcePath to virtualTargetPath server side.
+// If both paths are on the same storage backend and it supports server side
+// copies, for example S3, GCS and Azure Blob, the file contents are not
+// transferred, otherwise the file is read from the source and written to the target
+func (c *BaseConnection) Copy(virtualSourcePath, virtualTargetPath string) error {
+	if virtualSourcePath == virtualTargetPath {
+		c.Log(logger.LevelDebug, "copy source and target are the same: %#v", virtualSourcePath)
+		return c.GetOpUnsupportedError()
+	}
+	fsSrc, fsSourcePath, err := c.GetFsAndResolvedPath(virtualSourcePath)
+	if err != nil {
+		return err
+	}
+	fsDst, fsTargetPath, err := c.GetFsAndResolvedPath(virtualTargetPath)
+	if err != nil {
+		return err
+	}
+	srcInfo, err := fsSrc.Lstat(fsSourcePath)
+	if err != nil {
+		return c.GetFsError(fsSrc, err)
+	}
+	if !srcInfo.Mode().IsRegular() {
+		c.Log(logger.LevelDebug, "unable to copy %#v, only regular files are supported", virtualSourcePath)
+		return c.GetOpUnsupportedError()
+	}
+	if !c.isCopyPermitted(fsDst, fsTargetPath, virtualSourcePath, virtualTargetPath) {
+		return c.GetPermissionDeniedError()
+	}
+	numFiles := 1
+	initialSize := int64(0)
+	if dstInfo, err := fsDst.Lstat(fsTargetPath); err == nil {
+		if !dstInfo.Mode().IsRegular() {
+			c.Log(logger.LevelDebug, "unable to copy %#v, the target %#v is not a regular file",
+				virtualSourcePath, virtualTargetPath)
+			return c.GetOpUnsupportedError()
+		}
+		if !c.User.HasPerm(dataprovider.PermOverwrite, path.Dir(virtualTargetPath)) {
+			c.Log(logger.LevelDebug, "copying %#v -> %#v is not allowed. Target exists but the user %#v "+
+				"has no overwrite permission", virtualSourcePath, virtualTargetPath, c.User.Username)
+			return c.GetPermissionDeniedError()
+		}
+		numFiles = 0
+		initialSize = dstInfo.Size()
+	} else if !fsDst.IsNotExist(err) {
+		return c.GetFsError(fsDst, err)
+	}
+	quotaResult := c.HasSpace(numFiles > 0, false, virtualTargetPath)
+	if !quotaResult.HasSpace {
+		c.Log(logger.LevelInfo, "denying copy due to quota limits")
+		return c.GetQuotaExceededError()
+	}
+	maxWriteSize, _ := c.GetMaxWriteSize(quotaResult, false, initialSize, fsDst.IsUploadResumeSupported())
+	if maxWriteSize > 0 && srcInfo.Size() > maxWriteSize {
+		c.Log(logger.LevelInfo, "denying copy due to quota limits, file size: %v, max allowed size: %v",
+			srcInfo.Size(), maxWriteSize)
+		return c.GetQuotaExceededError()
+	}
+	if copier, ok := fsSrc.(vfs.FsFileCopier); ok && c.isLocalOrSameFolderRename(virtualSourcePath, virtualTargetPath) {
+		err = copier.CopyFile(fsSourcePath, fsTargetPath)
+	} else {
+		err = c.copyFileContents(fsSrc, fsDst, fsSourcePath, fsTargetPath)
+	}
+	if err != nil {
+		c.Log(logger.LevelWarn, "failed to copy %#v -> %#v: %+v", fsSourcePath, fsTargetPath, err)
+		return c.GetFsError(fsDst, err)
+	}
+	vfs.SetPathPermissions(fsDst, fsTargetPath, c.User.GetUID(), c.User.GetGID())
+	c.updateQuotaAfterCopy(virtualTargetPath, numFiles, srcInfo.Size()-initialSize)
+	logger.CommandLog(copyLogSender, fsSourcePath, fsTargetPath, c.User.Username, "", c.ID, c.protocol, -1, -1,
+		"", "", "", srcInfo.Size(), c.localAddr, c.remoteAddr)
+	ExecuteActionNotification(&c.User, operationCopy, fsSourcePath, virtualSourcePath, fsTargetPath, virtualTargetPath, "",
+		c.protocol, c.GetRemoteIP(), srcInfo.Size(), nil)
+
+	return nil
+}
+
+// copyFileContents reads fsSourcePath from fsSrc and writes its contents to fsTargetPath on fsDst
+func (c *BaseConnection) copyFileContents(fsSrc, fsDst vfs.Fs, fsSourcePath, fsTargetPath string) error {
+	srcFile, srcReader, srcCancelFn, err := fsSrc.Open(fsSourcePath, 0)
+	if err != nil {
+		return err
+	}
+	var reader io.ReadCloser = srcReader
+	if srcFile != nil {
+		reader = srcFile
+	}
+	dstFile, dstWriter, dstCancelFn, err := fsDst.Create(fsTargetPath, 0)
+	if err != nil {
+		if srcCancelFn != nil {
+			srcCancelFn()
+		}
+		reader.Close()
+		return err
+	}
+	var writer io.WriteCloser = dstWriter
+	if dstFile != nil {
+		writer = dstFile
+	}
+	_, err = io.Copy(writer, reader)
+	if err != nil && dstCancelFn != nil {
+		dstCancelFn()
+	}
+	errClose := writer.Close()
+	if err == nil {
+		err = errClose
+	}
+	if srcCancelFn != nil {
+		srcCancelFn()
+	}
+	reader.Close()
+	return err
+}
+
+func (c *BaseConnection) updateQuotaAfterCopy(virtualTargetPath string, numFiles int, sizeDiff int64) {
+	vfolder, err := c.User.GetVirtualFolderForPath(path.Dir(virtualTargetPath))
+	if err == nil {
+		dataprovider.UpdateVirtualFolderQuota(&vfolder.BaseVirtualFolder, numFiles, sizeDiff, false) //nolint:errcheck
+		if vfolder.IsIncludedInUserQuota() {
+			dataprovider.UpdateUserQuota(&c.User, numFiles, sizeDiff, false) //nolint:errcheck
+		}
+	} else {
+		dataprovider.UpdateUserQuota(&c.User, numFiles, sizeDiff, false) //nolint:errcheck
+	}
+}
+
+func (c *BaseConnection) isCopyPermitted(fsDst vfs.Fs, fsTargetPath, virtualSourcePath, virtualTargetPath string) bool {
+	if c.User.IsVirtualFolder(virtualTargetPath) {
+		c.Log(logger.LevelWarn, "copying to a virtual folder root is not allowed: %#v", virtualTargetPath)
+		return false
+	}
+	if c.User.IsMappedPath(fsTargetPath) && vfs.IsLocalOrCryptoFs(fsDst) {
+		c.Log(logger.LevelWarn, "copying to a directory mapped as virtual folder is not allowed: %#v", fsTargetPath)
+		return false
+	}
+	if !c.User.IsFileAllowed(virtualSourcePath) || !c.User.IsFileAllowed(virtualTargetPath) {
+		c.Log(logger.LevelDebug, "copying file is not allowed, source: %#v target: %#v",
+			virtualSourcePath, virtualTargetPath)
+		return false
+	}
+	if !c.User.HasPerms([]string{dataprovider.PermListItems, dataprovider.PermDownload}, path.Dir(virtualSourcePath)) {
+		return false
+	}
+	return c.User.HasPerm(dataprovider.PermUpload, path.Dir(virtualTargetPath))
+}
+
+// CombineFiles concatenates the regular files virtualSourcePaths, in the given order, and
+// stores the result in virtualTargetPath. If the target already exists the sources are
+// appended to it. The files are combined server side, so all the paths must be on the
+// same storage backend and it must implement the vfs.FsFileCombiner interface.
+// The source files are removed after combining them
+func (c *BaseConnection) CombineFiles(virtualTargetPath string, virtualSourcePaths []string) error {
+	if len(virtualSourcePaths) == 0 {
+		return c.GetGenericError(nil)
+	}
+	fs, fsTargetPath, err := c.GetFsAndResolvedPath(virtualTargetPath)
+	if err != nil {
+		return err
+	}
+	combiner, ok := fs.(vfs.FsFileCombiner)
+	if !ok {
+		c.Log(logger.LevelDebug, "unable to combine files, the filesystem for %#v does not support it", virtualTargetPath)
+		return c.GetOpUnsupportedError()
+	}
+	numFiles := 1
+	initialSize := int64(0)
+	var fsSourcePaths []string
+	if info, err := fs.Lstat(fsTargetPath); err == nil {
+		if !info.Mode().IsRegular() {
+			c.Log(logger.LevelDebug, "unable to combine files, the target %#v is not a regular file", virtualTargetPath)
+			return c.GetOpUnsupportedError()
+		}
+		if !c.User.HasPerm(dataprovider.PermOverwrite, path.Dir(virtualTargetPath)) {
+			return c.GetPermissionDeniedError()
+		}
+		numFiles = 0
+		initialSize = info.Size()
+		fsSourcePaths = append(fsSourcePaths, fsTargetPath)
+	} else if !fs.IsNotExist(err) {
+		return c.GetFsError(fs, err)
+	}
+	sourcesInfo := make([]os.FileInfo, 0, len(virtualSourcePaths))
+	combinedSize := int64(0)
+	for _, virtualSourcePath := range virtualSourcePaths {
+		if virtualSourcePath == virtualTargetPath || !c.isLocalOrSameFolderRename(virtualSourcePath, virtualTargetPath) {
+			c.Log(logger.LevelDebug, "unable to combine %#v into %#v, source and target must be different files "+
+				"within the same folder", virtualSourcePath, virtualTargetPath)
+			return c.GetOpUnsupportedError()
+		}
+		_, fsSourcePath, err := c.GetFsAndResolvedPath(virtualSourcePath)
+		if err != nil {
+			return err
+		}
+		if util.IsStringInSlice(fsSourcePath, fsSourcePaths) {
+			c.Log(logger.LevelDebug, "unable to combine files, duplicated source %#v", virtualSourcePath)
+			return c.GetOpUnsupportedError()
+		}
+		if !c.isCopyPermitted(fs, fsTargetPath, virtualSourcePath, virtualTargetPath) {
+			return c.GetPermissionDeniedError()
+		}
+		if err := c.IsRemoveFileAllowed(virtualSourcePath); err != nil {
+			return err
+		}
+		info, err := fs.Lstat(fsSourcePath)
+		if err != nil {
+			return c.GetFsError(fs, err)
+		}
+		if !info.Mode().IsRegular() {
+			c.Log(logger.LevelDebug, "unable to combine files, the source %#v is not a regular file", virtualSourcePath)
+			return c.GetOpUnsupportedError()
+		}
+		fsSourcePaths = append(fsSourcePaths, fsSourcePath)
+		sourcesInfo = append(sourcesInfo, info)
+		combinedSize += info.Size()
+	}
+	if c.User.Filters.MaxUploadFileSize > 0 && initialSize+combinedSize > c.User.Filters.MaxUploadFileSize {
+		c.Log(logger.LevelInfo, "denying combine, the combined size %v exceeds the max upload file size %v",
+			initialSize+combinedSize, c.User.Filters.MaxUploadFileSize)
+		return c.GetQuotaExceededError()
+	}
+	if err := ExecutePreAction(&c.User, OperationPreUpload, fsTargetPath, virtualTargetPath, c.protocol, c.GetRemoteIP(),
+		initialSize, 0); err != nil {
+		c.Log(logger.LevelDebug, "combine into %#v denied by pre action: %v", virtualTargetPath, err)
+		return c.GetPermissionDeniedError()
+	}
+	if err := combiner.CombineFiles(fsTargetPath, fsSourcePaths); err != nil {
+		c.Log(logger.LevelWarn, "failed to combine files into %#v: %+v", fsTargetPath, err)
+		return c.GetFsError(fs, err)
+	}
+	c.updateQuotaAfterCopy(virtualTargetPath, numFiles, combinedSize)
+	logger.CommandLog(combineLogSender, fsTargetPath, "", c.User.Username, "", c.ID, c.protocol, -1, -1,
+		"", "", "", initialSize+combinedSize, c.localAddr, c.remoteAddr)
+	ExecuteActionNotification(&c.User, operationUpload, fsTargetPath, virtualTargetPath, "", "", "", c.protocol,
+		c.GetRemoteIP(), initialSize+combinedSize, nil)
+
+	var errRemove error
+	for idx, virtualSourcePath := range virtualSourcePaths {
+		fsSourcePath := fsSourcePaths[len(fsSourcePaths)-len(virtualSourcePaths)+idx]
+		if err := c.RemoveFile(fs, fsSourcePath, virtualSourcePath, sourcesInfo[idx]); err != nil {
+			c.Log(logger.LevelWarn, "unable to remove combined file %#v: %v", virtualSourcePath, err)
+			if errRemove == nil {
+				errRemove = err
+			}
+		}
+	}
+	return errRemove
+}
+
 // CreateSymlink creates fsTargetPath as a symbolic link to fsSourcePath
 func (c *BaseConnection) CreateSymlink(virtualSourcePath, virtualTargetPath string) error {
 	if c.isCrossFoldersRequest(virtualSourcePath, virtualTargetPath) {
@@ -833,6 +1096,20 @@ func (c *BaseConnection) GetMaxWriteSize(quotaResult vfs.QuotaCheckResult, isRes
 	return maxWriteSize, nil
 }
 
+// GetMaxWriteSizeForPendingUpload returns the max write size for an interrupted upload
+// to a Cloud Storage backend resumed at the specified offset. The interrupted uploads
+// are not included in the quota, so the already uploaded bytes are subtracted from
+// the max write size allowed for a new file
+func (c *BaseConnection) GetMaxWriteSizeForPendingUpload(maxWriteSize, offset int64) (int64, error) {
+	if maxWriteSize <= 0 || offset <= 0 {
+		return maxWriteSize, nil
+	}
+	if maxWriteSize <= offset {
+		return 0, c.GetQuotaExceededError()
+	}
+	return maxWriteSize - offset, nil
+}
+
 // HasSpace checks user's quota usage
 func (c *BaseConnection) HasSpace(checkFiles, getUsage bool, requestPath string) vfs.QuotaCheckResult {
 	result := vfs.QuotaCheckResult{
@@ -883,6 +1160,46 @@ func (c *BaseConnection) HasSpace(checkFiles, getUsage bool, requestPath string)
 	return result
 }
 
+// GetTransferQuota returns the data transfers quota for the given virtual path.
+// Transfers inside a virtual folder with data transfer limits are restricted by
+// both the user and the folder limits
+func (c *BaseConnection) GetTransferQuota(virtualPath string) dataprovider.TransferQuota {
+	var result dataprovider.TransferQuota
+
+	if dataprovider.GetQuotaTracking() == 0 {
+		return result
+	}
+	if c.User.HasTransferQuotaRestrictions() {
+		ulSize, dlSize, err := dataprovider.GetUsedTransferQuota(&c.User)
+		if err != nil {
+			c.Log(logger.LevelWarn, "error getting used transfer quota for %#v: %v", c.User.Username, err)
+			// deny transfers if we are unable to get the used data transfer
+			ulSize = c.User.UploadDataTransfer * 1048576
+			dlSize = c.User.DownloadDataTransfer * 1048576
+			if c.User.TotalDataTransfer > 0 {
+				ulSize = c.User.TotalDataTransfer * 1048576
+			}
+		}
+		result = dataprovider.NewTransferQuota(c.User.UploadDataTransfer, c.User.DownloadDataTransfer,
+			c.User.TotalDataTransfer, ulSize, dlSize)
+	}
+	vfolder, err := c.User.GetVirtualFolderForPath(path.Dir(virtualPath))
+	if err == nil && vfolder.HasTransferQuotaRestrictions() {
+		ulSize, dlSize, err := dataprovider.GetUsedVirtualFolderTransferQuota(&vfolder.BaseVirtualFolder)
+		if err != nil {
+			c.Log(logger.LevelWarn, "error getting used transfer quota for folder %#v: %v", vfolder.Name, err)
+			ulSize = vfolder.UploadDataTransfer * 1048576
+			dlSize = vfolder.DownloadDataTransfer * 1048576
+			if vfolder.TotalDataTransfer > 0 {
+				ulSize = vfolder.TotalDataTransfer * 1048576
+			}
+		}
+		result = result.Merge(dataprovider.NewTransferQuota(vfolder.UploadDataTransfer, vfolder.DownloadDataTransfer,
+			vfolder.TotalDataTransfer, ulSize, dlSize))
+	}
+	return result
+}
+
 // returns true if this is a rename on the same fs or local virtual folders
 func (c *BaseConnection) isLocalOrSameFolderRename(virtualSourcePath, virtualTargetPath string) bool {
 	sourceFolder, errSrc := c.User.GetVirtualFolderForPath(virtualSourcePath)
@@ -1046,7 +1363,7 @@ func (c *BaseConnection) GetPermissionDeniedError() error {
 	switch c.protocol {
 	case ProtocolSFTP:
 		return sftp.ErrSSHFxPermissionDenied
-	case ProtocolWebDAV, ProtocolFTP, ProtocolHTTP:
+	case ProtocolWebDAV, ProtocolFTP, ProtocolHTTP, ProtocolHTTPShare, ProtocolS3:
 		return os.ErrPermission
 	default:
 		return ErrPermissionDenied
@@ -1058,7 +1375,7 @@ func (c *BaseConnection) GetNotExistError() error {
 	switch c.protocol {
 	case ProtocolSFTP:
 		return sftp.ErrSSHFxNoSuchFile
-	case ProtocolWebDAV, ProtocolFTP, ProtocolHTTP:
+	case ProtocolWebDAV, ProtocolFTP, ProtocolHTTP, ProtocolHTTPShare, ProtocolS3:
 		return os.ErrNotExist
 	default:
 		return ErrNotExist
@@ -1087,6 +1404,16 @@ func (c *BaseConnection) GetQuotaExceededError() error {
 	}
 }
 
+// GetReadQuotaExceededError returns an appropriate read quota limit exceeded error for the connection protocol
+func (c *BaseConnection) GetReadQuotaExceededError() error {
+	switch c.protocol {
+	case ProtocolSFTP:
+		return fmt.Errorf("%w: %v", sftp.ErrSSHFxFailure, ErrReadQuotaExceeded.Error())
+	default:
+		return ErrReadQuotaExceeded
+	}
+}
+
 // IsQuotaExceededError returns true if the given error is a quota exceeded error
 func (c *BaseConnection) IsQuotaExceededError(err error) bool {
 	switch c.protocol {
@@ -1121,7 +1448,7 @@ func (c *BaseConnection) GetGenericError(err error) error {
 		return sftp.ErrSSHFxFailure
 	default:
 		if err == ErrPermissionDenied || err == ErrNotExist || err == ErrOpUnsupported ||
-			err == ErrQuotaExceeded || err == vfs.ErrStorageSizeUnavailable {
+			err == ErrQuotaExceeded || err == ErrReadQuotaExceeded || err == vfs.ErrStorageSizeUnavailable {
 			return err
 		}
 		return ErrGenericFailure
@@ -1,17 +1,21 @@
 package common
 
 import (
+	"io"
 	"os"
 	"path"
 	"path/filepath"
 	"runtime"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/eikenb/pipeat"
 	"github.com/pkg/sftp"
 	"github.com/stretchr/testify/assert"
 
 	"github.com/drakkan/sftpgo/v2/dataprovider"
+	"github.com/drakkan/sftpgo/v2/kms"
 	"github.com/drakkan/sftpgo/v2/sdk"
 	"github.com/drakkan/sftpgo/v2/vfs"
 )
@@ -43,6 +47,41 @@ func newMockOsFs(hasVirtualFolders bool, connectionID, rootDir string) vfs.Fs {
 	}
 }
 
+// mockCloudFs returns pipes for downloads, as the cloud backends do
+type mockCloudFs struct {
+	vfs.Fs
+	opens int32
+}
+
+func (fs *mockCloudFs) Open(name string, offset int64) (vfs.File, *pipeat.PipeReaderAt, func(), error) {
+	atomic.AddInt32(&fs.opens, 1)
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		f.Close()
+		return nil, nil, nil, err
+	}
+	r, w, err := pipeat.Pipe()
+	if err != nil {
+		f.Close()
+		return nil, nil, nil, err
+	}
+	go func() {
+		_, err := io.Copy(w, f)
+		f.Close()
+		w.CloseWithError(err) //nolint:errcheck
+	}()
+	return nil, r, func() {}, nil
+}
+
+func newMockCloudFs(connectionID, rootDir string) *mockCloudFs {
+	return &mockCloudFs{
+		Fs: vfs.NewOsFs(connectionID, rootDir, ""),
+	}
+}
+
 func TestRemoveErrors(t *testing.T) {
 	mappedPath := filepath.Join(os.TempDir(), "map")
 	homePath := filepath.Join(os.TempDir(), "home")
@@ -75,6 +114,25 @@ func TestRemoveErrors(t *testing.T) {
 	assert.Error(t, err)
 }
 
+func TestCombineFilesErrors(t *testing.T) {
+	user := dataprovider.User{
+		BaseUser: sdk.BaseUser{
+			Username: "combine_errors_user",
+			HomeDir:  filepath.Clean(os.TempDir()),
+		},
+	}
+	user.Permissions = make(map[string][]string)
+	user.Permissions["/"] = []string{dataprovider.PermAny}
+	conn := NewBaseConnection("", ProtocolFTP, "", "", user)
+	err := conn.CombineFiles("/target", nil)
+	assert.Error(t, err)
+	// the local filesystem does not combine files server side
+	err = conn.CombineFiles("/target", []string{"/source1", "/source2"})
+	if assert.Error(t, err) {
+		assert.ErrorIs(t, err, ErrOpUnsupported)
+	}
+}
+
 func TestSetStatMode(t *testing.T) {
 	oldSetStatMode := Config.SetstatMode
 	Config.SetstatMode = 1
@@ -333,3 +391,244 @@ func TestMaxWriteSize(t *testing.T) {
 	assert.EqualError(t, err, ErrOpUnsupported.Error())
 	assert.Equal(t, int64(0), size)
 }
+
+func TestReadCache(t *testing.T) {
+	readFile := func(fs vfs.Fs, name string, offset int64) []byte {
+		_, r, _, err := fs.Open(name, offset)
+		if !assert.NoError(t, err) {
+			return nil
+		}
+		data, err := io.ReadAll(r)
+		assert.NoError(t, err)
+		assert.NoError(t, r.Close())
+		return data
+	}
+
+	rootDir := filepath.Join(os.TempDir(), "read_cache")
+	err := os.MkdirAll(rootDir, os.ModePerm)
+	assert.NoError(t, err)
+	mockFs := newMockCloudFs("id", rootDir)
+	fsConfig := vfs.Filesystem{
+		Provider: sdk.LocalFilesystemProvider,
+		ReadCache: vfs.ReadCacheConfig{
+			ReadCacheConfig: sdk.ReadCacheConfig{
+				MaxSize:  1,
+				Eviction: "lru",
+			},
+		},
+	}
+	// the read cache is only supported for cloud backends
+	fs := vfs.NewReadCacheFs(mockFs, &fsConfig)
+	_, ok := fs.(*vfs.ReadCacheFs)
+	assert.False(t, ok)
+	fsConfig.Provider = sdk.S3FilesystemProvider
+	fs = vfs.NewReadCacheFs(mockFs, &fsConfig)
+	_, ok = fs.(*vfs.ReadCacheFs)
+	assert.True(t, ok)
+
+	content := []byte("read cache content")
+	filePath := filepath.Join(rootDir, "file")
+	err = os.WriteFile(filePath, content, os.ModePerm)
+	assert.NoError(t, err)
+
+	assert.Equal(t, content, readFile(fs, filePath, 0))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&mockFs.opens))
+	// now the file is served from the cache, partial downloads included
+	assert.Equal(t, content, readFile(fs, filePath, 0))
+	assert.Equal(t, content[5:], readFile(fs, filePath, 5))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&mockFs.opens))
+	// a modification outside SFTPGo must be detected
+	content = []byte("modified read cache content")
+	err = os.WriteFile(filePath, content, os.ModePerm)
+	assert.NoError(t, err)
+	assert.Equal(t, content, readFile(fs, filePath, 0))
+	assert.Equal(t, int32(2), atomic.LoadInt32(&mockFs.opens))
+	assert.Equal(t, content, readFile(fs, filePath, 0))
+	assert.Equal(t, int32(2), atomic.LoadInt32(&mockFs.opens))
+	// a rename through SFTPGo must invalidate the cached file
+	renamedPath := filepath.Join(rootDir, "renamed")
+	err = fs.Rename(filePath, renamedPath)
+	assert.NoError(t, err)
+	assert.Equal(t, content, readFile(fs, renamedPath, 0))
+	assert.Equal(t, int32(3), atomic.LoadInt32(&mockFs.opens))
+	err = fs.Rename(renamedPath, filePath)
+	assert.NoError(t, err)
+	// the cache is shared among filesystems pointing to the same storage
+	otherFs := vfs.NewReadCacheFs(mockFs, &fsConfig)
+	assert.Equal(t, content, readFile(otherFs, filePath, 0))
+	assert.Equal(t, int32(4), atomic.LoadInt32(&mockFs.opens))
+	assert.Equal(t, content, readFile(fs, filePath, 0))
+	assert.Equal(t, int32(4), atomic.LoadInt32(&mockFs.opens))
+	// an upload through SFTPGo must invalidate the cached file
+	f, _, _, err := otherFs.Create(filePath, 0)
+	assert.NoError(t, err)
+	content = []byte("uploaded")
+	_, err = f.Write(content)
+	assert.NoError(t, err)
+	err = f.Close()
+	assert.NoError(t, err)
+	assert.Equal(t, content, readFile(fs, filePath, 0))
+	assert.Equal(t, int32(5), atomic.LoadInt32(&mockFs.opens))
+	// files bigger than the max cache size are never cached
+	bigFilePath := filepath.Join(rootDir, "big")
+	err = os.WriteFile(bigFilePath, make([]byte, 1048577), os.ModePerm)
+	assert.NoError(t, err)
+	assert.Len(t, readFile(fs, bigFilePath, 0), 1048577)
+	assert.Len(t, readFile(fs, bigFilePath, 0), 1048577)
+	assert.Equal(t, int32(7), atomic.LoadInt32(&mockFs.opens))
+	// a file that fills the cache evicts the other ones
+	err = os.WriteFile(bigFilePath, make([]byte, 1048576), os.ModePerm)
+	assert.NoError(t, err)
+	assert.Len(t, readFile(fs, bigFilePath, 0), 1048576)
+	assert.Len(t, readFile(fs, bigFilePath, 0), 1048576)
+	assert.Equal(t, int32(8), atomic.LoadInt32(&mockFs.opens))
+	assert.Equal(t, content, readFile(fs, filePath, 0))
+	assert.Equal(t, int32(9), atomic.LoadInt32(&mockFs.opens))
+	// removing the file must invalidate the cached contents
+	err = fs.Remove(filePath, false)
+	assert.NoError(t, err)
+	_, _, _, err = fs.Open(filePath, 0)
+	assert.Error(t, err)
+
+	err = os.RemoveAll(rootDir)
+	assert.NoError(t, err)
+}
+
+func TestEncryptedFs(t *testing.T) {
+	readFile := func(fs vfs.Fs, name string, offset int64) []byte {
+		_, r, _, err := fs.Open(name, offset)
+		if !assert.NoError(t, err) {
+			return nil
+		}
+		data, err := io.ReadAll(r)
+		assert.NoError(t, err)
+		assert.NoError(t, r.Close())
+		return data
+	}
+	writeFile := func(fs vfs.Fs, name string, content []byte) {
+		_, w, _, err := fs.Create(name, 0)
+		if !assert.NoError(t, err) {
+			return
+		}
+		_, err = w.Write(content)
+		assert.NoError(t, err)
+		assert.NoError(t, w.Close())
+	}
+
+	rootDir := filepath.Join(os.TempDir(), "encrypted_fs")
+	err := os.MkdirAll(filepath.Join(rootDir, "sub"), os.ModePerm)
+	assert.NoError(t, err)
+	mockFs := newMockCloudFs("id", rootDir)
+	fsConfig := vfs.Filesystem{
+		Provider: sdk.S3FilesystemProvider,
+	}
+	// the encryption is disabled without a passphrase
+	fs, err := vfs.NewEncryptedFs(mockFs, &fsConfig)
+	assert.NoError(t, err)
+	assert.False(t, vfs.IsEncryptedFs(fs))
+	fsConfig.Encryption.Passphrase = kms.NewPlainSecret("encryption passphrase")
+	fsConfig.Provider = sdk.FTPFilesystemProvider
+	fs, err = vfs.NewEncryptedFs(mockFs, &fsConfig)
+	assert.NoError(t, err)
+	assert.False(t, vfs.IsEncryptedFs(fs))
+	fsConfig.Provider = sdk.S3FilesystemProvider
+	fs, err = vfs.NewEncryptedFs(mockFs, &fsConfig)
+	assert.NoError(t, err)
+	assert.True(t, vfs.IsEncryptedFs(fs))
+	assert.False(t, fs.IsUploadResumeSupported())
+	_, ok := fs.(vfs.FsFileCombiner)
+	assert.False(t, ok)
+
+	// three full packages and a partial one
+	content := make([]byte, 3*65536+100)
+	for idx := range content {
+		content[idx] = byte(idx % 251)
+	}
+	filePath := filepath.Join(rootDir, "file")
+	writeFile(fs, filePath, content)
+	info, err := os.Stat(filePath)
+	assert.NoError(t, err)
+	assert.Greater(t, info.Size(), int64(len(content)))
+	rawContent, err := os.ReadFile(filePath)
+	assert.NoError(t, err)
+	assert.NotContains(t, string(rawContent), string(content[:100]))
+	info, err = fs.Stat(filePath)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(len(content)), info.Size())
+	info, err = fs.Lstat(filePath)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(len(content)), info.Size())
+
+	assert.Equal(t, content, readFile(fs, filePath, 0))
+	for _, offset := range []int64{10, 65536, 70000, 2*65536 + 1, int64(len(content)) - 1} {
+		assert.Equal(t, content[offset:], readFile(fs, filePath, offset), "offset %v", offset)
+	}
+	assert.Len(t, readFile(fs, filePath, int64(len(content))), 0)
+	// an empty file
+	emptyPath := filepath.Join(rootDir, "sub", "empty")
+	writeFile(fs, emptyPath, nil)
+	assert.Len(t, readFile(fs, emptyPath, 0), 0)
+	smallPath := filepath.Join(rootDir, "sub", "small")
+	writeFile(fs, smallPath, []byte("small text content"))
+	assert.Equal(t, []byte("small text content"), readFile(fs, smallPath, 0))
+	assert.Equal(t, []byte("content"), readFile(fs, smallPath, 11))
+	mimeType, err := fs.GetMimeType(smallPath)
+	assert.NoError(t, err)
+	assert.Contains(t, mimeType, "text/plain")
+
+	list, err := fs.ReadDir(filepath.Join(rootDir, "sub"))
+	assert.NoError(t, err)
+	assert.Len(t, list, 2)
+	for _, info := range list {
+		switch info.Name() {
+		case "empty":
+			assert.Equal(t, int64(0), info.Size())
+		case "small":
+			assert.Equal(t, int64(18), info.Size())
+		default:
+			t.Errorf("unexpected file %#v", info.Name())
+		}
+	}
+	// the quota is computed on the decrypted sizes
+	numFiles, size, err := fs.ScanRootDirContents()
+	assert.NoError(t, err)
+	assert.Equal(t, 3, numFiles)
+	assert.Equal(t, int64(len(content)+18), size)
+	walkedSize := int64(0)
+	err = fs.Walk(rootDir, func(walkedPath string, info os.FileInfo, err error) error {
+		if err == nil && info.Mode().IsRegular() {
+			walkedSize += info.Size()
+		}
+		return err
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, size, walkedSize)
+	err = fs.Truncate(filePath, 0)
+	assert.ErrorIs(t, err, vfs.ErrVfsUnsupported)
+
+	// the ciphertext can be cached, ranged reads are served from the cache
+	fsConfig.ReadCache.MaxSize = 1
+	cachedFs, err := vfs.NewEncryptedFs(vfs.NewReadCacheFs(mockFs, &fsConfig), &fsConfig)
+	assert.NoError(t, err)
+	assert.Equal(t, content, readFile(cachedFs, filePath, 0))
+	opens := atomic.LoadInt32(&mockFs.opens)
+	assert.Equal(t, content[70000:], readFile(cachedFs, filePath, 70000))
+	assert.Equal(t, opens, atomic.LoadInt32(&mockFs.opens))
+
+	// a different passphrase cannot decrypt the files
+	fsConfig.ReadCache.MaxSize = 0
+	fsConfig.Encryption.Passphrase = kms.NewPlainSecret("another passphrase")
+	otherFs, err := vfs.NewEncryptedFs(mockFs, &fsConfig)
+	assert.NoError(t, err)
+	_, r, _, err := otherFs.Open(smallPath, 0)
+	if assert.NoError(t, err) {
+		_, err = io.ReadAll(r)
+		assert.Error(t, err)
+		r.Close()
+	}
+	_, err = otherFs.GetMimeType(smallPath)
+	assert.Error(t, err)
+
+	err = os.RemoveAll(rootDir)
+	assert.NoError(t, err)
+}
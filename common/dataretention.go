@@ -11,6 +11,7 @@ import (
 	"os/exec"
 	"path"
 	"path/filepath"
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -59,6 +60,7 @@ func (c *ActiveRetentionChecks) Get() []RetentionCheck {
 			StartTime:     check.StartTime,
 			Notifications: notificationsCopy,
 			Email:         check.Email,
+			DryRun:        check.DryRun,
 			Folders:       foldersCopy,
 		})
 	}
@@ -85,6 +87,9 @@ func (c *ActiveRetentionChecks) Add(check RetentionCheck, user *dataprovider.Use
 	check.StartTime = util.GetTimeAsMsSinceEpoch(time.Now())
 	check.conn = conn
 	check.updateUserPermissions()
+	if check.DryRun {
+		check.expiredFiles = make(map[string]bool)
+	}
 	c.Checks = append(c.Checks, check)
 
 	return &check
@@ -115,8 +120,20 @@ type FolderRetention struct {
 	// for the paths "/" and "/sub" then the retention for "/" is applied for any file outside
 	// the "/sub" directory
 	Path string `json:"path"`
-	// Retention time in hours. 0 means exclude this path
+	// Retention time in hours. 0 means no age based retention. If MaxFiles and
+	// MaxSize are 0 too the path is excluded
 	Retention int `json:"retention"`
+	// MaxFiles defines the maximum number of files to keep inside this path,
+	// the oldest files are removed first. 0 means no limit
+	MaxFiles int `json:"max_files,omitempty"`
+	// MaxSize defines the maximum size, as bytes, of the files to keep inside this path,
+	// the oldest files are removed first. 0 means no limit
+	MaxSize int64 `json:"max_size,omitempty"`
+	// ArchivePath is an optional virtual path where the expired files are moved
+	// instead of deleting them. It can be a directory on a different virtual folder
+	// and so on a different storage backend. The directory structure relative to Path
+	// is preserved
+	ArchivePath string `json:"archive_path,omitempty"`
 	// DeleteEmptyDirs defines if empty directories will be deleted.
 	// The user need the delete permission
 	DeleteEmptyDirs bool `json:"delete_empty_dirs,omitempty"`
@@ -136,17 +153,62 @@ func (f *FolderRetention) isValid() error {
 		return util.NewValidationError(fmt.Sprintf("invalid folder retention %v, it must be greater or equal to zero",
 			f.Retention))
 	}
+	if f.MaxFiles < 0 {
+		return util.NewValidationError(fmt.Sprintf("invalid max files %v for path %#v, it must be greater or equal to zero",
+			f.MaxFiles, f.Path))
+	}
+	if f.MaxSize < 0 {
+		return util.NewValidationError(fmt.Sprintf("invalid max size %v for path %#v, it must be greater or equal to zero",
+			f.MaxSize, f.Path))
+	}
+	if f.ArchivePath != "" {
+		f.ArchivePath = path.Clean(f.ArchivePath)
+		if !path.IsAbs(f.ArchivePath) {
+			return util.NewValidationError(fmt.Sprintf("folder retention: invalid archive path %#v, please specify an absolute POSIX path",
+				f.ArchivePath))
+		}
+		if isPathInside(f.ArchivePath, f.Path) {
+			return util.NewValidationError(fmt.Sprintf("folder retention: the path %#v cannot be inside the archive path %#v",
+				f.Path, f.ArchivePath))
+		}
+	}
 	return nil
 }
 
+func (f *FolderRetention) hasLimits() bool {
+	return f.MaxFiles > 0 || f.MaxSize > 0
+}
+
+func (f *FolderRetention) isExcluded() bool {
+	return f.Retention == 0 && !f.hasLimits()
+}
+
+// isPathInside returns true if virtualPath is dirPath or is inside it
+func isPathInside(dirPath, virtualPath string) bool {
+	if dirPath == "/" || dirPath == virtualPath {
+		return true
+	}
+	return strings.HasPrefix(virtualPath, dirPath+"/")
+}
+
 type folderRetentionCheckResult struct {
-	Path         string        `json:"path"`
-	Retention    int           `json:"retention"`
-	DeletedFiles int           `json:"deleted_files"`
-	DeletedSize  int64         `json:"deleted_size"`
-	Elapsed      time.Duration `json:"-"`
-	Info         string        `json:"info,omitempty"`
-	Error        string        `json:"error,omitempty"`
+	Path          string        `json:"path"`
+	Retention     int           `json:"retention"`
+	MaxFiles      int           `json:"max_files,omitempty"`
+	MaxSize       int64         `json:"max_size,omitempty"`
+	ArchivePath   string        `json:"archive_path,omitempty"`
+	DeletedFiles  int           `json:"deleted_files"`
+	DeletedSize   int64         `json:"deleted_size"`
+	ArchivedFiles int           `json:"archived_files"`
+	ArchivedSize  int64         `json:"archived_size"`
+	Elapsed       time.Duration `json:"-"`
+	Info          string        `json:"info,omitempty"`
+	Error         string        `json:"error,omitempty"`
+}
+
+type retentionFile struct {
+	virtualPath string
+	info        os.FileInfo
 }
 
 // RetentionCheck defines an active retention check
@@ -161,9 +223,14 @@ type RetentionCheck struct {
 	Notifications []RetentionCheckNotification `json:"notifications,omitempty"`
 	// email to use if the notification method is set to email
 	Email string `json:"email,omitempty"`
+	// DryRun defines if the check only reports the files to delete or archive
+	// without changing anything
+	DryRun bool `json:"dry_run,omitempty"`
 	// Cleanup results
 	results []*folderRetentionCheckResult `json:"-"`
 	conn    *BaseConnection
+	// files already processed in dry run mode
+	expiredFiles map[string]bool
 }
 
 // Validate returns an error if the specified folders are not valid
@@ -175,7 +242,7 @@ func (c *RetentionCheck) Validate() error {
 		if err := f.isValid(); err != nil {
 			return err
 		}
-		if f.Retention > 0 {
+		if !f.isExcluded() {
 			nothingToDo = false
 		}
 		if _, ok := folderPaths[f.Path]; ok {
@@ -210,10 +277,42 @@ func (c *RetentionCheck) updateUserPermissions() {
 	for _, folder := range c.Folders {
 		if folder.IgnoreUserPermissions {
 			c.conn.User.Permissions[folder.Path] = []string{dataprovider.PermAny}
+			if folder.ArchivePath != "" {
+				c.conn.User.Permissions[folder.ArchivePath] = []string{dataprovider.PermAny}
+			}
+		}
+	}
+}
+
+// checkArchiveRootPaths creates the root directories for the filesystems used
+// as archive, if missing, as we do on user login
+func (c *RetentionCheck) checkArchiveRootPaths() {
+	if c.DryRun {
+		return
+	}
+	for _, folder := range c.Folders {
+		if folder.ArchivePath == "" {
+			continue
+		}
+		fs, err := c.conn.User.GetFilesystemForPath(folder.ArchivePath, c.conn.ID)
+		if err == nil {
+			fs.CheckRootPath(c.conn.User.Username, c.conn.User.GetUID(), c.conn.User.GetGID())
 		}
 	}
 }
 
+// isArchiveDir returns true if the specified directory is an archive path,
+// or is inside an archive path, for any of the folders to check.
+// The archived files must not be processed again
+func (c *RetentionCheck) isArchiveDir(virtualPath string) bool {
+	for _, folder := range c.Folders {
+		if folder.ArchivePath != "" && isPathInside(folder.ArchivePath, virtualPath) {
+			return true
+		}
+	}
+	return false
+}
+
 func (c *RetentionCheck) getFolderRetention(folderPath string) (FolderRetention, error) {
 	dirsForPath := util.GetDirsForVirtualPath(folderPath)
 	for _, dirPath := range dirsForPath {
@@ -235,6 +334,65 @@ func (c *RetentionCheck) removeFile(virtualPath string, info os.FileInfo) error
 	return c.conn.RemoveFile(fs, fsPath, virtualPath, info)
 }
 
+func (c *RetentionCheck) archiveFile(folderRetention *FolderRetention, virtualPath string) error {
+	target := path.Join(folderRetention.ArchivePath, strings.TrimPrefix(virtualPath, folderRetention.Path))
+	if err := createEventActionTargetDir(c.conn, target); err != nil {
+		return err
+	}
+	if c.conn.isLocalOrSameFolderRename(virtualPath, target) {
+		return c.conn.Rename(virtualPath, target)
+	}
+	// the archive path is on a different storage backend, copy the file and then remove the source
+	if !c.conn.User.HasPerm(dataprovider.PermUpload, path.Dir(target)) {
+		return c.conn.GetPermissionDeniedError()
+	}
+	if err := copyFileForEventAction(c.conn, virtualPath, target); err != nil {
+		return err
+	}
+	fs, fsPath, err := c.conn.GetFsAndResolvedPath(virtualPath)
+	if err != nil {
+		return err
+	}
+	info, err := fs.Lstat(fsPath)
+	if err != nil {
+		return c.conn.GetFsError(fs, err)
+	}
+	return c.conn.RemoveFile(fs, fsPath, virtualPath, info)
+}
+
+// processExpiredFile deletes or archives the specified file and updates the result.
+// In dry run mode the file is only added to the result
+func (c *RetentionCheck) processExpiredFile(folderRetention *FolderRetention, virtualPath string, info os.FileInfo,
+	result *folderRetentionCheckResult,
+) error {
+	if c.DryRun {
+		c.expiredFiles[virtualPath] = true
+	} else if folderRetention.ArchivePath != "" {
+		if err := c.archiveFile(folderRetention, virtualPath); err != nil {
+			return err
+		}
+	} else {
+		if err := c.removeFile(virtualPath, info); err != nil {
+			return err
+		}
+	}
+	if folderRetention.ArchivePath != "" {
+		result.ArchivedFiles++
+		result.ArchivedSize += info.Size()
+	} else {
+		result.DeletedFiles++
+		result.DeletedSize += info.Size()
+	}
+	return nil
+}
+
+func (c *RetentionCheck) isAlreadyProcessed(virtualPath string) bool {
+	if !c.DryRun {
+		return false
+	}
+	return c.expiredFiles[virtualPath]
+}
+
 func (c *RetentionCheck) cleanupFolder(folderPath string) error {
 	cleanupPerms := []string{dataprovider.PermListItems, dataprovider.PermDelete}
 	startTime := time.Now()
@@ -258,6 +416,7 @@ func (c *RetentionCheck) cleanupFolder(folderPath string) error {
 		return err
 	}
 	result.Retention = folderRetention.Retention
+	result.ArchivePath = folderRetention.ArchivePath
 	if folderRetention.Retention == 0 {
 		result.Elapsed = time.Since(startTime)
 		result.Info = "data retention check skipped: retention is set to 0"
@@ -281,6 +440,10 @@ func (c *RetentionCheck) cleanupFolder(folderPath string) error {
 	for _, info := range files {
 		virtualPath := path.Join(folderPath, info.Name())
 		if info.IsDir() {
+			if c.isArchiveDir(virtualPath) {
+				c.conn.Log(logger.LevelDebug, "retention check skipped for archive folder %#v", virtualPath)
+				continue
+			}
 			if err := c.cleanupFolder(virtualPath); err != nil {
 				result.Elapsed = time.Since(startTime)
 				result.Error = fmt.Sprintf("unable to check folder: %v", err)
@@ -288,33 +451,147 @@ func (c *RetentionCheck) cleanupFolder(folderPath string) error {
 				return err
 			}
 		} else {
+			if c.isAlreadyProcessed(virtualPath) {
+				continue
+			}
 			retentionTime := info.ModTime().Add(time.Duration(folderRetention.Retention) * time.Hour)
 			if retentionTime.Before(time.Now()) {
-				if err := c.removeFile(virtualPath, info); err != nil {
+				if err := c.processExpiredFile(&folderRetention, virtualPath, info, result); err != nil {
 					result.Elapsed = time.Since(startTime)
 					result.Error = fmt.Sprintf("unable to remove file %#v: %v", virtualPath, err)
 					c.conn.Log(logger.LevelWarn, "unable to remove file %#v, retention %v: %v",
 						virtualPath, retentionTime, err)
 					return err
 				}
-				c.conn.Log(logger.LevelDebug, "removed file %#v, modification time: %v, retention: %v hours, retention time: %v",
-					virtualPath, info.ModTime(), folderRetention.Retention, retentionTime)
-				result.DeletedFiles++
-				result.DeletedSize += info.Size()
+				c.conn.Log(logger.LevelDebug, "expired file %#v, modification time: %v, retention: %v hours, retention time: %v, "+
+					"archive path: %#v, dry run? %v", virtualPath, info.ModTime(), folderRetention.Retention, retentionTime,
+					folderRetention.ArchivePath, c.DryRun)
 			}
 		}
 	}
 
-	if folderRetention.DeleteEmptyDirs {
+	if folderRetention.DeleteEmptyDirs && !c.DryRun {
 		c.checkEmptyDirRemoval(folderPath)
 	}
 	result.Elapsed = time.Since(startTime)
-	c.conn.Log(logger.LevelDebug, "retention check completed for folder %#v, deleted files: %v, deleted size: %v bytes",
-		folderPath, result.DeletedFiles, result.DeletedSize)
+	c.conn.Log(logger.LevelDebug, "retention check completed for folder %#v, deleted files: %v, deleted size: %v bytes, "+
+		"archived files: %v, archived size: %v bytes", folderPath, result.DeletedFiles, result.DeletedSize,
+		result.ArchivedFiles, result.ArchivedSize)
 
 	return nil
 }
 
+// collectFiles adds to files the regular files inside folderPath, and its sub directories,
+// to which the specified folder retention applies
+func (c *RetentionCheck) collectFiles(folderRetention *FolderRetention, folderPath string, files *[]retentionFile) error {
+	cleanupPerms := []string{dataprovider.PermListItems, dataprovider.PermDelete}
+	if !c.conn.User.HasPerms(cleanupPerms, folderPath) {
+		c.conn.Log(logger.LevelInfo, "user %#v does not have permissions to check limits on %#v, skipped",
+			c.conn.User.Username, folderPath)
+		return nil
+	}
+	contents, err := c.conn.ListDir(folderPath)
+	if err != nil {
+		if err == c.conn.GetNotExistError() {
+			return nil
+		}
+		return err
+	}
+	for _, info := range contents {
+		virtualPath := path.Join(folderPath, info.Name())
+		if info.IsDir() {
+			if c.isArchiveDir(virtualPath) {
+				continue
+			}
+			dirRetention, err := c.getFolderRetention(virtualPath)
+			if err != nil || dirRetention.Path != folderRetention.Path {
+				// a more specific retention applies to this directory
+				continue
+			}
+			if err := c.collectFiles(folderRetention, virtualPath, files); err != nil {
+				return err
+			}
+		} else if info.Mode().IsRegular() && !c.isAlreadyProcessed(virtualPath) {
+			*files = append(*files, retentionFile{
+				virtualPath: virtualPath,
+				info:        info,
+			})
+		}
+	}
+	return nil
+}
+
+// applyLimits removes, or archives, the oldest files inside the specified folder
+// until both the max files and the max size limits are satisfied
+func (c *RetentionCheck) applyLimits(folderRetention *FolderRetention) error {
+	startTime := time.Now()
+	result := &folderRetentionCheckResult{
+		Path:        folderRetention.Path,
+		Retention:   folderRetention.Retention,
+		MaxFiles:    folderRetention.MaxFiles,
+		MaxSize:     folderRetention.MaxSize,
+		ArchivePath: folderRetention.ArchivePath,
+	}
+	c.results = append(c.results, result)
+	c.conn.Log(logger.LevelDebug, "start limits check for folder %#v, max files: %v, max size: %v, archive path: %#v",
+		folderRetention.Path, folderRetention.MaxFiles, folderRetention.MaxSize, folderRetention.ArchivePath)
+
+	var files []retentionFile
+	if err := c.collectFiles(folderRetention, folderRetention.Path, &files); err != nil {
+		result.Elapsed = time.Since(startTime)
+		result.Error = fmt.Sprintf("unable to list files: %v", err)
+		c.conn.Log(logger.LevelWarn, "unable to list files inside %#v: %v", folderRetention.Path, err)
+		return err
+	}
+	// newest files first
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].info.ModTime().After(files[j].info.ModTime())
+	})
+	numFiles := 0
+	totalSize := int64(0)
+	for _, file := range files {
+		numFiles++
+		totalSize += file.info.Size()
+		if (folderRetention.MaxFiles > 0 && numFiles > folderRetention.MaxFiles) ||
+			(folderRetention.MaxSize > 0 && totalSize > folderRetention.MaxSize) {
+			if err := c.processExpiredFile(folderRetention, file.virtualPath, file.info, result); err != nil {
+				result.Elapsed = time.Since(startTime)
+				result.Error = fmt.Sprintf("unable to remove file %#v: %v", file.virtualPath, err)
+				c.conn.Log(logger.LevelWarn, "unable to remove file %#v exceeding the limits: %v", file.virtualPath, err)
+				return err
+			}
+			c.conn.Log(logger.LevelDebug, "file %#v exceeds the limits, modification time: %v, archive path: %#v, dry run? %v",
+				file.virtualPath, file.info.ModTime(), folderRetention.ArchivePath, c.DryRun)
+		}
+	}
+	if folderRetention.DeleteEmptyDirs && !c.DryRun {
+		c.removeEmptyDirs(folderRetention, folderRetention.Path)
+	}
+	result.Elapsed = time.Since(startTime)
+	c.conn.Log(logger.LevelDebug, "limits check completed for folder %#v, deleted files: %v, deleted size: %v bytes, "+
+		"archived files: %v, archived size: %v bytes", folderRetention.Path, result.DeletedFiles, result.DeletedSize,
+		result.ArchivedFiles, result.ArchivedSize)
+	return nil
+}
+
+func (c *RetentionCheck) removeEmptyDirs(folderRetention *FolderRetention, folderPath string) {
+	contents, err := c.conn.ListDir(folderPath)
+	if err != nil {
+		return
+	}
+	for _, info := range contents {
+		virtualPath := path.Join(folderPath, info.Name())
+		if !info.IsDir() || c.isArchiveDir(virtualPath) {
+			continue
+		}
+		dirRetention, err := c.getFolderRetention(virtualPath)
+		if err == nil && dirRetention.Path == folderRetention.Path {
+			c.removeEmptyDirs(folderRetention, virtualPath)
+		}
+	}
+	c.checkEmptyDirRemoval(folderPath)
+}
+
 func (c *RetentionCheck) checkEmptyDirRemoval(folderPath string) {
 	if folderPath != "/" && c.conn.User.HasPerm(dataprovider.PermDelete, path.Dir(folderPath)) {
 		files, err := c.conn.ListDir(folderPath)
@@ -326,24 +603,34 @@ func (c *RetentionCheck) checkEmptyDirRemoval(folderPath string) {
 }
 
 // Start starts the retention check
-func (c *RetentionCheck) Start() {
-	c.conn.Log(logger.LevelInfo, "retention check started")
+func (c *RetentionCheck) Start() error {
+	c.conn.Log(logger.LevelInfo, "retention check started, dry run? %v", c.DryRun)
 	defer RetentionChecks.remove(c.conn.User.Username)
 	defer c.conn.CloseFS() //nolint:errcheck
 
 	startTime := time.Now()
-	for _, folder := range c.Folders {
+	c.checkArchiveRootPaths()
+	for idx := range c.Folders {
+		folder := &c.Folders[idx]
 		if folder.Retention > 0 {
 			if err := c.cleanupFolder(folder.Path); err != nil {
 				c.conn.Log(logger.LevelWarn, "retention check failed, unable to cleanup folder %#v", folder.Path)
 				c.sendNotifications(time.Since(startTime), err)
-				return
+				return err
+			}
+		}
+		if folder.hasLimits() {
+			if err := c.applyLimits(folder); err != nil {
+				c.conn.Log(logger.LevelWarn, "retention check failed, unable to apply limits to folder %#v", folder.Path)
+				c.sendNotifications(time.Since(startTime), err)
+				return err
 			}
 		}
 	}
 
 	c.conn.Log(logger.LevelInfo, "retention check completed")
 	c.sendNotifications(time.Since(startTime), nil)
+	return nil
 }
 
 func (c *RetentionCheck) sendNotifications(elapsed time.Duration, err error) {
@@ -357,19 +644,31 @@ func (c *RetentionCheck) sendNotifications(elapsed time.Duration, err error) {
 	}
 }
 
-func (c *RetentionCheck) sendEmailNotification(elapsed time.Duration, errCheck error) error {
-	body := new(bytes.Buffer)
-	data := make(map[string]interface{})
-	data["Results"] = c.results
+func (c *RetentionCheck) getTotals() (int, int64, int, int64) {
 	totalDeletedFiles := 0
 	totalDeletedSize := int64(0)
+	totalArchivedFiles := 0
+	totalArchivedSize := int64(0)
 	for _, result := range c.results {
 		totalDeletedFiles += result.DeletedFiles
 		totalDeletedSize += result.DeletedSize
+		totalArchivedFiles += result.ArchivedFiles
+		totalArchivedSize += result.ArchivedSize
 	}
+	return totalDeletedFiles, totalDeletedSize, totalArchivedFiles, totalArchivedSize
+}
+
+func (c *RetentionCheck) sendEmailNotification(elapsed time.Duration, errCheck error) error {
+	body := new(bytes.Buffer)
+	data := make(map[string]interface{})
+	data["Results"] = c.results
+	totalDeletedFiles, totalDeletedSize, totalArchivedFiles, totalArchivedSize := c.getTotals()
 	data["HumanizeSize"] = util.ByteCountIEC
 	data["TotalFiles"] = totalDeletedFiles
 	data["TotalSize"] = totalDeletedSize
+	data["TotalArchivedFiles"] = totalArchivedFiles
+	data["TotalArchivedSize"] = totalArchivedSize
+	data["DryRun"] = c.DryRun
 	data["Elapsed"] = elapsed
 	data["Username"] = c.conn.User.Username
 	data["StartTime"] = util.GetTimeFromMsecSinceEpoch(c.StartTime)
@@ -395,12 +694,7 @@ func (c *RetentionCheck) sendEmailNotification(elapsed time.Duration, errCheck e
 
 func (c *RetentionCheck) sendHookNotification(elapsed time.Duration, errCheck error) error {
 	data := make(map[string]interface{})
-	totalDeletedFiles := 0
-	totalDeletedSize := int64(0)
-	for _, result := range c.results {
-		totalDeletedFiles += result.DeletedFiles
-		totalDeletedSize += result.DeletedSize
-	}
+	totalDeletedFiles, totalDeletedSize, totalArchivedFiles, totalArchivedSize := c.getTotals()
 	data["username"] = c.conn.User.Username
 	data["start_time"] = c.StartTime
 	data["elapsed"] = elapsed.Milliseconds()
@@ -411,6 +705,9 @@ func (c *RetentionCheck) sendHookNotification(elapsed time.Duration, errCheck er
 	}
 	data["total_deleted_files"] = totalDeletedFiles
 	data["total_deleted_size"] = totalDeletedSize
+	data["total_archived_files"] = totalArchivedFiles
+	data["total_archived_size"] = totalArchivedSize
+	data["dry_run"] = c.DryRun
 	data["details"] = c.results
 	jsonData, _ := json.Marshal(data)
 
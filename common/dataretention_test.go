@@ -55,6 +55,37 @@ func TestRetentionValidation(t *testing.T) {
 	require.Error(t, err)
 	assert.Contains(t, err.Error(), `duplicated folder path "/"`)
 
+	check.Folders = []FolderRetention{
+		{
+			Path:     "/",
+			MaxFiles: -1,
+		},
+	}
+	err = check.Validate()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid max files")
+	check.Folders[0].MaxFiles = 0
+	check.Folders[0].MaxSize = -1
+	err = check.Validate()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid max size")
+	check.Folders[0].MaxSize = 100
+	check.Folders[0].ArchivePath = "archive"
+	err = check.Validate()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid archive path")
+	check.Folders[0].ArchivePath = "/archive/.."
+	err = check.Validate()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "cannot be inside the archive path")
+	check.Folders[0].ArchivePath = "/archive/"
+	err = check.Validate()
+	assert.NoError(t, err)
+	assert.Equal(t, "/archive", check.Folders[0].ArchivePath)
+	assert.True(t, isPathInside("/", "/archive"))
+	assert.True(t, isPathInside("/archive", "/archive/sub"))
+	assert.False(t, isPathInside("/archive", "/archive1"))
+
 	check.Folders = []FolderRetention{
 		{
 			Path:      "/dir1",
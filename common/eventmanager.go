@@ -0,0 +1,388 @@
+package common
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/xid"
+
+	"github.com/drakkan/sftpgo/v2/dataprovider"
+	"github.com/drakkan/sftpgo/v2/httpclient"
+	"github.com/drakkan/sftpgo/v2/logger"
+	"github.com/drakkan/sftpgo/v2/smtp"
+	"github.com/drakkan/sftpgo/v2/util"
+)
+
+var (
+	// eventManager handles the event rules defined in the data provider
+	eventManager eventRulesContainer
+)
+
+func init() {
+	dataprovider.SetEventRulesCallbacks(eventManager.loadRules, eventManager.handleProviderEvent)
+}
+
+// eventRulesContainer stores the event rules loaded from the data provider
+type eventRulesContainer struct {
+	sync.RWMutex
+	FsEvents       []dataprovider.EventRule
+	ProviderEvents []dataprovider.EventRule
+}
+
+func (r *eventRulesContainer) loadRules() {
+	rules, err := dataprovider.DumpEventRules()
+	if err != nil {
+		logger.Warn(logSender, "", "unable to load event rules: %v", err)
+		return
+	}
+	var fsEvents, providerEvents []dataprovider.EventRule
+	for _, rule := range rules {
+		switch rule.Trigger {
+		case dataprovider.EventTriggerFsEvent:
+			fsEvents = append(fsEvents, rule)
+		case dataprovider.EventTriggerProviderEvent:
+			providerEvents = append(providerEvents, rule)
+		}
+	}
+
+	r.Lock()
+	defer r.Unlock()
+
+	r.FsEvents = fsEvents
+	r.ProviderEvents = providerEvents
+	logger.Debug(logSender, "", "event rules loaded, fs events: %v, provider events: %v", len(fsEvents),
+		len(providerEvents))
+}
+
+func (r *eventRulesContainer) hasFsRules() bool {
+	r.RLock()
+	defer r.RUnlock()
+
+	return len(r.FsEvents) > 0
+}
+
+func (r *eventRulesContainer) getMatchingFsRules(params *EventParams, groups []string) []dataprovider.EventRule {
+	r.RLock()
+	defer r.RUnlock()
+
+	var rules []dataprovider.EventRule
+	for idx := range r.FsEvents {
+		if r.FsEvents[idx].MatchesFsEvent(params.Event, params.Name, params.VirtualPath, params.Protocol, groups,
+			params.FileSize, params.Status) {
+			rules = append(rules, r.FsEvents[idx])
+		}
+	}
+	return rules
+}
+
+func (r *eventRulesContainer) getMatchingProviderRules(params *EventParams) []dataprovider.EventRule {
+	r.RLock()
+	defer r.RUnlock()
+
+	var rules []dataprovider.EventRule
+	for idx := range r.ProviderEvents {
+		if r.ProviderEvents[idx].MatchesProviderEvent(params.Event, params.ObjectType, params.ObjectName) {
+			rules = append(rules, r.ProviderEvents[idx])
+		}
+	}
+	return rules
+}
+
+// handleFsEvent executes the rules matching the specified filesystem event
+func (r *eventRulesContainer) handleFsEvent(user *dataprovider.User, params EventParams) {
+	if params.Protocol == ProtocolEventAction || !r.hasFsRules() {
+		// events generated by the actions themselves never trigger other rules
+		return
+	}
+	rules := r.getMatchingFsRules(&params, user.Groups)
+	if len(rules) == 0 {
+		return
+	}
+	go executeRulesActions(rules, params)
+}
+
+// handleProviderEvent executes the rules matching the specified provider event
+func (r *eventRulesContainer) handleProviderEvent(operation, executor, ip, objectType, objectName string) {
+	params := EventParams{
+		Name:       executor,
+		Event:      operation,
+		ObjectType: objectType,
+		ObjectName: objectName,
+		IP:         ip,
+		Timestamp:  time.Now().UnixNano(),
+	}
+	rules := r.getMatchingProviderRules(&params)
+	if len(rules) == 0 {
+		return
+	}
+	go executeRulesActions(rules, params)
+}
+
+// EventParams defines the supported event parameters
+type EventParams struct {
+	// Username for filesystem events, executor for provider events
+	Name              string
+	Event             string
+	Status            int
+	VirtualPath       string
+	FsPath            string
+	VirtualTargetPath string
+	FsTargetPath      string
+	ObjectName        string
+	ObjectType        string
+	FileSize          int64
+	Protocol          string
+	IP                string
+	Timestamp         int64
+}
+
+func (p *EventParams) getStringReplacements() []string {
+	return []string{
+		"{{Event}}", p.Event,
+		"{{Name}}", p.Name,
+		"{{VirtualPath}}", p.VirtualPath,
+		"{{VirtualDirPath}}", path.Dir(p.VirtualPath),
+		"{{FsPath}}", p.FsPath,
+		"{{VirtualTargetPath}}", p.VirtualTargetPath,
+		"{{FsTargetPath}}", p.FsTargetPath,
+		"{{ObjectName}}", p.ObjectName,
+		"{{ObjectType}}", p.ObjectType,
+		"{{FileSize}}", fmt.Sprintf("%v", p.FileSize),
+		"{{Protocol}}", p.Protocol,
+		"{{IP}}", p.IP,
+		"{{Status}}", fmt.Sprintf("%v", p.Status),
+		"{{Timestamp}}", fmt.Sprintf("%v", p.Timestamp),
+	}
+}
+
+func (p *EventParams) getReplacer() *strings.Replacer {
+	return strings.NewReplacer(p.getStringReplacements()...)
+}
+
+func executeRulesActions(rules []dataprovider.EventRule, params EventParams) {
+	replacer := params.getReplacer()
+	for idx := range rules {
+		rule := &rules[idx]
+		startTime := time.Now()
+		for actionIdx := range rule.Actions {
+			action := &rule.Actions[actionIdx]
+			if err := executeRuleAction(action, &params, replacer); err != nil {
+				// the next actions could depend on this one, for example
+				// a delete after a copy, so we stop here
+				logger.Warn(logSender, "", "unable to execute action %#v for rule %#v, event %#v: %v",
+					action.Type.GetTypeAsString(), rule.Name, params.Event, err)
+				break
+			}
+		}
+		logger.Debug(logSender, "", "actions for rule %#v and event %#v executed, elapsed: %v", rule.Name,
+			params.Event, time.Since(startTime))
+	}
+}
+
+func executeRuleAction(action *dataprovider.EventAction, params *EventParams, replacer *strings.Replacer) error {
+	switch action.Type {
+	case dataprovider.EventActionTypeHTTP:
+		return executeHTTPRuleAction(&action.HTTPConfig, replacer)
+	case dataprovider.EventActionTypeCommand:
+		return executeCommandRuleAction(&action.CmdConfig, replacer)
+	case dataprovider.EventActionTypeEmail:
+		return executeEmailRuleAction(&action.EmailConfig, replacer)
+	case dataprovider.EventActionTypeFsCopy, dataprovider.EventActionTypeFsRename, dataprovider.EventActionTypeFsDelete:
+		return executeFsRuleAction(action, params, replacer)
+	default:
+		return fmt.Errorf("unsupported action type: %v", action.Type)
+	}
+}
+
+func executeHTTPRuleAction(c *dataprovider.EventActionHTTPConfig, replacer *strings.Replacer) error {
+	var body io.Reader
+	if c.Body != "" && c.Method != http.MethodGet {
+		body = bytes.NewBufferString(replacer.Replace(c.Body))
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(c.Timeout)*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, c.Method, c.Endpoint, body)
+	if err != nil {
+		return err
+	}
+	for _, kv := range c.Headers {
+		req.Header.Set(kv.Key, replacer.Replace(kv.Value))
+	}
+	startTime := time.Now()
+	resp, err := httpclient.GetHTTPClient().Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	logger.Debug(logSender, "", "HTTP action executed, method %v, endpoint %#v, status code: %v, elapsed: %v",
+		c.Method, c.Endpoint, resp.StatusCode, time.Since(startTime))
+	if resp.StatusCode < http.StatusOK || resp.StatusCode > http.StatusNoContent {
+		return errUnexpectedHTTResponse
+	}
+	return nil
+}
+
+func executeCommandRuleAction(c *dataprovider.EventActionCommandConfig, replacer *strings.Replacer) error {
+	// the rules could be defined by an instance, sharing the same data provider, with different enabled commands
+	if !dataprovider.IsEventCommandEnabled(c.Cmd) {
+		return fmt.Errorf("command %#v is not enabled", c.Cmd)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(c.Timeout)*time.Second)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, c.Cmd)
+	cmd.Env = os.Environ()
+	for _, kv := range c.EnvVars {
+		cmd.Env = append(cmd.Env, fmt.Sprintf("%v=%v", kv.Key, replacer.Replace(kv.Value)))
+	}
+	startTime := time.Now()
+	err := cmd.Run()
+
+	logger.Debug(logSender, "", "command action %#v executed, elapsed: %v, error: %v", c.Cmd,
+		time.Since(startTime), err)
+	return err
+}
+
+func executeEmailRuleAction(c *dataprovider.EventActionEmailConfig, replacer *strings.Replacer) error {
+	if !smtp.IsEnabled() {
+		return errors.New("unable to send email, no SMTP server is configured")
+	}
+	subject := replacer.Replace(c.Subject)
+	body := replacer.Replace(c.Body)
+	startTime := time.Now()
+	for _, recipient := range c.Recipients {
+		if err := smtp.SendEmail(recipient, subject, body, smtp.EmailContentTypeTextPlain); err != nil {
+			return fmt.Errorf("unable to send email to %#v: %w", recipient, err)
+		}
+	}
+	logger.Debug(logSender, "", "email action executed, recipients: %v, elapsed: %v", c.GetRecipientsAsString(),
+		time.Since(startTime))
+	return nil
+}
+
+func getEventActionConnection(username string) (*BaseConnection, error) {
+	user, err := dataprovider.GetUserWithGroupSettings(username)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get user %#v: %w", username, err)
+	}
+	// the actions are defined by an administrator, the user permissions don't apply
+	user.Permissions = make(map[string][]string)
+	user.Permissions["/"] = []string{dataprovider.PermAny}
+	user.Filters.FilePatterns = nil
+	user.Filters.DeniedProtocols = nil
+	conn := NewBaseConnection(xid.New().String(), ProtocolEventAction, "", "", user)
+	return conn, nil
+}
+
+func getEventActionTarget(target, virtualPath string, replacer *strings.Replacer) string {
+	target = replacer.Replace(target)
+	if strings.HasSuffix(target, "/") {
+		target += path.Base(virtualPath)
+	}
+	return util.CleanPath(target)
+}
+
+func executeFsRuleAction(action *dataprovider.EventAction, params *EventParams, replacer *strings.Replacer) error {
+	conn, err := getEventActionConnection(params.Name)
+	if err != nil {
+		return err
+	}
+	defer conn.CloseFS() //nolint:errcheck
+
+	// for rename and copy events the file to process is at the target path
+	virtualPath := params.VirtualPath
+	if params.Event == operationRename || params.Event == operationCopy {
+		virtualPath = params.VirtualTargetPath
+	}
+
+	switch action.Type {
+	case dataprovider.EventActionTypeFsDelete:
+		fs, fsPath, err := conn.GetFsAndResolvedPath(virtualPath)
+		if err != nil {
+			return err
+		}
+		info, err := fs.Lstat(fsPath)
+		if err != nil {
+			return conn.GetFsError(fs, err)
+		}
+		if info.IsDir() {
+			return fmt.Errorf("unable to delete %#v, it is a directory", virtualPath)
+		}
+		return conn.RemoveFile(fs, fsPath, virtualPath, info)
+	case dataprovider.EventActionTypeFsRename:
+		target := getEventActionTarget(action.FsConfig.Target, virtualPath, replacer)
+		if err := createEventActionTargetDir(conn, target); err != nil {
+			return err
+		}
+		return conn.Rename(virtualPath, target)
+	default:
+		target := getEventActionTarget(action.FsConfig.Target, virtualPath, replacer)
+		if err := createEventActionTargetDir(conn, target); err != nil {
+			return err
+		}
+		return copyFileForEventAction(conn, virtualPath, target)
+	}
+}
+
+func createEventActionTargetDir(conn *BaseConnection, virtualTargetPath string) error {
+	fs, fsTargetPath, err := conn.GetFsAndResolvedPath(virtualTargetPath)
+	if err != nil {
+		return err
+	}
+	// MkdirAll creates the missing parent directories for the given file path
+	return fs.MkdirAll(fsTargetPath, conn.User.GetUID(), conn.User.GetGID())
+}
+
+func copyFileForEventAction(conn *BaseConnection, virtualSourcePath, virtualTargetPath string) error {
+	if virtualSourcePath == virtualTargetPath {
+		return fmt.Errorf("the copy source and target cannot be the same: %#v", virtualSourcePath)
+	}
+	fsSrc, fsSourcePath, err := conn.GetFsAndResolvedPath(virtualSourcePath)
+	if err != nil {
+		return err
+	}
+	fsDst, fsTargetPath, err := conn.GetFsAndResolvedPath(virtualTargetPath)
+	if err != nil {
+		return err
+	}
+	srcInfo, err := fsSrc.Stat(fsSourcePath)
+	if err != nil {
+		return conn.GetFsError(fsSrc, err)
+	}
+	if !srcInfo.Mode().IsRegular() {
+		return fmt.Errorf("unable to copy %#v, it is not a regular file", virtualSourcePath)
+	}
+	numFiles := 1
+	sizeDiff := srcInfo.Size()
+	if dstInfo, err := fsDst.Stat(fsTargetPath); err == nil {
+		if dstInfo.IsDir() {
+			return fmt.Errorf("unable to copy %#v, the target %#v is a directory", virtualSourcePath, virtualTargetPath)
+		}
+		numFiles = 0
+		sizeDiff -= dstInfo.Size()
+	}
+	quotaResult := conn.HasSpace(numFiles > 0, false, virtualTargetPath)
+	if !quotaResult.HasSpace || (quotaResult.AllowedSize > 0 && sizeDiff > quotaResult.AllowedSize) {
+		return conn.GetQuotaExceededError()
+	}
+
+	if err := conn.copyFileContents(fsSrc, fsDst, fsSourcePath, fsTargetPath); err != nil {
+		conn.Log(logger.LevelError, "unable to copy %#v to %#v: %v", virtualSourcePath, virtualTargetPath, err)
+		return conn.GetFsError(fsDst, err)
+	}
+	conn.updateQuotaAfterCopy(virtualTargetPath, numFiles, sizeDiff)
+	conn.Log(logger.LevelDebug, "file %#v copied to %#v", virtualSourcePath, virtualTargetPath)
+	return nil
+}
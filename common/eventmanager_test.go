@@ -0,0 +1,209 @@
+package common
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/drakkan/sftpgo/v2/dataprovider"
+)
+
+func TestEventRuleMatchFsEvents(t *testing.T) {
+	rule := dataprovider.EventRule{
+		Trigger: dataprovider.EventTriggerFsEvent,
+		Conditions: dataprovider.EventConditions{
+			FsEvents: []string{operationUpload, operationDownload},
+			Options: dataprovider.ConditionOptions{
+				Names: []dataprovider.ConditionPattern{
+					{
+						Pattern: "user*",
+					},
+					{
+						Pattern: "admin",
+					},
+				},
+				GroupNames: []dataprovider.ConditionPattern{
+					{
+						Pattern:      "guests",
+						InverseMatch: true,
+					},
+				},
+				FsPaths: []dataprovider.ConditionPattern{
+					{
+						Pattern: "/inbound/*.csv",
+					},
+				},
+				Protocols:   []string{ProtocolSFTP, ProtocolFTP},
+				MinFileSize: 10,
+				MaxFileSize: 100,
+				Statuses:    []int{1, 3},
+			},
+		},
+	}
+	assert.True(t, rule.MatchesFsEvent(operationUpload, "user1", "/inbound/file.csv", ProtocolSFTP, nil, 50, 1))
+	assert.True(t, rule.MatchesFsEvent(operationDownload, "admin", "/inbound/file.csv", ProtocolFTP,
+		[]string{"guests", "staff"}, 10, 3))
+	assert.False(t, rule.MatchesFsEvent(operationDelete, "user1", "/inbound/file.csv", ProtocolSFTP, nil, 50, 1))
+	assert.False(t, rule.MatchesFsEvent(operationUpload, "admin1", "/inbound/file.csv", ProtocolSFTP, nil, 50, 1))
+	assert.False(t, rule.MatchesFsEvent(operationUpload, "user1", "/inbound/file.csv", ProtocolSFTP,
+		[]string{"guests"}, 50, 1))
+	assert.False(t, rule.MatchesFsEvent(operationUpload, "user1", "/inbound/sub/file.csv", ProtocolSFTP, nil, 50, 1))
+	assert.False(t, rule.MatchesFsEvent(operationUpload, "user1", "/inbound/file.txt", ProtocolSFTP, nil, 50, 1))
+	assert.False(t, rule.MatchesFsEvent(operationUpload, "user1", "/inbound/file.csv", ProtocolWebDAV, nil, 50, 1))
+	assert.False(t, rule.MatchesFsEvent(operationUpload, "user1", "/inbound/file.csv", ProtocolSFTP, nil, 9, 1))
+	assert.False(t, rule.MatchesFsEvent(operationUpload, "user1", "/inbound/file.csv", ProtocolSFTP, nil, 101, 1))
+	assert.False(t, rule.MatchesFsEvent(operationUpload, "user1", "/inbound/file.csv", ProtocolSFTP, nil, 50, 2))
+	// a provider rule never matches filesystem events
+	rule.Trigger = dataprovider.EventTriggerProviderEvent
+	assert.False(t, rule.MatchesFsEvent(operationUpload, "user1", "/inbound/file.csv", ProtocolSFTP, nil, 50, 1))
+	// no options means any event of the configured types
+	rule.Trigger = dataprovider.EventTriggerFsEvent
+	rule.Conditions.Options = dataprovider.ConditionOptions{}
+	assert.True(t, rule.MatchesFsEvent(operationDownload, "any", "/file", ProtocolHTTP, nil, 0, 2))
+}
+
+func TestEventRuleMatchProviderEvents(t *testing.T) {
+	rule := dataprovider.EventRule{
+		Trigger: dataprovider.EventTriggerProviderEvent,
+		Conditions: dataprovider.EventConditions{
+			ProviderEvents: []string{"add", "delete"},
+			Options: dataprovider.ConditionOptions{
+				Names: []dataprovider.ConditionPattern{
+					{
+						Pattern:      "tmp_*",
+						InverseMatch: true,
+					},
+				},
+				ProviderObjects: []string{"user", "group"},
+			},
+		},
+	}
+	assert.True(t, rule.MatchesProviderEvent("add", "user", "user1"))
+	assert.True(t, rule.MatchesProviderEvent("delete", "group", "group1"))
+	assert.False(t, rule.MatchesProviderEvent("update", "user", "user1"))
+	assert.False(t, rule.MatchesProviderEvent("add", "admin", "admin1"))
+	assert.False(t, rule.MatchesProviderEvent("add", "user", "tmp_user"))
+	rule.Trigger = dataprovider.EventTriggerFsEvent
+	assert.False(t, rule.MatchesProviderEvent("add", "user", "user1"))
+}
+
+func TestEventRulesContainer(t *testing.T) {
+	container := eventRulesContainer{
+		FsEvents: []dataprovider.EventRule{
+			{
+				Name:    "rule1",
+				Trigger: dataprovider.EventTriggerFsEvent,
+				Conditions: dataprovider.EventConditions{
+					FsEvents: []string{operationUpload},
+				},
+			},
+			{
+				Name:    "rule2",
+				Trigger: dataprovider.EventTriggerFsEvent,
+				Conditions: dataprovider.EventConditions{
+					FsEvents: []string{operationUpload, operationRename},
+					Options: dataprovider.ConditionOptions{
+						Protocols: []string{ProtocolFTP},
+					},
+				},
+			},
+		},
+		ProviderEvents: []dataprovider.EventRule{
+			{
+				Name:    "rule3",
+				Trigger: dataprovider.EventTriggerProviderEvent,
+				Conditions: dataprovider.EventConditions{
+					ProviderEvents: []string{"update"},
+				},
+			},
+		},
+	}
+	assert.True(t, container.hasFsRules())
+	rules := container.getMatchingFsRules(&EventParams{Event: operationUpload, Protocol: ProtocolFTP}, nil)
+	assert.Len(t, rules, 2)
+	rules = container.getMatchingFsRules(&EventParams{Event: operationUpload, Protocol: ProtocolSFTP}, nil)
+	if assert.Len(t, rules, 1) {
+		assert.Equal(t, "rule1", rules[0].Name)
+	}
+	rules = container.getMatchingFsRules(&EventParams{Event: operationDelete, Protocol: ProtocolSFTP}, nil)
+	assert.Len(t, rules, 0)
+	rules = container.getMatchingProviderRules(&EventParams{Event: "update", ObjectType: "user"})
+	assert.Len(t, rules, 1)
+	rules = container.getMatchingProviderRules(&EventParams{Event: "add", ObjectType: "user"})
+	assert.Len(t, rules, 0)
+
+	container = eventRulesContainer{}
+	assert.False(t, container.hasFsRules())
+}
+
+func TestEventParamsReplacer(t *testing.T) {
+	params := EventParams{
+		Name:              "user",
+		Event:             operationRename,
+		Status:            1,
+		VirtualPath:       "/dir/file.txt",
+		FsPath:            "/home/user/dir/file.txt",
+		VirtualTargetPath: "/dir/file1.txt",
+		FsTargetPath:      "/home/user/dir/file1.txt",
+		FileSize:          123,
+		Protocol:          ProtocolSFTP,
+		IP:                "127.0.0.1",
+		Timestamp:         456,
+	}
+	replacer := params.getReplacer()
+	assert.Equal(t, "rename user /dir/file.txt /dir /home/user/dir/file.txt", replacer.Replace(
+		"{{Event}} {{Name}} {{VirtualPath}} {{VirtualDirPath}} {{FsPath}}"))
+	assert.Equal(t, "/dir/file1.txt /home/user/dir/file1.txt 123 SFTP 127.0.0.1 1 456", replacer.Replace(
+		"{{VirtualTargetPath}} {{FsTargetPath}} {{FileSize}} {{Protocol}} {{IP}} {{Status}} {{Timestamp}}"))
+	assert.Equal(t, "{{Unknown}}", replacer.Replace("{{Unknown}}"))
+
+	assert.Equal(t, "/archive/user/file.txt", getEventActionTarget("/archive/{{Name}}/", params.VirtualPath, replacer))
+	assert.Equal(t, "/archive/rename.txt", getEventActionTarget("/archive/{{Event}}.txt", params.VirtualPath, replacer))
+	assert.Equal(t, "/dir/a", getEventActionTarget("{{VirtualDirPath}}/../dir/a", params.VirtualPath, replacer))
+
+	params = EventParams{
+		Name:       "admin",
+		Event:      "add",
+		ObjectName: "user1",
+		ObjectType: "user",
+	}
+	replacer = params.getReplacer()
+	assert.Equal(t, "admin added user user1", replacer.Replace("{{Name}} {{Event}}ed {{ObjectType}} {{ObjectName}}"))
+}
+
+func TestEventRuleActionErrors(t *testing.T) {
+	params := EventParams{}
+	replacer := params.getReplacer()
+	err := executeRuleAction(&dataprovider.EventAction{Type: 100}, &params, replacer)
+	assert.Error(t, err)
+	err = executeHTTPRuleAction(&dataprovider.EventActionHTTPConfig{
+		Endpoint: "http://127.0.0.1:1/",
+		Method:   http.MethodPost,
+		Body:     "{{Event}}",
+		Timeout:  1,
+	}, replacer)
+	assert.Error(t, err)
+	err = executeHTTPRuleAction(&dataprovider.EventActionHTTPConfig{
+		Endpoint: "http://127.0.0.1:1/",
+		Method:   "invalid method",
+		Timeout:  1,
+	}, replacer)
+	assert.Error(t, err)
+	err = executeCommandRuleAction(&dataprovider.EventActionCommandConfig{
+		Cmd:     "/invalid/path",
+		Timeout: 1,
+	}, replacer)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "is not enabled")
+	}
+	err = executeEmailRuleAction(&dataprovider.EventActionEmailConfig{
+		Recipients: []string{"a@example.com"},
+		Subject:    "subject",
+		Body:       "body",
+	}, replacer)
+	assert.Error(t, err)
+	err = executeFsRuleAction(&dataprovider.EventAction{Type: dataprovider.EventActionTypeFsDelete},
+		&EventParams{Name: "missing user"}, replacer)
+	assert.Error(t, err)
+}
@@ -321,6 +321,71 @@ func TestSetStat(t *testing.T) {
 	assert.NoError(t, err)
 }
 
+func TestWebDAVPropertiesRenameRemove(t *testing.T) {
+	u := getTestUser()
+	user, _, err := httpdtest.AddUser(u, http.StatusCreated)
+	assert.NoError(t, err)
+	setProperties := func(virtualPath string) {
+		err := dataprovider.SetWebDAVProperties(&dataprovider.WebDAVProperties{
+			Username: user.Username,
+			Path:     virtualPath,
+			Properties: []dataprovider.WebDAVProperty{
+				{
+					Namespace: "urn:test:",
+					Name:      "color",
+					InnerXML:  "blue",
+				},
+			},
+		})
+		assert.NoError(t, err)
+	}
+	hasProperties := func(virtualPath string) bool {
+		props, err := dataprovider.GetWebDAVProperties(user.Username, virtualPath)
+		assert.NoError(t, err)
+		return len(props.Properties) > 0
+	}
+	conn, client, err := getSftpClient(user)
+	if assert.NoError(t, err) {
+		defer conn.Close()
+		defer client.Close()
+		// the dead properties follow the renames done using any protocol
+		err = writeSFTPFile(testFileName, 100, client)
+		assert.NoError(t, err)
+		setProperties("/" + testFileName)
+		err = client.Rename(testFileName, testFileName+"1")
+		assert.NoError(t, err)
+		assert.False(t, hasProperties("/"+testFileName))
+		assert.True(t, hasProperties("/"+testFileName+"1"))
+		testDir := "/adir"
+		err = client.Mkdir(testDir)
+		assert.NoError(t, err)
+		err = writeSFTPFile(path.Join(testDir, testFileName), 100, client)
+		assert.NoError(t, err)
+		setProperties(testDir)
+		setProperties(path.Join(testDir, testFileName))
+		err = client.Rename(testDir, testDir+"1")
+		assert.NoError(t, err)
+		assert.False(t, hasProperties(testDir))
+		assert.False(t, hasProperties(path.Join(testDir, testFileName)))
+		assert.True(t, hasProperties(testDir+"1"))
+		assert.True(t, hasProperties(path.Join(testDir+"1", testFileName)))
+		// and they are removed with the path
+		err = client.Remove(testFileName + "1")
+		assert.NoError(t, err)
+		assert.False(t, hasProperties("/"+testFileName+"1"))
+		err = client.Remove(path.Join(testDir+"1", testFileName))
+		assert.NoError(t, err)
+		assert.False(t, hasProperties(path.Join(testDir+"1", testFileName)))
+		err = client.RemoveDirectory(testDir + "1")
+		assert.NoError(t, err)
+		assert.False(t, hasProperties(testDir+"1"))
+	}
+	_, err = httpdtest.RemoveUser(user, http.StatusOK)
+	assert.NoError(t, err)
+	err = os.RemoveAll(user.GetHomeDir())
+	assert.NoError(t, err)
+}
+
 func TestPermissionErrors(t *testing.T) {
 	user, _, err := httpdtest.AddUser(getTestUser(), http.StatusCreated)
 	assert.NoError(t, err)
@@ -37,14 +37,18 @@ type BaseTransfer struct { //nolint:maligned
 	InitialSize     int64
 	isNewFile       bool
 	transferType    int
-	AbortTransfer   int32
+	transferQuota   dataprovider.TransferQuota
+	// true for a chunk of a resumable upload that does not complete the file
+	isIncompleteUpload bool
+	AbortTransfer      int32
 	sync.Mutex
 	ErrTransfer error
 }
 
 // NewBaseTransfer returns a new BaseTransfer and adds it to the given connection
 func NewBaseTransfer(file vfs.File, conn *BaseConnection, cancelFn func(), fsPath, effectiveFsPath, requestPath string,
-	transferType int, minWriteOffset, initialSize, maxWriteSize int64, isNewFile bool, fs vfs.Fs) *BaseTransfer {
+	transferType int, minWriteOffset, initialSize, maxWriteSize int64, isNewFile bool, fs vfs.Fs,
+	transferQuota dataprovider.TransferQuota) *BaseTransfer {
 	t := &BaseTransfer{
 		ID:              conn.GetTransferID(),
 		File:            file,
@@ -63,6 +67,7 @@ func NewBaseTransfer(file vfs.File, conn *BaseConnection, cancelFn func(), fsPat
 		MaxWriteSize:    maxWriteSize,
 		AbortTransfer:   0,
 		Fs:              fs,
+		transferQuota:   transferQuota,
 	}
 
 	conn.AddTransfer(t)
@@ -92,6 +97,11 @@ func (t *BaseTransfer) GetSize() int64 {
 	return atomic.LoadInt64(&t.BytesReceived)
 }
 
+// GetTransferQuota returns the data transfer quota limits
+func (t *BaseTransfer) GetTransferQuota() dataprovider.TransferQuota {
+	return t.transferQuota
+}
+
 // GetStartTime returns the start time
 func (t *BaseTransfer) GetStartTime() time.Time {
 	return t.start
@@ -115,6 +125,55 @@ func (t *BaseTransfer) GetFsPath() string {
 	return t.fsPath
 }
 
+// CheckRead returns an error if read is not allowed
+// because the data transfer quota is exceeded
+func (t *BaseTransfer) CheckRead() error {
+	if !t.transferQuota.HasLimits() {
+		return nil
+	}
+	bytesSent := atomic.LoadInt64(&t.BytesSent)
+	if t.transferQuota.TotalSize > 0 {
+		transferred := bytesSent + atomic.LoadInt64(&t.BytesReceived)
+		if transferred > t.transferQuota.AllowedTotalSize {
+			t.Connection.Log(logger.LevelInfo, "denying read due to transfer quota limit, transferred: %v, allowed: %v",
+				transferred, t.transferQuota.AllowedTotalSize)
+			return t.Connection.GetReadQuotaExceededError()
+		}
+	}
+	if t.transferQuota.DLSize > 0 && bytesSent > t.transferQuota.AllowedDLSize {
+		t.Connection.Log(logger.LevelInfo, "denying read due to download quota limit, downloaded: %v, allowed: %v",
+			bytesSent, t.transferQuota.AllowedDLSize)
+		return t.Connection.GetReadQuotaExceededError()
+	}
+	return nil
+}
+
+// CheckWrite returns an error if write is not allowed
+// because the max write size or the data transfer quota are exceeded
+func (t *BaseTransfer) CheckWrite() error {
+	bytesReceived := atomic.LoadInt64(&t.BytesReceived)
+	if t.MaxWriteSize > 0 && bytesReceived > t.MaxWriteSize {
+		return t.Connection.GetQuotaExceededError()
+	}
+	if !t.transferQuota.HasLimits() {
+		return nil
+	}
+	if t.transferQuota.TotalSize > 0 {
+		transferred := atomic.LoadInt64(&t.BytesSent) + bytesReceived
+		if transferred > t.transferQuota.AllowedTotalSize {
+			t.Connection.Log(logger.LevelInfo, "denying write due to transfer quota limit, transferred: %v, allowed: %v",
+				transferred, t.transferQuota.AllowedTotalSize)
+			return t.Connection.GetQuotaExceededError()
+		}
+	}
+	if t.transferQuota.ULSize > 0 && bytesReceived > t.transferQuota.AllowedULSize {
+		t.Connection.Log(logger.LevelInfo, "denying write due to upload quota limit, uploaded: %v, allowed: %v",
+			bytesReceived, t.transferQuota.AllowedULSize)
+		return t.Connection.GetQuotaExceededError()
+	}
+	return nil
+}
+
 // GetRealFsPath returns the real transfer filesystem path.
 // If atomic uploads are enabled this differ from fsPath
 func (t *BaseTransfer) GetRealFsPath(fsPath string) string {
@@ -127,6 +186,17 @@ func (t *BaseTransfer) GetRealFsPath(fsPath string) string {
 	return ""
 }
 
+// SetIncompleteUpload marks an upload as incomplete, for example a chunk of a
+// resumable upload. Closing an incomplete upload keeps the temporary file as is,
+// the quota update, the final rename and the upload action are deferred until
+// the transfer that completes the file is closed
+func (t *BaseTransfer) SetIncompleteUpload(incomplete bool) {
+	t.Lock()
+	defer t.Unlock()
+
+	t.isIncompleteUpload = incomplete
+}
+
 // SetCancelFn sets the cancel function for the transfer
 func (t *BaseTransfer) SetCancelFn(cancelFn func()) {
 	t.cancelFn = cancelFn
@@ -146,6 +216,7 @@ func (t *BaseTransfer) Truncate(fsPath string, size int64) (int64, error) {
 					sizeDiff := initialSize - size
 					t.MaxWriteSize += sizeDiff
 					metric.TransferCompleted(atomic.LoadInt64(&t.BytesSent), atomic.LoadInt64(&t.BytesReceived), t.transferType, t.ErrTransfer)
+					t.updateTransferQuota(atomic.LoadInt64(&t.BytesReceived), 0)
 					atomic.StoreInt64(&t.BytesReceived, 0)
 				}
 				t.Unlock()
@@ -190,7 +261,10 @@ func (t *BaseTransfer) getUploadFileSize() (int64, error) {
 	if err == nil {
 		fileSize = info.Size()
 	}
-	if vfs.IsCryptOsFs(t.Fs) && t.ErrTransfer != nil {
+	// partial encrypted files cannot be decrypted, interrupted uploads to cloud
+	// storage backends are instead never visible as objects, they can be resumed
+	isPartialEncrypted := vfs.IsCryptOsFs(t.Fs) || (vfs.IsEncryptedFs(t.Fs) && vfs.IsSFTPFs(t.Fs))
+	if isPartialEncrypted && t.ErrTransfer != nil {
 		errDelete := t.Fs.Remove(t.fsPath, false)
 		if errDelete != nil {
 			t.Connection.Log(logger.LevelWarn, "error removing partial crypto file %#v: %v", t.fsPath, errDelete)
@@ -213,6 +287,12 @@ func (t *BaseTransfer) Close() error {
 		numFiles = 1
 	}
 	metric.TransferCompleted(atomic.LoadInt64(&t.BytesSent), atomic.LoadInt64(&t.BytesReceived), t.transferType, t.ErrTransfer)
+	t.updateTransferQuota(atomic.LoadInt64(&t.BytesReceived), atomic.LoadInt64(&t.BytesSent))
+	if t.transferType == TransferUpload && t.isIncompleteUpload {
+		t.Connection.Log(logger.LevelDebug, "incomplete upload closed, bytes received: %v, temporary file: %#v, error: %v",
+			atomic.LoadInt64(&t.BytesReceived), t.effectiveFsPath, t.ErrTransfer)
+		return t.ErrTransfer
+	}
 	if t.File != nil && t.Connection.IsQuotaExceededError(t.ErrTransfer) {
 		// if quota is exceeded we try to remove the partial file for uploads to local filesystem
 		err = t.Fs.Remove(t.File.Name(), false)
@@ -267,7 +347,9 @@ func (t *BaseTransfer) Close() error {
 }
 
 func (t *BaseTransfer) updateQuota(numFiles int, fileSize int64) bool {
-	// S3 uploads are atomic, if there is an error nothing is uploaded
+	// uploads to cloud storage backends are atomic, if there is an error nothing
+	// is uploaded. The data stored for interrupted uploads are not included in
+	// the quota until the upload is resumed and completed
 	if t.File == nil && t.ErrTransfer != nil {
 		return false
 	}
@@ -288,6 +370,20 @@ func (t *BaseTransfer) updateQuota(numFiles int, fileSize int64) bool {
 	return false
 }
 
+// updateTransferQuota updates the used data transfer for the user and,
+// if the transfer is inside a virtual folder, for the folder
+func (t *BaseTransfer) updateTransferQuota(uploadSize, downloadSize int64) {
+	if uploadSize == 0 && downloadSize == 0 {
+		return
+	}
+	vfolder, err := t.Connection.User.GetVirtualFolderForPath(path.Dir(t.requestPath))
+	if err == nil {
+		dataprovider.UpdateVirtualFolderTransferQuota(&vfolder.BaseVirtualFolder, uploadSize, //nolint:errcheck
+			downloadSize, false)
+	}
+	dataprovider.UpdateUserTransferQuota(&t.Connection.User, uploadSize, downloadSize, false) //nolint:errcheck
+}
+
 // HandleThrottle manage bandwidth throttling
 func (t *BaseTransfer) HandleThrottle() {
 	var wantedBandwidth int64
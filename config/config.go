@@ -19,6 +19,7 @@ import (
 	"github.com/drakkan/sftpgo/v2/kms"
 	"github.com/drakkan/sftpgo/v2/logger"
 	"github.com/drakkan/sftpgo/v2/mfa"
+	"github.com/drakkan/sftpgo/v2/s3d"
 	"github.com/drakkan/sftpgo/v2/sdk/plugin"
 	"github.com/drakkan/sftpgo/v2/sftpd"
 	"github.com/drakkan/sftpgo/v2/smtp"
@@ -68,6 +69,13 @@ var (
 		Prefix:          "",
 		ProxyAllowed:    nil,
 	}
+	defaultS3DBinding = s3d.Binding{
+		Address:         "",
+		Port:            0,
+		EnableHTTPS:     false,
+		TLSCipherSuites: nil,
+		ProxyAllowed:    nil,
+	}
 	defaultHTTPDBinding = httpd.Binding{
 		Address:         "127.0.0.1",
 		Port:            8080,
@@ -84,7 +92,7 @@ var (
 		Period:                 1000,
 		Burst:                  1,
 		Type:                   2,
-		Protocols:              []string{common.ProtocolSSH, common.ProtocolFTP, common.ProtocolWebDAV, common.ProtocolHTTP},
+		Protocols:              []string{common.ProtocolSSH, common.ProtocolFTP, common.ProtocolWebDAV, common.ProtocolHTTP, common.ProtocolS3},
 		AllowList:              []string{},
 		GenerateDefenderEvents: false,
 		EntriesSoftLimit:       100,
@@ -102,6 +110,7 @@ type globalConfig struct {
 	SFTPD           sftpd.Configuration   `json:"sftpd" mapstructure:"sftpd"`
 	FTPD            ftpd.Configuration    `json:"ftpd" mapstructure:"ftpd"`
 	WebDAVD         webdavd.Configuration `json:"webdavd" mapstructure:"webdavd"`
+	S3D             s3d.Configuration     `json:"s3d" mapstructure:"s3d"`
 	ProviderConf    dataprovider.Config   `json:"data_provider" mapstructure:"data_provider"`
 	HTTPDConfig     httpd.Conf            `json:"httpd" mapstructure:"httpd"`
 	HTTPConfig      httpclient.Config     `json:"http" mapstructure:"http"`
@@ -214,6 +223,13 @@ func Init() {
 					MaxSize: 1000,
 				},
 			},
+			LockSystem: webdavd.LockSystemMemory,
+		},
+		S3D: s3d.Configuration{
+			Bindings:           []s3d.Binding{defaultS3DBinding},
+			CertificateFile:    "",
+			CertificateKeyFile: "",
+			Region:             "us-east-1",
 		},
 		ProviderConf: dataprovider.Config{
 			Driver:           "sqlite",
@@ -260,13 +276,29 @@ func Init() {
 					MinEntropy: 0,
 				},
 			},
-			PasswordCaching:           true,
-			UpdateMode:                0,
-			PreferDatabaseCredentials: false,
-			SkipNaturalKeysValidation: false,
-			DelayedQuotaUpdate:        0,
-			CreateDefaultAdmin:        false,
-			IsShared:                  0,
+			PasswordCaching:                true,
+			UpdateMode:                     0,
+			PreferDatabaseCredentials:      false,
+			SkipNaturalKeysValidation:      false,
+			DelayedQuotaUpdate:             0,
+			CreateDefaultAdmin:             false,
+			IsShared:                       0,
+			PasswordExpirationNotification: 0,
+			AccountLockout: dataprovider.AccountLockoutConfig{
+				Enabled:         false,
+				MaxFailures:     10,
+				ObservationTime: 15,
+				LockoutTime:     30,
+			},
+			EventStore: dataprovider.EventStoreConfig{
+				FsEvents:        []string{},
+				ProviderEvents:  []string{},
+				ProviderObjects: []string{},
+				Retention:       720,
+			},
+			EventManager: dataprovider.EventManagerConfig{
+				EnabledCommands: []string{},
+			},
 		},
 		HTTPDConfig: httpd.Conf{
 			Bindings:           []httpd.Binding{defaultHTTPDBinding},
@@ -373,6 +405,16 @@ func SetWebDAVDConfig(config webdavd.Configuration) {
 	globalConf.WebDAVD = config
 }
 
+// GetS3DConfig returns the configuration for the S3 compatible API server
+func GetS3DConfig() s3d.Configuration {
+	return globalConf.S3D
+}
+
+// SetS3DConfig sets the configuration for the S3 compatible API server
+func SetS3DConfig(config s3d.Configuration) {
+	globalConf.S3D = config
+}
+
 // GetHTTPDConfig returns the configuration for the HTTP server
 func GetHTTPDConfig() httpd.Conf {
 	return globalConf.HTTPDConfig
@@ -439,7 +481,7 @@ func GetSMTPConfig() smtp.Config {
 }
 
 // HasServicesToStart returns true if the config defines at least a service to start.
-// Supported services are SFTP, FTP and WebDAV
+// Supported services are SFTP, FTP, WebDAV and S3
 func HasServicesToStart() bool {
 	if globalConf.SFTPD.ShouldBind() {
 		return true
@@ -450,6 +492,9 @@ func HasServicesToStart() bool {
 	if globalConf.WebDAVD.ShouldBind() {
 		return true
 	}
+	if globalConf.S3D.ShouldBind() {
+		return true
+	}
 	return false
 }
 
@@ -567,6 +612,7 @@ func loadBindingsFromEnv() {
 		getSFTPDBindindFromEnv(idx)
 		getFTPDBindingFromEnv(idx)
 		getWebDAVDBindingFromEnv(idx)
+		getS3DBindingFromEnv(idx)
 		getHTTPDBindingFromEnv(idx)
 		getHTTPClientCertificatesFromEnv(idx)
 		getHTTPClientHeadersFromEnv(idx)
@@ -963,6 +1009,53 @@ func getWebDAVDBindingFromEnv(idx int) {
 	}
 }
 
+func getS3DBindingFromEnv(idx int) {
+	binding := s3d.Binding{}
+	if len(globalConf.S3D.Bindings) > idx {
+		binding = globalConf.S3D.Bindings[idx]
+	}
+
+	isSet := false
+
+	port, ok := lookupIntFromEnv(fmt.Sprintf("SFTPGO_S3D__BINDINGS__%v__PORT", idx))
+	if ok {
+		binding.Port = int(port)
+		isSet = true
+	}
+
+	address, ok := os.LookupEnv(fmt.Sprintf("SFTPGO_S3D__BINDINGS__%v__ADDRESS", idx))
+	if ok {
+		binding.Address = address
+		isSet = true
+	}
+
+	enableHTTPS, ok := lookupBoolFromEnv(fmt.Sprintf("SFTPGO_S3D__BINDINGS__%v__ENABLE_HTTPS", idx))
+	if ok {
+		binding.EnableHTTPS = enableHTTPS
+		isSet = true
+	}
+
+	tlsCiphers, ok := lookupStringListFromEnv(fmt.Sprintf("SFTPGO_S3D__BINDINGS__%v__TLS_CIPHER_SUITES", idx))
+	if ok {
+		binding.TLSCipherSuites = tlsCiphers
+		isSet = true
+	}
+
+	proxyAllowed, ok := lookupStringListFromEnv(fmt.Sprintf("SFTPGO_S3D__BINDINGS__%v__PROXY_ALLOWED", idx))
+	if ok {
+		binding.ProxyAllowed = proxyAllowed
+		isSet = true
+	}
+
+	if isSet {
+		if len(globalConf.S3D.Bindings) > idx {
+			globalConf.S3D.Bindings[idx] = binding
+		} else {
+			globalConf.S3D.Bindings = append(globalConf.S3D.Bindings, binding)
+		}
+	}
+}
+
 func getHTTPDBindingFromEnv(idx int) {
 	binding := httpd.Binding{
 		EnableWebAdmin:  true,
@@ -1154,6 +1247,10 @@ func setViperDefaults() {
 	viper.SetDefault("webdavd.cache.users.max_size", globalConf.WebDAVD.Cache.Users.MaxSize)
 	viper.SetDefault("webdavd.cache.mime_types.enabled", globalConf.WebDAVD.Cache.MimeTypes.Enabled)
 	viper.SetDefault("webdavd.cache.mime_types.max_size", globalConf.WebDAVD.Cache.MimeTypes.MaxSize)
+	viper.SetDefault("webdavd.lock_system", globalConf.WebDAVD.LockSystem)
+	viper.SetDefault("s3d.certificate_file", globalConf.S3D.CertificateFile)
+	viper.SetDefault("s3d.certificate_key_file", globalConf.S3D.CertificateKeyFile)
+	viper.SetDefault("s3d.region", globalConf.S3D.Region)
 	viper.SetDefault("data_provider.driver", globalConf.ProviderConf.Driver)
 	viper.SetDefault("data_provider.name", globalConf.ProviderConf.Name)
 	viper.SetDefault("data_provider.host", globalConf.ProviderConf.Host)
@@ -1191,6 +1288,16 @@ func setViperDefaults() {
 	viper.SetDefault("data_provider.delayed_quota_update", globalConf.ProviderConf.DelayedQuotaUpdate)
 	viper.SetDefault("data_provider.create_default_admin", globalConf.ProviderConf.CreateDefaultAdmin)
 	viper.SetDefault("data_provider.is_shared", globalConf.ProviderConf.IsShared)
+	viper.SetDefault("data_provider.password_expiration_notification", globalConf.ProviderConf.PasswordExpirationNotification)
+	viper.SetDefault("data_provider.account_lockout.enabled", globalConf.ProviderConf.AccountLockout.Enabled)
+	viper.SetDefault("data_provider.account_lockout.max_failures", globalConf.ProviderConf.AccountLockout.MaxFailures)
+	viper.SetDefault("data_provider.account_lockout.observation_time", globalConf.ProviderConf.AccountLockout.ObservationTime)
+	viper.SetDefault("data_provider.account_lockout.lockout_time", globalConf.ProviderConf.AccountLockout.LockoutTime)
+	viper.SetDefault("data_provider.event_store.fs_events", globalConf.ProviderConf.EventStore.FsEvents)
+	viper.SetDefault("data_provider.event_store.provider_events", globalConf.ProviderConf.EventStore.ProviderEvents)
+	viper.SetDefault("data_provider.event_store.provider_objects", globalConf.ProviderConf.EventStore.ProviderObjects)
+	viper.SetDefault("data_provider.event_store.retention", globalConf.ProviderConf.EventStore.Retention)
+	viper.SetDefault("data_provider.event_manager.enabled_commands", globalConf.ProviderConf.EventManager.EnabledCommands)
 	viper.SetDefault("httpd.templates_path", globalConf.HTTPDConfig.TemplatesPath)
 	viper.SetDefault("httpd.static_files_path", globalConf.HTTPDConfig.StaticFilesPath)
 	viper.SetDefault("httpd.backups_path", globalConf.HTTPDConfig.BackupsPath)
@@ -0,0 +1,169 @@
+package dataprovider
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/drakkan/sftpgo/v2/logger"
+	"github.com/drakkan/sftpgo/v2/util"
+)
+
+// Supported account types for the account lockout
+const (
+	AccountTypeUser  = "user"
+	AccountTypeAdmin = "admin"
+)
+
+// ErrAccountLocked defines the error returned if an account is locked after too many failed logins
+var ErrAccountLocked = errors.New("account locked after too many failed logins")
+
+// maximum attempts to record a failed login if concurrent updates are detected
+const accountLockoutMaxUpdateAttempts = 20
+
+// AccountLockout defines the failed logins tracking for a user or an admin
+type AccountLockout struct {
+	// Username of the user or admin
+	Username string `json:"username"`
+	// Account type, "user" or "admin"
+	Type string `json:"type"`
+	// Failed logins within the observation time
+	Failures int `json:"failures"`
+	// First failed login, within the observation time, as unix timestamp in milliseconds
+	FirstFailureAt int64 `json:"first_failure_at"`
+	// Last failed login as unix timestamp in milliseconds
+	LastFailureAt int64 `json:"last_failure_at"`
+	// Lock time as unix timestamp in milliseconds, 0 means not locked
+	LockedAt int64 `json:"locked_at"`
+	// Lock expiration as unix timestamp in milliseconds.
+	// 0 for a locked account means locked until unlocked by an administrator
+	LockedUntil int64 `json:"locked_until"`
+}
+
+// IsLocked returns true if the account is currently locked
+func (l *AccountLockout) IsLocked() bool {
+	if l.LockedAt == 0 {
+		return false
+	}
+	return l.LockedUntil == 0 || l.LockedUntil > util.GetTimeAsMsSinceEpoch(time.Now())
+}
+
+func (l *AccountLockout) getKey() string {
+	return getAccountLockoutKey(l.Username, l.Type)
+}
+
+func (l *AccountLockout) validate() error {
+	if l.Username == "" {
+		return util.NewValidationError("username is mandatory")
+	}
+	if !util.IsStringInSlice(l.Type, []string{AccountTypeUser, AccountTypeAdmin}) {
+		return util.NewValidationError(fmt.Sprintf("invalid account type: %#v", l.Type))
+	}
+	return nil
+}
+
+// addFailure records a failed login at the given time and locks the account
+// if the maximum allowed failures are reached within the observation time
+func (l *AccountLockout) addFailure(now time.Time) {
+	nowMillis := util.GetTimeAsMsSinceEpoch(now)
+	if l.LockedAt > 0 && !l.IsLocked() {
+		// the lock is expired, start counting again
+		l.Failures = 0
+		l.LockedAt = 0
+		l.LockedUntil = 0
+	}
+	windowStart := now.Add(-time.Duration(config.AccountLockout.ObservationTime) * time.Minute)
+	if l.Failures == 0 || l.FirstFailureAt < util.GetTimeAsMsSinceEpoch(windowStart) {
+		l.Failures = 0
+		l.FirstFailureAt = nowMillis
+	}
+	l.Failures++
+	l.LastFailureAt = nowMillis
+	if l.LockedAt == 0 && l.Failures >= config.AccountLockout.MaxFailures {
+		l.LockedAt = nowMillis
+		if config.AccountLockout.LockoutTime > 0 {
+			l.LockedUntil = util.GetTimeAsMsSinceEpoch(now.Add(time.Duration(config.AccountLockout.LockoutTime) * time.Minute))
+		}
+	}
+}
+
+func getAccountLockoutKey(username, accountType string) string {
+	return accountType + ":" + username
+}
+
+// getAccountLockoutForLogin returns the failed logins tracking for the given account,
+// if any, or ErrAccountLocked if the account is locked
+func getAccountLockoutForLogin(username, accountType string) (*AccountLockout, error) {
+	if !config.AccountLockout.Enabled {
+		return nil, nil
+	}
+	lockout, err := provider.accountLockoutExists(username, accountType)
+	if err != nil {
+		if _, ok := err.(*util.RecordNotFoundError); !ok {
+			providerLog(logger.LevelWarn, "unable to get account lockout for %v %#v: %v", accountType, username, err)
+		}
+		return nil, nil
+	}
+	if lockout.IsLocked() {
+		providerLog(logger.LevelDebug, "login denied for locked %v %#v", accountType, username)
+		return &lockout, ErrAccountLocked
+	}
+	return &lockout, nil
+}
+
+// updateAccountLockout updates the failed logins tracking for the given account
+// based on the login result
+func updateAccountLockout(username, accountType string, lockout *AccountLockout, loginErr error) {
+	if !config.AccountLockout.Enabled {
+		return
+	}
+	if loginErr == nil || errors.Is(loginErr, ErrPasswordChangeRequired) {
+		if lockout != nil {
+			if err := provider.deleteAccountLockout(username, accountType); err != nil {
+				providerLog(logger.LevelWarn, "unable to reset failed logins for %v %#v: %v", accountType, username, err)
+			}
+		}
+		return
+	}
+	if !errors.Is(loginErr, ErrInvalidCredentials) {
+		return
+	}
+	// the failure is recorded atomically within the provider, the lockout
+	// loaded before the login could be outdated if there are concurrent logins
+	updated, err := provider.addAccountLockoutFailure(username, accountType, time.Now())
+	if err != nil {
+		providerLog(logger.LevelWarn, "unable to save failed login for %v %#v: %v", accountType, username, err)
+		return
+	}
+	lockout = &updated
+	if lockout.IsLocked() {
+		providerLog(logger.LevelInfo, "%v %#v locked after %v failed logins, locked until: %v", accountType, username,
+			lockout.Failures, lockout.LockedUntil)
+		if accountType == AccountTypeUser {
+			RemoveCachedWebDAVUser(username)
+		}
+	}
+}
+
+// IsAccountLockoutEnabled returns true if the per-account lockout is enabled
+func IsAccountLockoutEnabled() bool {
+	return config.AccountLockout.Enabled
+}
+
+// GetAccountLockouts returns the tracked failed logins for users and admins
+func GetAccountLockouts(limit, offset int, order string) ([]AccountLockout, error) {
+	return provider.getAccountLockouts(limit, offset, order)
+}
+
+// GetAccountLockout returns the failed logins tracking for the specified account
+func GetAccountLockout(username, accountType string) (AccountLockout, error) {
+	return provider.accountLockoutExists(username, accountType)
+}
+
+// DeleteAccountLockout resets the failed logins for the specified account and unlocks it
+func DeleteAccountLockout(username, accountType string) error {
+	if _, err := provider.accountLockoutExists(username, accountType); err != nil {
+		return err
+	}
+	return provider.deleteAccountLockout(username, accountType)
+}
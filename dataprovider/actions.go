@@ -26,13 +26,22 @@ const (
 )
 
 const (
-	actionObjectUser   = "user"
-	actionObjectAdmin  = "admin"
-	actionObjectAPIKey = "api_key"
+	actionObjectUser      = "user"
+	actionObjectAdmin     = "admin"
+	actionObjectAPIKey    = "api_key"
+	actionObjectShare     = "share"
+	actionObjectGroup     = "group"
+	actionObjectEventRule = "event_rule"
+	actionObjectSchedule  = "schedule"
 )
 
 func executeAction(operation, executor, ip, objectType, objectName string, object plugin.Renderer) {
-	plugin.Handler.NotifyProviderEvent(time.Now().UnixNano(), operation, executor, objectType, objectName, ip, object)
+	timestamp := time.Now().UnixNano()
+	plugin.Handler.NotifyProviderEvent(timestamp, operation, executor, objectType, objectName, ip, object)
+	addProviderEvent(timestamp, operation, executor, objectType, objectName, ip)
+	if fnHandleRuleForProviderEvent != nil {
+		fnHandleRuleForProviderEvent(operation, executor, ip, objectType, objectName)
+	}
 	if config.Actions.Hook == "" {
 		return
 	}
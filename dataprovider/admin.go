@@ -10,6 +10,7 @@ import (
 	"os"
 	"regexp"
 	"strings"
+	"time"
 
 	"github.com/alexedwards/argon2id"
 	passwordvalidator "github.com/wagslane/go-password-validator"
@@ -40,6 +41,8 @@ const (
 	PermAdminViewDefender     = "view_defender"
 	PermAdminRetentionChecks  = "retention_checks"
 	PermAdminViewEvents       = "view_events"
+	PermAdminManageEventRules = "manage_event_rules"
+	PermAdminManageSchedules  = "manage_schedules"
 )
 
 var (
@@ -47,7 +50,8 @@ var (
 	validAdminPerms = []string{PermAdminAny, PermAdminAddUsers, PermAdminChangeUsers, PermAdminDeleteUsers,
 		PermAdminViewUsers, PermAdminViewConnections, PermAdminCloseConnections, PermAdminViewServerStatus,
 		PermAdminManageAdmins, PermAdminManageAPIKeys, PermAdminQuotaScans, PermAdminManageSystem,
-		PermAdminManageDefender, PermAdminViewDefender, PermAdminRetentionChecks, PermAdminViewEvents}
+		PermAdminManageDefender, PermAdminViewDefender, PermAdminRetentionChecks, PermAdminViewEvents,
+		PermAdminManageEventRules, PermAdminManageSchedules}
 )
 
 // TOTPConfig defines the time-based one time password configuration
@@ -96,6 +100,13 @@ type AdminFilters struct {
 	// Each code can only be used once, you should use these codes to login and disable or
 	// reset 2FA for your account
 	RecoveryCodes []sdk.RecoveryCode `json:"recovery_codes,omitempty"`
+	// If set the admin must change the password before using the web admin or the REST API.
+	// The flag is automatically cleared after a password change
+	RequirePasswordChange bool `json:"require_password_change,omitempty"`
+	// Password expiration as number of days, 0 means no expiration
+	PasswordExpiration int `json:"password_expiration,omitempty"`
+	// Restrict the admin to a subset of users, an empty scope means no restrictions
+	Scope AdminScope `json:"scope,omitempty"`
 }
 
 // Admin defines a SFTPGo admin
@@ -118,6 +129,8 @@ type Admin struct {
 	UpdatedAt int64 `json:"updated_at"`
 	// Last login as unix timestamp in milliseconds
 	LastLogin int64 `json:"last_login"`
+	// Last password change as unix timestamp in milliseconds
+	LastPasswordChange int64 `json:"last_password_change"`
 }
 
 // CountUnusedRecoveryCodes returns the number of unused recovery codes
@@ -151,6 +164,7 @@ func (a *Admin) checkPassword() error {
 			}
 			a.Password = pwd
 		}
+		a.LastPasswordChange = util.GetTimeAsMsSinceEpoch(time.Now())
 	}
 	return nil
 }
@@ -217,9 +231,15 @@ func (a *Admin) validate() error {
 	if err := a.validatePermissions(); err != nil {
 		return err
 	}
+	if err := a.Filters.Scope.validate(a.Permissions); err != nil {
+		return err
+	}
 	if a.Email != "" && !emailRegex.MatchString(a.Email) {
 		return util.NewValidationError(fmt.Sprintf("email %#v is not valid", a.Email))
 	}
+	if a.Filters.PasswordExpiration < 0 {
+		return util.NewValidationError("password expiration cannot be negative")
+	}
 	for _, IPMask := range a.Filters.AllowList {
 		_, _, err := net.ParseCIDR(IPMask)
 		if err != nil {
@@ -241,6 +261,26 @@ func (a *Admin) CheckPassword(password string) (bool, error) {
 	return argon2id.ComparePasswordAndHash(password, a.Password)
 }
 
+// GetPasswordExpirationTime returns the time when the current password expires.
+// The zero time is returned if the password never expires
+func (a *Admin) GetPasswordExpirationTime() time.Time {
+	if a.Filters.PasswordExpiration <= 0 {
+		return time.Time{}
+	}
+	lastChange := util.GetTimeFromMsecSinceEpoch(a.LastPasswordChange)
+	return lastChange.Add(time.Duration(a.Filters.PasswordExpiration) * 24 * time.Hour)
+}
+
+// MustChangePassword returns true if the admin must change the password
+// before using the web admin or the REST API
+func (a *Admin) MustChangePassword() bool {
+	if a.Filters.RequirePasswordChange {
+		return true
+	}
+	expiration := a.GetPasswordExpirationTime()
+	return !expiration.IsZero() && expiration.Before(time.Now())
+}
+
 // CanLoginFromIP returns true if login from the given IP is allowed
 func (a *Admin) CanLoginFromIP(ip string) bool {
 	if len(a.Filters.AllowList) == 0 {
@@ -275,6 +315,16 @@ func (a *Admin) CanLogin(ip string) error {
 }
 
 func (a *Admin) checkUserAndPass(password, ip string) error {
+	lockout, err := getAccountLockoutForLogin(a.Username, AccountTypeAdmin)
+	if err != nil {
+		return err
+	}
+	err = a.doCheckUserAndPass(password, ip)
+	updateAccountLockout(a.Username, AccountTypeAdmin, lockout, err)
+	return err
+}
+
+func (a *Admin) doCheckUserAndPass(password, ip string) error {
 	if err := a.CanLogin(ip); err != nil {
 		return err
 	}
@@ -354,6 +404,11 @@ func (a *Admin) GetAllowedIPAsString() string {
 	return strings.Join(a.Filters.AllowList, ",")
 }
 
+// GetScopeUsernamePrefixesAsString returns the scope username prefixes as comma separated string
+func (a *Admin) GetScopeUsernamePrefixesAsString() string {
+	return strings.Join(a.Filters.Scope.UsernamePrefixes, ",")
+}
+
 // GetValidPerms returns the allowed admin permissions
 func (a *Admin) GetValidPerms() []string {
 	return validAdminPerms
@@ -368,6 +423,9 @@ func (a *Admin) GetInfoString() string {
 	if len(a.Filters.AllowList) > 0 {
 		result += fmt.Sprintf("Allowed IP/Mask: %v. ", len(a.Filters.AllowList))
 	}
+	if a.HasScope() {
+		result += "Scoped. "
+	}
 	return result
 }
 
@@ -392,6 +450,9 @@ func (a *Admin) getACopy() Admin {
 	filters := AdminFilters{}
 	filters.AllowList = make([]string, len(a.Filters.AllowList))
 	filters.AllowAPIKeyAuth = a.Filters.AllowAPIKeyAuth
+	filters.RequirePasswordChange = a.Filters.RequirePasswordChange
+	filters.PasswordExpiration = a.Filters.PasswordExpiration
+	filters.Scope = a.Filters.Scope.getACopy()
 	filters.TOTPConfig.Enabled = a.Filters.TOTPConfig.Enabled
 	filters.TOTPConfig.ConfigName = a.Filters.TOTPConfig.ConfigName
 	filters.TOTPConfig.Secret = a.Filters.TOTPConfig.Secret.Clone()
@@ -408,18 +469,19 @@ func (a *Admin) getACopy() Admin {
 	}
 
 	return Admin{
-		ID:             a.ID,
-		Status:         a.Status,
-		Username:       a.Username,
-		Password:       a.Password,
-		Email:          a.Email,
-		Permissions:    permissions,
-		Filters:        filters,
-		AdditionalInfo: a.AdditionalInfo,
-		Description:    a.Description,
-		LastLogin:      a.LastLogin,
-		CreatedAt:      a.CreatedAt,
-		UpdatedAt:      a.UpdatedAt,
+		ID:                 a.ID,
+		Status:             a.Status,
+		Username:           a.Username,
+		Password:           a.Password,
+		Email:              a.Email,
+		Permissions:        permissions,
+		Filters:            filters,
+		AdditionalInfo:     a.AdditionalInfo,
+		Description:        a.Description,
+		LastLogin:          a.LastLogin,
+		LastPasswordChange: a.LastPasswordChange,
+		CreatedAt:          a.CreatedAt,
+		UpdatedAt:          a.UpdatedAt,
 	}
 }
 
@@ -0,0 +1,408 @@
+package dataprovider
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/drakkan/sftpgo/v2/util"
+	"github.com/drakkan/sftpgo/v2/vfs"
+)
+
+// permissions not allowed for admins restricted to a scope, they would allow
+// to escape from the scope
+var scopeForbiddenPerms = []string{PermAdminAny, PermAdminManageAdmins, PermAdminManageAPIKeys,
+	PermAdminViewEvents, PermAdminManageEventRules, PermAdminManageSchedules}
+
+// AdminScope defines the subset of users an admin can view and manage.
+// A user is within the scope if it matches at least one of the defined criteria.
+// An empty scope means no restrictions
+type AdminScope struct {
+	// Users that are members of at least one of these groups
+	Groups []string `json:"groups,omitempty"`
+	// Users whose username starts with one of these prefixes
+	UsernamePrefixes []string `json:"username_prefixes,omitempty"`
+	// Users created by this admin
+	CreatedUsers bool `json:"created_users,omitempty"`
+}
+
+// IsEmpty returns true if no restrictions are defined
+func (s *AdminScope) IsEmpty() bool {
+	return len(s.Groups) == 0 && len(s.UsernamePrefixes) == 0 && !s.CreatedUsers
+}
+
+func (s *AdminScope) validate(permissions []string) error {
+	var groups []string
+	for _, name := range s.Groups {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if _, err := provider.groupExists(name); err != nil {
+			return util.NewValidationError(fmt.Sprintf("scope: unable to get group %#v: %v", name, err))
+		}
+		groups = append(groups, name)
+	}
+	s.Groups = util.RemoveDuplicates(groups)
+	var prefixes []string
+	for _, prefix := range s.UsernamePrefixes {
+		prefix = strings.TrimSpace(prefix)
+		if prefix != "" {
+			prefixes = append(prefixes, prefix)
+		}
+	}
+	s.UsernamePrefixes = util.RemoveDuplicates(prefixes)
+	if s.IsEmpty() {
+		return nil
+	}
+	for _, perm := range permissions {
+		if util.IsStringInSlice(perm, scopeForbiddenPerms) {
+			return util.NewValidationError(fmt.Sprintf("the permission %#v is not allowed for admins with a scope", perm))
+		}
+	}
+	return nil
+}
+
+func (s *AdminScope) getACopy() AdminScope {
+	groups := make([]string, len(s.Groups))
+	copy(groups, s.Groups)
+	prefixes := make([]string, len(s.UsernamePrefixes))
+	copy(prefixes, s.UsernamePrefixes)
+
+	return AdminScope{
+		Groups:           groups,
+		UsernamePrefixes: prefixes,
+		CreatedUsers:     s.CreatedUsers,
+	}
+}
+
+// HasScope returns true if the admin is restricted to a subset of users
+func (a *Admin) HasScope() bool {
+	return !a.Filters.Scope.IsEmpty()
+}
+
+// IsUserInScope returns true if the admin can view and manage the specified user
+func (a *Admin) IsUserInScope(user *User) bool {
+	if !a.HasScope() {
+		return true
+	}
+	if a.Filters.Scope.CreatedUsers && user.Filters.CreatedBy == a.Username {
+		return true
+	}
+	for _, prefix := range a.Filters.Scope.UsernamePrefixes {
+		if strings.HasPrefix(user.Username, prefix) {
+			return true
+		}
+	}
+	for _, name := range user.Groups {
+		if a.IsGroupInScope(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsGroupInScope returns true if the admin can view and manage the specified group
+func (a *Admin) IsGroupInScope(name string) bool {
+	if !a.HasScope() {
+		return true
+	}
+	return util.IsStringInSlice(name, a.Filters.Scope.Groups)
+}
+
+// IsFolderInScope returns true if the admin can view and manage the specified folder.
+// A folder is within the scope if it is used by at least one user or group and all
+// the users and groups using it are within the scope
+func (a *Admin) IsFolderInScope(folder *vfs.BaseVirtualFolder) bool {
+	return a.isFolderInScope(folder, "", "")
+}
+
+// isFolderInScope checks the folder scope ignoring the specified user and group,
+// this way we can check if the folder can be associated to an existing user/group
+func (a *Admin) isFolderInScope(folder *vfs.BaseVirtualFolder, ignoredUser, ignoredGroup string) bool {
+	if !a.HasScope() {
+		return true
+	}
+	var numUsers, numGroups int
+	for _, name := range folder.Groups {
+		if name == ignoredGroup {
+			continue
+		}
+		numGroups++
+		if !a.IsGroupInScope(name) {
+			return false
+		}
+	}
+	for _, username := range folder.Users {
+		if username == ignoredUser {
+			continue
+		}
+		numUsers++
+		user, err := provider.userExists(username)
+		if err != nil || !a.IsUserInScope(&user) {
+			return false
+		}
+	}
+	if ignoredUser != "" || ignoredGroup != "" {
+		// folders used only by the checked user/group, or not used at all, can be associated
+		return true
+	}
+	return numUsers > 0 || numGroups > 0
+}
+
+// CheckUserScope returns an error if the specified user, or the groups and
+// virtual folders associated to it, are not within the admin scope
+func (a *Admin) CheckUserScope(user *User) error {
+	if !a.HasScope() {
+		return nil
+	}
+	if !a.IsUserInScope(user) {
+		return util.NewValidationError(fmt.Sprintf("user %#v is not within your scope", user.Username))
+	}
+	for _, name := range user.Groups {
+		if !a.IsGroupInScope(name) {
+			return util.NewValidationError(fmt.Sprintf("group %#v is not within your scope", name))
+		}
+	}
+	for idx := range user.VirtualFolders {
+		if err := a.checkFolderAssociation(user.VirtualFolders[idx].Name, user.Username, ""); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CheckGroupScope returns an error if the specified group, or the virtual
+// folders associated to it, are not within the admin scope
+func (a *Admin) CheckGroupScope(group *Group) error {
+	if !a.HasScope() {
+		return nil
+	}
+	if !a.IsGroupInScope(group.Name) {
+		return util.NewValidationError(fmt.Sprintf("group %#v is not within your scope", group.Name))
+	}
+	for idx := range group.VirtualFolders {
+		if err := a.checkFolderAssociation(group.VirtualFolders[idx].Name, "", group.Name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (a *Admin) checkFolderAssociation(name, username, groupName string) error {
+	folder, err := provider.getFolderByName(name)
+	if err != nil {
+		if _, ok := err.(*util.RecordNotFoundError); ok {
+			// the folder will be created
+			return nil
+		}
+		return err
+	}
+	if !a.isFolderInScope(&folder, username, groupName) {
+		return util.NewValidationError(fmt.Sprintf("virtual folder %#v is not within your scope", name))
+	}
+	return nil
+}
+
+// GetUsersForAdmin returns the users within the scope of the specified admin
+// respecting limit and offset
+func GetUsersForAdmin(admin *Admin, limit, offset int, order string) ([]User, error) {
+	if !admin.HasScope() {
+		return GetUsers(limit, offset, order)
+	}
+	if limit <= 0 {
+		return []User{}, nil
+	}
+	users, err := provider.getUsersInScope(admin, limit, offset, order)
+	if err != nil {
+		return users, err
+	}
+	for idx := range users {
+		users[idx].PrepareForRendering()
+	}
+	return users, nil
+}
+
+// GetFoldersForAdmin returns the virtual folders within the scope of the specified admin
+// respecting limit and offset
+func GetFoldersForAdmin(admin *Admin, limit, offset int, order string) ([]vfs.BaseVirtualFolder, error) {
+	if !admin.HasScope() {
+		return GetFolders(limit, offset, order)
+	}
+	folders, err := getFoldersInScope(admin)
+	if err != nil {
+		return folders, err
+	}
+	sort.Slice(folders, func(i, j int) bool {
+		if order == OrderDESC {
+			return folders[i].Name > folders[j].Name
+		}
+		return folders[i].Name < folders[j].Name
+	})
+	start, end := getPaginationBounds(len(folders), limit, offset)
+
+	return folders[start:end], nil
+}
+
+// GetGroupsForAdmin returns the groups within the scope of the specified admin
+// respecting limit and offset
+func GetGroupsForAdmin(admin *Admin, limit, offset int, order string) ([]Group, error) {
+	if !admin.HasScope() {
+		return GetGroups(limit, offset, order)
+	}
+	groups, err := provider.getGroupsWithNames(admin.Filters.Scope.Groups)
+	if err != nil {
+		return groups, err
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		if order == OrderDESC {
+			return groups[i].Name > groups[j].Name
+		}
+		return groups[i].Name < groups[j].Name
+	})
+	start, end := getPaginationBounds(len(groups), limit, offset)
+	groups = groups[start:end]
+	for idx := range groups {
+		// getGroupsWithNames does not return the associated users
+		group, err := provider.groupExists(groups[idx].Name)
+		if err != nil {
+			return groups, err
+		}
+		groups[idx] = group
+	}
+
+	return groups, nil
+}
+
+// DumpDataForAdmin returns the users, folders, groups, shares and S3 access keys within the scope
+// of the specified admin. Admins, API keys and event rules are never included for scoped admins
+func DumpDataForAdmin(admin *Admin) (BackupData, error) {
+	if !admin.HasScope() {
+		return DumpData()
+	}
+	var data BackupData
+	users, err := getUsersInScope(admin)
+	if err != nil {
+		return data, err
+	}
+	folders, err := getFoldersInScope(admin)
+	if err != nil {
+		return data, err
+	}
+	groups, err := provider.dumpGroups()
+	if err != nil {
+		return data, err
+	}
+	shares, err := provider.dumpShares()
+	if err != nil {
+		return data, err
+	}
+	s3AccessKeys, err := provider.dumpS3AccessKeys()
+	if err != nil {
+		return data, err
+	}
+	usernames := make(map[string]bool)
+	for _, user := range users {
+		usernames[user.Username] = true
+	}
+	data.Users = users
+	data.Folders = folders
+	data.Groups = make([]Group, 0, len(admin.Filters.Scope.Groups))
+	for _, group := range groups {
+		if admin.IsGroupInScope(group.Name) {
+			data.Groups = append(data.Groups, group)
+		}
+	}
+	data.Shares = make([]Share, 0, len(shares))
+	for _, share := range shares {
+		if usernames[share.Username] {
+			data.Shares = append(data.Shares, share)
+		}
+	}
+	data.S3AccessKeys = make([]S3AccessKey, 0, len(s3AccessKeys))
+	for _, key := range s3AccessKeys {
+		if usernames[key.Username] {
+			data.S3AccessKeys = append(data.S3AccessKeys, key)
+		}
+	}
+	data.Admins = []Admin{}
+	data.APIKeys = []APIKey{}
+	data.EventRules = []EventRule{}
+	data.Schedules = []Schedule{}
+	data.Version = DumpVersion
+	return data, nil
+}
+
+// getCreatedByFilter returns the JSON fragment stored within the filters
+// of the users created by the specified admin
+func getCreatedByFilter(username string) string {
+	value, _ := json.Marshal(username)
+	return `"created_by":` + string(value)
+}
+
+func getUsersInScope(admin *Admin) ([]User, error) {
+	users, err := provider.getUsersInScope(admin, 0, 0, OrderASC)
+	if err != nil {
+		return nil, err
+	}
+	for idx := range users {
+		if err := addCredentialsToUser(&users[idx]); err != nil {
+			return nil, err
+		}
+	}
+	return users, nil
+}
+
+// getFoldersInScope returns the virtual folders within the admin scope. Only the
+// folders associated to the users and groups within the scope are checked
+func getFoldersInScope(admin *Admin) ([]vfs.BaseVirtualFolder, error) {
+	users, err := provider.getUsersInScope(admin, 0, 0, OrderASC)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for idx := range users {
+		for _, folder := range users[idx].VirtualFolders {
+			names = append(names, folder.Name)
+		}
+	}
+	for _, groupName := range admin.Filters.Scope.Groups {
+		group, err := provider.groupExists(groupName)
+		if err != nil {
+			if _, ok := err.(*util.RecordNotFoundError); ok {
+				continue
+			}
+			return nil, err
+		}
+		for _, folder := range group.VirtualFolders {
+			names = append(names, folder.Name)
+		}
+	}
+	names = util.RemoveDuplicates(names)
+	result := make([]vfs.BaseVirtualFolder, 0, len(names))
+	for _, name := range names {
+		folder, err := provider.getFolderByName(name)
+		if err != nil {
+			if _, ok := err.(*util.RecordNotFoundError); ok {
+				continue
+			}
+			return nil, err
+		}
+		if admin.IsFolderInScope(&folder) {
+			result = append(result, folder)
+		}
+	}
+	return result, nil
+}
+
+func getPaginationBounds(length, limit, offset int) (int, int) {
+	if offset >= length {
+		return length, length
+	}
+	end := offset + limit
+	if limit <= 0 || end > length {
+		end = length
+	}
+	return offset, end
+}
@@ -4,11 +4,13 @@
 package dataprovider
 
 import (
+	"bytes"
 	"crypto/x509"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"path/filepath"
+	"sort"
 	"time"
 
 	bolt "go.etcd.io/bbolt"
@@ -20,16 +22,30 @@ import (
 )
 
 const (
-	boltDatabaseVersion = 13
+	boltDatabaseVersion = 18
 )
 
 var (
-	usersBucket     = []byte("users")
-	foldersBucket   = []byte("folders")
-	adminsBucket    = []byte("admins")
-	apiKeysBucket   = []byte("api_keys")
-	dbVersionBucket = []byte("db_version")
-	dbVersionKey    = []byte("version")
+	usersBucket            = []byte("users")
+	foldersBucket          = []byte("folders")
+	groupsBucket           = []byte("groups")
+	adminsBucket           = []byte("admins")
+	apiKeysBucket          = []byte("api_keys")
+	sharesBucket           = []byte("shares")
+	shareUploadsBucket     = []byte("share_uploads")
+	lockoutsBucket         = []byte("account_lockouts")
+	fsEventsBucket         = []byte("fs_events")
+	providerEventsBucket   = []byte("provider_events")
+	eventRulesBucket       = []byte("events_rules")
+	schedulesBucket        = []byte("schedules")
+	webDAVLocksBucket      = []byte("webdav_locks")
+	webDAVPropsBucket      = []byte("webdav_properties")
+	s3AccessKeysBucket     = []byte("s3_access_keys")
+	tusUploadsBucket       = []byte("tus_uploads")
+	resetCodesBucket       = []byte("reset_codes")
+	multipartUploadsBucket = []byte("multipart_uploads")
+	dbVersionBucket        = []byte("db_version")
+	dbVersionKey           = []byte("version")
 )
 
 // BoltProvider auth provider for bolt key/value store
@@ -77,6 +93,14 @@ func initializeBoltProvider(basePath string) error {
 			providerLog(logger.LevelWarn, "error creating folders bucket: %v", err)
 			return err
 		}
+		err = dbHandle.Update(func(tx *bolt.Tx) error {
+			_, e := tx.CreateBucketIfNotExists(groupsBucket)
+			return e
+		})
+		if err != nil {
+			providerLog(logger.LevelWarn, "error creating groups bucket: %v", err)
+			return err
+		}
 		err = dbHandle.Update(func(tx *bolt.Tx) error {
 			_, e := tx.CreateBucketIfNotExists(adminsBucket)
 			return e
@@ -93,6 +117,110 @@ func initializeBoltProvider(basePath string) error {
 			providerLog(logger.LevelWarn, "error creating api keys bucket: %v", err)
 			return err
 		}
+		err = dbHandle.Update(func(tx *bolt.Tx) error {
+			_, e := tx.CreateBucketIfNotExists(sharesBucket)
+			return e
+		})
+		if err != nil {
+			providerLog(logger.LevelWarn, "error creating shares bucket: %v", err)
+			return err
+		}
+		err = dbHandle.Update(func(tx *bolt.Tx) error {
+			_, e := tx.CreateBucketIfNotExists(shareUploadsBucket)
+			return e
+		})
+		if err != nil {
+			providerLog(logger.LevelWarn, "error creating share uploads bucket: %v", err)
+			return err
+		}
+		err = dbHandle.Update(func(tx *bolt.Tx) error {
+			_, e := tx.CreateBucketIfNotExists(lockoutsBucket)
+			return e
+		})
+		if err != nil {
+			providerLog(logger.LevelWarn, "error creating account lockouts bucket: %v", err)
+			return err
+		}
+		err = dbHandle.Update(func(tx *bolt.Tx) error {
+			_, e := tx.CreateBucketIfNotExists(fsEventsBucket)
+			return e
+		})
+		if err != nil {
+			providerLog(logger.LevelWarn, "error creating fs events bucket: %v", err)
+			return err
+		}
+		err = dbHandle.Update(func(tx *bolt.Tx) error {
+			_, e := tx.CreateBucketIfNotExists(providerEventsBucket)
+			return e
+		})
+		if err != nil {
+			providerLog(logger.LevelWarn, "error creating provider events bucket: %v", err)
+			return err
+		}
+		err = dbHandle.Update(func(tx *bolt.Tx) error {
+			_, e := tx.CreateBucketIfNotExists(eventRulesBucket)
+			return e
+		})
+		if err != nil {
+			providerLog(logger.LevelWarn, "error creating event rules bucket: %v", err)
+			return err
+		}
+		err = dbHandle.Update(func(tx *bolt.Tx) error {
+			_, e := tx.CreateBucketIfNotExists(schedulesBucket)
+			return e
+		})
+		if err != nil {
+			providerLog(logger.LevelWarn, "error creating schedules bucket: %v", err)
+			return err
+		}
+		err = dbHandle.Update(func(tx *bolt.Tx) error {
+			_, e := tx.CreateBucketIfNotExists(webDAVLocksBucket)
+			return e
+		})
+		if err != nil {
+			providerLog(logger.LevelWarn, "error creating WebDAV locks bucket: %v", err)
+			return err
+		}
+		err = dbHandle.Update(func(tx *bolt.Tx) error {
+			_, e := tx.CreateBucketIfNotExists(webDAVPropsBucket)
+			return e
+		})
+		if err != nil {
+			providerLog(logger.LevelWarn, "error creating WebDAV properties bucket: %v", err)
+			return err
+		}
+		err = dbHandle.Update(func(tx *bolt.Tx) error {
+			_, e := tx.CreateBucketIfNotExists(s3AccessKeysBucket)
+			return e
+		})
+		if err != nil {
+			providerLog(logger.LevelWarn, "error creating S3 access keys bucket: %v", err)
+			return err
+		}
+		err = dbHandle.Update(func(tx *bolt.Tx) error {
+			_, e := tx.CreateBucketIfNotExists(tusUploadsBucket)
+			return e
+		})
+		if err != nil {
+			providerLog(logger.LevelWarn, "error creating tus uploads bucket: %v", err)
+			return err
+		}
+		err = dbHandle.Update(func(tx *bolt.Tx) error {
+			_, e := tx.CreateBucketIfNotExists(resetCodesBucket)
+			return e
+		})
+		if err != nil {
+			providerLog(logger.LevelWarn, "error creating reset codes bucket: %v", err)
+			return err
+		}
+		err = dbHandle.Update(func(tx *bolt.Tx) error {
+			_, e := tx.CreateBucketIfNotExists(multipartUploadsBucket)
+			return e
+		})
+		if err != nil {
+			providerLog(logger.LevelWarn, "error creating multipart uploads bucket: %v", err)
+			return err
+		}
 		err = dbHandle.Update(func(tx *bolt.Tx) error {
 			_, e := tx.CreateBucketIfNotExists(dbVersionBucket)
 			return e
@@ -325,6 +453,46 @@ func (p *BoltProvider) getUsedQuota(username string) (int, int64, error) {
 	return user.UsedQuotaFiles, user.UsedQuotaSize, err
 }
 
+func (p *BoltProvider) updateTransferQuota(username string, uploadSize, downloadSize int64, reset bool,
+	periodStart int64) error {
+	return p.dbHandle.Update(func(tx *bolt.Tx) error {
+		bucket, err := getUsersBucket(tx)
+		if err != nil {
+			return err
+		}
+		var u []byte
+		if u = bucket.Get([]byte(username)); u == nil {
+			return util.NewRecordNotFoundError(fmt.Sprintf("username %#v does not exist, unable to update transfer quota",
+				username))
+		}
+		var user User
+		err = json.Unmarshal(u, &user)
+		if err != nil {
+			return err
+		}
+		user.UsedUploadDataTransfer, user.UsedDownloadDataTransfer, user.LastDataTransferReset = getUpdatedTransferQuota(
+			user.UsedUploadDataTransfer, user.UsedDownloadDataTransfer, user.LastDataTransferReset, uploadSize,
+			downloadSize, reset, periodStart)
+		buf, err := json.Marshal(user)
+		if err != nil {
+			return err
+		}
+		err = bucket.Put([]byte(username), buf)
+		providerLog(logger.LevelDebug, "transfer quota updated for user %#v, ul increment: %v dl increment: %v is reset? %v",
+			username, uploadSize, downloadSize, reset)
+		return err
+	})
+}
+
+func (p *BoltProvider) getUsedTransferQuota(username string) (int64, int64, int64, error) {
+	user, err := p.userExists(username)
+	if err != nil {
+		providerLog(logger.LevelWarn, "unable to get transfer quota for user %v error: %v", username, err)
+		return 0, 0, 0, err
+	}
+	return user.UsedUploadDataTransfer, user.UsedDownloadDataTransfer, user.LastDataTransferReset, err
+}
+
 func (p *BoltProvider) adminExists(username string) (Admin, error) {
 	var admin Admin
 
@@ -396,6 +564,11 @@ func (p *BoltProvider) updateAdmin(admin *Admin) error {
 		admin.ID = oldAdmin.ID
 		admin.CreatedAt = oldAdmin.CreatedAt
 		admin.LastLogin = oldAdmin.LastLogin
+		if admin.Password == oldAdmin.Password {
+			admin.LastPasswordChange = oldAdmin.LastPasswordChange
+		} else {
+			admin.LastPasswordChange = util.GetTimeAsMsSinceEpoch(time.Now())
+		}
 		admin.UpdatedAt = util.GetTimeAsMsSinceEpoch(time.Now())
 		buf, err := json.Marshal(admin)
 		if err != nil {
@@ -533,6 +706,10 @@ func (p *BoltProvider) addUser(user *User) error {
 		if err != nil {
 			return err
 		}
+		groupBucket, err := getGroupsBucket(tx)
+		if err != nil {
+			return err
+		}
 		if u := bucket.Get([]byte(user.Username)); u != nil {
 			return fmt.Errorf("username %v already exists", user.Username)
 		}
@@ -544,6 +721,9 @@ func (p *BoltProvider) addUser(user *User) error {
 		user.LastQuotaUpdate = 0
 		user.UsedQuotaSize = 0
 		user.UsedQuotaFiles = 0
+		user.UsedUploadDataTransfer = 0
+		user.UsedDownloadDataTransfer = 0
+		user.LastDataTransferReset = 0
 		user.LastLogin = 0
 		user.CreatedAt = util.GetTimeAsMsSinceEpoch(time.Now())
 		user.UpdatedAt = util.GetTimeAsMsSinceEpoch(time.Now())
@@ -553,6 +733,12 @@ func (p *BoltProvider) addUser(user *User) error {
 				return err
 			}
 		}
+		for _, groupName := range user.Groups {
+			err = addUserToGroupMapping(user.Username, groupName, groupBucket)
+			if err != nil {
+				return err
+			}
+		}
 		buf, err := json.Marshal(user)
 		if err != nil {
 			return err
@@ -575,6 +761,10 @@ func (p *BoltProvider) updateUser(user *User) error {
 		if err != nil {
 			return err
 		}
+		groupBucket, err := getGroupsBucket(tx)
+		if err != nil {
+			return err
+		}
 		var u []byte
 		if u = bucket.Get([]byte(user.Username)); u == nil {
 			return util.NewRecordNotFoundError(fmt.Sprintf("username %#v does not exist", user.Username))
@@ -596,11 +786,31 @@ func (p *BoltProvider) updateUser(user *User) error {
 				return err
 			}
 		}
+		for _, groupName := range oldUser.Groups {
+			err = removeUserFromGroupMapping(oldUser.Username, groupName, groupBucket)
+			if err != nil {
+				return err
+			}
+		}
+		for _, groupName := range user.Groups {
+			err = addUserToGroupMapping(user.Username, groupName, groupBucket)
+			if err != nil {
+				return err
+			}
+		}
 		user.ID = oldUser.ID
 		user.LastQuotaUpdate = oldUser.LastQuotaUpdate
 		user.UsedQuotaSize = oldUser.UsedQuotaSize
 		user.UsedQuotaFiles = oldUser.UsedQuotaFiles
+		user.UsedUploadDataTransfer = oldUser.UsedUploadDataTransfer
+		user.UsedDownloadDataTransfer = oldUser.UsedDownloadDataTransfer
+		user.LastDataTransferReset = oldUser.LastDataTransferReset
 		user.LastLogin = oldUser.LastLogin
+		if user.Password == oldUser.Password {
+			user.LastPasswordChange = oldUser.LastPasswordChange
+		} else {
+			user.LastPasswordChange = util.GetTimeAsMsSinceEpoch(time.Now())
+		}
 		user.CreatedAt = oldUser.CreatedAt
 		user.UpdatedAt = util.GetTimeAsMsSinceEpoch(time.Now())
 		buf, err := json.Marshal(user)
@@ -634,10 +844,25 @@ func (p *BoltProvider) deleteUser(user *User) error {
 				}
 			}
 		}
+		if len(user.Groups) > 0 {
+			groupBucket, err := getGroupsBucket(tx)
+			if err != nil {
+				return err
+			}
+			for _, groupName := range user.Groups {
+				err = removeUserFromGroupMapping(user.Username, groupName, groupBucket)
+				if err != nil {
+					return err
+				}
+			}
+		}
 
 		if err := deleteRelatedAPIKey(tx, user.Username, APIKeyScopeUser); err != nil {
 			return err
 		}
+		if err := deleteRelatedShares(tx, user.Username); err != nil {
+			return err
+		}
 		return bucket.Delete([]byte(user.Username))
 	})
 }
@@ -675,6 +900,49 @@ func (p *BoltProvider) getRecentlyUpdatedUsers(after int64) ([]User, error) {
 	return nil, nil
 }
 
+func (p *BoltProvider) getUsersInScope(admin *Admin, limit, offset int, order string) ([]User, error) {
+	users := make([]User, 0, 10)
+
+	err := p.dbHandle.View(func(tx *bolt.Tx) error {
+		bucket, err := getUsersBucket(tx)
+		if err != nil {
+			return err
+		}
+		folderBucket, err := getFoldersBucket(tx)
+		if err != nil {
+			return err
+		}
+		cursor := bucket.Cursor()
+		next := cursor.Next
+		k, v := cursor.First()
+		if order == OrderDESC {
+			next = cursor.Prev
+			k, v = cursor.Last()
+		}
+		itNum := 0
+		for ; k != nil; k, v = next() {
+			user, err := joinUserAndFolders(v, folderBucket)
+			if err != nil {
+				return err
+			}
+			if !admin.IsUserInScope(&user) {
+				continue
+			}
+			itNum++
+			if itNum <= offset {
+				continue
+			}
+			users = append(users, user)
+			if limit > 0 && len(users) >= limit {
+				break
+			}
+		}
+		return nil
+	})
+
+	return users, err
+}
+
 func (p *BoltProvider) getUsers(limit int, offset int, order string) ([]User, error) {
 	users := make([]User, 0, limit)
 	var err error
@@ -829,6 +1097,7 @@ func (p *BoltProvider) addFolder(folder *vfs.BaseVirtualFolder) error {
 			return fmt.Errorf("folder %v already exists", folder.Name)
 		}
 		folder.Users = nil
+		folder.Groups = nil
 		return addFolderInternal(*folder, bucket)
 	})
 }
@@ -858,7 +1127,11 @@ func (p *BoltProvider) updateFolder(folder *vfs.BaseVirtualFolder) error {
 		folder.LastQuotaUpdate = oldFolder.LastQuotaUpdate
 		folder.UsedQuotaFiles = oldFolder.UsedQuotaFiles
 		folder.UsedQuotaSize = oldFolder.UsedQuotaSize
+		folder.UsedUploadDataTransfer = oldFolder.UsedUploadDataTransfer
+		folder.UsedDownloadDataTransfer = oldFolder.UsedDownloadDataTransfer
+		folder.LastDataTransferReset = oldFolder.LastDataTransferReset
 		folder.Users = oldFolder.Users
+		folder.Groups = oldFolder.Groups
 		buf, err := json.Marshal(folder)
 		if err != nil {
 			return err
@@ -912,6 +1185,38 @@ func (p *BoltProvider) deleteFolder(folder *vfs.BaseVirtualFolder) error {
 				return err
 			}
 		}
+		if len(folder.Groups) > 0 {
+			groupBucket, err := getGroupsBucket(tx)
+			if err != nil {
+				return err
+			}
+			for _, groupName := range folder.Groups {
+				var g []byte
+				if g = groupBucket.Get([]byte(groupName)); g == nil {
+					continue
+				}
+				var group Group
+				err = json.Unmarshal(g, &group)
+				if err != nil {
+					return err
+				}
+				var folders []vfs.VirtualFolder
+				for _, groupFolder := range group.VirtualFolders {
+					if folder.Name != groupFolder.Name {
+						folders = append(folders, groupFolder)
+					}
+				}
+				group.VirtualFolders = folders
+				buf, err := json.Marshal(group)
+				if err != nil {
+					return err
+				}
+				err = groupBucket.Put([]byte(group.Name), buf)
+				if err != nil {
+					return err
+				}
+			}
+		}
 
 		return bucket.Delete([]byte(folder.Name))
 	})
@@ -957,107 +1262,1837 @@ func (p *BoltProvider) getUsedFolderQuota(name string) (int, int64, error) {
 	return folder.UsedQuotaFiles, folder.UsedQuotaSize, err
 }
 
-func (p *BoltProvider) apiKeyExists(keyID string) (APIKey, error) {
-	var apiKey APIKey
-	err := p.dbHandle.View(func(tx *bolt.Tx) error {
-		bucket, err := getAPIKeysBucket(tx)
+func (p *BoltProvider) updateFolderTransferQuota(name string, uploadSize, downloadSize int64, reset bool,
+	periodStart int64) error {
+	return p.dbHandle.Update(func(tx *bolt.Tx) error {
+		bucket, err := getFoldersBucket(tx)
+		if err != nil {
+			return err
+		}
+		var f []byte
+		if f = bucket.Get([]byte(name)); f == nil {
+			return util.NewRecordNotFoundError(fmt.Sprintf("folder %#v does not exist, unable to update transfer quota",
+				name))
+		}
+		var folder vfs.BaseVirtualFolder
+		err = json.Unmarshal(f, &folder)
+		if err != nil {
+			return err
+		}
+		folder.UsedUploadDataTransfer, folder.UsedDownloadDataTransfer, folder.LastDataTransferReset = getUpdatedTransferQuota(
+			folder.UsedUploadDataTransfer, folder.UsedDownloadDataTransfer, folder.LastDataTransferReset, uploadSize,
+			downloadSize, reset, periodStart)
+		buf, err := json.Marshal(folder)
 		if err != nil {
 			return err
 		}
+		return bucket.Put([]byte(folder.Name), buf)
+	})
+}
 
-		k := bucket.Get([]byte(keyID))
-		if k == nil {
-			return util.NewRecordNotFoundError(fmt.Sprintf("API key %v does not exist", keyID))
+func (p *BoltProvider) getUsedFolderTransferQuota(name string) (int64, int64, int64, error) {
+	folder, err := p.getFolderByName(name)
+	if err != nil {
+		providerLog(logger.LevelWarn, "unable to get transfer quota for folder %#v error: %v", name, err)
+		return 0, 0, 0, err
+	}
+	return folder.UsedUploadDataTransfer, folder.UsedDownloadDataTransfer, folder.LastDataTransferReset, err
+}
+
+func (p *BoltProvider) groupExists(name string) (Group, error) {
+	var group Group
+	err := p.dbHandle.View(func(tx *bolt.Tx) error {
+		bucket, err := getGroupsBucket(tx)
+		if err != nil {
+			return err
 		}
-		return json.Unmarshal(k, &apiKey)
+		g := bucket.Get([]byte(name))
+		if g == nil {
+			return util.NewRecordNotFoundError(fmt.Sprintf("group %#v does not exist", name))
+		}
+		folderBucket, err := getFoldersBucket(tx)
+		if err != nil {
+			return err
+		}
+		group, err = joinGroupAndFolders(g, folderBucket)
+		return err
 	})
-	return apiKey, err
+	return group, err
 }
 
-func (p *BoltProvider) addAPIKey(apiKey *APIKey) error {
-	err := apiKey.validate()
+func (p *BoltProvider) addGroup(group *Group) error {
+	err := group.validate()
 	if err != nil {
 		return err
 	}
 	return p.dbHandle.Update(func(tx *bolt.Tx) error {
-		bucket, err := getAPIKeysBucket(tx)
+		bucket, err := getGroupsBucket(tx)
 		if err != nil {
 			return err
 		}
-		if a := bucket.Get([]byte(apiKey.KeyID)); a != nil {
-			return fmt.Errorf("API key %v already exists", apiKey.KeyID)
+		folderBucket, err := getFoldersBucket(tx)
+		if err != nil {
+			return err
+		}
+		if g := bucket.Get([]byte(group.Name)); g != nil {
+			return fmt.Errorf("group %v already exists", group.Name)
 		}
 		id, err := bucket.NextSequence()
 		if err != nil {
 			return err
 		}
-		apiKey.ID = int64(id)
-		apiKey.CreatedAt = util.GetTimeAsMsSinceEpoch(time.Now())
-		apiKey.UpdatedAt = util.GetTimeAsMsSinceEpoch(time.Now())
-		apiKey.LastUseAt = 0
-		buf, err := json.Marshal(apiKey)
+		group.ID = int64(id)
+		group.CreatedAt = util.GetTimeAsMsSinceEpoch(time.Now())
+		group.UpdatedAt = util.GetTimeAsMsSinceEpoch(time.Now())
+		group.Users = nil
+		for idx := range group.VirtualFolders {
+			err = addGroupToFolderMapping(&group.VirtualFolders[idx].BaseVirtualFolder, group, folderBucket)
+			if err != nil {
+				return err
+			}
+		}
+		buf, err := json.Marshal(group)
 		if err != nil {
 			return err
 		}
-		return bucket.Put([]byte(apiKey.KeyID), buf)
+		return bucket.Put([]byte(group.Name), buf)
 	})
 }
 
-func (p *BoltProvider) updateAPIKey(apiKey *APIKey) error {
-	err := apiKey.validate()
+func (p *BoltProvider) updateGroup(group *Group) error {
+	err := group.validate()
 	if err != nil {
 		return err
 	}
 	return p.dbHandle.Update(func(tx *bolt.Tx) error {
-		bucket, err := getAPIKeysBucket(tx)
+		bucket, err := getGroupsBucket(tx)
 		if err != nil {
 			return err
 		}
-		var a []byte
+		folderBucket, err := getFoldersBucket(tx)
+		if err != nil {
+			return err
+		}
+		var g []byte
+		if g = bucket.Get([]byte(group.Name)); g == nil {
+			return util.NewRecordNotFoundError(fmt.Sprintf("group %#v does not exist", group.Name))
+		}
+		var oldGroup Group
+		err = json.Unmarshal(g, &oldGroup)
+		if err != nil {
+			return err
+		}
+		for idx := range oldGroup.VirtualFolders {
+			err = removeGroupFromFolderMapping(&oldGroup.VirtualFolders[idx], &oldGroup, folderBucket)
+			if err != nil {
+				return err
+			}
+		}
+		for idx := range group.VirtualFolders {
+			err = addGroupToFolderMapping(&group.VirtualFolders[idx].BaseVirtualFolder, group, folderBucket)
+			if err != nil {
+				return err
+			}
+		}
+		group.ID = oldGroup.ID
+		group.CreatedAt = oldGroup.CreatedAt
+		group.UpdatedAt = util.GetTimeAsMsSinceEpoch(time.Now())
+		group.Users = oldGroup.Users
+		buf, err := json.Marshal(group)
+		if err != nil {
+			return err
+		}
+		return bucket.Put([]byte(group.Name), buf)
+	})
+}
+
+func (p *BoltProvider) deleteGroup(group *Group) error {
+	return p.dbHandle.Update(func(tx *bolt.Tx) error {
+		bucket, err := getGroupsBucket(tx)
+		if err != nil {
+			return err
+		}
+		var g []byte
+		if g = bucket.Get([]byte(group.Name)); g == nil {
+			return util.NewRecordNotFoundError(fmt.Sprintf("group %#v does not exist", group.Name))
+		}
+		var oldGroup Group
+		err = json.Unmarshal(g, &oldGroup)
+		if err != nil {
+			return err
+		}
+		if len(oldGroup.VirtualFolders) > 0 {
+			folderBucket, err := getFoldersBucket(tx)
+			if err != nil {
+				return err
+			}
+			for idx := range oldGroup.VirtualFolders {
+				err = removeGroupFromFolderMapping(&oldGroup.VirtualFolders[idx], &oldGroup, folderBucket)
+				if err != nil {
+					return err
+				}
+			}
+		}
+		if len(oldGroup.Users) > 0 {
+			usersBucket, err := getUsersBucket(tx)
+			if err != nil {
+				return err
+			}
+			for _, username := range oldGroup.Users {
+				var u []byte
+				if u = usersBucket.Get([]byte(username)); u == nil {
+					continue
+				}
+				var user User
+				err = json.Unmarshal(u, &user)
+				if err != nil {
+					return err
+				}
+				var groups []string
+				for _, groupName := range user.Groups {
+					if groupName != oldGroup.Name {
+						groups = append(groups, groupName)
+					}
+				}
+				user.Groups = groups
+				buf, err := json.Marshal(user)
+				if err != nil {
+					return err
+				}
+				err = usersBucket.Put([]byte(user.Username), buf)
+				if err != nil {
+					return err
+				}
+			}
+		}
+
+		return bucket.Delete([]byte(group.Name))
+	})
+}
+
+func (p *BoltProvider) getGroups(limit, offset int, order string) ([]Group, error) {
+	groups := make([]Group, 0, limit)
+	var err error
+	if limit <= 0 {
+		return groups, err
+	}
+	err = p.dbHandle.View(func(tx *bolt.Tx) error {
+		bucket, err := getGroupsBucket(tx)
+		if err != nil {
+			return err
+		}
+		folderBucket, err := getFoldersBucket(tx)
+		if err != nil {
+			return err
+		}
+		cursor := bucket.Cursor()
+		itNum := 0
+		if order == OrderASC {
+			for k, v := cursor.First(); k != nil; k, v = cursor.Next() {
+				itNum++
+				if itNum <= offset {
+					continue
+				}
+				group, err := joinGroupAndFolders(v, folderBucket)
+				if err != nil {
+					return err
+				}
+				group.PrepareForRendering()
+				groups = append(groups, group)
+				if len(groups) >= limit {
+					break
+				}
+			}
+		} else {
+			for k, v := cursor.Last(); k != nil; k, v = cursor.Prev() {
+				itNum++
+				if itNum <= offset {
+					continue
+				}
+				group, err := joinGroupAndFolders(v, folderBucket)
+				if err != nil {
+					return err
+				}
+				group.PrepareForRendering()
+				groups = append(groups, group)
+				if len(groups) >= limit {
+					break
+				}
+			}
+		}
+		return err
+	})
+	return groups, err
+}
+
+func (p *BoltProvider) getGroupsWithNames(names []string) ([]Group, error) {
+	groups := make([]Group, 0, len(names))
+	err := p.dbHandle.View(func(tx *bolt.Tx) error {
+		bucket, err := getGroupsBucket(tx)
+		if err != nil {
+			return err
+		}
+		folderBucket, err := getFoldersBucket(tx)
+		if err != nil {
+			return err
+		}
+		for _, name := range names {
+			g := bucket.Get([]byte(name))
+			if g == nil {
+				continue
+			}
+			group, err := joinGroupAndFolders(g, folderBucket)
+			if err != nil {
+				return err
+			}
+			groups = append(groups, group)
+		}
+		return nil
+	})
+	return groups, err
+}
+
+func (p *BoltProvider) dumpGroups() ([]Group, error) {
+	groups := make([]Group, 0, 50)
+	err := p.dbHandle.View(func(tx *bolt.Tx) error {
+		bucket, err := getGroupsBucket(tx)
+		if err != nil {
+			return err
+		}
+		folderBucket, err := getFoldersBucket(tx)
+		if err != nil {
+			return err
+		}
+		cursor := bucket.Cursor()
+		for k, v := cursor.First(); k != nil; k, v = cursor.Next() {
+			group, err := joinGroupAndFolders(v, folderBucket)
+			if err != nil {
+				return err
+			}
+			groups = append(groups, group)
+		}
+		return err
+	})
+	return groups, err
+}
+
+func (p *BoltProvider) apiKeyExists(keyID string) (APIKey, error) {
+	var apiKey APIKey
+	err := p.dbHandle.View(func(tx *bolt.Tx) error {
+		bucket, err := getAPIKeysBucket(tx)
+		if err != nil {
+			return err
+		}
+
+		k := bucket.Get([]byte(keyID))
+		if k == nil {
+			return util.NewRecordNotFoundError(fmt.Sprintf("API key %v does not exist", keyID))
+		}
+		return json.Unmarshal(k, &apiKey)
+	})
+	return apiKey, err
+}
+
+func (p *BoltProvider) addAPIKey(apiKey *APIKey) error {
+	err := apiKey.validate()
+	if err != nil {
+		return err
+	}
+	return p.dbHandle.Update(func(tx *bolt.Tx) error {
+		bucket, err := getAPIKeysBucket(tx)
+		if err != nil {
+			return err
+		}
+		if a := bucket.Get([]byte(apiKey.KeyID)); a != nil {
+			return fmt.Errorf("API key %v already exists", apiKey.KeyID)
+		}
+		id, err := bucket.NextSequence()
+		if err != nil {
+			return err
+		}
+		apiKey.ID = int64(id)
+		apiKey.CreatedAt = util.GetTimeAsMsSinceEpoch(time.Now())
+		apiKey.UpdatedAt = util.GetTimeAsMsSinceEpoch(time.Now())
+		apiKey.LastUseAt = 0
+		buf, err := json.Marshal(apiKey)
+		if err != nil {
+			return err
+		}
+		return bucket.Put([]byte(apiKey.KeyID), buf)
+	})
+}
+
+func (p *BoltProvider) updateAPIKey(apiKey *APIKey) error {
+	err := apiKey.validate()
+	if err != nil {
+		return err
+	}
+	return p.dbHandle.Update(func(tx *bolt.Tx) error {
+		bucket, err := getAPIKeysBucket(tx)
+		if err != nil {
+			return err
+		}
+		var a []byte
 
 		if a = bucket.Get([]byte(apiKey.KeyID)); a == nil {
 			return util.NewRecordNotFoundError(fmt.Sprintf("API key %v does not exist", apiKey.KeyID))
 		}
-		var oldAPIKey APIKey
-		err = json.Unmarshal(a, &oldAPIKey)
+		var oldAPIKey APIKey
+		err = json.Unmarshal(a, &oldAPIKey)
+		if err != nil {
+			return err
+		}
+
+		apiKey.ID = oldAPIKey.ID
+		apiKey.KeyID = oldAPIKey.KeyID
+		apiKey.Key = oldAPIKey.Key
+		apiKey.CreatedAt = oldAPIKey.CreatedAt
+		apiKey.LastUseAt = oldAPIKey.LastUseAt
+		apiKey.UpdatedAt = util.GetTimeAsMsSinceEpoch(time.Now())
+		buf, err := json.Marshal(apiKey)
+		if err != nil {
+			return err
+		}
+		return bucket.Put([]byte(apiKey.KeyID), buf)
+	})
+}
+
+func (p *BoltProvider) deleteAPIKeys(apiKey *APIKey) error {
+	return p.dbHandle.Update(func(tx *bolt.Tx) error {
+		bucket, err := getAPIKeysBucket(tx)
+		if err != nil {
+			return err
+		}
+
+		if bucket.Get([]byte(apiKey.KeyID)) == nil {
+			return util.NewRecordNotFoundError(fmt.Sprintf("API key %v does not exist", apiKey.KeyID))
+		}
+
+		return bucket.Delete([]byte(apiKey.KeyID))
+	})
+}
+
+func (p *BoltProvider) getAPIKeys(limit int, offset int, order string) ([]APIKey, error) {
+	apiKeys := make([]APIKey, 0, limit)
+
+	err := p.dbHandle.View(func(tx *bolt.Tx) error {
+		bucket, err := getAPIKeysBucket(tx)
+		if err != nil {
+			return err
+		}
+		cursor := bucket.Cursor()
+		itNum := 0
+		if order == OrderASC {
+			for k, v := cursor.First(); k != nil; k, v = cursor.Next() {
+				itNum++
+				if itNum <= offset {
+					continue
+				}
+				var apiKey APIKey
+				err = json.Unmarshal(v, &apiKey)
+				if err != nil {
+					return err
+				}
+				apiKey.HideConfidentialData()
+				apiKeys = append(apiKeys, apiKey)
+				if len(apiKeys) >= limit {
+					break
+				}
+			}
+			return nil
+		}
+		for k, v := cursor.Last(); k != nil; k, v = cursor.Prev() {
+			itNum++
+			if itNum <= offset {
+				continue
+			}
+			var apiKey APIKey
+			err = json.Unmarshal(v, &apiKey)
+			if err != nil {
+				return err
+			}
+			apiKey.HideConfidentialData()
+			apiKeys = append(apiKeys, apiKey)
+			if len(apiKeys) >= limit {
+				break
+			}
+		}
+		return nil
+	})
+
+	return apiKeys, err
+}
+
+func (p *BoltProvider) dumpAPIKeys() ([]APIKey, error) {
+	apiKeys := make([]APIKey, 0, 30)
+	err := p.dbHandle.View(func(tx *bolt.Tx) error {
+		bucket, err := getAPIKeysBucket(tx)
+		if err != nil {
+			return err
+		}
+
+		cursor := bucket.Cursor()
+		for k, v := cursor.First(); k != nil; k, v = cursor.Next() {
+			var apiKey APIKey
+			err = json.Unmarshal(v, &apiKey)
+			if err != nil {
+				return err
+			}
+			apiKeys = append(apiKeys, apiKey)
+		}
+		return err
+	})
+
+	return apiKeys, err
+}
+
+func (p *BoltProvider) shareExists(shareID, username string) (Share, error) {
+	var share Share
+	err := p.dbHandle.View(func(tx *bolt.Tx) error {
+		bucket, err := getSharesBucket(tx)
+		if err != nil {
+			return err
+		}
+
+		s := bucket.Get([]byte(shareID))
+		if s == nil {
+			return util.NewRecordNotFoundError(fmt.Sprintf("Share %v does not exist", shareID))
+		}
+		if err := json.Unmarshal(s, &share); err != nil {
+			return err
+		}
+		if username != "" && share.Username != username {
+			return util.NewRecordNotFoundError(fmt.Sprintf("Share %v does not exist", shareID))
+		}
+		return nil
+	})
+	return share, err
+}
+
+func (p *BoltProvider) addShare(share *Share) error {
+	err := share.validate()
+	if err != nil {
+		return err
+	}
+	return p.dbHandle.Update(func(tx *bolt.Tx) error {
+		bucket, err := getSharesBucket(tx)
+		if err != nil {
+			return err
+		}
+		if a := bucket.Get([]byte(share.ShareID)); a != nil {
+			return fmt.Errorf("share %v already exists", share.ShareID)
+		}
+		id, err := bucket.NextSequence()
+		if err != nil {
+			return err
+		}
+		share.ID = int64(id)
+		if !share.IsRestore {
+			share.CreatedAt = util.GetTimeAsMsSinceEpoch(time.Now())
+			share.UpdatedAt = share.CreatedAt
+			share.LastUseAt = 0
+			share.UsedTokens = 0
+		}
+		if share.CreatedAt == 0 {
+			share.CreatedAt = util.GetTimeAsMsSinceEpoch(time.Now())
+		}
+		if share.UpdatedAt == 0 {
+			share.UpdatedAt = share.CreatedAt
+		}
+		if err := p.checkShareUser(tx, share.Username); err != nil {
+			return err
+		}
+		buf, err := json.Marshal(share)
+		if err != nil {
+			return err
+		}
+		return bucket.Put([]byte(share.ShareID), buf)
+	})
+}
+
+func (p *BoltProvider) updateShare(share *Share) error {
+	if err := share.validate(); err != nil {
+		return err
+	}
+
+	return p.dbHandle.Update(func(tx *bolt.Tx) error {
+		bucket, err := getSharesBucket(tx)
+		if err != nil {
+			return err
+		}
+
+		var s []byte
+
+		if s = bucket.Get([]byte(share.ShareID)); s == nil {
+			return util.NewRecordNotFoundError(fmt.Sprintf("Share %v does not exist", share.ShareID))
+		}
+		var oldObject Share
+		if err = json.Unmarshal(s, &oldObject); err != nil {
+			return err
+		}
+
+		share.ID = oldObject.ID
+		share.ShareID = oldObject.ShareID
+		if !share.IsRestore {
+			share.UsedTokens = oldObject.UsedTokens
+			share.CreatedAt = oldObject.CreatedAt
+			share.LastUseAt = oldObject.LastUseAt
+			share.UpdatedAt = util.GetTimeAsMsSinceEpoch(time.Now())
+		}
+		if share.CreatedAt == 0 {
+			share.CreatedAt = util.GetTimeAsMsSinceEpoch(time.Now())
+		}
+		if share.UpdatedAt == 0 {
+			share.UpdatedAt = share.CreatedAt
+		}
+		if err := p.checkShareUser(tx, share.Username); err != nil {
+			return err
+		}
+		buf, err := json.Marshal(share)
+		if err != nil {
+			return err
+		}
+		return bucket.Put([]byte(share.ShareID), buf)
+	})
+}
+
+func (p *BoltProvider) deleteShare(share *Share) error {
+	return p.dbHandle.Update(func(tx *bolt.Tx) error {
+		bucket, err := getSharesBucket(tx)
+		if err != nil {
+			return err
+		}
+
+		if bucket.Get([]byte(share.ShareID)) == nil {
+			return util.NewRecordNotFoundError(fmt.Sprintf("Share %v does not exist", share.ShareID))
+		}
+		if err := deleteShareUploads(tx, share.ShareID); err != nil {
+			return err
+		}
+
+		return bucket.Delete([]byte(share.ShareID))
+	})
+}
+
+func (p *BoltProvider) getShares(limit int, offset int, order, username string) ([]Share, error) {
+	shares := make([]Share, 0, limit)
+
+	err := p.dbHandle.View(func(tx *bolt.Tx) error {
+		bucket, err := getSharesBucket(tx)
+		if err != nil {
+			return err
+		}
+		cursor := bucket.Cursor()
+		itNum := 0
+		if order == OrderASC {
+			for k, v := cursor.First(); k != nil; k, v = cursor.Next() {
+				var share Share
+				err = json.Unmarshal(v, &share)
+				if err != nil {
+					return err
+				}
+				if share.Username != username {
+					continue
+				}
+				itNum++
+				if itNum <= offset {
+					continue
+				}
+				share.HideConfidentialData()
+				shares = append(shares, share)
+				if len(shares) >= limit {
+					break
+				}
+			}
+			return nil
+		}
+		for k, v := cursor.Last(); k != nil; k, v = cursor.Prev() {
+			var share Share
+			err = json.Unmarshal(v, &share)
+			if err != nil {
+				return err
+			}
+			if share.Username != username {
+				continue
+			}
+			itNum++
+			if itNum <= offset {
+				continue
+			}
+			share.HideConfidentialData()
+			shares = append(shares, share)
+			if len(shares) >= limit {
+				break
+			}
+		}
+		return nil
+	})
+
+	return shares, err
+}
+
+func (p *BoltProvider) dumpShares() ([]Share, error) {
+	shares := make([]Share, 0, 30)
+	err := p.dbHandle.View(func(tx *bolt.Tx) error {
+		bucket, err := getSharesBucket(tx)
+		if err != nil {
+			return err
+		}
+
+		cursor := bucket.Cursor()
+		for k, v := cursor.First(); k != nil; k, v = cursor.Next() {
+			var share Share
+			err = json.Unmarshal(v, &share)
+			if err != nil {
+				return err
+			}
+			shares = append(shares, share)
+		}
+		return err
+	})
+
+	return shares, err
+}
+
+func (p *BoltProvider) updateShareLastUse(shareID string, numTokens int) error {
+	return p.dbHandle.Update(func(tx *bolt.Tx) error {
+		bucket, err := getSharesBucket(tx)
+		if err != nil {
+			return err
+		}
+		var u []byte
+		if u = bucket.Get([]byte(shareID)); u == nil {
+			return util.NewRecordNotFoundError(fmt.Sprintf("share %#v does not exist, unable to update last use", shareID))
+		}
+		var share Share
+		err = json.Unmarshal(u, &share)
+		if err != nil {
+			return err
+		}
+		share.LastUseAt = util.GetTimeAsMsSinceEpoch(time.Now())
+		share.UsedTokens += numTokens
+		buf, err := json.Marshal(share)
+		if err != nil {
+			return err
+		}
+		err = bucket.Put([]byte(shareID), buf)
+		if err != nil {
+			providerLog(logger.LevelWarn, "error updating last use for share %#v: %v", shareID, err)
+			return err
+		}
+		providerLog(logger.LevelDebug, "last use updated for share %#v", shareID)
+		return nil
+	})
+}
+
+func (p *BoltProvider) addShareUpload(upload *ShareUpload) error {
+	if err := upload.validate(); err != nil {
+		return err
+	}
+	return p.dbHandle.Update(func(tx *bolt.Tx) error {
+		sharesBucket, err := getSharesBucket(tx)
+		if err != nil {
+			return err
+		}
+		if sharesBucket.Get([]byte(upload.ShareID)) == nil {
+			return util.NewRecordNotFoundError(fmt.Sprintf("Share %v does not exist", upload.ShareID))
+		}
+		bucket, err := getShareUploadsBucket(tx)
+		if err != nil {
+			return err
+		}
+		id, err := bucket.NextSequence()
+		if err != nil {
+			return err
+		}
+		upload.ID = int64(id)
+		buf, err := json.Marshal(upload)
+		if err != nil {
+			return err
+		}
+		return bucket.Put(getShareUploadKey(upload.ShareID, id), buf)
+	})
+}
+
+func (p *BoltProvider) getShareUploads(shareID string, limit, offset int, order string) ([]ShareUpload, error) {
+	uploads := make([]ShareUpload, 0, limit)
+
+	err := p.dbHandle.View(func(tx *bolt.Tx) error {
+		bucket, err := getShareUploadsBucket(tx)
+		if err != nil {
+			return err
+		}
+		var keys [][]byte
+		prefix := getShareUploadKeyPrefix(shareID)
+		cursor := bucket.Cursor()
+		for k, _ := cursor.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, _ = cursor.Next() {
+			keys = append(keys, k)
+		}
+		if order == OrderDESC {
+			for i, j := 0, len(keys)-1; i < j; i, j = i+1, j-1 {
+				keys[i], keys[j] = keys[j], keys[i]
+			}
+		}
+		for idx, k := range keys {
+			if idx < offset {
+				continue
+			}
+			var upload ShareUpload
+			if err := json.Unmarshal(bucket.Get(k), &upload); err != nil {
+				return err
+			}
+			uploads = append(uploads, upload)
+			if len(uploads) >= limit {
+				break
+			}
+		}
+		return nil
+	})
+
+	return uploads, err
+}
+
+func (p *BoltProvider) checkShareUser(tx *bolt.Tx, username string) error {
+	bucket, err := getUsersBucket(tx)
+	if err != nil {
+		return err
+	}
+	if bucket.Get([]byte(username)) == nil {
+		return util.NewValidationError(fmt.Sprintf("unable to validate user %#v", username))
+	}
+	return nil
+}
+
+func (p *BoltProvider) accountLockoutExists(username, accountType string) (AccountLockout, error) {
+	var lockout AccountLockout
+
+	err := p.dbHandle.View(func(tx *bolt.Tx) error {
+		bucket, err := getLockoutsBucket(tx)
+		if err != nil {
+			return err
+		}
+		l := bucket.Get([]byte(getAccountLockoutKey(username, accountType)))
+		if l == nil {
+			return util.NewRecordNotFoundError(fmt.Sprintf("account lockout for %v %#v does not exist", accountType, username))
+		}
+		return json.Unmarshal(l, &lockout)
+	})
+
+	return lockout, err
+}
+
+func (p *BoltProvider) addAccountLockoutFailure(username, accountType string, now time.Time) (AccountLockout, error) {
+	lockout := AccountLockout{
+		Username: username,
+		Type:     accountType,
+	}
+	if err := lockout.validate(); err != nil {
+		return lockout, err
+	}
+	err := p.dbHandle.Update(func(tx *bolt.Tx) error {
+		bucket, err := getLockoutsBucket(tx)
+		if err != nil {
+			return err
+		}
+		key := []byte(lockout.getKey())
+		if l := bucket.Get(key); l != nil {
+			if err := json.Unmarshal(l, &lockout); err != nil {
+				return err
+			}
+		}
+		lockout.addFailure(now)
+		buf, err := json.Marshal(lockout)
+		if err != nil {
+			return err
+		}
+		return bucket.Put(key, buf)
+	})
+
+	return lockout, err
+}
+
+func (p *BoltProvider) deleteAccountLockout(username, accountType string) error {
+	return p.dbHandle.Update(func(tx *bolt.Tx) error {
+		bucket, err := getLockoutsBucket(tx)
+		if err != nil {
+			return err
+		}
+		key := []byte(getAccountLockoutKey(username, accountType))
+		if bucket.Get(key) == nil {
+			return util.NewRecordNotFoundError(fmt.Sprintf("account lockout for %v %#v does not exist", accountType, username))
+		}
+		return bucket.Delete(key)
+	})
+}
+
+func (p *BoltProvider) getAccountLockouts(limit, offset int, order string) ([]AccountLockout, error) {
+	lockouts := make([]AccountLockout, 0, limit)
+
+	err := p.dbHandle.View(func(tx *bolt.Tx) error {
+		bucket, err := getLockoutsBucket(tx)
+		if err != nil {
+			return err
+		}
+		cursor := bucket.Cursor()
+		itNum := 0
+		if order == OrderASC {
+			for k, v := cursor.First(); k != nil; k, v = cursor.Next() {
+				itNum++
+				if itNum <= offset {
+					continue
+				}
+				var lockout AccountLockout
+				if err := json.Unmarshal(v, &lockout); err != nil {
+					return err
+				}
+				lockouts = append(lockouts, lockout)
+				if len(lockouts) >= limit {
+					break
+				}
+			}
+			return nil
+		}
+		for k, v := cursor.Last(); k != nil; k, v = cursor.Prev() {
+			itNum++
+			if itNum <= offset {
+				continue
+			}
+			var lockout AccountLockout
+			if err := json.Unmarshal(v, &lockout); err != nil {
+				return err
+			}
+			lockouts = append(lockouts, lockout)
+			if len(lockouts) >= limit {
+				break
+			}
+		}
+		return nil
+	})
+
+	return lockouts, err
+}
+
+func (p *BoltProvider) webDAVLockExists(token string) (WebDAVLock, error) {
+	var lock WebDAVLock
+
+	err := p.dbHandle.View(func(tx *bolt.Tx) error {
+		bucket, err := getWebDAVLocksBucket(tx)
+		if err != nil {
+			return err
+		}
+		l := bucket.Get([]byte(token))
+		if l == nil {
+			return util.NewRecordNotFoundError(fmt.Sprintf("WebDAV lock %#v does not exist", token))
+		}
+		return json.Unmarshal(l, &lock)
+	})
+
+	return lock, err
+}
+
+func (p *BoltProvider) addWebDAVLock(lock *WebDAVLock) error {
+	if err := lock.validate(); err != nil {
+		return err
+	}
+	return p.dbHandle.Update(func(tx *bolt.Tx) error {
+		bucket, err := getWebDAVLocksBucket(tx)
+		if err != nil {
+			return err
+		}
+		if bucket.Get([]byte(lock.Token)) != nil {
+			return fmt.Errorf("WebDAV lock %#v already exists", lock.Token)
+		}
+		var expiredTokens [][]byte
+		cursor := bucket.Cursor()
+		for k, v := cursor.First(); k != nil; k, v = cursor.Next() {
+			var l WebDAVLock
+			if err := json.Unmarshal(v, &l); err != nil {
+				return err
+			}
+			if l.Username == lock.Username && l.Root == lock.Root {
+				if !l.isExpiredAt(lock.CreatedAt) {
+					return fmt.Errorf("a WebDAV lock for %#v already exists", lock.Root)
+				}
+				expiredTokens = append(expiredTokens, k)
+			}
+		}
+		for _, k := range expiredTokens {
+			if err := bucket.Delete(k); err != nil {
+				return err
+			}
+		}
+		buf, err := json.Marshal(lock)
+		if err != nil {
+			return err
+		}
+		return bucket.Put([]byte(lock.Token), buf)
+	})
+}
+
+func (p *BoltProvider) updateWebDAVLock(lock *WebDAVLock) error {
+	if err := lock.validate(); err != nil {
+		return err
+	}
+	return p.dbHandle.Update(func(tx *bolt.Tx) error {
+		bucket, err := getWebDAVLocksBucket(tx)
+		if err != nil {
+			return err
+		}
+		var l WebDAVLock
+		v := bucket.Get([]byte(lock.Token))
+		if v == nil {
+			return util.NewRecordNotFoundError(fmt.Sprintf("WebDAV lock %#v does not exist", lock.Token))
+		}
+		if err := json.Unmarshal(v, &l); err != nil {
+			return err
+		}
+		l.Duration = lock.Duration
+		l.ExpiresAt = lock.ExpiresAt
+		l.UpdatedAt = lock.UpdatedAt
+		buf, err := json.Marshal(l)
+		if err != nil {
+			return err
+		}
+		return bucket.Put([]byte(l.Token), buf)
+	})
+}
+
+func (p *BoltProvider) deleteWebDAVLock(token string) error {
+	return p.dbHandle.Update(func(tx *bolt.Tx) error {
+		bucket, err := getWebDAVLocksBucket(tx)
+		if err != nil {
+			return err
+		}
+		if bucket.Get([]byte(token)) == nil {
+			return util.NewRecordNotFoundError(fmt.Sprintf("WebDAV lock %#v does not exist", token))
+		}
+		return bucket.Delete([]byte(token))
+	})
+}
+
+func (p *BoltProvider) getWebDAVLocks(username string) ([]WebDAVLock, error) {
+	locks := make([]WebDAVLock, 0, 10)
+
+	err := p.dbHandle.View(func(tx *bolt.Tx) error {
+		bucket, err := getWebDAVLocksBucket(tx)
+		if err != nil {
+			return err
+		}
+		cursor := bucket.Cursor()
+		for k, v := cursor.First(); k != nil; k, v = cursor.Next() {
+			var lock WebDAVLock
+			if err := json.Unmarshal(v, &lock); err != nil {
+				return err
+			}
+			if lock.Username == username {
+				locks = append(locks, lock)
+			}
+		}
+		return nil
+	})
+
+	return locks, err
+}
+
+func (p *BoltProvider) deleteUserWebDAVLocks(username string) error {
+	return p.deleteWebDAVLocks(func(lock *WebDAVLock) bool {
+		return lock.Username == username
+	})
+}
+
+func (p *BoltProvider) cleanupWebDAVLocks(before int64) error {
+	return p.deleteWebDAVLocks(func(lock *WebDAVLock) bool {
+		return lock.ExpiresAt > 0 && lock.ExpiresAt <= before
+	})
+}
+
+func (p *BoltProvider) deleteWebDAVLocks(shouldDelete func(lock *WebDAVLock) bool) error {
+	return p.dbHandle.Update(func(tx *bolt.Tx) error {
+		bucket, err := getWebDAVLocksBucket(tx)
+		if err != nil {
+			return err
+		}
+		var keys [][]byte
+		cursor := bucket.Cursor()
+		for k, v := cursor.First(); k != nil; k, v = cursor.Next() {
+			var lock WebDAVLock
+			if err := json.Unmarshal(v, &lock); err != nil {
+				return err
+			}
+			if shouldDelete(&lock) {
+				keys = append(keys, k)
+			}
+		}
+		for _, k := range keys {
+			if err := bucket.Delete(k); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (p *BoltProvider) webDAVPropertiesExists(username, virtualPath string) (WebDAVProperties, error) {
+	var props WebDAVProperties
+
+	err := p.dbHandle.View(func(tx *bolt.Tx) error {
+		bucket, err := getWebDAVPropertiesBucket(tx)
+		if err != nil {
+			return err
+		}
+		v := bucket.Get([]byte(getWebDAVPropertiesKey(username, virtualPath)))
+		if v == nil {
+			return util.NewRecordNotFoundError(fmt.Sprintf("WebDAV properties for %#v do not exist", virtualPath))
+		}
+		return json.Unmarshal(v, &props)
+	})
+
+	return props, err
+}
+
+func (p *BoltProvider) setWebDAVProperties(props *WebDAVProperties) error {
+	if err := props.validate(); err != nil {
+		return err
+	}
+	return p.dbHandle.Update(func(tx *bolt.Tx) error {
+		bucket, err := getWebDAVPropertiesBucket(tx)
+		if err != nil {
+			return err
+		}
+		buf, err := json.Marshal(props)
+		if err != nil {
+			return err
+		}
+		return bucket.Put([]byte(props.getKey()), buf)
+	})
+}
+
+func (p *BoltProvider) deleteWebDAVProperties(username, virtualPath string) error {
+	return p.dbHandle.Update(func(tx *bolt.Tx) error {
+		bucket, err := getWebDAVPropertiesBucket(tx)
+		if err != nil {
+			return err
+		}
+		_, err = deleteBoltWebDAVPropertiesTree(bucket, username, virtualPath)
+		return err
+	})
+}
+
+func (p *BoltProvider) renameWebDAVProperties(username, source, target string) error {
+	return p.dbHandle.Update(func(tx *bolt.Tx) error {
+		bucket, err := getWebDAVPropertiesBucket(tx)
+		if err != nil {
+			return err
+		}
+		if _, err := deleteBoltWebDAVPropertiesTree(bucket, username, target); err != nil {
+			return err
+		}
+		renamed, err := deleteBoltWebDAVPropertiesTree(bucket, username, source)
+		if err != nil {
+			return err
+		}
+		for _, props := range renamed {
+			props.Path = getWebDAVPropertiesRenamedPath(props.Path, source, target)
+			buf, err := json.Marshal(props)
+			if err != nil {
+				return err
+			}
+			if err := bucket.Put([]byte(props.getKey()), buf); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (p *BoltProvider) deleteUserWebDAVProperties(username string) error {
+	return p.dbHandle.Update(func(tx *bolt.Tx) error {
+		bucket, err := getWebDAVPropertiesBucket(tx)
+		if err != nil {
+			return err
+		}
+		_, err = deleteBoltWebDAVPropertiesTree(bucket, username, "/")
+		return err
+	})
+}
+
+func (p *BoltProvider) s3AccessKeyExists(accessKeyID string) (S3AccessKey, error) {
+	var key S3AccessKey
+
+	err := p.dbHandle.View(func(tx *bolt.Tx) error {
+		bucket, err := getS3AccessKeysBucket(tx)
+		if err != nil {
+			return err
+		}
+		v := bucket.Get([]byte(accessKeyID))
+		if v == nil {
+			return util.NewRecordNotFoundError(fmt.Sprintf("S3 access key %#v does not exist", accessKeyID))
+		}
+		return json.Unmarshal(v, &key)
+	})
+
+	return key, err
+}
+
+func (p *BoltProvider) addS3AccessKey(key *S3AccessKey) error {
+	if err := key.validate(); err != nil {
+		return err
+	}
+	return p.dbHandle.Update(func(tx *bolt.Tx) error {
+		bucket, err := getS3AccessKeysBucket(tx)
+		if err != nil {
+			return err
+		}
+		if bucket.Get([]byte(key.AccessKeyID)) != nil {
+			return fmt.Errorf("S3 access key %#v already exists", key.AccessKeyID)
+		}
+		buf, err := json.Marshal(key)
+		if err != nil {
+			return err
+		}
+		return bucket.Put([]byte(key.AccessKeyID), buf)
+	})
+}
+
+func (p *BoltProvider) deleteS3AccessKey(accessKeyID, username string) error {
+	return p.dbHandle.Update(func(tx *bolt.Tx) error {
+		bucket, err := getS3AccessKeysBucket(tx)
+		if err != nil {
+			return err
+		}
+		var key S3AccessKey
+		v := bucket.Get([]byte(accessKeyID))
+		if v != nil {
+			if err := json.Unmarshal(v, &key); err != nil {
+				return err
+			}
+		}
+		if v == nil || key.Username != username {
+			return util.NewRecordNotFoundError(fmt.Sprintf("S3 access key %#v does not exist", accessKeyID))
+		}
+		return bucket.Delete([]byte(accessKeyID))
+	})
+}
+
+func (p *BoltProvider) getUserS3AccessKeys(username string) ([]S3AccessKey, error) {
+	keys := make([]S3AccessKey, 0, maxS3AccessKeysPerUser)
+
+	err := p.dbHandle.View(func(tx *bolt.Tx) error {
+		bucket, err := getS3AccessKeysBucket(tx)
+		if err != nil {
+			return err
+		}
+		cursor := bucket.Cursor()
+		for k, v := cursor.First(); k != nil; k, v = cursor.Next() {
+			var key S3AccessKey
+			if err := json.Unmarshal(v, &key); err != nil {
+				return err
+			}
+			if key.Username == username {
+				keys = append(keys, key)
+			}
+		}
+		return nil
+	})
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].CreatedAt < keys[j].CreatedAt
+	})
+
+	return keys, err
+}
+
+func (p *BoltProvider) deleteUserS3AccessKeys(username string) error {
+	return p.dbHandle.Update(func(tx *bolt.Tx) error {
+		bucket, err := getS3AccessKeysBucket(tx)
+		if err != nil {
+			return err
+		}
+		var keys [][]byte
+		cursor := bucket.Cursor()
+		for k, v := cursor.First(); k != nil; k, v = cursor.Next() {
+			var key S3AccessKey
+			if err := json.Unmarshal(v, &key); err != nil {
+				return err
+			}
+			if key.Username == username {
+				keys = append(keys, k)
+			}
+		}
+		for _, k := range keys {
+			if err := bucket.Delete(k); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (p *BoltProvider) updateS3AccessKey(key *S3AccessKey) error {
+	if err := key.validate(); err != nil {
+		return err
+	}
+	return p.dbHandle.Update(func(tx *bolt.Tx) error {
+		bucket, err := getS3AccessKeysBucket(tx)
+		if err != nil {
+			return err
+		}
+		if bucket.Get([]byte(key.AccessKeyID)) == nil {
+			return util.NewRecordNotFoundError(fmt.Sprintf("S3 access key %#v does not exist", key.AccessKeyID))
+		}
+		buf, err := json.Marshal(key)
+		if err != nil {
+			return err
+		}
+		return bucket.Put([]byte(key.AccessKeyID), buf)
+	})
+}
+
+func (p *BoltProvider) dumpS3AccessKeys() ([]S3AccessKey, error) {
+	keys := make([]S3AccessKey, 0, 30)
+
+	err := p.dbHandle.View(func(tx *bolt.Tx) error {
+		bucket, err := getS3AccessKeysBucket(tx)
+		if err != nil {
+			return err
+		}
+		cursor := bucket.Cursor()
+		for k, v := cursor.First(); k != nil; k, v = cursor.Next() {
+			var key S3AccessKey
+			if err := json.Unmarshal(v, &key); err != nil {
+				return err
+			}
+			keys = append(keys, key)
+		}
+		return nil
+	})
+
+	return keys, err
+}
+
+func (p *BoltProvider) tusUploadExists(uploadID string) (TusUpload, error) {
+	var upload TusUpload
+
+	err := p.dbHandle.View(func(tx *bolt.Tx) error {
+		bucket, err := getTusUploadsBucket(tx)
+		if err != nil {
+			return err
+		}
+		v := bucket.Get([]byte(uploadID))
+		if v == nil {
+			return util.NewRecordNotFoundError(fmt.Sprintf("upload %#v does not exist", uploadID))
+		}
+		return json.Unmarshal(v, &upload)
+	})
+
+	return upload, err
+}
+
+func (p *BoltProvider) addTusUpload(upload *TusUpload) error {
+	if err := upload.validate(); err != nil {
+		return err
+	}
+	return p.dbHandle.Update(func(tx *bolt.Tx) error {
+		bucket, err := getTusUploadsBucket(tx)
+		if err != nil {
+			return err
+		}
+		if bucket.Get([]byte(upload.ID)) != nil {
+			return fmt.Errorf("upload %#v already exists", upload.ID)
+		}
+		buf, err := json.Marshal(upload)
+		if err != nil {
+			return err
+		}
+		return bucket.Put([]byte(upload.ID), buf)
+	})
+}
+
+func (p *BoltProvider) updateTusUploadOffset(uploadID string, offset, expiresAt, updatedAt int64) error {
+	return p.dbHandle.Update(func(tx *bolt.Tx) error {
+		bucket, err := getTusUploadsBucket(tx)
+		if err != nil {
+			return err
+		}
+		v := bucket.Get([]byte(uploadID))
+		if v == nil {
+			return util.NewRecordNotFoundError(fmt.Sprintf("upload %#v does not exist", uploadID))
+		}
+		var upload TusUpload
+		if err := json.Unmarshal(v, &upload); err != nil {
+			return err
+		}
+		upload.Offset = offset
+		upload.ExpiresAt = expiresAt
+		upload.UpdatedAt = updatedAt
+		buf, err := json.Marshal(upload)
+		if err != nil {
+			return err
+		}
+		return bucket.Put([]byte(uploadID), buf)
+	})
+}
+
+func (p *BoltProvider) deleteTusUpload(uploadID string) error {
+	return p.dbHandle.Update(func(tx *bolt.Tx) error {
+		bucket, err := getTusUploadsBucket(tx)
+		if err != nil {
+			return err
+		}
+		if bucket.Get([]byte(uploadID)) == nil {
+			return util.NewRecordNotFoundError(fmt.Sprintf("upload %#v does not exist", uploadID))
+		}
+		return bucket.Delete([]byte(uploadID))
+	})
+}
+
+func (p *BoltProvider) getTusUploadsCount(username string) (int, error) {
+	count := 0
+
+	err := p.dbHandle.View(func(tx *bolt.Tx) error {
+		bucket, err := getTusUploadsBucket(tx)
+		if err != nil {
+			return err
+		}
+		cursor := bucket.Cursor()
+		for k, v := cursor.First(); k != nil; k, v = cursor.Next() {
+			var upload TusUpload
+			if err := json.Unmarshal(v, &upload); err != nil {
+				return err
+			}
+			if upload.Username == username {
+				count++
+			}
+		}
+		return nil
+	})
+
+	return count, err
+}
+
+func (p *BoltProvider) getExpiredTusUploads(before int64) ([]TusUpload, error) {
+	uploads := make([]TusUpload, 0, 10)
+
+	err := p.dbHandle.View(func(tx *bolt.Tx) error {
+		bucket, err := getTusUploadsBucket(tx)
+		if err != nil {
+			return err
+		}
+		cursor := bucket.Cursor()
+		for k, v := cursor.First(); k != nil; k, v = cursor.Next() {
+			var upload TusUpload
+			if err := json.Unmarshal(v, &upload); err != nil {
+				return err
+			}
+			if upload.ExpiresAt <= before {
+				uploads = append(uploads, upload)
+			}
+		}
+		return nil
+	})
+	sort.Slice(uploads, func(i, j int) bool {
+		return uploads[i].ExpiresAt < uploads[j].ExpiresAt
+	})
+
+	return uploads, err
+}
+
+func (p *BoltProvider) deleteUserTusUploads(username string) error {
+	return p.dbHandle.Update(func(tx *bolt.Tx) error {
+		bucket, err := getTusUploadsBucket(tx)
+		if err != nil {
+			return err
+		}
+		var keys [][]byte
+		cursor := bucket.Cursor()
+		for k, v := cursor.First(); k != nil; k, v = cursor.Next() {
+			var upload TusUpload
+			if err := json.Unmarshal(v, &upload); err != nil {
+				return err
+			}
+			if upload.Username == username {
+				keys = append(keys, k)
+			}
+		}
+		for _, k := range keys {
+			if err := bucket.Delete(k); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (p *BoltProvider) resetCodeExists(code string) (ResetCode, error) {
+	var resetCode ResetCode
+
+	err := p.dbHandle.View(func(tx *bolt.Tx) error {
+		bucket, err := getResetCodesBucket(tx)
+		if err != nil {
+			return err
+		}
+		v := bucket.Get([]byte(code))
+		if v == nil {
+			return util.NewRecordNotFoundError("reset code does not exist")
+		}
+		return json.Unmarshal(v, &resetCode)
+	})
+
+	return resetCode, err
+}
+
+func (p *BoltProvider) addResetCode(code *ResetCode) error {
+	if err := code.validate(); err != nil {
+		return err
+	}
+	return p.dbHandle.Update(func(tx *bolt.Tx) error {
+		bucket, err := getResetCodesBucket(tx)
+		if err != nil {
+			return err
+		}
+		if bucket.Get([]byte(code.Code)) != nil {
+			return errors.New("reset code already exists")
+		}
+		buf, err := json.Marshal(code)
+		if err != nil {
+			return err
+		}
+		return bucket.Put([]byte(code.Code), buf)
+	})
+}
+
+func (p *BoltProvider) deleteResetCode(code string) error {
+	return p.dbHandle.Update(func(tx *bolt.Tx) error {
+		bucket, err := getResetCodesBucket(tx)
+		if err != nil {
+			return err
+		}
+		if bucket.Get([]byte(code)) == nil {
+			return util.NewRecordNotFoundError("reset code does not exist")
+		}
+		return bucket.Delete([]byte(code))
+	})
+}
+
+func (p *BoltProvider) getResetCodesCount(username, accountType string, after int64) (int, error) {
+	count := 0
+
+	err := p.dbHandle.View(func(tx *bolt.Tx) error {
+		bucket, err := getResetCodesBucket(tx)
+		if err != nil {
+			return err
+		}
+		cursor := bucket.Cursor()
+		for k, v := cursor.First(); k != nil; k, v = cursor.Next() {
+			var code ResetCode
+			if err := json.Unmarshal(v, &code); err != nil {
+				return err
+			}
+			if code.Username == username && code.Type == accountType && code.ExpiresAt > after {
+				count++
+			}
+		}
+		return nil
+	})
+
+	return count, err
+}
+
+func (p *BoltProvider) deleteExpiredResetCodes(before int64) error {
+	return p.dbHandle.Update(func(tx *bolt.Tx) error {
+		bucket, err := getResetCodesBucket(tx)
+		if err != nil {
+			return err
+		}
+		var keys [][]byte
+		cursor := bucket.Cursor()
+		for k, v := cursor.First(); k != nil; k, v = cursor.Next() {
+			var code ResetCode
+			if err := json.Unmarshal(v, &code); err != nil {
+				return err
+			}
+			if code.ExpiresAt <= before {
+				keys = append(keys, k)
+			}
+		}
+		for _, k := range keys {
+			if err := bucket.Delete(k); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (p *BoltProvider) multipartUploadExists(key string) (vfs.MultipartUpload, error) {
+	var upload vfs.MultipartUpload
+
+	err := p.dbHandle.View(func(tx *bolt.Tx) error {
+		bucket, err := getMultipartUploadsBucket(tx)
+		if err != nil {
+			return err
+		}
+		v := bucket.Get([]byte(key))
+		if v == nil {
+			return util.NewRecordNotFoundError("multipart upload does not exist")
+		}
+		return json.Unmarshal(v, &upload)
+	})
+
+	return upload, err
+}
+
+func (p *BoltProvider) saveMultipartUpload(upload *vfs.MultipartUpload) error {
+	if err := validateMultipartUpload(upload); err != nil {
+		return err
+	}
+	return p.dbHandle.Update(func(tx *bolt.Tx) error {
+		bucket, err := getMultipartUploadsBucket(tx)
+		if err != nil {
+			return err
+		}
+		stored := *upload
+		if v := bucket.Get([]byte(upload.Key)); v != nil {
+			var oldUpload vfs.MultipartUpload
+			if err := json.Unmarshal(v, &oldUpload); err != nil {
+				return err
+			}
+			stored.CreatedAt = oldUpload.CreatedAt
+		}
+		buf, err := json.Marshal(stored)
+		if err != nil {
+			return err
+		}
+		return bucket.Put([]byte(upload.Key), buf)
+	})
+}
+
+func (p *BoltProvider) deleteMultipartUpload(key string) error {
+	return p.dbHandle.Update(func(tx *bolt.Tx) error {
+		bucket, err := getMultipartUploadsBucket(tx)
+		if err != nil {
+			return err
+		}
+		if bucket.Get([]byte(key)) == nil {
+			return util.NewRecordNotFoundError("multipart upload does not exist")
+		}
+		return bucket.Delete([]byte(key))
+	})
+}
+
+func (p *BoltProvider) cleanupMultipartUploads(before int64) error {
+	return p.dbHandle.Update(func(tx *bolt.Tx) error {
+		bucket, err := getMultipartUploadsBucket(tx)
+		if err != nil {
+			return err
+		}
+		var keys [][]byte
+		cursor := bucket.Cursor()
+		for k, v := cursor.First(); k != nil; k, v = cursor.Next() {
+			var upload vfs.MultipartUpload
+			if err := json.Unmarshal(v, &upload); err != nil {
+				return err
+			}
+			if upload.UpdatedAt <= before {
+				keys = append(keys, k)
+			}
+		}
+		for _, k := range keys {
+			if err := bucket.Delete(k); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (p *BoltProvider) addFsEvent(event *FsEvent) error {
+	return p.dbHandle.Update(func(tx *bolt.Tx) error {
+		bucket, err := getEventsBucket(tx, fsEventsBucket)
+		if err != nil {
+			return err
+		}
+		buf, err := json.Marshal(event)
+		if err != nil {
+			return err
+		}
+		return bucket.Put(getBoltEventKey(event.Timestamp, event.ID), buf)
+	})
+}
+
+func (p *BoltProvider) addProviderEvent(event *ProviderEvent) error {
+	return p.dbHandle.Update(func(tx *bolt.Tx) error {
+		bucket, err := getEventsBucket(tx, providerEventsBucket)
+		if err != nil {
+			return err
+		}
+		buf, err := json.Marshal(event)
+		if err != nil {
+			return err
+		}
+		return bucket.Put(getBoltEventKey(event.Timestamp, event.ID), buf)
+	})
+}
+
+func (p *BoltProvider) searchFsEvents(search *FsEventSearch) ([]FsEvent, error) {
+	events := make([]FsEvent, 0, search.Limit)
+
+	err := p.dbHandle.View(func(tx *bolt.Tx) error {
+		bucket, err := getEventsBucket(tx, fsEventsBucket)
+		if err != nil {
+			return err
+		}
+		return iterateBoltEvents(bucket, &search.eventSearch, func(v []byte) (bool, error) {
+			var event FsEvent
+			if err := json.Unmarshal(v, &event); err != nil {
+				return false, err
+			}
+			if search.matches(&event) {
+				events = append(events, event)
+			}
+			return len(events) < search.Limit, nil
+		})
+	})
+
+	return events, err
+}
+
+func (p *BoltProvider) searchProviderEvents(search *ProviderEventSearch) ([]ProviderEvent, error) {
+	events := make([]ProviderEvent, 0, search.Limit)
+
+	err := p.dbHandle.View(func(tx *bolt.Tx) error {
+		bucket, err := getEventsBucket(tx, providerEventsBucket)
+		if err != nil {
+			return err
+		}
+		return iterateBoltEvents(bucket, &search.eventSearch, func(v []byte) (bool, error) {
+			var event ProviderEvent
+			if err := json.Unmarshal(v, &event); err != nil {
+				return false, err
+			}
+			if search.matches(&event) {
+				events = append(events, event)
+			}
+			return len(events) < search.Limit, nil
+		})
+	})
+
+	return events, err
+}
+
+func (p *BoltProvider) cleanupEvents(before int64) error {
+	return p.dbHandle.Update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{fsEventsBucket, providerEventsBucket} {
+			bucket, err := getEventsBucket(tx, name)
+			if err != nil {
+				return err
+			}
+			var keys [][]byte
+			maxKey := getBoltEventKey(before, "")
+			cursor := bucket.Cursor()
+			for k, _ := cursor.First(); k != nil && bytes.Compare(k, maxKey) < 0; k, _ = cursor.Next() {
+				keys = append(keys, k)
+			}
+			for _, k := range keys {
+				if err := bucket.Delete(k); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
+
+func (p *BoltProvider) eventRuleExists(name string) (EventRule, error) {
+	var rule EventRule
+	err := p.dbHandle.View(func(tx *bolt.Tx) error {
+		bucket, err := getEventRulesBucket(tx)
+		if err != nil {
+			return err
+		}
+		r := bucket.Get([]byte(name))
+		if r == nil {
+			return util.NewRecordNotFoundError(fmt.Sprintf("event rule %#v does not exist", name))
+		}
+		return json.Unmarshal(r, &rule)
+	})
+	return rule, err
+}
+
+func (p *BoltProvider) addEventRule(rule *EventRule) error {
+	if err := rule.validate(); err != nil {
+		return err
+	}
+	return p.dbHandle.Update(func(tx *bolt.Tx) error {
+		bucket, err := getEventRulesBucket(tx)
+		if err != nil {
+			return err
+		}
+		if r := bucket.Get([]byte(rule.Name)); r != nil {
+			return fmt.Errorf("event rule %v already exists", rule.Name)
+		}
+		id, err := bucket.NextSequence()
+		if err != nil {
+			return err
+		}
+		rule.ID = int64(id)
+		rule.CreatedAt = util.GetTimeAsMsSinceEpoch(time.Now())
+		rule.UpdatedAt = util.GetTimeAsMsSinceEpoch(time.Now())
+		buf, err := json.Marshal(rule)
 		if err != nil {
 			return err
 		}
+		return bucket.Put([]byte(rule.Name), buf)
+	})
+}
 
-		apiKey.ID = oldAPIKey.ID
-		apiKey.KeyID = oldAPIKey.KeyID
-		apiKey.Key = oldAPIKey.Key
-		apiKey.CreatedAt = oldAPIKey.CreatedAt
-		apiKey.LastUseAt = oldAPIKey.LastUseAt
-		apiKey.UpdatedAt = util.GetTimeAsMsSinceEpoch(time.Now())
-		buf, err := json.Marshal(apiKey)
+func (p *BoltProvider) updateEventRule(rule *EventRule) error {
+	if err := rule.validate(); err != nil {
+		return err
+	}
+	return p.dbHandle.Update(func(tx *bolt.Tx) error {
+		bucket, err := getEventRulesBucket(tx)
 		if err != nil {
 			return err
 		}
-		return bucket.Put([]byte(apiKey.KeyID), buf)
+		var oldRule EventRule
+		r := bucket.Get([]byte(rule.Name))
+		if r == nil {
+			return util.NewRecordNotFoundError(fmt.Sprintf("event rule %#v does not exist", rule.Name))
+		}
+		if err := json.Unmarshal(r, &oldRule); err != nil {
+			return err
+		}
+		rule.ID = oldRule.ID
+		rule.CreatedAt = oldRule.CreatedAt
+		rule.UpdatedAt = util.GetTimeAsMsSinceEpoch(time.Now())
+		buf, err := json.Marshal(rule)
+		if err != nil {
+			return err
+		}
+		return bucket.Put([]byte(rule.Name), buf)
 	})
 }
 
-func (p *BoltProvider) deleteAPIKeys(apiKey *APIKey) error {
+func (p *BoltProvider) deleteEventRule(rule *EventRule) error {
 	return p.dbHandle.Update(func(tx *bolt.Tx) error {
-		bucket, err := getAPIKeysBucket(tx)
+		bucket, err := getEventRulesBucket(tx)
 		if err != nil {
 			return err
 		}
-
-		if bucket.Get([]byte(apiKey.KeyID)) == nil {
-			return util.NewRecordNotFoundError(fmt.Sprintf("API key %v does not exist", apiKey.KeyID))
+		if bucket.Get([]byte(rule.Name)) == nil {
+			return util.NewRecordNotFoundError(fmt.Sprintf("event rule %#v does not exist", rule.Name))
 		}
-
-		return bucket.Delete([]byte(apiKey.KeyID))
+		return bucket.Delete([]byte(rule.Name))
 	})
 }
 
-func (p *BoltProvider) getAPIKeys(limit int, offset int, order string) ([]APIKey, error) {
-	apiKeys := make([]APIKey, 0, limit)
-
+func (p *BoltProvider) getEventRules(limit, offset int, order string) ([]EventRule, error) {
+	rules := make([]EventRule, 0, limit)
+	if limit <= 0 {
+		return rules, nil
+	}
 	err := p.dbHandle.View(func(tx *bolt.Tx) error {
-		bucket, err := getAPIKeysBucket(tx)
+		bucket, err := getEventRulesBucket(tx)
 		if err != nil {
 			return err
 		}
@@ -1069,62 +3104,267 @@ func (p *BoltProvider) getAPIKeys(limit int, offset int, order string) ([]APIKey
 				if itNum <= offset {
 					continue
 				}
-				var apiKey APIKey
-				err = json.Unmarshal(v, &apiKey)
-				if err != nil {
+				var rule EventRule
+				if err := json.Unmarshal(v, &rule); err != nil {
 					return err
 				}
-				apiKey.HideConfidentialData()
-				apiKeys = append(apiKeys, apiKey)
-				if len(apiKeys) >= limit {
+				rules = append(rules, rule)
+				if len(rules) >= limit {
 					break
 				}
 			}
-			return nil
-		}
-		for k, v := cursor.Last(); k != nil; k, v = cursor.Prev() {
-			itNum++
-			if itNum <= offset {
-				continue
+		} else {
+			for k, v := cursor.Last(); k != nil; k, v = cursor.Prev() {
+				itNum++
+				if itNum <= offset {
+					continue
+				}
+				var rule EventRule
+				if err := json.Unmarshal(v, &rule); err != nil {
+					return err
+				}
+				rules = append(rules, rule)
+				if len(rules) >= limit {
+					break
+				}
 			}
-			var apiKey APIKey
-			err = json.Unmarshal(v, &apiKey)
-			if err != nil {
+		}
+		return nil
+	})
+	return rules, err
+}
+
+func (p *BoltProvider) dumpEventRules() ([]EventRule, error) {
+	rules := make([]EventRule, 0, 20)
+	err := p.dbHandle.View(func(tx *bolt.Tx) error {
+		bucket, err := getEventRulesBucket(tx)
+		if err != nil {
+			return err
+		}
+		cursor := bucket.Cursor()
+		for k, v := cursor.First(); k != nil; k, v = cursor.Next() {
+			var rule EventRule
+			if err := json.Unmarshal(v, &rule); err != nil {
 				return err
 			}
-			apiKey.HideConfidentialData()
-			apiKeys = append(apiKeys, apiKey)
-			if len(apiKeys) >= limit {
-				break
-			}
+			rules = append(rules, rule)
 		}
 		return nil
 	})
+	return rules, err
+}
 
-	return apiKeys, err
+func (p *BoltProvider) scheduleExists(name string) (Schedule, error) {
+	var schedule Schedule
+	err := p.dbHandle.View(func(tx *bolt.Tx) error {
+		bucket, err := getSchedulesBucket(tx)
+		if err != nil {
+			return err
+		}
+		s := bucket.Get([]byte(name))
+		if s == nil {
+			return util.NewRecordNotFoundError(fmt.Sprintf("schedule %#v does not exist", name))
+		}
+		return json.Unmarshal(s, &schedule)
+	})
+	return schedule, err
 }
 
-func (p *BoltProvider) dumpAPIKeys() ([]APIKey, error) {
-	apiKeys := make([]APIKey, 0, 30)
+func (p *BoltProvider) addSchedule(schedule *Schedule) error {
+	if err := schedule.validate(); err != nil {
+		return err
+	}
+	return p.dbHandle.Update(func(tx *bolt.Tx) error {
+		bucket, err := getSchedulesBucket(tx)
+		if err != nil {
+			return err
+		}
+		if s := bucket.Get([]byte(schedule.Name)); s != nil {
+			return fmt.Errorf("schedule %v already exists", schedule.Name)
+		}
+		id, err := bucket.NextSequence()
+		if err != nil {
+			return err
+		}
+		schedule.ID = int64(id)
+		schedule.CreatedAt = util.GetTimeAsMsSinceEpoch(time.Now())
+		schedule.UpdatedAt = util.GetTimeAsMsSinceEpoch(time.Now())
+		schedule.LastRun = 0
+		schedule.LastRunStatus = ScheduleRunStatusNone
+		schedule.LastRunError = ""
+		buf, err := json.Marshal(schedule)
+		if err != nil {
+			return err
+		}
+		return bucket.Put([]byte(schedule.Name), buf)
+	})
+}
+
+func (p *BoltProvider) updateSchedule(schedule *Schedule) error {
+	if err := schedule.validate(); err != nil {
+		return err
+	}
+	return p.dbHandle.Update(func(tx *bolt.Tx) error {
+		bucket, err := getSchedulesBucket(tx)
+		if err != nil {
+			return err
+		}
+		var oldSchedule Schedule
+		s := bucket.Get([]byte(schedule.Name))
+		if s == nil {
+			return util.NewRecordNotFoundError(fmt.Sprintf("schedule %#v does not exist", schedule.Name))
+		}
+		if err := json.Unmarshal(s, &oldSchedule); err != nil {
+			return err
+		}
+		schedule.ID = oldSchedule.ID
+		schedule.CreatedAt = oldSchedule.CreatedAt
+		schedule.UpdatedAt = util.GetTimeAsMsSinceEpoch(time.Now())
+		schedule.LastRun = oldSchedule.LastRun
+		schedule.LastRunStatus = oldSchedule.LastRunStatus
+		schedule.LastRunError = oldSchedule.LastRunError
+		buf, err := json.Marshal(schedule)
+		if err != nil {
+			return err
+		}
+		return bucket.Put([]byte(schedule.Name), buf)
+	})
+}
+
+func (p *BoltProvider) deleteSchedule(schedule *Schedule) error {
+	return p.dbHandle.Update(func(tx *bolt.Tx) error {
+		bucket, err := getSchedulesBucket(tx)
+		if err != nil {
+			return err
+		}
+		if bucket.Get([]byte(schedule.Name)) == nil {
+			return util.NewRecordNotFoundError(fmt.Sprintf("schedule %#v does not exist", schedule.Name))
+		}
+		return bucket.Delete([]byte(schedule.Name))
+	})
+}
+
+func (p *BoltProvider) getSchedules(limit, offset int, order string) ([]Schedule, error) {
+	schedules := make([]Schedule, 0, limit)
+	if limit <= 0 {
+		return schedules, nil
+	}
 	err := p.dbHandle.View(func(tx *bolt.Tx) error {
-		bucket, err := getAPIKeysBucket(tx)
+		bucket, err := getSchedulesBucket(tx)
 		if err != nil {
 			return err
 		}
+		cursor := bucket.Cursor()
+		itNum := 0
+		if order == OrderASC {
+			for k, v := cursor.First(); k != nil; k, v = cursor.Next() {
+				itNum++
+				if itNum <= offset {
+					continue
+				}
+				var schedule Schedule
+				if err := json.Unmarshal(v, &schedule); err != nil {
+					return err
+				}
+				schedules = append(schedules, schedule)
+				if len(schedules) >= limit {
+					break
+				}
+			}
+		} else {
+			for k, v := cursor.Last(); k != nil; k, v = cursor.Prev() {
+				itNum++
+				if itNum <= offset {
+					continue
+				}
+				var schedule Schedule
+				if err := json.Unmarshal(v, &schedule); err != nil {
+					return err
+				}
+				schedules = append(schedules, schedule)
+				if len(schedules) >= limit {
+					break
+				}
+			}
+		}
+		return nil
+	})
+	return schedules, err
+}
 
+func (p *BoltProvider) dumpSchedules() ([]Schedule, error) {
+	schedules := make([]Schedule, 0, 10)
+	err := p.dbHandle.View(func(tx *bolt.Tx) error {
+		bucket, err := getSchedulesBucket(tx)
+		if err != nil {
+			return err
+		}
 		cursor := bucket.Cursor()
 		for k, v := cursor.First(); k != nil; k, v = cursor.Next() {
-			var apiKey APIKey
-			err = json.Unmarshal(v, &apiKey)
-			if err != nil {
+			var schedule Schedule
+			if err := json.Unmarshal(v, &schedule); err != nil {
 				return err
 			}
-			apiKeys = append(apiKeys, apiKey)
+			schedules = append(schedules, schedule)
 		}
-		return err
+		return nil
 	})
+	return schedules, err
+}
 
-	return apiKeys, err
+func (p *BoltProvider) claimScheduleRun(name string, runAt int64) (bool, error) {
+	claimed := false
+	err := p.dbHandle.Update(func(tx *bolt.Tx) error {
+		bucket, err := getSchedulesBucket(tx)
+		if err != nil {
+			return err
+		}
+		var schedule Schedule
+		s := bucket.Get([]byte(name))
+		if s == nil {
+			return util.NewRecordNotFoundError(fmt.Sprintf("schedule %#v does not exist", name))
+		}
+		if err := json.Unmarshal(s, &schedule); err != nil {
+			return err
+		}
+		if schedule.LastRun >= runAt {
+			return nil
+		}
+		schedule.LastRun = runAt
+		schedule.LastRunStatus = ScheduleRunStatusRunning
+		schedule.LastRunError = ""
+		buf, err := json.Marshal(schedule)
+		if err != nil {
+			return err
+		}
+		claimed = true
+		return bucket.Put([]byte(name), buf)
+	})
+	return claimed, err
+}
+
+func (p *BoltProvider) setScheduleRunResult(name string, status int, runError string) error {
+	return p.dbHandle.Update(func(tx *bolt.Tx) error {
+		bucket, err := getSchedulesBucket(tx)
+		if err != nil {
+			return err
+		}
+		var schedule Schedule
+		s := bucket.Get([]byte(name))
+		if s == nil {
+			return util.NewRecordNotFoundError(fmt.Sprintf("schedule %#v does not exist", name))
+		}
+		if err := json.Unmarshal(s, &schedule); err != nil {
+			return err
+		}
+		schedule.LastRunStatus = status
+		schedule.LastRunError = runError
+		buf, err := json.Marshal(schedule)
+		if err != nil {
+			return err
+		}
+		return bucket.Put([]byte(name), buf)
+	})
 }
 
 func (p *BoltProvider) close() error {
@@ -1155,11 +3395,21 @@ func (p *BoltProvider) migrateDatabase() error {
 		logger.ErrorToConsole("%v", err)
 		return err
 	case version == 10:
-		return updateBoltDatabaseVersion(p.dbHandle, 13)
+		return updateBoltDatabaseToV18(p.dbHandle)
 	case version == 11:
-		return updateBoltDatabaseVersion(p.dbHandle, 13)
+		return updateBoltDatabaseToV18(p.dbHandle)
 	case version == 12:
-		return updateBoltDatabaseVersion(p.dbHandle, 13)
+		return updateBoltDatabaseToV18(p.dbHandle)
+	case version == 13:
+		return updateBoltDatabaseToV18(p.dbHandle)
+	case version == 14:
+		return updateBoltDatabaseToV18(p.dbHandle)
+	case version == 15:
+		return updateBoltDatabaseToV18(p.dbHandle)
+	case version == 16:
+		return updateBoltDatabaseToV18(p.dbHandle)
+	case version == 17:
+		return updateBoltDatabaseToV18(p.dbHandle)
 	default:
 		if version > boltDatabaseVersion {
 			providerLog(logger.LevelWarn, "database version %v is newer than the supported one: %v", version,
@@ -1181,6 +3431,16 @@ func (p *BoltProvider) revertDatabase(targetVersion int) error {
 		return errors.New("current version match target version, nothing to do")
 	}
 	switch dbVersion.Version {
+	case 18:
+		return downgradeBoltDatabaseFrom18To10(p.dbHandle)
+	case 17:
+		return downgradeBoltDatabaseFrom18To10(p.dbHandle)
+	case 16:
+		return downgradeBoltDatabaseFrom18To10(p.dbHandle)
+	case 15:
+		return downgradeBoltDatabaseFrom18To10(p.dbHandle)
+	case 14:
+		return downgradeBoltDatabaseFrom18To10(p.dbHandle)
 	case 13:
 		return updateBoltDatabaseVersion(p.dbHandle, 10)
 	case 12:
@@ -1215,6 +3475,29 @@ func joinUserAndFolders(u []byte, foldersBucket *bolt.Bucket) (User, error) {
 	return user, err
 }
 
+func joinGroupAndFolders(g []byte, foldersBucket *bolt.Bucket) (Group, error) {
+	var group Group
+	err := json.Unmarshal(g, &group)
+	if err != nil {
+		return group, err
+	}
+	if len(group.VirtualFolders) > 0 {
+		var folders []vfs.VirtualFolder
+		for idx := range group.VirtualFolders {
+			folder := &group.VirtualFolders[idx]
+			baseFolder, err := folderExistsInternal(folder.Name, foldersBucket)
+			if err != nil {
+				continue
+			}
+			folder.BaseVirtualFolder = baseFolder
+			folders = append(folders, *folder)
+		}
+		group.VirtualFolders = folders
+	}
+	group.SetEmptySecretsIfNil()
+	return group, err
+}
+
 func folderExistsInternal(name string, bucket *bolt.Bucket) (vfs.BaseVirtualFolder, error) {
 	var folder vfs.BaseVirtualFolder
 	f := bucket.Get([]byte(name))
@@ -1236,17 +3519,87 @@ func addFolderInternal(folder vfs.BaseVirtualFolder, bucket *bolt.Bucket) error
 	if err != nil {
 		return err
 	}
-	return bucket.Put([]byte(folder.Name), buf)
+	return bucket.Put([]byte(folder.Name), buf)
+}
+
+func addUserToFolderMapping(baseFolder *vfs.BaseVirtualFolder, user *User, bucket *bolt.Bucket) error {
+	f := bucket.Get([]byte(baseFolder.Name))
+	if f == nil {
+		// folder does not exists, try to create
+		baseFolder.LastQuotaUpdate = 0
+		baseFolder.UsedQuotaFiles = 0
+		baseFolder.UsedQuotaSize = 0
+		baseFolder.UsedUploadDataTransfer = 0
+		baseFolder.UsedDownloadDataTransfer = 0
+		baseFolder.LastDataTransferReset = 0
+		baseFolder.Users = []string{user.Username}
+		baseFolder.Groups = nil
+		return addFolderInternal(*baseFolder, bucket)
+	}
+	var oldFolder vfs.BaseVirtualFolder
+	err := json.Unmarshal(f, &oldFolder)
+	if err != nil {
+		return err
+	}
+	baseFolder.ID = oldFolder.ID
+	baseFolder.LastQuotaUpdate = oldFolder.LastQuotaUpdate
+	baseFolder.UsedQuotaFiles = oldFolder.UsedQuotaFiles
+	baseFolder.UsedQuotaSize = oldFolder.UsedQuotaSize
+	baseFolder.UsedUploadDataTransfer = oldFolder.UsedUploadDataTransfer
+	baseFolder.UsedDownloadDataTransfer = oldFolder.UsedDownloadDataTransfer
+	baseFolder.LastDataTransferReset = oldFolder.LastDataTransferReset
+	baseFolder.Users = oldFolder.Users
+	baseFolder.Groups = oldFolder.Groups
+	if !util.IsStringInSlice(user.Username, baseFolder.Users) {
+		baseFolder.Users = append(baseFolder.Users, user.Username)
+	}
+	buf, err := json.Marshal(baseFolder)
+	if err != nil {
+		return err
+	}
+	return bucket.Put([]byte(baseFolder.Name), buf)
+}
+
+func removeUserFromFolderMapping(folder *vfs.VirtualFolder, user *User, bucket *bolt.Bucket) error {
+	var f []byte
+	if f = bucket.Get([]byte(folder.Name)); f == nil {
+		// the folder does not exists so there is no associated user
+		return nil
+	}
+	var baseFolder vfs.BaseVirtualFolder
+	err := json.Unmarshal(f, &baseFolder)
+	if err != nil {
+		return err
+	}
+	if util.IsStringInSlice(user.Username, baseFolder.Users) {
+		var newUserMapping []string
+		for _, u := range baseFolder.Users {
+			if u != user.Username {
+				newUserMapping = append(newUserMapping, u)
+			}
+		}
+		baseFolder.Users = newUserMapping
+		buf, err := json.Marshal(baseFolder)
+		if err != nil {
+			return err
+		}
+		return bucket.Put([]byte(folder.Name), buf)
+	}
+	return err
 }
 
-func addUserToFolderMapping(baseFolder *vfs.BaseVirtualFolder, user *User, bucket *bolt.Bucket) error {
+func addGroupToFolderMapping(baseFolder *vfs.BaseVirtualFolder, group *Group, bucket *bolt.Bucket) error {
 	f := bucket.Get([]byte(baseFolder.Name))
 	if f == nil {
 		// folder does not exists, try to create
 		baseFolder.LastQuotaUpdate = 0
 		baseFolder.UsedQuotaFiles = 0
 		baseFolder.UsedQuotaSize = 0
-		baseFolder.Users = []string{user.Username}
+		baseFolder.UsedUploadDataTransfer = 0
+		baseFolder.UsedDownloadDataTransfer = 0
+		baseFolder.LastDataTransferReset = 0
+		baseFolder.Users = nil
+		baseFolder.Groups = []string{group.Name}
 		return addFolderInternal(*baseFolder, bucket)
 	}
 	var oldFolder vfs.BaseVirtualFolder
@@ -1258,9 +3611,13 @@ func addUserToFolderMapping(baseFolder *vfs.BaseVirtualFolder, user *User, bucke
 	baseFolder.LastQuotaUpdate = oldFolder.LastQuotaUpdate
 	baseFolder.UsedQuotaFiles = oldFolder.UsedQuotaFiles
 	baseFolder.UsedQuotaSize = oldFolder.UsedQuotaSize
+	baseFolder.UsedUploadDataTransfer = oldFolder.UsedUploadDataTransfer
+	baseFolder.UsedDownloadDataTransfer = oldFolder.UsedDownloadDataTransfer
+	baseFolder.LastDataTransferReset = oldFolder.LastDataTransferReset
 	baseFolder.Users = oldFolder.Users
-	if !util.IsStringInSlice(user.Username, baseFolder.Users) {
-		baseFolder.Users = append(baseFolder.Users, user.Username)
+	baseFolder.Groups = oldFolder.Groups
+	if !util.IsStringInSlice(group.Name, baseFolder.Groups) {
+		baseFolder.Groups = append(baseFolder.Groups, group.Name)
 	}
 	buf, err := json.Marshal(baseFolder)
 	if err != nil {
@@ -1269,10 +3626,10 @@ func addUserToFolderMapping(baseFolder *vfs.BaseVirtualFolder, user *User, bucke
 	return bucket.Put([]byte(baseFolder.Name), buf)
 }
 
-func removeUserFromFolderMapping(folder *vfs.VirtualFolder, user *User, bucket *bolt.Bucket) error {
+func removeGroupFromFolderMapping(folder *vfs.VirtualFolder, group *Group, bucket *bolt.Bucket) error {
 	var f []byte
 	if f = bucket.Get([]byte(folder.Name)); f == nil {
-		// the folder does not exists so there is no associated user
+		// the folder does not exists so there is no associated group
 		return nil
 	}
 	var baseFolder vfs.BaseVirtualFolder
@@ -1280,14 +3637,14 @@ func removeUserFromFolderMapping(folder *vfs.VirtualFolder, user *User, bucket *
 	if err != nil {
 		return err
 	}
-	if util.IsStringInSlice(user.Username, baseFolder.Users) {
-		var newUserMapping []string
-		for _, u := range baseFolder.Users {
-			if u != user.Username {
-				newUserMapping = append(newUserMapping, u)
+	if util.IsStringInSlice(group.Name, baseFolder.Groups) {
+		var newGroupMapping []string
+		for _, g := range baseFolder.Groups {
+			if g != group.Name {
+				newGroupMapping = append(newGroupMapping, g)
 			}
 		}
-		baseFolder.Users = newUserMapping
+		baseFolder.Groups = newGroupMapping
 		buf, err := json.Marshal(baseFolder)
 		if err != nil {
 			return err
@@ -1297,6 +3654,55 @@ func removeUserFromFolderMapping(folder *vfs.VirtualFolder, user *User, bucket *
 	return err
 }
 
+func addUserToGroupMapping(username, groupName string, bucket *bolt.Bucket) error {
+	g := bucket.Get([]byte(groupName))
+	if g == nil {
+		return util.NewRecordNotFoundError(fmt.Sprintf("group %#v does not exist", groupName))
+	}
+	var group Group
+	err := json.Unmarshal(g, &group)
+	if err != nil {
+		return err
+	}
+	if util.IsStringInSlice(username, group.Users) {
+		return nil
+	}
+	group.Users = append(group.Users, username)
+	buf, err := json.Marshal(group)
+	if err != nil {
+		return err
+	}
+	return bucket.Put([]byte(group.Name), buf)
+}
+
+func removeUserFromGroupMapping(username, groupName string, bucket *bolt.Bucket) error {
+	var g []byte
+	if g = bucket.Get([]byte(groupName)); g == nil {
+		// the group does not exists so there is no associated user
+		return nil
+	}
+	var group Group
+	err := json.Unmarshal(g, &group)
+	if err != nil {
+		return err
+	}
+	if util.IsStringInSlice(username, group.Users) {
+		var newUserMapping []string
+		for _, u := range group.Users {
+			if u != username {
+				newUserMapping = append(newUserMapping, u)
+			}
+		}
+		group.Users = newUserMapping
+		buf, err := json.Marshal(group)
+		if err != nil {
+			return err
+		}
+		return bucket.Put([]byte(group.Name), buf)
+	}
+	return err
+}
+
 func deleteRelatedAPIKey(tx *bolt.Tx, username string, scope APIKeyScope) error {
 	bucket, err := getAPIKeysBucket(tx)
 	if err != nil {
@@ -1330,6 +3736,251 @@ func deleteRelatedAPIKey(tx *bolt.Tx, username string, scope APIKeyScope) error
 	return nil
 }
 
+func deleteRelatedShares(tx *bolt.Tx, username string) error {
+	bucket, err := getSharesBucket(tx)
+	if err != nil {
+		return err
+	}
+	var toRemove []string
+	cursor := bucket.Cursor()
+	for k, v := cursor.First(); k != nil; k, v = cursor.Next() {
+		var share Share
+		err = json.Unmarshal(v, &share)
+		if err != nil {
+			return err
+		}
+		if share.Username == username {
+			toRemove = append(toRemove, share.ShareID)
+		}
+	}
+
+	for _, k := range toRemove {
+		if err := deleteShareUploads(tx, k); err != nil {
+			return err
+		}
+		if err := bucket.Delete([]byte(k)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func deleteShareUploads(tx *bolt.Tx, shareID string) error {
+	bucket, err := getShareUploadsBucket(tx)
+	if err != nil {
+		return err
+	}
+	var toRemove [][]byte
+	prefix := getShareUploadKeyPrefix(shareID)
+	cursor := bucket.Cursor()
+	for k, _ := cursor.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, _ = cursor.Next() {
+		toRemove = append(toRemove, k)
+	}
+
+	for _, k := range toRemove {
+		if err := bucket.Delete(k); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// share uploads are stored using the share ID as prefix followed by a zero
+// padded sequence number, so they are sorted by upload order
+func getShareUploadKeyPrefix(shareID string) []byte {
+	return []byte(shareID + "/")
+}
+
+func getShareUploadKey(shareID string, id uint64) []byte {
+	return []byte(fmt.Sprintf("%v/%020d", shareID, id))
+}
+
+func getShareUploadsBucket(tx *bolt.Tx) (*bolt.Bucket, error) {
+	var err error
+
+	bucket := tx.Bucket(shareUploadsBucket)
+	if bucket == nil {
+		err = errors.New("unable to find share uploads bucket, bolt database structure not correcly defined")
+	}
+	return bucket, err
+}
+
+func getEventsBucket(tx *bolt.Tx, name []byte) (*bolt.Bucket, error) {
+	var err error
+
+	bucket := tx.Bucket(name)
+	if bucket == nil {
+		err = fmt.Errorf("unable to find %v bucket, bolt database structure not correcly defined", string(name))
+	}
+	return bucket, err
+}
+
+// getBoltEventKey returns the key for the specified event, keys are sorted by timestamp
+func getBoltEventKey(timestamp int64, id string) []byte {
+	return []byte(fmt.Sprintf("%020d_%v", timestamp, id))
+}
+
+// iterateBoltEvents calls fn for the events within the search time range, ordered as
+// requested, until fn returns false or an error
+func iterateBoltEvents(bucket *bolt.Bucket, search *eventSearch, fn func(v []byte) (bool, error)) error {
+	cursor := bucket.Cursor()
+	if search.Order == OrderASC {
+		var k, v []byte
+		if search.StartTimestamp > 0 {
+			k, v = cursor.Seek(getBoltEventKey(search.StartTimestamp, ""))
+		} else {
+			k, v = cursor.First()
+		}
+		var maxKey []byte
+		if search.EndTimestamp > 0 {
+			maxKey = getBoltEventKey(search.EndTimestamp+1, "")
+		}
+		for ; k != nil; k, v = cursor.Next() {
+			if maxKey != nil && bytes.Compare(k, maxKey) >= 0 {
+				return nil
+			}
+			next, err := fn(v)
+			if err != nil || !next {
+				return err
+			}
+		}
+		return nil
+	}
+	var k, v []byte
+	if search.EndTimestamp > 0 {
+		k, _ = cursor.Seek(getBoltEventKey(search.EndTimestamp+1, ""))
+		if k == nil {
+			k, v = cursor.Last()
+		} else {
+			k, v = cursor.Prev()
+		}
+	} else {
+		k, v = cursor.Last()
+	}
+	var minKey []byte
+	if search.StartTimestamp > 0 {
+		minKey = getBoltEventKey(search.StartTimestamp, "")
+	}
+	for ; k != nil; k, v = cursor.Prev() {
+		if minKey != nil && bytes.Compare(k, minKey) < 0 {
+			return nil
+		}
+		next, err := fn(v)
+		if err != nil || !next {
+			return err
+		}
+	}
+	return nil
+}
+
+func getLockoutsBucket(tx *bolt.Tx) (*bolt.Bucket, error) {
+	var err error
+
+	bucket := tx.Bucket(lockoutsBucket)
+	if bucket == nil {
+		err = errors.New("unable to find account lockouts bucket, bolt database structure not correcly defined")
+	}
+	return bucket, err
+}
+
+func getWebDAVLocksBucket(tx *bolt.Tx) (*bolt.Bucket, error) {
+	var err error
+
+	bucket := tx.Bucket(webDAVLocksBucket)
+	if bucket == nil {
+		err = errors.New("unable to find WebDAV locks bucket, bolt database structure not correcly defined")
+	}
+	return bucket, err
+}
+
+// deleteBoltWebDAVPropertiesTree removes the WebDAV properties for root and all its descendants
+// and returns the removed properties
+func deleteBoltWebDAVPropertiesTree(bucket *bolt.Bucket, username, root string) ([]WebDAVProperties, error) {
+	var keys [][]byte
+	var deleted []WebDAVProperties
+
+	prefix := []byte(getWebDAVPropertiesKey(username, root))
+	cursor := bucket.Cursor()
+	for k, v := cursor.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, v = cursor.Next() {
+		var props WebDAVProperties
+		if err := json.Unmarshal(v, &props); err != nil {
+			return nil, err
+		}
+		if props.Username == username && isWebDAVPropertiesPathInTree(props.Path, root) {
+			keys = append(keys, k)
+			deleted = append(deleted, props)
+		}
+	}
+	for _, k := range keys {
+		if err := bucket.Delete(k); err != nil {
+			return nil, err
+		}
+	}
+	return deleted, nil
+}
+
+func getWebDAVPropertiesBucket(tx *bolt.Tx) (*bolt.Bucket, error) {
+	var err error
+
+	bucket := tx.Bucket(webDAVPropsBucket)
+	if bucket == nil {
+		err = errors.New("unable to find WebDAV properties bucket, bolt database structure not correcly defined")
+	}
+	return bucket, err
+}
+
+func getS3AccessKeysBucket(tx *bolt.Tx) (*bolt.Bucket, error) {
+	var err error
+
+	bucket := tx.Bucket(s3AccessKeysBucket)
+	if bucket == nil {
+		err = errors.New("unable to find S3 access keys bucket, bolt database structure not correcly defined")
+	}
+	return bucket, err
+}
+
+func getTusUploadsBucket(tx *bolt.Tx) (*bolt.Bucket, error) {
+	var err error
+
+	bucket := tx.Bucket(tusUploadsBucket)
+	if bucket == nil {
+		err = errors.New("unable to find tus uploads bucket, bolt database structure not correcly defined")
+	}
+	return bucket, err
+}
+
+func getResetCodesBucket(tx *bolt.Tx) (*bolt.Bucket, error) {
+	var err error
+
+	bucket := tx.Bucket(resetCodesBucket)
+	if bucket == nil {
+		err = errors.New("unable to find reset codes bucket, bolt database structure not correcly defined")
+	}
+	return bucket, err
+}
+
+func getMultipartUploadsBucket(tx *bolt.Tx) (*bolt.Bucket, error) {
+	var err error
+
+	bucket := tx.Bucket(multipartUploadsBucket)
+	if bucket == nil {
+		err = errors.New("unable to find multipart uploads bucket, bolt database structure not correcly defined")
+	}
+	return bucket, err
+}
+
+func getSharesBucket(tx *bolt.Tx) (*bolt.Bucket, error) {
+	var err error
+
+	bucket := tx.Bucket(sharesBucket)
+	if bucket == nil {
+		err = errors.New("unable to find shares bucket, bolt database structure not correcly defined")
+	}
+	return bucket, err
+}
+
 func getAPIKeysBucket(tx *bolt.Tx) (*bolt.Bucket, error) {
 	var err error
 
@@ -1359,6 +4010,33 @@ func getUsersBucket(tx *bolt.Tx) (*bolt.Bucket, error) {
 	return bucket, err
 }
 
+func getGroupsBucket(tx *bolt.Tx) (*bolt.Bucket, error) {
+	var err error
+	bucket := tx.Bucket(groupsBucket)
+	if bucket == nil {
+		err = fmt.Errorf("unable to find groups bucket, bolt database structure not correcly defined")
+	}
+	return bucket, err
+}
+
+func getEventRulesBucket(tx *bolt.Tx) (*bolt.Bucket, error) {
+	var err error
+	bucket := tx.Bucket(eventRulesBucket)
+	if bucket == nil {
+		err = fmt.Errorf("unable to find event rules bucket, bolt database structure not correcly defined")
+	}
+	return bucket, err
+}
+
+func getSchedulesBucket(tx *bolt.Tx) (*bolt.Bucket, error) {
+	var err error
+	bucket := tx.Bucket(schedulesBucket)
+	if bucket == nil {
+		err = fmt.Errorf("unable to find schedules bucket, bolt database structure not correcly defined")
+	}
+	return bucket, err
+}
+
 func getFoldersBucket(tx *bolt.Tx) (*bolt.Bucket, error) {
 	var err error
 	bucket := tx.Bucket(foldersBucket)
@@ -1404,3 +4082,75 @@ func updateBoltDatabaseVersion(dbHandle *bolt.DB, version int) error {
 	})
 	return err
 }
+
+// updateBoltDatabaseToV18 initializes the last password change for existing
+// users and admins using their last update time
+func updateBoltDatabaseToV18(dbHandle *bolt.DB) error {
+	logger.InfoToConsole("updating database version to 18")
+	providerLog(logger.LevelInfo, "updating database version to 18")
+	err := dbHandle.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(usersBucket)
+		if bucket != nil {
+			cursor := bucket.Cursor()
+			for k, v := cursor.First(); k != nil; k, v = cursor.Next() {
+				var user User
+				if err := json.Unmarshal(v, &user); err != nil {
+					return err
+				}
+				user.LastPasswordChange = user.UpdatedAt
+				buf, err := json.Marshal(user)
+				if err != nil {
+					return err
+				}
+				if err := bucket.Put(k, buf); err != nil {
+					return err
+				}
+			}
+		}
+		bucket = tx.Bucket(adminsBucket)
+		if bucket != nil {
+			cursor := bucket.Cursor()
+			for k, v := cursor.First(); k != nil; k, v = cursor.Next() {
+				var admin Admin
+				if err := json.Unmarshal(v, &admin); err != nil {
+					return err
+				}
+				admin.LastPasswordChange = admin.UpdatedAt
+				buf, err := json.Marshal(admin)
+				if err != nil {
+					return err
+				}
+				if err := bucket.Put(k, buf); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return updateBoltDatabaseVersion(dbHandle, 18)
+}
+
+func downgradeBoltDatabaseFrom18To10(dbHandle *bolt.DB) error {
+	logger.InfoToConsole("downgrading database version: %v -> 10", boltDatabaseVersion)
+	providerLog(logger.LevelInfo, "downgrading database version: %v -> 10", boltDatabaseVersion)
+	err := dbHandle.Update(func(tx *bolt.Tx) error {
+		for _, bucket := range [][]byte{groupsBucket, shareUploadsBucket, sharesBucket, lockoutsBucket, fsEventsBucket,
+			providerEventsBucket, eventRulesBucket, schedulesBucket, webDAVLocksBucket, webDAVPropsBucket,
+			s3AccessKeysBucket, tusUploadsBucket, resetCodesBucket, multipartUploadsBucket} {
+			if tx.Bucket(bucket) == nil {
+				continue
+			}
+			if err := tx.DeleteBucket(bucket); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return updateBoltDatabaseVersion(dbHandle, 10)
+}
@@ -74,6 +74,12 @@ func (cache *usersCache) swap(user *User) {
 			delete(cache.users, user.Username)
 			return
 		}
+		if len(cachedUser.User.Groups) > 0 || len(user.Groups) > 0 {
+			// the cached user has the group settings applied, we cannot safely swap it
+			providerLog(logger.LevelDebug, "user %#v belongs to some groups, removing from cache", user.Username)
+			delete(cache.users, user.Username)
+			return
+		}
 		if cachedUser.User.isFsEqual(user) {
 			// the updated user has the same fs as the cached one, we can preserve the lock filesystem
 			providerLog(logger.LevelDebug, "current password and fs unchanged for for user %#v, swap cached one",
@@ -147,3 +153,16 @@ func GetCachedWebDAVUser(username string) (*CachedUser, bool) {
 func RemoveCachedWebDAVUser(username string) {
 	webDAVUsersCache.remove(username)
 }
+
+// removeCachedGroupMembers removes the cached WebDAV users belonging to the specified groups
+func removeCachedGroupMembers(groups []string) {
+	for _, name := range groups {
+		group, err := provider.groupExists(name)
+		if err != nil {
+			continue
+		}
+		for _, username := range group.Users {
+			webDAVUsersCache.remove(username)
+		}
+	}
+}
@@ -70,7 +70,7 @@ const (
 	CockroachDataProviderName = "cockroachdb"
 	// DumpVersion defines the version for the dump.
 	// For restore/load we support the current version and the previous one
-	DumpVersion = 9
+	DumpVersion = 10
 
 	argonPwdPrefix            = "$argon2id$"
 	bcryptPwdPrefix           = "$2a$"
@@ -107,6 +107,7 @@ const (
 	protocolFTP    = "FTP"
 	protocolWebDAV = "DAV"
 	protocolHTTP   = "HTTP"
+	protocolS3     = "S3"
 )
 
 var (
@@ -119,48 +120,68 @@ var (
 	// ValidLoginMethods defines all the valid login methods
 	ValidLoginMethods = []string{SSHLoginMethodPublicKey, LoginMethodPassword, SSHLoginMethodKeyboardInteractive,
 		SSHLoginMethodKeyAndPassword, SSHLoginMethodKeyAndKeyboardInt, LoginMethodTLSCertificate,
-		LoginMethodTLSCertificateAndPwd}
+		LoginMethodTLSCertificateAndPwd, LoginMethodS3AccessKey}
 	// SSHMultiStepsLoginMethods defines the supported Multi-Step Authentications
 	SSHMultiStepsLoginMethods = []string{SSHLoginMethodKeyAndPassword, SSHLoginMethodKeyAndKeyboardInt}
 	// ErrNoAuthTryed defines the error for connection closed before authentication
 	ErrNoAuthTryed = errors.New("no auth tryed")
 	// ValidProtocols defines all the valid protcols
-	ValidProtocols = []string{protocolSSH, protocolFTP, protocolWebDAV, protocolHTTP}
+	ValidProtocols = []string{protocolSSH, protocolFTP, protocolWebDAV, protocolHTTP, protocolS3}
 	// MFAProtocols defines the supported protocols for multi-factor authentication
 	MFAProtocols = []string{protocolHTTP, protocolSSH, protocolFTP}
 	// ErrNoInitRequired defines the error returned by InitProvider if no inizialization/update is required
 	ErrNoInitRequired = errors.New("the data provider is up to date")
 	// ErrInvalidCredentials defines the error to return if the supplied credentials are invalid
-	ErrInvalidCredentials   = errors.New("invalid credentials")
-	isAdminCreated          = int32(0)
-	validTLSUsernames       = []string{string(sdk.TLSUsernameNone), string(sdk.TLSUsernameCN)}
-	config                  Config
-	provider                Provider
-	sqlPlaceholders         []string
-	internalHashPwdPrefixes = []string{argonPwdPrefix, bcryptPwdPrefix}
-	hashPwdPrefixes         = []string{argonPwdPrefix, bcryptPwdPrefix, pbkdf2SHA1Prefix, pbkdf2SHA256Prefix,
+	ErrInvalidCredentials = errors.New("invalid credentials")
+	// ErrLoginNotAllowedFromIP defines the error to return if login is denied from the current IP
+	ErrLoginNotAllowedFromIP = errors.New("login is not allowed from this IP")
+	// ErrPasswordChangeRequired defines the error to return if the password must be changed before login
+	ErrPasswordChangeRequired = errors.New("password change required")
+	isAdminCreated            = int32(0)
+	validTLSUsernames         = []string{string(sdk.TLSUsernameNone), string(sdk.TLSUsernameCN)}
+	config                    Config
+	provider                  Provider
+	sqlPlaceholders           []string
+	internalHashPwdPrefixes   = []string{argonPwdPrefix, bcryptPwdPrefix}
+	hashPwdPrefixes           = []string{argonPwdPrefix, bcryptPwdPrefix, pbkdf2SHA1Prefix, pbkdf2SHA256Prefix,
 		pbkdf2SHA512Prefix, pbkdf2SHA256B64SaltPrefix, md5cryptPwdPrefix, md5cryptApr1PwdPrefix, sha512cryptPwdPrefix}
-	pbkdfPwdPrefixes        = []string{pbkdf2SHA1Prefix, pbkdf2SHA256Prefix, pbkdf2SHA512Prefix, pbkdf2SHA256B64SaltPrefix}
-	pbkdfPwdB64SaltPrefixes = []string{pbkdf2SHA256B64SaltPrefix}
-	unixPwdPrefixes         = []string{md5cryptPwdPrefix, md5cryptApr1PwdPrefix, sha512cryptPwdPrefix}
-	sharedProviders         = []string{PGSQLDataProviderName, MySQLDataProviderName, CockroachDataProviderName}
-	logSender               = "dataProvider"
-	availabilityTicker      *time.Ticker
-	availabilityTickerDone  chan bool
-	updateCachesTicker      *time.Ticker
-	updateCachesTickerDone  chan bool
-	lastCachesUpdate        int64
-	credentialsDirPath      string
-	sqlTableUsers           = "users"
-	sqlTableFolders         = "folders"
-	sqlTableFoldersMapping  = "folders_mapping"
-	sqlTableAdmins          = "admins"
-	sqlTableAPIKeys         = "api_keys"
-	sqlTableSchemaVersion   = "schema_version"
-	argon2Params            *argon2id.Params
-	lastLoginMinDelay       = 10 * time.Minute
-	usernameRegex           = regexp.MustCompile("^[a-zA-Z0-9-_.~]+$")
-	tempPath                string
+	pbkdfPwdPrefixes             = []string{pbkdf2SHA1Prefix, pbkdf2SHA256Prefix, pbkdf2SHA512Prefix, pbkdf2SHA256B64SaltPrefix}
+	pbkdfPwdB64SaltPrefixes      = []string{pbkdf2SHA256B64SaltPrefix}
+	unixPwdPrefixes              = []string{md5cryptPwdPrefix, md5cryptApr1PwdPrefix, sha512cryptPwdPrefix}
+	sharedProviders              = []string{PGSQLDataProviderName, MySQLDataProviderName, CockroachDataProviderName}
+	logSender                    = "dataProvider"
+	availabilityTicker           *time.Ticker
+	availabilityTickerDone       chan bool
+	updateCachesTicker           *time.Ticker
+	updateCachesTickerDone       chan bool
+	lastCachesUpdate             int64
+	credentialsDirPath           string
+	sqlTableUsers                = "users"
+	sqlTableFolders              = "folders"
+	sqlTableFoldersMapping       = "folders_mapping"
+	sqlTableAdmins               = "admins"
+	sqlTableAPIKeys              = "api_keys"
+	sqlTableShares               = "shares"
+	sqlTableShareUploads         = "share_uploads"
+	sqlTableGroups               = "user_groups"
+	sqlTableGroupsMapping        = "groups_mapping"
+	sqlTableGroupsFoldersMapping = "groups_folders_mapping"
+	sqlTableAccountLockouts      = "account_lockouts"
+	sqlTableFsEvents             = "fs_events"
+	sqlTableProviderEvents       = "provider_events"
+	sqlTableEventsRules          = "events_rules"
+	sqlTableSchedules            = "schedules"
+	sqlTableWebDAVLocks          = "webdav_locks"
+	sqlTableWebDAVProperties     = "webdav_properties"
+	sqlTableS3AccessKeys         = "s3_access_keys"
+	sqlTableTusUploads           = "tus_uploads"
+	sqlTableResetCodes           = "reset_codes"
+	sqlTableMultipartUploads     = "multipart_uploads"
+	sqlTableSchemaVersion        = "schema_version"
+	argon2Params                 *argon2id.Params
+	lastLoginMinDelay            = 10 * time.Minute
+	usernameRegex                = regexp.MustCompile("^[a-zA-Z0-9-_.~]+$")
+	tempPath                     string
 )
 
 type schemaVersion struct {
@@ -204,11 +225,40 @@ type PasswordValidation struct {
 	Users PasswordValidationRules `json:"users" mapstructure:"users"`
 }
 
+// AccountLockoutConfig defines the per-account lockout after repeated failed logins
+type AccountLockoutConfig struct {
+	// Set to true to lock users and admins after repeated failed logins
+	Enabled bool `json:"enabled" mapstructure:"enabled"`
+	// Number of failed logins, within the observation time, that will lock the account
+	MaxFailures int `json:"max_failures" mapstructure:"max_failures"`
+	// Time window, in minutes, for counting the failed logins
+	ObservationTime int `json:"observation_time" mapstructure:"observation_time"`
+	// Lockout duration, in minutes. 0 means that the account remains locked
+	// until it is unlocked by an administrator
+	LockoutTime int `json:"lockout_time" mapstructure:"lockout_time"`
+}
+
+func (c *AccountLockoutConfig) validate() error {
+	if !c.Enabled {
+		return nil
+	}
+	if c.MaxFailures <= 0 {
+		return fmt.Errorf("invalid account lockout max failures: %v", c.MaxFailures)
+	}
+	if c.ObservationTime <= 0 {
+		return fmt.Errorf("invalid account lockout observation time: %v", c.ObservationTime)
+	}
+	if c.LockoutTime < 0 {
+		return fmt.Errorf("invalid account lockout time: %v", c.LockoutTime)
+	}
+	return nil
+}
+
 // ObjectsActions defines the action to execute on user create, update, delete for the specified objects
 type ObjectsActions struct {
 	// Valid values are add, update, delete. Empty slice to disable
 	ExecuteOn []string `json:"execute_on" mapstructure:"execute_on"`
-	// Valid values are user, admin, api_key
+	// Valid values are user, admin, api_key, share
 	ExecuteFor []string `json:"execute_for" mapstructure:"execute_for"`
 	// Absolute path to an external program or an HTTP URL
 	Hook string `json:"hook" mapstructure:"hook"`
@@ -264,7 +314,7 @@ type Config struct {
 	// defined as the path obtained joining the base dir and the username
 	UsersBaseDir string `json:"users_base_dir" mapstructure:"users_base_dir"`
 	// Actions to execute on objects add, update, delete.
-	// The supported objects are user, admin, api_key.
+	// The supported objects are user, admin, api_key, share.
 	// Update action will not be fired for internal updates such as the last login or the user quota fields.
 	Actions ObjectsActions `json:"actions" mapstructure:"actions"`
 	// Absolute path to an external program or an HTTP URL to invoke for users authentication.
@@ -360,15 +410,36 @@ type Config struct {
 	// based on the "updated_at" field, and updates its internal caches if users are updated from
 	// a different instance. This check, if enabled, is executed every 10 minutes
 	IsShared int `json:"is_shared" mapstructure:"is_shared"`
+	// PasswordExpirationNotification defines how many days before the password expiration
+	// users and admins with an email address will be notified. An SMTP server must be configured.
+	// The notification is sent once a day until the password is changed or expires.
+	// 0 means disabled
+	PasswordExpirationNotification int `json:"password_expiration_notification" mapstructure:"password_expiration_notification"`
+	// AccountLockout defines the per-account lockout after repeated failed logins.
+	// The failed logins are stored in the data provider, so they are shared across
+	// multiple SFTPGo instances using the same data provider
+	AccountLockout AccountLockoutConfig `json:"account_lockout" mapstructure:"account_lockout"`
+	// EventStore defines the configuration for the built-in events store.
+	// Filesystem and provider events are stored within the data provider and
+	// can be searched using the REST API and the web admin
+	EventStore EventStoreConfig `json:"event_store" mapstructure:"event_store"`
+	// EventManager defines the configuration for the event manager.
+	// Command actions can only execute the commands enabled here
+	EventManager EventManagerConfig `json:"event_manager" mapstructure:"event_manager"`
 }
 
 // BackupData defines the structure for the backup/restore files
 type BackupData struct {
-	Users   []User                  `json:"users"`
-	Folders []vfs.BaseVirtualFolder `json:"folders"`
-	Admins  []Admin                 `json:"admins"`
-	APIKeys []APIKey                `json:"api_keys"`
-	Version int                     `json:"version"`
+	Users        []User                  `json:"users"`
+	Folders      []vfs.BaseVirtualFolder `json:"folders"`
+	Groups       []Group                 `json:"groups"`
+	Admins       []Admin                 `json:"admins"`
+	APIKeys      []APIKey                `json:"api_keys"`
+	Shares       []Share                 `json:"shares"`
+	EventRules   []EventRule             `json:"event_rules"`
+	Schedules    []Schedule              `json:"schedules"`
+	S3AccessKeys []S3AccessKey           `json:"s3_access_keys"`
+	Version      int                     `json:"version"`
 }
 
 // HasFolder returns true if the folder with the given name is included
@@ -408,11 +479,14 @@ type Provider interface {
 	validateUserAndTLSCert(username, protocol string, tlsCert *x509.Certificate) (User, error)
 	updateQuota(username string, filesAdd int, sizeAdd int64, reset bool) error
 	getUsedQuota(username string) (int, int64, error)
+	updateTransferQuota(username string, uploadSize, downloadSize int64, reset bool, periodStart int64) error
+	getUsedTransferQuota(username string) (int64, int64, int64, error)
 	userExists(username string) (User, error)
 	addUser(user *User) error
 	updateUser(user *User) error
 	deleteUser(user *User) error
 	getUsers(limit int, offset int, order string) ([]User, error)
+	getUsersInScope(admin *Admin, limit, offset int, order string) ([]User, error)
 	dumpUsers() ([]User, error)
 	getRecentlyUpdatedUsers(after int64) ([]User, error)
 	updateLastLogin(username string) error
@@ -425,7 +499,16 @@ type Provider interface {
 	deleteFolder(folder *vfs.BaseVirtualFolder) error
 	updateFolderQuota(name string, filesAdd int, sizeAdd int64, reset bool) error
 	getUsedFolderQuota(name string) (int, int64, error)
+	updateFolderTransferQuota(name string, uploadSize, downloadSize int64, reset bool, periodStart int64) error
+	getUsedFolderTransferQuota(name string) (int64, int64, int64, error)
 	dumpFolders() ([]vfs.BaseVirtualFolder, error)
+	groupExists(name string) (Group, error)
+	addGroup(group *Group) error
+	updateGroup(group *Group) error
+	deleteGroup(group *Group) error
+	getGroups(limit, offset int, order string) ([]Group, error)
+	getGroupsWithNames(names []string) ([]Group, error)
+	dumpGroups() ([]Group, error)
 	adminExists(username string) (Admin, error)
 	addAdmin(admin *Admin) error
 	updateAdmin(admin *Admin) error
@@ -440,6 +523,73 @@ type Provider interface {
 	getAPIKeys(limit int, offset int, order string) ([]APIKey, error)
 	dumpAPIKeys() ([]APIKey, error)
 	updateAPIKeyLastUse(keyID string) error
+	shareExists(shareID, username string) (Share, error)
+	addShare(share *Share) error
+	updateShare(share *Share) error
+	deleteShare(share *Share) error
+	getShares(limit int, offset int, order, username string) ([]Share, error)
+	dumpShares() ([]Share, error)
+	updateShareLastUse(shareID string, numTokens int) error
+	addShareUpload(upload *ShareUpload) error
+	getShareUploads(shareID string, limit, offset int, order string) ([]ShareUpload, error)
+	accountLockoutExists(username, accountType string) (AccountLockout, error)
+	addAccountLockoutFailure(username, accountType string, now time.Time) (AccountLockout, error)
+	deleteAccountLockout(username, accountType string) error
+	getAccountLockouts(limit, offset int, order string) ([]AccountLockout, error)
+	addFsEvent(event *FsEvent) error
+	addProviderEvent(event *ProviderEvent) error
+	searchFsEvents(search *FsEventSearch) ([]FsEvent, error)
+	searchProviderEvents(search *ProviderEventSearch) ([]ProviderEvent, error)
+	cleanupEvents(before int64) error
+	webDAVLockExists(token string) (WebDAVLock, error)
+	addWebDAVLock(lock *WebDAVLock) error
+	updateWebDAVLock(lock *WebDAVLock) error
+	deleteWebDAVLock(token string) error
+	getWebDAVLocks(username string) ([]WebDAVLock, error)
+	deleteUserWebDAVLocks(username string) error
+	cleanupWebDAVLocks(before int64) error
+	webDAVPropertiesExists(username, virtualPath string) (WebDAVProperties, error)
+	setWebDAVProperties(props *WebDAVProperties) error
+	deleteWebDAVProperties(username, virtualPath string) error
+	renameWebDAVProperties(username, source, target string) error
+	deleteUserWebDAVProperties(username string) error
+	s3AccessKeyExists(accessKeyID string) (S3AccessKey, error)
+	addS3AccessKey(key *S3AccessKey) error
+	deleteS3AccessKey(accessKeyID, username string) error
+	getUserS3AccessKeys(username string) ([]S3AccessKey, error)
+	deleteUserS3AccessKeys(username string) error
+	updateS3AccessKey(key *S3AccessKey) error
+	dumpS3AccessKeys() ([]S3AccessKey, error)
+	tusUploadExists(uploadID string) (TusUpload, error)
+	addTusUpload(upload *TusUpload) error
+	updateTusUploadOffset(uploadID string, offset, expiresAt, updatedAt int64) error
+	deleteTusUpload(uploadID string) error
+	getTusUploadsCount(username string) (int, error)
+	getExpiredTusUploads(before int64) ([]TusUpload, error)
+	deleteUserTusUploads(username string) error
+	resetCodeExists(code string) (ResetCode, error)
+	addResetCode(code *ResetCode) error
+	deleteResetCode(code string) error
+	getResetCodesCount(username, accountType string, after int64) (int, error)
+	deleteExpiredResetCodes(before int64) error
+	multipartUploadExists(key string) (vfs.MultipartUpload, error)
+	saveMultipartUpload(upload *vfs.MultipartUpload) error
+	deleteMultipartUpload(key string) error
+	cleanupMultipartUploads(before int64) error
+	eventRuleExists(name string) (EventRule, error)
+	addEventRule(rule *EventRule) error
+	updateEventRule(rule *EventRule) error
+	deleteEventRule(rule *EventRule) error
+	getEventRules(limit, offset int, order string) ([]EventRule, error)
+	dumpEventRules() ([]EventRule, error)
+	scheduleExists(name string) (Schedule, error)
+	addSchedule(schedule *Schedule) error
+	updateSchedule(schedule *Schedule) error
+	deleteSchedule(schedule *Schedule) error
+	getSchedules(limit, offset int, order string) ([]Schedule, error)
+	dumpSchedules() ([]Schedule, error)
+	claimScheduleRun(name string, runAt int64) (bool, error)
+	setScheduleRunResult(name string, status int, runError string) error
 	checkAvailability() error
 	close() error
 	reloadConfig() error
@@ -465,6 +615,7 @@ func Initialize(cnf Config, basePath string, checkAdmins bool) error {
 		credentialsDirPath = filepath.Join(basePath, config.CredentialsPath)
 	}
 	vfs.SetCredentialsDirPath(credentialsDirPath)
+	eventStoreInstanceID = getEventStoreInstanceID()
 
 	if err = initializeHashingAlgo(&cnf); err != nil {
 		return err
@@ -473,10 +624,20 @@ func Initialize(cnf Config, basePath string, checkAdmins bool) error {
 	if err = validateHooks(); err != nil {
 		return err
 	}
+	if err = config.AccountLockout.validate(); err != nil {
+		return err
+	}
+	if err = config.EventStore.validate(); err != nil {
+		return err
+	}
+	if err = config.EventManager.validate(); err != nil {
+		return err
+	}
 	err = createProvider(basePath)
 	if err != nil {
 		return err
 	}
+	vfs.SetMultipartUploadStore(&multipartUploadStore{})
 	if cnf.UpdateMode == 0 {
 		err = provider.initializeDatabase()
 		if err != nil && err != ErrNoInitRequired {
@@ -509,7 +670,13 @@ func Initialize(cnf Config, basePath string, checkAdmins bool) error {
 	atomic.StoreInt32(&isAdminCreated, int32(len(admins)))
 	startAvailabilityTimer()
 	startUpdateCachesTimer()
+	startPasswordExpirationTimer()
+	startEventStoreCleanupTimer()
+	eventsWriter.start()
+	startWebDAVLocksCleanupTimer()
+	startMultipartUploadsCleanupTimer()
 	delayedQuotaUpdater.start()
+	reloadEventRules()
 	return nil
 }
 
@@ -574,10 +741,32 @@ func validateSQLTablesPrefix() error {
 		sqlTableFoldersMapping = config.SQLTablesPrefix + sqlTableFoldersMapping
 		sqlTableAdmins = config.SQLTablesPrefix + sqlTableAdmins
 		sqlTableAPIKeys = config.SQLTablesPrefix + sqlTableAPIKeys
+		sqlTableShares = config.SQLTablesPrefix + sqlTableShares
+		sqlTableShareUploads = config.SQLTablesPrefix + sqlTableShareUploads
+		sqlTableGroups = config.SQLTablesPrefix + sqlTableGroups
+		sqlTableGroupsMapping = config.SQLTablesPrefix + sqlTableGroupsMapping
+		sqlTableGroupsFoldersMapping = config.SQLTablesPrefix + sqlTableGroupsFoldersMapping
+		sqlTableAccountLockouts = config.SQLTablesPrefix + sqlTableAccountLockouts
+		sqlTableFsEvents = config.SQLTablesPrefix + sqlTableFsEvents
+		sqlTableProviderEvents = config.SQLTablesPrefix + sqlTableProviderEvents
+		sqlTableEventsRules = config.SQLTablesPrefix + sqlTableEventsRules
+		sqlTableSchedules = config.SQLTablesPrefix + sqlTableSchedules
+		sqlTableWebDAVLocks = config.SQLTablesPrefix + sqlTableWebDAVLocks
+		sqlTableWebDAVProperties = config.SQLTablesPrefix + sqlTableWebDAVProperties
+		sqlTableS3AccessKeys = config.SQLTablesPrefix + sqlTableS3AccessKeys
+		sqlTableTusUploads = config.SQLTablesPrefix + sqlTableTusUploads
+		sqlTableResetCodes = config.SQLTablesPrefix + sqlTableResetCodes
+		sqlTableMultipartUploads = config.SQLTablesPrefix + sqlTableMultipartUploads
 		sqlTableSchemaVersion = config.SQLTablesPrefix + sqlTableSchemaVersion
 		providerLog(logger.LevelDebug, "sql table for users %#v, folders %#v folders mapping %#v admins %#v "+
-			"api keys %#v schema version %#v", sqlTableUsers, sqlTableFolders, sqlTableFoldersMapping, sqlTableAdmins,
-			sqlTableAPIKeys, sqlTableSchemaVersion)
+			"api keys %#v shares %#v share uploads %#v groups %#v groups mapping %#v groups folders mapping %#v "+
+			"account lockouts %#v fs events %#v provider events %#v events rules %#v schedules %#v WebDAV locks %#v "+
+			"WebDAV properties %#v S3 access keys %#v tus uploads %#v reset codes %#v multipart uploads %#v "+
+			"schema version %#v", sqlTableUsers, sqlTableFolders,
+			sqlTableFoldersMapping, sqlTableAdmins, sqlTableAPIKeys, sqlTableShares, sqlTableShareUploads, sqlTableGroups,
+			sqlTableGroupsMapping, sqlTableGroupsFoldersMapping, sqlTableAccountLockouts, sqlTableFsEvents,
+			sqlTableProviderEvents, sqlTableEventsRules, sqlTableSchedules, sqlTableWebDAVLocks, sqlTableWebDAVProperties,
+			sqlTableS3AccessKeys, sqlTableTusUploads, sqlTableResetCodes, sqlTableMultipartUploads, sqlTableSchemaVersion)
 	}
 	return nil
 }
@@ -666,15 +855,20 @@ func CheckCachedUserCredentials(user *CachedUser, password, loginMethod, protoco
 	if password == "" {
 		return ErrInvalidCredentials
 	}
+	lockout, err := getAccountLockoutForLogin(user.User.Username, AccountTypeUser)
+	if err != nil {
+		return err
+	}
 	if user.Password != "" {
 		if password == user.Password {
-			return nil
+			return checkPasswordChangeRequirement(&user.User, protocol)
 		}
 	} else {
 		if ok, _ := isPasswordOK(&user.User, password); ok {
-			return nil
+			return checkPasswordChangeRequirement(&user.User, protocol)
 		}
 	}
+	updateAccountLockout(user.User.Username, AccountTypeUser, lockout, ErrInvalidCredentials)
 	return ErrInvalidCredentials
 }
 
@@ -720,16 +914,22 @@ func CheckCompositeCredentials(username, password, ip, loginMethod, protocol str
 
 // CheckUserBeforeTLSAuth checks if a user exits before trying mutual TLS
 func CheckUserBeforeTLSAuth(username, ip, protocol string, tlsCert *x509.Certificate) (User, error) {
+	var user User
+	var err error
 	if plugin.Handler.HasAuthScope(plugin.AuthScopeTLSCertificate) {
-		return doPluginAuth(username, "", nil, ip, protocol, tlsCert, plugin.AuthScopeTLSCertificate)
-	}
-	if config.ExternalAuthHook != "" && (config.ExternalAuthScope == 0 || config.ExternalAuthScope&8 != 0) {
-		return doExternalAuth(username, "", nil, "", ip, protocol, tlsCert)
+		user, err = doPluginAuth(username, "", nil, ip, protocol, tlsCert, plugin.AuthScopeTLSCertificate)
+	} else if config.ExternalAuthHook != "" && (config.ExternalAuthScope == 0 || config.ExternalAuthScope&8 != 0) {
+		user, err = doExternalAuth(username, "", nil, "", ip, protocol, tlsCert)
+	} else if config.PreLoginHook != "" {
+		user, err = executePreLoginHook(username, LoginMethodTLSCertificate, ip, protocol)
+	} else {
+		user, err = UserExists(username)
 	}
-	if config.PreLoginHook != "" {
-		return executePreLoginHook(username, LoginMethodTLSCertificate, ip, protocol)
+	if err != nil {
+		return user, err
 	}
-	return UserExists(username)
+	err = user.LoadAndApplyGroupSettings()
+	return user, err
 }
 
 // CheckUserAndTLSCert returns the SFTPGo user with the given username and check if the
@@ -841,6 +1041,16 @@ func UpdateAPIKeyLastUse(apiKey *APIKey) error {
 	return nil
 }
 
+// UpdateShareLastUse updates the LastUseAt and UsedTokens for the given share
+func UpdateShareLastUse(share *Share, numTokens int) error {
+	return provider.updateShareLastUse(share.ShareID, numTokens)
+}
+
+// AddShareUpload records a file uploaded using a share with write scope
+func AddShareUpload(upload *ShareUpload) error {
+	return provider.addShareUpload(upload)
+}
+
 // UpdateLastLogin updates the last login field for the given SFTPGo user
 func UpdateLastLogin(user *User) {
 	lastLogin := util.GetTimeFromMsecSinceEpoch(user.LastLogin)
@@ -928,6 +1138,97 @@ func GetUsedVirtualFolderQuota(name string) (int, int64, error) {
 	return files + delayedFiles, size + delayedSize, err
 }
 
+// UpdateUserTransferQuota updates the data transfer quota for the given user adding uploadSize and downloadSize.
+// If reset is true uploadSize and downloadSize indicates the used data transfer instead of the difference.
+// The used data transfer is automatically reset if the configured reset period is elapsed
+func UpdateUserTransferQuota(user *User, uploadSize, downloadSize int64, reset bool) error {
+	if config.TrackQuota == 0 {
+		return util.NewMethodDisabledError(trackQuotaDisabledError)
+	} else if config.TrackQuota == 2 && !reset && !user.HasTransferQuotaRestrictions() {
+		return nil
+	}
+	if uploadSize == 0 && downloadSize == 0 && !reset {
+		return nil
+	}
+	periodStart := user.DataTransferResetPeriod.GetCurrentPeriodStart(time.Now())
+	if config.DelayedQuotaUpdate == 0 || reset {
+		if reset {
+			delayedQuotaUpdater.resetUserTransferQuota(user.Username)
+		}
+		return provider.updateTransferQuota(user.Username, uploadSize, downloadSize, reset, periodStart)
+	}
+	delayedQuotaUpdater.updateUserTransferQuota(user.Username, uploadSize, downloadSize, periodStart)
+	return nil
+}
+
+// UpdateVirtualFolderTransferQuota updates the data transfer quota for the given virtual folder adding
+// uploadSize and downloadSize.
+// If reset is true uploadSize and downloadSize indicates the used data transfer instead of the difference.
+// The used data transfer is automatically reset if the configured reset period is elapsed
+func UpdateVirtualFolderTransferQuota(vfolder *vfs.BaseVirtualFolder, uploadSize, downloadSize int64, reset bool) error {
+	if config.TrackQuota == 0 {
+		return util.NewMethodDisabledError(trackQuotaDisabledError)
+	} else if config.TrackQuota == 2 && !reset && !vfolder.HasTransferQuotaRestrictions() {
+		return nil
+	}
+	if uploadSize == 0 && downloadSize == 0 && !reset {
+		return nil
+	}
+	periodStart := vfolder.DataTransferResetPeriod.GetCurrentPeriodStart(time.Now())
+	if config.DelayedQuotaUpdate == 0 || reset {
+		if reset {
+			delayedQuotaUpdater.resetFolderTransferQuota(vfolder.Name)
+		}
+		return provider.updateFolderTransferQuota(vfolder.Name, uploadSize, downloadSize, reset, periodStart)
+	}
+	delayedQuotaUpdater.updateFolderTransferQuota(vfolder.Name, uploadSize, downloadSize, periodStart)
+	return nil
+}
+
+// GetUsedTransferQuota returns the uploaded and downloaded bytes for the given user
+// in the current data transfer reset period
+func GetUsedTransferQuota(user *User) (int64, int64, error) {
+	if config.TrackQuota == 0 {
+		return 0, 0, util.NewMethodDisabledError(trackQuotaDisabledError)
+	}
+	ulSize, dlSize, lastReset, err := provider.getUsedTransferQuota(user.Username)
+	if err != nil {
+		return 0, 0, err
+	}
+	pending := delayedQuotaUpdater.getUserPendingTransferQuota(user.Username)
+	ulSize, dlSize = getCurrentPeriodTransferQuota(ulSize, dlSize, lastReset, pending, user.DataTransferResetPeriod)
+	return ulSize, dlSize, nil
+}
+
+// GetUsedVirtualFolderTransferQuota returns the uploaded and downloaded bytes for the given
+// virtual folder in the current data transfer reset period
+func GetUsedVirtualFolderTransferQuota(vfolder *vfs.BaseVirtualFolder) (int64, int64, error) {
+	if config.TrackQuota == 0 {
+		return 0, 0, util.NewMethodDisabledError(trackQuotaDisabledError)
+	}
+	ulSize, dlSize, lastReset, err := provider.getUsedFolderTransferQuota(vfolder.Name)
+	if err != nil {
+		return 0, 0, err
+	}
+	pending := delayedQuotaUpdater.getFolderPendingTransferQuota(vfolder.Name)
+	ulSize, dlSize = getCurrentPeriodTransferQuota(ulSize, dlSize, lastReset, pending, vfolder.DataTransferResetPeriod)
+	return ulSize, dlSize, nil
+}
+
+func getCurrentPeriodTransferQuota(ulSize, dlSize, lastReset int64, pending transferQuotaObject,
+	resetPeriod sdk.DataTransferResetPeriod) (int64, int64) {
+	periodStart := resetPeriod.GetCurrentPeriodStart(time.Now())
+	if lastReset < periodStart {
+		ulSize = 0
+		dlSize = 0
+	}
+	if pending.periodStart >= periodStart {
+		ulSize += pending.ulSize
+		dlSize += pending.dlSize
+	}
+	return ulSize, dlSize
+}
+
 // AddAPIKey adds a new API key
 func AddAPIKey(apiKey *APIKey, executor, ipAddress string) error {
 	err := provider.addAPIKey(apiKey)
@@ -959,6 +1260,46 @@ func DeleteAPIKey(keyID string, executor, ipAddress string) error {
 	return err
 }
 
+// AddShare adds a new share
+func AddShare(share *Share, executor, ipAddress string) error {
+	err := provider.addShare(share)
+	if err == nil {
+		executeAction(operationAdd, executor, ipAddress, actionObjectShare, share.ShareID, share)
+	}
+	return err
+}
+
+// UpdateShare updates an existing share
+func UpdateShare(share *Share, executor, ipAddress string) error {
+	err := provider.updateShare(share)
+	if err == nil {
+		executeAction(operationUpdate, executor, ipAddress, actionObjectShare, share.ShareID, share)
+	}
+	return err
+}
+
+// DeleteShare deletes an existing share
+func DeleteShare(shareID string, executor, ipAddress string) error {
+	share, err := provider.shareExists(shareID, executor)
+	if err != nil {
+		return err
+	}
+	err = provider.deleteShare(&share)
+	if err == nil {
+		executeAction(operationDelete, executor, ipAddress, actionObjectShare, shareID, &share)
+	}
+	return err
+}
+
+// ShareExists returns the share with the given ID if it exists.
+// If username is not empty the share must belong to the specified user
+func ShareExists(shareID, username string) (Share, error) {
+	if shareID == "" {
+		return Share{}, util.NewRecordNotFoundError(fmt.Sprintf("Share %#v does not exist", shareID))
+	}
+	return provider.shareExists(shareID, username)
+}
+
 // APIKeyExists returns the API key with the given ID if it exists
 func APIKeyExists(keyID string) (APIKey, error) {
 	if keyID == "" {
@@ -979,6 +1320,9 @@ func AddAdmin(admin *Admin, executor, ipAddress string) error {
 	admin.Filters.TOTPConfig = TOTPConfig{
 		Enabled: false,
 	}
+	if admin.LastPasswordChange == 0 {
+		admin.LastPasswordChange = util.GetTimeAsMsSinceEpoch(time.Now())
+	}
 	err := provider.addAdmin(admin)
 	if err == nil {
 		atomic.StoreInt32(&isAdminCreated, 1)
@@ -1004,6 +1348,7 @@ func DeleteAdmin(username, executor, ipAddress string) error {
 	}
 	err = provider.deleteAdmin(&admin)
 	if err == nil {
+		provider.deleteAccountLockout(admin.Username, AccountTypeAdmin) //nolint:errcheck
 		executeAction(operationDelete, executor, ipAddress, actionObjectAdmin, admin.Username, &admin)
 	}
 	return err
@@ -1019,12 +1364,191 @@ func UserExists(username string) (User, error) {
 	return provider.userExists(username)
 }
 
+// GetUserWithGroupSettings returns the SFTPGo user with the given username with the settings
+// inherited from its groups already applied. The returned user must not be saved to the
+// data provider, use UserExists to get a user to update
+func GetUserWithGroupSettings(username string) (User, error) {
+	user, err := provider.userExists(username)
+	if err != nil {
+		return user, err
+	}
+	err = user.LoadAndApplyGroupSettings()
+	return user, err
+}
+
+// GroupExists returns the group with the given name if it exists
+func GroupExists(name string) (Group, error) {
+	return provider.groupExists(name)
+}
+
+// AddGroup adds a new group
+func AddGroup(group *Group, executor, ipAddress string) error {
+	err := provider.addGroup(group)
+	if err == nil {
+		executeAction(operationAdd, executor, ipAddress, actionObjectGroup, group.Name, group)
+	}
+	return err
+}
+
+// UpdateGroup updates an existing group.
+// The cached WebDAV users belonging to the group are removed from the cache
+func UpdateGroup(group *Group, users []string, executor, ipAddress string) error {
+	err := provider.updateGroup(group)
+	if err == nil {
+		for _, user := range users {
+			provider.setUpdatedAt(user)
+			RemoveCachedWebDAVUser(user)
+		}
+		executeAction(operationUpdate, executor, ipAddress, actionObjectGroup, group.Name, group)
+	}
+	return err
+}
+
+// DeleteGroup deletes an existing group
+func DeleteGroup(name, executor, ipAddress string) error {
+	group, err := provider.groupExists(name)
+	if err != nil {
+		return err
+	}
+	err = provider.deleteGroup(&group)
+	if err == nil {
+		for _, user := range group.Users {
+			provider.setUpdatedAt(user)
+			u, err := provider.userExists(user)
+			if err == nil {
+				executeAction(operationUpdate, executor, ipAddress, actionObjectUser, u.Username, &u)
+			}
+			RemoveCachedWebDAVUser(user)
+		}
+		executeAction(operationDelete, executor, ipAddress, actionObjectGroup, group.Name, &group)
+	}
+	return err
+}
+
+// GetGroups returns an array of groups respecting limit and offset
+func GetGroups(limit, offset int, order string) ([]Group, error) {
+	return provider.getGroups(limit, offset, order)
+}
+
+// EventRuleExists returns the event rule with the given name if it exists
+func EventRuleExists(name string) (EventRule, error) {
+	return provider.eventRuleExists(name)
+}
+
+// AddEventRule adds a new event rule
+func AddEventRule(rule *EventRule, executor, ipAddress string) error {
+	err := provider.addEventRule(rule)
+	if err == nil {
+		reloadEventRules()
+		executeAction(operationAdd, executor, ipAddress, actionObjectEventRule, rule.Name, rule)
+	}
+	return err
+}
+
+// UpdateEventRule updates an existing event rule
+func UpdateEventRule(rule *EventRule, executor, ipAddress string) error {
+	err := provider.updateEventRule(rule)
+	if err == nil {
+		reloadEventRules()
+		executeAction(operationUpdate, executor, ipAddress, actionObjectEventRule, rule.Name, rule)
+	}
+	return err
+}
+
+// DeleteEventRule deletes an existing event rule
+func DeleteEventRule(name, executor, ipAddress string) error {
+	rule, err := provider.eventRuleExists(name)
+	if err != nil {
+		return err
+	}
+	err = provider.deleteEventRule(&rule)
+	if err == nil {
+		reloadEventRules()
+		executeAction(operationDelete, executor, ipAddress, actionObjectEventRule, rule.Name, &rule)
+	}
+	return err
+}
+
+// GetEventRules returns an array of event rules respecting limit and offset
+func GetEventRules(limit, offset int, order string) ([]EventRule, error) {
+	return provider.getEventRules(limit, offset, order)
+}
+
+// DumpEventRules returns all the event rules
+func DumpEventRules() ([]EventRule, error) {
+	return provider.dumpEventRules()
+}
+
+// ScheduleExists returns the schedule with the given name if it exists
+func ScheduleExists(name string) (Schedule, error) {
+	return provider.scheduleExists(name)
+}
+
+// AddSchedule adds a new schedule
+func AddSchedule(schedule *Schedule, executor, ipAddress string) error {
+	err := provider.addSchedule(schedule)
+	if err == nil {
+		executeAction(operationAdd, executor, ipAddress, actionObjectSchedule, schedule.Name, schedule)
+	}
+	return err
+}
+
+// UpdateSchedule updates an existing schedule, the last run details are preserved
+func UpdateSchedule(schedule *Schedule, executor, ipAddress string) error {
+	err := provider.updateSchedule(schedule)
+	if err == nil {
+		executeAction(operationUpdate, executor, ipAddress, actionObjectSchedule, schedule.Name, schedule)
+	}
+	return err
+}
+
+// DeleteSchedule deletes an existing schedule
+func DeleteSchedule(name, executor, ipAddress string) error {
+	schedule, err := provider.scheduleExists(name)
+	if err != nil {
+		return err
+	}
+	err = provider.deleteSchedule(&schedule)
+	if err == nil {
+		executeAction(operationDelete, executor, ipAddress, actionObjectSchedule, schedule.Name, &schedule)
+	}
+	return err
+}
+
+// GetSchedules returns an array of schedules respecting limit and offset
+func GetSchedules(limit, offset int, order string) ([]Schedule, error) {
+	return provider.getSchedules(limit, offset, order)
+}
+
+// DumpSchedules returns all the schedules
+func DumpSchedules() ([]Schedule, error) {
+	return provider.dumpSchedules()
+}
+
+// ClaimScheduleRun marks the schedule with the given name as running at the specified time.
+// It returns false if the run was already claimed, for example by another instance
+// sharing the same data provider
+func ClaimScheduleRun(name string, runAt time.Time) (bool, error) {
+	return provider.claimScheduleRun(name, util.GetTimeAsMsSinceEpoch(runAt))
+}
+
+// SetScheduleRunResult stores the result of the last run for the schedule with the given name
+func SetScheduleRunResult(name string, runErr error) error {
+	if runErr != nil {
+		return provider.setScheduleRunResult(name, ScheduleRunStatusFailed, runErr.Error())
+	}
+	return provider.setScheduleRunResult(name, ScheduleRunStatusSuccess, "")
+}
+
 // AddUser adds a new SFTPGo user.
 func AddUser(user *User, executor, ipAddress string) error {
 	user.Filters.RecoveryCodes = nil
 	user.Filters.TOTPConfig = sdk.TOTPConfig{
 		Enabled: false,
 	}
+	if user.LastPasswordChange == 0 {
+		user.LastPasswordChange = util.GetTimeAsMsSinceEpoch(time.Now())
+	}
 	err := provider.addUser(user)
 	if err == nil {
 		executeAction(operationAdd, executor, ipAddress, actionObjectUser, user.Username, user)
@@ -1054,6 +1578,11 @@ func DeleteUser(username, executor, ipAddress string) error {
 		RemoveCachedWebDAVUser(user.Username)
 		delayedQuotaUpdater.resetUserQuota(username)
 		cachedPasswords.Remove(username)
+		provider.deleteAccountLockout(user.Username, AccountTypeUser) //nolint:errcheck
+		provider.deleteUserWebDAVLocks(user.Username)                 //nolint:errcheck
+		provider.deleteUserWebDAVProperties(user.Username)            //nolint:errcheck
+		provider.deleteUserS3AccessKeys(user.Username)                //nolint:errcheck
+		provider.deleteUserTusUploads(user.Username)                  //nolint:errcheck
 		executeAction(operationDelete, executor, ipAddress, actionObjectUser, user.Username, &user)
 	}
 	return err
@@ -1066,6 +1595,16 @@ func ReloadConfig() error {
 	return provider.reloadConfig()
 }
 
+// GetShares returns an array of shares respecting limit and offset
+func GetShares(limit, offset int, order, username string) ([]Share, error) {
+	return provider.getShares(limit, offset, order, username)
+}
+
+// GetShareUploads returns the files uploaded using the given share respecting limit and offset
+func GetShareUploads(shareID string, limit, offset int, order string) ([]ShareUpload, error) {
+	return provider.getShareUploads(shareID, limit, offset, order)
+}
+
 // GetAPIKeys returns an array of API keys respecting limit and offset
 func GetAPIKeys(limit, offset int, order string) ([]APIKey, error) {
 	return provider.getAPIKeys(limit, offset, order)
@@ -1100,6 +1639,7 @@ func UpdateFolder(folder *vfs.BaseVirtualFolder, users []string, executor, ipAdd
 				RemoveCachedWebDAVUser(user)
 			}
 		}
+		removeCachedGroupMembers(folder.Groups)
 	}
 	return err
 }
@@ -1120,6 +1660,7 @@ func DeleteFolder(folderName, executor, ipAddress string) error {
 			}
 			RemoveCachedWebDAVUser(user)
 		}
+		removeCachedGroupMembers(folder.Groups)
 		delayedQuotaUpdater.resetFolderQuota(folderName)
 	}
 	return err
@@ -1146,6 +1687,10 @@ func DumpData() (BackupData, error) {
 	if err != nil {
 		return data, err
 	}
+	groups, err := provider.dumpGroups()
+	if err != nil {
+		return data, err
+	}
 	admins, err := provider.dumpAdmins()
 	if err != nil {
 		return data, err
@@ -1156,8 +1701,29 @@ func DumpData() (BackupData, error) {
 	}
 	data.Users = users
 	data.Folders = folders
+	data.Groups = groups
 	data.Admins = admins
+	shares, err := provider.dumpShares()
+	if err != nil {
+		return data, err
+	}
+	rules, err := provider.dumpEventRules()
+	if err != nil {
+		return data, err
+	}
 	data.APIKeys = apiKeys
+	data.Shares = shares
+	schedules, err := provider.dumpSchedules()
+	if err != nil {
+		return data, err
+	}
+	data.EventRules = rules
+	data.Schedules = schedules
+	s3AccessKeys, err := provider.dumpS3AccessKeys()
+	if err != nil {
+		return data, err
+	}
+	data.S3AccessKeys = s3AccessKeys
 	data.Version = DumpVersion
 	return data, err
 }
@@ -1198,6 +1764,11 @@ func Close() error {
 		updateCachesTickerDone <- true
 		updateCachesTicker = nil
 	}
+	stopPasswordExpirationTimer()
+	stopEventStoreCleanupTimer()
+	eventsWriter.stop()
+	stopWebDAVLocksCleanupTimer()
+	stopMultipartUploadsCleanupTimer()
 	return provider.close()
 }
 
@@ -1233,7 +1804,8 @@ func buildUserHomeDir(user *User) {
 			return
 		}
 		switch user.FsConfig.Provider {
-		case sdk.SFTPFilesystemProvider, sdk.S3FilesystemProvider, sdk.AzureBlobFilesystemProvider, sdk.GCSFilesystemProvider:
+		case sdk.SFTPFilesystemProvider, sdk.FTPFilesystemProvider, sdk.WebDAVFilesystemProvider, sdk.S3FilesystemProvider,
+			sdk.AzureBlobFilesystemProvider, sdk.GCSFilesystemProvider:
 			if tempPath != "" {
 				user.HomeDir = filepath.Join(tempPath, user.Username)
 			} else {
@@ -1367,6 +1939,27 @@ func validateUserVirtualFolders(user *User) error {
 	return nil
 }
 
+func validateUserGroups(user *User) error {
+	if len(user.Groups) == 0 {
+		user.Groups = nil
+		return nil
+	}
+	groups := util.RemoveDuplicates(user.Groups)
+	for _, name := range groups {
+		if name == "" {
+			return util.NewValidationError("group name cannot be empty")
+		}
+		if _, err := provider.groupExists(name); err != nil {
+			if _, ok := err.(*util.RecordNotFoundError); ok {
+				return util.NewValidationError(fmt.Sprintf("group %#v does not exist", name))
+			}
+			return err
+		}
+	}
+	user.Groups = groups
+	return nil
+}
+
 func validateUserTOTPConfig(c *sdk.TOTPConfig, username string) error {
 	if !c.Enabled {
 		c.ConfigName = ""
@@ -1421,20 +2014,29 @@ func validatePermissions(user *User) error {
 	if len(user.Permissions) == 0 {
 		return util.NewValidationError("please grant some permissions to this user")
 	}
-	permissions := make(map[string][]string)
 	if _, ok := user.Permissions["/"]; !ok {
 		return util.NewValidationError("permissions for the root dir \"/\" must be set")
 	}
-	for dir, perms := range user.Permissions {
+	permissions, err := cleanPermissions(user.Permissions)
+	if err != nil {
+		return err
+	}
+	user.Permissions = permissions
+	return nil
+}
+
+func cleanPermissions(dirPerms map[string][]string) (map[string][]string, error) {
+	permissions := make(map[string][]string)
+	for dir, perms := range dirPerms {
 		if len(perms) == 0 && dir == "/" {
-			return util.NewValidationError(fmt.Sprintf("no permissions granted for the directory: %#v", dir))
+			return nil, util.NewValidationError(fmt.Sprintf("no permissions granted for the directory: %#v", dir))
 		}
 		if len(perms) > len(ValidPerms) {
-			return util.NewValidationError("invalid permissions")
+			return nil, util.NewValidationError("invalid permissions")
 		}
 		for _, p := range perms {
 			if !util.IsStringInSlice(p, ValidPerms) {
-				return util.NewValidationError(fmt.Sprintf("invalid permission: %#v", p))
+				return nil, util.NewValidationError(fmt.Sprintf("invalid permission: %#v", p))
 			}
 		}
 		cleanedDir := filepath.ToSlash(path.Clean(dir))
@@ -1442,10 +2044,10 @@ func validatePermissions(user *User) error {
 			cleanedDir = strings.TrimSuffix(cleanedDir, "/")
 		}
 		if !path.IsAbs(cleanedDir) {
-			return util.NewValidationError(fmt.Sprintf("cannot set permissions for non absolute path: %#v", dir))
+			return nil, util.NewValidationError(fmt.Sprintf("cannot set permissions for non absolute path: %#v", dir))
 		}
 		if dir != cleanedDir && cleanedDir == "/" {
-			return util.NewValidationError(fmt.Sprintf("cannot set permissions for invalid subdirectory: %#v is an alias for \"/\"", dir))
+			return nil, util.NewValidationError(fmt.Sprintf("cannot set permissions for invalid subdirectory: %#v is an alias for \"/\"", dir))
 		}
 		if util.IsStringInSlice(PermAny, perms) {
 			permissions[cleanedDir] = []string{PermAny}
@@ -1453,8 +2055,7 @@ func validatePermissions(user *User) error {
 			permissions[cleanedDir] = util.RemoveDuplicates(perms)
 		}
 	}
-	user.Permissions = permissions
-	return nil
+	return permissions, nil
 }
 
 func validatePublicKeys(user *User) error {
@@ -1520,6 +2121,30 @@ func validateFiltersPatternExtensions(user *User) error {
 	return nil
 }
 
+func validateDataRetentionPolicies(policies []sdk.DataRetentionPolicy) ([]sdk.DataRetentionPolicy, error) {
+	if len(policies) == 0 {
+		return nil, nil
+	}
+	paths := make(map[string]bool)
+	result := make([]sdk.DataRetentionPolicy, 0, len(policies))
+	for _, p := range policies {
+		if p.Path == "" || !path.IsAbs(p.Path) {
+			return nil, util.NewValidationError(fmt.Sprintf("invalid retention path %#v, it must be an absolute virtual path",
+				p.Path))
+		}
+		p.Path = util.CleanPath(p.Path)
+		if paths[p.Path] {
+			return nil, util.NewValidationError(fmt.Sprintf("duplicated retention path %#v", p.Path))
+		}
+		paths[p.Path] = true
+		if p.Retention < 0 {
+			return nil, util.NewValidationError(fmt.Sprintf("invalid retention %v for path %#v", p.Retention, p.Path))
+		}
+		result = append(result, p)
+	}
+	return result, nil
+}
+
 func checkEmptyFiltersStruct(user *User) {
 	if len(user.Filters.AllowedIP) == 0 {
 		user.Filters.AllowedIP = []string{}
@@ -1575,6 +2200,18 @@ func validateFilters(user *User) error {
 			return util.NewValidationError(fmt.Sprintf("invalid web client options %#v", opts))
 		}
 	}
+	if user.Filters.PasswordExpiration < 0 {
+		return util.NewValidationError("password expiration cannot be negative")
+	}
+	if (user.Filters.RequirePasswordChange || user.Filters.PasswordExpiration > 0) &&
+		util.IsStringInSlice(sdk.WebClientPasswordChangeDisabled, user.Filters.WebClient) {
+		return util.NewValidationError("password expiration and password change requirement cannot be set if the password change is disabled")
+	}
+	retention, err := validateDataRetentionPolicies(user.Filters.DataRetention)
+	if err != nil {
+		return err
+	}
+	user.Filters.DataRetention = retention
 	return validateFiltersPatternExtensions(user)
 }
 
@@ -1639,6 +2276,17 @@ func validateBaseParams(user *User) error {
 	if !filepath.IsAbs(user.HomeDir) {
 		return util.NewValidationError(fmt.Sprintf("home_dir must be an absolute path, actual value: %v", user.HomeDir))
 	}
+	return validateDataTransferLimits(user.UploadDataTransfer, user.DownloadDataTransfer, user.TotalDataTransfer,
+		user.DataTransferResetPeriod)
+}
+
+func validateDataTransferLimits(upload, download, total int64, resetPeriod sdk.DataTransferResetPeriod) error {
+	if upload < 0 || download < 0 || total < 0 {
+		return util.NewValidationError("data transfer limits cannot be negative")
+	}
+	if !resetPeriod.IsValid() {
+		return util.NewValidationError(fmt.Sprintf("invalid data transfer reset period %#v", resetPeriod))
+	}
 	return nil
 }
 
@@ -1662,6 +2310,7 @@ func createUserPasswordHash(user *User) error {
 			}
 			user.Password = pwd
 		}
+		user.LastPasswordChange = util.GetTimeAsMsSinceEpoch(time.Now())
 	}
 	return nil
 }
@@ -1688,6 +2337,15 @@ func ValidateFolder(folder *vfs.BaseVirtualFolder) error {
 	if folder.HasRedactedSecret() {
 		return errors.New("cannot save a folder with a redacted secret")
 	}
+	if err := validateDataTransferLimits(folder.UploadDataTransfer, folder.DownloadDataTransfer,
+		folder.TotalDataTransfer, folder.DataTransferResetPeriod); err != nil {
+		return err
+	}
+	retention, err := validateDataRetentionPolicies(folder.DataRetention)
+	if err != nil {
+		return err
+	}
+	folder.DataRetention = retention
 	if err := folder.FsConfig.Validate(folder); err != nil {
 		return err
 	}
@@ -1720,6 +2378,9 @@ func ValidateUser(user *User) error {
 	if err := validateUserVirtualFolders(user); err != nil {
 		return err
 	}
+	if err := validateUserGroups(user); err != nil {
+		return err
+	}
 	if user.Status < 0 || user.Status > 1 {
 		return util.NewValidationError(fmt.Sprintf("invalid user status: %v", user.Status))
 	}
@@ -1777,7 +2438,14 @@ func isPasswordOK(user *User, password string) (bool, error) {
 }
 
 func checkUserAndTLSCertificate(user *User, protocol string, tlsCert *x509.Certificate) (User, error) {
-	err := user.CheckLoginConditions()
+	if _, err := getAccountLockoutForLogin(user.Username, AccountTypeUser); err != nil {
+		return *user, err
+	}
+	err := user.LoadAndApplyGroupSettings()
+	if err != nil {
+		return *user, err
+	}
+	err = user.CheckLoginConditions()
 	if err != nil {
 		return *user, err
 	}
@@ -1796,7 +2464,21 @@ func checkUserAndTLSCertificate(user *User, protocol string, tlsCert *x509.Certi
 }
 
 func checkUserAndPass(user *User, password, ip, protocol string) (User, error) {
-	err := user.CheckLoginConditions()
+	lockout, err := getAccountLockoutForLogin(user.Username, AccountTypeUser)
+	if err != nil {
+		return *user, err
+	}
+	u, err := doCheckUserAndPass(user, password, ip, protocol)
+	updateAccountLockout(user.Username, AccountTypeUser, lockout, err)
+	return u, err
+}
+
+func doCheckUserAndPass(user *User, password, ip, protocol string) (User, error) {
+	err := user.LoadAndApplyGroupSettings()
+	if err != nil {
+		return *user, err
+	}
+	err = user.CheckLoginConditions()
 	if err != nil {
 		return *user, err
 	}
@@ -1836,7 +2518,23 @@ func checkUserAndPass(user *User, password, ip, protocol string) (User, error) {
 	if !match {
 		err = ErrInvalidCredentials
 	}
-	return *user, err
+	if err != nil {
+		return *user, err
+	}
+	return *user, checkPasswordChangeRequirement(user, protocol)
+}
+
+// checkPasswordChangeRequirement returns an error if the user must change the password
+// before login. Users can always login via HTTP to change their password
+func checkPasswordChangeRequirement(user *User, protocol string) error {
+	if protocol == protocolHTTP || !user.MustChangePassword() {
+		return nil
+	}
+	if user.Filters.RequirePasswordChange {
+		return fmt.Errorf("%w: please login to the WebClient and set a new password", ErrPasswordChangeRequired)
+	}
+	return fmt.Errorf("%w: the password expired on %v, please login to the WebClient and set a new one",
+		ErrPasswordChangeRequired, user.GetPasswordExpirationTime().UTC().Format("2006-01-02 15:04:05"))
 }
 
 func checkUserPasscode(user *User, password, protocol string) (string, error) {
@@ -1874,7 +2572,14 @@ func checkUserPasscode(user *User, password, protocol string) (string, error) {
 }
 
 func checkUserAndPubKey(user *User, pubKey []byte) (User, string, error) {
-	err := user.CheckLoginConditions()
+	if _, err := getAccountLockoutForLogin(user.Username, AccountTypeUser); err != nil {
+		return *user, "", err
+	}
+	err := user.LoadAndApplyGroupSettings()
+	if err != nil {
+		return *user, "", err
+	}
+	err = user.CheckLoginConditions()
 	if err != nil {
 		return *user, "", err
 	}
@@ -2039,6 +2744,8 @@ func checkCacheUpdates() {
 		webDAVUsersCache.swap(&user)
 		cachedPasswords.Remove(user.Username)
 	}
+	// event rules could be updated from a different instance
+	reloadEventRules()
 
 	lastCachesUpdate = checkTime
 	providerLog(logger.LevelDebug, "end caches check, new update time %v", util.GetTimeFromMsecSinceEpoch(lastCachesUpdate))
@@ -2134,6 +2841,10 @@ func doBuiltinKeyboardInteractiveAuth(user *User, client ssh.KeyboardInteractive
 	}
 	_, err = checkUserAndPass(user, answers[0], ip, protocol)
 	if err != nil {
+		if errors.Is(err, ErrPasswordChangeRequired) {
+			// show the reason to the client, the challenge has no questions
+			client(user.Username, err.Error(), nil, nil) //nolint:errcheck
+		}
 		return 0, err
 	}
 	if !user.Filters.TOTPConfig.Enabled || !util.IsStringInSlice(protocolSSH, user.Filters.TOTPConfig.Protocols) {
@@ -2352,15 +3063,24 @@ func executeKeyboardInteractiveProgram(user *User, authHook string, client ssh.K
 
 func doKeyboardInteractiveAuth(user *User, authHook string, client ssh.KeyboardInteractiveChallenge, ip, protocol string) (User, error) {
 	var authResult int
-	var err error
+	lockout, err := getAccountLockoutForLogin(user.Username, AccountTypeUser)
+	if err != nil {
+		return *user, err
+	}
+	if err = user.LoadAndApplyGroupSettings(); err != nil {
+		return *user, err
+	}
+	// the builtin authentication tracks failed logins within checkUserAndPass
 	if plugin.Handler.HasAuthScope(plugin.AuthScopeKeyboardInteractive) {
 		authResult, err = executeKeyboardInteractivePlugin(user, client, ip, protocol)
+		updateAccountLockout(user.Username, AccountTypeUser, lockout, getKeyboardInteractiveLoginError(authResult, err))
 	} else if authHook != "" {
 		if strings.HasPrefix(authHook, "http") {
 			authResult, err = executeKeyboardInteractiveHTTPHook(user, authHook, client, ip, protocol)
 		} else {
 			authResult, err = executeKeyboardInteractiveProgram(user, authHook, client, ip, protocol)
 		}
+		updateAccountLockout(user.Username, AccountTypeUser, lockout, getKeyboardInteractiveLoginError(authResult, err))
 	} else {
 		authResult, err = doBuiltinKeyboardInteractiveAuth(user, client, ip, protocol)
 	}
@@ -2377,6 +3097,16 @@ func doKeyboardInteractiveAuth(user *User, authHook string, client ssh.KeyboardI
 	return *user, nil
 }
 
+func getKeyboardInteractiveLoginError(authResult int, err error) error {
+	if err != nil {
+		return err
+	}
+	if authResult != 1 {
+		return ErrInvalidCredentials
+	}
+	return nil
+}
+
 func isCheckPasswordHookDefined(protocol string) bool {
 	if config.CheckPasswordHook == "" {
 		return false
@@ -0,0 +1,683 @@
+package dataprovider
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/drakkan/sftpgo/v2/logger"
+	"github.com/drakkan/sftpgo/v2/util"
+)
+
+// EventTrigger defines the supported event rule triggers
+type EventTrigger int
+
+// Supported event rule triggers
+const (
+	// Filesystem events such as upload, download, mkdir ...
+	EventTriggerFsEvent EventTrigger = iota + 1
+	// Provider events such as add, update, delete
+	EventTriggerProviderEvent
+)
+
+// EventActionType defines the supported event rule action types
+type EventActionType int
+
+// Supported event rule action types
+const (
+	EventActionTypeHTTP EventActionType = iota + 1
+	EventActionTypeCommand
+	EventActionTypeEmail
+	// Copy the file that triggered the event to another virtual path
+	EventActionTypeFsCopy
+	// Rename (move) the file that triggered the event to another virtual path
+	EventActionTypeFsRename
+	// Delete the file that triggered the event
+	EventActionTypeFsDelete
+)
+
+const (
+	maxEventActionTimeout = 300
+)
+
+var (
+	// SupportedRuleFsEvents defines the supported filesystem events for event rules
+	SupportedRuleFsEvents = []string{"upload", "download", "delete", "rename", "copy", "mkdir", "rmdir", "ssh_cmd"}
+	// SupportedRuleProviderEvents defines the supported provider events for event rules
+	SupportedRuleProviderEvents = []string{operationAdd, operationUpdate, operationDelete}
+	// SupportedRuleProviderObjects defines the supported provider objects for event rules
+	SupportedRuleProviderObjects = []string{actionObjectUser, actionObjectGroup, actionObjectAdmin,
+		actionObjectAPIKey, actionObjectShare, actionObjectEventRule, actionObjectSchedule}
+	supportedHTTPActionMethods = []string{http.MethodPost, http.MethodGet, http.MethodPut}
+)
+
+// EventManagerConfig defines the configuration for the event manager
+type EventManagerConfig struct {
+	// Absolute paths to the commands allowed for command actions.
+	// Command actions cannot be added if no command is enabled
+	EnabledCommands []string `json:"enabled_commands" mapstructure:"enabled_commands"`
+}
+
+func (c *EventManagerConfig) validate() error {
+	for _, cmd := range c.EnabledCommands {
+		if !filepath.IsAbs(cmd) {
+			return fmt.Errorf("invalid event manager command %#v, it must be an absolute path", cmd)
+		}
+	}
+	return nil
+}
+
+// IsEventCommandEnabled returns true if the specified command can be executed
+// by command actions
+func IsEventCommandEnabled(cmd string) bool {
+	return util.IsStringInSlice(cmd, config.EventManager.EnabledCommands)
+}
+
+// event rules callbacks, they are set within the common package to avoid import cycles
+var (
+	fnReloadEventRules           func()
+	fnHandleRuleForProviderEvent func(operation, executor, ip, objectType, objectName string)
+)
+
+// SetEventRulesCallbacks sets the callbacks used to reload the event rules
+// after a change and to handle the provider events
+func SetEventRulesCallbacks(reload func(), handleProviderEvent func(operation, executor, ip, objectType, objectName string)) {
+	fnReloadEventRules = reload
+	fnHandleRuleForProviderEvent = handleProviderEvent
+}
+
+func reloadEventRules() {
+	if fnReloadEventRules != nil {
+		fnReloadEventRules()
+	}
+}
+
+// GetTriggerAsString returns the event trigger as string
+func (t EventTrigger) GetTriggerAsString() string {
+	switch t {
+	case EventTriggerFsEvent:
+		return "Filesystem event"
+	case EventTriggerProviderEvent:
+		return "Provider event"
+	default:
+		return "Unknown"
+	}
+}
+
+// GetTypeAsString returns the event action type as string
+func (t EventActionType) GetTypeAsString() string {
+	switch t {
+	case EventActionTypeHTTP:
+		return "HTTP"
+	case EventActionTypeCommand:
+		return "Command"
+	case EventActionTypeEmail:
+		return "Email"
+	case EventActionTypeFsCopy:
+		return "Copy"
+	case EventActionTypeFsRename:
+		return "Rename"
+	case EventActionTypeFsDelete:
+		return "Delete"
+	default:
+		return "Unknown"
+	}
+}
+
+// ConditionPattern defines a pattern for condition filters
+type ConditionPattern struct {
+	// Shell like pattern, for example "/inbound/*.csv"
+	Pattern string `json:"pattern"`
+	// If true the condition is satisfied if the pattern does not match
+	InverseMatch bool `json:"inverse_match,omitempty"`
+}
+
+func (p *ConditionPattern) validate() error {
+	if p.Pattern == "" {
+		return util.NewValidationError("empty condition pattern not allowed")
+	}
+	if _, err := path.Match(p.Pattern, "abc"); err != nil {
+		return util.NewValidationError(fmt.Sprintf("invalid condition pattern %#v", p.Pattern))
+	}
+	return nil
+}
+
+func (p *ConditionPattern) match(name string) bool {
+	matched, err := path.Match(p.Pattern, name)
+	if err != nil {
+		return false
+	}
+	if p.InverseMatch {
+		return !matched
+	}
+	return matched
+}
+
+// ConditionOptions defines the options for event conditions
+type ConditionOptions struct {
+	// Usernames for filesystem events, object names for provider events
+	Names []ConditionPattern `json:"names,omitempty"`
+	// Group names, the user that triggered a filesystem event must be a member
+	// of at least one group matching these patterns
+	GroupNames []ConditionPattern `json:"group_names,omitempty"`
+	// Virtual paths for filesystem events
+	FsPaths []ConditionPattern `json:"fs_paths,omitempty"`
+	// Protocols for filesystem events
+	Protocols []string `json:"protocols,omitempty"`
+	// Provider objects for provider events
+	ProviderObjects []string `json:"provider_objects,omitempty"`
+	// File size limits for filesystem events, 0 means no limit
+	MinFileSize int64 `json:"min_size,omitempty"`
+	MaxFileSize int64 `json:"max_size,omitempty"`
+	// Filesystem events statuses: 1 OK, 2 failed, 3 quota exceeded. Empty means any status
+	Statuses []int `json:"statuses,omitempty"`
+}
+
+func (o *ConditionOptions) getACopy() ConditionOptions {
+	names := make([]ConditionPattern, len(o.Names))
+	copy(names, o.Names)
+	groupNames := make([]ConditionPattern, len(o.GroupNames))
+	copy(groupNames, o.GroupNames)
+	fsPaths := make([]ConditionPattern, len(o.FsPaths))
+	copy(fsPaths, o.FsPaths)
+	protocols := make([]string, len(o.Protocols))
+	copy(protocols, o.Protocols)
+	providerObjects := make([]string, len(o.ProviderObjects))
+	copy(providerObjects, o.ProviderObjects)
+	statuses := make([]int, len(o.Statuses))
+	copy(statuses, o.Statuses)
+
+	return ConditionOptions{
+		Names:           names,
+		GroupNames:      groupNames,
+		FsPaths:         fsPaths,
+		Protocols:       protocols,
+		ProviderObjects: providerObjects,
+		MinFileSize:     o.MinFileSize,
+		MaxFileSize:     o.MaxFileSize,
+		Statuses:        statuses,
+	}
+}
+
+func (o *ConditionOptions) validate(trigger EventTrigger) error {
+	for idx := range o.Names {
+		if err := o.Names[idx].validate(); err != nil {
+			return err
+		}
+	}
+	for idx := range o.GroupNames {
+		if err := o.GroupNames[idx].validate(); err != nil {
+			return err
+		}
+	}
+	for idx := range o.FsPaths {
+		if err := o.FsPaths[idx].validate(); err != nil {
+			return err
+		}
+	}
+	switch trigger {
+	case EventTriggerFsEvent:
+		o.ProviderObjects = nil
+		if o.MinFileSize < 0 || o.MaxFileSize < 0 {
+			return util.NewValidationError("file size limits cannot be negative")
+		}
+		if o.MaxFileSize > 0 && o.MinFileSize > o.MaxFileSize {
+			return util.NewValidationError(fmt.Sprintf("invalid file size range: %v-%v", o.MinFileSize, o.MaxFileSize))
+		}
+		for _, status := range o.Statuses {
+			if status < 1 || status > 3 {
+				return util.NewValidationError(fmt.Sprintf("invalid status: %v", status))
+			}
+		}
+	default:
+		o.GroupNames = nil
+		o.FsPaths = nil
+		o.Protocols = nil
+		o.MinFileSize = 0
+		o.MaxFileSize = 0
+		o.Statuses = nil
+		for _, obj := range o.ProviderObjects {
+			if !util.IsStringInSlice(obj, SupportedRuleProviderObjects) {
+				return util.NewValidationError(fmt.Sprintf("unsupported provider object: %#v", obj))
+			}
+		}
+	}
+	return nil
+}
+
+// EventConditions defines the conditions for an event rule
+type EventConditions struct {
+	// Only one between FsEvents and ProviderEvents is allowed
+	FsEvents       []string         `json:"fs_events,omitempty"`
+	ProviderEvents []string         `json:"provider_events,omitempty"`
+	Options        ConditionOptions `json:"options"`
+}
+
+func (c *EventConditions) getACopy() EventConditions {
+	fsEvents := make([]string, len(c.FsEvents))
+	copy(fsEvents, c.FsEvents)
+	providerEvents := make([]string, len(c.ProviderEvents))
+	copy(providerEvents, c.ProviderEvents)
+
+	return EventConditions{
+		FsEvents:       fsEvents,
+		ProviderEvents: providerEvents,
+		Options:        c.Options.getACopy(),
+	}
+}
+
+func (c *EventConditions) validate(trigger EventTrigger) error {
+	switch trigger {
+	case EventTriggerFsEvent:
+		c.ProviderEvents = nil
+		if len(c.FsEvents) == 0 {
+			return util.NewValidationError("at least one filesystem event is required")
+		}
+		for _, ev := range c.FsEvents {
+			if !util.IsStringInSlice(ev, SupportedRuleFsEvents) {
+				return util.NewValidationError(fmt.Sprintf("unsupported fs event: %#v", ev))
+			}
+		}
+	case EventTriggerProviderEvent:
+		c.FsEvents = nil
+		if len(c.ProviderEvents) == 0 {
+			return util.NewValidationError("at least one provider event is required")
+		}
+		for _, ev := range c.ProviderEvents {
+			if !util.IsStringInSlice(ev, SupportedRuleProviderEvents) {
+				return util.NewValidationError(fmt.Sprintf("unsupported provider event: %#v", ev))
+			}
+		}
+	}
+	return c.Options.validate(trigger)
+}
+
+// KeyValue defines a key/value pair
+type KeyValue struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+// EventActionHTTPConfig defines the configuration for an HTTP event action
+type EventActionHTTPConfig struct {
+	Endpoint string     `json:"endpoint"`
+	Method   string     `json:"method"`
+	Headers  []KeyValue `json:"headers,omitempty"`
+	// Request body, placeholders are supported. Ignored for GET requests
+	Body string `json:"body,omitempty"`
+	// Timeout in seconds
+	Timeout int `json:"timeout"`
+}
+
+// GetHeadersAsString returns the HTTP headers as "key: value" lines
+func (c *EventActionHTTPConfig) GetHeadersAsString() string {
+	var lines []string
+	for _, kv := range c.Headers {
+		lines = append(lines, fmt.Sprintf("%v: %v", kv.Key, kv.Value))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (c *EventActionHTTPConfig) validate() error {
+	if c.Endpoint == "" {
+		return util.NewValidationError("HTTP endpoint is required")
+	}
+	u, err := url.Parse(c.Endpoint)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return util.NewValidationError(fmt.Sprintf("invalid HTTP endpoint %#v", c.Endpoint))
+	}
+	if !util.IsStringInSlice(c.Method, supportedHTTPActionMethods) {
+		return util.NewValidationError(fmt.Sprintf("unsupported HTTP method: %#v", c.Method))
+	}
+	for _, kv := range c.Headers {
+		if kv.Key == "" || kv.Value == "" {
+			return util.NewValidationError("invalid HTTP header, key and value are required")
+		}
+	}
+	if c.Timeout < 1 || c.Timeout > maxEventActionTimeout {
+		return util.NewValidationError(fmt.Sprintf("invalid HTTP timeout %v, allowed range: 1-%v",
+			c.Timeout, maxEventActionTimeout))
+	}
+	return nil
+}
+
+// EventActionCommandConfig defines the configuration for a command event action
+type EventActionCommandConfig struct {
+	// Absolute path to the command to execute
+	Cmd string `json:"cmd"`
+	// Timeout in seconds
+	Timeout int `json:"timeout"`
+	// Environment variables to set, placeholders are supported for the values
+	EnvVars []KeyValue `json:"env_vars,omitempty"`
+}
+
+// GetEnvVarsAsString returns the environment variables as "key=value" lines
+func (c *EventActionCommandConfig) GetEnvVarsAsString() string {
+	var lines []string
+	for _, kv := range c.EnvVars {
+		lines = append(lines, fmt.Sprintf("%v=%v", kv.Key, kv.Value))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (c *EventActionCommandConfig) validate() error {
+	if c.Cmd == "" || !filepath.IsAbs(c.Cmd) {
+		return util.NewValidationError(fmt.Sprintf("invalid command %#v, it must be an absolute path", c.Cmd))
+	}
+	if len(config.EventManager.EnabledCommands) == 0 {
+		return util.NewValidationError("command actions are disabled, no command is enabled in the configuration")
+	}
+	if !IsEventCommandEnabled(c.Cmd) {
+		return util.NewValidationError(fmt.Sprintf("command %#v is not enabled", c.Cmd))
+	}
+	if c.Timeout < 1 || c.Timeout > maxEventActionTimeout {
+		return util.NewValidationError(fmt.Sprintf("invalid command timeout %v, allowed range: 1-%v",
+			c.Timeout, maxEventActionTimeout))
+	}
+	for _, kv := range c.EnvVars {
+		if kv.Key == "" {
+			return util.NewValidationError("invalid command environment variable, the key is required")
+		}
+	}
+	return nil
+}
+
+// EventActionEmailConfig defines the configuration for an email event action
+type EventActionEmailConfig struct {
+	Recipients []string `json:"recipients"`
+	// Subject and body support placeholders
+	Subject string `json:"subject"`
+	Body    string `json:"body"`
+}
+
+// GetRecipientsAsString returns the list of recipients as comma separated string
+func (c *EventActionEmailConfig) GetRecipientsAsString() string {
+	return strings.Join(c.Recipients, ",")
+}
+
+func (c *EventActionEmailConfig) validate() error {
+	var recipients []string
+	for _, r := range c.Recipients {
+		r = strings.TrimSpace(r)
+		if r == "" {
+			continue
+		}
+		if !emailRegex.MatchString(r) {
+			return util.NewValidationError(fmt.Sprintf("email %#v is not valid", r))
+		}
+		recipients = append(recipients, r)
+	}
+	c.Recipients = util.RemoveDuplicates(recipients)
+	if len(c.Recipients) == 0 {
+		return util.NewValidationError("at least one email recipient is required")
+	}
+	if c.Subject == "" {
+		return util.NewValidationError("email subject is required")
+	}
+	if c.Body == "" {
+		return util.NewValidationError("email body is required")
+	}
+	return nil
+}
+
+// EventActionFsConfig defines the configuration for filesystem event actions
+type EventActionFsConfig struct {
+	// Target virtual path for copy and rename actions, placeholders are supported.
+	// If the target ends with "/" the file name is preserved
+	Target string `json:"target,omitempty"`
+}
+
+// EventAction defines an action to execute if the rule conditions match
+type EventAction struct {
+	Type        EventActionType          `json:"type"`
+	HTTPConfig  EventActionHTTPConfig    `json:"http_config,omitempty"`
+	CmdConfig   EventActionCommandConfig `json:"cmd_config,omitempty"`
+	EmailConfig EventActionEmailConfig   `json:"email_config,omitempty"`
+	FsConfig    EventActionFsConfig      `json:"fs_config,omitempty"`
+}
+
+func (a *EventAction) getACopy() EventAction {
+	headers := make([]KeyValue, len(a.HTTPConfig.Headers))
+	copy(headers, a.HTTPConfig.Headers)
+	envVars := make([]KeyValue, len(a.CmdConfig.EnvVars))
+	copy(envVars, a.CmdConfig.EnvVars)
+	recipients := make([]string, len(a.EmailConfig.Recipients))
+	copy(recipients, a.EmailConfig.Recipients)
+
+	return EventAction{
+		Type: a.Type,
+		HTTPConfig: EventActionHTTPConfig{
+			Endpoint: a.HTTPConfig.Endpoint,
+			Method:   a.HTTPConfig.Method,
+			Headers:  headers,
+			Body:     a.HTTPConfig.Body,
+			Timeout:  a.HTTPConfig.Timeout,
+		},
+		CmdConfig: EventActionCommandConfig{
+			Cmd:     a.CmdConfig.Cmd,
+			Timeout: a.CmdConfig.Timeout,
+			EnvVars: envVars,
+		},
+		EmailConfig: EventActionEmailConfig{
+			Recipients: recipients,
+			Subject:    a.EmailConfig.Subject,
+			Body:       a.EmailConfig.Body,
+		},
+		FsConfig: EventActionFsConfig{
+			Target: a.FsConfig.Target,
+		},
+	}
+}
+
+func (a *EventAction) validate(trigger EventTrigger) error {
+	// we only keep the configuration for the defined action type
+	switch a.Type {
+	case EventActionTypeHTTP:
+		a.CmdConfig = EventActionCommandConfig{}
+		a.EmailConfig = EventActionEmailConfig{}
+		a.FsConfig = EventActionFsConfig{}
+		return a.HTTPConfig.validate()
+	case EventActionTypeCommand:
+		a.HTTPConfig = EventActionHTTPConfig{}
+		a.EmailConfig = EventActionEmailConfig{}
+		a.FsConfig = EventActionFsConfig{}
+		return a.CmdConfig.validate()
+	case EventActionTypeEmail:
+		a.HTTPConfig = EventActionHTTPConfig{}
+		a.CmdConfig = EventActionCommandConfig{}
+		a.FsConfig = EventActionFsConfig{}
+		return a.EmailConfig.validate()
+	case EventActionTypeFsCopy, EventActionTypeFsRename, EventActionTypeFsDelete:
+		a.HTTPConfig = EventActionHTTPConfig{}
+		a.CmdConfig = EventActionCommandConfig{}
+		a.EmailConfig = EventActionEmailConfig{}
+		if trigger != EventTriggerFsEvent {
+			return util.NewValidationError(fmt.Sprintf("action %#v is only supported for filesystem events",
+				a.Type.GetTypeAsString()))
+		}
+		if a.Type == EventActionTypeFsDelete {
+			a.FsConfig.Target = ""
+			return nil
+		}
+		if a.FsConfig.Target == "" || !strings.HasPrefix(a.FsConfig.Target, "/") {
+			return util.NewValidationError(fmt.Sprintf("invalid target path %#v, it must be an absolute virtual path",
+				a.FsConfig.Target))
+		}
+		return nil
+	default:
+		return util.NewValidationError(fmt.Sprintf("unsupported action type: %v", a.Type))
+	}
+}
+
+// EventRule defines the trigger, conditions and actions for an event rule
+type EventRule struct {
+	// Data provider unique identifier
+	ID int64 `json:"id"`
+	// Rule name
+	Name string `json:"name"`
+	// optional description
+	Description string `json:"description,omitempty"`
+	// creation time as unix timestamp in milliseconds
+	CreatedAt int64 `json:"created_at"`
+	// last update time as unix timestamp in milliseconds
+	UpdatedAt int64 `json:"updated_at"`
+	// Event trigger
+	Trigger EventTrigger `json:"trigger"`
+	// Event conditions
+	Conditions EventConditions `json:"conditions"`
+	// Actions to execute, in order, if the conditions match
+	Actions []EventAction `json:"actions"`
+}
+
+// GetActionsAsString returns the action types as comma separated string
+func (r *EventRule) GetActionsAsString() string {
+	actions := make([]string, 0, len(r.Actions))
+	for _, action := range r.Actions {
+		actions = append(actions, action.Type.GetTypeAsString())
+	}
+	return strings.Join(actions, ",")
+}
+
+// GetEventsAsString returns the events as comma separated string
+func (r *EventRule) GetEventsAsString() string {
+	if r.Trigger == EventTriggerProviderEvent {
+		return strings.Join(r.Conditions.ProviderEvents, ",")
+	}
+	return strings.Join(r.Conditions.FsEvents, ",")
+}
+
+// RenderAsJSON implements the renderer interface used within plugins
+func (r *EventRule) RenderAsJSON(reload bool) ([]byte, error) {
+	if reload {
+		rule, err := provider.eventRuleExists(r.Name)
+		if err != nil {
+			providerLog(logger.LevelWarn, "unable to reload event rule before rendering as json: %v", err)
+			return nil, err
+		}
+		return json.Marshal(rule)
+	}
+	return json.Marshal(r)
+}
+
+func (r *EventRule) validate() error {
+	if r.Name == "" {
+		return util.NewValidationError("rule name is mandatory")
+	}
+	if !config.SkipNaturalKeysValidation && !usernameRegex.MatchString(r.Name) {
+		return util.NewValidationError(fmt.Sprintf("rule name %#v is not valid, the following characters are allowed: a-zA-Z0-9-_.~",
+			r.Name))
+	}
+	if r.Trigger != EventTriggerFsEvent && r.Trigger != EventTriggerProviderEvent {
+		return util.NewValidationError(fmt.Sprintf("unsupported rule trigger: %v", r.Trigger))
+	}
+	if err := r.Conditions.validate(r.Trigger); err != nil {
+		return err
+	}
+	if len(r.Actions) == 0 {
+		return util.NewValidationError("at least one action is required")
+	}
+	for idx := range r.Actions {
+		if err := r.Actions[idx].validate(r.Trigger); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *EventRule) getACopy() EventRule {
+	actions := make([]EventAction, 0, len(r.Actions))
+	for idx := range r.Actions {
+		actions = append(actions, r.Actions[idx].getACopy())
+	}
+
+	return EventRule{
+		ID:          r.ID,
+		Name:        r.Name,
+		Description: r.Description,
+		CreatedAt:   r.CreatedAt,
+		UpdatedAt:   r.UpdatedAt,
+		Trigger:     r.Trigger,
+		Conditions:  r.Conditions.getACopy(),
+		Actions:     actions,
+	}
+}
+
+// MatchesFsEvent returns true if the rule conditions match the specified filesystem event
+func (r *EventRule) MatchesFsEvent(operation, username, virtualPath, protocol string, groups []string,
+	fileSize int64, status int,
+) bool {
+	if r.Trigger != EventTriggerFsEvent || !util.IsStringInSlice(operation, r.Conditions.FsEvents) {
+		return false
+	}
+	options := &r.Conditions.Options
+	if !matchConditionPatterns(options.Names, username) {
+		return false
+	}
+	if len(options.GroupNames) > 0 {
+		if len(groups) == 0 {
+			// users without groups only match inverse patterns
+			groups = []string{""}
+		}
+		matched := false
+		for _, group := range groups {
+			if matchConditionPatterns(options.GroupNames, group) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	if !matchConditionPatterns(options.FsPaths, virtualPath) {
+		return false
+	}
+	if len(options.Protocols) > 0 && !util.IsStringInSlice(protocol, options.Protocols) {
+		return false
+	}
+	if options.MinFileSize > 0 && fileSize < options.MinFileSize {
+		return false
+	}
+	if options.MaxFileSize > 0 && fileSize > options.MaxFileSize {
+		return false
+	}
+	if len(options.Statuses) > 0 {
+		for _, s := range options.Statuses {
+			if s == status {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+// MatchesProviderEvent returns true if the rule conditions match the specified provider event
+func (r *EventRule) MatchesProviderEvent(operation, objectType, objectName string) bool {
+	if r.Trigger != EventTriggerProviderEvent || !util.IsStringInSlice(operation, r.Conditions.ProviderEvents) {
+		return false
+	}
+	options := &r.Conditions.Options
+	if len(options.ProviderObjects) > 0 && !util.IsStringInSlice(objectType, options.ProviderObjects) {
+		return false
+	}
+	return matchConditionPatterns(options.Names, objectName)
+}
+
+// matchConditionPatterns returns true if the name matches at least one of the specified
+// patterns or if no pattern is defined
+func matchConditionPatterns(patterns []ConditionPattern, name string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+	for idx := range patterns {
+		if patterns[idx].match(name) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,421 @@
+package dataprovider
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/rs/xid"
+
+	"github.com/drakkan/sftpgo/v2/logger"
+	"github.com/drakkan/sftpgo/v2/util"
+)
+
+const (
+	eventStoreCleanupInterval = 1 * time.Hour
+	// maximum number of events waiting to be stored, additional events are discarded
+	eventStoreQueueSize = 1024
+)
+
+var (
+	eventStoreCleanupTicker     *time.Ticker
+	eventStoreCleanupTickerDone chan bool
+	// identifier stored with each event, it allows to distinguish SFTPGo instances
+	// sharing the same data provider. It is set on provider initialization
+	eventStoreInstanceID string
+	eventsWriter         eventStoreWriter
+)
+
+// EventStoreConfig defines the configuration for the built-in events store
+type EventStoreConfig struct {
+	// Filesystem events to store, for example upload, download, delete, rename, mkdir, rmdir, ssh_cmd
+	FsEvents []string `json:"fs_events" mapstructure:"fs_events"`
+	// Provider events to store: add, update, delete
+	ProviderEvents []string `json:"provider_events" mapstructure:"provider_events"`
+	// Provider objects to store events for: user, group, admin, api_key, share
+	ProviderObjects []string `json:"provider_objects" mapstructure:"provider_objects"`
+	// Events older than the specified number of hours are automatically removed.
+	// 0 means that events are never removed
+	Retention int `json:"retention" mapstructure:"retention"`
+}
+
+// IsEnabled returns true if at least a filesystem or provider event must be stored
+func (c *EventStoreConfig) IsEnabled() bool {
+	if len(c.FsEvents) > 0 {
+		return true
+	}
+	if len(c.ProviderEvents) > 0 && len(c.ProviderObjects) > 0 {
+		return true
+	}
+	return false
+}
+
+func (c *EventStoreConfig) validate() error {
+	if c.Retention < 0 {
+		return fmt.Errorf("invalid event store retention: %v", c.Retention)
+	}
+	return nil
+}
+
+// FsEvent defines a filesystem event stored within the built-in events store
+type FsEvent struct {
+	ID                string `json:"id"`
+	Timestamp         int64  `json:"timestamp"`
+	Action            string `json:"action"`
+	Username          string `json:"username"`
+	FsPath            string `json:"fs_path"`
+	FsTargetPath      string `json:"fs_target_path,omitempty"`
+	VirtualPath       string `json:"virtual_path"`
+	VirtualTargetPath string `json:"virtual_target_path,omitempty"`
+	SSHCmd            string `json:"ssh_cmd,omitempty"`
+	FileSize          int64  `json:"file_size,omitempty"`
+	Status            int    `json:"status"`
+	Protocol          string `json:"protocol"`
+	IP                string `json:"ip,omitempty"`
+	InstanceID        string `json:"instance_id,omitempty"`
+}
+
+// ProviderEvent defines a provider event stored within the built-in events store
+type ProviderEvent struct {
+	ID         string `json:"id"`
+	Timestamp  int64  `json:"timestamp"`
+	Action     string `json:"action"`
+	Username   string `json:"username"`
+	IP         string `json:"ip,omitempty"`
+	ObjectType string `json:"object_type"`
+	ObjectName string `json:"object_name"`
+	InstanceID string `json:"instance_id,omitempty"`
+}
+
+type eventSearch struct {
+	// Events with a timestamp, in nanoseconds, lower than this value are excluded. 0 means no limit
+	StartTimestamp int64
+	// Events with a timestamp, in nanoseconds, greater than this value are excluded. 0 means no limit
+	EndTimestamp int64
+	Actions      []string
+	Username     string
+	IP           string
+	InstanceIDs  []string
+	// Events with these IDs are excluded, this is useful for cursor based pagination
+	ExcludeIDs []string
+	Limit      int
+	// OrderASC or OrderDESC, events are ordered by timestamp
+	Order string
+}
+
+func (s *eventSearch) matches(id string, timestamp int64, action, username, ip, instanceID string) bool {
+	if s.StartTimestamp > 0 && timestamp < s.StartTimestamp {
+		return false
+	}
+	if s.EndTimestamp > 0 && timestamp > s.EndTimestamp {
+		return false
+	}
+	if len(s.Actions) > 0 && !util.IsStringInSlice(action, s.Actions) {
+		return false
+	}
+	if s.Username != "" && username != s.Username {
+		return false
+	}
+	if s.IP != "" && ip != s.IP {
+		return false
+	}
+	if len(s.InstanceIDs) > 0 && !util.IsStringInSlice(instanceID, s.InstanceIDs) {
+		return false
+	}
+	return !util.IsStringInSlice(id, s.ExcludeIDs)
+}
+
+// FsEventSearch defines the filters for filesystem events searches
+type FsEventSearch struct {
+	eventSearch
+	SSHCmd    string
+	Protocols []string
+	Statuses  []int32
+}
+
+// NewFsEventSearch returns a filesystem events search with the specified filters
+func NewFsEventSearch(startTimestamp, endTimestamp int64, username, ip, sshCmd string, actions, protocols,
+	instanceIDs, excludeIDs []string, statuses []int32, limit int, order string,
+) *FsEventSearch {
+	return &FsEventSearch{
+		eventSearch: eventSearch{
+			StartTimestamp: startTimestamp,
+			EndTimestamp:   endTimestamp,
+			Actions:        actions,
+			Username:       username,
+			IP:             ip,
+			InstanceIDs:    instanceIDs,
+			ExcludeIDs:     excludeIDs,
+			Limit:          limit,
+			Order:          order,
+		},
+		SSHCmd:    sshCmd,
+		Protocols: protocols,
+		Statuses:  statuses,
+	}
+}
+
+func (s *FsEventSearch) matches(event *FsEvent) bool {
+	if !s.eventSearch.matches(event.ID, event.Timestamp, event.Action, event.Username, event.IP, event.InstanceID) {
+		return false
+	}
+	if s.SSHCmd != "" && event.SSHCmd != s.SSHCmd {
+		return false
+	}
+	if len(s.Protocols) > 0 && !util.IsStringInSlice(event.Protocol, s.Protocols) {
+		return false
+	}
+	if len(s.Statuses) > 0 {
+		for _, status := range s.Statuses {
+			if int32(event.Status) == status {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+// ProviderEventSearch defines the filters for provider events searches
+type ProviderEventSearch struct {
+	eventSearch
+	ObjectName  string
+	ObjectTypes []string
+}
+
+// NewProviderEventSearch returns a provider events search with the specified filters
+func NewProviderEventSearch(startTimestamp, endTimestamp int64, username, ip, objectName string, limit int,
+	order string, actions, objectTypes, instanceIDs, excludeIDs []string,
+) *ProviderEventSearch {
+	return &ProviderEventSearch{
+		eventSearch: eventSearch{
+			StartTimestamp: startTimestamp,
+			EndTimestamp:   endTimestamp,
+			Actions:        actions,
+			Username:       username,
+			IP:             ip,
+			InstanceIDs:    instanceIDs,
+			ExcludeIDs:     excludeIDs,
+			Limit:          limit,
+			Order:          order,
+		},
+		ObjectName:  objectName,
+		ObjectTypes: objectTypes,
+	}
+}
+
+func (s *ProviderEventSearch) matches(event *ProviderEvent) bool {
+	if !s.eventSearch.matches(event.ID, event.Timestamp, event.Action, event.Username, event.IP, event.InstanceID) {
+		return false
+	}
+	if s.ObjectName != "" && event.ObjectName != s.ObjectName {
+		return false
+	}
+	if len(s.ObjectTypes) > 0 && !util.IsStringInSlice(event.ObjectType, s.ObjectTypes) {
+		return false
+	}
+	return true
+}
+
+// IsEventStoreEnabled returns true if the built-in events store is enabled
+func IsEventStoreEnabled() bool {
+	return config.EventStore.IsEnabled()
+}
+
+// AddFsEvent stores the specified filesystem event, if configured
+func AddFsEvent(timestamp int64, action, username, fsPath, fsTargetPath, sshCmd, protocol, ip, virtualPath,
+	virtualTargetPath string, fileSize int64, status int,
+) {
+	if !util.IsStringInSlice(action, config.EventStore.FsEvents) {
+		return
+	}
+	event := &FsEvent{
+		ID:                xid.New().String(),
+		Timestamp:         timestamp,
+		Action:            action,
+		Username:          username,
+		FsPath:            fsPath,
+		FsTargetPath:      fsTargetPath,
+		VirtualPath:       virtualPath,
+		VirtualTargetPath: virtualTargetPath,
+		SSHCmd:            sshCmd,
+		FileSize:          fileSize,
+		Status:            status,
+		Protocol:          protocol,
+		IP:                ip,
+		InstanceID:        eventStoreInstanceID,
+	}
+
+	eventsWriter.enqueue(eventStoreItem{fsEvent: event})
+}
+
+func addProviderEvent(timestamp int64, action, username, objectType, objectName, ip string) {
+	if !util.IsStringInSlice(action, config.EventStore.ProviderEvents) ||
+		!util.IsStringInSlice(objectType, config.EventStore.ProviderObjects) {
+		return
+	}
+	event := &ProviderEvent{
+		ID:         xid.New().String(),
+		Timestamp:  timestamp,
+		Action:     action,
+		Username:   username,
+		IP:         ip,
+		ObjectType: objectType,
+		ObjectName: objectName,
+		InstanceID: eventStoreInstanceID,
+	}
+
+	eventsWriter.enqueue(eventStoreItem{providerEvent: event})
+}
+
+// SearchFsEvents returns the stored filesystem events matching the specified filters
+func SearchFsEvents(search *FsEventSearch) ([]FsEvent, error) {
+	if search.Limit <= 0 {
+		return []FsEvent{}, nil
+	}
+	if search.Order != OrderASC {
+		search.Order = OrderDESC
+	}
+	return provider.searchFsEvents(search)
+}
+
+// SearchProviderEvents returns the stored provider events matching the specified filters
+func SearchProviderEvents(search *ProviderEventSearch) ([]ProviderEvent, error) {
+	if search.Limit <= 0 {
+		return []ProviderEvent{}, nil
+	}
+	if search.Order != OrderASC {
+		search.Order = OrderDESC
+	}
+	return provider.searchProviderEvents(search)
+}
+
+func getEventStoreInstanceID() string {
+	hostname, err := os.Hostname()
+	if err != nil || hostname == "" {
+		return "sftpgo"
+	}
+	return hostname
+}
+
+// eventStoreItem defines an event waiting to be stored, only one field is set
+type eventStoreItem struct {
+	fsEvent       *FsEvent
+	providerEvent *ProviderEvent
+}
+
+func (i *eventStoreItem) store() {
+	if i.fsEvent != nil {
+		if err := provider.addFsEvent(i.fsEvent); err != nil {
+			providerLog(logger.LevelWarn, "unable to store fs event %#v for user %#v: %v",
+				i.fsEvent.Action, i.fsEvent.Username, err)
+		}
+		return
+	}
+	if err := provider.addProviderEvent(i.providerEvent); err != nil {
+		providerLog(logger.LevelWarn, "unable to store provider event %#v for object %#v: %v",
+			i.providerEvent.Action, i.providerEvent.ObjectName, err)
+	}
+}
+
+// eventStoreWriter stores the events asynchronously using a single worker,
+// the pending events are bounded so a slow data provider cannot pile up
+// goroutines and database connections
+type eventStoreWriter struct {
+	sync.RWMutex
+	queue chan eventStoreItem
+	done  chan bool
+}
+
+func (w *eventStoreWriter) start() {
+	w.Lock()
+	defer w.Unlock()
+
+	if w.queue != nil || !config.EventStore.IsEnabled() {
+		return
+	}
+	w.queue = make(chan eventStoreItem, eventStoreQueueSize)
+	w.done = make(chan bool)
+
+	go w.loop(w.queue, w.done)
+}
+
+func (w *eventStoreWriter) loop(queue <-chan eventStoreItem, done chan<- bool) {
+	providerLog(logger.LevelDebug, "event store writer started")
+	for item := range queue {
+		item.store()
+	}
+	providerLog(logger.LevelDebug, "event store writer stopped")
+	close(done)
+}
+
+func (w *eventStoreWriter) enqueue(item eventStoreItem) {
+	w.RLock()
+	defer w.RUnlock()
+
+	if w.queue == nil {
+		providerLog(logger.LevelDebug, "event store writer not started, event discarded")
+		return
+	}
+	select {
+	case w.queue <- item:
+	default:
+		providerLog(logger.LevelWarn, "event store queue is full, event discarded")
+	}
+}
+
+// stop waits for the pending events to be stored
+func (w *eventStoreWriter) stop() {
+	w.Lock()
+	queue := w.queue
+	done := w.done
+	w.queue = nil
+	w.done = nil
+	w.Unlock()
+
+	if queue != nil {
+		close(queue)
+		<-done
+	}
+}
+
+// startEventStoreCleanupTimer periodically removes the events older
+// than the configured retention
+func startEventStoreCleanupTimer() {
+	if !config.EventStore.IsEnabled() || config.EventStore.Retention <= 0 {
+		return
+	}
+	providerLog(logger.LevelDebug, "event store cleanup started, retention: %v hours", config.EventStore.Retention)
+	eventStoreCleanupTicker = time.NewTicker(eventStoreCleanupInterval)
+	eventStoreCleanupTickerDone = make(chan bool)
+	cleanupEvents(time.Now())
+
+	go func() {
+		for {
+			select {
+			case <-eventStoreCleanupTickerDone:
+				return
+			case t := <-eventStoreCleanupTicker.C:
+				cleanupEvents(t)
+			}
+		}
+	}()
+}
+
+func stopEventStoreCleanupTimer() {
+	if eventStoreCleanupTicker != nil {
+		eventStoreCleanupTicker.Stop()
+		eventStoreCleanupTickerDone <- true
+		eventStoreCleanupTicker = nil
+	}
+}
+
+func cleanupEvents(now time.Time) {
+	before := now.Add(-time.Duration(config.EventStore.Retention) * time.Hour).UnixNano()
+	if err := provider.cleanupEvents(before); err != nil {
+		providerLog(logger.LevelWarn, "unable to remove events older than %v: %v", time.Unix(0, before), err)
+		return
+	}
+	providerLog(logger.LevelDebug, "events older than %v removed", time.Unix(0, before))
+}
@@ -0,0 +1,310 @@
+package dataprovider
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/drakkan/sftpgo/v2/logger"
+	"github.com/drakkan/sftpgo/v2/sdk"
+	"github.com/drakkan/sftpgo/v2/util"
+	"github.com/drakkan/sftpgo/v2/vfs"
+)
+
+// GroupUserSettings defines the settings inherited by the users belonging to a group
+type GroupUserSettings struct {
+	// Permissions per directory, they are merged with the user permissions
+	// for the directories not explicitly configured for the user
+	Permissions map[string][]string `json:"permissions,omitempty"`
+	// Maximum concurrent sessions, used if not set at user level
+	MaxSessions int `json:"max_sessions,omitempty"`
+	// Maximum size allowed as bytes, used if not set at user level
+	QuotaSize int64 `json:"quota_size,omitempty"`
+	// Maximum number of files allowed, used if not set at user level
+	QuotaFiles int `json:"quota_files,omitempty"`
+	// Maximum upload bandwidth as KB/s, used if not set at user level
+	UploadBandwidth int64 `json:"upload_bandwidth,omitempty"`
+	// Maximum download bandwidth as KB/s, used if not set at user level
+	DownloadBandwidth int64 `json:"download_bandwidth,omitempty"`
+	// Data transfer limits as MB, used if the user has no data transfer limits
+	UploadDataTransfer   int64 `json:"upload_data_transfer,omitempty"`
+	DownloadDataTransfer int64 `json:"download_data_transfer,omitempty"`
+	TotalDataTransfer    int64 `json:"total_data_transfer,omitempty"`
+	// Data transfer reset period, used together with the inherited data transfer limits
+	DataTransferResetPeriod sdk.DataTransferResetPeriod `json:"data_transfer_reset_period,omitempty"`
+	// Additional restrictions
+	Filters sdk.UserFilters `json:"filters"`
+	// Filesystem configuration details, used for users with a local filesystem
+	FsConfig vfs.Filesystem `json:"filesystem"`
+}
+
+// Group defines a set of settings shared among multiple users.
+// The group settings are merged with the user ones at login
+type Group struct {
+	// Data provider unique identifier
+	ID int64 `json:"id"`
+	// Group name
+	Name string `json:"name"`
+	// optional description
+	Description string `json:"description,omitempty"`
+	// creation time as unix timestamp in milliseconds
+	CreatedAt int64 `json:"created_at"`
+	// last update time as unix timestamp in milliseconds
+	UpdatedAt int64 `json:"updated_at"`
+	// Settings to apply to the group members
+	UserSettings GroupUserSettings `json:"user_settings"`
+	// Mapping between virtual paths and virtual folders
+	VirtualFolders []vfs.VirtualFolder `json:"virtual_folders,omitempty"`
+	// list of usernames associated with this group
+	Users []string `json:"users,omitempty"`
+}
+
+// GetEncryptionAdditionalData returns the additional data to use for AEAD
+func (g *Group) GetEncryptionAdditionalData() string {
+	return fmt.Sprintf("group_%v", g.Name)
+}
+
+// GetGCSCredentialsFilePath returns the path for GCS credentials.
+// Group credentials are always stored inside the data provider
+// so this file is never written
+func (g *Group) GetGCSCredentialsFilePath() string {
+	return filepath.Join(credentialsDirPath, "groups", fmt.Sprintf("%v_gcs_credentials.json", g.Name))
+}
+
+// GetUsersAsString returns the list of users as comma separated string
+func (g *Group) GetUsersAsString() string {
+	return strings.Join(g.Users, ",")
+}
+
+// GetInfoString returns group's info as string.
+func (g *Group) GetInfoString() string {
+	var result strings.Builder
+	if len(g.UserSettings.Permissions) > 0 {
+		result.WriteString(fmt.Sprintf("Permissions: %v. ", len(g.UserSettings.Permissions)))
+	}
+	if len(g.VirtualFolders) > 0 {
+		result.WriteString(fmt.Sprintf("Virtual folders: %v. ", len(g.VirtualFolders)))
+	}
+	switch g.UserSettings.FsConfig.Provider {
+	case sdk.S3FilesystemProvider:
+		result.WriteString("Storage: S3. ")
+	case sdk.GCSFilesystemProvider:
+		result.WriteString("Storage: GCS. ")
+	case sdk.AzureBlobFilesystemProvider:
+		result.WriteString("Storage: Azure. ")
+	case sdk.CryptedFilesystemProvider:
+		result.WriteString("Storage: Encrypted. ")
+	case sdk.SFTPFilesystemProvider:
+		result.WriteString("Storage: SFTP. ")
+	case sdk.FTPFilesystemProvider:
+		result.WriteString("Storage: FTP. ")
+	case sdk.WebDAVFilesystemProvider:
+		result.WriteString("Storage: WebDAV. ")
+	}
+	if g.UserSettings.QuotaSize > 0 || g.UserSettings.QuotaFiles > 0 {
+		result.WriteString(fmt.Sprintf("Quota: %v/%v. ", util.ByteCountIEC(g.UserSettings.QuotaSize),
+			g.UserSettings.QuotaFiles))
+	}
+	return result.String()
+}
+
+// GetPermissionsAsJSON returns the permissions as json byte array
+func (g *Group) GetPermissionsAsJSON() ([]byte, error) {
+	return json.Marshal(g.UserSettings.Permissions)
+}
+
+// GetRootDirPermissions returns the permissions for the root directory, if any
+func (g *Group) GetRootDirPermissions() []string {
+	return g.UserSettings.Permissions["/"]
+}
+
+// GetSubDirPermissions returns permissions for sub directories
+func (g *Group) GetSubDirPermissions() []sdk.DirectoryPermissions {
+	user := g.getUserForSettings()
+	return user.GetSubDirPermissions()
+}
+
+// GetFlatFilePatterns returns file patterns as flat list
+// duplicating a path if it has both allowed and denied patterns
+func (g *Group) GetFlatFilePatterns() []sdk.PatternsFilter {
+	user := g.getUserForSettings()
+	return user.GetFlatFilePatterns()
+}
+
+// GetAllowedIPAsString returns the allowed IP as comma separated string
+func (g *Group) GetAllowedIPAsString() string {
+	return strings.Join(g.UserSettings.Filters.AllowedIP, ",")
+}
+
+// GetDeniedIPAsString returns the denied IP as comma separated string
+func (g *Group) GetDeniedIPAsString() string {
+	return strings.Join(g.UserSettings.Filters.DeniedIP, ",")
+}
+
+func (g *Group) getUserForSettings() User {
+	return User{
+		BaseUser: sdk.BaseUser{
+			Username:    g.Name,
+			Permissions: g.UserSettings.Permissions,
+			Filters:     g.UserSettings.Filters,
+		},
+		VirtualFolders: g.VirtualFolders,
+	}
+}
+
+// RenderAsJSON implements the renderer interface used within plugins
+func (g *Group) RenderAsJSON(reload bool) ([]byte, error) {
+	if reload {
+		group, err := provider.groupExists(g.Name)
+		if err != nil {
+			providerLog(logger.LevelWarn, "unable to reload group before rendering as json: %v", err)
+			return nil, err
+		}
+		group.PrepareForRendering()
+		return json.Marshal(group)
+	}
+	g.PrepareForRendering()
+	return json.Marshal(g)
+}
+
+// PrepareForRendering prepares a group for rendering.
+// It hides confidential data and set to nil the empty secrets
+// so they are not serialized
+func (g *Group) PrepareForRendering() {
+	g.UserSettings.FsConfig.HideConfidentialData()
+	g.UserSettings.FsConfig.SetNilSecretsIfEmpty()
+	for idx := range g.VirtualFolders {
+		folder := &g.VirtualFolders[idx]
+		folder.PrepareForRendering()
+	}
+}
+
+// SetEmptySecretsIfNil sets the secrets to empty if nil
+func (g *Group) SetEmptySecretsIfNil() {
+	g.UserSettings.FsConfig.SetEmptySecretsIfNil()
+	for idx := range g.VirtualFolders {
+		vfolder := &g.VirtualFolders[idx]
+		vfolder.FsConfig.SetEmptySecretsIfNil()
+	}
+}
+
+func (g *Group) hasRedactedSecret() bool {
+	if g.UserSettings.FsConfig.HasRedactedSecret() {
+		return true
+	}
+	for idx := range g.VirtualFolders {
+		folder := &g.VirtualFolders[idx]
+		if folder.HasRedactedSecret() {
+			return true
+		}
+	}
+	return false
+}
+
+func (g *Group) validate() error {
+	g.SetEmptySecretsIfNil()
+	if g.Name == "" {
+		return util.NewValidationError("group name is mandatory")
+	}
+	if !config.SkipNaturalKeysValidation && !usernameRegex.MatchString(g.Name) {
+		return util.NewValidationError(fmt.Sprintf("group name %#v is not valid, the following characters are allowed: a-zA-Z0-9-_.~",
+			g.Name))
+	}
+	if g.hasRedactedSecret() {
+		return util.NewValidationError("cannot save a group with a redacted secret")
+	}
+	if g.UserSettings.MaxSessions < 0 || g.UserSettings.QuotaSize < 0 || g.UserSettings.QuotaFiles < 0 ||
+		g.UserSettings.UploadBandwidth < 0 || g.UserSettings.DownloadBandwidth < 0 {
+		return util.NewValidationError("max sessions, quota and bandwidth limits cannot be negative")
+	}
+	if err := validateDataTransferLimits(g.UserSettings.UploadDataTransfer, g.UserSettings.DownloadDataTransfer,
+		g.UserSettings.TotalDataTransfer, g.UserSettings.DataTransferResetPeriod); err != nil {
+		return err
+	}
+	permissions, err := cleanPermissions(g.UserSettings.Permissions)
+	if err != nil {
+		return err
+	}
+	g.UserSettings.Permissions = permissions
+	if err := g.validateFsConfig(); err != nil {
+		return err
+	}
+	// we reuse the user validation logic for virtual folders and filters,
+	// the settings that make sense only for a specific user are not allowed
+	user := g.getUserForSettings()
+	user.Permissions = nil
+	user.Filters.TOTPConfig = sdk.TOTPConfig{}
+	user.Filters.RecoveryCodes = nil
+	user.Filters.UserType = ""
+	user.Filters.AllowAPIKeyAuth = false
+	user.Filters.RequirePasswordChange = false
+	user.Filters.CreatedBy = ""
+	if err := validateUserVirtualFolders(&user); err != nil {
+		return err
+	}
+	if err := validateFilters(&user); err != nil {
+		return err
+	}
+	g.VirtualFolders = user.VirtualFolders
+	g.UserSettings.Filters = user.Filters
+	return nil
+}
+
+func (g *Group) validateFsConfig() error {
+	fsConfig := &g.UserSettings.FsConfig
+	if err := fsConfig.Validate(g); err != nil {
+		return err
+	}
+	// GCS credentials for groups are always stored inside the data provider,
+	// they are inherited by users with different names so we cannot use a file
+	if fsConfig.Provider == sdk.GCSFilesystemProvider && fsConfig.GCSConfig.Credentials.IsPlain() {
+		fsConfig.GCSConfig.Credentials.SetAdditionalData(g.GetEncryptionAdditionalData())
+		if err := fsConfig.GCSConfig.Credentials.Encrypt(); err != nil {
+			return util.NewValidationError(fmt.Sprintf("could not encrypt GCS credentials: %v", err))
+		}
+	}
+	return nil
+}
+
+func (g *Group) getACopy() Group {
+	g.SetEmptySecretsIfNil()
+	virtualFolders := make([]vfs.VirtualFolder, 0, len(g.VirtualFolders))
+	for idx := range g.VirtualFolders {
+		vfolder := g.VirtualFolders[idx].GetACopy()
+		virtualFolders = append(virtualFolders, vfolder)
+	}
+	users := make([]string, len(g.Users))
+	copy(users, g.Users)
+	permissions := make(map[string][]string)
+	for k, v := range g.UserSettings.Permissions {
+		perms := make([]string, len(v))
+		copy(perms, v)
+		permissions[k] = perms
+	}
+	filters := copyBaseUserFilters(g.UserSettings.Filters)
+
+	return Group{
+		ID:          g.ID,
+		Name:        g.Name,
+		Description: g.Description,
+		CreatedAt:   g.CreatedAt,
+		UpdatedAt:   g.UpdatedAt,
+		UserSettings: GroupUserSettings{
+			Permissions:             permissions,
+			MaxSessions:             g.UserSettings.MaxSessions,
+			QuotaSize:               g.UserSettings.QuotaSize,
+			QuotaFiles:              g.UserSettings.QuotaFiles,
+			UploadBandwidth:         g.UserSettings.UploadBandwidth,
+			DownloadBandwidth:       g.UserSettings.DownloadBandwidth,
+			UploadDataTransfer:      g.UserSettings.UploadDataTransfer,
+			DownloadDataTransfer:    g.UserSettings.DownloadDataTransfer,
+			TotalDataTransfer:       g.UserSettings.TotalDataTransfer,
+			DataTransferResetPeriod: g.UserSettings.DataTransferResetPeriod,
+			Filters:                 filters,
+			FsConfig:                g.UserSettings.FsConfig.GetACopy(),
+		},
+		VirtualFolders: virtualFolders,
+		Users:          users,
+	}
+}
@@ -32,6 +32,10 @@ type memoryProviderHandle struct {
 	vfolders map[string]vfs.BaseVirtualFolder
 	// slice with ordered folder names
 	vfoldersNames []string
+	// map for groups, group name is the key
+	groups map[string]Group
+	// slice with ordered group names
+	groupnames []string
 	// map for admins, username is the key
 	admins map[string]Admin
 	// slice with ordered admins
@@ -40,6 +44,38 @@ type memoryProviderHandle struct {
 	apiKeys map[string]APIKey
 	// slice with ordered API keys KeyID
 	apiKeysIDs []string
+	// map for shares, shareID is the key
+	shares map[string]Share
+	// slice with ordered shares shareID
+	sharesIDs []string
+	// map for uploads using shares, shareID is the key
+	shareUploads map[string][]ShareUpload
+	// map for account lockouts, account type and username are the key
+	accountLockouts map[string]AccountLockout
+	// stored filesystem events
+	fsEvents []FsEvent
+	// stored provider events
+	providerEvents []ProviderEvent
+	// map for event rules, rule name is the key
+	eventRules map[string]EventRule
+	// slice with ordered event rules names
+	eventRulesNames []string
+	// map for schedules, schedule name is the key
+	schedules map[string]Schedule
+	// slice with ordered schedules names
+	schedulesNames []string
+	// map for WebDAV locks, lock token is the key
+	webDAVLocks map[string]WebDAVLock
+	// map for WebDAV dead properties, username and virtual path are the key
+	webDAVProperties map[string]WebDAVProperties
+	// map for S3 access keys, access key id is the key
+	s3AccessKeys map[string]S3AccessKey
+	// map for tus uploads, upload id is the key
+	tusUploads map[string]TusUpload
+	// map for password reset codes, code hash is the key
+	resetCodes map[string]ResetCode
+	// map for interrupted multipart uploads, upload key is the key
+	multipartUploads map[string]vfs.MultipartUpload
 }
 
 // MemoryProvider auth provider for a memory store
@@ -57,16 +93,32 @@ func initializeMemoryProvider(basePath string) {
 	}
 	provider = &MemoryProvider{
 		dbHandle: &memoryProviderHandle{
-			isClosed:        false,
-			usernames:       []string{},
-			users:           make(map[string]User),
-			vfolders:        make(map[string]vfs.BaseVirtualFolder),
-			vfoldersNames:   []string{},
-			admins:          make(map[string]Admin),
-			adminsUsernames: []string{},
-			apiKeys:         make(map[string]APIKey),
-			apiKeysIDs:      []string{},
-			configFile:      configFile,
+			isClosed:         false,
+			usernames:        []string{},
+			users:            make(map[string]User),
+			vfolders:         make(map[string]vfs.BaseVirtualFolder),
+			vfoldersNames:    []string{},
+			groups:           make(map[string]Group),
+			groupnames:       []string{},
+			admins:           make(map[string]Admin),
+			adminsUsernames:  []string{},
+			apiKeys:          make(map[string]APIKey),
+			apiKeysIDs:       []string{},
+			shares:           make(map[string]Share),
+			sharesIDs:        []string{},
+			shareUploads:     make(map[string][]ShareUpload),
+			accountLockouts:  make(map[string]AccountLockout),
+			eventRules:       make(map[string]EventRule),
+			eventRulesNames:  []string{},
+			schedules:        make(map[string]Schedule),
+			schedulesNames:   []string{},
+			webDAVLocks:      make(map[string]WebDAVLock),
+			webDAVProperties: make(map[string]WebDAVProperties),
+			s3AccessKeys:     make(map[string]S3AccessKey),
+			tusUploads:       make(map[string]TusUpload),
+			resetCodes:       make(map[string]ResetCode),
+			multipartUploads: make(map[string]vfs.MultipartUpload),
+			configFile:       configFile,
 		},
 	}
 	if err := provider.reloadConfig(); err != nil {
@@ -241,6 +293,41 @@ func (p *MemoryProvider) getUsedQuota(username string) (int, int64, error) {
 	return user.UsedQuotaFiles, user.UsedQuotaSize, err
 }
 
+func (p *MemoryProvider) updateTransferQuota(username string, uploadSize, downloadSize int64, reset bool,
+	periodStart int64) error {
+	p.dbHandle.Lock()
+	defer p.dbHandle.Unlock()
+	if p.dbHandle.isClosed {
+		return errMemoryProviderClosed
+	}
+	user, err := p.userExistsInternal(username)
+	if err != nil {
+		providerLog(logger.LevelWarn, "unable to update transfer quota for user %#v error: %v", username, err)
+		return err
+	}
+	user.UsedUploadDataTransfer, user.UsedDownloadDataTransfer, user.LastDataTransferReset = getUpdatedTransferQuota(
+		user.UsedUploadDataTransfer, user.UsedDownloadDataTransfer, user.LastDataTransferReset, uploadSize,
+		downloadSize, reset, periodStart)
+	providerLog(logger.LevelDebug, "transfer quota updated for user %#v, ul increment: %v dl increment: %v is reset? %v",
+		username, uploadSize, downloadSize, reset)
+	p.dbHandle.users[user.Username] = user
+	return nil
+}
+
+func (p *MemoryProvider) getUsedTransferQuota(username string) (int64, int64, int64, error) {
+	p.dbHandle.Lock()
+	defer p.dbHandle.Unlock()
+	if p.dbHandle.isClosed {
+		return 0, 0, 0, errMemoryProviderClosed
+	}
+	user, err := p.userExistsInternal(username)
+	if err != nil {
+		providerLog(logger.LevelWarn, "unable to get transfer quota for user %#v error: %v", username, err)
+		return 0, 0, 0, err
+	}
+	return user.UsedUploadDataTransfer, user.UsedDownloadDataTransfer, user.LastDataTransferReset, err
+}
+
 func (p *MemoryProvider) addUser(user *User) error {
 	// we can query virtual folder while validating a user
 	// so we have to check without holding the lock
@@ -263,10 +350,16 @@ func (p *MemoryProvider) addUser(user *User) error {
 	user.LastQuotaUpdate = 0
 	user.UsedQuotaSize = 0
 	user.UsedQuotaFiles = 0
+	user.UsedUploadDataTransfer = 0
+	user.UsedDownloadDataTransfer = 0
+	user.LastDataTransferReset = 0
 	user.LastLogin = 0
 	user.CreatedAt = util.GetTimeAsMsSinceEpoch(time.Now())
 	user.UpdatedAt = util.GetTimeAsMsSinceEpoch(time.Now())
 	user.VirtualFolders = p.joinVirtualFoldersFields(user)
+	for _, groupName := range user.Groups {
+		p.addUserToGroupMapping(user.Username, groupName)
+	}
 	p.dbHandle.users[user.Username] = user.getACopy()
 	p.dbHandle.usernames = append(p.dbHandle.usernames, user.Username)
 	sort.Strings(p.dbHandle.usernames)
@@ -294,11 +387,25 @@ func (p *MemoryProvider) updateUser(user *User) error {
 	for _, oldFolder := range u.VirtualFolders {
 		p.removeUserFromFolderMapping(oldFolder.Name, u.Username)
 	}
+	for _, oldGroup := range u.Groups {
+		p.removeUserFromGroupMapping(u.Username, oldGroup)
+	}
 	user.VirtualFolders = p.joinVirtualFoldersFields(user)
+	for _, groupName := range user.Groups {
+		p.addUserToGroupMapping(user.Username, groupName)
+	}
 	user.LastQuotaUpdate = u.LastQuotaUpdate
 	user.UsedQuotaSize = u.UsedQuotaSize
 	user.UsedQuotaFiles = u.UsedQuotaFiles
+	user.UsedUploadDataTransfer = u.UsedUploadDataTransfer
+	user.UsedDownloadDataTransfer = u.UsedDownloadDataTransfer
+	user.LastDataTransferReset = u.LastDataTransferReset
 	user.LastLogin = u.LastLogin
+	if user.Password == u.Password {
+		user.LastPasswordChange = u.LastPasswordChange
+	} else {
+		user.LastPasswordChange = util.GetTimeAsMsSinceEpoch(time.Now())
+	}
 	user.CreatedAt = u.CreatedAt
 	user.UpdatedAt = util.GetTimeAsMsSinceEpoch(time.Now())
 	user.ID = u.ID
@@ -320,6 +427,9 @@ func (p *MemoryProvider) deleteUser(user *User) error {
 	for _, oldFolder := range u.VirtualFolders {
 		p.removeUserFromFolderMapping(oldFolder.Name, u.Username)
 	}
+	for _, oldGroup := range u.Groups {
+		p.removeUserFromGroupMapping(u.Username, oldGroup)
+	}
 	delete(p.dbHandle.users, user.Username)
 	// this could be more efficient
 	p.dbHandle.usernames = make([]string, 0, len(p.dbHandle.users))
@@ -328,6 +438,7 @@ func (p *MemoryProvider) deleteUser(user *User) error {
 	}
 	sort.Strings(p.dbHandle.usernames)
 	p.deleteAPIKeysWithUser(user.Username)
+	p.deleteSharesWithUser(user.Username)
 	return nil
 }
 
@@ -369,6 +480,35 @@ func (p *MemoryProvider) getRecentlyUpdatedUsers(after int64) ([]User, error) {
 	return nil, nil
 }
 
+func (p *MemoryProvider) getUsersInScope(admin *Admin, limit, offset int, order string) ([]User, error) {
+	p.dbHandle.Lock()
+	defer p.dbHandle.Unlock()
+	users := make([]User, 0, 10)
+	if p.dbHandle.isClosed {
+		return users, errMemoryProviderClosed
+	}
+	itNum := 0
+	for i := range p.dbHandle.usernames {
+		username := p.dbHandle.usernames[i]
+		if order == OrderDESC {
+			username = p.dbHandle.usernames[len(p.dbHandle.usernames)-1-i]
+		}
+		u := p.dbHandle.users[username]
+		if !admin.IsUserInScope(&u) {
+			continue
+		}
+		itNum++
+		if itNum <= offset {
+			continue
+		}
+		users = append(users, u.getACopy())
+		if limit > 0 && len(users) >= limit {
+			break
+		}
+	}
+	return users, nil
+}
+
 func (p *MemoryProvider) getUsers(limit int, offset int, order string) ([]User, error) {
 	users := make([]User, 0, limit)
 	var err error
@@ -431,15 +571,18 @@ func (p *MemoryProvider) userExistsInternal(username string) (User, error) {
 }
 
 func (p *MemoryProvider) addAdmin(admin *Admin) error {
+	// we can query groups while validating an admin
+	// so we have to check without holding the lock
+	err := admin.validate()
+	if err != nil {
+		return err
+	}
+
 	p.dbHandle.Lock()
 	defer p.dbHandle.Unlock()
 	if p.dbHandle.isClosed {
 		return errMemoryProviderClosed
 	}
-	err := admin.validate()
-	if err != nil {
-		return err
-	}
 	_, err = p.adminExistsInternal(admin.Username)
 	if err == nil {
 		return fmt.Errorf("admin %#v already exists", admin.Username)
@@ -455,15 +598,18 @@ func (p *MemoryProvider) addAdmin(admin *Admin) error {
 }
 
 func (p *MemoryProvider) updateAdmin(admin *Admin) error {
+	// we can query groups while validating an admin
+	// so we have to check without holding the lock
+	err := admin.validate()
+	if err != nil {
+		return err
+	}
+
 	p.dbHandle.Lock()
 	defer p.dbHandle.Unlock()
 	if p.dbHandle.isClosed {
 		return errMemoryProviderClosed
 	}
-	err := admin.validate()
-	if err != nil {
-		return err
-	}
 	a, err := p.adminExistsInternal(admin.Username)
 	if err != nil {
 		return err
@@ -471,6 +617,11 @@ func (p *MemoryProvider) updateAdmin(admin *Admin) error {
 	admin.ID = a.ID
 	admin.CreatedAt = a.CreatedAt
 	admin.LastLogin = a.LastLogin
+	if admin.Password == a.Password {
+		admin.LastPasswordChange = a.LastPasswordChange
+	} else {
+		admin.LastPasswordChange = util.GetTimeAsMsSinceEpoch(time.Now())
+	}
 	admin.UpdatedAt = util.GetTimeAsMsSinceEpoch(time.Now())
 	p.dbHandle.admins[admin.Username] = admin.getACopy()
 	return nil
@@ -598,6 +749,39 @@ func (p *MemoryProvider) updateFolderQuota(name string, filesAdd int, sizeAdd in
 	return nil
 }
 
+func (p *MemoryProvider) updateFolderTransferQuota(name string, uploadSize, downloadSize int64, reset bool,
+	periodStart int64) error {
+	p.dbHandle.Lock()
+	defer p.dbHandle.Unlock()
+	if p.dbHandle.isClosed {
+		return errMemoryProviderClosed
+	}
+	folder, err := p.folderExistsInternal(name)
+	if err != nil {
+		providerLog(logger.LevelWarn, "unable to update transfer quota for folder %#v error: %v", name, err)
+		return err
+	}
+	folder.UsedUploadDataTransfer, folder.UsedDownloadDataTransfer, folder.LastDataTransferReset = getUpdatedTransferQuota(
+		folder.UsedUploadDataTransfer, folder.UsedDownloadDataTransfer, folder.LastDataTransferReset, uploadSize,
+		downloadSize, reset, periodStart)
+	p.dbHandle.vfolders[name] = folder
+	return nil
+}
+
+func (p *MemoryProvider) getUsedFolderTransferQuota(name string) (int64, int64, int64, error) {
+	p.dbHandle.Lock()
+	defer p.dbHandle.Unlock()
+	if p.dbHandle.isClosed {
+		return 0, 0, 0, errMemoryProviderClosed
+	}
+	folder, err := p.folderExistsInternal(name)
+	if err != nil {
+		providerLog(logger.LevelWarn, "unable to get transfer quota for folder %#v error: %v", name, err)
+		return 0, 0, 0, err
+	}
+	return folder.UsedUploadDataTransfer, folder.UsedDownloadDataTransfer, folder.LastDataTransferReset, err
+}
+
 func (p *MemoryProvider) getUsedFolderQuota(name string) (int, int64, error) {
 	p.dbHandle.Lock()
 	defer p.dbHandle.Unlock()
@@ -655,7 +839,7 @@ func (p *MemoryProvider) addOrUpdateFolderInternal(baseFolder *vfs.BaseVirtualFo
 		folder.MappedPath = baseFolder.MappedPath
 		folder.Description = baseFolder.Description
 		folder.FsConfig = baseFolder.FsConfig.GetACopy()
-		if !util.IsStringInSlice(username, folder.Users) {
+		if username != "" && !util.IsStringInSlice(username, folder.Users) {
 			folder.Users = append(folder.Users, username)
 		}
 		p.updateFoldersMappingInternal(folder)
@@ -667,7 +851,11 @@ func (p *MemoryProvider) addOrUpdateFolderInternal(baseFolder *vfs.BaseVirtualFo
 		folder.UsedQuotaSize = usedQuotaSize
 		folder.UsedQuotaFiles = usedQuotaFiles
 		folder.LastQuotaUpdate = lastQuotaUpdate
-		folder.Users = []string{username}
+		folder.Users = nil
+		folder.Groups = nil
+		if username != "" {
+			folder.Users = []string{username}
+		}
 		p.updateFoldersMappingInternal(folder)
 		return folder, nil
 	}
@@ -757,6 +945,7 @@ func (p *MemoryProvider) addFolder(folder *vfs.BaseVirtualFolder) error {
 	}
 	folder.ID = p.getNextFolderID()
 	folder.Users = nil
+	folder.Groups = nil
 	p.dbHandle.vfolders[folder.Name] = folder.GetACopy()
 	p.dbHandle.vfoldersNames = append(p.dbHandle.vfoldersNames, folder.Name)
 	sort.Strings(p.dbHandle.vfoldersNames)
@@ -782,7 +971,11 @@ func (p *MemoryProvider) updateFolder(folder *vfs.BaseVirtualFolder) error {
 	folder.LastQuotaUpdate = f.LastQuotaUpdate
 	folder.UsedQuotaFiles = f.UsedQuotaFiles
 	folder.UsedQuotaSize = f.UsedQuotaSize
+	folder.UsedUploadDataTransfer = f.UsedUploadDataTransfer
+	folder.UsedDownloadDataTransfer = f.UsedDownloadDataTransfer
+	folder.LastDataTransferReset = f.LastDataTransferReset
 	folder.Users = f.Users
+	folder.Groups = f.Groups
 	p.dbHandle.vfolders[folder.Name] = folder.GetACopy()
 	// now update the related users
 	for _, username := range folder.Users {
@@ -800,6 +993,19 @@ func (p *MemoryProvider) updateFolder(folder *vfs.BaseVirtualFolder) error {
 			p.dbHandle.users[user.Username] = user
 		}
 	}
+	// and the related groups
+	for _, groupName := range folder.Groups {
+		group, err := p.groupExistsInternal(groupName)
+		if err == nil {
+			for idx := range group.VirtualFolders {
+				groupFolder := &group.VirtualFolders[idx]
+				if folder.Name == groupFolder.Name {
+					groupFolder.BaseVirtualFolder = folder.GetACopy()
+				}
+			}
+			p.dbHandle.groups[group.Name] = group
+		}
+	}
 	return nil
 }
 
@@ -810,10 +1016,24 @@ func (p *MemoryProvider) deleteFolder(folder *vfs.BaseVirtualFolder) error {
 		return errMemoryProviderClosed
 	}
 
-	_, err := p.folderExistsInternal(folder.Name)
+	f, err := p.folderExistsInternal(folder.Name)
 	if err != nil {
 		return err
 	}
+	for _, groupName := range f.Groups {
+		group, err := p.groupExistsInternal(groupName)
+		if err == nil {
+			var folders []vfs.VirtualFolder
+			for idx := range group.VirtualFolders {
+				groupFolder := &group.VirtualFolders[idx]
+				if folder.Name != groupFolder.Name {
+					folders = append(folders, *groupFolder)
+				}
+			}
+			group.VirtualFolders = folders
+			p.dbHandle.groups[group.Name] = group
+		}
+	}
 	for _, username := range folder.Users {
 		user, err := p.userExistsInternal(username)
 		if err == nil {
@@ -837,6 +1057,240 @@ func (p *MemoryProvider) deleteFolder(folder *vfs.BaseVirtualFolder) error {
 	return nil
 }
 
+func (p *MemoryProvider) groupExistsInternal(name string) (Group, error) {
+	if val, ok := p.dbHandle.groups[name]; ok {
+		return val.getACopy(), nil
+	}
+	return Group{}, util.NewRecordNotFoundError(fmt.Sprintf("group %#v does not exist", name))
+}
+
+func (p *MemoryProvider) groupExists(name string) (Group, error) {
+	p.dbHandle.Lock()
+	defer p.dbHandle.Unlock()
+	if p.dbHandle.isClosed {
+		return Group{}, errMemoryProviderClosed
+	}
+	return p.groupExistsInternal(name)
+}
+
+func (p *MemoryProvider) addGroup(group *Group) error {
+	err := group.validate()
+	if err != nil {
+		return err
+	}
+
+	p.dbHandle.Lock()
+	defer p.dbHandle.Unlock()
+	if p.dbHandle.isClosed {
+		return errMemoryProviderClosed
+	}
+
+	_, err = p.groupExistsInternal(group.Name)
+	if err == nil {
+		return fmt.Errorf("group %#v already exists", group.Name)
+	}
+	group.ID = p.getNextGroupID()
+	group.CreatedAt = util.GetTimeAsMsSinceEpoch(time.Now())
+	group.UpdatedAt = util.GetTimeAsMsSinceEpoch(time.Now())
+	group.Users = nil
+	group.VirtualFolders = p.joinGroupVirtualFoldersFields(group)
+	p.dbHandle.groups[group.Name] = group.getACopy()
+	p.dbHandle.groupnames = append(p.dbHandle.groupnames, group.Name)
+	sort.Strings(p.dbHandle.groupnames)
+	return nil
+}
+
+func (p *MemoryProvider) updateGroup(group *Group) error {
+	err := group.validate()
+	if err != nil {
+		return err
+	}
+
+	p.dbHandle.Lock()
+	defer p.dbHandle.Unlock()
+	if p.dbHandle.isClosed {
+		return errMemoryProviderClosed
+	}
+	g, err := p.groupExistsInternal(group.Name)
+	if err != nil {
+		return err
+	}
+	for _, oldFolder := range g.VirtualFolders {
+		p.removeGroupFromFolderMapping(oldFolder.Name, g.Name)
+	}
+	group.VirtualFolders = p.joinGroupVirtualFoldersFields(group)
+	group.ID = g.ID
+	group.CreatedAt = g.CreatedAt
+	group.UpdatedAt = util.GetTimeAsMsSinceEpoch(time.Now())
+	group.Users = g.Users
+	p.dbHandle.groups[group.Name] = group.getACopy()
+	return nil
+}
+
+func (p *MemoryProvider) deleteGroup(group *Group) error {
+	p.dbHandle.Lock()
+	defer p.dbHandle.Unlock()
+	if p.dbHandle.isClosed {
+		return errMemoryProviderClosed
+	}
+	g, err := p.groupExistsInternal(group.Name)
+	if err != nil {
+		return err
+	}
+	for _, oldFolder := range g.VirtualFolders {
+		p.removeGroupFromFolderMapping(oldFolder.Name, g.Name)
+	}
+	for _, username := range g.Users {
+		user, err := p.userExistsInternal(username)
+		if err == nil {
+			var groups []string
+			for _, groupName := range user.Groups {
+				if groupName != g.Name {
+					groups = append(groups, groupName)
+				}
+			}
+			user.Groups = groups
+			p.dbHandle.users[user.Username] = user
+		}
+	}
+	delete(p.dbHandle.groups, group.Name)
+	p.dbHandle.groupnames = make([]string, 0, len(p.dbHandle.groups))
+	for name := range p.dbHandle.groups {
+		p.dbHandle.groupnames = append(p.dbHandle.groupnames, name)
+	}
+	sort.Strings(p.dbHandle.groupnames)
+	return nil
+}
+
+func (p *MemoryProvider) getGroups(limit, offset int, order string) ([]Group, error) {
+	groups := make([]Group, 0, limit)
+	var err error
+	p.dbHandle.Lock()
+	defer p.dbHandle.Unlock()
+	if p.dbHandle.isClosed {
+		return groups, errMemoryProviderClosed
+	}
+	if limit <= 0 {
+		return groups, err
+	}
+	itNum := 0
+	if order == OrderASC {
+		for _, name := range p.dbHandle.groupnames {
+			itNum++
+			if itNum <= offset {
+				continue
+			}
+			g := p.dbHandle.groups[name]
+			group := g.getACopy()
+			group.PrepareForRendering()
+			groups = append(groups, group)
+			if len(groups) >= limit {
+				break
+			}
+		}
+	} else {
+		for i := len(p.dbHandle.groupnames) - 1; i >= 0; i-- {
+			itNum++
+			if itNum <= offset {
+				continue
+			}
+			name := p.dbHandle.groupnames[i]
+			g := p.dbHandle.groups[name]
+			group := g.getACopy()
+			group.PrepareForRendering()
+			groups = append(groups, group)
+			if len(groups) >= limit {
+				break
+			}
+		}
+	}
+	return groups, err
+}
+
+func (p *MemoryProvider) getGroupsWithNames(names []string) ([]Group, error) {
+	p.dbHandle.Lock()
+	defer p.dbHandle.Unlock()
+	if p.dbHandle.isClosed {
+		return nil, errMemoryProviderClosed
+	}
+	groups := make([]Group, 0, len(names))
+	for _, name := range names {
+		if g, ok := p.dbHandle.groups[name]; ok {
+			groups = append(groups, g.getACopy())
+		}
+	}
+	return groups, nil
+}
+
+func (p *MemoryProvider) dumpGroups() ([]Group, error) {
+	p.dbHandle.Lock()
+	defer p.dbHandle.Unlock()
+	groups := make([]Group, 0, len(p.dbHandle.groupnames))
+	if p.dbHandle.isClosed {
+		return groups, errMemoryProviderClosed
+	}
+	for _, name := range p.dbHandle.groupnames {
+		g := p.dbHandle.groups[name]
+		groups = append(groups, g.getACopy())
+	}
+	return groups, nil
+}
+
+func (p *MemoryProvider) joinGroupVirtualFoldersFields(group *Group) []vfs.VirtualFolder {
+	var folders []vfs.VirtualFolder
+	for idx := range group.VirtualFolders {
+		folder := &group.VirtualFolders[idx]
+		f, err := p.addOrUpdateFolderInternal(&folder.BaseVirtualFolder, "", 0, 0, 0)
+		if err == nil {
+			if !util.IsStringInSlice(group.Name, f.Groups) {
+				f.Groups = append(f.Groups, group.Name)
+				p.updateFoldersMappingInternal(f)
+			}
+			folder.BaseVirtualFolder = f
+			folders = append(folders, *folder)
+		}
+	}
+	return folders
+}
+
+func (p *MemoryProvider) removeGroupFromFolderMapping(folderName, groupName string) {
+	folder, err := p.folderExistsInternal(folderName)
+	if err == nil {
+		var groups []string
+		for _, group := range folder.Groups {
+			if group != groupName {
+				groups = append(groups, group)
+			}
+		}
+		folder.Groups = groups
+		p.dbHandle.vfolders[folder.Name] = folder
+	}
+}
+
+func (p *MemoryProvider) addUserToGroupMapping(username, groupName string) {
+	group, err := p.groupExistsInternal(groupName)
+	if err == nil {
+		if !util.IsStringInSlice(username, group.Users) {
+			group.Users = append(group.Users, username)
+			p.dbHandle.groups[group.Name] = group
+		}
+	}
+}
+
+func (p *MemoryProvider) removeUserFromGroupMapping(username, groupName string) {
+	group, err := p.groupExistsInternal(groupName)
+	if err == nil {
+		var usernames []string
+		for _, user := range group.Users {
+			if user != username {
+				usernames = append(usernames, user)
+			}
+		}
+		group.Users = usernames
+		p.dbHandle.groups[group.Name] = group
+	}
+}
+
 func (p *MemoryProvider) apiKeyExistsInternal(keyID string) (APIKey, error) {
 	if val, ok := p.dbHandle.apiKeys[keyID]; ok {
 		return val.getACopy(), nil
@@ -1001,32 +1455,1350 @@ func (p *MemoryProvider) deleteAPIKeysWithAdmin(username string) {
 	}
 }
 
-func (p *MemoryProvider) getNextID() int64 {
-	nextID := int64(1)
-	for _, v := range p.dbHandle.users {
-		if v.ID >= nextID {
-			nextID = v.ID + 1
+func (p *MemoryProvider) shareExistsInternal(shareID, username string) (Share, error) {
+	if val, ok := p.dbHandle.shares[shareID]; ok {
+		if username != "" && val.Username != username {
+			return Share{}, util.NewRecordNotFoundError(fmt.Sprintf("Share %#v does not exist", shareID))
 		}
+		return val.getACopy(), nil
 	}
-	return nextID
+	return Share{}, util.NewRecordNotFoundError(fmt.Sprintf("Share %#v does not exist", shareID))
 }
 
-func (p *MemoryProvider) getNextFolderID() int64 {
-	nextID := int64(1)
-	for _, v := range p.dbHandle.vfolders {
-		if v.ID >= nextID {
-			nextID = v.ID + 1
-		}
+func (p *MemoryProvider) shareExists(shareID, username string) (Share, error) {
+	p.dbHandle.Lock()
+	defer p.dbHandle.Unlock()
+	if p.dbHandle.isClosed {
+		return Share{}, errMemoryProviderClosed
 	}
-	return nextID
+	return p.shareExistsInternal(shareID, username)
 }
 
-func (p *MemoryProvider) getNextAdminID() int64 {
-	nextID := int64(1)
-	for _, a := range p.dbHandle.admins {
-		if a.ID >= nextID {
-			nextID = a.ID + 1
-		}
+func (p *MemoryProvider) addShare(share *Share) error {
+	err := share.validate()
+	if err != nil {
+		return err
+	}
+
+	p.dbHandle.Lock()
+	defer p.dbHandle.Unlock()
+	if p.dbHandle.isClosed {
+		return errMemoryProviderClosed
+	}
+
+	_, err = p.shareExistsInternal(share.ShareID, share.Username)
+	if err == nil {
+		return fmt.Errorf("share %#v already exists", share.ShareID)
+	}
+	if _, err := p.userExistsInternal(share.Username); err != nil {
+		return util.NewValidationError(fmt.Sprintf("related user %#v does not exists", share.Username))
+	}
+	if !share.IsRestore {
+		share.CreatedAt = util.GetTimeAsMsSinceEpoch(time.Now())
+		share.UpdatedAt = share.CreatedAt
+		share.LastUseAt = 0
+		share.UsedTokens = 0
+	}
+	if share.CreatedAt == 0 {
+		share.CreatedAt = util.GetTimeAsMsSinceEpoch(time.Now())
+	}
+	if share.UpdatedAt == 0 {
+		share.UpdatedAt = share.CreatedAt
+	}
+	p.dbHandle.shares[share.ShareID] = share.getACopy()
+	p.dbHandle.sharesIDs = append(p.dbHandle.sharesIDs, share.ShareID)
+	sort.Strings(p.dbHandle.sharesIDs)
+	return nil
+}
+
+func (p *MemoryProvider) updateShare(share *Share) error {
+	err := share.validate()
+	if err != nil {
+		return err
+	}
+
+	p.dbHandle.Lock()
+	defer p.dbHandle.Unlock()
+	if p.dbHandle.isClosed {
+		return errMemoryProviderClosed
+	}
+	s, err := p.shareExistsInternal(share.ShareID, share.Username)
+	if err != nil {
+		return err
+	}
+	if _, err := p.userExistsInternal(share.Username); err != nil {
+		return util.NewValidationError(fmt.Sprintf("related user %#v does not exists", share.Username))
+	}
+	share.ID = s.ID
+	share.ShareID = s.ShareID
+	if !share.IsRestore {
+		share.UsedTokens = s.UsedTokens
+		share.CreatedAt = s.CreatedAt
+		share.LastUseAt = s.LastUseAt
+		share.UpdatedAt = util.GetTimeAsMsSinceEpoch(time.Now())
+	}
+	if share.CreatedAt == 0 {
+		share.CreatedAt = util.GetTimeAsMsSinceEpoch(time.Now())
+	}
+	if share.UpdatedAt == 0 {
+		share.UpdatedAt = share.CreatedAt
+	}
+	p.dbHandle.shares[share.ShareID] = share.getACopy()
+	return nil
+}
+
+func (p *MemoryProvider) deleteShare(share *Share) error {
+	p.dbHandle.Lock()
+	defer p.dbHandle.Unlock()
+	if p.dbHandle.isClosed {
+		return errMemoryProviderClosed
+	}
+	_, err := p.shareExistsInternal(share.ShareID, share.Username)
+	if err != nil {
+		return err
+	}
+
+	delete(p.dbHandle.shares, share.ShareID)
+	delete(p.dbHandle.shareUploads, share.ShareID)
+	p.updateSharesOrdering()
+
+	return nil
+}
+
+func (p *MemoryProvider) updateSharesOrdering() {
+	// this could be more efficient
+	p.dbHandle.sharesIDs = make([]string, 0, len(p.dbHandle.shares))
+	for shareID := range p.dbHandle.shares {
+		p.dbHandle.sharesIDs = append(p.dbHandle.sharesIDs, shareID)
+	}
+	sort.Strings(p.dbHandle.sharesIDs)
+}
+
+func (p *MemoryProvider) getShares(limit int, offset int, order, username string) ([]Share, error) {
+	p.dbHandle.Lock()
+	defer p.dbHandle.Unlock()
+
+	if p.dbHandle.isClosed {
+		return []Share{}, errMemoryProviderClosed
+	}
+	if limit <= 0 {
+		return []Share{}, nil
+	}
+	shares := make([]Share, 0, limit)
+	itNum := 0
+	if order == OrderDESC {
+		for i := len(p.dbHandle.sharesIDs) - 1; i >= 0; i-- {
+			shareID := p.dbHandle.sharesIDs[i]
+			s := p.dbHandle.shares[shareID]
+			if s.Username != username {
+				continue
+			}
+			itNum++
+			if itNum <= offset {
+				continue
+			}
+			share := s.getACopy()
+			share.HideConfidentialData()
+			shares = append(shares, share)
+			if len(shares) >= limit {
+				break
+			}
+		}
+	} else {
+		for _, shareID := range p.dbHandle.sharesIDs {
+			s := p.dbHandle.shares[shareID]
+			if s.Username != username {
+				continue
+			}
+			itNum++
+			if itNum <= offset {
+				continue
+			}
+			share := s.getACopy()
+			share.HideConfidentialData()
+			shares = append(shares, share)
+			if len(shares) >= limit {
+				break
+			}
+		}
+	}
+
+	return shares, nil
+}
+
+func (p *MemoryProvider) dumpShares() ([]Share, error) {
+	p.dbHandle.Lock()
+	defer p.dbHandle.Unlock()
+
+	shares := make([]Share, 0, len(p.dbHandle.shares))
+	if p.dbHandle.isClosed {
+		return shares, errMemoryProviderClosed
+	}
+	for _, s := range p.dbHandle.shares {
+		shares = append(shares, s)
+	}
+	return shares, nil
+}
+
+func (p *MemoryProvider) updateShareLastUse(shareID string, numTokens int) error {
+	p.dbHandle.Lock()
+	defer p.dbHandle.Unlock()
+	if p.dbHandle.isClosed {
+		return errMemoryProviderClosed
+	}
+	share, err := p.shareExistsInternal(shareID, "")
+	if err != nil {
+		return err
+	}
+	share.LastUseAt = util.GetTimeAsMsSinceEpoch(time.Now())
+	share.UsedTokens += numTokens
+	p.dbHandle.shares[share.ShareID] = share
+	return nil
+}
+
+func (p *MemoryProvider) deleteSharesWithUser(username string) {
+	for k, v := range p.dbHandle.shares {
+		if v.Username == username {
+			delete(p.dbHandle.shares, k)
+			delete(p.dbHandle.shareUploads, k)
+		}
+	}
+	p.updateSharesOrdering()
+}
+
+func (p *MemoryProvider) addShareUpload(upload *ShareUpload) error {
+	if err := upload.validate(); err != nil {
+		return err
+	}
+	p.dbHandle.Lock()
+	defer p.dbHandle.Unlock()
+	if p.dbHandle.isClosed {
+		return errMemoryProviderClosed
+	}
+	if _, err := p.shareExistsInternal(upload.ShareID, ""); err != nil {
+		return err
+	}
+	uploads := p.dbHandle.shareUploads[upload.ShareID]
+	upload.ID = int64(len(uploads) + 1)
+	p.dbHandle.shareUploads[upload.ShareID] = append(uploads, *upload)
+	return nil
+}
+
+func (p *MemoryProvider) getShareUploads(shareID string, limit, offset int, order string) ([]ShareUpload, error) {
+	p.dbHandle.Lock()
+	defer p.dbHandle.Unlock()
+
+	if p.dbHandle.isClosed {
+		return []ShareUpload{}, errMemoryProviderClosed
+	}
+	if limit <= 0 {
+		return []ShareUpload{}, nil
+	}
+	uploads := make([]ShareUpload, 0, limit)
+	shareUploads := p.dbHandle.shareUploads[shareID]
+	if offset >= len(shareUploads) {
+		return uploads, nil
+	}
+	if order == OrderDESC {
+		for i := len(shareUploads) - 1 - offset; i >= 0; i-- {
+			uploads = append(uploads, shareUploads[i])
+			if len(uploads) >= limit {
+				break
+			}
+		}
+	} else {
+		for _, upload := range shareUploads[offset:] {
+			uploads = append(uploads, upload)
+			if len(uploads) >= limit {
+				break
+			}
+		}
+	}
+
+	return uploads, nil
+}
+
+func (p *MemoryProvider) accountLockoutExists(username, accountType string) (AccountLockout, error) {
+	p.dbHandle.Lock()
+	defer p.dbHandle.Unlock()
+	if p.dbHandle.isClosed {
+		return AccountLockout{}, errMemoryProviderClosed
+	}
+	lockout, ok := p.dbHandle.accountLockouts[getAccountLockoutKey(username, accountType)]
+	if !ok {
+		return lockout, util.NewRecordNotFoundError(fmt.Sprintf("account lockout for %v %#v does not exist", accountType, username))
+	}
+	return lockout, nil
+}
+
+func (p *MemoryProvider) addAccountLockoutFailure(username, accountType string, now time.Time) (AccountLockout, error) {
+	p.dbHandle.Lock()
+	defer p.dbHandle.Unlock()
+	if p.dbHandle.isClosed {
+		return AccountLockout{}, errMemoryProviderClosed
+	}
+	lockout := AccountLockout{
+		Username: username,
+		Type:     accountType,
+	}
+	if err := lockout.validate(); err != nil {
+		return lockout, err
+	}
+	if l, ok := p.dbHandle.accountLockouts[lockout.getKey()]; ok {
+		lockout = l
+	}
+	lockout.addFailure(now)
+	p.dbHandle.accountLockouts[lockout.getKey()] = lockout
+	return lockout, nil
+}
+
+func (p *MemoryProvider) deleteAccountLockout(username, accountType string) error {
+	p.dbHandle.Lock()
+	defer p.dbHandle.Unlock()
+	if p.dbHandle.isClosed {
+		return errMemoryProviderClosed
+	}
+	key := getAccountLockoutKey(username, accountType)
+	if _, ok := p.dbHandle.accountLockouts[key]; !ok {
+		return util.NewRecordNotFoundError(fmt.Sprintf("account lockout for %v %#v does not exist", accountType, username))
+	}
+	delete(p.dbHandle.accountLockouts, key)
+	return nil
+}
+
+func (p *MemoryProvider) getAccountLockouts(limit, offset int, order string) ([]AccountLockout, error) {
+	lockouts := make([]AccountLockout, 0, limit)
+
+	p.dbHandle.Lock()
+	defer p.dbHandle.Unlock()
+
+	if p.dbHandle.isClosed {
+		return lockouts, errMemoryProviderClosed
+	}
+	if limit <= 0 {
+		return lockouts, nil
+	}
+	keys := make([]string, 0, len(p.dbHandle.accountLockouts))
+	for k := range p.dbHandle.accountLockouts {
+		keys = append(keys, k)
+	}
+	if order == OrderDESC {
+		sort.Sort(sort.Reverse(sort.StringSlice(keys)))
+	} else {
+		sort.Strings(keys)
+	}
+	if offset >= len(keys) {
+		return lockouts, nil
+	}
+	for _, k := range keys[offset:] {
+		lockouts = append(lockouts, p.dbHandle.accountLockouts[k])
+		if len(lockouts) >= limit {
+			break
+		}
+	}
+
+	return lockouts, nil
+}
+
+func (p *MemoryProvider) addFsEvent(event *FsEvent) error {
+	p.dbHandle.Lock()
+	defer p.dbHandle.Unlock()
+	if p.dbHandle.isClosed {
+		return errMemoryProviderClosed
+	}
+	p.dbHandle.fsEvents = append(p.dbHandle.fsEvents, *event)
+	return nil
+}
+
+func (p *MemoryProvider) addProviderEvent(event *ProviderEvent) error {
+	p.dbHandle.Lock()
+	defer p.dbHandle.Unlock()
+	if p.dbHandle.isClosed {
+		return errMemoryProviderClosed
+	}
+	p.dbHandle.providerEvents = append(p.dbHandle.providerEvents, *event)
+	return nil
+}
+
+func (p *MemoryProvider) searchFsEvents(search *FsEventSearch) ([]FsEvent, error) {
+	p.dbHandle.Lock()
+	defer p.dbHandle.Unlock()
+	if p.dbHandle.isClosed {
+		return nil, errMemoryProviderClosed
+	}
+	events := make([]FsEvent, 0, search.Limit)
+	for idx := range p.dbHandle.fsEvents {
+		if search.matches(&p.dbHandle.fsEvents[idx]) {
+			events = append(events, p.dbHandle.fsEvents[idx])
+		}
+	}
+	sort.Slice(events, func(i, j int) bool {
+		if events[i].Timestamp == events[j].Timestamp {
+			return events[i].ID < events[j].ID
+		}
+		return events[i].Timestamp < events[j].Timestamp
+	})
+	if search.Order == OrderDESC {
+		for i, j := 0, len(events)-1; i < j; i, j = i+1, j-1 {
+			events[i], events[j] = events[j], events[i]
+		}
+	}
+	if len(events) > search.Limit {
+		events = events[:search.Limit]
+	}
+	return events, nil
+}
+
+func (p *MemoryProvider) searchProviderEvents(search *ProviderEventSearch) ([]ProviderEvent, error) {
+	p.dbHandle.Lock()
+	defer p.dbHandle.Unlock()
+	if p.dbHandle.isClosed {
+		return nil, errMemoryProviderClosed
+	}
+	events := make([]ProviderEvent, 0, search.Limit)
+	for idx := range p.dbHandle.providerEvents {
+		if search.matches(&p.dbHandle.providerEvents[idx]) {
+			events = append(events, p.dbHandle.providerEvents[idx])
+		}
+	}
+	sort.Slice(events, func(i, j int) bool {
+		if events[i].Timestamp == events[j].Timestamp {
+			return events[i].ID < events[j].ID
+		}
+		return events[i].Timestamp < events[j].Timestamp
+	})
+	if search.Order == OrderDESC {
+		for i, j := 0, len(events)-1; i < j; i, j = i+1, j-1 {
+			events[i], events[j] = events[j], events[i]
+		}
+	}
+	if len(events) > search.Limit {
+		events = events[:search.Limit]
+	}
+	return events, nil
+}
+
+func (p *MemoryProvider) cleanupEvents(before int64) error {
+	p.dbHandle.Lock()
+	defer p.dbHandle.Unlock()
+	if p.dbHandle.isClosed {
+		return errMemoryProviderClosed
+	}
+	fsEvents := make([]FsEvent, 0, len(p.dbHandle.fsEvents))
+	for _, event := range p.dbHandle.fsEvents {
+		if event.Timestamp >= before {
+			fsEvents = append(fsEvents, event)
+		}
+	}
+	p.dbHandle.fsEvents = fsEvents
+	providerEvents := make([]ProviderEvent, 0, len(p.dbHandle.providerEvents))
+	for _, event := range p.dbHandle.providerEvents {
+		if event.Timestamp >= before {
+			providerEvents = append(providerEvents, event)
+		}
+	}
+	p.dbHandle.providerEvents = providerEvents
+	return nil
+}
+
+func (p *MemoryProvider) webDAVLockExists(token string) (WebDAVLock, error) {
+	p.dbHandle.Lock()
+	defer p.dbHandle.Unlock()
+	if p.dbHandle.isClosed {
+		return WebDAVLock{}, errMemoryProviderClosed
+	}
+	lock, ok := p.dbHandle.webDAVLocks[token]
+	if !ok {
+		return lock, util.NewRecordNotFoundError(fmt.Sprintf("WebDAV lock %#v does not exist", token))
+	}
+	return lock, nil
+}
+
+func (p *MemoryProvider) addWebDAVLock(lock *WebDAVLock) error {
+	if err := lock.validate(); err != nil {
+		return err
+	}
+	p.dbHandle.Lock()
+	defer p.dbHandle.Unlock()
+	if p.dbHandle.isClosed {
+		return errMemoryProviderClosed
+	}
+	if _, ok := p.dbHandle.webDAVLocks[lock.Token]; ok {
+		return fmt.Errorf("WebDAV lock %#v already exists", lock.Token)
+	}
+	for token, l := range p.dbHandle.webDAVLocks {
+		if l.Username == lock.Username && l.Root == lock.Root {
+			if !l.isExpiredAt(lock.CreatedAt) {
+				return fmt.Errorf("a WebDAV lock for %#v already exists", lock.Root)
+			}
+			delete(p.dbHandle.webDAVLocks, token)
+		}
+	}
+	p.dbHandle.webDAVLocks[lock.Token] = *lock
+	return nil
+}
+
+func (p *MemoryProvider) updateWebDAVLock(lock *WebDAVLock) error {
+	if err := lock.validate(); err != nil {
+		return err
+	}
+	p.dbHandle.Lock()
+	defer p.dbHandle.Unlock()
+	if p.dbHandle.isClosed {
+		return errMemoryProviderClosed
+	}
+	l, ok := p.dbHandle.webDAVLocks[lock.Token]
+	if !ok {
+		return util.NewRecordNotFoundError(fmt.Sprintf("WebDAV lock %#v does not exist", lock.Token))
+	}
+	l.Duration = lock.Duration
+	l.ExpiresAt = lock.ExpiresAt
+	l.UpdatedAt = lock.UpdatedAt
+	p.dbHandle.webDAVLocks[lock.Token] = l
+	return nil
+}
+
+func (p *MemoryProvider) deleteWebDAVLock(token string) error {
+	p.dbHandle.Lock()
+	defer p.dbHandle.Unlock()
+	if p.dbHandle.isClosed {
+		return errMemoryProviderClosed
+	}
+	if _, ok := p.dbHandle.webDAVLocks[token]; !ok {
+		return util.NewRecordNotFoundError(fmt.Sprintf("WebDAV lock %#v does not exist", token))
+	}
+	delete(p.dbHandle.webDAVLocks, token)
+	return nil
+}
+
+func (p *MemoryProvider) getWebDAVLocks(username string) ([]WebDAVLock, error) {
+	p.dbHandle.Lock()
+	defer p.dbHandle.Unlock()
+	if p.dbHandle.isClosed {
+		return nil, errMemoryProviderClosed
+	}
+	locks := make([]WebDAVLock, 0, 10)
+	for _, lock := range p.dbHandle.webDAVLocks {
+		if lock.Username == username {
+			locks = append(locks, lock)
+		}
+	}
+	return locks, nil
+}
+
+func (p *MemoryProvider) deleteUserWebDAVLocks(username string) error {
+	p.dbHandle.Lock()
+	defer p.dbHandle.Unlock()
+	if p.dbHandle.isClosed {
+		return errMemoryProviderClosed
+	}
+	for token, lock := range p.dbHandle.webDAVLocks {
+		if lock.Username == username {
+			delete(p.dbHandle.webDAVLocks, token)
+		}
+	}
+	return nil
+}
+
+func (p *MemoryProvider) cleanupWebDAVLocks(before int64) error {
+	p.dbHandle.Lock()
+	defer p.dbHandle.Unlock()
+	if p.dbHandle.isClosed {
+		return errMemoryProviderClosed
+	}
+	for token, lock := range p.dbHandle.webDAVLocks {
+		if lock.ExpiresAt > 0 && lock.ExpiresAt <= before {
+			delete(p.dbHandle.webDAVLocks, token)
+		}
+	}
+	return nil
+}
+
+func (p *MemoryProvider) webDAVPropertiesExists(username, virtualPath string) (WebDAVProperties, error) {
+	p.dbHandle.Lock()
+	defer p.dbHandle.Unlock()
+	if p.dbHandle.isClosed {
+		return WebDAVProperties{}, errMemoryProviderClosed
+	}
+	props, ok := p.dbHandle.webDAVProperties[getWebDAVPropertiesKey(username, virtualPath)]
+	if !ok {
+		return props, util.NewRecordNotFoundError(fmt.Sprintf("WebDAV properties for %#v do not exist", virtualPath))
+	}
+	props.Properties = append([]WebDAVProperty(nil), props.Properties...)
+	return props, nil
+}
+
+func (p *MemoryProvider) setWebDAVProperties(props *WebDAVProperties) error {
+	if err := props.validate(); err != nil {
+		return err
+	}
+	p.dbHandle.Lock()
+	defer p.dbHandle.Unlock()
+	if p.dbHandle.isClosed {
+		return errMemoryProviderClosed
+	}
+	stored := *props
+	stored.Properties = append([]WebDAVProperty(nil), props.Properties...)
+	p.dbHandle.webDAVProperties[props.getKey()] = stored
+	return nil
+}
+
+func (p *MemoryProvider) deleteWebDAVProperties(username, virtualPath string) error {
+	p.dbHandle.Lock()
+	defer p.dbHandle.Unlock()
+	if p.dbHandle.isClosed {
+		return errMemoryProviderClosed
+	}
+	p.deleteWebDAVPropertiesTree(username, virtualPath)
+	return nil
+}
+
+func (p *MemoryProvider) renameWebDAVProperties(username, source, target string) error {
+	p.dbHandle.Lock()
+	defer p.dbHandle.Unlock()
+	if p.dbHandle.isClosed {
+		return errMemoryProviderClosed
+	}
+	p.deleteWebDAVPropertiesTree(username, target)
+	var renamed []WebDAVProperties
+	for key, props := range p.dbHandle.webDAVProperties {
+		if props.Username == username && isWebDAVPropertiesPathInTree(props.Path, source) {
+			delete(p.dbHandle.webDAVProperties, key)
+			props.Path = getWebDAVPropertiesRenamedPath(props.Path, source, target)
+			renamed = append(renamed, props)
+		}
+	}
+	for _, props := range renamed {
+		p.dbHandle.webDAVProperties[props.getKey()] = props
+	}
+	return nil
+}
+
+func (p *MemoryProvider) deleteWebDAVPropertiesTree(username, root string) {
+	for key, props := range p.dbHandle.webDAVProperties {
+		if props.Username == username && isWebDAVPropertiesPathInTree(props.Path, root) {
+			delete(p.dbHandle.webDAVProperties, key)
+		}
+	}
+}
+
+func (p *MemoryProvider) deleteUserWebDAVProperties(username string) error {
+	p.dbHandle.Lock()
+	defer p.dbHandle.Unlock()
+	if p.dbHandle.isClosed {
+		return errMemoryProviderClosed
+	}
+	for key, props := range p.dbHandle.webDAVProperties {
+		if props.Username == username {
+			delete(p.dbHandle.webDAVProperties, key)
+		}
+	}
+	return nil
+}
+
+func (p *MemoryProvider) s3AccessKeyExists(accessKeyID string) (S3AccessKey, error) {
+	p.dbHandle.Lock()
+	defer p.dbHandle.Unlock()
+	if p.dbHandle.isClosed {
+		return S3AccessKey{}, errMemoryProviderClosed
+	}
+	key, ok := p.dbHandle.s3AccessKeys[accessKeyID]
+	if !ok {
+		return key, util.NewRecordNotFoundError(fmt.Sprintf("S3 access key %#v does not exist", accessKeyID))
+	}
+	return key.getACopy(), nil
+}
+
+func (p *MemoryProvider) addS3AccessKey(key *S3AccessKey) error {
+	if err := key.validate(); err != nil {
+		return err
+	}
+	p.dbHandle.Lock()
+	defer p.dbHandle.Unlock()
+	if p.dbHandle.isClosed {
+		return errMemoryProviderClosed
+	}
+	if _, ok := p.dbHandle.s3AccessKeys[key.AccessKeyID]; ok {
+		return fmt.Errorf("S3 access key %#v already exists", key.AccessKeyID)
+	}
+	p.dbHandle.s3AccessKeys[key.AccessKeyID] = key.getACopy()
+	return nil
+}
+
+func (p *MemoryProvider) deleteS3AccessKey(accessKeyID, username string) error {
+	p.dbHandle.Lock()
+	defer p.dbHandle.Unlock()
+	if p.dbHandle.isClosed {
+		return errMemoryProviderClosed
+	}
+	key, ok := p.dbHandle.s3AccessKeys[accessKeyID]
+	if !ok || key.Username != username {
+		return util.NewRecordNotFoundError(fmt.Sprintf("S3 access key %#v does not exist", accessKeyID))
+	}
+	delete(p.dbHandle.s3AccessKeys, accessKeyID)
+	return nil
+}
+
+func (p *MemoryProvider) getUserS3AccessKeys(username string) ([]S3AccessKey, error) {
+	p.dbHandle.Lock()
+	defer p.dbHandle.Unlock()
+	if p.dbHandle.isClosed {
+		return nil, errMemoryProviderClosed
+	}
+	keys := make([]S3AccessKey, 0, maxS3AccessKeysPerUser)
+	for _, key := range p.dbHandle.s3AccessKeys {
+		if key.Username == username {
+			keys = append(keys, key.getACopy())
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].CreatedAt < keys[j].CreatedAt
+	})
+	return keys, nil
+}
+
+func (p *MemoryProvider) deleteUserS3AccessKeys(username string) error {
+	p.dbHandle.Lock()
+	defer p.dbHandle.Unlock()
+	if p.dbHandle.isClosed {
+		return errMemoryProviderClosed
+	}
+	for accessKeyID, key := range p.dbHandle.s3AccessKeys {
+		if key.Username == username {
+			delete(p.dbHandle.s3AccessKeys, accessKeyID)
+		}
+	}
+	return nil
+}
+
+func (p *MemoryProvider) updateS3AccessKey(key *S3AccessKey) error {
+	if err := key.validate(); err != nil {
+		return err
+	}
+	p.dbHandle.Lock()
+	defer p.dbHandle.Unlock()
+	if p.dbHandle.isClosed {
+		return errMemoryProviderClosed
+	}
+	if _, ok := p.dbHandle.s3AccessKeys[key.AccessKeyID]; !ok {
+		return util.NewRecordNotFoundError(fmt.Sprintf("S3 access key %#v does not exist", key.AccessKeyID))
+	}
+	p.dbHandle.s3AccessKeys[key.AccessKeyID] = key.getACopy()
+	return nil
+}
+
+func (p *MemoryProvider) dumpS3AccessKeys() ([]S3AccessKey, error) {
+	p.dbHandle.Lock()
+	defer p.dbHandle.Unlock()
+	if p.dbHandle.isClosed {
+		return nil, errMemoryProviderClosed
+	}
+	keys := make([]S3AccessKey, 0, len(p.dbHandle.s3AccessKeys))
+	for _, key := range p.dbHandle.s3AccessKeys {
+		keys = append(keys, key.getACopy())
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].CreatedAt < keys[j].CreatedAt
+	})
+	return keys, nil
+}
+
+func (p *MemoryProvider) tusUploadExists(uploadID string) (TusUpload, error) {
+	p.dbHandle.Lock()
+	defer p.dbHandle.Unlock()
+	if p.dbHandle.isClosed {
+		return TusUpload{}, errMemoryProviderClosed
+	}
+	upload, ok := p.dbHandle.tusUploads[uploadID]
+	if !ok {
+		return upload, util.NewRecordNotFoundError(fmt.Sprintf("upload %#v does not exist", uploadID))
+	}
+	return upload, nil
+}
+
+func (p *MemoryProvider) addTusUpload(upload *TusUpload) error {
+	if err := upload.validate(); err != nil {
+		return err
+	}
+	p.dbHandle.Lock()
+	defer p.dbHandle.Unlock()
+	if p.dbHandle.isClosed {
+		return errMemoryProviderClosed
+	}
+	if _, ok := p.dbHandle.tusUploads[upload.ID]; ok {
+		return fmt.Errorf("upload %#v already exists", upload.ID)
+	}
+	p.dbHandle.tusUploads[upload.ID] = *upload
+	return nil
+}
+
+func (p *MemoryProvider) updateTusUploadOffset(uploadID string, offset, expiresAt, updatedAt int64) error {
+	p.dbHandle.Lock()
+	defer p.dbHandle.Unlock()
+	if p.dbHandle.isClosed {
+		return errMemoryProviderClosed
+	}
+	upload, ok := p.dbHandle.tusUploads[uploadID]
+	if !ok {
+		return util.NewRecordNotFoundError(fmt.Sprintf("upload %#v does not exist", uploadID))
+	}
+	upload.Offset = offset
+	upload.ExpiresAt = expiresAt
+	upload.UpdatedAt = updatedAt
+	p.dbHandle.tusUploads[uploadID] = upload
+	return nil
+}
+
+func (p *MemoryProvider) deleteTusUpload(uploadID string) error {
+	p.dbHandle.Lock()
+	defer p.dbHandle.Unlock()
+	if p.dbHandle.isClosed {
+		return errMemoryProviderClosed
+	}
+	if _, ok := p.dbHandle.tusUploads[uploadID]; !ok {
+		return util.NewRecordNotFoundError(fmt.Sprintf("upload %#v does not exist", uploadID))
+	}
+	delete(p.dbHandle.tusUploads, uploadID)
+	return nil
+}
+
+func (p *MemoryProvider) getTusUploadsCount(username string) (int, error) {
+	p.dbHandle.Lock()
+	defer p.dbHandle.Unlock()
+	if p.dbHandle.isClosed {
+		return 0, errMemoryProviderClosed
+	}
+	count := 0
+	for _, upload := range p.dbHandle.tusUploads {
+		if upload.Username == username {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (p *MemoryProvider) getExpiredTusUploads(before int64) ([]TusUpload, error) {
+	p.dbHandle.Lock()
+	defer p.dbHandle.Unlock()
+	if p.dbHandle.isClosed {
+		return nil, errMemoryProviderClosed
+	}
+	uploads := make([]TusUpload, 0, 10)
+	for _, upload := range p.dbHandle.tusUploads {
+		if upload.ExpiresAt <= before {
+			uploads = append(uploads, upload)
+		}
+	}
+	sort.Slice(uploads, func(i, j int) bool {
+		return uploads[i].ExpiresAt < uploads[j].ExpiresAt
+	})
+	return uploads, nil
+}
+
+func (p *MemoryProvider) deleteUserTusUploads(username string) error {
+	p.dbHandle.Lock()
+	defer p.dbHandle.Unlock()
+	if p.dbHandle.isClosed {
+		return errMemoryProviderClosed
+	}
+	for uploadID, upload := range p.dbHandle.tusUploads {
+		if upload.Username == username {
+			delete(p.dbHandle.tusUploads, uploadID)
+		}
+	}
+	return nil
+}
+
+func (p *MemoryProvider) resetCodeExists(code string) (ResetCode, error) {
+	p.dbHandle.Lock()
+	defer p.dbHandle.Unlock()
+	if p.dbHandle.isClosed {
+		return ResetCode{}, errMemoryProviderClosed
+	}
+	resetCode, ok := p.dbHandle.resetCodes[code]
+	if !ok {
+		return resetCode, util.NewRecordNotFoundError("reset code does not exist")
+	}
+	return resetCode, nil
+}
+
+func (p *MemoryProvider) addResetCode(code *ResetCode) error {
+	if err := code.validate(); err != nil {
+		return err
+	}
+	p.dbHandle.Lock()
+	defer p.dbHandle.Unlock()
+	if p.dbHandle.isClosed {
+		return errMemoryProviderClosed
+	}
+	if _, ok := p.dbHandle.resetCodes[code.Code]; ok {
+		return errors.New("reset code already exists")
+	}
+	p.dbHandle.resetCodes[code.Code] = *code
+	return nil
+}
+
+func (p *MemoryProvider) deleteResetCode(code string) error {
+	p.dbHandle.Lock()
+	defer p.dbHandle.Unlock()
+	if p.dbHandle.isClosed {
+		return errMemoryProviderClosed
+	}
+	if _, ok := p.dbHandle.resetCodes[code]; !ok {
+		return util.NewRecordNotFoundError("reset code does not exist")
+	}
+	delete(p.dbHandle.resetCodes, code)
+	return nil
+}
+
+func (p *MemoryProvider) getResetCodesCount(username, accountType string, after int64) (int, error) {
+	p.dbHandle.Lock()
+	defer p.dbHandle.Unlock()
+	if p.dbHandle.isClosed {
+		return 0, errMemoryProviderClosed
+	}
+	count := 0
+	for _, code := range p.dbHandle.resetCodes {
+		if code.Username == username && code.Type == accountType && code.ExpiresAt > after {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (p *MemoryProvider) deleteExpiredResetCodes(before int64) error {
+	p.dbHandle.Lock()
+	defer p.dbHandle.Unlock()
+	if p.dbHandle.isClosed {
+		return errMemoryProviderClosed
+	}
+	for k, code := range p.dbHandle.resetCodes {
+		if code.ExpiresAt <= before {
+			delete(p.dbHandle.resetCodes, k)
+		}
+	}
+	return nil
+}
+
+func (p *MemoryProvider) multipartUploadExists(key string) (vfs.MultipartUpload, error) {
+	p.dbHandle.Lock()
+	defer p.dbHandle.Unlock()
+	if p.dbHandle.isClosed {
+		return vfs.MultipartUpload{}, errMemoryProviderClosed
+	}
+	upload, ok := p.dbHandle.multipartUploads[key]
+	if !ok {
+		return upload, util.NewRecordNotFoundError("multipart upload does not exist")
+	}
+	return upload, nil
+}
+
+func (p *MemoryProvider) saveMultipartUpload(upload *vfs.MultipartUpload) error {
+	if err := validateMultipartUpload(upload); err != nil {
+		return err
+	}
+	p.dbHandle.Lock()
+	defer p.dbHandle.Unlock()
+	if p.dbHandle.isClosed {
+		return errMemoryProviderClosed
+	}
+	stored := *upload
+	if oldUpload, ok := p.dbHandle.multipartUploads[upload.Key]; ok {
+		stored.CreatedAt = oldUpload.CreatedAt
+	}
+	p.dbHandle.multipartUploads[upload.Key] = stored
+	return nil
+}
+
+func (p *MemoryProvider) deleteMultipartUpload(key string) error {
+	p.dbHandle.Lock()
+	defer p.dbHandle.Unlock()
+	if p.dbHandle.isClosed {
+		return errMemoryProviderClosed
+	}
+	if _, ok := p.dbHandle.multipartUploads[key]; !ok {
+		return util.NewRecordNotFoundError("multipart upload does not exist")
+	}
+	delete(p.dbHandle.multipartUploads, key)
+	return nil
+}
+
+func (p *MemoryProvider) cleanupMultipartUploads(before int64) error {
+	p.dbHandle.Lock()
+	defer p.dbHandle.Unlock()
+	if p.dbHandle.isClosed {
+		return errMemoryProviderClosed
+	}
+	for k, upload := range p.dbHandle.multipartUploads {
+		if upload.UpdatedAt <= before {
+			delete(p.dbHandle.multipartUploads, k)
+		}
+	}
+	return nil
+}
+
+func (p *MemoryProvider) eventRuleExistsInternal(name string) (EventRule, error) {
+	if val, ok := p.dbHandle.eventRules[name]; ok {
+		return val.getACopy(), nil
+	}
+	return EventRule{}, util.NewRecordNotFoundError(fmt.Sprintf("event rule %#v does not exist", name))
+}
+
+func (p *MemoryProvider) eventRuleExists(name string) (EventRule, error) {
+	p.dbHandle.Lock()
+	defer p.dbHandle.Unlock()
+	if p.dbHandle.isClosed {
+		return EventRule{}, errMemoryProviderClosed
+	}
+	return p.eventRuleExistsInternal(name)
+}
+
+func (p *MemoryProvider) addEventRule(rule *EventRule) error {
+	if err := rule.validate(); err != nil {
+		return err
+	}
+
+	p.dbHandle.Lock()
+	defer p.dbHandle.Unlock()
+	if p.dbHandle.isClosed {
+		return errMemoryProviderClosed
+	}
+
+	_, err := p.eventRuleExistsInternal(rule.Name)
+	if err == nil {
+		return fmt.Errorf("event rule %#v already exists", rule.Name)
+	}
+	rule.ID = p.getNextEventRuleID()
+	rule.CreatedAt = util.GetTimeAsMsSinceEpoch(time.Now())
+	rule.UpdatedAt = util.GetTimeAsMsSinceEpoch(time.Now())
+	p.dbHandle.eventRules[rule.Name] = rule.getACopy()
+	p.dbHandle.eventRulesNames = append(p.dbHandle.eventRulesNames, rule.Name)
+	sort.Strings(p.dbHandle.eventRulesNames)
+	return nil
+}
+
+func (p *MemoryProvider) updateEventRule(rule *EventRule) error {
+	if err := rule.validate(); err != nil {
+		return err
+	}
+
+	p.dbHandle.Lock()
+	defer p.dbHandle.Unlock()
+	if p.dbHandle.isClosed {
+		return errMemoryProviderClosed
+	}
+	r, err := p.eventRuleExistsInternal(rule.Name)
+	if err != nil {
+		return err
+	}
+	rule.ID = r.ID
+	rule.CreatedAt = r.CreatedAt
+	rule.UpdatedAt = util.GetTimeAsMsSinceEpoch(time.Now())
+	p.dbHandle.eventRules[rule.Name] = rule.getACopy()
+	return nil
+}
+
+func (p *MemoryProvider) deleteEventRule(rule *EventRule) error {
+	p.dbHandle.Lock()
+	defer p.dbHandle.Unlock()
+	if p.dbHandle.isClosed {
+		return errMemoryProviderClosed
+	}
+	if _, err := p.eventRuleExistsInternal(rule.Name); err != nil {
+		return err
+	}
+	delete(p.dbHandle.eventRules, rule.Name)
+	p.dbHandle.eventRulesNames = make([]string, 0, len(p.dbHandle.eventRules))
+	for name := range p.dbHandle.eventRules {
+		p.dbHandle.eventRulesNames = append(p.dbHandle.eventRulesNames, name)
+	}
+	sort.Strings(p.dbHandle.eventRulesNames)
+	return nil
+}
+
+func (p *MemoryProvider) getEventRules(limit, offset int, order string) ([]EventRule, error) {
+	rules := make([]EventRule, 0, limit)
+	p.dbHandle.Lock()
+	defer p.dbHandle.Unlock()
+	if p.dbHandle.isClosed {
+		return rules, errMemoryProviderClosed
+	}
+	if limit <= 0 {
+		return rules, nil
+	}
+	itNum := 0
+	if order == OrderASC {
+		for _, name := range p.dbHandle.eventRulesNames {
+			itNum++
+			if itNum <= offset {
+				continue
+			}
+			r := p.dbHandle.eventRules[name]
+			rules = append(rules, r.getACopy())
+			if len(rules) >= limit {
+				break
+			}
+		}
+	} else {
+		for i := len(p.dbHandle.eventRulesNames) - 1; i >= 0; i-- {
+			itNum++
+			if itNum <= offset {
+				continue
+			}
+			r := p.dbHandle.eventRules[p.dbHandle.eventRulesNames[i]]
+			rules = append(rules, r.getACopy())
+			if len(rules) >= limit {
+				break
+			}
+		}
+	}
+	return rules, nil
+}
+
+func (p *MemoryProvider) dumpEventRules() ([]EventRule, error) {
+	p.dbHandle.Lock()
+	defer p.dbHandle.Unlock()
+	rules := make([]EventRule, 0, len(p.dbHandle.eventRulesNames))
+	if p.dbHandle.isClosed {
+		return rules, errMemoryProviderClosed
+	}
+	for _, name := range p.dbHandle.eventRulesNames {
+		r := p.dbHandle.eventRules[name]
+		rules = append(rules, r.getACopy())
+	}
+	return rules, nil
+}
+
+func (p *MemoryProvider) scheduleExistsInternal(name string) (Schedule, error) {
+	if val, ok := p.dbHandle.schedules[name]; ok {
+		return val.getACopy(), nil
+	}
+	return Schedule{}, util.NewRecordNotFoundError(fmt.Sprintf("schedule %#v does not exist", name))
+}
+
+func (p *MemoryProvider) scheduleExists(name string) (Schedule, error) {
+	p.dbHandle.Lock()
+	defer p.dbHandle.Unlock()
+	if p.dbHandle.isClosed {
+		return Schedule{}, errMemoryProviderClosed
+	}
+	return p.scheduleExistsInternal(name)
+}
+
+func (p *MemoryProvider) addSchedule(schedule *Schedule) error {
+	if err := schedule.validate(); err != nil {
+		return err
+	}
+
+	p.dbHandle.Lock()
+	defer p.dbHandle.Unlock()
+	if p.dbHandle.isClosed {
+		return errMemoryProviderClosed
+	}
+
+	_, err := p.scheduleExistsInternal(schedule.Name)
+	if err == nil {
+		return fmt.Errorf("schedule %#v already exists", schedule.Name)
+	}
+	schedule.ID = p.getNextScheduleID()
+	schedule.CreatedAt = util.GetTimeAsMsSinceEpoch(time.Now())
+	schedule.UpdatedAt = util.GetTimeAsMsSinceEpoch(time.Now())
+	schedule.LastRun = 0
+	schedule.LastRunStatus = ScheduleRunStatusNone
+	schedule.LastRunError = ""
+	p.dbHandle.schedules[schedule.Name] = schedule.getACopy()
+	p.dbHandle.schedulesNames = append(p.dbHandle.schedulesNames, schedule.Name)
+	sort.Strings(p.dbHandle.schedulesNames)
+	return nil
+}
+
+func (p *MemoryProvider) updateSchedule(schedule *Schedule) error {
+	if err := schedule.validate(); err != nil {
+		return err
+	}
+
+	p.dbHandle.Lock()
+	defer p.dbHandle.Unlock()
+	if p.dbHandle.isClosed {
+		return errMemoryProviderClosed
+	}
+	s, err := p.scheduleExistsInternal(schedule.Name)
+	if err != nil {
+		return err
+	}
+	schedule.ID = s.ID
+	schedule.CreatedAt = s.CreatedAt
+	schedule.UpdatedAt = util.GetTimeAsMsSinceEpoch(time.Now())
+	schedule.LastRun = s.LastRun
+	schedule.LastRunStatus = s.LastRunStatus
+	schedule.LastRunError = s.LastRunError
+	p.dbHandle.schedules[schedule.Name] = schedule.getACopy()
+	return nil
+}
+
+func (p *MemoryProvider) deleteSchedule(schedule *Schedule) error {
+	p.dbHandle.Lock()
+	defer p.dbHandle.Unlock()
+	if p.dbHandle.isClosed {
+		return errMemoryProviderClosed
+	}
+	if _, err := p.scheduleExistsInternal(schedule.Name); err != nil {
+		return err
+	}
+	delete(p.dbHandle.schedules, schedule.Name)
+	p.dbHandle.schedulesNames = make([]string, 0, len(p.dbHandle.schedules))
+	for name := range p.dbHandle.schedules {
+		p.dbHandle.schedulesNames = append(p.dbHandle.schedulesNames, name)
+	}
+	sort.Strings(p.dbHandle.schedulesNames)
+	return nil
+}
+
+func (p *MemoryProvider) getSchedules(limit, offset int, order string) ([]Schedule, error) {
+	schedules := make([]Schedule, 0, limit)
+	p.dbHandle.Lock()
+	defer p.dbHandle.Unlock()
+	if p.dbHandle.isClosed {
+		return schedules, errMemoryProviderClosed
+	}
+	if limit <= 0 {
+		return schedules, nil
+	}
+	itNum := 0
+	if order == OrderASC {
+		for _, name := range p.dbHandle.schedulesNames {
+			itNum++
+			if itNum <= offset {
+				continue
+			}
+			s := p.dbHandle.schedules[name]
+			schedules = append(schedules, s.getACopy())
+			if len(schedules) >= limit {
+				break
+			}
+		}
+	} else {
+		for i := len(p.dbHandle.schedulesNames) - 1; i >= 0; i-- {
+			itNum++
+			if itNum <= offset {
+				continue
+			}
+			s := p.dbHandle.schedules[p.dbHandle.schedulesNames[i]]
+			schedules = append(schedules, s.getACopy())
+			if len(schedules) >= limit {
+				break
+			}
+		}
+	}
+	return schedules, nil
+}
+
+func (p *MemoryProvider) dumpSchedules() ([]Schedule, error) {
+	p.dbHandle.Lock()
+	defer p.dbHandle.Unlock()
+	schedules := make([]Schedule, 0, len(p.dbHandle.schedulesNames))
+	if p.dbHandle.isClosed {
+		return schedules, errMemoryProviderClosed
+	}
+	for _, name := range p.dbHandle.schedulesNames {
+		s := p.dbHandle.schedules[name]
+		schedules = append(schedules, s.getACopy())
+	}
+	return schedules, nil
+}
+
+func (p *MemoryProvider) claimScheduleRun(name string, runAt int64) (bool, error) {
+	p.dbHandle.Lock()
+	defer p.dbHandle.Unlock()
+	if p.dbHandle.isClosed {
+		return false, errMemoryProviderClosed
+	}
+	schedule, err := p.scheduleExistsInternal(name)
+	if err != nil {
+		return false, err
+	}
+	if schedule.LastRun >= runAt {
+		return false, nil
+	}
+	schedule.LastRun = runAt
+	schedule.LastRunStatus = ScheduleRunStatusRunning
+	schedule.LastRunError = ""
+	p.dbHandle.schedules[name] = schedule
+	return true, nil
+}
+
+func (p *MemoryProvider) setScheduleRunResult(name string, status int, runError string) error {
+	p.dbHandle.Lock()
+	defer p.dbHandle.Unlock()
+	if p.dbHandle.isClosed {
+		return errMemoryProviderClosed
+	}
+	schedule, err := p.scheduleExistsInternal(name)
+	if err != nil {
+		return err
+	}
+	schedule.LastRunStatus = status
+	schedule.LastRunError = runError
+	p.dbHandle.schedules[name] = schedule
+	return nil
+}
+
+func (p *MemoryProvider) getNextID() int64 {
+	nextID := int64(1)
+	for _, v := range p.dbHandle.users {
+		if v.ID >= nextID {
+			nextID = v.ID + 1
+		}
+	}
+	return nextID
+}
+
+func (p *MemoryProvider) getNextFolderID() int64 {
+	nextID := int64(1)
+	for _, v := range p.dbHandle.vfolders {
+		if v.ID >= nextID {
+			nextID = v.ID + 1
+		}
+	}
+	return nextID
+}
+
+func (p *MemoryProvider) getNextGroupID() int64 {
+	nextID := int64(1)
+	for _, g := range p.dbHandle.groups {
+		if g.ID >= nextID {
+			nextID = g.ID + 1
+		}
+	}
+	return nextID
+}
+
+func (p *MemoryProvider) getNextEventRuleID() int64 {
+	nextID := int64(1)
+	for _, r := range p.dbHandle.eventRules {
+		if r.ID >= nextID {
+			nextID = r.ID + 1
+		}
+	}
+	return nextID
+}
+
+func (p *MemoryProvider) getNextScheduleID() int64 {
+	nextID := int64(1)
+	for _, s := range p.dbHandle.schedules {
+		if s.ID >= nextID {
+			nextID = s.ID + 1
+		}
+	}
+	return nextID
+}
+
+func (p *MemoryProvider) getNextAdminID() int64 {
+	nextID := int64(1)
+	for _, a := range p.dbHandle.admins {
+		if a.ID >= nextID {
+			nextID = a.ID + 1
+		}
 	}
 	return nextID
 }
@@ -1038,8 +2810,26 @@ func (p *MemoryProvider) clear() {
 	p.dbHandle.users = make(map[string]User)
 	p.dbHandle.vfoldersNames = []string{}
 	p.dbHandle.vfolders = make(map[string]vfs.BaseVirtualFolder)
+	p.dbHandle.groupnames = []string{}
+	p.dbHandle.groups = make(map[string]Group)
 	p.dbHandle.admins = make(map[string]Admin)
 	p.dbHandle.adminsUsernames = []string{}
+	p.dbHandle.shares = make(map[string]Share)
+	p.dbHandle.sharesIDs = []string{}
+	p.dbHandle.shareUploads = make(map[string][]ShareUpload)
+	p.dbHandle.accountLockouts = make(map[string]AccountLockout)
+	p.dbHandle.fsEvents = nil
+	p.dbHandle.providerEvents = nil
+	p.dbHandle.eventRules = make(map[string]EventRule)
+	p.dbHandle.eventRulesNames = []string{}
+	p.dbHandle.schedules = make(map[string]Schedule)
+	p.dbHandle.schedulesNames = []string{}
+	p.dbHandle.webDAVLocks = make(map[string]WebDAVLock)
+	p.dbHandle.webDAVProperties = make(map[string]WebDAVProperties)
+	p.dbHandle.s3AccessKeys = make(map[string]S3AccessKey)
+	p.dbHandle.tusUploads = make(map[string]TusUpload)
+	p.dbHandle.resetCodes = make(map[string]ResetCode)
+	p.dbHandle.multipartUploads = make(map[string]vfs.MultipartUpload)
 }
 
 func (p *MemoryProvider) reloadConfig() error {
@@ -1079,6 +2869,10 @@ func (p *MemoryProvider) reloadConfig() error {
 		return err
 	}
 
+	if err := p.restoreGroups(&dump); err != nil {
+		return err
+	}
+
 	if err := p.restoreUsers(&dump); err != nil {
 		return err
 	}
@@ -1091,10 +2885,89 @@ func (p *MemoryProvider) reloadConfig() error {
 		return err
 	}
 
+	if err := p.restoreShares(&dump); err != nil {
+		return err
+	}
+
+	if err := p.restoreEventRules(&dump); err != nil {
+		return err
+	}
+
+	if err := p.restoreSchedules(&dump); err != nil {
+		return err
+	}
+
 	providerLog(logger.LevelDebug, "config loaded from file: %#v", p.dbHandle.configFile)
 	return nil
 }
 
+func (p *MemoryProvider) restoreShares(dump *BackupData) error {
+	for _, share := range dump.Shares {
+		s, err := p.shareExists(share.ShareID, "")
+		share := share // pin
+		share.IsRestore = true
+		if err == nil {
+			share.ID = s.ID
+			err = UpdateShare(&share, ActionExecutorSystem, "")
+			if err != nil {
+				providerLog(logger.LevelWarn, "error updating share %#v: %v", share.ShareID, err)
+				return err
+			}
+		} else {
+			err = AddShare(&share, ActionExecutorSystem, "")
+			if err != nil {
+				providerLog(logger.LevelWarn, "error adding share %#v: %v", share.ShareID, err)
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (p *MemoryProvider) restoreEventRules(dump *BackupData) error {
+	for _, rule := range dump.EventRules {
+		rule := rule // pin
+		r, err := p.eventRuleExists(rule.Name)
+		if err == nil {
+			rule.ID = r.ID
+			err = UpdateEventRule(&rule, ActionExecutorSystem, "")
+			if err != nil {
+				providerLog(logger.LevelWarn, "error updating event rule %#v: %v", rule.Name, err)
+				return err
+			}
+		} else {
+			err = AddEventRule(&rule, ActionExecutorSystem, "")
+			if err != nil {
+				providerLog(logger.LevelWarn, "error adding event rule %#v: %v", rule.Name, err)
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (p *MemoryProvider) restoreSchedules(dump *BackupData) error {
+	for _, schedule := range dump.Schedules {
+		schedule := schedule // pin
+		s, err := p.scheduleExists(schedule.Name)
+		if err == nil {
+			schedule.ID = s.ID
+			err = UpdateSchedule(&schedule, ActionExecutorSystem, "")
+			if err != nil {
+				providerLog(logger.LevelWarn, "error updating schedule %#v: %v", schedule.Name, err)
+				return err
+			}
+		} else {
+			err = AddSchedule(&schedule, ActionExecutorSystem, "")
+			if err != nil {
+				providerLog(logger.LevelWarn, "error adding schedule %#v: %v", schedule.Name, err)
+				return err
+			}
+		}
+	}
+	return nil
+}
+
 func (p *MemoryProvider) restoreAPIKeys(dump *BackupData) error {
 	for _, apiKey := range dump.APIKeys {
 		if apiKey.KeyID == "" {
@@ -1165,6 +3038,28 @@ func (p *MemoryProvider) restoreFolders(dump *BackupData) error {
 	return nil
 }
 
+func (p *MemoryProvider) restoreGroups(dump *BackupData) error {
+	for _, group := range dump.Groups {
+		group := group // pin
+		g, err := p.groupExists(group.Name)
+		if err == nil {
+			group.ID = g.ID
+			err = UpdateGroup(&group, g.Users, ActionExecutorSystem, "")
+			if err != nil {
+				providerLog(logger.LevelWarn, "error updating group %#v: %v", group.Name, err)
+				return err
+			}
+		} else {
+			err = AddGroup(&group, ActionExecutorSystem, "")
+			if err != nil {
+				providerLog(logger.LevelWarn, "error adding group %#v: %v", group.Name, err)
+				return err
+			}
+		}
+	}
+	return nil
+}
+
 func (p *MemoryProvider) restoreUsers(dump *BackupData) error {
 	for _, user := range dump.Users {
 		user := user // pin
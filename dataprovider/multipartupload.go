@@ -0,0 +1,87 @@
+package dataprovider
+
+import (
+	"time"
+
+	"github.com/drakkan/sftpgo/v2/logger"
+	"github.com/drakkan/sftpgo/v2/util"
+	"github.com/drakkan/sftpgo/v2/vfs"
+)
+
+const (
+	multipartUploadsCleanupInterval = 1 * time.Hour
+)
+
+var (
+	multipartUploadsCleanupTicker     *time.Ticker
+	multipartUploadsCleanupTickerDone chan bool
+)
+
+// multipartUploadStore stores the interrupted uploads to the Cloud Storage
+// backends within the data provider. This way the uploads can be resumed
+// after a restart or using a different SFTPGo instance
+type multipartUploadStore struct{}
+
+func (s *multipartUploadStore) GetMultipartUpload(key string) (vfs.MultipartUpload, error) {
+	return provider.multipartUploadExists(key)
+}
+
+func (s *multipartUploadStore) SaveMultipartUpload(upload *vfs.MultipartUpload) error {
+	return provider.saveMultipartUpload(upload)
+}
+
+func (s *multipartUploadStore) DeleteMultipartUpload(key string) error {
+	return provider.deleteMultipartUpload(key)
+}
+
+func validateMultipartUpload(upload *vfs.MultipartUpload) error {
+	if upload.Key == "" {
+		return util.NewValidationError("upload key is mandatory")
+	}
+	if upload.Size < 0 {
+		return util.NewValidationError("invalid upload size")
+	}
+	if upload.State == "" {
+		return util.NewValidationError("upload state is mandatory")
+	}
+	if upload.CreatedAt == 0 || upload.UpdatedAt == 0 {
+		return util.NewValidationError("creation and update time are mandatory")
+	}
+	return nil
+}
+
+func startMultipartUploadsCleanupTimer() {
+	multipartUploadsCleanupTicker = time.NewTicker(multipartUploadsCleanupInterval)
+	multipartUploadsCleanupTickerDone = make(chan bool)
+
+	go func() {
+		for {
+			select {
+			case <-multipartUploadsCleanupTickerDone:
+				return
+			case t := <-multipartUploadsCleanupTicker.C:
+				cleanupMultipartUploads(t)
+			}
+		}
+	}()
+}
+
+func stopMultipartUploadsCleanupTimer() {
+	if multipartUploadsCleanupTicker != nil {
+		multipartUploadsCleanupTicker.Stop()
+		multipartUploadsCleanupTickerDone <- true
+		multipartUploadsCleanupTicker = nil
+	}
+}
+
+// cleanupMultipartUploads removes the interrupted uploads that cannot be resumed anymore.
+// The data uploaded to S3 are not removed, an S3 lifecycle rule should be configured to
+// abort the incomplete multipart uploads
+func cleanupMultipartUploads(now time.Time) {
+	before := util.GetTimeAsMsSinceEpoch(now.Add(-vfs.MultipartUploadMaxAge))
+	if err := provider.cleanupMultipartUploads(before); err != nil {
+		providerLog(logger.LevelWarn, "unable to remove multipart uploads not updated since %v: %v", before, err)
+		return
+	}
+	providerLog(logger.LevelDebug, "multipart uploads not updated since %v removed", before)
+}
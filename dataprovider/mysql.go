@@ -66,6 +66,153 @@ const (
 
 	mysqlV13SQL     = "ALTER TABLE `{{users}}` ADD COLUMN `email` varchar(255) NULL;"
 	mysqlV13DownSQL = "ALTER TABLE `{{users}}` DROP COLUMN `email`;"
+	mysqlV14SQL     = "CREATE TABLE `{{shares}}` (`id` integer AUTO_INCREMENT NOT NULL PRIMARY KEY, " +
+		"`share_id` varchar(60) NOT NULL UNIQUE, `name` varchar(255) NOT NULL, `description` varchar(512) NULL, " +
+		"`scope` integer NOT NULL, `paths` longtext NOT NULL, `created_at` bigint NOT NULL, " +
+		"`updated_at` bigint NOT NULL, `last_use_at` bigint NOT NULL, `expires_at` bigint NOT NULL, " +
+		"`password` longtext NULL, `max_tokens` integer NOT NULL, `used_tokens` integer NOT NULL, " +
+		"`allow_from` longtext NULL, `user_id` integer NOT NULL);" +
+		"ALTER TABLE `{{shares}}` ADD CONSTRAINT `{{prefix}}shares_user_id_fk_users_id` " +
+		"FOREIGN KEY (`user_id`) REFERENCES `{{users}}` (`id`) ON DELETE CASCADE;"
+	mysqlV14DownSQL = "DROP TABLE `{{shares}}` CASCADE;"
+	mysqlV15SQL     = "CREATE TABLE `{{share_uploads}}` (`id` integer AUTO_INCREMENT NOT NULL PRIMARY KEY, " +
+		"`path` longtext NOT NULL, `size` bigint NOT NULL, `ip` varchar(50) NOT NULL, `uploader` varchar(255) NULL, " +
+		"`uploaded_at` bigint NOT NULL, `share_id` integer NOT NULL);" +
+		"ALTER TABLE `{{share_uploads}}` ADD CONSTRAINT `{{prefix}}share_uploads_share_id_fk_shares_id` " +
+		"FOREIGN KEY (`share_id`) REFERENCES `{{shares}}` (`id`) ON DELETE CASCADE;"
+	mysqlV15DownSQL = "DROP TABLE `{{share_uploads}}` CASCADE;"
+	mysqlV16SQL     = "CREATE TABLE `{{groups}}` (`id` integer AUTO_INCREMENT NOT NULL PRIMARY KEY, `name` varchar(255) NOT NULL UNIQUE, " +
+		"`description` varchar(512) NULL, `created_at` bigint NOT NULL, `updated_at` bigint NOT NULL, `user_settings` longtext NULL);" +
+		"CREATE TABLE `{{groups_folders_mapping}}` (`id` integer AUTO_INCREMENT NOT NULL PRIMARY KEY, " +
+		"`virtual_path` varchar(512) NOT NULL, `quota_size` bigint NOT NULL, `quota_files` integer NOT NULL, " +
+		"`folder_id` integer NOT NULL, `group_id` integer NOT NULL);" +
+		"ALTER TABLE `{{groups_folders_mapping}}` ADD CONSTRAINT `{{prefix}}unique_group_folder_mapping` UNIQUE (`group_id`, `folder_id`);" +
+		"ALTER TABLE `{{groups_folders_mapping}}` ADD CONSTRAINT `{{prefix}}groups_folders_mapping_folder_id_fk_folders_id` " +
+		"FOREIGN KEY (`folder_id`) REFERENCES `{{folders}}` (`id`) ON DELETE CASCADE;" +
+		"ALTER TABLE `{{groups_folders_mapping}}` ADD CONSTRAINT `{{prefix}}groups_folders_mapping_group_id_fk_groups_id` " +
+		"FOREIGN KEY (`group_id`) REFERENCES `{{groups}}` (`id`) ON DELETE CASCADE;" +
+		"CREATE TABLE `{{groups_mapping}}` (`id` integer AUTO_INCREMENT NOT NULL PRIMARY KEY, " +
+		"`user_id` integer NOT NULL, `group_id` integer NOT NULL);" +
+		"ALTER TABLE `{{groups_mapping}}` ADD CONSTRAINT `{{prefix}}unique_group_mapping` UNIQUE (`user_id`, `group_id`);" +
+		"ALTER TABLE `{{groups_mapping}}` ADD CONSTRAINT `{{prefix}}groups_mapping_user_id_fk_users_id` " +
+		"FOREIGN KEY (`user_id`) REFERENCES `{{users}}` (`id`) ON DELETE CASCADE;" +
+		"ALTER TABLE `{{groups_mapping}}` ADD CONSTRAINT `{{prefix}}groups_mapping_group_id_fk_groups_id` " +
+		"FOREIGN KEY (`group_id`) REFERENCES `{{groups}}` (`id`) ON DELETE CASCADE;"
+	mysqlV16DownSQL = "DROP TABLE `{{groups_mapping}}` CASCADE;" +
+		"DROP TABLE `{{groups_folders_mapping}}` CASCADE;" +
+		"DROP TABLE `{{groups}}` CASCADE;"
+	mysqlV17SQL = "ALTER TABLE `{{users}}` ADD COLUMN `upload_data_transfer` bigint DEFAULT 0 NOT NULL;" +
+		"ALTER TABLE `{{users}}` ALTER COLUMN `upload_data_transfer` DROP DEFAULT;" +
+		"ALTER TABLE `{{users}}` ADD COLUMN `download_data_transfer` bigint DEFAULT 0 NOT NULL;" +
+		"ALTER TABLE `{{users}}` ALTER COLUMN `download_data_transfer` DROP DEFAULT;" +
+		"ALTER TABLE `{{users}}` ADD COLUMN `total_data_transfer` bigint DEFAULT 0 NOT NULL;" +
+		"ALTER TABLE `{{users}}` ALTER COLUMN `total_data_transfer` DROP DEFAULT;" +
+		"ALTER TABLE `{{users}}` ADD COLUMN `used_upload_data_transfer` bigint DEFAULT 0 NOT NULL;" +
+		"ALTER TABLE `{{users}}` ALTER COLUMN `used_upload_data_transfer` DROP DEFAULT;" +
+		"ALTER TABLE `{{users}}` ADD COLUMN `used_download_data_transfer` bigint DEFAULT 0 NOT NULL;" +
+		"ALTER TABLE `{{users}}` ALTER COLUMN `used_download_data_transfer` DROP DEFAULT;" +
+		"ALTER TABLE `{{users}}` ADD COLUMN `last_data_transfer_reset` bigint DEFAULT 0 NOT NULL;" +
+		"ALTER TABLE `{{users}}` ALTER COLUMN `last_data_transfer_reset` DROP DEFAULT;" +
+		"ALTER TABLE `{{users}}` ADD COLUMN `data_transfer_reset_period` varchar(32) NULL;" +
+		"ALTER TABLE `{{folders}}` ADD COLUMN `upload_data_transfer` bigint DEFAULT 0 NOT NULL;" +
+		"ALTER TABLE `{{folders}}` ALTER COLUMN `upload_data_transfer` DROP DEFAULT;" +
+		"ALTER TABLE `{{folders}}` ADD COLUMN `download_data_transfer` bigint DEFAULT 0 NOT NULL;" +
+		"ALTER TABLE `{{folders}}` ALTER COLUMN `download_data_transfer` DROP DEFAULT;" +
+		"ALTER TABLE `{{folders}}` ADD COLUMN `total_data_transfer` bigint DEFAULT 0 NOT NULL;" +
+		"ALTER TABLE `{{folders}}` ALTER COLUMN `total_data_transfer` DROP DEFAULT;" +
+		"ALTER TABLE `{{folders}}` ADD COLUMN `used_upload_data_transfer` bigint DEFAULT 0 NOT NULL;" +
+		"ALTER TABLE `{{folders}}` ALTER COLUMN `used_upload_data_transfer` DROP DEFAULT;" +
+		"ALTER TABLE `{{folders}}` ADD COLUMN `used_download_data_transfer` bigint DEFAULT 0 NOT NULL;" +
+		"ALTER TABLE `{{folders}}` ALTER COLUMN `used_download_data_transfer` DROP DEFAULT;" +
+		"ALTER TABLE `{{folders}}` ADD COLUMN `last_data_transfer_reset` bigint DEFAULT 0 NOT NULL;" +
+		"ALTER TABLE `{{folders}}` ALTER COLUMN `last_data_transfer_reset` DROP DEFAULT;" +
+		"ALTER TABLE `{{folders}}` ADD COLUMN `data_transfer_reset_period` varchar(32) NULL"
+	mysqlV17DownSQL = "ALTER TABLE `{{folders}}` DROP COLUMN `data_transfer_reset_period`;" +
+		"ALTER TABLE `{{folders}}` DROP COLUMN `last_data_transfer_reset`;" +
+		"ALTER TABLE `{{folders}}` DROP COLUMN `used_download_data_transfer`;" +
+		"ALTER TABLE `{{folders}}` DROP COLUMN `used_upload_data_transfer`;" +
+		"ALTER TABLE `{{folders}}` DROP COLUMN `total_data_transfer`;" +
+		"ALTER TABLE `{{folders}}` DROP COLUMN `download_data_transfer`;" +
+		"ALTER TABLE `{{folders}}` DROP COLUMN `upload_data_transfer`;" +
+		"ALTER TABLE `{{users}}` DROP COLUMN `data_transfer_reset_period`;" +
+		"ALTER TABLE `{{users}}` DROP COLUMN `last_data_transfer_reset`;" +
+		"ALTER TABLE `{{users}}` DROP COLUMN `used_download_data_transfer`;" +
+		"ALTER TABLE `{{users}}` DROP COLUMN `used_upload_data_transfer`;" +
+		"ALTER TABLE `{{users}}` DROP COLUMN `total_data_transfer`;" +
+		"ALTER TABLE `{{users}}` DROP COLUMN `download_data_transfer`;" +
+		"ALTER TABLE `{{users}}` DROP COLUMN `upload_data_transfer`"
+	mysqlV18SQL = "ALTER TABLE `{{users}}` ADD COLUMN `last_password_change` bigint DEFAULT 0 NOT NULL;" +
+		"ALTER TABLE `{{users}}` ALTER COLUMN `last_password_change` DROP DEFAULT;" +
+		"UPDATE `{{users}}` SET `last_password_change` = `updated_at`;" +
+		"ALTER TABLE `{{admins}}` ADD COLUMN `last_password_change` bigint DEFAULT 0 NOT NULL;" +
+		"ALTER TABLE `{{admins}}` ALTER COLUMN `last_password_change` DROP DEFAULT;" +
+		"UPDATE `{{admins}}` SET `last_password_change` = `updated_at`"
+	mysqlV18DownSQL = "ALTER TABLE `{{admins}}` DROP COLUMN `last_password_change`;" +
+		"ALTER TABLE `{{users}}` DROP COLUMN `last_password_change`"
+	mysqlV19SQL = "CREATE TABLE `{{account_lockouts}}` (`id` integer AUTO_INCREMENT NOT NULL PRIMARY KEY, " +
+		"`username` varchar(255) NOT NULL, `account_type` varchar(16) NOT NULL, `failures` integer NOT NULL, " +
+		"`first_failure_at` bigint NOT NULL, `last_failure_at` bigint NOT NULL, `locked_at` bigint NOT NULL, " +
+		"`locked_until` bigint NOT NULL);" +
+		"ALTER TABLE `{{account_lockouts}}` ADD CONSTRAINT `{{prefix}}unique_account_lockout` UNIQUE (`username`, `account_type`);"
+	mysqlV19DownSQL = "DROP TABLE `{{account_lockouts}}` CASCADE;"
+	mysqlV20SQL     = "CREATE TABLE `{{fs_events}}` (`id` varchar(36) NOT NULL PRIMARY KEY, `timestamp` bigint NOT NULL, " +
+		"`action` varchar(60) NOT NULL, `username` varchar(255) NOT NULL, `fs_path` longtext NOT NULL, " +
+		"`fs_target_path` longtext NOT NULL, `virtual_path` longtext NOT NULL, `virtual_target_path` longtext NOT NULL, " +
+		"`ssh_cmd` varchar(255) NOT NULL, `file_size` bigint NOT NULL, `status` integer NOT NULL, " +
+		"`protocol` varchar(30) NOT NULL, `ip` varchar(50) NOT NULL, `instance_id` varchar(255) NOT NULL);" +
+		"CREATE TABLE `{{provider_events}}` (`id` varchar(36) NOT NULL PRIMARY KEY, `timestamp` bigint NOT NULL, " +
+		"`action` varchar(60) NOT NULL, `username` varchar(255) NOT NULL, `ip` varchar(50) NOT NULL, " +
+		"`object_type` varchar(60) NOT NULL, `object_name` varchar(255) NOT NULL, `instance_id` varchar(255) NOT NULL);" +
+		"CREATE INDEX `{{prefix}}fs_events_timestamp_idx` ON `{{fs_events}}` (`timestamp`);" +
+		"CREATE INDEX `{{prefix}}provider_events_timestamp_idx` ON `{{provider_events}}` (`timestamp`);"
+	mysqlV20DownSQL = "DROP TABLE `{{provider_events}}` CASCADE;" +
+		"DROP TABLE `{{fs_events}}` CASCADE;"
+	mysqlV21SQL = "CREATE TABLE `{{events_rules}}` (`id` integer AUTO_INCREMENT NOT NULL PRIMARY KEY, `name` varchar(255) NOT NULL UNIQUE, " +
+		"`description` varchar(512) NULL, `created_at` bigint NOT NULL, `updated_at` bigint NOT NULL, `event_trigger` integer NOT NULL, " +
+		"`conditions` longtext NOT NULL, `actions` longtext NOT NULL);"
+	mysqlV21DownSQL = "DROP TABLE `{{events_rules}}` CASCADE;"
+	mysqlV22SQL     = "CREATE TABLE `{{schedules}}` (`id` integer AUTO_INCREMENT NOT NULL PRIMARY KEY, `name` varchar(255) NOT NULL UNIQUE, " +
+		"`description` varchar(512) NULL, `created_at` bigint NOT NULL, `updated_at` bigint NOT NULL, `status` integer NOT NULL, " +
+		"`cron_expression` varchar(255) NOT NULL, `task` integer NOT NULL, `options` longtext NOT NULL, " +
+		"`last_run` bigint DEFAULT 0 NOT NULL, `last_run_status` integer DEFAULT 0 NOT NULL, `last_run_error` longtext NULL);"
+	mysqlV22DownSQL = "DROP TABLE `{{schedules}}` CASCADE;"
+	mysqlV23SQL     = "ALTER TABLE `{{folders}}` ADD COLUMN `data_retention` longtext NULL;"
+	mysqlV23DownSQL = "ALTER TABLE `{{folders}}` DROP COLUMN `data_retention`;"
+	mysqlV24SQL     = "CREATE TABLE `{{webdav_locks}}` (`id` integer AUTO_INCREMENT NOT NULL PRIMARY KEY, " +
+		"`token` varchar(255) NOT NULL UNIQUE, `username` varchar(255) NOT NULL, `root` longtext NOT NULL, " +
+		"`root_key` varchar(64) NOT NULL, `duration` bigint NOT NULL, `expires_at` bigint NOT NULL, `owner_xml` longtext NULL, " +
+		"`zero_depth` integer NOT NULL, `created_at` bigint NOT NULL, `updated_at` bigint NOT NULL);" +
+		"ALTER TABLE `{{webdav_locks}}` ADD CONSTRAINT `{{prefix}}unique_webdav_lock_root` UNIQUE (`username`, `root_key`);" +
+		"CREATE INDEX `{{prefix}}webdav_locks_expires_at_idx` ON `{{webdav_locks}}` (`expires_at`);"
+	mysqlV24DownSQL = "DROP TABLE `{{webdav_locks}}` CASCADE;"
+	mysqlV25SQL     = "CREATE TABLE `{{webdav_properties}}` (`id` integer AUTO_INCREMENT NOT NULL PRIMARY KEY, " +
+		"`username` varchar(255) NOT NULL, `path` longtext NOT NULL, `path_key` varchar(64) NOT NULL, " +
+		"`properties` longtext NOT NULL, `updated_at` bigint NOT NULL);" +
+		"ALTER TABLE `{{webdav_properties}}` ADD CONSTRAINT `{{prefix}}unique_webdav_properties_path` UNIQUE (`username`, `path_key`);"
+	mysqlV25DownSQL = "DROP TABLE `{{webdav_properties}}` CASCADE;"
+	mysqlV26SQL     = "CREATE TABLE `{{s3_access_keys}}` (`id` integer AUTO_INCREMENT NOT NULL PRIMARY KEY, " +
+		"`access_key_id` varchar(64) NOT NULL UNIQUE, `username` varchar(255) NOT NULL, " +
+		"`secret_access_key` longtext NOT NULL, `description` longtext NULL, `created_at` bigint NOT NULL);" +
+		"CREATE INDEX `{{prefix}}s3_access_keys_username_idx` ON `{{s3_access_keys}}` (`username`);"
+	mysqlV26DownSQL = "DROP TABLE `{{s3_access_keys}}` CASCADE;"
+	mysqlV27SQL     = "CREATE TABLE `{{tus_uploads}}` (`id` integer AUTO_INCREMENT NOT NULL PRIMARY KEY, " +
+		"`upload_id` varchar(64) NOT NULL UNIQUE, `username` varchar(255) NOT NULL, `virtual_path` longtext NOT NULL, " +
+		"`fs_path` longtext NOT NULL, `upload_length` bigint NOT NULL, `upload_offset` bigint NOT NULL, " +
+		"`expires_at` bigint NOT NULL, `created_at` bigint NOT NULL, `updated_at` bigint NOT NULL);" +
+		"CREATE INDEX `{{prefix}}tus_uploads_username_idx` ON `{{tus_uploads}}` (`username`);" +
+		"CREATE INDEX `{{prefix}}tus_uploads_expires_at_idx` ON `{{tus_uploads}}` (`expires_at`);"
+	mysqlV27DownSQL = "DROP TABLE `{{tus_uploads}}` CASCADE;"
+	mysqlV28SQL     = "CREATE TABLE `{{reset_codes}}` (`id` integer AUTO_INCREMENT NOT NULL PRIMARY KEY, " +
+		"`code` varchar(64) NOT NULL UNIQUE, `username` varchar(255) NOT NULL, `account_type` varchar(16) NOT NULL, " +
+		"`password_fingerprint` varchar(64) NOT NULL, `expires_at` bigint NOT NULL, `created_at` bigint NOT NULL);" +
+		"CREATE INDEX `{{prefix}}reset_codes_username_idx` ON `{{reset_codes}}` (`username`);" +
+		"CREATE INDEX `{{prefix}}reset_codes_expires_at_idx` ON `{{reset_codes}}` (`expires_at`);"
+	mysqlV28DownSQL = "DROP TABLE `{{reset_codes}}` CASCADE;"
+	mysqlV29SQL     = "CREATE TABLE `{{multipart_uploads}}` (`id` integer AUTO_INCREMENT NOT NULL PRIMARY KEY, " +
+		"`upload_key` varchar(64) NOT NULL UNIQUE, `upload_size` bigint NOT NULL, `state` longtext NOT NULL, " +
+		"`created_at` bigint NOT NULL, `updated_at` bigint NOT NULL);" +
+		"CREATE INDEX `{{prefix}}multipart_uploads_updated_at_idx` ON `{{multipart_uploads}}` (`updated_at`);"
+	mysqlV29DownSQL = "DROP TABLE `{{multipart_uploads}}` CASCADE;"
 )
 
 // MySQLProvider auth provider for MySQL/MariaDB database
@@ -137,6 +284,14 @@ func (p *MySQLProvider) getUsedQuota(username string) (int, int64, error) {
 	return sqlCommonGetUsedQuota(username, p.dbHandle)
 }
 
+func (p *MySQLProvider) updateTransferQuota(username string, uploadSize, downloadSize int64, reset bool, periodStart int64) error {
+	return sqlCommonUpdateTransferQuota(username, uploadSize, downloadSize, reset, periodStart, p.dbHandle)
+}
+
+func (p *MySQLProvider) getUsedTransferQuota(username string) (int64, int64, int64, error) {
+	return sqlCommonGetUsedTransferQuota(username, p.dbHandle)
+}
+
 func (p *MySQLProvider) setUpdatedAt(username string) {
 	sqlCommonSetUpdatedAt(username, p.dbHandle)
 }
@@ -173,6 +328,10 @@ func (p *MySQLProvider) getRecentlyUpdatedUsers(after int64) ([]User, error) {
 	return sqlCommonGetRecentlyUpdatedUsers(after, p.dbHandle)
 }
 
+func (p *MySQLProvider) getUsersInScope(admin *Admin, limit, offset int, order string) ([]User, error) {
+	return sqlCommonGetUsersInScope(admin, limit, offset, order, p.dbHandle)
+}
+
 func (p *MySQLProvider) getUsers(limit int, offset int, order string) ([]User, error) {
 	return sqlCommonGetUsers(limit, offset, order, p.dbHandle)
 }
@@ -211,6 +370,42 @@ func (p *MySQLProvider) getUsedFolderQuota(name string) (int, int64, error) {
 	return sqlCommonGetFolderUsedQuota(name, p.dbHandle)
 }
 
+func (p *MySQLProvider) updateFolderTransferQuota(name string, uploadSize, downloadSize int64, reset bool, periodStart int64) error {
+	return sqlCommonUpdateFolderTransferQuota(name, uploadSize, downloadSize, reset, periodStart, p.dbHandle)
+}
+
+func (p *MySQLProvider) getUsedFolderTransferQuota(name string) (int64, int64, int64, error) {
+	return sqlCommonGetFolderUsedTransferQuota(name, p.dbHandle)
+}
+
+func (p *MySQLProvider) groupExists(name string) (Group, error) {
+	return sqlCommonGetGroupByName(name, p.dbHandle)
+}
+
+func (p *MySQLProvider) addGroup(group *Group) error {
+	return sqlCommonAddGroup(group, p.dbHandle)
+}
+
+func (p *MySQLProvider) updateGroup(group *Group) error {
+	return sqlCommonUpdateGroup(group, p.dbHandle)
+}
+
+func (p *MySQLProvider) deleteGroup(group *Group) error {
+	return sqlCommonDeleteGroup(group, p.dbHandle)
+}
+
+func (p *MySQLProvider) getGroups(limit, offset int, order string) ([]Group, error) {
+	return sqlCommonGetGroups(limit, offset, order, p.dbHandle)
+}
+
+func (p *MySQLProvider) getGroupsWithNames(names []string) ([]Group, error) {
+	return sqlCommonGetGroupsWithNames(names, p.dbHandle)
+}
+
+func (p *MySQLProvider) dumpGroups() ([]Group, error) {
+	return sqlCommonDumpGroups(p.dbHandle)
+}
+
 func (p *MySQLProvider) adminExists(username string) (Admin, error) {
 	return sqlCommonGetAdminByUsername(username, p.dbHandle)
 }
@@ -267,6 +462,274 @@ func (p *MySQLProvider) updateAPIKeyLastUse(keyID string) error {
 	return sqlCommonUpdateAPIKeyLastUse(keyID, p.dbHandle)
 }
 
+func (p *MySQLProvider) shareExists(shareID, username string) (Share, error) {
+	return sqlCommonGetShareByID(shareID, username, p.dbHandle)
+}
+
+func (p *MySQLProvider) addShare(share *Share) error {
+	return sqlCommonAddShare(share, p.dbHandle)
+}
+
+func (p *MySQLProvider) updateShare(share *Share) error {
+	return sqlCommonUpdateShare(share, p.dbHandle)
+}
+
+func (p *MySQLProvider) deleteShare(share *Share) error {
+	return sqlCommonDeleteShare(share, p.dbHandle)
+}
+
+func (p *MySQLProvider) getShares(limit int, offset int, order, username string) ([]Share, error) {
+	return sqlCommonGetShares(limit, offset, order, username, p.dbHandle)
+}
+
+func (p *MySQLProvider) dumpShares() ([]Share, error) {
+	return sqlCommonDumpShares(p.dbHandle)
+}
+
+func (p *MySQLProvider) updateShareLastUse(shareID string, numTokens int) error {
+	return sqlCommonUpdateShareLastUse(shareID, numTokens, p.dbHandle)
+}
+
+func (p *MySQLProvider) addShareUpload(upload *ShareUpload) error {
+	return sqlCommonAddShareUpload(upload, p.dbHandle)
+}
+
+func (p *MySQLProvider) getShareUploads(shareID string, limit, offset int, order string) ([]ShareUpload, error) {
+	return sqlCommonGetShareUploads(shareID, limit, offset, order, p.dbHandle)
+}
+
+func (p *MySQLProvider) accountLockoutExists(username, accountType string) (AccountLockout, error) {
+	return sqlCommonGetAccountLockout(username, accountType, p.dbHandle)
+}
+
+func (p *MySQLProvider) addAccountLockoutFailure(username, accountType string, now time.Time) (AccountLockout, error) {
+	return sqlCommonAddAccountLockoutFailure(username, accountType, now, p.dbHandle)
+}
+
+func (p *MySQLProvider) deleteAccountLockout(username, accountType string) error {
+	return sqlCommonDeleteAccountLockout(username, accountType, p.dbHandle)
+}
+
+func (p *MySQLProvider) getAccountLockouts(limit, offset int, order string) ([]AccountLockout, error) {
+	return sqlCommonGetAccountLockouts(limit, offset, order, p.dbHandle)
+}
+
+func (p *MySQLProvider) addFsEvent(event *FsEvent) error {
+	return sqlCommonAddFsEvent(event, p.dbHandle)
+}
+
+func (p *MySQLProvider) addProviderEvent(event *ProviderEvent) error {
+	return sqlCommonAddProviderEvent(event, p.dbHandle)
+}
+
+func (p *MySQLProvider) searchFsEvents(search *FsEventSearch) ([]FsEvent, error) {
+	return sqlCommonSearchFsEvents(search, p.dbHandle)
+}
+
+func (p *MySQLProvider) searchProviderEvents(search *ProviderEventSearch) ([]ProviderEvent, error) {
+	return sqlCommonSearchProviderEvents(search, p.dbHandle)
+}
+
+func (p *MySQLProvider) cleanupEvents(before int64) error {
+	return sqlCommonCleanupEvents(before, p.dbHandle)
+}
+
+func (p *MySQLProvider) webDAVLockExists(token string) (WebDAVLock, error) {
+	return sqlCommonGetWebDAVLock(token, p.dbHandle)
+}
+
+func (p *MySQLProvider) addWebDAVLock(lock *WebDAVLock) error {
+	return sqlCommonAddWebDAVLock(lock, p.dbHandle)
+}
+
+func (p *MySQLProvider) updateWebDAVLock(lock *WebDAVLock) error {
+	return sqlCommonUpdateWebDAVLock(lock, p.dbHandle)
+}
+
+func (p *MySQLProvider) deleteWebDAVLock(token string) error {
+	return sqlCommonDeleteWebDAVLock(token, p.dbHandle)
+}
+
+func (p *MySQLProvider) getWebDAVLocks(username string) ([]WebDAVLock, error) {
+	return sqlCommonGetWebDAVLocks(username, p.dbHandle)
+}
+
+func (p *MySQLProvider) deleteUserWebDAVLocks(username string) error {
+	return sqlCommonDeleteUserWebDAVLocks(username, p.dbHandle)
+}
+
+func (p *MySQLProvider) cleanupWebDAVLocks(before int64) error {
+	return sqlCommonCleanupWebDAVLocks(before, p.dbHandle)
+}
+
+func (p *MySQLProvider) webDAVPropertiesExists(username, virtualPath string) (WebDAVProperties, error) {
+	return sqlCommonGetWebDAVProperties(username, virtualPath, p.dbHandle)
+}
+
+func (p *MySQLProvider) setWebDAVProperties(props *WebDAVProperties) error {
+	return sqlCommonSetWebDAVProperties(props, p.dbHandle)
+}
+
+func (p *MySQLProvider) deleteWebDAVProperties(username, virtualPath string) error {
+	return sqlCommonDeleteWebDAVProperties(username, virtualPath, p.dbHandle)
+}
+
+func (p *MySQLProvider) renameWebDAVProperties(username, source, target string) error {
+	return sqlCommonRenameWebDAVProperties(username, source, target, p.dbHandle)
+}
+
+func (p *MySQLProvider) deleteUserWebDAVProperties(username string) error {
+	return sqlCommonDeleteUserWebDAVProperties(username, p.dbHandle)
+}
+
+func (p *MySQLProvider) s3AccessKeyExists(accessKeyID string) (S3AccessKey, error) {
+	return sqlCommonGetS3AccessKey(accessKeyID, p.dbHandle)
+}
+
+func (p *MySQLProvider) addS3AccessKey(key *S3AccessKey) error {
+	return sqlCommonAddS3AccessKey(key, p.dbHandle)
+}
+
+func (p *MySQLProvider) deleteS3AccessKey(accessKeyID, username string) error {
+	return sqlCommonDeleteS3AccessKey(accessKeyID, username, p.dbHandle)
+}
+
+func (p *MySQLProvider) getUserS3AccessKeys(username string) ([]S3AccessKey, error) {
+	return sqlCommonGetUserS3AccessKeys(username, p.dbHandle)
+}
+
+func (p *MySQLProvider) deleteUserS3AccessKeys(username string) error {
+	return sqlCommonDeleteUserS3AccessKeys(username, p.dbHandle)
+}
+
+func (p *MySQLProvider) updateS3AccessKey(key *S3AccessKey) error {
+	return sqlCommonUpdateS3AccessKey(key, p.dbHandle)
+}
+
+func (p *MySQLProvider) dumpS3AccessKeys() ([]S3AccessKey, error) {
+	return sqlCommonDumpS3AccessKeys(p.dbHandle)
+}
+
+func (p *MySQLProvider) tusUploadExists(uploadID string) (TusUpload, error) {
+	return sqlCommonGetTusUpload(uploadID, p.dbHandle)
+}
+
+func (p *MySQLProvider) addTusUpload(upload *TusUpload) error {
+	return sqlCommonAddTusUpload(upload, p.dbHandle)
+}
+
+func (p *MySQLProvider) updateTusUploadOffset(uploadID string, offset, expiresAt, updatedAt int64) error {
+	return sqlCommonUpdateTusUploadOffset(uploadID, offset, expiresAt, updatedAt, p.dbHandle)
+}
+
+func (p *MySQLProvider) deleteTusUpload(uploadID string) error {
+	return sqlCommonDeleteTusUpload(uploadID, p.dbHandle)
+}
+
+func (p *MySQLProvider) getTusUploadsCount(username string) (int, error) {
+	return sqlCommonGetTusUploadsCount(username, p.dbHandle)
+}
+
+func (p *MySQLProvider) getExpiredTusUploads(before int64) ([]TusUpload, error) {
+	return sqlCommonGetExpiredTusUploads(before, p.dbHandle)
+}
+
+func (p *MySQLProvider) deleteUserTusUploads(username string) error {
+	return sqlCommonDeleteUserTusUploads(username, p.dbHandle)
+}
+
+func (p *MySQLProvider) resetCodeExists(code string) (ResetCode, error) {
+	return sqlCommonGetResetCode(code, p.dbHandle)
+}
+
+func (p *MySQLProvider) addResetCode(code *ResetCode) error {
+	return sqlCommonAddResetCode(code, p.dbHandle)
+}
+
+func (p *MySQLProvider) deleteResetCode(code string) error {
+	return sqlCommonDeleteResetCode(code, p.dbHandle)
+}
+
+func (p *MySQLProvider) getResetCodesCount(username, accountType string, after int64) (int, error) {
+	return sqlCommonGetResetCodesCount(username, accountType, after, p.dbHandle)
+}
+
+func (p *MySQLProvider) deleteExpiredResetCodes(before int64) error {
+	return sqlCommonDeleteExpiredResetCodes(before, p.dbHandle)
+}
+
+func (p *MySQLProvider) multipartUploadExists(key string) (vfs.MultipartUpload, error) {
+	return sqlCommonGetMultipartUpload(key, p.dbHandle)
+}
+
+func (p *MySQLProvider) saveMultipartUpload(upload *vfs.MultipartUpload) error {
+	return sqlCommonSaveMultipartUpload(upload, p.dbHandle)
+}
+
+func (p *MySQLProvider) deleteMultipartUpload(key string) error {
+	return sqlCommonDeleteMultipartUpload(key, p.dbHandle)
+}
+
+func (p *MySQLProvider) cleanupMultipartUploads(before int64) error {
+	return sqlCommonCleanupMultipartUploads(before, p.dbHandle)
+}
+
+func (p *MySQLProvider) eventRuleExists(name string) (EventRule, error) {
+	return sqlCommonGetEventRuleByName(name, p.dbHandle)
+}
+
+func (p *MySQLProvider) addEventRule(rule *EventRule) error {
+	return sqlCommonAddEventRule(rule, p.dbHandle)
+}
+
+func (p *MySQLProvider) updateEventRule(rule *EventRule) error {
+	return sqlCommonUpdateEventRule(rule, p.dbHandle)
+}
+
+func (p *MySQLProvider) deleteEventRule(rule *EventRule) error {
+	return sqlCommonDeleteEventRule(rule, p.dbHandle)
+}
+
+func (p *MySQLProvider) getEventRules(limit, offset int, order string) ([]EventRule, error) {
+	return sqlCommonGetEventRules(limit, offset, order, p.dbHandle)
+}
+
+func (p *MySQLProvider) dumpEventRules() ([]EventRule, error) {
+	return sqlCommonDumpEventRules(p.dbHandle)
+}
+
+func (p *MySQLProvider) scheduleExists(name string) (Schedule, error) {
+	return sqlCommonGetScheduleByName(name, p.dbHandle)
+}
+
+func (p *MySQLProvider) addSchedule(schedule *Schedule) error {
+	return sqlCommonAddSchedule(schedule, p.dbHandle)
+}
+
+func (p *MySQLProvider) updateSchedule(schedule *Schedule) error {
+	return sqlCommonUpdateSchedule(schedule, p.dbHandle)
+}
+
+func (p *MySQLProvider) deleteSchedule(schedule *Schedule) error {
+	return sqlCommonDeleteSchedule(schedule, p.dbHandle)
+}
+
+func (p *MySQLProvider) getSchedules(limit, offset int, order string) ([]Schedule, error) {
+	return sqlCommonGetSchedules(limit, offset, order, p.dbHandle)
+}
+
+func (p *MySQLProvider) dumpSchedules() ([]Schedule, error) {
+	return sqlCommonDumpSchedules(p.dbHandle)
+}
+
+func (p *MySQLProvider) claimScheduleRun(name string, runAt int64) (bool, error) {
+	return sqlCommonClaimScheduleRun(name, runAt, p.dbHandle)
+}
+
+func (p *MySQLProvider) setScheduleRunResult(name string, status int, runError string) error {
+	return sqlCommonSetScheduleRunResult(name, status, runError, p.dbHandle)
+}
+
 func (p *MySQLProvider) close() error {
 	return p.dbHandle.Close()
 }
@@ -312,6 +775,38 @@ func (p *MySQLProvider) migrateDatabase() error {
 		return updateMySQLDatabaseFromV11(p.dbHandle)
 	case version == 12:
 		return updateMySQLDatabaseFromV12(p.dbHandle)
+	case version == 13:
+		return updateMySQLDatabaseFromV13(p.dbHandle)
+	case version == 14:
+		return updateMySQLDatabaseFromV14(p.dbHandle)
+	case version == 15:
+		return updateMySQLDatabaseFromV15(p.dbHandle)
+	case version == 16:
+		return updateMySQLDatabaseFromV16(p.dbHandle)
+	case version == 17:
+		return updateMySQLDatabaseFromV17(p.dbHandle)
+	case version == 18:
+		return updateMySQLDatabaseFromV18(p.dbHandle)
+	case version == 19:
+		return updateMySQLDatabaseFromV19(p.dbHandle)
+	case version == 20:
+		return updateMySQLDatabaseFromV20(p.dbHandle)
+	case version == 21:
+		return updateMySQLDatabaseFromV21(p.dbHandle)
+	case version == 22:
+		return updateMySQLDatabaseFromV22(p.dbHandle)
+	case version == 23:
+		return updateMySQLDatabaseFromV23(p.dbHandle)
+	case version == 24:
+		return updateMySQLDatabaseFromV24(p.dbHandle)
+	case version == 25:
+		return updateMySQLDatabaseFromV25(p.dbHandle)
+	case version == 26:
+		return updateMySQLDatabaseFromV26(p.dbHandle)
+	case version == 27:
+		return updateMySQLDatabaseFromV27(p.dbHandle)
+	case version == 28:
+		return updateMySQLDatabaseFromV28(p.dbHandle)
 	default:
 		if version > sqlDatabaseVersion {
 			providerLog(logger.LevelWarn, "database version %v is newer than the supported one: %v", version,
@@ -334,6 +829,38 @@ func (p *MySQLProvider) revertDatabase(targetVersion int) error {
 	}
 
 	switch dbVersion.Version {
+	case 29:
+		return downgradeMySQLDatabaseFromV29(p.dbHandle)
+	case 28:
+		return downgradeMySQLDatabaseFromV28(p.dbHandle)
+	case 27:
+		return downgradeMySQLDatabaseFromV27(p.dbHandle)
+	case 26:
+		return downgradeMySQLDatabaseFromV26(p.dbHandle)
+	case 25:
+		return downgradeMySQLDatabaseFromV25(p.dbHandle)
+	case 24:
+		return downgradeMySQLDatabaseFromV24(p.dbHandle)
+	case 23:
+		return downgradeMySQLDatabaseFromV23(p.dbHandle)
+	case 22:
+		return downgradeMySQLDatabaseFromV22(p.dbHandle)
+	case 21:
+		return downgradeMySQLDatabaseFromV21(p.dbHandle)
+	case 20:
+		return downgradeMySQLDatabaseFromV20(p.dbHandle)
+	case 19:
+		return downgradeMySQLDatabaseFromV19(p.dbHandle)
+	case 18:
+		return downgradeMySQLDatabaseFromV18(p.dbHandle)
+	case 17:
+		return downgradeMySQLDatabaseFromV17(p.dbHandle)
+	case 16:
+		return downgradeMySQLDatabaseFromV16(p.dbHandle)
+	case 15:
+		return downgradeMySQLDatabaseFromV15(p.dbHandle)
+	case 14:
+		return downgradeMySQLDatabaseFromV14(p.dbHandle)
 	case 13:
 		return downgradeMySQLDatabaseFromV13(p.dbHandle)
 	case 12:
@@ -360,7 +887,231 @@ func updateMySQLDatabaseFromV11(dbHandle *sql.DB) error {
 }
 
 func updateMySQLDatabaseFromV12(dbHandle *sql.DB) error {
-	return updateMySQLDatabaseFrom12To13(dbHandle)
+	if err := updateMySQLDatabaseFrom12To13(dbHandle); err != nil {
+		return err
+	}
+	return updateMySQLDatabaseFromV13(dbHandle)
+}
+
+func updateMySQLDatabaseFromV13(dbHandle *sql.DB) error {
+	if err := updateMySQLDatabaseFrom13To14(dbHandle); err != nil {
+		return err
+	}
+	return updateMySQLDatabaseFromV14(dbHandle)
+}
+
+func updateMySQLDatabaseFromV14(dbHandle *sql.DB) error {
+	if err := updateMySQLDatabaseFrom14To15(dbHandle); err != nil {
+		return err
+	}
+	return updateMySQLDatabaseFromV15(dbHandle)
+}
+
+func updateMySQLDatabaseFromV15(dbHandle *sql.DB) error {
+	if err := updateMySQLDatabaseFrom15To16(dbHandle); err != nil {
+		return err
+	}
+	return updateMySQLDatabaseFromV16(dbHandle)
+}
+
+func updateMySQLDatabaseFromV16(dbHandle *sql.DB) error {
+	if err := updateMySQLDatabaseFrom16To17(dbHandle); err != nil {
+		return err
+	}
+	return updateMySQLDatabaseFromV17(dbHandle)
+}
+
+func updateMySQLDatabaseFromV17(dbHandle *sql.DB) error {
+	if err := updateMySQLDatabaseFrom17To18(dbHandle); err != nil {
+		return err
+	}
+	return updateMySQLDatabaseFromV18(dbHandle)
+}
+
+func updateMySQLDatabaseFromV18(dbHandle *sql.DB) error {
+	if err := updateMySQLDatabaseFrom18To19(dbHandle); err != nil {
+		return err
+	}
+	return updateMySQLDatabaseFromV19(dbHandle)
+}
+
+func updateMySQLDatabaseFromV19(dbHandle *sql.DB) error {
+	if err := updateMySQLDatabaseFrom19To20(dbHandle); err != nil {
+		return err
+	}
+	return updateMySQLDatabaseFromV20(dbHandle)
+}
+
+func updateMySQLDatabaseFromV20(dbHandle *sql.DB) error {
+	if err := updateMySQLDatabaseFrom20To21(dbHandle); err != nil {
+		return err
+	}
+	return updateMySQLDatabaseFromV21(dbHandle)
+}
+
+func updateMySQLDatabaseFromV21(dbHandle *sql.DB) error {
+	if err := updateMySQLDatabaseFrom21To22(dbHandle); err != nil {
+		return err
+	}
+	return updateMySQLDatabaseFromV22(dbHandle)
+}
+
+func updateMySQLDatabaseFromV22(dbHandle *sql.DB) error {
+	if err := updateMySQLDatabaseFrom22To23(dbHandle); err != nil {
+		return err
+	}
+	return updateMySQLDatabaseFromV23(dbHandle)
+}
+
+func updateMySQLDatabaseFromV23(dbHandle *sql.DB) error {
+	if err := updateMySQLDatabaseFrom23To24(dbHandle); err != nil {
+		return err
+	}
+	return updateMySQLDatabaseFromV24(dbHandle)
+}
+
+func updateMySQLDatabaseFromV24(dbHandle *sql.DB) error {
+	if err := updateMySQLDatabaseFrom24To25(dbHandle); err != nil {
+		return err
+	}
+	return updateMySQLDatabaseFromV25(dbHandle)
+}
+
+func updateMySQLDatabaseFromV25(dbHandle *sql.DB) error {
+	if err := updateMySQLDatabaseFrom25To26(dbHandle); err != nil {
+		return err
+	}
+	return updateMySQLDatabaseFromV26(dbHandle)
+}
+
+func updateMySQLDatabaseFromV26(dbHandle *sql.DB) error {
+	if err := updateMySQLDatabaseFrom26To27(dbHandle); err != nil {
+		return err
+	}
+	return updateMySQLDatabaseFromV27(dbHandle)
+}
+
+func updateMySQLDatabaseFromV27(dbHandle *sql.DB) error {
+	if err := updateMySQLDatabaseFrom27To28(dbHandle); err != nil {
+		return err
+	}
+	return updateMySQLDatabaseFromV28(dbHandle)
+}
+
+func updateMySQLDatabaseFromV28(dbHandle *sql.DB) error {
+	return updateMySQLDatabaseFrom28To29(dbHandle)
+}
+
+func downgradeMySQLDatabaseFromV29(dbHandle *sql.DB) error {
+	if err := downgradeMySQLDatabaseFrom29To28(dbHandle); err != nil {
+		return err
+	}
+	return downgradeMySQLDatabaseFromV28(dbHandle)
+}
+
+func downgradeMySQLDatabaseFromV28(dbHandle *sql.DB) error {
+	if err := downgradeMySQLDatabaseFrom28To27(dbHandle); err != nil {
+		return err
+	}
+	return downgradeMySQLDatabaseFromV27(dbHandle)
+}
+
+func downgradeMySQLDatabaseFromV27(dbHandle *sql.DB) error {
+	if err := downgradeMySQLDatabaseFrom27To26(dbHandle); err != nil {
+		return err
+	}
+	return downgradeMySQLDatabaseFromV26(dbHandle)
+}
+
+func downgradeMySQLDatabaseFromV26(dbHandle *sql.DB) error {
+	if err := downgradeMySQLDatabaseFrom26To25(dbHandle); err != nil {
+		return err
+	}
+	return downgradeMySQLDatabaseFromV25(dbHandle)
+}
+
+func downgradeMySQLDatabaseFromV25(dbHandle *sql.DB) error {
+	if err := downgradeMySQLDatabaseFrom25To24(dbHandle); err != nil {
+		return err
+	}
+	return downgradeMySQLDatabaseFromV24(dbHandle)
+}
+
+func downgradeMySQLDatabaseFromV24(dbHandle *sql.DB) error {
+	if err := downgradeMySQLDatabaseFrom24To23(dbHandle); err != nil {
+		return err
+	}
+	return downgradeMySQLDatabaseFromV23(dbHandle)
+}
+
+func downgradeMySQLDatabaseFromV23(dbHandle *sql.DB) error {
+	if err := downgradeMySQLDatabaseFrom23To22(dbHandle); err != nil {
+		return err
+	}
+	return downgradeMySQLDatabaseFromV22(dbHandle)
+}
+
+func downgradeMySQLDatabaseFromV22(dbHandle *sql.DB) error {
+	if err := downgradeMySQLDatabaseFrom22To21(dbHandle); err != nil {
+		return err
+	}
+	return downgradeMySQLDatabaseFromV21(dbHandle)
+}
+
+func downgradeMySQLDatabaseFromV21(dbHandle *sql.DB) error {
+	if err := downgradeMySQLDatabaseFrom21To20(dbHandle); err != nil {
+		return err
+	}
+	return downgradeMySQLDatabaseFromV20(dbHandle)
+}
+
+func downgradeMySQLDatabaseFromV20(dbHandle *sql.DB) error {
+	if err := downgradeMySQLDatabaseFrom20To19(dbHandle); err != nil {
+		return err
+	}
+	return downgradeMySQLDatabaseFromV19(dbHandle)
+}
+
+func downgradeMySQLDatabaseFromV19(dbHandle *sql.DB) error {
+	if err := downgradeMySQLDatabaseFrom19To18(dbHandle); err != nil {
+		return err
+	}
+	return downgradeMySQLDatabaseFromV18(dbHandle)
+}
+
+func downgradeMySQLDatabaseFromV18(dbHandle *sql.DB) error {
+	if err := downgradeMySQLDatabaseFrom18To17(dbHandle); err != nil {
+		return err
+	}
+	return downgradeMySQLDatabaseFromV17(dbHandle)
+}
+
+func downgradeMySQLDatabaseFromV17(dbHandle *sql.DB) error {
+	if err := downgradeMySQLDatabaseFrom17To16(dbHandle); err != nil {
+		return err
+	}
+	return downgradeMySQLDatabaseFromV16(dbHandle)
+}
+
+func downgradeMySQLDatabaseFromV16(dbHandle *sql.DB) error {
+	if err := downgradeMySQLDatabaseFrom16To15(dbHandle); err != nil {
+		return err
+	}
+	return downgradeMySQLDatabaseFromV15(dbHandle)
+}
+
+func downgradeMySQLDatabaseFromV15(dbHandle *sql.DB) error {
+	if err := downgradeMySQLDatabaseFrom15To14(dbHandle); err != nil {
+		return err
+	}
+	return downgradeMySQLDatabaseFromV14(dbHandle)
+}
+
+func downgradeMySQLDatabaseFromV14(dbHandle *sql.DB) error {
+	if err := downgradeMySQLDatabaseFrom14To13(dbHandle); err != nil {
+		return err
+	}
+	return downgradeMySQLDatabaseFromV13(dbHandle)
 }
 
 func downgradeMySQLDatabaseFromV13(dbHandle *sql.DB) error {
@@ -381,6 +1132,255 @@ func downgradeMySQLDatabaseFromV11(dbHandle *sql.DB) error {
 	return downgradeMySQLDatabaseFrom11To10(dbHandle)
 }
 
+func updateMySQLDatabaseFrom28To29(dbHandle *sql.DB) error {
+	logger.InfoToConsole("updating database version: 28 -> 29")
+	providerLog(logger.LevelInfo, "updating database version: 28 -> 29")
+	sql := strings.ReplaceAll(mysqlV29SQL, "{{multipart_uploads}}", sqlTableMultipartUploads)
+	sql = strings.ReplaceAll(sql, "{{prefix}}", config.SQLTablesPrefix)
+	return sqlCommonExecSQLAndUpdateDBVersion(dbHandle, strings.Split(sql, ";"), 29)
+}
+
+func downgradeMySQLDatabaseFrom29To28(dbHandle *sql.DB) error {
+	logger.InfoToConsole("downgrading database version: 29 -> 28")
+	providerLog(logger.LevelInfo, "downgrading database version: 29 -> 28")
+	sql := strings.ReplaceAll(mysqlV29DownSQL, "{{multipart_uploads}}", sqlTableMultipartUploads)
+	return sqlCommonExecSQLAndUpdateDBVersion(dbHandle, strings.Split(sql, ";"), 28)
+}
+
+func updateMySQLDatabaseFrom27To28(dbHandle *sql.DB) error {
+	logger.InfoToConsole("updating database version: 27 -> 28")
+	providerLog(logger.LevelInfo, "updating database version: 27 -> 28")
+	sql := strings.ReplaceAll(mysqlV28SQL, "{{reset_codes}}", sqlTableResetCodes)
+	sql = strings.ReplaceAll(sql, "{{prefix}}", config.SQLTablesPrefix)
+	return sqlCommonExecSQLAndUpdateDBVersion(dbHandle, strings.Split(sql, ";"), 28)
+}
+
+func downgradeMySQLDatabaseFrom28To27(dbHandle *sql.DB) error {
+	logger.InfoToConsole("downgrading database version: 28 -> 27")
+	providerLog(logger.LevelInfo, "downgrading database version: 28 -> 27")
+	sql := strings.ReplaceAll(mysqlV28DownSQL, "{{reset_codes}}", sqlTableResetCodes)
+	return sqlCommonExecSQLAndUpdateDBVersion(dbHandle, strings.Split(sql, ";"), 27)
+}
+
+func updateMySQLDatabaseFrom26To27(dbHandle *sql.DB) error {
+	logger.InfoToConsole("updating database version: 26 -> 27")
+	providerLog(logger.LevelInfo, "updating database version: 26 -> 27")
+	sql := strings.ReplaceAll(mysqlV27SQL, "{{tus_uploads}}", sqlTableTusUploads)
+	sql = strings.ReplaceAll(sql, "{{prefix}}", config.SQLTablesPrefix)
+	return sqlCommonExecSQLAndUpdateDBVersion(dbHandle, strings.Split(sql, ";"), 27)
+}
+
+func downgradeMySQLDatabaseFrom27To26(dbHandle *sql.DB) error {
+	logger.InfoToConsole("downgrading database version: 27 -> 26")
+	providerLog(logger.LevelInfo, "downgrading database version: 27 -> 26")
+	sql := strings.ReplaceAll(mysqlV27DownSQL, "{{tus_uploads}}", sqlTableTusUploads)
+	return sqlCommonExecSQLAndUpdateDBVersion(dbHandle, strings.Split(sql, ";"), 26)
+}
+
+func updateMySQLDatabaseFrom25To26(dbHandle *sql.DB) error {
+	logger.InfoToConsole("updating database version: 25 -> 26")
+	providerLog(logger.LevelInfo, "updating database version: 25 -> 26")
+	sql := strings.ReplaceAll(mysqlV26SQL, "{{s3_access_keys}}", sqlTableS3AccessKeys)
+	sql = strings.ReplaceAll(sql, "{{prefix}}", config.SQLTablesPrefix)
+	return sqlCommonExecSQLAndUpdateDBVersion(dbHandle, strings.Split(sql, ";"), 26)
+}
+
+func downgradeMySQLDatabaseFrom26To25(dbHandle *sql.DB) error {
+	logger.InfoToConsole("downgrading database version: 26 -> 25")
+	providerLog(logger.LevelInfo, "downgrading database version: 26 -> 25")
+	sql := strings.ReplaceAll(mysqlV26DownSQL, "{{s3_access_keys}}", sqlTableS3AccessKeys)
+	return sqlCommonExecSQLAndUpdateDBVersion(dbHandle, strings.Split(sql, ";"), 25)
+}
+
+func updateMySQLDatabaseFrom24To25(dbHandle *sql.DB) error {
+	logger.InfoToConsole("updating database version: 24 -> 25")
+	providerLog(logger.LevelInfo, "updating database version: 24 -> 25")
+	sql := strings.ReplaceAll(mysqlV25SQL, "{{webdav_properties}}", sqlTableWebDAVProperties)
+	sql = strings.ReplaceAll(sql, "{{prefix}}", config.SQLTablesPrefix)
+	return sqlCommonExecSQLAndUpdateDBVersion(dbHandle, strings.Split(sql, ";"), 25)
+}
+
+func downgradeMySQLDatabaseFrom25To24(dbHandle *sql.DB) error {
+	logger.InfoToConsole("downgrading database version: 25 -> 24")
+	providerLog(logger.LevelInfo, "downgrading database version: 25 -> 24")
+	sql := strings.ReplaceAll(mysqlV25DownSQL, "{{webdav_properties}}", sqlTableWebDAVProperties)
+	return sqlCommonExecSQLAndUpdateDBVersion(dbHandle, strings.Split(sql, ";"), 24)
+}
+
+func updateMySQLDatabaseFrom23To24(dbHandle *sql.DB) error {
+	logger.InfoToConsole("updating database version: 23 -> 24")
+	providerLog(logger.LevelInfo, "updating database version: 23 -> 24")
+	sql := strings.ReplaceAll(mysqlV24SQL, "{{webdav_locks}}", sqlTableWebDAVLocks)
+	sql = strings.ReplaceAll(sql, "{{prefix}}", config.SQLTablesPrefix)
+	return sqlCommonExecSQLAndUpdateDBVersion(dbHandle, strings.Split(sql, ";"), 24)
+}
+
+func downgradeMySQLDatabaseFrom24To23(dbHandle *sql.DB) error {
+	logger.InfoToConsole("downgrading database version: 24 -> 23")
+	providerLog(logger.LevelInfo, "downgrading database version: 24 -> 23")
+	sql := strings.ReplaceAll(mysqlV24DownSQL, "{{webdav_locks}}", sqlTableWebDAVLocks)
+	return sqlCommonExecSQLAndUpdateDBVersion(dbHandle, strings.Split(sql, ";"), 23)
+}
+
+func updateMySQLDatabaseFrom22To23(dbHandle *sql.DB) error {
+	logger.InfoToConsole("updating database version: 22 -> 23")
+	providerLog(logger.LevelInfo, "updating database version: 22 -> 23")
+	sql := strings.ReplaceAll(mysqlV23SQL, "{{folders}}", sqlTableFolders)
+	return sqlCommonExecSQLAndUpdateDBVersion(dbHandle, strings.Split(sql, ";"), 23)
+}
+
+func downgradeMySQLDatabaseFrom23To22(dbHandle *sql.DB) error {
+	logger.InfoToConsole("downgrading database version: 23 -> 22")
+	providerLog(logger.LevelInfo, "downgrading database version: 23 -> 22")
+	sql := strings.ReplaceAll(mysqlV23DownSQL, "{{folders}}", sqlTableFolders)
+	return sqlCommonExecSQLAndUpdateDBVersion(dbHandle, strings.Split(sql, ";"), 22)
+}
+
+func updateMySQLDatabaseFrom21To22(dbHandle *sql.DB) error {
+	logger.InfoToConsole("updating database version: 21 -> 22")
+	providerLog(logger.LevelInfo, "updating database version: 21 -> 22")
+	sql := strings.ReplaceAll(mysqlV22SQL, "{{schedules}}", sqlTableSchedules)
+	return sqlCommonExecSQLAndUpdateDBVersion(dbHandle, strings.Split(sql, ";"), 22)
+}
+
+func downgradeMySQLDatabaseFrom22To21(dbHandle *sql.DB) error {
+	logger.InfoToConsole("downgrading database version: 22 -> 21")
+	providerLog(logger.LevelInfo, "downgrading database version: 22 -> 21")
+	sql := strings.ReplaceAll(mysqlV22DownSQL, "{{schedules}}", sqlTableSchedules)
+	return sqlCommonExecSQLAndUpdateDBVersion(dbHandle, strings.Split(sql, ";"), 21)
+}
+
+func updateMySQLDatabaseFrom20To21(dbHandle *sql.DB) error {
+	logger.InfoToConsole("updating database version: 20 -> 21")
+	providerLog(logger.LevelInfo, "updating database version: 20 -> 21")
+	sql := strings.ReplaceAll(mysqlV21SQL, "{{events_rules}}", sqlTableEventsRules)
+	return sqlCommonExecSQLAndUpdateDBVersion(dbHandle, strings.Split(sql, ";"), 21)
+}
+
+func downgradeMySQLDatabaseFrom21To20(dbHandle *sql.DB) error {
+	logger.InfoToConsole("downgrading database version: 21 -> 20")
+	providerLog(logger.LevelInfo, "downgrading database version: 21 -> 20")
+	sql := strings.ReplaceAll(mysqlV21DownSQL, "{{events_rules}}", sqlTableEventsRules)
+	return sqlCommonExecSQLAndUpdateDBVersion(dbHandle, strings.Split(sql, ";"), 20)
+}
+
+func updateMySQLDatabaseFrom19To20(dbHandle *sql.DB) error {
+	logger.InfoToConsole("updating database version: 19 -> 20")
+	providerLog(logger.LevelInfo, "updating database version: 19 -> 20")
+	sql := strings.ReplaceAll(mysqlV20SQL, "{{fs_events}}", sqlTableFsEvents)
+	sql = strings.ReplaceAll(sql, "{{provider_events}}", sqlTableProviderEvents)
+	sql = strings.ReplaceAll(sql, "{{prefix}}", config.SQLTablesPrefix)
+	return sqlCommonExecSQLAndUpdateDBVersion(dbHandle, strings.Split(sql, ";"), 20)
+}
+
+func downgradeMySQLDatabaseFrom20To19(dbHandle *sql.DB) error {
+	logger.InfoToConsole("downgrading database version: 20 -> 19")
+	providerLog(logger.LevelInfo, "downgrading database version: 20 -> 19")
+	sql := strings.ReplaceAll(mysqlV20DownSQL, "{{fs_events}}", sqlTableFsEvents)
+	sql = strings.ReplaceAll(sql, "{{provider_events}}", sqlTableProviderEvents)
+	return sqlCommonExecSQLAndUpdateDBVersion(dbHandle, strings.Split(sql, ";"), 19)
+}
+
+func updateMySQLDatabaseFrom18To19(dbHandle *sql.DB) error {
+	logger.InfoToConsole("updating database version: 18 -> 19")
+	providerLog(logger.LevelInfo, "updating database version: 18 -> 19")
+	sql := strings.ReplaceAll(mysqlV19SQL, "{{account_lockouts}}", sqlTableAccountLockouts)
+	sql = strings.ReplaceAll(sql, "{{prefix}}", config.SQLTablesPrefix)
+	return sqlCommonExecSQLAndUpdateDBVersion(dbHandle, strings.Split(sql, ";"), 19)
+}
+
+func downgradeMySQLDatabaseFrom19To18(dbHandle *sql.DB) error {
+	logger.InfoToConsole("downgrading database version: 19 -> 18")
+	providerLog(logger.LevelInfo, "downgrading database version: 19 -> 18")
+	sql := strings.ReplaceAll(mysqlV19DownSQL, "{{account_lockouts}}", sqlTableAccountLockouts)
+	return sqlCommonExecSQLAndUpdateDBVersion(dbHandle, strings.Split(sql, ";"), 18)
+}
+
+func updateMySQLDatabaseFrom17To18(dbHandle *sql.DB) error {
+	logger.InfoToConsole("updating database version: 17 -> 18")
+	providerLog(logger.LevelInfo, "updating database version: 17 -> 18")
+	sql := strings.ReplaceAll(mysqlV18SQL, "{{users}}", sqlTableUsers)
+	sql = strings.ReplaceAll(sql, "{{admins}}", sqlTableAdmins)
+	return sqlCommonExecSQLAndUpdateDBVersion(dbHandle, strings.Split(sql, ";"), 18)
+}
+
+func downgradeMySQLDatabaseFrom18To17(dbHandle *sql.DB) error {
+	logger.InfoToConsole("downgrading database version: 18 -> 17")
+	providerLog(logger.LevelInfo, "downgrading database version: 18 -> 17")
+	sql := strings.ReplaceAll(mysqlV18DownSQL, "{{users}}", sqlTableUsers)
+	sql = strings.ReplaceAll(sql, "{{admins}}", sqlTableAdmins)
+	return sqlCommonExecSQLAndUpdateDBVersion(dbHandle, strings.Split(sql, ";"), 17)
+}
+
+func updateMySQLDatabaseFrom16To17(dbHandle *sql.DB) error {
+	logger.InfoToConsole("updating database version: 16 -> 17")
+	providerLog(logger.LevelInfo, "updating database version: 16 -> 17")
+	sql := strings.ReplaceAll(mysqlV17SQL, "{{users}}", sqlTableUsers)
+	sql = strings.ReplaceAll(sql, "{{folders}}", sqlTableFolders)
+	return sqlCommonExecSQLAndUpdateDBVersion(dbHandle, strings.Split(sql, ";"), 17)
+}
+
+func downgradeMySQLDatabaseFrom17To16(dbHandle *sql.DB) error {
+	logger.InfoToConsole("downgrading database version: 17 -> 16")
+	providerLog(logger.LevelInfo, "downgrading database version: 17 -> 16")
+	sql := strings.ReplaceAll(mysqlV17DownSQL, "{{users}}", sqlTableUsers)
+	sql = strings.ReplaceAll(sql, "{{folders}}", sqlTableFolders)
+	return sqlCommonExecSQLAndUpdateDBVersion(dbHandle, strings.Split(sql, ";"), 16)
+}
+
+func updateMySQLDatabaseFrom15To16(dbHandle *sql.DB) error {
+	logger.InfoToConsole("updating database version: 15 -> 16")
+	providerLog(logger.LevelInfo, "updating database version: 15 -> 16")
+	sql := strings.ReplaceAll(mysqlV16SQL, "{{groups_folders_mapping}}", sqlTableGroupsFoldersMapping)
+	sql = strings.ReplaceAll(sql, "{{groups_mapping}}", sqlTableGroupsMapping)
+	sql = strings.ReplaceAll(sql, "{{groups}}", sqlTableGroups)
+	sql = strings.ReplaceAll(sql, "{{folders}}", sqlTableFolders)
+	sql = strings.ReplaceAll(sql, "{{users}}", sqlTableUsers)
+	sql = strings.ReplaceAll(sql, "{{prefix}}", config.SQLTablesPrefix)
+	return sqlCommonExecSQLAndUpdateDBVersion(dbHandle, strings.Split(sql, ";"), 16)
+}
+
+func downgradeMySQLDatabaseFrom16To15(dbHandle *sql.DB) error {
+	logger.InfoToConsole("downgrading database version: 16 -> 15")
+	providerLog(logger.LevelInfo, "downgrading database version: 16 -> 15")
+	sql := strings.ReplaceAll(mysqlV16DownSQL, "{{groups_folders_mapping}}", sqlTableGroupsFoldersMapping)
+	sql = strings.ReplaceAll(sql, "{{groups_mapping}}", sqlTableGroupsMapping)
+	sql = strings.ReplaceAll(sql, "{{groups}}", sqlTableGroups)
+	return sqlCommonExecSQLAndUpdateDBVersion(dbHandle, strings.Split(sql, ";"), 15)
+}
+
+func updateMySQLDatabaseFrom14To15(dbHandle *sql.DB) error {
+	logger.InfoToConsole("updating database version: 14 -> 15")
+	providerLog(logger.LevelInfo, "updating database version: 14 -> 15")
+	sql := strings.ReplaceAll(mysqlV15SQL, "{{shares}}", sqlTableShares)
+	sql = strings.ReplaceAll(sql, "{{share_uploads}}", sqlTableShareUploads)
+	sql = strings.ReplaceAll(sql, "{{prefix}}", config.SQLTablesPrefix)
+	return sqlCommonExecSQLAndUpdateDBVersion(dbHandle, strings.Split(sql, ";"), 15)
+}
+
+func downgradeMySQLDatabaseFrom15To14(dbHandle *sql.DB) error {
+	logger.InfoToConsole("downgrading database version: 15 -> 14")
+	providerLog(logger.LevelInfo, "downgrading database version: 15 -> 14")
+	sql := strings.ReplaceAll(mysqlV15DownSQL, "{{share_uploads}}", sqlTableShareUploads)
+	return sqlCommonExecSQLAndUpdateDBVersion(dbHandle, strings.Split(sql, ";"), 14)
+}
+
+func updateMySQLDatabaseFrom13To14(dbHandle *sql.DB) error {
+	logger.InfoToConsole("updating database version: 13 -> 14")
+	providerLog(logger.LevelInfo, "updating database version: 13 -> 14")
+	sql := strings.ReplaceAll(mysqlV14SQL, "{{users}}", sqlTableUsers)
+	sql = strings.ReplaceAll(sql, "{{shares}}", sqlTableShares)
+	sql = strings.ReplaceAll(sql, "{{prefix}}", config.SQLTablesPrefix)
+	return sqlCommonExecSQLAndUpdateDBVersion(dbHandle, strings.Split(sql, ";"), 14)
+}
+
+func downgradeMySQLDatabaseFrom14To13(dbHandle *sql.DB) error {
+	logger.InfoToConsole("downgrading database version: 14 -> 13")
+	providerLog(logger.LevelInfo, "downgrading database version: 14 -> 13")
+	sql := strings.ReplaceAll(mysqlV14DownSQL, "{{shares}}", sqlTableShares)
+	return sqlCommonExecSQLAndUpdateDBVersion(dbHandle, strings.Split(sql, ";"), 13)
+}
+
 func updateMySQLDatabaseFrom12To13(dbHandle *sql.DB) error {
 	logger.InfoToConsole("updating database version: 12 -> 13")
 	providerLog(logger.LevelInfo, "updating database version: 12 -> 13")
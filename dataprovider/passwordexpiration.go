@@ -0,0 +1,134 @@
+package dataprovider
+
+import (
+	"bytes"
+	"fmt"
+	"time"
+
+	"github.com/drakkan/sftpgo/v2/logger"
+	"github.com/drakkan/sftpgo/v2/smtp"
+)
+
+const (
+	pwdExpirationCheckInterval = 1 * time.Hour
+	pwdExpirationPageSize      = 100
+)
+
+var (
+	pwdExpirationTicker     *time.Ticker
+	pwdExpirationTickerDone chan bool
+)
+
+type pwdExpirationNotification struct {
+	Username       string
+	ExpirationDate string
+	Days           int
+}
+
+// startPasswordExpirationTimer periodically checks for passwords about to expire
+// and notifies users and admins with an email address.
+// The check runs every hour and each account is notified at most once a day,
+// in the hour that matches the time of the last password change
+func startPasswordExpirationTimer() {
+	if config.PasswordExpirationNotification <= 0 {
+		return
+	}
+	providerLog(logger.LevelDebug, "password expiration check started, notification days: %v",
+		config.PasswordExpirationNotification)
+	pwdExpirationTicker = time.NewTicker(pwdExpirationCheckInterval)
+	pwdExpirationTickerDone = make(chan bool)
+
+	go func() {
+		for {
+			select {
+			case <-pwdExpirationTickerDone:
+				return
+			case t := <-pwdExpirationTicker.C:
+				checkPasswordExpirations(t)
+			}
+		}
+	}()
+}
+
+func stopPasswordExpirationTimer() {
+	if pwdExpirationTicker != nil {
+		pwdExpirationTicker.Stop()
+		pwdExpirationTickerDone <- true
+		pwdExpirationTicker = nil
+	}
+}
+
+func checkPasswordExpirations(now time.Time) {
+	if !smtp.IsEnabled() {
+		providerLog(logger.LevelDebug, "password expiration check skipped, smtp is not configured")
+		return
+	}
+	for offset := 0; ; offset += pwdExpirationPageSize {
+		users, err := provider.getUsers(pwdExpirationPageSize, offset, OrderASC)
+		if err != nil {
+			providerLog(logger.LevelWarn, "password expiration check, unable to get users: %v", err)
+			return
+		}
+		for idx := range users {
+			user := &users[idx]
+			if user.Email == "" || user.Password == "" {
+				continue
+			}
+			if err := user.LoadAndApplyGroupSettings(); err != nil {
+				providerLog(logger.LevelWarn, "password expiration check, unable to load group settings for user %#v: %v",
+					user.Username, err)
+				continue
+			}
+			notifyPasswordExpiration(user.Username, user.Email, user.GetPasswordExpirationTime(), now)
+		}
+		if len(users) < pwdExpirationPageSize {
+			break
+		}
+	}
+	for offset := 0; ; offset += pwdExpirationPageSize {
+		admins, err := provider.getAdmins(pwdExpirationPageSize, offset, OrderASC)
+		if err != nil {
+			providerLog(logger.LevelWarn, "password expiration check, unable to get admins: %v", err)
+			return
+		}
+		for idx := range admins {
+			admin := &admins[idx]
+			if admin.Email == "" {
+				continue
+			}
+			notifyPasswordExpiration(admin.Username, admin.Email, admin.GetPasswordExpirationTime(), now)
+		}
+		if len(admins) < pwdExpirationPageSize {
+			break
+		}
+	}
+}
+
+func notifyPasswordExpiration(username, email string, expiration, now time.Time) {
+	if expiration.IsZero() || !expiration.After(now) {
+		return
+	}
+	remaining := expiration.Sub(now)
+	if remaining > time.Duration(config.PasswordExpirationNotification)*24*time.Hour {
+		return
+	}
+	if remaining%(24*time.Hour) >= pwdExpirationCheckInterval {
+		return
+	}
+	data := pwdExpirationNotification{
+		Username:       username,
+		ExpirationDate: expiration.UTC().Format("2006-01-02 15:04:05 UTC"),
+		Days:           int(remaining / (24 * time.Hour)),
+	}
+	body := new(bytes.Buffer)
+	if err := smtp.RenderPasswordExpirationTemplate(body, data); err != nil {
+		providerLog(logger.LevelWarn, "unable to render password expiration template for %#v: %v", username, err)
+		return
+	}
+	subject := fmt.Sprintf("SFTPGo password expiration for %#v", username)
+	if err := smtp.SendEmail(email, subject, body.String(), smtp.EmailContentTypeTextHTML); err != nil {
+		providerLog(logger.LevelWarn, "unable to send password expiration email to %#v: %v", username, err)
+		return
+	}
+	providerLog(logger.LevelInfo, "password expiration email sent to %#v, expiration: %v", username, expiration)
+}
@@ -78,6 +78,177 @@ ALTER TABLE "{{admins}}" DROP COLUMN "last_login" CASCADE;
 `
 	pgsqlV13SQL     = `ALTER TABLE "{{users}}" ADD COLUMN "email" varchar(255) NULL;`
 	pgsqlV13DownSQL = `ALTER TABLE "{{users}}" DROP COLUMN "email" CASCADE;`
+	pgsqlV14SQL     = `CREATE TABLE "{{shares}}" ("id" serial NOT NULL PRIMARY KEY,
+"share_id" varchar(60) NOT NULL UNIQUE, "name" varchar(255) NOT NULL, "description" varchar(512) NULL,
+"scope" integer NOT NULL, "paths" text NOT NULL, "created_at" bigint NOT NULL, "updated_at" bigint NOT NULL,
+"last_use_at" bigint NOT NULL, "expires_at" bigint NOT NULL, "password" text NULL, "max_tokens" integer NOT NULL,
+"used_tokens" integer NOT NULL, "allow_from" text NULL, "user_id" integer NOT NULL);
+ALTER TABLE "{{shares}}" ADD CONSTRAINT "{{prefix}}shares_user_id_fk_users_id" FOREIGN KEY ("user_id")
+REFERENCES "{{users}}" ("id") MATCH SIMPLE ON UPDATE NO ACTION ON DELETE CASCADE;
+CREATE INDEX "{{prefix}}shares_user_id_idx" ON "{{shares}}" ("user_id");
+`
+	pgsqlV14DownSQL = `DROP TABLE "{{shares}}" CASCADE;`
+	pgsqlV15SQL     = `CREATE TABLE "{{share_uploads}}" ("id" serial NOT NULL PRIMARY KEY,
+"path" text NOT NULL, "size" bigint NOT NULL, "ip" varchar(50) NOT NULL, "uploader" varchar(255) NULL,
+"uploaded_at" bigint NOT NULL, "share_id" integer NOT NULL);
+ALTER TABLE "{{share_uploads}}" ADD CONSTRAINT "{{prefix}}share_uploads_share_id_fk_shares_id" FOREIGN KEY ("share_id")
+REFERENCES "{{shares}}" ("id") MATCH SIMPLE ON UPDATE NO ACTION ON DELETE CASCADE;
+CREATE INDEX "{{prefix}}share_uploads_share_id_idx" ON "{{share_uploads}}" ("share_id");
+`
+	pgsqlV15DownSQL = `DROP TABLE "{{share_uploads}}" CASCADE;`
+	pgsqlV16SQL     = `CREATE TABLE "{{groups}}" ("id" serial NOT NULL PRIMARY KEY, "name" varchar(255) NOT NULL UNIQUE,
+"description" varchar(512) NULL, "created_at" bigint NOT NULL, "updated_at" bigint NOT NULL, "user_settings" text NULL);
+CREATE TABLE "{{groups_folders_mapping}}" ("id" serial NOT NULL PRIMARY KEY, "virtual_path" varchar(512) NOT NULL,
+"quota_size" bigint NOT NULL, "quota_files" integer NOT NULL, "folder_id" integer NOT NULL, "group_id" integer NOT NULL);
+ALTER TABLE "{{groups_folders_mapping}}" ADD CONSTRAINT "{{prefix}}unique_group_folder_mapping" UNIQUE ("group_id", "folder_id");
+ALTER TABLE "{{groups_folders_mapping}}" ADD CONSTRAINT "{{prefix}}groups_folders_mapping_folder_id_fk_folders_id"
+FOREIGN KEY ("folder_id") REFERENCES "{{folders}}" ("id") MATCH SIMPLE ON UPDATE NO ACTION ON DELETE CASCADE;
+ALTER TABLE "{{groups_folders_mapping}}" ADD CONSTRAINT "{{prefix}}groups_folders_mapping_group_id_fk_groups_id"
+FOREIGN KEY ("group_id") REFERENCES "{{groups}}" ("id") MATCH SIMPLE ON UPDATE NO ACTION ON DELETE CASCADE;
+CREATE TABLE "{{groups_mapping}}" ("id" serial NOT NULL PRIMARY KEY, "user_id" integer NOT NULL, "group_id" integer NOT NULL);
+ALTER TABLE "{{groups_mapping}}" ADD CONSTRAINT "{{prefix}}unique_group_mapping" UNIQUE ("user_id", "group_id");
+ALTER TABLE "{{groups_mapping}}" ADD CONSTRAINT "{{prefix}}groups_mapping_user_id_fk_users_id"
+FOREIGN KEY ("user_id") REFERENCES "{{users}}" ("id") MATCH SIMPLE ON UPDATE NO ACTION ON DELETE CASCADE;
+ALTER TABLE "{{groups_mapping}}" ADD CONSTRAINT "{{prefix}}groups_mapping_group_id_fk_groups_id"
+FOREIGN KEY ("group_id") REFERENCES "{{groups}}" ("id") MATCH SIMPLE ON UPDATE NO ACTION ON DELETE CASCADE;
+CREATE INDEX "{{prefix}}groups_folders_mapping_folder_id_idx" ON "{{groups_folders_mapping}}" ("folder_id");
+CREATE INDEX "{{prefix}}groups_folders_mapping_group_id_idx" ON "{{groups_folders_mapping}}" ("group_id");
+CREATE INDEX "{{prefix}}groups_mapping_user_id_idx" ON "{{groups_mapping}}" ("user_id");
+CREATE INDEX "{{prefix}}groups_mapping_group_id_idx" ON "{{groups_mapping}}" ("group_id");
+`
+	pgsqlV16DownSQL = `DROP TABLE "{{groups_mapping}}" CASCADE;
+DROP TABLE "{{groups_folders_mapping}}" CASCADE;
+DROP TABLE "{{groups}}" CASCADE;
+`
+	pgsqlV17SQL = `ALTER TABLE "{{users}}" ADD COLUMN "upload_data_transfer" bigint DEFAULT 0 NOT NULL;
+ALTER TABLE "{{users}}" ALTER COLUMN "upload_data_transfer" DROP DEFAULT;
+ALTER TABLE "{{users}}" ADD COLUMN "download_data_transfer" bigint DEFAULT 0 NOT NULL;
+ALTER TABLE "{{users}}" ALTER COLUMN "download_data_transfer" DROP DEFAULT;
+ALTER TABLE "{{users}}" ADD COLUMN "total_data_transfer" bigint DEFAULT 0 NOT NULL;
+ALTER TABLE "{{users}}" ALTER COLUMN "total_data_transfer" DROP DEFAULT;
+ALTER TABLE "{{users}}" ADD COLUMN "used_upload_data_transfer" bigint DEFAULT 0 NOT NULL;
+ALTER TABLE "{{users}}" ALTER COLUMN "used_upload_data_transfer" DROP DEFAULT;
+ALTER TABLE "{{users}}" ADD COLUMN "used_download_data_transfer" bigint DEFAULT 0 NOT NULL;
+ALTER TABLE "{{users}}" ALTER COLUMN "used_download_data_transfer" DROP DEFAULT;
+ALTER TABLE "{{users}}" ADD COLUMN "last_data_transfer_reset" bigint DEFAULT 0 NOT NULL;
+ALTER TABLE "{{users}}" ALTER COLUMN "last_data_transfer_reset" DROP DEFAULT;
+ALTER TABLE "{{users}}" ADD COLUMN "data_transfer_reset_period" varchar(32) NULL;
+ALTER TABLE "{{folders}}" ADD COLUMN "upload_data_transfer" bigint DEFAULT 0 NOT NULL;
+ALTER TABLE "{{folders}}" ALTER COLUMN "upload_data_transfer" DROP DEFAULT;
+ALTER TABLE "{{folders}}" ADD COLUMN "download_data_transfer" bigint DEFAULT 0 NOT NULL;
+ALTER TABLE "{{folders}}" ALTER COLUMN "download_data_transfer" DROP DEFAULT;
+ALTER TABLE "{{folders}}" ADD COLUMN "total_data_transfer" bigint DEFAULT 0 NOT NULL;
+ALTER TABLE "{{folders}}" ALTER COLUMN "total_data_transfer" DROP DEFAULT;
+ALTER TABLE "{{folders}}" ADD COLUMN "used_upload_data_transfer" bigint DEFAULT 0 NOT NULL;
+ALTER TABLE "{{folders}}" ALTER COLUMN "used_upload_data_transfer" DROP DEFAULT;
+ALTER TABLE "{{folders}}" ADD COLUMN "used_download_data_transfer" bigint DEFAULT 0 NOT NULL;
+ALTER TABLE "{{folders}}" ALTER COLUMN "used_download_data_transfer" DROP DEFAULT;
+ALTER TABLE "{{folders}}" ADD COLUMN "last_data_transfer_reset" bigint DEFAULT 0 NOT NULL;
+ALTER TABLE "{{folders}}" ALTER COLUMN "last_data_transfer_reset" DROP DEFAULT;
+ALTER TABLE "{{folders}}" ADD COLUMN "data_transfer_reset_period" varchar(32) NULL;
+`
+	pgsqlV17DownSQL = `ALTER TABLE "{{folders}}" DROP COLUMN "data_transfer_reset_period" CASCADE;
+ALTER TABLE "{{folders}}" DROP COLUMN "last_data_transfer_reset" CASCADE;
+ALTER TABLE "{{folders}}" DROP COLUMN "used_download_data_transfer" CASCADE;
+ALTER TABLE "{{folders}}" DROP COLUMN "used_upload_data_transfer" CASCADE;
+ALTER TABLE "{{folders}}" DROP COLUMN "total_data_transfer" CASCADE;
+ALTER TABLE "{{folders}}" DROP COLUMN "download_data_transfer" CASCADE;
+ALTER TABLE "{{folders}}" DROP COLUMN "upload_data_transfer" CASCADE;
+ALTER TABLE "{{users}}" DROP COLUMN "data_transfer_reset_period" CASCADE;
+ALTER TABLE "{{users}}" DROP COLUMN "last_data_transfer_reset" CASCADE;
+ALTER TABLE "{{users}}" DROP COLUMN "used_download_data_transfer" CASCADE;
+ALTER TABLE "{{users}}" DROP COLUMN "used_upload_data_transfer" CASCADE;
+ALTER TABLE "{{users}}" DROP COLUMN "total_data_transfer" CASCADE;
+ALTER TABLE "{{users}}" DROP COLUMN "download_data_transfer" CASCADE;
+ALTER TABLE "{{users}}" DROP COLUMN "upload_data_transfer" CASCADE;
+`
+	pgsqlV18SQL = `ALTER TABLE "{{users}}" ADD COLUMN "last_password_change" bigint DEFAULT 0 NOT NULL;
+ALTER TABLE "{{users}}" ALTER COLUMN "last_password_change" DROP DEFAULT;
+UPDATE "{{users}}" SET "last_password_change" = "updated_at";
+ALTER TABLE "{{admins}}" ADD COLUMN "last_password_change" bigint DEFAULT 0 NOT NULL;
+ALTER TABLE "{{admins}}" ALTER COLUMN "last_password_change" DROP DEFAULT;
+UPDATE "{{admins}}" SET "last_password_change" = "updated_at";
+`
+	pgsqlV18DownSQL = `ALTER TABLE "{{admins}}" DROP COLUMN "last_password_change" CASCADE;
+ALTER TABLE "{{users}}" DROP COLUMN "last_password_change" CASCADE;
+`
+	pgsqlV19SQL = `CREATE TABLE "{{account_lockouts}}" ("id" serial NOT NULL PRIMARY KEY,
+"username" varchar(255) NOT NULL, "account_type" varchar(16) NOT NULL, "failures" integer NOT NULL,
+"first_failure_at" bigint NOT NULL, "last_failure_at" bigint NOT NULL, "locked_at" bigint NOT NULL,
+"locked_until" bigint NOT NULL,
+CONSTRAINT "{{prefix}}unique_account_lockout" UNIQUE ("username", "account_type"));
+`
+	pgsqlV19DownSQL = `DROP TABLE "{{account_lockouts}}" CASCADE;`
+	pgsqlV20SQL     = `CREATE TABLE "{{fs_events}}" ("id" varchar(36) NOT NULL PRIMARY KEY, "timestamp" bigint NOT NULL,
+"action" varchar(60) NOT NULL, "username" varchar(255) NOT NULL, "fs_path" text NOT NULL,
+"fs_target_path" text NOT NULL, "virtual_path" text NOT NULL, "virtual_target_path" text NOT NULL,
+"ssh_cmd" varchar(255) NOT NULL, "file_size" bigint NOT NULL, "status" integer NOT NULL,
+"protocol" varchar(30) NOT NULL, "ip" varchar(50) NOT NULL, "instance_id" varchar(255) NOT NULL);
+CREATE TABLE "{{provider_events}}" ("id" varchar(36) NOT NULL PRIMARY KEY, "timestamp" bigint NOT NULL,
+"action" varchar(60) NOT NULL, "username" varchar(255) NOT NULL, "ip" varchar(50) NOT NULL,
+"object_type" varchar(60) NOT NULL, "object_name" varchar(255) NOT NULL, "instance_id" varchar(255) NOT NULL);
+CREATE INDEX "{{prefix}}fs_events_timestamp_idx" ON "{{fs_events}}" ("timestamp");
+CREATE INDEX "{{prefix}}provider_events_timestamp_idx" ON "{{provider_events}}" ("timestamp");
+`
+	pgsqlV20DownSQL = `DROP TABLE "{{provider_events}}" CASCADE;
+DROP TABLE "{{fs_events}}" CASCADE;
+`
+	pgsqlV21SQL = `CREATE TABLE "{{events_rules}}" ("id" serial NOT NULL PRIMARY KEY, "name" varchar(255) NOT NULL UNIQUE,
+"description" varchar(512) NULL, "created_at" bigint NOT NULL, "updated_at" bigint NOT NULL, "event_trigger" integer NOT NULL,
+"conditions" text NOT NULL, "actions" text NOT NULL);
+`
+	pgsqlV21DownSQL = `DROP TABLE "{{events_rules}}" CASCADE;
+`
+	pgsqlV22SQL = `CREATE TABLE "{{schedules}}" ("id" serial NOT NULL PRIMARY KEY, "name" varchar(255) NOT NULL UNIQUE,
+"description" varchar(512) NULL, "created_at" bigint NOT NULL, "updated_at" bigint NOT NULL, "status" integer NOT NULL,
+"cron_expression" varchar(255) NOT NULL, "task" integer NOT NULL, "options" text NOT NULL, "last_run" bigint DEFAULT 0 NOT NULL,
+"last_run_status" integer DEFAULT 0 NOT NULL, "last_run_error" text NULL);
+`
+	pgsqlV22DownSQL = `DROP TABLE "{{schedules}}" CASCADE;
+`
+	pgsqlV23SQL     = `ALTER TABLE "{{folders}}" ADD COLUMN "data_retention" text NULL;`
+	pgsqlV23DownSQL = `ALTER TABLE "{{folders}}" DROP COLUMN "data_retention" CASCADE;`
+	pgsqlV24SQL     = `CREATE TABLE "{{webdav_locks}}" ("id" serial NOT NULL PRIMARY KEY,
+"token" varchar(255) NOT NULL UNIQUE, "username" varchar(255) NOT NULL, "root" text NOT NULL,
+"root_key" varchar(64) NOT NULL, "duration" bigint NOT NULL, "expires_at" bigint NOT NULL, "owner_xml" text NULL,
+"zero_depth" boolean NOT NULL, "created_at" bigint NOT NULL, "updated_at" bigint NOT NULL,
+CONSTRAINT "{{prefix}}unique_webdav_lock_root" UNIQUE ("username", "root_key"));
+CREATE INDEX "{{prefix}}webdav_locks_expires_at_idx" ON "{{webdav_locks}}" ("expires_at");
+`
+	pgsqlV24DownSQL = `DROP TABLE "{{webdav_locks}}" CASCADE;`
+	pgsqlV25SQL     = `CREATE TABLE "{{webdav_properties}}" ("id" serial NOT NULL PRIMARY KEY,
+"username" varchar(255) NOT NULL, "path" text NOT NULL, "path_key" varchar(64) NOT NULL, "properties" text NOT NULL,
+"updated_at" bigint NOT NULL,
+CONSTRAINT "{{prefix}}unique_webdav_properties_path" UNIQUE ("username", "path_key"));
+`
+	pgsqlV25DownSQL = `DROP TABLE "{{webdav_properties}}" CASCADE;`
+	pgsqlV26SQL     = `CREATE TABLE "{{s3_access_keys}}" ("id" serial NOT NULL PRIMARY KEY,
+"access_key_id" varchar(64) NOT NULL UNIQUE, "username" varchar(255) NOT NULL, "secret_access_key" text NOT NULL,
+"description" text NULL, "created_at" bigint NOT NULL);
+CREATE INDEX "{{prefix}}s3_access_keys_username_idx" ON "{{s3_access_keys}}" ("username");
+`
+	pgsqlV26DownSQL = `DROP TABLE "{{s3_access_keys}}" CASCADE;`
+	pgsqlV27SQL     = `CREATE TABLE "{{tus_uploads}}" ("id" serial NOT NULL PRIMARY KEY,
+"upload_id" varchar(64) NOT NULL UNIQUE, "username" varchar(255) NOT NULL, "virtual_path" text NOT NULL,
+"fs_path" text NOT NULL, "upload_length" bigint NOT NULL, "upload_offset" bigint NOT NULL,
+"expires_at" bigint NOT NULL, "created_at" bigint NOT NULL, "updated_at" bigint NOT NULL);
+CREATE INDEX "{{prefix}}tus_uploads_username_idx" ON "{{tus_uploads}}" ("username");
+CREATE INDEX "{{prefix}}tus_uploads_expires_at_idx" ON "{{tus_uploads}}" ("expires_at");
+`
+	pgsqlV27DownSQL = `DROP TABLE "{{tus_uploads}}" CASCADE;`
+	pgsqlV28SQL     = `CREATE TABLE "{{reset_codes}}" ("id" serial NOT NULL PRIMARY KEY,
+"code" varchar(64) NOT NULL UNIQUE, "username" varchar(255) NOT NULL, "account_type" varchar(16) NOT NULL,
+"password_fingerprint" varchar(64) NOT NULL, "expires_at" bigint NOT NULL, "created_at" bigint NOT NULL);
+CREATE INDEX "{{prefix}}reset_codes_username_idx" ON "{{reset_codes}}" ("username");
+CREATE INDEX "{{prefix}}reset_codes_expires_at_idx" ON "{{reset_codes}}" ("expires_at");
+`
+	pgsqlV28DownSQL = `DROP TABLE "{{reset_codes}}" CASCADE;`
+	pgsqlV29SQL     = `CREATE TABLE "{{multipart_uploads}}" ("id" serial NOT NULL PRIMARY KEY,
+"upload_key" varchar(64) NOT NULL UNIQUE, "upload_size" bigint NOT NULL, "state" text NOT NULL,
+"created_at" bigint NOT NULL, "updated_at" bigint NOT NULL);
+CREATE INDEX "{{prefix}}multipart_uploads_updated_at_idx" ON "{{multipart_uploads}}" ("updated_at");
+`
+	pgsqlV29DownSQL = `DROP TABLE "{{multipart_uploads}}" CASCADE;`
 )
 
 // PGSQLProvider auth provider for PostgreSQL database
@@ -149,6 +320,14 @@ func (p *PGSQLProvider) getUsedQuota(username string) (int, int64, error) {
 	return sqlCommonGetUsedQuota(username, p.dbHandle)
 }
 
+func (p *PGSQLProvider) updateTransferQuota(username string, uploadSize, downloadSize int64, reset bool, periodStart int64) error {
+	return sqlCommonUpdateTransferQuota(username, uploadSize, downloadSize, reset, periodStart, p.dbHandle)
+}
+
+func (p *PGSQLProvider) getUsedTransferQuota(username string) (int64, int64, int64, error) {
+	return sqlCommonGetUsedTransferQuota(username, p.dbHandle)
+}
+
 func (p *PGSQLProvider) setUpdatedAt(username string) {
 	sqlCommonSetUpdatedAt(username, p.dbHandle)
 }
@@ -185,6 +364,10 @@ func (p *PGSQLProvider) getRecentlyUpdatedUsers(after int64) ([]User, error) {
 	return sqlCommonGetRecentlyUpdatedUsers(after, p.dbHandle)
 }
 
+func (p *PGSQLProvider) getUsersInScope(admin *Admin, limit, offset int, order string) ([]User, error) {
+	return sqlCommonGetUsersInScope(admin, limit, offset, order, p.dbHandle)
+}
+
 func (p *PGSQLProvider) getUsers(limit int, offset int, order string) ([]User, error) {
 	return sqlCommonGetUsers(limit, offset, order, p.dbHandle)
 }
@@ -223,6 +406,42 @@ func (p *PGSQLProvider) getUsedFolderQuota(name string) (int, int64, error) {
 	return sqlCommonGetFolderUsedQuota(name, p.dbHandle)
 }
 
+func (p *PGSQLProvider) updateFolderTransferQuota(name string, uploadSize, downloadSize int64, reset bool, periodStart int64) error {
+	return sqlCommonUpdateFolderTransferQuota(name, uploadSize, downloadSize, reset, periodStart, p.dbHandle)
+}
+
+func (p *PGSQLProvider) getUsedFolderTransferQuota(name string) (int64, int64, int64, error) {
+	return sqlCommonGetFolderUsedTransferQuota(name, p.dbHandle)
+}
+
+func (p *PGSQLProvider) groupExists(name string) (Group, error) {
+	return sqlCommonGetGroupByName(name, p.dbHandle)
+}
+
+func (p *PGSQLProvider) addGroup(group *Group) error {
+	return sqlCommonAddGroup(group, p.dbHandle)
+}
+
+func (p *PGSQLProvider) updateGroup(group *Group) error {
+	return sqlCommonUpdateGroup(group, p.dbHandle)
+}
+
+func (p *PGSQLProvider) deleteGroup(group *Group) error {
+	return sqlCommonDeleteGroup(group, p.dbHandle)
+}
+
+func (p *PGSQLProvider) getGroups(limit, offset int, order string) ([]Group, error) {
+	return sqlCommonGetGroups(limit, offset, order, p.dbHandle)
+}
+
+func (p *PGSQLProvider) getGroupsWithNames(names []string) ([]Group, error) {
+	return sqlCommonGetGroupsWithNames(names, p.dbHandle)
+}
+
+func (p *PGSQLProvider) dumpGroups() ([]Group, error) {
+	return sqlCommonDumpGroups(p.dbHandle)
+}
+
 func (p *PGSQLProvider) adminExists(username string) (Admin, error) {
 	return sqlCommonGetAdminByUsername(username, p.dbHandle)
 }
@@ -279,6 +498,274 @@ func (p *PGSQLProvider) updateAPIKeyLastUse(keyID string) error {
 	return sqlCommonUpdateAPIKeyLastUse(keyID, p.dbHandle)
 }
 
+func (p *PGSQLProvider) shareExists(shareID, username string) (Share, error) {
+	return sqlCommonGetShareByID(shareID, username, p.dbHandle)
+}
+
+func (p *PGSQLProvider) addShare(share *Share) error {
+	return sqlCommonAddShare(share, p.dbHandle)
+}
+
+func (p *PGSQLProvider) updateShare(share *Share) error {
+	return sqlCommonUpdateShare(share, p.dbHandle)
+}
+
+func (p *PGSQLProvider) deleteShare(share *Share) error {
+	return sqlCommonDeleteShare(share, p.dbHandle)
+}
+
+func (p *PGSQLProvider) getShares(limit int, offset int, order, username string) ([]Share, error) {
+	return sqlCommonGetShares(limit, offset, order, username, p.dbHandle)
+}
+
+func (p *PGSQLProvider) dumpShares() ([]Share, error) {
+	return sqlCommonDumpShares(p.dbHandle)
+}
+
+func (p *PGSQLProvider) updateShareLastUse(shareID string, numTokens int) error {
+	return sqlCommonUpdateShareLastUse(shareID, numTokens, p.dbHandle)
+}
+
+func (p *PGSQLProvider) addShareUpload(upload *ShareUpload) error {
+	return sqlCommonAddShareUpload(upload, p.dbHandle)
+}
+
+func (p *PGSQLProvider) getShareUploads(shareID string, limit, offset int, order string) ([]ShareUpload, error) {
+	return sqlCommonGetShareUploads(shareID, limit, offset, order, p.dbHandle)
+}
+
+func (p *PGSQLProvider) accountLockoutExists(username, accountType string) (AccountLockout, error) {
+	return sqlCommonGetAccountLockout(username, accountType, p.dbHandle)
+}
+
+func (p *PGSQLProvider) addAccountLockoutFailure(username, accountType string, now time.Time) (AccountLockout, error) {
+	return sqlCommonAddAccountLockoutFailure(username, accountType, now, p.dbHandle)
+}
+
+func (p *PGSQLProvider) deleteAccountLockout(username, accountType string) error {
+	return sqlCommonDeleteAccountLockout(username, accountType, p.dbHandle)
+}
+
+func (p *PGSQLProvider) getAccountLockouts(limit, offset int, order string) ([]AccountLockout, error) {
+	return sqlCommonGetAccountLockouts(limit, offset, order, p.dbHandle)
+}
+
+func (p *PGSQLProvider) addFsEvent(event *FsEvent) error {
+	return sqlCommonAddFsEvent(event, p.dbHandle)
+}
+
+func (p *PGSQLProvider) addProviderEvent(event *ProviderEvent) error {
+	return sqlCommonAddProviderEvent(event, p.dbHandle)
+}
+
+func (p *PGSQLProvider) searchFsEvents(search *FsEventSearch) ([]FsEvent, error) {
+	return sqlCommonSearchFsEvents(search, p.dbHandle)
+}
+
+func (p *PGSQLProvider) searchProviderEvents(search *ProviderEventSearch) ([]ProviderEvent, error) {
+	return sqlCommonSearchProviderEvents(search, p.dbHandle)
+}
+
+func (p *PGSQLProvider) cleanupEvents(before int64) error {
+	return sqlCommonCleanupEvents(before, p.dbHandle)
+}
+
+func (p *PGSQLProvider) webDAVLockExists(token string) (WebDAVLock, error) {
+	return sqlCommonGetWebDAVLock(token, p.dbHandle)
+}
+
+func (p *PGSQLProvider) addWebDAVLock(lock *WebDAVLock) error {
+	return sqlCommonAddWebDAVLock(lock, p.dbHandle)
+}
+
+func (p *PGSQLProvider) updateWebDAVLock(lock *WebDAVLock) error {
+	return sqlCommonUpdateWebDAVLock(lock, p.dbHandle)
+}
+
+func (p *PGSQLProvider) deleteWebDAVLock(token string) error {
+	return sqlCommonDeleteWebDAVLock(token, p.dbHandle)
+}
+
+func (p *PGSQLProvider) getWebDAVLocks(username string) ([]WebDAVLock, error) {
+	return sqlCommonGetWebDAVLocks(username, p.dbHandle)
+}
+
+func (p *PGSQLProvider) deleteUserWebDAVLocks(username string) error {
+	return sqlCommonDeleteUserWebDAVLocks(username, p.dbHandle)
+}
+
+func (p *PGSQLProvider) cleanupWebDAVLocks(before int64) error {
+	return sqlCommonCleanupWebDAVLocks(before, p.dbHandle)
+}
+
+func (p *PGSQLProvider) webDAVPropertiesExists(username, virtualPath string) (WebDAVProperties, error) {
+	return sqlCommonGetWebDAVProperties(username, virtualPath, p.dbHandle)
+}
+
+func (p *PGSQLProvider) setWebDAVProperties(props *WebDAVProperties) error {
+	return sqlCommonSetWebDAVProperties(props, p.dbHandle)
+}
+
+func (p *PGSQLProvider) deleteWebDAVProperties(username, virtualPath string) error {
+	return sqlCommonDeleteWebDAVProperties(username, virtualPath, p.dbHandle)
+}
+
+func (p *PGSQLProvider) renameWebDAVProperties(username, source, target string) error {
+	return sqlCommonRenameWebDAVProperties(username, source, target, p.dbHandle)
+}
+
+func (p *PGSQLProvider) deleteUserWebDAVProperties(username string) error {
+	return sqlCommonDeleteUserWebDAVProperties(username, p.dbHandle)
+}
+
+func (p *PGSQLProvider) s3AccessKeyExists(accessKeyID string) (S3AccessKey, error) {
+	return sqlCommonGetS3AccessKey(accessKeyID, p.dbHandle)
+}
+
+func (p *PGSQLProvider) addS3AccessKey(key *S3AccessKey) error {
+	return sqlCommonAddS3AccessKey(key, p.dbHandle)
+}
+
+func (p *PGSQLProvider) deleteS3AccessKey(accessKeyID, username string) error {
+	return sqlCommonDeleteS3AccessKey(accessKeyID, username, p.dbHandle)
+}
+
+func (p *PGSQLProvider) getUserS3AccessKeys(username string) ([]S3AccessKey, error) {
+	return sqlCommonGetUserS3AccessKeys(username, p.dbHandle)
+}
+
+func (p *PGSQLProvider) deleteUserS3AccessKeys(username string) error {
+	return sqlCommonDeleteUserS3AccessKeys(username, p.dbHandle)
+}
+
+func (p *PGSQLProvider) updateS3AccessKey(key *S3AccessKey) error {
+	return sqlCommonUpdateS3AccessKey(key, p.dbHandle)
+}
+
+func (p *PGSQLProvider) dumpS3AccessKeys() ([]S3AccessKey, error) {
+	return sqlCommonDumpS3AccessKeys(p.dbHandle)
+}
+
+func (p *PGSQLProvider) tusUploadExists(uploadID string) (TusUpload, error) {
+	return sqlCommonGetTusUpload(uploadID, p.dbHandle)
+}
+
+func (p *PGSQLProvider) addTusUpload(upload *TusUpload) error {
+	return sqlCommonAddTusUpload(upload, p.dbHandle)
+}
+
+func (p *PGSQLProvider) updateTusUploadOffset(uploadID string, offset, expiresAt, updatedAt int64) error {
+	return sqlCommonUpdateTusUploadOffset(uploadID, offset, expiresAt, updatedAt, p.dbHandle)
+}
+
+func (p *PGSQLProvider) deleteTusUpload(uploadID string) error {
+	return sqlCommonDeleteTusUpload(uploadID, p.dbHandle)
+}
+
+func (p *PGSQLProvider) getTusUploadsCount(username string) (int, error) {
+	return sqlCommonGetTusUploadsCount(username, p.dbHandle)
+}
+
+func (p *PGSQLProvider) getExpiredTusUploads(before int64) ([]TusUpload, error) {
+	return sqlCommonGetExpiredTusUploads(before, p.dbHandle)
+}
+
+func (p *PGSQLProvider) deleteUserTusUploads(username string) error {
+	return sqlCommonDeleteUserTusUploads(username, p.dbHandle)
+}
+
+func (p *PGSQLProvider) resetCodeExists(code string) (ResetCode, error) {
+	return sqlCommonGetResetCode(code, p.dbHandle)
+}
+
+func (p *PGSQLProvider) addResetCode(code *ResetCode) error {
+	return sqlCommonAddResetCode(code, p.dbHandle)
+}
+
+func (p *PGSQLProvider) deleteResetCode(code string) error {
+	return sqlCommonDeleteResetCode(code, p.dbHandle)
+}
+
+func (p *PGSQLProvider) getResetCodesCount(username, accountType string, after int64) (int, error) {
+	return sqlCommonGetResetCodesCount(username, accountType, after, p.dbHandle)
+}
+
+func (p *PGSQLProvider) deleteExpiredResetCodes(before int64) error {
+	return sqlCommonDeleteExpiredResetCodes(before, p.dbHandle)
+}
+
+func (p *PGSQLProvider) multipartUploadExists(key string) (vfs.MultipartUpload, error) {
+	return sqlCommonGetMultipartUpload(key, p.dbHandle)
+}
+
+func (p *PGSQLProvider) saveMultipartUpload(upload *vfs.MultipartUpload) error {
+	return sqlCommonSaveMultipartUpload(upload, p.dbHandle)
+}
+
+func (p *PGSQLProvider) deleteMultipartUpload(key string) error {
+	return sqlCommonDeleteMultipartUpload(key, p.dbHandle)
+}
+
+func (p *PGSQLProvider) cleanupMultipartUploads(before int64) error {
+	return sqlCommonCleanupMultipartUploads(before, p.dbHandle)
+}
+
+func (p *PGSQLProvider) eventRuleExists(name string) (EventRule, error) {
+	return sqlCommonGetEventRuleByName(name, p.dbHandle)
+}
+
+func (p *PGSQLProvider) addEventRule(rule *EventRule) error {
+	return sqlCommonAddEventRule(rule, p.dbHandle)
+}
+
+func (p *PGSQLProvider) updateEventRule(rule *EventRule) error {
+	return sqlCommonUpdateEventRule(rule, p.dbHandle)
+}
+
+func (p *PGSQLProvider) deleteEventRule(rule *EventRule) error {
+	return sqlCommonDeleteEventRule(rule, p.dbHandle)
+}
+
+func (p *PGSQLProvider) getEventRules(limit, offset int, order string) ([]EventRule, error) {
+	return sqlCommonGetEventRules(limit, offset, order, p.dbHandle)
+}
+
+func (p *PGSQLProvider) dumpEventRules() ([]EventRule, error) {
+	return sqlCommonDumpEventRules(p.dbHandle)
+}
+
+func (p *PGSQLProvider) scheduleExists(name string) (Schedule, error) {
+	return sqlCommonGetScheduleByName(name, p.dbHandle)
+}
+
+func (p *PGSQLProvider) addSchedule(schedule *Schedule) error {
+	return sqlCommonAddSchedule(schedule, p.dbHandle)
+}
+
+func (p *PGSQLProvider) updateSchedule(schedule *Schedule) error {
+	return sqlCommonUpdateSchedule(schedule, p.dbHandle)
+}
+
+func (p *PGSQLProvider) deleteSchedule(schedule *Schedule) error {
+	return sqlCommonDeleteSchedule(schedule, p.dbHandle)
+}
+
+func (p *PGSQLProvider) getSchedules(limit, offset int, order string) ([]Schedule, error) {
+	return sqlCommonGetSchedules(limit, offset, order, p.dbHandle)
+}
+
+func (p *PGSQLProvider) dumpSchedules() ([]Schedule, error) {
+	return sqlCommonDumpSchedules(p.dbHandle)
+}
+
+func (p *PGSQLProvider) claimScheduleRun(name string, runAt int64) (bool, error) {
+	return sqlCommonClaimScheduleRun(name, runAt, p.dbHandle)
+}
+
+func (p *PGSQLProvider) setScheduleRunResult(name string, status int, runError string) error {
+	return sqlCommonSetScheduleRunResult(name, status, runError, p.dbHandle)
+}
+
 func (p *PGSQLProvider) close() error {
 	return p.dbHandle.Close()
 }
@@ -330,6 +817,38 @@ func (p *PGSQLProvider) migrateDatabase() error {
 		return updatePGSQLDatabaseFromV11(p.dbHandle)
 	case version == 12:
 		return updatePGSQLDatabaseFromV12(p.dbHandle)
+	case version == 13:
+		return updatePGSQLDatabaseFromV13(p.dbHandle)
+	case version == 14:
+		return updatePGSQLDatabaseFromV14(p.dbHandle)
+	case version == 15:
+		return updatePGSQLDatabaseFromV15(p.dbHandle)
+	case version == 16:
+		return updatePGSQLDatabaseFromV16(p.dbHandle)
+	case version == 17:
+		return updatePGSQLDatabaseFromV17(p.dbHandle)
+	case version == 18:
+		return updatePGSQLDatabaseFromV18(p.dbHandle)
+	case version == 19:
+		return updatePGSQLDatabaseFromV19(p.dbHandle)
+	case version == 20:
+		return updatePGSQLDatabaseFromV20(p.dbHandle)
+	case version == 21:
+		return updatePGSQLDatabaseFromV21(p.dbHandle)
+	case version == 22:
+		return updatePGSQLDatabaseFromV22(p.dbHandle)
+	case version == 23:
+		return updatePGSQLDatabaseFromV23(p.dbHandle)
+	case version == 24:
+		return updatePGSQLDatabaseFromV24(p.dbHandle)
+	case version == 25:
+		return updatePGSQLDatabaseFromV25(p.dbHandle)
+	case version == 26:
+		return updatePGSQLDatabaseFromV26(p.dbHandle)
+	case version == 27:
+		return updatePGSQLDatabaseFromV27(p.dbHandle)
+	case version == 28:
+		return updatePGSQLDatabaseFromV28(p.dbHandle)
 	default:
 		if version > sqlDatabaseVersion {
 			providerLog(logger.LevelWarn, "database version %v is newer than the supported one: %v", version,
@@ -352,6 +871,38 @@ func (p *PGSQLProvider) revertDatabase(targetVersion int) error {
 	}
 
 	switch dbVersion.Version {
+	case 29:
+		return downgradePGSQLDatabaseFromV29(p.dbHandle)
+	case 28:
+		return downgradePGSQLDatabaseFromV28(p.dbHandle)
+	case 27:
+		return downgradePGSQLDatabaseFromV27(p.dbHandle)
+	case 26:
+		return downgradePGSQLDatabaseFromV26(p.dbHandle)
+	case 25:
+		return downgradePGSQLDatabaseFromV25(p.dbHandle)
+	case 24:
+		return downgradePGSQLDatabaseFromV24(p.dbHandle)
+	case 23:
+		return downgradePGSQLDatabaseFromV23(p.dbHandle)
+	case 22:
+		return downgradePGSQLDatabaseFromV22(p.dbHandle)
+	case 21:
+		return downgradePGSQLDatabaseFromV21(p.dbHandle)
+	case 20:
+		return downgradePGSQLDatabaseFromV20(p.dbHandle)
+	case 19:
+		return downgradePGSQLDatabaseFromV19(p.dbHandle)
+	case 18:
+		return downgradePGSQLDatabaseFromV18(p.dbHandle)
+	case 17:
+		return downgradePGSQLDatabaseFromV17(p.dbHandle)
+	case 16:
+		return downgradePGSQLDatabaseFromV16(p.dbHandle)
+	case 15:
+		return downgradePGSQLDatabaseFromV15(p.dbHandle)
+	case 14:
+		return downgradePGSQLDatabaseFromV14(p.dbHandle)
 	case 13:
 		return downgradePGSQLDatabaseFromV13(p.dbHandle)
 	case 12:
@@ -378,7 +929,231 @@ func updatePGSQLDatabaseFromV11(dbHandle *sql.DB) error {
 }
 
 func updatePGSQLDatabaseFromV12(dbHandle *sql.DB) error {
-	return updatePGSQLDatabaseFrom12To13(dbHandle)
+	if err := updatePGSQLDatabaseFrom12To13(dbHandle); err != nil {
+		return err
+	}
+	return updatePGSQLDatabaseFromV13(dbHandle)
+}
+
+func updatePGSQLDatabaseFromV13(dbHandle *sql.DB) error {
+	if err := updatePGSQLDatabaseFrom13To14(dbHandle); err != nil {
+		return err
+	}
+	return updatePGSQLDatabaseFromV14(dbHandle)
+}
+
+func updatePGSQLDatabaseFromV14(dbHandle *sql.DB) error {
+	if err := updatePGSQLDatabaseFrom14To15(dbHandle); err != nil {
+		return err
+	}
+	return updatePGSQLDatabaseFromV15(dbHandle)
+}
+
+func updatePGSQLDatabaseFromV15(dbHandle *sql.DB) error {
+	if err := updatePGSQLDatabaseFrom15To16(dbHandle); err != nil {
+		return err
+	}
+	return updatePGSQLDatabaseFromV16(dbHandle)
+}
+
+func updatePGSQLDatabaseFromV16(dbHandle *sql.DB) error {
+	if err := updatePGSQLDatabaseFrom16To17(dbHandle); err != nil {
+		return err
+	}
+	return updatePGSQLDatabaseFromV17(dbHandle)
+}
+
+func updatePGSQLDatabaseFromV17(dbHandle *sql.DB) error {
+	if err := updatePGSQLDatabaseFrom17To18(dbHandle); err != nil {
+		return err
+	}
+	return updatePGSQLDatabaseFromV18(dbHandle)
+}
+
+func updatePGSQLDatabaseFromV18(dbHandle *sql.DB) error {
+	if err := updatePGSQLDatabaseFrom18To19(dbHandle); err != nil {
+		return err
+	}
+	return updatePGSQLDatabaseFromV19(dbHandle)
+}
+
+func updatePGSQLDatabaseFromV19(dbHandle *sql.DB) error {
+	if err := updatePGSQLDatabaseFrom19To20(dbHandle); err != nil {
+		return err
+	}
+	return updatePGSQLDatabaseFromV20(dbHandle)
+}
+
+func updatePGSQLDatabaseFromV20(dbHandle *sql.DB) error {
+	if err := updatePGSQLDatabaseFrom20To21(dbHandle); err != nil {
+		return err
+	}
+	return updatePGSQLDatabaseFromV21(dbHandle)
+}
+
+func updatePGSQLDatabaseFromV21(dbHandle *sql.DB) error {
+	if err := updatePGSQLDatabaseFrom21To22(dbHandle); err != nil {
+		return err
+	}
+	return updatePGSQLDatabaseFromV22(dbHandle)
+}
+
+func updatePGSQLDatabaseFromV22(dbHandle *sql.DB) error {
+	if err := updatePGSQLDatabaseFrom22To23(dbHandle); err != nil {
+		return err
+	}
+	return updatePGSQLDatabaseFromV23(dbHandle)
+}
+
+func updatePGSQLDatabaseFromV23(dbHandle *sql.DB) error {
+	if err := updatePGSQLDatabaseFrom23To24(dbHandle); err != nil {
+		return err
+	}
+	return updatePGSQLDatabaseFromV24(dbHandle)
+}
+
+func updatePGSQLDatabaseFromV24(dbHandle *sql.DB) error {
+	if err := updatePGSQLDatabaseFrom24To25(dbHandle); err != nil {
+		return err
+	}
+	return updatePGSQLDatabaseFromV25(dbHandle)
+}
+
+func updatePGSQLDatabaseFromV25(dbHandle *sql.DB) error {
+	if err := updatePGSQLDatabaseFrom25To26(dbHandle); err != nil {
+		return err
+	}
+	return updatePGSQLDatabaseFromV26(dbHandle)
+}
+
+func updatePGSQLDatabaseFromV26(dbHandle *sql.DB) error {
+	if err := updatePGSQLDatabaseFrom26To27(dbHandle); err != nil {
+		return err
+	}
+	return updatePGSQLDatabaseFromV27(dbHandle)
+}
+
+func updatePGSQLDatabaseFromV27(dbHandle *sql.DB) error {
+	if err := updatePGSQLDatabaseFrom27To28(dbHandle); err != nil {
+		return err
+	}
+	return updatePGSQLDatabaseFromV28(dbHandle)
+}
+
+func updatePGSQLDatabaseFromV28(dbHandle *sql.DB) error {
+	return updatePGSQLDatabaseFrom28To29(dbHandle)
+}
+
+func downgradePGSQLDatabaseFromV29(dbHandle *sql.DB) error {
+	if err := downgradePGSQLDatabaseFrom29To28(dbHandle); err != nil {
+		return err
+	}
+	return downgradePGSQLDatabaseFromV28(dbHandle)
+}
+
+func downgradePGSQLDatabaseFromV28(dbHandle *sql.DB) error {
+	if err := downgradePGSQLDatabaseFrom28To27(dbHandle); err != nil {
+		return err
+	}
+	return downgradePGSQLDatabaseFromV27(dbHandle)
+}
+
+func downgradePGSQLDatabaseFromV27(dbHandle *sql.DB) error {
+	if err := downgradePGSQLDatabaseFrom27To26(dbHandle); err != nil {
+		return err
+	}
+	return downgradePGSQLDatabaseFromV26(dbHandle)
+}
+
+func downgradePGSQLDatabaseFromV26(dbHandle *sql.DB) error {
+	if err := downgradePGSQLDatabaseFrom26To25(dbHandle); err != nil {
+		return err
+	}
+	return downgradePGSQLDatabaseFromV25(dbHandle)
+}
+
+func downgradePGSQLDatabaseFromV25(dbHandle *sql.DB) error {
+	if err := downgradePGSQLDatabaseFrom25To24(dbHandle); err != nil {
+		return err
+	}
+	return downgradePGSQLDatabaseFromV24(dbHandle)
+}
+
+func downgradePGSQLDatabaseFromV24(dbHandle *sql.DB) error {
+	if err := downgradePGSQLDatabaseFrom24To23(dbHandle); err != nil {
+		return err
+	}
+	return downgradePGSQLDatabaseFromV23(dbHandle)
+}
+
+func downgradePGSQLDatabaseFromV23(dbHandle *sql.DB) error {
+	if err := downgradePGSQLDatabaseFrom23To22(dbHandle); err != nil {
+		return err
+	}
+	return downgradePGSQLDatabaseFromV22(dbHandle)
+}
+
+func downgradePGSQLDatabaseFromV22(dbHandle *sql.DB) error {
+	if err := downgradePGSQLDatabaseFrom22To21(dbHandle); err != nil {
+		return err
+	}
+	return downgradePGSQLDatabaseFromV21(dbHandle)
+}
+
+func downgradePGSQLDatabaseFromV21(dbHandle *sql.DB) error {
+	if err := downgradePGSQLDatabaseFrom21To20(dbHandle); err != nil {
+		return err
+	}
+	return downgradePGSQLDatabaseFromV20(dbHandle)
+}
+
+func downgradePGSQLDatabaseFromV20(dbHandle *sql.DB) error {
+	if err := downgradePGSQLDatabaseFrom20To19(dbHandle); err != nil {
+		return err
+	}
+	return downgradePGSQLDatabaseFromV19(dbHandle)
+}
+
+func downgradePGSQLDatabaseFromV19(dbHandle *sql.DB) error {
+	if err := downgradePGSQLDatabaseFrom19To18(dbHandle); err != nil {
+		return err
+	}
+	return downgradePGSQLDatabaseFromV18(dbHandle)
+}
+
+func downgradePGSQLDatabaseFromV18(dbHandle *sql.DB) error {
+	if err := downgradePGSQLDatabaseFrom18To17(dbHandle); err != nil {
+		return err
+	}
+	return downgradePGSQLDatabaseFromV17(dbHandle)
+}
+
+func downgradePGSQLDatabaseFromV17(dbHandle *sql.DB) error {
+	if err := downgradePGSQLDatabaseFrom17To16(dbHandle); err != nil {
+		return err
+	}
+	return downgradePGSQLDatabaseFromV16(dbHandle)
+}
+
+func downgradePGSQLDatabaseFromV16(dbHandle *sql.DB) error {
+	if err := downgradePGSQLDatabaseFrom16To15(dbHandle); err != nil {
+		return err
+	}
+	return downgradePGSQLDatabaseFromV15(dbHandle)
+}
+
+func downgradePGSQLDatabaseFromV15(dbHandle *sql.DB) error {
+	if err := downgradePGSQLDatabaseFrom15To14(dbHandle); err != nil {
+		return err
+	}
+	return downgradePGSQLDatabaseFromV14(dbHandle)
+}
+
+func downgradePGSQLDatabaseFromV14(dbHandle *sql.DB) error {
+	if err := downgradePGSQLDatabaseFrom14To13(dbHandle); err != nil {
+		return err
+	}
+	return downgradePGSQLDatabaseFromV13(dbHandle)
 }
 
 func downgradePGSQLDatabaseFromV13(dbHandle *sql.DB) error {
@@ -399,6 +1174,255 @@ func downgradePGSQLDatabaseFromV11(dbHandle *sql.DB) error {
 	return downgradePGSQLDatabaseFrom11To10(dbHandle)
 }
 
+func updatePGSQLDatabaseFrom28To29(dbHandle *sql.DB) error {
+	logger.InfoToConsole("updating database version: 28 -> 29")
+	providerLog(logger.LevelInfo, "updating database version: 28 -> 29")
+	sql := strings.ReplaceAll(pgsqlV29SQL, "{{multipart_uploads}}", sqlTableMultipartUploads)
+	sql = strings.ReplaceAll(sql, "{{prefix}}", config.SQLTablesPrefix)
+	return sqlCommonExecSQLAndUpdateDBVersion(dbHandle, []string{sql}, 29)
+}
+
+func downgradePGSQLDatabaseFrom29To28(dbHandle *sql.DB) error {
+	logger.InfoToConsole("downgrading database version: 29 -> 28")
+	providerLog(logger.LevelInfo, "downgrading database version: 29 -> 28")
+	sql := strings.ReplaceAll(pgsqlV29DownSQL, "{{multipart_uploads}}", sqlTableMultipartUploads)
+	return sqlCommonExecSQLAndUpdateDBVersion(dbHandle, []string{sql}, 28)
+}
+
+func updatePGSQLDatabaseFrom27To28(dbHandle *sql.DB) error {
+	logger.InfoToConsole("updating database version: 27 -> 28")
+	providerLog(logger.LevelInfo, "updating database version: 27 -> 28")
+	sql := strings.ReplaceAll(pgsqlV28SQL, "{{reset_codes}}", sqlTableResetCodes)
+	sql = strings.ReplaceAll(sql, "{{prefix}}", config.SQLTablesPrefix)
+	return sqlCommonExecSQLAndUpdateDBVersion(dbHandle, []string{sql}, 28)
+}
+
+func downgradePGSQLDatabaseFrom28To27(dbHandle *sql.DB) error {
+	logger.InfoToConsole("downgrading database version: 28 -> 27")
+	providerLog(logger.LevelInfo, "downgrading database version: 28 -> 27")
+	sql := strings.ReplaceAll(pgsqlV28DownSQL, "{{reset_codes}}", sqlTableResetCodes)
+	return sqlCommonExecSQLAndUpdateDBVersion(dbHandle, []string{sql}, 27)
+}
+
+func updatePGSQLDatabaseFrom26To27(dbHandle *sql.DB) error {
+	logger.InfoToConsole("updating database version: 26 -> 27")
+	providerLog(logger.LevelInfo, "updating database version: 26 -> 27")
+	sql := strings.ReplaceAll(pgsqlV27SQL, "{{tus_uploads}}", sqlTableTusUploads)
+	sql = strings.ReplaceAll(sql, "{{prefix}}", config.SQLTablesPrefix)
+	return sqlCommonExecSQLAndUpdateDBVersion(dbHandle, []string{sql}, 27)
+}
+
+func downgradePGSQLDatabaseFrom27To26(dbHandle *sql.DB) error {
+	logger.InfoToConsole("downgrading database version: 27 -> 26")
+	providerLog(logger.LevelInfo, "downgrading database version: 27 -> 26")
+	sql := strings.ReplaceAll(pgsqlV27DownSQL, "{{tus_uploads}}", sqlTableTusUploads)
+	return sqlCommonExecSQLAndUpdateDBVersion(dbHandle, []string{sql}, 26)
+}
+
+func updatePGSQLDatabaseFrom25To26(dbHandle *sql.DB) error {
+	logger.InfoToConsole("updating database version: 25 -> 26")
+	providerLog(logger.LevelInfo, "updating database version: 25 -> 26")
+	sql := strings.ReplaceAll(pgsqlV26SQL, "{{s3_access_keys}}", sqlTableS3AccessKeys)
+	sql = strings.ReplaceAll(sql, "{{prefix}}", config.SQLTablesPrefix)
+	return sqlCommonExecSQLAndUpdateDBVersion(dbHandle, []string{sql}, 26)
+}
+
+func downgradePGSQLDatabaseFrom26To25(dbHandle *sql.DB) error {
+	logger.InfoToConsole("downgrading database version: 26 -> 25")
+	providerLog(logger.LevelInfo, "downgrading database version: 26 -> 25")
+	sql := strings.ReplaceAll(pgsqlV26DownSQL, "{{s3_access_keys}}", sqlTableS3AccessKeys)
+	return sqlCommonExecSQLAndUpdateDBVersion(dbHandle, []string{sql}, 25)
+}
+
+func updatePGSQLDatabaseFrom24To25(dbHandle *sql.DB) error {
+	logger.InfoToConsole("updating database version: 24 -> 25")
+	providerLog(logger.LevelInfo, "updating database version: 24 -> 25")
+	sql := strings.ReplaceAll(pgsqlV25SQL, "{{webdav_properties}}", sqlTableWebDAVProperties)
+	sql = strings.ReplaceAll(sql, "{{prefix}}", config.SQLTablesPrefix)
+	return sqlCommonExecSQLAndUpdateDBVersion(dbHandle, []string{sql}, 25)
+}
+
+func downgradePGSQLDatabaseFrom25To24(dbHandle *sql.DB) error {
+	logger.InfoToConsole("downgrading database version: 25 -> 24")
+	providerLog(logger.LevelInfo, "downgrading database version: 25 -> 24")
+	sql := strings.ReplaceAll(pgsqlV25DownSQL, "{{webdav_properties}}", sqlTableWebDAVProperties)
+	return sqlCommonExecSQLAndUpdateDBVersion(dbHandle, []string{sql}, 24)
+}
+
+func updatePGSQLDatabaseFrom23To24(dbHandle *sql.DB) error {
+	logger.InfoToConsole("updating database version: 23 -> 24")
+	providerLog(logger.LevelInfo, "updating database version: 23 -> 24")
+	sql := strings.ReplaceAll(pgsqlV24SQL, "{{webdav_locks}}", sqlTableWebDAVLocks)
+	sql = strings.ReplaceAll(sql, "{{prefix}}", config.SQLTablesPrefix)
+	return sqlCommonExecSQLAndUpdateDBVersion(dbHandle, []string{sql}, 24)
+}
+
+func downgradePGSQLDatabaseFrom24To23(dbHandle *sql.DB) error {
+	logger.InfoToConsole("downgrading database version: 24 -> 23")
+	providerLog(logger.LevelInfo, "downgrading database version: 24 -> 23")
+	sql := strings.ReplaceAll(pgsqlV24DownSQL, "{{webdav_locks}}", sqlTableWebDAVLocks)
+	return sqlCommonExecSQLAndUpdateDBVersion(dbHandle, []string{sql}, 23)
+}
+
+func updatePGSQLDatabaseFrom22To23(dbHandle *sql.DB) error {
+	logger.InfoToConsole("updating database version: 22 -> 23")
+	providerLog(logger.LevelInfo, "updating database version: 22 -> 23")
+	sql := strings.ReplaceAll(pgsqlV23SQL, "{{folders}}", sqlTableFolders)
+	return sqlCommonExecSQLAndUpdateDBVersion(dbHandle, []string{sql}, 23)
+}
+
+func downgradePGSQLDatabaseFrom23To22(dbHandle *sql.DB) error {
+	logger.InfoToConsole("downgrading database version: 23 -> 22")
+	providerLog(logger.LevelInfo, "downgrading database version: 23 -> 22")
+	sql := strings.ReplaceAll(pgsqlV23DownSQL, "{{folders}}", sqlTableFolders)
+	return sqlCommonExecSQLAndUpdateDBVersion(dbHandle, []string{sql}, 22)
+}
+
+func updatePGSQLDatabaseFrom21To22(dbHandle *sql.DB) error {
+	logger.InfoToConsole("updating database version: 21 -> 22")
+	providerLog(logger.LevelInfo, "updating database version: 21 -> 22")
+	sql := strings.ReplaceAll(pgsqlV22SQL, "{{schedules}}", sqlTableSchedules)
+	return sqlCommonExecSQLAndUpdateDBVersion(dbHandle, []string{sql}, 22)
+}
+
+func downgradePGSQLDatabaseFrom22To21(dbHandle *sql.DB) error {
+	logger.InfoToConsole("downgrading database version: 22 -> 21")
+	providerLog(logger.LevelInfo, "downgrading database version: 22 -> 21")
+	sql := strings.ReplaceAll(pgsqlV22DownSQL, "{{schedules}}", sqlTableSchedules)
+	return sqlCommonExecSQLAndUpdateDBVersion(dbHandle, []string{sql}, 21)
+}
+
+func updatePGSQLDatabaseFrom20To21(dbHandle *sql.DB) error {
+	logger.InfoToConsole("updating database version: 20 -> 21")
+	providerLog(logger.LevelInfo, "updating database version: 20 -> 21")
+	sql := strings.ReplaceAll(pgsqlV21SQL, "{{events_rules}}", sqlTableEventsRules)
+	return sqlCommonExecSQLAndUpdateDBVersion(dbHandle, []string{sql}, 21)
+}
+
+func downgradePGSQLDatabaseFrom21To20(dbHandle *sql.DB) error {
+	logger.InfoToConsole("downgrading database version: 21 -> 20")
+	providerLog(logger.LevelInfo, "downgrading database version: 21 -> 20")
+	sql := strings.ReplaceAll(pgsqlV21DownSQL, "{{events_rules}}", sqlTableEventsRules)
+	return sqlCommonExecSQLAndUpdateDBVersion(dbHandle, []string{sql}, 20)
+}
+
+func updatePGSQLDatabaseFrom19To20(dbHandle *sql.DB) error {
+	logger.InfoToConsole("updating database version: 19 -> 20")
+	providerLog(logger.LevelInfo, "updating database version: 19 -> 20")
+	sql := strings.ReplaceAll(pgsqlV20SQL, "{{fs_events}}", sqlTableFsEvents)
+	sql = strings.ReplaceAll(sql, "{{provider_events}}", sqlTableProviderEvents)
+	sql = strings.ReplaceAll(sql, "{{prefix}}", config.SQLTablesPrefix)
+	return sqlCommonExecSQLAndUpdateDBVersion(dbHandle, []string{sql}, 20)
+}
+
+func downgradePGSQLDatabaseFrom20To19(dbHandle *sql.DB) error {
+	logger.InfoToConsole("downgrading database version: 20 -> 19")
+	providerLog(logger.LevelInfo, "downgrading database version: 20 -> 19")
+	sql := strings.ReplaceAll(pgsqlV20DownSQL, "{{fs_events}}", sqlTableFsEvents)
+	sql = strings.ReplaceAll(sql, "{{provider_events}}", sqlTableProviderEvents)
+	return sqlCommonExecSQLAndUpdateDBVersion(dbHandle, []string{sql}, 19)
+}
+
+func updatePGSQLDatabaseFrom18To19(dbHandle *sql.DB) error {
+	logger.InfoToConsole("updating database version: 18 -> 19")
+	providerLog(logger.LevelInfo, "updating database version: 18 -> 19")
+	sql := strings.ReplaceAll(pgsqlV19SQL, "{{account_lockouts}}", sqlTableAccountLockouts)
+	sql = strings.ReplaceAll(sql, "{{prefix}}", config.SQLTablesPrefix)
+	return sqlCommonExecSQLAndUpdateDBVersion(dbHandle, []string{sql}, 19)
+}
+
+func downgradePGSQLDatabaseFrom19To18(dbHandle *sql.DB) error {
+	logger.InfoToConsole("downgrading database version: 19 -> 18")
+	providerLog(logger.LevelInfo, "downgrading database version: 19 -> 18")
+	sql := strings.ReplaceAll(pgsqlV19DownSQL, "{{account_lockouts}}", sqlTableAccountLockouts)
+	return sqlCommonExecSQLAndUpdateDBVersion(dbHandle, []string{sql}, 18)
+}
+
+func updatePGSQLDatabaseFrom17To18(dbHandle *sql.DB) error {
+	logger.InfoToConsole("updating database version: 17 -> 18")
+	providerLog(logger.LevelInfo, "updating database version: 17 -> 18")
+	sql := strings.ReplaceAll(pgsqlV18SQL, "{{users}}", sqlTableUsers)
+	sql = strings.ReplaceAll(sql, "{{admins}}", sqlTableAdmins)
+	return sqlCommonExecSQLAndUpdateDBVersion(dbHandle, []string{sql}, 18)
+}
+
+func downgradePGSQLDatabaseFrom18To17(dbHandle *sql.DB) error {
+	logger.InfoToConsole("downgrading database version: 18 -> 17")
+	providerLog(logger.LevelInfo, "downgrading database version: 18 -> 17")
+	sql := strings.ReplaceAll(pgsqlV18DownSQL, "{{users}}", sqlTableUsers)
+	sql = strings.ReplaceAll(sql, "{{admins}}", sqlTableAdmins)
+	return sqlCommonExecSQLAndUpdateDBVersion(dbHandle, []string{sql}, 17)
+}
+
+func updatePGSQLDatabaseFrom16To17(dbHandle *sql.DB) error {
+	logger.InfoToConsole("updating database version: 16 -> 17")
+	providerLog(logger.LevelInfo, "updating database version: 16 -> 17")
+	sql := strings.ReplaceAll(pgsqlV17SQL, "{{users}}", sqlTableUsers)
+	sql = strings.ReplaceAll(sql, "{{folders}}", sqlTableFolders)
+	return sqlCommonExecSQLAndUpdateDBVersion(dbHandle, []string{sql}, 17)
+}
+
+func downgradePGSQLDatabaseFrom17To16(dbHandle *sql.DB) error {
+	logger.InfoToConsole("downgrading database version: 17 -> 16")
+	providerLog(logger.LevelInfo, "downgrading database version: 17 -> 16")
+	sql := strings.ReplaceAll(pgsqlV17DownSQL, "{{users}}", sqlTableUsers)
+	sql = strings.ReplaceAll(sql, "{{folders}}", sqlTableFolders)
+	return sqlCommonExecSQLAndUpdateDBVersion(dbHandle, []string{sql}, 16)
+}
+
+func updatePGSQLDatabaseFrom15To16(dbHandle *sql.DB) error {
+	logger.InfoToConsole("updating database version: 15 -> 16")
+	providerLog(logger.LevelInfo, "updating database version: 15 -> 16")
+	sql := strings.ReplaceAll(pgsqlV16SQL, "{{groups_folders_mapping}}", sqlTableGroupsFoldersMapping)
+	sql = strings.ReplaceAll(sql, "{{groups_mapping}}", sqlTableGroupsMapping)
+	sql = strings.ReplaceAll(sql, "{{groups}}", sqlTableGroups)
+	sql = strings.ReplaceAll(sql, "{{folders}}", sqlTableFolders)
+	sql = strings.ReplaceAll(sql, "{{users}}", sqlTableUsers)
+	sql = strings.ReplaceAll(sql, "{{prefix}}", config.SQLTablesPrefix)
+	return sqlCommonExecSQLAndUpdateDBVersion(dbHandle, []string{sql}, 16)
+}
+
+func downgradePGSQLDatabaseFrom16To15(dbHandle *sql.DB) error {
+	logger.InfoToConsole("downgrading database version: 16 -> 15")
+	providerLog(logger.LevelInfo, "downgrading database version: 16 -> 15")
+	sql := strings.ReplaceAll(pgsqlV16DownSQL, "{{groups_folders_mapping}}", sqlTableGroupsFoldersMapping)
+	sql = strings.ReplaceAll(sql, "{{groups_mapping}}", sqlTableGroupsMapping)
+	sql = strings.ReplaceAll(sql, "{{groups}}", sqlTableGroups)
+	return sqlCommonExecSQLAndUpdateDBVersion(dbHandle, []string{sql}, 15)
+}
+
+func updatePGSQLDatabaseFrom14To15(dbHandle *sql.DB) error {
+	logger.InfoToConsole("updating database version: 14 -> 15")
+	providerLog(logger.LevelInfo, "updating database version: 14 -> 15")
+	sql := strings.ReplaceAll(pgsqlV15SQL, "{{shares}}", sqlTableShares)
+	sql = strings.ReplaceAll(sql, "{{share_uploads}}", sqlTableShareUploads)
+	sql = strings.ReplaceAll(sql, "{{prefix}}", config.SQLTablesPrefix)
+	return sqlCommonExecSQLAndUpdateDBVersion(dbHandle, []string{sql}, 15)
+}
+
+func downgradePGSQLDatabaseFrom15To14(dbHandle *sql.DB) error {
+	logger.InfoToConsole("downgrading database version: 15 -> 14")
+	providerLog(logger.LevelInfo, "downgrading database version: 15 -> 14")
+	sql := strings.ReplaceAll(pgsqlV15DownSQL, "{{share_uploads}}", sqlTableShareUploads)
+	return sqlCommonExecSQLAndUpdateDBVersion(dbHandle, []string{sql}, 14)
+}
+
+func updatePGSQLDatabaseFrom13To14(dbHandle *sql.DB) error {
+	logger.InfoToConsole("updating database version: 13 -> 14")
+	providerLog(logger.LevelInfo, "updating database version: 13 -> 14")
+	sql := strings.ReplaceAll(pgsqlV14SQL, "{{users}}", sqlTableUsers)
+	sql = strings.ReplaceAll(sql, "{{shares}}", sqlTableShares)
+	sql = strings.ReplaceAll(sql, "{{prefix}}", config.SQLTablesPrefix)
+	return sqlCommonExecSQLAndUpdateDBVersion(dbHandle, []string{sql}, 14)
+}
+
+func downgradePGSQLDatabaseFrom14To13(dbHandle *sql.DB) error {
+	logger.InfoToConsole("downgrading database version: 14 -> 13")
+	providerLog(logger.LevelInfo, "downgrading database version: 14 -> 13")
+	sql := strings.ReplaceAll(pgsqlV14DownSQL, "{{shares}}", sqlTableShares)
+	return sqlCommonExecSQLAndUpdateDBVersion(dbHandle, []string{sql}, 13)
+}
+
 func updatePGSQLDatabaseFrom12To13(dbHandle *sql.DB) error {
 	logger.InfoToConsole("updating database version: 12 -> 13")
 	providerLog(logger.LevelInfo, "updating database version: 12 -> 13")
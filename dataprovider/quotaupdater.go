@@ -18,18 +18,31 @@ type quotaObject struct {
 	files int
 }
 
+// transferQuotaObject stores the pending data transfer updates.
+// periodStart is the start of the data transfer reset period the
+// pending updates refer to
+type transferQuotaObject struct {
+	ulSize      int64
+	dlSize      int64
+	periodStart int64
+}
+
 type quotaUpdater struct {
 	paramsMutex sync.RWMutex
 	waitTime    time.Duration
 	sync.RWMutex
-	pendingUserQuotaUpdates   map[string]quotaObject
-	pendingFolderQuotaUpdates map[string]quotaObject
+	pendingUserQuotaUpdates           map[string]quotaObject
+	pendingFolderQuotaUpdates         map[string]quotaObject
+	pendingUserTransferQuotaUpdates   map[string]transferQuotaObject
+	pendingFolderTransferQuotaUpdates map[string]transferQuotaObject
 }
 
 func newQuotaUpdater() quotaUpdater {
 	return quotaUpdater{
-		pendingUserQuotaUpdates:   make(map[string]quotaObject),
-		pendingFolderQuotaUpdates: make(map[string]quotaObject),
+		pendingUserQuotaUpdates:           make(map[string]quotaObject),
+		pendingFolderQuotaUpdates:         make(map[string]quotaObject),
+		pendingUserTransferQuotaUpdates:   make(map[string]transferQuotaObject),
+		pendingFolderTransferQuotaUpdates: make(map[string]transferQuotaObject),
 	}
 }
 
@@ -50,6 +63,8 @@ func (q *quotaUpdater) loop() {
 		providerLog(logger.LevelDebug, "delayed quota update check start")
 		q.storeUsersQuota()
 		q.storeFoldersQuota()
+		q.storeUsersTransferQuota()
+		q.storeFoldersTransferQuota()
 		providerLog(logger.LevelDebug, "delayed quota update check end")
 		waitTime = q.getWaitTime()
 	}
@@ -181,3 +196,122 @@ func (q *quotaUpdater) storeFoldersQuota() {
 		}
 	}
 }
+
+func (q *quotaUpdater) resetUserTransferQuota(username string) {
+	q.Lock()
+	defer q.Unlock()
+
+	delete(q.pendingUserTransferQuotaUpdates, username)
+}
+
+func (q *quotaUpdater) updateUserTransferQuota(username string, ulSize, dlSize, periodStart int64) {
+	q.Lock()
+	defer q.Unlock()
+
+	updatePendingTransferQuota(q.pendingUserTransferQuotaUpdates, username, ulSize, dlSize, periodStart)
+}
+
+func (q *quotaUpdater) getUserPendingTransferQuota(username string) transferQuotaObject {
+	q.RLock()
+	defer q.RUnlock()
+
+	return q.pendingUserTransferQuotaUpdates[username]
+}
+
+func (q *quotaUpdater) resetFolderTransferQuota(name string) {
+	q.Lock()
+	defer q.Unlock()
+
+	delete(q.pendingFolderTransferQuotaUpdates, name)
+}
+
+func (q *quotaUpdater) updateFolderTransferQuota(name string, ulSize, dlSize, periodStart int64) {
+	q.Lock()
+	defer q.Unlock()
+
+	updatePendingTransferQuota(q.pendingFolderTransferQuotaUpdates, name, ulSize, dlSize, periodStart)
+}
+
+func (q *quotaUpdater) getFolderPendingTransferQuota(name string) transferQuotaObject {
+	q.RLock()
+	defer q.RUnlock()
+
+	return q.pendingFolderTransferQuotaUpdates[name]
+}
+
+func (q *quotaUpdater) getTransferQuotaUsernames() []string {
+	q.RLock()
+	defer q.RUnlock()
+
+	result := make([]string, 0, len(q.pendingUserTransferQuotaUpdates))
+	for username := range q.pendingUserTransferQuotaUpdates {
+		result = append(result, username)
+	}
+
+	return result
+}
+
+func (q *quotaUpdater) getTransferQuotaFoldernames() []string {
+	q.RLock()
+	defer q.RUnlock()
+
+	result := make([]string, 0, len(q.pendingFolderTransferQuotaUpdates))
+	for name := range q.pendingFolderTransferQuotaUpdates {
+		result = append(result, name)
+	}
+
+	return result
+}
+
+func (q *quotaUpdater) storeUsersTransferQuota() {
+	for _, username := range q.getTransferQuotaUsernames() {
+		obj := q.getUserPendingTransferQuota(username)
+		if obj.ulSize != 0 || obj.dlSize != 0 {
+			err := provider.updateTransferQuota(username, obj.ulSize, obj.dlSize, false, obj.periodStart)
+			if err != nil {
+				providerLog(logger.LevelWarn, "unable to update transfer quota delayed for user %#v: %v", username, err)
+				continue
+			}
+			q.updateUserTransferQuota(username, -obj.ulSize, -obj.dlSize, obj.periodStart)
+		}
+	}
+}
+
+func (q *quotaUpdater) storeFoldersTransferQuota() {
+	for _, name := range q.getTransferQuotaFoldernames() {
+		obj := q.getFolderPendingTransferQuota(name)
+		if obj.ulSize != 0 || obj.dlSize != 0 {
+			err := provider.updateFolderTransferQuota(name, obj.ulSize, obj.dlSize, false, obj.periodStart)
+			if err != nil {
+				providerLog(logger.LevelWarn, "unable to update transfer quota delayed for folder %#v: %v", name, err)
+				continue
+			}
+			q.updateFolderTransferQuota(name, -obj.ulSize, -obj.dlSize, obj.periodStart)
+		}
+	}
+}
+
+// updatePendingTransferQuota adds the given sizes to the pending updates.
+// If a new reset period is started the updates for the previous period are
+// discarded, the stored values will be reset anyway. Updates for a previous
+// period, for example the ones used to subtract already stored values, are
+// ignored if a new period is started in the meantime
+func updatePendingTransferQuota(pendingUpdates map[string]transferQuotaObject, key string,
+	ulSize, dlSize, periodStart int64) {
+	obj, ok := pendingUpdates[key]
+	if ok && periodStart < obj.periodStart {
+		return
+	}
+	if periodStart > obj.periodStart {
+		obj.ulSize = 0
+		obj.dlSize = 0
+		obj.periodStart = periodStart
+	}
+	obj.ulSize += ulSize
+	obj.dlSize += dlSize
+	if obj.ulSize == 0 && obj.dlSize == 0 {
+		delete(pendingUpdates, key)
+		return
+	}
+	pendingUpdates[key] = obj
+}
@@ -0,0 +1,76 @@
+package dataprovider
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/drakkan/sftpgo/v2/util"
+)
+
+// ResetCode defines a password reset code stored within the data provider.
+// Storing the codes in the data provider allows to use them after a restart
+// or using a different SFTPGo instance
+type ResetCode struct {
+	// Hash of the code sent via email, the code itself is never stored
+	Code string `json:"code"`
+	// Username of the account the code was issued for
+	Username string `json:"username"`
+	// Account type, "user" or "admin"
+	Type string `json:"type"`
+	// Fingerprint of the password hash at issue time, a password change
+	// invalidates the code
+	PasswordFingerprint string `json:"password_fingerprint"`
+	// Code expiration as unix timestamp in milliseconds
+	ExpiresAt int64 `json:"expires_at"`
+	// Creation time as unix timestamp in milliseconds
+	CreatedAt int64 `json:"created_at"`
+}
+
+// IsExpired returns true if the code is expired at the given time
+func (c *ResetCode) IsExpired(now time.Time) bool {
+	return c.ExpiresAt <= util.GetTimeAsMsSinceEpoch(now)
+}
+
+func (c *ResetCode) validate() error {
+	if c.Code == "" {
+		return util.NewValidationError("code is mandatory")
+	}
+	if c.Username == "" {
+		return util.NewValidationError("username is mandatory")
+	}
+	if !util.IsStringInSlice(c.Type, []string{AccountTypeUser, AccountTypeAdmin}) {
+		return util.NewValidationError(fmt.Sprintf("invalid account type: %#v", c.Type))
+	}
+	if c.PasswordFingerprint == "" {
+		return util.NewValidationError("password fingerprint is mandatory")
+	}
+	if c.ExpiresAt == 0 || c.CreatedAt == 0 {
+		return util.NewValidationError("creation and expiration time are mandatory")
+	}
+	return nil
+}
+
+// AddResetCode stores a new password reset code
+func AddResetCode(code *ResetCode) error {
+	return provider.addResetCode(code)
+}
+
+// GetResetCode returns the password reset code with the given hash, expired codes are returned too
+func GetResetCode(code string) (ResetCode, error) {
+	return provider.resetCodeExists(code)
+}
+
+// DeleteResetCode removes the password reset code with the given hash
+func DeleteResetCode(code string) error {
+	return provider.deleteResetCode(code)
+}
+
+// GetPendingResetCodesCount returns the number of not expired password reset codes for the specified account
+func GetPendingResetCodesCount(username, accountType string) (int, error) {
+	return provider.getResetCodesCount(username, accountType, util.GetTimeAsMsSinceEpoch(time.Now()))
+}
+
+// DeleteExpiredResetCodes removes the password reset codes expired at the given time
+func DeleteExpiredResetCodes(now time.Time) error {
+	return provider.deleteExpiredResetCodes(util.GetTimeAsMsSinceEpoch(now))
+}
@@ -0,0 +1,203 @@
+package dataprovider
+
+import (
+	"encoding/base32"
+	"encoding/base64"
+	"fmt"
+	"time"
+
+	"github.com/drakkan/sftpgo/v2/kms"
+	"github.com/drakkan/sftpgo/v2/logger"
+	"github.com/drakkan/sftpgo/v2/util"
+)
+
+const (
+	// maximum number of S3 access keys for each user
+	maxS3AccessKeysPerUser = 10
+)
+
+// S3AccessKey defines an access key used to authenticate a user against the S3 compatible API.
+// The secret access key is required to verify the request signatures, so it is stored
+// encrypted, using the configured KMS, instead of hashed
+type S3AccessKey struct {
+	// Unique access key identifier
+	AccessKeyID string `json:"access_key_id"`
+	// Secret access key, it is never rendered
+	SecretAccessKey *kms.Secret `json:"secret_access_key,omitempty"`
+	// Username of the key owner
+	Username string `json:"username"`
+	// Optional description
+	Description string `json:"description,omitempty"`
+	// Creation time as unix timestamp in milliseconds
+	CreatedAt int64 `json:"created_at"`
+	// the generated secret, it is available only after the key creation
+	plainSecret string
+}
+
+func (k *S3AccessKey) getACopy() S3AccessKey {
+	var secret *kms.Secret
+	if k.SecretAccessKey != nil {
+		secret = k.SecretAccessKey.Clone()
+	}
+	return S3AccessKey{
+		AccessKeyID:     k.AccessKeyID,
+		SecretAccessKey: secret,
+		Username:        k.Username,
+		Description:     k.Description,
+		CreatedAt:       k.CreatedAt,
+	}
+}
+
+// HideConfidentialData hides the secret access key
+func (k *S3AccessKey) HideConfidentialData() {
+	k.SecretAccessKey = nil
+}
+
+// DisplaySecretAccessKey returns the generated secret access key to show to the user.
+// The secret is available only after the key creation
+func (k *S3AccessKey) DisplaySecretAccessKey() string {
+	return k.plainSecret
+}
+
+// generateKey generates a new access key identifier and the encrypted secret access key
+func (k *S3AccessKey) generateKey() error {
+	k.AccessKeyID = base32.StdEncoding.EncodeToString(util.GenerateRandomBytes(15))
+	k.plainSecret = base64.RawStdEncoding.EncodeToString(util.GenerateRandomBytes(30))
+	k.SecretAccessKey = kms.NewPlainSecret(k.plainSecret)
+	k.SecretAccessKey.SetAdditionalData(k.Username)
+	if err := k.SecretAccessKey.Encrypt(); err != nil {
+		return fmt.Errorf("unable to encrypt the secret access key: %w", err)
+	}
+	k.CreatedAt = util.GetTimeAsMsSinceEpoch(time.Now())
+	return nil
+}
+
+func (k *S3AccessKey) validate() error {
+	if k.AccessKeyID == "" {
+		return util.NewValidationError("access key id is mandatory")
+	}
+	if k.Username == "" {
+		return util.NewValidationError("username is mandatory")
+	}
+	if k.SecretAccessKey == nil || !k.SecretAccessKey.IsEncrypted() {
+		return util.NewValidationError("the secret access key must be encrypted")
+	}
+	if k.CreatedAt == 0 {
+		return util.NewValidationError("creation time is mandatory")
+	}
+	return nil
+}
+
+// AddS3AccessKey generates and stores a new S3 access key for the user specified in the given key.
+// The generated secret access key can be displayed, only once, using DisplaySecretAccessKey
+func AddS3AccessKey(key *S3AccessKey) error {
+	if _, err := provider.userExists(key.Username); err != nil {
+		return util.NewValidationError(fmt.Sprintf("unable to check S3 access key user %#v: %v", key.Username, err))
+	}
+	keys, err := provider.getUserS3AccessKeys(key.Username)
+	if err != nil {
+		return err
+	}
+	if len(keys) >= maxS3AccessKeysPerUser {
+		return util.NewValidationError(fmt.Sprintf("the maximum number of S3 access keys, %v, has been reached for user %#v",
+			maxS3AccessKeysPerUser, key.Username))
+	}
+	if err := key.generateKey(); err != nil {
+		return err
+	}
+	return provider.addS3AccessKey(key)
+}
+
+// GetUserS3AccessKeys returns the S3 access keys for the specified user, the secrets are hidden
+func GetUserS3AccessKeys(username string) ([]S3AccessKey, error) {
+	keys, err := provider.getUserS3AccessKeys(username)
+	if err != nil {
+		return nil, err
+	}
+	for idx := range keys {
+		keys[idx].HideConfidentialData()
+	}
+	return keys, nil
+}
+
+// S3AccessKeyExists returns the S3 access key with the given identifier, the secret is hidden
+func S3AccessKeyExists(accessKeyID string) (S3AccessKey, error) {
+	key, err := provider.s3AccessKeyExists(accessKeyID)
+	if err != nil {
+		return key, err
+	}
+	key.HideConfidentialData()
+	return key, nil
+}
+
+// RestoreS3AccessKey adds the given S3 access key, or updates it if an access key with the
+// same identifier already exists. The secret access key is not generated, it is restored
+// from the given key and it is encrypted, using the configured KMS, if it is in plain text
+func RestoreS3AccessKey(key *S3AccessKey) error {
+	if key.AccessKeyID == "" {
+		return util.NewValidationError("access key id is mandatory")
+	}
+	if _, err := provider.userExists(key.Username); err != nil {
+		return util.NewValidationError(fmt.Sprintf("unable to check S3 access key user %#v: %v", key.Username, err))
+	}
+	if key.SecretAccessKey == nil || key.SecretAccessKey.IsEmpty() {
+		return util.NewValidationError("the secret access key is mandatory")
+	}
+	if key.SecretAccessKey.IsPlain() {
+		key.SecretAccessKey.SetAdditionalData(key.Username)
+		if err := key.SecretAccessKey.Encrypt(); err != nil {
+			return fmt.Errorf("unable to encrypt the secret access key: %w", err)
+		}
+	}
+	if key.CreatedAt == 0 {
+		key.CreatedAt = util.GetTimeAsMsSinceEpoch(time.Now())
+	}
+	if _, err := provider.s3AccessKeyExists(key.AccessKeyID); err == nil {
+		return provider.updateS3AccessKey(key)
+	}
+	keys, err := provider.getUserS3AccessKeys(key.Username)
+	if err != nil {
+		return err
+	}
+	if len(keys) >= maxS3AccessKeysPerUser {
+		return util.NewValidationError(fmt.Sprintf("the maximum number of S3 access keys, %v, has been reached for user %#v",
+			maxS3AccessKeysPerUser, key.Username))
+	}
+	return provider.addS3AccessKey(key)
+}
+
+// DeleteS3AccessKey removes the S3 access key with the given identifier for the specified user
+func DeleteS3AccessKey(accessKeyID, username string) error {
+	return provider.deleteS3AccessKey(accessKeyID, username)
+}
+
+// CheckUserAndS3AccessKey returns the user owning the given S3 access key if the verify
+// function, called with the plain secret access key, returns no error.
+// The verify function must return ErrInvalidCredentials if the secret does not match,
+// failed verifications are tracked as failed logins
+func CheckUserAndS3AccessKey(accessKeyID string, verify func(secret string) error) (User, error) {
+	key, err := provider.s3AccessKeyExists(accessKeyID)
+	if err != nil {
+		return User{}, err
+	}
+	user := User{}
+	user.Username = key.Username
+	lockout, err := getAccountLockoutForLogin(key.Username, AccountTypeUser)
+	if err != nil {
+		return user, err
+	}
+	if err := key.SecretAccessKey.TryDecrypt(); err != nil {
+		providerLog(logger.LevelError, "unable to decrypt S3 access key %#v: %v", accessKeyID, err)
+		return user, err
+	}
+	err = verify(key.SecretAccessKey.GetPayload())
+	updateAccountLockout(key.Username, AccountTypeUser, lockout, err)
+	if err != nil {
+		return user, err
+	}
+	user, err = GetUserWithGroupSettings(key.Username)
+	if err != nil {
+		return user, err
+	}
+	return user, user.CheckLoginConditions()
+}
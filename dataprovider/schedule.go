@@ -0,0 +1,331 @@
+package dataprovider
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/drakkan/sftpgo/v2/logger"
+	"github.com/drakkan/sftpgo/v2/sdk"
+	"github.com/drakkan/sftpgo/v2/util"
+)
+
+// ScheduleTask defines the supported scheduled task types
+type ScheduleTask int
+
+// Supported scheduled tasks
+const (
+	// Quota scan for users and/or virtual folders
+	ScheduleTaskQuotaScan ScheduleTask = iota + 1
+	// Data retention check for users
+	ScheduleTaskRetentionCheck
+	// Backup of the data provider to the configured backups path
+	ScheduleTaskBackup
+)
+
+// Supported schedule statuses
+const (
+	ScheduleStatusDisabled = iota
+	ScheduleStatusEnabled
+)
+
+// Supported last run statuses
+const (
+	ScheduleRunStatusNone = iota
+	ScheduleRunStatusRunning
+	ScheduleRunStatusSuccess
+	ScheduleRunStatusFailed
+)
+
+// GetTaskAsString returns the scheduled task as string
+func (t ScheduleTask) GetTaskAsString() string {
+	switch t {
+	case ScheduleTaskQuotaScan:
+		return "Quota scan"
+	case ScheduleTaskRetentionCheck:
+		return "Retention check"
+	case ScheduleTaskBackup:
+		return "Backup"
+	default:
+		return "Unknown"
+	}
+}
+
+// Supported notifications for retention check schedules
+const (
+	ScheduleNotificationHook  = "Hook"
+	ScheduleNotificationEmail = "Email"
+)
+
+// ScheduleOptions defines the targets and the task specific options
+type ScheduleOptions struct {
+	// Run the task for all the users
+	AllUsers bool `json:"all_users,omitempty"`
+	// Run the task for these users
+	Usernames []string `json:"usernames,omitempty"`
+	// Run the task for all the virtual folders, quota scans only
+	AllFolders bool `json:"all_folders,omitempty"`
+	// Run the task for these virtual folders, quota scans only
+	FolderNames []string `json:"folder_names,omitempty"`
+	// Data retention, retention checks only. If empty the data retention
+	// policies stored for each user and its virtual folders are applied
+	Retention []sdk.DataRetentionPolicy `json:"retention,omitempty"`
+	// How to notify the results, retention checks only
+	Notifications []string `json:"notifications,omitempty"`
+	// Email address for the email notifications, retention checks only.
+	// If empty the user email is used
+	Email string `json:"email,omitempty"`
+}
+
+func (o *ScheduleOptions) getACopy() ScheduleOptions {
+	usernames := make([]string, len(o.Usernames))
+	copy(usernames, o.Usernames)
+	folders := make([]string, len(o.FolderNames))
+	copy(folders, o.FolderNames)
+	retention := make([]sdk.DataRetentionPolicy, len(o.Retention))
+	copy(retention, o.Retention)
+	notifications := make([]string, len(o.Notifications))
+	copy(notifications, o.Notifications)
+
+	return ScheduleOptions{
+		AllUsers:      o.AllUsers,
+		Usernames:     usernames,
+		AllFolders:    o.AllFolders,
+		FolderNames:   folders,
+		Retention:     retention,
+		Notifications: notifications,
+		Email:         o.Email,
+	}
+}
+
+// HasNotification returns true if the specified notification is enabled
+func (o *ScheduleOptions) HasNotification(notification string) bool {
+	return util.IsStringInSlice(notification, o.Notifications)
+}
+
+func (o *ScheduleOptions) validateRetention() error {
+	retention, err := validateDataRetentionPolicies(o.Retention)
+	if err != nil {
+		return err
+	}
+	o.Retention = retention
+	if len(o.Retention) > 0 {
+		nothingToDo := true
+		for _, r := range o.Retention {
+			if r.Retention > 0 {
+				nothingToDo = false
+				break
+			}
+		}
+		if nothingToDo {
+			return util.NewValidationError("nothing to delete, all the retention paths are excluded")
+		}
+	}
+	o.Notifications = util.RemoveDuplicates(o.Notifications)
+	for _, notification := range o.Notifications {
+		if notification != ScheduleNotificationHook && notification != ScheduleNotificationEmail {
+			return util.NewValidationError(fmt.Sprintf("invalid notification %#v", notification))
+		}
+	}
+	if !o.HasNotification(ScheduleNotificationEmail) {
+		o.Email = ""
+	}
+	if o.Email != "" && !emailRegex.MatchString(o.Email) {
+		return util.NewValidationError(fmt.Sprintf("email %#v is not valid", o.Email))
+	}
+	return nil
+}
+
+func (o *ScheduleOptions) validate(task ScheduleTask) error {
+	o.Usernames = util.RemoveDuplicates(o.Usernames)
+	o.FolderNames = util.RemoveDuplicates(o.FolderNames)
+	if o.AllUsers {
+		o.Usernames = nil
+	}
+	if o.AllFolders {
+		o.FolderNames = nil
+	}
+	switch task {
+	case ScheduleTaskQuotaScan:
+		o.Retention = nil
+		o.Notifications = nil
+		o.Email = ""
+		if !o.AllUsers && !o.AllFolders && len(o.Usernames) == 0 && len(o.FolderNames) == 0 {
+			return util.NewValidationError("at least a user or a folder is required for quota scans")
+		}
+		return nil
+	case ScheduleTaskRetentionCheck:
+		o.AllFolders = false
+		o.FolderNames = nil
+		if !o.AllUsers && len(o.Usernames) == 0 {
+			return util.NewValidationError("at least a user is required for retention checks")
+		}
+		return o.validateRetention()
+	default:
+		// backups have no options
+		o.AllUsers = false
+		o.Usernames = nil
+		o.AllFolders = false
+		o.FolderNames = nil
+		o.Retention = nil
+		o.Notifications = nil
+		o.Email = ""
+		return nil
+	}
+}
+
+// Schedule defines a recurring maintenance task
+type Schedule struct {
+	// Data provider unique identifier
+	ID int64 `json:"id"`
+	// Schedule name
+	Name string `json:"name"`
+	// optional description
+	Description string `json:"description,omitempty"`
+	// creation time as unix timestamp in milliseconds
+	CreatedAt int64 `json:"created_at"`
+	// last update time as unix timestamp in milliseconds
+	UpdatedAt int64 `json:"updated_at"`
+	// 1 enabled, 0 disabled
+	Status int `json:"status"`
+	// Cron expression, for example "0 2 * * *" to run the task every day at 02:00
+	CronExpression string `json:"cron_expression"`
+	// Task to execute
+	Task ScheduleTask `json:"task"`
+	// Task targets and options
+	Options ScheduleOptions `json:"options"`
+	// Last run start time as unix timestamp in milliseconds, read only
+	LastRun int64 `json:"last_run"`
+	// Last run status, read only
+	LastRunStatus int `json:"last_run_status"`
+	// Last run error, if any, read only
+	LastRunError string `json:"last_run_error,omitempty"`
+}
+
+// GetStatusAsString returns the schedule status as string
+func (s *Schedule) GetStatusAsString() string {
+	if s.Status == ScheduleStatusEnabled {
+		return "Enabled"
+	}
+	return "Disabled"
+}
+
+// GetLastRunStatusAsString returns the last run status as string
+func (s *Schedule) GetLastRunStatusAsString() string {
+	switch s.LastRunStatus {
+	case ScheduleRunStatusRunning:
+		return "Running"
+	case ScheduleRunStatusSuccess:
+		return "Success"
+	case ScheduleRunStatusFailed:
+		return "Failed"
+	default:
+		return "Never run"
+	}
+}
+
+// GetLastRunAsString returns the last run time and status as string
+func (s *Schedule) GetLastRunAsString() string {
+	if s.LastRun == 0 {
+		return s.GetLastRunStatusAsString()
+	}
+	t := util.GetTimeFromMsecSinceEpoch(s.LastRun).UTC()
+	return fmt.Sprintf("%v UTC, %v", t.Format("2006-01-02 15:04"), s.GetLastRunStatusAsString()) // YYYY-MM-DD HH:MM
+}
+
+// GetNextRunAsString returns the next run time as string
+func (s *Schedule) GetNextRunAsString() string {
+	next := s.GetNextRun(time.Now().UTC())
+	if next.IsZero() {
+		return ""
+	}
+	return fmt.Sprintf("%v UTC", next.Format("2006-01-02 15:04")) // YYYY-MM-DD HH:MM
+}
+
+// GetTargetsAsString returns the task targets as string
+func (s *Schedule) GetTargetsAsString() string {
+	var result []string
+	if s.Options.AllUsers {
+		result = append(result, "All users")
+	} else if len(s.Options.Usernames) > 0 {
+		result = append(result, "Users: "+strings.Join(s.Options.Usernames, ","))
+	}
+	if s.Options.AllFolders {
+		result = append(result, "All folders")
+	} else if len(s.Options.FolderNames) > 0 {
+		result = append(result, "Folders: "+strings.Join(s.Options.FolderNames, ","))
+	}
+	return strings.Join(result, ". ")
+}
+
+// GetNextRun returns the next scheduled run after the given time.
+// The zero time is returned if the schedule is disabled or invalid
+func (s *Schedule) GetNextRun(after time.Time) time.Time {
+	if s.Status != ScheduleStatusEnabled {
+		return time.Time{}
+	}
+	cron, err := util.ParseCronExpression(s.CronExpression)
+	if err != nil {
+		return time.Time{}
+	}
+	return cron.Next(after)
+}
+
+// RenderAsJSON implements the renderer interface used within plugins
+func (s *Schedule) RenderAsJSON(reload bool) ([]byte, error) {
+	if reload {
+		schedule, err := provider.scheduleExists(s.Name)
+		if err != nil {
+			providerLog(logger.LevelWarn, "unable to reload schedule before rendering as json: %v", err)
+			return nil, err
+		}
+		return json.Marshal(schedule)
+	}
+	return json.Marshal(s)
+}
+
+func (s *Schedule) validate() error {
+	if s.Name == "" {
+		return util.NewValidationError("schedule name is mandatory")
+	}
+	if !config.SkipNaturalKeysValidation && !usernameRegex.MatchString(s.Name) {
+		return util.NewValidationError(fmt.Sprintf("schedule name %#v is not valid, the following characters are allowed: a-zA-Z0-9-_.~",
+			s.Name))
+	}
+	if s.Status != ScheduleStatusEnabled && s.Status != ScheduleStatusDisabled {
+		return util.NewValidationError(fmt.Sprintf("invalid schedule status: %v", s.Status))
+	}
+	s.CronExpression = strings.Join(strings.Fields(s.CronExpression), " ")
+	if s.CronExpression == "" {
+		return util.NewValidationError("cron expression is mandatory")
+	}
+	cron, err := util.ParseCronExpression(s.CronExpression)
+	if err != nil {
+		return util.NewValidationError(err.Error())
+	}
+	if cron.Next(time.Now()).IsZero() {
+		return util.NewValidationError(fmt.Sprintf("cron expression %#v never runs", s.CronExpression))
+	}
+	if s.Task < ScheduleTaskQuotaScan || s.Task > ScheduleTaskBackup {
+		return util.NewValidationError(fmt.Sprintf("unsupported schedule task: %v", s.Task))
+	}
+	return s.Options.validate(s.Task)
+}
+
+func (s *Schedule) getACopy() Schedule {
+	return Schedule{
+		ID:             s.ID,
+		Name:           s.Name,
+		Description:    s.Description,
+		CreatedAt:      s.CreatedAt,
+		UpdatedAt:      s.UpdatedAt,
+		Status:         s.Status,
+		CronExpression: s.CronExpression,
+		Task:           s.Task,
+		Options:        s.Options.getACopy(),
+		LastRun:        s.LastRun,
+		LastRunStatus:  s.LastRunStatus,
+		LastRunError:   s.LastRunError,
+	}
+}
@@ -12,6 +12,7 @@ import (
 
 	"github.com/cockroachdb/cockroach-go/v2/crdb"
 
+	"github.com/drakkan/sftpgo/v2/kms"
 	"github.com/drakkan/sftpgo/v2/logger"
 	"github.com/drakkan/sftpgo/v2/sdk"
 	"github.com/drakkan/sftpgo/v2/util"
@@ -19,7 +20,7 @@ import (
 )
 
 const (
-	sqlDatabaseVersion     = 13
+	sqlDatabaseVersion     = 29
 	defaultSQLQueryTimeout = 10 * time.Second
 	longSQLQueryTimeout    = 60 * time.Second
 )
@@ -34,67 +35,114 @@ type sqlScanner interface {
 	Scan(dest ...interface{}) error
 }
 
-func sqlCommonGetAPIKeyByID(keyID string, dbHandle sqlQuerier) (APIKey, error) {
-	var apiKey APIKey
+func sqlCommonGetShareByID(shareID, username string, dbHandle sqlQuerier) (Share, error) {
+	var share Share
 	ctx, cancel := context.WithTimeout(context.Background(), defaultSQLQueryTimeout)
 	defer cancel()
-	q := getAPIKeyByIDQuery()
+
+	filterUser := username != ""
+	q := getShareByIDQuery(filterUser)
 	stmt, err := dbHandle.PrepareContext(ctx, q)
 	if err != nil {
 		providerLog(logger.LevelWarn, "error preparing database query %#v: %v", q, err)
-		return apiKey, err
+		return share, err
 	}
 	defer stmt.Close()
-	row := stmt.QueryRowContext(ctx, keyID)
-
-	apiKey, err = getAPIKeyFromDbRow(row)
-	if err != nil {
-		return apiKey, err
+	var row *sql.Row
+	if filterUser {
+		row = stmt.QueryRowContext(ctx, shareID, username)
+	} else {
+		row = stmt.QueryRowContext(ctx, shareID)
 	}
-	return getAPIKeyWithRelatedFields(ctx, apiKey, dbHandle)
+
+	return getShareFromDbRow(row)
 }
 
-func sqlCommonAddAPIKey(apiKey *APIKey, dbHandle *sql.DB) error {
-	err := apiKey.validate()
+func sqlCommonAddShare(share *Share, dbHandle *sql.DB) error {
+	err := share.validate()
 	if err != nil {
 		return err
 	}
 
-	userID, adminID, err := sqlCommonGetAPIKeyRelatedIDs(apiKey)
+	user, err := provider.userExists(share.Username)
+	if err != nil {
+		return util.NewValidationError(fmt.Sprintf("unable to validate user %#v", share.Username))
+	}
+
+	paths, err := json.Marshal(share.Paths)
 	if err != nil {
 		return err
 	}
+	var allowFrom []byte
+	if len(share.AllowFrom) > 0 {
+		res, err := json.Marshal(share.AllowFrom)
+		if err == nil {
+			allowFrom = res
+		}
+	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), defaultSQLQueryTimeout)
 	defer cancel()
-	q := getAddAPIKeyQuery()
+	q := getAddShareQuery()
 	stmt, err := dbHandle.PrepareContext(ctx, q)
 	if err != nil {
 		providerLog(logger.LevelWarn, "error preparing database query %#v: %v", q, err)
 		return err
 	}
 	defer stmt.Close()
-
-	_, err = stmt.ExecContext(ctx, apiKey.KeyID, apiKey.Name, apiKey.Key, apiKey.Scope, util.GetTimeAsMsSinceEpoch(time.Now()),
-		util.GetTimeAsMsSinceEpoch(time.Now()), apiKey.LastUseAt, apiKey.ExpiresAt, apiKey.Description,
-		userID, adminID)
+	usedTokens := 0
+	createdAt := util.GetTimeAsMsSinceEpoch(time.Now())
+	updatedAt := createdAt
+	lastUseAt := int64(0)
+	if share.IsRestore {
+		usedTokens = share.UsedTokens
+		if share.CreatedAt > 0 {
+			createdAt = share.CreatedAt
+		}
+		if share.UpdatedAt > 0 {
+			updatedAt = share.UpdatedAt
+		}
+		lastUseAt = share.LastUseAt
+	}
+	_, err = stmt.ExecContext(ctx, share.ShareID, share.Name, share.Description, share.Scope,
+		string(paths), createdAt, updatedAt, lastUseAt, share.ExpiresAt, share.Password,
+		share.MaxTokens, usedTokens, string(allowFrom), user.ID)
 	return err
 }
 
-func sqlCommonUpdateAPIKey(apiKey *APIKey, dbHandle *sql.DB) error {
-	err := apiKey.validate()
+func sqlCommonUpdateShare(share *Share, dbHandle *sql.DB) error {
+	err := share.validate()
 	if err != nil {
 		return err
 	}
 
-	userID, adminID, err := sqlCommonGetAPIKeyRelatedIDs(apiKey)
+	paths, err := json.Marshal(share.Paths)
 	if err != nil {
 		return err
 	}
 
+	var allowFrom []byte
+	if len(share.AllowFrom) > 0 {
+		res, err := json.Marshal(share.AllowFrom)
+		if err == nil {
+			allowFrom = res
+		}
+	}
+
+	user, err := provider.userExists(share.Username)
+	if err != nil {
+		return util.NewValidationError(fmt.Sprintf("unable to validate user %#v", share.Username))
+	}
+
 	ctx, cancel := context.WithTimeout(context.Background(), defaultSQLQueryTimeout)
 	defer cancel()
-	q := getUpdateAPIKeyQuery()
+	var q string
+	if share.IsRestore {
+		q = getUpdateShareRestoreQuery()
+	} else {
+		q = getUpdateShareQuery()
+	}
+
 	stmt, err := dbHandle.PrepareContext(ctx, q)
 	if err != nil {
 		providerLog(logger.LevelWarn, "error preparing database query %#v: %v", q, err)
@@ -102,933 +150,3203 @@ func sqlCommonUpdateAPIKey(apiKey *APIKey, dbHandle *sql.DB) error {
 	}
 	defer stmt.Close()
 
-	_, err = stmt.ExecContext(ctx, apiKey.Name, apiKey.Scope, apiKey.ExpiresAt, userID, adminID,
-		apiKey.Description, util.GetTimeAsMsSinceEpoch(time.Now()), apiKey.KeyID)
+	if share.IsRestore {
+		if share.CreatedAt == 0 {
+			share.CreatedAt = util.GetTimeAsMsSinceEpoch(time.Now())
+		}
+		if share.UpdatedAt == 0 {
+			share.UpdatedAt = share.CreatedAt
+		}
+		_, err = stmt.ExecContext(ctx, share.Name, share.Description, share.Scope, string(paths),
+			share.CreatedAt, share.UpdatedAt, share.LastUseAt, share.ExpiresAt, share.Password, share.MaxTokens,
+			share.UsedTokens, string(allowFrom), user.ID, share.ShareID)
+	} else {
+		_, err = stmt.ExecContext(ctx, share.Name, share.Description, share.Scope, string(paths),
+			util.GetTimeAsMsSinceEpoch(time.Now()), share.ExpiresAt, share.Password, share.MaxTokens,
+			string(allowFrom), user.ID, share.ShareID)
+	}
 	return err
 }
 
-func sqlCommonDeleteAPIKey(apiKey *APIKey, dbHandle *sql.DB) error {
+func sqlCommonDeleteShare(share *Share, dbHandle *sql.DB) error {
 	ctx, cancel := context.WithTimeout(context.Background(), defaultSQLQueryTimeout)
 	defer cancel()
-	q := getDeleteAPIKeyQuery()
+	q := getDeleteShareQuery()
 	stmt, err := dbHandle.PrepareContext(ctx, q)
 	if err != nil {
 		providerLog(logger.LevelWarn, "error preparing database query %#v: %v", q, err)
 		return err
 	}
 	defer stmt.Close()
-	_, err = stmt.ExecContext(ctx, apiKey.KeyID)
+	_, err = stmt.ExecContext(ctx, share.ShareID)
 	return err
 }
 
-func sqlCommonGetAPIKeys(limit, offset int, order string, dbHandle sqlQuerier) ([]APIKey, error) {
-	apiKeys := make([]APIKey, 0, limit)
+func sqlCommonGetShares(limit, offset int, order, username string, dbHandle sqlQuerier) ([]Share, error) {
+	shares := make([]Share, 0, limit)
 
 	ctx, cancel := context.WithTimeout(context.Background(), defaultSQLQueryTimeout)
 	defer cancel()
-	q := getAPIKeysQuery(order)
+	q := getSharesQuery(order)
 	stmt, err := dbHandle.PrepareContext(ctx, q)
 	if err != nil {
 		providerLog(logger.LevelWarn, "error preparing database query %#v: %v", q, err)
 		return nil, err
 	}
 	defer stmt.Close()
-
-	rows, err := stmt.QueryContext(ctx, limit, offset)
+	rows, err := stmt.QueryContext(ctx, username, limit, offset)
 	if err != nil {
-		return apiKeys, err
+		return shares, err
 	}
 	defer rows.Close()
 
 	for rows.Next() {
-		k, err := getAPIKeyFromDbRow(rows)
+		s, err := getShareFromDbRow(rows)
 		if err != nil {
-			return apiKeys, err
+			return shares, err
 		}
-		k.HideConfidentialData()
-		apiKeys = append(apiKeys, k)
-	}
-	err = rows.Err()
-	if err != nil {
-		return apiKeys, err
-	}
-	apiKeys, err = getRelatedValuesForAPIKeys(ctx, apiKeys, dbHandle, APIKeyScopeAdmin)
-	if err != nil {
-		return apiKeys, err
+		s.HideConfidentialData()
+		shares = append(shares, s)
 	}
 
-	return getRelatedValuesForAPIKeys(ctx, apiKeys, dbHandle, APIKeyScopeUser)
+	return shares, rows.Err()
 }
 
-func sqlCommonDumpAPIKeys(dbHandle sqlQuerier) ([]APIKey, error) {
-	apiKeys := make([]APIKey, 0, 30)
+func sqlCommonDumpShares(dbHandle sqlQuerier) ([]Share, error) {
+	shares := make([]Share, 0, 30)
 
 	ctx, cancel := context.WithTimeout(context.Background(), defaultSQLQueryTimeout)
 	defer cancel()
-	q := getDumpAPIKeysQuery()
+	q := getDumpSharesQuery()
 	stmt, err := dbHandle.PrepareContext(ctx, q)
 	if err != nil {
 		providerLog(logger.LevelWarn, "error preparing database query %#v: %v", q, err)
 		return nil, err
 	}
 	defer stmt.Close()
-
 	rows, err := stmt.QueryContext(ctx)
 	if err != nil {
-		return apiKeys, err
+		return shares, err
 	}
 	defer rows.Close()
 
 	for rows.Next() {
-		k, err := getAPIKeyFromDbRow(rows)
+		s, err := getShareFromDbRow(rows)
 		if err != nil {
-			return apiKeys, err
+			return shares, err
 		}
-		apiKeys = append(apiKeys, k)
-	}
-	err = rows.Err()
-	if err != nil {
-		return apiKeys, err
-	}
-	apiKeys, err = getRelatedValuesForAPIKeys(ctx, apiKeys, dbHandle, APIKeyScopeAdmin)
-	if err != nil {
-		return apiKeys, err
+		shares = append(shares, s)
 	}
 
-	return getRelatedValuesForAPIKeys(ctx, apiKeys, dbHandle, APIKeyScopeUser)
+	return shares, rows.Err()
 }
 
-func sqlCommonGetAdminByUsername(username string, dbHandle sqlQuerier) (Admin, error) {
-	var admin Admin
+func sqlCommonUpdateShareLastUse(shareID string, numTokens int, dbHandle *sql.DB) error {
 	ctx, cancel := context.WithTimeout(context.Background(), defaultSQLQueryTimeout)
 	defer cancel()
-	q := getAdminByUsernameQuery()
+	q := getUpdateShareLastUseQuery()
 	stmt, err := dbHandle.PrepareContext(ctx, q)
 	if err != nil {
 		providerLog(logger.LevelWarn, "error preparing database query %#v: %v", q, err)
-		return admin, err
+		return err
 	}
 	defer stmt.Close()
-	row := stmt.QueryRowContext(ctx, username)
-
-	return getAdminFromDbRow(row)
-}
-
-func sqlCommonValidateAdminAndPass(username, password, ip string, dbHandle *sql.DB) (Admin, error) {
-	admin, err := sqlCommonGetAdminByUsername(username, dbHandle)
-	if err != nil {
-		providerLog(logger.LevelWarn, "error authenticating admin %#v: %v", username, err)
-		return admin, ErrInvalidCredentials
+	_, err = stmt.ExecContext(ctx, util.GetTimeAsMsSinceEpoch(time.Now()), numTokens, shareID)
+	if err == nil {
+		providerLog(logger.LevelDebug, "last use updated for shared object %#v", shareID)
+	} else {
+		providerLog(logger.LevelWarn, "error updating last use for shared object %#v: %v", shareID, err)
 	}
-	err = admin.checkUserAndPass(password, ip)
-	return admin, err
+	return err
 }
 
-func sqlCommonAddAdmin(admin *Admin, dbHandle *sql.DB) error {
-	err := admin.validate()
-	if err != nil {
+func sqlCommonAddShareUpload(upload *ShareUpload, dbHandle *sql.DB) error {
+	if err := upload.validate(); err != nil {
 		return err
 	}
-
 	ctx, cancel := context.WithTimeout(context.Background(), defaultSQLQueryTimeout)
 	defer cancel()
-	q := getAddAdminQuery()
+	q := getAddShareUploadQuery()
 	stmt, err := dbHandle.PrepareContext(ctx, q)
 	if err != nil {
 		providerLog(logger.LevelWarn, "error preparing database query %#v: %v", q, err)
 		return err
 	}
 	defer stmt.Close()
+	_, err = stmt.ExecContext(ctx, upload.Path, upload.Size, upload.IP, upload.Uploader, upload.UploadedAt,
+		upload.ShareID)
+	return err
+}
 
-	perms, err := json.Marshal(admin.Permissions)
+func sqlCommonGetShareUploads(shareID string, limit, offset int, order string, dbHandle sqlQuerier) ([]ShareUpload, error) {
+	uploads := make([]ShareUpload, 0, limit)
+
+	ctx, cancel := context.WithTimeout(context.Background(), defaultSQLQueryTimeout)
+	defer cancel()
+	q := getShareUploadsQuery(order)
+	stmt, err := dbHandle.PrepareContext(ctx, q)
 	if err != nil {
-		return err
+		providerLog(logger.LevelWarn, "error preparing database query %#v: %v", q, err)
+		return nil, err
 	}
-
-	filters, err := json.Marshal(admin.Filters)
+	defer stmt.Close()
+	rows, err := stmt.QueryContext(ctx, shareID, limit, offset)
 	if err != nil {
-		return err
+		return uploads, err
 	}
+	defer rows.Close()
 
-	_, err = stmt.ExecContext(ctx, admin.Username, admin.Password, admin.Status, admin.Email, string(perms),
-		string(filters), admin.AdditionalInfo, admin.Description, util.GetTimeAsMsSinceEpoch(time.Now()),
-		util.GetTimeAsMsSinceEpoch(time.Now()))
-	return err
+	for rows.Next() {
+		var upload ShareUpload
+		var uploader sql.NullString
+		err = rows.Scan(&upload.ShareID, &upload.Path, &upload.Size, &upload.IP, &uploader, &upload.UploadedAt)
+		if err != nil {
+			return uploads, err
+		}
+		if uploader.Valid {
+			upload.Uploader = uploader.String
+		}
+		uploads = append(uploads, upload)
+	}
+
+	return uploads, rows.Err()
 }
 
-func sqlCommonUpdateAdmin(admin *Admin, dbHandle *sql.DB) error {
-	err := admin.validate()
+func sqlCommonGetAccountLockout(username, accountType string, dbHandle sqlQuerier) (AccountLockout, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultSQLQueryTimeout)
+	defer cancel()
+	q := getAccountLockoutQuery()
+	stmt, err := dbHandle.PrepareContext(ctx, q)
 	if err != nil {
-		return err
+		providerLog(logger.LevelWarn, "error preparing database query %#v: %v", q, err)
+		return AccountLockout{}, err
+	}
+	defer stmt.Close()
+	row := stmt.QueryRowContext(ctx, username, accountType)
+	return getAccountLockoutFromDbRow(row)
+}
+
+func sqlCommonAddAccountLockoutFailure(username, accountType string, now time.Time, dbHandle *sql.DB) (AccountLockout, error) {
+	var lastErr error
+	for i := 0; i < accountLockoutMaxUpdateAttempts; i++ {
+		lockout, err := sqlCommonGetAccountLockout(username, accountType, dbHandle)
+		if err != nil {
+			if _, ok := err.(*util.RecordNotFoundError); !ok {
+				return lockout, err
+			}
+			lockout = AccountLockout{
+				Username: username,
+				Type:     accountType,
+			}
+			lockout.addFailure(now)
+			// a concurrent login could insert the same lockout, in this case
+			// the unique constraint is violated and we try again
+			lastErr = sqlCommonAddAccountLockout(&lockout, dbHandle)
+			if lastErr == nil {
+				return lockout, nil
+			}
+			continue
+		}
+		previous := lockout
+		lockout.addFailure(now)
+		updated, err := sqlCommonUpdateAccountLockout(&lockout, &previous, dbHandle)
+		if err != nil {
+			return lockout, err
+		}
+		if updated {
+			return lockout, nil
+		}
+		lastErr = fmt.Errorf("concurrent update detected for account lockout %v %#v", accountType, username)
 	}
+	return AccountLockout{}, lastErr
+}
 
+func sqlCommonAddAccountLockout(lockout *AccountLockout, dbHandle *sql.DB) error {
+	if err := lockout.validate(); err != nil {
+		return err
+	}
 	ctx, cancel := context.WithTimeout(context.Background(), defaultSQLQueryTimeout)
 	defer cancel()
-	q := getUpdateAdminQuery()
+	q := getAddAccountLockoutQuery()
 	stmt, err := dbHandle.PrepareContext(ctx, q)
 	if err != nil {
 		providerLog(logger.LevelWarn, "error preparing database query %#v: %v", q, err)
 		return err
 	}
 	defer stmt.Close()
+	_, err = stmt.ExecContext(ctx, lockout.Failures, lockout.FirstFailureAt, lockout.LastFailureAt,
+		lockout.LockedAt, lockout.LockedUntil, lockout.Username, lockout.Type)
+	return err
+}
 
-	perms, err := json.Marshal(admin.Permissions)
+// sqlCommonUpdateAccountLockout updates the lockout only if it still matches
+// the previous version, it returns false if a concurrent update happened
+func sqlCommonUpdateAccountLockout(lockout, previous *AccountLockout, dbHandle *sql.DB) (bool, error) {
+	if err := lockout.validate(); err != nil {
+		return false, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), defaultSQLQueryTimeout)
+	defer cancel()
+	q := getUpdateAccountLockoutQuery()
+	stmt, err := dbHandle.PrepareContext(ctx, q)
 	if err != nil {
-		return err
+		providerLog(logger.LevelWarn, "error preparing database query %#v: %v", q, err)
+		return false, err
 	}
-
-	filters, err := json.Marshal(admin.Filters)
+	defer stmt.Close()
+	res, err := stmt.ExecContext(ctx, lockout.Failures, lockout.FirstFailureAt, lockout.LastFailureAt,
+		lockout.LockedAt, lockout.LockedUntil, lockout.Username, lockout.Type, previous.Failures,
+		previous.FirstFailureAt, previous.LastFailureAt, previous.LockedAt)
 	if err != nil {
-		return err
+		return false, err
 	}
-
-	_, err = stmt.ExecContext(ctx, admin.Password, admin.Status, admin.Email, string(perms), string(filters),
-		admin.AdditionalInfo, admin.Description, util.GetTimeAsMsSinceEpoch(time.Now()), admin.Username)
-	return err
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected > 0, nil
 }
 
-func sqlCommonDeleteAdmin(admin *Admin, dbHandle *sql.DB) error {
+func sqlCommonDeleteAccountLockout(username, accountType string, dbHandle *sql.DB) error {
 	ctx, cancel := context.WithTimeout(context.Background(), defaultSQLQueryTimeout)
 	defer cancel()
-	q := getDeleteAdminQuery()
+	q := getDeleteAccountLockoutQuery()
 	stmt, err := dbHandle.PrepareContext(ctx, q)
 	if err != nil {
 		providerLog(logger.LevelWarn, "error preparing database query %#v: %v", q, err)
 		return err
 	}
 	defer stmt.Close()
-	_, err = stmt.ExecContext(ctx, admin.Username)
+	_, err = stmt.ExecContext(ctx, username, accountType)
 	return err
 }
 
-func sqlCommonGetAdmins(limit, offset int, order string, dbHandle sqlQuerier) ([]Admin, error) {
-	admins := make([]Admin, 0, limit)
+func sqlCommonGetAccountLockouts(limit, offset int, order string, dbHandle sqlQuerier) ([]AccountLockout, error) {
+	lockouts := make([]AccountLockout, 0, limit)
 
 	ctx, cancel := context.WithTimeout(context.Background(), defaultSQLQueryTimeout)
 	defer cancel()
-	q := getAdminsQuery(order)
+	q := getAccountLockoutsQuery(order)
 	stmt, err := dbHandle.PrepareContext(ctx, q)
 	if err != nil {
 		providerLog(logger.LevelWarn, "error preparing database query %#v: %v", q, err)
 		return nil, err
 	}
 	defer stmt.Close()
-
 	rows, err := stmt.QueryContext(ctx, limit, offset)
 	if err != nil {
-		return admins, err
+		return lockouts, err
 	}
 	defer rows.Close()
 
 	for rows.Next() {
-		a, err := getAdminFromDbRow(rows)
+		lockout, err := getAccountLockoutFromDbRow(rows)
 		if err != nil {
-			return admins, err
+			return lockouts, err
 		}
-		a.HideConfidentialData()
-		admins = append(admins, a)
+		lockouts = append(lockouts, lockout)
 	}
 
-	return admins, rows.Err()
+	return lockouts, rows.Err()
 }
 
-func sqlCommonDumpAdmins(dbHandle sqlQuerier) ([]Admin, error) {
-	admins := make([]Admin, 0, 30)
-
+func sqlCommonGetWebDAVLock(token string, dbHandle sqlQuerier) (WebDAVLock, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), defaultSQLQueryTimeout)
 	defer cancel()
-	q := getDumpAdminsQuery()
+	q := getWebDAVLockQuery()
 	stmt, err := dbHandle.PrepareContext(ctx, q)
 	if err != nil {
 		providerLog(logger.LevelWarn, "error preparing database query %#v: %v", q, err)
-		return nil, err
+		return WebDAVLock{}, err
 	}
 	defer stmt.Close()
+	row := stmt.QueryRowContext(ctx, token)
+	return getWebDAVLockFromDbRow(row)
+}
 
-	rows, err := stmt.QueryContext(ctx)
-	if err != nil {
-		return admins, err
+func sqlCommonAddWebDAVLock(lock *WebDAVLock, dbHandle *sql.DB) error {
+	if err := lock.validate(); err != nil {
+		return err
 	}
-	defer rows.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), defaultSQLQueryTimeout)
+	defer cancel()
 
-	for rows.Next() {
-		a, err := getAdminFromDbRow(rows)
+	return sqlCommonExecuteTx(ctx, dbHandle, func(tx *sql.Tx) error {
+		// a lock for the same resource, expired at the creation time of the new one, could
+		// be still stored, it would violate the unique constraint
+		q := getDeleteExpiredWebDAVLockQuery()
+		stmt, err := tx.PrepareContext(ctx, q)
 		if err != nil {
-			return admins, err
+			providerLog(logger.LevelWarn, "error preparing database query %#v: %v", q, err)
+			return err
 		}
-		admins = append(admins, a)
-	}
-
-	return admins, rows.Err()
+		defer stmt.Close()
+		_, err = stmt.ExecContext(ctx, lock.Username, lock.getRootKey(), lock.CreatedAt)
+		if err != nil {
+			return err
+		}
+		q = getAddWebDAVLockQuery()
+		insertStmt, err := tx.PrepareContext(ctx, q)
+		if err != nil {
+			providerLog(logger.LevelWarn, "error preparing database query %#v: %v", q, err)
+			return err
+		}
+		defer insertStmt.Close()
+		_, err = insertStmt.ExecContext(ctx, lock.Token, lock.Username, lock.Root, lock.getRootKey(), lock.Duration,
+			lock.ExpiresAt, lock.OwnerXML, lock.ZeroDepth, lock.CreatedAt, lock.UpdatedAt)
+		return err
+	})
 }
 
-func sqlCommonGetUserByUsername(username string, dbHandle sqlQuerier) (User, error) {
-	var user User
+func sqlCommonUpdateWebDAVLock(lock *WebDAVLock, dbHandle *sql.DB) error {
+	if err := lock.validate(); err != nil {
+		return err
+	}
 	ctx, cancel := context.WithTimeout(context.Background(), defaultSQLQueryTimeout)
 	defer cancel()
-	q := getUserByUsernameQuery()
+	q := getUpdateWebDAVLockQuery()
 	stmt, err := dbHandle.PrepareContext(ctx, q)
 	if err != nil {
 		providerLog(logger.LevelWarn, "error preparing database query %#v: %v", q, err)
-		return user, err
+		return err
 	}
 	defer stmt.Close()
-
-	row := stmt.QueryRowContext(ctx, username)
-	user, err = getUserFromDbRow(row)
+	res, err := stmt.ExecContext(ctx, lock.Duration, lock.ExpiresAt, lock.UpdatedAt, lock.Token)
 	if err != nil {
-		return user, err
+		return err
 	}
-	return getUserWithVirtualFolders(ctx, user, dbHandle)
+	return sqlCommonRequireRowAffected(res, fmt.Sprintf("WebDAV lock %#v does not exist", lock.Token))
 }
 
-func sqlCommonValidateUserAndPass(username, password, ip, protocol string, dbHandle *sql.DB) (User, error) {
-	var user User
-	if password == "" {
-		return user, errors.New("credentials cannot be null or empty")
+func sqlCommonDeleteWebDAVLock(token string, dbHandle *sql.DB) error {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultSQLQueryTimeout)
+	defer cancel()
+	q := getDeleteWebDAVLockQuery()
+	stmt, err := dbHandle.PrepareContext(ctx, q)
+	if err != nil {
+		providerLog(logger.LevelWarn, "error preparing database query %#v: %v", q, err)
+		return err
 	}
-	user, err := sqlCommonGetUserByUsername(username, dbHandle)
+	defer stmt.Close()
+	res, err := stmt.ExecContext(ctx, token)
 	if err != nil {
-		providerLog(logger.LevelWarn, "error authenticating user %#v: %v", username, err)
-		return user, err
+		return err
 	}
-	return checkUserAndPass(&user, password, ip, protocol)
+	return sqlCommonRequireRowAffected(res, fmt.Sprintf("WebDAV lock %#v does not exist", token))
 }
 
-func sqlCommonValidateUserAndTLSCertificate(username, protocol string, tlsCert *x509.Certificate, dbHandle *sql.DB) (User, error) {
-	var user User
-	if tlsCert == nil {
-		return user, errors.New("TLS certificate cannot be null or empty")
-	}
-	user, err := sqlCommonGetUserByUsername(username, dbHandle)
-	if err != nil {
-		providerLog(logger.LevelWarn, "error authenticating user %#v: %v", username, err)
-		return user, err
-	}
-	return checkUserAndTLSCertificate(&user, protocol, tlsCert)
-}
-
-func sqlCommonValidateUserAndPubKey(username string, pubKey []byte, dbHandle *sql.DB) (User, string, error) {
-	var user User
-	if len(pubKey) == 0 {
-		return user, "", errors.New("credentials cannot be null or empty")
-	}
-	user, err := sqlCommonGetUserByUsername(username, dbHandle)
-	if err != nil {
-		providerLog(logger.LevelWarn, "error authenticating user %#v: %v", username, err)
-		return user, "", err
-	}
-	return checkUserAndPubKey(&user, pubKey)
-}
-
-func sqlCommonCheckAvailability(dbHandle *sql.DB) error {
-	ctx, cancel := context.WithTimeout(context.Background(), defaultSQLQueryTimeout)
-	defer cancel()
-	return dbHandle.PingContext(ctx)
-}
+func sqlCommonGetWebDAVLocks(username string, dbHandle sqlQuerier) ([]WebDAVLock, error) {
+	locks := make([]WebDAVLock, 0, 10)
 
-func sqlCommonUpdateQuota(username string, filesAdd int, sizeAdd int64, reset bool, dbHandle *sql.DB) error {
 	ctx, cancel := context.WithTimeout(context.Background(), defaultSQLQueryTimeout)
 	defer cancel()
-	q := getUpdateQuotaQuery(reset)
+	q := getWebDAVLocksQuery()
 	stmt, err := dbHandle.PrepareContext(ctx, q)
 	if err != nil {
 		providerLog(logger.LevelWarn, "error preparing database query %#v: %v", q, err)
-		return err
+		return nil, err
 	}
 	defer stmt.Close()
-	_, err = stmt.ExecContext(ctx, sizeAdd, filesAdd, util.GetTimeAsMsSinceEpoch(time.Now()), username)
-	if err == nil {
-		providerLog(logger.LevelDebug, "quota updated for user %#v, files increment: %v size increment: %v is reset? %v",
-			username, filesAdd, sizeAdd, reset)
-	} else {
-		providerLog(logger.LevelWarn, "error updating quota for user %#v: %v", username, err)
-	}
-	return err
-}
-
-func sqlCommonGetUsedQuota(username string, dbHandle *sql.DB) (int, int64, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), defaultSQLQueryTimeout)
-	defer cancel()
-	q := getQuotaQuery()
-	stmt, err := dbHandle.PrepareContext(ctx, q)
+	rows, err := stmt.QueryContext(ctx, username)
 	if err != nil {
-		providerLog(logger.LevelWarn, "error preparing database query %#v: %v", q, err)
-		return 0, 0, err
+		return locks, err
 	}
-	defer stmt.Close()
+	defer rows.Close()
 
-	var usedFiles int
-	var usedSize int64
-	err = stmt.QueryRowContext(ctx, username).Scan(&usedSize, &usedFiles)
-	if err != nil {
-		providerLog(logger.LevelWarn, "error getting quota for user: %v, error: %v", username, err)
-		return 0, 0, err
+	for rows.Next() {
+		lock, err := getWebDAVLockFromDbRow(rows)
+		if err != nil {
+			return locks, err
+		}
+		locks = append(locks, lock)
 	}
-	return usedFiles, usedSize, err
+
+	return locks, rows.Err()
 }
 
-func sqlCommonUpdateAPIKeyLastUse(keyID string, dbHandle *sql.DB) error {
+func sqlCommonDeleteUserWebDAVLocks(username string, dbHandle *sql.DB) error {
 	ctx, cancel := context.WithTimeout(context.Background(), defaultSQLQueryTimeout)
 	defer cancel()
-	q := getUpdateAPIKeyLastUseQuery()
+	q := getDeleteUserWebDAVLocksQuery()
 	stmt, err := dbHandle.PrepareContext(ctx, q)
 	if err != nil {
 		providerLog(logger.LevelWarn, "error preparing database query %#v: %v", q, err)
 		return err
 	}
 	defer stmt.Close()
-	_, err = stmt.ExecContext(ctx, util.GetTimeAsMsSinceEpoch(time.Now()), keyID)
-	if err == nil {
-		providerLog(logger.LevelDebug, "last use updated for key %#v", keyID)
-	} else {
-		providerLog(logger.LevelWarn, "error updating last use for key %#v: %v", keyID, err)
-	}
+	_, err = stmt.ExecContext(ctx, username)
 	return err
 }
 
-func sqlCommonUpdateAdminLastLogin(username string, dbHandle *sql.DB) error {
-	ctx, cancel := context.WithTimeout(context.Background(), defaultSQLQueryTimeout)
+func sqlCommonCleanupWebDAVLocks(before int64, dbHandle *sql.DB) error {
+	ctx, cancel := context.WithTimeout(context.Background(), longSQLQueryTimeout)
 	defer cancel()
-	q := getUpdateAdminLastLoginQuery()
+	q := getCleanupWebDAVLocksQuery()
 	stmt, err := dbHandle.PrepareContext(ctx, q)
 	if err != nil {
 		providerLog(logger.LevelWarn, "error preparing database query %#v: %v", q, err)
 		return err
 	}
 	defer stmt.Close()
-	_, err = stmt.ExecContext(ctx, util.GetTimeAsMsSinceEpoch(time.Now()), username)
-	if err == nil {
-		providerLog(logger.LevelDebug, "last login updated for admin %#v", username)
-	} else {
-		providerLog(logger.LevelWarn, "error updating last login for admin %#v: %v", username, err)
-	}
+	_, err = stmt.ExecContext(ctx, before)
 	return err
 }
 
-func sqlCommonSetUpdatedAt(username string, dbHandle *sql.DB) {
+func sqlCommonGetWebDAVProperties(username, virtualPath string, dbHandle sqlQuerier) (WebDAVProperties, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), defaultSQLQueryTimeout)
 	defer cancel()
-	q := getSetUpdateAtQuery()
+	q := getWebDAVPropertiesQuery()
 	stmt, err := dbHandle.PrepareContext(ctx, q)
 	if err != nil {
 		providerLog(logger.LevelWarn, "error preparing database query %#v: %v", q, err)
-		return
+		return WebDAVProperties{}, err
 	}
 	defer stmt.Close()
-	_, err = stmt.ExecContext(ctx, util.GetTimeAsMsSinceEpoch(time.Now()), username)
-	if err == nil {
-		providerLog(logger.LevelDebug, "updated_at set for user %#v", username)
-	} else {
-		providerLog(logger.LevelWarn, "error setting updated_at for user %#v: %v", username, err)
-	}
+	row := stmt.QueryRowContext(ctx, username, getWebDAVPropertiesPathKey(virtualPath))
+	return getWebDAVPropertiesFromDbRow(row)
 }
 
-func sqlCommonUpdateLastLogin(username string, dbHandle *sql.DB) error {
+func sqlCommonSetWebDAVProperties(props *WebDAVProperties, dbHandle *sql.DB) error {
+	if err := props.validate(); err != nil {
+		return err
+	}
+	properties, err := json.Marshal(props.Properties)
+	if err != nil {
+		return err
+	}
 	ctx, cancel := context.WithTimeout(context.Background(), defaultSQLQueryTimeout)
 	defer cancel()
-	q := getUpdateLastLoginQuery()
+	q := getUpdateWebDAVPropertiesQuery()
 	stmt, err := dbHandle.PrepareContext(ctx, q)
 	if err != nil {
 		providerLog(logger.LevelWarn, "error preparing database query %#v: %v", q, err)
 		return err
 	}
 	defer stmt.Close()
-	_, err = stmt.ExecContext(ctx, util.GetTimeAsMsSinceEpoch(time.Now()), username)
-	if err == nil {
-		providerLog(logger.LevelDebug, "last login updated for user %#v", username)
-	} else {
-		providerLog(logger.LevelWarn, "error updating last login for user %#v: %v", username, err)
+	res, err := stmt.ExecContext(ctx, string(properties), props.UpdatedAt, props.Username, props.getPathKey())
+	if err != nil {
+		return err
 	}
-	return err
-}
-
-func sqlCommonAddUser(user *User, dbHandle *sql.DB) error {
-	err := ValidateUser(user)
+	if affected, err := res.RowsAffected(); err == nil && affected > 0 {
+		return nil
+	}
+	q = getAddWebDAVPropertiesQuery()
+	insertStmt, err := dbHandle.PrepareContext(ctx, q)
 	if err != nil {
+		providerLog(logger.LevelWarn, "error preparing database query %#v: %v", q, err)
 		return err
 	}
-	ctx, cancel := context.WithTimeout(context.Background(), defaultSQLQueryTimeout)
+	defer insertStmt.Close()
+	_, err = insertStmt.ExecContext(ctx, string(properties), props.UpdatedAt, props.Username, props.Path,
+		props.getPathKey())
+	return err
+}
+
+func sqlCommonDeleteWebDAVProperties(username, virtualPath string, dbHandle *sql.DB) error {
+	ctx, cancel := context.WithTimeout(context.Background(), longSQLQueryTimeout)
 	defer cancel()
 
 	return sqlCommonExecuteTx(ctx, dbHandle, func(tx *sql.Tx) error {
-		q := getAddUserQuery()
-		stmt, err := tx.PrepareContext(ctx, q)
-		if err != nil {
-			providerLog(logger.LevelWarn, "error preparing database query %#v: %v", q, err)
-			return err
-		}
-		defer stmt.Close()
-		permissions, err := user.GetPermissionsAsJSON()
-		if err != nil {
-			return err
-		}
-		publicKeys, err := user.GetPublicKeysAsJSON()
-		if err != nil {
-			return err
-		}
-		filters, err := user.GetFiltersAsJSON()
-		if err != nil {
-			return err
-		}
-		fsConfig, err := user.GetFsConfigAsJSON()
-		if err != nil {
-			return err
-		}
-		_, err = stmt.ExecContext(ctx, user.Username, user.Password, string(publicKeys), user.HomeDir, user.UID, user.GID, user.MaxSessions, user.QuotaSize,
-			user.QuotaFiles, string(permissions), user.UploadBandwidth, user.DownloadBandwidth, user.Status, user.ExpirationDate, string(filters),
-			string(fsConfig), user.AdditionalInfo, user.Description, user.Email, util.GetTimeAsMsSinceEpoch(time.Now()),
-			util.GetTimeAsMsSinceEpoch(time.Now()))
-		if err != nil {
-			return err
-		}
-		return generateVirtualFoldersMapping(ctx, user, tx)
+		return sqlCommonDeleteWebDAVPropertiesTree(ctx, username, virtualPath, tx)
 	})
 }
 
-func sqlCommonUpdateUser(user *User, dbHandle *sql.DB) error {
-	err := ValidateUser(user)
-	if err != nil {
-		return err
-	}
-	ctx, cancel := context.WithTimeout(context.Background(), defaultSQLQueryTimeout)
+func sqlCommonRenameWebDAVProperties(username, source, target string, dbHandle *sql.DB) error {
+	ctx, cancel := context.WithTimeout(context.Background(), longSQLQueryTimeout)
 	defer cancel()
 
 	return sqlCommonExecuteTx(ctx, dbHandle, func(tx *sql.Tx) error {
-		q := getUpdateUserQuery()
-		stmt, err := tx.PrepareContext(ctx, q)
-		if err != nil {
-			providerLog(logger.LevelWarn, "error preparing database query %#v: %v", q, err)
-			return err
-		}
-		defer stmt.Close()
-		permissions, err := user.GetPermissionsAsJSON()
-		if err != nil {
-			return err
-		}
-		publicKeys, err := user.GetPublicKeysAsJSON()
-		if err != nil {
+		if err := sqlCommonDeleteWebDAVPropertiesTree(ctx, username, target, tx); err != nil {
 			return err
 		}
-		filters, err := user.GetFiltersAsJSON()
+		paths, err := sqlCommonGetWebDAVPropertiesTree(ctx, username, source, tx)
 		if err != nil {
 			return err
 		}
-		fsConfig, err := user.GetFsConfigAsJSON()
+		q := getUpdateWebDAVPropertiesPathQuery()
+		stmt, err := tx.PrepareContext(ctx, q)
 		if err != nil {
+			providerLog(logger.LevelWarn, "error preparing database query %#v: %v", q, err)
 			return err
 		}
-		_, err = stmt.ExecContext(ctx, user.Password, string(publicKeys), user.HomeDir, user.UID, user.GID, user.MaxSessions, user.QuotaSize,
-			user.QuotaFiles, string(permissions), user.UploadBandwidth, user.DownloadBandwidth, user.Status, user.ExpirationDate,
-			string(filters), string(fsConfig), user.AdditionalInfo, user.Description, user.Email, util.GetTimeAsMsSinceEpoch(time.Now()),
-			user.ID)
-		if err != nil {
-			return err
+		defer stmt.Close()
+		for id, p := range paths {
+			newPath := getWebDAVPropertiesRenamedPath(p, source, target)
+			if _, err := stmt.ExecContext(ctx, newPath, getWebDAVPropertiesPathKey(newPath), id); err != nil {
+				return err
+			}
 		}
-		return generateVirtualFoldersMapping(ctx, user, tx)
+		return nil
 	})
 }
 
-func sqlCommonDeleteUser(user *User, dbHandle *sql.DB) error {
-	ctx, cancel := context.WithTimeout(context.Background(), defaultSQLQueryTimeout)
-	defer cancel()
-	q := getDeleteUserQuery()
-	stmt, err := dbHandle.PrepareContext(ctx, q)
+func sqlCommonDeleteWebDAVPropertiesTree(ctx context.Context, username, root string, tx *sql.Tx) error {
+	paths, err := sqlCommonGetWebDAVPropertiesTree(ctx, username, root, tx)
+	if err != nil {
+		return err
+	}
+	if len(paths) == 0 {
+		return nil
+	}
+	q := getDeleteWebDAVPropertiesByIDQuery()
+	stmt, err := tx.PrepareContext(ctx, q)
 	if err != nil {
 		providerLog(logger.LevelWarn, "error preparing database query %#v: %v", q, err)
 		return err
 	}
 	defer stmt.Close()
-	_, err = stmt.ExecContext(ctx, user.ID)
-	return err
+	for id := range paths {
+		if _, err := stmt.ExecContext(ctx, id); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
-func sqlCommonDumpUsers(dbHandle sqlQuerier) ([]User, error) {
-	users := make([]User, 0, 100)
-	ctx, cancel := context.WithTimeout(context.Background(), longSQLQueryTimeout)
-	defer cancel()
-	q := getDumpUsersQuery()
-	stmt, err := dbHandle.PrepareContext(ctx, q)
+// sqlCommonGetWebDAVPropertiesTree returns the paths, mapped by row id, for root and all its descendants
+func sqlCommonGetWebDAVPropertiesTree(ctx context.Context, username, root string, tx *sql.Tx) (map[int64]string, error) {
+	paths := make(map[int64]string)
+	q := getWebDAVPropertiesTreeQuery()
+	stmt, err := tx.PrepareContext(ctx, q)
 	if err != nil {
 		providerLog(logger.LevelWarn, "error preparing database query %#v: %v", q, err)
 		return nil, err
 	}
 	defer stmt.Close()
-	rows, err := stmt.QueryContext(ctx)
+	rows, err := stmt.QueryContext(ctx, username, getWebDAVPropertiesPathKey(root),
+		getWebDAVPropertiesTreeLikePattern(root))
 	if err != nil {
-		return users, err
+		return nil, err
 	}
-
 	defer rows.Close()
+
 	for rows.Next() {
-		u, err := getUserFromDbRow(rows)
-		if err != nil {
-			return users, err
+		var id int64
+		var p string
+		if err := rows.Scan(&id, &p); err != nil {
+			return nil, err
 		}
-		err = addCredentialsToUser(&u)
-		if err != nil {
-			return users, err
+		// LIKE could be case insensitive, for example with MySQL
+		if isWebDAVPropertiesPathInTree(p, root) {
+			paths[id] = p
 		}
-		users = append(users, u)
 	}
-	err = rows.Err()
+	return paths, rows.Err()
+}
+
+func sqlCommonDeleteUserWebDAVProperties(username string, dbHandle *sql.DB) error {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultSQLQueryTimeout)
+	defer cancel()
+	q := getDeleteUserWebDAVPropertiesQuery()
+	stmt, err := dbHandle.PrepareContext(ctx, q)
 	if err != nil {
-		return users, err
+		providerLog(logger.LevelWarn, "error preparing database query %#v: %v", q, err)
+		return err
 	}
-	return getUsersWithVirtualFolders(ctx, users, dbHandle)
+	defer stmt.Close()
+	_, err = stmt.ExecContext(ctx, username)
+	return err
 }
 
-func sqlCommonGetRecentlyUpdatedUsers(after int64, dbHandle sqlQuerier) ([]User, error) {
-	users := make([]User, 0, 10)
+func sqlCommonGetS3AccessKey(accessKeyID string, dbHandle sqlQuerier) (S3AccessKey, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), defaultSQLQueryTimeout)
 	defer cancel()
-	q := getRecentlyUpdatedUsersQuery()
+	q := getS3AccessKeyQuery()
 	stmt, err := dbHandle.PrepareContext(ctx, q)
 	if err != nil {
 		providerLog(logger.LevelWarn, "error preparing database query %#v: %v", q, err)
-		return nil, err
+		return S3AccessKey{}, err
 	}
 	defer stmt.Close()
+	row := stmt.QueryRowContext(ctx, accessKeyID)
+	return getS3AccessKeyFromDbRow(row)
+}
 
-	rows, err := stmt.QueryContext(ctx, after)
-	if err == nil {
-		defer rows.Close()
-		for rows.Next() {
-			u, err := getUserFromDbRow(rows)
-			if err != nil {
-				return users, err
-			}
-			users = append(users, u)
-		}
+func sqlCommonAddS3AccessKey(key *S3AccessKey, dbHandle *sql.DB) error {
+	if err := key.validate(); err != nil {
+		return err
 	}
-	err = rows.Err()
+	secret, err := json.Marshal(key.SecretAccessKey)
 	if err != nil {
-		return users, err
+		return err
 	}
-	return getUsersWithVirtualFolders(ctx, users, dbHandle)
+	ctx, cancel := context.WithTimeout(context.Background(), defaultSQLQueryTimeout)
+	defer cancel()
+	q := getAddS3AccessKeyQuery()
+	stmt, err := dbHandle.PrepareContext(ctx, q)
+	if err != nil {
+		providerLog(logger.LevelWarn, "error preparing database query %#v: %v", q, err)
+		return err
+	}
+	defer stmt.Close()
+	_, err = stmt.ExecContext(ctx, key.AccessKeyID, key.Username, string(secret),
+		sql.NullString{String: key.Description, Valid: key.Description != ""}, key.CreatedAt)
+	return err
 }
 
-func sqlCommonGetUsers(limit int, offset int, order string, dbHandle sqlQuerier) ([]User, error) {
-	users := make([]User, 0, limit)
+func sqlCommonDeleteS3AccessKey(accessKeyID, username string, dbHandle *sql.DB) error {
 	ctx, cancel := context.WithTimeout(context.Background(), defaultSQLQueryTimeout)
 	defer cancel()
-	q := getUsersQuery(order)
+	q := getDeleteS3AccessKeyQuery()
 	stmt, err := dbHandle.PrepareContext(ctx, q)
 	if err != nil {
 		providerLog(logger.LevelWarn, "error preparing database query %#v: %v", q, err)
-		return nil, err
+		return err
 	}
 	defer stmt.Close()
-
-	rows, err := stmt.QueryContext(ctx, limit, offset)
-	if err == nil {
-		defer rows.Close()
-		for rows.Next() {
-			u, err := getUserFromDbRow(rows)
-			if err != nil {
-				return users, err
-			}
-			u.PrepareForRendering()
-			users = append(users, u)
-		}
-	}
-	err = rows.Err()
+	res, err := stmt.ExecContext(ctx, accessKeyID, username)
 	if err != nil {
-		return users, err
+		return err
 	}
-	return getUsersWithVirtualFolders(ctx, users, dbHandle)
+	return sqlCommonRequireRowAffected(res, fmt.Sprintf("S3 access key %#v does not exist", accessKeyID))
 }
 
-func getAPIKeyFromDbRow(row sqlScanner) (APIKey, error) {
-	var apiKey APIKey
-	var userID, adminID sql.NullInt64
-	var description sql.NullString
-
-	err := row.Scan(&apiKey.KeyID, &apiKey.Name, &apiKey.Key, &apiKey.Scope, &apiKey.CreatedAt, &apiKey.UpdatedAt,
-		&apiKey.LastUseAt, &apiKey.ExpiresAt, &description, &userID, &adminID)
+func sqlCommonGetUserS3AccessKeys(username string, dbHandle sqlQuerier) ([]S3AccessKey, error) {
+	keys := make([]S3AccessKey, 0, maxS3AccessKeysPerUser)
 
+	ctx, cancel := context.WithTimeout(context.Background(), defaultSQLQueryTimeout)
+	defer cancel()
+	q := getUserS3AccessKeysQuery()
+	stmt, err := dbHandle.PrepareContext(ctx, q)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return apiKey, util.NewRecordNotFoundError(err.Error())
-		}
-		return apiKey, err
-	}
-
-	if userID.Valid {
-		apiKey.userID = userID.Int64
+		providerLog(logger.LevelWarn, "error preparing database query %#v: %v", q, err)
+		return nil, err
 	}
-	if adminID.Valid {
-		apiKey.adminID = adminID.Int64
+	defer stmt.Close()
+	rows, err := stmt.QueryContext(ctx, username)
+	if err != nil {
+		return keys, err
 	}
-	if description.Valid {
-		apiKey.Description = description.String
+	defer rows.Close()
+
+	for rows.Next() {
+		key, err := getS3AccessKeyFromDbRow(rows)
+		if err != nil {
+			return keys, err
+		}
+		keys = append(keys, key)
 	}
 
-	return apiKey, nil
+	return keys, rows.Err()
 }
 
-func getAdminFromDbRow(row sqlScanner) (Admin, error) {
-	var admin Admin
-	var email, filters, additionalInfo, permissions, description sql.NullString
-
-	err := row.Scan(&admin.ID, &admin.Username, &admin.Password, &admin.Status, &email, &permissions,
-		&filters, &additionalInfo, &description, &admin.CreatedAt, &admin.UpdatedAt, &admin.LastLogin)
-
+func sqlCommonDeleteUserS3AccessKeys(username string, dbHandle *sql.DB) error {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultSQLQueryTimeout)
+	defer cancel()
+	q := getDeleteUserS3AccessKeysQuery()
+	stmt, err := dbHandle.PrepareContext(ctx, q)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return admin, util.NewRecordNotFoundError(err.Error())
-		}
-		return admin, err
-	}
-
-	if permissions.Valid {
-		var perms []string
-		err = json.Unmarshal([]byte(permissions.String), &perms)
-		if err != nil {
-			return admin, err
-		}
-		admin.Permissions = perms
+		providerLog(logger.LevelWarn, "error preparing database query %#v: %v", q, err)
+		return err
 	}
+	defer stmt.Close()
+	_, err = stmt.ExecContext(ctx, username)
+	return err
+}
 
-	if email.Valid {
-		admin.Email = email.String
+func sqlCommonUpdateS3AccessKey(key *S3AccessKey, dbHandle *sql.DB) error {
+	if err := key.validate(); err != nil {
+		return err
 	}
-	if filters.Valid {
-		var adminFilters AdminFilters
-		err = json.Unmarshal([]byte(filters.String), &adminFilters)
-		if err == nil {
-			admin.Filters = adminFilters
-		}
+	secret, err := json.Marshal(key.SecretAccessKey)
+	if err != nil {
+		return err
 	}
-	if additionalInfo.Valid {
-		admin.AdditionalInfo = additionalInfo.String
+	ctx, cancel := context.WithTimeout(context.Background(), defaultSQLQueryTimeout)
+	defer cancel()
+	q := getUpdateS3AccessKeyQuery()
+	stmt, err := dbHandle.PrepareContext(ctx, q)
+	if err != nil {
+		providerLog(logger.LevelWarn, "error preparing database query %#v: %v", q, err)
+		return err
 	}
-	if description.Valid {
-		admin.Description = description.String
+	defer stmt.Close()
+	res, err := stmt.ExecContext(ctx, key.Username, string(secret),
+		sql.NullString{String: key.Description, Valid: key.Description != ""}, key.CreatedAt, key.AccessKeyID)
+	if err != nil {
+		return err
 	}
-
-	admin.SetEmptySecretsIfNil()
-	return admin, nil
+	return sqlCommonRequireRowAffected(res, fmt.Sprintf("S3 access key %#v does not exist", key.AccessKeyID))
 }
 
-func getUserFromDbRow(row sqlScanner) (User, error) {
-	var user User
-	var permissions sql.NullString
-	var password sql.NullString
-	var publicKey sql.NullString
-	var filters sql.NullString
-	var fsConfig sql.NullString
-	var additionalInfo, description, email sql.NullString
+func sqlCommonDumpS3AccessKeys(dbHandle sqlQuerier) ([]S3AccessKey, error) {
+	keys := make([]S3AccessKey, 0, 30)
 
-	err := row.Scan(&user.ID, &user.Username, &password, &publicKey, &user.HomeDir, &user.UID, &user.GID, &user.MaxSessions,
-		&user.QuotaSize, &user.QuotaFiles, &permissions, &user.UsedQuotaSize, &user.UsedQuotaFiles, &user.LastQuotaUpdate,
-		&user.UploadBandwidth, &user.DownloadBandwidth, &user.ExpirationDate, &user.LastLogin, &user.Status, &filters, &fsConfig,
-		&additionalInfo, &description, &email, &user.CreatedAt, &user.UpdatedAt)
+	ctx, cancel := context.WithTimeout(context.Background(), longSQLQueryTimeout)
+	defer cancel()
+	q := getDumpS3AccessKeysQuery()
+	stmt, err := dbHandle.PrepareContext(ctx, q)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return user, util.NewRecordNotFoundError(err.Error())
-		}
-		return user, err
-	}
-	if password.Valid {
-		user.Password = password.String
+		providerLog(logger.LevelWarn, "error preparing database query %#v: %v", q, err)
+		return nil, err
 	}
-	// we can have a empty string or an invalid json in null string
-	// so we do a relaxed test if the field is optional, for example we
-	// populate public keys only if unmarshal does not return an error
-	if publicKey.Valid {
-		var list []string
-		err = json.Unmarshal([]byte(publicKey.String), &list)
-		if err == nil {
-			user.PublicKeys = list
-		}
+	defer stmt.Close()
+	rows, err := stmt.QueryContext(ctx)
+	if err != nil {
+		return keys, err
 	}
-	if permissions.Valid {
-		perms := make(map[string][]string)
-		err = json.Unmarshal([]byte(permissions.String), &perms)
+	defer rows.Close()
+
+	for rows.Next() {
+		key, err := getS3AccessKeyFromDbRow(rows)
 		if err != nil {
-			providerLog(logger.LevelWarn, "unable to deserialize permissions for user %#v: %v", user.Username, err)
-			return user, fmt.Errorf("unable to deserialize permissions for user %#v: %v", user.Username, err)
+			return keys, err
 		}
-		user.Permissions = perms
-	}
-	if filters.Valid {
-		var userFilters sdk.UserFilters
-		err = json.Unmarshal([]byte(filters.String), &userFilters)
-		if err == nil {
-			user.Filters = userFilters
-		}
-	}
-	if fsConfig.Valid {
-		var fs vfs.Filesystem
-		err = json.Unmarshal([]byte(fsConfig.String), &fs)
-		if err == nil {
-			user.FsConfig = fs
-		}
-	}
-	if additionalInfo.Valid {
-		user.AdditionalInfo = additionalInfo.String
-	}
-	if description.Valid {
-		user.Description = description.String
+		keys = append(keys, key)
 	}
-	if email.Valid {
-		user.Email = email.String
+
+	return keys, rows.Err()
+}
+
+func sqlCommonGetTusUpload(uploadID string, dbHandle sqlQuerier) (TusUpload, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultSQLQueryTimeout)
+	defer cancel()
+	q := getTusUploadQuery()
+	stmt, err := dbHandle.PrepareContext(ctx, q)
+	if err != nil {
+		providerLog(logger.LevelWarn, "error preparing database query %#v: %v", q, err)
+		return TusUpload{}, err
 	}
-	user.SetEmptySecretsIfNil()
-	return user, nil
+	defer stmt.Close()
+	row := stmt.QueryRowContext(ctx, uploadID)
+	return getTusUploadFromDbRow(row)
 }
 
-func sqlCommonCheckFolderExists(ctx context.Context, name string, dbHandle sqlQuerier) error {
-	var folderName string
-	q := checkFolderNameQuery()
+func sqlCommonAddTusUpload(upload *TusUpload, dbHandle *sql.DB) error {
+	if err := upload.validate(); err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), defaultSQLQueryTimeout)
+	defer cancel()
+	q := getAddTusUploadQuery()
 	stmt, err := dbHandle.PrepareContext(ctx, q)
 	if err != nil {
 		providerLog(logger.LevelWarn, "error preparing database query %#v: %v", q, err)
 		return err
 	}
 	defer stmt.Close()
-	row := stmt.QueryRowContext(ctx, name)
-	return row.Scan(&folderName)
+	_, err = stmt.ExecContext(ctx, upload.ID, upload.Username, upload.VirtualPath, upload.FsPath, upload.Length,
+		upload.Offset, upload.ExpiresAt, upload.CreatedAt, upload.UpdatedAt)
+	return err
 }
 
-func sqlCommonGetFolder(ctx context.Context, name string, dbHandle sqlQuerier) (vfs.BaseVirtualFolder, error) {
-	var folder vfs.BaseVirtualFolder
-	q := getFolderByNameQuery()
+func sqlCommonUpdateTusUploadOffset(uploadID string, offset, expiresAt, updatedAt int64, dbHandle *sql.DB) error {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultSQLQueryTimeout)
+	defer cancel()
+	q := getUpdateTusUploadOffsetQuery()
 	stmt, err := dbHandle.PrepareContext(ctx, q)
 	if err != nil {
 		providerLog(logger.LevelWarn, "error preparing database query %#v: %v", q, err)
-		return folder, err
+		return err
 	}
 	defer stmt.Close()
-	row := stmt.QueryRowContext(ctx, name)
-	var mappedPath, description, fsConfig sql.NullString
-	err = row.Scan(&folder.ID, &mappedPath, &folder.UsedQuotaSize, &folder.UsedQuotaFiles, &folder.LastQuotaUpdate,
-		&folder.Name, &description, &fsConfig)
-	if err == sql.ErrNoRows {
-		return folder, util.NewRecordNotFoundError(err.Error())
+	res, err := stmt.ExecContext(ctx, offset, expiresAt, updatedAt, uploadID)
+	if err != nil {
+		return err
 	}
-	if mappedPath.Valid {
-		folder.MappedPath = mappedPath.String
+	return sqlCommonRequireRowAffected(res, fmt.Sprintf("upload %#v does not exist", uploadID))
+}
+
+func sqlCommonDeleteTusUpload(uploadID string, dbHandle *sql.DB) error {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultSQLQueryTimeout)
+	defer cancel()
+	q := getDeleteTusUploadQuery()
+	stmt, err := dbHandle.PrepareContext(ctx, q)
+	if err != nil {
+		providerLog(logger.LevelWarn, "error preparing database query %#v: %v", q, err)
+		return err
 	}
-	if description.Valid {
-		folder.Description = description.String
+	defer stmt.Close()
+	res, err := stmt.ExecContext(ctx, uploadID)
+	if err != nil {
+		return err
 	}
-	if fsConfig.Valid {
-		var fs vfs.Filesystem
-		err = json.Unmarshal([]byte(fsConfig.String), &fs)
-		if err == nil {
-			folder.FsConfig = fs
-		}
+	return sqlCommonRequireRowAffected(res, fmt.Sprintf("upload %#v does not exist", uploadID))
+}
+
+func sqlCommonGetTusUploadsCount(username string, dbHandle sqlQuerier) (int, error) {
+	var count int
+
+	ctx, cancel := context.WithTimeout(context.Background(), defaultSQLQueryTimeout)
+	defer cancel()
+	q := getCountTusUploadsQuery()
+	stmt, err := dbHandle.PrepareContext(ctx, q)
+	if err != nil {
+		providerLog(logger.LevelWarn, "error preparing database query %#v: %v", q, err)
+		return count, err
 	}
-	return folder, err
+	defer stmt.Close()
+	err = stmt.QueryRowContext(ctx, username).Scan(&count)
+	return count, err
 }
 
-func sqlCommonGetFolderByName(ctx context.Context, name string, dbHandle sqlQuerier) (vfs.BaseVirtualFolder, error) {
-	folder, err := sqlCommonGetFolder(ctx, name, dbHandle)
+func sqlCommonGetExpiredTusUploads(before int64, dbHandle sqlQuerier) ([]TusUpload, error) {
+	uploads := make([]TusUpload, 0, 10)
+
+	ctx, cancel := context.WithTimeout(context.Background(), defaultSQLQueryTimeout)
+	defer cancel()
+	q := getExpiredTusUploadsQuery()
+	stmt, err := dbHandle.PrepareContext(ctx, q)
 	if err != nil {
-		return folder, err
+		providerLog(logger.LevelWarn, "error preparing database query %#v: %v", q, err)
+		return nil, err
 	}
-	folders, err := getVirtualFoldersWithUsers([]vfs.BaseVirtualFolder{folder}, dbHandle)
+	defer stmt.Close()
+	rows, err := stmt.QueryContext(ctx, before)
 	if err != nil {
-		return folder, err
+		return uploads, err
 	}
-	if len(folders) != 1 {
-		return folder, fmt.Errorf("unable to associate users with folder %#v", name)
+	defer rows.Close()
+
+	for rows.Next() {
+		upload, err := getTusUploadFromDbRow(rows)
+		if err != nil {
+			return uploads, err
+		}
+		uploads = append(uploads, upload)
 	}
-	return folders[0], nil
+
+	return uploads, rows.Err()
 }
 
-func sqlCommonAddOrUpdateFolder(ctx context.Context, baseFolder *vfs.BaseVirtualFolder, usedQuotaSize int64,
-	usedQuotaFiles int, lastQuotaUpdate int64, dbHandle sqlQuerier) (vfs.BaseVirtualFolder, error) {
-	var folder vfs.BaseVirtualFolder
+func sqlCommonDeleteUserTusUploads(username string, dbHandle *sql.DB) error {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultSQLQueryTimeout)
+	defer cancel()
+	q := getDeleteUserTusUploadsQuery()
+	stmt, err := dbHandle.PrepareContext(ctx, q)
+	if err != nil {
+		providerLog(logger.LevelWarn, "error preparing database query %#v: %v", q, err)
+		return err
+	}
+	defer stmt.Close()
+	_, err = stmt.ExecContext(ctx, username)
+	return err
+}
+
+func sqlCommonGetResetCode(code string, dbHandle sqlQuerier) (ResetCode, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultSQLQueryTimeout)
+	defer cancel()
+	q := getResetCodeQuery()
+	stmt, err := dbHandle.PrepareContext(ctx, q)
+	if err != nil {
+		providerLog(logger.LevelWarn, "error preparing database query %#v: %v", q, err)
+		return ResetCode{}, err
+	}
+	defer stmt.Close()
+	row := stmt.QueryRowContext(ctx, code)
+	return getResetCodeFromDbRow(row)
+}
+
+func sqlCommonAddResetCode(code *ResetCode, dbHandle *sql.DB) error {
+	if err := code.validate(); err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), defaultSQLQueryTimeout)
+	defer cancel()
+	q := getAddResetCodeQuery()
+	stmt, err := dbHandle.PrepareContext(ctx, q)
+	if err != nil {
+		providerLog(logger.LevelWarn, "error preparing database query %#v: %v", q, err)
+		return err
+	}
+	defer stmt.Close()
+	_, err = stmt.ExecContext(ctx, code.Code, code.Username, code.Type, code.PasswordFingerprint, code.ExpiresAt,
+		code.CreatedAt)
+	return err
+}
+
+func sqlCommonDeleteResetCode(code string, dbHandle *sql.DB) error {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultSQLQueryTimeout)
+	defer cancel()
+	q := getDeleteResetCodeQuery()
+	stmt, err := dbHandle.PrepareContext(ctx, q)
+	if err != nil {
+		providerLog(logger.LevelWarn, "error preparing database query %#v: %v", q, err)
+		return err
+	}
+	defer stmt.Close()
+	res, err := stmt.ExecContext(ctx, code)
+	if err != nil {
+		return err
+	}
+	return sqlCommonRequireRowAffected(res, "reset code does not exist")
+}
+
+func sqlCommonGetResetCodesCount(username, accountType string, after int64, dbHandle sqlQuerier) (int, error) {
+	var count int
+
+	ctx, cancel := context.WithTimeout(context.Background(), defaultSQLQueryTimeout)
+	defer cancel()
+	q := getCountResetCodesQuery()
+	stmt, err := dbHandle.PrepareContext(ctx, q)
+	if err != nil {
+		providerLog(logger.LevelWarn, "error preparing database query %#v: %v", q, err)
+		return count, err
+	}
+	defer stmt.Close()
+	err = stmt.QueryRowContext(ctx, username, accountType, after).Scan(&count)
+	return count, err
+}
+
+func sqlCommonDeleteExpiredResetCodes(before int64, dbHandle *sql.DB) error {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultSQLQueryTimeout)
+	defer cancel()
+	q := getDeleteExpiredResetCodesQuery()
+	stmt, err := dbHandle.PrepareContext(ctx, q)
+	if err != nil {
+		providerLog(logger.LevelWarn, "error preparing database query %#v: %v", q, err)
+		return err
+	}
+	defer stmt.Close()
+	_, err = stmt.ExecContext(ctx, before)
+	return err
+}
+
+func sqlCommonGetMultipartUpload(key string, dbHandle sqlQuerier) (vfs.MultipartUpload, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultSQLQueryTimeout)
+	defer cancel()
+	q := getMultipartUploadQuery()
+	stmt, err := dbHandle.PrepareContext(ctx, q)
+	if err != nil {
+		providerLog(logger.LevelWarn, "error preparing database query %#v: %v", q, err)
+		return vfs.MultipartUpload{}, err
+	}
+	defer stmt.Close()
+	row := stmt.QueryRowContext(ctx, key)
+	return getMultipartUploadFromDbRow(row)
+}
+
+func sqlCommonSaveMultipartUpload(upload *vfs.MultipartUpload, dbHandle *sql.DB) error {
+	if err := validateMultipartUpload(upload); err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), defaultSQLQueryTimeout)
+	defer cancel()
+	q := getUpdateMultipartUploadQuery()
+	stmt, err := dbHandle.PrepareContext(ctx, q)
+	if err != nil {
+		providerLog(logger.LevelWarn, "error preparing database query %#v: %v", q, err)
+		return err
+	}
+	defer stmt.Close()
+	res, err := stmt.ExecContext(ctx, upload.Size, upload.State, upload.UpdatedAt, upload.Key)
+	if err != nil {
+		return err
+	}
+	if affected, err := res.RowsAffected(); err == nil && affected > 0 {
+		return nil
+	}
+	q = getAddMultipartUploadQuery()
+	insertStmt, err := dbHandle.PrepareContext(ctx, q)
+	if err != nil {
+		providerLog(logger.LevelWarn, "error preparing database query %#v: %v", q, err)
+		return err
+	}
+	defer insertStmt.Close()
+	_, err = insertStmt.ExecContext(ctx, upload.Key, upload.Size, upload.State, upload.CreatedAt, upload.UpdatedAt)
+	return err
+}
+
+func sqlCommonDeleteMultipartUpload(key string, dbHandle *sql.DB) error {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultSQLQueryTimeout)
+	defer cancel()
+	q := getDeleteMultipartUploadQuery()
+	stmt, err := dbHandle.PrepareContext(ctx, q)
+	if err != nil {
+		providerLog(logger.LevelWarn, "error preparing database query %#v: %v", q, err)
+		return err
+	}
+	defer stmt.Close()
+	res, err := stmt.ExecContext(ctx, key)
+	if err != nil {
+		return err
+	}
+	return sqlCommonRequireRowAffected(res, "multipart upload does not exist")
+}
+
+func sqlCommonCleanupMultipartUploads(before int64, dbHandle *sql.DB) error {
+	ctx, cancel := context.WithTimeout(context.Background(), longSQLQueryTimeout)
+	defer cancel()
+	q := getCleanupMultipartUploadsQuery()
+	stmt, err := dbHandle.PrepareContext(ctx, q)
+	if err != nil {
+		providerLog(logger.LevelWarn, "error preparing database query %#v: %v", q, err)
+		return err
+	}
+	defer stmt.Close()
+	_, err = stmt.ExecContext(ctx, before)
+	return err
+}
+
+func sqlCommonRequireRowAffected(res sql.Result, notFoundMessage string) error {
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return util.NewRecordNotFoundError(notFoundMessage)
+	}
+	return nil
+}
+
+func sqlCommonAddFsEvent(event *FsEvent, dbHandle *sql.DB) error {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultSQLQueryTimeout)
+	defer cancel()
+	q := getAddFsEventQuery()
+	stmt, err := dbHandle.PrepareContext(ctx, q)
+	if err != nil {
+		providerLog(logger.LevelWarn, "error preparing database query %#v: %v", q, err)
+		return err
+	}
+	defer stmt.Close()
+	_, err = stmt.ExecContext(ctx, event.ID, event.Timestamp, event.Action, event.Username, event.FsPath,
+		event.FsTargetPath, event.VirtualPath, event.VirtualTargetPath, event.SSHCmd, event.FileSize, event.Status,
+		event.Protocol, event.IP, event.InstanceID)
+	return err
+}
+
+func sqlCommonAddProviderEvent(event *ProviderEvent, dbHandle *sql.DB) error {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultSQLQueryTimeout)
+	defer cancel()
+	q := getAddProviderEventQuery()
+	stmt, err := dbHandle.PrepareContext(ctx, q)
+	if err != nil {
+		providerLog(logger.LevelWarn, "error preparing database query %#v: %v", q, err)
+		return err
+	}
+	defer stmt.Close()
+	_, err = stmt.ExecContext(ctx, event.ID, event.Timestamp, event.Action, event.Username, event.IP,
+		event.ObjectType, event.ObjectName, event.InstanceID)
+	return err
+}
+
+func sqlCommonSearchFsEvents(search *FsEventSearch, dbHandle sqlQuerier) ([]FsEvent, error) {
+	events := make([]FsEvent, 0, search.Limit)
+
+	ctx, cancel := context.WithTimeout(context.Background(), longSQLQueryTimeout)
+	defer cancel()
+	q, args := getSearchFsEventsQuery(search)
+	stmt, err := dbHandle.PrepareContext(ctx, q)
+	if err != nil {
+		providerLog(logger.LevelWarn, "error preparing database query %#v: %v", q, err)
+		return nil, err
+	}
+	defer stmt.Close()
+	rows, err := stmt.QueryContext(ctx, args...)
+	if err != nil {
+		return events, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var event FsEvent
+		err = rows.Scan(&event.ID, &event.Timestamp, &event.Action, &event.Username, &event.FsPath,
+			&event.FsTargetPath, &event.VirtualPath, &event.VirtualTargetPath, &event.SSHCmd, &event.FileSize,
+			&event.Status, &event.Protocol, &event.IP, &event.InstanceID)
+		if err != nil {
+			return events, err
+		}
+		events = append(events, event)
+	}
+
+	return events, rows.Err()
+}
+
+func sqlCommonSearchProviderEvents(search *ProviderEventSearch, dbHandle sqlQuerier) ([]ProviderEvent, error) {
+	events := make([]ProviderEvent, 0, search.Limit)
+
+	ctx, cancel := context.WithTimeout(context.Background(), longSQLQueryTimeout)
+	defer cancel()
+	q, args := getSearchProviderEventsQuery(search)
+	stmt, err := dbHandle.PrepareContext(ctx, q)
+	if err != nil {
+		providerLog(logger.LevelWarn, "error preparing database query %#v: %v", q, err)
+		return nil, err
+	}
+	defer stmt.Close()
+	rows, err := stmt.QueryContext(ctx, args...)
+	if err != nil {
+		return events, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var event ProviderEvent
+		err = rows.Scan(&event.ID, &event.Timestamp, &event.Action, &event.Username, &event.IP,
+			&event.ObjectType, &event.ObjectName, &event.InstanceID)
+		if err != nil {
+			return events, err
+		}
+		events = append(events, event)
+	}
+
+	return events, rows.Err()
+}
+
+func sqlCommonCleanupEvents(before int64, dbHandle *sql.DB) error {
+	ctx, cancel := context.WithTimeout(context.Background(), longSQLQueryTimeout)
+	defer cancel()
+
+	for _, q := range []string{getCleanupFsEventsQuery(), getCleanupProviderEventsQuery()} {
+		stmt, err := dbHandle.PrepareContext(ctx, q)
+		if err != nil {
+			providerLog(logger.LevelWarn, "error preparing database query %#v: %v", q, err)
+			return err
+		}
+		_, err = stmt.ExecContext(ctx, before)
+		stmt.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func sqlCommonGetAPIKeyByID(keyID string, dbHandle sqlQuerier) (APIKey, error) {
+	var apiKey APIKey
+	ctx, cancel := context.WithTimeout(context.Background(), defaultSQLQueryTimeout)
+	defer cancel()
+	q := getAPIKeyByIDQuery()
+	stmt, err := dbHandle.PrepareContext(ctx, q)
+	if err != nil {
+		providerLog(logger.LevelWarn, "error preparing database query %#v: %v", q, err)
+		return apiKey, err
+	}
+	defer stmt.Close()
+	row := stmt.QueryRowContext(ctx, keyID)
+
+	apiKey, err = getAPIKeyFromDbRow(row)
+	if err != nil {
+		return apiKey, err
+	}
+	return getAPIKeyWithRelatedFields(ctx, apiKey, dbHandle)
+}
+
+func sqlCommonAddAPIKey(apiKey *APIKey, dbHandle *sql.DB) error {
+	err := apiKey.validate()
+	if err != nil {
+		return err
+	}
+
+	userID, adminID, err := sqlCommonGetAPIKeyRelatedIDs(apiKey)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), defaultSQLQueryTimeout)
+	defer cancel()
+	q := getAddAPIKeyQuery()
+	stmt, err := dbHandle.PrepareContext(ctx, q)
+	if err != nil {
+		providerLog(logger.LevelWarn, "error preparing database query %#v: %v", q, err)
+		return err
+	}
+	defer stmt.Close()
+
+	_, err = stmt.ExecContext(ctx, apiKey.KeyID, apiKey.Name, apiKey.Key, apiKey.Scope, util.GetTimeAsMsSinceEpoch(time.Now()),
+		util.GetTimeAsMsSinceEpoch(time.Now()), apiKey.LastUseAt, apiKey.ExpiresAt, apiKey.Description,
+		userID, adminID)
+	return err
+}
+
+func sqlCommonUpdateAPIKey(apiKey *APIKey, dbHandle *sql.DB) error {
+	err := apiKey.validate()
+	if err != nil {
+		return err
+	}
+
+	userID, adminID, err := sqlCommonGetAPIKeyRelatedIDs(apiKey)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), defaultSQLQueryTimeout)
+	defer cancel()
+	q := getUpdateAPIKeyQuery()
+	stmt, err := dbHandle.PrepareContext(ctx, q)
+	if err != nil {
+		providerLog(logger.LevelWarn, "error preparing database query %#v: %v", q, err)
+		return err
+	}
+	defer stmt.Close()
+
+	_, err = stmt.ExecContext(ctx, apiKey.Name, apiKey.Scope, apiKey.ExpiresAt, userID, adminID,
+		apiKey.Description, util.GetTimeAsMsSinceEpoch(time.Now()), apiKey.KeyID)
+	return err
+}
+
+func sqlCommonDeleteAPIKey(apiKey *APIKey, dbHandle *sql.DB) error {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultSQLQueryTimeout)
+	defer cancel()
+	q := getDeleteAPIKeyQuery()
+	stmt, err := dbHandle.PrepareContext(ctx, q)
+	if err != nil {
+		providerLog(logger.LevelWarn, "error preparing database query %#v: %v", q, err)
+		return err
+	}
+	defer stmt.Close()
+	_, err = stmt.ExecContext(ctx, apiKey.KeyID)
+	return err
+}
+
+func sqlCommonGetAPIKeys(limit, offset int, order string, dbHandle sqlQuerier) ([]APIKey, error) {
+	apiKeys := make([]APIKey, 0, limit)
+
+	ctx, cancel := context.WithTimeout(context.Background(), defaultSQLQueryTimeout)
+	defer cancel()
+	q := getAPIKeysQuery(order)
+	stmt, err := dbHandle.PrepareContext(ctx, q)
+	if err != nil {
+		providerLog(logger.LevelWarn, "error preparing database query %#v: %v", q, err)
+		return nil, err
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.QueryContext(ctx, limit, offset)
+	if err != nil {
+		return apiKeys, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		k, err := getAPIKeyFromDbRow(rows)
+		if err != nil {
+			return apiKeys, err
+		}
+		k.HideConfidentialData()
+		apiKeys = append(apiKeys, k)
+	}
+	err = rows.Err()
+	if err != nil {
+		return apiKeys, err
+	}
+	apiKeys, err = getRelatedValuesForAPIKeys(ctx, apiKeys, dbHandle, APIKeyScopeAdmin)
+	if err != nil {
+		return apiKeys, err
+	}
+
+	return getRelatedValuesForAPIKeys(ctx, apiKeys, dbHandle, APIKeyScopeUser)
+}
+
+func sqlCommonDumpAPIKeys(dbHandle sqlQuerier) ([]APIKey, error) {
+	apiKeys := make([]APIKey, 0, 30)
+
+	ctx, cancel := context.WithTimeout(context.Background(), defaultSQLQueryTimeout)
+	defer cancel()
+	q := getDumpAPIKeysQuery()
+	stmt, err := dbHandle.PrepareContext(ctx, q)
+	if err != nil {
+		providerLog(logger.LevelWarn, "error preparing database query %#v: %v", q, err)
+		return nil, err
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.QueryContext(ctx)
+	if err != nil {
+		return apiKeys, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		k, err := getAPIKeyFromDbRow(rows)
+		if err != nil {
+			return apiKeys, err
+		}
+		apiKeys = append(apiKeys, k)
+	}
+	err = rows.Err()
+	if err != nil {
+		return apiKeys, err
+	}
+	apiKeys, err = getRelatedValuesForAPIKeys(ctx, apiKeys, dbHandle, APIKeyScopeAdmin)
+	if err != nil {
+		return apiKeys, err
+	}
+
+	return getRelatedValuesForAPIKeys(ctx, apiKeys, dbHandle, APIKeyScopeUser)
+}
+
+func sqlCommonGetAdminByUsername(username string, dbHandle sqlQuerier) (Admin, error) {
+	var admin Admin
+	ctx, cancel := context.WithTimeout(context.Background(), defaultSQLQueryTimeout)
+	defer cancel()
+	q := getAdminByUsernameQuery()
+	stmt, err := dbHandle.PrepareContext(ctx, q)
+	if err != nil {
+		providerLog(logger.LevelWarn, "error preparing database query %#v: %v", q, err)
+		return admin, err
+	}
+	defer stmt.Close()
+	row := stmt.QueryRowContext(ctx, username)
+
+	return getAdminFromDbRow(row)
+}
+
+func sqlCommonValidateAdminAndPass(username, password, ip string, dbHandle *sql.DB) (Admin, error) {
+	admin, err := sqlCommonGetAdminByUsername(username, dbHandle)
+	if err != nil {
+		providerLog(logger.LevelWarn, "error authenticating admin %#v: %v", username, err)
+		return admin, ErrInvalidCredentials
+	}
+	err = admin.checkUserAndPass(password, ip)
+	return admin, err
+}
+
+func sqlCommonAddAdmin(admin *Admin, dbHandle *sql.DB) error {
+	err := admin.validate()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), defaultSQLQueryTimeout)
+	defer cancel()
+	q := getAddAdminQuery()
+	stmt, err := dbHandle.PrepareContext(ctx, q)
+	if err != nil {
+		providerLog(logger.LevelWarn, "error preparing database query %#v: %v", q, err)
+		return err
+	}
+	defer stmt.Close()
+
+	perms, err := json.Marshal(admin.Permissions)
+	if err != nil {
+		return err
+	}
+
+	filters, err := json.Marshal(admin.Filters)
+	if err != nil {
+		return err
+	}
+
+	_, err = stmt.ExecContext(ctx, admin.Username, admin.Password, admin.Status, admin.Email, string(perms),
+		string(filters), admin.AdditionalInfo, admin.Description, util.GetTimeAsMsSinceEpoch(time.Now()),
+		util.GetTimeAsMsSinceEpoch(time.Now()), admin.LastPasswordChange)
+	return err
+}
+
+func sqlCommonUpdateAdmin(admin *Admin, dbHandle *sql.DB) error {
+	err := admin.validate()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), defaultSQLQueryTimeout)
+	defer cancel()
+
+	perms, err := json.Marshal(admin.Permissions)
+	if err != nil {
+		return err
+	}
+
+	filters, err := json.Marshal(admin.Filters)
+	if err != nil {
+		return err
+	}
+
+	return sqlCommonExecuteTx(ctx, dbHandle, func(tx *sql.Tx) error {
+		if err := sqlCommonUpdateAdminLastPasswordChange(ctx, admin, tx); err != nil {
+			return err
+		}
+		q := getUpdateAdminQuery()
+		stmt, err := tx.PrepareContext(ctx, q)
+		if err != nil {
+			providerLog(logger.LevelWarn, "error preparing database query %#v: %v", q, err)
+			return err
+		}
+		defer stmt.Close()
+
+		_, err = stmt.ExecContext(ctx, admin.Password, admin.Status, admin.Email, string(perms), string(filters),
+			admin.AdditionalInfo, admin.Description, util.GetTimeAsMsSinceEpoch(time.Now()), admin.Username)
+		return err
+	})
+}
+
+// sqlCommonUpdateAdminLastPasswordChange sets the last password change time
+// if the password to save differs from the stored one
+func sqlCommonUpdateAdminLastPasswordChange(ctx context.Context, admin *Admin, dbHandle sqlQuerier) error {
+	q := getUpdateAdminLastPasswordChangeQuery()
+	stmt, err := dbHandle.PrepareContext(ctx, q)
+	if err != nil {
+		providerLog(logger.LevelWarn, "error preparing database query %#v: %v", q, err)
+		return err
+	}
+	defer stmt.Close()
+	_, err = stmt.ExecContext(ctx, util.GetTimeAsMsSinceEpoch(time.Now()), admin.Username, admin.Password)
+	return err
+}
+
+func sqlCommonDeleteAdmin(admin *Admin, dbHandle *sql.DB) error {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultSQLQueryTimeout)
+	defer cancel()
+	q := getDeleteAdminQuery()
+	stmt, err := dbHandle.PrepareContext(ctx, q)
+	if err != nil {
+		providerLog(logger.LevelWarn, "error preparing database query %#v: %v", q, err)
+		return err
+	}
+	defer stmt.Close()
+	_, err = stmt.ExecContext(ctx, admin.Username)
+	return err
+}
+
+func sqlCommonGetAdmins(limit, offset int, order string, dbHandle sqlQuerier) ([]Admin, error) {
+	admins := make([]Admin, 0, limit)
+
+	ctx, cancel := context.WithTimeout(context.Background(), defaultSQLQueryTimeout)
+	defer cancel()
+	q := getAdminsQuery(order)
+	stmt, err := dbHandle.PrepareContext(ctx, q)
+	if err != nil {
+		providerLog(logger.LevelWarn, "error preparing database query %#v: %v", q, err)
+		return nil, err
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.QueryContext(ctx, limit, offset)
+	if err != nil {
+		return admins, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		a, err := getAdminFromDbRow(rows)
+		if err != nil {
+			return admins, err
+		}
+		a.HideConfidentialData()
+		admins = append(admins, a)
+	}
+
+	return admins, rows.Err()
+}
+
+func sqlCommonDumpAdmins(dbHandle sqlQuerier) ([]Admin, error) {
+	admins := make([]Admin, 0, 30)
+
+	ctx, cancel := context.WithTimeout(context.Background(), defaultSQLQueryTimeout)
+	defer cancel()
+	q := getDumpAdminsQuery()
+	stmt, err := dbHandle.PrepareContext(ctx, q)
+	if err != nil {
+		providerLog(logger.LevelWarn, "error preparing database query %#v: %v", q, err)
+		return nil, err
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.QueryContext(ctx)
+	if err != nil {
+		return admins, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		a, err := getAdminFromDbRow(rows)
+		if err != nil {
+			return admins, err
+		}
+		admins = append(admins, a)
+	}
+
+	return admins, rows.Err()
+}
+
+func sqlCommonGetUserByUsername(username string, dbHandle sqlQuerier) (User, error) {
+	var user User
+	ctx, cancel := context.WithTimeout(context.Background(), defaultSQLQueryTimeout)
+	defer cancel()
+	q := getUserByUsernameQuery()
+	stmt, err := dbHandle.PrepareContext(ctx, q)
+	if err != nil {
+		providerLog(logger.LevelWarn, "error preparing database query %#v: %v", q, err)
+		return user, err
+	}
+	defer stmt.Close()
+
+	row := stmt.QueryRowContext(ctx, username)
+	user, err = getUserFromDbRow(row)
+	if err != nil {
+		return user, err
+	}
+	return getUserWithVirtualFolders(ctx, user, dbHandle)
+}
+
+func sqlCommonValidateUserAndPass(username, password, ip, protocol string, dbHandle *sql.DB) (User, error) {
+	var user User
+	if password == "" {
+		return user, errors.New("credentials cannot be null or empty")
+	}
+	user, err := sqlCommonGetUserByUsername(username, dbHandle)
+	if err != nil {
+		providerLog(logger.LevelWarn, "error authenticating user %#v: %v", username, err)
+		return user, err
+	}
+	return checkUserAndPass(&user, password, ip, protocol)
+}
+
+func sqlCommonValidateUserAndTLSCertificate(username, protocol string, tlsCert *x509.Certificate, dbHandle *sql.DB) (User, error) {
+	var user User
+	if tlsCert == nil {
+		return user, errors.New("TLS certificate cannot be null or empty")
+	}
+	user, err := sqlCommonGetUserByUsername(username, dbHandle)
+	if err != nil {
+		providerLog(logger.LevelWarn, "error authenticating user %#v: %v", username, err)
+		return user, err
+	}
+	return checkUserAndTLSCertificate(&user, protocol, tlsCert)
+}
+
+func sqlCommonValidateUserAndPubKey(username string, pubKey []byte, dbHandle *sql.DB) (User, string, error) {
+	var user User
+	if len(pubKey) == 0 {
+		return user, "", errors.New("credentials cannot be null or empty")
+	}
+	user, err := sqlCommonGetUserByUsername(username, dbHandle)
+	if err != nil {
+		providerLog(logger.LevelWarn, "error authenticating user %#v: %v", username, err)
+		return user, "", err
+	}
+	return checkUserAndPubKey(&user, pubKey)
+}
+
+func sqlCommonCheckAvailability(dbHandle *sql.DB) error {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultSQLQueryTimeout)
+	defer cancel()
+	return dbHandle.PingContext(ctx)
+}
+
+func sqlCommonUpdateQuota(username string, filesAdd int, sizeAdd int64, reset bool, dbHandle *sql.DB) error {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultSQLQueryTimeout)
+	defer cancel()
+	q := getUpdateQuotaQuery(reset)
+	stmt, err := dbHandle.PrepareContext(ctx, q)
+	if err != nil {
+		providerLog(logger.LevelWarn, "error preparing database query %#v: %v", q, err)
+		return err
+	}
+	defer stmt.Close()
+	_, err = stmt.ExecContext(ctx, sizeAdd, filesAdd, util.GetTimeAsMsSinceEpoch(time.Now()), username)
+	if err == nil {
+		providerLog(logger.LevelDebug, "quota updated for user %#v, files increment: %v size increment: %v is reset? %v",
+			username, filesAdd, sizeAdd, reset)
+	} else {
+		providerLog(logger.LevelWarn, "error updating quota for user %#v: %v", username, err)
+	}
+	return err
+}
+
+func sqlCommonGetUsedQuota(username string, dbHandle *sql.DB) (int, int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultSQLQueryTimeout)
+	defer cancel()
+	q := getQuotaQuery()
+	stmt, err := dbHandle.PrepareContext(ctx, q)
+	if err != nil {
+		providerLog(logger.LevelWarn, "error preparing database query %#v: %v", q, err)
+		return 0, 0, err
+	}
+	defer stmt.Close()
+
+	var usedFiles int
+	var usedSize int64
+	err = stmt.QueryRowContext(ctx, username).Scan(&usedSize, &usedFiles)
+	if err != nil {
+		providerLog(logger.LevelWarn, "error getting quota for user: %v, error: %v", username, err)
+		return 0, 0, err
+	}
+	return usedFiles, usedSize, err
+}
+
+func sqlCommonUpdateTransferQuota(username string, uploadSize, downloadSize int64, reset bool, periodStart int64,
+	dbHandle *sql.DB) error {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultSQLQueryTimeout)
+	defer cancel()
+	q := getUpdateTransferQuotaQuery(reset)
+	stmt, err := dbHandle.PrepareContext(ctx, q)
+	if err != nil {
+		providerLog(logger.LevelWarn, "error preparing database query %#v: %v", q, err)
+		return err
+	}
+	defer stmt.Close()
+	_, err = stmt.ExecContext(ctx, getUpdateTransferQuotaArgs(uploadSize, downloadSize, reset, periodStart, username)...)
+	if err == nil {
+		providerLog(logger.LevelDebug, "transfer quota updated for user %#v, ul increment: %v dl increment: %v is reset? %v",
+			username, uploadSize, downloadSize, reset)
+	} else {
+		providerLog(logger.LevelWarn, "error updating transfer quota for user %#v: %v", username, err)
+	}
+	return err
+}
+
+func sqlCommonGetUsedTransferQuota(username string, dbHandle *sql.DB) (int64, int64, int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultSQLQueryTimeout)
+	defer cancel()
+	q := getTransferQuotaQuery()
+	stmt, err := dbHandle.PrepareContext(ctx, q)
+	if err != nil {
+		providerLog(logger.LevelWarn, "error preparing database query %#v: %v", q, err)
+		return 0, 0, 0, err
+	}
+	defer stmt.Close()
+
+	var ulSize, dlSize, lastReset int64
+	err = stmt.QueryRowContext(ctx, username).Scan(&ulSize, &dlSize, &lastReset)
+	if err != nil {
+		providerLog(logger.LevelWarn, "error getting transfer quota for user: %v, error: %v", username, err)
+		return 0, 0, 0, err
+	}
+	return ulSize, dlSize, lastReset, err
+}
+
+func getUpdateTransferQuotaArgs(uploadSize, downloadSize int64, reset bool, periodStart int64, key string) []interface{} {
+	now := util.GetTimeAsMsSinceEpoch(time.Now())
+	if reset {
+		return []interface{}{uploadSize, downloadSize, now, key}
+	}
+	return []interface{}{periodStart, uploadSize, uploadSize, periodStart, downloadSize, downloadSize, periodStart,
+		now, key}
+}
+
+func sqlCommonUpdateAPIKeyLastUse(keyID string, dbHandle *sql.DB) error {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultSQLQueryTimeout)
+	defer cancel()
+	q := getUpdateAPIKeyLastUseQuery()
+	stmt, err := dbHandle.PrepareContext(ctx, q)
+	if err != nil {
+		providerLog(logger.LevelWarn, "error preparing database query %#v: %v", q, err)
+		return err
+	}
+	defer stmt.Close()
+	_, err = stmt.ExecContext(ctx, util.GetTimeAsMsSinceEpoch(time.Now()), keyID)
+	if err == nil {
+		providerLog(logger.LevelDebug, "last use updated for key %#v", keyID)
+	} else {
+		providerLog(logger.LevelWarn, "error updating last use for key %#v: %v", keyID, err)
+	}
+	return err
+}
+
+func sqlCommonUpdateAdminLastLogin(username string, dbHandle *sql.DB) error {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultSQLQueryTimeout)
+	defer cancel()
+	q := getUpdateAdminLastLoginQuery()
+	stmt, err := dbHandle.PrepareContext(ctx, q)
+	if err != nil {
+		providerLog(logger.LevelWarn, "error preparing database query %#v: %v", q, err)
+		return err
+	}
+	defer stmt.Close()
+	_, err = stmt.ExecContext(ctx, util.GetTimeAsMsSinceEpoch(time.Now()), username)
+	if err == nil {
+		providerLog(logger.LevelDebug, "last login updated for admin %#v", username)
+	} else {
+		providerLog(logger.LevelWarn, "error updating last login for admin %#v: %v", username, err)
+	}
+	return err
+}
+
+func sqlCommonSetUpdatedAt(username string, dbHandle *sql.DB) {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultSQLQueryTimeout)
+	defer cancel()
+	q := getSetUpdateAtQuery()
+	stmt, err := dbHandle.PrepareContext(ctx, q)
+	if err != nil {
+		providerLog(logger.LevelWarn, "error preparing database query %#v: %v", q, err)
+		return
+	}
+	defer stmt.Close()
+	_, err = stmt.ExecContext(ctx, util.GetTimeAsMsSinceEpoch(time.Now()), username)
+	if err == nil {
+		providerLog(logger.LevelDebug, "updated_at set for user %#v", username)
+	} else {
+		providerLog(logger.LevelWarn, "error setting updated_at for user %#v: %v", username, err)
+	}
+}
+
+func sqlCommonUpdateLastLogin(username string, dbHandle *sql.DB) error {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultSQLQueryTimeout)
+	defer cancel()
+	q := getUpdateLastLoginQuery()
+	stmt, err := dbHandle.PrepareContext(ctx, q)
+	if err != nil {
+		providerLog(logger.LevelWarn, "error preparing database query %#v: %v", q, err)
+		return err
+	}
+	defer stmt.Close()
+	_, err = stmt.ExecContext(ctx, util.GetTimeAsMsSinceEpoch(time.Now()), username)
+	if err == nil {
+		providerLog(logger.LevelDebug, "last login updated for user %#v", username)
+	} else {
+		providerLog(logger.LevelWarn, "error updating last login for user %#v: %v", username, err)
+	}
+	return err
+}
+
+func sqlCommonAddUser(user *User, dbHandle *sql.DB) error {
+	err := ValidateUser(user)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), defaultSQLQueryTimeout)
+	defer cancel()
+
+	return sqlCommonExecuteTx(ctx, dbHandle, func(tx *sql.Tx) error {
+		q := getAddUserQuery()
+		stmt, err := tx.PrepareContext(ctx, q)
+		if err != nil {
+			providerLog(logger.LevelWarn, "error preparing database query %#v: %v", q, err)
+			return err
+		}
+		defer stmt.Close()
+		permissions, err := user.GetPermissionsAsJSON()
+		if err != nil {
+			return err
+		}
+		publicKeys, err := user.GetPublicKeysAsJSON()
+		if err != nil {
+			return err
+		}
+		filters, err := user.GetFiltersAsJSON()
+		if err != nil {
+			return err
+		}
+		fsConfig, err := user.GetFsConfigAsJSON()
+		if err != nil {
+			return err
+		}
+		_, err = stmt.ExecContext(ctx, user.Username, user.Password, string(publicKeys), user.HomeDir, user.UID, user.GID, user.MaxSessions, user.QuotaSize,
+			user.QuotaFiles, string(permissions), user.UploadBandwidth, user.DownloadBandwidth, user.Status, user.ExpirationDate, string(filters),
+			string(fsConfig), user.AdditionalInfo, user.Description, user.Email, util.GetTimeAsMsSinceEpoch(time.Now()),
+			util.GetTimeAsMsSinceEpoch(time.Now()), user.UploadDataTransfer, user.DownloadDataTransfer, user.TotalDataTransfer,
+			string(user.DataTransferResetPeriod), user.LastPasswordChange)
+		if err != nil {
+			return err
+		}
+		if err := generateVirtualFoldersMapping(ctx, user, tx); err != nil {
+			return err
+		}
+		return generateGroupsMapping(ctx, user, tx)
+	})
+}
+
+func sqlCommonUpdateUser(user *User, dbHandle *sql.DB) error {
+	err := ValidateUser(user)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), defaultSQLQueryTimeout)
+	defer cancel()
+
+	return sqlCommonExecuteTx(ctx, dbHandle, func(tx *sql.Tx) error {
+		if err := sqlCommonUpdateUserLastPasswordChange(ctx, user, tx); err != nil {
+			return err
+		}
+		q := getUpdateUserQuery()
+		stmt, err := tx.PrepareContext(ctx, q)
+		if err != nil {
+			providerLog(logger.LevelWarn, "error preparing database query %#v: %v", q, err)
+			return err
+		}
+		defer stmt.Close()
+		permissions, err := user.GetPermissionsAsJSON()
+		if err != nil {
+			return err
+		}
+		publicKeys, err := user.GetPublicKeysAsJSON()
+		if err != nil {
+			return err
+		}
+		filters, err := user.GetFiltersAsJSON()
+		if err != nil {
+			return err
+		}
+		fsConfig, err := user.GetFsConfigAsJSON()
+		if err != nil {
+			return err
+		}
+		_, err = stmt.ExecContext(ctx, user.Password, string(publicKeys), user.HomeDir, user.UID, user.GID, user.MaxSessions, user.QuotaSize,
+			user.QuotaFiles, string(permissions), user.UploadBandwidth, user.DownloadBandwidth, user.Status, user.ExpirationDate,
+			string(filters), string(fsConfig), user.AdditionalInfo, user.Description, user.Email, util.GetTimeAsMsSinceEpoch(time.Now()),
+			user.UploadDataTransfer, user.DownloadDataTransfer, user.TotalDataTransfer, string(user.DataTransferResetPeriod), user.ID)
+		if err != nil {
+			return err
+		}
+		if err := generateVirtualFoldersMapping(ctx, user, tx); err != nil {
+			return err
+		}
+		return generateGroupsMapping(ctx, user, tx)
+	})
+}
+
+// sqlCommonUpdateUserLastPasswordChange sets the last password change time
+// if the password to save differs from the stored one
+func sqlCommonUpdateUserLastPasswordChange(ctx context.Context, user *User, dbHandle sqlQuerier) error {
+	q := getUpdateUserLastPasswordChangeQuery()
+	stmt, err := dbHandle.PrepareContext(ctx, q)
+	if err != nil {
+		providerLog(logger.LevelWarn, "error preparing database query %#v: %v", q, err)
+		return err
+	}
+	defer stmt.Close()
+	_, err = stmt.ExecContext(ctx, util.GetTimeAsMsSinceEpoch(time.Now()), user.ID, user.Password)
+	return err
+}
+
+func sqlCommonDeleteUser(user *User, dbHandle *sql.DB) error {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultSQLQueryTimeout)
+	defer cancel()
+	q := getDeleteUserQuery()
+	stmt, err := dbHandle.PrepareContext(ctx, q)
+	if err != nil {
+		providerLog(logger.LevelWarn, "error preparing database query %#v: %v", q, err)
+		return err
+	}
+	defer stmt.Close()
+	_, err = stmt.ExecContext(ctx, user.ID)
+	return err
+}
+
+func sqlCommonDumpUsers(dbHandle sqlQuerier) ([]User, error) {
+	users := make([]User, 0, 100)
+	ctx, cancel := context.WithTimeout(context.Background(), longSQLQueryTimeout)
+	defer cancel()
+	q := getDumpUsersQuery()
+	stmt, err := dbHandle.PrepareContext(ctx, q)
+	if err != nil {
+		providerLog(logger.LevelWarn, "error preparing database query %#v: %v", q, err)
+		return nil, err
+	}
+	defer stmt.Close()
+	rows, err := stmt.QueryContext(ctx)
+	if err != nil {
+		return users, err
+	}
+
+	defer rows.Close()
+	for rows.Next() {
+		u, err := getUserFromDbRow(rows)
+		if err != nil {
+			return users, err
+		}
+		err = addCredentialsToUser(&u)
+		if err != nil {
+			return users, err
+		}
+		users = append(users, u)
+	}
+	err = rows.Err()
+	if err != nil {
+		return users, err
+	}
+	return getUsersWithVirtualFolders(ctx, users, dbHandle)
+}
+
+func sqlCommonGetRecentlyUpdatedUsers(after int64, dbHandle sqlQuerier) ([]User, error) {
+	users := make([]User, 0, 10)
+	ctx, cancel := context.WithTimeout(context.Background(), defaultSQLQueryTimeout)
+	defer cancel()
+	q := getRecentlyUpdatedUsersQuery()
+	stmt, err := dbHandle.PrepareContext(ctx, q)
+	if err != nil {
+		providerLog(logger.LevelWarn, "error preparing database query %#v: %v", q, err)
+		return nil, err
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.QueryContext(ctx, after)
+	if err == nil {
+		defer rows.Close()
+		for rows.Next() {
+			u, err := getUserFromDbRow(rows)
+			if err != nil {
+				return users, err
+			}
+			users = append(users, u)
+		}
+	}
+	err = rows.Err()
+	if err != nil {
+		return users, err
+	}
+	return getUsersWithVirtualFolders(ctx, users, dbHandle)
+}
+
+func sqlCommonGetUsers(limit int, offset int, order string, dbHandle sqlQuerier) ([]User, error) {
+	users := make([]User, 0, limit)
+	ctx, cancel := context.WithTimeout(context.Background(), defaultSQLQueryTimeout)
+	defer cancel()
+	q := getUsersQuery(order)
+	stmt, err := dbHandle.PrepareContext(ctx, q)
+	if err != nil {
+		providerLog(logger.LevelWarn, "error preparing database query %#v: %v", q, err)
+		return nil, err
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.QueryContext(ctx, limit, offset)
+	if err == nil {
+		defer rows.Close()
+		for rows.Next() {
+			u, err := getUserFromDbRow(rows)
+			if err != nil {
+				return users, err
+			}
+			u.PrepareForRendering()
+			users = append(users, u)
+		}
+	}
+	err = rows.Err()
+	if err != nil {
+		return users, err
+	}
+	return getUsersWithVirtualFolders(ctx, users, dbHandle)
+}
+
+// sqlCommonGetUsersInScope returns the users within the admin scope, all the matching
+// users are returned if limit is less than or equal to 0
+func sqlCommonGetUsersInScope(admin *Admin, limit, offset int, order string, dbHandle sqlQuerier) ([]User, error) {
+	users := make([]User, 0, 10)
+	ctx, cancel := context.WithTimeout(context.Background(), longSQLQueryTimeout)
+	defer cancel()
+	q, args := getUsersInScopeQuery(admin, limit, offset, order)
+	stmt, err := dbHandle.PrepareContext(ctx, q)
+	if err != nil {
+		providerLog(logger.LevelWarn, "error preparing database query %#v: %v", q, err)
+		return nil, err
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.QueryContext(ctx, args...)
+	if err != nil {
+		return users, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		u, err := getUserFromDbRow(rows)
+		if err != nil {
+			return users, err
+		}
+		users = append(users, u)
+	}
+	err = rows.Err()
+	if err != nil {
+		return users, err
+	}
+	users, err = getUsersWithVirtualFolders(ctx, users, dbHandle)
+	if err != nil {
+		return users, err
+	}
+	// LIKE is case insensitive for some databases, the exact check is done here
+	result := make([]User, 0, len(users))
+	for idx := range users {
+		if admin.IsUserInScope(&users[idx]) {
+			result = append(result, users[idx])
+		}
+	}
+	return result, nil
+}
+
+func getShareFromDbRow(row sqlScanner) (Share, error) {
+	var share Share
+	var description, password, allowFrom, paths sql.NullString
+
+	err := row.Scan(&share.ShareID, &share.Name, &description, &share.Scope,
+		&paths, &share.Username, &share.CreatedAt, &share.UpdatedAt,
+		&share.LastUseAt, &share.ExpiresAt, &password, &share.MaxTokens,
+		&share.UsedTokens, &allowFrom)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return share, util.NewRecordNotFoundError(err.Error())
+		}
+		return share, err
+	}
+	if paths.Valid {
+		var list []string
+		err = json.Unmarshal([]byte(paths.String), &list)
+		if err != nil {
+			return share, err
+		}
+		share.Paths = list
+	} else {
+		return share, errors.New("unable to decode shared paths")
+	}
+	if description.Valid {
+		share.Description = description.String
+	}
+	if password.Valid {
+		share.Password = password.String
+	}
+	if allowFrom.Valid {
+		var list []string
+		err = json.Unmarshal([]byte(allowFrom.String), &list)
+		if err == nil {
+			share.AllowFrom = list
+		}
+	}
+	return share, nil
+}
+
+func getAccountLockoutFromDbRow(row sqlScanner) (AccountLockout, error) {
+	var lockout AccountLockout
+
+	err := row.Scan(&lockout.Username, &lockout.Type, &lockout.Failures, &lockout.FirstFailureAt,
+		&lockout.LastFailureAt, &lockout.LockedAt, &lockout.LockedUntil)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return lockout, util.NewRecordNotFoundError(err.Error())
+		}
+		return lockout, err
+	}
+
+	return lockout, nil
+}
+
+func getAPIKeyFromDbRow(row sqlScanner) (APIKey, error) {
+	var apiKey APIKey
+	var userID, adminID sql.NullInt64
+	var description sql.NullString
+
+	err := row.Scan(&apiKey.KeyID, &apiKey.Name, &apiKey.Key, &apiKey.Scope, &apiKey.CreatedAt, &apiKey.UpdatedAt,
+		&apiKey.LastUseAt, &apiKey.ExpiresAt, &description, &userID, &adminID)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return apiKey, util.NewRecordNotFoundError(err.Error())
+		}
+		return apiKey, err
+	}
+
+	if userID.Valid {
+		apiKey.userID = userID.Int64
+	}
+	if adminID.Valid {
+		apiKey.adminID = adminID.Int64
+	}
+	if description.Valid {
+		apiKey.Description = description.String
+	}
+
+	return apiKey, nil
+}
+
+func getAdminFromDbRow(row sqlScanner) (Admin, error) {
+	var admin Admin
+	var email, filters, additionalInfo, permissions, description sql.NullString
+
+	err := row.Scan(&admin.ID, &admin.Username, &admin.Password, &admin.Status, &email, &permissions,
+		&filters, &additionalInfo, &description, &admin.CreatedAt, &admin.UpdatedAt, &admin.LastLogin, &admin.LastPasswordChange)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return admin, util.NewRecordNotFoundError(err.Error())
+		}
+		return admin, err
+	}
+
+	if permissions.Valid {
+		var perms []string
+		err = json.Unmarshal([]byte(permissions.String), &perms)
+		if err != nil {
+			return admin, err
+		}
+		admin.Permissions = perms
+	}
+
+	if email.Valid {
+		admin.Email = email.String
+	}
+	if filters.Valid {
+		var adminFilters AdminFilters
+		err = json.Unmarshal([]byte(filters.String), &adminFilters)
+		if err == nil {
+			admin.Filters = adminFilters
+		}
+	}
+	if additionalInfo.Valid {
+		admin.AdditionalInfo = additionalInfo.String
+	}
+	if description.Valid {
+		admin.Description = description.String
+	}
+
+	admin.SetEmptySecretsIfNil()
+	return admin, nil
+}
+
+func getUserFromDbRow(row sqlScanner) (User, error) {
+	var user User
+	var permissions sql.NullString
+	var password sql.NullString
+	var publicKey sql.NullString
+	var filters sql.NullString
+	var fsConfig sql.NullString
+	var additionalInfo, description, email, resetPeriod sql.NullString
+
+	err := row.Scan(&user.ID, &user.Username, &password, &publicKey, &user.HomeDir, &user.UID, &user.GID, &user.MaxSessions,
+		&user.QuotaSize, &user.QuotaFiles, &permissions, &user.UsedQuotaSize, &user.UsedQuotaFiles, &user.LastQuotaUpdate,
+		&user.UploadBandwidth, &user.DownloadBandwidth, &user.ExpirationDate, &user.LastLogin, &user.Status, &filters, &fsConfig,
+		&additionalInfo, &description, &email, &user.CreatedAt, &user.UpdatedAt, &user.UploadDataTransfer,
+		&user.DownloadDataTransfer, &user.TotalDataTransfer, &user.UsedUploadDataTransfer, &user.UsedDownloadDataTransfer,
+		&user.LastDataTransferReset, &resetPeriod, &user.LastPasswordChange)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return user, util.NewRecordNotFoundError(err.Error())
+		}
+		return user, err
+	}
+	if password.Valid {
+		user.Password = password.String
+	}
+	// we can have a empty string or an invalid json in null string
+	// so we do a relaxed test if the field is optional, for example we
+	// populate public keys only if unmarshal does not return an error
+	if publicKey.Valid {
+		var list []string
+		err = json.Unmarshal([]byte(publicKey.String), &list)
+		if err == nil {
+			user.PublicKeys = list
+		}
+	}
+	if permissions.Valid {
+		perms := make(map[string][]string)
+		err = json.Unmarshal([]byte(permissions.String), &perms)
+		if err != nil {
+			providerLog(logger.LevelWarn, "unable to deserialize permissions for user %#v: %v", user.Username, err)
+			return user, fmt.Errorf("unable to deserialize permissions for user %#v: %v", user.Username, err)
+		}
+		user.Permissions = perms
+	}
+	if filters.Valid {
+		var userFilters sdk.UserFilters
+		err = json.Unmarshal([]byte(filters.String), &userFilters)
+		if err == nil {
+			user.Filters = userFilters
+		}
+	}
+	if fsConfig.Valid {
+		var fs vfs.Filesystem
+		err = json.Unmarshal([]byte(fsConfig.String), &fs)
+		if err == nil {
+			user.FsConfig = fs
+		}
+	}
+	if additionalInfo.Valid {
+		user.AdditionalInfo = additionalInfo.String
+	}
+	if description.Valid {
+		user.Description = description.String
+	}
+	if email.Valid {
+		user.Email = email.String
+	}
+	if resetPeriod.Valid {
+		user.DataTransferResetPeriod = sdk.DataTransferResetPeriod(resetPeriod.String)
+	}
+	user.SetEmptySecretsIfNil()
+	return user, nil
+}
+
+func getGroupFromDbRow(row sqlScanner) (Group, error) {
+	var group Group
+	var description, userSettings sql.NullString
+
+	err := row.Scan(&group.ID, &group.Name, &description, &group.CreatedAt, &group.UpdatedAt, &userSettings)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return group, util.NewRecordNotFoundError(err.Error())
+		}
+		return group, err
+	}
+	if description.Valid {
+		group.Description = description.String
+	}
+	if userSettings.Valid {
+		var settings GroupUserSettings
+		err = json.Unmarshal([]byte(userSettings.String), &settings)
+		if err != nil {
+			providerLog(logger.LevelWarn, "unable to deserialize user settings for group %#v: %v", group.Name, err)
+			return group, fmt.Errorf("unable to deserialize user settings for group %#v: %v", group.Name, err)
+		}
+		group.UserSettings = settings
+	}
+	group.SetEmptySecretsIfNil()
+	return group, nil
+}
+
+func getEventRuleFromDbRow(row sqlScanner) (EventRule, error) {
+	var rule EventRule
+	var description sql.NullString
+	var conditions, actions []byte
+
+	err := row.Scan(&rule.ID, &rule.Name, &description, &rule.CreatedAt, &rule.UpdatedAt, &rule.Trigger,
+		&conditions, &actions)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return rule, util.NewRecordNotFoundError(err.Error())
+		}
+		return rule, err
+	}
+	if description.Valid {
+		rule.Description = description.String
+	}
+	if err := json.Unmarshal(conditions, &rule.Conditions); err != nil {
+		providerLog(logger.LevelWarn, "unable to deserialize conditions for event rule %#v: %v", rule.Name, err)
+		return rule, fmt.Errorf("unable to deserialize conditions for event rule %#v: %v", rule.Name, err)
+	}
+	if err := json.Unmarshal(actions, &rule.Actions); err != nil {
+		providerLog(logger.LevelWarn, "unable to deserialize actions for event rule %#v: %v", rule.Name, err)
+		return rule, fmt.Errorf("unable to deserialize actions for event rule %#v: %v", rule.Name, err)
+	}
+	return rule, nil
+}
+
+func getWebDAVLockFromDbRow(row sqlScanner) (WebDAVLock, error) {
+	var lock WebDAVLock
+	var ownerXML sql.NullString
+
+	err := row.Scan(&lock.Token, &lock.Username, &lock.Root, &lock.Duration, &lock.ExpiresAt, &ownerXML,
+		&lock.ZeroDepth, &lock.CreatedAt, &lock.UpdatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return lock, util.NewRecordNotFoundError(err.Error())
+		}
+		return lock, err
+	}
+	if ownerXML.Valid {
+		lock.OwnerXML = ownerXML.String
+	}
+	return lock, nil
+}
+
+func getWebDAVPropertiesFromDbRow(row sqlScanner) (WebDAVProperties, error) {
+	var props WebDAVProperties
+	var properties []byte
+
+	err := row.Scan(&props.Username, &props.Path, &properties, &props.UpdatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return props, util.NewRecordNotFoundError(err.Error())
+		}
+		return props, err
+	}
+	if err := json.Unmarshal(properties, &props.Properties); err != nil {
+		providerLog(logger.LevelWarn, "unable to deserialize WebDAV properties for path %#v: %v", props.Path, err)
+		return props, fmt.Errorf("unable to deserialize WebDAV properties for path %#v: %v", props.Path, err)
+	}
+	return props, nil
+}
+
+func getS3AccessKeyFromDbRow(row sqlScanner) (S3AccessKey, error) {
+	var key S3AccessKey
+	var secret []byte
+	var description sql.NullString
+
+	err := row.Scan(&key.AccessKeyID, &key.Username, &secret, &description, &key.CreatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return key, util.NewRecordNotFoundError(err.Error())
+		}
+		return key, err
+	}
+	if description.Valid {
+		key.Description = description.String
+	}
+	key.SecretAccessKey = kms.NewEmptySecret()
+	if err := json.Unmarshal(secret, key.SecretAccessKey); err != nil {
+		providerLog(logger.LevelWarn, "unable to deserialize the secret for S3 access key %#v: %v", key.AccessKeyID, err)
+		return key, fmt.Errorf("unable to deserialize the secret for S3 access key %#v: %v", key.AccessKeyID, err)
+	}
+	return key, nil
+}
+
+func getTusUploadFromDbRow(row sqlScanner) (TusUpload, error) {
+	var upload TusUpload
+
+	err := row.Scan(&upload.ID, &upload.Username, &upload.VirtualPath, &upload.FsPath, &upload.Length, &upload.Offset,
+		&upload.ExpiresAt, &upload.CreatedAt, &upload.UpdatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return upload, util.NewRecordNotFoundError(err.Error())
+		}
+		return upload, err
+	}
+	return upload, nil
+}
+
+func getMultipartUploadFromDbRow(row sqlScanner) (vfs.MultipartUpload, error) {
+	var upload vfs.MultipartUpload
+
+	err := row.Scan(&upload.Key, &upload.Size, &upload.State, &upload.CreatedAt, &upload.UpdatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return upload, util.NewRecordNotFoundError(err.Error())
+		}
+		return upload, err
+	}
+	return upload, nil
+}
+
+func getResetCodeFromDbRow(row sqlScanner) (ResetCode, error) {
+	var code ResetCode
+
+	err := row.Scan(&code.Code, &code.Username, &code.Type, &code.PasswordFingerprint, &code.ExpiresAt, &code.CreatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return code, util.NewRecordNotFoundError(err.Error())
+		}
+		return code, err
+	}
+	return code, nil
+}
+
+func getScheduleFromDbRow(row sqlScanner) (Schedule, error) {
+	var schedule Schedule
+	var description, lastRunError sql.NullString
+	var options []byte
+
+	err := row.Scan(&schedule.ID, &schedule.Name, &description, &schedule.CreatedAt, &schedule.UpdatedAt,
+		&schedule.Status, &schedule.CronExpression, &schedule.Task, &options, &schedule.LastRun,
+		&schedule.LastRunStatus, &lastRunError)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return schedule, util.NewRecordNotFoundError(err.Error())
+		}
+		return schedule, err
+	}
+	if description.Valid {
+		schedule.Description = description.String
+	}
+	if lastRunError.Valid {
+		schedule.LastRunError = lastRunError.String
+	}
+	if err := json.Unmarshal(options, &schedule.Options); err != nil {
+		providerLog(logger.LevelWarn, "unable to deserialize options for schedule %#v: %v", schedule.Name, err)
+		return schedule, fmt.Errorf("unable to deserialize options for schedule %#v: %v", schedule.Name, err)
+	}
+	return schedule, nil
+}
+
+func sqlCommonCheckFolderExists(ctx context.Context, name string, dbHandle sqlQuerier) error {
+	var folderName string
+	q := checkFolderNameQuery()
+	stmt, err := dbHandle.PrepareContext(ctx, q)
+	if err != nil {
+		providerLog(logger.LevelWarn, "error preparing database query %#v: %v", q, err)
+		return err
+	}
+	defer stmt.Close()
+	row := stmt.QueryRowContext(ctx, name)
+	return row.Scan(&folderName)
+}
+
+func sqlCommonGetFolder(ctx context.Context, name string, dbHandle sqlQuerier) (vfs.BaseVirtualFolder, error) {
+	var folder vfs.BaseVirtualFolder
+	q := getFolderByNameQuery()
+	stmt, err := dbHandle.PrepareContext(ctx, q)
+	if err != nil {
+		providerLog(logger.LevelWarn, "error preparing database query %#v: %v", q, err)
+		return folder, err
+	}
+	defer stmt.Close()
+	row := stmt.QueryRowContext(ctx, name)
+	var mappedPath, description, fsConfig, resetPeriod, dataRetention sql.NullString
+	err = row.Scan(&folder.ID, &mappedPath, &folder.UsedQuotaSize, &folder.UsedQuotaFiles, &folder.LastQuotaUpdate,
+		&folder.Name, &description, &fsConfig, &folder.UploadDataTransfer,
+		&folder.DownloadDataTransfer, &folder.TotalDataTransfer, &folder.UsedUploadDataTransfer,
+		&folder.UsedDownloadDataTransfer, &folder.LastDataTransferReset, &resetPeriod, &dataRetention)
+	if err == sql.ErrNoRows {
+		return folder, util.NewRecordNotFoundError(err.Error())
+	}
+	if mappedPath.Valid {
+		folder.MappedPath = mappedPath.String
+	}
+	if description.Valid {
+		folder.Description = description.String
+	}
+	if resetPeriod.Valid {
+		folder.DataTransferResetPeriod = sdk.DataTransferResetPeriod(resetPeriod.String)
+	}
+	if dataRetention.Valid {
+		var retention []sdk.DataRetentionPolicy
+		if err := json.Unmarshal([]byte(dataRetention.String), &retention); err == nil {
+			folder.DataRetention = retention
+		}
+	}
+	if fsConfig.Valid {
+		var fs vfs.Filesystem
+		err = json.Unmarshal([]byte(fsConfig.String), &fs)
+		if err == nil {
+			folder.FsConfig = fs
+		}
+	}
+	return folder, err
+}
+
+func sqlCommonGetFolderByName(ctx context.Context, name string, dbHandle sqlQuerier) (vfs.BaseVirtualFolder, error) {
+	folder, err := sqlCommonGetFolder(ctx, name, dbHandle)
+	if err != nil {
+		return folder, err
+	}
+	folders, err := getVirtualFoldersWithUsers([]vfs.BaseVirtualFolder{folder}, dbHandle)
+	if err != nil {
+		return folder, err
+	}
+	if len(folders) != 1 {
+		return folder, fmt.Errorf("unable to associate users with folder %#v", name)
+	}
+	return folders[0], nil
+}
+
+func sqlCommonAddOrUpdateFolder(ctx context.Context, baseFolder *vfs.BaseVirtualFolder, usedQuotaSize int64,
+	usedQuotaFiles int, lastQuotaUpdate int64, dbHandle sqlQuerier) (vfs.BaseVirtualFolder, error) {
+	var folder vfs.BaseVirtualFolder
 	// FIXME: we could use an UPSERT here, this SELECT could be racy
 	err := sqlCommonCheckFolderExists(ctx, baseFolder.Name, dbHandle)
 	switch err {
 	case nil:
 		err = sqlCommonUpdateFolder(baseFolder, dbHandle)
 		if err != nil {
-			return folder, err
+			return folder, err
+		}
+	case sql.ErrNoRows:
+		baseFolder.UsedQuotaFiles = usedQuotaFiles
+		baseFolder.UsedQuotaSize = usedQuotaSize
+		baseFolder.LastQuotaUpdate = lastQuotaUpdate
+		err = sqlCommonAddFolder(baseFolder, dbHandle)
+		if err != nil {
+			return folder, err
+		}
+	default:
+		return folder, err
+	}
+
+	return sqlCommonGetFolder(ctx, baseFolder.Name, dbHandle)
+}
+
+func sqlCommonAddFolder(folder *vfs.BaseVirtualFolder, dbHandle sqlQuerier) error {
+	err := ValidateFolder(folder)
+	if err != nil {
+		return err
+	}
+	fsConfig, err := json.Marshal(folder.FsConfig)
+	if err != nil {
+		return err
+	}
+	dataRetention, err := getFolderDataRetentionForDB(folder)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), defaultSQLQueryTimeout)
+	defer cancel()
+	q := getAddFolderQuery()
+	stmt, err := dbHandle.PrepareContext(ctx, q)
+	if err != nil {
+		providerLog(logger.LevelWarn, "error preparing database query %#v: %v", q, err)
+		return err
+	}
+	defer stmt.Close()
+	_, err = stmt.ExecContext(ctx, folder.MappedPath, folder.UsedQuotaSize, folder.UsedQuotaFiles,
+		folder.LastQuotaUpdate, folder.Name, folder.Description, string(fsConfig), folder.UploadDataTransfer,
+		folder.DownloadDataTransfer, folder.TotalDataTransfer, folder.UsedUploadDataTransfer, folder.UsedDownloadDataTransfer,
+		folder.LastDataTransferReset, string(folder.DataTransferResetPeriod), dataRetention)
+	return err
+}
+
+func sqlCommonUpdateFolder(folder *vfs.BaseVirtualFolder, dbHandle sqlQuerier) error {
+	err := ValidateFolder(folder)
+	if err != nil {
+		return err
+	}
+	fsConfig, err := json.Marshal(folder.FsConfig)
+	if err != nil {
+		return err
+	}
+	dataRetention, err := getFolderDataRetentionForDB(folder)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), defaultSQLQueryTimeout)
+	defer cancel()
+	q := getUpdateFolderQuery()
+	stmt, err := dbHandle.PrepareContext(ctx, q)
+	if err != nil {
+		providerLog(logger.LevelWarn, "error preparing database query %#v: %v", q, err)
+		return err
+	}
+	defer stmt.Close()
+	_, err = stmt.ExecContext(ctx, folder.MappedPath, folder.Description, string(fsConfig), folder.UploadDataTransfer,
+		folder.DownloadDataTransfer, folder.TotalDataTransfer, string(folder.DataTransferResetPeriod), dataRetention,
+		folder.Name)
+	return err
+}
+
+func getFolderDataRetentionForDB(folder *vfs.BaseVirtualFolder) (sql.NullString, error) {
+	if len(folder.DataRetention) == 0 {
+		return sql.NullString{}, nil
+	}
+	data, err := json.Marshal(folder.DataRetention)
+	if err != nil {
+		return sql.NullString{}, err
+	}
+	return sql.NullString{String: string(data), Valid: true}, nil
+}
+
+func sqlCommonDeleteFolder(folder *vfs.BaseVirtualFolder, dbHandle sqlQuerier) error {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultSQLQueryTimeout)
+	defer cancel()
+	q := getDeleteFolderQuery()
+	stmt, err := dbHandle.PrepareContext(ctx, q)
+	if err != nil {
+		providerLog(logger.LevelWarn, "error preparing database query %#v: %v", q, err)
+		return err
+	}
+	defer stmt.Close()
+	_, err = stmt.ExecContext(ctx, folder.ID)
+	return err
+}
+
+func sqlCommonDumpFolders(dbHandle sqlQuerier) ([]vfs.BaseVirtualFolder, error) {
+	folders := make([]vfs.BaseVirtualFolder, 0, 50)
+	ctx, cancel := context.WithTimeout(context.Background(), longSQLQueryTimeout)
+	defer cancel()
+	q := getDumpFoldersQuery()
+	stmt, err := dbHandle.PrepareContext(ctx, q)
+	if err != nil {
+		providerLog(logger.LevelWarn, "error preparing database query %#v: %v", q, err)
+		return nil, err
+	}
+	defer stmt.Close()
+	rows, err := stmt.QueryContext(ctx)
+	if err != nil {
+		return folders, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var folder vfs.BaseVirtualFolder
+		var mappedPath, description, fsConfig, resetPeriod, dataRetention sql.NullString
+		err = rows.Scan(&folder.ID, &mappedPath, &folder.UsedQuotaSize, &folder.UsedQuotaFiles,
+			&folder.LastQuotaUpdate, &folder.Name, &description, &fsConfig, &folder.UploadDataTransfer,
+			&folder.DownloadDataTransfer, &folder.TotalDataTransfer, &folder.UsedUploadDataTransfer,
+			&folder.UsedDownloadDataTransfer, &folder.LastDataTransferReset, &resetPeriod, &dataRetention)
+		if err != nil {
+			return folders, err
+		}
+		if mappedPath.Valid {
+			folder.MappedPath = mappedPath.String
+		}
+		if description.Valid {
+			folder.Description = description.String
+		}
+		if resetPeriod.Valid {
+			folder.DataTransferResetPeriod = sdk.DataTransferResetPeriod(resetPeriod.String)
+		}
+		if dataRetention.Valid {
+			var retention []sdk.DataRetentionPolicy
+			if err := json.Unmarshal([]byte(dataRetention.String), &retention); err == nil {
+				folder.DataRetention = retention
+			}
+		}
+		if fsConfig.Valid {
+			var fs vfs.Filesystem
+			err = json.Unmarshal([]byte(fsConfig.String), &fs)
+			if err == nil {
+				folder.FsConfig = fs
+			}
+		}
+		folders = append(folders, folder)
+	}
+	err = rows.Err()
+	if err != nil {
+		return folders, err
+	}
+	return getVirtualFoldersWithUsers(folders, dbHandle)
+}
+
+func sqlCommonGetFolders(limit, offset int, order string, dbHandle sqlQuerier) ([]vfs.BaseVirtualFolder, error) {
+	folders := make([]vfs.BaseVirtualFolder, 0, limit)
+	ctx, cancel := context.WithTimeout(context.Background(), defaultSQLQueryTimeout)
+	defer cancel()
+	q := getFoldersQuery(order)
+	stmt, err := dbHandle.PrepareContext(ctx, q)
+	if err != nil {
+		providerLog(logger.LevelWarn, "error preparing database query %#v: %v", q, err)
+		return nil, err
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.QueryContext(ctx, limit, offset)
+	if err != nil {
+		return folders, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var folder vfs.BaseVirtualFolder
+		var mappedPath, description, fsConfig, resetPeriod, dataRetention sql.NullString
+		err = rows.Scan(&folder.ID, &mappedPath, &folder.UsedQuotaSize, &folder.UsedQuotaFiles,
+			&folder.LastQuotaUpdate, &folder.Name, &description, &fsConfig, &folder.UploadDataTransfer,
+			&folder.DownloadDataTransfer, &folder.TotalDataTransfer, &folder.UsedUploadDataTransfer,
+			&folder.UsedDownloadDataTransfer, &folder.LastDataTransferReset, &resetPeriod, &dataRetention)
+		if err != nil {
+			return folders, err
+		}
+		if mappedPath.Valid {
+			folder.MappedPath = mappedPath.String
+		}
+		if description.Valid {
+			folder.Description = description.String
+		}
+		if resetPeriod.Valid {
+			folder.DataTransferResetPeriod = sdk.DataTransferResetPeriod(resetPeriod.String)
+		}
+		if dataRetention.Valid {
+			var retention []sdk.DataRetentionPolicy
+			if err := json.Unmarshal([]byte(dataRetention.String), &retention); err == nil {
+				folder.DataRetention = retention
+			}
+		}
+		if fsConfig.Valid {
+			var fs vfs.Filesystem
+			err = json.Unmarshal([]byte(fsConfig.String), &fs)
+			if err == nil {
+				folder.FsConfig = fs
+			}
+		}
+		folder.PrepareForRendering()
+		folders = append(folders, folder)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return folders, err
+	}
+	return getVirtualFoldersWithUsers(folders, dbHandle)
+}
+
+func sqlCommonGetGroupByName(name string, dbHandle sqlQuerier) (Group, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultSQLQueryTimeout)
+	defer cancel()
+	q := getGroupByNameQuery()
+	stmt, err := dbHandle.PrepareContext(ctx, q)
+	if err != nil {
+		providerLog(logger.LevelWarn, "error preparing database query %#v: %v", q, err)
+		return Group{}, err
+	}
+	defer stmt.Close()
+
+	row := stmt.QueryRowContext(ctx, name)
+	group, err := getGroupFromDbRow(row)
+	if err != nil {
+		return group, err
+	}
+	groups, err := getGroupsWithVirtualFolders(ctx, []Group{group}, dbHandle)
+	if err != nil {
+		return group, err
+	}
+	groups, err = getGroupsWithUsers(ctx, groups, dbHandle)
+	if err != nil {
+		return group, err
+	}
+	if len(groups) != 1 {
+		return group, fmt.Errorf("unable to associate folders and users with group %#v", name)
+	}
+	return groups[0], nil
+}
+
+func sqlCommonGetGroupsWithNames(names []string, dbHandle sqlQuerier) ([]Group, error) {
+	if len(names) == 0 {
+		return nil, nil
+	}
+	groups := make([]Group, 0, len(names))
+	ctx, cancel := context.WithTimeout(context.Background(), defaultSQLQueryTimeout)
+	defer cancel()
+	q := getGroupsWithNamesQuery(len(names))
+	stmt, err := dbHandle.PrepareContext(ctx, q)
+	if err != nil {
+		providerLog(logger.LevelWarn, "error preparing database query %#v: %v", q, err)
+		return nil, err
+	}
+	defer stmt.Close()
+
+	args := make([]interface{}, 0, len(names))
+	for _, name := range names {
+		args = append(args, name)
+	}
+	rows, err := stmt.QueryContext(ctx, args...)
+	if err != nil {
+		return groups, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		group, err := getGroupFromDbRow(rows)
+		if err != nil {
+			return groups, err
+		}
+		groups = append(groups, group)
+	}
+	err = rows.Err()
+	if err != nil {
+		return groups, err
+	}
+	return getGroupsWithVirtualFolders(ctx, groups, dbHandle)
+}
+
+func sqlCommonGetGroups(limit, offset int, order string, dbHandle sqlQuerier) ([]Group, error) {
+	groups := make([]Group, 0, limit)
+	ctx, cancel := context.WithTimeout(context.Background(), defaultSQLQueryTimeout)
+	defer cancel()
+	q := getGroupsQuery(order)
+	stmt, err := dbHandle.PrepareContext(ctx, q)
+	if err != nil {
+		providerLog(logger.LevelWarn, "error preparing database query %#v: %v", q, err)
+		return nil, err
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.QueryContext(ctx, limit, offset)
+	if err != nil {
+		return groups, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		group, err := getGroupFromDbRow(rows)
+		if err != nil {
+			return groups, err
 		}
-	case sql.ErrNoRows:
-		baseFolder.UsedQuotaFiles = usedQuotaFiles
-		baseFolder.UsedQuotaSize = usedQuotaSize
-		baseFolder.LastQuotaUpdate = lastQuotaUpdate
-		err = sqlCommonAddFolder(baseFolder, dbHandle)
+		groups = append(groups, group)
+	}
+	err = rows.Err()
+	if err != nil {
+		return groups, err
+	}
+	groups, err = getGroupsWithVirtualFolders(ctx, groups, dbHandle)
+	if err != nil {
+		return groups, err
+	}
+	groups, err = getGroupsWithUsers(ctx, groups, dbHandle)
+	if err != nil {
+		return groups, err
+	}
+	for idx := range groups {
+		groups[idx].PrepareForRendering()
+	}
+	return groups, nil
+}
+
+func sqlCommonDumpGroups(dbHandle sqlQuerier) ([]Group, error) {
+	groups := make([]Group, 0, 50)
+	ctx, cancel := context.WithTimeout(context.Background(), longSQLQueryTimeout)
+	defer cancel()
+	q := getDumpGroupsQuery()
+	stmt, err := dbHandle.PrepareContext(ctx, q)
+	if err != nil {
+		providerLog(logger.LevelWarn, "error preparing database query %#v: %v", q, err)
+		return nil, err
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.QueryContext(ctx)
+	if err != nil {
+		return groups, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		group, err := getGroupFromDbRow(rows)
+		if err != nil {
+			return groups, err
+		}
+		groups = append(groups, group)
+	}
+	err = rows.Err()
+	if err != nil {
+		return groups, err
+	}
+	return getGroupsWithVirtualFolders(ctx, groups, dbHandle)
+}
+
+func sqlCommonAddGroup(group *Group, dbHandle *sql.DB) error {
+	if err := group.validate(); err != nil {
+		return err
+	}
+	settings, err := json.Marshal(group.UserSettings)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), defaultSQLQueryTimeout)
+	defer cancel()
+
+	return sqlCommonExecuteTx(ctx, dbHandle, func(tx *sql.Tx) error {
+		q := getAddGroupQuery()
+		stmt, err := tx.PrepareContext(ctx, q)
+		if err != nil {
+			providerLog(logger.LevelWarn, "error preparing database query %#v: %v", q, err)
+			return err
+		}
+		defer stmt.Close()
+		_, err = stmt.ExecContext(ctx, group.Name, group.Description, util.GetTimeAsMsSinceEpoch(time.Now()),
+			util.GetTimeAsMsSinceEpoch(time.Now()), string(settings))
+		if err != nil {
+			return err
+		}
+		return generateGroupVirtualFoldersMapping(ctx, group, tx)
+	})
+}
+
+func sqlCommonUpdateGroup(group *Group, dbHandle *sql.DB) error {
+	if err := group.validate(); err != nil {
+		return err
+	}
+	settings, err := json.Marshal(group.UserSettings)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), defaultSQLQueryTimeout)
+	defer cancel()
+
+	return sqlCommonExecuteTx(ctx, dbHandle, func(tx *sql.Tx) error {
+		q := getUpdateGroupQuery()
+		stmt, err := tx.PrepareContext(ctx, q)
+		if err != nil {
+			providerLog(logger.LevelWarn, "error preparing database query %#v: %v", q, err)
+			return err
+		}
+		defer stmt.Close()
+		_, err = stmt.ExecContext(ctx, group.Description, string(settings), util.GetTimeAsMsSinceEpoch(time.Now()),
+			group.Name)
+		if err != nil {
+			return err
+		}
+		return generateGroupVirtualFoldersMapping(ctx, group, tx)
+	})
+}
+
+func sqlCommonDeleteGroup(group *Group, dbHandle *sql.DB) error {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultSQLQueryTimeout)
+	defer cancel()
+	q := getDeleteGroupQuery()
+	stmt, err := dbHandle.PrepareContext(ctx, q)
+	if err != nil {
+		providerLog(logger.LevelWarn, "error preparing database query %#v: %v", q, err)
+		return err
+	}
+	defer stmt.Close()
+	_, err = stmt.ExecContext(ctx, group.ID)
+	return err
+}
+
+func sqlCommonGetEventRuleByName(name string, dbHandle sqlQuerier) (EventRule, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultSQLQueryTimeout)
+	defer cancel()
+	q := getEventRuleByNameQuery()
+	stmt, err := dbHandle.PrepareContext(ctx, q)
+	if err != nil {
+		providerLog(logger.LevelWarn, "error preparing database query %#v: %v", q, err)
+		return EventRule{}, err
+	}
+	defer stmt.Close()
+
+	row := stmt.QueryRowContext(ctx, name)
+	return getEventRuleFromDbRow(row)
+}
+
+func sqlCommonGetEventRules(limit, offset int, order string, dbHandle sqlQuerier) ([]EventRule, error) {
+	rules := make([]EventRule, 0, limit)
+	ctx, cancel := context.WithTimeout(context.Background(), defaultSQLQueryTimeout)
+	defer cancel()
+	q := getEventRulesQuery(order)
+	stmt, err := dbHandle.PrepareContext(ctx, q)
+	if err != nil {
+		providerLog(logger.LevelWarn, "error preparing database query %#v: %v", q, err)
+		return nil, err
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.QueryContext(ctx, limit, offset)
+	if err != nil {
+		return rules, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		rule, err := getEventRuleFromDbRow(rows)
+		if err != nil {
+			return rules, err
+		}
+		rules = append(rules, rule)
+	}
+	return rules, rows.Err()
+}
+
+func sqlCommonDumpEventRules(dbHandle sqlQuerier) ([]EventRule, error) {
+	rules := make([]EventRule, 0, 10)
+	ctx, cancel := context.WithTimeout(context.Background(), longSQLQueryTimeout)
+	defer cancel()
+	q := getDumpEventRulesQuery()
+	stmt, err := dbHandle.PrepareContext(ctx, q)
+	if err != nil {
+		providerLog(logger.LevelWarn, "error preparing database query %#v: %v", q, err)
+		return nil, err
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.QueryContext(ctx)
+	if err != nil {
+		return rules, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		rule, err := getEventRuleFromDbRow(rows)
+		if err != nil {
+			return rules, err
+		}
+		rules = append(rules, rule)
+	}
+	return rules, rows.Err()
+}
+
+func sqlCommonAddEventRule(rule *EventRule, dbHandle *sql.DB) error {
+	if err := rule.validate(); err != nil {
+		return err
+	}
+	conditions, err := json.Marshal(rule.Conditions)
+	if err != nil {
+		return err
+	}
+	actions, err := json.Marshal(rule.Actions)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), defaultSQLQueryTimeout)
+	defer cancel()
+	q := getAddEventRuleQuery()
+	stmt, err := dbHandle.PrepareContext(ctx, q)
+	if err != nil {
+		providerLog(logger.LevelWarn, "error preparing database query %#v: %v", q, err)
+		return err
+	}
+	defer stmt.Close()
+	_, err = stmt.ExecContext(ctx, rule.Name, rule.Description, util.GetTimeAsMsSinceEpoch(time.Now()),
+		util.GetTimeAsMsSinceEpoch(time.Now()), rule.Trigger, string(conditions), string(actions))
+	return err
+}
+
+func sqlCommonUpdateEventRule(rule *EventRule, dbHandle *sql.DB) error {
+	if err := rule.validate(); err != nil {
+		return err
+	}
+	conditions, err := json.Marshal(rule.Conditions)
+	if err != nil {
+		return err
+	}
+	actions, err := json.Marshal(rule.Actions)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), defaultSQLQueryTimeout)
+	defer cancel()
+	q := getUpdateEventRuleQuery()
+	stmt, err := dbHandle.PrepareContext(ctx, q)
+	if err != nil {
+		providerLog(logger.LevelWarn, "error preparing database query %#v: %v", q, err)
+		return err
+	}
+	defer stmt.Close()
+	_, err = stmt.ExecContext(ctx, rule.Description, util.GetTimeAsMsSinceEpoch(time.Now()), rule.Trigger,
+		string(conditions), string(actions), rule.Name)
+	return err
+}
+
+func sqlCommonDeleteEventRule(rule *EventRule, dbHandle *sql.DB) error {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultSQLQueryTimeout)
+	defer cancel()
+	q := getDeleteEventRuleQuery()
+	stmt, err := dbHandle.PrepareContext(ctx, q)
+	if err != nil {
+		providerLog(logger.LevelWarn, "error preparing database query %#v: %v", q, err)
+		return err
+	}
+	defer stmt.Close()
+	_, err = stmt.ExecContext(ctx, rule.Name)
+	return err
+}
+
+func sqlCommonGetScheduleByName(name string, dbHandle sqlQuerier) (Schedule, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultSQLQueryTimeout)
+	defer cancel()
+	q := getScheduleByNameQuery()
+	stmt, err := dbHandle.PrepareContext(ctx, q)
+	if err != nil {
+		providerLog(logger.LevelWarn, "error preparing database query %#v: %v", q, err)
+		return Schedule{}, err
+	}
+	defer stmt.Close()
+
+	row := stmt.QueryRowContext(ctx, name)
+	return getScheduleFromDbRow(row)
+}
+
+func sqlCommonGetSchedules(limit, offset int, order string, dbHandle sqlQuerier) ([]Schedule, error) {
+	schedules := make([]Schedule, 0, limit)
+	ctx, cancel := context.WithTimeout(context.Background(), defaultSQLQueryTimeout)
+	defer cancel()
+	q := getSchedulesQuery(order)
+	stmt, err := dbHandle.PrepareContext(ctx, q)
+	if err != nil {
+		providerLog(logger.LevelWarn, "error preparing database query %#v: %v", q, err)
+		return nil, err
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.QueryContext(ctx, limit, offset)
+	if err != nil {
+		return schedules, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		schedule, err := getScheduleFromDbRow(rows)
+		if err != nil {
+			return schedules, err
+		}
+		schedules = append(schedules, schedule)
+	}
+	return schedules, rows.Err()
+}
+
+func sqlCommonDumpSchedules(dbHandle sqlQuerier) ([]Schedule, error) {
+	schedules := make([]Schedule, 0, 10)
+	ctx, cancel := context.WithTimeout(context.Background(), longSQLQueryTimeout)
+	defer cancel()
+	q := getDumpSchedulesQuery()
+	stmt, err := dbHandle.PrepareContext(ctx, q)
+	if err != nil {
+		providerLog(logger.LevelWarn, "error preparing database query %#v: %v", q, err)
+		return nil, err
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.QueryContext(ctx)
+	if err != nil {
+		return schedules, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		schedule, err := getScheduleFromDbRow(rows)
 		if err != nil {
-			return folder, err
+			return schedules, err
 		}
-	default:
-		return folder, err
+		schedules = append(schedules, schedule)
 	}
-
-	return sqlCommonGetFolder(ctx, baseFolder.Name, dbHandle)
+	return schedules, rows.Err()
 }
 
-func sqlCommonAddFolder(folder *vfs.BaseVirtualFolder, dbHandle sqlQuerier) error {
-	err := ValidateFolder(folder)
-	if err != nil {
+func sqlCommonAddSchedule(schedule *Schedule, dbHandle *sql.DB) error {
+	if err := schedule.validate(); err != nil {
 		return err
 	}
-	fsConfig, err := json.Marshal(folder.FsConfig)
+	options, err := json.Marshal(schedule.Options)
 	if err != nil {
 		return err
 	}
 	ctx, cancel := context.WithTimeout(context.Background(), defaultSQLQueryTimeout)
 	defer cancel()
-	q := getAddFolderQuery()
+	q := getAddScheduleQuery()
 	stmt, err := dbHandle.PrepareContext(ctx, q)
 	if err != nil {
 		providerLog(logger.LevelWarn, "error preparing database query %#v: %v", q, err)
 		return err
 	}
 	defer stmt.Close()
-	_, err = stmt.ExecContext(ctx, folder.MappedPath, folder.UsedQuotaSize, folder.UsedQuotaFiles,
-		folder.LastQuotaUpdate, folder.Name, folder.Description, string(fsConfig))
+	_, err = stmt.ExecContext(ctx, schedule.Name, schedule.Description, util.GetTimeAsMsSinceEpoch(time.Now()),
+		util.GetTimeAsMsSinceEpoch(time.Now()), schedule.Status, schedule.CronExpression, schedule.Task, string(options))
 	return err
 }
 
-func sqlCommonUpdateFolder(folder *vfs.BaseVirtualFolder, dbHandle sqlQuerier) error {
-	err := ValidateFolder(folder)
-	if err != nil {
+func sqlCommonUpdateSchedule(schedule *Schedule, dbHandle *sql.DB) error {
+	if err := schedule.validate(); err != nil {
 		return err
 	}
-	fsConfig, err := json.Marshal(folder.FsConfig)
+	options, err := json.Marshal(schedule.Options)
 	if err != nil {
 		return err
 	}
 	ctx, cancel := context.WithTimeout(context.Background(), defaultSQLQueryTimeout)
 	defer cancel()
-	q := getUpdateFolderQuery()
+	q := getUpdateScheduleQuery()
 	stmt, err := dbHandle.PrepareContext(ctx, q)
 	if err != nil {
 		providerLog(logger.LevelWarn, "error preparing database query %#v: %v", q, err)
 		return err
 	}
 	defer stmt.Close()
-	_, err = stmt.ExecContext(ctx, folder.MappedPath, folder.Description, string(fsConfig), folder.Name)
+	_, err = stmt.ExecContext(ctx, schedule.Description, util.GetTimeAsMsSinceEpoch(time.Now()), schedule.Status,
+		schedule.CronExpression, schedule.Task, string(options), schedule.Name)
 	return err
 }
 
-func sqlCommonDeleteFolder(folder *vfs.BaseVirtualFolder, dbHandle sqlQuerier) error {
+func sqlCommonDeleteSchedule(schedule *Schedule, dbHandle *sql.DB) error {
 	ctx, cancel := context.WithTimeout(context.Background(), defaultSQLQueryTimeout)
 	defer cancel()
-	q := getDeleteFolderQuery()
+	q := getDeleteScheduleQuery()
 	stmt, err := dbHandle.PrepareContext(ctx, q)
 	if err != nil {
 		providerLog(logger.LevelWarn, "error preparing database query %#v: %v", q, err)
 		return err
 	}
 	defer stmt.Close()
-	_, err = stmt.ExecContext(ctx, folder.ID)
+	_, err = stmt.ExecContext(ctx, schedule.Name)
 	return err
 }
 
-func sqlCommonDumpFolders(dbHandle sqlQuerier) ([]vfs.BaseVirtualFolder, error) {
-	folders := make([]vfs.BaseVirtualFolder, 0, 50)
-	ctx, cancel := context.WithTimeout(context.Background(), longSQLQueryTimeout)
+func sqlCommonClaimScheduleRun(name string, runAt int64, dbHandle *sql.DB) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultSQLQueryTimeout)
 	defer cancel()
-	q := getDumpFoldersQuery()
+	q := getClaimScheduleRunQuery()
+	stmt, err := dbHandle.PrepareContext(ctx, q)
+	if err != nil {
+		providerLog(logger.LevelWarn, "error preparing database query %#v: %v", q, err)
+		return false, err
+	}
+	defer stmt.Close()
+	res, err := stmt.ExecContext(ctx, runAt, ScheduleRunStatusRunning, name, runAt)
+	if err != nil {
+		return false, err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	// the run is claimed only if this instance updated the last run time,
+	// another instance sharing the same database could have already done it
+	return affected == 1, nil
+}
+
+func sqlCommonSetScheduleRunResult(name string, status int, runError string, dbHandle *sql.DB) error {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultSQLQueryTimeout)
+	defer cancel()
+	q := getSetScheduleRunResultQuery()
+	stmt, err := dbHandle.PrepareContext(ctx, q)
+	if err != nil {
+		providerLog(logger.LevelWarn, "error preparing database query %#v: %v", q, err)
+		return err
+	}
+	defer stmt.Close()
+	_, err = stmt.ExecContext(ctx, status, runError, name)
+	return err
+}
+
+func getGroupsWithVirtualFolders(ctx context.Context, groups []Group, dbHandle sqlQuerier) ([]Group, error) {
+	if len(groups) == 0 {
+		return groups, nil
+	}
+
+	var err error
+	groupsVirtualFolders := make(map[int64][]vfs.VirtualFolder)
+	q := getRelatedFoldersForGroupsQuery(groups)
 	stmt, err := dbHandle.PrepareContext(ctx, q)
 	if err != nil {
 		providerLog(logger.LevelWarn, "error preparing database query %#v: %v", q, err)
@@ -1037,16 +3355,20 @@ func sqlCommonDumpFolders(dbHandle sqlQuerier) ([]vfs.BaseVirtualFolder, error)
 	defer stmt.Close()
 	rows, err := stmt.QueryContext(ctx)
 	if err != nil {
-		return folders, err
+		return nil, err
 	}
 	defer rows.Close()
 	for rows.Next() {
-		var folder vfs.BaseVirtualFolder
-		var mappedPath, description, fsConfig sql.NullString
-		err = rows.Scan(&folder.ID, &mappedPath, &folder.UsedQuotaSize, &folder.UsedQuotaFiles,
-			&folder.LastQuotaUpdate, &folder.Name, &description, &fsConfig)
+		var folder vfs.VirtualFolder
+		var groupID int64
+		var mappedPath, fsConfig, description, resetPeriod, dataRetention sql.NullString
+		err = rows.Scan(&folder.ID, &folder.Name, &mappedPath, &folder.UsedQuotaSize, &folder.UsedQuotaFiles,
+			&folder.LastQuotaUpdate, &folder.VirtualPath, &folder.QuotaSize, &folder.QuotaFiles, &groupID, &fsConfig,
+			&description, &folder.UploadDataTransfer,
+			&folder.DownloadDataTransfer, &folder.TotalDataTransfer, &folder.UsedUploadDataTransfer,
+			&folder.UsedDownloadDataTransfer, &folder.LastDataTransferReset, &resetPeriod, &dataRetention)
 		if err != nil {
-			return folders, err
+			return groups, err
 		}
 		if mappedPath.Valid {
 			folder.MappedPath = mappedPath.String
@@ -1054,6 +3376,15 @@ func sqlCommonDumpFolders(dbHandle sqlQuerier) ([]vfs.BaseVirtualFolder, error)
 		if description.Valid {
 			folder.Description = description.String
 		}
+		if resetPeriod.Valid {
+			folder.DataTransferResetPeriod = sdk.DataTransferResetPeriod(resetPeriod.String)
+		}
+		if dataRetention.Valid {
+			var retention []sdk.DataRetentionPolicy
+			if err := json.Unmarshal([]byte(dataRetention.String), &retention); err == nil {
+				folder.DataRetention = retention
+			}
+		}
 		if fsConfig.Valid {
 			var fs vfs.Filesystem
 			err = json.Unmarshal([]byte(fsConfig.String), &fs)
@@ -1061,62 +3392,62 @@ func sqlCommonDumpFolders(dbHandle sqlQuerier) ([]vfs.BaseVirtualFolder, error)
 				folder.FsConfig = fs
 			}
 		}
-		folders = append(folders, folder)
+		groupsVirtualFolders[groupID] = append(groupsVirtualFolders[groupID], folder)
 	}
 	err = rows.Err()
 	if err != nil {
-		return folders, err
+		return groups, err
 	}
-	return getVirtualFoldersWithUsers(folders, dbHandle)
+	if len(groupsVirtualFolders) == 0 {
+		return groups, err
+	}
+	for idx := range groups {
+		ref := &groups[idx]
+		ref.VirtualFolders = groupsVirtualFolders[ref.ID]
+	}
+	return groups, err
 }
 
-func sqlCommonGetFolders(limit, offset int, order string, dbHandle sqlQuerier) ([]vfs.BaseVirtualFolder, error) {
-	folders := make([]vfs.BaseVirtualFolder, 0, limit)
-	ctx, cancel := context.WithTimeout(context.Background(), defaultSQLQueryTimeout)
-	defer cancel()
-	q := getFoldersQuery(order)
+func getGroupsWithUsers(ctx context.Context, groups []Group, dbHandle sqlQuerier) ([]Group, error) {
+	if len(groups) == 0 {
+		return groups, nil
+	}
+
+	var err error
+	groupsUsers := make(map[int64][]string)
+	q := getRelatedUsersForGroupsQuery(groups)
 	stmt, err := dbHandle.PrepareContext(ctx, q)
 	if err != nil {
 		providerLog(logger.LevelWarn, "error preparing database query %#v: %v", q, err)
 		return nil, err
 	}
 	defer stmt.Close()
-
-	rows, err := stmt.QueryContext(ctx, limit, offset)
+	rows, err := stmt.QueryContext(ctx)
 	if err != nil {
-		return folders, err
+		return nil, err
 	}
 	defer rows.Close()
 	for rows.Next() {
-		var folder vfs.BaseVirtualFolder
-		var mappedPath, description, fsConfig sql.NullString
-		err = rows.Scan(&folder.ID, &mappedPath, &folder.UsedQuotaSize, &folder.UsedQuotaFiles,
-			&folder.LastQuotaUpdate, &folder.Name, &description, &fsConfig)
+		var groupID int64
+		var username string
+		err = rows.Scan(&groupID, &username)
 		if err != nil {
-			return folders, err
-		}
-		if mappedPath.Valid {
-			folder.MappedPath = mappedPath.String
-		}
-		if description.Valid {
-			folder.Description = description.String
-		}
-		if fsConfig.Valid {
-			var fs vfs.Filesystem
-			err = json.Unmarshal([]byte(fsConfig.String), &fs)
-			if err == nil {
-				folder.FsConfig = fs
-			}
+			return groups, err
 		}
-		folder.PrepareForRendering()
-		folders = append(folders, folder)
+		groupsUsers[groupID] = append(groupsUsers[groupID], username)
 	}
-
 	err = rows.Err()
 	if err != nil {
-		return folders, err
+		return groups, err
 	}
-	return getVirtualFoldersWithUsers(folders, dbHandle)
+	if len(groupsUsers) == 0 {
+		return groups, err
+	}
+	for idx := range groups {
+		ref := &groups[idx]
+		ref.Users = groupsUsers[ref.ID]
+	}
+	return groups, err
 }
 
 func sqlCommonClearFolderMapping(ctx context.Context, user *User, dbHandle sqlQuerier) error {
@@ -1163,6 +3494,88 @@ func generateVirtualFoldersMapping(ctx context.Context, user *User, dbHandle sql
 	return err
 }
 
+func sqlCommonClearGroupsMapping(ctx context.Context, user *User, dbHandle sqlQuerier) error {
+	q := getClearUserGroupMappingQuery()
+	stmt, err := dbHandle.PrepareContext(ctx, q)
+	if err != nil {
+		providerLog(logger.LevelWarn, "error preparing database query %#v: %v", q, err)
+		return err
+	}
+	defer stmt.Close()
+	_, err = stmt.ExecContext(ctx, user.Username)
+	return err
+}
+
+func sqlCommonAddGroupMapping(ctx context.Context, user *User, groupName string, dbHandle sqlQuerier) error {
+	q := getAddUserGroupMappingQuery()
+	stmt, err := dbHandle.PrepareContext(ctx, q)
+	if err != nil {
+		providerLog(logger.LevelWarn, "error preparing database query %#v: %v", q, err)
+		return err
+	}
+	defer stmt.Close()
+	_, err = stmt.ExecContext(ctx, user.Username, groupName)
+	return err
+}
+
+func generateGroupsMapping(ctx context.Context, user *User, dbHandle sqlQuerier) error {
+	err := sqlCommonClearGroupsMapping(ctx, user, dbHandle)
+	if err != nil {
+		return err
+	}
+	for _, groupName := range user.Groups {
+		err = sqlCommonAddGroupMapping(ctx, user, groupName, dbHandle)
+		if err != nil {
+			return err
+		}
+	}
+	return err
+}
+
+func sqlCommonClearGroupFolderMapping(ctx context.Context, group *Group, dbHandle sqlQuerier) error {
+	q := getClearGroupFolderMappingQuery()
+	stmt, err := dbHandle.PrepareContext(ctx, q)
+	if err != nil {
+		providerLog(logger.LevelWarn, "error preparing database query %#v: %v", q, err)
+		return err
+	}
+	defer stmt.Close()
+	_, err = stmt.ExecContext(ctx, group.Name)
+	return err
+}
+
+func sqlCommonAddGroupFolderMapping(ctx context.Context, group *Group, folder *vfs.VirtualFolder, dbHandle sqlQuerier) error {
+	q := getAddGroupFolderMappingQuery()
+	stmt, err := dbHandle.PrepareContext(ctx, q)
+	if err != nil {
+		providerLog(logger.LevelWarn, "error preparing database query %#v: %v", q, err)
+		return err
+	}
+	defer stmt.Close()
+	_, err = stmt.ExecContext(ctx, folder.VirtualPath, folder.QuotaSize, folder.QuotaFiles, folder.ID, group.Name)
+	return err
+}
+
+func generateGroupVirtualFoldersMapping(ctx context.Context, group *Group, dbHandle sqlQuerier) error {
+	err := sqlCommonClearGroupFolderMapping(ctx, group, dbHandle)
+	if err != nil {
+		return err
+	}
+	for idx := range group.VirtualFolders {
+		vfolder := &group.VirtualFolders[idx]
+		f, err := sqlCommonAddOrUpdateFolder(ctx, &vfolder.BaseVirtualFolder, 0, 0, 0, dbHandle)
+		if err != nil {
+			return err
+		}
+		vfolder.BaseVirtualFolder = f
+		err = sqlCommonAddGroupFolderMapping(ctx, group, vfolder, dbHandle)
+		if err != nil {
+			return err
+		}
+	}
+	return err
+}
+
 func getUserWithVirtualFolders(ctx context.Context, user User, dbHandle sqlQuerier) (User, error) {
 	users, err := getUsersWithVirtualFolders(ctx, []User{user}, dbHandle)
 	if err != nil {
@@ -1196,10 +3609,12 @@ func getUsersWithVirtualFolders(ctx context.Context, users []User, dbHandle sqlQ
 	for rows.Next() {
 		var folder vfs.VirtualFolder
 		var userID int64
-		var mappedPath, fsConfig, description sql.NullString
+		var mappedPath, fsConfig, description, resetPeriod, dataRetention sql.NullString
 		err = rows.Scan(&folder.ID, &folder.Name, &mappedPath, &folder.UsedQuotaSize, &folder.UsedQuotaFiles,
 			&folder.LastQuotaUpdate, &folder.VirtualPath, &folder.QuotaSize, &folder.QuotaFiles, &userID, &fsConfig,
-			&description)
+			&description, &folder.UploadDataTransfer,
+			&folder.DownloadDataTransfer, &folder.TotalDataTransfer, &folder.UsedUploadDataTransfer,
+			&folder.UsedDownloadDataTransfer, &folder.LastDataTransferReset, &resetPeriod, &dataRetention)
 		if err != nil {
 			return users, err
 		}
@@ -1209,6 +3624,15 @@ func getUsersWithVirtualFolders(ctx context.Context, users []User, dbHandle sqlQ
 		if description.Valid {
 			folder.Description = description.String
 		}
+		if resetPeriod.Valid {
+			folder.DataTransferResetPeriod = sdk.DataTransferResetPeriod(resetPeriod.String)
+		}
+		if dataRetention.Valid {
+			var retention []sdk.DataRetentionPolicy
+			if err := json.Unmarshal([]byte(dataRetention.String), &retention); err == nil {
+				folder.DataRetention = retention
+			}
+		}
 		if fsConfig.Valid {
 			var fs vfs.Filesystem
 			err = json.Unmarshal([]byte(fsConfig.String), &fs)
@@ -1222,12 +3646,53 @@ func getUsersWithVirtualFolders(ctx context.Context, users []User, dbHandle sqlQ
 	if err != nil {
 		return users, err
 	}
-	if len(usersVirtualFolders) == 0 {
+	if len(usersVirtualFolders) > 0 {
+		for idx := range users {
+			ref := &users[idx]
+			ref.VirtualFolders = usersVirtualFolders[ref.ID]
+		}
+	}
+	return getUsersWithGroups(ctx, users, dbHandle)
+}
+
+func getUsersWithGroups(ctx context.Context, users []User, dbHandle sqlQuerier) ([]User, error) {
+	if len(users) == 0 {
+		return users, nil
+	}
+
+	var err error
+	usersGroups := make(map[int64][]string)
+	q := getRelatedGroupsForUsersQuery(users)
+	stmt, err := dbHandle.PrepareContext(ctx, q)
+	if err != nil {
+		providerLog(logger.LevelWarn, "error preparing database query %#v: %v", q, err)
+		return nil, err
+	}
+	defer stmt.Close()
+	rows, err := stmt.QueryContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var userID int64
+		var groupName string
+		err = rows.Scan(&userID, &groupName)
+		if err != nil {
+			return users, err
+		}
+		usersGroups[userID] = append(usersGroups[userID], groupName)
+	}
+	err = rows.Err()
+	if err != nil {
+		return users, err
+	}
+	if len(usersGroups) == 0 {
 		return users, err
 	}
 	for idx := range users {
 		ref := &users[idx]
-		ref.VirtualFolders = usersVirtualFolders[ref.ID]
+		ref.Groups = usersGroups[ref.ID]
 	}
 	return users, err
 }
@@ -1266,12 +3731,53 @@ func getVirtualFoldersWithUsers(folders []vfs.BaseVirtualFolder, dbHandle sqlQue
 	if err != nil {
 		return folders, err
 	}
-	if len(vFoldersUsers) == 0 {
+	if len(vFoldersUsers) > 0 {
+		for idx := range folders {
+			ref := &folders[idx]
+			ref.Users = vFoldersUsers[ref.ID]
+		}
+	}
+	return getVirtualFoldersWithGroups(ctx, folders, dbHandle)
+}
+
+func getVirtualFoldersWithGroups(ctx context.Context, folders []vfs.BaseVirtualFolder, dbHandle sqlQuerier) ([]vfs.BaseVirtualFolder, error) {
+	if len(folders) == 0 {
+		return folders, nil
+	}
+
+	var err error
+	vFoldersGroups := make(map[int64][]string)
+	q := getRelatedGroupsForFoldersQuery(folders)
+	stmt, err := dbHandle.PrepareContext(ctx, q)
+	if err != nil {
+		providerLog(logger.LevelWarn, "error preparing database query %#v: %v", q, err)
+		return nil, err
+	}
+	defer stmt.Close()
+	rows, err := stmt.QueryContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var groupName string
+		var folderID int64
+		err = rows.Scan(&folderID, &groupName)
+		if err != nil {
+			return folders, err
+		}
+		vFoldersGroups[folderID] = append(vFoldersGroups[folderID], groupName)
+	}
+	err = rows.Err()
+	if err != nil {
+		return folders, err
+	}
+	if len(vFoldersGroups) == 0 {
 		return folders, err
 	}
 	for idx := range folders {
 		ref := &folders[idx]
-		ref.Users = vFoldersUsers[ref.ID]
+		ref.Groups = vFoldersGroups[ref.ID]
 	}
 	return folders, err
 }
@@ -1296,6 +3802,47 @@ func sqlCommonUpdateFolderQuota(name string, filesAdd int, sizeAdd int64, reset
 	return err
 }
 
+func sqlCommonUpdateFolderTransferQuota(name string, uploadSize, downloadSize int64, reset bool, periodStart int64,
+	dbHandle *sql.DB) error {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultSQLQueryTimeout)
+	defer cancel()
+	q := getUpdateFolderTransferQuotaQuery(reset)
+	stmt, err := dbHandle.PrepareContext(ctx, q)
+	if err != nil {
+		providerLog(logger.LevelWarn, "error preparing database query %#v: %v", q, err)
+		return err
+	}
+	defer stmt.Close()
+	_, err = stmt.ExecContext(ctx, getUpdateTransferQuotaArgs(uploadSize, downloadSize, reset, periodStart, name)...)
+	if err == nil {
+		providerLog(logger.LevelDebug, "transfer quota updated for folder %#v, ul increment: %v dl increment: %v is reset? %v",
+			name, uploadSize, downloadSize, reset)
+	} else {
+		providerLog(logger.LevelWarn, "error updating transfer quota for folder %#v: %v", name, err)
+	}
+	return err
+}
+
+func sqlCommonGetFolderUsedTransferQuota(name string, dbHandle *sql.DB) (int64, int64, int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultSQLQueryTimeout)
+	defer cancel()
+	q := getTransferQuotaFolderQuery()
+	stmt, err := dbHandle.PrepareContext(ctx, q)
+	if err != nil {
+		providerLog(logger.LevelWarn, "error preparing database query %#v: %v", q, err)
+		return 0, 0, 0, err
+	}
+	defer stmt.Close()
+
+	var ulSize, dlSize, lastReset int64
+	err = stmt.QueryRowContext(ctx, name).Scan(&ulSize, &dlSize, &lastReset)
+	if err != nil {
+		providerLog(logger.LevelWarn, "error getting transfer quota for folder: %v, error: %v", name, err)
+		return 0, 0, 0, err
+	}
+	return ulSize, dlSize, lastReset, err
+}
+
 func sqlCommonGetFolderUsedQuota(mappedPath string, dbHandle *sql.DB) (int, int64, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), defaultSQLQueryTimeout)
 	defer cancel()
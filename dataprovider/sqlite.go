@@ -11,6 +11,7 @@ import (
 	"fmt"
 	"path/filepath"
 	"strings"
+	"time"
 
 	// we import go-sqlite3 here to be able to disable SQLite support using a build tag
 	_ "github.com/mattn/go-sqlite3"
@@ -69,6 +70,157 @@ ALTER TABLE "{{admins}}" DROP COLUMN "last_login";
 `
 	sqliteV13SQL     = `ALTER TABLE "{{users}}" ADD COLUMN "email" varchar(255) NULL;`
 	sqliteV13DownSQL = `ALTER TABLE "{{users}}" DROP COLUMN "email";`
+	sqliteV14SQL     = `CREATE TABLE "{{shares}}" ("id" integer NOT NULL PRIMARY KEY AUTOINCREMENT,
+"share_id" varchar(60) NOT NULL UNIQUE, "name" varchar(255) NOT NULL, "description" varchar(512) NULL,
+"scope" integer NOT NULL, "paths" text NOT NULL, "created_at" bigint NOT NULL, "updated_at" bigint NOT NULL,
+"last_use_at" bigint NOT NULL, "expires_at" bigint NOT NULL, "password" text NULL, "max_tokens" integer NOT NULL,
+"used_tokens" integer NOT NULL, "allow_from" text NULL,
+"user_id" integer NOT NULL REFERENCES "{{users}}" ("id") ON DELETE CASCADE DEFERRABLE INITIALLY DEFERRED);
+CREATE INDEX "{{prefix}}shares_user_id_idx" ON "{{shares}}" ("user_id");
+`
+	sqliteV14DownSQL = `DROP TABLE "{{shares}}";`
+	sqliteV15SQL     = `CREATE TABLE "{{share_uploads}}" ("id" integer NOT NULL PRIMARY KEY AUTOINCREMENT,
+"path" text NOT NULL, "size" bigint NOT NULL, "ip" varchar(50) NOT NULL, "uploader" varchar(255) NULL,
+"uploaded_at" bigint NOT NULL,
+"share_id" integer NOT NULL REFERENCES "{{shares}}" ("id") ON DELETE CASCADE DEFERRABLE INITIALLY DEFERRED);
+CREATE INDEX "{{prefix}}share_uploads_share_id_idx" ON "{{share_uploads}}" ("share_id");
+`
+	sqliteV15DownSQL = `DROP TABLE "{{share_uploads}}";`
+	sqliteV16SQL     = `CREATE TABLE "{{groups}}" ("id" integer NOT NULL PRIMARY KEY AUTOINCREMENT, "name" varchar(255) NOT NULL UNIQUE,
+"description" varchar(512) NULL, "created_at" bigint NOT NULL, "updated_at" bigint NOT NULL, "user_settings" text NULL);
+CREATE TABLE "{{groups_folders_mapping}}" ("id" integer NOT NULL PRIMARY KEY AUTOINCREMENT,
+"virtual_path" varchar(512) NOT NULL, "quota_size" bigint NOT NULL, "quota_files" integer NOT NULL,
+"folder_id" integer NOT NULL REFERENCES "{{folders}}" ("id") ON DELETE CASCADE DEFERRABLE INITIALLY DEFERRED,
+"group_id" integer NOT NULL REFERENCES "{{groups}}" ("id") ON DELETE CASCADE DEFERRABLE INITIALLY DEFERRED,
+CONSTRAINT "{{prefix}}unique_group_folder_mapping" UNIQUE ("group_id", "folder_id"));
+CREATE TABLE "{{groups_mapping}}" ("id" integer NOT NULL PRIMARY KEY AUTOINCREMENT,
+"user_id" integer NOT NULL REFERENCES "{{users}}" ("id") ON DELETE CASCADE DEFERRABLE INITIALLY DEFERRED,
+"group_id" integer NOT NULL REFERENCES "{{groups}}" ("id") ON DELETE CASCADE DEFERRABLE INITIALLY DEFERRED,
+CONSTRAINT "{{prefix}}unique_group_mapping" UNIQUE ("user_id", "group_id"));
+CREATE INDEX "{{prefix}}groups_folders_mapping_folder_id_idx" ON "{{groups_folders_mapping}}" ("folder_id");
+CREATE INDEX "{{prefix}}groups_folders_mapping_group_id_idx" ON "{{groups_folders_mapping}}" ("group_id");
+CREATE INDEX "{{prefix}}groups_mapping_user_id_idx" ON "{{groups_mapping}}" ("user_id");
+CREATE INDEX "{{prefix}}groups_mapping_group_id_idx" ON "{{groups_mapping}}" ("group_id");
+`
+	sqliteV16DownSQL = `DROP TABLE "{{groups_mapping}}";
+DROP TABLE "{{groups_folders_mapping}}";
+DROP TABLE "{{groups}}";
+`
+	sqliteV17SQL = `ALTER TABLE "{{users}}" ADD COLUMN "upload_data_transfer" bigint DEFAULT 0 NOT NULL;
+ALTER TABLE "{{users}}" ADD COLUMN "download_data_transfer" bigint DEFAULT 0 NOT NULL;
+ALTER TABLE "{{users}}" ADD COLUMN "total_data_transfer" bigint DEFAULT 0 NOT NULL;
+ALTER TABLE "{{users}}" ADD COLUMN "used_upload_data_transfer" bigint DEFAULT 0 NOT NULL;
+ALTER TABLE "{{users}}" ADD COLUMN "used_download_data_transfer" bigint DEFAULT 0 NOT NULL;
+ALTER TABLE "{{users}}" ADD COLUMN "last_data_transfer_reset" bigint DEFAULT 0 NOT NULL;
+ALTER TABLE "{{users}}" ADD COLUMN "data_transfer_reset_period" varchar(32) NULL;
+ALTER TABLE "{{folders}}" ADD COLUMN "upload_data_transfer" bigint DEFAULT 0 NOT NULL;
+ALTER TABLE "{{folders}}" ADD COLUMN "download_data_transfer" bigint DEFAULT 0 NOT NULL;
+ALTER TABLE "{{folders}}" ADD COLUMN "total_data_transfer" bigint DEFAULT 0 NOT NULL;
+ALTER TABLE "{{folders}}" ADD COLUMN "used_upload_data_transfer" bigint DEFAULT 0 NOT NULL;
+ALTER TABLE "{{folders}}" ADD COLUMN "used_download_data_transfer" bigint DEFAULT 0 NOT NULL;
+ALTER TABLE "{{folders}}" ADD COLUMN "last_data_transfer_reset" bigint DEFAULT 0 NOT NULL;
+ALTER TABLE "{{folders}}" ADD COLUMN "data_transfer_reset_period" varchar(32) NULL;
+`
+	sqliteV17DownSQL = `ALTER TABLE "{{folders}}" DROP COLUMN "data_transfer_reset_period";
+ALTER TABLE "{{folders}}" DROP COLUMN "last_data_transfer_reset";
+ALTER TABLE "{{folders}}" DROP COLUMN "used_download_data_transfer";
+ALTER TABLE "{{folders}}" DROP COLUMN "used_upload_data_transfer";
+ALTER TABLE "{{folders}}" DROP COLUMN "total_data_transfer";
+ALTER TABLE "{{folders}}" DROP COLUMN "download_data_transfer";
+ALTER TABLE "{{folders}}" DROP COLUMN "upload_data_transfer";
+ALTER TABLE "{{users}}" DROP COLUMN "data_transfer_reset_period";
+ALTER TABLE "{{users}}" DROP COLUMN "last_data_transfer_reset";
+ALTER TABLE "{{users}}" DROP COLUMN "used_download_data_transfer";
+ALTER TABLE "{{users}}" DROP COLUMN "used_upload_data_transfer";
+ALTER TABLE "{{users}}" DROP COLUMN "total_data_transfer";
+ALTER TABLE "{{users}}" DROP COLUMN "download_data_transfer";
+ALTER TABLE "{{users}}" DROP COLUMN "upload_data_transfer";
+`
+	sqliteV18SQL = `ALTER TABLE "{{users}}" ADD COLUMN "last_password_change" bigint DEFAULT 0 NOT NULL;
+UPDATE "{{users}}" SET "last_password_change" = "updated_at";
+ALTER TABLE "{{admins}}" ADD COLUMN "last_password_change" bigint DEFAULT 0 NOT NULL;
+UPDATE "{{admins}}" SET "last_password_change" = "updated_at";
+`
+	sqliteV18DownSQL = `ALTER TABLE "{{admins}}" DROP COLUMN "last_password_change";
+ALTER TABLE "{{users}}" DROP COLUMN "last_password_change";
+`
+	sqliteV19SQL = `CREATE TABLE "{{account_lockouts}}" ("id" integer NOT NULL PRIMARY KEY AUTOINCREMENT,
+"username" varchar(255) NOT NULL, "account_type" varchar(16) NOT NULL, "failures" integer NOT NULL,
+"first_failure_at" bigint NOT NULL, "last_failure_at" bigint NOT NULL, "locked_at" bigint NOT NULL,
+"locked_until" bigint NOT NULL,
+CONSTRAINT "{{prefix}}unique_account_lockout" UNIQUE ("username", "account_type"));
+`
+	sqliteV19DownSQL = `DROP TABLE "{{account_lockouts}}";`
+	sqliteV20SQL     = `CREATE TABLE "{{fs_events}}" ("id" varchar(36) NOT NULL PRIMARY KEY, "timestamp" bigint NOT NULL,
+"action" varchar(60) NOT NULL, "username" varchar(255) NOT NULL, "fs_path" text NOT NULL,
+"fs_target_path" text NOT NULL, "virtual_path" text NOT NULL, "virtual_target_path" text NOT NULL,
+"ssh_cmd" varchar(255) NOT NULL, "file_size" bigint NOT NULL, "status" integer NOT NULL,
+"protocol" varchar(30) NOT NULL, "ip" varchar(50) NOT NULL, "instance_id" varchar(255) NOT NULL);
+CREATE TABLE "{{provider_events}}" ("id" varchar(36) NOT NULL PRIMARY KEY, "timestamp" bigint NOT NULL,
+"action" varchar(60) NOT NULL, "username" varchar(255) NOT NULL, "ip" varchar(50) NOT NULL,
+"object_type" varchar(60) NOT NULL, "object_name" varchar(255) NOT NULL, "instance_id" varchar(255) NOT NULL);
+CREATE INDEX "{{prefix}}fs_events_timestamp_idx" ON "{{fs_events}}" ("timestamp");
+CREATE INDEX "{{prefix}}provider_events_timestamp_idx" ON "{{provider_events}}" ("timestamp");
+`
+	sqliteV20DownSQL = `DROP TABLE "{{provider_events}}";
+DROP TABLE "{{fs_events}}";
+`
+	sqliteV21SQL = `CREATE TABLE "{{events_rules}}" ("id" integer NOT NULL PRIMARY KEY AUTOINCREMENT, "name" varchar(255) NOT NULL UNIQUE,
+"description" varchar(512) NULL, "created_at" bigint NOT NULL, "updated_at" bigint NOT NULL, "event_trigger" integer NOT NULL,
+"conditions" text NOT NULL, "actions" text NOT NULL);
+`
+	sqliteV21DownSQL = `DROP TABLE "{{events_rules}}";
+`
+	sqliteV22SQL = `CREATE TABLE "{{schedules}}" ("id" integer NOT NULL PRIMARY KEY AUTOINCREMENT, "name" varchar(255) NOT NULL UNIQUE,
+"description" varchar(512) NULL, "created_at" bigint NOT NULL, "updated_at" bigint NOT NULL, "status" integer NOT NULL,
+"cron_expression" varchar(255) NOT NULL, "task" integer NOT NULL, "options" text NOT NULL, "last_run" bigint DEFAULT 0 NOT NULL,
+"last_run_status" integer DEFAULT 0 NOT NULL, "last_run_error" text NULL);
+`
+	sqliteV22DownSQL = `DROP TABLE "{{schedules}}";
+`
+	sqliteV23SQL     = `ALTER TABLE "{{folders}}" ADD COLUMN "data_retention" text NULL;`
+	sqliteV23DownSQL = `ALTER TABLE "{{folders}}" DROP COLUMN "data_retention";`
+	sqliteV24SQL     = `CREATE TABLE "{{webdav_locks}}" ("id" integer NOT NULL PRIMARY KEY AUTOINCREMENT,
+"token" varchar(255) NOT NULL UNIQUE, "username" varchar(255) NOT NULL, "root" text NOT NULL,
+"root_key" varchar(64) NOT NULL, "duration" bigint NOT NULL, "expires_at" bigint NOT NULL, "owner_xml" text NULL,
+"zero_depth" integer NOT NULL, "created_at" bigint NOT NULL, "updated_at" bigint NOT NULL,
+CONSTRAINT "{{prefix}}unique_webdav_lock_root" UNIQUE ("username", "root_key"));
+CREATE INDEX "{{prefix}}webdav_locks_expires_at_idx" ON "{{webdav_locks}}" ("expires_at");
+`
+	sqliteV24DownSQL = `DROP TABLE "{{webdav_locks}}";`
+	sqliteV25SQL     = `CREATE TABLE "{{webdav_properties}}" ("id" integer NOT NULL PRIMARY KEY AUTOINCREMENT,
+"username" varchar(255) NOT NULL, "path" text NOT NULL, "path_key" varchar(64) NOT NULL, "properties" text NOT NULL,
+"updated_at" bigint NOT NULL,
+CONSTRAINT "{{prefix}}unique_webdav_properties_path" UNIQUE ("username", "path_key"));
+`
+	sqliteV25DownSQL = `DROP TABLE "{{webdav_properties}}";`
+	sqliteV26SQL     = `CREATE TABLE "{{s3_access_keys}}" ("id" integer NOT NULL PRIMARY KEY AUTOINCREMENT,
+"access_key_id" varchar(64) NOT NULL UNIQUE, "username" varchar(255) NOT NULL, "secret_access_key" text NOT NULL,
+"description" text NULL, "created_at" bigint NOT NULL);
+CREATE INDEX "{{prefix}}s3_access_keys_username_idx" ON "{{s3_access_keys}}" ("username");
+`
+	sqliteV26DownSQL = `DROP TABLE "{{s3_access_keys}}";`
+	sqliteV27SQL     = `CREATE TABLE "{{tus_uploads}}" ("id" integer NOT NULL PRIMARY KEY AUTOINCREMENT,
+"upload_id" varchar(64) NOT NULL UNIQUE, "username" varchar(255) NOT NULL, "virtual_path" text NOT NULL,
+"fs_path" text NOT NULL, "upload_length" bigint NOT NULL, "upload_offset" bigint NOT NULL,
+"expires_at" bigint NOT NULL, "created_at" bigint NOT NULL, "updated_at" bigint NOT NULL);
+CREATE INDEX "{{prefix}}tus_uploads_username_idx" ON "{{tus_uploads}}" ("username");
+CREATE INDEX "{{prefix}}tus_uploads_expires_at_idx" ON "{{tus_uploads}}" ("expires_at");
+`
+	sqliteV27DownSQL = `DROP TABLE "{{tus_uploads}}";`
+	sqliteV28SQL     = `CREATE TABLE "{{reset_codes}}" ("id" integer NOT NULL PRIMARY KEY AUTOINCREMENT,
+"code" varchar(64) NOT NULL UNIQUE, "username" varchar(255) NOT NULL, "account_type" varchar(16) NOT NULL,
+"password_fingerprint" varchar(64) NOT NULL, "expires_at" bigint NOT NULL, "created_at" bigint NOT NULL);
+CREATE INDEX "{{prefix}}reset_codes_username_idx" ON "{{reset_codes}}" ("username");
+CREATE INDEX "{{prefix}}reset_codes_expires_at_idx" ON "{{reset_codes}}" ("expires_at");
+`
+	sqliteV28DownSQL = `DROP TABLE "{{reset_codes}}";`
+	sqliteV29SQL     = `CREATE TABLE "{{multipart_uploads}}" ("id" integer NOT NULL PRIMARY KEY AUTOINCREMENT,
+"upload_key" varchar(64) NOT NULL UNIQUE, "upload_size" bigint NOT NULL, "state" text NOT NULL,
+"created_at" bigint NOT NULL, "updated_at" bigint NOT NULL);
+CREATE INDEX "{{prefix}}multipart_uploads_updated_at_idx" ON "{{multipart_uploads}}" ("updated_at");
+`
+	sqliteV29DownSQL = `DROP TABLE "{{multipart_uploads}}";`
 )
 
 // SQLiteProvider auth provider for SQLite database
@@ -132,6 +284,14 @@ func (p *SQLiteProvider) getUsedQuota(username string) (int, int64, error) {
 	return sqlCommonGetUsedQuota(username, p.dbHandle)
 }
 
+func (p *SQLiteProvider) updateTransferQuota(username string, uploadSize, downloadSize int64, reset bool, periodStart int64) error {
+	return sqlCommonUpdateTransferQuota(username, uploadSize, downloadSize, reset, periodStart, p.dbHandle)
+}
+
+func (p *SQLiteProvider) getUsedTransferQuota(username string) (int64, int64, int64, error) {
+	return sqlCommonGetUsedTransferQuota(username, p.dbHandle)
+}
+
 func (p *SQLiteProvider) setUpdatedAt(username string) {
 	sqlCommonSetUpdatedAt(username, p.dbHandle)
 }
@@ -169,6 +329,10 @@ func (p *SQLiteProvider) getRecentlyUpdatedUsers(after int64) ([]User, error) {
 	return nil, nil
 }
 
+func (p *SQLiteProvider) getUsersInScope(admin *Admin, limit, offset int, order string) ([]User, error) {
+	return sqlCommonGetUsersInScope(admin, limit, offset, order, p.dbHandle)
+}
+
 func (p *SQLiteProvider) getUsers(limit int, offset int, order string) ([]User, error) {
 	return sqlCommonGetUsers(limit, offset, order, p.dbHandle)
 }
@@ -207,6 +371,42 @@ func (p *SQLiteProvider) getUsedFolderQuota(name string) (int, int64, error) {
 	return sqlCommonGetFolderUsedQuota(name, p.dbHandle)
 }
 
+func (p *SQLiteProvider) updateFolderTransferQuota(name string, uploadSize, downloadSize int64, reset bool, periodStart int64) error {
+	return sqlCommonUpdateFolderTransferQuota(name, uploadSize, downloadSize, reset, periodStart, p.dbHandle)
+}
+
+func (p *SQLiteProvider) getUsedFolderTransferQuota(name string) (int64, int64, int64, error) {
+	return sqlCommonGetFolderUsedTransferQuota(name, p.dbHandle)
+}
+
+func (p *SQLiteProvider) groupExists(name string) (Group, error) {
+	return sqlCommonGetGroupByName(name, p.dbHandle)
+}
+
+func (p *SQLiteProvider) addGroup(group *Group) error {
+	return sqlCommonAddGroup(group, p.dbHandle)
+}
+
+func (p *SQLiteProvider) updateGroup(group *Group) error {
+	return sqlCommonUpdateGroup(group, p.dbHandle)
+}
+
+func (p *SQLiteProvider) deleteGroup(group *Group) error {
+	return sqlCommonDeleteGroup(group, p.dbHandle)
+}
+
+func (p *SQLiteProvider) getGroups(limit, offset int, order string) ([]Group, error) {
+	return sqlCommonGetGroups(limit, offset, order, p.dbHandle)
+}
+
+func (p *SQLiteProvider) getGroupsWithNames(names []string) ([]Group, error) {
+	return sqlCommonGetGroupsWithNames(names, p.dbHandle)
+}
+
+func (p *SQLiteProvider) dumpGroups() ([]Group, error) {
+	return sqlCommonDumpGroups(p.dbHandle)
+}
+
 func (p *SQLiteProvider) adminExists(username string) (Admin, error) {
 	return sqlCommonGetAdminByUsername(username, p.dbHandle)
 }
@@ -263,6 +463,274 @@ func (p *SQLiteProvider) updateAPIKeyLastUse(keyID string) error {
 	return sqlCommonUpdateAPIKeyLastUse(keyID, p.dbHandle)
 }
 
+func (p *SQLiteProvider) shareExists(shareID, username string) (Share, error) {
+	return sqlCommonGetShareByID(shareID, username, p.dbHandle)
+}
+
+func (p *SQLiteProvider) addShare(share *Share) error {
+	return sqlCommonAddShare(share, p.dbHandle)
+}
+
+func (p *SQLiteProvider) updateShare(share *Share) error {
+	return sqlCommonUpdateShare(share, p.dbHandle)
+}
+
+func (p *SQLiteProvider) deleteShare(share *Share) error {
+	return sqlCommonDeleteShare(share, p.dbHandle)
+}
+
+func (p *SQLiteProvider) getShares(limit int, offset int, order, username string) ([]Share, error) {
+	return sqlCommonGetShares(limit, offset, order, username, p.dbHandle)
+}
+
+func (p *SQLiteProvider) dumpShares() ([]Share, error) {
+	return sqlCommonDumpShares(p.dbHandle)
+}
+
+func (p *SQLiteProvider) updateShareLastUse(shareID string, numTokens int) error {
+	return sqlCommonUpdateShareLastUse(shareID, numTokens, p.dbHandle)
+}
+
+func (p *SQLiteProvider) addShareUpload(upload *ShareUpload) error {
+	return sqlCommonAddShareUpload(upload, p.dbHandle)
+}
+
+func (p *SQLiteProvider) getShareUploads(shareID string, limit, offset int, order string) ([]ShareUpload, error) {
+	return sqlCommonGetShareUploads(shareID, limit, offset, order, p.dbHandle)
+}
+
+func (p *SQLiteProvider) accountLockoutExists(username, accountType string) (AccountLockout, error) {
+	return sqlCommonGetAccountLockout(username, accountType, p.dbHandle)
+}
+
+func (p *SQLiteProvider) addAccountLockoutFailure(username, accountType string, now time.Time) (AccountLockout, error) {
+	return sqlCommonAddAccountLockoutFailure(username, accountType, now, p.dbHandle)
+}
+
+func (p *SQLiteProvider) deleteAccountLockout(username, accountType string) error {
+	return sqlCommonDeleteAccountLockout(username, accountType, p.dbHandle)
+}
+
+func (p *SQLiteProvider) getAccountLockouts(limit, offset int, order string) ([]AccountLockout, error) {
+	return sqlCommonGetAccountLockouts(limit, offset, order, p.dbHandle)
+}
+
+func (p *SQLiteProvider) addFsEvent(event *FsEvent) error {
+	return sqlCommonAddFsEvent(event, p.dbHandle)
+}
+
+func (p *SQLiteProvider) addProviderEvent(event *ProviderEvent) error {
+	return sqlCommonAddProviderEvent(event, p.dbHandle)
+}
+
+func (p *SQLiteProvider) searchFsEvents(search *FsEventSearch) ([]FsEvent, error) {
+	return sqlCommonSearchFsEvents(search, p.dbHandle)
+}
+
+func (p *SQLiteProvider) searchProviderEvents(search *ProviderEventSearch) ([]ProviderEvent, error) {
+	return sqlCommonSearchProviderEvents(search, p.dbHandle)
+}
+
+func (p *SQLiteProvider) cleanupEvents(before int64) error {
+	return sqlCommonCleanupEvents(before, p.dbHandle)
+}
+
+func (p *SQLiteProvider) webDAVLockExists(token string) (WebDAVLock, error) {
+	return sqlCommonGetWebDAVLock(token, p.dbHandle)
+}
+
+func (p *SQLiteProvider) addWebDAVLock(lock *WebDAVLock) error {
+	return sqlCommonAddWebDAVLock(lock, p.dbHandle)
+}
+
+func (p *SQLiteProvider) updateWebDAVLock(lock *WebDAVLock) error {
+	return sqlCommonUpdateWebDAVLock(lock, p.dbHandle)
+}
+
+func (p *SQLiteProvider) deleteWebDAVLock(token string) error {
+	return sqlCommonDeleteWebDAVLock(token, p.dbHandle)
+}
+
+func (p *SQLiteProvider) getWebDAVLocks(username string) ([]WebDAVLock, error) {
+	return sqlCommonGetWebDAVLocks(username, p.dbHandle)
+}
+
+func (p *SQLiteProvider) deleteUserWebDAVLocks(username string) error {
+	return sqlCommonDeleteUserWebDAVLocks(username, p.dbHandle)
+}
+
+func (p *SQLiteProvider) cleanupWebDAVLocks(before int64) error {
+	return sqlCommonCleanupWebDAVLocks(before, p.dbHandle)
+}
+
+func (p *SQLiteProvider) webDAVPropertiesExists(username, virtualPath string) (WebDAVProperties, error) {
+	return sqlCommonGetWebDAVProperties(username, virtualPath, p.dbHandle)
+}
+
+func (p *SQLiteProvider) setWebDAVProperties(props *WebDAVProperties) error {
+	return sqlCommonSetWebDAVProperties(props, p.dbHandle)
+}
+
+func (p *SQLiteProvider) deleteWebDAVProperties(username, virtualPath string) error {
+	return sqlCommonDeleteWebDAVProperties(username, virtualPath, p.dbHandle)
+}
+
+func (p *SQLiteProvider) renameWebDAVProperties(username, source, target string) error {
+	return sqlCommonRenameWebDAVProperties(username, source, target, p.dbHandle)
+}
+
+func (p *SQLiteProvider) deleteUserWebDAVProperties(username string) error {
+	return sqlCommonDeleteUserWebDAVProperties(username, p.dbHandle)
+}
+
+func (p *SQLiteProvider) s3AccessKeyExists(accessKeyID string) (S3AccessKey, error) {
+	return sqlCommonGetS3AccessKey(accessKeyID, p.dbHandle)
+}
+
+func (p *SQLiteProvider) addS3AccessKey(key *S3AccessKey) error {
+	return sqlCommonAddS3AccessKey(key, p.dbHandle)
+}
+
+func (p *SQLiteProvider) deleteS3AccessKey(accessKeyID, username string) error {
+	return sqlCommonDeleteS3AccessKey(accessKeyID, username, p.dbHandle)
+}
+
+func (p *SQLiteProvider) getUserS3AccessKeys(username string) ([]S3AccessKey, error) {
+	return sqlCommonGetUserS3AccessKeys(username, p.dbHandle)
+}
+
+func (p *SQLiteProvider) deleteUserS3AccessKeys(username string) error {
+	return sqlCommonDeleteUserS3AccessKeys(username, p.dbHandle)
+}
+
+func (p *SQLiteProvider) updateS3AccessKey(key *S3AccessKey) error {
+	return sqlCommonUpdateS3AccessKey(key, p.dbHandle)
+}
+
+func (p *SQLiteProvider) dumpS3AccessKeys() ([]S3AccessKey, error) {
+	return sqlCommonDumpS3AccessKeys(p.dbHandle)
+}
+
+func (p *SQLiteProvider) tusUploadExists(uploadID string) (TusUpload, error) {
+	return sqlCommonGetTusUpload(uploadID, p.dbHandle)
+}
+
+func (p *SQLiteProvider) addTusUpload(upload *TusUpload) error {
+	return sqlCommonAddTusUpload(upload, p.dbHandle)
+}
+
+func (p *SQLiteProvider) updateTusUploadOffset(uploadID string, offset, expiresAt, updatedAt int64) error {
+	return sqlCommonUpdateTusUploadOffset(uploadID, offset, expiresAt, updatedAt, p.dbHandle)
+}
+
+func (p *SQLiteProvider) deleteTusUpload(uploadID string) error {
+	return sqlCommonDeleteTusUpload(uploadID, p.dbHandle)
+}
+
+func (p *SQLiteProvider) getTusUploadsCount(username string) (int, error) {
+	return sqlCommonGetTusUploadsCount(username, p.dbHandle)
+}
+
+func (p *SQLiteProvider) getExpiredTusUploads(before int64) ([]TusUpload, error) {
+	return sqlCommonGetExpiredTusUploads(before, p.dbHandle)
+}
+
+func (p *SQLiteProvider) deleteUserTusUploads(username string) error {
+	return sqlCommonDeleteUserTusUploads(username, p.dbHandle)
+}
+
+func (p *SQLiteProvider) resetCodeExists(code string) (ResetCode, error) {
+	return sqlCommonGetResetCode(code, p.dbHandle)
+}
+
+func (p *SQLiteProvider) addResetCode(code *ResetCode) error {
+	return sqlCommonAddResetCode(code, p.dbHandle)
+}
+
+func (p *SQLiteProvider) deleteResetCode(code string) error {
+	return sqlCommonDeleteResetCode(code, p.dbHandle)
+}
+
+func (p *SQLiteProvider) getResetCodesCount(username, accountType string, after int64) (int, error) {
+	return sqlCommonGetResetCodesCount(username, accountType, after, p.dbHandle)
+}
+
+func (p *SQLiteProvider) deleteExpiredResetCodes(before int64) error {
+	return sqlCommonDeleteExpiredResetCodes(before, p.dbHandle)
+}
+
+func (p *SQLiteProvider) multipartUploadExists(key string) (vfs.MultipartUpload, error) {
+	return sqlCommonGetMultipartUpload(key, p.dbHandle)
+}
+
+func (p *SQLiteProvider) saveMultipartUpload(upload *vfs.MultipartUpload) error {
+	return sqlCommonSaveMultipartUpload(upload, p.dbHandle)
+}
+
+func (p *SQLiteProvider) deleteMultipartUpload(key string) error {
+	return sqlCommonDeleteMultipartUpload(key, p.dbHandle)
+}
+
+func (p *SQLiteProvider) cleanupMultipartUploads(before int64) error {
+	return sqlCommonCleanupMultipartUploads(before, p.dbHandle)
+}
+
+func (p *SQLiteProvider) eventRuleExists(name string) (EventRule, error) {
+	return sqlCommonGetEventRuleByName(name, p.dbHandle)
+}
+
+func (p *SQLiteProvider) addEventRule(rule *EventRule) error {
+	return sqlCommonAddEventRule(rule, p.dbHandle)
+}
+
+func (p *SQLiteProvider) updateEventRule(rule *EventRule) error {
+	return sqlCommonUpdateEventRule(rule, p.dbHandle)
+}
+
+func (p *SQLiteProvider) deleteEventRule(rule *EventRule) error {
+	return sqlCommonDeleteEventRule(rule, p.dbHandle)
+}
+
+func (p *SQLiteProvider) getEventRules(limit, offset int, order string) ([]EventRule, error) {
+	return sqlCommonGetEventRules(limit, offset, order, p.dbHandle)
+}
+
+func (p *SQLiteProvider) dumpEventRules() ([]EventRule, error) {
+	return sqlCommonDumpEventRules(p.dbHandle)
+}
+
+func (p *SQLiteProvider) scheduleExists(name string) (Schedule, error) {
+	return sqlCommonGetScheduleByName(name, p.dbHandle)
+}
+
+func (p *SQLiteProvider) addSchedule(schedule *Schedule) error {
+	return sqlCommonAddSchedule(schedule, p.dbHandle)
+}
+
+func (p *SQLiteProvider) updateSchedule(schedule *Schedule) error {
+	return sqlCommonUpdateSchedule(schedule, p.dbHandle)
+}
+
+func (p *SQLiteProvider) deleteSchedule(schedule *Schedule) error {
+	return sqlCommonDeleteSchedule(schedule, p.dbHandle)
+}
+
+func (p *SQLiteProvider) getSchedules(limit, offset int, order string) ([]Schedule, error) {
+	return sqlCommonGetSchedules(limit, offset, order, p.dbHandle)
+}
+
+func (p *SQLiteProvider) dumpSchedules() ([]Schedule, error) {
+	return sqlCommonDumpSchedules(p.dbHandle)
+}
+
+func (p *SQLiteProvider) claimScheduleRun(name string, runAt int64) (bool, error) {
+	return sqlCommonClaimScheduleRun(name, runAt, p.dbHandle)
+}
+
+func (p *SQLiteProvider) setScheduleRunResult(name string, status int, runError string) error {
+	return sqlCommonSetScheduleRunResult(name, status, runError, p.dbHandle)
+}
+
 func (p *SQLiteProvider) close() error {
 	return p.dbHandle.Close()
 }
@@ -308,6 +776,38 @@ func (p *SQLiteProvider) migrateDatabase() error {
 		return updateSQLiteDatabaseFromV11(p.dbHandle)
 	case version == 12:
 		return updateSQLiteDatabaseFromV12(p.dbHandle)
+	case version == 13:
+		return updateSQLiteDatabaseFromV13(p.dbHandle)
+	case version == 14:
+		return updateSQLiteDatabaseFromV14(p.dbHandle)
+	case version == 15:
+		return updateSQLiteDatabaseFromV15(p.dbHandle)
+	case version == 16:
+		return updateSQLiteDatabaseFromV16(p.dbHandle)
+	case version == 17:
+		return updateSQLiteDatabaseFromV17(p.dbHandle)
+	case version == 18:
+		return updateSQLiteDatabaseFromV18(p.dbHandle)
+	case version == 19:
+		return updateSQLiteDatabaseFromV19(p.dbHandle)
+	case version == 20:
+		return updateSQLiteDatabaseFromV20(p.dbHandle)
+	case version == 21:
+		return updateSQLiteDatabaseFromV21(p.dbHandle)
+	case version == 22:
+		return updateSQLiteDatabaseFromV22(p.dbHandle)
+	case version == 23:
+		return updateSQLiteDatabaseFromV23(p.dbHandle)
+	case version == 24:
+		return updateSQLiteDatabaseFromV24(p.dbHandle)
+	case version == 25:
+		return updateSQLiteDatabaseFromV25(p.dbHandle)
+	case version == 26:
+		return updateSQLiteDatabaseFromV26(p.dbHandle)
+	case version == 27:
+		return updateSQLiteDatabaseFromV27(p.dbHandle)
+	case version == 28:
+		return updateSQLiteDatabaseFromV28(p.dbHandle)
 	default:
 		if version > sqlDatabaseVersion {
 			providerLog(logger.LevelWarn, "database version %v is newer than the supported one: %v", version,
@@ -330,6 +830,38 @@ func (p *SQLiteProvider) revertDatabase(targetVersion int) error {
 	}
 
 	switch dbVersion.Version {
+	case 29:
+		return downgradeSQLiteDatabaseFromV29(p.dbHandle)
+	case 28:
+		return downgradeSQLiteDatabaseFromV28(p.dbHandle)
+	case 27:
+		return downgradeSQLiteDatabaseFromV27(p.dbHandle)
+	case 26:
+		return downgradeSQLiteDatabaseFromV26(p.dbHandle)
+	case 25:
+		return downgradeSQLiteDatabaseFromV25(p.dbHandle)
+	case 24:
+		return downgradeSQLiteDatabaseFromV24(p.dbHandle)
+	case 23:
+		return downgradeSQLiteDatabaseFromV23(p.dbHandle)
+	case 22:
+		return downgradeSQLiteDatabaseFromV22(p.dbHandle)
+	case 21:
+		return downgradeSQLiteDatabaseFromV21(p.dbHandle)
+	case 20:
+		return downgradeSQLiteDatabaseFromV20(p.dbHandle)
+	case 19:
+		return downgradeSQLiteDatabaseFromV19(p.dbHandle)
+	case 18:
+		return downgradeSQLiteDatabaseFromV18(p.dbHandle)
+	case 17:
+		return downgradeSQLiteDatabaseFromV17(p.dbHandle)
+	case 16:
+		return downgradeSQLiteDatabaseFromV16(p.dbHandle)
+	case 15:
+		return downgradeSQLiteDatabaseFromV15(p.dbHandle)
+	case 14:
+		return downgradeSQLiteDatabaseFromV14(p.dbHandle)
 	case 13:
 		return downgradeSQLiteDatabaseFromV13(p.dbHandle)
 	case 12:
@@ -356,7 +888,231 @@ func updateSQLiteDatabaseFromV11(dbHandle *sql.DB) error {
 }
 
 func updateSQLiteDatabaseFromV12(dbHandle *sql.DB) error {
-	return updateSQLiteDatabaseFrom12To13(dbHandle)
+	if err := updateSQLiteDatabaseFrom12To13(dbHandle); err != nil {
+		return err
+	}
+	return updateSQLiteDatabaseFromV13(dbHandle)
+}
+
+func updateSQLiteDatabaseFromV13(dbHandle *sql.DB) error {
+	if err := updateSQLiteDatabaseFrom13To14(dbHandle); err != nil {
+		return err
+	}
+	return updateSQLiteDatabaseFromV14(dbHandle)
+}
+
+func updateSQLiteDatabaseFromV14(dbHandle *sql.DB) error {
+	if err := updateSQLiteDatabaseFrom14To15(dbHandle); err != nil {
+		return err
+	}
+	return updateSQLiteDatabaseFromV15(dbHandle)
+}
+
+func updateSQLiteDatabaseFromV15(dbHandle *sql.DB) error {
+	if err := updateSQLiteDatabaseFrom15To16(dbHandle); err != nil {
+		return err
+	}
+	return updateSQLiteDatabaseFromV16(dbHandle)
+}
+
+func updateSQLiteDatabaseFromV16(dbHandle *sql.DB) error {
+	if err := updateSQLiteDatabaseFrom16To17(dbHandle); err != nil {
+		return err
+	}
+	return updateSQLiteDatabaseFromV17(dbHandle)
+}
+
+func updateSQLiteDatabaseFromV17(dbHandle *sql.DB) error {
+	if err := updateSQLiteDatabaseFrom17To18(dbHandle); err != nil {
+		return err
+	}
+	return updateSQLiteDatabaseFromV18(dbHandle)
+}
+
+func updateSQLiteDatabaseFromV18(dbHandle *sql.DB) error {
+	if err := updateSQLiteDatabaseFrom18To19(dbHandle); err != nil {
+		return err
+	}
+	return updateSQLiteDatabaseFromV19(dbHandle)
+}
+
+func updateSQLiteDatabaseFromV19(dbHandle *sql.DB) error {
+	if err := updateSQLiteDatabaseFrom19To20(dbHandle); err != nil {
+		return err
+	}
+	return updateSQLiteDatabaseFromV20(dbHandle)
+}
+
+func updateSQLiteDatabaseFromV20(dbHandle *sql.DB) error {
+	if err := updateSQLiteDatabaseFrom20To21(dbHandle); err != nil {
+		return err
+	}
+	return updateSQLiteDatabaseFromV21(dbHandle)
+}
+
+func updateSQLiteDatabaseFromV21(dbHandle *sql.DB) error {
+	if err := updateSQLiteDatabaseFrom21To22(dbHandle); err != nil {
+		return err
+	}
+	return updateSQLiteDatabaseFromV22(dbHandle)
+}
+
+func updateSQLiteDatabaseFromV22(dbHandle *sql.DB) error {
+	if err := updateSQLiteDatabaseFrom22To23(dbHandle); err != nil {
+		return err
+	}
+	return updateSQLiteDatabaseFromV23(dbHandle)
+}
+
+func updateSQLiteDatabaseFromV23(dbHandle *sql.DB) error {
+	if err := updateSQLiteDatabaseFrom23To24(dbHandle); err != nil {
+		return err
+	}
+	return updateSQLiteDatabaseFromV24(dbHandle)
+}
+
+func updateSQLiteDatabaseFromV24(dbHandle *sql.DB) error {
+	if err := updateSQLiteDatabaseFrom24To25(dbHandle); err != nil {
+		return err
+	}
+	return updateSQLiteDatabaseFromV25(dbHandle)
+}
+
+func updateSQLiteDatabaseFromV25(dbHandle *sql.DB) error {
+	if err := updateSQLiteDatabaseFrom25To26(dbHandle); err != nil {
+		return err
+	}
+	return updateSQLiteDatabaseFromV26(dbHandle)
+}
+
+func updateSQLiteDatabaseFromV26(dbHandle *sql.DB) error {
+	if err := updateSQLiteDatabaseFrom26To27(dbHandle); err != nil {
+		return err
+	}
+	return updateSQLiteDatabaseFromV27(dbHandle)
+}
+
+func updateSQLiteDatabaseFromV27(dbHandle *sql.DB) error {
+	if err := updateSQLiteDatabaseFrom27To28(dbHandle); err != nil {
+		return err
+	}
+	return updateSQLiteDatabaseFromV28(dbHandle)
+}
+
+func updateSQLiteDatabaseFromV28(dbHandle *sql.DB) error {
+	return updateSQLiteDatabaseFrom28To29(dbHandle)
+}
+
+func downgradeSQLiteDatabaseFromV29(dbHandle *sql.DB) error {
+	if err := downgradeSQLiteDatabaseFrom29To28(dbHandle); err != nil {
+		return err
+	}
+	return downgradeSQLiteDatabaseFromV28(dbHandle)
+}
+
+func downgradeSQLiteDatabaseFromV28(dbHandle *sql.DB) error {
+	if err := downgradeSQLiteDatabaseFrom28To27(dbHandle); err != nil {
+		return err
+	}
+	return downgradeSQLiteDatabaseFromV27(dbHandle)
+}
+
+func downgradeSQLiteDatabaseFromV27(dbHandle *sql.DB) error {
+	if err := downgradeSQLiteDatabaseFrom27To26(dbHandle); err != nil {
+		return err
+	}
+	return downgradeSQLiteDatabaseFromV26(dbHandle)
+}
+
+func downgradeSQLiteDatabaseFromV26(dbHandle *sql.DB) error {
+	if err := downgradeSQLiteDatabaseFrom26To25(dbHandle); err != nil {
+		return err
+	}
+	return downgradeSQLiteDatabaseFromV25(dbHandle)
+}
+
+func downgradeSQLiteDatabaseFromV25(dbHandle *sql.DB) error {
+	if err := downgradeSQLiteDatabaseFrom25To24(dbHandle); err != nil {
+		return err
+	}
+	return downgradeSQLiteDatabaseFromV24(dbHandle)
+}
+
+func downgradeSQLiteDatabaseFromV24(dbHandle *sql.DB) error {
+	if err := downgradeSQLiteDatabaseFrom24To23(dbHandle); err != nil {
+		return err
+	}
+	return downgradeSQLiteDatabaseFromV23(dbHandle)
+}
+
+func downgradeSQLiteDatabaseFromV23(dbHandle *sql.DB) error {
+	if err := downgradeSQLiteDatabaseFrom23To22(dbHandle); err != nil {
+		return err
+	}
+	return downgradeSQLiteDatabaseFromV22(dbHandle)
+}
+
+func downgradeSQLiteDatabaseFromV22(dbHandle *sql.DB) error {
+	if err := downgradeSQLiteDatabaseFrom22To21(dbHandle); err != nil {
+		return err
+	}
+	return downgradeSQLiteDatabaseFromV21(dbHandle)
+}
+
+func downgradeSQLiteDatabaseFromV21(dbHandle *sql.DB) error {
+	if err := downgradeSQLiteDatabaseFrom21To20(dbHandle); err != nil {
+		return err
+	}
+	return downgradeSQLiteDatabaseFromV20(dbHandle)
+}
+
+func downgradeSQLiteDatabaseFromV20(dbHandle *sql.DB) error {
+	if err := downgradeSQLiteDatabaseFrom20To19(dbHandle); err != nil {
+		return err
+	}
+	return downgradeSQLiteDatabaseFromV19(dbHandle)
+}
+
+func downgradeSQLiteDatabaseFromV19(dbHandle *sql.DB) error {
+	if err := downgradeSQLiteDatabaseFrom19To18(dbHandle); err != nil {
+		return err
+	}
+	return downgradeSQLiteDatabaseFromV18(dbHandle)
+}
+
+func downgradeSQLiteDatabaseFromV18(dbHandle *sql.DB) error {
+	if err := downgradeSQLiteDatabaseFrom18To17(dbHandle); err != nil {
+		return err
+	}
+	return downgradeSQLiteDatabaseFromV17(dbHandle)
+}
+
+func downgradeSQLiteDatabaseFromV17(dbHandle *sql.DB) error {
+	if err := downgradeSQLiteDatabaseFrom17To16(dbHandle); err != nil {
+		return err
+	}
+	return downgradeSQLiteDatabaseFromV16(dbHandle)
+}
+
+func downgradeSQLiteDatabaseFromV16(dbHandle *sql.DB) error {
+	if err := downgradeSQLiteDatabaseFrom16To15(dbHandle); err != nil {
+		return err
+	}
+	return downgradeSQLiteDatabaseFromV15(dbHandle)
+}
+
+func downgradeSQLiteDatabaseFromV15(dbHandle *sql.DB) error {
+	if err := downgradeSQLiteDatabaseFrom15To14(dbHandle); err != nil {
+		return err
+	}
+	return downgradeSQLiteDatabaseFromV14(dbHandle)
+}
+
+func downgradeSQLiteDatabaseFromV14(dbHandle *sql.DB) error {
+	if err := downgradeSQLiteDatabaseFrom14To13(dbHandle); err != nil {
+		return err
+	}
+	return downgradeSQLiteDatabaseFromV13(dbHandle)
 }
 
 func downgradeSQLiteDatabaseFromV13(dbHandle *sql.DB) error {
@@ -377,6 +1133,255 @@ func downgradeSQLiteDatabaseFromV11(dbHandle *sql.DB) error {
 	return downgradeSQLiteDatabaseFrom11To10(dbHandle)
 }
 
+func updateSQLiteDatabaseFrom28To29(dbHandle *sql.DB) error {
+	logger.InfoToConsole("updating database version: 28 -> 29")
+	providerLog(logger.LevelInfo, "updating database version: 28 -> 29")
+	sql := strings.ReplaceAll(sqliteV29SQL, "{{multipart_uploads}}", sqlTableMultipartUploads)
+	sql = strings.ReplaceAll(sql, "{{prefix}}", config.SQLTablesPrefix)
+	return sqlCommonExecSQLAndUpdateDBVersion(dbHandle, []string{sql}, 29)
+}
+
+func downgradeSQLiteDatabaseFrom29To28(dbHandle *sql.DB) error {
+	logger.InfoToConsole("downgrading database version: 29 -> 28")
+	providerLog(logger.LevelInfo, "downgrading database version: 29 -> 28")
+	sql := strings.ReplaceAll(sqliteV29DownSQL, "{{multipart_uploads}}", sqlTableMultipartUploads)
+	return sqlCommonExecSQLAndUpdateDBVersion(dbHandle, []string{sql}, 28)
+}
+
+func updateSQLiteDatabaseFrom27To28(dbHandle *sql.DB) error {
+	logger.InfoToConsole("updating database version: 27 -> 28")
+	providerLog(logger.LevelInfo, "updating database version: 27 -> 28")
+	sql := strings.ReplaceAll(sqliteV28SQL, "{{reset_codes}}", sqlTableResetCodes)
+	sql = strings.ReplaceAll(sql, "{{prefix}}", config.SQLTablesPrefix)
+	return sqlCommonExecSQLAndUpdateDBVersion(dbHandle, []string{sql}, 28)
+}
+
+func downgradeSQLiteDatabaseFrom28To27(dbHandle *sql.DB) error {
+	logger.InfoToConsole("downgrading database version: 28 -> 27")
+	providerLog(logger.LevelInfo, "downgrading database version: 28 -> 27")
+	sql := strings.ReplaceAll(sqliteV28DownSQL, "{{reset_codes}}", sqlTableResetCodes)
+	return sqlCommonExecSQLAndUpdateDBVersion(dbHandle, []string{sql}, 27)
+}
+
+func updateSQLiteDatabaseFrom26To27(dbHandle *sql.DB) error {
+	logger.InfoToConsole("updating database version: 26 -> 27")
+	providerLog(logger.LevelInfo, "updating database version: 26 -> 27")
+	sql := strings.ReplaceAll(sqliteV27SQL, "{{tus_uploads}}", sqlTableTusUploads)
+	sql = strings.ReplaceAll(sql, "{{prefix}}", config.SQLTablesPrefix)
+	return sqlCommonExecSQLAndUpdateDBVersion(dbHandle, []string{sql}, 27)
+}
+
+func downgradeSQLiteDatabaseFrom27To26(dbHandle *sql.DB) error {
+	logger.InfoToConsole("downgrading database version: 27 -> 26")
+	providerLog(logger.LevelInfo, "downgrading database version: 27 -> 26")
+	sql := strings.ReplaceAll(sqliteV27DownSQL, "{{tus_uploads}}", sqlTableTusUploads)
+	return sqlCommonExecSQLAndUpdateDBVersion(dbHandle, []string{sql}, 26)
+}
+
+func updateSQLiteDatabaseFrom25To26(dbHandle *sql.DB) error {
+	logger.InfoToConsole("updating database version: 25 -> 26")
+	providerLog(logger.LevelInfo, "updating database version: 25 -> 26")
+	sql := strings.ReplaceAll(sqliteV26SQL, "{{s3_access_keys}}", sqlTableS3AccessKeys)
+	sql = strings.ReplaceAll(sql, "{{prefix}}", config.SQLTablesPrefix)
+	return sqlCommonExecSQLAndUpdateDBVersion(dbHandle, []string{sql}, 26)
+}
+
+func downgradeSQLiteDatabaseFrom26To25(dbHandle *sql.DB) error {
+	logger.InfoToConsole("downgrading database version: 26 -> 25")
+	providerLog(logger.LevelInfo, "downgrading database version: 26 -> 25")
+	sql := strings.ReplaceAll(sqliteV26DownSQL, "{{s3_access_keys}}", sqlTableS3AccessKeys)
+	return sqlCommonExecSQLAndUpdateDBVersion(dbHandle, []string{sql}, 25)
+}
+
+func updateSQLiteDatabaseFrom24To25(dbHandle *sql.DB) error {
+	logger.InfoToConsole("updating database version: 24 -> 25")
+	providerLog(logger.LevelInfo, "updating database version: 24 -> 25")
+	sql := strings.ReplaceAll(sqliteV25SQL, "{{webdav_properties}}", sqlTableWebDAVProperties)
+	sql = strings.ReplaceAll(sql, "{{prefix}}", config.SQLTablesPrefix)
+	return sqlCommonExecSQLAndUpdateDBVersion(dbHandle, []string{sql}, 25)
+}
+
+func downgradeSQLiteDatabaseFrom25To24(dbHandle *sql.DB) error {
+	logger.InfoToConsole("downgrading database version: 25 -> 24")
+	providerLog(logger.LevelInfo, "downgrading database version: 25 -> 24")
+	sql := strings.ReplaceAll(sqliteV25DownSQL, "{{webdav_properties}}", sqlTableWebDAVProperties)
+	return sqlCommonExecSQLAndUpdateDBVersion(dbHandle, []string{sql}, 24)
+}
+
+func updateSQLiteDatabaseFrom23To24(dbHandle *sql.DB) error {
+	logger.InfoToConsole("updating database version: 23 -> 24")
+	providerLog(logger.LevelInfo, "updating database version: 23 -> 24")
+	sql := strings.ReplaceAll(sqliteV24SQL, "{{webdav_locks}}", sqlTableWebDAVLocks)
+	sql = strings.ReplaceAll(sql, "{{prefix}}", config.SQLTablesPrefix)
+	return sqlCommonExecSQLAndUpdateDBVersion(dbHandle, []string{sql}, 24)
+}
+
+func downgradeSQLiteDatabaseFrom24To23(dbHandle *sql.DB) error {
+	logger.InfoToConsole("downgrading database version: 24 -> 23")
+	providerLog(logger.LevelInfo, "downgrading database version: 24 -> 23")
+	sql := strings.ReplaceAll(sqliteV24DownSQL, "{{webdav_locks}}", sqlTableWebDAVLocks)
+	return sqlCommonExecSQLAndUpdateDBVersion(dbHandle, []string{sql}, 23)
+}
+
+func updateSQLiteDatabaseFrom22To23(dbHandle *sql.DB) error {
+	logger.InfoToConsole("updating database version: 22 -> 23")
+	providerLog(logger.LevelInfo, "updating database version: 22 -> 23")
+	sql := strings.ReplaceAll(sqliteV23SQL, "{{folders}}", sqlTableFolders)
+	return sqlCommonExecSQLAndUpdateDBVersion(dbHandle, []string{sql}, 23)
+}
+
+func downgradeSQLiteDatabaseFrom23To22(dbHandle *sql.DB) error {
+	logger.InfoToConsole("downgrading database version: 23 -> 22")
+	providerLog(logger.LevelInfo, "downgrading database version: 23 -> 22")
+	sql := strings.ReplaceAll(sqliteV23DownSQL, "{{folders}}", sqlTableFolders)
+	return sqlCommonExecSQLAndUpdateDBVersion(dbHandle, []string{sql}, 22)
+}
+
+func updateSQLiteDatabaseFrom21To22(dbHandle *sql.DB) error {
+	logger.InfoToConsole("updating database version: 21 -> 22")
+	providerLog(logger.LevelInfo, "updating database version: 21 -> 22")
+	sql := strings.ReplaceAll(sqliteV22SQL, "{{schedules}}", sqlTableSchedules)
+	return sqlCommonExecSQLAndUpdateDBVersion(dbHandle, []string{sql}, 22)
+}
+
+func downgradeSQLiteDatabaseFrom22To21(dbHandle *sql.DB) error {
+	logger.InfoToConsole("downgrading database version: 22 -> 21")
+	providerLog(logger.LevelInfo, "downgrading database version: 22 -> 21")
+	sql := strings.ReplaceAll(sqliteV22DownSQL, "{{schedules}}", sqlTableSchedules)
+	return sqlCommonExecSQLAndUpdateDBVersion(dbHandle, []string{sql}, 21)
+}
+
+func updateSQLiteDatabaseFrom20To21(dbHandle *sql.DB) error {
+	logger.InfoToConsole("updating database version: 20 -> 21")
+	providerLog(logger.LevelInfo, "updating database version: 20 -> 21")
+	sql := strings.ReplaceAll(sqliteV21SQL, "{{events_rules}}", sqlTableEventsRules)
+	return sqlCommonExecSQLAndUpdateDBVersion(dbHandle, []string{sql}, 21)
+}
+
+func downgradeSQLiteDatabaseFrom21To20(dbHandle *sql.DB) error {
+	logger.InfoToConsole("downgrading database version: 21 -> 20")
+	providerLog(logger.LevelInfo, "downgrading database version: 21 -> 20")
+	sql := strings.ReplaceAll(sqliteV21DownSQL, "{{events_rules}}", sqlTableEventsRules)
+	return sqlCommonExecSQLAndUpdateDBVersion(dbHandle, []string{sql}, 20)
+}
+
+func updateSQLiteDatabaseFrom19To20(dbHandle *sql.DB) error {
+	logger.InfoToConsole("updating database version: 19 -> 20")
+	providerLog(logger.LevelInfo, "updating database version: 19 -> 20")
+	sql := strings.ReplaceAll(sqliteV20SQL, "{{fs_events}}", sqlTableFsEvents)
+	sql = strings.ReplaceAll(sql, "{{provider_events}}", sqlTableProviderEvents)
+	sql = strings.ReplaceAll(sql, "{{prefix}}", config.SQLTablesPrefix)
+	return sqlCommonExecSQLAndUpdateDBVersion(dbHandle, []string{sql}, 20)
+}
+
+func downgradeSQLiteDatabaseFrom20To19(dbHandle *sql.DB) error {
+	logger.InfoToConsole("downgrading database version: 20 -> 19")
+	providerLog(logger.LevelInfo, "downgrading database version: 20 -> 19")
+	sql := strings.ReplaceAll(sqliteV20DownSQL, "{{fs_events}}", sqlTableFsEvents)
+	sql = strings.ReplaceAll(sql, "{{provider_events}}", sqlTableProviderEvents)
+	return sqlCommonExecSQLAndUpdateDBVersion(dbHandle, []string{sql}, 19)
+}
+
+func updateSQLiteDatabaseFrom18To19(dbHandle *sql.DB) error {
+	logger.InfoToConsole("updating database version: 18 -> 19")
+	providerLog(logger.LevelInfo, "updating database version: 18 -> 19")
+	sql := strings.ReplaceAll(sqliteV19SQL, "{{account_lockouts}}", sqlTableAccountLockouts)
+	sql = strings.ReplaceAll(sql, "{{prefix}}", config.SQLTablesPrefix)
+	return sqlCommonExecSQLAndUpdateDBVersion(dbHandle, []string{sql}, 19)
+}
+
+func downgradeSQLiteDatabaseFrom19To18(dbHandle *sql.DB) error {
+	logger.InfoToConsole("downgrading database version: 19 -> 18")
+	providerLog(logger.LevelInfo, "downgrading database version: 19 -> 18")
+	sql := strings.ReplaceAll(sqliteV19DownSQL, "{{account_lockouts}}", sqlTableAccountLockouts)
+	return sqlCommonExecSQLAndUpdateDBVersion(dbHandle, []string{sql}, 18)
+}
+
+func updateSQLiteDatabaseFrom17To18(dbHandle *sql.DB) error {
+	logger.InfoToConsole("updating database version: 17 -> 18")
+	providerLog(logger.LevelInfo, "updating database version: 17 -> 18")
+	sql := strings.ReplaceAll(sqliteV18SQL, "{{users}}", sqlTableUsers)
+	sql = strings.ReplaceAll(sql, "{{admins}}", sqlTableAdmins)
+	return sqlCommonExecSQLAndUpdateDBVersion(dbHandle, []string{sql}, 18)
+}
+
+func downgradeSQLiteDatabaseFrom18To17(dbHandle *sql.DB) error {
+	logger.InfoToConsole("downgrading database version: 18 -> 17")
+	providerLog(logger.LevelInfo, "downgrading database version: 18 -> 17")
+	sql := strings.ReplaceAll(sqliteV18DownSQL, "{{users}}", sqlTableUsers)
+	sql = strings.ReplaceAll(sql, "{{admins}}", sqlTableAdmins)
+	return sqlCommonExecSQLAndUpdateDBVersion(dbHandle, []string{sql}, 17)
+}
+
+func updateSQLiteDatabaseFrom16To17(dbHandle *sql.DB) error {
+	logger.InfoToConsole("updating database version: 16 -> 17")
+	providerLog(logger.LevelInfo, "updating database version: 16 -> 17")
+	sql := strings.ReplaceAll(sqliteV17SQL, "{{users}}", sqlTableUsers)
+	sql = strings.ReplaceAll(sql, "{{folders}}", sqlTableFolders)
+	return sqlCommonExecSQLAndUpdateDBVersion(dbHandle, []string{sql}, 17)
+}
+
+func downgradeSQLiteDatabaseFrom17To16(dbHandle *sql.DB) error {
+	logger.InfoToConsole("downgrading database version: 17 -> 16")
+	providerLog(logger.LevelInfo, "downgrading database version: 17 -> 16")
+	sql := strings.ReplaceAll(sqliteV17DownSQL, "{{users}}", sqlTableUsers)
+	sql = strings.ReplaceAll(sql, "{{folders}}", sqlTableFolders)
+	return sqlCommonExecSQLAndUpdateDBVersion(dbHandle, []string{sql}, 16)
+}
+
+func updateSQLiteDatabaseFrom15To16(dbHandle *sql.DB) error {
+	logger.InfoToConsole("updating database version: 15 -> 16")
+	providerLog(logger.LevelInfo, "updating database version: 15 -> 16")
+	sql := strings.ReplaceAll(sqliteV16SQL, "{{groups_folders_mapping}}", sqlTableGroupsFoldersMapping)
+	sql = strings.ReplaceAll(sql, "{{groups_mapping}}", sqlTableGroupsMapping)
+	sql = strings.ReplaceAll(sql, "{{groups}}", sqlTableGroups)
+	sql = strings.ReplaceAll(sql, "{{folders}}", sqlTableFolders)
+	sql = strings.ReplaceAll(sql, "{{users}}", sqlTableUsers)
+	sql = strings.ReplaceAll(sql, "{{prefix}}", config.SQLTablesPrefix)
+	return sqlCommonExecSQLAndUpdateDBVersion(dbHandle, []string{sql}, 16)
+}
+
+func downgradeSQLiteDatabaseFrom16To15(dbHandle *sql.DB) error {
+	logger.InfoToConsole("downgrading database version: 16 -> 15")
+	providerLog(logger.LevelInfo, "downgrading database version: 16 -> 15")
+	sql := strings.ReplaceAll(sqliteV16DownSQL, "{{groups_folders_mapping}}", sqlTableGroupsFoldersMapping)
+	sql = strings.ReplaceAll(sql, "{{groups_mapping}}", sqlTableGroupsMapping)
+	sql = strings.ReplaceAll(sql, "{{groups}}", sqlTableGroups)
+	return sqlCommonExecSQLAndUpdateDBVersion(dbHandle, []string{sql}, 15)
+}
+
+func updateSQLiteDatabaseFrom14To15(dbHandle *sql.DB) error {
+	logger.InfoToConsole("updating database version: 14 -> 15")
+	providerLog(logger.LevelInfo, "updating database version: 14 -> 15")
+	sql := strings.ReplaceAll(sqliteV15SQL, "{{shares}}", sqlTableShares)
+	sql = strings.ReplaceAll(sql, "{{share_uploads}}", sqlTableShareUploads)
+	sql = strings.ReplaceAll(sql, "{{prefix}}", config.SQLTablesPrefix)
+	return sqlCommonExecSQLAndUpdateDBVersion(dbHandle, []string{sql}, 15)
+}
+
+func downgradeSQLiteDatabaseFrom15To14(dbHandle *sql.DB) error {
+	logger.InfoToConsole("downgrading database version: 15 -> 14")
+	providerLog(logger.LevelInfo, "downgrading database version: 15 -> 14")
+	sql := strings.ReplaceAll(sqliteV15DownSQL, "{{share_uploads}}", sqlTableShareUploads)
+	return sqlCommonExecSQLAndUpdateDBVersion(dbHandle, []string{sql}, 14)
+}
+
+func updateSQLiteDatabaseFrom13To14(dbHandle *sql.DB) error {
+	logger.InfoToConsole("updating database version: 13 -> 14")
+	providerLog(logger.LevelInfo, "updating database version: 13 -> 14")
+	sql := strings.ReplaceAll(sqliteV14SQL, "{{users}}", sqlTableUsers)
+	sql = strings.ReplaceAll(sql, "{{shares}}", sqlTableShares)
+	sql = strings.ReplaceAll(sql, "{{prefix}}", config.SQLTablesPrefix)
+	return sqlCommonExecSQLAndUpdateDBVersion(dbHandle, []string{sql}, 14)
+}
+
+func downgradeSQLiteDatabaseFrom14To13(dbHandle *sql.DB) error {
+	logger.InfoToConsole("downgrading database version: 14 -> 13")
+	providerLog(logger.LevelInfo, "downgrading database version: 14 -> 13")
+	sql := strings.ReplaceAll(sqliteV14DownSQL, "{{shares}}", sqlTableShares)
+	return sqlCommonExecSQLAndUpdateDBVersion(dbHandle, []string{sql}, 13)
+}
+
 func updateSQLiteDatabaseFrom12To13(dbHandle *sql.DB) error {
 	logger.InfoToConsole("updating database version: 12 -> 13")
 	providerLog(logger.LevelInfo, "updating database version: 12 -> 13")
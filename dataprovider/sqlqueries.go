@@ -11,10 +11,32 @@ import (
 const (
 	selectUserFields = "id,username,password,public_keys,home_dir,uid,gid,max_sessions,quota_size,quota_files,permissions,used_quota_size," +
 		"used_quota_files,last_quota_update,upload_bandwidth,download_bandwidth,expiration_date,last_login,status,filters,filesystem," +
-		"additional_info,description,email,created_at,updated_at"
-	selectFolderFields = "id,path,used_quota_size,used_quota_files,last_quota_update,name,description,filesystem"
-	selectAdminFields  = "id,username,password,status,email,permissions,filters,additional_info,description,created_at,updated_at,last_login"
+		"additional_info,description,email,created_at,updated_at,upload_data_transfer,download_data_transfer,total_data_transfer," +
+		"used_upload_data_transfer,used_download_data_transfer,last_data_transfer_reset,data_transfer_reset_period,last_password_change"
+	selectFolderFields = "id,path,used_quota_size,used_quota_files,last_quota_update,name,description,filesystem," +
+		"upload_data_transfer,download_data_transfer,total_data_transfer,used_upload_data_transfer," +
+		"used_download_data_transfer,last_data_transfer_reset,data_transfer_reset_period,data_retention"
+	selectAdminFields = "id,username,password,status,email,permissions,filters,additional_info,description,created_at,updated_at," +
+		"last_login,last_password_change"
 	selectAPIKeyFields = "key_id,name,api_key,scope,created_at,updated_at,last_use_at,expires_at,description,user_id,admin_id"
+	selectShareFields  = "s.share_id,s.name,s.description,s.scope,s.paths,u.username,s.created_at,s.updated_at,s.last_use_at," +
+		"s.expires_at,s.password,s.max_tokens,s.used_tokens,s.allow_from"
+	selectShareUploadFields = "s.share_id,su.path,su.size,su.ip,su.uploader,su.uploaded_at"
+	selectLockoutFields     = "username,account_type,failures,first_failure_at,last_failure_at,locked_at,locked_until"
+	selectGroupFields       = "id,name,description,created_at,updated_at,user_settings"
+	selectFsEventFields     = "id,timestamp,action,username,fs_path,fs_target_path,virtual_path,virtual_target_path," +
+		"ssh_cmd,file_size,status,protocol,ip,instance_id"
+	selectProviderEventFields = "id,timestamp,action,username,ip,object_type,object_name,instance_id"
+	selectEventRuleFields     = "id,name,description,created_at,updated_at,event_trigger,conditions,actions"
+	selectScheduleFields      = "id,name,description,created_at,updated_at,status,cron_expression,task,options,last_run," +
+		"last_run_status,last_run_error"
+	selectWebDAVLockFields       = "token,username,root,duration,expires_at,owner_xml,zero_depth,created_at,updated_at"
+	selectWebDAVPropertiesFields = "username,path,properties,updated_at"
+	selectS3AccessKeyFields      = "access_key_id,username,secret_access_key,description,created_at"
+	selectTusUploadFields        = "upload_id,username,virtual_path,fs_path,upload_length,upload_offset,expires_at," +
+		"created_at,updated_at"
+	selectResetCodeFields       = "code,username,account_type,password_fingerprint,expires_at,created_at"
+	selectMultipartUploadFields = "upload_key,upload_size,state,created_at,updated_at"
 )
 
 func getSQLPlaceholders() []string {
@@ -29,6 +51,14 @@ func getSQLPlaceholders() []string {
 	return placeholders
 }
 
+// escapeLikePattern escapes the wildcards in the given string so it can be used
+// within a LIKE pattern with the "!" escape character
+func escapeLikePattern(s string) string {
+	s = strings.ReplaceAll(s, "!", "!!")
+	s = strings.ReplaceAll(s, "%", "!%")
+	return strings.ReplaceAll(s, "_", "!_")
+}
+
 func getAdminByUsernameQuery() string {
 	return fmt.Sprintf(`SELECT %v FROM %v WHERE username = %v`, selectAdminFields, sqlTableAdmins, sqlPlaceholders[0])
 }
@@ -43,10 +73,10 @@ func getDumpAdminsQuery() string {
 }
 
 func getAddAdminQuery() string {
-	return fmt.Sprintf(`INSERT INTO %v (username,password,status,email,permissions,filters,additional_info,description,created_at,updated_at,last_login)
-		VALUES (%v,%v,%v,%v,%v,%v,%v,%v,%v,%v,0)`, sqlTableAdmins, sqlPlaceholders[0], sqlPlaceholders[1],
+	return fmt.Sprintf(`INSERT INTO %v (username,password,status,email,permissions,filters,additional_info,description,created_at,updated_at,last_login,
+		last_password_change) VALUES (%v,%v,%v,%v,%v,%v,%v,%v,%v,%v,0,%v)`, sqlTableAdmins, sqlPlaceholders[0], sqlPlaceholders[1],
 		sqlPlaceholders[2], sqlPlaceholders[3], sqlPlaceholders[4], sqlPlaceholders[5], sqlPlaceholders[6], sqlPlaceholders[7],
-		sqlPlaceholders[8], sqlPlaceholders[9])
+		sqlPlaceholders[8], sqlPlaceholders[9], sqlPlaceholders[10])
 }
 
 func getUpdateAdminQuery() string {
@@ -55,10 +85,382 @@ func getUpdateAdminQuery() string {
 		sqlPlaceholders[3], sqlPlaceholders[4], sqlPlaceholders[5], sqlPlaceholders[6], sqlPlaceholders[7], sqlPlaceholders[8])
 }
 
+func getUpdateAdminLastPasswordChangeQuery() string {
+	return fmt.Sprintf(`UPDATE %v SET last_password_change=%v WHERE username = %v AND password <> %v`, sqlTableAdmins,
+		sqlPlaceholders[0], sqlPlaceholders[1], sqlPlaceholders[2])
+}
+
 func getDeleteAdminQuery() string {
 	return fmt.Sprintf(`DELETE FROM %v WHERE username = %v`, sqlTableAdmins, sqlPlaceholders[0])
 }
 
+func getShareByIDQuery(filterUser bool) string {
+	if filterUser {
+		return fmt.Sprintf(`SELECT %v FROM %v s INNER JOIN %v u ON s.user_id = u.id WHERE s.share_id = %v AND u.username = %v`,
+			selectShareFields, sqlTableShares, sqlTableUsers, sqlPlaceholders[0], sqlPlaceholders[1])
+	}
+	return fmt.Sprintf(`SELECT %v FROM %v s INNER JOIN %v u ON s.user_id = u.id WHERE s.share_id = %v`,
+		selectShareFields, sqlTableShares, sqlTableUsers, sqlPlaceholders[0])
+}
+
+func getSharesQuery(order string) string {
+	return fmt.Sprintf(`SELECT %v FROM %v s INNER JOIN %v u ON s.user_id = u.id WHERE u.username = %v ORDER BY s.share_id %v LIMIT %v OFFSET %v`,
+		selectShareFields, sqlTableShares, sqlTableUsers, sqlPlaceholders[0], order, sqlPlaceholders[1], sqlPlaceholders[2])
+}
+
+func getDumpSharesQuery() string {
+	return fmt.Sprintf(`SELECT %v FROM %v s INNER JOIN %v u ON s.user_id = u.id`,
+		selectShareFields, sqlTableShares, sqlTableUsers)
+}
+
+func getAddShareQuery() string {
+	return fmt.Sprintf(`INSERT INTO %v (share_id,name,description,scope,paths,created_at,updated_at,last_use_at,
+		expires_at,password,max_tokens,used_tokens,allow_from,user_id) VALUES (%v,%v,%v,%v,%v,%v,%v,%v,%v,%v,%v,%v,%v,%v)`,
+		sqlTableShares, sqlPlaceholders[0], sqlPlaceholders[1], sqlPlaceholders[2], sqlPlaceholders[3],
+		sqlPlaceholders[4], sqlPlaceholders[5], sqlPlaceholders[6], sqlPlaceholders[7], sqlPlaceholders[8],
+		sqlPlaceholders[9], sqlPlaceholders[10], sqlPlaceholders[11], sqlPlaceholders[12], sqlPlaceholders[13])
+}
+
+func getUpdateShareRestoreQuery() string {
+	return fmt.Sprintf(`UPDATE %v SET name=%v,description=%v,scope=%v,paths=%v,created_at=%v,updated_at=%v,
+		last_use_at=%v,expires_at=%v,password=%v,max_tokens=%v,used_tokens=%v,allow_from=%v,user_id=%v WHERE share_id = %v`,
+		sqlTableShares, sqlPlaceholders[0], sqlPlaceholders[1], sqlPlaceholders[2], sqlPlaceholders[3],
+		sqlPlaceholders[4], sqlPlaceholders[5], sqlPlaceholders[6], sqlPlaceholders[7], sqlPlaceholders[8],
+		sqlPlaceholders[9], sqlPlaceholders[10], sqlPlaceholders[11], sqlPlaceholders[12], sqlPlaceholders[13])
+}
+
+func getUpdateShareQuery() string {
+	return fmt.Sprintf(`UPDATE %v SET name=%v,description=%v,scope=%v,paths=%v,updated_at=%v,expires_at=%v,
+		password=%v,max_tokens=%v,allow_from=%v,user_id=%v WHERE share_id = %v`, sqlTableShares,
+		sqlPlaceholders[0], sqlPlaceholders[1], sqlPlaceholders[2], sqlPlaceholders[3], sqlPlaceholders[4],
+		sqlPlaceholders[5], sqlPlaceholders[6], sqlPlaceholders[7], sqlPlaceholders[8], sqlPlaceholders[9],
+		sqlPlaceholders[10])
+}
+
+func getDeleteShareQuery() string {
+	return fmt.Sprintf(`DELETE FROM %v WHERE share_id = %v`, sqlTableShares, sqlPlaceholders[0])
+}
+
+func getUpdateShareLastUseQuery() string {
+	return fmt.Sprintf(`UPDATE %v SET last_use_at = %v, used_tokens = used_tokens +%v WHERE share_id = %v`,
+		sqlTableShares, sqlPlaceholders[0], sqlPlaceholders[1], sqlPlaceholders[2])
+}
+
+func getAddShareUploadQuery() string {
+	return fmt.Sprintf(`INSERT INTO %v (path,size,ip,uploader,uploaded_at,share_id) VALUES (%v,%v,%v,%v,%v,
+		(SELECT id FROM %v WHERE share_id = %v))`, sqlTableShareUploads, sqlPlaceholders[0], sqlPlaceholders[1],
+		sqlPlaceholders[2], sqlPlaceholders[3], sqlPlaceholders[4], sqlTableShares, sqlPlaceholders[5])
+}
+
+func getShareUploadsQuery(order string) string {
+	return fmt.Sprintf(`SELECT %v FROM %v su INNER JOIN %v s ON su.share_id = s.id WHERE s.share_id = %v
+		ORDER BY su.id %v LIMIT %v OFFSET %v`, selectShareUploadFields, sqlTableShareUploads, sqlTableShares,
+		sqlPlaceholders[0], order, sqlPlaceholders[1], sqlPlaceholders[2])
+}
+
+func getAccountLockoutQuery() string {
+	return fmt.Sprintf(`SELECT %v FROM %v WHERE username = %v AND account_type = %v`, selectLockoutFields,
+		sqlTableAccountLockouts, sqlPlaceholders[0], sqlPlaceholders[1])
+}
+
+func getAccountLockoutsQuery(order string) string {
+	return fmt.Sprintf(`SELECT %v FROM %v ORDER BY account_type %v,username %v LIMIT %v OFFSET %v`, selectLockoutFields,
+		sqlTableAccountLockouts, order, order, sqlPlaceholders[0], sqlPlaceholders[1])
+}
+
+func getAddAccountLockoutQuery() string {
+	return fmt.Sprintf(`INSERT INTO %v (failures,first_failure_at,last_failure_at,locked_at,locked_until,username,account_type)
+		VALUES (%v,%v,%v,%v,%v,%v,%v)`, sqlTableAccountLockouts, sqlPlaceholders[0], sqlPlaceholders[1], sqlPlaceholders[2],
+		sqlPlaceholders[3], sqlPlaceholders[4], sqlPlaceholders[5], sqlPlaceholders[6])
+}
+
+// the update succeeds only if the lockout was not modified after it was read
+func getUpdateAccountLockoutQuery() string {
+	return fmt.Sprintf(`UPDATE %v SET failures = %v,first_failure_at = %v,last_failure_at = %v,locked_at = %v,locked_until = %v
+		WHERE username = %v AND account_type = %v AND failures = %v AND first_failure_at = %v AND last_failure_at = %v
+		AND locked_at = %v`, sqlTableAccountLockouts, sqlPlaceholders[0], sqlPlaceholders[1],
+		sqlPlaceholders[2], sqlPlaceholders[3], sqlPlaceholders[4], sqlPlaceholders[5], sqlPlaceholders[6],
+		sqlPlaceholders[7], sqlPlaceholders[8], sqlPlaceholders[9], sqlPlaceholders[10])
+}
+
+func getDeleteAccountLockoutQuery() string {
+	return fmt.Sprintf(`DELETE FROM %v WHERE username = %v AND account_type = %v`, sqlTableAccountLockouts,
+		sqlPlaceholders[0], sqlPlaceholders[1])
+}
+
+func getWebDAVLockQuery() string {
+	return fmt.Sprintf(`SELECT %v FROM %v WHERE token = %v`, selectWebDAVLockFields, sqlTableWebDAVLocks,
+		sqlPlaceholders[0])
+}
+
+func getWebDAVLocksQuery() string {
+	return fmt.Sprintf(`SELECT %v FROM %v WHERE username = %v ORDER BY id ASC`, selectWebDAVLockFields,
+		sqlTableWebDAVLocks, sqlPlaceholders[0])
+}
+
+func getAddWebDAVLockQuery() string {
+	return fmt.Sprintf(`INSERT INTO %v (token,username,root,root_key,duration,expires_at,owner_xml,zero_depth,created_at,
+		updated_at) VALUES (%v,%v,%v,%v,%v,%v,%v,%v,%v,%v)`, sqlTableWebDAVLocks, sqlPlaceholders[0], sqlPlaceholders[1],
+		sqlPlaceholders[2], sqlPlaceholders[3], sqlPlaceholders[4], sqlPlaceholders[5], sqlPlaceholders[6],
+		sqlPlaceholders[7], sqlPlaceholders[8], sqlPlaceholders[9])
+}
+
+func getUpdateWebDAVLockQuery() string {
+	return fmt.Sprintf(`UPDATE %v SET duration = %v,expires_at = %v,updated_at = %v WHERE token = %v`,
+		sqlTableWebDAVLocks, sqlPlaceholders[0], sqlPlaceholders[1], sqlPlaceholders[2], sqlPlaceholders[3])
+}
+
+func getDeleteWebDAVLockQuery() string {
+	return fmt.Sprintf(`DELETE FROM %v WHERE token = %v`, sqlTableWebDAVLocks, sqlPlaceholders[0])
+}
+
+func getDeleteExpiredWebDAVLockQuery() string {
+	return fmt.Sprintf(`DELETE FROM %v WHERE username = %v AND root_key = %v AND expires_at > 0 AND expires_at <= %v`,
+		sqlTableWebDAVLocks, sqlPlaceholders[0], sqlPlaceholders[1], sqlPlaceholders[2])
+}
+
+func getDeleteUserWebDAVLocksQuery() string {
+	return fmt.Sprintf(`DELETE FROM %v WHERE username = %v`, sqlTableWebDAVLocks, sqlPlaceholders[0])
+}
+
+func getCleanupWebDAVLocksQuery() string {
+	return fmt.Sprintf(`DELETE FROM %v WHERE expires_at > 0 AND expires_at <= %v`, sqlTableWebDAVLocks,
+		sqlPlaceholders[0])
+}
+
+func getWebDAVPropertiesQuery() string {
+	return fmt.Sprintf(`SELECT %v FROM %v WHERE username = %v AND path_key = %v`, selectWebDAVPropertiesFields,
+		sqlTableWebDAVProperties, sqlPlaceholders[0], sqlPlaceholders[1])
+}
+
+func getWebDAVPropertiesTreeQuery() string {
+	return fmt.Sprintf(`SELECT id,path FROM %v WHERE username = %v AND (path_key = %v OR path LIKE %v ESCAPE '!')`,
+		sqlTableWebDAVProperties, sqlPlaceholders[0], sqlPlaceholders[1], sqlPlaceholders[2])
+}
+
+func getAddWebDAVPropertiesQuery() string {
+	return fmt.Sprintf(`INSERT INTO %v (properties,updated_at,username,path,path_key) VALUES (%v,%v,%v,%v,%v)`,
+		sqlTableWebDAVProperties, sqlPlaceholders[0], sqlPlaceholders[1], sqlPlaceholders[2], sqlPlaceholders[3],
+		sqlPlaceholders[4])
+}
+
+func getUpdateWebDAVPropertiesQuery() string {
+	return fmt.Sprintf(`UPDATE %v SET properties = %v,updated_at = %v WHERE username = %v AND path_key = %v`,
+		sqlTableWebDAVProperties, sqlPlaceholders[0], sqlPlaceholders[1], sqlPlaceholders[2], sqlPlaceholders[3])
+}
+
+func getUpdateWebDAVPropertiesPathQuery() string {
+	return fmt.Sprintf(`UPDATE %v SET path = %v,path_key = %v WHERE id = %v`, sqlTableWebDAVProperties,
+		sqlPlaceholders[0], sqlPlaceholders[1], sqlPlaceholders[2])
+}
+
+func getDeleteWebDAVPropertiesByIDQuery() string {
+	return fmt.Sprintf(`DELETE FROM %v WHERE id = %v`, sqlTableWebDAVProperties, sqlPlaceholders[0])
+}
+
+func getDeleteUserWebDAVPropertiesQuery() string {
+	return fmt.Sprintf(`DELETE FROM %v WHERE username = %v`, sqlTableWebDAVProperties, sqlPlaceholders[0])
+}
+
+func getAddFsEventQuery() string {
+	return fmt.Sprintf(`INSERT INTO %v (id,timestamp,action,username,fs_path,fs_target_path,virtual_path,virtual_target_path,
+		ssh_cmd,file_size,status,protocol,ip,instance_id) VALUES (%v,%v,%v,%v,%v,%v,%v,%v,%v,%v,%v,%v,%v,%v)`,
+		sqlTableFsEvents, sqlPlaceholders[0], sqlPlaceholders[1], sqlPlaceholders[2], sqlPlaceholders[3],
+		sqlPlaceholders[4], sqlPlaceholders[5], sqlPlaceholders[6], sqlPlaceholders[7], sqlPlaceholders[8],
+		sqlPlaceholders[9], sqlPlaceholders[10], sqlPlaceholders[11], sqlPlaceholders[12], sqlPlaceholders[13])
+}
+
+func getAddProviderEventQuery() string {
+	return fmt.Sprintf(`INSERT INTO %v (id,timestamp,action,username,ip,object_type,object_name,instance_id)
+		VALUES (%v,%v,%v,%v,%v,%v,%v,%v)`, sqlTableProviderEvents, sqlPlaceholders[0], sqlPlaceholders[1],
+		sqlPlaceholders[2], sqlPlaceholders[3], sqlPlaceholders[4], sqlPlaceholders[5], sqlPlaceholders[6],
+		sqlPlaceholders[7])
+}
+
+func getSearchFsEventsQuery(search *FsEventSearch) (string, []interface{}) {
+	var conditions sqlConditions
+
+	conditions.addEventSearch(&search.eventSearch)
+	if search.SSHCmd != "" {
+		conditions.add("ssh_cmd = %v", search.SSHCmd)
+	}
+	conditions.addIn("protocol", getSQLArgsFromStrings(search.Protocols), false)
+	statuses := make([]interface{}, 0, len(search.Statuses))
+	for _, status := range search.Statuses {
+		statuses = append(statuses, status)
+	}
+	conditions.addIn("status", statuses, false)
+	conditions.args = append(conditions.args, search.Limit)
+
+	return fmt.Sprintf(`SELECT %v FROM %v%v ORDER BY timestamp %v,id %v LIMIT %v`, selectFsEventFields,
+		sqlTableFsEvents, conditions.getWhereClause(), search.Order, search.Order,
+		getSQLPlaceholder(len(conditions.args))), conditions.args
+}
+
+func getSearchProviderEventsQuery(search *ProviderEventSearch) (string, []interface{}) {
+	var conditions sqlConditions
+
+	conditions.addEventSearch(&search.eventSearch)
+	if search.ObjectName != "" {
+		conditions.add("object_name = %v", search.ObjectName)
+	}
+	conditions.addIn("object_type", getSQLArgsFromStrings(search.ObjectTypes), false)
+	conditions.args = append(conditions.args, search.Limit)
+
+	return fmt.Sprintf(`SELECT %v FROM %v%v ORDER BY timestamp %v,id %v LIMIT %v`, selectProviderEventFields,
+		sqlTableProviderEvents, conditions.getWhereClause(), search.Order, search.Order,
+		getSQLPlaceholder(len(conditions.args))), conditions.args
+}
+
+func getCleanupFsEventsQuery() string {
+	return fmt.Sprintf(`DELETE FROM %v WHERE timestamp < %v`, sqlTableFsEvents, sqlPlaceholders[0])
+}
+
+func getCleanupProviderEventsQuery() string {
+	return fmt.Sprintf(`DELETE FROM %v WHERE timestamp < %v`, sqlTableProviderEvents, sqlPlaceholders[0])
+}
+
+func getGroupByNameQuery() string {
+	return fmt.Sprintf(`SELECT %v FROM %v WHERE name = %v`, selectGroupFields, sqlTableGroups, sqlPlaceholders[0])
+}
+
+func getGroupsQuery(order string) string {
+	return fmt.Sprintf(`SELECT %v FROM %v ORDER BY name %v LIMIT %v OFFSET %v`, selectGroupFields, sqlTableGroups,
+		order, sqlPlaceholders[0], sqlPlaceholders[1])
+}
+
+func getGroupsWithNamesQuery(numArgs int) string {
+	var sb strings.Builder
+	for idx := 0; idx < numArgs; idx++ {
+		if sb.Len() == 0 {
+			sb.WriteString("(")
+		} else {
+			sb.WriteString(",")
+		}
+		if config.Driver == PGSQLDataProviderName || config.Driver == CockroachDataProviderName {
+			sb.WriteString(fmt.Sprintf("$%v", idx+1))
+		} else {
+			sb.WriteString("?")
+		}
+	}
+	if sb.Len() > 0 {
+		sb.WriteString(")")
+	} else {
+		sb.WriteString("('')")
+	}
+	return fmt.Sprintf(`SELECT %v FROM %v WHERE name IN %v`, selectGroupFields, sqlTableGroups, sb.String())
+}
+
+func getDumpGroupsQuery() string {
+	return fmt.Sprintf(`SELECT %v FROM %v`, selectGroupFields, sqlTableGroups)
+}
+
+func getAddGroupQuery() string {
+	return fmt.Sprintf(`INSERT INTO %v (name,description,created_at,updated_at,user_settings)
+		VALUES (%v,%v,%v,%v,%v)`, sqlTableGroups, sqlPlaceholders[0], sqlPlaceholders[1], sqlPlaceholders[2],
+		sqlPlaceholders[3], sqlPlaceholders[4])
+}
+
+func getUpdateGroupQuery() string {
+	return fmt.Sprintf(`UPDATE %v SET description=%v,user_settings=%v,updated_at=%v WHERE name = %v`, sqlTableGroups,
+		sqlPlaceholders[0], sqlPlaceholders[1], sqlPlaceholders[2], sqlPlaceholders[3])
+}
+
+func getDeleteGroupQuery() string {
+	return fmt.Sprintf(`DELETE FROM %v WHERE id = %v`, sqlTableGroups, sqlPlaceholders[0])
+}
+
+func getClearGroupFolderMappingQuery() string {
+	return fmt.Sprintf(`DELETE FROM %v WHERE group_id = (SELECT id FROM %v WHERE name = %v)`, sqlTableGroupsFoldersMapping,
+		sqlTableGroups, sqlPlaceholders[0])
+}
+
+func getAddGroupFolderMappingQuery() string {
+	return fmt.Sprintf(`INSERT INTO %v (virtual_path,quota_size,quota_files,folder_id,group_id)
+		VALUES (%v,%v,%v,%v,(SELECT id FROM %v WHERE name = %v))`, sqlTableGroupsFoldersMapping, sqlPlaceholders[0],
+		sqlPlaceholders[1], sqlPlaceholders[2], sqlPlaceholders[3], sqlTableGroups, sqlPlaceholders[4])
+}
+
+func getClearUserGroupMappingQuery() string {
+	return fmt.Sprintf(`DELETE FROM %v WHERE user_id = (SELECT id FROM %v WHERE username = %v)`, sqlTableGroupsMapping,
+		sqlTableUsers, sqlPlaceholders[0])
+}
+
+func getAddUserGroupMappingQuery() string {
+	return fmt.Sprintf(`INSERT INTO %v (user_id,group_id) VALUES ((SELECT id FROM %v WHERE username = %v),
+		(SELECT id FROM %v WHERE name = %v))`, sqlTableGroupsMapping, sqlTableUsers, sqlPlaceholders[0],
+		sqlTableGroups, sqlPlaceholders[1])
+}
+
+func getEventRuleByNameQuery() string {
+	return fmt.Sprintf(`SELECT %v FROM %v WHERE name = %v`, selectEventRuleFields, sqlTableEventsRules, sqlPlaceholders[0])
+}
+
+func getEventRulesQuery(order string) string {
+	return fmt.Sprintf(`SELECT %v FROM %v ORDER BY name %v LIMIT %v OFFSET %v`, selectEventRuleFields,
+		sqlTableEventsRules, order, sqlPlaceholders[0], sqlPlaceholders[1])
+}
+
+func getDumpEventRulesQuery() string {
+	return fmt.Sprintf(`SELECT %v FROM %v`, selectEventRuleFields, sqlTableEventsRules)
+}
+
+func getAddEventRuleQuery() string {
+	return fmt.Sprintf(`INSERT INTO %v (name,description,created_at,updated_at,event_trigger,conditions,actions)
+		VALUES (%v,%v,%v,%v,%v,%v,%v)`, sqlTableEventsRules, sqlPlaceholders[0], sqlPlaceholders[1],
+		sqlPlaceholders[2], sqlPlaceholders[3], sqlPlaceholders[4], sqlPlaceholders[5], sqlPlaceholders[6])
+}
+
+func getUpdateEventRuleQuery() string {
+	return fmt.Sprintf(`UPDATE %v SET description=%v,updated_at=%v,event_trigger=%v,conditions=%v,actions=%v
+		WHERE name = %v`, sqlTableEventsRules, sqlPlaceholders[0], sqlPlaceholders[1], sqlPlaceholders[2],
+		sqlPlaceholders[3], sqlPlaceholders[4], sqlPlaceholders[5])
+}
+
+func getDeleteEventRuleQuery() string {
+	return fmt.Sprintf(`DELETE FROM %v WHERE name = %v`, sqlTableEventsRules, sqlPlaceholders[0])
+}
+
+func getScheduleByNameQuery() string {
+	return fmt.Sprintf(`SELECT %v FROM %v WHERE name = %v`, selectScheduleFields, sqlTableSchedules, sqlPlaceholders[0])
+}
+
+func getSchedulesQuery(order string) string {
+	return fmt.Sprintf(`SELECT %v FROM %v ORDER BY name %v LIMIT %v OFFSET %v`, selectScheduleFields,
+		sqlTableSchedules, order, sqlPlaceholders[0], sqlPlaceholders[1])
+}
+
+func getDumpSchedulesQuery() string {
+	return fmt.Sprintf(`SELECT %v FROM %v`, selectScheduleFields, sqlTableSchedules)
+}
+
+func getAddScheduleQuery() string {
+	return fmt.Sprintf(`INSERT INTO %v (name,description,created_at,updated_at,status,cron_expression,task,options,
+		last_run,last_run_status) VALUES (%v,%v,%v,%v,%v,%v,%v,%v,0,0)`, sqlTableSchedules, sqlPlaceholders[0],
+		sqlPlaceholders[1], sqlPlaceholders[2], sqlPlaceholders[3], sqlPlaceholders[4], sqlPlaceholders[5],
+		sqlPlaceholders[6], sqlPlaceholders[7])
+}
+
+func getUpdateScheduleQuery() string {
+	return fmt.Sprintf(`UPDATE %v SET description=%v,updated_at=%v,status=%v,cron_expression=%v,task=%v,options=%v
+		WHERE name = %v`, sqlTableSchedules, sqlPlaceholders[0], sqlPlaceholders[1], sqlPlaceholders[2],
+		sqlPlaceholders[3], sqlPlaceholders[4], sqlPlaceholders[5], sqlPlaceholders[6])
+}
+
+func getDeleteScheduleQuery() string {
+	return fmt.Sprintf(`DELETE FROM %v WHERE name = %v`, sqlTableSchedules, sqlPlaceholders[0])
+}
+
+func getClaimScheduleRunQuery() string {
+	return fmt.Sprintf(`UPDATE %v SET last_run=%v,last_run_status=%v,last_run_error=NULL WHERE name = %v AND last_run < %v`,
+		sqlTableSchedules, sqlPlaceholders[0], sqlPlaceholders[1], sqlPlaceholders[2], sqlPlaceholders[3])
+}
+
+func getSetScheduleRunResultQuery() string {
+	return fmt.Sprintf(`UPDATE %v SET last_run_status=%v,last_run_error=%v WHERE name = %v`, sqlTableSchedules,
+		sqlPlaceholders[0], sqlPlaceholders[1], sqlPlaceholders[2])
+}
+
 func getAPIKeyByIDQuery() string {
 	return fmt.Sprintf(`SELECT %v FROM %v WHERE key_id = %v`, selectAPIKeyFields, sqlTableAPIKeys, sqlPlaceholders[0])
 }
@@ -140,6 +542,37 @@ func getUsersQuery(order string) string {
 		order, sqlPlaceholders[0], sqlPlaceholders[1])
 }
 
+// getUsersInScopeQuery returns the query, and its arguments, to get the users matching at least one
+// of the admin scope criteria. All the matching users are returned if limit is less than or equal to 0
+func getUsersInScopeQuery(admin *Admin, limit, offset int, order string) (string, []interface{}) {
+	var conditions sqlConditions
+
+	scope := &admin.Filters.Scope
+	for _, prefix := range scope.UsernamePrefixes {
+		conditions.add(`username LIKE %v ESCAPE '!'`, escapeLikePattern(prefix)+"%")
+	}
+	if len(scope.Groups) > 0 {
+		conditions.addIn("g.name", getSQLArgsFromStrings(scope.Groups), false)
+		idx := len(conditions.conditions) - 1
+		conditions.conditions[idx] = fmt.Sprintf(`id IN (SELECT gm.user_id FROM %v gm INNER JOIN %v g
+			ON gm.group_id = g.id WHERE %v)`, sqlTableGroupsMapping, sqlTableGroups, conditions.conditions[idx])
+	}
+	if scope.CreatedUsers {
+		conditions.add(`filters LIKE %v ESCAPE '!'`, "%"+escapeLikePattern(getCreatedByFilter(admin.Username))+"%")
+	}
+	if len(conditions.conditions) == 0 {
+		conditions.conditions = append(conditions.conditions, "1 = 0")
+	}
+	q := fmt.Sprintf(`SELECT %v FROM %v WHERE %v ORDER BY username %v`, selectUserFields, sqlTableUsers,
+		strings.Join(conditions.conditions, " OR "), order)
+	if limit > 0 {
+		conditions.args = append(conditions.args, limit, offset)
+		q += fmt.Sprintf(` LIMIT %v OFFSET %v`, getSQLPlaceholder(len(conditions.args)-1),
+			getSQLPlaceholder(len(conditions.args)))
+	}
+	return q, conditions.args
+}
+
 func getRecentlyUpdatedUsersQuery() string {
 	return fmt.Sprintf(`SELECT %v FROM %v WHERE updated_at >= %v`, selectUserFields, sqlTableUsers, sqlPlaceholders[0])
 }
@@ -161,6 +594,30 @@ func getUpdateQuotaQuery(reset bool) string {
 		WHERE username = %v`, sqlTableUsers, sqlPlaceholders[0], sqlPlaceholders[1], sqlPlaceholders[2], sqlPlaceholders[3])
 }
 
+func getUpdateTransferQuotaQuery(reset bool) string {
+	if reset {
+		return fmt.Sprintf(`UPDATE %v SET used_upload_data_transfer = %v,used_download_data_transfer = %v,last_data_transfer_reset = %v
+			WHERE username = %v`, sqlTableUsers, sqlPlaceholders[0], sqlPlaceholders[1], sqlPlaceholders[2], sqlPlaceholders[3])
+	}
+	return getUpdatePeriodicTransferQuotaQuery(sqlTableUsers, "username")
+}
+
+func getUpdatePeriodicTransferQuotaQuery(table, keyField string) string {
+	// if the data transfer was last reset before the start of the current period the
+	// used data transfer is replaced, this way the reset is atomic
+	return fmt.Sprintf(`UPDATE %v SET used_upload_data_transfer = CASE WHEN last_data_transfer_reset < %v THEN %v
+		ELSE used_upload_data_transfer + %v END,used_download_data_transfer = CASE WHEN last_data_transfer_reset < %v
+		THEN %v ELSE used_download_data_transfer + %v END,last_data_transfer_reset = CASE WHEN last_data_transfer_reset < %v
+		THEN %v ELSE last_data_transfer_reset END WHERE %v = %v`, table, sqlPlaceholders[0], sqlPlaceholders[1],
+		sqlPlaceholders[2], sqlPlaceholders[3], sqlPlaceholders[4], sqlPlaceholders[5], sqlPlaceholders[6],
+		sqlPlaceholders[7], keyField, sqlPlaceholders[8])
+}
+
+func getTransferQuotaQuery() string {
+	return fmt.Sprintf(`SELECT used_upload_data_transfer,used_download_data_transfer,last_data_transfer_reset FROM %v
+		WHERE username = %v`, sqlTableUsers, sqlPlaceholders[0])
+}
+
 func getSetUpdateAtQuery() string {
 	return fmt.Sprintf(`UPDATE %v SET updated_at = %v WHERE username = %v`, sqlTableUsers, sqlPlaceholders[0], sqlPlaceholders[1])
 }
@@ -185,21 +642,31 @@ func getQuotaQuery() string {
 func getAddUserQuery() string {
 	return fmt.Sprintf(`INSERT INTO %v (username,password,public_keys,home_dir,uid,gid,max_sessions,quota_size,quota_files,permissions,
 		used_quota_size,used_quota_files,last_quota_update,upload_bandwidth,download_bandwidth,status,last_login,expiration_date,filters,
-		filesystem,additional_info,description,email,created_at,updated_at)
-		VALUES (%v,%v,%v,%v,%v,%v,%v,%v,%v,%v,0,0,0,%v,%v,%v,0,%v,%v,%v,%v,%v,%v,%v,%v)`, sqlTableUsers, sqlPlaceholders[0], sqlPlaceholders[1],
-		sqlPlaceholders[2], sqlPlaceholders[3], sqlPlaceholders[4], sqlPlaceholders[5], sqlPlaceholders[6], sqlPlaceholders[7],
-		sqlPlaceholders[8], sqlPlaceholders[9], sqlPlaceholders[10], sqlPlaceholders[11], sqlPlaceholders[12], sqlPlaceholders[13],
-		sqlPlaceholders[14], sqlPlaceholders[15], sqlPlaceholders[16], sqlPlaceholders[17], sqlPlaceholders[18], sqlPlaceholders[19],
-		sqlPlaceholders[20])
+		filesystem,additional_info,description,email,created_at,updated_at,upload_data_transfer,download_data_transfer,
+		total_data_transfer,data_transfer_reset_period,used_upload_data_transfer,used_download_data_transfer,last_data_transfer_reset,
+		last_password_change)
+		VALUES (%v,%v,%v,%v,%v,%v,%v,%v,%v,%v,0,0,0,%v,%v,%v,0,%v,%v,%v,%v,%v,%v,%v,%v,%v,%v,%v,%v,0,0,0,%v)`, sqlTableUsers,
+		sqlPlaceholders[0], sqlPlaceholders[1], sqlPlaceholders[2], sqlPlaceholders[3], sqlPlaceholders[4], sqlPlaceholders[5],
+		sqlPlaceholders[6], sqlPlaceholders[7], sqlPlaceholders[8], sqlPlaceholders[9], sqlPlaceholders[10], sqlPlaceholders[11],
+		sqlPlaceholders[12], sqlPlaceholders[13], sqlPlaceholders[14], sqlPlaceholders[15], sqlPlaceholders[16], sqlPlaceholders[17],
+		sqlPlaceholders[18], sqlPlaceholders[19], sqlPlaceholders[20], sqlPlaceholders[21], sqlPlaceholders[22], sqlPlaceholders[23],
+		sqlPlaceholders[24], sqlPlaceholders[25])
+}
+
+func getUpdateUserLastPasswordChangeQuery() string {
+	return fmt.Sprintf(`UPDATE %v SET last_password_change=%v WHERE id = %v AND COALESCE(password,'') <> %v`, sqlTableUsers,
+		sqlPlaceholders[0], sqlPlaceholders[1], sqlPlaceholders[2])
 }
 
 func getUpdateUserQuery() string {
 	return fmt.Sprintf(`UPDATE %v SET password=%v,public_keys=%v,home_dir=%v,uid=%v,gid=%v,max_sessions=%v,quota_size=%v,
 		quota_files=%v,permissions=%v,upload_bandwidth=%v,download_bandwidth=%v,status=%v,expiration_date=%v,filters=%v,filesystem=%v,
-		additional_info=%v,description=%v,email=%v,updated_at=%v WHERE id = %v`, sqlTableUsers, sqlPlaceholders[0], sqlPlaceholders[1], sqlPlaceholders[2], sqlPlaceholders[3],
-		sqlPlaceholders[4], sqlPlaceholders[5], sqlPlaceholders[6], sqlPlaceholders[7], sqlPlaceholders[8], sqlPlaceholders[9],
-		sqlPlaceholders[10], sqlPlaceholders[11], sqlPlaceholders[12], sqlPlaceholders[13], sqlPlaceholders[14], sqlPlaceholders[15],
-		sqlPlaceholders[16], sqlPlaceholders[17], sqlPlaceholders[18], sqlPlaceholders[19])
+		additional_info=%v,description=%v,email=%v,updated_at=%v,upload_data_transfer=%v,download_data_transfer=%v,
+		total_data_transfer=%v,data_transfer_reset_period=%v WHERE id = %v`, sqlTableUsers, sqlPlaceholders[0], sqlPlaceholders[1],
+		sqlPlaceholders[2], sqlPlaceholders[3], sqlPlaceholders[4], sqlPlaceholders[5], sqlPlaceholders[6], sqlPlaceholders[7],
+		sqlPlaceholders[8], sqlPlaceholders[9], sqlPlaceholders[10], sqlPlaceholders[11], sqlPlaceholders[12], sqlPlaceholders[13],
+		sqlPlaceholders[14], sqlPlaceholders[15], sqlPlaceholders[16], sqlPlaceholders[17], sqlPlaceholders[18], sqlPlaceholders[19],
+		sqlPlaceholders[20], sqlPlaceholders[21], sqlPlaceholders[22], sqlPlaceholders[23])
 }
 
 func getDeleteUserQuery() string {
@@ -215,14 +682,19 @@ func checkFolderNameQuery() string {
 }
 
 func getAddFolderQuery() string {
-	return fmt.Sprintf(`INSERT INTO %v (path,used_quota_size,used_quota_files,last_quota_update,name,description,filesystem)
-		VALUES (%v,%v,%v,%v,%v,%v,%v)`, sqlTableFolders, sqlPlaceholders[0], sqlPlaceholders[1], sqlPlaceholders[2],
-		sqlPlaceholders[3], sqlPlaceholders[4], sqlPlaceholders[5], sqlPlaceholders[6])
+	return fmt.Sprintf(`INSERT INTO %v (path,used_quota_size,used_quota_files,last_quota_update,name,description,filesystem,
+		upload_data_transfer,download_data_transfer,total_data_transfer,used_upload_data_transfer,used_download_data_transfer,
+		last_data_transfer_reset,data_transfer_reset_period,data_retention) VALUES (%v,%v,%v,%v,%v,%v,%v,%v,%v,%v,%v,%v,%v,%v,%v)`,
+		sqlTableFolders, sqlPlaceholders[0], sqlPlaceholders[1], sqlPlaceholders[2], sqlPlaceholders[3], sqlPlaceholders[4],
+		sqlPlaceholders[5], sqlPlaceholders[6], sqlPlaceholders[7], sqlPlaceholders[8], sqlPlaceholders[9], sqlPlaceholders[10],
+		sqlPlaceholders[11], sqlPlaceholders[12], sqlPlaceholders[13], sqlPlaceholders[14])
 }
 
 func getUpdateFolderQuery() string {
-	return fmt.Sprintf(`UPDATE %v SET path=%v,description=%v,filesystem=%v WHERE name = %v`, sqlTableFolders, sqlPlaceholders[0],
-		sqlPlaceholders[1], sqlPlaceholders[2], sqlPlaceholders[3])
+	return fmt.Sprintf(`UPDATE %v SET path=%v,description=%v,filesystem=%v,upload_data_transfer=%v,download_data_transfer=%v,
+		total_data_transfer=%v,data_transfer_reset_period=%v,data_retention=%v WHERE name = %v`, sqlTableFolders,
+		sqlPlaceholders[0], sqlPlaceholders[1], sqlPlaceholders[2], sqlPlaceholders[3], sqlPlaceholders[4], sqlPlaceholders[5],
+		sqlPlaceholders[6], sqlPlaceholders[7], sqlPlaceholders[8])
 }
 
 func getDeleteFolderQuery() string {
@@ -254,6 +726,19 @@ func getUpdateFolderQuotaQuery(reset bool) string {
 		WHERE name = %v`, sqlTableFolders, sqlPlaceholders[0], sqlPlaceholders[1], sqlPlaceholders[2], sqlPlaceholders[3])
 }
 
+func getUpdateFolderTransferQuotaQuery(reset bool) string {
+	if reset {
+		return fmt.Sprintf(`UPDATE %v SET used_upload_data_transfer = %v,used_download_data_transfer = %v,last_data_transfer_reset = %v
+			WHERE name = %v`, sqlTableFolders, sqlPlaceholders[0], sqlPlaceholders[1], sqlPlaceholders[2], sqlPlaceholders[3])
+	}
+	return getUpdatePeriodicTransferQuotaQuery(sqlTableFolders, "name")
+}
+
+func getTransferQuotaFolderQuery() string {
+	return fmt.Sprintf(`SELECT used_upload_data_transfer,used_download_data_transfer,last_data_transfer_reset FROM %v
+		WHERE name = %v`, sqlTableFolders, sqlPlaceholders[0])
+}
+
 func getQuotaFolderQuery() string {
 	return fmt.Sprintf(`SELECT used_quota_size,used_quota_files FROM %v WHERE name = %v`, sqlTableFolders,
 		sqlPlaceholders[0])
@@ -273,7 +758,10 @@ func getRelatedFoldersForUsersQuery(users []User) string {
 		sb.WriteString(")")
 	}
 	return fmt.Sprintf(`SELECT f.id,f.name,f.path,f.used_quota_size,f.used_quota_files,f.last_quota_update,fm.virtual_path,
-		fm.quota_size,fm.quota_files,fm.user_id,f.filesystem,f.description FROM %v f INNER JOIN %v fm ON f.id = fm.folder_id WHERE
+		fm.quota_size,fm.quota_files,fm.user_id,f.filesystem,f.description,
+		f.upload_data_transfer,f.download_data_transfer,f.total_data_transfer,f.used_upload_data_transfer,
+		f.used_download_data_transfer,f.last_data_transfer_reset,f.data_transfer_reset_period,f.data_retention FROM %v f
+		INNER JOIN %v fm ON f.id = fm.folder_id WHERE
 		fm.user_id IN %v ORDER BY fm.user_id`, sqlTableFolders, sqlTableFoldersMapping, sb.String())
 }
 
@@ -294,6 +782,78 @@ func getRelatedUsersForFoldersQuery(folders []vfs.BaseVirtualFolder) string {
 		WHERE fm.folder_id IN %v ORDER BY fm.folder_id`, sqlTableFoldersMapping, sqlTableUsers, sb.String())
 }
 
+func getRelatedGroupsForUsersQuery(users []User) string {
+	var sb strings.Builder
+	for _, u := range users {
+		if sb.Len() == 0 {
+			sb.WriteString("(")
+		} else {
+			sb.WriteString(",")
+		}
+		sb.WriteString(strconv.FormatInt(u.ID, 10))
+	}
+	if sb.Len() > 0 {
+		sb.WriteString(")")
+	}
+	return fmt.Sprintf(`SELECT gm.user_id,g.name FROM %v g INNER JOIN %v gm ON g.id = gm.group_id
+		WHERE gm.user_id IN %v ORDER BY gm.id`, sqlTableGroups, sqlTableGroupsMapping, sb.String())
+}
+
+func getRelatedUsersForGroupsQuery(groups []Group) string {
+	var sb strings.Builder
+	for _, g := range groups {
+		if sb.Len() == 0 {
+			sb.WriteString("(")
+		} else {
+			sb.WriteString(",")
+		}
+		sb.WriteString(strconv.FormatInt(g.ID, 10))
+	}
+	if sb.Len() > 0 {
+		sb.WriteString(")")
+	}
+	return fmt.Sprintf(`SELECT gm.group_id,u.username FROM %v gm INNER JOIN %v u ON gm.user_id = u.id
+		WHERE gm.group_id IN %v ORDER BY gm.group_id`, sqlTableGroupsMapping, sqlTableUsers, sb.String())
+}
+
+func getRelatedFoldersForGroupsQuery(groups []Group) string {
+	var sb strings.Builder
+	for _, g := range groups {
+		if sb.Len() == 0 {
+			sb.WriteString("(")
+		} else {
+			sb.WriteString(",")
+		}
+		sb.WriteString(strconv.FormatInt(g.ID, 10))
+	}
+	if sb.Len() > 0 {
+		sb.WriteString(")")
+	}
+	return fmt.Sprintf(`SELECT f.id,f.name,f.path,f.used_quota_size,f.used_quota_files,f.last_quota_update,fm.virtual_path,
+		fm.quota_size,fm.quota_files,fm.group_id,f.filesystem,f.description,
+		f.upload_data_transfer,f.download_data_transfer,f.total_data_transfer,f.used_upload_data_transfer,
+		f.used_download_data_transfer,f.last_data_transfer_reset,f.data_transfer_reset_period,f.data_retention FROM %v f
+		INNER JOIN %v fm ON f.id = fm.folder_id WHERE
+		fm.group_id IN %v ORDER BY fm.group_id`, sqlTableFolders, sqlTableGroupsFoldersMapping, sb.String())
+}
+
+func getRelatedGroupsForFoldersQuery(folders []vfs.BaseVirtualFolder) string {
+	var sb strings.Builder
+	for _, f := range folders {
+		if sb.Len() == 0 {
+			sb.WriteString("(")
+		} else {
+			sb.WriteString(",")
+		}
+		sb.WriteString(strconv.FormatInt(f.ID, 10))
+	}
+	if sb.Len() > 0 {
+		sb.WriteString(")")
+	}
+	return fmt.Sprintf(`SELECT fm.folder_id,g.name FROM %v fm INNER JOIN %v g ON fm.group_id = g.id
+		WHERE fm.folder_id IN %v ORDER BY fm.folder_id`, sqlTableGroupsFoldersMapping, sqlTableGroups, sb.String())
+}
+
 func getDatabaseVersionQuery() string {
 	return fmt.Sprintf("SELECT version from %v LIMIT 1", sqlTableSchemaVersion)
 }
@@ -301,3 +861,189 @@ func getDatabaseVersionQuery() string {
 func getUpdateDBVersionQuery() string {
 	return fmt.Sprintf(`UPDATE %v SET version=%v`, sqlTableSchemaVersion, sqlPlaceholders[0])
 }
+
+// getSQLPlaceholder returns the placeholder for the query argument at the specified position, starting from 1
+func getSQLPlaceholder(position int) string {
+	if config.Driver == PGSQLDataProviderName || config.Driver == CockroachDataProviderName {
+		return fmt.Sprintf("$%v", position)
+	}
+	return "?"
+}
+
+// sqlConditions allows to build a WHERE clause with a variable number of arguments
+type sqlConditions struct {
+	conditions []string
+	args       []interface{}
+}
+
+// add adds a condition, the condition must contain a single %v verb for the argument placeholder
+func (c *sqlConditions) add(condition string, arg interface{}) {
+	c.args = append(c.args, arg)
+	c.conditions = append(c.conditions, fmt.Sprintf(condition, getSQLPlaceholder(len(c.args))))
+}
+
+func (c *sqlConditions) addIn(field string, values []interface{}, exclude bool) {
+	if len(values) == 0 {
+		return
+	}
+	placeholders := make([]string, 0, len(values))
+	for _, val := range values {
+		c.args = append(c.args, val)
+		placeholders = append(placeholders, getSQLPlaceholder(len(c.args)))
+	}
+	operator := "IN"
+	if exclude {
+		operator = "NOT IN"
+	}
+	c.conditions = append(c.conditions, fmt.Sprintf("%v %v (%v)", field, operator, strings.Join(placeholders, ",")))
+}
+
+func (c *sqlConditions) addEventSearch(search *eventSearch) {
+	if search.StartTimestamp > 0 {
+		c.add("timestamp >= %v", search.StartTimestamp)
+	}
+	if search.EndTimestamp > 0 {
+		c.add("timestamp <= %v", search.EndTimestamp)
+	}
+	c.addIn("action", getSQLArgsFromStrings(search.Actions), false)
+	if search.Username != "" {
+		c.add("username = %v", search.Username)
+	}
+	if search.IP != "" {
+		c.add("ip = %v", search.IP)
+	}
+	c.addIn("instance_id", getSQLArgsFromStrings(search.InstanceIDs), false)
+	c.addIn("id", getSQLArgsFromStrings(search.ExcludeIDs), true)
+}
+
+func (c *sqlConditions) getWhereClause() string {
+	if len(c.conditions) == 0 {
+		return ""
+	}
+	return " WHERE " + strings.Join(c.conditions, " AND ")
+}
+
+func getSQLArgsFromStrings(values []string) []interface{} {
+	args := make([]interface{}, 0, len(values))
+	for _, val := range values {
+		args = append(args, val)
+	}
+	return args
+}
+
+func getS3AccessKeyQuery() string {
+	return fmt.Sprintf(`SELECT %v FROM %v WHERE access_key_id = %v`, selectS3AccessKeyFields, sqlTableS3AccessKeys,
+		sqlPlaceholders[0])
+}
+
+func getUserS3AccessKeysQuery() string {
+	return fmt.Sprintf(`SELECT %v FROM %v WHERE username = %v ORDER BY id ASC`, selectS3AccessKeyFields,
+		sqlTableS3AccessKeys, sqlPlaceholders[0])
+}
+
+func getAddS3AccessKeyQuery() string {
+	return fmt.Sprintf(`INSERT INTO %v (access_key_id,username,secret_access_key,description,created_at)
+		VALUES (%v,%v,%v,%v,%v)`, sqlTableS3AccessKeys, sqlPlaceholders[0], sqlPlaceholders[1], sqlPlaceholders[2],
+		sqlPlaceholders[3], sqlPlaceholders[4])
+}
+
+func getDeleteS3AccessKeyQuery() string {
+	return fmt.Sprintf(`DELETE FROM %v WHERE access_key_id = %v AND username = %v`, sqlTableS3AccessKeys,
+		sqlPlaceholders[0], sqlPlaceholders[1])
+}
+
+func getDeleteUserS3AccessKeysQuery() string {
+	return fmt.Sprintf(`DELETE FROM %v WHERE username = %v`, sqlTableS3AccessKeys, sqlPlaceholders[0])
+}
+
+func getUpdateS3AccessKeyQuery() string {
+	return fmt.Sprintf(`UPDATE %v SET username=%v,secret_access_key=%v,description=%v,created_at=%v
+		WHERE access_key_id = %v`, sqlTableS3AccessKeys, sqlPlaceholders[0], sqlPlaceholders[1], sqlPlaceholders[2],
+		sqlPlaceholders[3], sqlPlaceholders[4])
+}
+
+func getDumpS3AccessKeysQuery() string {
+	return fmt.Sprintf(`SELECT %v FROM %v ORDER BY id ASC`, selectS3AccessKeyFields, sqlTableS3AccessKeys)
+}
+
+func getTusUploadQuery() string {
+	return fmt.Sprintf(`SELECT %v FROM %v WHERE upload_id = %v`, selectTusUploadFields, sqlTableTusUploads,
+		sqlPlaceholders[0])
+}
+
+func getAddTusUploadQuery() string {
+	return fmt.Sprintf(`INSERT INTO %v (upload_id,username,virtual_path,fs_path,upload_length,upload_offset,expires_at,
+		created_at,updated_at) VALUES (%v,%v,%v,%v,%v,%v,%v,%v,%v)`, sqlTableTusUploads, sqlPlaceholders[0],
+		sqlPlaceholders[1], sqlPlaceholders[2], sqlPlaceholders[3], sqlPlaceholders[4], sqlPlaceholders[5],
+		sqlPlaceholders[6], sqlPlaceholders[7], sqlPlaceholders[8])
+}
+
+func getUpdateTusUploadOffsetQuery() string {
+	return fmt.Sprintf(`UPDATE %v SET upload_offset = %v,expires_at = %v,updated_at = %v WHERE upload_id = %v`,
+		sqlTableTusUploads, sqlPlaceholders[0], sqlPlaceholders[1], sqlPlaceholders[2], sqlPlaceholders[3])
+}
+
+func getDeleteTusUploadQuery() string {
+	return fmt.Sprintf(`DELETE FROM %v WHERE upload_id = %v`, sqlTableTusUploads, sqlPlaceholders[0])
+}
+
+func getCountTusUploadsQuery() string {
+	return fmt.Sprintf(`SELECT COUNT(*) FROM %v WHERE username = %v`, sqlTableTusUploads, sqlPlaceholders[0])
+}
+
+func getExpiredTusUploadsQuery() string {
+	return fmt.Sprintf(`SELECT %v FROM %v WHERE expires_at <= %v ORDER BY expires_at ASC`, selectTusUploadFields,
+		sqlTableTusUploads, sqlPlaceholders[0])
+}
+
+func getDeleteUserTusUploadsQuery() string {
+	return fmt.Sprintf(`DELETE FROM %v WHERE username = %v`, sqlTableTusUploads, sqlPlaceholders[0])
+}
+
+func getResetCodeQuery() string {
+	return fmt.Sprintf(`SELECT %v FROM %v WHERE code = %v`, selectResetCodeFields, sqlTableResetCodes,
+		sqlPlaceholders[0])
+}
+
+func getAddResetCodeQuery() string {
+	return fmt.Sprintf(`INSERT INTO %v (code,username,account_type,password_fingerprint,expires_at,created_at)
+		VALUES (%v,%v,%v,%v,%v,%v)`, sqlTableResetCodes, sqlPlaceholders[0], sqlPlaceholders[1], sqlPlaceholders[2],
+		sqlPlaceholders[3], sqlPlaceholders[4], sqlPlaceholders[5])
+}
+
+func getDeleteResetCodeQuery() string {
+	return fmt.Sprintf(`DELETE FROM %v WHERE code = %v`, sqlTableResetCodes, sqlPlaceholders[0])
+}
+
+func getCountResetCodesQuery() string {
+	return fmt.Sprintf(`SELECT COUNT(*) FROM %v WHERE username = %v AND account_type = %v AND expires_at > %v`,
+		sqlTableResetCodes, sqlPlaceholders[0], sqlPlaceholders[1], sqlPlaceholders[2])
+}
+
+func getDeleteExpiredResetCodesQuery() string {
+	return fmt.Sprintf(`DELETE FROM %v WHERE expires_at <= %v`, sqlTableResetCodes, sqlPlaceholders[0])
+}
+
+func getMultipartUploadQuery() string {
+	return fmt.Sprintf(`SELECT %v FROM %v WHERE upload_key = %v`, selectMultipartUploadFields, sqlTableMultipartUploads,
+		sqlPlaceholders[0])
+}
+
+func getAddMultipartUploadQuery() string {
+	return fmt.Sprintf(`INSERT INTO %v (upload_key,upload_size,state,created_at,updated_at) VALUES (%v,%v,%v,%v,%v)`,
+		sqlTableMultipartUploads, sqlPlaceholders[0], sqlPlaceholders[1], sqlPlaceholders[2], sqlPlaceholders[3],
+		sqlPlaceholders[4])
+}
+
+func getUpdateMultipartUploadQuery() string {
+	return fmt.Sprintf(`UPDATE %v SET upload_size = %v,state = %v,updated_at = %v WHERE upload_key = %v`,
+		sqlTableMultipartUploads, sqlPlaceholders[0], sqlPlaceholders[1], sqlPlaceholders[2], sqlPlaceholders[3])
+}
+
+func getDeleteMultipartUploadQuery() string {
+	return fmt.Sprintf(`DELETE FROM %v WHERE upload_key = %v`, sqlTableMultipartUploads, sqlPlaceholders[0])
+}
+
+func getCleanupMultipartUploadsQuery() string {
+	return fmt.Sprintf(`DELETE FROM %v WHERE updated_at <= %v`, sqlTableMultipartUploads, sqlPlaceholders[0])
+}
@@ -0,0 +1,97 @@
+package dataprovider
+
+import (
+	"time"
+
+	"github.com/drakkan/sftpgo/v2/util"
+)
+
+// TransferQuota defines the data transfer limits and the remaining allowances,
+// as bytes, for a transfer. A zero limit means no restriction
+type TransferQuota struct {
+	ULSize           int64
+	DLSize           int64
+	TotalSize        int64
+	AllowedULSize    int64
+	AllowedDLSize    int64
+	AllowedTotalSize int64
+}
+
+// NewTransferQuota returns a TransferQuota for the given limits, as MB, and
+// used data transfer, as bytes. The upload and download limits are ignored
+// if a total limit is set
+func NewTransferQuota(uploadLimit, downloadLimit, totalLimit, usedUL, usedDL int64) TransferQuota {
+	var q TransferQuota
+	if totalLimit > 0 {
+		q.TotalSize = totalLimit * 1048576
+		q.AllowedTotalSize = q.TotalSize - usedUL - usedDL
+		return q
+	}
+	if uploadLimit > 0 {
+		q.ULSize = uploadLimit * 1048576
+		q.AllowedULSize = q.ULSize - usedUL
+	}
+	if downloadLimit > 0 {
+		q.DLSize = downloadLimit * 1048576
+		q.AllowedDLSize = q.DLSize - usedDL
+	}
+	return q
+}
+
+// HasLimits returns true if at least a data transfer limit is defined
+func (q *TransferQuota) HasLimits() bool {
+	return q.ULSize > 0 || q.DLSize > 0 || q.TotalSize > 0
+}
+
+// HasUploadSpace returns true if uploads are allowed
+func (q *TransferQuota) HasUploadSpace() bool {
+	if q.TotalSize > 0 && q.AllowedTotalSize <= 0 {
+		return false
+	}
+	return q.ULSize <= 0 || q.AllowedULSize > 0
+}
+
+// HasDownloadSpace returns true if downloads are allowed
+func (q *TransferQuota) HasDownloadSpace() bool {
+	if q.TotalSize > 0 && q.AllowedTotalSize <= 0 {
+		return false
+	}
+	return q.DLSize <= 0 || q.AllowedDLSize > 0
+}
+
+// Merge returns the most restrictive combination of this TransferQuota
+// and the given one
+func (q *TransferQuota) Merge(other TransferQuota) TransferQuota {
+	ulSize, allowedUL := mergeTransferLimit(q.ULSize, q.AllowedULSize, other.ULSize, other.AllowedULSize)
+	dlSize, allowedDL := mergeTransferLimit(q.DLSize, q.AllowedDLSize, other.DLSize, other.AllowedDLSize)
+	totalSize, allowedTotal := mergeTransferLimit(q.TotalSize, q.AllowedTotalSize, other.TotalSize, other.AllowedTotalSize)
+	return TransferQuota{
+		ULSize:           ulSize,
+		DLSize:           dlSize,
+		TotalSize:        totalSize,
+		AllowedULSize:    allowedUL,
+		AllowedDLSize:    allowedDL,
+		AllowedTotalSize: allowedTotal,
+	}
+}
+
+func mergeTransferLimit(size, allowed, otherSize, otherAllowed int64) (int64, int64) {
+	if otherSize <= 0 {
+		return size, allowed
+	}
+	if size <= 0 || otherAllowed < allowed {
+		return otherSize, otherAllowed
+	}
+	return size, allowed
+}
+
+// getUpdatedTransferQuota returns the used upload and download data transfer
+// and the last reset time after applying the given update.
+// The used data transfer is reset if it was last reset before periodStart
+func getUpdatedTransferQuota(usedUL, usedDL, lastReset, uploadSize, downloadSize int64, reset bool,
+	periodStart int64) (int64, int64, int64) {
+	if reset || lastReset < periodStart {
+		return uploadSize, downloadSize, util.GetTimeAsMsSinceEpoch(time.Now())
+	}
+	return usedUL + uploadSize, usedDL + downloadSize, lastReset
+}
@@ -0,0 +1,92 @@
+package dataprovider
+
+import (
+	"time"
+
+	"github.com/drakkan/sftpgo/v2/util"
+)
+
+// TusUpload defines a resumable upload session stored within the data provider.
+// Storing the sessions in the data provider allows to resume the uploads after
+// a restart or using a different SFTPGo instance
+type TusUpload struct {
+	// Upload ID, it is unique
+	ID string `json:"id"`
+	// Username of the upload owner
+	Username string `json:"username"`
+	// Target file, as cleaned virtual path
+	VirtualPath string `json:"virtual_path"`
+	// Temporary file path, as seen by the storage backend
+	FsPath string `json:"fs_path"`
+	// Upload length in bytes
+	Length int64 `json:"length"`
+	// Bytes stored so far
+	Offset int64 `json:"offset"`
+	// Upload expiration as unix timestamp in milliseconds
+	ExpiresAt int64 `json:"expires_at"`
+	// Creation time as unix timestamp in milliseconds
+	CreatedAt int64 `json:"created_at"`
+	// Last update as unix timestamp in milliseconds
+	UpdatedAt int64 `json:"updated_at"`
+}
+
+// IsExpired returns true if the upload is expired at the given time
+func (u *TusUpload) IsExpired(now time.Time) bool {
+	return u.ExpiresAt <= util.GetTimeAsMsSinceEpoch(now)
+}
+
+// GetExpiration returns the upload expiration
+func (u *TusUpload) GetExpiration() time.Time {
+	return util.GetTimeFromMsecSinceEpoch(u.ExpiresAt)
+}
+
+func (u *TusUpload) validate() error {
+	if u.ID == "" {
+		return util.NewValidationError("upload ID is mandatory")
+	}
+	if u.Username == "" {
+		return util.NewValidationError("username is mandatory")
+	}
+	if u.VirtualPath == "" || u.FsPath == "" {
+		return util.NewValidationError("upload paths are mandatory")
+	}
+	if u.Length < 0 || u.Offset < 0 || u.Offset > u.Length {
+		return util.NewValidationError("invalid upload length or offset")
+	}
+	if u.ExpiresAt == 0 || u.CreatedAt == 0 {
+		return util.NewValidationError("creation and expiration time are mandatory")
+	}
+	return nil
+}
+
+// AddTusUpload stores a new resumable upload session
+func AddTusUpload(upload *TusUpload) error {
+	return provider.addTusUpload(upload)
+}
+
+// GetTusUpload returns the resumable upload with the given ID, expired uploads are returned too
+func GetTusUpload(uploadID string) (TusUpload, error) {
+	return provider.tusUploadExists(uploadID)
+}
+
+// UpdateTusUploadOffset updates the bytes stored and the expiration for the
+// specified resumable upload
+func UpdateTusUploadOffset(uploadID string, offset int64, expiresAt time.Time) error {
+	return provider.updateTusUploadOffset(uploadID, offset, util.GetTimeAsMsSinceEpoch(expiresAt),
+		util.GetTimeAsMsSinceEpoch(time.Now()))
+}
+
+// DeleteTusUpload removes the resumable upload with the given ID
+func DeleteTusUpload(uploadID string) error {
+	return provider.deleteTusUpload(uploadID)
+}
+
+// GetTusUploadsCount returns the number of resumable uploads for the specified user
+func GetTusUploadsCount(username string) (int, error) {
+	return provider.getTusUploadsCount(username)
+}
+
+// GetExpiredTusUploads returns the resumable uploads expired at the given time
+func GetExpiredTusUploads(now time.Time) ([]TusUpload, error) {
+	return provider.getExpiredTusUploads(util.GetTimeAsMsSinceEpoch(now))
+}
@@ -62,6 +62,7 @@ const (
 	SSHLoginMethodKeyAndKeyboardInt   = "publickey+keyboard-interactive"
 	LoginMethodTLSCertificate         = "TLSCertificate"
 	LoginMethodTLSCertificateAndPwd   = "TLSCertificate+password"
+	LoginMethodS3AccessKey            = "S3AccessKey"
 )
 
 var (
@@ -75,8 +76,13 @@ type User struct {
 	VirtualFolders []vfs.VirtualFolder `json:"virtual_folders,omitempty"`
 	// Filesystem configuration details
 	FsConfig vfs.Filesystem `json:"filesystem"`
+	// Names of the groups this user belongs to.
+	// The group settings are merged with the user ones at login
+	Groups []string `json:"groups,omitempty"`
 	// we store the filesystem here using the base path as key.
 	fsCache map[string]vfs.Fs `json:"-"`
+	// true if the settings inherited from the groups are already merged
+	groupSettingsApplied bool `json:"-"`
 }
 
 // GetFilesystem returns the base filesystem for this user
@@ -93,25 +99,36 @@ func (u *User) GetFilesystem(connectionID string) (fs vfs.Fs, err error) {
 func (u *User) getRootFs(connectionID string) (fs vfs.Fs, err error) {
 	switch u.FsConfig.Provider {
 	case sdk.S3FilesystemProvider:
-		return vfs.NewS3Fs(connectionID, u.GetHomeDir(), "", u.FsConfig.S3Config)
+		fs, err = vfs.NewS3Fs(connectionID, u.GetHomeDir(), "", u.FsConfig.S3Config)
 	case sdk.GCSFilesystemProvider:
 		config := u.FsConfig.GCSConfig
 		config.CredentialFile = u.GetGCSCredentialsFilePath()
-		return vfs.NewGCSFs(connectionID, u.GetHomeDir(), "", config)
+		fs, err = vfs.NewGCSFs(connectionID, u.GetHomeDir(), "", config)
 	case sdk.AzureBlobFilesystemProvider:
-		return vfs.NewAzBlobFs(connectionID, u.GetHomeDir(), "", u.FsConfig.AzBlobConfig)
+		fs, err = vfs.NewAzBlobFs(connectionID, u.GetHomeDir(), "", u.FsConfig.AzBlobConfig)
 	case sdk.CryptedFilesystemProvider:
 		return vfs.NewCryptFs(connectionID, u.GetHomeDir(), "", u.FsConfig.CryptConfig)
 	case sdk.SFTPFilesystemProvider:
-		forbiddenSelfUsers, err := u.getForbiddenSFTPSelfUsers(u.FsConfig.SFTPConfig.Username)
+		var forbiddenSelfUsers []string
+		forbiddenSelfUsers, err = u.getForbiddenSFTPSelfUsers(u.FsConfig.SFTPConfig.Username)
 		if err != nil {
 			return nil, err
 		}
 		forbiddenSelfUsers = append(forbiddenSelfUsers, u.Username)
-		return vfs.NewSFTPFs(connectionID, "", u.GetHomeDir(), forbiddenSelfUsers, u.FsConfig.SFTPConfig)
+		fs, err = vfs.NewSFTPFs(connectionID, "", u.GetHomeDir(), forbiddenSelfUsers, u.FsConfig.SFTPConfig)
+	case sdk.FTPFilesystemProvider:
+		return vfs.NewFTPFs(connectionID, "", u.GetHomeDir(), u.FsConfig.FTPConfig)
+	case sdk.WebDAVFilesystemProvider:
+		return vfs.NewWebDAVFs(connectionID, "", u.GetHomeDir(), u.FsConfig.WebDAVConfig)
 	default:
 		return vfs.NewOsFs(connectionID, u.GetHomeDir(), ""), nil
 	}
+	// cloud filesystems can be wrapped by the local read cache, remote
+	// filesystems can be wrapped by the client side encryption
+	if err != nil {
+		return fs, err
+	}
+	return vfs.NewEncryptedFs(vfs.NewReadCacheFs(fs, &u.FsConfig), &u.FsConfig)
 }
 
 // CheckFsRoot check the root directory for the main fs and the virtual folders.
@@ -192,6 +209,26 @@ func (u *User) CheckLoginConditions() error {
 	return nil
 }
 
+// GetPasswordExpirationTime returns the time when the current password expires.
+// The zero time is returned if the password never expires
+func (u *User) GetPasswordExpirationTime() time.Time {
+	if u.Filters.PasswordExpiration <= 0 {
+		return time.Time{}
+	}
+	lastChange := util.GetTimeFromMsecSinceEpoch(u.LastPasswordChange)
+	return lastChange.Add(time.Duration(u.Filters.PasswordExpiration) * 24 * time.Hour)
+}
+
+// MustChangePassword returns true if the user must change the password before using
+// protocols other than HTTP
+func (u *User) MustChangePassword() bool {
+	if u.Filters.RequirePasswordChange {
+		return true
+	}
+	expiration := u.GetPasswordExpirationTime()
+	return !expiration.IsZero() && expiration.Before(time.Now())
+}
+
 // hideConfidentialData hides user confidential data
 func (u *User) hideConfidentialData() {
 	u.Password = ""
@@ -304,6 +341,10 @@ func (u *User) SetEmptySecrets() {
 	u.FsConfig.CryptConfig.Passphrase = kms.NewEmptySecret()
 	u.FsConfig.SFTPConfig.Password = kms.NewEmptySecret()
 	u.FsConfig.SFTPConfig.PrivateKey = kms.NewEmptySecret()
+	u.FsConfig.FTPConfig.Password = kms.NewEmptySecret()
+	u.FsConfig.WebDAVConfig.Password = kms.NewEmptySecret()
+	u.FsConfig.WebDAVConfig.BearerToken = kms.NewEmptySecret()
+	u.FsConfig.Encryption.Passphrase = kms.NewEmptySecret()
 	for idx := range u.VirtualFolders {
 		folder := &u.VirtualFolders[idx]
 		folder.FsConfig.SetEmptySecretsIfNil()
@@ -635,7 +676,8 @@ func (u *User) IsPartialAuth(loginMethod string) bool {
 		return false
 	}
 	for _, method := range u.GetAllowedLoginMethods() {
-		if method == LoginMethodTLSCertificate || method == LoginMethodTLSCertificateAndPwd {
+		if method == LoginMethodTLSCertificate || method == LoginMethodTLSCertificateAndPwd ||
+			method == LoginMethodS3AccessKey {
 			continue
 		}
 		if !util.IsStringInSlice(method, SSHMultiStepsLoginMethods) {
@@ -678,6 +720,32 @@ func (u *User) GetFlatFilePatterns() []sdk.PatternsFilter {
 	return result
 }
 
+// GetDataRetention returns the data retention policies to apply for this user.
+// The user defined policies are returned together with the ones defined for the
+// mapped virtual folders, the folders policies are mapped to the user virtual paths.
+// If a policy is defined for the same virtual path both in the user and in a
+// virtual folder, the user one takes precedence
+func (u *User) GetDataRetention() []sdk.DataRetentionPolicy {
+	result := make([]sdk.DataRetentionPolicy, 0, len(u.Filters.DataRetention))
+	paths := make(map[string]bool)
+	for _, policy := range u.Filters.DataRetention {
+		paths[policy.Path] = true
+		result = append(result, policy)
+	}
+	for idx := range u.VirtualFolders {
+		folder := &u.VirtualFolders[idx]
+		for _, policy := range folder.DataRetention {
+			policy.Path = path.Join(folder.VirtualPath, policy.Path)
+			if paths[policy.Path] {
+				continue
+			}
+			paths[policy.Path] = true
+			result = append(result, policy)
+		}
+	}
+	return result
+}
+
 // IsFileAllowed returns true if the specified file is allowed by the file restrictions filters
 func (u *User) IsFileAllowed(virtualPath string) bool {
 	return u.isFilePatternAllowed(virtualPath)
@@ -732,6 +800,11 @@ func (u *User) CanChangePassword() bool {
 	return !util.IsStringInSlice(sdk.WebClientPasswordChangeDisabled, u.Filters.WebClient)
 }
 
+// CanResetPassword returns true if this user is allowed to reset its password
+func (u *User) CanResetPassword() bool {
+	return u.CanChangePassword() && !util.IsStringInSlice(sdk.WebClientPasswordResetDisabled, u.Filters.WebClient)
+}
+
 // CanChangeAPIKeyAuth returns true if this user is allowed to enable/disable API key authentication
 func (u *User) CanChangeAPIKeyAuth() bool {
 	return !util.IsStringInSlice(sdk.WebClientAPIKeyAuthChangeDisabled, u.Filters.WebClient)
@@ -742,6 +815,12 @@ func (u *User) CanChangeInfo() bool {
 	return !util.IsStringInSlice(sdk.WebClientInfoChangeDisabled, u.Filters.WebClient)
 }
 
+// CanManageShares returns true if this user is allowed to manage shares
+// from the web client and the user REST API
+func (u *User) CanManageShares() bool {
+	return !util.IsStringInSlice(sdk.WebClientSharesDisabled, u.Filters.WebClient)
+}
+
 // CanManagePublicKeys returns true if this user is allowed to manage public keys
 // from the web client. Used in web client UI
 func (u *User) CanManagePublicKeys() bool {
@@ -880,6 +959,32 @@ func (u *User) HasQuotaRestrictions() bool {
 	return u.QuotaFiles > 0 || u.QuotaSize > 0
 }
 
+// HasTransferQuotaRestrictions returns true if there are data transfer limits for this user
+func (u *User) HasTransferQuotaRestrictions() bool {
+	return u.UploadDataTransfer > 0 || u.DownloadDataTransfer > 0 || u.TotalDataTransfer > 0
+}
+
+// GetDataTransferSummary returns the used data transfer and the configured limits as string
+func (u *User) GetDataTransferSummary() string {
+	if u.TotalDataTransfer > 0 {
+		return fmt.Sprintf("Transfer: %v/%v", util.ByteCountIEC(u.UsedUploadDataTransfer+u.UsedDownloadDataTransfer),
+			util.ByteCountIEC(u.TotalDataTransfer*1048576))
+	}
+	var result string
+	if u.UploadDataTransfer > 0 {
+		result = fmt.Sprintf("Upload: %v/%v", util.ByteCountIEC(u.UsedUploadDataTransfer),
+			util.ByteCountIEC(u.UploadDataTransfer*1048576))
+	}
+	if u.DownloadDataTransfer > 0 {
+		if result != "" {
+			result += ". "
+		}
+		result += fmt.Sprintf("Download: %v/%v", util.ByteCountIEC(u.UsedDownloadDataTransfer),
+			util.ByteCountIEC(u.DownloadDataTransfer*1048576))
+	}
+	return result
+}
+
 // GetQuotaSummary returns used quota and limits if defined
 func (u *User) GetQuotaSummary() string {
 	var result string
@@ -1034,79 +1139,206 @@ func (u *User) getACopy() User {
 		vfolder := u.VirtualFolders[idx].GetACopy()
 		virtualFolders = append(virtualFolders, vfolder)
 	}
+	var groups []string
+	if len(u.Groups) > 0 {
+		groups = make([]string, len(u.Groups))
+		copy(groups, u.Groups)
+	}
 	permissions := make(map[string][]string)
 	for k, v := range u.Permissions {
 		perms := make([]string, len(v))
 		copy(perms, v)
 		permissions[k] = perms
 	}
-	filters := sdk.UserFilters{}
-	filters.MaxUploadFileSize = u.Filters.MaxUploadFileSize
-	filters.TLSUsername = u.Filters.TLSUsername
-	filters.UserType = u.Filters.UserType
-	filters.TOTPConfig.Enabled = u.Filters.TOTPConfig.Enabled
-	filters.TOTPConfig.ConfigName = u.Filters.TOTPConfig.ConfigName
-	filters.TOTPConfig.Secret = u.Filters.TOTPConfig.Secret.Clone()
-	filters.TOTPConfig.Protocols = make([]string, len(u.Filters.TOTPConfig.Protocols))
-	copy(filters.TOTPConfig.Protocols, u.Filters.TOTPConfig.Protocols)
-	filters.AllowedIP = make([]string, len(u.Filters.AllowedIP))
-	copy(filters.AllowedIP, u.Filters.AllowedIP)
-	filters.DeniedIP = make([]string, len(u.Filters.DeniedIP))
-	copy(filters.DeniedIP, u.Filters.DeniedIP)
-	filters.DeniedLoginMethods = make([]string, len(u.Filters.DeniedLoginMethods))
-	copy(filters.DeniedLoginMethods, u.Filters.DeniedLoginMethods)
-	filters.FilePatterns = make([]sdk.PatternsFilter, len(u.Filters.FilePatterns))
-	copy(filters.FilePatterns, u.Filters.FilePatterns)
-	filters.DeniedProtocols = make([]string, len(u.Filters.DeniedProtocols))
-	copy(filters.DeniedProtocols, u.Filters.DeniedProtocols)
-	filters.Hooks.ExternalAuthDisabled = u.Filters.Hooks.ExternalAuthDisabled
-	filters.Hooks.PreLoginDisabled = u.Filters.Hooks.PreLoginDisabled
-	filters.Hooks.CheckPasswordDisabled = u.Filters.Hooks.CheckPasswordDisabled
-	filters.DisableFsChecks = u.Filters.DisableFsChecks
-	filters.AllowAPIKeyAuth = u.Filters.AllowAPIKeyAuth
-	filters.WebClient = make([]string, len(u.Filters.WebClient))
-	copy(filters.WebClient, u.Filters.WebClient)
-	filters.RecoveryCodes = make([]sdk.RecoveryCode, 0)
-	for _, code := range u.Filters.RecoveryCodes {
-		if code.Secret == nil {
-			code.Secret = kms.NewEmptySecret()
-		}
-		filters.RecoveryCodes = append(filters.RecoveryCodes, sdk.RecoveryCode{
-			Secret: code.Secret.Clone(),
-			Used:   code.Used,
-		})
-	}
+	filters := copyBaseUserFilters(u.Filters)
 
 	return User{
 		BaseUser: sdk.BaseUser{
-			ID:                u.ID,
-			Username:          u.Username,
-			Email:             u.Email,
-			Password:          u.Password,
-			PublicKeys:        pubKeys,
-			HomeDir:           u.HomeDir,
-			UID:               u.UID,
-			GID:               u.GID,
-			MaxSessions:       u.MaxSessions,
-			QuotaSize:         u.QuotaSize,
-			QuotaFiles:        u.QuotaFiles,
-			Permissions:       permissions,
-			UsedQuotaSize:     u.UsedQuotaSize,
-			UsedQuotaFiles:    u.UsedQuotaFiles,
-			LastQuotaUpdate:   u.LastQuotaUpdate,
-			UploadBandwidth:   u.UploadBandwidth,
-			DownloadBandwidth: u.DownloadBandwidth,
-			Status:            u.Status,
-			ExpirationDate:    u.ExpirationDate,
-			LastLogin:         u.LastLogin,
-			Filters:           filters,
-			AdditionalInfo:    u.AdditionalInfo,
-			Description:       u.Description,
-			CreatedAt:         u.CreatedAt,
-			UpdatedAt:         u.UpdatedAt,
+			ID:                       u.ID,
+			Username:                 u.Username,
+			Email:                    u.Email,
+			Password:                 u.Password,
+			PublicKeys:               pubKeys,
+			HomeDir:                  u.HomeDir,
+			UID:                      u.UID,
+			GID:                      u.GID,
+			MaxSessions:              u.MaxSessions,
+			QuotaSize:                u.QuotaSize,
+			QuotaFiles:               u.QuotaFiles,
+			Permissions:              permissions,
+			UsedQuotaSize:            u.UsedQuotaSize,
+			UsedQuotaFiles:           u.UsedQuotaFiles,
+			LastQuotaUpdate:          u.LastQuotaUpdate,
+			UploadBandwidth:          u.UploadBandwidth,
+			DownloadBandwidth:        u.DownloadBandwidth,
+			UploadDataTransfer:       u.UploadDataTransfer,
+			DownloadDataTransfer:     u.DownloadDataTransfer,
+			TotalDataTransfer:        u.TotalDataTransfer,
+			UsedUploadDataTransfer:   u.UsedUploadDataTransfer,
+			UsedDownloadDataTransfer: u.UsedDownloadDataTransfer,
+			LastDataTransferReset:    u.LastDataTransferReset,
+			DataTransferResetPeriod:  u.DataTransferResetPeriod,
+			Status:                   u.Status,
+			ExpirationDate:           u.ExpirationDate,
+			LastLogin:                u.LastLogin,
+			LastPasswordChange:       u.LastPasswordChange,
+			Filters:                  filters,
+			AdditionalInfo:           u.AdditionalInfo,
+			Description:              u.Description,
+			CreatedAt:                u.CreatedAt,
+			UpdatedAt:                u.UpdatedAt,
 		},
 		VirtualFolders: virtualFolders,
 		FsConfig:       u.FsConfig.GetACopy(),
+		Groups:         groups,
+	}
+}
+
+// GetGroupsAsString returns the list of groups as comma separated string
+func (u *User) GetGroupsAsString() string {
+	return strings.Join(u.Groups, ",")
+}
+
+// LoadAndApplyGroupSettings loads the groups this user belongs to and merges
+// their settings with the user ones. The settings explicitly configured for
+// the user always take precedence over the inherited ones
+func (u *User) LoadAndApplyGroupSettings() error {
+	if len(u.Groups) == 0 || u.groupSettingsApplied {
+		return nil
+	}
+	groups, err := provider.getGroupsWithNames(u.Groups)
+	if err != nil {
+		return fmt.Errorf("unable to get groups for user %#v: %w", u.Username, err)
+	}
+	u.applyGroupSettings(groups)
+	return nil
+}
+
+func (u *User) applyGroupSettings(groups []Group) {
+	if u.groupSettingsApplied {
+		return
+	}
+	u.groupSettingsApplied = true
+	// the groups are applied in the order defined for the user
+	for _, name := range u.Groups {
+		for idx := range groups {
+			if groups[idx].Name == name {
+				u.mergeWithGroup(&groups[idx])
+				break
+			}
+		}
+	}
+}
+
+func (u *User) mergeWithGroup(group *Group) {
+	settings := &group.UserSettings
+	if u.Permissions == nil {
+		u.Permissions = make(map[string][]string)
+	}
+	for dir, perms := range settings.Permissions {
+		if _, ok := u.Permissions[dir]; !ok {
+			u.Permissions[dir] = perms
+		}
+	}
+	if u.MaxSessions == 0 {
+		u.MaxSessions = settings.MaxSessions
+	}
+	if u.QuotaSize == 0 {
+		u.QuotaSize = settings.QuotaSize
+	}
+	if u.QuotaFiles == 0 {
+		u.QuotaFiles = settings.QuotaFiles
+	}
+	if u.UploadBandwidth == 0 {
+		u.UploadBandwidth = settings.UploadBandwidth
+	}
+	if u.DownloadBandwidth == 0 {
+		u.DownloadBandwidth = settings.DownloadBandwidth
+	}
+	if !u.HasTransferQuotaRestrictions() {
+		u.UploadDataTransfer = settings.UploadDataTransfer
+		u.DownloadDataTransfer = settings.DownloadDataTransfer
+		u.TotalDataTransfer = settings.TotalDataTransfer
+		if u.DataTransferResetPeriod == sdk.DataTransferResetNever {
+			u.DataTransferResetPeriod = settings.DataTransferResetPeriod
+		}
+	}
+	if u.FsConfig.Provider == sdk.LocalFilesystemProvider && settings.FsConfig.Provider != sdk.LocalFilesystemProvider {
+		u.FsConfig = settings.FsConfig.GetACopy()
+	}
+	u.mergeGroupFilters(&settings.Filters)
+	u.mergeGroupVirtualFolders(group.VirtualFolders)
+}
+
+func (u *User) mergeGroupFilters(filters *sdk.UserFilters) {
+	u.Filters.AllowedIP = util.RemoveDuplicates(append(u.Filters.AllowedIP, filters.AllowedIP...))
+	u.Filters.DeniedIP = util.RemoveDuplicates(append(u.Filters.DeniedIP, filters.DeniedIP...))
+	u.Filters.DeniedLoginMethods = util.RemoveDuplicates(append(u.Filters.DeniedLoginMethods,
+		filters.DeniedLoginMethods...))
+	u.Filters.DeniedProtocols = util.RemoveDuplicates(append(u.Filters.DeniedProtocols, filters.DeniedProtocols...))
+	u.Filters.WebClient = util.RemoveDuplicates(append(u.Filters.WebClient, filters.WebClient...))
+	for _, pattern := range filters.FilePatterns {
+		found := false
+		for _, p := range u.Filters.FilePatterns {
+			if p.Path == pattern.Path {
+				found = true
+				break
+			}
+		}
+		if !found {
+			u.Filters.FilePatterns = append(u.Filters.FilePatterns, pattern)
+		}
+	}
+	if u.Filters.MaxUploadFileSize == 0 {
+		u.Filters.MaxUploadFileSize = filters.MaxUploadFileSize
+	}
+	if u.Filters.TLSUsername == "" || u.Filters.TLSUsername == sdk.TLSUsernameNone {
+		if filters.TLSUsername != "" {
+			u.Filters.TLSUsername = filters.TLSUsername
+		}
+	}
+	if filters.Hooks.ExternalAuthDisabled {
+		u.Filters.Hooks.ExternalAuthDisabled = true
+	}
+	if filters.Hooks.PreLoginDisabled {
+		u.Filters.Hooks.PreLoginDisabled = true
+	}
+	if filters.Hooks.CheckPasswordDisabled {
+		u.Filters.Hooks.CheckPasswordDisabled = true
+	}
+	if filters.DisableFsChecks {
+		u.Filters.DisableFsChecks = true
+	}
+	if u.Filters.PasswordExpiration == 0 {
+		u.Filters.PasswordExpiration = filters.PasswordExpiration
+	}
+	for _, policy := range filters.DataRetention {
+		found := false
+		for _, p := range u.Filters.DataRetention {
+			if p.Path == policy.Path {
+				found = true
+				break
+			}
+		}
+		if !found {
+			u.Filters.DataRetention = append(u.Filters.DataRetention, policy)
+		}
+	}
+}
+
+func (u *User) mergeGroupVirtualFolders(folders []vfs.VirtualFolder) {
+	for idx := range folders {
+		folder := &folders[idx]
+		found := false
+		for _, v := range u.VirtualFolders {
+			if v.Name == folder.Name || v.VirtualPath == folder.VirtualPath {
+				found = true
+				break
+			}
+		}
+		if !found {
+			u.VirtualFolders = append(u.VirtualFolders, folder.GetACopy())
+		}
 	}
 }
 
@@ -1119,3 +1351,52 @@ func (u *User) GetEncryptionAdditionalData() string {
 func (u *User) GetGCSCredentialsFilePath() string {
 	return filepath.Join(credentialsDirPath, fmt.Sprintf("%v_gcs_credentials.json", u.Username))
 }
+
+func copyBaseUserFilters(in sdk.UserFilters) sdk.UserFilters {
+	filters := sdk.UserFilters{}
+	filters.MaxUploadFileSize = in.MaxUploadFileSize
+	filters.TLSUsername = in.TLSUsername
+	filters.UserType = in.UserType
+	filters.TOTPConfig.Enabled = in.TOTPConfig.Enabled
+	filters.TOTPConfig.ConfigName = in.TOTPConfig.ConfigName
+	if in.TOTPConfig.Secret != nil {
+		filters.TOTPConfig.Secret = in.TOTPConfig.Secret.Clone()
+	}
+	filters.TOTPConfig.Protocols = make([]string, len(in.TOTPConfig.Protocols))
+	copy(filters.TOTPConfig.Protocols, in.TOTPConfig.Protocols)
+	filters.AllowedIP = make([]string, len(in.AllowedIP))
+	copy(filters.AllowedIP, in.AllowedIP)
+	filters.DeniedIP = make([]string, len(in.DeniedIP))
+	copy(filters.DeniedIP, in.DeniedIP)
+	filters.DeniedLoginMethods = make([]string, len(in.DeniedLoginMethods))
+	copy(filters.DeniedLoginMethods, in.DeniedLoginMethods)
+	filters.FilePatterns = make([]sdk.PatternsFilter, len(in.FilePatterns))
+	copy(filters.FilePatterns, in.FilePatterns)
+	filters.DeniedProtocols = make([]string, len(in.DeniedProtocols))
+	copy(filters.DeniedProtocols, in.DeniedProtocols)
+	filters.Hooks.ExternalAuthDisabled = in.Hooks.ExternalAuthDisabled
+	filters.Hooks.PreLoginDisabled = in.Hooks.PreLoginDisabled
+	filters.Hooks.CheckPasswordDisabled = in.Hooks.CheckPasswordDisabled
+	filters.DisableFsChecks = in.DisableFsChecks
+	filters.AllowAPIKeyAuth = in.AllowAPIKeyAuth
+	filters.RequirePasswordChange = in.RequirePasswordChange
+	filters.PasswordExpiration = in.PasswordExpiration
+	filters.CreatedBy = in.CreatedBy
+	filters.WebClient = make([]string, len(in.WebClient))
+	copy(filters.WebClient, in.WebClient)
+	if len(in.DataRetention) > 0 {
+		filters.DataRetention = make([]sdk.DataRetentionPolicy, len(in.DataRetention))
+		copy(filters.DataRetention, in.DataRetention)
+	}
+	filters.RecoveryCodes = make([]sdk.RecoveryCode, 0)
+	for _, code := range in.RecoveryCodes {
+		if code.Secret == nil {
+			code.Secret = kms.NewEmptySecret()
+		}
+		filters.RecoveryCodes = append(filters.RecoveryCodes, sdk.RecoveryCode{
+			Secret: code.Secret.Clone(),
+			Used:   code.Used,
+		})
+	}
+	return filters
+}
@@ -0,0 +1,172 @@
+package dataprovider
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+
+	"github.com/drakkan/sftpgo/v2/logger"
+	"github.com/drakkan/sftpgo/v2/util"
+)
+
+const (
+	webDAVLocksCleanupInterval = 10 * time.Minute
+)
+
+var (
+	webDAVLocksCleanupTicker     *time.Ticker
+	webDAVLocksCleanupTickerDone chan bool
+)
+
+// WebDAVLock defines a WebDAV lock stored within the data provider.
+// Storing the locks in the data provider allows to share them between
+// multiple SFTPGo instances and to preserve them across restarts
+type WebDAVLock struct {
+	// Opaque lock token, it is unique
+	Token string `json:"token"`
+	// Username of the lock owner
+	Username string `json:"username"`
+	// Locked resource, as cleaned virtual path
+	Root string `json:"root"`
+	// Lock duration in milliseconds, a negative value means infinite
+	Duration int64 `json:"duration"`
+	// Lock expiration as unix timestamp in milliseconds, 0 means no expiration
+	ExpiresAt int64 `json:"expires_at"`
+	// Owner as XML, as sent by the WebDAV client
+	OwnerXML string `json:"owner_xml,omitempty"`
+	// ZeroDepth is true if the lock does not apply to the root descendants
+	ZeroDepth bool `json:"zero_depth"`
+	// Creation time as unix timestamp in milliseconds
+	CreatedAt int64 `json:"created_at"`
+	// Last refresh as unix timestamp in milliseconds
+	UpdatedAt int64 `json:"updated_at"`
+}
+
+// IsExpired returns true if the lock is expired at the given time
+func (l *WebDAVLock) IsExpired(now time.Time) bool {
+	return l.isExpiredAt(util.GetTimeAsMsSinceEpoch(now))
+}
+
+func (l *WebDAVLock) isExpiredAt(timestamp int64) bool {
+	return l.ExpiresAt > 0 && l.ExpiresAt <= timestamp
+}
+
+// GetDuration returns the lock duration, a negative value means infinite
+func (l *WebDAVLock) GetDuration() time.Duration {
+	if l.Duration < 0 {
+		return -1
+	}
+	return time.Duration(l.Duration) * time.Millisecond
+}
+
+// Refresh sets the lock duration starting from the given time,
+// a negative duration means infinite
+func (l *WebDAVLock) Refresh(now time.Time, duration time.Duration) {
+	if duration < 0 {
+		l.Duration = -1
+		l.ExpiresAt = 0
+	} else {
+		l.Duration = duration.Milliseconds()
+		l.ExpiresAt = util.GetTimeAsMsSinceEpoch(now.Add(duration))
+	}
+	l.UpdatedAt = util.GetTimeAsMsSinceEpoch(now)
+	if l.CreatedAt == 0 {
+		l.CreatedAt = l.UpdatedAt
+	}
+}
+
+// getRootKey returns a fixed length key that identifies the locked resource,
+// we can have only one lock for each resource
+func (l *WebDAVLock) getRootKey() string {
+	h := sha256.Sum256([]byte(l.Root))
+	return hex.EncodeToString(h[:])
+}
+
+func (l *WebDAVLock) validate() error {
+	if l.Token == "" {
+		return util.NewValidationError("token is mandatory")
+	}
+	if l.Username == "" {
+		return util.NewValidationError("username is mandatory")
+	}
+	if l.Root == "" {
+		return util.NewValidationError("root is mandatory")
+	}
+	if l.CreatedAt == 0 {
+		return util.NewValidationError("creation time is mandatory")
+	}
+	if l.Duration >= 0 && l.ExpiresAt == 0 {
+		return util.NewValidationError("expiration is mandatory for locks with a finite duration")
+	}
+	return nil
+}
+
+// AddWebDAVLock stores a new WebDAV lock.
+// Adding a lock for a resource already locked by the same user will fail,
+// locks for the same resource expired at the creation time of the new one are replaced
+func AddWebDAVLock(lock *WebDAVLock) error {
+	return provider.addWebDAVLock(lock)
+}
+
+// UpdateWebDAVLock updates the duration and expiration for an existing WebDAV lock
+func UpdateWebDAVLock(lock *WebDAVLock) error {
+	return provider.updateWebDAVLock(lock)
+}
+
+// GetWebDAVLock returns the WebDAV lock with the given token, expired locks are returned too
+func GetWebDAVLock(token string) (WebDAVLock, error) {
+	return provider.webDAVLockExists(token)
+}
+
+// GetWebDAVLocks returns the WebDAV locks for the specified user not yet expired at the given time
+func GetWebDAVLocks(username string, now time.Time) ([]WebDAVLock, error) {
+	locks, err := provider.getWebDAVLocks(username)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]WebDAVLock, 0, len(locks))
+	for _, lock := range locks {
+		if !lock.IsExpired(now) {
+			result = append(result, lock)
+		}
+	}
+	return result, nil
+}
+
+// DeleteWebDAVLock removes the WebDAV lock with the given token
+func DeleteWebDAVLock(token string) error {
+	return provider.deleteWebDAVLock(token)
+}
+
+// startWebDAVLocksCleanupTimer periodically removes the expired WebDAV locks
+func startWebDAVLocksCleanupTimer() {
+	webDAVLocksCleanupTicker = time.NewTicker(webDAVLocksCleanupInterval)
+	webDAVLocksCleanupTickerDone = make(chan bool)
+
+	go func() {
+		for {
+			select {
+			case <-webDAVLocksCleanupTickerDone:
+				return
+			case t := <-webDAVLocksCleanupTicker.C:
+				cleanupWebDAVLocks(t)
+			}
+		}
+	}()
+}
+
+func stopWebDAVLocksCleanupTimer() {
+	if webDAVLocksCleanupTicker != nil {
+		webDAVLocksCleanupTicker.Stop()
+		webDAVLocksCleanupTickerDone <- true
+		webDAVLocksCleanupTicker = nil
+	}
+}
+
+func cleanupWebDAVLocks(now time.Time) {
+	if err := provider.cleanupWebDAVLocks(util.GetTimeAsMsSinceEpoch(now)); err != nil {
+		providerLog(logger.LevelWarn, "unable to remove WebDAV locks expired before %v: %v", now, err)
+		return
+	}
+	providerLog(logger.LevelDebug, "WebDAV locks expired before %v removed", now)
+}
@@ -0,0 +1,130 @@
+package dataprovider
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/drakkan/sftpgo/v2/util"
+)
+
+// WebDAVProperty defines a WebDAV dead property
+type WebDAVProperty struct {
+	// XML namespace
+	Namespace string `json:"namespace"`
+	// XML local name
+	Name string `json:"name"`
+	// Value of the xml:lang attribute, if any
+	Lang string `json:"lang,omitempty"`
+	// Property value as raw XML
+	InnerXML string `json:"inner_xml,omitempty"`
+}
+
+// WebDAVProperties defines the WebDAV dead properties stored for a path
+type WebDAVProperties struct {
+	// Username of the path owner
+	Username string `json:"username"`
+	// Virtual path
+	Path string `json:"path"`
+	// Dead properties, an empty slice means no properties
+	Properties []WebDAVProperty `json:"properties"`
+	// Last update as unix timestamp in milliseconds
+	UpdatedAt int64 `json:"updated_at"`
+}
+
+func (p *WebDAVProperties) getPathKey() string {
+	return getWebDAVPropertiesPathKey(p.Path)
+}
+
+func (p *WebDAVProperties) getKey() string {
+	return getWebDAVPropertiesKey(p.Username, p.Path)
+}
+
+func (p *WebDAVProperties) validate() error {
+	if p.Username == "" {
+		return util.NewValidationError("username is mandatory")
+	}
+	if p.Path == "" {
+		return util.NewValidationError("path is mandatory")
+	}
+	for _, prop := range p.Properties {
+		if prop.Name == "" {
+			return util.NewValidationError("property name is mandatory")
+		}
+	}
+	return nil
+}
+
+// getWebDAVPropertiesPathKey returns a fixed length key that identifies a virtual path
+func getWebDAVPropertiesPathKey(virtualPath string) string {
+	h := sha256.Sum256([]byte(virtualPath))
+	return hex.EncodeToString(h[:])
+}
+
+func getWebDAVPropertiesKey(username, virtualPath string) string {
+	return username + ":" + virtualPath
+}
+
+// isWebDAVPropertiesPathInTree returns true if virtualPath is root or one of its descendants
+func isWebDAVPropertiesPathInTree(virtualPath, root string) bool {
+	if virtualPath == root || root == "/" {
+		return true
+	}
+	return strings.HasPrefix(virtualPath, root+"/")
+}
+
+// getWebDAVPropertiesRenamedPath returns the new path for virtualPath after renaming source to target,
+// virtualPath must be source or one of its descendants
+func getWebDAVPropertiesRenamedPath(virtualPath, source, target string) string {
+	return path.Join(target, strings.TrimPrefix(virtualPath, source))
+}
+
+// getWebDAVPropertiesTreeLikePattern returns a pattern, to use with the LIKE operator and
+// the "!" escape character, matching all the descendants of the given root
+func getWebDAVPropertiesTreeLikePattern(root string) string {
+	if root == "/" {
+		return "/%"
+	}
+	return escapeLikePattern(root) + "/%"
+}
+
+// GetWebDAVProperties returns the WebDAV dead properties for the given user and virtual path.
+// Empty properties are returned if nothing is stored for the specified path
+func GetWebDAVProperties(username, virtualPath string) (WebDAVProperties, error) {
+	props, err := provider.webDAVPropertiesExists(username, virtualPath)
+	if err != nil {
+		if _, ok := err.(*util.RecordNotFoundError); ok {
+			return WebDAVProperties{
+				Username: username,
+				Path:     virtualPath,
+			}, nil
+		}
+		return props, err
+	}
+	return props, nil
+}
+
+// SetWebDAVProperties stores the WebDAV dead properties for a path,
+// the stored properties are removed if the given properties are empty
+func SetWebDAVProperties(props *WebDAVProperties) error {
+	if len(props.Properties) == 0 {
+		return provider.deleteWebDAVProperties(props.Username, props.Path)
+	}
+	props.UpdatedAt = util.GetTimeAsMsSinceEpoch(time.Now())
+	return provider.setWebDAVProperties(props)
+}
+
+// DeleteWebDAVProperties removes the WebDAV dead properties for the given virtual path
+// and all its descendants
+func DeleteWebDAVProperties(username, virtualPath string) error {
+	return provider.deleteWebDAVProperties(username, virtualPath)
+}
+
+// RenameWebDAVProperties moves the WebDAV dead properties for the given virtual path,
+// and all its descendants, to the target path. The properties previously stored for the
+// target path, and its descendants, are removed
+func RenameWebDAVProperties(username, source, target string) error {
+	return provider.renameWebDAVProperties(username, source, target)
+}
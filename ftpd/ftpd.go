@@ -181,9 +181,10 @@ type Configuration struct {
 	// decrypting the file
 	HASHSupport int `json:"hash_support" mapstructure:"hash_support"`
 	// Set to 1 to enable support for the non standard "COMB" FTP command.
-	// Combine is only supported for local filesystem, for cloud backends it has
-	// no advantage as it will download the partial files and will upload the
-	// combined one. Cloud backends natively support multipart uploads.
+	// Combine is supported for local filesystem and for S3, Google Cloud Storage
+	// and Azure Blob backends. Cloud backends combine the partial files server
+	// side, without downloading them. The partial files and the combined one
+	// must be within the same folder.
 	CombineSupport int `json:"combine_support" mapstructure:"combine_support"`
 	// Port Range for data connections. Random if not specified
 	PassivePortRange PortRange `json:"passive_port_range" mapstructure:"passive_port_range"`
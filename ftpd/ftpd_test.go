@@ -665,6 +665,21 @@ func TestLoginNonExistentUser(t *testing.T) {
 	assert.Error(t, err)
 }
 
+func TestLoginPasswordChangeRequired(t *testing.T) {
+	u := getTestUser()
+	u.Filters.RequirePasswordChange = true
+	user, _, err := httpdtest.AddUser(u, http.StatusCreated)
+	assert.NoError(t, err)
+	_, err = getFTPClient(user, false, nil)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), dataprovider.ErrPasswordChangeRequired.Error())
+	}
+	_, err = httpdtest.RemoveUser(user, http.StatusOK)
+	assert.NoError(t, err)
+	err = os.RemoveAll(user.GetHomeDir())
+	assert.NoError(t, err)
+}
+
 func TestLoginExternalAuth(t *testing.T) {
 	if runtime.GOOS == osWindows {
 		t.Skip("this test is not available on Windows")
@@ -1376,6 +1391,230 @@ func TestSFTPBuffered(t *testing.T) {
 	assert.NoError(t, err)
 }
 
+func TestFTPFs(t *testing.T) {
+	u := getTestUser()
+	localUser, _, err := httpdtest.AddUser(u, http.StatusCreated)
+	assert.NoError(t, err)
+	for _, tlsMode := range []int{0, 1, 2} {
+		u = getTestFTPUser()
+		u.QuotaFiles = 100
+		u.FsConfig.FTPConfig.TLSMode = tlsMode
+		if tlsMode > 0 {
+			u.FsConfig.FTPConfig.SkipTLSVerify = true
+		}
+		if tlsMode == 2 {
+			u.FsConfig.FTPConfig.Endpoint = ftpSrvAddrTLS
+		}
+		ftpUser, _, err := httpdtest.AddUser(u, http.StatusCreated)
+		assert.NoError(t, err)
+		client, err := getFTPClient(ftpUser, true, nil)
+		if assert.NoError(t, err) {
+			testFilePath := filepath.Join(homeBasePath, testFileName)
+			testFileSize := int64(65535)
+			err = createTestFile(testFilePath, testFileSize)
+			assert.NoError(t, err)
+			err = checkBasicFTP(client)
+			assert.NoError(t, err)
+			err = ftpUploadFile(testFilePath, testFileName, testFileSize, client, 0)
+			assert.NoError(t, err)
+			// overwrite an existing file
+			err = ftpUploadFile(testFilePath, testFileName, testFileSize, client, 0)
+			assert.NoError(t, err)
+			localDownloadPath := filepath.Join(homeBasePath, testDLFileName)
+			err = ftpDownloadFile(testFileName, localDownloadPath, testFileSize, client, 0)
+			assert.NoError(t, err)
+			user, _, err := httpdtest.GetUserByUsername(ftpUser.Username, http.StatusOK)
+			assert.NoError(t, err)
+			assert.Equal(t, 1, user.UsedQuotaFiles)
+			assert.Equal(t, testFileSize, user.UsedQuotaSize)
+			info, err := os.Stat(filepath.Join(localUser.GetHomeDir(), testFileName))
+			if assert.NoError(t, err) {
+				assert.Equal(t, testFileSize, info.Size())
+			}
+			err = client.Delete(testFileName)
+			assert.NoError(t, err)
+			// resume an upload, for this backend uploads can be resumed only from the end of the file
+			data := []byte("test data")
+			err = os.WriteFile(testFilePath, data, os.ModePerm)
+			assert.NoError(t, err)
+			err = ftpUploadFile(testFilePath, testFileName, int64(len(data)), client, 0)
+			assert.NoError(t, err)
+			err = ftpUploadFile(testFilePath, testFileName, int64(len(data)+5), client, 5)
+			assert.Error(t, err)
+			err = ftpUploadFile(testFilePath, testFileName, int64(2*len(data)), client, uint64(len(data)))
+			assert.NoError(t, err)
+			readed, err := os.ReadFile(filepath.Join(localUser.GetHomeDir(), testFileName))
+			assert.NoError(t, err)
+			assert.Equal(t, "test datatest data", string(readed))
+			err = ftpDownloadFile(testFileName, localDownloadPath, int64(len(data)+4), client, 5)
+			assert.NoError(t, err)
+			readed, err = os.ReadFile(localDownloadPath)
+			assert.NoError(t, err)
+			assert.Equal(t, []byte("datatest data"), readed)
+			// append to the file
+			srcFile, err := os.Open(testFilePath)
+			if assert.NoError(t, err) {
+				err = client.Append(testFileName, srcFile)
+				assert.NoError(t, err)
+				err = srcFile.Close()
+				assert.NoError(t, err)
+				size, err := client.FileSize(testFileName)
+				assert.NoError(t, err)
+				assert.Equal(t, int64(3*len(data)), size)
+			}
+			user, _, err = httpdtest.GetUserByUsername(ftpUser.Username, http.StatusOK)
+			assert.NoError(t, err)
+			assert.Equal(t, 1, user.UsedQuotaFiles)
+			assert.Equal(t, int64(3*len(data)), user.UsedQuotaSize)
+			// directories and rename
+			testDir := "ftpdir"
+			err = client.MakeDir(testDir)
+			assert.NoError(t, err)
+			err = client.Rename(testFileName, path.Join(testDir, testFileName))
+			assert.NoError(t, err)
+			entries, err := client.List(testDir)
+			assert.NoError(t, err)
+			found := false
+			for _, entry := range entries {
+				if entry.Name == testFileName {
+					found = true
+					assert.Equal(t, ftp.EntryTypeFile, entry.Type)
+					assert.Equal(t, uint64(3*len(data)), entry.Size)
+				}
+			}
+			assert.True(t, found)
+			_, err = client.FileSize(testFileName)
+			assert.Error(t, err)
+			err = client.RemoveDir(testDir)
+			assert.Error(t, err)
+			err = client.Delete(path.Join(testDir, testFileName))
+			assert.NoError(t, err)
+			err = client.RemoveDir(testDir)
+			assert.NoError(t, err)
+			_, err = os.Stat(filepath.Join(localUser.GetHomeDir(), testDir))
+			assert.True(t, os.IsNotExist(err))
+			user, _, err = httpdtest.GetUserByUsername(ftpUser.Username, http.StatusOK)
+			assert.NoError(t, err)
+			assert.Equal(t, 0, user.UsedQuotaFiles)
+			assert.Equal(t, int64(0), user.UsedQuotaSize)
+
+			err = os.Remove(testFilePath)
+			assert.NoError(t, err)
+			err = os.Remove(localDownloadPath)
+			assert.NoError(t, err)
+			err = client.Quit()
+			assert.NoError(t, err)
+		}
+		_, err = httpdtest.RemoveUser(ftpUser, http.StatusOK)
+		assert.NoError(t, err)
+		err = os.RemoveAll(ftpUser.GetHomeDir())
+		assert.NoError(t, err)
+	}
+	// invalid credentials
+	u = getTestFTPUser()
+	u.FsConfig.FTPConfig.Password = kms.NewPlainSecret("wrong password")
+	ftpUser, _, err := httpdtest.AddUser(u, http.StatusCreated)
+	assert.NoError(t, err)
+	_, err = getFTPClient(ftpUser, true, nil)
+	assert.Error(t, err)
+	_, err = httpdtest.RemoveUser(ftpUser, http.StatusOK)
+	assert.NoError(t, err)
+
+	_, err = httpdtest.RemoveUser(localUser, http.StatusOK)
+	assert.NoError(t, err)
+	err = os.RemoveAll(localUser.GetHomeDir())
+	assert.NoError(t, err)
+}
+
+func TestFTPFsVirtualFolder(t *testing.T) {
+	u := getTestUser()
+	localUser, _, err := httpdtest.AddUser(u, http.StatusCreated)
+	assert.NoError(t, err)
+	u = getTestUser()
+	u.Username += "_1"
+	u.QuotaFiles = 100
+	mappedPath := filepath.Join(os.TempDir(), "ftpfs")
+	folderName := filepath.Base(mappedPath)
+	vdirPath := "/vdir/ftp"
+	u.VirtualFolders = append(u.VirtualFolders, vfs.VirtualFolder{
+		BaseVirtualFolder: vfs.BaseVirtualFolder{
+			Name: folderName,
+			FsConfig: vfs.Filesystem{
+				Provider: sdk.FTPFilesystemProvider,
+				FTPConfig: vfs.FTPFsConfig{
+					FTPFsConfig: sdk.FTPFsConfig{
+						Endpoint: ftpServerAddr,
+						Username: defaultUsername,
+						Password: kms.NewPlainSecret(defaultPassword),
+						Prefix:   "/prefix",
+						TLSMode:  1,
+						// the test certificate is for localhost
+						SkipTLSVerify: true,
+					},
+				},
+			},
+			MappedPath: mappedPath,
+		},
+		VirtualPath: vdirPath,
+		QuotaFiles:  -1,
+		QuotaSize:   -1,
+	})
+	user, _, err := httpdtest.AddUser(u, http.StatusCreated)
+	assert.NoError(t, err)
+	client, err := getFTPClient(user, true, nil)
+	if assert.NoError(t, err) {
+		testFilePath := filepath.Join(homeBasePath, testFileName)
+		testFileSize := int64(131072)
+		err = createTestFile(testFilePath, testFileSize)
+		assert.NoError(t, err)
+		err = ftpUploadFile(testFilePath, path.Join(vdirPath, testFileName), testFileSize, client, 0)
+		assert.NoError(t, err)
+		info, err := os.Stat(filepath.Join(localUser.GetHomeDir(), "prefix", testFileName))
+		if assert.NoError(t, err) {
+			assert.Equal(t, testFileSize, info.Size())
+		}
+		localDownloadPath := filepath.Join(homeBasePath, testDLFileName)
+		err = ftpDownloadFile(path.Join(vdirPath, testFileName), localDownloadPath, testFileSize, client, 0)
+		assert.NoError(t, err)
+		// a path outside the prefix cannot be reached
+		_, err = client.FileSize(path.Join(vdirPath, "..", "..", testFileName))
+		assert.Error(t, err)
+		err = client.Rename(path.Join(vdirPath, testFileName), path.Join(vdirPath, testFileName+"_1"))
+		assert.NoError(t, err)
+		folder, _, err := httpdtest.GetFolderByName(folderName, http.StatusOK)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, folder.UsedQuotaFiles)
+		assert.Equal(t, testFileSize, folder.UsedQuotaSize)
+		user, _, err = httpdtest.GetUserByUsername(user.Username, http.StatusOK)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, user.UsedQuotaFiles)
+		assert.Equal(t, testFileSize, user.UsedQuotaSize)
+		err = client.Delete(path.Join(vdirPath, testFileName+"_1"))
+		assert.NoError(t, err)
+		_, err = os.Stat(filepath.Join(localUser.GetHomeDir(), "prefix", testFileName+"_1"))
+		assert.True(t, os.IsNotExist(err))
+
+		err = os.Remove(testFilePath)
+		assert.NoError(t, err)
+		err = os.Remove(localDownloadPath)
+		assert.NoError(t, err)
+		err = client.Quit()
+		assert.NoError(t, err)
+	}
+	_, err = httpdtest.RemoveUser(user, http.StatusOK)
+	assert.NoError(t, err)
+	_, err = httpdtest.RemoveFolder(vfs.BaseVirtualFolder{Name: folderName}, http.StatusOK)
+	assert.NoError(t, err)
+	_, err = httpdtest.RemoveUser(localUser, http.StatusOK)
+	assert.NoError(t, err)
+	err = os.RemoveAll(localUser.GetHomeDir())
+	assert.NoError(t, err)
+	err = os.RemoveAll(user.GetHomeDir())
+	assert.NoError(t, err)
+	err = os.RemoveAll(mappedPath)
+	assert.NoError(t, err)
+}
+
 func TestResume(t *testing.T) {
 	u := getTestUser()
 	localUser, _, err := httpdtest.AddUser(u, http.StatusCreated)
@@ -3069,6 +3308,16 @@ func getTestSFTPUser() dataprovider.User {
 	return u
 }
 
+func getTestFTPUser() dataprovider.User {
+	u := getTestUser()
+	u.Username = u.Username + "_ftp"
+	u.FsConfig.Provider = sdk.FTPFilesystemProvider
+	u.FsConfig.FTPConfig.Endpoint = ftpServerAddr
+	u.FsConfig.FTPConfig.Username = defaultUsername
+	u.FsConfig.FTPConfig.Password = kms.NewPlainSecret(defaultPassword)
+	return u
+}
+
 func getExtAuthScriptContent(user dataprovider.User, nonJSONResponse bool, username string) []byte {
 	extAuthContent := []byte("#!/bin/sh\n\n")
 	extAuthContent = append(extAuthContent, []byte(fmt.Sprintf("if test \"$SFTPGO_AUTHD_USERNAME\" = \"%v\"; then\n", user.Username))...)
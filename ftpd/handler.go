@@ -21,6 +21,7 @@ import (
 var (
 	errNotImplemented   = errors.New("not implemented")
 	errCOMBNotSupported = errors.New("COMB is not supported for this filesystem")
+	errCOMBReadWrite    = errors.New("reading or writing is not supported while combining files server side")
 )
 
 // Connection details for an FTP connection.
@@ -28,6 +29,22 @@ var (
 type Connection struct {
 	*common.BaseConnection
 	clientContext ftpserver.ClientContext
+	// the pending COMB command, if combined server side
+	combine *combineTransfer
+}
+
+func (c *Connection) setCombineTransfer(t *combineTransfer) {
+	c.Lock()
+	defer c.Unlock()
+
+	c.combine = t
+}
+
+func (c *Connection) getCombineTransfer() *combineTransfer {
+	c.RLock()
+	defer c.RUnlock()
+
+	return c.combine
 }
 
 func (c *Connection) getFTPMode() string {
@@ -121,6 +138,11 @@ func (c *Connection) Remove(name string) error {
 		c.Log(logger.LevelDebug, "cannot remove %#v is not a file/symlink", p)
 		return c.GetGenericError(nil)
 	}
+	if combine := c.getCombineTransfer(); combine != nil && combine.isSource(name) {
+		// the source files are removed after combining them server side
+		c.Log(logger.LevelDebug, "remove for %#v deferred, it is a source for the pending combine", name)
+		return nil
+	}
 	return c.RemoveFile(fs, p, name, fi)
 }
 
@@ -306,7 +328,10 @@ func (c *Connection) GetHandle(name string, flags int, offset int64) (ftpserver.
 	}
 
 	if c.GetCommand() == "COMB" && !vfs.IsLocalOsFs(fs) {
-		return nil, errCOMBNotSupported
+		if _, ok := fs.(vfs.FsFileCombiner); !ok {
+			return nil, errCOMBNotSupported
+		}
+		return c.getCombineHandle(name, flags)
 	}
 
 	if flags&os.O_WRONLY != 0 {
@@ -315,6 +340,23 @@ func (c *Connection) GetHandle(name string, flags int, offset int64) (ftpserver.
 	return c.downloadFile(fs, p, name, offset)
 }
 
+// getCombineHandle returns the handles for a COMB command combined server side.
+// ftpserverlib opens the target file for writing and then the source files for reading
+func (c *Connection) getCombineHandle(name string, flags int) (ftpserver.FileTransfer, error) {
+	if flags&os.O_WRONLY != 0 {
+		t := &combineTransfer{
+			connection: c,
+			ftpPath:    name,
+		}
+		c.setCombineTransfer(t)
+		return t, nil
+	}
+	if c.getCombineTransfer() == nil {
+		return nil, errCOMBNotSupported
+	}
+	return &combineSource{ftpPath: name}, nil
+}
+
 func (c *Connection) downloadFile(fs vfs.Fs, fsPath, ftpPath string, offset int64) (ftpserver.FileTransfer, error) {
 	if !c.User.HasPerm(dataprovider.PermDownload, path.Dir(ftpPath)) {
 		return nil, c.GetPermissionDeniedError()
@@ -325,6 +367,12 @@ func (c *Connection) downloadFile(fs vfs.Fs, fsPath, ftpPath string, offset int6
 		return nil, c.GetPermissionDeniedError()
 	}
 
+	transferQuota := c.GetTransferQuota(ftpPath)
+	if !transferQuota.HasDownloadSpace() {
+		c.Log(logger.LevelInfo, "denying file read due to quota limits")
+		return nil, c.GetReadQuotaExceededError()
+	}
+
 	if err := common.ExecutePreAction(&c.User, common.OperationPreDownload, fsPath, ftpPath, c.GetProtocol(), c.GetRemoteIP(),
 		0, 0); err != nil {
 		c.Log(logger.LevelDebug, "download for file %#v denied by pre action: %v", ftpPath, err)
@@ -338,7 +386,7 @@ func (c *Connection) downloadFile(fs vfs.Fs, fsPath, ftpPath string, offset int6
 	}
 
 	baseTransfer := common.NewBaseTransfer(file, c.BaseConnection, cancelFn, fsPath, fsPath, ftpPath, common.TransferDownload,
-		0, 0, 0, false, fs)
+		0, 0, 0, false, fs, transferQuota)
 	baseTransfer.SetFtpMode(c.getFTPMode())
 	t := newTransfer(baseTransfer, nil, r, offset)
 
@@ -361,7 +409,7 @@ func (c *Connection) uploadFile(fs vfs.Fs, fsPath, ftpPath string, flags int) (f
 		if !c.User.HasPerm(dataprovider.PermUpload, path.Dir(ftpPath)) {
 			return nil, fmt.Errorf("%w, no upload permission", ftpserver.ErrFileNameNotAllowed)
 		}
-		return c.handleFTPUploadToNewFile(fs, fsPath, filePath, ftpPath)
+		return c.handleFTPUploadToNewFile(fs, flags, fsPath, filePath, ftpPath)
 	}
 
 	if statErr != nil {
@@ -382,18 +430,30 @@ func (c *Connection) uploadFile(fs vfs.Fs, fsPath, ftpPath string, flags int) (f
 	return c.handleFTPUploadToExistingFile(fs, flags, fsPath, filePath, stat.Size(), ftpPath)
 }
 
-func (c *Connection) handleFTPUploadToNewFile(fs vfs.Fs, resolvedPath, filePath, requestPath string) (ftpserver.FileTransfer, error) {
+func (c *Connection) handleFTPUploadToNewFile(fs vfs.Fs, flags int, resolvedPath, filePath,
+	requestPath string) (ftpserver.FileTransfer, error) {
 	quotaResult := c.HasSpace(true, false, requestPath)
 	if !quotaResult.HasSpace {
 		c.Log(logger.LevelInfo, "denying file write due to quota limits")
 		return nil, ftpserver.ErrStorageExceeded
 	}
+	transferQuota := c.GetTransferQuota(requestPath)
+	if !transferQuota.HasUploadSpace() {
+		c.Log(logger.LevelInfo, "denying file write due to transfer quota limits")
+		return nil, ftpserver.ErrStorageExceeded
+	}
 	if err := common.ExecutePreAction(&c.User, common.OperationPreUpload, resolvedPath, requestPath, c.GetProtocol(),
 		c.GetRemoteIP(), 0, 0); err != nil {
 		c.Log(logger.LevelDebug, "upload for file %#v denied by pre action: %v", requestPath, err)
 		return nil, fmt.Errorf("%w, denied by pre-upload action", ftpserver.ErrFileNameNotAllowed)
 	}
-	file, w, cancelFn, err := fs.Create(filePath, 0)
+	createFlags := 0
+	// interrupted uploads to Cloud Storage backends are not visible as files, we
+	// resume them for REST+STOR, the REST offset must match the uploaded size
+	if flags&os.O_CREATE != 0 && flags&(os.O_TRUNC|os.O_APPEND) == 0 && vfs.HasPendingUploadResume(fs) {
+		createFlags = flags
+	}
+	file, w, cancelFn, err := fs.Create(filePath, createFlags)
 	if err != nil {
 		c.Log(logger.LevelWarn, "error creating file %#v: %+v", resolvedPath, err)
 		return nil, c.GetFsError(fs, err)
@@ -403,9 +463,21 @@ func (c *Connection) handleFTPUploadToNewFile(fs vfs.Fs, resolvedPath, filePath,
 
 	// we can get an error only for resume
 	maxWriteSize, _ := c.GetMaxWriteSize(quotaResult, false, 0, fs.IsUploadResumeSupported())
+	minWriteOffset := int64(0)
+	if w != nil && w.GetOffset() > 0 {
+		minWriteOffset = w.GetOffset()
+		c.Log(logger.LevelDebug, "resuming interrupted upload, file path: %#v offset: %v", filePath, minWriteOffset)
+		maxWriteSize, err = c.GetMaxWriteSizeForPendingUpload(maxWriteSize, minWriteOffset)
+		if err != nil {
+			c.Log(logger.LevelInfo, "denying resume for interrupted upload due to quota limits")
+			cancelFn()
+			w.Close() //nolint:errcheck
+			return nil, ftpserver.ErrStorageExceeded
+		}
+	}
 
 	baseTransfer := common.NewBaseTransfer(file, c.BaseConnection, cancelFn, resolvedPath, filePath, requestPath,
-		common.TransferUpload, 0, 0, maxWriteSize, true, fs)
+		common.TransferUpload, minWriteOffset, 0, maxWriteSize, true, fs, transferQuota)
 	baseTransfer.SetFtpMode(c.getFTPMode())
 	t := newTransfer(baseTransfer, w, nil, 0)
 
@@ -420,6 +492,11 @@ func (c *Connection) handleFTPUploadToExistingFile(fs vfs.Fs, flags int, resolve
 		c.Log(logger.LevelInfo, "denying file write due to quota limits")
 		return nil, ftpserver.ErrStorageExceeded
 	}
+	transferQuota := c.GetTransferQuota(requestPath)
+	if !transferQuota.HasUploadSpace() {
+		c.Log(logger.LevelInfo, "denying file write due to transfer quota limits")
+		return nil, ftpserver.ErrStorageExceeded
+	}
 	minWriteOffset := int64(0)
 	// ftpserverlib sets:
 	// - os.O_WRONLY | os.O_APPEND for APPE and COMB
@@ -482,7 +559,7 @@ func (c *Connection) handleFTPUploadToExistingFile(fs vfs.Fs, flags int, resolve
 	vfs.SetPathPermissions(fs, filePath, c.User.GetUID(), c.User.GetGID())
 
 	baseTransfer := common.NewBaseTransfer(file, c.BaseConnection, cancelFn, resolvedPath, filePath, requestPath,
-		common.TransferUpload, minWriteOffset, initialSize, maxWriteSize, false, fs)
+		common.TransferUpload, minWriteOffset, initialSize, maxWriteSize, false, fs, transferQuota)
 	baseTransfer.SetFtpMode(c.getFTPMode())
 	t := newTransfer(baseTransfer, w, nil, 0)
 
@@ -1,9 +1,11 @@
 package ftpd
 
 import (
+	"bytes"
 	"crypto/tls"
 	"crypto/x509"
 	"fmt"
+	"io"
 	"net"
 	"os"
 	"path/filepath"
@@ -366,6 +368,62 @@ func newMockOsFs(err, statErr error, atomicUpload bool, connectionID, rootDir st
 	}
 }
 
+// mockCloudFs simulates a Cloud Storage filesystem with an interrupted upload.
+// The interrupted upload is not visible as a file and it is resumed if Create
+// is called with a resume flag, otherwise it is discarded
+type mockCloudFs struct {
+	vfs.Fs
+	pendingSize int64
+	createFlags int
+}
+
+// Name returns the name for the Fs implementation
+func (fs *mockCloudFs) Name() string {
+	return "mockCloudFs"
+}
+
+// IsUploadResumeSupported returns true if resuming uploads is supported
+func (*mockCloudFs) IsUploadResumeSupported() bool {
+	return true
+}
+
+// IsAtomicUploadSupported returns true if atomic upload is supported
+func (*mockCloudFs) IsAtomicUploadSupported() bool {
+	return false
+}
+
+// Stat returns a FileInfo describing the named file
+func (*mockCloudFs) Stat(name string) (os.FileInfo, error) {
+	return nil, os.ErrNotExist
+}
+
+// Lstat returns a FileInfo describing the named file
+func (*mockCloudFs) Lstat(name string) (os.FileInfo, error) {
+	return nil, os.ErrNotExist
+}
+
+// Create simulates an upload to a Cloud Storage backend
+func (fs *mockCloudFs) Create(name string, flag int) (vfs.File, *vfs.PipeWriter, func(), error) {
+	fs.createFlags = flag
+	r, w, err := pipeat.Pipe()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	var p *vfs.PipeWriter
+	if flag > 0 && flag&os.O_TRUNC == 0 {
+		p = vfs.NewPipeWriterAtOffset(w, fs.pendingSize)
+	} else {
+		fs.pendingSize = 0
+		p = vfs.NewPipeWriter(w)
+	}
+	go func() {
+		_, err := io.Copy(io.Discard, r)
+		r.Close() //nolint:errcheck
+		p.Done(err)
+	}()
+	return nil, p, func() {}, nil
+}
+
 func TestInitialization(t *testing.T) {
 	oldMgr := certMgr
 	certMgr = nil
@@ -780,6 +838,71 @@ func TestUploadOverwriteErrors(t *testing.T) {
 	assert.Error(t, err)
 }
 
+func TestUploadInterruptedCloudUpload(t *testing.T) {
+	user := dataprovider.User{
+		BaseUser: sdk.BaseUser{
+			Username: "user",
+			HomeDir:  filepath.Clean(os.TempDir()),
+		},
+	}
+	// upload only permissions, existing files cannot be overwritten
+	user.Permissions = make(map[string][]string)
+	user.Permissions["/"] = []string{dataprovider.PermListItems, dataprovider.PermUpload}
+	mockCC := mockFTPClientContext{}
+	connID := fmt.Sprintf("%v", mockCC.ID())
+	fs := &mockCloudFs{
+		Fs:          vfs.NewOsFs(connID, user.GetHomeDir(), ""),
+		pendingSize: 1024,
+	}
+	connection := &Connection{
+		BaseConnection: common.NewBaseConnection(connID, common.ProtocolFTP, "", "", user),
+		clientContext:  mockCC,
+	}
+	fsPath := filepath.Join(user.GetHomeDir(), "file.dat")
+	// REST+STOR resumes the interrupted upload
+	tr, err := connection.uploadFile(fs, fsPath, "/file.dat", os.O_WRONLY|os.O_CREATE)
+	require.NoError(t, err)
+	upload := tr.(*transfer)
+	assert.Equal(t, os.O_WRONLY|os.O_CREATE, fs.createFlags)
+	assert.Equal(t, int64(1024), upload.MinWriteOffset)
+	assert.Equal(t, int64(0), upload.InitialSize)
+	pos, err := upload.Seek(1024, io.SeekStart)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1024), pos)
+	_, err = upload.Write([]byte("data"))
+	assert.NoError(t, err)
+	err = upload.Close()
+	assert.NoError(t, err)
+	// the REST offset must match the uploaded size
+	tr, err = connection.uploadFile(fs, fsPath, "/file.dat", os.O_WRONLY|os.O_CREATE)
+	require.NoError(t, err)
+	upload = tr.(*transfer)
+	_, err = upload.Seek(512, io.SeekStart)
+	assert.Error(t, err)
+	err = upload.Close()
+	assert.Error(t, err)
+	// STOR and APPE retry the upload from the beginning
+	for _, flags := range []int{os.O_WRONLY | os.O_CREATE | os.O_TRUNC, os.O_WRONLY | os.O_APPEND} {
+		fs.pendingSize = 1024
+		tr, err = connection.uploadFile(fs, fsPath, "/file.dat", flags)
+		require.NoError(t, err)
+		upload = tr.(*transfer)
+		assert.Equal(t, 0, fs.createFlags)
+		assert.Equal(t, int64(0), fs.pendingSize)
+		assert.Equal(t, int64(0), upload.MinWriteOffset)
+		_, err = upload.Write([]byte("data"))
+		assert.NoError(t, err)
+		err = upload.Close()
+		assert.NoError(t, err)
+	}
+	// the quota is checked for the whole file
+	connection.User.Filters.MaxUploadFileSize = 1024
+	fs.pendingSize = 1024
+	_, err = connection.uploadFile(fs, fsPath, "/file.dat", os.O_WRONLY|os.O_CREATE)
+	assert.ErrorIs(t, err, ftpserver.ErrStorageExceeded)
+	assert.Len(t, connection.GetTransfers(), 0)
+}
+
 func TestTransferErrors(t *testing.T) {
 	testfile := "testfile"
 	file, err := os.Create(testfile)
@@ -800,7 +923,7 @@ func TestTransferErrors(t *testing.T) {
 		clientContext:  mockCC,
 	}
 	baseTransfer := common.NewBaseTransfer(file, connection.BaseConnection, nil, file.Name(), file.Name(), testfile,
-		common.TransferDownload, 0, 0, 0, false, fs)
+		common.TransferDownload, 0, 0, 0, false, fs, dataprovider.TransferQuota{})
 	tr := newTransfer(baseTransfer, nil, nil, 0)
 	err = tr.Close()
 	assert.NoError(t, err)
@@ -818,7 +941,7 @@ func TestTransferErrors(t *testing.T) {
 	r, _, err := pipeat.Pipe()
 	assert.NoError(t, err)
 	baseTransfer = common.NewBaseTransfer(nil, connection.BaseConnection, nil, testfile, testfile, testfile,
-		common.TransferUpload, 0, 0, 0, false, fs)
+		common.TransferUpload, 0, 0, 0, false, fs, dataprovider.TransferQuota{})
 	tr = newTransfer(baseTransfer, nil, r, 10)
 	pos, err := tr.Seek(10, 0)
 	assert.NoError(t, err)
@@ -830,7 +953,7 @@ func TestTransferErrors(t *testing.T) {
 	assert.NoError(t, err)
 	pipeWriter := vfs.NewPipeWriter(w)
 	baseTransfer = common.NewBaseTransfer(nil, connection.BaseConnection, nil, testfile, testfile, testfile,
-		common.TransferUpload, 0, 0, 0, false, fs)
+		common.TransferUpload, 0, 0, 0, false, fs, dataprovider.TransferQuota{})
 	tr = newTransfer(baseTransfer, pipeWriter, nil, 0)
 
 	err = r.Close()
@@ -846,10 +969,100 @@ func TestTransferErrors(t *testing.T) {
 	if assert.Error(t, err) {
 		assert.EqualError(t, err, common.ErrOpUnsupported.Error())
 	}
+
+	r, w, err = pipeat.Pipe()
+	assert.NoError(t, err)
+	pipeWriter = vfs.NewPipeWriterAtOffset(w, 10)
+	baseTransfer = common.NewBaseTransfer(nil, connection.BaseConnection, nil, testfile, testfile, testfile,
+		common.TransferUpload, 10, 10, 0, false, fs, dataprovider.TransferQuota{})
+	tr = newTransfer(baseTransfer, pipeWriter, nil, 0)
+	pos, err = tr.Seek(10, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(10), pos)
+	_, err = tr.Seek(5, 0)
+	if assert.Error(t, err) {
+		assert.EqualError(t, err, common.ErrOpUnsupported.Error())
+	}
+	_, err = pipeWriter.WriteAt([]byte("data"), 5)
+	assert.Error(t, err)
+	err = r.Close()
+	assert.NoError(t, err)
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		pipeWriter.Done(nil)
+	}()
+	err = tr.closeIO()
+	assert.NoError(t, err)
 	err = os.Remove(testfile)
 	assert.NoError(t, err)
 }
 
+func TestCombineTransfer(t *testing.T) {
+	user := dataprovider.User{
+		BaseUser: sdk.BaseUser{
+			Username: "user",
+			HomeDir:  filepath.Clean(os.TempDir()),
+		},
+	}
+	user.Permissions = make(map[string][]string)
+	user.Permissions["/"] = []string{dataprovider.PermAny}
+	mockCC := mockFTPClientContext{}
+	connID := fmt.Sprintf("%v", mockCC.ID())
+	connection := &Connection{
+		BaseConnection: common.NewBaseConnection(connID, common.ProtocolFTP, "", "", user),
+		clientContext:  mockCC,
+	}
+	_, err := connection.getCombineHandle("/file.1", os.O_RDONLY)
+	assert.ErrorIs(t, err, errCOMBNotSupported)
+
+	handle, err := connection.getCombineHandle("/file", os.O_WRONLY|os.O_CREATE)
+	assert.NoError(t, err)
+	tr, ok := handle.(*combineTransfer)
+	if assert.True(t, ok) {
+		assert.Equal(t, tr, connection.getCombineTransfer())
+		_, err = tr.Write([]byte("data"))
+		assert.ErrorIs(t, err, errCOMBReadWrite)
+		_, err = tr.Read(make([]byte, 10))
+		assert.ErrorIs(t, err, errCOMBReadWrite)
+		_, err = tr.Seek(10, io.SeekStart)
+		assert.ErrorIs(t, err, common.ErrOpUnsupported)
+		_, err = tr.ReadFrom(bytes.NewBuffer([]byte("data")))
+		assert.ErrorIs(t, err, errCOMBReadWrite)
+
+		for _, name := range []string{"/file.1", "/file.2"} {
+			src, err := connection.getCombineHandle(name, os.O_RDONLY)
+			assert.NoError(t, err)
+			_, err = src.Read(make([]byte, 10))
+			assert.ErrorIs(t, err, errCOMBReadWrite)
+			_, err = src.Write([]byte("data"))
+			assert.ErrorIs(t, err, errCOMBReadWrite)
+			_, err = src.Seek(0, io.SeekStart)
+			assert.ErrorIs(t, err, common.ErrOpUnsupported)
+			n, err := io.Copy(tr, src)
+			assert.NoError(t, err)
+			assert.Equal(t, int64(0), n)
+			err = src.Close()
+			assert.NoError(t, err)
+		}
+		assert.True(t, tr.isSource("/file.1"))
+		assert.True(t, tr.isSource("/file.2"))
+		assert.False(t, tr.isSource("/file"))
+		// the local filesystem does not combine files server side
+		err = tr.Close()
+		assert.ErrorIs(t, err, common.ErrOpUnsupported)
+		assert.Nil(t, connection.getCombineTransfer())
+		err = tr.Close()
+		assert.ErrorIs(t, err, common.ErrTransferClosed)
+		_, err = tr.ReadFrom(&combineSource{ftpPath: "/file.3"})
+		assert.ErrorIs(t, err, errCOMBReadWrite)
+	}
+	// nothing to combine
+	handle, err = connection.getCombineHandle("/file", os.O_WRONLY|os.O_APPEND)
+	assert.NoError(t, err)
+	err = handle.Close()
+	assert.NoError(t, err)
+}
+
 func TestVerifyTLSConnection(t *testing.T) {
 	oldCertMgr := certMgr
 
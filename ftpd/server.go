@@ -191,6 +191,9 @@ func (s *Server) AuthUser(cc ftpserver.ClientContext, username, password string)
 	if err != nil {
 		user.Username = username
 		updateLoginMetrics(&user, ipAddr, loginMethod, err)
+		if errors.Is(err, dataprovider.ErrPasswordChangeRequired) {
+			return nil, err
+		}
 		return nil, dataprovider.ErrInvalidCredentials
 	}
 
@@ -8,6 +8,7 @@ import (
 	"github.com/eikenb/pipeat"
 
 	"github.com/drakkan/sftpgo/v2/common"
+	"github.com/drakkan/sftpgo/v2/util"
 	"github.com/drakkan/sftpgo/v2/vfs"
 )
 
@@ -49,6 +50,9 @@ func (t *transfer) Read(p []byte) (n int, err error) {
 	n, err = t.reader.Read(p)
 	atomic.AddInt64(&t.BytesSent, int64(n))
 
+	if err == nil {
+		err = t.CheckRead()
+	}
 	if err != nil && err != io.EOF {
 		t.TransferError(err)
 		return
@@ -64,8 +68,8 @@ func (t *transfer) Write(p []byte) (n int, err error) {
 	n, err = t.writer.Write(p)
 	atomic.AddInt64(&t.BytesReceived, int64(n))
 
-	if t.MaxWriteSize > 0 && err == nil && atomic.LoadInt64(&t.BytesReceived) > t.MaxWriteSize {
-		err = t.Connection.GetQuotaExceededError()
+	if err == nil {
+		err = t.CheckWrite()
 	}
 	if err != nil {
 		t.TransferError(err)
@@ -88,6 +92,11 @@ func (t *transfer) Seek(offset int64, whence int) (int64, error) {
 	if t.reader != nil && t.expectedOffset == offset && whence == io.SeekStart {
 		return offset, nil
 	}
+	// for Cloud Storage filesystems resumed uploads always continue from the end
+	// of the existing object so the requested offset must match
+	if t.writer != nil && t.MinWriteOffset == offset && whence == io.SeekStart {
+		return offset, nil
+	}
 	t.TransferError(errors.New("seek is unsupported for this transfer"))
 	return 0, common.ErrOpUnsupported
 }
@@ -132,3 +141,77 @@ func (t *transfer) setFinished() error {
 	t.isFinished = true
 	return nil
 }
+
+// combineTransfer handles the COMB command for the storage backends that can
+// combine files server side. No data is transferred: ftpserverlib copies each
+// source file to the target using io.Copy, so ReadFrom records the sources and
+// they are combined when the transfer is closed
+type combineTransfer struct {
+	connection *Connection
+	ftpPath    string
+	sources    []string
+	isFinished bool
+}
+
+func (t *combineTransfer) Read(p []byte) (int, error) {
+	return 0, errCOMBReadWrite
+}
+
+func (t *combineTransfer) Write(p []byte) (int, error) {
+	return 0, errCOMBReadWrite
+}
+
+func (t *combineTransfer) Seek(offset int64, whence int) (int64, error) {
+	return 0, common.ErrOpUnsupported
+}
+
+// ReadFrom adds the source file to combine, it is called by io.Copy
+func (t *combineTransfer) ReadFrom(r io.Reader) (int64, error) {
+	t.connection.UpdateLastActivity()
+	src, ok := r.(*combineSource)
+	if !ok || t.isFinished {
+		return 0, errCOMBReadWrite
+	}
+	t.sources = append(t.sources, src.ftpPath)
+	return 0, nil
+}
+
+// isSource returns true if the specified path was added as source file
+func (t *combineTransfer) isSource(ftpPath string) bool {
+	return util.IsStringInSlice(ftpPath, t.sources)
+}
+
+// Close combines the source files into the target one.
+// The source files are removed if the combine succeeds
+func (t *combineTransfer) Close() error {
+	if t.isFinished {
+		return common.ErrTransferClosed
+	}
+	t.isFinished = true
+	t.connection.setCombineTransfer(nil)
+	if len(t.sources) == 0 {
+		return nil
+	}
+	return t.connection.CombineFiles(t.ftpPath, t.sources)
+}
+
+// combineSource is a source file for a COMB command handled by a combineTransfer
+type combineSource struct {
+	ftpPath string
+}
+
+func (s *combineSource) Read(p []byte) (int, error) {
+	return 0, errCOMBReadWrite
+}
+
+func (s *combineSource) Write(p []byte) (int, error) {
+	return 0, errCOMBReadWrite
+}
+
+func (s *combineSource) Seek(offset int64, whence int) (int64, error) {
+	return 0, common.ErrOpUnsupported
+}
+
+func (s *combineSource) Close() error {
+	return nil
+}
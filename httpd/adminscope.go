@@ -0,0 +1,175 @@
+package httpd
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/drakkan/sftpgo/v2/common"
+	"github.com/drakkan/sftpgo/v2/dataprovider"
+	"github.com/drakkan/sftpgo/v2/util"
+	"github.com/drakkan/sftpgo/v2/vfs"
+)
+
+// getCurrentAdmin returns the admin associated with the request token.
+// We always read the admin from the data provider so scope changes apply
+// immediately and not only after a new login
+func getCurrentAdmin(r *http.Request) (dataprovider.Admin, error) {
+	claims, err := getTokenClaims(r)
+	if err != nil || claims.Username == "" {
+		return dataprovider.Admin{}, util.NewValidationError("invalid token claims")
+	}
+	return dataprovider.AdminExists(claims.Username)
+}
+
+// getCurrentAPIAdmin is like getCurrentAdmin but it sends an error response on failure
+func getCurrentAPIAdmin(w http.ResponseWriter, r *http.Request) (dataprovider.Admin, error) {
+	admin, err := getCurrentAdmin(r)
+	if err != nil {
+		if _, ok := err.(*util.ValidationError); ok {
+			sendAPIResponse(w, r, err, "Invalid token claims", http.StatusBadRequest)
+		} else {
+			sendAPIResponse(w, r, err, "", getRespStatus(err))
+		}
+	}
+	return admin, err
+}
+
+// getCurrentWebAdmin is like getCurrentAdmin but it renders an error page on failure
+func getCurrentWebAdmin(w http.ResponseWriter, r *http.Request) (dataprovider.Admin, error) {
+	admin, err := getCurrentAdmin(r)
+	if err != nil {
+		if _, ok := err.(*util.ValidationError); ok {
+			renderBadRequestPage(w, r, err)
+		} else if _, ok := err.(*util.RecordNotFoundError); ok {
+			renderNotFoundPage(w, r, err)
+		} else {
+			renderInternalServerErrorPage(w, r, err)
+		}
+	}
+	return admin, err
+}
+
+// getUserInScope returns the user with the specified username if it is within
+// the admin scope. Users outside the scope are reported as not found
+func getUserInScope(admin *dataprovider.Admin, username string) (dataprovider.User, error) {
+	user, err := dataprovider.UserExists(username)
+	if err != nil {
+		return user, err
+	}
+	if !admin.IsUserInScope(&user) {
+		return dataprovider.User{}, util.NewRecordNotFoundError(fmt.Sprintf("username %#v does not exist", username))
+	}
+	return user, nil
+}
+
+// getFolderInScope returns the folder with the specified name if it is within
+// the admin scope. Folders outside the scope are reported as not found
+func getFolderInScope(admin *dataprovider.Admin, name string) (vfs.BaseVirtualFolder, error) {
+	folder, err := dataprovider.GetFolderByName(name)
+	if err != nil {
+		return folder, err
+	}
+	if !admin.IsFolderInScope(&folder) {
+		return vfs.BaseVirtualFolder{}, util.NewRecordNotFoundError(fmt.Sprintf("folder %#v does not exist", name))
+	}
+	return folder, nil
+}
+
+// getGroupInScope returns the group with the specified name if it is within
+// the admin scope. Groups outside the scope are reported as not found
+func getGroupInScope(admin *dataprovider.Admin, name string) (dataprovider.Group, error) {
+	if !admin.IsGroupInScope(name) {
+		return dataprovider.Group{}, util.NewRecordNotFoundError(fmt.Sprintf("group %#v does not exist", name))
+	}
+	return dataprovider.GroupExists(name)
+}
+
+// checkUserInScope returns a not found error if the specified user is not
+// within the scope of the admin associated with the request
+func checkUserInScope(r *http.Request, user *dataprovider.User) error {
+	admin, err := getCurrentAdmin(r)
+	if err != nil {
+		return err
+	}
+	if !admin.IsUserInScope(user) {
+		return util.NewRecordNotFoundError(fmt.Sprintf("username %#v does not exist", user.Username))
+	}
+	return nil
+}
+
+// checkFolderInScope returns a not found error if the specified folder is not
+// within the scope of the admin associated with the request
+func checkFolderInScope(r *http.Request, folder *vfs.BaseVirtualFolder) error {
+	admin, err := getCurrentAdmin(r)
+	if err != nil {
+		return err
+	}
+	if !admin.IsFolderInScope(folder) {
+		return util.NewRecordNotFoundError(fmt.Sprintf("folder %#v does not exist", folder.Name))
+	}
+	return nil
+}
+
+func isUsernameInScope(admin *dataprovider.Admin, username string) bool {
+	if !admin.HasScope() {
+		return true
+	}
+	_, err := getUserInScope(admin, username)
+	return err == nil
+}
+
+func getConnectionsInScope(admin *dataprovider.Admin) []*common.ConnectionStatus {
+	stats := common.Connections.GetStats()
+	if !admin.HasScope() {
+		return stats
+	}
+	result := make([]*common.ConnectionStatus, 0, len(stats))
+	for _, stat := range stats {
+		if isUsernameInScope(admin, stat.Username) {
+			result = append(result, stat)
+		}
+	}
+	return result
+}
+
+func getUsersQuotaScansInScope(admin *dataprovider.Admin) []common.ActiveQuotaScan {
+	scans := common.QuotaScans.GetUsersQuotaScans()
+	if !admin.HasScope() {
+		return scans
+	}
+	result := make([]common.ActiveQuotaScan, 0, len(scans))
+	for _, scan := range scans {
+		if isUsernameInScope(admin, scan.Username) {
+			result = append(result, scan)
+		}
+	}
+	return result
+}
+
+func getFoldersQuotaScansInScope(admin *dataprovider.Admin) []common.ActiveVirtualFolderQuotaScan {
+	scans := common.QuotaScans.GetVFoldersQuotaScans()
+	if !admin.HasScope() {
+		return scans
+	}
+	result := make([]common.ActiveVirtualFolderQuotaScan, 0, len(scans))
+	for _, scan := range scans {
+		if _, err := getFolderInScope(admin, scan.Name); err == nil {
+			result = append(result, scan)
+		}
+	}
+	return result
+}
+
+func getRetentionChecksInScope(admin *dataprovider.Admin) []common.RetentionCheck {
+	checks := common.RetentionChecks.Get()
+	if !admin.HasScope() {
+		return checks
+	}
+	result := make([]common.RetentionCheck, 0, len(checks))
+	for _, check := range checks {
+		if isUsernameInScope(admin, check.Username) {
+			result = append(result, check)
+		}
+	}
+	return result
+}
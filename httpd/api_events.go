@@ -5,6 +5,8 @@ import (
 	"net/http"
 	"strconv"
 
+	"github.com/go-chi/render"
+
 	"github.com/drakkan/sftpgo/v2/dataprovider"
 	"github.com/drakkan/sftpgo/v2/sdk/plugin"
 	"github.com/drakkan/sftpgo/v2/util"
@@ -67,6 +69,13 @@ func (c *commonEventSearchParams) fromRequest(r *http.Request) error {
 	return nil
 }
 
+func (c *commonEventSearchParams) getOrder() string {
+	if c.Order == 1 {
+		return dataprovider.OrderASC
+	}
+	return dataprovider.OrderDESC
+}
+
 type fsEventSearchParams struct {
 	commonEventSearchParams
 	SSHCmd    string
@@ -119,6 +128,18 @@ func searchFsEvents(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if useEventStore() {
+		events, err := dataprovider.SearchFsEvents(dataprovider.NewFsEventSearch(params.StartTimestamp,
+			params.EndTimestamp, params.Username, params.IP, params.SSHCmd, params.Actions, params.Protocols,
+			params.InstanceIDs, params.ExcludeIDs, params.Statuses, params.Limit, params.getOrder()))
+		if err != nil {
+			sendAPIResponse(w, r, err, "", getRespStatus(err))
+			return
+		}
+		render.JSON(w, r, events)
+		return
+	}
+
 	data, _, _, err := plugin.Handler.SearchFsEvents(params.StartTimestamp, params.EndTimestamp, params.Username,
 		params.IP, params.SSHCmd, params.Actions, params.Protocols, params.InstanceIDs, params.ExcludeIDs,
 		params.Statuses, params.Limit, params.Order)
@@ -141,6 +162,18 @@ func searchProviderEvents(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if useEventStore() {
+		events, err := dataprovider.SearchProviderEvents(dataprovider.NewProviderEventSearch(params.StartTimestamp,
+			params.EndTimestamp, params.Username, params.IP, params.ObjectName, params.Limit, params.getOrder(),
+			params.Actions, params.ObjectTypes, params.InstanceIDs, params.ExcludeIDs))
+		if err != nil {
+			sendAPIResponse(w, r, err, "", getRespStatus(err))
+			return
+		}
+		render.JSON(w, r, events)
+		return
+	}
+
 	data, _, _, err := plugin.Handler.SearchProviderEvents(params.StartTimestamp, params.EndTimestamp, params.Username,
 		params.IP, params.ObjectName, params.Limit, params.Order, params.Actions, params.ObjectTypes, params.InstanceIDs,
 		params.ExcludeIDs)
@@ -152,3 +185,14 @@ func searchProviderEvents(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json; charset=utf-8")
 	w.Write(data) //nolint:errcheck
 }
+
+// useEventStore returns true if events must be searched using the built-in events store.
+// An events searcher plugin, if defined, takes precedence
+func useEventStore() bool {
+	return !plugin.Handler.HasSearcher() && dataprovider.IsEventStoreEnabled()
+}
+
+// isEventSearchAvailable returns true if an events searcher plugin or the built-in events store is enabled
+func isEventSearchAvailable() bool {
+	return plugin.Handler.HasSearcher() || dataprovider.IsEventStoreEnabled()
+}
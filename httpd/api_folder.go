@@ -18,7 +18,11 @@ func getFolders(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	folders, err := dataprovider.GetFolders(limit, offset, order)
+	admin, err := getCurrentAPIAdmin(w, r)
+	if err != nil {
+		return
+	}
+	folders, err := dataprovider.GetFoldersForAdmin(&admin, limit, offset, order)
 	if err == nil {
 		render.JSON(w, r, folders)
 	} else {
@@ -50,13 +54,18 @@ func updateFolder(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	admin, err := getCurrentAPIAdmin(w, r)
+	if err != nil {
+		return
+	}
 	name := getURLParam(r, "name")
-	folder, err := dataprovider.GetFolderByName(name)
+	folder, err := getFolderInScope(&admin, name)
 	if err != nil {
 		sendAPIResponse(w, r, err, "", getRespStatus(err))
 		return
 	}
 	users := folder.Users
+	groups := folder.Groups
 	folderID := folder.ID
 	currentS3AccessSecret := folder.FsConfig.S3Config.AccessSecret
 	currentAzAccountKey := folder.FsConfig.AzBlobConfig.AccountKey
@@ -65,12 +74,20 @@ func updateFolder(w http.ResponseWriter, r *http.Request) {
 	currentCryptoPassphrase := folder.FsConfig.CryptConfig.Passphrase
 	currentSFTPPassword := folder.FsConfig.SFTPConfig.Password
 	currentSFTPKey := folder.FsConfig.SFTPConfig.PrivateKey
+	currentFTPPassword := folder.FsConfig.FTPConfig.Password
+	currentWebDAVPassword := folder.FsConfig.WebDAVConfig.Password
+	currentWebDAVBearerToken := folder.FsConfig.WebDAVConfig.BearerToken
+	currentEncryptionPassphrase := folder.FsConfig.Encryption.Passphrase
 
 	folder.FsConfig.S3Config = vfs.S3FsConfig{}
 	folder.FsConfig.AzBlobConfig = vfs.AzBlobFsConfig{}
 	folder.FsConfig.GCSConfig = vfs.GCSFsConfig{}
 	folder.FsConfig.CryptConfig = vfs.CryptFsConfig{}
 	folder.FsConfig.SFTPConfig = vfs.SFTPFsConfig{}
+	folder.FsConfig.FTPConfig = vfs.FTPFsConfig{}
+	folder.FsConfig.WebDAVConfig = vfs.WebDAVFsConfig{}
+	folder.FsConfig.ReadCache = vfs.ReadCacheConfig{}
+	folder.FsConfig.Encryption = vfs.EncryptionConfig{}
 	err = render.DecodeJSON(r.Body, &folder)
 	if err != nil {
 		sendAPIResponse(w, r, err, "", http.StatusBadRequest)
@@ -78,9 +95,11 @@ func updateFolder(w http.ResponseWriter, r *http.Request) {
 	}
 	folder.ID = folderID
 	folder.Name = name
+	folder.Groups = groups
 	folder.FsConfig.SetEmptySecretsIfNil()
 	updateEncryptedSecrets(&folder.FsConfig, currentS3AccessSecret, currentAzAccountKey, currentAzSASUrl, currentGCSCredentials,
-		currentCryptoPassphrase, currentSFTPPassword, currentSFTPKey)
+		currentCryptoPassphrase, currentSFTPPassword, currentSFTPKey, currentFTPPassword, currentWebDAVPassword,
+		currentWebDAVBearerToken, currentEncryptionPassphrase)
 	err = dataprovider.UpdateFolder(&folder, users, claims.Username, util.GetIPFromRemoteAddress(r.RemoteAddr))
 	if err != nil {
 		sendAPIResponse(w, r, err, "", getRespStatus(err))
@@ -106,7 +125,15 @@ func renderFolder(w http.ResponseWriter, r *http.Request, name string, status in
 
 func getFolderByName(w http.ResponseWriter, r *http.Request) {
 	r.Body = http.MaxBytesReader(w, r.Body, maxRequestSize)
+	admin, err := getCurrentAPIAdmin(w, r)
+	if err != nil {
+		return
+	}
 	name := getURLParam(r, "name")
+	if _, err := getFolderInScope(&admin, name); err != nil {
+		sendAPIResponse(w, r, err, "", getRespStatus(err))
+		return
+	}
 	renderFolder(w, r, name, http.StatusOK)
 }
 
@@ -117,7 +144,15 @@ func deleteFolder(w http.ResponseWriter, r *http.Request) {
 		sendAPIResponse(w, r, err, "Invalid token claims", http.StatusBadRequest)
 		return
 	}
+	admin, err := getCurrentAPIAdmin(w, r)
+	if err != nil {
+		return
+	}
 	name := getURLParam(r, "name")
+	if _, err := getFolderInScope(&admin, name); err != nil {
+		sendAPIResponse(w, r, err, "", getRespStatus(err))
+		return
+	}
 	err = dataprovider.DeleteFolder(name, claims.Username, util.GetIPFromRemoteAddress(r.RemoteAddr))
 	if err != nil {
 		sendAPIResponse(w, r, err, "", getRespStatus(err))
@@ -0,0 +1,182 @@
+package httpd
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/go-chi/render"
+
+	"github.com/drakkan/sftpgo/v2/dataprovider"
+	"github.com/drakkan/sftpgo/v2/util"
+	"github.com/drakkan/sftpgo/v2/vfs"
+)
+
+func getGroups(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxRequestSize)
+	limit, offset, order, err := getSearchFilters(w, r)
+	if err != nil {
+		return
+	}
+
+	admin, err := getCurrentAPIAdmin(w, r)
+	if err != nil {
+		return
+	}
+	groups, err := dataprovider.GetGroupsForAdmin(&admin, limit, offset, order)
+	if err == nil {
+		render.JSON(w, r, groups)
+	} else {
+		sendAPIResponse(w, r, err, "", http.StatusInternalServerError)
+	}
+}
+
+func addGroup(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxRequestSize)
+	claims, err := getTokenClaims(r)
+	if err != nil || claims.Username == "" {
+		sendAPIResponse(w, r, err, "Invalid token claims", http.StatusBadRequest)
+		return
+	}
+	admin, err := getCurrentAPIAdmin(w, r)
+	if err != nil {
+		return
+	}
+	if admin.HasScope() {
+		sendAPIResponse(w, r, nil, "Admins with a scope cannot add groups", http.StatusForbidden)
+		return
+	}
+	var group dataprovider.Group
+	err = render.DecodeJSON(r.Body, &group)
+	if err != nil {
+		sendAPIResponse(w, r, err, "", http.StatusBadRequest)
+		return
+	}
+	err = dataprovider.AddGroup(&group, claims.Username, util.GetIPFromRemoteAddress(r.RemoteAddr))
+	if err != nil {
+		sendAPIResponse(w, r, err, "", getRespStatus(err))
+		return
+	}
+	renderGroup(w, r, group.Name, http.StatusCreated)
+}
+
+func updateGroup(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxRequestSize)
+	claims, err := getTokenClaims(r)
+	if err != nil || claims.Username == "" {
+		sendAPIResponse(w, r, err, "Invalid token claims", http.StatusBadRequest)
+		return
+	}
+
+	admin, err := getCurrentAPIAdmin(w, r)
+	if err != nil {
+		return
+	}
+	name := getURLParam(r, "name")
+	group, err := getGroupInScope(&admin, name)
+	if err != nil {
+		sendAPIResponse(w, r, err, "", getRespStatus(err))
+		return
+	}
+	users := group.Users
+	groupID := group.ID
+	createdAt := group.CreatedAt
+	currentS3AccessSecret := group.UserSettings.FsConfig.S3Config.AccessSecret
+	currentAzAccountKey := group.UserSettings.FsConfig.AzBlobConfig.AccountKey
+	currentAzSASUrl := group.UserSettings.FsConfig.AzBlobConfig.SASURL
+	currentGCSCredentials := group.UserSettings.FsConfig.GCSConfig.Credentials
+	currentCryptoPassphrase := group.UserSettings.FsConfig.CryptConfig.Passphrase
+	currentSFTPPassword := group.UserSettings.FsConfig.SFTPConfig.Password
+	currentSFTPKey := group.UserSettings.FsConfig.SFTPConfig.PrivateKey
+	currentFTPPassword := group.UserSettings.FsConfig.FTPConfig.Password
+	currentWebDAVPassword := group.UserSettings.FsConfig.WebDAVConfig.Password
+	currentWebDAVBearerToken := group.UserSettings.FsConfig.WebDAVConfig.BearerToken
+	currentEncryptionPassphrase := group.UserSettings.FsConfig.Encryption.Passphrase
+
+	group.UserSettings.Permissions = make(map[string][]string)
+	group.UserSettings.FsConfig.S3Config = vfs.S3FsConfig{}
+	group.UserSettings.FsConfig.AzBlobConfig = vfs.AzBlobFsConfig{}
+	group.UserSettings.FsConfig.GCSConfig = vfs.GCSFsConfig{}
+	group.UserSettings.FsConfig.CryptConfig = vfs.CryptFsConfig{}
+	group.UserSettings.FsConfig.SFTPConfig = vfs.SFTPFsConfig{}
+	group.UserSettings.FsConfig.FTPConfig = vfs.FTPFsConfig{}
+	group.UserSettings.FsConfig.WebDAVConfig = vfs.WebDAVFsConfig{}
+	group.UserSettings.FsConfig.ReadCache = vfs.ReadCacheConfig{}
+	group.UserSettings.FsConfig.Encryption = vfs.EncryptionConfig{}
+	group.VirtualFolders = nil
+	err = render.DecodeJSON(r.Body, &group)
+	if err != nil {
+		sendAPIResponse(w, r, err, "", http.StatusBadRequest)
+		return
+	}
+	group.ID = groupID
+	group.Name = name
+	group.CreatedAt = createdAt
+	group.Users = users
+	group.SetEmptySecretsIfNil()
+	updateEncryptedSecrets(&group.UserSettings.FsConfig, currentS3AccessSecret, currentAzAccountKey, currentAzSASUrl,
+		currentGCSCredentials, currentCryptoPassphrase, currentSFTPPassword, currentSFTPKey, currentFTPPassword,
+		currentWebDAVPassword, currentWebDAVBearerToken, currentEncryptionPassphrase)
+	if err := admin.CheckGroupScope(&group); err != nil {
+		sendAPIResponse(w, r, err, "", getRespStatus(err))
+		return
+	}
+	err = dataprovider.UpdateGroup(&group, users, claims.Username, util.GetIPFromRemoteAddress(r.RemoteAddr))
+	if err != nil {
+		sendAPIResponse(w, r, err, "", getRespStatus(err))
+		return
+	}
+	sendAPIResponse(w, r, nil, "Group updated", http.StatusOK)
+}
+
+func renderGroup(w http.ResponseWriter, r *http.Request, name string, status int) {
+	group, err := dataprovider.GroupExists(name)
+	if err != nil {
+		sendAPIResponse(w, r, err, "", getRespStatus(err))
+		return
+	}
+	group.PrepareForRendering()
+	if status != http.StatusOK {
+		ctx := context.WithValue(r.Context(), render.StatusCtxKey, status)
+		render.JSON(w, r.WithContext(ctx), group)
+	} else {
+		render.JSON(w, r, group)
+	}
+}
+
+func getGroupByName(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxRequestSize)
+	admin, err := getCurrentAPIAdmin(w, r)
+	if err != nil {
+		return
+	}
+	name := getURLParam(r, "name")
+	if _, err := getGroupInScope(&admin, name); err != nil {
+		sendAPIResponse(w, r, err, "", getRespStatus(err))
+		return
+	}
+	renderGroup(w, r, name, http.StatusOK)
+}
+
+func deleteGroup(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxRequestSize)
+	claims, err := getTokenClaims(r)
+	if err != nil || claims.Username == "" {
+		sendAPIResponse(w, r, err, "Invalid token claims", http.StatusBadRequest)
+		return
+	}
+	admin, err := getCurrentAPIAdmin(w, r)
+	if err != nil {
+		return
+	}
+	if admin.HasScope() {
+		sendAPIResponse(w, r, nil, "Admins with a scope cannot delete groups", http.StatusForbidden)
+		return
+	}
+	name := getURLParam(r, "name")
+	err = dataprovider.DeleteGroup(name, claims.Username, util.GetIPFromRemoteAddress(r.RemoteAddr))
+	if err != nil {
+		sendAPIResponse(w, r, err, "", getRespStatus(err))
+		return
+	}
+	sendAPIResponse(w, r, err, "Group deleted", http.StatusOK)
+}
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"mime/multipart"
 	"net/http"
 	"os"
 	"path"
@@ -25,7 +26,7 @@ func getUserConnection(w http.ResponseWriter, r *http.Request) (*Connection, err
 		sendAPIResponse(w, r, err, "Invalid token claims", http.StatusBadRequest)
 		return nil, fmt.Errorf("invalid token claims %w", err)
 	}
-	user, err := dataprovider.UserExists(claims.Username)
+	user, err := dataprovider.GetUserWithGroupSettings(claims.Username)
 	if err != nil {
 		sendAPIResponse(w, r, nil, "Unable to retrieve your user", getRespStatus(err))
 		return nil, err
@@ -193,33 +194,85 @@ func uploadUserFiles(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if len(doUploadFiles(w, r, connection, parentDir, files, true)) == len(files) {
+		sendAPIResponse(w, r, nil, "Upload completed", http.StatusCreated)
+	}
+}
+
+// doUploadFiles saves the given multipart files inside parentDir and returns
+// the files successfully uploaded, in the same order. If overwrite is false
+// the existing files are not replaced. On error the response is sent and the
+// remaining files are skipped
+func doUploadFiles(w http.ResponseWriter, r *http.Request, connection *Connection, parentDir string,
+	files []*multipart.FileHeader, overwrite bool,
+) []*multipart.FileHeader {
+	uploaded := make([]*multipart.FileHeader, 0, len(files))
 	for _, f := range files {
 		file, err := f.Open()
 		if err != nil {
 			sendAPIResponse(w, r, err, fmt.Sprintf("Unable to read uploaded file %#v", f.Filename), getMappedStatusCode(err))
-			return
+			return uploaded
 		}
 		defer file.Close()
 
 		filePath := path.Join(parentDir, f.Filename)
+		if !overwrite {
+			release, err := reserveUploadPath(connection, filePath)
+			if err != nil {
+				sendAPIResponse(w, r, err, fmt.Sprintf("Unable to write file %#v", f.Filename), getReservedPathStatus(err))
+				return uploaded
+			}
+			defer release()
+		}
 		writer, err := connection.getFileWriter(filePath)
 		if err != nil {
 			sendAPIResponse(w, r, err, fmt.Sprintf("Unable to write file %#v", f.Filename), getMappedStatusCode(err))
-			return
+			return uploaded
 		}
 		_, err = io.Copy(writer, file)
 		if err != nil {
 			writer.Close() //nolint:errcheck
 			sendAPIResponse(w, r, err, fmt.Sprintf("Error saving file %#v", f.Filename), getMappedStatusCode(err))
-			return
+			return uploaded
 		}
 		err = writer.Close()
 		if err != nil {
 			sendAPIResponse(w, r, err, fmt.Sprintf("Error closing file %#v", f.Filename), getMappedStatusCode(err))
-			return
+			return uploaded
 		}
+		uploaded = append(uploaded, f)
+	}
+	return uploaded
+}
+
+// reserveUploadPath reserves filePath for a new file. It fails if the file
+// already exists or if another upload to the same path is in progress.
+// The returned function must be called to release the reservation
+func reserveUploadPath(connection *Connection, filePath string) (func(), error) {
+	key := connection.User.Username + ":" + filePath
+	if _, loaded := reservedUploadPaths.LoadOrStore(key, true); loaded {
+		return nil, errUploadInProgress
+	}
+	release := func() {
+		reservedUploadPaths.Delete(key)
+	}
+	_, err := connection.DoStat(filePath, 0)
+	if err == nil {
+		release()
+		return nil, os.ErrExist
 	}
-	sendAPIResponse(w, r, nil, "Upload completed", http.StatusCreated)
+	if !errors.Is(err, os.ErrNotExist) {
+		release()
+		return nil, err
+	}
+	return release, nil
+}
+
+func getReservedPathStatus(err error) int {
+	if errors.Is(err, os.ErrExist) || errors.Is(err, errUploadInProgress) {
+		return http.StatusConflict
+	}
+	return getMappedStatusCode(err)
 }
 
 func renameUserFile(w http.ResponseWriter, r *http.Request) {
@@ -428,6 +481,34 @@ func changeUserPassword(w http.ResponseWriter, r *http.Request) {
 	sendAPIResponse(w, r, err, "Password updated", http.StatusOK)
 }
 
+func forgotUserPassword(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxRequestSize)
+
+	err := handleForgotPassword(r, getURLParam(r, "username"), false)
+	if err != nil {
+		sendAPIResponse(w, r, err, "", getRespStatus(err))
+		return
+	}
+	sendAPIResponse(w, r, err, "Check your email for the reset code", http.StatusAccepted)
+}
+
+func resetUserPassword(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxRequestSize)
+
+	var req pwdReset
+	err := render.DecodeJSON(r.Body, &req)
+	if err != nil {
+		sendAPIResponse(w, r, err, "", http.StatusBadRequest)
+		return
+	}
+	_, err = handleResetUserPassword(r, getURLParam(r, "username"), req.Code, req.Password)
+	if err != nil {
+		sendAPIResponse(w, r, err, "", getRespStatus(err))
+		return
+	}
+	sendAPIResponse(w, r, err, "Password updated", http.StatusOK)
+}
+
 func doChangeUserPassword(r *http.Request, currentPassword, newPassword, confirmNewPassword string) error {
 	if currentPassword == "" || newPassword == "" || confirmNewPassword == "" {
 		return util.NewValidationError("please provide the current password and the new one two times")
@@ -442,12 +523,18 @@ func doChangeUserPassword(r *http.Request, currentPassword, newPassword, confirm
 	if err != nil || claims.Username == "" {
 		return errors.New("invalid token claims")
 	}
-	user, err := dataprovider.CheckUserAndPass(claims.Username, currentPassword, util.GetIPFromRemoteAddress(r.RemoteAddr),
+	_, err = dataprovider.CheckUserAndPass(claims.Username, currentPassword, util.GetIPFromRemoteAddress(r.RemoteAddr),
 		common.ProtocolHTTP)
 	if err != nil {
 		return util.NewValidationError("current password does not match")
 	}
+	// the checked user has the group settings applied, we need the stored one to update it
+	user, err := dataprovider.UserExists(claims.Username)
+	if err != nil {
+		return err
+	}
 	user.Password = newPassword
+	user.Filters.RequirePasswordChange = false
 
 	return dataprovider.UpdateUser(&user, dataprovider.ActionExecutorSelf, util.GetIPFromRemoteAddress(r.RemoteAddr))
 }
@@ -0,0 +1,64 @@
+package httpd
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/go-chi/render"
+
+	"github.com/drakkan/sftpgo/v2/dataprovider"
+	"github.com/drakkan/sftpgo/v2/util"
+)
+
+func getAccountLockouts(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxRequestSize)
+	limit, offset, order, err := getSearchFilters(w, r)
+	if err != nil {
+		return
+	}
+
+	lockouts, err := dataprovider.GetAccountLockouts(limit, offset, order)
+	if err != nil {
+		sendAPIResponse(w, r, err, "", getRespStatus(err))
+		return
+	}
+	render.JSON(w, r, lockouts)
+}
+
+func getAccountLockout(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxRequestSize)
+	accountType, username, err := getAccountLockoutFromParams(r)
+	if err != nil {
+		sendAPIResponse(w, r, err, "", http.StatusBadRequest)
+		return
+	}
+	lockout, err := dataprovider.GetAccountLockout(username, accountType)
+	if err != nil {
+		sendAPIResponse(w, r, err, "", getRespStatus(err))
+		return
+	}
+	render.JSON(w, r, lockout)
+}
+
+func deleteAccountLockout(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxRequestSize)
+	accountType, username, err := getAccountLockoutFromParams(r)
+	if err != nil {
+		sendAPIResponse(w, r, err, "", http.StatusBadRequest)
+		return
+	}
+	err = dataprovider.DeleteAccountLockout(username, accountType)
+	if err != nil {
+		sendAPIResponse(w, r, err, "", getRespStatus(err))
+		return
+	}
+	sendAPIResponse(w, r, nil, "Account unlocked", http.StatusOK)
+}
+
+func getAccountLockoutFromParams(r *http.Request) (string, string, error) {
+	accountType := getURLParam(r, "type")
+	if !util.IsStringInSlice(accountType, []string{dataprovider.AccountTypeUser, dataprovider.AccountTypeAdmin}) {
+		return "", "", util.NewValidationError(fmt.Sprintf("invalid account type %#v", accountType))
+	}
+	return accountType, getURLParam(r, "username"), nil
+}
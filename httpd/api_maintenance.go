@@ -36,6 +36,10 @@ func validateBackupFile(outputFile string) (string, error) {
 
 func dumpData(w http.ResponseWriter, r *http.Request) {
 	r.Body = http.MaxBytesReader(w, r.Body, maxRequestSize)
+	admin, err := getCurrentAPIAdmin(w, r)
+	if err != nil {
+		return
+	}
 	var outputFile, outputData, indent string
 	if _, ok := r.URL.Query()["output-file"]; ok {
 		outputFile = strings.TrimSpace(r.URL.Query().Get("output-file"))
@@ -48,7 +52,6 @@ func dumpData(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if outputData != "1" {
-		var err error
 		outputFile, err = validateBackupFile(outputFile)
 		if err != nil {
 			sendAPIResponse(w, r, err, "", http.StatusBadRequest)
@@ -64,7 +67,7 @@ func dumpData(w http.ResponseWriter, r *http.Request) {
 		logger.Debug(logSender, "", "dumping data to: %#v", outputFile)
 	}
 
-	backup, err := dataprovider.DumpData()
+	backup, err := dataprovider.DumpDataForAdmin(&admin)
 	if err != nil {
 		logger.Warn(logSender, "", "dumping data error: %v, output file: %#v", err, outputFile)
 		sendAPIResponse(w, r, err, "", getRespStatus(err))
@@ -102,6 +105,9 @@ func loadDataFromRequest(w http.ResponseWriter, r *http.Request) {
 		sendAPIResponse(w, r, err, "Invalid token claims", http.StatusBadRequest)
 		return
 	}
+	if !checkRestoreAllowed(w, r) {
+		return
+	}
 	_, scanQuota, mode, err := getLoaddataOptions(r)
 	if err != nil {
 		sendAPIResponse(w, r, err, "", http.StatusBadRequest)
@@ -129,6 +135,9 @@ func loadData(w http.ResponseWriter, r *http.Request) {
 		sendAPIResponse(w, r, err, "Invalid token claims", http.StatusBadRequest)
 		return
 	}
+	if !checkRestoreAllowed(w, r) {
+		return
+	}
 	inputFile, scanQuota, mode, err := getLoaddataOptions(r)
 	if err != nil {
 		sendAPIResponse(w, r, err, "", http.StatusBadRequest)
@@ -160,6 +169,20 @@ func loadData(w http.ResponseWriter, r *http.Request) {
 	sendAPIResponse(w, r, err, "Data restored", http.StatusOK)
 }
 
+// checkRestoreAllowed sends a forbidden response if the admin associated with the
+// request has a scope, a backup could overwrite objects outside the scope
+func checkRestoreAllowed(w http.ResponseWriter, r *http.Request) bool {
+	admin, err := getCurrentAPIAdmin(w, r)
+	if err != nil {
+		return false
+	}
+	if admin.HasScope() {
+		sendAPIResponse(w, r, nil, "Admins with a scope cannot restore backups", http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
 func restoreBackup(content []byte, inputFile string, scanQuota, mode int, executor, ipAddress string) error {
 	dump, err := dataprovider.ParseDumpData(content)
 	if err != nil {
@@ -170,6 +193,10 @@ func restoreBackup(content []byte, inputFile string, scanQuota, mode int, execut
 		return err
 	}
 
+	if err = RestoreGroups(dump.Groups, inputFile, mode, executor, ipAddress); err != nil {
+		return err
+	}
+
 	if err = RestoreUsers(dump.Users, inputFile, mode, scanQuota, executor, ipAddress); err != nil {
 		return err
 	}
@@ -182,8 +209,24 @@ func restoreBackup(content []byte, inputFile string, scanQuota, mode int, execut
 		return err
 	}
 
-	logger.Debug(logSender, "", "backup restored, users: %v, folders: %v, admins: %vs",
-		len(dump.Users), len(dump.Folders), len(dump.Admins))
+	if err = RestoreShares(dump.Shares, inputFile, mode, executor, ipAddress); err != nil {
+		return err
+	}
+
+	if err = RestoreEventRules(dump.EventRules, inputFile, mode, executor, ipAddress); err != nil {
+		return err
+	}
+
+	if err = RestoreSchedules(dump.Schedules, inputFile, mode, executor, ipAddress); err != nil {
+		return err
+	}
+
+	if err = RestoreS3AccessKeys(dump.S3AccessKeys, inputFile, mode); err != nil {
+		return err
+	}
+
+	logger.Debug(logSender, "", "backup restored, users: %v, groups: %v, folders: %v, admins: %vs",
+		len(dump.Users), len(dump.Groups), len(dump.Folders), len(dump.Admins))
 
 	return nil
 }
@@ -244,6 +287,123 @@ func RestoreFolders(folders []vfs.BaseVirtualFolder, inputFile string, mode, sca
 	return nil
 }
 
+// RestoreGroups restores the specified groups
+func RestoreGroups(groups []dataprovider.Group, inputFile string, mode int, executor, ipAddress string) error {
+	for _, group := range groups {
+		group := group // pin
+		g, err := dataprovider.GroupExists(group.Name)
+		if err == nil {
+			if mode == 1 {
+				logger.Debug(logSender, "", "loaddata mode 1, existing group %#v not updated", g.Name)
+				continue
+			}
+			group.ID = g.ID
+			err = dataprovider.UpdateGroup(&group, g.Users, executor, ipAddress)
+			logger.Debug(logSender, "", "restoring existing group: %+v, dump file: %#v, error: %v", group, inputFile, err)
+		} else {
+			group.Users = nil
+			err = dataprovider.AddGroup(&group, executor, ipAddress)
+			logger.Debug(logSender, "", "adding new group: %+v, dump file: %#v, error: %v", group, inputFile, err)
+		}
+		if err != nil {
+			return fmt.Errorf("unable to restore group %#v: %w", group.Name, err)
+		}
+	}
+	return nil
+}
+
+// RestoreEventRules restores the specified event rules
+func RestoreEventRules(rules []dataprovider.EventRule, inputFile string, mode int, executor, ipAddress string) error {
+	for _, rule := range rules {
+		rule := rule // pin
+		r, err := dataprovider.EventRuleExists(rule.Name)
+		if err == nil {
+			if mode == 1 {
+				logger.Debug(logSender, "", "loaddata mode 1, existing event rule %#v not updated", r.Name)
+				continue
+			}
+			rule.ID = r.ID
+			err = dataprovider.UpdateEventRule(&rule, executor, ipAddress)
+			logger.Debug(logSender, "", "restoring existing event rule: %+v, dump file: %#v, error: %v", rule, inputFile, err)
+		} else {
+			err = dataprovider.AddEventRule(&rule, executor, ipAddress)
+			logger.Debug(logSender, "", "adding new event rule: %+v, dump file: %#v, error: %v", rule, inputFile, err)
+		}
+		if err != nil {
+			return fmt.Errorf("unable to restore event rule %#v: %w", rule.Name, err)
+		}
+	}
+	return nil
+}
+
+// RestoreSchedules restores the specified schedules
+func RestoreSchedules(schedules []dataprovider.Schedule, inputFile string, mode int, executor, ipAddress string) error {
+	for _, schedule := range schedules {
+		schedule := schedule // pin
+		s, err := dataprovider.ScheduleExists(schedule.Name)
+		if err == nil {
+			if mode == 1 {
+				logger.Debug(logSender, "", "loaddata mode 1, existing schedule %#v not updated", s.Name)
+				continue
+			}
+			schedule.ID = s.ID
+			err = dataprovider.UpdateSchedule(&schedule, executor, ipAddress)
+			logger.Debug(logSender, "", "restoring existing schedule: %+v, dump file: %#v, error: %v", schedule, inputFile, err)
+		} else {
+			err = dataprovider.AddSchedule(&schedule, executor, ipAddress)
+			logger.Debug(logSender, "", "adding new schedule: %+v, dump file: %#v, error: %v", schedule, inputFile, err)
+		}
+		if err != nil {
+			return fmt.Errorf("unable to restore schedule %#v: %w", schedule.Name, err)
+		}
+	}
+	return nil
+}
+
+// RestoreShares restores the specified shares
+func RestoreShares(shares []dataprovider.Share, inputFile string, mode int, executor, ipAddress string) error {
+	for _, share := range shares {
+		share := share // pin
+		share.IsRestore = true
+		s, err := dataprovider.ShareExists(share.ShareID, "")
+		if err == nil {
+			if mode == 1 {
+				logger.Debug(logSender, "", "loaddata mode 1, existing share %#v not updated", share.ShareID)
+				continue
+			}
+			share.ID = s.ID
+			err = dataprovider.UpdateShare(&share, executor, ipAddress)
+			logger.Debug(logSender, "", "restoring existing share: %+v, dump file: %#v, error: %v", share, inputFile, err)
+		} else {
+			err = dataprovider.AddShare(&share, executor, ipAddress)
+			logger.Debug(logSender, "", "adding new share: %+v, dump file: %#v, error: %v", share, inputFile, err)
+		}
+		if err != nil {
+			return fmt.Errorf("unable to restore share %#v: %w", share.ShareID, err)
+		}
+	}
+	return nil
+}
+
+// RestoreS3AccessKeys restores the specified S3 access keys, the related users must exist
+func RestoreS3AccessKeys(keys []dataprovider.S3AccessKey, inputFile string, mode int) error {
+	for _, key := range keys {
+		key := key // pin
+		_, err := dataprovider.S3AccessKeyExists(key.AccessKeyID)
+		if err == nil && mode == 1 {
+			logger.Debug(logSender, "", "loaddata mode 1, existing S3 access key %#v not updated", key.AccessKeyID)
+			continue
+		}
+		err = dataprovider.RestoreS3AccessKey(&key)
+		logger.Debug(logSender, "", "restoring S3 access key %#v for user %#v, dump file: %#v, error: %v",
+			key.AccessKeyID, key.Username, inputFile, err)
+		if err != nil {
+			return fmt.Errorf("unable to restore S3 access key %#v: %w", key.AccessKeyID, err)
+		}
+	}
+	return nil
+}
+
 // RestoreAPIKeys restores the specified API keys
 func RestoreAPIKeys(apiKeys []dataprovider.APIKey, inputFile string, mode int, executor, ipAddress string) error {
 	for _, apiKey := range apiKeys {
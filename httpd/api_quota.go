@@ -23,14 +23,27 @@ type quotaUsage struct {
 	UsedQuotaFiles int   `json:"used_quota_files"`
 }
 
+type transferQuotaUsage struct {
+	UsedUploadDataTransfer   int64 `json:"used_upload_data_transfer"`
+	UsedDownloadDataTransfer int64 `json:"used_download_data_transfer"`
+}
+
 func getUsersQuotaScans(w http.ResponseWriter, r *http.Request) {
 	r.Body = http.MaxBytesReader(w, r.Body, maxRequestSize)
-	render.JSON(w, r, common.QuotaScans.GetUsersQuotaScans())
+	admin, err := getCurrentAPIAdmin(w, r)
+	if err != nil {
+		return
+	}
+	render.JSON(w, r, getUsersQuotaScansInScope(&admin))
 }
 
 func getFoldersQuotaScans(w http.ResponseWriter, r *http.Request) {
 	r.Body = http.MaxBytesReader(w, r.Body, maxRequestSize)
-	render.JSON(w, r, common.QuotaScans.GetVFoldersQuotaScans())
+	admin, err := getCurrentAPIAdmin(w, r)
+	if err != nil {
+		return
+	}
+	render.JSON(w, r, getFoldersQuotaScansInScope(&admin))
 }
 
 func updateUserQuotaUsage(w http.ResponseWriter, r *http.Request) {
@@ -86,6 +99,132 @@ func updateFolderQuotaUsageCompat(w http.ResponseWriter, r *http.Request) {
 	doUpdateFolderQuotaUsage(w, r, f.Name, usage)
 }
 
+func getUserTransferQuotaUsage(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxRequestSize)
+	user, err := dataprovider.GetUserWithGroupSettings(getURLParam(r, "username"))
+	if err != nil {
+		sendAPIResponse(w, r, err, "", getRespStatus(err))
+		return
+	}
+	if err := checkUserInScope(r, &user); err != nil {
+		sendAPIResponse(w, r, err, "", getRespStatus(err))
+		return
+	}
+	ulSize, dlSize, err := dataprovider.GetUsedTransferQuota(&user)
+	if err != nil {
+		sendAPIResponse(w, r, err, "", getRespStatus(err))
+		return
+	}
+	render.JSON(w, r, transferQuotaUsage{
+		UsedUploadDataTransfer:   ulSize,
+		UsedDownloadDataTransfer: dlSize,
+	})
+}
+
+func updateUserTransferQuotaUsage(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxRequestSize)
+	var usage transferQuotaUsage
+	err := render.DecodeJSON(r.Body, &usage)
+	if err != nil {
+		sendAPIResponse(w, r, err, "", http.StatusBadRequest)
+		return
+	}
+	if usage.UsedUploadDataTransfer < 0 || usage.UsedDownloadDataTransfer < 0 {
+		sendAPIResponse(w, r, errors.New("invalid used transfer quota parameters, negative values are not allowed"),
+			"", http.StatusBadRequest)
+		return
+	}
+	mode, err := getQuotaUpdateMode(r)
+	if err != nil {
+		sendAPIResponse(w, r, err, "", http.StatusBadRequest)
+		return
+	}
+	user, err := dataprovider.GetUserWithGroupSettings(getURLParam(r, "username"))
+	if err != nil {
+		sendAPIResponse(w, r, err, "", getRespStatus(err))
+		return
+	}
+	if err := checkUserInScope(r, &user); err != nil {
+		sendAPIResponse(w, r, err, "", getRespStatus(err))
+		return
+	}
+	if mode == quotaUpdateModeAdd && !user.HasTransferQuotaRestrictions() && dataprovider.GetQuotaTracking() == 2 {
+		sendAPIResponse(w, r, errors.New("this user has no transfer quota restrictions, only reset mode is supported"),
+			"", http.StatusBadRequest)
+		return
+	}
+	err = dataprovider.UpdateUserTransferQuota(&user, usage.UsedUploadDataTransfer, usage.UsedDownloadDataTransfer,
+		mode == quotaUpdateModeReset)
+	if err != nil {
+		sendAPIResponse(w, r, err, "", getRespStatus(err))
+	} else {
+		sendAPIResponse(w, r, err, "Transfer quota updated", http.StatusOK)
+	}
+}
+
+func getFolderTransferQuotaUsage(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxRequestSize)
+	folder, err := dataprovider.GetFolderByName(getURLParam(r, "name"))
+	if err != nil {
+		sendAPIResponse(w, r, err, "", getRespStatus(err))
+		return
+	}
+	if err := checkFolderInScope(r, &folder); err != nil {
+		sendAPIResponse(w, r, err, "", getRespStatus(err))
+		return
+	}
+	ulSize, dlSize, err := dataprovider.GetUsedVirtualFolderTransferQuota(&folder)
+	if err != nil {
+		sendAPIResponse(w, r, err, "", getRespStatus(err))
+		return
+	}
+	render.JSON(w, r, transferQuotaUsage{
+		UsedUploadDataTransfer:   ulSize,
+		UsedDownloadDataTransfer: dlSize,
+	})
+}
+
+func updateFolderTransferQuotaUsage(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxRequestSize)
+	var usage transferQuotaUsage
+	err := render.DecodeJSON(r.Body, &usage)
+	if err != nil {
+		sendAPIResponse(w, r, err, "", http.StatusBadRequest)
+		return
+	}
+	if usage.UsedUploadDataTransfer < 0 || usage.UsedDownloadDataTransfer < 0 {
+		sendAPIResponse(w, r, errors.New("invalid used transfer quota parameters, negative values are not allowed"),
+			"", http.StatusBadRequest)
+		return
+	}
+	mode, err := getQuotaUpdateMode(r)
+	if err != nil {
+		sendAPIResponse(w, r, err, "", http.StatusBadRequest)
+		return
+	}
+	folder, err := dataprovider.GetFolderByName(getURLParam(r, "name"))
+	if err != nil {
+		sendAPIResponse(w, r, err, "", getRespStatus(err))
+		return
+	}
+	if err := checkFolderInScope(r, &folder); err != nil {
+		sendAPIResponse(w, r, err, "", getRespStatus(err))
+		return
+	}
+	if mode == quotaUpdateModeAdd && !folder.HasTransferQuotaRestrictions() && dataprovider.GetQuotaTracking() == 2 {
+		sendAPIResponse(w, r, errors.New("this folder has no transfer quota restrictions, only reset mode is supported"),
+			"", http.StatusBadRequest)
+		return
+	}
+	err = dataprovider.UpdateVirtualFolderTransferQuota(&folder, usage.UsedUploadDataTransfer, usage.UsedDownloadDataTransfer,
+		mode == quotaUpdateModeReset)
+	if err != nil {
+		sendAPIResponse(w, r, err, "", getRespStatus(err))
+	} else {
+		sendAPIResponse(w, r, err, "Transfer quota updated", http.StatusOK)
+	}
+}
+
 func startUserQuotaScan(w http.ResponseWriter, r *http.Request) {
 	r.Body = http.MaxBytesReader(w, r.Body, maxRequestSize)
 	doStartUserQuotaScan(w, r, getURLParam(r, "username"))
@@ -129,11 +268,15 @@ func doUpdateUserQuotaUsage(w http.ResponseWriter, r *http.Request, username str
 		sendAPIResponse(w, r, err, "", http.StatusBadRequest)
 		return
 	}
-	user, err := dataprovider.UserExists(username)
+	user, err := dataprovider.GetUserWithGroupSettings(username)
 	if err != nil {
 		sendAPIResponse(w, r, err, "", getRespStatus(err))
 		return
 	}
+	if err := checkUserInScope(r, &user); err != nil {
+		sendAPIResponse(w, r, err, "", getRespStatus(err))
+		return
+	}
 	if mode == quotaUpdateModeAdd && !user.HasQuotaRestrictions() && dataprovider.GetQuotaTracking() == 2 {
 		sendAPIResponse(w, r, errors.New("this user has no quota restrictions, only reset mode is supported"),
 			"", http.StatusBadRequest)
@@ -168,6 +311,10 @@ func doUpdateFolderQuotaUsage(w http.ResponseWriter, r *http.Request, name strin
 		sendAPIResponse(w, r, err, "", getRespStatus(err))
 		return
 	}
+	if err := checkFolderInScope(r, &folder); err != nil {
+		sendAPIResponse(w, r, err, "", getRespStatus(err))
+		return
+	}
 	if !common.QuotaScans.AddVFolderQuotaScan(folder.Name) {
 		sendAPIResponse(w, r, err, "A quota scan is in progress for this folder", http.StatusConflict)
 		return
@@ -186,11 +333,15 @@ func doStartUserQuotaScan(w http.ResponseWriter, r *http.Request, username strin
 		sendAPIResponse(w, r, nil, "Quota tracking is disabled!", http.StatusForbidden)
 		return
 	}
-	user, err := dataprovider.UserExists(username)
+	user, err := dataprovider.GetUserWithGroupSettings(username)
 	if err != nil {
 		sendAPIResponse(w, r, err, "", getRespStatus(err))
 		return
 	}
+	if err := checkUserInScope(r, &user); err != nil {
+		sendAPIResponse(w, r, err, "", getRespStatus(err))
+		return
+	}
 	if !common.QuotaScans.AddUserQuotaScan(user.Username) {
 		sendAPIResponse(w, r, err, fmt.Sprintf("Another scan is already in progress for user %#v", username),
 			http.StatusConflict)
@@ -210,6 +361,10 @@ func doStartFolderQuotaScan(w http.ResponseWriter, r *http.Request, name string)
 		sendAPIResponse(w, r, err, "", getRespStatus(err))
 		return
 	}
+	if err := checkFolderInScope(r, &folder); err != nil {
+		sendAPIResponse(w, r, err, "", getRespStatus(err))
+		return
+	}
 	if !common.QuotaScans.AddVFolderQuotaScan(folder.Name) {
 		sendAPIResponse(w, r, err, fmt.Sprintf("Another scan is already in progress for folder %#v", name),
 			http.StatusConflict)
@@ -8,21 +8,35 @@ import (
 
 	"github.com/drakkan/sftpgo/v2/common"
 	"github.com/drakkan/sftpgo/v2/dataprovider"
+	"github.com/drakkan/sftpgo/v2/util"
 )
 
 func getRetentionChecks(w http.ResponseWriter, r *http.Request) {
 	r.Body = http.MaxBytesReader(w, r.Body, maxRequestSize)
-	render.JSON(w, r, common.RetentionChecks.Get())
+	admin, err := getCurrentAPIAdmin(w, r)
+	if err != nil {
+		return
+	}
+	render.JSON(w, r, getRetentionChecksInScope(&admin))
 }
 
 func startRetentionCheck(w http.ResponseWriter, r *http.Request) {
 	r.Body = http.MaxBytesReader(w, r.Body, maxRequestSize)
+	admin, err := getCurrentAPIAdmin(w, r)
+	if err != nil {
+		return
+	}
 	username := getURLParam(r, "username")
-	user, err := dataprovider.UserExists(username)
+	user, err := dataprovider.GetUserWithGroupSettings(username)
 	if err != nil {
 		sendAPIResponse(w, r, err, "", getRespStatus(err))
 		return
 	}
+	if !admin.IsUserInScope(&user) {
+		err = util.NewRecordNotFoundError(fmt.Sprintf("username %#v does not exist", username))
+		sendAPIResponse(w, r, err, "", getRespStatus(err))
+		return
+	}
 	var check common.RetentionCheck
 
 	err = render.DecodeJSON(r.Body, &check.Folders)
@@ -30,20 +44,15 @@ func startRetentionCheck(w http.ResponseWriter, r *http.Request) {
 		sendAPIResponse(w, r, err, "", http.StatusBadRequest)
 		return
 	}
+	if len(check.Folders) == 0 {
+		// apply the data retention policies stored for the user and its virtual folders
+		check.Folders = getFoldersRetention(user.GetDataRetention())
+	}
 
+	check.DryRun = r.URL.Query().Get("dry_run") == "true"
 	check.Notifications = getCommaSeparatedQueryParam(r, "notifications")
 	for _, notification := range check.Notifications {
 		if notification == common.RetentionCheckNotificationEmail {
-			claims, err := getTokenClaims(r)
-			if err != nil {
-				sendAPIResponse(w, r, err, "Invalid token claims", http.StatusBadRequest)
-				return
-			}
-			admin, err := dataprovider.AdminExists(claims.Username)
-			if err != nil {
-				sendAPIResponse(w, r, err, "", getRespStatus(err))
-				return
-			}
 			check.Email = admin.Email
 		}
 	}
@@ -57,6 +66,6 @@ func startRetentionCheck(w http.ResponseWriter, r *http.Request) {
 			http.StatusConflict)
 		return
 	}
-	go c.Start()
+	go c.Start() //nolint:errcheck
 	sendAPIResponse(w, r, err, "Check started", http.StatusAccepted)
 }
@@ -0,0 +1,83 @@
+package httpd
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/go-chi/render"
+
+	"github.com/drakkan/sftpgo/v2/dataprovider"
+)
+
+func getUserS3AccessKeys(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxRequestSize)
+	admin, err := getCurrentAPIAdmin(w, r)
+	if err != nil {
+		return
+	}
+	username := getURLParam(r, "username")
+	if _, err := getUserInScope(&admin, username); err != nil {
+		sendAPIResponse(w, r, err, "", getRespStatus(err))
+		return
+	}
+	keys, err := dataprovider.GetUserS3AccessKeys(username)
+	if err != nil {
+		sendAPIResponse(w, r, err, "", getRespStatus(err))
+		return
+	}
+	render.JSON(w, r, keys)
+}
+
+func addUserS3AccessKey(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxRequestSize)
+	admin, err := getCurrentAPIAdmin(w, r)
+	if err != nil {
+		return
+	}
+	username := getURLParam(r, "username")
+	if _, err := getUserInScope(&admin, username); err != nil {
+		sendAPIResponse(w, r, err, "", getRespStatus(err))
+		return
+	}
+	var key dataprovider.S3AccessKey
+	err = render.DecodeJSON(r.Body, &key)
+	if err != nil {
+		sendAPIResponse(w, r, err, "", http.StatusBadRequest)
+		return
+	}
+	key.AccessKeyID = ""
+	key.SecretAccessKey = nil
+	key.Username = username
+	if err := dataprovider.AddS3AccessKey(&key); err != nil {
+		sendAPIResponse(w, r, err, "", getRespStatus(err))
+		return
+	}
+	response := make(map[string]string)
+	response["message"] = "S3 access key created. This is the only time the secret access key is visible, please save it."
+	response["access_key_id"] = key.AccessKeyID
+	response["secret_access_key"] = key.DisplaySecretAccessKey()
+	w.Header().Add("Location", fmt.Sprintf("%v/%v/s3keys/%v", userPath, url.PathEscape(username), key.AccessKeyID))
+	w.Header().Add("X-Object-ID", key.AccessKeyID)
+	ctx := context.WithValue(r.Context(), render.StatusCtxKey, http.StatusCreated)
+	render.JSON(w, r.WithContext(ctx), response)
+}
+
+func deleteUserS3AccessKey(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxRequestSize)
+	admin, err := getCurrentAPIAdmin(w, r)
+	if err != nil {
+		return
+	}
+	username := getURLParam(r, "username")
+	if _, err := getUserInScope(&admin, username); err != nil {
+		sendAPIResponse(w, r, err, "", getRespStatus(err))
+		return
+	}
+	if err := dataprovider.DeleteS3AccessKey(getURLParam(r, "id"), username); err != nil {
+		sendAPIResponse(w, r, err, "", getRespStatus(err))
+		return
+	}
+	sendAPIResponse(w, r, nil, "S3 access key deleted", http.StatusOK)
+}
@@ -0,0 +1,142 @@
+package httpd
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/render"
+
+	"github.com/drakkan/sftpgo/v2/dataprovider"
+	"github.com/drakkan/sftpgo/v2/util"
+)
+
+func getSchedules(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxRequestSize)
+	limit, offset, order, err := getSearchFilters(w, r)
+	if err != nil {
+		return
+	}
+
+	schedules, err := dataprovider.GetSchedules(limit, offset, order)
+	if err != nil {
+		sendAPIResponse(w, r, err, "", http.StatusInternalServerError)
+		return
+	}
+	render.JSON(w, r, schedules)
+}
+
+func getScheduleByName(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxRequestSize)
+	name := getURLParam(r, "name")
+	renderSchedule(w, r, name, http.StatusOK)
+}
+
+func renderSchedule(w http.ResponseWriter, r *http.Request, name string, status int) {
+	schedule, err := dataprovider.ScheduleExists(name)
+	if err != nil {
+		sendAPIResponse(w, r, err, "", getRespStatus(err))
+		return
+	}
+	if status != http.StatusOK {
+		ctx := context.WithValue(r.Context(), render.StatusCtxKey, status)
+		render.JSON(w, r.WithContext(ctx), schedule)
+	} else {
+		render.JSON(w, r, schedule)
+	}
+}
+
+func addSchedule(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxRequestSize)
+	claims, err := getTokenClaims(r)
+	if err != nil || claims.Username == "" {
+		sendAPIResponse(w, r, err, "Invalid token claims", http.StatusBadRequest)
+		return
+	}
+	var schedule dataprovider.Schedule
+	err = render.DecodeJSON(r.Body, &schedule)
+	if err != nil {
+		sendAPIResponse(w, r, err, "", http.StatusBadRequest)
+		return
+	}
+	err = dataprovider.AddSchedule(&schedule, claims.Username, util.GetIPFromRemoteAddress(r.RemoteAddr))
+	if err != nil {
+		sendAPIResponse(w, r, err, "", getRespStatus(err))
+		return
+	}
+	renderSchedule(w, r, schedule.Name, http.StatusCreated)
+}
+
+func updateSchedule(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxRequestSize)
+	claims, err := getTokenClaims(r)
+	if err != nil || claims.Username == "" {
+		sendAPIResponse(w, r, err, "Invalid token claims", http.StatusBadRequest)
+		return
+	}
+	name := getURLParam(r, "name")
+	schedule, err := dataprovider.ScheduleExists(name)
+	if err != nil {
+		sendAPIResponse(w, r, err, "", getRespStatus(err))
+		return
+	}
+	scheduleID := schedule.ID
+	createdAt := schedule.CreatedAt
+
+	var updatedSchedule dataprovider.Schedule
+	err = render.DecodeJSON(r.Body, &updatedSchedule)
+	if err != nil {
+		sendAPIResponse(w, r, err, "", http.StatusBadRequest)
+		return
+	}
+	updatedSchedule.ID = scheduleID
+	updatedSchedule.Name = name
+	updatedSchedule.CreatedAt = createdAt
+	err = dataprovider.UpdateSchedule(&updatedSchedule, claims.Username, util.GetIPFromRemoteAddress(r.RemoteAddr))
+	if err != nil {
+		sendAPIResponse(w, r, err, "", getRespStatus(err))
+		return
+	}
+	sendAPIResponse(w, r, nil, "Schedule updated", http.StatusOK)
+}
+
+func deleteSchedule(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxRequestSize)
+	claims, err := getTokenClaims(r)
+	if err != nil || claims.Username == "" {
+		sendAPIResponse(w, r, err, "Invalid token claims", http.StatusBadRequest)
+		return
+	}
+	name := getURLParam(r, "name")
+	err = dataprovider.DeleteSchedule(name, claims.Username, util.GetIPFromRemoteAddress(r.RemoteAddr))
+	if err != nil {
+		sendAPIResponse(w, r, err, "", getRespStatus(err))
+		return
+	}
+	sendAPIResponse(w, r, err, "Schedule deleted", http.StatusOK)
+}
+
+func runSchedule(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxRequestSize)
+	name := getURLParam(r, "name")
+	schedule, err := dataprovider.ScheduleExists(name)
+	if err != nil {
+		sendAPIResponse(w, r, err, "", getRespStatus(err))
+		return
+	}
+	started, err := taskScheduler.startSchedule(schedule, time.Now().UTC())
+	if err == errScheduleRunning {
+		sendAPIResponse(w, r, err, fmt.Sprintf("Schedule %#v is already running", name), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		sendAPIResponse(w, r, err, "", getRespStatus(err))
+		return
+	}
+	if !started {
+		sendAPIResponse(w, r, nil, fmt.Sprintf("Schedule %#v was already executed", name), http.StatusConflict)
+		return
+	}
+	sendAPIResponse(w, r, nil, "Schedule started", http.StatusAccepted)
+}
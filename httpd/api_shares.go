@@ -0,0 +1,401 @@
+package httpd
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/go-chi/render"
+	"github.com/rs/xid"
+
+	"github.com/drakkan/sftpgo/v2/common"
+	"github.com/drakkan/sftpgo/v2/dataprovider"
+	"github.com/drakkan/sftpgo/v2/logger"
+	"github.com/drakkan/sftpgo/v2/util"
+)
+
+const (
+	shareBasicRealm = "Basic realm=\"SFTPGo Share\""
+)
+
+func getShares(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxRequestSize)
+	claims, err := getTokenClaims(r)
+	if err != nil || claims.Username == "" {
+		sendAPIResponse(w, r, err, "Invalid token claims", http.StatusBadRequest)
+		return
+	}
+	limit, offset, order, err := getSearchFilters(w, r)
+	if err != nil {
+		return
+	}
+
+	shares, err := dataprovider.GetShares(limit, offset, order, claims.Username)
+	if err != nil {
+		sendAPIResponse(w, r, err, "", getRespStatus(err))
+		return
+	}
+	render.JSON(w, r, shares)
+}
+
+func getShareByID(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxRequestSize)
+	claims, err := getTokenClaims(r)
+	if err != nil || claims.Username == "" {
+		sendAPIResponse(w, r, err, "Invalid token claims", http.StatusBadRequest)
+		return
+	}
+	shareID := getURLParam(r, "id")
+	share, err := dataprovider.ShareExists(shareID, claims.Username)
+	if err != nil {
+		sendAPIResponse(w, r, err, "", getRespStatus(err))
+		return
+	}
+	share.HideConfidentialData()
+
+	render.JSON(w, r, share)
+}
+
+func addShare(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxRequestSize)
+	claims, err := getTokenClaims(r)
+	if err != nil || claims.Username == "" {
+		sendAPIResponse(w, r, err, "Invalid token claims", http.StatusBadRequest)
+		return
+	}
+	var share dataprovider.Share
+	err = render.DecodeJSON(r.Body, &share)
+	if err != nil {
+		sendAPIResponse(w, r, err, "", http.StatusBadRequest)
+		return
+	}
+	share.ID = 0
+	share.ShareID = ""
+	share.LastUseAt = 0
+	share.Username = claims.Username
+	err = dataprovider.AddShare(&share, claims.Username, util.GetIPFromRemoteAddress(r.RemoteAddr))
+	if err != nil {
+		sendAPIResponse(w, r, err, "", getRespStatus(err))
+		return
+	}
+	w.Header().Add("Location", fmt.Sprintf("%v/%v", userSharesPath, share.ShareID))
+	w.Header().Add("X-Object-ID", share.ShareID)
+	sendAPIResponse(w, r, nil, "Share created", http.StatusCreated)
+}
+
+func updateShare(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxRequestSize)
+	claims, err := getTokenClaims(r)
+	if err != nil || claims.Username == "" {
+		sendAPIResponse(w, r, err, "Invalid token claims", http.StatusBadRequest)
+		return
+	}
+	shareID := getURLParam(r, "id")
+	share, err := dataprovider.ShareExists(shareID, claims.Username)
+	if err != nil {
+		sendAPIResponse(w, r, err, "", getRespStatus(err))
+		return
+	}
+
+	oldPassword := share.Password
+	err = render.DecodeJSON(r.Body, &share)
+	if err != nil {
+		sendAPIResponse(w, r, err, "", http.StatusBadRequest)
+		return
+	}
+
+	share.ShareID = shareID
+	share.Username = claims.Username
+	if share.Password == redactedSecret {
+		share.Password = oldPassword
+	}
+	if err := dataprovider.UpdateShare(&share, claims.Username, util.GetIPFromRemoteAddress(r.RemoteAddr)); err != nil {
+		sendAPIResponse(w, r, err, "", getRespStatus(err))
+		return
+	}
+	sendAPIResponse(w, r, nil, "Share updated", http.StatusOK)
+}
+
+func deleteShare(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxRequestSize)
+	shareID := getURLParam(r, "id")
+	claims, err := getTokenClaims(r)
+	if err != nil || claims.Username == "" {
+		sendAPIResponse(w, r, err, "Invalid token claims", http.StatusBadRequest)
+		return
+	}
+
+	err = dataprovider.DeleteShare(shareID, claims.Username, util.GetIPFromRemoteAddress(r.RemoteAddr))
+	if err != nil {
+		sendAPIResponse(w, r, err, "", getRespStatus(err))
+		return
+	}
+	sendAPIResponse(w, r, err, "Share deleted", http.StatusOK)
+}
+
+func getShareUploads(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxRequestSize)
+	claims, err := getTokenClaims(r)
+	if err != nil || claims.Username == "" {
+		sendAPIResponse(w, r, err, "Invalid token claims", http.StatusBadRequest)
+		return
+	}
+	limit, offset, order, err := getSearchFilters(w, r)
+	if err != nil {
+		return
+	}
+	share, err := dataprovider.ShareExists(getURLParam(r, "id"), claims.Username)
+	if err != nil {
+		sendAPIResponse(w, r, err, "", getRespStatus(err))
+		return
+	}
+
+	uploads, err := dataprovider.GetShareUploads(share.ShareID, limit, offset, order)
+	if err != nil {
+		sendAPIResponse(w, r, err, "", getRespStatus(err))
+		return
+	}
+	render.JSON(w, r, uploads)
+}
+
+func downloadFromShare(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxRequestSize)
+	share, connection, err := checkPublicShare(w, r, []dataprovider.ShareScope{dataprovider.ShareScopeRead}, false)
+	if err != nil {
+		return
+	}
+	doDownloadFromShare(w, r, share, connection, false)
+}
+
+func handleClientGetPublicShare(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxRequestSize)
+	validScopes := []dataprovider.ShareScope{dataprovider.ShareScopeRead, dataprovider.ShareScopeWrite}
+	share, connection, err := checkPublicShare(w, r, validScopes, true)
+	if err != nil {
+		return
+	}
+	if share.Scope == dataprovider.ShareScopeWrite {
+		renderUploadToSharePage(w, r, &share)
+		return
+	}
+	doDownloadFromShare(w, r, share, connection, true)
+}
+
+func uploadToShare(w http.ResponseWriter, r *http.Request) {
+	if maxUploadFileSize > 0 {
+		r.Body = http.MaxBytesReader(w, r.Body, maxUploadFileSize)
+	}
+	share, connection, err := checkPublicShare(w, r, []dataprovider.ShareScope{dataprovider.ShareScopeWrite}, false)
+	if err != nil {
+		return
+	}
+	common.Connections.Add(connection)
+	defer common.Connections.Remove(connection.GetID())
+
+	err = r.ParseMultipartForm(maxMultipartMem)
+	if err != nil {
+		sendAPIResponse(w, r, err, "Unable to parse multipart form", http.StatusBadRequest)
+		return
+	}
+	defer r.MultipartForm.RemoveAll() //nolint:errcheck
+
+	files := r.MultipartForm.File["filename"]
+	if len(files) == 0 {
+		sendAPIResponse(w, r, nil, "No files uploaded!", http.StatusBadRequest)
+		return
+	}
+	if share.MaxTokens > 0 && share.UsedTokens+len(files) > share.MaxTokens {
+		sendAPIResponse(w, r, nil, "Allowed usage exceeded", http.StatusBadRequest)
+		return
+	}
+	uploader := strings.TrimSpace(r.Form.Get("uploader"))
+	if len(uploader) > 255 {
+		sendAPIResponse(w, r, nil, "The uploader name cannot exceed 255 characters", http.StatusBadRequest)
+		return
+	}
+	// uploaders can only add files inside the shared directory
+	for _, f := range files {
+		f.Filename = path.Base(util.CleanPath(f.Filename))
+	}
+	// uploaders cannot overwrite existing files or files uploaded concurrently
+	uploaded := doUploadFiles(w, r, connection, share.Paths[0], files, false)
+	if len(uploaded) > 0 {
+		dataprovider.UpdateShareLastUse(&share, len(uploaded)) //nolint:errcheck
+		for _, f := range uploaded {
+			upload := dataprovider.ShareUpload{
+				ShareID:  share.ShareID,
+				Path:     path.Join(share.Paths[0], f.Filename),
+				Size:     f.Size,
+				IP:       connection.GetRemoteIP(),
+				Uploader: uploader,
+			}
+			if err := dataprovider.AddShareUpload(&upload); err != nil {
+				connection.Log(logger.LevelWarn, "unable to save upload %#v for share %#v: %v", upload.Path,
+					share.ShareID, err)
+			}
+		}
+	}
+	if len(uploaded) == len(files) {
+		sendAPIResponse(w, r, nil, "Upload completed", http.StatusCreated)
+	}
+}
+
+func doDownloadFromShare(w http.ResponseWriter, r *http.Request, share dataprovider.Share, connection *Connection,
+	isWebClient bool,
+) {
+	common.Connections.Add(connection)
+	defer common.Connections.Remove(connection.GetID())
+
+	compress := true
+	var info os.FileInfo
+	if len(share.Paths) == 1 && r.URL.Query().Get("compress") == "false" {
+		var err error
+		info, err = connection.Stat(share.Paths[0], 1)
+		if err != nil {
+			sendShareError(w, r, err, "Unable to stat the shared path", getMappedStatusCode(err), isWebClient)
+			return
+		}
+		if info.Mode().IsRegular() {
+			compress = false
+		}
+	}
+
+	if compress {
+		dataprovider.UpdateShareLastUse(&share, 1) //nolint:errcheck
+		baseDir, files := getShareZipEntries(share.Paths)
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"share-%v.zip\"", share.ShareID))
+		renderCompressedFiles(w, connection, baseDir, files)
+		return
+	}
+	status, err := downloadFile(w, r, connection, share.Paths[0], info)
+	if err != nil {
+		if status > 0 {
+			sendShareError(w, r, err, http.StatusText(status), status, isWebClient)
+		}
+		return
+	}
+	dataprovider.UpdateShareLastUse(&share, 1) //nolint:errcheck
+}
+
+// getShareZipEntries returns the base directory and the entries to add to the
+// zip archive. If all the shared paths have the same parent directory we use
+// it as base directory, so the archive will not contain the full paths
+func getShareZipEntries(paths []string) (string, []string) {
+	baseDir := path.Dir(paths[0])
+	for _, p := range paths[1:] {
+		if path.Dir(p) != baseDir {
+			return "/", paths
+		}
+	}
+	files := make([]string, 0, len(paths))
+	for _, p := range paths {
+		files = append(files, path.Base(p))
+	}
+	return baseDir, files
+}
+
+func sendShareError(w http.ResponseWriter, r *http.Request, err error, message string, code int, isWebClient bool) {
+	if isWebClient {
+		renderClientMessagePage(w, r, message, "", code, err, "")
+		return
+	}
+	sendAPIResponse(w, r, err, message, code)
+}
+
+func checkPublicShare(w http.ResponseWriter, r *http.Request, validScopes []dataprovider.ShareScope,
+	isWebClient bool,
+) (dataprovider.Share, *Connection, error) {
+	shareID := getURLParam(r, "id")
+	share, err := dataprovider.ShareExists(shareID, "")
+	if err != nil {
+		sendShareError(w, r, err, "", getRespStatus(err), isWebClient)
+		return share, nil, err
+	}
+	if !isShareScopeValid(share.Scope, validScopes) {
+		err = errors.New("invalid share scope")
+		sendShareError(w, r, err, "", http.StatusForbidden, isWebClient)
+		return share, nil, err
+	}
+	ipAddr := util.GetIPFromRemoteAddress(r.RemoteAddr)
+	ok, err := share.IsUsable(ipAddr)
+	if !ok || err != nil {
+		status := getRespStatus(err)
+		if errors.Is(err, dataprovider.ErrLoginNotAllowedFromIP) {
+			status = http.StatusForbidden
+		}
+		sendShareError(w, r, err, "", status, isWebClient)
+		return share, nil, errors.New("login not allowed")
+	}
+	if share.Password != "" {
+		_, password, ok := r.BasicAuth()
+		if !ok {
+			w.Header().Set(common.HTTPAuthenticationHeader, shareBasicRealm)
+			sendShareError(w, r, nil, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized, isWebClient)
+			return share, nil, dataprovider.ErrInvalidCredentials
+		}
+		match, err := share.CheckPassword(password)
+		if !match || err != nil {
+			common.AddDefenderEvent(ipAddr, common.HostEventLoginFailed)
+			w.Header().Set(common.HTTPAuthenticationHeader, shareBasicRealm)
+			sendShareError(w, r, nil, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized, isWebClient)
+			return share, nil, dataprovider.ErrInvalidCredentials
+		}
+	}
+	user, err := dataprovider.GetUserWithGroupSettings(share.Username)
+	if err != nil {
+		sendShareError(w, r, err, "", getRespStatus(err), isWebClient)
+		return share, nil, err
+	}
+	connID := xid.New().String()
+	connectionID := fmt.Sprintf("%v_%v", common.ProtocolHTTPShare, connID)
+	if err := checkHTTPShareUser(&user, r, connectionID); err != nil {
+		sendShareError(w, r, err, "", http.StatusForbidden, isWebClient)
+		return share, nil, err
+	}
+	connection := &Connection{
+		BaseConnection: common.NewBaseConnection(connID, common.ProtocolHTTPShare, util.GetHTTPLocalAddress(r),
+			r.RemoteAddr, user),
+		request: r,
+	}
+
+	return share, connection, nil
+}
+
+func isShareScopeValid(scope dataprovider.ShareScope, validScopes []dataprovider.ShareScope) bool {
+	for _, s := range validScopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// checkHTTPShareUser checks if the share owner is still allowed to use the
+// shared contents
+func checkHTTPShareUser(user *dataprovider.User, r *http.Request, connectionID string) error {
+	if err := user.CheckLoginConditions(); err != nil {
+		logger.Debug(logSender, connectionID, "share owner %#v cannot login: %v", user.Username, err)
+		return errors.New("the owner of this share cannot login")
+	}
+	if !user.CanManageShares() {
+		logger.Debug(logSender, connectionID, "share owner %#v is not allowed to share files", user.Username)
+		return errors.New("the owner of this share is not allowed to share files")
+	}
+	if util.IsStringInSlice(common.ProtocolHTTP, user.Filters.DeniedProtocols) {
+		logger.Debug(logSender, connectionID, "cannot use share from user %#v, protocol HTTP is not allowed",
+			user.Username)
+		return fmt.Errorf("protocol HTTP is not allowed for user %#v", user.Username)
+	}
+	if user.MaxSessions > 0 {
+		activeSessions := common.Connections.GetActiveSessions(user.Username)
+		if activeSessions >= user.MaxSessions {
+			logger.Debug(logSender, connectionID, "share refused for user %#v, too many open sessions: %v/%v",
+				user.Username, activeSessions, user.MaxSessions)
+			return fmt.Errorf("too many open sessions: %v", activeSessions)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,456 @@
+package httpd
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/xid"
+
+	"github.com/drakkan/sftpgo/v2/common"
+	"github.com/drakkan/sftpgo/v2/dataprovider"
+	"github.com/drakkan/sftpgo/v2/logger"
+	"github.com/drakkan/sftpgo/v2/util"
+	"github.com/drakkan/sftpgo/v2/vfs"
+)
+
+// resumable uploads are implemented using the tus protocol, see https://tus.io/protocols/resumable-upload.html
+const (
+	tusResumableVersion    = "1.0.0"
+	tusExtensions          = "creation,creation-with-upload,expiration,termination"
+	tusOffsetContentType   = "application/offset+octet-stream"
+	tusUploadExpiration    = 24 * time.Hour
+	tusMaxUploadsPerUser   = 100
+	tusResumableHeader     = "Tus-Resumable"
+	tusVersionHeader       = "Tus-Version"
+	tusExtensionHeader     = "Tus-Extension"
+	tusMaxSizeHeader       = "Tus-Max-Size"
+	tusUploadOffsetHeader  = "Upload-Offset"
+	tusUploadLengthHeader  = "Upload-Length"
+	tusUploadMetaHeader    = "Upload-Metadata"
+	tusUploadExpiresHeader = "Upload-Expires"
+)
+
+var (
+	errTusOffsetMismatch = errors.New("upload offset mismatch")
+	// locks for the uploads handled by this instance, the upload ID is the key.
+	// The upload sessions are stored in the data provider so they survive
+	// restarts and can be resumed using any instance. The temporary file size
+	// is used as offset, chunks sent with a stale offset are rejected
+	tusUploadLocks sync.Map
+)
+
+// lockTusUpload serializes the requests for the same upload, the returned
+// function releases the lock
+func lockTusUpload(uploadID string) func() {
+	val, _ := tusUploadLocks.LoadOrStore(uploadID, &sync.Mutex{})
+	mu := val.(*sync.Mutex)
+	mu.Lock()
+	return mu.Unlock
+}
+
+func getTusUpload(uploadID, username string) (dataprovider.TusUpload, error) {
+	upload, err := dataprovider.GetTusUpload(uploadID)
+	if err != nil {
+		return upload, err
+	}
+	if upload.Username != username || upload.IsExpired(time.Now()) {
+		return upload, util.NewRecordNotFoundError(fmt.Sprintf("upload %#v does not exist", uploadID))
+	}
+	return upload, nil
+}
+
+// getAndLockTusUpload returns the specified upload after acquiring its lock,
+// the lock must be released using the returned function
+func getAndLockTusUpload(uploadID, username string) (dataprovider.TusUpload, func(), error) {
+	// check the upload before locking, so we don't add locks for missing uploads
+	if _, err := getTusUpload(uploadID, username); err != nil {
+		return dataprovider.TusUpload{}, nil, err
+	}
+	unlock := lockTusUpload(uploadID)
+	// the upload could be completed or removed while we were waiting for the lock
+	upload, err := getTusUpload(uploadID, username)
+	if err != nil {
+		unlock()
+		return upload, nil, err
+	}
+	return upload, unlock, nil
+}
+
+// deleteTusUploadSession removes the upload session, the upload lock must be held
+func deleteTusUploadSession(uploadID string) error {
+	tusUploadLocks.Delete(uploadID)
+	return dataprovider.DeleteTusUpload(uploadID)
+}
+
+func getTusUploadOffset(fs vfs.Fs, fsPath string) (int64, error) {
+	info, err := fs.Stat(fsPath)
+	if err != nil {
+		if fs.IsNotExist(err) {
+			return 0, nil
+		}
+		return 0, err
+	}
+	return info.Size(), nil
+}
+
+// parseTusMetadata parses the Upload-Metadata header, it consists of comma
+// separated key-value pairs, the value is base64 encoded and optional
+func parseTusMetadata(header string) (map[string]string, error) {
+	metadata := make(map[string]string)
+	for _, pair := range strings.Split(header, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		parts := strings.SplitN(pair, " ", 2)
+		key := strings.TrimSpace(parts[0])
+		value := ""
+		if len(parts) == 2 {
+			decoded, err := base64.StdEncoding.DecodeString(strings.TrimSpace(parts[1]))
+			if err != nil {
+				return nil, fmt.Errorf("invalid metadata value for key %#v: %w", key, err)
+			}
+			value = string(decoded)
+		}
+		metadata[key] = value
+	}
+	return metadata, nil
+}
+
+func setTusHeaders(w http.ResponseWriter) {
+	w.Header().Set(tusResumableHeader, tusResumableVersion)
+	w.Header().Set("Cache-Control", "no-store")
+}
+
+// checkTusResumable checks the protocol version requested by the client
+func checkTusResumable(w http.ResponseWriter, r *http.Request) bool {
+	setTusHeaders(w)
+	if r.Header.Get(tusResumableHeader) != tusResumableVersion {
+		w.Header().Set(tusVersionHeader, tusResumableVersion)
+		sendAPIResponse(w, r, nil, "Unsupported tus protocol version", http.StatusPreconditionFailed)
+		return false
+	}
+	return true
+}
+
+func getTusUploadsOptions(w http.ResponseWriter, r *http.Request) {
+	setTusHeaders(w)
+	w.Header().Set(tusVersionHeader, tusResumableVersion)
+	w.Header().Set(tusExtensionHeader, tusExtensions)
+	if maxUploadFileSize > 0 {
+		w.Header().Set(tusMaxSizeHeader, strconv.FormatInt(maxUploadFileSize, 10))
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func createTusUpload(w http.ResponseWriter, r *http.Request) {
+	if maxUploadFileSize > 0 {
+		r.Body = http.MaxBytesReader(w, r.Body, maxUploadFileSize)
+	}
+	if !checkTusResumable(w, r) {
+		return
+	}
+	length, err := strconv.ParseInt(r.Header.Get(tusUploadLengthHeader), 10, 64)
+	if err != nil || length < 0 {
+		sendAPIResponse(w, r, err, "Invalid Upload-Length header", http.StatusBadRequest)
+		return
+	}
+	if maxUploadFileSize > 0 && length > maxUploadFileSize {
+		sendAPIResponse(w, r, nil, "The upload length exceeds the maximum allowed size", http.StatusRequestEntityTooLarge)
+		return
+	}
+	metadata, err := parseTusMetadata(r.Header.Get(tusUploadMetaHeader))
+	if err != nil {
+		sendAPIResponse(w, r, err, "Invalid Upload-Metadata header", http.StatusBadRequest)
+		return
+	}
+	fileName := path.Base(util.CleanPath(metadata["filename"]))
+	if fileName == "/" {
+		sendAPIResponse(w, r, nil, "The filename is required in the Upload-Metadata header", http.StatusBadRequest)
+		return
+	}
+
+	connection, err := getUserConnection(w, r)
+	if err != nil {
+		return
+	}
+	common.Connections.Add(connection)
+	defer common.Connections.Remove(connection.GetID())
+
+	numUploads, err := dataprovider.GetTusUploadsCount(connection.User.Username)
+	if err != nil {
+		sendAPIResponse(w, r, err, "Unable to count the resumable uploads", getRespStatus(err))
+		return
+	}
+	if numUploads >= tusMaxUploadsPerUser {
+		sendAPIResponse(w, r, nil, "Too many resumable uploads in progress", http.StatusTooManyRequests)
+		return
+	}
+	filePath := path.Join(util.CleanPath(r.URL.Query().Get("path")), fileName)
+	fsPath, err := connection.getTusUploadPath(filePath, length)
+	if err != nil {
+		sendAPIResponse(w, r, err, fmt.Sprintf("Unable to upload file %#v", filePath), getTusErrorStatus(err))
+		return
+	}
+	now := time.Now()
+	upload := &dataprovider.TusUpload{
+		ID:          xid.New().String(),
+		Username:    connection.User.Username,
+		VirtualPath: filePath,
+		FsPath:      fsPath,
+		Length:      length,
+		ExpiresAt:   util.GetTimeAsMsSinceEpoch(now.Add(tusUploadExpiration)),
+		CreatedAt:   util.GetTimeAsMsSinceEpoch(now),
+		UpdatedAt:   util.GetTimeAsMsSinceEpoch(now),
+	}
+	unlock := lockTusUpload(upload.ID)
+	defer unlock()
+
+	if err := dataprovider.AddTusUpload(upload); err != nil {
+		sendAPIResponse(w, r, err, fmt.Sprintf("Unable to upload file %#v", filePath), getRespStatus(err))
+		return
+	}
+	connection.Log(logger.LevelDebug, "resumable upload %#v created for file %#v, length: %v", upload.ID,
+		filePath, length)
+
+	w.Header().Set("Location", fmt.Sprintf("%v/%v", strings.TrimSuffix(r.URL.Path, "/"), upload.ID))
+	// the upload can start with the creation request or, for empty files, it is
+	// already completed
+	if r.Header.Get("Content-Type") == tusOffsetContentType || length == 0 {
+		offset, err := writeTusChunk(connection, upload, 0, r.Body)
+		if err != nil {
+			sendAPIResponse(w, r, err, fmt.Sprintf("Unable to upload file %#v", filePath), getTusErrorStatus(err))
+			return
+		}
+		w.Header().Set(tusUploadOffsetHeader, strconv.FormatInt(offset, 10))
+	}
+	w.Header().Set(tusUploadExpiresHeader, upload.GetExpiration().UTC().Format(http.TimeFormat))
+	w.WriteHeader(http.StatusCreated)
+}
+
+func getTusUploadInfo(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxRequestSize)
+	if !checkTusResumable(w, r) {
+		return
+	}
+	connection, err := getUserConnection(w, r)
+	if err != nil {
+		return
+	}
+	common.Connections.Add(connection)
+	defer common.Connections.Remove(connection.GetID())
+
+	upload, unlock, err := getAndLockTusUpload(getURLParam(r, "id"), connection.User.Username)
+	if err != nil {
+		sendAPIResponse(w, r, err, "", getRespStatus(err))
+		return
+	}
+	defer unlock()
+
+	fs, err := connection.User.GetFilesystemForPath(upload.VirtualPath, connection.ID)
+	if err != nil {
+		sendAPIResponse(w, r, err, "", getMappedStatusCode(err))
+		return
+	}
+	offset, err := getTusUploadOffset(fs, upload.FsPath)
+	if err != nil {
+		err = connection.GetFsError(fs, err)
+		sendAPIResponse(w, r, err, "", getMappedStatusCode(err))
+		return
+	}
+	if offset != upload.Offset {
+		// a chunk was stored but the session was not updated, for example
+		// because of a restart, the temporary file is authoritative
+		if err := dataprovider.UpdateTusUploadOffset(upload.ID, offset, upload.GetExpiration()); err != nil {
+			connection.Log(logger.LevelWarn, "unable to update the offset for upload %#v: %v", upload.ID, err)
+		}
+	}
+	w.Header().Set(tusUploadOffsetHeader, strconv.FormatInt(offset, 10))
+	w.Header().Set(tusUploadLengthHeader, strconv.FormatInt(upload.Length, 10))
+	w.Header().Set(tusUploadExpiresHeader, upload.GetExpiration().UTC().Format(http.TimeFormat))
+	w.WriteHeader(http.StatusOK)
+}
+
+func uploadTusChunk(w http.ResponseWriter, r *http.Request) {
+	if maxUploadFileSize > 0 {
+		r.Body = http.MaxBytesReader(w, r.Body, maxUploadFileSize)
+	}
+	if !checkTusResumable(w, r) {
+		return
+	}
+	if r.Header.Get("Content-Type") != tusOffsetContentType {
+		sendAPIResponse(w, r, nil, fmt.Sprintf("Content-Type must be %#v", tusOffsetContentType),
+			http.StatusUnsupportedMediaType)
+		return
+	}
+	offset, err := strconv.ParseInt(r.Header.Get(tusUploadOffsetHeader), 10, 64)
+	if err != nil || offset < 0 {
+		sendAPIResponse(w, r, err, "Invalid Upload-Offset header", http.StatusBadRequest)
+		return
+	}
+	connection, err := getUserConnection(w, r)
+	if err != nil {
+		return
+	}
+	common.Connections.Add(connection)
+	defer common.Connections.Remove(connection.GetID())
+
+	upload, unlock, err := getAndLockTusUpload(getURLParam(r, "id"), connection.User.Username)
+	if err != nil {
+		sendAPIResponse(w, r, err, "", getRespStatus(err))
+		return
+	}
+	defer unlock()
+
+	offset, err = writeTusChunk(connection, &upload, offset, r.Body)
+	if err != nil {
+		sendAPIResponse(w, r, err, fmt.Sprintf("Unable to upload file %#v", upload.VirtualPath),
+			getTusErrorStatus(err))
+		return
+	}
+	w.Header().Set(tusUploadOffsetHeader, strconv.FormatInt(offset, 10))
+	if offset < upload.Length {
+		w.Header().Set(tusUploadExpiresHeader, upload.GetExpiration().UTC().Format(http.TimeFormat))
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func deleteTusUpload(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxRequestSize)
+	if !checkTusResumable(w, r) {
+		return
+	}
+	connection, err := getUserConnection(w, r)
+	if err != nil {
+		return
+	}
+	common.Connections.Add(connection)
+	defer common.Connections.Remove(connection.GetID())
+
+	upload, unlock, err := getAndLockTusUpload(getURLParam(r, "id"), connection.User.Username)
+	if err != nil {
+		sendAPIResponse(w, r, err, "", getRespStatus(err))
+		return
+	}
+	defer unlock()
+
+	if err := deleteTusUploadSession(upload.ID); err != nil {
+		sendAPIResponse(w, r, err, "", getRespStatus(err))
+		return
+	}
+	removeTusUploadFile(&connection.User, &upload)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// writeTusChunk appends the data read from the given reader to the specified
+// upload and returns the new offset. If the upload is complete the file is
+// moved to its final path, the quota is updated and the upload action executed.
+// Each stored chunk extends the upload expiration, so slow uploads that are still
+// making progress are not removed while in progress
+func writeTusChunk(connection *Connection, upload *dataprovider.TusUpload, offset int64, reader io.Reader) (int64, error) {
+	if offset > upload.Length {
+		return offset, errTusOffsetMismatch
+	}
+	file, err := connection.getTusChunkWriter(upload, offset)
+	if err != nil {
+		return offset, err
+	}
+	_, err = io.Copy(file, io.LimitReader(reader, upload.Length-offset))
+	if err != nil {
+		file.TransferError(err)
+		file.Close() //nolint:errcheck
+		return offset, err
+	}
+	offset += file.GetSize()
+	if offset == upload.Length {
+		if file.InitialSize > 0 {
+			// the target file will be overwritten, update the quota as for the
+			// other uploads to local and SFTP filesystems
+			connection.releaseOverwrittenSize(upload.VirtualPath, file.InitialSize)
+			file.InitialSize = 0
+		}
+		file.SetIncompleteUpload(false)
+		if err := deleteTusUploadSession(upload.ID); err != nil {
+			connection.Log(logger.LevelWarn, "unable to remove completed upload %#v: %v", upload.ID, err)
+		}
+		connection.Log(logger.LevelDebug, "resumable upload %#v completed for file %#v", upload.ID,
+			upload.VirtualPath)
+		return offset, file.Close()
+	}
+	if err := file.Close(); err != nil {
+		return offset, err
+	}
+	upload.Offset = offset
+	upload.ExpiresAt = util.GetTimeAsMsSinceEpoch(time.Now().Add(tusUploadExpiration))
+	if err := dataprovider.UpdateTusUploadOffset(upload.ID, offset, upload.GetExpiration()); err != nil {
+		connection.Log(logger.LevelWarn, "unable to update the offset for upload %#v: %v", upload.ID, err)
+	}
+	return offset, nil
+}
+
+func getTusErrorStatus(err error) int {
+	if errors.Is(err, errTusOffsetMismatch) {
+		return http.StatusConflict
+	}
+	if errors.Is(err, common.ErrQuotaExceeded) {
+		return http.StatusRequestEntityTooLarge
+	}
+	if errors.Is(err, common.ErrOpUnsupported) {
+		// the clients can fall back to a non resumable upload
+		return http.StatusNotImplemented
+	}
+	return getMappedStatusCode(err)
+}
+
+func removeTusUploadFile(user *dataprovider.User, upload *dataprovider.TusUpload) {
+	fs, err := user.GetFilesystemForPath(upload.VirtualPath, "")
+	if err != nil {
+		logger.Warn(logSender, "", "unable to get the filesystem to remove the resumable upload %#v: %v",
+			upload.ID, err)
+		return
+	}
+	if err := fs.Remove(upload.FsPath, false); err != nil && !fs.IsNotExist(err) {
+		logger.Warn(logSender, "", "unable to remove the temporary file %#v for the resumable upload %#v: %v",
+			upload.FsPath, upload.ID, err)
+	}
+}
+
+func cleanupExpiredTusUploads() {
+	uploads, err := dataprovider.GetExpiredTusUploads(time.Now())
+	if err != nil {
+		logger.Warn(logSender, "", "unable to get the expired resumable uploads: %v", err)
+		return
+	}
+	for idx := range uploads {
+		cleanupExpiredTusUpload(&uploads[idx])
+	}
+}
+
+func cleanupExpiredTusUpload(upload *dataprovider.TusUpload) {
+	unlock := lockTusUpload(upload.ID)
+	defer unlock()
+
+	// the upload could be already removed by another instance
+	if err := deleteTusUploadSession(upload.ID); err != nil {
+		logger.Debug(logSender, "", "unable to remove the expired upload %#v: %v", upload.ID, err)
+		return
+	}
+	user, err := dataprovider.GetUserWithGroupSettings(upload.Username)
+	if err != nil {
+		logger.Warn(logSender, "", "unable to get user %#v to remove the expired upload %#v: %v",
+			upload.Username, upload.ID, err)
+		return
+	}
+	removeTusUploadFile(&user, upload)
+	user.CloseFs() //nolint:errcheck
+	logger.Debug(logSender, "", "expired resumable upload %#v for file %#v removed", upload.ID,
+		upload.VirtualPath)
+}
@@ -23,7 +23,11 @@ func getUsers(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	users, err := dataprovider.GetUsers(limit, offset, order)
+	admin, err := getCurrentAPIAdmin(w, r)
+	if err != nil {
+		return
+	}
+	users, err := dataprovider.GetUsersForAdmin(&admin, limit, offset, order)
 	if err == nil {
 		render.JSON(w, r, users)
 	} else {
@@ -33,7 +37,15 @@ func getUsers(w http.ResponseWriter, r *http.Request) {
 
 func getUserByUsername(w http.ResponseWriter, r *http.Request) {
 	r.Body = http.MaxBytesReader(w, r.Body, maxRequestSize)
+	admin, err := getCurrentAPIAdmin(w, r)
+	if err != nil {
+		return
+	}
 	username := getURLParam(r, "username")
+	if _, err := getUserInScope(&admin, username); err != nil {
+		sendAPIResponse(w, r, err, "", getRespStatus(err))
+		return
+	}
 	renderUser(w, r, username, http.StatusOK)
 }
 
@@ -60,12 +72,21 @@ func addUser(w http.ResponseWriter, r *http.Request) {
 		sendAPIResponse(w, r, err, "Invalid token claims", http.StatusBadRequest)
 		return
 	}
+	admin, err := getCurrentAPIAdmin(w, r)
+	if err != nil {
+		return
+	}
 	var user dataprovider.User
 	err = render.DecodeJSON(r.Body, &user)
 	if err != nil {
 		sendAPIResponse(w, r, err, "", http.StatusBadRequest)
 		return
 	}
+	user.Filters.CreatedBy = claims.Username
+	if err := admin.CheckUserScope(&user); err != nil {
+		sendAPIResponse(w, r, err, "", getRespStatus(err))
+		return
+	}
 	err = dataprovider.AddUser(&user, claims.Username, util.GetIPFromRemoteAddress(r.RemoteAddr))
 	if err != nil {
 		sendAPIResponse(w, r, err, "", getRespStatus(err))
@@ -81,8 +102,12 @@ func disableUser2FA(w http.ResponseWriter, r *http.Request) {
 		sendAPIResponse(w, r, err, "Invalid token claims", http.StatusBadRequest)
 		return
 	}
+	admin, err := getCurrentAPIAdmin(w, r)
+	if err != nil {
+		return
+	}
 	username := getURLParam(r, "username")
-	user, err := dataprovider.UserExists(username)
+	user, err := getUserInScope(&admin, username)
 	if err != nil {
 		sendAPIResponse(w, r, err, "", getRespStatus(err))
 		return
@@ -116,12 +141,17 @@ func updateUser(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 	}
-	user, err := dataprovider.UserExists(username)
+	admin, err := getCurrentAPIAdmin(w, r)
+	if err != nil {
+		return
+	}
+	user, err := getUserInScope(&admin, username)
 	if err != nil {
 		sendAPIResponse(w, r, err, "", getRespStatus(err))
 		return
 	}
 	userID := user.ID
+	createdBy := user.Filters.CreatedBy
 	totpConfig := user.Filters.TOTPConfig
 	recoveryCodes := user.Filters.RecoveryCodes
 	currentPermissions := user.Permissions
@@ -132,6 +162,10 @@ func updateUser(w http.ResponseWriter, r *http.Request) {
 	currentCryptoPassphrase := user.FsConfig.CryptConfig.Passphrase
 	currentSFTPPassword := user.FsConfig.SFTPConfig.Password
 	currentSFTPKey := user.FsConfig.SFTPConfig.PrivateKey
+	currentFTPPassword := user.FsConfig.FTPConfig.Password
+	currentWebDAVPassword := user.FsConfig.WebDAVConfig.Password
+	currentWebDAVBearerToken := user.FsConfig.WebDAVConfig.BearerToken
+	currentEncryptionPassphrase := user.FsConfig.Encryption.Passphrase
 
 	user.Permissions = make(map[string][]string)
 	user.FsConfig.S3Config = vfs.S3FsConfig{}
@@ -139,6 +173,10 @@ func updateUser(w http.ResponseWriter, r *http.Request) {
 	user.FsConfig.GCSConfig = vfs.GCSFsConfig{}
 	user.FsConfig.CryptConfig = vfs.CryptFsConfig{}
 	user.FsConfig.SFTPConfig = vfs.SFTPFsConfig{}
+	user.FsConfig.FTPConfig = vfs.FTPFsConfig{}
+	user.FsConfig.WebDAVConfig = vfs.WebDAVFsConfig{}
+	user.FsConfig.ReadCache = vfs.ReadCacheConfig{}
+	user.FsConfig.Encryption = vfs.EncryptionConfig{}
 	user.Filters.TOTPConfig = sdk.TOTPConfig{}
 	user.Filters.RecoveryCodes = nil
 	user.VirtualFolders = nil
@@ -151,13 +189,19 @@ func updateUser(w http.ResponseWriter, r *http.Request) {
 	user.Username = username
 	user.Filters.TOTPConfig = totpConfig
 	user.Filters.RecoveryCodes = recoveryCodes
+	user.Filters.CreatedBy = createdBy
 	user.SetEmptySecretsIfNil()
 	// we use new Permissions if passed otherwise the old ones
 	if len(user.Permissions) == 0 {
 		user.Permissions = currentPermissions
 	}
 	updateEncryptedSecrets(&user.FsConfig, currentS3AccessSecret, currentAzAccountKey, currentAzSASUrl,
-		currentGCSCredentials, currentCryptoPassphrase, currentSFTPPassword, currentSFTPKey)
+		currentGCSCredentials, currentCryptoPassphrase, currentSFTPPassword, currentSFTPKey, currentFTPPassword,
+		currentWebDAVPassword, currentWebDAVBearerToken, currentEncryptionPassphrase)
+	if err := admin.CheckUserScope(&user); err != nil {
+		sendAPIResponse(w, r, err, "", getRespStatus(err))
+		return
+	}
 	err = dataprovider.UpdateUser(&user, claims.Username, util.GetIPFromRemoteAddress(r.RemoteAddr))
 	if err != nil {
 		sendAPIResponse(w, r, err, "", getRespStatus(err))
@@ -176,7 +220,15 @@ func deleteUser(w http.ResponseWriter, r *http.Request) {
 		sendAPIResponse(w, r, err, "Invalid token claims", http.StatusBadRequest)
 		return
 	}
+	admin, err := getCurrentAPIAdmin(w, r)
+	if err != nil {
+		return
+	}
 	username := getURLParam(r, "username")
+	if _, err := getUserInScope(&admin, username); err != nil {
+		sendAPIResponse(w, r, err, "", getRespStatus(err))
+		return
+	}
 	err = dataprovider.DeleteUser(username, claims.Username, util.GetIPFromRemoteAddress(r.RemoteAddr))
 	if err != nil {
 		sendAPIResponse(w, r, err, "", getRespStatus(err))
@@ -195,13 +247,17 @@ func disconnectUser(username string) {
 }
 
 func updateEncryptedSecrets(fsConfig *vfs.Filesystem, currentS3AccessSecret, currentAzAccountKey, currentAzSASUrl,
-	currentGCSCredentials, currentCryptoPassphrase, currentSFTPPassword, currentSFTPKey *kms.Secret) {
+	currentGCSCredentials, currentCryptoPassphrase, currentSFTPPassword, currentSFTPKey,
+	currentFTPPassword, currentWebDAVPassword, currentWebDAVBearerToken, currentEncryptionPassphrase *kms.Secret) {
 	// we use the new access secret if plain or empty, otherwise the old value
 	switch fsConfig.Provider {
 	case sdk.S3FilesystemProvider:
 		if fsConfig.S3Config.AccessSecret.IsNotPlainAndNotEmpty() {
 			fsConfig.S3Config.AccessSecret = currentS3AccessSecret
 		}
+		if fsConfig.Encryption.Passphrase.IsNotPlainAndNotEmpty() {
+			fsConfig.Encryption.Passphrase = currentEncryptionPassphrase
+		}
 	case sdk.AzureBlobFilesystemProvider:
 		if fsConfig.AzBlobConfig.AccountKey.IsNotPlainAndNotEmpty() {
 			fsConfig.AzBlobConfig.AccountKey = currentAzAccountKey
@@ -209,10 +265,16 @@ func updateEncryptedSecrets(fsConfig *vfs.Filesystem, currentS3AccessSecret, cur
 		if fsConfig.AzBlobConfig.SASURL.IsNotPlainAndNotEmpty() {
 			fsConfig.AzBlobConfig.SASURL = currentAzSASUrl
 		}
+		if fsConfig.Encryption.Passphrase.IsNotPlainAndNotEmpty() {
+			fsConfig.Encryption.Passphrase = currentEncryptionPassphrase
+		}
 	case sdk.GCSFilesystemProvider:
 		if fsConfig.GCSConfig.Credentials.IsNotPlainAndNotEmpty() {
 			fsConfig.GCSConfig.Credentials = currentGCSCredentials
 		}
+		if fsConfig.Encryption.Passphrase.IsNotPlainAndNotEmpty() {
+			fsConfig.Encryption.Passphrase = currentEncryptionPassphrase
+		}
 	case sdk.CryptedFilesystemProvider:
 		if fsConfig.CryptConfig.Passphrase.IsNotPlainAndNotEmpty() {
 			fsConfig.CryptConfig.Passphrase = currentCryptoPassphrase
@@ -224,5 +286,19 @@ func updateEncryptedSecrets(fsConfig *vfs.Filesystem, currentS3AccessSecret, cur
 		if fsConfig.SFTPConfig.PrivateKey.IsNotPlainAndNotEmpty() {
 			fsConfig.SFTPConfig.PrivateKey = currentSFTPKey
 		}
+		if fsConfig.Encryption.Passphrase.IsNotPlainAndNotEmpty() {
+			fsConfig.Encryption.Passphrase = currentEncryptionPassphrase
+		}
+	case sdk.FTPFilesystemProvider:
+		if fsConfig.FTPConfig.Password.IsNotPlainAndNotEmpty() {
+			fsConfig.FTPConfig.Password = currentFTPPassword
+		}
+	case sdk.WebDAVFilesystemProvider:
+		if fsConfig.WebDAVConfig.Password.IsNotPlainAndNotEmpty() {
+			fsConfig.WebDAVConfig.Password = currentWebDAVPassword
+		}
+		if fsConfig.WebDAVConfig.BearerToken.IsNotPlainAndNotEmpty() {
+			fsConfig.WebDAVConfig.BearerToken = currentWebDAVBearerToken
+		}
 	}
 }
@@ -31,6 +31,11 @@ type pwdChange struct {
 	NewPassword     string `json:"new_password"`
 }
 
+type pwdReset struct {
+	Code     string `json:"code"`
+	Password string `json:"password"`
+}
+
 type baseProfile struct {
 	Email           string `json:"email,omitempty"`
 	Description     string `json:"description,omitempty"`
@@ -90,6 +95,8 @@ func getMappedStatusCode(err error) int {
 		statusCode = http.StatusForbidden
 	case os.ErrNotExist:
 		statusCode = http.StatusNotFound
+	case common.ErrReadQuotaExceeded:
+		statusCode = http.StatusForbidden
 	default:
 		statusCode = http.StatusInternalServerError
 	}
@@ -125,6 +132,23 @@ func handleCloseConnection(w http.ResponseWriter, r *http.Request) {
 		sendAPIResponse(w, r, nil, "connectionID is mandatory", http.StatusBadRequest)
 		return
 	}
+	admin, err := getCurrentAPIAdmin(w, r)
+	if err != nil {
+		return
+	}
+	if admin.HasScope() {
+		found := false
+		for _, stat := range getConnectionsInScope(&admin) {
+			if stat.ConnectionID == connectionID {
+				found = true
+				break
+			}
+		}
+		if !found {
+			sendAPIResponse(w, r, nil, "Not Found", http.StatusNotFound)
+			return
+		}
+	}
 	if common.Connections.Close(connectionID) {
 		sendAPIResponse(w, r, nil, "Connection closed", http.StatusOK)
 	} else {
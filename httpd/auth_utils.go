@@ -31,6 +31,7 @@ const (
 	claimUsernameKey    = "username"
 	claimPermissionsKey = "permissions"
 	claimAPIKey         = "api_key"
+	claimMustChangePwd  = "chpwd"
 	basicRealm          = "Basic realm=\"SFTPGo\""
 )
 
@@ -48,6 +49,8 @@ type jwtTokenClaims struct {
 	Signature   string
 	Audience    string
 	APIKeyID    string
+	// MustChangePassword is true if the account can only change its password
+	MustChangePassword bool
 }
 
 func (c *jwtTokenClaims) hasUserAudience() bool {
@@ -65,6 +68,9 @@ func (c *jwtTokenClaims) asMap() map[string]interface{} {
 	if c.APIKeyID != "" {
 		claims[claimAPIKey] = c.APIKeyID
 	}
+	if c.MustChangePassword {
+		claims[claimMustChangePwd] = true
+	}
 	claims[jwt.SubjectKey] = c.Signature
 
 	return claims
@@ -102,6 +108,13 @@ func (c *jwtTokenClaims) Decode(token map[string]interface{}) {
 		}
 	}
 
+	if val, ok := token[claimMustChangePwd]; ok {
+		switch v := val.(type) {
+		case bool:
+			c.MustChangePassword = v
+		}
+	}
+
 	permissions := token[claimPermissionsKey]
 	switch v := permissions.(type) {
 	case []interface{}:
@@ -11,7 +11,10 @@ import (
 	"github.com/drakkan/sftpgo/v2/vfs"
 )
 
-var errTransferAborted = errors.New("transfer aborted")
+var (
+	errTransferAborted  = errors.New("transfer aborted")
+	errUploadInProgress = errors.New("another upload to the same path is in progress")
+)
 
 type httpdFile struct {
 	*common.BaseTransfer
@@ -50,6 +53,9 @@ func (f *httpdFile) Read(p []byte) (n int, err error) {
 	n, err = f.reader.Read(p)
 	atomic.AddInt64(&f.BytesSent, int64(n))
 
+	if err == nil {
+		err = f.CheckRead()
+	}
 	if err != nil && err != io.EOF {
 		f.TransferError(err)
 		return
@@ -69,8 +75,8 @@ func (f *httpdFile) Write(p []byte) (n int, err error) {
 	n, err = f.writer.Write(p)
 	atomic.AddInt64(&f.BytesReceived, int64(n))
 
-	if f.MaxWriteSize > 0 && err == nil && atomic.LoadInt64(&f.BytesReceived) > f.MaxWriteSize {
-		err = common.ErrQuotaExceeded
+	if err == nil {
+		err = f.CheckWrite()
 	}
 	if err != nil {
 		f.TransferError(err)
@@ -98,7 +98,14 @@ func (c *Connection) getFileReader(name string, offset int64, method string) (io
 		return nil, err
 	}
 
+	var transferQuota dataprovider.TransferQuota
 	if method != http.MethodHead {
+		transferQuota = c.GetTransferQuota(name)
+		if !transferQuota.HasDownloadSpace() {
+			c.Log(logger.LevelInfo, "denying file read due to quota limits")
+			return nil, c.GetReadQuotaExceededError()
+		}
+
 		if err := common.ExecutePreAction(&c.User, common.OperationPreDownload, p, name, c.GetProtocol(), c.GetRemoteIP(), 0, 0); err != nil {
 			c.Log(logger.LevelDebug, "download for file %#v denied by pre action: %v", name, err)
 			return nil, c.GetPermissionDeniedError()
@@ -112,7 +119,7 @@ func (c *Connection) getFileReader(name string, offset int64, method string) (io
 	}
 
 	baseTransfer := common.NewBaseTransfer(file, c.BaseConnection, cancelFn, p, p, name, common.TransferDownload,
-		0, 0, 0, false, fs)
+		0, 0, 0, false, fs, transferQuota)
 	return newHTTPDFile(baseTransfer, nil, r), nil
 }
 
@@ -168,12 +175,131 @@ func (c *Connection) getFileWriter(name string) (io.WriteCloser, error) {
 	return c.handleUploadFile(fs, p, filePath, name, false, stat.Size())
 }
 
+// getTusUploadPath checks if a resumable upload of the given size is allowed for
+// the specified path and returns the temporary path where the upload will be saved
+func (c *Connection) getTusUploadPath(name string, length int64) (string, error) {
+	c.UpdateLastActivity()
+
+	if !c.User.IsFileAllowed(name) {
+		c.Log(logger.LevelWarn, "writing file %#v is not allowed", name)
+		return "", c.GetPermissionDeniedError()
+	}
+
+	fs, p, err := c.GetFsAndResolvedPath(name)
+	if err != nil {
+		return "", err
+	}
+	if !fs.IsUploadResumeSupported() || !fs.IsAtomicUploadSupported() {
+		c.Log(logger.LevelDebug, "resumable uploads are not supported for file %#v", name)
+		return "", c.GetOpUnsupportedError()
+	}
+
+	isNewFile, fileSize, err := c.checkTusUploadTarget(fs, p, name)
+	if err != nil {
+		return "", err
+	}
+	quotaResult := c.HasSpace(isNewFile, false, name)
+	if !quotaResult.HasSpace {
+		c.Log(logger.LevelInfo, "denying file write due to quota limits")
+		return "", common.ErrQuotaExceeded
+	}
+	transferQuota := c.GetTransferQuota(name)
+	if !transferQuota.HasUploadSpace() {
+		c.Log(logger.LevelInfo, "denying file write due to transfer quota limits")
+		return "", common.ErrQuotaExceeded
+	}
+	maxWriteSize, _ := c.GetMaxWriteSize(quotaResult, false, fileSize, true)
+	if maxWriteSize > 0 && length > maxWriteSize {
+		c.Log(logger.LevelInfo, "denying file write, upload length %v exceeds the allowed size %v", length, maxWriteSize)
+		return "", common.ErrQuotaExceeded
+	}
+	err = common.ExecutePreAction(&c.User, common.OperationPreUpload, p, name, c.GetProtocol(), c.GetRemoteIP(), fileSize, os.O_TRUNC)
+	if err != nil {
+		c.Log(logger.LevelDebug, "upload for file %#v denied by pre action: %v", name, err)
+		return "", c.GetPermissionDeniedError()
+	}
+
+	return fs.GetAtomicUploadPath(p), nil
+}
+
+// getTusChunkWriter returns a writer to append data to the temporary file of
+// a resumable upload starting from the given offset
+func (c *Connection) getTusChunkWriter(upload *dataprovider.TusUpload, offset int64) (*httpdFile, error) {
+	c.UpdateLastActivity()
+
+	fs, p, err := c.GetFsAndResolvedPath(upload.VirtualPath)
+	if err != nil {
+		return nil, err
+	}
+	isNewFile, fileSize, err := c.checkTusUploadTarget(fs, p, upload.VirtualPath)
+	if err != nil {
+		return nil, err
+	}
+	currentOffset, err := getTusUploadOffset(fs, upload.FsPath)
+	if err != nil {
+		c.Log(logger.LevelError, "error performing file stat %#v: %+v", upload.FsPath, err)
+		return nil, c.GetFsError(fs, err)
+	}
+	if currentOffset != offset {
+		c.Log(logger.LevelDebug, "upload offset mismatch for file %#v, requested: %v, current: %v",
+			upload.VirtualPath, offset, currentOffset)
+		return nil, errTusOffsetMismatch
+	}
+	transferQuota := c.GetTransferQuota(upload.VirtualPath)
+	if !transferQuota.HasUploadSpace() {
+		c.Log(logger.LevelInfo, "denying file write due to transfer quota limits")
+		return nil, common.ErrQuotaExceeded
+	}
+
+	file, w, cancelFn, err := fs.Create(upload.FsPath, os.O_WRONLY|os.O_CREATE|os.O_APPEND)
+	if err != nil {
+		c.Log(logger.LevelWarn, "error opening temporary file %#v for append: %+v", upload.FsPath, err)
+		return nil, c.GetFsError(fs, err)
+	}
+
+	vfs.SetPathPermissions(fs, upload.FsPath, c.User.GetUID(), c.User.GetGID())
+
+	baseTransfer := common.NewBaseTransfer(file, c.BaseConnection, cancelFn, p, upload.FsPath, upload.VirtualPath,
+		common.TransferUpload, offset, fileSize, upload.Length-offset, isNewFile, fs, transferQuota)
+	baseTransfer.SetIncompleteUpload(true)
+	return newHTTPDFile(baseTransfer, w, nil), nil
+}
+
+// checkTusUploadTarget checks the permissions to write the target file of a
+// resumable upload, it returns if the target is a new file and the current size
+func (c *Connection) checkTusUploadTarget(fs vfs.Fs, fsPath, name string) (bool, int64, error) {
+	stat, err := fs.Lstat(fsPath)
+	if (err == nil && stat.Mode()&os.ModeSymlink != 0) || fs.IsNotExist(err) {
+		if !c.User.HasPerm(dataprovider.PermUpload, path.Dir(name)) {
+			return false, 0, c.GetPermissionDeniedError()
+		}
+		return true, 0, nil
+	}
+	if err != nil {
+		c.Log(logger.LevelError, "error performing file stat %#v: %+v", fsPath, err)
+		return false, 0, c.GetFsError(fs, err)
+	}
+	if stat.IsDir() {
+		c.Log(logger.LevelWarn, "attempted to open a directory for writing to: %#v", fsPath)
+		return false, 0, c.GetOpUnsupportedError()
+	}
+	if !c.User.HasPerm(dataprovider.PermOverwrite, path.Dir(name)) {
+		return false, 0, c.GetPermissionDeniedError()
+	}
+	return false, stat.Size(), nil
+}
+
 func (c *Connection) handleUploadFile(fs vfs.Fs, resolvedPath, filePath, requestPath string, isNewFile bool, fileSize int64) (io.WriteCloser, error) {
 	quotaResult := c.HasSpace(isNewFile, false, requestPath)
 	if !quotaResult.HasSpace {
 		c.Log(logger.LevelInfo, "denying file write due to quota limits")
 		return nil, common.ErrQuotaExceeded
 	}
+	transferQuota := c.GetTransferQuota(requestPath)
+	if !transferQuota.HasUploadSpace() {
+		c.Log(logger.LevelInfo, "denying file write due to transfer quota limits")
+		return nil, common.ErrQuotaExceeded
+	}
 	err := common.ExecutePreAction(&c.User, common.OperationPreUpload, resolvedPath, requestPath, c.GetProtocol(), c.GetRemoteIP(), fileSize, os.O_TRUNC)
 	if err != nil {
 		c.Log(logger.LevelDebug, "upload for file %#v denied by pre action: %v", requestPath, err)
@@ -191,15 +317,7 @@ func (c *Connection) handleUploadFile(fs vfs.Fs, resolvedPath, filePath, request
 	initialSize := int64(0)
 	if !isNewFile {
 		if vfs.IsLocalOrSFTPFs(fs) {
-			vfolder, err := c.User.GetVirtualFolderForPath(path.Dir(requestPath))
-			if err == nil {
-				dataprovider.UpdateVirtualFolderQuota(&vfolder.BaseVirtualFolder, 0, -fileSize, false) //nolint:errcheck
-				if vfolder.IsIncludedInUserQuota() {
-					dataprovider.UpdateUserQuota(&c.User, 0, -fileSize, false) //nolint:errcheck
-				}
-			} else {
-				dataprovider.UpdateUserQuota(&c.User, 0, -fileSize, false) //nolint:errcheck
-			}
+			c.releaseOverwrittenSize(requestPath, fileSize)
 		} else {
 			initialSize = fileSize
 		}
@@ -211,6 +329,20 @@ func (c *Connection) handleUploadFile(fs vfs.Fs, resolvedPath, filePath, request
 	vfs.SetPathPermissions(fs, filePath, c.User.GetUID(), c.User.GetGID())
 
 	baseTransfer := common.NewBaseTransfer(file, c.BaseConnection, cancelFn, resolvedPath, filePath, requestPath,
-		common.TransferUpload, 0, initialSize, maxWriteSize, isNewFile, fs)
+		common.TransferUpload, 0, initialSize, maxWriteSize, isNewFile, fs, transferQuota)
 	return newHTTPDFile(baseTransfer, w, nil), nil
 }
+
+// releaseOverwrittenSize removes the size of a file that will be overwritten
+// from the used quota
+func (c *Connection) releaseOverwrittenSize(requestPath string, fileSize int64) {
+	vfolder, err := c.User.GetVirtualFolderForPath(path.Dir(requestPath))
+	if err == nil {
+		dataprovider.UpdateVirtualFolderQuota(&vfolder.BaseVirtualFolder, 0, -fileSize, false) //nolint:errcheck
+		if vfolder.IsIncludedInUserQuota() {
+			dataprovider.UpdateUserQuota(&c.User, 0, -fileSize, false) //nolint:errcheck
+		}
+	} else {
+		dataprovider.UpdateUserQuota(&c.User, 0, -fileSize, false) //nolint:errcheck
+	}
+}
@@ -24,6 +24,7 @@ import (
 	"github.com/drakkan/sftpgo/v2/ftpd"
 	"github.com/drakkan/sftpgo/v2/logger"
 	"github.com/drakkan/sftpgo/v2/mfa"
+	"github.com/drakkan/sftpgo/v2/s3d"
 	"github.com/drakkan/sftpgo/v2/sftpd"
 	"github.com/drakkan/sftpgo/v2/util"
 	"github.com/drakkan/sftpgo/v2/webdavd"
@@ -42,6 +43,7 @@ const (
 	userPath                              = "/api/v2/users"
 	versionPath                           = "/api/v2/version"
 	folderPath                            = "/api/v2/folders"
+	groupPath                             = "/api/v2/groups"
 	serverStatusPath                      = "/api/v2/status"
 	dumpDataPath                          = "/api/v2/dumpdata"
 	loadDataPath                          = "/api/v2/loaddata"
@@ -51,6 +53,7 @@ const (
 	defenderBanTime                       = "/api/v2/defender/bantime"
 	defenderUnban                         = "/api/v2/defender/unban"
 	defenderScore                         = "/api/v2/defender/score"
+	accountLockoutsPath                   = "/api/v2/lockouts"
 	adminPath                             = "/api/v2/admins"
 	adminPwdPath                          = "/api/v2/admin/changepwd"
 	adminPwdCompatPath                    = "/api/v2/changepwd/admin"
@@ -61,6 +64,7 @@ const (
 	userDirsPath                          = "/api/v2/user/dirs"
 	userFilePath                          = "/api/v2/user/file"
 	userFilesPath                         = "/api/v2/user/files"
+	userUploadsPath                       = "/api/v2/user/uploads"
 	userStreamZipPath                     = "/api/v2/user/streamzip"
 	apiKeysPath                           = "/api/v2/apikeys"
 	adminTOTPConfigsPath                  = "/api/v2/admin/totp/configs"
@@ -74,10 +78,14 @@ const (
 	userTOTPSavePath                      = "/api/v2/user/totp/save"
 	user2FARecoveryCodesPath              = "/api/v2/user/2fa/recoverycodes"
 	userProfilePath                       = "/api/v2/user/profile"
+	userSharesPath                        = "/api/v2/user/shares"
 	retentionBasePath                     = "/api/v2/retention/users"
 	retentionChecksPath                   = "/api/v2/retention/users/checks"
 	fsEventsPath                          = "/api/v2/events/fs"
 	providerEventsPath                    = "/api/v2/events/provider"
+	eventRulesPath                        = "/api/v2/eventrules"
+	schedulesPath                         = "/api/v2/schedules"
+	sharesPath                            = "/api/v2/shares"
 	healthzPath                           = "/healthz"
 	webRootPathDefault                    = "/"
 	webBasePathDefault                    = "/web"
@@ -87,12 +95,16 @@ const (
 	webLoginPathDefault                   = "/web/admin/login"
 	webAdminTwoFactorPathDefault          = "/web/admin/twofactor"
 	webAdminTwoFactorRecoveryPathDefault  = "/web/admin/twofactor-recovery"
+	webAdminForgotPwdPathDefault          = "/web/admin/forgot-password"
+	webAdminResetPwdPathDefault           = "/web/admin/reset-password"
 	webLogoutPathDefault                  = "/web/admin/logout"
 	webUsersPathDefault                   = "/web/admin/users"
 	webUserPathDefault                    = "/web/admin/user"
 	webConnectionsPathDefault             = "/web/admin/connections"
 	webFoldersPathDefault                 = "/web/admin/folders"
 	webFolderPathDefault                  = "/web/admin/folder"
+	webGroupsPathDefault                  = "/web/admin/groups"
+	webGroupPathDefault                   = "/web/admin/group"
 	webStatusPathDefault                  = "/web/admin/status"
 	webAdminsPathDefault                  = "/web/admin/managers"
 	webAdminPathDefault                   = "/web/admin/manager"
@@ -112,10 +124,22 @@ const (
 	webTemplateFolderDefault              = "/web/admin/template/folder"
 	webDefenderPathDefault                = "/web/admin/defender"
 	webDefenderHostsPathDefault           = "/web/admin/defender/hosts"
+	webAccountLockoutsPathDefault         = "/web/admin/lockouts"
+	webAccountLockoutsListPathDefault     = "/web/admin/lockouts/accounts"
+	webEventsPathDefault                  = "/web/admin/events"
+	webEventsFsSearchPathDefault          = "/web/admin/events/fs"
+	webEventsProviderSearchPathDefault    = "/web/admin/events/provider"
+	webEventRulesPathDefault              = "/web/admin/eventrules"
+	webEventRulePathDefault               = "/web/admin/eventrule"
+	webSchedulesPathDefault               = "/web/admin/schedules"
+	webSchedulePathDefault                = "/web/admin/schedule"
 	webClientLoginPathDefault             = "/web/client/login"
 	webClientTwoFactorPathDefault         = "/web/client/twofactor"
 	webClientTwoFactorRecoveryPathDefault = "/web/client/twofactor-recovery"
+	webClientForgotPwdPathDefault         = "/web/client/forgot-password"
+	webClientResetPwdPathDefault          = "/web/client/reset-password"
 	webClientFilesPathDefault             = "/web/client/files"
+	webClientUploadsPathDefault           = "/web/client/uploads"
 	webClientEditFilePathDefault          = "/web/client/editfile"
 	webClientDirsPathDefault              = "/web/client/dirs"
 	webClientDownloadZipPathDefault       = "/web/client/downloadzip"
@@ -125,6 +149,9 @@ const (
 	webClientTOTPValidatePathDefault      = "/web/client/totp/validate"
 	webClientTOTPSavePathDefault          = "/web/client/totp/save"
 	webClientRecoveryCodesPathDefault     = "/web/client/recoverycodes"
+	webClientSharesPathDefault            = "/web/client/shares"
+	webClientSharePathDefault             = "/web/client/share"
+	webClientPubSharesPathDefault         = "/web/client/pubshares"
 	webChangeClientPwdPathDefault         = "/web/client/changepwd"
 	webClientLogoutPathDefault            = "/web/client/logout"
 	webStaticFilesPathDefault             = "/static"
@@ -144,6 +171,7 @@ var (
 	cleanupTicker                  *time.Ticker
 	cleanupDone                    chan bool
 	invalidatedJWTTokens           sync.Map
+	reservedUploadPaths            sync.Map
 	csrfTokenAuth                  *jwtauth.JWTAuth
 	webRootPath                    string
 	webBasePath                    string
@@ -153,12 +181,16 @@ var (
 	webLoginPath                   string
 	webAdminTwoFactorPath          string
 	webAdminTwoFactorRecoveryPath  string
+	webAdminForgotPwdPath          string
+	webAdminResetPwdPath           string
 	webLogoutPath                  string
 	webUsersPath                   string
 	webUserPath                    string
 	webConnectionsPath             string
 	webFoldersPath                 string
 	webFolderPath                  string
+	webGroupsPath                  string
+	webGroupPath                   string
 	webStatusPath                  string
 	webAdminsPath                  string
 	webAdminPath                   string
@@ -178,10 +210,22 @@ var (
 	webTemplateFolder              string
 	webDefenderPath                string
 	webDefenderHostsPath           string
+	webAccountLockoutsPath         string
+	webAccountLockoutsListPath     string
+	webEventsPath                  string
+	webEventsFsSearchPath          string
+	webEventsProviderSearchPath    string
+	webEventRulesPath              string
+	webEventRulePath               string
+	webSchedulesPath               string
+	webSchedulePath                string
 	webClientLoginPath             string
 	webClientTwoFactorPath         string
 	webClientTwoFactorRecoveryPath string
+	webClientForgotPwdPath         string
+	webClientResetPwdPath          string
 	webClientFilesPath             string
+	webClientUploadsPath           string
 	webClientEditFilePath          string
 	webClientDirsPath              string
 	webClientDownloadZipPath       string
@@ -192,6 +236,9 @@ var (
 	webClientTOTPValidatePath      string
 	webClientTOTPSavePath          string
 	webClientRecoveryCodesPath     string
+	webClientSharesPath            string
+	webClientSharePath             string
+	webClientPubSharesPath         string
 	webClientLogoutPath            string
 	webStaticFilesPath             string
 	// max upload size for http clients, 1GB by default
@@ -298,6 +345,7 @@ type ServicesStatus struct {
 	SSH          sftpd.ServiceStatus         `json:"ssh"`
 	FTP          ftpd.ServiceStatus          `json:"ftp"`
 	WebDAV       webdavd.ServiceStatus       `json:"webdav"`
+	S3           s3d.ServiceStatus           `json:"s3"`
 	DataProvider dataprovider.ProviderStatus `json:"data_provider"`
 	Defender     defenderStatus              `json:"defender"`
 	MFA          mfa.ServiceStatus           `json:"mfa"`
@@ -448,6 +496,7 @@ func (c *Conf) Initialize(configDir string) error {
 
 	maxUploadFileSize = c.MaxUploadFileSize
 	startCleanupTicker(tokenDuration)
+	startSchedulerTicker()
 	return <-exitChannel
 }
 
@@ -482,6 +531,7 @@ func getServicesStatus() ServicesStatus {
 		SSH:          sftpd.GetStatus(),
 		FTP:          ftpd.GetStatus(),
 		WebDAV:       webdavd.GetStatus(),
+		S3:           s3d.GetStatus(),
 		DataProvider: dataprovider.GetProviderStatus(),
 		Defender: defenderStatus{
 			IsActive: common.Config.DefenderConfig.Enabled,
@@ -516,7 +566,10 @@ func updateWebClientURLs(baseURL string) {
 	webClientLoginPath = path.Join(baseURL, webClientLoginPathDefault)
 	webClientTwoFactorPath = path.Join(baseURL, webClientTwoFactorPathDefault)
 	webClientTwoFactorRecoveryPath = path.Join(baseURL, webClientTwoFactorRecoveryPathDefault)
+	webClientForgotPwdPath = path.Join(baseURL, webClientForgotPwdPathDefault)
+	webClientResetPwdPath = path.Join(baseURL, webClientResetPwdPathDefault)
 	webClientFilesPath = path.Join(baseURL, webClientFilesPathDefault)
+	webClientUploadsPath = path.Join(baseURL, webClientUploadsPathDefault)
 	webClientEditFilePath = path.Join(baseURL, webClientEditFilePathDefault)
 	webClientDirsPath = path.Join(baseURL, webClientDirsPathDefault)
 	webClientDownloadZipPath = path.Join(baseURL, webClientDownloadZipPathDefault)
@@ -528,6 +581,9 @@ func updateWebClientURLs(baseURL string) {
 	webClientTOTPValidatePath = path.Join(baseURL, webClientTOTPValidatePathDefault)
 	webClientTOTPSavePath = path.Join(baseURL, webClientTOTPSavePathDefault)
 	webClientRecoveryCodesPath = path.Join(baseURL, webClientRecoveryCodesPathDefault)
+	webClientSharesPath = path.Join(baseURL, webClientSharesPathDefault)
+	webClientSharePath = path.Join(baseURL, webClientSharePathDefault)
+	webClientPubSharesPath = path.Join(baseURL, webClientPubSharesPathDefault)
 }
 
 func updateWebAdminURLs(baseURL string) {
@@ -541,12 +597,16 @@ func updateWebAdminURLs(baseURL string) {
 	webLoginPath = path.Join(baseURL, webLoginPathDefault)
 	webAdminTwoFactorPath = path.Join(baseURL, webAdminTwoFactorPathDefault)
 	webAdminTwoFactorRecoveryPath = path.Join(baseURL, webAdminTwoFactorRecoveryPathDefault)
+	webAdminForgotPwdPath = path.Join(baseURL, webAdminForgotPwdPathDefault)
+	webAdminResetPwdPath = path.Join(baseURL, webAdminResetPwdPathDefault)
 	webLogoutPath = path.Join(baseURL, webLogoutPathDefault)
 	webUsersPath = path.Join(baseURL, webUsersPathDefault)
 	webUserPath = path.Join(baseURL, webUserPathDefault)
 	webConnectionsPath = path.Join(baseURL, webConnectionsPathDefault)
 	webFoldersPath = path.Join(baseURL, webFoldersPathDefault)
 	webFolderPath = path.Join(baseURL, webFolderPathDefault)
+	webGroupsPath = path.Join(baseURL, webGroupsPathDefault)
+	webGroupPath = path.Join(baseURL, webGroupPathDefault)
 	webStatusPath = path.Join(baseURL, webStatusPathDefault)
 	webAdminsPath = path.Join(baseURL, webAdminsPathDefault)
 	webAdminPath = path.Join(baseURL, webAdminPathDefault)
@@ -566,6 +626,15 @@ func updateWebAdminURLs(baseURL string) {
 	webTemplateFolder = path.Join(baseURL, webTemplateFolderDefault)
 	webDefenderHostsPath = path.Join(baseURL, webDefenderHostsPathDefault)
 	webDefenderPath = path.Join(baseURL, webDefenderPathDefault)
+	webAccountLockoutsPath = path.Join(baseURL, webAccountLockoutsPathDefault)
+	webAccountLockoutsListPath = path.Join(baseURL, webAccountLockoutsListPathDefault)
+	webEventsPath = path.Join(baseURL, webEventsPathDefault)
+	webEventsFsSearchPath = path.Join(baseURL, webEventsFsSearchPathDefault)
+	webEventsProviderSearchPath = path.Join(baseURL, webEventsProviderSearchPathDefault)
+	webEventRulesPath = path.Join(baseURL, webEventRulesPathDefault)
+	webEventRulePath = path.Join(baseURL, webEventRulePathDefault)
+	webSchedulesPath = path.Join(baseURL, webSchedulesPathDefault)
+	webSchedulePath = path.Join(baseURL, webSchedulePathDefault)
 	webStaticFilesPath = path.Join(baseURL, webStaticFilesPathDefault)
 }
 
@@ -595,6 +664,8 @@ func startCleanupTicker(duration time.Duration) {
 				return
 			case <-cleanupTicker.C:
 				cleanupExpiredJWTTokens()
+				cleanupExpiredTusUploads()
+				cleanupExpiredResetCodes()
 			}
 		}
 	}()
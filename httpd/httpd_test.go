@@ -3,6 +3,7 @@ package httpd_test
 import (
 	"bytes"
 	"crypto/rand"
+	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -16,6 +17,7 @@ import (
 	"os"
 	"path"
 	"path/filepath"
+	"regexp"
 	"runtime"
 	"strconv"
 	"strings"
@@ -28,6 +30,7 @@ import (
 	_ "github.com/lib/pq"
 	"github.com/lithammer/shortuuid/v3"
 	_ "github.com/mattn/go-sqlite3"
+	"github.com/mhale/smtpd"
 	"github.com/pquerna/otp"
 	"github.com/pquerna/otp/totp"
 	"github.com/rs/xid"
@@ -48,6 +51,7 @@ import (
 	"github.com/drakkan/sftpgo/v2/sdk"
 	"github.com/drakkan/sftpgo/v2/sdk/plugin"
 	"github.com/drakkan/sftpgo/v2/sftpd"
+	"github.com/drakkan/sftpgo/v2/smtp"
 	"github.com/drakkan/sftpgo/v2/util"
 	"github.com/drakkan/sftpgo/v2/vfs"
 )
@@ -69,6 +73,9 @@ const (
 	adminPath                       = "/api/v2/admins"
 	adminPwdPath                    = "/api/v2/admin/changepwd"
 	folderPath                      = "/api/v2/folders"
+	groupPath                       = "/api/v2/groups"
+	dumpDataPath                    = "/api/v2/dumpdata"
+	loadDataPath                    = "/api/v2/loaddata"
 	activeConnectionsPath           = "/api/v2/connections"
 	serverStatusPath                = "/api/v2/status"
 	quotasBasePath                  = "/api/v2/quotas"
@@ -86,6 +93,7 @@ const (
 	userPublicKeysPath              = "/api/v2/user/publickeys"
 	userDirsPath                    = "/api/v2/user/dirs"
 	userFilesPath                   = "/api/v2/user/files"
+	userUploadsPath                 = "/api/v2/user/uploads"
 	userStreamZipPath               = "/api/v2/user/streamzip"
 	apiKeysPath                     = "/api/v2/apikeys"
 	adminTOTPConfigsPath            = "/api/v2/admin/totp/configs"
@@ -100,9 +108,13 @@ const (
 	userTOTPSavePath                = "/api/v2/user/totp/save"
 	user2FARecoveryCodesPath        = "/api/v2/user/2fa/recoverycodes"
 	userProfilePath                 = "/api/v2/user/profile"
+	userSharesPath                  = "/api/v2/user/shares"
 	retentionBasePath               = "/api/v2/retention/users"
 	fsEventsPath                    = "/api/v2/events/fs"
 	providerEventsPath              = "/api/v2/events/provider"
+	sharesPath                      = "/api/v2/shares"
+	eventRulesPath                  = "/api/v2/eventrules"
+	schedulesPath                   = "/api/v2/schedules"
 	healthzPath                     = "/healthz"
 	webBasePath                     = "/web"
 	webBasePathAdmin                = "/web/admin"
@@ -113,6 +125,8 @@ const (
 	webUserPath                     = "/web/admin/user"
 	webFoldersPath                  = "/web/admin/folders"
 	webFolderPath                   = "/web/admin/folder"
+	webGroupsPath                   = "/web/admin/groups"
+	webGroupPath                    = "/web/admin/group"
 	webConnectionsPath              = "/web/admin/connections"
 	webStatusPath                   = "/web/admin/status"
 	webAdminsPath                   = "/web/admin/managers"
@@ -124,13 +138,25 @@ const (
 	webTemplateUser                 = "/web/admin/template/user"
 	webTemplateFolder               = "/web/admin/template/folder"
 	webDefenderPath                 = "/web/admin/defender"
+	webAccountLockoutsPath          = "/web/admin/lockouts"
+	webAccountLockoutsListPath      = "/web/admin/lockouts/accounts"
+	webEventsPath                   = "/web/admin/events"
+	webEventsFsSearchPath           = "/web/admin/events/fs"
+	webEventsProviderSearchPath     = "/web/admin/events/provider"
+	webEventRulesPath               = "/web/admin/eventrules"
+	webEventRulePath                = "/web/admin/eventrule"
+	webSchedulesPath                = "/web/admin/schedules"
+	webSchedulePath                 = "/web/admin/schedule"
 	webAdminTwoFactorPath           = "/web/admin/twofactor"
 	webAdminTwoFactorRecoveryPath   = "/web/admin/twofactor-recovery"
+	webAdminForgotPwdPath           = "/web/admin/forgot-password"
+	webAdminResetPwdPath            = "/web/admin/reset-password"
 	webAdminMFAPath                 = "/web/admin/mfa"
 	webAdminTOTPSavePath            = "/web/admin/totp/save"
 	webBasePathClient               = "/web/client"
 	webClientLoginPath              = "/web/client/login"
 	webClientFilesPath              = "/web/client/files"
+	webClientUploadsPath            = "/web/client/uploads"
 	webClientEditFilePath           = "/web/client/editfile"
 	webClientDirsPath               = "/web/client/dirs"
 	webClientDownloadZipPath        = "/web/client/downloadzip"
@@ -138,11 +164,17 @@ const (
 	webClientProfilePath            = "/web/client/profile"
 	webClientTwoFactorPath          = "/web/client/twofactor"
 	webClientTwoFactorRecoveryPath  = "/web/client/twofactor-recovery"
+	webClientForgotPwdPath          = "/web/client/forgot-password"
+	webClientResetPwdPath           = "/web/client/reset-password"
 	webClientLogoutPath             = "/web/client/logout"
 	webClientMFAPath                = "/web/client/mfa"
 	webClientTOTPSavePath           = "/web/client/totp/save"
+	webClientSharesPath             = "/web/client/shares"
+	webClientSharePath              = "/web/client/share"
+	webClientPubSharesPath          = "/web/client/pubshares"
 	httpBaseURL                     = "http://127.0.0.1:8081"
 	sftpServerAddr                  = "127.0.0.1:8022"
+	smtpServerAddr                  = "127.0.0.1:3525"
 	configDir                       = ".."
 	httpsCert                       = `-----BEGIN CERTIFICATE-----
 MIICHTCCAaKgAwIBAgIUHnqw7QnB1Bj9oUsNpdb+ZkFPOxMwCgYIKoZIzj0EAwIw
@@ -188,6 +220,7 @@ var (
 	providerDriverName string
 	postConnectPath    string
 	preActionPath      string
+	lastReceivedEmail  receivedEmail
 )
 
 type fakeConnection struct {
@@ -238,6 +271,42 @@ type recoveryCode struct {
 	Used bool   `json:"used"`
 }
 
+type receivedEmail struct {
+	sync.RWMutex
+	From string
+	To   []string
+	Data []byte
+}
+
+func (e *receivedEmail) set(from string, to []string, data []byte) {
+	e.Lock()
+	defer e.Unlock()
+
+	e.From = from
+	e.To = to
+	e.Data = data
+}
+
+func (e *receivedEmail) reset() {
+	e.Lock()
+	defer e.Unlock()
+
+	e.From = ""
+	e.To = nil
+	e.Data = nil
+}
+
+func (e *receivedEmail) get() receivedEmail {
+	e.RLock()
+	defer e.RUnlock()
+
+	return receivedEmail{
+		From: e.From,
+		To:   e.To,
+		Data: e.Data,
+	}
+}
+
 func TestMain(m *testing.M) {
 	homeBasePath = os.TempDir()
 	logfilePath := filepath.Join(configDir, "sftpgo_api_test.log")
@@ -246,6 +315,12 @@ func TestMain(m *testing.M) {
 	os.Setenv("SFTPGO_DATA_PROVIDER__CREATE_DEFAULT_ADMIN", "1")
 	os.Setenv("SFTPGO_DEFAULT_ADMIN_USERNAME", "admin")
 	os.Setenv("SFTPGO_DEFAULT_ADMIN_PASSWORD", "password")
+	// the event store configuration must survive the data provider reinitializations done in some test cases
+	os.Setenv("SFTPGO_DATA_PROVIDER__EVENT_STORE__FS_EVENTS", "upload,download,delete,rename,mkdir,rmdir,ssh_cmd")
+	os.Setenv("SFTPGO_DATA_PROVIDER__EVENT_STORE__PROVIDER_EVENTS", "add,update,delete")
+	os.Setenv("SFTPGO_DATA_PROVIDER__EVENT_STORE__PROVIDER_OBJECTS", "user,group,admin,api_key,share")
+	os.Setenv("SFTPGO_DATA_PROVIDER__EVENT_MANAGER__ENABLED_COMMANDS", filepath.Join(os.TempDir(), "cmd")+","+
+		filepath.Join(os.TempDir(), "event_cmd.sh"))
 	err := config.LoadConfig(configDir, "")
 	if err != nil {
 		logger.WarnToConsole("error loading configuration: %v", err)
@@ -256,16 +331,7 @@ func TestMain(m *testing.M) {
 		logger.WarnToConsole("error getting exe path: %v", err)
 		os.Exit(1)
 	}
-	pluginsConfig := []plugin.Config{
-		{
-			Type:     "eventsearcher",
-			Cmd:      filepath.Join(wdPath, "..", "tests", "eventsearcher", "eventsearcher"),
-			AutoMTLS: true,
-		},
-	}
-	if runtime.GOOS == osWindows {
-		pluginsConfig[0].Cmd += ".exe"
-	}
+	pluginsConfig := getTestPluginsConfig(wdPath)
 	providerConf := config.GetProviderConf()
 	credentialsPath = filepath.Join(os.TempDir(), "test_credentials")
 	providerConf.CredentialsPath = credentialsPath
@@ -344,8 +410,19 @@ func TestMain(m *testing.M) {
 		}
 	}()
 
+	go func() {
+		if err := smtpd.ListenAndServe(smtpServerAddr, func(remoteAddr net.Addr, from string, to []string, data []byte) error {
+			lastReceivedEmail.set(from, to, data)
+			return nil
+		}, "SFTPGo test", "localhost"); err != nil {
+			logger.ErrorToConsole("could not start SMTP server: %v", err)
+			os.Exit(1)
+		}
+	}()
+
 	waitTCPListening(httpdConf.Bindings[0].GetAddress())
 	waitTCPListening(sftpdConf.Bindings[0].GetAddress())
+	waitTCPListening(smtpServerAddr)
 	httpd.ReloadCertificateMgr() //nolint:errcheck
 	// now start an https server
 	certPath := filepath.Join(os.TempDir(), "test.crt")
@@ -910,6 +987,374 @@ func TestLoginAdminAPITOTP(t *testing.T) {
 	assert.NoError(t, err)
 }
 
+func TestScopedAdmin(t *testing.T) {
+	group1 := dataprovider.Group{
+		Name: "scope_group1",
+	}
+	group2 := dataprovider.Group{
+		Name: "scope_group2",
+	}
+	group1, _, err := httpdtest.AddGroup(group1, http.StatusCreated)
+	assert.NoError(t, err)
+	group2, _, err = httpdtest.AddGroup(group2, http.StatusCreated)
+	assert.NoError(t, err)
+
+	a := getTestAdmin()
+	a.Username = altAdminUsername
+	a.Password = altAdminPassword
+	a.Permissions = []string{dataprovider.PermAdminAddUsers, dataprovider.PermAdminChangeUsers,
+		dataprovider.PermAdminDeleteUsers, dataprovider.PermAdminViewUsers, dataprovider.PermAdminViewConnections,
+		dataprovider.PermAdminCloseConnections, dataprovider.PermAdminQuotaScans, dataprovider.PermAdminManageSystem,
+		dataprovider.PermAdminRetentionChecks}
+	a.Filters.Scope.Groups = []string{group1.Name, "missing_group"}
+	_, resp, err := httpdtest.AddAdmin(a, http.StatusBadRequest)
+	assert.NoError(t, err, string(resp))
+	assert.Contains(t, string(resp), "missing_group")
+	a.Filters.Scope.Groups = []string{group1.Name}
+	a.Filters.Scope.UsernamePrefixes = []string{"scope_"}
+	a.Filters.Scope.CreatedUsers = true
+	a.Permissions = append(a.Permissions, dataprovider.PermAdminManageAdmins)
+	_, resp, err = httpdtest.AddAdmin(a, http.StatusBadRequest)
+	assert.NoError(t, err, string(resp))
+	assert.Contains(t, string(resp), "is not allowed for admins with a scope")
+	a.Permissions = a.Permissions[:len(a.Permissions)-1]
+	admin, _, err := httpdtest.AddAdmin(a, http.StatusCreated)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{group1.Name}, admin.Filters.Scope.Groups)
+	assert.Equal(t, []string{"scope_"}, admin.Filters.Scope.UsernamePrefixes)
+	assert.True(t, admin.Filters.Scope.CreatedUsers)
+
+	folderIn := "scope_folder_in"
+	folderOut := "scope_folder_out"
+	u1 := getTestUser()
+	u1.Username = "scope_user1"
+	u1.VirtualFolders = append(u1.VirtualFolders, vfs.VirtualFolder{
+		BaseVirtualFolder: vfs.BaseVirtualFolder{
+			Name:       folderIn,
+			MappedPath: filepath.Join(os.TempDir(), folderIn),
+		},
+		VirtualPath: "/vdir",
+	})
+	user1, _, err := httpdtest.AddUser(u1, http.StatusCreated)
+	assert.NoError(t, err)
+	assert.Equal(t, defaultTokenAuthUser, user1.Filters.CreatedBy)
+	u2 := getTestUser()
+	u2.Username = "other_user2"
+	u2.Groups = []string{group1.Name}
+	user2, _, err := httpdtest.AddUser(u2, http.StatusCreated)
+	assert.NoError(t, err)
+	u3 := getTestUser()
+	u3.Username = "other_user3"
+	u3.Groups = []string{group2.Name}
+	u3.VirtualFolders = append(u3.VirtualFolders, vfs.VirtualFolder{
+		BaseVirtualFolder: vfs.BaseVirtualFolder{
+			Name:       folderOut,
+			MappedPath: filepath.Join(os.TempDir(), folderOut),
+		},
+		VirtualPath: "/vdir",
+	})
+	user3, _, err := httpdtest.AddUser(u3, http.StatusCreated)
+	assert.NoError(t, err)
+	// "_" is not a wildcard for the username prefixes
+	u6 := getTestUser()
+	u6.Username = "scopexuser6"
+	user6, _, err := httpdtest.AddUser(u6, http.StatusCreated)
+	assert.NoError(t, err)
+
+	token, err := getJWTAPITokenFromTestServer(altAdminUsername, altAdminPassword)
+	assert.NoError(t, err)
+	// users
+	req, _ := http.NewRequest(http.MethodGet, userPath, nil)
+	setBearerForReq(req, token)
+	rr := executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
+	var users []dataprovider.User
+	err = render.DecodeJSON(rr.Body, &users)
+	assert.NoError(t, err)
+	if assert.Len(t, users, 2) {
+		assert.Equal(t, user2.Username, users[0].Username)
+		assert.Equal(t, user1.Username, users[1].Username)
+	}
+	req, _ = http.NewRequest(http.MethodGet, userPath+"?limit=1&offset=1&order=DESC", nil)
+	setBearerForReq(req, token)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
+	users = nil
+	err = render.DecodeJSON(rr.Body, &users)
+	assert.NoError(t, err)
+	if assert.Len(t, users, 1) {
+		assert.Equal(t, user2.Username, users[0].Username)
+	}
+	req, _ = http.NewRequest(http.MethodGet, path.Join(userPath, user1.Username), nil)
+	setBearerForReq(req, token)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
+	for _, method := range []string{http.MethodGet, http.MethodPut, http.MethodDelete} {
+		req, _ = http.NewRequest(method, path.Join(userPath, user3.Username), bytes.NewBuffer([]byte("{}")))
+		setBearerForReq(req, token)
+		rr = executeRequest(req)
+		checkResponseCode(t, http.StatusNotFound, rr)
+	}
+	req, _ = http.NewRequest(http.MethodPut, path.Join(userPath, user3.Username, "2fa", "disable"), nil)
+	setBearerForReq(req, token)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusNotFound, rr)
+	// quota, transfer quota and retention checks
+	req, _ = http.NewRequest(http.MethodPost, path.Join(quotasBasePath, "users", user3.Username, "scan"), nil)
+	setBearerForReq(req, token)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusNotFound, rr)
+	req, _ = http.NewRequest(http.MethodPut, path.Join(quotasBasePath, "users", user3.Username, "usage"),
+		bytes.NewBuffer([]byte(`{"used_quota_size":1,"used_quota_files":1}`)))
+	setBearerForReq(req, token)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusNotFound, rr)
+	req, _ = http.NewRequest(http.MethodGet, path.Join(quotasBasePath, "users", user3.Username, "transfer-usage"), nil)
+	setBearerForReq(req, token)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusNotFound, rr)
+	req, _ = http.NewRequest(http.MethodGet, path.Join(quotasBasePath, "users", user1.Username, "transfer-usage"), nil)
+	setBearerForReq(req, token)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
+	req, _ = http.NewRequest(http.MethodPost, path.Join(retentionBasePath, user3.Username, "check"),
+		bytes.NewBuffer([]byte(`[{"path":"/","retention":24}]`)))
+	setBearerForReq(req, token)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusNotFound, rr)
+	assert.True(t, common.QuotaScans.AddUserQuotaScan(user3.Username))
+	assert.True(t, common.QuotaScans.AddUserQuotaScan(user1.Username))
+	req, _ = http.NewRequest(http.MethodGet, quotaScanPath, nil)
+	setBearerForReq(req, token)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
+	var scans []common.ActiveQuotaScan
+	err = render.DecodeJSON(rr.Body, &scans)
+	assert.NoError(t, err)
+	if assert.Len(t, scans, 1) {
+		assert.Equal(t, user1.Username, scans[0].Username)
+	}
+	assert.True(t, common.QuotaScans.RemoveUserQuotaScan(user3.Username))
+	assert.True(t, common.QuotaScans.RemoveUserQuotaScan(user1.Username))
+	// folders
+	req, _ = http.NewRequest(http.MethodGet, folderPath, nil)
+	setBearerForReq(req, token)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
+	var folders []vfs.BaseVirtualFolder
+	err = render.DecodeJSON(rr.Body, &folders)
+	assert.NoError(t, err)
+	if assert.Len(t, folders, 1) {
+		assert.Equal(t, folderIn, folders[0].Name)
+	}
+	req, _ = http.NewRequest(http.MethodGet, path.Join(folderPath, folderIn), nil)
+	setBearerForReq(req, token)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
+	for _, method := range []string{http.MethodGet, http.MethodPut, http.MethodDelete} {
+		req, _ = http.NewRequest(method, path.Join(folderPath, folderOut), bytes.NewBuffer([]byte("{}")))
+		setBearerForReq(req, token)
+		rr = executeRequest(req)
+		checkResponseCode(t, http.StatusNotFound, rr)
+	}
+	req, _ = http.NewRequest(http.MethodPost, path.Join(quotasBasePath, "folders", folderOut, "scan"), nil)
+	setBearerForReq(req, token)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusNotFound, rr)
+	// groups
+	req, _ = http.NewRequest(http.MethodGet, groupPath, nil)
+	setBearerForReq(req, token)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
+	var groups []dataprovider.Group
+	err = render.DecodeJSON(rr.Body, &groups)
+	assert.NoError(t, err)
+	if assert.Len(t, groups, 1) {
+		assert.Equal(t, group1.Name, groups[0].Name)
+		assert.Equal(t, []string{user2.Username}, groups[0].Users)
+	}
+	req, _ = http.NewRequest(http.MethodGet, path.Join(groupPath, group2.Name), nil)
+	setBearerForReq(req, token)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusNotFound, rr)
+	req, _ = http.NewRequest(http.MethodPost, groupPath, bytes.NewBuffer([]byte(`{"name":"scope_new_group"}`)))
+	setBearerForReq(req, token)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusForbidden, rr)
+	req, _ = http.NewRequest(http.MethodDelete, path.Join(groupPath, group1.Name), nil)
+	setBearerForReq(req, token)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusForbidden, rr)
+	asJSON, err := json.Marshal(group1)
+	assert.NoError(t, err)
+	req, _ = http.NewRequest(http.MethodPut, path.Join(groupPath, group1.Name), bytes.NewBuffer(asJSON))
+	setBearerForReq(req, token)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
+	// add and update users
+	u4 := getTestUser()
+	u4.Username = "created_user4"
+	asJSON, err = json.Marshal(u4)
+	assert.NoError(t, err)
+	req, _ = http.NewRequest(http.MethodPost, userPath, bytes.NewBuffer(asJSON))
+	setBearerForReq(req, token)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusCreated, rr)
+	user4, _, err := httpdtest.GetUserByUsername(u4.Username, http.StatusOK)
+	assert.NoError(t, err)
+	assert.Equal(t, altAdminUsername, user4.Filters.CreatedBy)
+	u5 := getTestUser()
+	u5.Username = "created_user5"
+	u5.Groups = []string{group2.Name}
+	asJSON, err = json.Marshal(u5)
+	assert.NoError(t, err)
+	req, _ = http.NewRequest(http.MethodPost, userPath, bytes.NewBuffer(asJSON))
+	setBearerForReq(req, token)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusBadRequest, rr)
+	assert.Contains(t, rr.Body.String(), "is not within your scope")
+	u5.Groups = nil
+	u5.VirtualFolders = append(u5.VirtualFolders, vfs.VirtualFolder{
+		BaseVirtualFolder: vfs.BaseVirtualFolder{
+			Name: folderOut,
+		},
+		VirtualPath: "/vdir",
+	})
+	asJSON, err = json.Marshal(u5)
+	assert.NoError(t, err)
+	req, _ = http.NewRequest(http.MethodPost, userPath, bytes.NewBuffer(asJSON))
+	setBearerForReq(req, token)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusBadRequest, rr)
+	assert.Contains(t, rr.Body.String(), "is not within your scope")
+	// the created_by field cannot be changed
+	req, _ = http.NewRequest(http.MethodPut, path.Join(userPath, user4.Username),
+		bytes.NewBuffer([]byte(`{"filters":{"created_by":"other_admin"}}`)))
+	setBearerForReq(req, token)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
+	user4, _, err = httpdtest.GetUserByUsername(u4.Username, http.StatusOK)
+	assert.NoError(t, err)
+	assert.Equal(t, altAdminUsername, user4.Filters.CreatedBy)
+	req, _ = http.NewRequest(http.MethodGet, userPath+"?limit=2&offset=1", nil)
+	setBearerForReq(req, token)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
+	users = nil
+	err = render.DecodeJSON(rr.Body, &users)
+	assert.NoError(t, err)
+	if assert.Len(t, users, 2) {
+		assert.Equal(t, user2.Username, users[0].Username)
+		assert.Equal(t, user1.Username, users[1].Username)
+	}
+	req, _ = http.NewRequest(http.MethodGet, userPath+"?limit=1&order=ASC", nil)
+	setBearerForReq(req, token)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
+	users = nil
+	err = render.DecodeJSON(rr.Body, &users)
+	assert.NoError(t, err)
+	if assert.Len(t, users, 1) {
+		assert.Equal(t, user4.Username, users[0].Username)
+	}
+	// user2 cannot be moved outside the scope
+	req, _ = http.NewRequest(http.MethodPut, path.Join(userPath, user2.Username),
+		bytes.NewBuffer([]byte(`{"groups":["scope_group2"]}`)))
+	setBearerForReq(req, token)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusBadRequest, rr)
+	// connections
+	c1 := common.NewBaseConnection("scopeConnID1", common.ProtocolSFTP, "", "", user1)
+	fakeConn1 := &fakeConnection{
+		BaseConnection: c1,
+	}
+	common.Connections.Add(fakeConn1)
+	c3 := common.NewBaseConnection("scopeConnID3", common.ProtocolSFTP, "", "", user3)
+	fakeConn3 := &fakeConnection{
+		BaseConnection: c3,
+	}
+	common.Connections.Add(fakeConn3)
+	req, _ = http.NewRequest(http.MethodGet, activeConnectionsPath, nil)
+	setBearerForReq(req, token)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
+	var stats []common.ConnectionStatus
+	err = render.DecodeJSON(rr.Body, &stats)
+	assert.NoError(t, err)
+	if assert.Len(t, stats, 1) {
+		assert.Equal(t, user1.Username, stats[0].Username)
+	}
+	req, _ = http.NewRequest(http.MethodDelete, path.Join(activeConnectionsPath, fakeConn3.GetID()), nil)
+	setBearerForReq(req, token)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusNotFound, rr)
+	assert.Len(t, common.Connections.GetStats(), 2)
+	req, _ = http.NewRequest(http.MethodDelete, path.Join(activeConnectionsPath, fakeConn1.GetID()), nil)
+	setBearerForReq(req, token)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
+	common.Connections.Remove(fakeConn3.GetID())
+	assert.Len(t, common.Connections.GetStats(), 0)
+	// dump and restore
+	keyIn, _, _, err := httpdtest.AddUserS3AccessKey(user1.Username, "", http.StatusCreated)
+	assert.NoError(t, err)
+	_, _, _, err = httpdtest.AddUserS3AccessKey(user3.Username, "", http.StatusCreated)
+	assert.NoError(t, err)
+	req, _ = http.NewRequest(http.MethodGet, dumpDataPath+"?output-data=1", nil)
+	setBearerForReq(req, token)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
+	var backup dataprovider.BackupData
+	err = json.Unmarshal(rr.Body.Bytes(), &backup)
+	assert.NoError(t, err)
+	assert.Len(t, backup.Users, 3)
+	assert.False(t, backup.HasFolder(folderOut))
+	assert.True(t, backup.HasFolder(folderIn))
+	assert.Len(t, backup.Folders, 1)
+	if assert.Len(t, backup.Groups, 1) {
+		assert.Equal(t, group1.Name, backup.Groups[0].Name)
+	}
+	assert.Len(t, backup.Admins, 0)
+	assert.Len(t, backup.APIKeys, 0)
+	if assert.Len(t, backup.S3AccessKeys, 1) {
+		assert.Equal(t, keyIn, backup.S3AccessKeys[0].AccessKeyID)
+		assert.Equal(t, user1.Username, backup.S3AccessKeys[0].Username)
+	}
+	for _, u := range backup.Users {
+		assert.NotEqual(t, user3.Username, u.Username)
+	}
+	req, _ = http.NewRequest(http.MethodPost, loadDataPath, bytes.NewBuffer(rr.Body.Bytes()))
+	setBearerForReq(req, token)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusForbidden, rr)
+	// scope changes apply without a new login
+	admin.Filters.Scope.UsernamePrefixes = []string{"other_"}
+	admin, _, err = httpdtest.UpdateAdmin(admin, http.StatusOK)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"other_"}, admin.Filters.Scope.UsernamePrefixes)
+	req, _ = http.NewRequest(http.MethodGet, path.Join(userPath, user3.Username), nil)
+	setBearerForReq(req, token)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
+
+	for _, u := range []dataprovider.User{user1, user2, user3, user4, user6} {
+		_, err = httpdtest.RemoveUser(u, http.StatusOK)
+		assert.NoError(t, err)
+		err = os.RemoveAll(u.GetHomeDir())
+		assert.NoError(t, err)
+	}
+	for _, name := range []string{folderIn, folderOut} {
+		_, err = httpdtest.RemoveFolder(vfs.BaseVirtualFolder{Name: name}, http.StatusOK)
+		assert.NoError(t, err)
+	}
+	for _, g := range []dataprovider.Group{group1, group2} {
+		_, err = httpdtest.RemoveGroup(g, http.StatusOK)
+		assert.NoError(t, err)
+	}
+	_, err = httpdtest.RemoveAdmin(admin, http.StatusOK)
+	assert.NoError(t, err)
+}
+
 func TestHTTPStreamZipError(t *testing.T) {
 	user, _, err := httpdtest.AddUser(getTestUser(), http.StatusCreated)
 	assert.NoError(t, err)
@@ -1682,27 +2127,171 @@ func TestRetentionAPI(t *testing.T) {
 	assert.NoError(t, err)
 }
 
-func TestAddUserInvalidVirtualFolders(t *testing.T) {
+func TestRetentionLimitsAndArchive(t *testing.T) {
+	mappedPath := filepath.Join(os.TempDir(), "retention_archive")
+	folderName := filepath.Base(mappedPath)
 	u := getTestUser()
-	folderName := "fname"
 	u.VirtualFolders = append(u.VirtualFolders, vfs.VirtualFolder{
 		BaseVirtualFolder: vfs.BaseVirtualFolder{
-			MappedPath: filepath.Join(os.TempDir(), "mapped_dir"),
 			Name:       folderName,
+			MappedPath: mappedPath,
 		},
-		VirtualPath: "vdir", // invalid
+		VirtualPath: "/vdir",
+		QuotaSize:   -1,
+		QuotaFiles:  -1,
 	})
-	_, _, err := httpdtest.AddUser(u, http.StatusBadRequest)
+	user, _, err := httpdtest.AddUser(u, http.StatusCreated)
 	assert.NoError(t, err)
-	u.VirtualFolders = nil
-	u.VirtualFolders = append(u.VirtualFolders, vfs.VirtualFolder{
-		BaseVirtualFolder: vfs.BaseVirtualFolder{
-			MappedPath: filepath.Join(os.TempDir(), "mapped_dir"),
-			Name:       folderName,
+
+	logsDir := filepath.Join(user.HomeDir, "logs")
+	err = os.MkdirAll(filepath.Join(logsDir, "sub"), os.ModePerm)
+	assert.NoError(t, err)
+	for i := 1; i <= 6; i++ {
+		localFilePath := filepath.Join(logsDir, fmt.Sprintf("file%v", i))
+		if i == 6 {
+			localFilePath = filepath.Join(logsDir, "sub", fmt.Sprintf("file%v", i))
+		}
+		err = os.WriteFile(localFilePath, []byte("test data!"), os.ModePerm)
+		assert.NoError(t, err)
+		modTime := time.Now().Add(-time.Duration(6-i) * time.Hour)
+		if i == 6 {
+			modTime = time.Now().Add(-6 * time.Hour)
+		}
+		err = os.Chtimes(localFilePath, modTime, modTime)
+		assert.NoError(t, err)
+	}
+
+	folderRetention := []common.FolderRetention{
+		{
+			Path:        "/logs",
+			MaxFiles:    3,
+			ArchivePath: "/",
 		},
-		VirtualPath: "/", // invalid
-	})
-	_, _, err = httpdtest.AddUser(u, http.StatusBadRequest)
+	}
+	resp, err := httpdtest.StartRetentionCheck(user.Username, folderRetention, http.StatusBadRequest)
+	assert.NoError(t, err)
+	assert.Contains(t, string(resp), "cannot be inside the archive path")
+	folderRetention[0].ArchivePath = "relative"
+	resp, err = httpdtest.StartRetentionCheck(user.Username, folderRetention, http.StatusBadRequest)
+	assert.NoError(t, err)
+	assert.Contains(t, string(resp), "invalid archive path")
+	folderRetention[0].ArchivePath = ""
+	folderRetention[0].MaxFiles = -1
+	resp, err = httpdtest.StartRetentionCheck(user.Username, folderRetention, http.StatusBadRequest)
+	assert.NoError(t, err)
+	assert.Contains(t, string(resp), "invalid max files")
+	folderRetention[0].MaxFiles = 0
+	folderRetention[0].MaxSize = -1
+	resp, err = httpdtest.StartRetentionCheck(user.Username, folderRetention, http.StatusBadRequest)
+	assert.NoError(t, err)
+	assert.Contains(t, string(resp), "invalid max size")
+
+	folderRetention[0].MaxSize = 0
+	folderRetention[0].MaxFiles = 3
+	folderRetention[0].ArchivePath = "/vdir/archive"
+	asJSON, err := json.Marshal(folderRetention)
+	assert.NoError(t, err)
+	token, err := getJWTAPITokenFromTestServer(defaultTokenAuthUser, defaultTokenAuthPass)
+	assert.NoError(t, err)
+	req, _ := http.NewRequest(http.MethodPost, retentionBasePath+"/"+user.Username+"/check?dry_run=true",
+		bytes.NewBuffer(asJSON))
+	setBearerForReq(req, token)
+	rr := executeRequest(req)
+	checkResponseCode(t, http.StatusAccepted, rr)
+	assert.Eventually(t, func() bool {
+		return len(common.RetentionChecks.Get()) == 0
+	}, 1000*time.Millisecond, 50*time.Millisecond)
+	// dry run, nothing is changed
+	for i := 1; i <= 5; i++ {
+		assert.FileExists(t, filepath.Join(logsDir, fmt.Sprintf("file%v", i)))
+	}
+	assert.FileExists(t, filepath.Join(logsDir, "sub", "file6"))
+	assert.NoDirExists(t, filepath.Join(mappedPath, "archive"))
+	// the oldest files are moved to the archive path inside the virtual folder
+	_, err = httpdtest.StartRetentionCheck(user.Username, folderRetention, http.StatusAccepted)
+	assert.NoError(t, err)
+	assert.Eventually(t, func() bool {
+		return len(common.RetentionChecks.Get()) == 0
+	}, 1000*time.Millisecond, 50*time.Millisecond)
+	assert.NoFileExists(t, filepath.Join(logsDir, "sub", "file6"))
+	assert.NoFileExists(t, filepath.Join(logsDir, "file1"))
+	assert.NoFileExists(t, filepath.Join(logsDir, "file2"))
+	assert.FileExists(t, filepath.Join(mappedPath, "archive", "sub", "file6"))
+	assert.FileExists(t, filepath.Join(mappedPath, "archive", "file1"))
+	assert.FileExists(t, filepath.Join(mappedPath, "archive", "file2"))
+	for i := 3; i <= 5; i++ {
+		assert.FileExists(t, filepath.Join(logsDir, fmt.Sprintf("file%v", i)))
+	}
+	// keep at most 15 bytes, only the newest file is kept
+	folderRetention[0].MaxFiles = 0
+	folderRetention[0].MaxSize = 15
+	folderRetention[0].ArchivePath = ""
+	folderRetention[0].DeleteEmptyDirs = true
+	_, err = httpdtest.StartRetentionCheck(user.Username, folderRetention, http.StatusAccepted)
+	assert.NoError(t, err)
+	assert.Eventually(t, func() bool {
+		return len(common.RetentionChecks.Get()) == 0
+	}, 1000*time.Millisecond, 50*time.Millisecond)
+	assert.NoFileExists(t, filepath.Join(logsDir, "file3"))
+	assert.NoFileExists(t, filepath.Join(logsDir, "file4"))
+	assert.FileExists(t, filepath.Join(logsDir, "file5"))
+	assert.NoDirExists(t, filepath.Join(logsDir, "sub"))
+	// age based retention, archive inside the same storage backend
+	err = os.Chtimes(filepath.Join(logsDir, "file5"), time.Now().Add(-48*time.Hour), time.Now().Add(-48*time.Hour))
+	assert.NoError(t, err)
+	folderRetention = []common.FolderRetention{
+		{
+			Path:        "/",
+			Retention:   24,
+			ArchivePath: "/archive",
+		},
+		{
+			Path:      "/vdir",
+			Retention: 0,
+		},
+	}
+	for i := 0; i < 2; i++ {
+		_, err = httpdtest.StartRetentionCheck(user.Username, folderRetention, http.StatusAccepted)
+		assert.NoError(t, err)
+		assert.Eventually(t, func() bool {
+			return len(common.RetentionChecks.Get()) == 0
+		}, 1000*time.Millisecond, 50*time.Millisecond)
+		// the archived files are not checked again
+		assert.NoFileExists(t, filepath.Join(logsDir, "file5"))
+		assert.FileExists(t, filepath.Join(user.HomeDir, "archive", "logs", "file5"))
+	}
+
+	_, err = httpdtest.RemoveUser(user, http.StatusOK)
+	assert.NoError(t, err)
+	_, err = httpdtest.RemoveFolder(vfs.BaseVirtualFolder{Name: folderName}, http.StatusOK)
+	assert.NoError(t, err)
+	err = os.RemoveAll(user.GetHomeDir())
+	assert.NoError(t, err)
+	err = os.RemoveAll(mappedPath)
+	assert.NoError(t, err)
+}
+
+func TestAddUserInvalidVirtualFolders(t *testing.T) {
+	u := getTestUser()
+	folderName := "fname"
+	u.VirtualFolders = append(u.VirtualFolders, vfs.VirtualFolder{
+		BaseVirtualFolder: vfs.BaseVirtualFolder{
+			MappedPath: filepath.Join(os.TempDir(), "mapped_dir"),
+			Name:       folderName,
+		},
+		VirtualPath: "vdir", // invalid
+	})
+	_, _, err := httpdtest.AddUser(u, http.StatusBadRequest)
+	assert.NoError(t, err)
+	u.VirtualFolders = nil
+	u.VirtualFolders = append(u.VirtualFolders, vfs.VirtualFolder{
+		BaseVirtualFolder: vfs.BaseVirtualFolder{
+			MappedPath: filepath.Join(os.TempDir(), "mapped_dir"),
+			Name:       folderName,
+		},
+		VirtualPath: "/", // invalid
+	})
+	_, _, err = httpdtest.AddUser(u, http.StatusBadRequest)
 	assert.NoError(t, err)
 	u.VirtualFolders = nil
 	u.VirtualFolders = append(u.VirtualFolders, vfs.VirtualFolder{
@@ -2029,6 +2618,287 @@ func TestUpdateUserQuotaUsage(t *testing.T) {
 	assert.NoError(t, err)
 }
 
+func TestUpdateUserTransferQuotaUsage(t *testing.T) {
+	u := getTestUser()
+	u.UsedUploadDataTransfer = 100
+	u.UsedDownloadDataTransfer = 200
+	user, _, err := httpdtest.AddUser(u, http.StatusCreated)
+	assert.NoError(t, err)
+	// the used data transfer cannot be set adding or updating the user
+	assert.Equal(t, int64(0), user.UsedUploadDataTransfer)
+	assert.Equal(t, int64(0), user.UsedDownloadDataTransfer)
+	_, err = httpdtest.UpdateTransferQuotaUsage(u, "invalid_mode", http.StatusBadRequest)
+	assert.NoError(t, err)
+	_, err = httpdtest.UpdateTransferQuotaUsage(u, "", http.StatusOK)
+	assert.NoError(t, err)
+	ulSize, dlSize, _, err := httpdtest.GetTransferQuotaUsage(user.Username, http.StatusOK)
+	assert.NoError(t, err)
+	assert.Equal(t, u.UsedUploadDataTransfer, ulSize)
+	assert.Equal(t, u.UsedDownloadDataTransfer, dlSize)
+	user, _, err = httpdtest.GetUserByUsername(user.Username, http.StatusOK)
+	assert.NoError(t, err)
+	assert.Equal(t, u.UsedUploadDataTransfer, user.UsedUploadDataTransfer)
+	assert.Equal(t, u.UsedDownloadDataTransfer, user.UsedDownloadDataTransfer)
+	assert.Greater(t, user.LastDataTransferReset, int64(0))
+	_, err = httpdtest.UpdateTransferQuotaUsage(u, "add", http.StatusBadRequest)
+	assert.NoError(t, err, "user has no transfer quota restrictions add mode should fail")
+	user.TotalDataTransfer = 100
+	user, _, err = httpdtest.UpdateUser(user, http.StatusOK, "")
+	assert.NoError(t, err)
+	// updating the user must preserve the used data transfer
+	assert.Equal(t, u.UsedUploadDataTransfer, user.UsedUploadDataTransfer)
+	assert.Equal(t, u.UsedDownloadDataTransfer, user.UsedDownloadDataTransfer)
+	_, err = httpdtest.UpdateTransferQuotaUsage(u, "add", http.StatusOK)
+	assert.NoError(t, err)
+	ulSize, dlSize, _, err = httpdtest.GetTransferQuotaUsage(user.Username, http.StatusOK)
+	assert.NoError(t, err)
+	assert.Equal(t, 2*u.UsedUploadDataTransfer, ulSize)
+	assert.Equal(t, 2*u.UsedDownloadDataTransfer, dlSize)
+	u.UsedUploadDataTransfer = -1
+	_, err = httpdtest.UpdateTransferQuotaUsage(u, "", http.StatusBadRequest)
+	assert.NoError(t, err)
+	u.UsedUploadDataTransfer = 0
+	u.Username = u.Username + "1"
+	_, err = httpdtest.UpdateTransferQuotaUsage(u, "", http.StatusNotFound)
+	assert.NoError(t, err)
+	_, _, _, err = httpdtest.GetTransferQuotaUsage(u.Username, http.StatusNotFound)
+	assert.NoError(t, err)
+	_, err = httpdtest.RemoveUser(user, http.StatusOK)
+	assert.NoError(t, err)
+}
+
+func TestDataTransferLimitsValidation(t *testing.T) {
+	u := getTestUser()
+	u.UploadDataTransfer = -1
+	_, resp, err := httpdtest.AddUser(u, http.StatusBadRequest)
+	assert.NoError(t, err, string(resp))
+	u.UploadDataTransfer = 0
+	u.TotalDataTransfer = -1
+	_, resp, err = httpdtest.AddUser(u, http.StatusBadRequest)
+	assert.NoError(t, err, string(resp))
+	u.TotalDataTransfer = 0
+	u.DataTransferResetPeriod = "yearly"
+	_, resp, err = httpdtest.AddUser(u, http.StatusBadRequest)
+	assert.NoError(t, err, string(resp))
+	assert.Contains(t, string(resp), "invalid data transfer reset period")
+
+	f := vfs.BaseVirtualFolder{
+		Name:                 "vdirtransfer",
+		MappedPath:           filepath.Join(os.TempDir(), "vdirtransfer"),
+		DownloadDataTransfer: -1,
+	}
+	_, resp, err = httpdtest.AddFolder(f, http.StatusBadRequest)
+	assert.NoError(t, err, string(resp))
+	f.DownloadDataTransfer = 0
+	f.DataTransferResetPeriod = "hourly"
+	_, resp, err = httpdtest.AddFolder(f, http.StatusBadRequest)
+	assert.NoError(t, err, string(resp))
+
+	g := dataprovider.Group{
+		Name: "transfer_group",
+	}
+	g.UserSettings.UploadDataTransfer = -1
+	_, resp, err = httpdtest.AddGroup(g, http.StatusBadRequest)
+	assert.NoError(t, err, string(resp))
+	g.UserSettings.UploadDataTransfer = 10
+	g.UserSettings.DataTransferResetPeriod = sdk.DataTransferResetWeekly
+	group, resp, err := httpdtest.AddGroup(g, http.StatusCreated)
+	assert.NoError(t, err, string(resp))
+	assert.Equal(t, int64(10), group.UserSettings.UploadDataTransfer)
+	assert.Equal(t, sdk.DataTransferResetWeekly, group.UserSettings.DataTransferResetPeriod)
+	_, err = httpdtest.RemoveGroup(group, http.StatusOK)
+	assert.NoError(t, err)
+}
+
+func TestDataTransferLimits(t *testing.T) {
+	u := getTestUser()
+	u.UploadDataTransfer = 1
+	u.DownloadDataTransfer = 1
+	u.DataTransferResetPeriod = sdk.DataTransferResetMonthly
+	user, _, err := httpdtest.AddUser(u, http.StatusCreated)
+	assert.NoError(t, err)
+	webAPIToken, err := getJWTAPIUserTokenFromTestServer(defaultUsername, defaultPassword)
+	assert.NoError(t, err)
+
+	testFileName := "file.dat"
+	testFileContents := []byte("test data transfer")
+	uploadFile := func(expectedStatusCode int) *httptest.ResponseRecorder {
+		body := new(bytes.Buffer)
+		writer := multipart.NewWriter(body)
+		part, err := writer.CreateFormFile("filename", testFileName)
+		assert.NoError(t, err)
+		_, err = part.Write(testFileContents)
+		assert.NoError(t, err)
+		err = writer.Close()
+		assert.NoError(t, err)
+		req, err := http.NewRequest(http.MethodPost, userFilesPath, bytes.NewReader(body.Bytes()))
+		assert.NoError(t, err)
+		req.Header.Add("Content-Type", writer.FormDataContentType())
+		setBearerForReq(req, webAPIToken)
+		rr := executeRequest(req)
+		checkResponseCode(t, expectedStatusCode, rr)
+		return rr
+	}
+	downloadFile := func(expectedStatusCode int) *httptest.ResponseRecorder {
+		req, err := http.NewRequest(http.MethodGet, userFilesPath+"?path="+testFileName, nil)
+		assert.NoError(t, err)
+		setBearerForReq(req, webAPIToken)
+		rr := executeRequest(req)
+		checkResponseCode(t, expectedStatusCode, rr)
+		return rr
+	}
+
+	uploadFile(http.StatusCreated)
+	rr := downloadFile(http.StatusOK)
+	assert.Equal(t, testFileContents, rr.Body.Bytes())
+	ulSize, dlSize, _, err := httpdtest.GetTransferQuotaUsage(user.Username, http.StatusOK)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(len(testFileContents)), ulSize)
+	assert.Equal(t, int64(len(testFileContents)), dlSize)
+	// exhaust the upload allowance
+	user.UsedUploadDataTransfer = 1048576
+	user.UsedDownloadDataTransfer = dlSize
+	_, err = httpdtest.UpdateTransferQuotaUsage(user, "", http.StatusOK)
+	assert.NoError(t, err)
+	rr = uploadFile(http.StatusInternalServerError)
+	assert.Contains(t, rr.Body.String(), common.ErrQuotaExceeded.Error())
+	// with atomic uploads the existing file could be moved before checking the quota
+	err = os.WriteFile(filepath.Join(user.GetHomeDir(), testFileName), testFileContents, os.ModePerm)
+	assert.NoError(t, err)
+	downloadFile(http.StatusOK)
+	// exhaust the download allowance too
+	user.UsedDownloadDataTransfer = 1048576
+	_, err = httpdtest.UpdateTransferQuotaUsage(user, "", http.StatusOK)
+	assert.NoError(t, err)
+	rr = downloadFile(http.StatusForbidden)
+	assert.Contains(t, rr.Body.String(), common.ErrReadQuotaExceeded.Error())
+	// a total limit overrides the individual ones
+	user.TotalDataTransfer = 3
+	user, _, err = httpdtest.UpdateUser(user, http.StatusOK, "")
+	assert.NoError(t, err)
+	uploadFile(http.StatusCreated)
+	downloadFile(http.StatusOK)
+	user.UsedUploadDataTransfer = 2 * 1048576
+	_, err = httpdtest.UpdateTransferQuotaUsage(user, "", http.StatusOK)
+	assert.NoError(t, err)
+	downloadFile(http.StatusForbidden)
+	uploadFile(http.StatusInternalServerError)
+	err = os.WriteFile(filepath.Join(user.GetHomeDir(), testFileName), testFileContents, os.ModePerm)
+	assert.NoError(t, err)
+	// a reset restores the allowance
+	user.UsedUploadDataTransfer = 0
+	user.UsedDownloadDataTransfer = 0
+	_, err = httpdtest.UpdateTransferQuotaUsage(user, "", http.StatusOK)
+	assert.NoError(t, err)
+	downloadFile(http.StatusOK)
+
+	_, err = httpdtest.RemoveUser(user, http.StatusOK)
+	assert.NoError(t, err)
+	err = os.RemoveAll(user.GetHomeDir())
+	assert.NoError(t, err)
+}
+
+func TestDataTransferLimitsVirtualFolder(t *testing.T) {
+	mappedPath := filepath.Join(os.TempDir(), "vdirtransfer")
+	folderName := filepath.Base(mappedPath)
+	vdirPath := "/vdir"
+	f := vfs.BaseVirtualFolder{
+		Name:                    folderName,
+		MappedPath:              mappedPath,
+		DownloadDataTransfer:    1,
+		DataTransferResetPeriod: sdk.DataTransferResetDaily,
+	}
+	folder, resp, err := httpdtest.AddFolder(f, http.StatusCreated)
+	assert.NoError(t, err, string(resp))
+	u := getTestUser()
+	u.TotalDataTransfer = 100
+	u.VirtualFolders = append(u.VirtualFolders, vfs.VirtualFolder{
+		BaseVirtualFolder: folder,
+		VirtualPath:       vdirPath,
+		QuotaSize:         -1,
+		QuotaFiles:        -1,
+	})
+	user, _, err := httpdtest.AddUser(u, http.StatusCreated)
+	assert.NoError(t, err)
+	folder, _, err = httpdtest.GetFolderByName(folderName, http.StatusOK)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), folder.DownloadDataTransfer)
+	assert.Equal(t, sdk.DataTransferResetDaily, folder.DataTransferResetPeriod)
+
+	testFileName := "file.dat"
+	testFileContents := []byte("folder data transfer")
+	err = os.MkdirAll(mappedPath, os.ModePerm)
+	assert.NoError(t, err)
+	err = os.WriteFile(filepath.Join(mappedPath, testFileName), testFileContents, os.ModePerm)
+	assert.NoError(t, err)
+	err = os.MkdirAll(user.GetHomeDir(), os.ModePerm)
+	assert.NoError(t, err)
+	err = os.WriteFile(filepath.Join(user.GetHomeDir(), testFileName), testFileContents, os.ModePerm)
+	assert.NoError(t, err)
+	webAPIToken, err := getJWTAPIUserTokenFromTestServer(defaultUsername, defaultPassword)
+	assert.NoError(t, err)
+	downloadFile := func(name string, expectedStatusCode int) {
+		req, err := http.NewRequest(http.MethodGet, userFilesPath+"?path="+url.QueryEscape(name), nil)
+		assert.NoError(t, err)
+		setBearerForReq(req, webAPIToken)
+		rr := executeRequest(req)
+		checkResponseCode(t, expectedStatusCode, rr)
+	}
+	downloadFile(path.Join(vdirPath, testFileName), http.StatusOK)
+	ulSize, dlSize, _, err := httpdtest.GetFolderTransferQuotaUsage(folderName, http.StatusOK)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), ulSize)
+	assert.Equal(t, int64(len(testFileContents)), dlSize)
+	// the folder data transfer is included in the user one
+	ulSize, dlSize, _, err = httpdtest.GetTransferQuotaUsage(user.Username, http.StatusOK)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), ulSize)
+	assert.Equal(t, int64(len(testFileContents)), dlSize)
+
+	folder.UsedDownloadDataTransfer = 1048576
+	_, err = httpdtest.UpdateFolderTransferQuotaUsage(folder, "invalid", http.StatusBadRequest)
+	assert.NoError(t, err)
+	_, err = httpdtest.UpdateFolderTransferQuotaUsage(folder, "add", http.StatusOK)
+	assert.NoError(t, err)
+	ulSize, dlSize, _, err = httpdtest.GetFolderTransferQuotaUsage(folderName, http.StatusOK)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), ulSize)
+	assert.Equal(t, int64(len(testFileContents))+1048576, dlSize)
+	downloadFile(path.Join(vdirPath, testFileName), http.StatusForbidden)
+	// the limits for the folder does not apply outside the folder
+	downloadFile(testFileName, http.StatusOK)
+	// updating the folder must preserve the used data transfer
+	folder.Description = "updated"
+	_, _, err = httpdtest.UpdateFolder(folder, http.StatusOK)
+	assert.NoError(t, err)
+	_, dlSize, _, err = httpdtest.GetFolderTransferQuotaUsage(folderName, http.StatusOK)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(len(testFileContents))+1048576, dlSize)
+	_, err = httpdtest.UpdateFolderTransferQuotaUsage(folder, "", http.StatusOK)
+	assert.NoError(t, err)
+	_, dlSize, _, err = httpdtest.GetFolderTransferQuotaUsage(folderName, http.StatusOK)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1048576), dlSize)
+	folder.UsedDownloadDataTransfer = -1
+	_, err = httpdtest.UpdateFolderTransferQuotaUsage(folder, "", http.StatusBadRequest)
+	assert.NoError(t, err)
+	folder.UsedDownloadDataTransfer = 0
+	folder.Name += "_1"
+	_, err = httpdtest.UpdateFolderTransferQuotaUsage(folder, "", http.StatusNotFound)
+	assert.NoError(t, err)
+	_, _, _, err = httpdtest.GetFolderTransferQuotaUsage(folder.Name, http.StatusNotFound)
+	assert.NoError(t, err)
+
+	_, err = httpdtest.RemoveUser(user, http.StatusOK)
+	assert.NoError(t, err)
+	_, err = httpdtest.RemoveFolder(vfs.BaseVirtualFolder{Name: folderName}, http.StatusOK)
+	assert.NoError(t, err)
+	err = os.RemoveAll(user.GetHomeDir())
+	assert.NoError(t, err)
+	err = os.RemoveAll(mappedPath)
+	assert.NoError(t, err)
+}
+
 func TestUserFolderMapping(t *testing.T) {
 	mappedPath1 := filepath.Join(os.TempDir(), "mapped_dir1")
 	mappedPath2 := filepath.Join(os.TempDir(), "mapped_dir2")
@@ -2593,54 +3463,379 @@ func TestUserSFTPFs(t *testing.T) {
 	assert.NoError(t, err)
 }
 
-func TestUserHiddenFields(t *testing.T) {
-	err := dataprovider.Close()
+func TestUserFTPFs(t *testing.T) {
+	user, _, err := httpdtest.AddUser(getTestUser(), http.StatusCreated)
 	assert.NoError(t, err)
-	err = config.LoadConfig(configDir, "")
+	user.FsConfig.Provider = sdk.FTPFilesystemProvider
+	user.FsConfig.FTPConfig.Endpoint = "127.0.0.1" // missing port
+	user.FsConfig.FTPConfig.Username = "ftp_user"
+	user.FsConfig.FTPConfig.Password = kms.NewPlainSecret("ftp_pwd")
+	_, resp, err := httpdtest.UpdateUser(user, http.StatusBadRequest, "")
 	assert.NoError(t, err)
-	providerConf := config.GetProviderConf()
-	providerConf.PreferDatabaseCredentials = true
-	err = dataprovider.Initialize(providerConf, configDir, true)
+	assert.Contains(t, string(resp), "invalid endpoint")
+	user.FsConfig.FTPConfig.Endpoint = "127.0.0.1:21"
+	user.FsConfig.FTPConfig.Username = ""
+	_, resp, err = httpdtest.UpdateUser(user, http.StatusBadRequest, "")
+	assert.NoError(t, err)
+	assert.Contains(t, string(resp), "username cannot be empty")
+	user.FsConfig.FTPConfig.Username = "ftp_user"
+	user.FsConfig.FTPConfig.TLSMode = 3
+	_, resp, err = httpdtest.UpdateUser(user, http.StatusBadRequest, "")
 	assert.NoError(t, err)
+	assert.Contains(t, string(resp), "invalid tls_mode")
 
-	// sensitive data must be hidden but not deleted from the dataprovider
-	usernames := []string{"user1", "user2", "user3", "user4", "user5"}
-	u1 := getTestUser()
-	u1.Username = usernames[0]
-	u1.FsConfig.Provider = sdk.S3FilesystemProvider
-	u1.FsConfig.S3Config.Bucket = "test"
-	u1.FsConfig.S3Config.Region = "us-east-1"
-	u1.FsConfig.S3Config.AccessKey = "S3-Access-Key"
-	u1.FsConfig.S3Config.AccessSecret = kms.NewPlainSecret("S3-Access-Secret")
-	user1, _, err := httpdtest.AddUser(u1, http.StatusCreated)
+	user.FsConfig.FTPConfig.TLSMode = 1
+	user.FsConfig.FTPConfig.SkipTLSVerify = true
+	user.FsConfig.FTPConfig.DisableEPSV = true
+	user, _, err = httpdtest.UpdateUser(user, http.StatusOK, "")
 	assert.NoError(t, err)
+	assert.Equal(t, "/", user.FsConfig.FTPConfig.Prefix)
+	assert.Equal(t, 1, user.FsConfig.FTPConfig.TLSMode)
+	assert.True(t, user.FsConfig.FTPConfig.SkipTLSVerify)
+	assert.True(t, user.FsConfig.FTPConfig.DisableEPSV)
+	initialPwdPayload := user.FsConfig.FTPConfig.Password.GetPayload()
+	assert.Equal(t, kms.SecretStatusSecretBox, user.FsConfig.FTPConfig.Password.GetStatus())
+	assert.NotEmpty(t, initialPwdPayload)
+	assert.Empty(t, user.FsConfig.FTPConfig.Password.GetAdditionalData())
+	assert.Empty(t, user.FsConfig.FTPConfig.Password.GetKey())
+	user.FsConfig.FTPConfig.Password.SetStatus(kms.SecretStatusSecretBox)
+	user.FsConfig.FTPConfig.Password.SetAdditionalData("adata")
+	user.FsConfig.FTPConfig.Password.SetKey("fake pwd key")
+	user.FsConfig.FTPConfig.Prefix = "/ftp/prefix"
+	user.FsConfig.FTPConfig.DisableEPSV = false
+	user, bb, err := httpdtest.UpdateUser(user, http.StatusOK, "")
+	assert.NoError(t, err, string(bb))
+	assert.Equal(t, kms.SecretStatusSecretBox, user.FsConfig.FTPConfig.Password.GetStatus())
+	assert.Equal(t, initialPwdPayload, user.FsConfig.FTPConfig.Password.GetPayload())
+	assert.Empty(t, user.FsConfig.FTPConfig.Password.GetAdditionalData())
+	assert.Empty(t, user.FsConfig.FTPConfig.Password.GetKey())
+	assert.Equal(t, "/ftp/prefix", user.FsConfig.FTPConfig.Prefix)
+	assert.False(t, user.FsConfig.FTPConfig.DisableEPSV)
 
-	u2 := getTestUser()
-	u2.Username = usernames[1]
-	u2.FsConfig.Provider = sdk.GCSFilesystemProvider
-	u2.FsConfig.GCSConfig.Bucket = "test"
-	u2.FsConfig.GCSConfig.Credentials = kms.NewPlainSecret("fake credentials")
-	user2, _, err := httpdtest.AddUser(u2, http.StatusCreated)
+	_, err = httpdtest.RemoveUser(user, http.StatusOK)
+	assert.NoError(t, err)
+	user.Password = defaultPassword
+	user.ID = 0
+	user.CreatedAt = 0
+	user.FsConfig.Provider = sdk.FTPFilesystemProvider
+	user.FsConfig.FTPConfig.Endpoint = "127.0.0.1:21"
+	user.FsConfig.FTPConfig.Username = "ftp_user"
+	user.FsConfig.FTPConfig.Password = kms.NewSecret(kms.SecretStatusSecretBox, "invalid encrypted payload", "", "")
+	_, _, err = httpdtest.AddUser(user, http.StatusCreated)
+	assert.Error(t, err)
+	// the password is optional, for example for anonymous access
+	user.FsConfig.FTPConfig.Username = "anonymous"
+	user.FsConfig.FTPConfig.Password = kms.NewEmptySecret()
+	user, _, err = httpdtest.AddUser(user, http.StatusCreated)
 	assert.NoError(t, err)
+	assert.Nil(t, user.FsConfig.FTPConfig.Password)
 
-	u3 := getTestUser()
-	u3.Username = usernames[2]
-	u3.FsConfig.Provider = sdk.AzureBlobFilesystemProvider
-	u3.FsConfig.AzBlobConfig.Container = "test"
-	u3.FsConfig.AzBlobConfig.AccountName = "Server-Account-Name"
-	u3.FsConfig.AzBlobConfig.AccountKey = kms.NewPlainSecret("Server-Account-Key")
-	user3, _, err := httpdtest.AddUser(u3, http.StatusCreated)
+	_, err = httpdtest.RemoveUser(user, http.StatusOK)
 	assert.NoError(t, err)
+}
 
-	u4 := getTestUser()
-	u4.Username = usernames[3]
-	u4.FsConfig.Provider = sdk.CryptedFilesystemProvider
-	u4.FsConfig.CryptConfig.Passphrase = kms.NewPlainSecret("test passphrase")
-	user4, _, err := httpdtest.AddUser(u4, http.StatusCreated)
+func TestUserWebDAVFs(t *testing.T) {
+	user, _, err := httpdtest.AddUser(getTestUser(), http.StatusCreated)
+	assert.NoError(t, err)
+	user.FsConfig.Provider = sdk.WebDAVFilesystemProvider
+	user.FsConfig.WebDAVConfig.Endpoint = "ftp://127.0.0.1/dav"
+	user.FsConfig.WebDAVConfig.Username = "dav_user"
+	user.FsConfig.WebDAVConfig.Password = kms.NewPlainSecret("dav_pwd")
+	_, resp, err := httpdtest.UpdateUser(user, http.StatusBadRequest, "")
+	assert.NoError(t, err)
+	assert.Contains(t, string(resp), "invalid endpoint scheme")
+	user.FsConfig.WebDAVConfig.Endpoint = "https:///dav"
+	_, resp, err = httpdtest.UpdateUser(user, http.StatusBadRequest, "")
 	assert.NoError(t, err)
+	assert.Contains(t, string(resp), "the host is missing")
+	user.FsConfig.WebDAVConfig.Endpoint = "https://127.0.0.1/dav/"
+	user.FsConfig.WebDAVConfig.Username = ""
+	_, resp, err = httpdtest.UpdateUser(user, http.StatusBadRequest, "")
+	assert.NoError(t, err)
+	assert.Contains(t, string(resp), "username cannot be empty")
+	user.FsConfig.WebDAVConfig.Username = "dav_user"
+	user.FsConfig.WebDAVConfig.BearerToken = kms.NewPlainSecret("token")
+	_, resp, err = httpdtest.UpdateUser(user, http.StatusBadRequest, "")
+	assert.NoError(t, err)
+	assert.Contains(t, string(resp), "cannot be used together")
 
-	u5 := getTestUser()
-	u5.Username = usernames[4]
+	user.FsConfig.WebDAVConfig.BearerToken = kms.NewEmptySecret()
+	user.FsConfig.WebDAVConfig.SkipTLSVerify = true
+	user, _, err = httpdtest.UpdateUser(user, http.StatusOK, "")
+	assert.NoError(t, err)
+	assert.Equal(t, "/", user.FsConfig.WebDAVConfig.Prefix)
+	assert.True(t, user.FsConfig.WebDAVConfig.SkipTLSVerify)
+	assert.Nil(t, user.FsConfig.WebDAVConfig.BearerToken)
+	initialPwdPayload := user.FsConfig.WebDAVConfig.Password.GetPayload()
+	assert.Equal(t, kms.SecretStatusSecretBox, user.FsConfig.WebDAVConfig.Password.GetStatus())
+	assert.NotEmpty(t, initialPwdPayload)
+	assert.Empty(t, user.FsConfig.WebDAVConfig.Password.GetAdditionalData())
+	assert.Empty(t, user.FsConfig.WebDAVConfig.Password.GetKey())
+	user.FsConfig.WebDAVConfig.Password.SetStatus(kms.SecretStatusSecretBox)
+	user.FsConfig.WebDAVConfig.Password.SetAdditionalData("adata")
+	user.FsConfig.WebDAVConfig.Password.SetKey("fake pwd key")
+	user.FsConfig.WebDAVConfig.Prefix = "/dav/prefix"
+	user.FsConfig.WebDAVConfig.SkipTLSVerify = false
+	user, bb, err := httpdtest.UpdateUser(user, http.StatusOK, "")
+	assert.NoError(t, err, string(bb))
+	assert.Equal(t, kms.SecretStatusSecretBox, user.FsConfig.WebDAVConfig.Password.GetStatus())
+	assert.Equal(t, initialPwdPayload, user.FsConfig.WebDAVConfig.Password.GetPayload())
+	assert.Empty(t, user.FsConfig.WebDAVConfig.Password.GetAdditionalData())
+	assert.Empty(t, user.FsConfig.WebDAVConfig.Password.GetKey())
+	assert.Equal(t, "/dav/prefix", user.FsConfig.WebDAVConfig.Prefix)
+	assert.False(t, user.FsConfig.WebDAVConfig.SkipTLSVerify)
+	// switch to bearer authentication
+	user.FsConfig.WebDAVConfig.Username = ""
+	user.FsConfig.WebDAVConfig.Password = kms.NewEmptySecret()
+	user.FsConfig.WebDAVConfig.BearerToken = kms.NewPlainSecret("bearer token")
+	user, bb, err = httpdtest.UpdateUser(user, http.StatusOK, "")
+	assert.NoError(t, err, string(bb))
+	assert.Nil(t, user.FsConfig.WebDAVConfig.Password)
+	assert.Equal(t, kms.SecretStatusSecretBox, user.FsConfig.WebDAVConfig.BearerToken.GetStatus())
+	assert.NotEmpty(t, user.FsConfig.WebDAVConfig.BearerToken.GetPayload())
+
+	_, err = httpdtest.RemoveUser(user, http.StatusOK)
+	assert.NoError(t, err)
+	user.Password = defaultPassword
+	user.ID = 0
+	user.CreatedAt = 0
+	user.FsConfig.Provider = sdk.WebDAVFilesystemProvider
+	user.FsConfig.WebDAVConfig.BearerToken = kms.NewSecret(kms.SecretStatusSecretBox, "invalid encrypted payload", "", "")
+	_, _, err = httpdtest.AddUser(user, http.StatusCreated)
+	assert.Error(t, err)
+	// anonymous access is allowed
+	user.FsConfig.WebDAVConfig.BearerToken = kms.NewEmptySecret()
+	user, _, err = httpdtest.AddUser(user, http.StatusCreated)
+	assert.NoError(t, err)
+	assert.Nil(t, user.FsConfig.WebDAVConfig.Password)
+	assert.Nil(t, user.FsConfig.WebDAVConfig.BearerToken)
+
+	_, err = httpdtest.RemoveUser(user, http.StatusOK)
+	assert.NoError(t, err)
+}
+
+func TestUserReadCache(t *testing.T) {
+	user, _, err := httpdtest.AddUser(getTestUser(), http.StatusCreated)
+	assert.NoError(t, err)
+	user.FsConfig.Provider = sdk.S3FilesystemProvider
+	user.FsConfig.S3Config.Bucket = "test"
+	user.FsConfig.S3Config.Region = "us-east-1"
+	user.FsConfig.S3Config.AccessKey = "Server-Access-Key"
+	user.FsConfig.S3Config.AccessSecret = kms.NewPlainSecret("Server-Access-Secret")
+	user.FsConfig.S3Config.Endpoint = "http://127.0.0.1:9000"
+	user.FsConfig.ReadCache.MaxSize = -1
+	_, resp, err := httpdtest.UpdateUser(user, http.StatusBadRequest, "")
+	assert.NoError(t, err)
+	assert.Contains(t, string(resp), "invalid max size")
+	user.FsConfig.ReadCache.MaxSize = 100
+	user.FsConfig.ReadCache.TTL = -1
+	_, resp, err = httpdtest.UpdateUser(user, http.StatusBadRequest, "")
+	assert.NoError(t, err)
+	assert.Contains(t, string(resp), "invalid ttl")
+	user.FsConfig.ReadCache.TTL = 3600
+	user.FsConfig.ReadCache.Eviction = "fifo"
+	_, resp, err = httpdtest.UpdateUser(user, http.StatusBadRequest, "")
+	assert.NoError(t, err)
+	assert.Contains(t, string(resp), "invalid eviction policy")
+	user.FsConfig.ReadCache.Eviction = ""
+	user, resp, err = httpdtest.UpdateUser(user, http.StatusOK, "")
+	assert.NoError(t, err, string(resp))
+	assert.Equal(t, int64(100), user.FsConfig.ReadCache.MaxSize)
+	assert.Equal(t, 3600, user.FsConfig.ReadCache.TTL)
+	assert.Equal(t, "lru", user.FsConfig.ReadCache.Eviction)
+	user.FsConfig.ReadCache.Eviction = "LFU"
+	user, resp, err = httpdtest.UpdateUser(user, http.StatusOK, "")
+	assert.NoError(t, err, string(resp))
+	assert.Equal(t, "lfu", user.FsConfig.ReadCache.Eviction)
+	// the TTL and the eviction policy are reset if the read cache is disabled
+	user.FsConfig.ReadCache.MaxSize = 0
+	user, resp, err = httpdtest.UpdateUser(user, http.StatusOK, "")
+	assert.NoError(t, err, string(resp))
+	assert.Equal(t, 0, user.FsConfig.ReadCache.TTL)
+	assert.Empty(t, user.FsConfig.ReadCache.Eviction)
+	// the read cache is not supported for local filesystems
+	user.FsConfig.Provider = sdk.LocalFilesystemProvider
+	user.FsConfig.S3Config = vfs.S3FsConfig{}
+	user.FsConfig.ReadCache.MaxSize = 100
+	user, resp, err = httpdtest.UpdateUser(user, http.StatusOK, "")
+	assert.NoError(t, err, string(resp))
+	assert.Equal(t, int64(0), user.FsConfig.ReadCache.MaxSize)
+
+	_, err = httpdtest.RemoveUser(user, http.StatusOK)
+	assert.NoError(t, err)
+
+	folder := vfs.BaseVirtualFolder{
+		Name: "read_cache_folder",
+		FsConfig: vfs.Filesystem{
+			Provider: sdk.GCSFilesystemProvider,
+			GCSConfig: vfs.GCSFsConfig{
+				GCSFsConfig: sdk.GCSFsConfig{
+					Bucket:               "test",
+					AutomaticCredentials: 1,
+				},
+			},
+			ReadCache: vfs.ReadCacheConfig{
+				ReadCacheConfig: sdk.ReadCacheConfig{
+					MaxSize:  50,
+					Eviction: "lfu",
+				},
+			},
+		},
+	}
+	folder, resp, err = httpdtest.AddFolder(folder, http.StatusCreated)
+	assert.NoError(t, err, string(resp))
+	assert.Equal(t, int64(50), folder.FsConfig.ReadCache.MaxSize)
+	assert.Equal(t, "lfu", folder.FsConfig.ReadCache.Eviction)
+	folder.FsConfig.ReadCache.TTL = 60
+	folder, resp, err = httpdtest.UpdateFolder(folder, http.StatusOK)
+	assert.NoError(t, err, string(resp))
+	assert.Equal(t, 60, folder.FsConfig.ReadCache.TTL)
+	_, err = httpdtest.RemoveFolder(folder, http.StatusOK)
+	assert.NoError(t, err)
+}
+
+func TestUserEncryption(t *testing.T) {
+	user, _, err := httpdtest.AddUser(getTestUser(), http.StatusCreated)
+	assert.NoError(t, err)
+	user.FsConfig.Provider = sdk.S3FilesystemProvider
+	user.FsConfig.S3Config.Bucket = "test"
+	user.FsConfig.S3Config.Region = "us-east-1"
+	user.FsConfig.S3Config.AccessKey = "Server-Access-Key"
+	user.FsConfig.S3Config.AccessSecret = kms.NewPlainSecret("Server-Access-Secret")
+	user.FsConfig.S3Config.Endpoint = "http://127.0.0.1:9000"
+	user.FsConfig.Encryption.Passphrase = kms.NewPlainSecret("encryption passphrase")
+	user, resp, err := httpdtest.UpdateUser(user, http.StatusOK, "")
+	assert.NoError(t, err, string(resp))
+	initialPayload := user.FsConfig.Encryption.Passphrase.GetPayload()
+	assert.Equal(t, kms.SecretStatusSecretBox, user.FsConfig.Encryption.Passphrase.GetStatus())
+	assert.NotEmpty(t, initialPayload)
+	assert.Empty(t, user.FsConfig.Encryption.Passphrase.GetAdditionalData())
+	assert.Empty(t, user.FsConfig.Encryption.Passphrase.GetKey())
+	// the existing passphrase is preserved if a not plain secret is sent
+	user.FsConfig.Encryption.Passphrase.SetAdditionalData("data")
+	user.FsConfig.Encryption.Passphrase.SetKey("fake pass key")
+	user, resp, err = httpdtest.UpdateUser(user, http.StatusOK, "")
+	assert.NoError(t, err, string(resp))
+	assert.Equal(t, kms.SecretStatusSecretBox, user.FsConfig.Encryption.Passphrase.GetStatus())
+	assert.Equal(t, initialPayload, user.FsConfig.Encryption.Passphrase.GetPayload())
+	dbUser, err := dataprovider.UserExists(user.Username)
+	assert.NoError(t, err)
+	err = dbUser.FsConfig.Encryption.Passphrase.Decrypt()
+	assert.NoError(t, err)
+	assert.Equal(t, "encryption passphrase", dbUser.FsConfig.Encryption.Passphrase.GetPayload())
+	// an empty passphrase disables the encryption
+	user.FsConfig.Encryption.Passphrase = kms.NewEmptySecret()
+	user, resp, err = httpdtest.UpdateUser(user, http.StatusOK, "")
+	assert.NoError(t, err, string(resp))
+	assert.Nil(t, user.FsConfig.Encryption.Passphrase)
+	// the encryption is not supported for local filesystems
+	user.FsConfig.Provider = sdk.LocalFilesystemProvider
+	user.FsConfig.S3Config = vfs.S3FsConfig{}
+	user.FsConfig.Encryption.Passphrase = kms.NewPlainSecret("local passphrase")
+	user, resp, err = httpdtest.UpdateUser(user, http.StatusOK, "")
+	assert.NoError(t, err, string(resp))
+	assert.Nil(t, user.FsConfig.Encryption.Passphrase)
+	_, err = httpdtest.RemoveUser(user, http.StatusOK)
+	assert.NoError(t, err)
+
+	user.Password = defaultPassword
+	user.ID = 0
+	user.CreatedAt = 0
+	user.FsConfig.Provider = sdk.GCSFilesystemProvider
+	user.FsConfig.GCSConfig.Bucket = "test"
+	user.FsConfig.GCSConfig.AutomaticCredentials = 1
+	user.FsConfig.Encryption.Passphrase = kms.NewSecret(kms.SecretStatusSecretBox, "invalid encrypted payload", "", "")
+	_, resp, err = httpdtest.AddUser(user, http.StatusBadRequest)
+	assert.NoError(t, err)
+	assert.Contains(t, string(resp), "could not validate encryption config")
+	user.FsConfig.Encryption.Passphrase = kms.NewSecret(kms.SecretStatusRedacted, "redacted", "", "")
+	_, _, err = httpdtest.AddUser(user, http.StatusBadRequest)
+	assert.NoError(t, err)
+
+	folder := vfs.BaseVirtualFolder{
+		Name: "encrypted_folder",
+		FsConfig: vfs.Filesystem{
+			Provider: sdk.SFTPFilesystemProvider,
+			SFTPConfig: vfs.SFTPFsConfig{
+				SFTPFsConfig: sdk.SFTPFsConfig{
+					Endpoint: "127.0.0.1:2022",
+					Username: "sftp_user",
+					Password: kms.NewPlainSecret("sftp_pwd"),
+				},
+			},
+			Encryption: vfs.EncryptionConfig{
+				EncryptionConfig: sdk.EncryptionConfig{
+					Passphrase: kms.NewPlainSecret("folder passphrase"),
+				},
+			},
+		},
+	}
+	folder, resp, err = httpdtest.AddFolder(folder, http.StatusCreated)
+	assert.NoError(t, err, string(resp))
+	initialPayload = folder.FsConfig.Encryption.Passphrase.GetPayload()
+	assert.Equal(t, kms.SecretStatusSecretBox, folder.FsConfig.Encryption.Passphrase.GetStatus())
+	assert.NotEmpty(t, initialPayload)
+	folder.FsConfig.Encryption.Passphrase.SetKey("fake key")
+	folder, resp, err = httpdtest.UpdateFolder(folder, http.StatusOK)
+	assert.NoError(t, err, string(resp))
+	assert.Equal(t, initialPayload, folder.FsConfig.Encryption.Passphrase.GetPayload())
+	dbFolder, err := dataprovider.GetFolderByName(folder.Name)
+	assert.NoError(t, err)
+	err = dbFolder.FsConfig.Encryption.Passphrase.Decrypt()
+	assert.NoError(t, err)
+	assert.Equal(t, "folder passphrase", dbFolder.FsConfig.Encryption.Passphrase.GetPayload())
+	_, err = httpdtest.RemoveFolder(folder, http.StatusOK)
+	assert.NoError(t, err)
+}
+
+func TestUserHiddenFields(t *testing.T) {
+	err := dataprovider.Close()
+	assert.NoError(t, err)
+	err = config.LoadConfig(configDir, "")
+	assert.NoError(t, err)
+	providerConf := config.GetProviderConf()
+	providerConf.PreferDatabaseCredentials = true
+	err = dataprovider.Initialize(providerConf, configDir, true)
+	assert.NoError(t, err)
+
+	// sensitive data must be hidden but not deleted from the dataprovider
+	usernames := []string{"user1", "user2", "user3", "user4", "user5"}
+	u1 := getTestUser()
+	u1.Username = usernames[0]
+	u1.FsConfig.Provider = sdk.S3FilesystemProvider
+	u1.FsConfig.S3Config.Bucket = "test"
+	u1.FsConfig.S3Config.Region = "us-east-1"
+	u1.FsConfig.S3Config.AccessKey = "S3-Access-Key"
+	u1.FsConfig.S3Config.AccessSecret = kms.NewPlainSecret("S3-Access-Secret")
+	user1, _, err := httpdtest.AddUser(u1, http.StatusCreated)
+	assert.NoError(t, err)
+
+	u2 := getTestUser()
+	u2.Username = usernames[1]
+	u2.FsConfig.Provider = sdk.GCSFilesystemProvider
+	u2.FsConfig.GCSConfig.Bucket = "test"
+	u2.FsConfig.GCSConfig.Credentials = kms.NewPlainSecret("fake credentials")
+	user2, _, err := httpdtest.AddUser(u2, http.StatusCreated)
+	assert.NoError(t, err)
+
+	u3 := getTestUser()
+	u3.Username = usernames[2]
+	u3.FsConfig.Provider = sdk.AzureBlobFilesystemProvider
+	u3.FsConfig.AzBlobConfig.Container = "test"
+	u3.FsConfig.AzBlobConfig.AccountName = "Server-Account-Name"
+	u3.FsConfig.AzBlobConfig.AccountKey = kms.NewPlainSecret("Server-Account-Key")
+	user3, _, err := httpdtest.AddUser(u3, http.StatusCreated)
+	assert.NoError(t, err)
+
+	u4 := getTestUser()
+	u4.Username = usernames[3]
+	u4.FsConfig.Provider = sdk.CryptedFilesystemProvider
+	u4.FsConfig.CryptConfig.Passphrase = kms.NewPlainSecret("test passphrase")
+	user4, _, err := httpdtest.AddUser(u4, http.StatusCreated)
+	assert.NoError(t, err)
+
+	u5 := getTestUser()
+	u5.Username = usernames[4]
 	u5.FsConfig.Provider = sdk.SFTPFilesystemProvider
 	u5.FsConfig.SFTPConfig.Endpoint = "127.0.0.1:2022"
 	u5.FsConfig.SFTPConfig.Username = "sftp_user"
@@ -3713,6 +4908,10 @@ func TestQuotaTrackingDisabled(t *testing.T) {
 	assert.NoError(t, err)
 	_, err = httpdtest.UpdateQuotaUsage(user, "", http.StatusForbidden)
 	assert.NoError(t, err)
+	_, err = httpdtest.UpdateTransferQuotaUsage(user, "", http.StatusForbidden)
+	assert.NoError(t, err)
+	_, _, _, err = httpdtest.GetTransferQuotaUsage(user.Username, http.StatusForbidden)
+	assert.NoError(t, err)
 	_, err = httpdtest.RemoveUser(user, http.StatusOK)
 	assert.NoError(t, err)
 	// folder quota scan must fail
@@ -3726,6 +4925,8 @@ func TestQuotaTrackingDisabled(t *testing.T) {
 	assert.NoError(t, err)
 	_, err = httpdtest.UpdateFolderQuotaUsage(folder, "", http.StatusForbidden)
 	assert.NoError(t, err)
+	_, err = httpdtest.UpdateFolderTransferQuotaUsage(folder, "", http.StatusForbidden)
+	assert.NoError(t, err)
 	_, err = httpdtest.RemoveFolder(folder, http.StatusOK)
 	assert.NoError(t, err)
 
@@ -3947,63 +5148,264 @@ func TestFolders(t *testing.T) {
 	assert.NoError(t, err)
 }
 
-func TestDumpdata(t *testing.T) {
-	err := dataprovider.Close()
+func TestGroups(t *testing.T) {
+	mappedPath := filepath.Join(os.TempDir(), "group_vfolder")
+	folderName := filepath.Base(mappedPath)
+	g := dataprovider.Group{
+		Name:        "invalid name",
+		Description: "test group",
+	}
+	_, _, err := httpdtest.AddGroup(g, http.StatusBadRequest)
 	assert.NoError(t, err)
-	err = config.LoadConfig(configDir, "")
+	g.Name = "test_group"
+	g.UserSettings.Permissions = map[string][]string{
+		"/sub": {"invalid"},
+	}
+	_, _, err = httpdtest.AddGroup(g, http.StatusBadRequest)
 	assert.NoError(t, err)
-	providerConf := config.GetProviderConf()
-	err = dataprovider.Initialize(providerConf, configDir, true)
+	g.UserSettings.Permissions = map[string][]string{
+		"/sub": {dataprovider.PermListItems, dataprovider.PermDownload},
+	}
+	g.UserSettings.MaxSessions = 2
+	g.UserSettings.QuotaFiles = 100
+	g.UserSettings.Filters.DeniedProtocols = []string{common.ProtocolFTP}
+	g.VirtualFolders = append(g.VirtualFolders, vfs.VirtualFolder{
+		BaseVirtualFolder: vfs.BaseVirtualFolder{
+			Name:       folderName,
+			MappedPath: mappedPath,
+		},
+		VirtualPath: "/vgroup",
+		QuotaSize:   -1,
+		QuotaFiles:  -1,
+	})
+	group, resp, err := httpdtest.AddGroup(g, http.StatusCreated)
+	assert.NoError(t, err, string(resp))
+	_, _, err = httpdtest.AddGroup(g, http.StatusInternalServerError)
 	assert.NoError(t, err)
-	_, rawResp, err := httpdtest.Dumpdata("", "", "", http.StatusBadRequest)
-	assert.NoError(t, err, string(rawResp))
-	_, _, err = httpdtest.Dumpdata(filepath.Join(backupsPath, "backup.json"), "", "", http.StatusBadRequest)
+	// the virtual folder must be auto created and mapped to the group
+	folder, _, err := httpdtest.GetFolderByName(folderName, http.StatusOK)
 	assert.NoError(t, err)
-	_, rawResp, err = httpdtest.Dumpdata("../backup.json", "", "", http.StatusBadRequest)
-	assert.NoError(t, err, string(rawResp))
-	_, rawResp, err = httpdtest.Dumpdata("backup.json", "", "0", http.StatusOK)
-	assert.NoError(t, err, string(rawResp))
-	response, _, err := httpdtest.Dumpdata("", "1", "0", http.StatusOK)
+	assert.Len(t, folder.Users, 0)
+	assert.Equal(t, []string{group.Name}, folder.Groups)
+
+	groups, _, err := httpdtest.GetGroups(0, 0, http.StatusOK)
 	assert.NoError(t, err)
-	_, ok := response["admins"]
-	assert.True(t, ok)
-	_, ok = response["users"]
-	assert.True(t, ok)
-	_, ok = response["folders"]
-	assert.True(t, ok)
-	_, ok = response["version"]
-	assert.True(t, ok)
-	_, rawResp, err = httpdtest.Dumpdata("backup.json", "", "1", http.StatusOK)
-	assert.NoError(t, err, string(rawResp))
-	err = os.Remove(filepath.Join(backupsPath, "backup.json"))
+	found := false
+	for _, gr := range groups {
+		if gr.Name == group.Name {
+			found = true
+		}
+	}
+	assert.True(t, found)
+
+	u := getTestUser()
+	u.Groups = []string{"missing group"}
+	_, resp, err = httpdtest.AddUser(u, http.StatusBadRequest)
 	assert.NoError(t, err)
-	if runtime.GOOS != osWindows {
-		err = os.Chmod(backupsPath, 0001)
-		assert.NoError(t, err)
-		_, _, err = httpdtest.Dumpdata("bck.json", "", "", http.StatusForbidden)
-		assert.NoError(t, err)
-		// subdir cannot be created
-		_, _, err = httpdtest.Dumpdata(filepath.Join("subdir", "bck.json"), "", "", http.StatusForbidden)
-		assert.NoError(t, err)
-		err = os.Chmod(backupsPath, 0755)
-		assert.NoError(t, err)
+	assert.Contains(t, string(resp), "does not exist")
+	u.Groups = []string{group.Name}
+	user, resp, err := httpdtest.AddUser(u, http.StatusCreated)
+	assert.NoError(t, err, string(resp))
+	assert.Equal(t, []string{group.Name}, user.Groups)
+	// the group settings are not stored inside the user
+	assert.Len(t, user.VirtualFolders, 0)
+	assert.Equal(t, 0, user.QuotaFiles)
+
+	group, _, err = httpdtest.GetGroupByName(group.Name, http.StatusOK)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{user.Username}, group.Users)
+	group.Description = "updated desc"
+	group.UserSettings.QuotaSize = 1048576
+	group, _, err = httpdtest.UpdateGroup(group, http.StatusOK)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{user.Username}, group.Users)
+
+	userWithGroup, err := dataprovider.GetUserWithGroupSettings(user.Username)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, userWithGroup.MaxSessions)
+	assert.Equal(t, 100, userWithGroup.QuotaFiles)
+	assert.Equal(t, int64(1048576), userWithGroup.QuotaSize)
+	assert.Equal(t, defaultPerms, userWithGroup.GetPermissionsForPath("/"))
+	assert.Equal(t, []string{dataprovider.PermListItems, dataprovider.PermDownload},
+		userWithGroup.GetPermissionsForPath("/sub"))
+	assert.Equal(t, []string{common.ProtocolFTP}, userWithGroup.Filters.DeniedProtocols)
+	if assert.Len(t, userWithGroup.VirtualFolders, 1) {
+		assert.Equal(t, "/vgroup", userWithGroup.VirtualFolders[0].VirtualPath)
+		assert.Equal(t, mappedPath, userWithGroup.VirtualFolders[0].MappedPath)
 	}
-	err = dataprovider.Close()
+	// the user settings take precedence
+	user.QuotaFiles = 10
+	user.VirtualFolders = append(user.VirtualFolders, vfs.VirtualFolder{
+		BaseVirtualFolder: vfs.BaseVirtualFolder{
+			Name:       folderName,
+			MappedPath: mappedPath,
+		},
+		VirtualPath: "/vuser",
+	})
+	user, _, err = httpdtest.UpdateUser(user, http.StatusOK, "")
 	assert.NoError(t, err)
-	err = config.LoadConfig(configDir, "")
+	userWithGroup, err = dataprovider.GetUserWithGroupSettings(user.Username)
 	assert.NoError(t, err)
-	providerConf = config.GetProviderConf()
-	providerConf.CredentialsPath = credentialsPath
-	err = os.RemoveAll(credentialsPath)
+	assert.Equal(t, 10, userWithGroup.QuotaFiles)
+	if assert.Len(t, userWithGroup.VirtualFolders, 1) {
+		assert.Equal(t, "/vuser", userWithGroup.VirtualFolders[0].VirtualPath)
+	}
+	folder, _, err = httpdtest.GetFolderByName(folderName, http.StatusOK)
 	assert.NoError(t, err)
-	err = dataprovider.Initialize(providerConf, configDir, true)
+	assert.Equal(t, []string{user.Username}, folder.Users)
+	assert.Equal(t, []string{group.Name}, folder.Groups)
+
+	_, err = httpdtest.RemoveGroup(group, http.StatusOK)
 	assert.NoError(t, err)
-}
+	_, err = httpdtest.RemoveGroup(group, http.StatusNotFound)
+	assert.NoError(t, err)
+	_, _, err = httpdtest.UpdateGroup(group, http.StatusNotFound)
+	assert.NoError(t, err)
+	user, _, err = httpdtest.GetUserByUsername(user.Username, http.StatusOK)
+	assert.NoError(t, err)
+	assert.Len(t, user.Groups, 0)
+	folder, _, err = httpdtest.GetFolderByName(folderName, http.StatusOK)
+	assert.NoError(t, err)
+	assert.Len(t, folder.Groups, 0)
 
-func TestDefenderAPI(t *testing.T) {
-	oldConfig := config.GetCommonConfig()
+	_, err = httpdtest.RemoveUser(user, http.StatusOK)
+	assert.NoError(t, err)
+	err = os.RemoveAll(user.GetHomeDir())
+	assert.NoError(t, err)
+	_, err = httpdtest.RemoveFolder(vfs.BaseVirtualFolder{Name: folderName}, http.StatusOK)
+	assert.NoError(t, err)
+}
 
-	cfg := config.GetCommonConfig()
+func TestGroupVirtualFolderUsage(t *testing.T) {
+	mappedPath := filepath.Join(os.TempDir(), "group_vfolder")
+	folderName := filepath.Base(mappedPath)
+	g := dataprovider.Group{
+		Name: "group_with_folder",
+	}
+	g.VirtualFolders = append(g.VirtualFolders, vfs.VirtualFolder{
+		BaseVirtualFolder: vfs.BaseVirtualFolder{
+			Name:       folderName,
+			MappedPath: mappedPath,
+		},
+		VirtualPath: "/vgroup",
+		QuotaSize:   -1,
+		QuotaFiles:  -1,
+	})
+	g.UserSettings.Permissions = map[string][]string{
+		"/vgroup": {dataprovider.PermListItems},
+	}
+	group, _, err := httpdtest.AddGroup(g, http.StatusCreated)
+	assert.NoError(t, err)
+	u := getTestUser()
+	u.Groups = []string{group.Name}
+	user, _, err := httpdtest.AddUser(u, http.StatusCreated)
+	assert.NoError(t, err)
+	err = os.MkdirAll(mappedPath, os.ModePerm)
+	assert.NoError(t, err)
+	testFileName := "file_in_group_folder.dat"
+	err = createTestFile(filepath.Join(mappedPath, testFileName), 100)
+	assert.NoError(t, err)
+
+	token, err := getJWTAPIUserTokenFromTestServer(defaultUsername, defaultPassword)
+	assert.NoError(t, err)
+	req, err := http.NewRequest(http.MethodGet, userDirsPath, nil)
+	assert.NoError(t, err)
+	setBearerForReq(req, token)
+	rr := executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
+	var contents []map[string]interface{}
+	err = json.NewDecoder(rr.Body).Decode(&contents)
+	assert.NoError(t, err)
+	if assert.Len(t, contents, 1) {
+		assert.Equal(t, "vgroup", contents[0]["name"])
+	}
+	req, err = http.NewRequest(http.MethodGet, userDirsPath+"?path=%2Fvgroup", nil)
+	assert.NoError(t, err)
+	setBearerForReq(req, token)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
+	contents = nil
+	err = json.NewDecoder(rr.Body).Decode(&contents)
+	assert.NoError(t, err)
+	if assert.Len(t, contents, 1) {
+		assert.Equal(t, testFileName, contents[0]["name"])
+	}
+	// download is not allowed inside the group folder
+	req, err = http.NewRequest(http.MethodGet, userFilesPath+"?path=%2Fvgroup%2F"+testFileName, nil)
+	assert.NoError(t, err)
+	setBearerForReq(req, token)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusForbidden, rr)
+
+	_, err = httpdtest.RemoveUser(user, http.StatusOK)
+	assert.NoError(t, err)
+	err = os.RemoveAll(user.GetHomeDir())
+	assert.NoError(t, err)
+	_, err = httpdtest.RemoveGroup(group, http.StatusOK)
+	assert.NoError(t, err)
+	_, err = httpdtest.RemoveFolder(vfs.BaseVirtualFolder{Name: folderName}, http.StatusOK)
+	assert.NoError(t, err)
+	err = os.RemoveAll(mappedPath)
+	assert.NoError(t, err)
+}
+
+func TestDumpdata(t *testing.T) {
+	err := dataprovider.Close()
+	assert.NoError(t, err)
+	err = config.LoadConfig(configDir, "")
+	assert.NoError(t, err)
+	providerConf := config.GetProviderConf()
+	err = dataprovider.Initialize(providerConf, configDir, true)
+	assert.NoError(t, err)
+	_, rawResp, err := httpdtest.Dumpdata("", "", "", http.StatusBadRequest)
+	assert.NoError(t, err, string(rawResp))
+	_, _, err = httpdtest.Dumpdata(filepath.Join(backupsPath, "backup.json"), "", "", http.StatusBadRequest)
+	assert.NoError(t, err)
+	_, rawResp, err = httpdtest.Dumpdata("../backup.json", "", "", http.StatusBadRequest)
+	assert.NoError(t, err, string(rawResp))
+	_, rawResp, err = httpdtest.Dumpdata("backup.json", "", "0", http.StatusOK)
+	assert.NoError(t, err, string(rawResp))
+	response, _, err := httpdtest.Dumpdata("", "1", "0", http.StatusOK)
+	assert.NoError(t, err)
+	_, ok := response["admins"]
+	assert.True(t, ok)
+	_, ok = response["users"]
+	assert.True(t, ok)
+	_, ok = response["folders"]
+	assert.True(t, ok)
+	_, ok = response["version"]
+	assert.True(t, ok)
+	_, rawResp, err = httpdtest.Dumpdata("backup.json", "", "1", http.StatusOK)
+	assert.NoError(t, err, string(rawResp))
+	err = os.Remove(filepath.Join(backupsPath, "backup.json"))
+	assert.NoError(t, err)
+	if runtime.GOOS != osWindows {
+		err = os.Chmod(backupsPath, 0001)
+		assert.NoError(t, err)
+		_, _, err = httpdtest.Dumpdata("bck.json", "", "", http.StatusForbidden)
+		assert.NoError(t, err)
+		// subdir cannot be created
+		_, _, err = httpdtest.Dumpdata(filepath.Join("subdir", "bck.json"), "", "", http.StatusForbidden)
+		assert.NoError(t, err)
+		err = os.Chmod(backupsPath, 0755)
+		assert.NoError(t, err)
+	}
+	err = dataprovider.Close()
+	assert.NoError(t, err)
+	err = config.LoadConfig(configDir, "")
+	assert.NoError(t, err)
+	providerConf = config.GetProviderConf()
+	providerConf.CredentialsPath = credentialsPath
+	err = os.RemoveAll(credentialsPath)
+	assert.NoError(t, err)
+	err = dataprovider.Initialize(providerConf, configDir, true)
+	assert.NoError(t, err)
+}
+
+func TestDefenderAPI(t *testing.T) {
+	oldConfig := config.GetCommonConfig()
+
+	cfg := config.GetCommonConfig()
 	cfg.DefenderConfig.Enabled = true
 	cfg.DefenderConfig.Threshold = 3
 	cfg.DefenderConfig.ScoreLimitExceeded = 2
@@ -4120,1123 +5522,1132 @@ func TestDefenderAPIErrors(t *testing.T) {
 	require.NoError(t, err)
 }
 
-func TestLoaddataFromPostBody(t *testing.T) {
-	mappedPath := filepath.Join(os.TempDir(), "restored_folder")
-	folderName := filepath.Base(mappedPath)
-	user := getTestUser()
-	user.ID = 1
-	user.Username = "test_user_restored"
-	admin := getTestAdmin()
-	admin.ID = 1
-	admin.Username = "test_admin_restored"
-	backupData := dataprovider.BackupData{}
-	backupData.Users = append(backupData.Users, user)
-	backupData.Admins = append(backupData.Admins, admin)
-	backupData.Folders = []vfs.BaseVirtualFolder{
-		{
-			Name:            folderName,
-			MappedPath:      mappedPath,
-			UsedQuotaSize:   123,
-			UsedQuotaFiles:  456,
-			LastQuotaUpdate: 789,
-			Users:           []string{"user"},
-		},
-		{
-			Name:       folderName,
-			MappedPath: mappedPath + "1",
-		},
-	}
-	backupData.APIKeys = append(backupData.APIKeys, dataprovider.APIKey{})
-	backupContent, err := json.Marshal(backupData)
+func TestAccountLockout(t *testing.T) {
+	err := dataprovider.Close()
 	assert.NoError(t, err)
-	_, _, err = httpdtest.LoaddataFromPostBody(nil, "0", "0", http.StatusBadRequest)
+	err = config.LoadConfig(configDir, "")
 	assert.NoError(t, err)
-	_, _, err = httpdtest.LoaddataFromPostBody(backupContent, "a", "0", http.StatusBadRequest)
+	providerConf := config.GetProviderConf()
+	providerConf.CredentialsPath = credentialsPath
+	providerConf.AccountLockout.Enabled = true
+	providerConf.AccountLockout.MaxFailures = 3
+	providerConf.AccountLockout.LockoutTime = 0
+	err = dataprovider.Initialize(providerConf, configDir, true)
 	assert.NoError(t, err)
-	_, _, err = httpdtest.LoaddataFromPostBody([]byte("invalid content"), "0", "0", http.StatusBadRequest)
+
+	user, _, err := httpdtest.AddUser(getTestUser(), http.StatusCreated)
 	assert.NoError(t, err)
-	_, _, err = httpdtest.LoaddataFromPostBody(backupContent, "0", "0", http.StatusInternalServerError)
+	a := getTestAdmin()
+	a.Username = altAdminUsername
+	a.Password = altAdminPassword
+	admin, _, err := httpdtest.AddAdmin(a, http.StatusCreated)
+	assert.NoError(t, err)
+	// a successful login resets the failed logins
+	_, err = dataprovider.CheckUserAndPass(user.Username, "wrong", "127.0.0.1", common.ProtocolSSH)
+	assert.ErrorIs(t, err, dataprovider.ErrInvalidCredentials)
+	lockout, _, err := httpdtest.GetAccountLockout(dataprovider.AccountTypeUser, user.Username, http.StatusOK)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, lockout.Failures)
+	assert.False(t, lockout.IsLocked())
+	_, err = dataprovider.CheckUserAndPass(user.Username, defaultPassword, "127.0.0.1", common.ProtocolSSH)
+	assert.NoError(t, err)
+	_, _, err = httpdtest.GetAccountLockout(dataprovider.AccountTypeUser, user.Username, http.StatusNotFound)
 	assert.NoError(t, err)
 
-	keyID := shortuuid.New()
-	backupData.APIKeys = []dataprovider.APIKey{
-		{
-			Name:  "test key",
-			Scope: dataprovider.APIKeyScopeAdmin,
-			KeyID: keyID,
-			Key:   fmt.Sprintf("%v.%v", shortuuid.New(), shortuuid.New()),
-		},
+	for i := 0; i < 3; i++ {
+		_, err = dataprovider.CheckUserAndPass(user.Username, "wrong", "127.0.0.1", common.ProtocolFTP)
+		assert.ErrorIs(t, err, dataprovider.ErrInvalidCredentials)
+		_, err = dataprovider.CheckAdminAndPass(admin.Username, "wrong", "127.0.0.1")
+		assert.ErrorIs(t, err, dataprovider.ErrInvalidCredentials)
 	}
-	backupContent, err = json.Marshal(backupData)
+	// the accounts are now locked, the right credentials are rejected too
+	_, err = dataprovider.CheckUserAndPass(user.Username, defaultPassword, "127.0.0.1", common.ProtocolWebDAV)
+	assert.ErrorIs(t, err, dataprovider.ErrAccountLocked)
+	_, err = dataprovider.CheckAdminAndPass(admin.Username, altAdminPassword, "127.0.0.1")
+	assert.ErrorIs(t, err, dataprovider.ErrAccountLocked)
+	_, err = getJWTAPITokenFromTestServer(admin.Username, altAdminPassword)
+	assert.Error(t, err)
+
+	lockouts, _, err := httpdtest.GetAccountLockouts(0, 0, http.StatusOK)
 	assert.NoError(t, err)
-	_, _, err = httpdtest.LoaddataFromPostBody(backupContent, "0", "0", http.StatusOK)
+	if assert.Len(t, lockouts, 2) {
+		assert.Equal(t, dataprovider.AccountTypeAdmin, lockouts[0].Type)
+		assert.Equal(t, admin.Username, lockouts[0].Username)
+		assert.Equal(t, dataprovider.AccountTypeUser, lockouts[1].Type)
+		assert.Equal(t, user.Username, lockouts[1].Username)
+	}
+	lockouts, _, err = httpdtest.GetAccountLockouts(1, 1, http.StatusOK)
 	assert.NoError(t, err)
-	user, _, err = httpdtest.GetUserByUsername(user.Username, http.StatusOK)
+	if assert.Len(t, lockouts, 1) {
+		assert.Equal(t, user.Username, lockouts[0].Username)
+	}
+	lockout, _, err = httpdtest.GetAccountLockout(dataprovider.AccountTypeUser, user.Username, http.StatusOK)
 	assert.NoError(t, err)
-	_, err = httpdtest.RemoveUser(user, http.StatusOK)
+	assert.Equal(t, 3, lockout.Failures)
+	assert.True(t, lockout.IsLocked())
+	assert.Greater(t, lockout.LockedAt, int64(0))
+	assert.Equal(t, int64(0), lockout.LockedUntil)
+	assert.GreaterOrEqual(t, lockout.LastFailureAt, lockout.FirstFailureAt)
+	_, _, err = httpdtest.GetAccountLockout("invalid", user.Username, http.StatusBadRequest)
+	assert.NoError(t, err)
+	_, err = httpdtest.RemoveAccountLockout("invalid", user.Username, http.StatusBadRequest)
 	assert.NoError(t, err)
 
-	admin, _, err = httpdtest.GetAdminByUsername(admin.Username, http.StatusOK)
+	_, err = httpdtest.RemoveAccountLockout(dataprovider.AccountTypeUser, user.Username, http.StatusOK)
 	assert.NoError(t, err)
+	_, err = httpdtest.RemoveAccountLockout(dataprovider.AccountTypeUser, user.Username, http.StatusNotFound)
+	assert.NoError(t, err)
+	_, err = dataprovider.CheckUserAndPass(user.Username, defaultPassword, "127.0.0.1", common.ProtocolWebDAV)
+	assert.NoError(t, err)
+	// removing the admin removes the lockout too
 	_, err = httpdtest.RemoveAdmin(admin, http.StatusOK)
 	assert.NoError(t, err)
-	apiKey, _, err := httpdtest.GetAPIKeyByID(keyID, http.StatusOK)
+	_, _, err = httpdtest.GetAccountLockout(dataprovider.AccountTypeAdmin, admin.Username, http.StatusNotFound)
 	assert.NoError(t, err)
-	_, err = httpdtest.RemoveAPIKey(apiKey, http.StatusOK)
+	_, err = httpdtest.RemoveUser(user, http.StatusOK)
+	assert.NoError(t, err)
+	err = os.RemoveAll(user.GetHomeDir())
 	assert.NoError(t, err)
 
-	folder, _, err := httpdtest.GetFolderByName(folderName, http.StatusOK)
+	err = dataprovider.Close()
 	assert.NoError(t, err)
-	assert.Equal(t, mappedPath+"1", folder.MappedPath)
-	assert.Equal(t, int64(123), folder.UsedQuotaSize)
-	assert.Equal(t, 456, folder.UsedQuotaFiles)
-	assert.Equal(t, int64(789), folder.LastQuotaUpdate)
-	assert.Len(t, folder.Users, 0)
-	_, err = httpdtest.RemoveFolder(folder, http.StatusOK)
+	err = config.LoadConfig(configDir, "")
+	assert.NoError(t, err)
+	providerConf = config.GetProviderConf()
+	providerConf.CredentialsPath = credentialsPath
+	err = os.RemoveAll(credentialsPath)
+	assert.NoError(t, err)
+	err = dataprovider.Initialize(providerConf, configDir, true)
 	assert.NoError(t, err)
 }
 
-func TestLoaddata(t *testing.T) {
-	mappedPath := filepath.Join(os.TempDir(), "restored_folder")
-	folderName := filepath.Base(mappedPath)
-	foldeDesc := "restored folder desc"
-	user := getTestUser()
-	user.ID = 1
-	user.Username = "test_user_restore"
-	admin := getTestAdmin()
-	admin.ID = 1
-	admin.Username = "test_admin_restore"
-	apiKey := dataprovider.APIKey{
-		Name:  shortuuid.New(),
-		Scope: dataprovider.APIKeyScopeAdmin,
-		KeyID: shortuuid.New(),
-		Key:   fmt.Sprintf("%v.%v", shortuuid.New(), shortuuid.New()),
-	}
-	backupData := dataprovider.BackupData{}
-	backupData.Users = append(backupData.Users, user)
-	backupData.Admins = append(backupData.Admins, admin)
-	backupData.Folders = []vfs.BaseVirtualFolder{
-		{
-			Name:            folderName,
-			MappedPath:      mappedPath + "1",
-			UsedQuotaSize:   123,
-			UsedQuotaFiles:  456,
-			LastQuotaUpdate: 789,
-			Users:           []string{"user"},
-		},
-		{
-			MappedPath:  mappedPath,
-			Name:        folderName,
-			Description: foldeDesc,
-		},
-	}
-	backupData.APIKeys = append(backupData.APIKeys, apiKey)
-	backupContent, err := json.Marshal(backupData)
+func TestAccountLockoutConcurrentLogins(t *testing.T) {
+	err := dataprovider.Close()
 	assert.NoError(t, err)
-	backupFilePath := filepath.Join(backupsPath, "backup.json")
-	err = os.WriteFile(backupFilePath, backupContent, os.ModePerm)
+	err = config.LoadConfig(configDir, "")
 	assert.NoError(t, err)
-	_, _, err = httpdtest.Loaddata(backupFilePath, "a", "", http.StatusBadRequest)
+	providerConf := config.GetProviderConf()
+	providerConf.CredentialsPath = credentialsPath
+	providerConf.AccountLockout.Enabled = true
+	providerConf.AccountLockout.MaxFailures = 15
+	providerConf.AccountLockout.LockoutTime = 0
+	err = dataprovider.Initialize(providerConf, configDir, true)
 	assert.NoError(t, err)
-	_, _, err = httpdtest.Loaddata(backupFilePath, "", "a", http.StatusBadRequest)
+
+	user, _, err := httpdtest.AddUser(getTestUser(), http.StatusCreated)
 	assert.NoError(t, err)
-	_, _, err = httpdtest.Loaddata("backup.json", "1", "", http.StatusBadRequest)
+	// all the parallel failed logins must be recorded
+	numLogins := 10
+	var wg sync.WaitGroup
+	for i := 0; i < numLogins; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			_, err := dataprovider.CheckUserAndPass(user.Username, "wrong", "127.0.0.1", common.ProtocolSSH)
+			assert.ErrorIs(t, err, dataprovider.ErrInvalidCredentials)
+		}()
+	}
+	wg.Wait()
+	lockout, err := dataprovider.GetAccountLockout(user.Username, dataprovider.AccountTypeUser)
+	assert.NoError(t, err)
+	assert.Equal(t, numLogins, lockout.Failures)
+	assert.False(t, lockout.IsLocked())
+	// the threshold cannot be bypassed using parallel logins
+	for i := 0; i < numLogins; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			_, err := dataprovider.CheckUserAndPass(user.Username, "wrong", "127.0.0.1", common.ProtocolSSH)
+			assert.Error(t, err)
+		}()
+	}
+	wg.Wait()
+	lockout, err = dataprovider.GetAccountLockout(user.Username, dataprovider.AccountTypeUser)
 	assert.NoError(t, err)
-	_, _, err = httpdtest.Loaddata(backupFilePath+"a", "1", "", http.StatusBadRequest)
+	assert.GreaterOrEqual(t, lockout.Failures, providerConf.AccountLockout.MaxFailures)
+	assert.True(t, lockout.IsLocked())
+	_, err = dataprovider.CheckUserAndPass(user.Username, defaultPassword, "127.0.0.1", common.ProtocolSSH)
+	assert.ErrorIs(t, err, dataprovider.ErrAccountLocked)
+
+	_, err = httpdtest.RemoveUser(user, http.StatusOK)
 	assert.NoError(t, err)
-	if runtime.GOOS != osWindows {
-		err = os.Chmod(backupFilePath, 0111)
-		assert.NoError(t, err)
-		_, _, err = httpdtest.Loaddata(backupFilePath, "1", "", http.StatusForbidden)
-		assert.NoError(t, err)
-		err = os.Chmod(backupFilePath, 0644)
-		assert.NoError(t, err)
-	}
-	// add user, folder, admin, API key from backup
-	_, _, err = httpdtest.Loaddata(backupFilePath, "1", "", http.StatusOK)
+	err = os.RemoveAll(user.GetHomeDir())
 	assert.NoError(t, err)
-	// update from backup
-	_, _, err = httpdtest.Loaddata(backupFilePath, "2", "", http.StatusOK)
+
+	err = dataprovider.Close()
 	assert.NoError(t, err)
-	user, _, err = httpdtest.GetUserByUsername(user.Username, http.StatusOK)
+	err = config.LoadConfig(configDir, "")
 	assert.NoError(t, err)
-	_, err = httpdtest.RemoveUser(user, http.StatusOK)
+	providerConf = config.GetProviderConf()
+	providerConf.CredentialsPath = credentialsPath
+	err = os.RemoveAll(credentialsPath)
+	assert.NoError(t, err)
+	err = dataprovider.Initialize(providerConf, configDir, true)
 	assert.NoError(t, err)
+}
 
-	admin, _, err = httpdtest.GetAdminByUsername(admin.Username, http.StatusOK)
+func TestPasswordReset(t *testing.T) {
+	_, err := httpdtest.AdminForgotPassword(defaultTokenAuthUser, http.StatusBadRequest)
 	assert.NoError(t, err)
-	_, err = httpdtest.RemoveAdmin(admin, http.StatusOK)
+
+	err = dataprovider.Close()
+	assert.NoError(t, err)
+	err = config.LoadConfig(configDir, "")
+	assert.NoError(t, err)
+	providerConf := config.GetProviderConf()
+	providerConf.CredentialsPath = credentialsPath
+	providerConf.AccountLockout.Enabled = true
+	providerConf.AccountLockout.MaxFailures = 2
+	providerConf.AccountLockout.LockoutTime = 0
+	providerConf.PasswordValidation.Users.MinEntropy = 50
+	err = dataprovider.Initialize(providerConf, configDir, true)
 	assert.NoError(t, err)
+	smtpCfg := smtp.Config{
+		Host:          "127.0.0.1",
+		Port:          3525,
+		TemplatesPath: "templates",
+	}
+	err = smtpCfg.Initialize(configDir)
+	require.NoError(t, err)
 
-	apiKey, _, err = httpdtest.GetAPIKeyByID(apiKey.KeyID, http.StatusOK)
+	newPassword := "Rt9#mZq!2vLp$wX4"
+	a := getTestAdmin()
+	a.Username = altAdminUsername
+	a.Password = "Kd8&nWu!5sQe#yB1"
+	admin, _, err := httpdtest.AddAdmin(a, http.StatusCreated)
 	assert.NoError(t, err)
-	_, err = httpdtest.RemoveAPIKey(apiKey, http.StatusOK)
+	u := getTestUser()
+	u.Password = a.Password
+	u.Email = "user@example.com"
+	user, _, err := httpdtest.AddUser(u, http.StatusCreated)
 	assert.NoError(t, err)
 
-	folder, _, err := httpdtest.GetFolderByName(folderName, http.StatusOK)
+	oldConfig := config.GetCommonConfig()
+	cfg := config.GetCommonConfig()
+	cfg.DefenderConfig.Enabled = true
+	cfg.DefenderConfig.Threshold = 100
+	cfg.DefenderConfig.ScoreInvalid = 2
+	cfg.DefenderConfig.ScoreLimitExceeded = 3
+	err = common.Initialize(cfg)
+	require.NoError(t, err)
+	// no email is sent for a missing account and the request is accepted anyway
+	lastReceivedEmail.reset()
+	_, err = httpdtest.AdminForgotPassword("missing admin", http.StatusAccepted)
 	assert.NoError(t, err)
-	assert.Equal(t, mappedPath, folder.MappedPath)
-	assert.Equal(t, int64(123), folder.UsedQuotaSize)
-	assert.Equal(t, 456, folder.UsedQuotaFiles)
-	assert.Equal(t, int64(789), folder.LastQuotaUpdate)
-	assert.Equal(t, foldeDesc, folder.Description)
-	assert.Len(t, folder.Users, 0)
-	_, err = httpdtest.RemoveFolder(folder, http.StatusOK)
+	_, err = httpdtest.UserForgotPassword("missing user", http.StatusAccepted)
 	assert.NoError(t, err)
-	err = os.Remove(backupFilePath)
+	assert.Empty(t, lastReceivedEmail.get().To)
+	response, _, err := httpdtest.GetScore("127.0.0.1", http.StatusOK)
 	assert.NoError(t, err)
-	err = createTestFile(backupFilePath, 10485761)
+	assert.Equal(t, float64(4), response["score"])
+
+	_, err = httpdtest.AdminForgotPassword(admin.Username, http.StatusAccepted)
 	assert.NoError(t, err)
-	_, _, err = httpdtest.Loaddata(backupFilePath, "1", "0", http.StatusBadRequest)
+	code := getResetCodeFromEmail(t, admin.Email)
+	// a new code is not sent while the previous one is valid
+	lastReceivedEmail.reset()
+	_, err = httpdtest.AdminForgotPassword(admin.Username, http.StatusAccepted)
 	assert.NoError(t, err)
-	err = os.Remove(backupFilePath)
+	assert.Empty(t, lastReceivedEmail.get().To)
+	response, _, err = httpdtest.GetScore("127.0.0.1", http.StatusOK)
 	assert.NoError(t, err)
-	err = createTestFile(backupFilePath, 65535)
+	assert.Equal(t, float64(7), response["score"])
+	err = common.Initialize(oldConfig)
+	require.NoError(t, err)
+	// lock the admin, a successful reset must unlock it
+	for i := 0; i < 2; i++ {
+		_, err = dataprovider.CheckAdminAndPass(admin.Username, "wrong", "127.0.0.1")
+		assert.ErrorIs(t, err, dataprovider.ErrInvalidCredentials)
+	}
+	_, err = dataprovider.CheckAdminAndPass(admin.Username, a.Password, "127.0.0.1")
+	assert.ErrorIs(t, err, dataprovider.ErrAccountLocked)
+
+	_, err = httpdtest.AdminResetPassword(admin.Username, "invalid code", newPassword, http.StatusBadRequest)
 	assert.NoError(t, err)
-	_, _, err = httpdtest.Loaddata(backupFilePath, "1", "0", http.StatusBadRequest)
+	_, err = httpdtest.AdminResetPassword(admin.Username, code, "", http.StatusBadRequest)
 	assert.NoError(t, err)
-	err = os.Remove(backupFilePath)
+	_, err = httpdtest.AdminResetPassword(defaultTokenAuthUser, code, newPassword, http.StatusBadRequest)
 	assert.NoError(t, err)
-}
-
-func TestLoaddataMode(t *testing.T) {
-	mappedPath := filepath.Join(os.TempDir(), "restored_fold")
-	folderName := filepath.Base(mappedPath)
-	user := getTestUser()
-	user.ID = 1
-	user.Username = "test_user_restore"
-	admin := getTestAdmin()
-	admin.ID = 1
-	admin.Username = "test_admin_restore"
-	apiKey := dataprovider.APIKey{
-		Name:        shortuuid.New(),
-		Scope:       dataprovider.APIKeyScopeAdmin,
-		KeyID:       shortuuid.New(),
-		Key:         fmt.Sprintf("%v.%v", shortuuid.New(), shortuuid.New()),
-		Description: "desc",
-	}
-	backupData := dataprovider.BackupData{}
-	backupData.Users = append(backupData.Users, user)
-	backupData.Admins = append(backupData.Admins, admin)
-	backupData.Folders = []vfs.BaseVirtualFolder{
-		{
-			Name:            folderName,
-			MappedPath:      mappedPath,
-			UsedQuotaSize:   123,
-			UsedQuotaFiles:  456,
-			LastQuotaUpdate: 789,
-			Users:           []string{"user"},
-		},
-		{
-			MappedPath: mappedPath + "1",
-			Name:       folderName,
-		},
-	}
-	backupData.APIKeys = append(backupData.APIKeys, apiKey)
-	backupContent, _ := json.Marshal(backupData)
-	backupFilePath := filepath.Join(backupsPath, "backup.json")
-	err := os.WriteFile(backupFilePath, backupContent, os.ModePerm)
+	_, err = httpdtest.UserResetPassword(admin.Username, code, newPassword, http.StatusBadRequest)
 	assert.NoError(t, err)
-	_, _, err = httpdtest.Loaddata(backupFilePath, "0", "0", http.StatusOK)
+	_, err = httpdtest.AdminResetPassword(admin.Username, code, newPassword, http.StatusOK)
 	assert.NoError(t, err)
-	folder, _, err := httpdtest.GetFolderByName(folderName, http.StatusOK)
+	// a reset code can be used only once
+	_, err = httpdtest.AdminResetPassword(admin.Username, code, newPassword+"1", http.StatusBadRequest)
 	assert.NoError(t, err)
-	assert.Equal(t, mappedPath+"1", folder.MappedPath)
-	assert.Equal(t, int64(123), folder.UsedQuotaSize)
-	assert.Equal(t, 456, folder.UsedQuotaFiles)
-	assert.Equal(t, int64(789), folder.LastQuotaUpdate)
-	assert.Len(t, folder.Users, 0)
-	user, _, err = httpdtest.GetUserByUsername(user.Username, http.StatusOK)
+	_, _, err = httpdtest.GetAccountLockout(dataprovider.AccountTypeAdmin, admin.Username, http.StatusNotFound)
 	assert.NoError(t, err)
-	oldUploadBandwidth := user.UploadBandwidth
-	user.UploadBandwidth = oldUploadBandwidth + 128
+	_, err = getJWTAPITokenFromTestServer(admin.Username, newPassword)
+	assert.NoError(t, err)
+
+	_, err = httpdtest.UserForgotPassword(user.Username, http.StatusAccepted)
+	assert.NoError(t, err)
+	code = getResetCodeFromEmail(t, user.Email)
+	_, err = httpdtest.AdminResetPassword(user.Username, code, newPassword, http.StatusBadRequest)
+	assert.NoError(t, err)
+	user.Filters.WebClient = []string{sdk.WebClientPasswordResetDisabled}
 	user, _, err = httpdtest.UpdateUser(user, http.StatusOK, "")
 	assert.NoError(t, err)
-	admin, _, err = httpdtest.GetAdminByUsername(admin.Username, http.StatusOK)
+	body, err := httpdtest.UserResetPassword(user.Username, code, newPassword, http.StatusBadRequest)
 	assert.NoError(t, err)
-	oldInfo := admin.AdditionalInfo
-	oldDesc := admin.Description
-	admin.AdditionalInfo = "newInfo"
-	admin.Description = "newDesc"
-	admin, _, err = httpdtest.UpdateAdmin(admin, http.StatusOK)
+	assert.Contains(t, string(body), "password reset is not allowed")
+	// no code is sent if the password reset is not allowed
+	lastReceivedEmail.reset()
+	_, err = httpdtest.UserForgotPassword(user.Username, http.StatusAccepted)
 	assert.NoError(t, err)
-	apiKey, _, err = httpdtest.GetAPIKeyByID(apiKey.KeyID, http.StatusOK)
+	assert.Empty(t, lastReceivedEmail.get().To)
+	user.Filters.WebClient = []string{sdk.WebClientMFADisabled}
+	user, _, err = httpdtest.UpdateUser(user, http.StatusOK, "")
 	assert.NoError(t, err)
-	oldAPIKeyDesc := apiKey.Description
-	apiKey.ExpiresAt = util.GetTimeAsMsSinceEpoch(time.Now())
-	apiKey.Description = "new desc"
-	apiKey, _, err = httpdtest.UpdateAPIKey(apiKey, http.StatusOK)
+	body, err = httpdtest.UserResetPassword(user.Username, code, "weak", http.StatusBadRequest)
 	assert.NoError(t, err)
-
-	backupData.Folders = []vfs.BaseVirtualFolder{
-		{
-			MappedPath: mappedPath,
-			Name:       folderName,
-		},
-	}
-	_, _, err = httpdtest.Loaddata(backupFilePath, "0", "1", http.StatusOK)
+	assert.Contains(t, string(body), "insecure password")
+	_, err = httpdtest.UserResetPassword(user.Username, code, newPassword, http.StatusOK)
 	assert.NoError(t, err)
-	folder, _, err = httpdtest.GetFolderByName(folderName, http.StatusOK)
+	_, err = httpdtest.UserResetPassword(user.Username, code, newPassword, http.StatusBadRequest)
 	assert.NoError(t, err)
-	assert.Equal(t, mappedPath+"1", folder.MappedPath)
-	assert.Equal(t, int64(123), folder.UsedQuotaSize)
-	assert.Equal(t, 456, folder.UsedQuotaFiles)
-	assert.Equal(t, int64(789), folder.LastQuotaUpdate)
-	assert.Len(t, folder.Users, 0)
-
-	c := common.NewBaseConnection("connID", common.ProtocolFTP, "", "", user)
-	fakeConn := &fakeConnection{
-		BaseConnection: c,
-	}
-	common.Connections.Add(fakeConn)
-	assert.Len(t, common.Connections.GetStats(), 1)
-	user, _, err = httpdtest.GetUserByUsername(user.Username, http.StatusOK)
+	_, err = dataprovider.CheckUserAndPass(user.Username, newPassword, "127.0.0.1", common.ProtocolSSH)
 	assert.NoError(t, err)
-	assert.NotEqual(t, oldUploadBandwidth, user.UploadBandwidth)
-	admin, _, err = httpdtest.GetAdminByUsername(admin.Username, http.StatusOK)
+	// no code is sent for disabled accounts
+	user.Status = 0
+	user, _, err = httpdtest.UpdateUser(user, http.StatusOK, "")
 	assert.NoError(t, err)
-	assert.NotEqual(t, oldInfo, admin.AdditionalInfo)
-	assert.NotEqual(t, oldDesc, admin.Description)
-
-	apiKey, _, err = httpdtest.GetAPIKeyByID(apiKey.KeyID, http.StatusOK)
+	lastReceivedEmail.reset()
+	_, err = httpdtest.UserForgotPassword(user.Username, http.StatusAccepted)
 	assert.NoError(t, err)
-	assert.NotEqual(t, int64(0), apiKey.ExpiresAt)
-	assert.NotEqual(t, oldAPIKeyDesc, apiKey.Description)
+	assert.Empty(t, lastReceivedEmail.get().To)
 
-	_, _, err = httpdtest.Loaddata(backupFilePath, "0", "2", http.StatusOK)
-	assert.NoError(t, err)
-	// mode 2 will update the user and close the previous connection
-	assert.Len(t, common.Connections.GetStats(), 0)
-	user, _, err = httpdtest.GetUserByUsername(user.Username, http.StatusOK)
+	_, err = httpdtest.RemoveAdmin(admin, http.StatusOK)
 	assert.NoError(t, err)
-	assert.Equal(t, oldUploadBandwidth, user.UploadBandwidth)
 	_, err = httpdtest.RemoveUser(user, http.StatusOK)
 	assert.NoError(t, err)
-	_, err = httpdtest.RemoveAdmin(admin, http.StatusOK)
+	err = os.RemoveAll(user.GetHomeDir())
 	assert.NoError(t, err)
-	_, err = httpdtest.RemoveFolder(folder, http.StatusOK)
+
+	smtpCfg = smtp.Config{}
+	err = smtpCfg.Initialize(configDir)
+	require.NoError(t, err)
+	err = dataprovider.Close()
 	assert.NoError(t, err)
-	err = os.Remove(backupFilePath)
+	err = config.LoadConfig(configDir, "")
+	assert.NoError(t, err)
+	providerConf = config.GetProviderConf()
+	providerConf.CredentialsPath = credentialsPath
+	err = os.RemoveAll(credentialsPath)
+	assert.NoError(t, err)
+	err = dataprovider.Initialize(providerConf, configDir, true)
 	assert.NoError(t, err)
 }
 
-func TestRateLimiter(t *testing.T) {
-	oldConfig := config.GetCommonConfig()
-
-	cfg := config.GetCommonConfig()
-	cfg.RateLimitersConfig = []common.RateLimiterConfig{
-		{
-			Average:   1,
-			Period:    1000,
-			Burst:     1,
-			Type:      1,
-			Protocols: []string{common.ProtocolHTTP},
-		},
-	}
-
-	err := common.Initialize(cfg)
+func TestWebPasswordResetMock(t *testing.T) {
+	req, err := http.NewRequest(http.MethodGet, webAdminForgotPwdPath, nil)
+	assert.NoError(t, err)
+	rr := executeRequest(req)
+	checkResponseCode(t, http.StatusNotFound, rr)
+	req, err = http.NewRequest(http.MethodGet, webClientResetPwdPath, nil)
 	assert.NoError(t, err)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusNotFound, rr)
 
-	client := &http.Client{
-		Timeout: 5 * time.Second,
+	smtpCfg := smtp.Config{
+		Host:          "127.0.0.1",
+		Port:          3525,
+		TemplatesPath: "templates",
 	}
-	resp, err := client.Get(httpBaseURL + healthzPath)
-	assert.NoError(t, err)
-	assert.Equal(t, http.StatusOK, resp.StatusCode)
-	err = resp.Body.Close()
-	assert.NoError(t, err)
+	err = smtpCfg.Initialize(configDir)
+	require.NoError(t, err)
 
-	resp, err = client.Get(httpBaseURL + healthzPath)
+	a := getTestAdmin()
+	a.Username = altAdminUsername
+	a.Password = altAdminPassword
+	admin, _, err := httpdtest.AddAdmin(a, http.StatusCreated)
 	assert.NoError(t, err)
-	assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
-	assert.Equal(t, "1", resp.Header.Get("Retry-After"))
-	assert.NotEmpty(t, resp.Header.Get("X-Retry-In"))
-	err = resp.Body.Close()
+	u := getTestUser()
+	u.Email = "user@example.com"
+	user, _, err := httpdtest.AddUser(u, http.StatusCreated)
 	assert.NoError(t, err)
 
-	resp, err = client.Get(httpBaseURL + webLoginPath)
+	req, err = http.NewRequest(http.MethodGet, webLoginPath, nil)
 	assert.NoError(t, err)
-	assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
-	assert.Equal(t, "1", resp.Header.Get("Retry-After"))
-	assert.NotEmpty(t, resp.Header.Get("X-Retry-In"))
-	err = resp.Body.Close()
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
+	assert.Contains(t, rr.Body.String(), webAdminForgotPwdPath)
+	req, err = http.NewRequest(http.MethodGet, webClientLoginPath, nil)
 	assert.NoError(t, err)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
+	assert.Contains(t, rr.Body.String(), webClientForgotPwdPath)
+	req, err = http.NewRequest(http.MethodGet, webAdminResetPwdPath, nil)
+	assert.NoError(t, err)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
 
-	resp, err = client.Get(httpBaseURL + webClientLoginPath)
+	csrfToken, err := getCSRFToken(httpBaseURL + webLoginPath)
 	assert.NoError(t, err)
-	assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
-	assert.Equal(t, "1", resp.Header.Get("Retry-After"))
-	assert.NotEmpty(t, resp.Header.Get("X-Retry-In"))
-	err = resp.Body.Close()
+	form := make(url.Values)
+	form.Set("username", admin.Username)
+	req, err = http.NewRequest(http.MethodPost, webAdminForgotPwdPath, bytes.NewBuffer([]byte(form.Encode())))
 	assert.NoError(t, err)
-
-	err = common.Initialize(oldConfig)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusForbidden, rr)
+	form.Set(csrfFormToken, csrfToken)
+	req, err = http.NewRequest(http.MethodPost, webAdminForgotPwdPath, bytes.NewBuffer([]byte(form.Encode())))
 	assert.NoError(t, err)
-}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rr = executeRequest(req)
+	assert.Equal(t, http.StatusFound, rr.Code)
+	assert.Equal(t, webAdminResetPwdPath, rr.Header().Get("Location"))
+	code := getResetCodeFromEmail(t, admin.Email)
 
-func TestHTTPSConnection(t *testing.T) {
-	client := &http.Client{
-		Timeout: 5 * time.Second,
-	}
-	resp, err := client.Get("https://localhost:8443" + healthzPath)
-	if assert.Error(t, err) {
-		if !strings.Contains(err.Error(), "certificate is not valid") &&
-			!strings.Contains(err.Error(), "certificate signed by unknown authority") {
-			assert.Fail(t, err.Error())
-		}
-	} else {
-		resp.Body.Close()
-	}
-}
-
-// test using mock http server
-
-func TestBasicUserHandlingMock(t *testing.T) {
-	token, err := getJWTAPITokenFromTestServer(defaultTokenAuthUser, defaultTokenAuthPass)
+	form = make(url.Values)
+	form.Set(csrfFormToken, csrfToken)
+	form.Set("code", code)
+	form.Set("password", "new admin password")
+	form.Set("confirm_password", "mismatch")
+	req, err = http.NewRequest(http.MethodPost, webAdminResetPwdPath, bytes.NewBuffer([]byte(form.Encode())))
 	assert.NoError(t, err)
-	user := getTestUser()
-	userAsJSON := getUserAsJSON(t, user)
-	req, err := http.NewRequest(http.MethodPost, userPath, bytes.NewBuffer(userAsJSON))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
+	assert.Contains(t, rr.Body.String(), "The two password fields do not match")
+	form.Set("confirm_password", "new admin password")
+	form.Set("code", "invalid")
+	req, err = http.NewRequest(http.MethodPost, webAdminResetPwdPath, bytes.NewBuffer([]byte(form.Encode())))
 	assert.NoError(t, err)
-	setBearerForReq(req, token)
-	rr := executeRequest(req)
-	checkResponseCode(t, http.StatusCreated, rr)
-	err = render.DecodeJSON(rr.Body, &user)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
+	assert.Contains(t, rr.Body.String(), "the reset code is invalid or expired")
+	form.Set("code", code)
+	req, err = http.NewRequest(http.MethodPost, webAdminResetPwdPath, bytes.NewBuffer([]byte(form.Encode())))
 	assert.NoError(t, err)
-	req, err = http.NewRequest(http.MethodPost, userPath, bytes.NewBuffer(userAsJSON))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rr = executeRequest(req)
+	assert.Equal(t, http.StatusFound, rr.Code)
+	assert.Equal(t, webUsersPath, rr.Header().Get("Location"))
+	_, err = getJWTWebTokenFromTestServer(admin.Username, "new admin password")
 	assert.NoError(t, err)
-	setBearerForReq(req, token)
+
+	csrfToken, err = getCSRFToken(httpBaseURL + webClientLoginPath)
+	assert.NoError(t, err)
+	form = make(url.Values)
+	form.Set(csrfFormToken, csrfToken)
+	form.Set("username", user.Username)
+	req, err = http.NewRequest(http.MethodPost, webClientForgotPwdPath, bytes.NewBuffer([]byte(form.Encode())))
+	assert.NoError(t, err)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusInternalServerError, rr)
-	user.MaxSessions = 10
-	user.UploadBandwidth = 128
-	user.Permissions["/"] = []string{dataprovider.PermAny, dataprovider.PermDelete, dataprovider.PermDownload}
-	userAsJSON = getUserAsJSON(t, user)
-	req, _ = http.NewRequest(http.MethodPut, userPath+"/"+user.Username, bytes.NewBuffer(userAsJSON))
-	setBearerForReq(req, token)
+	assert.Equal(t, http.StatusFound, rr.Code)
+	assert.Equal(t, webClientResetPwdPath, rr.Header().Get("Location"))
+	code = getResetCodeFromEmail(t, user.Email)
+	form.Set("username", "")
+	req, err = http.NewRequest(http.MethodPost, webClientForgotPwdPath, bytes.NewBuffer([]byte(form.Encode())))
+	assert.NoError(t, err)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 	rr = executeRequest(req)
 	checkResponseCode(t, http.StatusOK, rr)
+	assert.Contains(t, rr.Body.String(), "username is mandatory")
 
-	req, _ = http.NewRequest(http.MethodGet, userPath+"/"+user.Username, nil)
-	setBearerForReq(req, token)
+	form = make(url.Values)
+	form.Set(csrfFormToken, csrfToken)
+	form.Set("code", code)
+	form.Set("password", "new user password")
+	form.Set("confirm_password", "new user password")
+	req, err = http.NewRequest(http.MethodPost, webClientResetPwdPath, bytes.NewBuffer([]byte(form.Encode())))
+	assert.NoError(t, err)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusOK, rr)
-
-	var updatedUser dataprovider.User
-	err = render.DecodeJSON(rr.Body, &updatedUser)
+	assert.Equal(t, http.StatusFound, rr.Code)
+	assert.Equal(t, webClientFilesPath, rr.Header().Get("Location"))
+	_, err = getJWTWebClientTokenFromTestServer(user.Username, "new user password")
 	assert.NoError(t, err)
-	assert.Equal(t, user.MaxSessions, updatedUser.MaxSessions)
-	assert.Equal(t, user.UploadBandwidth, updatedUser.UploadBandwidth)
-	assert.Equal(t, 1, len(updatedUser.Permissions["/"]))
-	assert.True(t, util.IsStringInSlice(dataprovider.PermAny, updatedUser.Permissions["/"]))
-	req, _ = http.NewRequest(http.MethodDelete, userPath+"/"+user.Username, nil)
-	setBearerForReq(req, token)
+	// the code was already used
+	req, err = http.NewRequest(http.MethodPost, webClientResetPwdPath, bytes.NewBuffer([]byte(form.Encode())))
+	assert.NoError(t, err)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 	rr = executeRequest(req)
 	checkResponseCode(t, http.StatusOK, rr)
-}
+	assert.Contains(t, rr.Body.String(), "the reset code is invalid or expired")
 
-func TestAddUserNoUsernameMock(t *testing.T) {
-	token, err := getJWTAPITokenFromTestServer(defaultTokenAuthUser, defaultTokenAuthPass)
+	_, err = httpdtest.RemoveAdmin(admin, http.StatusOK)
 	assert.NoError(t, err)
-	user := getTestUser()
-	user.Username = ""
-	userAsJSON := getUserAsJSON(t, user)
-	req, _ := http.NewRequest(http.MethodPost, userPath, bytes.NewBuffer(userAsJSON))
-	setBearerForReq(req, token)
-	rr := executeRequest(req)
-	checkResponseCode(t, http.StatusBadRequest, rr)
-}
-
-func TestAddUserInvalidHomeDirMock(t *testing.T) {
-	token, err := getJWTAPITokenFromTestServer(defaultTokenAuthUser, defaultTokenAuthPass)
+	_, err = httpdtest.RemoveUser(user, http.StatusOK)
 	assert.NoError(t, err)
-	user := getTestUser()
-	user.HomeDir = "relative_path"
-	userAsJSON := getUserAsJSON(t, user)
-	req, _ := http.NewRequest(http.MethodPost, userPath, bytes.NewBuffer(userAsJSON))
-	setBearerForReq(req, token)
-	rr := executeRequest(req)
-	checkResponseCode(t, http.StatusBadRequest, rr)
+	err = os.RemoveAll(user.GetHomeDir())
+	assert.NoError(t, err)
+
+	smtpCfg = smtp.Config{}
+	err = smtpCfg.Initialize(configDir)
+	require.NoError(t, err)
 }
 
-func TestAddUserInvalidPermsMock(t *testing.T) {
-	token, err := getJWTAPITokenFromTestServer(defaultTokenAuthUser, defaultTokenAuthPass)
+func TestAccountLockoutConfigValidation(t *testing.T) {
+	err := dataprovider.Close()
 	assert.NoError(t, err)
-	user := getTestUser()
-	user.Permissions["/"] = []string{}
-	userAsJSON := getUserAsJSON(t, user)
-	req, _ := http.NewRequest(http.MethodPost, userPath, bytes.NewBuffer(userAsJSON))
-	setBearerForReq(req, token)
-	rr := executeRequest(req)
-	checkResponseCode(t, http.StatusBadRequest, rr)
-}
+	err = config.LoadConfig(configDir, "")
+	assert.NoError(t, err)
+	providerConf := config.GetProviderConf()
+	providerConf.CredentialsPath = credentialsPath
+	providerConf.AccountLockout.Enabled = true
+	providerConf.AccountLockout.MaxFailures = 0
+	err = dataprovider.Initialize(providerConf, configDir, true)
+	assert.Error(t, err)
+	providerConf.AccountLockout.MaxFailures = 3
+	providerConf.AccountLockout.LockoutTime = -1
+	err = dataprovider.Initialize(providerConf, configDir, true)
+	assert.Error(t, err)
 
-func TestAddFolderInvalidJsonMock(t *testing.T) {
-	token, err := getJWTAPITokenFromTestServer(defaultTokenAuthUser, defaultTokenAuthPass)
+	providerConf = config.GetProviderConf()
+	providerConf.CredentialsPath = credentialsPath
+	err = os.RemoveAll(credentialsPath)
+	assert.NoError(t, err)
+	err = dataprovider.Initialize(providerConf, configDir, true)
 	assert.NoError(t, err)
-	req, _ := http.NewRequest(http.MethodPost, folderPath, bytes.NewBuffer([]byte("invalid json")))
-	setBearerForReq(req, token)
-	rr := executeRequest(req)
-	checkResponseCode(t, http.StatusBadRequest, rr)
 }
 
-func TestUpdateFolderInvalidJsonMock(t *testing.T) {
-	folder := vfs.BaseVirtualFolder{
-		Name:       "name",
-		MappedPath: filepath.Clean(os.TempDir()),
+func TestLoaddataFromPostBody(t *testing.T) {
+	mappedPath := filepath.Join(os.TempDir(), "restored_folder")
+	folderName := filepath.Base(mappedPath)
+	user := getTestUser()
+	user.ID = 1
+	user.Username = "test_user_restored"
+	admin := getTestAdmin()
+	admin.ID = 1
+	admin.Username = "test_admin_restored"
+	backupData := dataprovider.BackupData{}
+	backupData.Users = append(backupData.Users, user)
+	backupData.Admins = append(backupData.Admins, admin)
+	backupData.Folders = []vfs.BaseVirtualFolder{
+		{
+			Name:            folderName,
+			MappedPath:      mappedPath,
+			UsedQuotaSize:   123,
+			UsedQuotaFiles:  456,
+			LastQuotaUpdate: 789,
+			Users:           []string{"user"},
+		},
+		{
+			Name:       folderName,
+			MappedPath: mappedPath + "1",
+		},
 	}
-	folder, resp, err := httpdtest.AddFolder(folder, http.StatusCreated)
-	assert.NoError(t, err, string(resp))
-
-	token, err := getJWTAPITokenFromTestServer(defaultTokenAuthUser, defaultTokenAuthPass)
+	backupData.APIKeys = append(backupData.APIKeys, dataprovider.APIKey{})
+	backupContent, err := json.Marshal(backupData)
 	assert.NoError(t, err)
-	req, _ := http.NewRequest(http.MethodPut, path.Join(folderPath, folder.Name), bytes.NewBuffer([]byte("not a json")))
-	setBearerForReq(req, token)
-	rr := executeRequest(req)
-	checkResponseCode(t, http.StatusBadRequest, rr)
-
-	_, err = httpdtest.RemoveFolder(folder, http.StatusOK)
+	_, _, err = httpdtest.LoaddataFromPostBody(nil, "0", "0", http.StatusBadRequest)
 	assert.NoError(t, err)
-}
-
-func TestUnbanInvalidJsonMock(t *testing.T) {
-	token, err := getJWTAPITokenFromTestServer(defaultTokenAuthUser, defaultTokenAuthPass)
+	_, _, err = httpdtest.LoaddataFromPostBody(backupContent, "a", "0", http.StatusBadRequest)
 	assert.NoError(t, err)
-	req, _ := http.NewRequest(http.MethodPost, defenderUnban, bytes.NewBuffer([]byte("invalid json")))
-	setBearerForReq(req, token)
-	rr := executeRequest(req)
-	checkResponseCode(t, http.StatusBadRequest, rr)
-}
-
-func TestAddUserInvalidJsonMock(t *testing.T) {
-	token, err := getJWTAPITokenFromTestServer(defaultTokenAuthUser, defaultTokenAuthPass)
+	_, _, err = httpdtest.LoaddataFromPostBody([]byte("invalid content"), "0", "0", http.StatusBadRequest)
 	assert.NoError(t, err)
-	req, _ := http.NewRequest(http.MethodPost, userPath, bytes.NewBuffer([]byte("invalid json")))
-	setBearerForReq(req, token)
-	rr := executeRequest(req)
-	checkResponseCode(t, http.StatusBadRequest, rr)
-}
-
-func TestAddAdminInvalidJsonMock(t *testing.T) {
-	token, err := getJWTAPITokenFromTestServer(defaultTokenAuthUser, defaultTokenAuthPass)
+	_, _, err = httpdtest.LoaddataFromPostBody(backupContent, "0", "0", http.StatusInternalServerError)
 	assert.NoError(t, err)
-	req, _ := http.NewRequest(http.MethodPost, adminPath, bytes.NewBuffer([]byte("...")))
-	setBearerForReq(req, token)
-	rr := executeRequest(req)
-	checkResponseCode(t, http.StatusBadRequest, rr)
-}
 
-func TestAddAdminNoPasswordMock(t *testing.T) {
-	token, err := getJWTAPITokenFromTestServer(defaultTokenAuthUser, defaultTokenAuthPass)
+	keyID := shortuuid.New()
+	backupData.APIKeys = []dataprovider.APIKey{
+		{
+			Name:  "test key",
+			Scope: dataprovider.APIKeyScopeAdmin,
+			KeyID: keyID,
+			Key:   fmt.Sprintf("%v.%v", shortuuid.New(), shortuuid.New()),
+		},
+	}
+	backupContent, err = json.Marshal(backupData)
 	assert.NoError(t, err)
-	admin := getTestAdmin()
-	admin.Password = ""
-	asJSON, err := json.Marshal(admin)
+	_, _, err = httpdtest.LoaddataFromPostBody(backupContent, "0", "0", http.StatusOK)
 	assert.NoError(t, err)
-	req, _ := http.NewRequest(http.MethodPost, adminPath, bytes.NewBuffer(asJSON))
-	setBearerForReq(req, token)
-	rr := executeRequest(req)
-	checkResponseCode(t, http.StatusBadRequest, rr)
-	assert.Contains(t, rr.Body.String(), "please set a password")
-}
-
-func TestAdminTwoFactorLogin(t *testing.T) {
-	admin := getTestAdmin()
-	admin.Username = altAdminUsername
-	admin.Password = altAdminPassword
-	admin, _, err := httpdtest.AddAdmin(admin, http.StatusCreated)
+	user, _, err = httpdtest.GetUserByUsername(user.Username, http.StatusOK)
 	assert.NoError(t, err)
-	// enable two factor authentication
-	configName, _, secret, _, err := mfa.GenerateTOTPSecret(mfa.GetAvailableTOTPConfigNames()[0], admin.Username)
+	_, err = httpdtest.RemoveUser(user, http.StatusOK)
 	assert.NoError(t, err)
-	altToken, err := getJWTAPITokenFromTestServer(altAdminUsername, altAdminPassword)
+
+	admin, _, err = httpdtest.GetAdminByUsername(admin.Username, http.StatusOK)
 	assert.NoError(t, err)
-	adminTOTPConfig := dataprovider.TOTPConfig{
-		Enabled:    true,
-		ConfigName: configName,
-		Secret:     kms.NewPlainSecret(secret),
-	}
-	asJSON, err := json.Marshal(adminTOTPConfig)
+	_, err = httpdtest.RemoveAdmin(admin, http.StatusOK)
 	assert.NoError(t, err)
-	req, err := http.NewRequest(http.MethodPost, adminTOTPSavePath, bytes.NewBuffer(asJSON))
+	apiKey, _, err := httpdtest.GetAPIKeyByID(keyID, http.StatusOK)
 	assert.NoError(t, err)
-	setBearerForReq(req, altToken)
-	rr := executeRequest(req)
-	checkResponseCode(t, http.StatusOK, rr)
-	admin, _, err = httpdtest.GetAdminByUsername(admin.Username, http.StatusOK)
+	_, err = httpdtest.RemoveAPIKey(apiKey, http.StatusOK)
 	assert.NoError(t, err)
-	assert.True(t, admin.Filters.TOTPConfig.Enabled)
 
-	req, err = http.NewRequest(http.MethodGet, admin2FARecoveryCodesPath, nil)
+	folder, _, err := httpdtest.GetFolderByName(folderName, http.StatusOK)
 	assert.NoError(t, err)
-	setBearerForReq(req, altToken)
-	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusOK, rr)
-	var recCodes []recoveryCode
-	err = json.Unmarshal(rr.Body.Bytes(), &recCodes)
+	assert.Equal(t, mappedPath+"1", folder.MappedPath)
+	assert.Equal(t, int64(123), folder.UsedQuotaSize)
+	assert.Equal(t, 456, folder.UsedQuotaFiles)
+	assert.Equal(t, int64(789), folder.LastQuotaUpdate)
+	assert.Len(t, folder.Users, 0)
+	_, err = httpdtest.RemoveFolder(folder, http.StatusOK)
 	assert.NoError(t, err)
-	assert.Len(t, recCodes, 12)
+}
 
-	admin, _, err = httpdtest.GetAdminByUsername(altAdminUsername, http.StatusOK)
-	assert.NoError(t, err)
-	assert.Len(t, admin.Filters.RecoveryCodes, 12)
-	for _, c := range admin.Filters.RecoveryCodes {
-		assert.Empty(t, c.Secret.GetAdditionalData())
-		assert.Empty(t, c.Secret.GetKey())
-		assert.Equal(t, kms.SecretStatusSecretBox, c.Secret.GetStatus())
-		assert.NotEmpty(t, c.Secret.GetPayload())
+func TestLoaddata(t *testing.T) {
+	mappedPath := filepath.Join(os.TempDir(), "restored_folder")
+	folderName := filepath.Base(mappedPath)
+	foldeDesc := "restored folder desc"
+	user := getTestUser()
+	user.ID = 1
+	user.Username = "test_user_restore"
+	admin := getTestAdmin()
+	admin.ID = 1
+	admin.Username = "test_admin_restore"
+	apiKey := dataprovider.APIKey{
+		Name:  shortuuid.New(),
+		Scope: dataprovider.APIKeyScopeAdmin,
+		KeyID: shortuuid.New(),
+		Key:   fmt.Sprintf("%v.%v", shortuuid.New(), shortuuid.New()),
 	}
-
-	webToken, err := getJWTWebTokenFromTestServer(defaultTokenAuthUser, defaultTokenAuthPass)
+	backupData := dataprovider.BackupData{}
+	backupData.Users = append(backupData.Users, user)
+	backupData.Admins = append(backupData.Admins, admin)
+	backupData.Folders = []vfs.BaseVirtualFolder{
+		{
+			Name:            folderName,
+			MappedPath:      mappedPath + "1",
+			UsedQuotaSize:   123,
+			UsedQuotaFiles:  456,
+			LastQuotaUpdate: 789,
+			Users:           []string{"user"},
+		},
+		{
+			MappedPath:  mappedPath,
+			Name:        folderName,
+			Description: foldeDesc,
+		},
+	}
+	backupData.APIKeys = append(backupData.APIKeys, apiKey)
+	backupContent, err := json.Marshal(backupData)
 	assert.NoError(t, err)
-	req, err = http.NewRequest(http.MethodGet, webAdminTwoFactorPath, nil)
+	backupFilePath := filepath.Join(backupsPath, "backup.json")
+	err = os.WriteFile(backupFilePath, backupContent, os.ModePerm)
 	assert.NoError(t, err)
-	setJWTCookieForReq(req, webToken)
-	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusNotFound, rr)
-
-	req, err = http.NewRequest(http.MethodGet, webAdminTwoFactorRecoveryPath, nil)
+	_, _, err = httpdtest.Loaddata(backupFilePath, "a", "", http.StatusBadRequest)
 	assert.NoError(t, err)
-	setJWTCookieForReq(req, webToken)
-	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusNotFound, rr)
-
-	req, err = http.NewRequest(http.MethodPost, webAdminTwoFactorPath, nil)
+	_, _, err = httpdtest.Loaddata(backupFilePath, "", "a", http.StatusBadRequest)
 	assert.NoError(t, err)
-	setJWTCookieForReq(req, webToken)
-	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusNotFound, rr)
-
-	req, err = http.NewRequest(http.MethodPost, webAdminTwoFactorRecoveryPath, nil)
+	_, _, err = httpdtest.Loaddata("backup.json", "1", "", http.StatusBadRequest)
 	assert.NoError(t, err)
-	setJWTCookieForReq(req, webToken)
-	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusNotFound, rr)
-
-	csrfToken, err := getCSRFToken(httpBaseURL + webLoginPath)
+	_, _, err = httpdtest.Loaddata(backupFilePath+"a", "1", "", http.StatusBadRequest)
 	assert.NoError(t, err)
-	form := getLoginForm(altAdminUsername, altAdminPassword, csrfToken)
-	req, err = http.NewRequest(http.MethodPost, webLoginPath, bytes.NewBuffer([]byte(form.Encode())))
+	if runtime.GOOS != osWindows {
+		err = os.Chmod(backupFilePath, 0111)
+		assert.NoError(t, err)
+		_, _, err = httpdtest.Loaddata(backupFilePath, "1", "", http.StatusForbidden)
+		assert.NoError(t, err)
+		err = os.Chmod(backupFilePath, 0644)
+		assert.NoError(t, err)
+	}
+	// add user, folder, admin, API key from backup
+	_, _, err = httpdtest.Loaddata(backupFilePath, "1", "", http.StatusOK)
 	assert.NoError(t, err)
-	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
-	rr = executeRequest(req)
-	assert.Equal(t, http.StatusFound, rr.Code)
-	assert.Equal(t, webAdminTwoFactorPath, rr.Header().Get("Location"))
-	cookie, err := getCookieFromResponse(rr)
+	// update from backup
+	_, _, err = httpdtest.Loaddata(backupFilePath, "2", "", http.StatusOK)
 	assert.NoError(t, err)
-
-	// without a cookie
-	req, err = http.NewRequest(http.MethodGet, webAdminTwoFactorRecoveryPath, nil)
+	user, _, err = httpdtest.GetUserByUsername(user.Username, http.StatusOK)
 	assert.NoError(t, err)
-	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusNotFound, rr)
-
-	req, err = http.NewRequest(http.MethodGet, webAdminTwoFactorPath, nil)
+	_, err = httpdtest.RemoveUser(user, http.StatusOK)
 	assert.NoError(t, err)
-	setJWTCookieForReq(req, cookie)
-	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusOK, rr)
 
-	req, err = http.NewRequest(http.MethodGet, webAdminTwoFactorRecoveryPath, nil)
+	admin, _, err = httpdtest.GetAdminByUsername(admin.Username, http.StatusOK)
+	assert.NoError(t, err)
+	_, err = httpdtest.RemoveAdmin(admin, http.StatusOK)
 	assert.NoError(t, err)
-	setJWTCookieForReq(req, cookie)
-	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusOK, rr)
 
-	// any other page will be redirected to the two factor auth page
-	req, err = http.NewRequest(http.MethodGet, webUsersPath, nil)
+	apiKey, _, err = httpdtest.GetAPIKeyByID(apiKey.KeyID, http.StatusOK)
 	assert.NoError(t, err)
-	setJWTCookieForReq(req, cookie)
-	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusFound, rr)
-	assert.Equal(t, webAdminTwoFactorPath, rr.Header().Get("Location"))
-	// a partial token cannot be used for user pages
-	req, err = http.NewRequest(http.MethodGet, webClientFilesPath, nil)
+	_, err = httpdtest.RemoveAPIKey(apiKey, http.StatusOK)
 	assert.NoError(t, err)
-	setJWTCookieForReq(req, cookie)
-	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusFound, rr)
-	assert.Equal(t, webClientLoginPath, rr.Header().Get("Location"))
 
-	passcode, err := generateTOTPPasscode(secret)
+	folder, _, err := httpdtest.GetFolderByName(folderName, http.StatusOK)
 	assert.NoError(t, err)
-	form = make(url.Values)
-	form.Set("passcode", passcode)
-	// no csrf
-	req, err = http.NewRequest(http.MethodPost, webAdminTwoFactorPath, bytes.NewBuffer([]byte(form.Encode())))
+	assert.Equal(t, mappedPath, folder.MappedPath)
+	assert.Equal(t, int64(123), folder.UsedQuotaSize)
+	assert.Equal(t, 456, folder.UsedQuotaFiles)
+	assert.Equal(t, int64(789), folder.LastQuotaUpdate)
+	assert.Equal(t, foldeDesc, folder.Description)
+	assert.Len(t, folder.Users, 0)
+	_, err = httpdtest.RemoveFolder(folder, http.StatusOK)
 	assert.NoError(t, err)
-	setJWTCookieForReq(req, cookie)
-	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
-	rr = executeRequest(req)
-	assert.Equal(t, http.StatusOK, rr.Code)
-	assert.Contains(t, rr.Body.String(), "unable to verify form token")
-
-	form.Set(csrfFormToken, csrfToken)
-	form.Set("passcode", "invalid_passcode")
-	req, err = http.NewRequest(http.MethodPost, webAdminTwoFactorPath, bytes.NewBuffer([]byte(form.Encode())))
+	err = os.Remove(backupFilePath)
 	assert.NoError(t, err)
-	setJWTCookieForReq(req, cookie)
-	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
-	rr = executeRequest(req)
-	assert.Equal(t, http.StatusOK, rr.Code)
-	assert.Contains(t, rr.Body.String(), "Invalid authentication code")
-
-	form.Set("passcode", "")
-	req, err = http.NewRequest(http.MethodPost, webAdminTwoFactorPath, bytes.NewBuffer([]byte(form.Encode())))
+	err = createTestFile(backupFilePath, 10485761)
 	assert.NoError(t, err)
-	setJWTCookieForReq(req, cookie)
-	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
-	rr = executeRequest(req)
-	assert.Equal(t, http.StatusOK, rr.Code)
-	assert.Contains(t, rr.Body.String(), "Invalid credentials")
-
-	form.Set("passcode", passcode)
-	req, err = http.NewRequest(http.MethodPost, webAdminTwoFactorPath, bytes.NewBuffer([]byte(form.Encode())))
+	_, _, err = httpdtest.Loaddata(backupFilePath, "1", "0", http.StatusBadRequest)
 	assert.NoError(t, err)
-	setJWTCookieForReq(req, cookie)
-	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
-	rr = executeRequest(req)
-	assert.Equal(t, http.StatusFound, rr.Code)
-	assert.Equal(t, webUsersPath, rr.Header().Get("Location"))
-	// the same cookie cannot be reused
-	req, err = http.NewRequest(http.MethodPost, webAdminTwoFactorPath, bytes.NewBuffer([]byte(form.Encode())))
+	err = os.Remove(backupFilePath)
 	assert.NoError(t, err)
-	setJWTCookieForReq(req, cookie)
-	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
-	rr = executeRequest(req)
-	assert.Equal(t, http.StatusNotFound, rr.Code)
-	// get a new cookie and login using a recovery code
-	form = getLoginForm(altAdminUsername, altAdminPassword, csrfToken)
-	req, err = http.NewRequest(http.MethodPost, webLoginPath, bytes.NewBuffer([]byte(form.Encode())))
+	err = createTestFile(backupFilePath, 65535)
 	assert.NoError(t, err)
-	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
-	rr = executeRequest(req)
-	assert.Equal(t, http.StatusFound, rr.Code)
-	assert.Equal(t, webAdminTwoFactorPath, rr.Header().Get("Location"))
-	cookie, err = getCookieFromResponse(rr)
+	_, _, err = httpdtest.Loaddata(backupFilePath, "1", "0", http.StatusBadRequest)
 	assert.NoError(t, err)
-
-	form = make(url.Values)
-	recoveryCode := recCodes[0].Code
-	form.Set("recovery_code", recoveryCode)
-	// no csrf
-	req, err = http.NewRequest(http.MethodPost, webAdminTwoFactorRecoveryPath, bytes.NewBuffer([]byte(form.Encode())))
+	err = os.Remove(backupFilePath)
 	assert.NoError(t, err)
-	setJWTCookieForReq(req, cookie)
-	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
-	rr = executeRequest(req)
-	assert.Equal(t, http.StatusOK, rr.Code)
-	assert.Contains(t, rr.Body.String(), "unable to verify form token")
+}
 
-	form.Set(csrfFormToken, csrfToken)
-	form.Set("recovery_code", "")
-	req, err = http.NewRequest(http.MethodPost, webAdminTwoFactorRecoveryPath, bytes.NewBuffer([]byte(form.Encode())))
+func TestLoaddataMode(t *testing.T) {
+	mappedPath := filepath.Join(os.TempDir(), "restored_fold")
+	folderName := filepath.Base(mappedPath)
+	user := getTestUser()
+	user.ID = 1
+	user.Username = "test_user_restore"
+	admin := getTestAdmin()
+	admin.ID = 1
+	admin.Username = "test_admin_restore"
+	apiKey := dataprovider.APIKey{
+		Name:        shortuuid.New(),
+		Scope:       dataprovider.APIKeyScopeAdmin,
+		KeyID:       shortuuid.New(),
+		Key:         fmt.Sprintf("%v.%v", shortuuid.New(), shortuuid.New()),
+		Description: "desc",
+	}
+	backupData := dataprovider.BackupData{}
+	backupData.Users = append(backupData.Users, user)
+	backupData.Admins = append(backupData.Admins, admin)
+	backupData.Folders = []vfs.BaseVirtualFolder{
+		{
+			Name:            folderName,
+			MappedPath:      mappedPath,
+			UsedQuotaSize:   123,
+			UsedQuotaFiles:  456,
+			LastQuotaUpdate: 789,
+			Users:           []string{"user"},
+		},
+		{
+			MappedPath: mappedPath + "1",
+			Name:       folderName,
+		},
+	}
+	backupData.APIKeys = append(backupData.APIKeys, apiKey)
+	backupContent, _ := json.Marshal(backupData)
+	backupFilePath := filepath.Join(backupsPath, "backup.json")
+	err := os.WriteFile(backupFilePath, backupContent, os.ModePerm)
 	assert.NoError(t, err)
-	setJWTCookieForReq(req, cookie)
-	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
-	rr = executeRequest(req)
-	assert.Equal(t, http.StatusOK, rr.Code)
-	assert.Contains(t, rr.Body.String(), "Invalid credentials")
-
-	form.Set("recovery_code", recoveryCode)
-	req, err = http.NewRequest(http.MethodPost, webAdminTwoFactorRecoveryPath, bytes.NewBuffer([]byte(form.Encode())))
+	_, _, err = httpdtest.Loaddata(backupFilePath, "0", "0", http.StatusOK)
 	assert.NoError(t, err)
-	setJWTCookieForReq(req, cookie)
-	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
-	rr = executeRequest(req)
-	assert.Equal(t, http.StatusFound, rr.Code)
-	assert.Equal(t, webUsersPath, rr.Header().Get("Location"))
-	authenticatedCookie, err := getCookieFromResponse(rr)
+	folder, _, err := httpdtest.GetFolderByName(folderName, http.StatusOK)
 	assert.NoError(t, err)
-	//render MFA page
-	req, err = http.NewRequest(http.MethodGet, webAdminMFAPath, nil)
+	assert.Equal(t, mappedPath+"1", folder.MappedPath)
+	assert.Equal(t, int64(123), folder.UsedQuotaSize)
+	assert.Equal(t, 456, folder.UsedQuotaFiles)
+	assert.Equal(t, int64(789), folder.LastQuotaUpdate)
+	assert.Len(t, folder.Users, 0)
+	user, _, err = httpdtest.GetUserByUsername(user.Username, http.StatusOK)
 	assert.NoError(t, err)
-	setJWTCookieForReq(req, authenticatedCookie)
-	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusOK, rr)
-	// check that the recovery code was marked as used
-	req, err = http.NewRequest(http.MethodGet, admin2FARecoveryCodesPath, nil)
+	oldUploadBandwidth := user.UploadBandwidth
+	user.UploadBandwidth = oldUploadBandwidth + 128
+	user, _, err = httpdtest.UpdateUser(user, http.StatusOK, "")
 	assert.NoError(t, err)
-	setBearerForReq(req, altToken)
-	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusOK, rr)
-	recCodes = nil
-	err = json.Unmarshal(rr.Body.Bytes(), &recCodes)
+	admin, _, err = httpdtest.GetAdminByUsername(admin.Username, http.StatusOK)
 	assert.NoError(t, err)
-	assert.Len(t, recCodes, 12)
-	found := false
-	for _, rc := range recCodes {
-		if rc.Code == recoveryCode {
-			found = true
-			assert.True(t, rc.Used)
-		} else {
-			assert.False(t, rc.Used)
-		}
-	}
-	assert.True(t, found)
-	// the same recovery code cannot be reused
-	form = getLoginForm(altAdminUsername, altAdminPassword, csrfToken)
-	req, err = http.NewRequest(http.MethodPost, webLoginPath, bytes.NewBuffer([]byte(form.Encode())))
+	oldInfo := admin.AdditionalInfo
+	oldDesc := admin.Description
+	admin.AdditionalInfo = "newInfo"
+	admin.Description = "newDesc"
+	admin, _, err = httpdtest.UpdateAdmin(admin, http.StatusOK)
 	assert.NoError(t, err)
-	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
-	rr = executeRequest(req)
-	assert.Equal(t, http.StatusFound, rr.Code)
-	assert.Equal(t, webAdminTwoFactorPath, rr.Header().Get("Location"))
-	cookie, err = getCookieFromResponse(rr)
+	apiKey, _, err = httpdtest.GetAPIKeyByID(apiKey.KeyID, http.StatusOK)
 	assert.NoError(t, err)
-	form = make(url.Values)
-	form.Set("recovery_code", recoveryCode)
-	form.Set(csrfFormToken, csrfToken)
-	req, err = http.NewRequest(http.MethodPost, webAdminTwoFactorRecoveryPath, bytes.NewBuffer([]byte(form.Encode())))
+	oldAPIKeyDesc := apiKey.Description
+	apiKey.ExpiresAt = util.GetTimeAsMsSinceEpoch(time.Now())
+	apiKey.Description = "new desc"
+	apiKey, _, err = httpdtest.UpdateAPIKey(apiKey, http.StatusOK)
 	assert.NoError(t, err)
-	setJWTCookieForReq(req, cookie)
-	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
-	rr = executeRequest(req)
-	assert.Equal(t, http.StatusOK, rr.Code)
-	assert.Contains(t, rr.Body.String(), "This recovery code was already used")
 
-	form.Set("recovery_code", "invalid_recovery_code")
-	req, err = http.NewRequest(http.MethodPost, webAdminTwoFactorRecoveryPath, bytes.NewBuffer([]byte(form.Encode())))
+	backupData.Folders = []vfs.BaseVirtualFolder{
+		{
+			MappedPath: mappedPath,
+			Name:       folderName,
+		},
+	}
+	_, _, err = httpdtest.Loaddata(backupFilePath, "0", "1", http.StatusOK)
 	assert.NoError(t, err)
-	setJWTCookieForReq(req, cookie)
-	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
-	rr = executeRequest(req)
-	assert.Equal(t, http.StatusOK, rr.Code)
-	assert.Contains(t, rr.Body.String(), "Invalid recovery code")
+	folder, _, err = httpdtest.GetFolderByName(folderName, http.StatusOK)
+	assert.NoError(t, err)
+	assert.Equal(t, mappedPath+"1", folder.MappedPath)
+	assert.Equal(t, int64(123), folder.UsedQuotaSize)
+	assert.Equal(t, 456, folder.UsedQuotaFiles)
+	assert.Equal(t, int64(789), folder.LastQuotaUpdate)
+	assert.Len(t, folder.Users, 0)
 
-	form = getLoginForm(altAdminUsername, altAdminPassword, csrfToken)
-	req, err = http.NewRequest(http.MethodPost, webLoginPath, bytes.NewBuffer([]byte(form.Encode())))
+	c := common.NewBaseConnection("connID", common.ProtocolFTP, "", "", user)
+	fakeConn := &fakeConnection{
+		BaseConnection: c,
+	}
+	common.Connections.Add(fakeConn)
+	assert.Len(t, common.Connections.GetStats(), 1)
+	user, _, err = httpdtest.GetUserByUsername(user.Username, http.StatusOK)
 	assert.NoError(t, err)
-	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
-	rr = executeRequest(req)
-	assert.Equal(t, http.StatusFound, rr.Code)
-	assert.Equal(t, webAdminTwoFactorPath, rr.Header().Get("Location"))
-	cookie, err = getCookieFromResponse(rr)
+	assert.NotEqual(t, oldUploadBandwidth, user.UploadBandwidth)
+	admin, _, err = httpdtest.GetAdminByUsername(admin.Username, http.StatusOK)
 	assert.NoError(t, err)
+	assert.NotEqual(t, oldInfo, admin.AdditionalInfo)
+	assert.NotEqual(t, oldDesc, admin.Description)
 
-	// disable TOTP
-	req, err = http.NewRequest(http.MethodPut, adminPath+"/"+altAdminUsername+"/2fa/disable", nil)
+	apiKey, _, err = httpdtest.GetAPIKeyByID(apiKey.KeyID, http.StatusOK)
 	assert.NoError(t, err)
-	setBearerForReq(req, altToken)
-	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusOK, rr)
+	assert.NotEqual(t, int64(0), apiKey.ExpiresAt)
+	assert.NotEqual(t, oldAPIKeyDesc, apiKey.Description)
 
-	form = make(url.Values)
-	form.Set("recovery_code", recoveryCode)
-	form.Set(csrfFormToken, csrfToken)
-	req, err = http.NewRequest(http.MethodPost, webAdminTwoFactorRecoveryPath, bytes.NewBuffer([]byte(form.Encode())))
+	_, _, err = httpdtest.Loaddata(backupFilePath, "0", "2", http.StatusOK)
 	assert.NoError(t, err)
-	setJWTCookieForReq(req, cookie)
-	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
-	rr = executeRequest(req)
-	assert.Equal(t, http.StatusOK, rr.Code)
-	assert.Contains(t, rr.Body.String(), "Two factory authentication is not enabled")
-
-	form.Set("passcode", passcode)
-	req, err = http.NewRequest(http.MethodPost, webAdminTwoFactorPath, bytes.NewBuffer([]byte(form.Encode())))
+	// mode 2 will update the user and close the previous connection
+	assert.Len(t, common.Connections.GetStats(), 0)
+	user, _, err = httpdtest.GetUserByUsername(user.Username, http.StatusOK)
 	assert.NoError(t, err)
-	setJWTCookieForReq(req, cookie)
-	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
-	rr = executeRequest(req)
-	assert.Equal(t, http.StatusOK, rr.Code)
-	assert.Contains(t, rr.Body.String(), "Two factory authentication is not enabled")
-
-	_, err = httpdtest.RemoveAdmin(admin, http.StatusOK)
+	assert.Equal(t, oldUploadBandwidth, user.UploadBandwidth)
+	_, err = httpdtest.RemoveUser(user, http.StatusOK)
 	assert.NoError(t, err)
-
-	req, err = http.NewRequest(http.MethodPost, webAdminTwoFactorRecoveryPath, bytes.NewBuffer([]byte(form.Encode())))
+	_, err = httpdtest.RemoveAdmin(admin, http.StatusOK)
 	assert.NoError(t, err)
-	setJWTCookieForReq(req, cookie)
-	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
-	rr = executeRequest(req)
-	assert.Equal(t, http.StatusOK, rr.Code)
-	assert.Contains(t, rr.Body.String(), "Invalid credentials")
-
-	req, err = http.NewRequest(http.MethodPost, webAdminTwoFactorPath, bytes.NewBuffer([]byte(form.Encode())))
+	_, err = httpdtest.RemoveFolder(folder, http.StatusOK)
 	assert.NoError(t, err)
-	setJWTCookieForReq(req, cookie)
-	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
-	rr = executeRequest(req)
-	assert.Equal(t, http.StatusOK, rr.Code)
-	assert.Contains(t, rr.Body.String(), "Invalid credentials")
-
-	req, err = http.NewRequest(http.MethodGet, webAdminMFAPath, nil)
+	err = os.Remove(backupFilePath)
 	assert.NoError(t, err)
-	setJWTCookieForReq(req, authenticatedCookie)
-	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusInternalServerError, rr)
 }
 
-func TestAdminTOTP(t *testing.T) {
-	token, err := getJWTAPITokenFromTestServer(defaultTokenAuthUser, defaultTokenAuthPass)
-	assert.NoError(t, err)
-	admin := getTestAdmin()
-	admin.Username = altAdminUsername
-	admin.Password = altAdminPassword
-	// TOTPConfig will be ignored on add
-	admin.Filters.TOTPConfig = dataprovider.TOTPConfig{
-		Enabled:    true,
-		ConfigName: "config",
-		Secret:     kms.NewEmptySecret(),
+func TestLoaddataS3AccessKeys(t *testing.T) {
+	user := getTestUser()
+	user.Username = "test_user_s3_restore"
+	key := dataprovider.S3AccessKey{
+		AccessKeyID:     "RESTOREDACCESSKEYID",
+		SecretAccessKey: kms.NewPlainSecret("restored secret"),
+		Username:        user.Username,
+		Description:     "desc",
 	}
-	asJSON, err := json.Marshal(admin)
+	backupData := dataprovider.BackupData{}
+	backupData.Users = append(backupData.Users, user)
+	backupData.S3AccessKeys = append(backupData.S3AccessKeys, key)
+	backupContent, err := json.Marshal(backupData)
 	assert.NoError(t, err)
-	req, err := http.NewRequest(http.MethodPost, adminPath, bytes.NewBuffer(asJSON))
+	_, _, err = httpdtest.LoaddataFromPostBody(backupContent, "0", "0", http.StatusOK)
 	assert.NoError(t, err)
-	setBearerForReq(req, token)
-	rr := executeRequest(req)
-	checkResponseCode(t, http.StatusCreated, rr)
-
-	admin, _, err = httpdtest.GetAdminByUsername(altAdminUsername, http.StatusOK)
+	restored, err := dataprovider.S3AccessKeyExists(key.AccessKeyID)
 	assert.NoError(t, err)
-	assert.False(t, admin.Filters.TOTPConfig.Enabled)
-	assert.Len(t, admin.Filters.RecoveryCodes, 0)
-
-	altToken, err := getJWTAPITokenFromTestServer(altAdminUsername, altAdminPassword)
+	assert.Equal(t, user.Username, restored.Username)
+	assert.Equal(t, key.Description, restored.Description)
+	assert.Greater(t, restored.CreatedAt, int64(0))
+	_, err = dataprovider.CheckUserAndS3AccessKey(key.AccessKeyID, func(secret string) error {
+		if secret != "restored secret" {
+			return dataprovider.ErrInvalidCredentials
+		}
+		return nil
+	})
 	assert.NoError(t, err)
-
-	req, err = http.NewRequest(http.MethodGet, adminTOTPConfigsPath, nil)
+	// the secret is encrypted within the dump and can be restored as is
+	token, err := getJWTAPITokenFromTestServer(defaultTokenAuthUser, defaultTokenAuthPass)
 	assert.NoError(t, err)
-	setBearerForReq(req, altToken)
-	rr = executeRequest(req)
+	req, _ := http.NewRequest(http.MethodGet, dumpDataPath+"?output-data=1", nil)
+	setBearerForReq(req, token)
+	rr := executeRequest(req)
 	checkResponseCode(t, http.StatusOK, rr)
-	var configs []mfa.TOTPConfig
-	err = json.Unmarshal(rr.Body.Bytes(), &configs)
-	assert.NoError(t, err, rr.Body.String())
-	assert.Len(t, configs, len(mfa.GetAvailableTOTPConfigs()))
-	totpConfig := configs[0]
-	totpReq := generateTOTPRequest{
-		ConfigName: totpConfig.Name,
-	}
-	asJSON, err = json.Marshal(totpReq)
+	var dump dataprovider.BackupData
+	err = json.Unmarshal(rr.Body.Bytes(), &dump)
 	assert.NoError(t, err)
-	req, err = http.NewRequest(http.MethodPost, adminTOTPGeneratePath, bytes.NewBuffer(asJSON))
+	for _, k := range dump.S3AccessKeys {
+		if k.AccessKeyID == key.AccessKeyID {
+			assert.True(t, k.SecretAccessKey.IsEncrypted())
+			backupData.S3AccessKeys = []dataprovider.S3AccessKey{k}
+		}
+	}
+	assert.True(t, backupData.S3AccessKeys[0].SecretAccessKey.IsEncrypted())
+	backupData.S3AccessKeys[0].Description = "updated desc"
+	backupContent, err = json.Marshal(backupData)
 	assert.NoError(t, err)
-	setBearerForReq(req, altToken)
-	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusOK, rr)
-	var totpGenResp generateTOTPResponse
-	err = json.Unmarshal(rr.Body.Bytes(), &totpGenResp)
+	// mode 1 does not update existing keys
+	_, _, err = httpdtest.LoaddataFromPostBody(backupContent, "0", "1", http.StatusOK)
 	assert.NoError(t, err)
-	assert.NotEmpty(t, totpGenResp.Secret)
-	assert.NotEmpty(t, totpGenResp.QRCode)
-
-	passcode, err := generateTOTPPasscode(totpGenResp.Secret)
+	restored, err = dataprovider.S3AccessKeyExists(key.AccessKeyID)
 	assert.NoError(t, err)
-	validateReq := validateTOTPRequest{
-		ConfigName: totpGenResp.ConfigName,
-		Passcode:   passcode,
-		Secret:     totpGenResp.Secret,
-	}
-	asJSON, err = json.Marshal(validateReq)
+	assert.Equal(t, key.Description, restored.Description)
+	_, _, err = httpdtest.LoaddataFromPostBody(backupContent, "0", "0", http.StatusOK)
 	assert.NoError(t, err)
-	req, err = http.NewRequest(http.MethodPost, adminTOTPValidatePath, bytes.NewBuffer(asJSON))
+	restored, err = dataprovider.S3AccessKeyExists(key.AccessKeyID)
 	assert.NoError(t, err)
-	setBearerForReq(req, altToken)
-	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusOK, rr)
-	// the same passcode cannot be reused
-	req, err = http.NewRequest(http.MethodPost, adminTOTPValidatePath, bytes.NewBuffer(asJSON))
+	assert.Equal(t, "updated desc", restored.Description)
+	_, err = dataprovider.CheckUserAndS3AccessKey(key.AccessKeyID, func(secret string) error {
+		if secret != "restored secret" {
+			return dataprovider.ErrInvalidCredentials
+		}
+		return nil
+	})
 	assert.NoError(t, err)
-	setBearerForReq(req, altToken)
-	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusBadRequest, rr)
-	assert.Contains(t, rr.Body.String(), "this passcode was already used")
-
-	adminTOTPConfig := dataprovider.TOTPConfig{
-		Enabled:    true,
-		ConfigName: totpGenResp.ConfigName,
-		Secret:     kms.NewPlainSecret(totpGenResp.Secret),
+	// the key owner must exist
+	backupData.Users = nil
+	backupData.S3AccessKeys = []dataprovider.S3AccessKey{
+		{
+			AccessKeyID:     "MISSINGUSERACCESSKEY",
+			SecretAccessKey: kms.NewPlainSecret("secret"),
+			Username:        "missing_user",
+		},
 	}
-	asJSON, err = json.Marshal(adminTOTPConfig)
+	backupContent, err = json.Marshal(backupData)
 	assert.NoError(t, err)
-	req, err = http.NewRequest(http.MethodPost, adminTOTPSavePath, bytes.NewBuffer(asJSON))
+	_, _, err = httpdtest.LoaddataFromPostBody(backupContent, "0", "0", http.StatusInternalServerError)
 	assert.NoError(t, err)
-	setBearerForReq(req, altToken)
-	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusOK, rr)
-	admin, _, err = httpdtest.GetAdminByUsername(altAdminUsername, http.StatusOK)
+	_, err = dataprovider.S3AccessKeyExists("MISSINGUSERACCESSKEY")
+	assert.Error(t, err)
+
+	_, err = httpdtest.RemoveUser(user, http.StatusOK)
 	assert.NoError(t, err)
-	assert.True(t, admin.Filters.TOTPConfig.Enabled)
-	assert.Equal(t, totpGenResp.ConfigName, admin.Filters.TOTPConfig.ConfigName)
-	assert.Empty(t, admin.Filters.TOTPConfig.Secret.GetKey())
-	assert.Empty(t, admin.Filters.TOTPConfig.Secret.GetAdditionalData())
-	assert.NotEmpty(t, admin.Filters.TOTPConfig.Secret.GetPayload())
-	assert.Equal(t, kms.SecretStatusSecretBox, admin.Filters.TOTPConfig.Secret.GetStatus())
-	admin.Filters.TOTPConfig = dataprovider.TOTPConfig{
-		Enabled:    false,
-		ConfigName: shortuuid.New(),
-		Secret:     kms.NewEmptySecret(),
-	}
-	admin.Filters.RecoveryCodes = []sdk.RecoveryCode{
+	err = os.RemoveAll(user.GetHomeDir())
+	assert.NoError(t, err)
+	_, err = dataprovider.S3AccessKeyExists(key.AccessKeyID)
+	assert.Error(t, err)
+}
+
+func TestRateLimiter(t *testing.T) {
+	oldConfig := config.GetCommonConfig()
+
+	cfg := config.GetCommonConfig()
+	cfg.RateLimitersConfig = []common.RateLimiterConfig{
 		{
-			Secret: kms.NewEmptySecret(),
+			Average:   1,
+			Period:    1000,
+			Burst:     1,
+			Type:      1,
+			Protocols: []string{common.ProtocolHTTP},
 		},
 	}
-	admin, resp, err := httpdtest.UpdateAdmin(admin, http.StatusOK)
-	assert.NoError(t, err, string(resp))
-	assert.True(t, admin.Filters.TOTPConfig.Enabled)
-	assert.Len(t, admin.Filters.RecoveryCodes, 12)
-	// if we use token we should get no recovery codes
-	req, err = http.NewRequest(http.MethodGet, admin2FARecoveryCodesPath, nil)
+
+	err := common.Initialize(cfg)
 	assert.NoError(t, err)
-	setBearerForReq(req, token)
-	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusOK, rr)
-	var recCodes []recoveryCode
-	err = json.Unmarshal(rr.Body.Bytes(), &recCodes)
+
+	client := &http.Client{
+		Timeout: 5 * time.Second,
+	}
+	resp, err := client.Get(httpBaseURL + healthzPath)
 	assert.NoError(t, err)
-	assert.Len(t, recCodes, 0)
-	// now the same but with altToken
-	req, err = http.NewRequest(http.MethodGet, admin2FARecoveryCodesPath, nil)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	err = resp.Body.Close()
 	assert.NoError(t, err)
-	setBearerForReq(req, altToken)
-	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusOK, rr)
-	recCodes = nil
-	err = json.Unmarshal(rr.Body.Bytes(), &recCodes)
+
+	resp, err = client.Get(httpBaseURL + healthzPath)
 	assert.NoError(t, err)
-	assert.Len(t, recCodes, 12)
-	// regenerate recovery codes
-	req, err = http.NewRequest(http.MethodPost, admin2FARecoveryCodesPath, nil)
+	assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+	assert.Equal(t, "1", resp.Header.Get("Retry-After"))
+	assert.NotEmpty(t, resp.Header.Get("X-Retry-In"))
+	err = resp.Body.Close()
 	assert.NoError(t, err)
-	setBearerForReq(req, altToken)
-	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusOK, rr)
-	// check that recovery codes are different
-	req, err = http.NewRequest(http.MethodGet, admin2FARecoveryCodesPath, nil)
+
+	resp, err = client.Get(httpBaseURL + webLoginPath)
 	assert.NoError(t, err)
-	setBearerForReq(req, altToken)
-	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusOK, rr)
-	var newRecCodes []recoveryCode
-	err = json.Unmarshal(rr.Body.Bytes(), &newRecCodes)
+	assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+	assert.Equal(t, "1", resp.Header.Get("Retry-After"))
+	assert.NotEmpty(t, resp.Header.Get("X-Retry-In"))
+	err = resp.Body.Close()
 	assert.NoError(t, err)
-	assert.Len(t, newRecCodes, 12)
-	assert.NotEqual(t, recCodes, newRecCodes)
-	// disable 2FA, the update admin API should not work
-	admin.Filters.TOTPConfig.Enabled = false
-	admin.Filters.RecoveryCodes = nil
-	admin, _, err = httpdtest.UpdateAdmin(admin, http.StatusOK)
+
+	resp, err = client.Get(httpBaseURL + webClientLoginPath)
 	assert.NoError(t, err)
-	assert.Equal(t, altAdminUsername, admin.Username)
-	assert.True(t, admin.Filters.TOTPConfig.Enabled)
-	assert.Len(t, admin.Filters.RecoveryCodes, 12)
-	// use the dedicated API
-	req, err = http.NewRequest(http.MethodPut, adminPath+"/"+altAdminUsername+"/2fa/disable", nil)
+	assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+	assert.Equal(t, "1", resp.Header.Get("Retry-After"))
+	assert.NotEmpty(t, resp.Header.Get("X-Retry-In"))
+	err = resp.Body.Close()
 	assert.NoError(t, err)
-	setBearerForReq(req, token)
-	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusOK, rr)
-	admin, _, err = httpdtest.GetAdminByUsername(altAdminUsername, http.StatusOK)
+
+	err = common.Initialize(oldConfig)
 	assert.NoError(t, err)
-	assert.False(t, admin.Filters.TOTPConfig.Enabled)
-	assert.Len(t, admin.Filters.RecoveryCodes, 0)
+}
 
-	req, _ = http.NewRequest(http.MethodDelete, path.Join(adminPath, altAdminUsername), nil)
-	setBearerForReq(req, token)
-	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusOK, rr)
+func TestHTTPSConnection(t *testing.T) {
+	client := &http.Client{
+		Timeout: 5 * time.Second,
+	}
+	resp, err := client.Get("https://localhost:8443" + healthzPath)
+	if assert.Error(t, err) {
+		if !strings.Contains(err.Error(), "certificate is not valid") &&
+			!strings.Contains(err.Error(), "certificate signed by unknown authority") {
+			assert.Fail(t, err.Error())
+		}
+	} else {
+		resp.Body.Close()
+	}
+}
 
-	req, err = http.NewRequest(http.MethodPut, adminPath+"/"+altAdminUsername+"/2fa/disable", nil)
+// test using mock http server
+
+func TestBasicUserHandlingMock(t *testing.T) {
+	token, err := getJWTAPITokenFromTestServer(defaultTokenAuthUser, defaultTokenAuthPass)
+	assert.NoError(t, err)
+	user := getTestUser()
+	userAsJSON := getUserAsJSON(t, user)
+	req, err := http.NewRequest(http.MethodPost, userPath, bytes.NewBuffer(userAsJSON))
 	assert.NoError(t, err)
 	setBearerForReq(req, token)
-	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusNotFound, rr)
-
-	req, err = http.NewRequest(http.MethodGet, admin2FARecoveryCodesPath, nil)
+	rr := executeRequest(req)
+	checkResponseCode(t, http.StatusCreated, rr)
+	err = render.DecodeJSON(rr.Body, &user)
 	assert.NoError(t, err)
-	setBearerForReq(req, altToken)
+	req, err = http.NewRequest(http.MethodPost, userPath, bytes.NewBuffer(userAsJSON))
+	assert.NoError(t, err)
+	setBearerForReq(req, token)
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusNotFound, rr)
+	checkResponseCode(t, http.StatusInternalServerError, rr)
+	user.MaxSessions = 10
+	user.UploadBandwidth = 128
+	user.Permissions["/"] = []string{dataprovider.PermAny, dataprovider.PermDelete, dataprovider.PermDownload}
+	userAsJSON = getUserAsJSON(t, user)
+	req, _ = http.NewRequest(http.MethodPut, userPath+"/"+user.Username, bytes.NewBuffer(userAsJSON))
+	setBearerForReq(req, token)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
 
-	req, err = http.NewRequest(http.MethodPost, admin2FARecoveryCodesPath, nil)
-	assert.NoError(t, err)
-	setBearerForReq(req, altToken)
+	req, _ = http.NewRequest(http.MethodGet, userPath+"/"+user.Username, nil)
+	setBearerForReq(req, token)
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusNotFound, rr)
+	checkResponseCode(t, http.StatusOK, rr)
 
-	req, err = http.NewRequest(http.MethodPost, adminTOTPSavePath, bytes.NewBuffer(asJSON))
+	var updatedUser dataprovider.User
+	err = render.DecodeJSON(rr.Body, &updatedUser)
 	assert.NoError(t, err)
-	setBearerForReq(req, altToken)
+	assert.Equal(t, user.MaxSessions, updatedUser.MaxSessions)
+	assert.Equal(t, user.UploadBandwidth, updatedUser.UploadBandwidth)
+	assert.Equal(t, 1, len(updatedUser.Permissions["/"]))
+	assert.True(t, util.IsStringInSlice(dataprovider.PermAny, updatedUser.Permissions["/"]))
+	req, _ = http.NewRequest(http.MethodDelete, userPath+"/"+user.Username, nil)
+	setBearerForReq(req, token)
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusNotFound, rr)
+	checkResponseCode(t, http.StatusOK, rr)
 }
 
-func TestChangeAdminPwdInvalidJsonMock(t *testing.T) {
+func TestAddUserNoUsernameMock(t *testing.T) {
 	token, err := getJWTAPITokenFromTestServer(defaultTokenAuthUser, defaultTokenAuthPass)
 	assert.NoError(t, err)
-	req, _ := http.NewRequest(http.MethodPut, adminPwdPath, bytes.NewBuffer([]byte("{")))
+	user := getTestUser()
+	user.Username = ""
+	userAsJSON := getUserAsJSON(t, user)
+	req, _ := http.NewRequest(http.MethodPost, userPath, bytes.NewBuffer(userAsJSON))
 	setBearerForReq(req, token)
 	rr := executeRequest(req)
 	checkResponseCode(t, http.StatusBadRequest, rr)
 }
 
-func TestMFAPermission(t *testing.T) {
-	user, _, err := httpdtest.AddUser(getTestUser(), http.StatusCreated)
+func TestAddUserInvalidHomeDirMock(t *testing.T) {
+	token, err := getJWTAPITokenFromTestServer(defaultTokenAuthUser, defaultTokenAuthPass)
 	assert.NoError(t, err)
+	user := getTestUser()
+	user.HomeDir = "relative_path"
+	userAsJSON := getUserAsJSON(t, user)
+	req, _ := http.NewRequest(http.MethodPost, userPath, bytes.NewBuffer(userAsJSON))
+	setBearerForReq(req, token)
+	rr := executeRequest(req)
+	checkResponseCode(t, http.StatusBadRequest, rr)
+}
 
-	webToken, err := getJWTWebClientTokenFromTestServer(defaultUsername, defaultPassword)
+func TestAddUserInvalidPermsMock(t *testing.T) {
+	token, err := getJWTAPITokenFromTestServer(defaultTokenAuthUser, defaultTokenAuthPass)
 	assert.NoError(t, err)
+	user := getTestUser()
+	user.Permissions["/"] = []string{}
+	userAsJSON := getUserAsJSON(t, user)
+	req, _ := http.NewRequest(http.MethodPost, userPath, bytes.NewBuffer(userAsJSON))
+	setBearerForReq(req, token)
+	rr := executeRequest(req)
+	checkResponseCode(t, http.StatusBadRequest, rr)
+}
 
-	req, err := http.NewRequest(http.MethodGet, webClientMFAPath, nil)
+func TestAddFolderInvalidJsonMock(t *testing.T) {
+	token, err := getJWTAPITokenFromTestServer(defaultTokenAuthUser, defaultTokenAuthPass)
 	assert.NoError(t, err)
-	req.RequestURI = webClientMFAPath
-	setJWTCookieForReq(req, webToken)
+	req, _ := http.NewRequest(http.MethodPost, folderPath, bytes.NewBuffer([]byte("invalid json")))
+	setBearerForReq(req, token)
 	rr := executeRequest(req)
-	checkResponseCode(t, http.StatusOK, rr)
+	checkResponseCode(t, http.StatusBadRequest, rr)
+}
 
-	user.Filters.WebClient = []string{sdk.WebClientMFADisabled}
-	user, _, err = httpdtest.UpdateUser(user, http.StatusOK, "")
-	assert.NoError(t, err)
+func TestUpdateFolderInvalidJsonMock(t *testing.T) {
+	folder := vfs.BaseVirtualFolder{
+		Name:       "name",
+		MappedPath: filepath.Clean(os.TempDir()),
+	}
+	folder, resp, err := httpdtest.AddFolder(folder, http.StatusCreated)
+	assert.NoError(t, err, string(resp))
 
-	webToken, err = getJWTWebClientTokenFromTestServer(defaultUsername, defaultPassword)
+	token, err := getJWTAPITokenFromTestServer(defaultTokenAuthUser, defaultTokenAuthPass)
 	assert.NoError(t, err)
+	req, _ := http.NewRequest(http.MethodPut, path.Join(folderPath, folder.Name), bytes.NewBuffer([]byte("not a json")))
+	setBearerForReq(req, token)
+	rr := executeRequest(req)
+	checkResponseCode(t, http.StatusBadRequest, rr)
 
-	req, err = http.NewRequest(http.MethodGet, webClientMFAPath, nil)
+	_, err = httpdtest.RemoveFolder(folder, http.StatusOK)
 	assert.NoError(t, err)
-	req.RequestURI = webClientMFAPath
-	setJWTCookieForReq(req, webToken)
-	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusForbidden, rr)
+}
 
-	_, err = httpdtest.RemoveUser(user, http.StatusOK)
-	assert.NoError(t, err)
-	err = os.RemoveAll(user.GetHomeDir())
+func TestUnbanInvalidJsonMock(t *testing.T) {
+	token, err := getJWTAPITokenFromTestServer(defaultTokenAuthUser, defaultTokenAuthPass)
 	assert.NoError(t, err)
+	req, _ := http.NewRequest(http.MethodPost, defenderUnban, bytes.NewBuffer([]byte("invalid json")))
+	setBearerForReq(req, token)
+	rr := executeRequest(req)
+	checkResponseCode(t, http.StatusBadRequest, rr)
 }
 
-func TestWebUserTwoFactorLogin(t *testing.T) {
-	u := getTestUser()
-	user, _, err := httpdtest.AddUser(u, http.StatusCreated)
-	assert.NoError(t, err)
-	// enable two factor authentication
-	configName, _, secret, _, err := mfa.GenerateTOTPSecret(mfa.GetAvailableTOTPConfigNames()[0], user.Username)
+func TestAddUserInvalidJsonMock(t *testing.T) {
+	token, err := getJWTAPITokenFromTestServer(defaultTokenAuthUser, defaultTokenAuthPass)
 	assert.NoError(t, err)
-	token, err := getJWTAPIUserTokenFromTestServer(defaultUsername, defaultPassword)
+	req, _ := http.NewRequest(http.MethodPost, userPath, bytes.NewBuffer([]byte("invalid json")))
+	setBearerForReq(req, token)
+	rr := executeRequest(req)
+	checkResponseCode(t, http.StatusBadRequest, rr)
+}
+
+func TestAddAdminInvalidJsonMock(t *testing.T) {
+	token, err := getJWTAPITokenFromTestServer(defaultTokenAuthUser, defaultTokenAuthPass)
 	assert.NoError(t, err)
-	adminToken, err := getJWTAPITokenFromTestServer(defaultTokenAuthUser, defaultTokenAuthPass)
+	req, _ := http.NewRequest(http.MethodPost, adminPath, bytes.NewBuffer([]byte("...")))
+	setBearerForReq(req, token)
+	rr := executeRequest(req)
+	checkResponseCode(t, http.StatusBadRequest, rr)
+}
+
+func TestAddAdminNoPasswordMock(t *testing.T) {
+	token, err := getJWTAPITokenFromTestServer(defaultTokenAuthUser, defaultTokenAuthPass)
 	assert.NoError(t, err)
-	webToken, err := getJWTWebClientTokenFromTestServer(defaultUsername, defaultPassword)
+	admin := getTestAdmin()
+	admin.Password = ""
+	asJSON, err := json.Marshal(admin)
 	assert.NoError(t, err)
+	req, _ := http.NewRequest(http.MethodPost, adminPath, bytes.NewBuffer(asJSON))
+	setBearerForReq(req, token)
+	rr := executeRequest(req)
+	checkResponseCode(t, http.StatusBadRequest, rr)
+	assert.Contains(t, rr.Body.String(), "please set a password")
+}
 
-	userTOTPConfig := sdk.TOTPConfig{
+func TestAdminTwoFactorLogin(t *testing.T) {
+	admin := getTestAdmin()
+	admin.Username = altAdminUsername
+	admin.Password = altAdminPassword
+	admin, _, err := httpdtest.AddAdmin(admin, http.StatusCreated)
+	assert.NoError(t, err)
+	// enable two factor authentication
+	configName, _, secret, _, err := mfa.GenerateTOTPSecret(mfa.GetAvailableTOTPConfigNames()[0], admin.Username)
+	assert.NoError(t, err)
+	altToken, err := getJWTAPITokenFromTestServer(altAdminUsername, altAdminPassword)
+	assert.NoError(t, err)
+	adminTOTPConfig := dataprovider.TOTPConfig{
 		Enabled:    true,
 		ConfigName: configName,
 		Secret:     kms.NewPlainSecret(secret),
-		Protocols:  []string{common.ProtocolHTTP},
 	}
-	asJSON, err := json.Marshal(userTOTPConfig)
+	asJSON, err := json.Marshal(adminTOTPConfig)
 	assert.NoError(t, err)
-	req, err := http.NewRequest(http.MethodPost, userTOTPSavePath, bytes.NewBuffer(asJSON))
+	req, err := http.NewRequest(http.MethodPost, adminTOTPSavePath, bytes.NewBuffer(asJSON))
 	assert.NoError(t, err)
-	setBearerForReq(req, token)
+	setBearerForReq(req, altToken)
 	rr := executeRequest(req)
 	checkResponseCode(t, http.StatusOK, rr)
+	admin, _, err = httpdtest.GetAdminByUsername(admin.Username, http.StatusOK)
+	assert.NoError(t, err)
+	assert.True(t, admin.Filters.TOTPConfig.Enabled)
 
-	req, err = http.NewRequest(http.MethodGet, user2FARecoveryCodesPath, nil)
+	req, err = http.NewRequest(http.MethodGet, admin2FARecoveryCodesPath, nil)
 	assert.NoError(t, err)
-	setBearerForReq(req, token)
+	setBearerForReq(req, altToken)
 	rr = executeRequest(req)
 	checkResponseCode(t, http.StatusOK, rr)
 	var recCodes []recoveryCode
@@ -5244,90 +6655,93 @@ func TestWebUserTwoFactorLogin(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Len(t, recCodes, 12)
 
-	user, _, err = httpdtest.GetUserByUsername(defaultUsername, http.StatusOK)
+	admin, _, err = httpdtest.GetAdminByUsername(altAdminUsername, http.StatusOK)
 	assert.NoError(t, err)
-	assert.Len(t, user.Filters.RecoveryCodes, 12)
-	for _, c := range user.Filters.RecoveryCodes {
+	assert.Len(t, admin.Filters.RecoveryCodes, 12)
+	for _, c := range admin.Filters.RecoveryCodes {
 		assert.Empty(t, c.Secret.GetAdditionalData())
 		assert.Empty(t, c.Secret.GetKey())
 		assert.Equal(t, kms.SecretStatusSecretBox, c.Secret.GetStatus())
 		assert.NotEmpty(t, c.Secret.GetPayload())
 	}
 
-	req, err = http.NewRequest(http.MethodGet, webClientTwoFactorPath, nil)
+	webToken, err := getJWTWebTokenFromTestServer(defaultTokenAuthUser, defaultTokenAuthPass)
+	assert.NoError(t, err)
+	req, err = http.NewRequest(http.MethodGet, webAdminTwoFactorPath, nil)
 	assert.NoError(t, err)
 	setJWTCookieForReq(req, webToken)
 	rr = executeRequest(req)
 	checkResponseCode(t, http.StatusNotFound, rr)
 
-	req, err = http.NewRequest(http.MethodGet, webClientTwoFactorRecoveryPath, nil)
+	req, err = http.NewRequest(http.MethodGet, webAdminTwoFactorRecoveryPath, nil)
 	assert.NoError(t, err)
 	setJWTCookieForReq(req, webToken)
 	rr = executeRequest(req)
 	checkResponseCode(t, http.StatusNotFound, rr)
 
-	req, err = http.NewRequest(http.MethodPost, webClientTwoFactorPath, nil)
+	req, err = http.NewRequest(http.MethodPost, webAdminTwoFactorPath, nil)
 	assert.NoError(t, err)
 	setJWTCookieForReq(req, webToken)
 	rr = executeRequest(req)
 	checkResponseCode(t, http.StatusNotFound, rr)
 
-	req, err = http.NewRequest(http.MethodPost, webClientTwoFactorRecoveryPath, nil)
+	req, err = http.NewRequest(http.MethodPost, webAdminTwoFactorRecoveryPath, nil)
 	assert.NoError(t, err)
 	setJWTCookieForReq(req, webToken)
 	rr = executeRequest(req)
 	checkResponseCode(t, http.StatusNotFound, rr)
 
-	csrfToken, err := getCSRFToken(httpBaseURL + webClientLoginPath)
+	csrfToken, err := getCSRFToken(httpBaseURL + webLoginPath)
 	assert.NoError(t, err)
-	form := getLoginForm(defaultUsername, defaultPassword, csrfToken)
-	req, err = http.NewRequest(http.MethodPost, webClientLoginPath, bytes.NewBuffer([]byte(form.Encode())))
+	form := getLoginForm(altAdminUsername, altAdminPassword, csrfToken)
+	req, err = http.NewRequest(http.MethodPost, webLoginPath, bytes.NewBuffer([]byte(form.Encode())))
 	assert.NoError(t, err)
 	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 	rr = executeRequest(req)
 	assert.Equal(t, http.StatusFound, rr.Code)
-	assert.Equal(t, webClientTwoFactorPath, rr.Header().Get("Location"))
+	assert.Equal(t, webAdminTwoFactorPath, rr.Header().Get("Location"))
 	cookie, err := getCookieFromResponse(rr)
 	assert.NoError(t, err)
 
-	req, err = http.NewRequest(http.MethodGet, webClientTwoFactorPath, nil)
+	// without a cookie
+	req, err = http.NewRequest(http.MethodGet, webAdminTwoFactorRecoveryPath, nil)
 	assert.NoError(t, err)
-	setJWTCookieForReq(req, cookie)
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusOK, rr)
+	checkResponseCode(t, http.StatusNotFound, rr)
 
-	// without a cookie
-	req, err = http.NewRequest(http.MethodGet, webClientTwoFactorPath, nil)
+	req, err = http.NewRequest(http.MethodGet, webAdminTwoFactorPath, nil)
 	assert.NoError(t, err)
+	setJWTCookieForReq(req, cookie)
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusNotFound, rr)
+	checkResponseCode(t, http.StatusOK, rr)
 
-	req, err = http.NewRequest(http.MethodGet, webClientTwoFactorRecoveryPath, nil)
+	req, err = http.NewRequest(http.MethodGet, webAdminTwoFactorRecoveryPath, nil)
 	assert.NoError(t, err)
 	setJWTCookieForReq(req, cookie)
 	rr = executeRequest(req)
 	checkResponseCode(t, http.StatusOK, rr)
+
 	// any other page will be redirected to the two factor auth page
-	req, err = http.NewRequest(http.MethodGet, webClientFilesPath, nil)
+	req, err = http.NewRequest(http.MethodGet, webUsersPath, nil)
 	assert.NoError(t, err)
 	setJWTCookieForReq(req, cookie)
 	rr = executeRequest(req)
 	checkResponseCode(t, http.StatusFound, rr)
-	assert.Equal(t, webClientTwoFactorPath, rr.Header().Get("Location"))
-	// a partial token cannot be used for admin pages
-	req, err = http.NewRequest(http.MethodGet, webUsersPath, nil)
+	assert.Equal(t, webAdminTwoFactorPath, rr.Header().Get("Location"))
+	// a partial token cannot be used for user pages
+	req, err = http.NewRequest(http.MethodGet, webClientFilesPath, nil)
 	assert.NoError(t, err)
 	setJWTCookieForReq(req, cookie)
 	rr = executeRequest(req)
 	checkResponseCode(t, http.StatusFound, rr)
-	assert.Equal(t, webLoginPath, rr.Header().Get("Location"))
+	assert.Equal(t, webClientLoginPath, rr.Header().Get("Location"))
 
 	passcode, err := generateTOTPPasscode(secret)
 	assert.NoError(t, err)
 	form = make(url.Values)
 	form.Set("passcode", passcode)
-
-	req, err = http.NewRequest(http.MethodPost, webClientTwoFactorPath, bytes.NewBuffer([]byte(form.Encode())))
+	// no csrf
+	req, err = http.NewRequest(http.MethodPost, webAdminTwoFactorPath, bytes.NewBuffer([]byte(form.Encode())))
 	assert.NoError(t, err)
 	setJWTCookieForReq(req, cookie)
 	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
@@ -5336,8 +6750,8 @@ func TestWebUserTwoFactorLogin(t *testing.T) {
 	assert.Contains(t, rr.Body.String(), "unable to verify form token")
 
 	form.Set(csrfFormToken, csrfToken)
-	form.Set("passcode", "invalid_user_passcode")
-	req, err = http.NewRequest(http.MethodPost, webClientTwoFactorPath, bytes.NewBuffer([]byte(form.Encode())))
+	form.Set("passcode", "invalid_passcode")
+	req, err = http.NewRequest(http.MethodPost, webAdminTwoFactorPath, bytes.NewBuffer([]byte(form.Encode())))
 	assert.NoError(t, err)
 	setJWTCookieForReq(req, cookie)
 	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
@@ -5346,7 +6760,7 @@ func TestWebUserTwoFactorLogin(t *testing.T) {
 	assert.Contains(t, rr.Body.String(), "Invalid authentication code")
 
 	form.Set("passcode", "")
-	req, err = http.NewRequest(http.MethodPost, webClientTwoFactorPath, bytes.NewBuffer([]byte(form.Encode())))
+	req, err = http.NewRequest(http.MethodPost, webAdminTwoFactorPath, bytes.NewBuffer([]byte(form.Encode())))
 	assert.NoError(t, err)
 	setJWTCookieForReq(req, cookie)
 	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
@@ -5355,28 +6769,28 @@ func TestWebUserTwoFactorLogin(t *testing.T) {
 	assert.Contains(t, rr.Body.String(), "Invalid credentials")
 
 	form.Set("passcode", passcode)
-	req, err = http.NewRequest(http.MethodPost, webClientTwoFactorPath, bytes.NewBuffer([]byte(form.Encode())))
+	req, err = http.NewRequest(http.MethodPost, webAdminTwoFactorPath, bytes.NewBuffer([]byte(form.Encode())))
 	assert.NoError(t, err)
 	setJWTCookieForReq(req, cookie)
 	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 	rr = executeRequest(req)
 	assert.Equal(t, http.StatusFound, rr.Code)
-	assert.Equal(t, webClientFilesPath, rr.Header().Get("Location"))
+	assert.Equal(t, webUsersPath, rr.Header().Get("Location"))
 	// the same cookie cannot be reused
-	req, err = http.NewRequest(http.MethodPost, webClientTwoFactorPath, bytes.NewBuffer([]byte(form.Encode())))
+	req, err = http.NewRequest(http.MethodPost, webAdminTwoFactorPath, bytes.NewBuffer([]byte(form.Encode())))
 	assert.NoError(t, err)
 	setJWTCookieForReq(req, cookie)
 	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 	rr = executeRequest(req)
 	assert.Equal(t, http.StatusNotFound, rr.Code)
 	// get a new cookie and login using a recovery code
-	form = getLoginForm(defaultUsername, defaultPassword, csrfToken)
-	req, err = http.NewRequest(http.MethodPost, webClientLoginPath, bytes.NewBuffer([]byte(form.Encode())))
+	form = getLoginForm(altAdminUsername, altAdminPassword, csrfToken)
+	req, err = http.NewRequest(http.MethodPost, webLoginPath, bytes.NewBuffer([]byte(form.Encode())))
 	assert.NoError(t, err)
 	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 	rr = executeRequest(req)
 	assert.Equal(t, http.StatusFound, rr.Code)
-	assert.Equal(t, webClientTwoFactorPath, rr.Header().Get("Location"))
+	assert.Equal(t, webAdminTwoFactorPath, rr.Header().Get("Location"))
 	cookie, err = getCookieFromResponse(rr)
 	assert.NoError(t, err)
 
@@ -5384,7 +6798,7 @@ func TestWebUserTwoFactorLogin(t *testing.T) {
 	recoveryCode := recCodes[0].Code
 	form.Set("recovery_code", recoveryCode)
 	// no csrf
-	req, err = http.NewRequest(http.MethodPost, webClientTwoFactorRecoveryPath, bytes.NewBuffer([]byte(form.Encode())))
+	req, err = http.NewRequest(http.MethodPost, webAdminTwoFactorRecoveryPath, bytes.NewBuffer([]byte(form.Encode())))
 	assert.NoError(t, err)
 	setJWTCookieForReq(req, cookie)
 	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
@@ -5394,7 +6808,7 @@ func TestWebUserTwoFactorLogin(t *testing.T) {
 
 	form.Set(csrfFormToken, csrfToken)
 	form.Set("recovery_code", "")
-	req, err = http.NewRequest(http.MethodPost, webClientTwoFactorRecoveryPath, bytes.NewBuffer([]byte(form.Encode())))
+	req, err = http.NewRequest(http.MethodPost, webAdminTwoFactorRecoveryPath, bytes.NewBuffer([]byte(form.Encode())))
 	assert.NoError(t, err)
 	setJWTCookieForReq(req, cookie)
 	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
@@ -5403,26 +6817,25 @@ func TestWebUserTwoFactorLogin(t *testing.T) {
 	assert.Contains(t, rr.Body.String(), "Invalid credentials")
 
 	form.Set("recovery_code", recoveryCode)
-	req, err = http.NewRequest(http.MethodPost, webClientTwoFactorRecoveryPath, bytes.NewBuffer([]byte(form.Encode())))
+	req, err = http.NewRequest(http.MethodPost, webAdminTwoFactorRecoveryPath, bytes.NewBuffer([]byte(form.Encode())))
 	assert.NoError(t, err)
 	setJWTCookieForReq(req, cookie)
 	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 	rr = executeRequest(req)
 	assert.Equal(t, http.StatusFound, rr.Code)
-	assert.Equal(t, webClientFilesPath, rr.Header().Get("Location"))
+	assert.Equal(t, webUsersPath, rr.Header().Get("Location"))
 	authenticatedCookie, err := getCookieFromResponse(rr)
 	assert.NoError(t, err)
 	//render MFA page
-	req, err = http.NewRequest(http.MethodGet, webClientMFAPath, nil)
+	req, err = http.NewRequest(http.MethodGet, webAdminMFAPath, nil)
 	assert.NoError(t, err)
 	setJWTCookieForReq(req, authenticatedCookie)
 	rr = executeRequest(req)
 	checkResponseCode(t, http.StatusOK, rr)
-
 	// check that the recovery code was marked as used
-	req, err = http.NewRequest(http.MethodGet, user2FARecoveryCodesPath, nil)
+	req, err = http.NewRequest(http.MethodGet, admin2FARecoveryCodesPath, nil)
 	assert.NoError(t, err)
-	setBearerForReq(req, token)
+	setBearerForReq(req, altToken)
 	rr = executeRequest(req)
 	checkResponseCode(t, http.StatusOK, rr)
 	recCodes = nil
@@ -5440,19 +6853,19 @@ func TestWebUserTwoFactorLogin(t *testing.T) {
 	}
 	assert.True(t, found)
 	// the same recovery code cannot be reused
-	form = getLoginForm(defaultUsername, defaultPassword, csrfToken)
-	req, err = http.NewRequest(http.MethodPost, webClientLoginPath, bytes.NewBuffer([]byte(form.Encode())))
+	form = getLoginForm(altAdminUsername, altAdminPassword, csrfToken)
+	req, err = http.NewRequest(http.MethodPost, webLoginPath, bytes.NewBuffer([]byte(form.Encode())))
 	assert.NoError(t, err)
 	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 	rr = executeRequest(req)
 	assert.Equal(t, http.StatusFound, rr.Code)
-	assert.Equal(t, webClientTwoFactorPath, rr.Header().Get("Location"))
+	assert.Equal(t, webAdminTwoFactorPath, rr.Header().Get("Location"))
 	cookie, err = getCookieFromResponse(rr)
 	assert.NoError(t, err)
 	form = make(url.Values)
 	form.Set("recovery_code", recoveryCode)
 	form.Set(csrfFormToken, csrfToken)
-	req, err = http.NewRequest(http.MethodPost, webClientTwoFactorRecoveryPath, bytes.NewBuffer([]byte(form.Encode())))
+	req, err = http.NewRequest(http.MethodPost, webAdminTwoFactorRecoveryPath, bytes.NewBuffer([]byte(form.Encode())))
 	assert.NoError(t, err)
 	setJWTCookieForReq(req, cookie)
 	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
@@ -5460,8 +6873,8 @@ func TestWebUserTwoFactorLogin(t *testing.T) {
 	assert.Equal(t, http.StatusOK, rr.Code)
 	assert.Contains(t, rr.Body.String(), "This recovery code was already used")
 
-	form.Set("recovery_code", "invalid_user_recovery_code")
-	req, err = http.NewRequest(http.MethodPost, webClientTwoFactorRecoveryPath, bytes.NewBuffer([]byte(form.Encode())))
+	form.Set("recovery_code", "invalid_recovery_code")
+	req, err = http.NewRequest(http.MethodPost, webAdminTwoFactorRecoveryPath, bytes.NewBuffer([]byte(form.Encode())))
 	assert.NoError(t, err)
 	setJWTCookieForReq(req, cookie)
 	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
@@ -5469,29 +6882,27 @@ func TestWebUserTwoFactorLogin(t *testing.T) {
 	assert.Equal(t, http.StatusOK, rr.Code)
 	assert.Contains(t, rr.Body.String(), "Invalid recovery code")
 
-	form = getLoginForm(defaultUsername, defaultPassword, csrfToken)
-	req, err = http.NewRequest(http.MethodPost, webClientLoginPath, bytes.NewBuffer([]byte(form.Encode())))
+	form = getLoginForm(altAdminUsername, altAdminPassword, csrfToken)
+	req, err = http.NewRequest(http.MethodPost, webLoginPath, bytes.NewBuffer([]byte(form.Encode())))
 	assert.NoError(t, err)
 	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 	rr = executeRequest(req)
 	assert.Equal(t, http.StatusFound, rr.Code)
-	assert.Equal(t, webClientTwoFactorPath, rr.Header().Get("Location"))
+	assert.Equal(t, webAdminTwoFactorPath, rr.Header().Get("Location"))
 	cookie, err = getCookieFromResponse(rr)
 	assert.NoError(t, err)
 
 	// disable TOTP
-	req, err = http.NewRequest(http.MethodPut, userPath+"/"+user.Username+"/2fa/disable", nil)
+	req, err = http.NewRequest(http.MethodPut, adminPath+"/"+altAdminUsername+"/2fa/disable", nil)
 	assert.NoError(t, err)
-	setBearerForReq(req, adminToken)
+	setBearerForReq(req, altToken)
 	rr = executeRequest(req)
 	checkResponseCode(t, http.StatusOK, rr)
 
 	form = make(url.Values)
 	form.Set("recovery_code", recoveryCode)
-	form.Set("passcode", passcode)
 	form.Set(csrfFormToken, csrfToken)
-
-	req, err = http.NewRequest(http.MethodPost, webClientTwoFactorRecoveryPath, bytes.NewBuffer([]byte(form.Encode())))
+	req, err = http.NewRequest(http.MethodPost, webAdminTwoFactorRecoveryPath, bytes.NewBuffer([]byte(form.Encode())))
 	assert.NoError(t, err)
 	setJWTCookieForReq(req, cookie)
 	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
@@ -5499,7 +6910,8 @@ func TestWebUserTwoFactorLogin(t *testing.T) {
 	assert.Equal(t, http.StatusOK, rr.Code)
 	assert.Contains(t, rr.Body.String(), "Two factory authentication is not enabled")
 
-	req, err = http.NewRequest(http.MethodPost, webClientTwoFactorPath, bytes.NewBuffer([]byte(form.Encode())))
+	form.Set("passcode", passcode)
+	req, err = http.NewRequest(http.MethodPost, webAdminTwoFactorPath, bytes.NewBuffer([]byte(form.Encode())))
 	assert.NoError(t, err)
 	setJWTCookieForReq(req, cookie)
 	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
@@ -5507,12 +6919,10 @@ func TestWebUserTwoFactorLogin(t *testing.T) {
 	assert.Equal(t, http.StatusOK, rr.Code)
 	assert.Contains(t, rr.Body.String(), "Two factory authentication is not enabled")
 
-	_, err = httpdtest.RemoveUser(user, http.StatusOK)
-	assert.NoError(t, err)
-	err = os.RemoveAll(user.GetHomeDir())
+	_, err = httpdtest.RemoveAdmin(admin, http.StatusOK)
 	assert.NoError(t, err)
 
-	req, err = http.NewRequest(http.MethodPost, webClientTwoFactorRecoveryPath, bytes.NewBuffer([]byte(form.Encode())))
+	req, err = http.NewRequest(http.MethodPost, webAdminTwoFactorRecoveryPath, bytes.NewBuffer([]byte(form.Encode())))
 	assert.NoError(t, err)
 	setJWTCookieForReq(req, cookie)
 	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
@@ -5520,7 +6930,7 @@ func TestWebUserTwoFactorLogin(t *testing.T) {
 	assert.Equal(t, http.StatusOK, rr.Code)
 	assert.Contains(t, rr.Body.String(), "Invalid credentials")
 
-	req, err = http.NewRequest(http.MethodPost, webClientTwoFactorPath, bytes.NewBuffer([]byte(form.Encode())))
+	req, err = http.NewRequest(http.MethodPost, webAdminTwoFactorPath, bytes.NewBuffer([]byte(form.Encode())))
 	assert.NoError(t, err)
 	setJWTCookieForReq(req, cookie)
 	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
@@ -5528,271 +6938,323 @@ func TestWebUserTwoFactorLogin(t *testing.T) {
 	assert.Equal(t, http.StatusOK, rr.Code)
 	assert.Contains(t, rr.Body.String(), "Invalid credentials")
 
-	req, err = http.NewRequest(http.MethodGet, webClientMFAPath, nil)
+	req, err = http.NewRequest(http.MethodGet, webAdminMFAPath, nil)
 	assert.NoError(t, err)
 	setJWTCookieForReq(req, authenticatedCookie)
 	rr = executeRequest(req)
 	checkResponseCode(t, http.StatusInternalServerError, rr)
 }
 
-func TestSearchEvents(t *testing.T) {
+func TestAdminTOTP(t *testing.T) {
 	token, err := getJWTAPITokenFromTestServer(defaultTokenAuthUser, defaultTokenAuthPass)
 	assert.NoError(t, err)
-
-	req, err := http.NewRequest(http.MethodGet, fsEventsPath+"?limit=10&order=ASC", nil)
+	admin := getTestAdmin()
+	admin.Username = altAdminUsername
+	admin.Password = altAdminPassword
+	// TOTPConfig will be ignored on add
+	admin.Filters.TOTPConfig = dataprovider.TOTPConfig{
+		Enabled:    true,
+		ConfigName: "config",
+		Secret:     kms.NewEmptySecret(),
+	}
+	asJSON, err := json.Marshal(admin)
+	assert.NoError(t, err)
+	req, err := http.NewRequest(http.MethodPost, adminPath, bytes.NewBuffer(asJSON))
 	assert.NoError(t, err)
 	setBearerForReq(req, token)
 	rr := executeRequest(req)
-	checkResponseCode(t, http.StatusOK, rr)
+	checkResponseCode(t, http.StatusCreated, rr)
 
-	// the test eventsearcher plugin returns error if start_timestamp < 0
-	req, err = http.NewRequest(http.MethodGet, fsEventsPath+"?start_timestamp=-1&end_timestamp=123456&statuses=1,2", nil)
+	admin, _, err = httpdtest.GetAdminByUsername(altAdminUsername, http.StatusOK)
 	assert.NoError(t, err)
-	setBearerForReq(req, token)
-	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusInternalServerError, rr)
+	assert.False(t, admin.Filters.TOTPConfig.Enabled)
+	assert.Len(t, admin.Filters.RecoveryCodes, 0)
 
-	req, err = http.NewRequest(http.MethodGet, fsEventsPath+"?limit=a", nil)
+	altToken, err := getJWTAPITokenFromTestServer(altAdminUsername, altAdminPassword)
 	assert.NoError(t, err)
-	setBearerForReq(req, token)
-	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusBadRequest, rr)
 
-	req, err = http.NewRequest(http.MethodGet, providerEventsPath, nil)
+	req, err = http.NewRequest(http.MethodGet, adminTOTPConfigsPath, nil)
 	assert.NoError(t, err)
-	setBearerForReq(req, token)
+	setBearerForReq(req, altToken)
 	rr = executeRequest(req)
 	checkResponseCode(t, http.StatusOK, rr)
-
-	// the test eventsearcher plugin returns error if start_timestamp < 0
-	req, err = http.NewRequest(http.MethodGet, providerEventsPath+"?start_timestamp=-1", nil)
+	var configs []mfa.TOTPConfig
+	err = json.Unmarshal(rr.Body.Bytes(), &configs)
+	assert.NoError(t, err, rr.Body.String())
+	assert.Len(t, configs, len(mfa.GetAvailableTOTPConfigs()))
+	totpConfig := configs[0]
+	totpReq := generateTOTPRequest{
+		ConfigName: totpConfig.Name,
+	}
+	asJSON, err = json.Marshal(totpReq)
 	assert.NoError(t, err)
-	setBearerForReq(req, token)
-	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusInternalServerError, rr)
-
-	req, err = http.NewRequest(http.MethodGet, providerEventsPath+"?limit=2000", nil)
+	req, err = http.NewRequest(http.MethodPost, adminTOTPGeneratePath, bytes.NewBuffer(asJSON))
 	assert.NoError(t, err)
-	setBearerForReq(req, token)
+	setBearerForReq(req, altToken)
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusBadRequest, rr)
-
-	req, err = http.NewRequest(http.MethodGet, fsEventsPath+"?start_timestamp=a", nil)
+	checkResponseCode(t, http.StatusOK, rr)
+	var totpGenResp generateTOTPResponse
+	err = json.Unmarshal(rr.Body.Bytes(), &totpGenResp)
 	assert.NoError(t, err)
-	setBearerForReq(req, token)
-	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusBadRequest, rr)
+	assert.NotEmpty(t, totpGenResp.Secret)
+	assert.NotEmpty(t, totpGenResp.QRCode)
 
-	req, err = http.NewRequest(http.MethodGet, fsEventsPath+"?end_timestamp=a", nil)
+	passcode, err := generateTOTPPasscode(totpGenResp.Secret)
 	assert.NoError(t, err)
-	setBearerForReq(req, token)
-	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusBadRequest, rr)
-
-	req, err = http.NewRequest(http.MethodGet, fsEventsPath+"?order=ASSC", nil)
+	validateReq := validateTOTPRequest{
+		ConfigName: totpGenResp.ConfigName,
+		Passcode:   passcode,
+		Secret:     totpGenResp.Secret,
+	}
+	asJSON, err = json.Marshal(validateReq)
 	assert.NoError(t, err)
-	setBearerForReq(req, token)
+	req, err = http.NewRequest(http.MethodPost, adminTOTPValidatePath, bytes.NewBuffer(asJSON))
+	assert.NoError(t, err)
+	setBearerForReq(req, altToken)
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusBadRequest, rr)
-
-	req, err = http.NewRequest(http.MethodGet, fsEventsPath+"?statuses=a,b", nil)
+	checkResponseCode(t, http.StatusOK, rr)
+	// the same passcode cannot be reused
+	req, err = http.NewRequest(http.MethodPost, adminTOTPValidatePath, bytes.NewBuffer(asJSON))
 	assert.NoError(t, err)
-	setBearerForReq(req, token)
+	setBearerForReq(req, altToken)
 	rr = executeRequest(req)
 	checkResponseCode(t, http.StatusBadRequest, rr)
-}
-
-func TestMFAErrors(t *testing.T) {
-	user, _, err := httpdtest.AddUser(getTestUser(), http.StatusCreated)
-	assert.NoError(t, err)
-	assert.False(t, user.Filters.TOTPConfig.Enabled)
-	userToken, err := getJWTAPIUserTokenFromTestServer(defaultUsername, defaultPassword)
-	assert.NoError(t, err)
-	adminToken, err := getJWTAPITokenFromTestServer(defaultTokenAuthUser, defaultTokenAuthPass)
-	assert.NoError(t, err)
+	assert.Contains(t, rr.Body.String(), "this passcode was already used")
 
-	// invalid config name
-	totpReq := generateTOTPRequest{
-		ConfigName: "invalid config name",
+	adminTOTPConfig := dataprovider.TOTPConfig{
+		Enabled:    true,
+		ConfigName: totpGenResp.ConfigName,
+		Secret:     kms.NewPlainSecret(totpGenResp.Secret),
 	}
-	asJSON, err := json.Marshal(totpReq)
-	assert.NoError(t, err)
-	req, err := http.NewRequest(http.MethodPost, userTOTPGeneratePath, bytes.NewBuffer(asJSON))
+	asJSON, err = json.Marshal(adminTOTPConfig)
 	assert.NoError(t, err)
-	setBearerForReq(req, userToken)
-	rr := executeRequest(req)
-	checkResponseCode(t, http.StatusBadRequest, rr)
-	// invalid JSON
-	invalidJSON := []byte("not a JSON")
-	req, err = http.NewRequest(http.MethodPost, userTOTPGeneratePath, bytes.NewBuffer(invalidJSON))
+	req, err = http.NewRequest(http.MethodPost, adminTOTPSavePath, bytes.NewBuffer(asJSON))
 	assert.NoError(t, err)
-	setBearerForReq(req, userToken)
+	setBearerForReq(req, altToken)
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusBadRequest, rr)
-
-	req, err = http.NewRequest(http.MethodPost, userTOTPSavePath, bytes.NewBuffer(invalidJSON))
+	checkResponseCode(t, http.StatusOK, rr)
+	admin, _, err = httpdtest.GetAdminByUsername(altAdminUsername, http.StatusOK)
 	assert.NoError(t, err)
-	setBearerForReq(req, userToken)
-	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusBadRequest, rr)
-
-	req, err = http.NewRequest(http.MethodPost, adminTOTPSavePath, bytes.NewBuffer(invalidJSON))
+	assert.True(t, admin.Filters.TOTPConfig.Enabled)
+	assert.Equal(t, totpGenResp.ConfigName, admin.Filters.TOTPConfig.ConfigName)
+	assert.Empty(t, admin.Filters.TOTPConfig.Secret.GetKey())
+	assert.Empty(t, admin.Filters.TOTPConfig.Secret.GetAdditionalData())
+	assert.NotEmpty(t, admin.Filters.TOTPConfig.Secret.GetPayload())
+	assert.Equal(t, kms.SecretStatusSecretBox, admin.Filters.TOTPConfig.Secret.GetStatus())
+	admin.Filters.TOTPConfig = dataprovider.TOTPConfig{
+		Enabled:    false,
+		ConfigName: shortuuid.New(),
+		Secret:     kms.NewEmptySecret(),
+	}
+	admin.Filters.RecoveryCodes = []sdk.RecoveryCode{
+		{
+			Secret: kms.NewEmptySecret(),
+		},
+	}
+	admin, resp, err := httpdtest.UpdateAdmin(admin, http.StatusOK)
+	assert.NoError(t, err, string(resp))
+	assert.True(t, admin.Filters.TOTPConfig.Enabled)
+	assert.Len(t, admin.Filters.RecoveryCodes, 12)
+	// if we use token we should get no recovery codes
+	req, err = http.NewRequest(http.MethodGet, admin2FARecoveryCodesPath, nil)
 	assert.NoError(t, err)
-	setBearerForReq(req, adminToken)
+	setBearerForReq(req, token)
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusBadRequest, rr)
-
-	req, err = http.NewRequest(http.MethodPost, adminTOTPValidatePath, bytes.NewBuffer(invalidJSON))
+	checkResponseCode(t, http.StatusOK, rr)
+	var recCodes []recoveryCode
+	err = json.Unmarshal(rr.Body.Bytes(), &recCodes)
 	assert.NoError(t, err)
-	setBearerForReq(req, adminToken)
+	assert.Len(t, recCodes, 0)
+	// now the same but with altToken
+	req, err = http.NewRequest(http.MethodGet, admin2FARecoveryCodesPath, nil)
+	assert.NoError(t, err)
+	setBearerForReq(req, altToken)
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusBadRequest, rr)
-	// invalid TOTP config name
-	userTOTPConfig := sdk.TOTPConfig{
-		Enabled:    true,
-		ConfigName: "missing name",
-		Secret:     kms.NewPlainSecret(xid.New().String()),
-		Protocols:  []string{common.ProtocolSSH},
-	}
-	asJSON, err = json.Marshal(userTOTPConfig)
+	checkResponseCode(t, http.StatusOK, rr)
+	recCodes = nil
+	err = json.Unmarshal(rr.Body.Bytes(), &recCodes)
 	assert.NoError(t, err)
-	req, err = http.NewRequest(http.MethodPost, userTOTPSavePath, bytes.NewBuffer(asJSON))
+	assert.Len(t, recCodes, 12)
+	// regenerate recovery codes
+	req, err = http.NewRequest(http.MethodPost, admin2FARecoveryCodesPath, nil)
 	assert.NoError(t, err)
-	setBearerForReq(req, userToken)
+	setBearerForReq(req, altToken)
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusBadRequest, rr)
-	assert.Contains(t, rr.Body.String(), "totp: config name")
-	// invalid TOTP secret
-	userTOTPConfig = sdk.TOTPConfig{
-		Enabled:    true,
-		ConfigName: mfa.GetAvailableTOTPConfigNames()[0],
-		Secret:     nil,
-		Protocols:  []string{common.ProtocolSSH},
-	}
-	asJSON, err = json.Marshal(userTOTPConfig)
-	assert.NoError(t, err)
-	req, err = http.NewRequest(http.MethodPost, userTOTPSavePath, bytes.NewBuffer(asJSON))
+	checkResponseCode(t, http.StatusOK, rr)
+	// check that recovery codes are different
+	req, err = http.NewRequest(http.MethodGet, admin2FARecoveryCodesPath, nil)
 	assert.NoError(t, err)
-	setBearerForReq(req, userToken)
+	setBearerForReq(req, altToken)
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusBadRequest, rr)
-	assert.Contains(t, rr.Body.String(), "totp: secret is mandatory")
-	// no protocol
-	userTOTPConfig = sdk.TOTPConfig{
-		Enabled:    true,
-		ConfigName: mfa.GetAvailableTOTPConfigNames()[0],
-		Secret:     kms.NewPlainSecret(xid.New().String()),
-		Protocols:  nil,
-	}
-	asJSON, err = json.Marshal(userTOTPConfig)
+	checkResponseCode(t, http.StatusOK, rr)
+	var newRecCodes []recoveryCode
+	err = json.Unmarshal(rr.Body.Bytes(), &newRecCodes)
 	assert.NoError(t, err)
-	req, err = http.NewRequest(http.MethodPost, userTOTPSavePath, bytes.NewBuffer(asJSON))
+	assert.Len(t, newRecCodes, 12)
+	assert.NotEqual(t, recCodes, newRecCodes)
+	// disable 2FA, the update admin API should not work
+	admin.Filters.TOTPConfig.Enabled = false
+	admin.Filters.RecoveryCodes = nil
+	admin, _, err = httpdtest.UpdateAdmin(admin, http.StatusOK)
 	assert.NoError(t, err)
-	setBearerForReq(req, userToken)
-	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusBadRequest, rr)
-	assert.Contains(t, rr.Body.String(), "totp: specify at least one protocol")
-	// invalid protocol
-	userTOTPConfig = sdk.TOTPConfig{
-		Enabled:    true,
-		ConfigName: mfa.GetAvailableTOTPConfigNames()[0],
-		Secret:     kms.NewPlainSecret(xid.New().String()),
-		Protocols:  []string{common.ProtocolWebDAV},
-	}
-	asJSON, err = json.Marshal(userTOTPConfig)
+	assert.Equal(t, altAdminUsername, admin.Username)
+	assert.True(t, admin.Filters.TOTPConfig.Enabled)
+	assert.Len(t, admin.Filters.RecoveryCodes, 12)
+	// use the dedicated API
+	req, err = http.NewRequest(http.MethodPut, adminPath+"/"+altAdminUsername+"/2fa/disable", nil)
 	assert.NoError(t, err)
-	req, err = http.NewRequest(http.MethodPost, userTOTPSavePath, bytes.NewBuffer(asJSON))
+	setBearerForReq(req, token)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
+	admin, _, err = httpdtest.GetAdminByUsername(altAdminUsername, http.StatusOK)
 	assert.NoError(t, err)
-	setBearerForReq(req, userToken)
+	assert.False(t, admin.Filters.TOTPConfig.Enabled)
+	assert.Len(t, admin.Filters.RecoveryCodes, 0)
+
+	req, _ = http.NewRequest(http.MethodDelete, path.Join(adminPath, altAdminUsername), nil)
+	setBearerForReq(req, token)
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusBadRequest, rr)
-	assert.Contains(t, rr.Body.String(), "totp: invalid protocol")
+	checkResponseCode(t, http.StatusOK, rr)
 
-	adminTOTPConfig := dataprovider.TOTPConfig{
-		Enabled:    true,
-		ConfigName: "",
-		Secret:     kms.NewPlainSecret("secret"),
-	}
-	asJSON, err = json.Marshal(adminTOTPConfig)
-	assert.NoError(t, err)
-	req, err = http.NewRequest(http.MethodPost, adminTOTPSavePath, bytes.NewBuffer(asJSON))
+	req, err = http.NewRequest(http.MethodPut, adminPath+"/"+altAdminUsername+"/2fa/disable", nil)
 	assert.NoError(t, err)
-	setBearerForReq(req, adminToken)
+	setBearerForReq(req, token)
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusBadRequest, rr)
-	assert.Contains(t, rr.Body.String(), "totp: config name is mandatory")
+	checkResponseCode(t, http.StatusNotFound, rr)
 
-	adminTOTPConfig = dataprovider.TOTPConfig{
-		Enabled:    true,
-		ConfigName: mfa.GetAvailableTOTPConfigNames()[0],
-		Secret:     nil,
-	}
-	asJSON, err = json.Marshal(adminTOTPConfig)
-	assert.NoError(t, err)
-	req, err = http.NewRequest(http.MethodPost, adminTOTPSavePath, bytes.NewBuffer(asJSON))
+	req, err = http.NewRequest(http.MethodGet, admin2FARecoveryCodesPath, nil)
 	assert.NoError(t, err)
-	setBearerForReq(req, adminToken)
+	setBearerForReq(req, altToken)
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusBadRequest, rr)
-	assert.Contains(t, rr.Body.String(), "totp: secret is mandatory")
+	checkResponseCode(t, http.StatusNotFound, rr)
 
-	// invalid TOTP secret status
-	userTOTPConfig = sdk.TOTPConfig{
-		Enabled:    true,
-		ConfigName: mfa.GetAvailableTOTPConfigNames()[0],
-		Secret:     kms.NewSecret(kms.SecretStatusRedacted, "", "", ""),
-		Protocols:  []string{common.ProtocolSSH},
-	}
-	asJSON, err = json.Marshal(userTOTPConfig)
-	assert.NoError(t, err)
-	req, err = http.NewRequest(http.MethodPost, userTOTPSavePath, bytes.NewBuffer(asJSON))
+	req, err = http.NewRequest(http.MethodPost, admin2FARecoveryCodesPath, nil)
 	assert.NoError(t, err)
-	setBearerForReq(req, userToken)
+	setBearerForReq(req, altToken)
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusBadRequest, rr)
-	// previous secret will be preserved and we have no secret saved
-	assert.Contains(t, rr.Body.String(), "totp: secret is mandatory")
+	checkResponseCode(t, http.StatusNotFound, rr)
 
 	req, err = http.NewRequest(http.MethodPost, adminTOTPSavePath, bytes.NewBuffer(asJSON))
 	assert.NoError(t, err)
-	setBearerForReq(req, adminToken)
+	setBearerForReq(req, altToken)
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusBadRequest, rr)
-	assert.Contains(t, rr.Body.String(), "totp: secret is mandatory")
+	checkResponseCode(t, http.StatusNotFound, rr)
+}
 
-	_, err = httpdtest.RemoveUser(user, http.StatusOK)
-	assert.NoError(t, err)
-	err = os.RemoveAll(user.GetHomeDir())
+func TestChangeAdminPwdInvalidJsonMock(t *testing.T) {
+	token, err := getJWTAPITokenFromTestServer(defaultTokenAuthUser, defaultTokenAuthPass)
 	assert.NoError(t, err)
+	req, _ := http.NewRequest(http.MethodPut, adminPwdPath, bytes.NewBuffer([]byte("{")))
+	setBearerForReq(req, token)
+	rr := executeRequest(req)
+	checkResponseCode(t, http.StatusBadRequest, rr)
 }
 
-func TestMFAInvalidSecret(t *testing.T) {
+func TestMFAPermission(t *testing.T) {
 	user, _, err := httpdtest.AddUser(getTestUser(), http.StatusCreated)
 	assert.NoError(t, err)
 
-	userToken, err := getJWTAPIUserTokenFromTestServer(defaultUsername, defaultPassword)
+	webToken, err := getJWTWebClientTokenFromTestServer(defaultUsername, defaultPassword)
 	assert.NoError(t, err)
 
-	user.Password = defaultPassword
-	user.Filters.TOTPConfig = sdk.TOTPConfig{
-		Enabled:    true,
-		ConfigName: mfa.GetAvailableTOTPConfigNames()[0],
-		Secret:     kms.NewSecret(kms.SecretStatusSecretBox, "payload", "key", user.Username),
-		Protocols:  []string{common.ProtocolSSH, common.ProtocolHTTP},
-	}
-	user.Filters.RecoveryCodes = append(user.Filters.RecoveryCodes, sdk.RecoveryCode{
-		Used:   false,
-		Secret: kms.NewSecret(kms.SecretStatusSecretBox, "payload", "key", user.Username),
-	})
-	err = dataprovider.UpdateUser(&user, "", "")
+	req, err := http.NewRequest(http.MethodGet, webClientMFAPath, nil)
 	assert.NoError(t, err)
+	req.RequestURI = webClientMFAPath
+	setJWTCookieForReq(req, webToken)
+	rr := executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
 
-	req, err := http.NewRequest(http.MethodGet, user2FARecoveryCodesPath, nil)
+	user.Filters.WebClient = []string{sdk.WebClientMFADisabled}
+	user, _, err = httpdtest.UpdateUser(user, http.StatusOK, "")
 	assert.NoError(t, err)
-	setBearerForReq(req, userToken)
+
+	webToken, err = getJWTWebClientTokenFromTestServer(defaultUsername, defaultPassword)
+	assert.NoError(t, err)
+
+	req, err = http.NewRequest(http.MethodGet, webClientMFAPath, nil)
+	assert.NoError(t, err)
+	req.RequestURI = webClientMFAPath
+	setJWTCookieForReq(req, webToken)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusForbidden, rr)
+
+	_, err = httpdtest.RemoveUser(user, http.StatusOK)
+	assert.NoError(t, err)
+	err = os.RemoveAll(user.GetHomeDir())
+	assert.NoError(t, err)
+}
+
+func TestWebUserTwoFactorLogin(t *testing.T) {
+	u := getTestUser()
+	user, _, err := httpdtest.AddUser(u, http.StatusCreated)
+	assert.NoError(t, err)
+	// enable two factor authentication
+	configName, _, secret, _, err := mfa.GenerateTOTPSecret(mfa.GetAvailableTOTPConfigNames()[0], user.Username)
+	assert.NoError(t, err)
+	token, err := getJWTAPIUserTokenFromTestServer(defaultUsername, defaultPassword)
+	assert.NoError(t, err)
+	adminToken, err := getJWTAPITokenFromTestServer(defaultTokenAuthUser, defaultTokenAuthPass)
+	assert.NoError(t, err)
+	webToken, err := getJWTWebClientTokenFromTestServer(defaultUsername, defaultPassword)
+	assert.NoError(t, err)
+
+	userTOTPConfig := sdk.TOTPConfig{
+		Enabled:    true,
+		ConfigName: configName,
+		Secret:     kms.NewPlainSecret(secret),
+		Protocols:  []string{common.ProtocolHTTP},
+	}
+	asJSON, err := json.Marshal(userTOTPConfig)
+	assert.NoError(t, err)
+	req, err := http.NewRequest(http.MethodPost, userTOTPSavePath, bytes.NewBuffer(asJSON))
+	assert.NoError(t, err)
+	setBearerForReq(req, token)
 	rr := executeRequest(req)
-	checkResponseCode(t, http.StatusInternalServerError, rr)
-	assert.Contains(t, rr.Body.String(), "Unable to decrypt recovery codes")
+	checkResponseCode(t, http.StatusOK, rr)
+
+	req, err = http.NewRequest(http.MethodGet, user2FARecoveryCodesPath, nil)
+	assert.NoError(t, err)
+	setBearerForReq(req, token)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
+	var recCodes []recoveryCode
+	err = json.Unmarshal(rr.Body.Bytes(), &recCodes)
+	assert.NoError(t, err)
+	assert.Len(t, recCodes, 12)
+
+	user, _, err = httpdtest.GetUserByUsername(defaultUsername, http.StatusOK)
+	assert.NoError(t, err)
+	assert.Len(t, user.Filters.RecoveryCodes, 12)
+	for _, c := range user.Filters.RecoveryCodes {
+		assert.Empty(t, c.Secret.GetAdditionalData())
+		assert.Empty(t, c.Secret.GetKey())
+		assert.Equal(t, kms.SecretStatusSecretBox, c.Secret.GetStatus())
+		assert.NotEmpty(t, c.Secret.GetPayload())
+	}
+
+	req, err = http.NewRequest(http.MethodGet, webClientTwoFactorPath, nil)
+	assert.NoError(t, err)
+	setJWTCookieForReq(req, webToken)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusNotFound, rr)
+
+	req, err = http.NewRequest(http.MethodGet, webClientTwoFactorRecoveryPath, nil)
+	assert.NoError(t, err)
+	setJWTCookieForReq(req, webToken)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusNotFound, rr)
+
+	req, err = http.NewRequest(http.MethodPost, webClientTwoFactorPath, nil)
+	assert.NoError(t, err)
+	setJWTCookieForReq(req, webToken)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusNotFound, rr)
+
+	req, err = http.NewRequest(http.MethodPost, webClientTwoFactorRecoveryPath, nil)
+	assert.NoError(t, err)
+	setJWTCookieForReq(req, webToken)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusNotFound, rr)
 
 	csrfToken, err := getCSRFToken(httpBaseURL + webClientLoginPath)
 	assert.NoError(t, err)
@@ -5805,3083 +7267,4462 @@ func TestMFAInvalidSecret(t *testing.T) {
 	assert.Equal(t, webClientTwoFactorPath, rr.Header().Get("Location"))
 	cookie, err := getCookieFromResponse(rr)
 	assert.NoError(t, err)
-	form = make(url.Values)
-	form.Set(csrfFormToken, csrfToken)
-	form.Set("passcode", "123456")
-	req, err = http.NewRequest(http.MethodPost, webClientTwoFactorPath, bytes.NewBuffer([]byte(form.Encode())))
+
+	req, err = http.NewRequest(http.MethodGet, webClientTwoFactorPath, nil)
 	assert.NoError(t, err)
 	setJWTCookieForReq(req, cookie)
-	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 	rr = executeRequest(req)
-	assert.Equal(t, http.StatusInternalServerError, rr.Code)
+	checkResponseCode(t, http.StatusOK, rr)
 
-	form = make(url.Values)
-	form.Set(csrfFormToken, csrfToken)
-	form.Set("recovery_code", "RC-123456")
-	req, err = http.NewRequest(http.MethodPost, webClientTwoFactorRecoveryPath, bytes.NewBuffer([]byte(form.Encode())))
+	// without a cookie
+	req, err = http.NewRequest(http.MethodGet, webClientTwoFactorPath, nil)
 	assert.NoError(t, err)
-	setJWTCookieForReq(req, cookie)
-	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 	rr = executeRequest(req)
-	assert.Equal(t, http.StatusInternalServerError, rr.Code)
+	checkResponseCode(t, http.StatusNotFound, rr)
 
-	req, err = http.NewRequest(http.MethodGet, fmt.Sprintf("%v%v", httpBaseURL, userTokenPath), nil)
+	req, err = http.NewRequest(http.MethodGet, webClientTwoFactorRecoveryPath, nil)
 	assert.NoError(t, err)
-	req.Header.Set("X-SFTPGO-OTP", "authcode")
-	req.SetBasicAuth(defaultUsername, defaultPassword)
-	resp, err := httpclient.GetHTTPClient().Do(req)
+	setJWTCookieForReq(req, cookie)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
+	// any other page will be redirected to the two factor auth page
+	req, err = http.NewRequest(http.MethodGet, webClientFilesPath, nil)
 	assert.NoError(t, err)
-	assert.Equal(t, http.StatusInternalServerError, resp.StatusCode)
-	err = resp.Body.Close()
+	setJWTCookieForReq(req, cookie)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusFound, rr)
+	assert.Equal(t, webClientTwoFactorPath, rr.Header().Get("Location"))
+	// a partial token cannot be used for admin pages
+	req, err = http.NewRequest(http.MethodGet, webUsersPath, nil)
 	assert.NoError(t, err)
+	setJWTCookieForReq(req, cookie)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusFound, rr)
+	assert.Equal(t, webLoginPath, rr.Header().Get("Location"))
 
-	_, err = httpdtest.RemoveUser(user, http.StatusOK)
+	passcode, err := generateTOTPPasscode(secret)
 	assert.NoError(t, err)
-	err = os.RemoveAll(user.GetHomeDir())
+	form = make(url.Values)
+	form.Set("passcode", passcode)
+
+	req, err = http.NewRequest(http.MethodPost, webClientTwoFactorPath, bytes.NewBuffer([]byte(form.Encode())))
 	assert.NoError(t, err)
+	setJWTCookieForReq(req, cookie)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rr = executeRequest(req)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Contains(t, rr.Body.String(), "unable to verify form token")
 
-	admin := getTestAdmin()
-	admin.Username = altAdminUsername
-	admin.Password = altAdminPassword
-	admin, _, err = httpdtest.AddAdmin(admin, http.StatusCreated)
+	form.Set(csrfFormToken, csrfToken)
+	form.Set("passcode", "invalid_user_passcode")
+	req, err = http.NewRequest(http.MethodPost, webClientTwoFactorPath, bytes.NewBuffer([]byte(form.Encode())))
 	assert.NoError(t, err)
+	setJWTCookieForReq(req, cookie)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rr = executeRequest(req)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Contains(t, rr.Body.String(), "Invalid authentication code")
 
-	admin.Password = altAdminPassword
-	admin.Filters.TOTPConfig = dataprovider.TOTPConfig{
-		Enabled:    true,
-		ConfigName: mfa.GetAvailableTOTPConfigNames()[0],
-		Secret:     kms.NewSecret(kms.SecretStatusSecretBox, "payload", "key", user.Username),
-	}
-	admin.Filters.RecoveryCodes = append(user.Filters.RecoveryCodes, sdk.RecoveryCode{
-		Used:   false,
-		Secret: kms.NewSecret(kms.SecretStatusSecretBox, "payload", "key", user.Username),
-	})
-	err = dataprovider.UpdateAdmin(&admin, "", "")
+	form.Set("passcode", "")
+	req, err = http.NewRequest(http.MethodPost, webClientTwoFactorPath, bytes.NewBuffer([]byte(form.Encode())))
 	assert.NoError(t, err)
+	setJWTCookieForReq(req, cookie)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rr = executeRequest(req)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Contains(t, rr.Body.String(), "Invalid credentials")
 
-	csrfToken, err = getCSRFToken(httpBaseURL + webLoginPath)
+	form.Set("passcode", passcode)
+	req, err = http.NewRequest(http.MethodPost, webClientTwoFactorPath, bytes.NewBuffer([]byte(form.Encode())))
 	assert.NoError(t, err)
-	form = getLoginForm(altAdminUsername, altAdminPassword, csrfToken)
-	req, err = http.NewRequest(http.MethodPost, webLoginPath, bytes.NewBuffer([]byte(form.Encode())))
+	setJWTCookieForReq(req, cookie)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rr = executeRequest(req)
+	assert.Equal(t, http.StatusFound, rr.Code)
+	assert.Equal(t, webClientFilesPath, rr.Header().Get("Location"))
+	// the same cookie cannot be reused
+	req, err = http.NewRequest(http.MethodPost, webClientTwoFactorPath, bytes.NewBuffer([]byte(form.Encode())))
+	assert.NoError(t, err)
+	setJWTCookieForReq(req, cookie)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rr = executeRequest(req)
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+	// get a new cookie and login using a recovery code
+	form = getLoginForm(defaultUsername, defaultPassword, csrfToken)
+	req, err = http.NewRequest(http.MethodPost, webClientLoginPath, bytes.NewBuffer([]byte(form.Encode())))
 	assert.NoError(t, err)
 	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 	rr = executeRequest(req)
 	assert.Equal(t, http.StatusFound, rr.Code)
-	assert.Equal(t, webAdminTwoFactorPath, rr.Header().Get("Location"))
+	assert.Equal(t, webClientTwoFactorPath, rr.Header().Get("Location"))
 	cookie, err = getCookieFromResponse(rr)
 	assert.NoError(t, err)
+
 	form = make(url.Values)
-	form.Set(csrfFormToken, csrfToken)
-	form.Set("passcode", "123456")
-	req, err = http.NewRequest(http.MethodPost, webAdminTwoFactorPath, bytes.NewBuffer([]byte(form.Encode())))
+	recoveryCode := recCodes[0].Code
+	form.Set("recovery_code", recoveryCode)
+	// no csrf
+	req, err = http.NewRequest(http.MethodPost, webClientTwoFactorRecoveryPath, bytes.NewBuffer([]byte(form.Encode())))
 	assert.NoError(t, err)
 	setJWTCookieForReq(req, cookie)
 	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 	rr = executeRequest(req)
-	assert.Equal(t, http.StatusInternalServerError, rr.Code)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Contains(t, rr.Body.String(), "unable to verify form token")
 
-	form = make(url.Values)
 	form.Set(csrfFormToken, csrfToken)
-	form.Set("recovery_code", "RC-123456")
-	req, err = http.NewRequest(http.MethodPost, webAdminTwoFactorRecoveryPath, bytes.NewBuffer([]byte(form.Encode())))
+	form.Set("recovery_code", "")
+	req, err = http.NewRequest(http.MethodPost, webClientTwoFactorRecoveryPath, bytes.NewBuffer([]byte(form.Encode())))
 	assert.NoError(t, err)
 	setJWTCookieForReq(req, cookie)
 	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 	rr = executeRequest(req)
-	assert.Equal(t, http.StatusInternalServerError, rr.Code)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Contains(t, rr.Body.String(), "Invalid credentials")
 
-	req, err = http.NewRequest(http.MethodGet, fmt.Sprintf("%v%v", httpBaseURL, tokenPath), nil)
+	form.Set("recovery_code", recoveryCode)
+	req, err = http.NewRequest(http.MethodPost, webClientTwoFactorRecoveryPath, bytes.NewBuffer([]byte(form.Encode())))
 	assert.NoError(t, err)
-	req.Header.Set("X-SFTPGO-OTP", "auth-code")
-	req.SetBasicAuth(altAdminUsername, altAdminPassword)
-	resp, err = httpclient.GetHTTPClient().Do(req)
+	setJWTCookieForReq(req, cookie)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rr = executeRequest(req)
+	assert.Equal(t, http.StatusFound, rr.Code)
+	assert.Equal(t, webClientFilesPath, rr.Header().Get("Location"))
+	authenticatedCookie, err := getCookieFromResponse(rr)
 	assert.NoError(t, err)
-	assert.Equal(t, http.StatusInternalServerError, resp.StatusCode)
-	err = resp.Body.Close()
+	//render MFA page
+	req, err = http.NewRequest(http.MethodGet, webClientMFAPath, nil)
 	assert.NoError(t, err)
+	setJWTCookieForReq(req, authenticatedCookie)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
 
-	_, err = httpdtest.RemoveAdmin(admin, http.StatusOK)
-	assert.NoError(t, err)
-}
-
-func TestWebUserTOTP(t *testing.T) {
-	u := getTestUser()
-	// TOTPConfig will be ignored on add
-	u.Filters.TOTPConfig = sdk.TOTPConfig{
-		Enabled:    true,
-		ConfigName: "",
-		Secret:     kms.NewEmptySecret(),
-		Protocols:  []string{common.ProtocolSSH},
-	}
-	user, _, err := httpdtest.AddUser(u, http.StatusCreated)
-	assert.NoError(t, err)
-	assert.False(t, user.Filters.TOTPConfig.Enabled)
-	token, err := getJWTAPIUserTokenFromTestServer(defaultUsername, defaultPassword)
-	assert.NoError(t, err)
-
-	req, err := http.NewRequest(http.MethodGet, userTOTPConfigsPath, nil)
+	// check that the recovery code was marked as used
+	req, err = http.NewRequest(http.MethodGet, user2FARecoveryCodesPath, nil)
 	assert.NoError(t, err)
 	setBearerForReq(req, token)
-	rr := executeRequest(req)
+	rr = executeRequest(req)
 	checkResponseCode(t, http.StatusOK, rr)
-	var configs []mfa.TOTPConfig
-	err = json.Unmarshal(rr.Body.Bytes(), &configs)
-	assert.NoError(t, err, rr.Body.String())
-	assert.Len(t, configs, len(mfa.GetAvailableTOTPConfigs()))
-	totpConfig := configs[0]
-	totpReq := generateTOTPRequest{
-		ConfigName: totpConfig.Name,
+	recCodes = nil
+	err = json.Unmarshal(rr.Body.Bytes(), &recCodes)
+	assert.NoError(t, err)
+	assert.Len(t, recCodes, 12)
+	found := false
+	for _, rc := range recCodes {
+		if rc.Code == recoveryCode {
+			found = true
+			assert.True(t, rc.Used)
+		} else {
+			assert.False(t, rc.Used)
+		}
 	}
-	asJSON, err := json.Marshal(totpReq)
+	assert.True(t, found)
+	// the same recovery code cannot be reused
+	form = getLoginForm(defaultUsername, defaultPassword, csrfToken)
+	req, err = http.NewRequest(http.MethodPost, webClientLoginPath, bytes.NewBuffer([]byte(form.Encode())))
 	assert.NoError(t, err)
-	req, err = http.NewRequest(http.MethodPost, userTOTPGeneratePath, bytes.NewBuffer(asJSON))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rr = executeRequest(req)
+	assert.Equal(t, http.StatusFound, rr.Code)
+	assert.Equal(t, webClientTwoFactorPath, rr.Header().Get("Location"))
+	cookie, err = getCookieFromResponse(rr)
 	assert.NoError(t, err)
-	setBearerForReq(req, token)
+	form = make(url.Values)
+	form.Set("recovery_code", recoveryCode)
+	form.Set(csrfFormToken, csrfToken)
+	req, err = http.NewRequest(http.MethodPost, webClientTwoFactorRecoveryPath, bytes.NewBuffer([]byte(form.Encode())))
+	assert.NoError(t, err)
+	setJWTCookieForReq(req, cookie)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusOK, rr)
-	var totpGenResp generateTOTPResponse
-	err = json.Unmarshal(rr.Body.Bytes(), &totpGenResp)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Contains(t, rr.Body.String(), "This recovery code was already used")
+
+	form.Set("recovery_code", "invalid_user_recovery_code")
+	req, err = http.NewRequest(http.MethodPost, webClientTwoFactorRecoveryPath, bytes.NewBuffer([]byte(form.Encode())))
 	assert.NoError(t, err)
-	assert.NotEmpty(t, totpGenResp.Secret)
-	assert.NotEmpty(t, totpGenResp.QRCode)
+	setJWTCookieForReq(req, cookie)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rr = executeRequest(req)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Contains(t, rr.Body.String(), "Invalid recovery code")
 
-	passcode, err := generateTOTPPasscode(totpGenResp.Secret)
+	form = getLoginForm(defaultUsername, defaultPassword, csrfToken)
+	req, err = http.NewRequest(http.MethodPost, webClientLoginPath, bytes.NewBuffer([]byte(form.Encode())))
 	assert.NoError(t, err)
-	validateReq := validateTOTPRequest{
-		ConfigName: totpGenResp.ConfigName,
-		Passcode:   passcode,
-		Secret:     totpGenResp.Secret,
-	}
-	asJSON, err = json.Marshal(validateReq)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rr = executeRequest(req)
+	assert.Equal(t, http.StatusFound, rr.Code)
+	assert.Equal(t, webClientTwoFactorPath, rr.Header().Get("Location"))
+	cookie, err = getCookieFromResponse(rr)
 	assert.NoError(t, err)
-	req, err = http.NewRequest(http.MethodPost, userTOTPValidatePath, bytes.NewBuffer(asJSON))
+
+	// disable TOTP
+	req, err = http.NewRequest(http.MethodPut, userPath+"/"+user.Username+"/2fa/disable", nil)
 	assert.NoError(t, err)
-	setBearerForReq(req, token)
+	setBearerForReq(req, adminToken)
 	rr = executeRequest(req)
 	checkResponseCode(t, http.StatusOK, rr)
-	// the same passcode cannot be reused
-	req, err = http.NewRequest(http.MethodPost, userTOTPValidatePath, bytes.NewBuffer(asJSON))
+
+	form = make(url.Values)
+	form.Set("recovery_code", recoveryCode)
+	form.Set("passcode", passcode)
+	form.Set(csrfFormToken, csrfToken)
+
+	req, err = http.NewRequest(http.MethodPost, webClientTwoFactorRecoveryPath, bytes.NewBuffer([]byte(form.Encode())))
 	assert.NoError(t, err)
-	setBearerForReq(req, token)
+	setJWTCookieForReq(req, cookie)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusBadRequest, rr)
-	assert.Contains(t, rr.Body.String(), "this passcode was already used")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Contains(t, rr.Body.String(), "Two factory authentication is not enabled")
 
-	userTOTPConfig := sdk.TOTPConfig{
-		Enabled:    true,
-		ConfigName: totpGenResp.ConfigName,
-		Secret:     kms.NewPlainSecret(totpGenResp.Secret),
-		Protocols:  []string{common.ProtocolSSH},
-	}
-	asJSON, err = json.Marshal(userTOTPConfig)
-	assert.NoError(t, err)
-	req, err = http.NewRequest(http.MethodPost, userTOTPSavePath, bytes.NewBuffer(asJSON))
+	req, err = http.NewRequest(http.MethodPost, webClientTwoFactorPath, bytes.NewBuffer([]byte(form.Encode())))
 	assert.NoError(t, err)
-	setBearerForReq(req, token)
+	setJWTCookieForReq(req, cookie)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusOK, rr)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Contains(t, rr.Body.String(), "Two factory authentication is not enabled")
 
-	user, _, err = httpdtest.GetUserByUsername(user.Username, http.StatusOK)
+	_, err = httpdtest.RemoveUser(user, http.StatusOK)
 	assert.NoError(t, err)
-	totpCfg := user.Filters.TOTPConfig
-	assert.True(t, totpCfg.Enabled)
-	secretPayload := totpCfg.Secret.GetPayload()
-	assert.Equal(t, totpGenResp.ConfigName, totpCfg.ConfigName)
-	assert.Empty(t, totpCfg.Secret.GetKey())
-	assert.Empty(t, totpCfg.Secret.GetAdditionalData())
-	assert.NotEmpty(t, secretPayload)
-	assert.Equal(t, kms.SecretStatusSecretBox, totpCfg.Secret.GetStatus())
-	assert.Len(t, totpCfg.Protocols, 1)
-	assert.Contains(t, totpCfg.Protocols, common.ProtocolSSH)
-	// update protocols only
-	userTOTPConfig = sdk.TOTPConfig{
-		Protocols: []string{common.ProtocolSSH, common.ProtocolFTP},
-		Secret:    kms.NewEmptySecret(),
-	}
-	asJSON, err = json.Marshal(userTOTPConfig)
+	err = os.RemoveAll(user.GetHomeDir())
 	assert.NoError(t, err)
-	req, err = http.NewRequest(http.MethodPost, userTOTPSavePath, bytes.NewBuffer(asJSON))
+
+	req, err = http.NewRequest(http.MethodPost, webClientTwoFactorRecoveryPath, bytes.NewBuffer([]byte(form.Encode())))
 	assert.NoError(t, err)
-	setBearerForReq(req, token)
+	setJWTCookieForReq(req, cookie)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusOK, rr)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Contains(t, rr.Body.String(), "Invalid credentials")
 
-	// update the user, TOTP should not be affected
-	user.Filters.TOTPConfig = sdk.TOTPConfig{
-		Enabled: false,
-		Secret:  kms.NewEmptySecret(),
-	}
-	_, _, err = httpdtest.UpdateUser(user, http.StatusOK, "")
+	req, err = http.NewRequest(http.MethodPost, webClientTwoFactorPath, bytes.NewBuffer([]byte(form.Encode())))
 	assert.NoError(t, err)
+	setJWTCookieForReq(req, cookie)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rr = executeRequest(req)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Contains(t, rr.Body.String(), "Invalid credentials")
 
-	user, _, err = httpdtest.GetUserByUsername(user.Username, http.StatusOK)
+	req, err = http.NewRequest(http.MethodGet, webClientMFAPath, nil)
 	assert.NoError(t, err)
-	assert.True(t, user.Filters.TOTPConfig.Enabled)
-	assert.Equal(t, totpCfg.ConfigName, user.Filters.TOTPConfig.ConfigName)
-	assert.Empty(t, user.Filters.TOTPConfig.Secret.GetKey())
-	assert.Empty(t, user.Filters.TOTPConfig.Secret.GetAdditionalData())
-	assert.Equal(t, secretPayload, user.Filters.TOTPConfig.Secret.GetPayload())
-	assert.Equal(t, kms.SecretStatusSecretBox, user.Filters.TOTPConfig.Secret.GetStatus())
-	assert.Len(t, user.Filters.TOTPConfig.Protocols, 2)
-	assert.Contains(t, user.Filters.TOTPConfig.Protocols, common.ProtocolSSH)
-	assert.Contains(t, user.Filters.TOTPConfig.Protocols, common.ProtocolFTP)
+	setJWTCookieForReq(req, authenticatedCookie)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusInternalServerError, rr)
+}
 
-	req, err = http.NewRequest(http.MethodGet, user2FARecoveryCodesPath, nil)
+func TestSearchEvents(t *testing.T) {
+	token, err := getJWTAPITokenFromTestServer(defaultTokenAuthUser, defaultTokenAuthPass)
+	assert.NoError(t, err)
+
+	req, err := http.NewRequest(http.MethodGet, fsEventsPath+"?limit=10&order=ASC", nil)
 	assert.NoError(t, err)
 	setBearerForReq(req, token)
-	rr = executeRequest(req)
+	rr := executeRequest(req)
 	checkResponseCode(t, http.StatusOK, rr)
-	var recCodes []recoveryCode
-	err = json.Unmarshal(rr.Body.Bytes(), &recCodes)
-	assert.NoError(t, err)
-	assert.Len(t, recCodes, 12)
-	// regenerate recovery codes
-	req, err = http.NewRequest(http.MethodPost, user2FARecoveryCodesPath, nil)
+
+	// the test eventsearcher plugin returns error if start_timestamp < 0
+	req, err = http.NewRequest(http.MethodGet, fsEventsPath+"?start_timestamp=-1&end_timestamp=123456&statuses=1,2", nil)
 	assert.NoError(t, err)
 	setBearerForReq(req, token)
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusOK, rr)
-	// check that recovery codes are different
-	req, err = http.NewRequest(http.MethodGet, user2FARecoveryCodesPath, nil)
+	checkResponseCode(t, http.StatusInternalServerError, rr)
+
+	req, err = http.NewRequest(http.MethodGet, fsEventsPath+"?limit=a", nil)
 	assert.NoError(t, err)
 	setBearerForReq(req, token)
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusOK, rr)
-	var newRecCodes []recoveryCode
-	err = json.Unmarshal(rr.Body.Bytes(), &newRecCodes)
-	assert.NoError(t, err)
-	assert.Len(t, newRecCodes, 12)
-	assert.NotEqual(t, recCodes, newRecCodes)
-	// disable 2FA, the update user API should not work
-	adminToken, err := getJWTAPITokenFromTestServer(defaultTokenAuthUser, defaultTokenAuthPass)
-	assert.NoError(t, err)
-	user.Filters.TOTPConfig.Enabled = false
-	user.Filters.RecoveryCodes = nil
-	user, _, err = httpdtest.UpdateUser(user, http.StatusOK, "")
-	assert.NoError(t, err)
-	assert.Equal(t, defaultUsername, user.Username)
-	assert.True(t, user.Filters.TOTPConfig.Enabled)
-	assert.Len(t, user.Filters.RecoveryCodes, 12)
-	// use the dedicated API
-	req, err = http.NewRequest(http.MethodPut, userPath+"/"+defaultUsername+"/2fa/disable", nil)
+	checkResponseCode(t, http.StatusBadRequest, rr)
+
+	req, err = http.NewRequest(http.MethodGet, providerEventsPath, nil)
 	assert.NoError(t, err)
-	setBearerForReq(req, adminToken)
+	setBearerForReq(req, token)
 	rr = executeRequest(req)
 	checkResponseCode(t, http.StatusOK, rr)
-	user, _, err = httpdtest.GetUserByUsername(defaultUsername, http.StatusOK)
-	assert.NoError(t, err)
-	assert.False(t, user.Filters.TOTPConfig.Enabled)
-	assert.Len(t, user.Filters.RecoveryCodes, 0)
 
-	_, err = httpdtest.RemoveUser(user, http.StatusOK)
+	// the test eventsearcher plugin returns error if start_timestamp < 0
+	req, err = http.NewRequest(http.MethodGet, providerEventsPath+"?start_timestamp=-1", nil)
 	assert.NoError(t, err)
-	err = os.RemoveAll(user.GetHomeDir())
+	setBearerForReq(req, token)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusInternalServerError, rr)
+
+	req, err = http.NewRequest(http.MethodGet, providerEventsPath+"?limit=2000", nil)
 	assert.NoError(t, err)
+	setBearerForReq(req, token)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusBadRequest, rr)
 
-	req, err = http.NewRequest(http.MethodPut, userPath+"/"+defaultUsername+"/2fa/disable", nil)
+	req, err = http.NewRequest(http.MethodGet, fsEventsPath+"?start_timestamp=a", nil)
 	assert.NoError(t, err)
-	setBearerForReq(req, adminToken)
+	setBearerForReq(req, token)
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusNotFound, rr)
+	checkResponseCode(t, http.StatusBadRequest, rr)
 
-	req, err = http.NewRequest(http.MethodGet, user2FARecoveryCodesPath, nil)
+	req, err = http.NewRequest(http.MethodGet, fsEventsPath+"?end_timestamp=a", nil)
 	assert.NoError(t, err)
 	setBearerForReq(req, token)
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusNotFound, rr)
+	checkResponseCode(t, http.StatusBadRequest, rr)
 
-	req, err = http.NewRequest(http.MethodPost, user2FARecoveryCodesPath, nil)
+	req, err = http.NewRequest(http.MethodGet, fsEventsPath+"?order=ASSC", nil)
 	assert.NoError(t, err)
 	setBearerForReq(req, token)
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusNotFound, rr)
+	checkResponseCode(t, http.StatusBadRequest, rr)
 
-	req, err = http.NewRequest(http.MethodPost, userTOTPSavePath, bytes.NewBuffer(asJSON))
+	req, err = http.NewRequest(http.MethodGet, fsEventsPath+"?statuses=a,b", nil)
 	assert.NoError(t, err)
 	setBearerForReq(req, token)
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusNotFound, rr)
+	checkResponseCode(t, http.StatusBadRequest, rr)
 }
 
-func TestWebAPIChangeUserProfileMock(t *testing.T) {
-	user, _, err := httpdtest.AddUser(getTestUser(), http.StatusCreated)
+func TestEventStore(t *testing.T) {
+	// remove the test eventsearcher plugin, the built-in event store will be used
+	plugin.Handler.Cleanup()
+	err := plugin.Initialize(nil, true)
 	assert.NoError(t, err)
-	assert.False(t, user.Filters.AllowAPIKeyAuth)
-	token, err := getJWTAPIUserTokenFromTestServer(defaultUsername, defaultPassword)
+	defer func() {
+		wdPath, err := os.Getwd()
+		assert.NoError(t, err)
+		err = plugin.Initialize(getTestPluginsConfig(wdPath), true)
+		assert.NoError(t, err)
+	}()
+
+	startTimestamp := time.Now().UnixNano()
+	user, _, err := httpdtest.AddUser(getTestUser(), http.StatusCreated)
 	assert.NoError(t, err)
-	// invalid json
-	req, err := http.NewRequest(http.MethodPut, userProfilePath, bytes.NewBuffer([]byte("{")))
+	user.AdditionalInfo = "event store"
+	user, _, err = httpdtest.UpdateUser(user, http.StatusOK, "")
 	assert.NoError(t, err)
-	setBearerForReq(req, token)
-	rr := executeRequest(req)
-	checkResponseCode(t, http.StatusBadRequest, rr)
+	common.ExecuteActionNotification(&user, "upload", filepath.Join(user.GetHomeDir(), "file.txt"), "/file.txt",
+		"", "", "", common.ProtocolSFTP, "127.0.0.1", 123, nil)
+	common.ExecuteActionNotification(&user, "rename", filepath.Join(user.GetHomeDir(), "file.txt"), "/file.txt",
+		filepath.Join(user.GetHomeDir(), "file1.txt"), "/file1.txt", "", common.ProtocolFTP, "127.0.0.1", 0, nil)
 
-	email := "userapi@example.com"
-	description := "user API description"
-	profileReq := make(map[string]interface{})
-	profileReq["allow_api_key_auth"] = true
-	profileReq["email"] = email
-	profileReq["description"] = description
-	profileReq["public_keys"] = []string{testPubKey, testPubKey1}
-	asJSON, err := json.Marshal(profileReq)
+	token, err := getJWTAPITokenFromTestServer(defaultTokenAuthUser, defaultTokenAuthPass)
 	assert.NoError(t, err)
-	req, err = http.NewRequest(http.MethodPut, userProfilePath, bytes.NewBuffer(asJSON))
+
+	searchProviderEvents := func(query string) []dataprovider.ProviderEvent {
+		var events []dataprovider.ProviderEvent
+		req, err := http.NewRequest(http.MethodGet, providerEventsPath+"?start_timestamp="+
+			strconv.FormatInt(startTimestamp, 10)+"&object_types=user&object_name="+url.QueryEscape(user.Username)+
+			query, nil)
+		assert.NoError(t, err)
+		setBearerForReq(req, token)
+		rr := executeRequest(req)
+		checkResponseCode(t, http.StatusOK, rr)
+		err = json.Unmarshal(rr.Body.Bytes(), &events)
+		assert.NoError(t, err)
+		return events
+	}
+	searchFsEvents := func(query string) []dataprovider.FsEvent {
+		var events []dataprovider.FsEvent
+		req, err := http.NewRequest(http.MethodGet, fsEventsPath+"?start_timestamp="+
+			strconv.FormatInt(startTimestamp, 10)+"&username="+url.QueryEscape(user.Username)+query, nil)
+		assert.NoError(t, err)
+		setBearerForReq(req, token)
+		rr := executeRequest(req)
+		checkResponseCode(t, http.StatusOK, rr)
+		err = json.Unmarshal(rr.Body.Bytes(), &events)
+		assert.NoError(t, err)
+		return events
+	}
+	// events are stored asynchronously
+	assert.Eventually(t, func() bool {
+		return len(searchProviderEvents("")) == 2 && len(searchFsEvents("")) == 2
+	}, 2*time.Second, 100*time.Millisecond)
+
+	providerEvents := searchProviderEvents("&order=ASC")
+	if assert.Len(t, providerEvents, 2) {
+		assert.Equal(t, "add", providerEvents[0].Action)
+		assert.Equal(t, "update", providerEvents[1].Action)
+		assert.Equal(t, defaultTokenAuthUser, providerEvents[0].Username)
+		assert.Equal(t, "user", providerEvents[0].ObjectType)
+		assert.NotEmpty(t, providerEvents[0].InstanceID)
+		assert.LessOrEqual(t, providerEvents[0].Timestamp, providerEvents[1].Timestamp)
+	}
+	providerEvents = searchProviderEvents("&limit=1")
+	if assert.Len(t, providerEvents, 1) {
+		assert.Equal(t, "update", providerEvents[0].Action)
+		providerEvents = searchProviderEvents("&limit=1&exclude_ids=" + providerEvents[0].ID)
+		if assert.Len(t, providerEvents, 1) {
+			assert.Equal(t, "add", providerEvents[0].Action)
+		}
+	}
+	assert.Len(t, searchProviderEvents("&actions=update,delete"), 1)
+	assert.Len(t, searchProviderEvents("&instance_ids=unknown"), 0)
+	assert.Len(t, searchProviderEvents("&end_timestamp="+strconv.FormatInt(startTimestamp, 10)), 0)
+
+	fsEvents := searchFsEvents("")
+	if assert.Len(t, fsEvents, 2) {
+		assert.Equal(t, "rename", fsEvents[0].Action)
+		assert.Equal(t, "/file1.txt", fsEvents[0].VirtualTargetPath)
+		assert.Equal(t, common.ProtocolFTP, fsEvents[0].Protocol)
+		assert.Equal(t, "upload", fsEvents[1].Action)
+		assert.Equal(t, int64(123), fsEvents[1].FileSize)
+		assert.Equal(t, 1, fsEvents[1].Status)
+		assert.Equal(t, "127.0.0.1", fsEvents[1].IP)
+	}
+	fsEvents = searchFsEvents("&protocols=" + common.ProtocolSFTP)
+	if assert.Len(t, fsEvents, 1) {
+		assert.Equal(t, "upload", fsEvents[0].Action)
+	}
+	assert.Len(t, searchFsEvents("&statuses=2,3"), 0)
+	assert.Len(t, searchFsEvents("&ip=127.0.0.2"), 0)
+	assert.Len(t, searchFsEvents("&ssh_cmd=md5sum"), 0)
+	assert.Len(t, searchFsEvents("&actions=upload&order=ASC&limit=10"), 1)
+
+	webToken, err := getJWTWebTokenFromTestServer(defaultTokenAuthUser, defaultTokenAuthPass)
 	assert.NoError(t, err)
-	setBearerForReq(req, token)
-	rr = executeRequest(req)
+	req, err := http.NewRequest(http.MethodGet, webEventsPath, nil)
+	assert.NoError(t, err)
+	setJWTCookieForReq(req, webToken)
+	rr := executeRequest(req)
 	checkResponseCode(t, http.StatusOK, rr)
+	assert.Contains(t, rr.Body.String(), "Search events")
 
-	profileReq = make(map[string]interface{})
-	req, err = http.NewRequest(http.MethodGet, userProfilePath, nil)
+	req, err = http.NewRequest(http.MethodGet, webEventsFsSearchPath+"?username="+url.QueryEscape(user.Username)+
+		"&start_timestamp="+strconv.FormatInt(startTimestamp, 10), nil)
 	assert.NoError(t, err)
-	setBearerForReq(req, token)
+	setJWTCookieForReq(req, webToken)
 	rr = executeRequest(req)
 	checkResponseCode(t, http.StatusOK, rr)
-	err = json.Unmarshal(rr.Body.Bytes(), &profileReq)
-	assert.NoError(t, err)
-	assert.Equal(t, email, profileReq["email"].(string))
-	assert.Equal(t, description, profileReq["description"].(string))
-	assert.True(t, profileReq["allow_api_key_auth"].(bool))
-	assert.Len(t, profileReq["public_keys"].([]interface{}), 2)
-	// set an invalid email
-	profileReq = make(map[string]interface{})
-	profileReq["email"] = "notavalidemail"
-	asJSON, err = json.Marshal(profileReq)
+	fsEvents = nil
+	err = json.Unmarshal(rr.Body.Bytes(), &fsEvents)
 	assert.NoError(t, err)
-	req, err = http.NewRequest(http.MethodPut, userProfilePath, bytes.NewBuffer(asJSON))
+	assert.Len(t, fsEvents, 2)
+
+	req, err = http.NewRequest(http.MethodGet, webEventsProviderSearchPath+"?limit=a", nil)
 	assert.NoError(t, err)
-	setBearerForReq(req, token)
+	setJWTCookieForReq(req, webToken)
 	rr = executeRequest(req)
 	checkResponseCode(t, http.StatusBadRequest, rr)
-	assert.Contains(t, rr.Body.String(), "Validation error: email")
-	// set an invalid public key
-	profileReq = make(map[string]interface{})
-	profileReq["public_keys"] = []string{"not a public key"}
-	asJSON, err = json.Marshal(profileReq)
+
+	_, err = httpdtest.RemoveUser(user, http.StatusOK)
 	assert.NoError(t, err)
-	req, err = http.NewRequest(http.MethodPut, userProfilePath, bytes.NewBuffer(asJSON))
+	err = os.RemoveAll(user.GetHomeDir())
 	assert.NoError(t, err)
-	setBearerForReq(req, token)
-	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusBadRequest, rr)
-	assert.Contains(t, rr.Body.String(), "Validation error: could not parse key")
+}
 
-	user.Filters.WebClient = []string{sdk.WebClientAPIKeyAuthChangeDisabled, sdk.WebClientPubKeyChangeDisabled}
-	user.Email = email
-	user.Description = description
-	user.Filters.AllowAPIKeyAuth = true
-	_, _, err = httpdtest.UpdateUser(user, http.StatusOK, "")
+func TestEventRules(t *testing.T) {
+	rule := dataprovider.EventRule{
+		Name:        "test_rule",
+		Description: "rule desc",
+		Trigger:     dataprovider.EventTriggerFsEvent,
+		Conditions: dataprovider.EventConditions{
+			FsEvents: []string{"upload", "rename"},
+			Options: dataprovider.ConditionOptions{
+				Names: []dataprovider.ConditionPattern{
+					{
+						Pattern: "user*",
+					},
+				},
+				FsPaths: []dataprovider.ConditionPattern{
+					{
+						Pattern:      "/tmp/*",
+						InverseMatch: true,
+					},
+				},
+				Protocols:   []string{common.ProtocolSFTP, common.ProtocolFTP},
+				MinFileSize: 10,
+				MaxFileSize: 1048576,
+				Statuses:    []int{1},
+			},
+		},
+		Actions: []dataprovider.EventAction{
+			{
+				Type: dataprovider.EventActionTypeHTTP,
+				HTTPConfig: dataprovider.EventActionHTTPConfig{
+					Endpoint: "http://127.0.0.1:8082/notify",
+					Method:   http.MethodPost,
+					Headers: []dataprovider.KeyValue{
+						{
+							Key:   "Content-Type",
+							Value: "application/json",
+						},
+					},
+					Body:    `{"event": "{{Event}}", "path": "{{VirtualPath}}"}`,
+					Timeout: 10,
+				},
+			},
+			{
+				Type: dataprovider.EventActionTypeFsCopy,
+				FsConfig: dataprovider.EventActionFsConfig{
+					Target: "/archive/",
+				},
+			},
+		},
+	}
+	rule, _, err := httpdtest.AddEventRule(rule, http.StatusCreated)
 	assert.NoError(t, err)
-	token, err = getJWTAPIUserTokenFromTestServer(defaultUsername, defaultPassword)
+	assert.Greater(t, rule.CreatedAt, int64(0))
+	assert.Greater(t, rule.UpdatedAt, int64(0))
+	_, _, err = httpdtest.AddEventRule(rule, http.StatusInternalServerError)
 	assert.NoError(t, err)
 
-	profileReq = make(map[string]interface{})
-	profileReq["allow_api_key_auth"] = false
-	profileReq["email"] = email
-	profileReq["description"] = description + "_mod"
-	profileReq["public_keys"] = []string{testPubKey}
-	asJSON, err = json.Marshal(profileReq)
+	rules, _, err := httpdtest.GetEventRules(0, 0, http.StatusOK)
 	assert.NoError(t, err)
-	req, err = http.NewRequest(http.MethodPut, userProfilePath, bytes.NewBuffer(asJSON))
+	found := false
+	for _, r := range rules {
+		if r.Name == rule.Name {
+			found = true
+		}
+	}
+	assert.True(t, found)
+	// the config for other action types must be discarded
+	rule.Actions[1].HTTPConfig.Endpoint = "http://127.0.0.1/"
+	rule.Description = "updated desc"
+	rule.Conditions.Options.Statuses = []int{2, 3}
+	rule.Actions = append(rule.Actions, dataprovider.EventAction{
+		Type: dataprovider.EventActionTypeCommand,
+		CmdConfig: dataprovider.EventActionCommandConfig{
+			Cmd:     filepath.Join(os.TempDir(), "cmd"),
+			Timeout: 20,
+			EnvVars: []dataprovider.KeyValue{
+				{
+					Key:   "SFTPGO_EVENT",
+					Value: "{{Event}}",
+				},
+			},
+		},
+	})
+	createdAt := rule.CreatedAt
+	rule, _, err = httpdtest.UpdateEventRule(rule, http.StatusOK)
 	assert.NoError(t, err)
-	setBearerForReq(req, token)
-	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusOK, rr)
-	assert.Contains(t, rr.Body.String(), "Profile updated")
-	// check that api key auth and public keys were not changed
-	profileReq = make(map[string]interface{})
-	req, err = http.NewRequest(http.MethodGet, userProfilePath, nil)
+	assert.Equal(t, createdAt, rule.CreatedAt)
+	assert.Empty(t, rule.Actions[1].HTTPConfig.Endpoint)
+	assert.Len(t, rule.Actions, 3)
+
+	fsEvents := rule.Conditions.FsEvents
+	rule.Conditions.FsEvents = nil
+	_, _, err = httpdtest.UpdateEventRule(rule, http.StatusBadRequest)
 	assert.NoError(t, err)
-	setBearerForReq(req, token)
-	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusOK, rr)
-	err = json.Unmarshal(rr.Body.Bytes(), &profileReq)
+	rule.Conditions.FsEvents = fsEvents
+
+	response, _, err := httpdtest.Dumpdata("", "1", "0", http.StatusOK)
 	assert.NoError(t, err)
-	assert.Equal(t, email, profileReq["email"].(string))
-	assert.Equal(t, description+"_mod", profileReq["description"].(string))
-	assert.True(t, profileReq["allow_api_key_auth"].(bool))
-	assert.Len(t, profileReq["public_keys"].([]interface{}), 2)
+	var dumpedRules []dataprovider.EventRule
+	data, err := json.Marshal(response["event_rules"])
+	assert.NoError(t, err)
+	err = json.Unmarshal(data, &dumpedRules)
+	assert.NoError(t, err)
+	found = false
+	for _, r := range dumpedRules {
+		if r.Name == rule.Name {
+			found = true
+			assert.Equal(t, "updated desc", r.Description)
+		}
+	}
+	assert.True(t, found)
 
-	user.Filters.WebClient = []string{sdk.WebClientAPIKeyAuthChangeDisabled, sdk.WebClientInfoChangeDisabled}
-	user.Description = description + "_mod"
-	_, _, err = httpdtest.UpdateUser(user, http.StatusOK, "")
+	backupData := dataprovider.BackupData{}
+	restoredRule := rule
+	restoredRule.Description = "restored desc"
+	backupData.EventRules = append(backupData.EventRules, restoredRule)
+	backupContent, err := json.Marshal(backupData)
 	assert.NoError(t, err)
-	token, err = getJWTAPIUserTokenFromTestServer(defaultUsername, defaultPassword)
+	// mode 1 does not update existing rules
+	_, _, err = httpdtest.LoaddataFromPostBody(backupContent, "0", "1", http.StatusOK)
+	assert.NoError(t, err)
+	rule, _, err = httpdtest.GetEventRuleByName(rule.Name, http.StatusOK)
+	assert.NoError(t, err)
+	assert.Equal(t, "updated desc", rule.Description)
+	_, _, err = httpdtest.LoaddataFromPostBody(backupContent, "0", "0", http.StatusOK)
+	assert.NoError(t, err)
+	rule, _, err = httpdtest.GetEventRuleByName(rule.Name, http.StatusOK)
 	assert.NoError(t, err)
+	assert.Equal(t, "restored desc", rule.Description)
 
-	profileReq = make(map[string]interface{})
-	profileReq["allow_api_key_auth"] = false
-	profileReq["email"] = "newemail@apiuser.com"
-	profileReq["description"] = description
-	profileReq["public_keys"] = []string{testPubKey}
-	asJSON, err = json.Marshal(profileReq)
+	_, err = httpdtest.RemoveEventRule(rule, http.StatusOK)
 	assert.NoError(t, err)
-	req, err = http.NewRequest(http.MethodPut, userProfilePath, bytes.NewBuffer(asJSON))
+	_, err = httpdtest.RemoveEventRule(rule, http.StatusNotFound)
 	assert.NoError(t, err)
-	setBearerForReq(req, token)
-	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusOK, rr)
-	profileReq = make(map[string]interface{})
-	req, err = http.NewRequest(http.MethodGet, userProfilePath, nil)
+	_, _, err = httpdtest.UpdateEventRule(rule, http.StatusNotFound)
 	assert.NoError(t, err)
-	setBearerForReq(req, token)
-	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusOK, rr)
-	err = json.Unmarshal(rr.Body.Bytes(), &profileReq)
+	_, _, err = httpdtest.GetEventRuleByName(rule.Name, http.StatusNotFound)
 	assert.NoError(t, err)
-	assert.Equal(t, email, profileReq["email"].(string))
-	assert.Equal(t, description+"_mod", profileReq["description"].(string))
-	assert.True(t, profileReq["allow_api_key_auth"].(bool))
-	assert.Len(t, profileReq["public_keys"].([]interface{}), 1)
-	// finally disable all profile permissions
-	user.Filters.WebClient = []string{sdk.WebClientAPIKeyAuthChangeDisabled, sdk.WebClientInfoChangeDisabled,
-		sdk.WebClientPubKeyChangeDisabled}
-	_, _, err = httpdtest.UpdateUser(user, http.StatusOK, "")
+	// a restored rule is added if missing
+	_, _, err = httpdtest.LoaddataFromPostBody(backupContent, "0", "1", http.StatusOK)
 	assert.NoError(t, err)
-	req, err = http.NewRequest(http.MethodPut, userProfilePath, bytes.NewBuffer(asJSON))
+	rule, _, err = httpdtest.GetEventRuleByName(rule.Name, http.StatusOK)
 	assert.NoError(t, err)
-	setBearerForReq(req, token)
-	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusForbidden, rr)
-	assert.Contains(t, rr.Body.String(), "You are not allowed to change anything")
+	_, err = httpdtest.RemoveEventRule(rule, http.StatusOK)
+	assert.NoError(t, err)
+}
 
-	_, err = httpdtest.RemoveUser(user, http.StatusOK)
+func TestEventRuleValidation(t *testing.T) {
+	getRule := func() dataprovider.EventRule {
+		return dataprovider.EventRule{
+			Name:    "rule_validation",
+			Trigger: dataprovider.EventTriggerProviderEvent,
+			Conditions: dataprovider.EventConditions{
+				ProviderEvents: []string{"add"},
+			},
+			Actions: []dataprovider.EventAction{
+				{
+					Type: dataprovider.EventActionTypeEmail,
+					EmailConfig: dataprovider.EventActionEmailConfig{
+						Recipients: []string{"admin@example.com"},
+						Subject:    "{{ObjectType}} {{ObjectName}} added",
+						Body:       "added by {{Name}}",
+					},
+				},
+			},
+		}
+	}
+	rule := getRule()
+	rule.Name = ""
+	_, resp, err := httpdtest.AddEventRule(rule, http.StatusBadRequest)
+	assert.NoError(t, err)
+	assert.Contains(t, string(resp), "rule name is mandatory")
+	rule = getRule()
+	rule.Name = "rule name"
+	_, resp, err = httpdtest.AddEventRule(rule, http.StatusBadRequest)
+	assert.NoError(t, err)
+	assert.Contains(t, string(resp), "is not valid")
+	rule = getRule()
+	rule.Trigger = 100
+	_, resp, err = httpdtest.AddEventRule(rule, http.StatusBadRequest)
+	assert.NoError(t, err)
+	assert.Contains(t, string(resp), "unsupported rule trigger")
+	rule = getRule()
+	rule.Conditions.ProviderEvents = nil
+	_, resp, err = httpdtest.AddEventRule(rule, http.StatusBadRequest)
+	assert.NoError(t, err)
+	assert.Contains(t, string(resp), "at least one provider event is required")
+	rule = getRule()
+	rule.Conditions.ProviderEvents = []string{"unknown"}
+	_, resp, err = httpdtest.AddEventRule(rule, http.StatusBadRequest)
+	assert.NoError(t, err)
+	assert.Contains(t, string(resp), "unsupported provider event")
+	rule = getRule()
+	rule.Conditions.Options.ProviderObjects = []string{"unknown"}
+	_, resp, err = httpdtest.AddEventRule(rule, http.StatusBadRequest)
+	assert.NoError(t, err)
+	assert.Contains(t, string(resp), "unsupported provider object")
+	rule = getRule()
+	rule.Conditions.Options.Names = []dataprovider.ConditionPattern{{}}
+	_, resp, err = httpdtest.AddEventRule(rule, http.StatusBadRequest)
+	assert.NoError(t, err)
+	assert.Contains(t, string(resp), "empty condition pattern not allowed")
+	rule = getRule()
+	rule.Actions = nil
+	_, resp, err = httpdtest.AddEventRule(rule, http.StatusBadRequest)
+	assert.NoError(t, err)
+	assert.Contains(t, string(resp), "at least one action is required")
+	rule = getRule()
+	rule.Actions[0].Type = 100
+	_, resp, err = httpdtest.AddEventRule(rule, http.StatusBadRequest)
+	assert.NoError(t, err)
+	assert.Contains(t, string(resp), "unsupported action type")
+	rule = getRule()
+	rule.Actions[0].EmailConfig.Recipients = []string{"invalid email"}
+	_, resp, err = httpdtest.AddEventRule(rule, http.StatusBadRequest)
+	assert.NoError(t, err)
+	assert.Contains(t, string(resp), "is not valid")
+	rule = getRule()
+	rule.Actions[0].EmailConfig.Recipients = nil
+	_, resp, err = httpdtest.AddEventRule(rule, http.StatusBadRequest)
+	assert.NoError(t, err)
+	assert.Contains(t, string(resp), "at least one email recipient is required")
+	rule = getRule()
+	rule.Actions[0].EmailConfig.Subject = ""
+	_, resp, err = httpdtest.AddEventRule(rule, http.StatusBadRequest)
+	assert.NoError(t, err)
+	assert.Contains(t, string(resp), "email subject is required")
+	rule = getRule()
+	rule.Actions[0].EmailConfig.Body = ""
+	_, resp, err = httpdtest.AddEventRule(rule, http.StatusBadRequest)
+	assert.NoError(t, err)
+	assert.Contains(t, string(resp), "email body is required")
+	rule = getRule()
+	rule.Actions[0].Type = dataprovider.EventActionTypeFsDelete
+	_, resp, err = httpdtest.AddEventRule(rule, http.StatusBadRequest)
+	assert.NoError(t, err)
+	assert.Contains(t, string(resp), "only supported for filesystem events")
+	rule = getRule()
+	rule.Actions[0] = dataprovider.EventAction{
+		Type: dataprovider.EventActionTypeHTTP,
+		HTTPConfig: dataprovider.EventActionHTTPConfig{
+			Endpoint: "ftp://127.0.0.1",
+			Method:   http.MethodPost,
+			Timeout:  10,
+		},
+	}
+	_, resp, err = httpdtest.AddEventRule(rule, http.StatusBadRequest)
+	assert.NoError(t, err)
+	assert.Contains(t, string(resp), "invalid HTTP endpoint")
+	rule.Actions[0].HTTPConfig.Endpoint = "http://127.0.0.1"
+	rule.Actions[0].HTTPConfig.Method = http.MethodDelete
+	_, resp, err = httpdtest.AddEventRule(rule, http.StatusBadRequest)
+	assert.NoError(t, err)
+	assert.Contains(t, string(resp), "unsupported HTTP method")
+	rule.Actions[0].HTTPConfig.Method = http.MethodGet
+	rule.Actions[0].HTTPConfig.Timeout = 0
+	_, resp, err = httpdtest.AddEventRule(rule, http.StatusBadRequest)
+	assert.NoError(t, err)
+	assert.Contains(t, string(resp), "invalid HTTP timeout")
+	rule.Actions[0].HTTPConfig.Timeout = 10
+	rule.Actions[0].HTTPConfig.Headers = []dataprovider.KeyValue{{Key: "a"}}
+	_, resp, err = httpdtest.AddEventRule(rule, http.StatusBadRequest)
+	assert.NoError(t, err)
+	assert.Contains(t, string(resp), "invalid HTTP header")
+	rule.Actions[0] = dataprovider.EventAction{
+		Type: dataprovider.EventActionTypeCommand,
+		CmdConfig: dataprovider.EventActionCommandConfig{
+			Cmd:     "relative",
+			Timeout: 10,
+		},
+	}
+	_, resp, err = httpdtest.AddEventRule(rule, http.StatusBadRequest)
+	assert.NoError(t, err)
+	assert.Contains(t, string(resp), "it must be an absolute path")
+	rule.Actions[0].CmdConfig.Cmd = filepath.Join(os.TempDir(), "cmd")
+	rule.Actions[0].CmdConfig.Timeout = 301
+	_, resp, err = httpdtest.AddEventRule(rule, http.StatusBadRequest)
+	assert.NoError(t, err)
+	assert.Contains(t, string(resp), "invalid command timeout")
+	rule.Actions[0].CmdConfig.Timeout = 30
+	rule.Actions[0].CmdConfig.EnvVars = []dataprovider.KeyValue{{Value: "a"}}
+	_, resp, err = httpdtest.AddEventRule(rule, http.StatusBadRequest)
+	assert.NoError(t, err)
+	assert.Contains(t, string(resp), "the key is required")
+	rule.Actions[0].CmdConfig.EnvVars = nil
+	rule.Actions[0].CmdConfig.Cmd = filepath.Join(os.TempDir(), "not_enabled_cmd")
+	_, resp, err = httpdtest.AddEventRule(rule, http.StatusBadRequest)
+	assert.NoError(t, err)
+	assert.Contains(t, string(resp), "is not enabled")
+
+	rule = getRule()
+	rule.Trigger = dataprovider.EventTriggerFsEvent
+	rule.Conditions.ProviderEvents = nil
+	_, resp, err = httpdtest.AddEventRule(rule, http.StatusBadRequest)
+	assert.NoError(t, err)
+	assert.Contains(t, string(resp), "at least one filesystem event is required")
+	rule.Conditions.FsEvents = []string{"pre-upload"}
+	_, resp, err = httpdtest.AddEventRule(rule, http.StatusBadRequest)
+	assert.NoError(t, err)
+	assert.Contains(t, string(resp), "unsupported fs event")
+	rule.Conditions.FsEvents = []string{"upload"}
+	rule.Conditions.Options.MinFileSize = -1
+	_, resp, err = httpdtest.AddEventRule(rule, http.StatusBadRequest)
+	assert.NoError(t, err)
+	assert.Contains(t, string(resp), "file size limits cannot be negative")
+	rule.Conditions.Options.MinFileSize = 100
+	rule.Conditions.Options.MaxFileSize = 10
+	_, resp, err = httpdtest.AddEventRule(rule, http.StatusBadRequest)
+	assert.NoError(t, err)
+	assert.Contains(t, string(resp), "invalid file size range")
+	rule.Conditions.Options.MaxFileSize = 0
+	rule.Conditions.Options.Statuses = []int{4}
+	_, resp, err = httpdtest.AddEventRule(rule, http.StatusBadRequest)
+	assert.NoError(t, err)
+	assert.Contains(t, string(resp), "invalid status")
+	rule.Conditions.Options.Statuses = nil
+	rule.Actions[0] = dataprovider.EventAction{
+		Type: dataprovider.EventActionTypeFsRename,
+		FsConfig: dataprovider.EventActionFsConfig{
+			Target: "relative",
+		},
+	}
+	_, resp, err = httpdtest.AddEventRule(rule, http.StatusBadRequest)
 	assert.NoError(t, err)
-	err = os.RemoveAll(user.GetHomeDir())
+	assert.Contains(t, string(resp), "it must be an absolute virtual path")
+	rule.Actions[0].FsConfig.Target = "/target"
+	rule, _, err = httpdtest.AddEventRule(rule, http.StatusCreated)
+	assert.NoError(t, err)
+	_, err = httpdtest.RemoveEventRule(rule, http.StatusOK)
 	assert.NoError(t, err)
+}
 
-	req, err = http.NewRequest(http.MethodGet, userProfilePath, nil)
+func TestEventRuleCommandsDisabled(t *testing.T) {
+	err := dataprovider.Close()
+	assert.NoError(t, err)
+	err = config.LoadConfig(configDir, "")
+	assert.NoError(t, err)
+	providerConf := config.GetProviderConf()
+	providerConf.CredentialsPath = credentialsPath
+	providerConf.EventManager.EnabledCommands = []string{"relative"}
+	err = dataprovider.Initialize(providerConf, configDir, true)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "it must be an absolute path")
+	}
+	providerConf.EventManager.EnabledCommands = nil
+	err = dataprovider.Initialize(providerConf, configDir, true)
 	assert.NoError(t, err)
-	setBearerForReq(req, token)
-	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusNotFound, rr)
 
-	req, err = http.NewRequest(http.MethodPut, userProfilePath, bytes.NewBuffer(asJSON))
+	rule := dataprovider.EventRule{
+		Name:    "disabled_cmd_rule",
+		Trigger: dataprovider.EventTriggerFsEvent,
+		Conditions: dataprovider.EventConditions{
+			FsEvents: []string{"upload"},
+		},
+		Actions: []dataprovider.EventAction{
+			{
+				Type: dataprovider.EventActionTypeCommand,
+				CmdConfig: dataprovider.EventActionCommandConfig{
+					Cmd:     filepath.Join(os.TempDir(), "cmd"),
+					Timeout: 10,
+				},
+			},
+		},
+	}
+	_, resp, err := httpdtest.AddEventRule(rule, http.StatusBadRequest)
+	assert.NoError(t, err)
+	assert.Contains(t, string(resp), "command actions are disabled")
+
+	err = dataprovider.Close()
+	assert.NoError(t, err)
+	err = config.LoadConfig(configDir, "")
+	assert.NoError(t, err)
+	providerConf = config.GetProviderConf()
+	providerConf.CredentialsPath = credentialsPath
+	err = os.RemoveAll(credentialsPath)
+	assert.NoError(t, err)
+	err = dataprovider.Initialize(providerConf, configDir, true)
 	assert.NoError(t, err)
-	setBearerForReq(req, token)
-	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusNotFound, rr)
 }
 
-func TestWebAPIChangeUserPwdMock(t *testing.T) {
-	user, _, err := httpdtest.AddUser(getTestUser(), http.StatusCreated)
+func TestEventRulesPermissions(t *testing.T) {
+	a := getTestAdmin()
+	a.Username = altAdminUsername
+	a.Password = altAdminPassword
+	a.Permissions = []string{dataprovider.PermAdminAddUsers, dataprovider.PermAdminChangeUsers}
+	admin, _, err := httpdtest.AddAdmin(a, http.StatusCreated)
 	assert.NoError(t, err)
-	token, err := getJWTAPIUserTokenFromTestServer(defaultUsername, defaultPassword)
+
+	token, err := getJWTAPITokenFromTestServer(altAdminUsername, altAdminPassword)
 	assert.NoError(t, err)
-	// invalid json
-	req, err := http.NewRequest(http.MethodPut, userPwdPath, bytes.NewBuffer([]byte("{")))
+	req, err := http.NewRequest(http.MethodGet, eventRulesPath, nil)
 	assert.NoError(t, err)
 	setBearerForReq(req, token)
 	rr := executeRequest(req)
-	checkResponseCode(t, http.StatusBadRequest, rr)
+	checkResponseCode(t, http.StatusForbidden, rr)
 
-	pwd := make(map[string]string)
-	pwd["current_password"] = defaultPassword
-	pwd["new_password"] = defaultPassword
-	asJSON, err := json.Marshal(pwd)
+	webToken, err := getJWTWebTokenFromTestServer(altAdminUsername, altAdminPassword)
 	assert.NoError(t, err)
-	req, err = http.NewRequest(http.MethodPut, userPwdPath, bytes.NewBuffer(asJSON))
+	req, err = http.NewRequest(http.MethodGet, webEventRulesPath, nil)
 	assert.NoError(t, err)
-	setBearerForReq(req, token)
+	setJWTCookieForReq(req, webToken)
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusBadRequest, rr)
-	assert.Contains(t, rr.Body.String(), "the new password must be different from the current one")
+	checkResponseCode(t, http.StatusForbidden, rr)
 
-	pwd["new_password"] = altAdminPassword
-	asJSON, err = json.Marshal(pwd)
+	admin.Permissions = append(admin.Permissions, dataprovider.PermAdminManageEventRules)
+	_, _, err = httpdtest.UpdateAdmin(admin, http.StatusOK)
 	assert.NoError(t, err)
-	req, err = http.NewRequest(http.MethodPut, userPwdPath, bytes.NewBuffer(asJSON))
+	// the permissions are stored inside the token
+	token, err = getJWTAPITokenFromTestServer(altAdminUsername, altAdminPassword)
+	assert.NoError(t, err)
+
+	req, err = http.NewRequest(http.MethodGet, eventRulesPath, nil)
 	assert.NoError(t, err)
 	setBearerForReq(req, token)
 	rr = executeRequest(req)
 	checkResponseCode(t, http.StatusOK, rr)
-	_, err = getJWTAPIUserTokenFromTestServer(defaultUsername, defaultPassword)
-	assert.Error(t, err)
-	token, err = getJWTAPIUserTokenFromTestServer(defaultUsername, altAdminPassword)
+
+	_, err = httpdtest.RemoveAdmin(admin, http.StatusOK)
 	assert.NoError(t, err)
-	assert.NotEmpty(t, token)
+}
 
-	// remove the change password permission
-	user.Filters.WebClient = []string{sdk.WebClientPasswordChangeDisabled}
-	user, _, err = httpdtest.UpdateUser(user, http.StatusOK, "")
+func TestEventRuleActions(t *testing.T) {
+	u := getTestUser()
+	u.QuotaFiles = 100
+	user, _, err := httpdtest.AddUser(u, http.StatusCreated)
 	assert.NoError(t, err)
-	assert.Len(t, user.Filters.WebClient, 1)
-	assert.Contains(t, user.Filters.WebClient, sdk.WebClientPasswordChangeDisabled)
 
-	token, err = getJWTAPIUserTokenFromTestServer(defaultUsername, altAdminPassword)
+	ruleFs := dataprovider.EventRule{
+		Name:    "archive_rule",
+		Trigger: dataprovider.EventTriggerFsEvent,
+		Conditions: dataprovider.EventConditions{
+			FsEvents: []string{"upload", "rename"},
+			Options: dataprovider.ConditionOptions{
+				Names: []dataprovider.ConditionPattern{
+					{
+						Pattern: user.Username,
+					},
+				},
+				FsPaths: []dataprovider.ConditionPattern{
+					{
+						Pattern: "/inbound/*.txt",
+					},
+				},
+				Protocols: []string{common.ProtocolSFTP},
+			},
+		},
+		Actions: []dataprovider.EventAction{
+			{
+				Type: dataprovider.EventActionTypeFsCopy,
+				FsConfig: dataprovider.EventActionFsConfig{
+					Target: "/archive/{{Name}}/",
+				},
+			},
+			{
+				Type: dataprovider.EventActionTypeFsRename,
+				FsConfig: dataprovider.EventActionFsConfig{
+					Target: "/processed/{{Event}}.txt",
+				},
+			},
+			{
+				Type: dataprovider.EventActionTypeFsDelete,
+			},
+		},
+	}
+	ruleFs, _, err = httpdtest.AddEventRule(ruleFs, http.StatusCreated)
 	assert.NoError(t, err)
-	assert.NotEmpty(t, token)
 
-	pwd["current_password"] = altAdminPassword
-	pwd["new_password"] = defaultPassword
-	asJSON, err = json.Marshal(pwd)
+	received := make(chan string, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if assert.NoError(t, err) {
+			received <- r.Header.Get("X-Event") + " " + string(body)
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	ruleProvider := dataprovider.EventRule{
+		Name:    "notify_rule",
+		Trigger: dataprovider.EventTriggerProviderEvent,
+		Conditions: dataprovider.EventConditions{
+			ProviderEvents: []string{"add", "delete"},
+			Options: dataprovider.ConditionOptions{
+				Names: []dataprovider.ConditionPattern{
+					{
+						Pattern: "evt_*",
+					},
+				},
+				ProviderObjects: []string{"user"},
+			},
+		},
+		Actions: []dataprovider.EventAction{
+			{
+				Type: dataprovider.EventActionTypeHTTP,
+				HTTPConfig: dataprovider.EventActionHTTPConfig{
+					Endpoint: server.URL,
+					Method:   http.MethodPost,
+					Headers: []dataprovider.KeyValue{
+						{
+							Key:   "X-Event",
+							Value: "{{Event}}",
+						},
+					},
+					Body:    "{{ObjectType}} {{ObjectName}} {{Name}}",
+					Timeout: 10,
+				},
+			},
+		},
+	}
+	ruleProvider, _, err = httpdtest.AddEventRule(ruleProvider, http.StatusCreated)
 	assert.NoError(t, err)
-	req, err = http.NewRequest(http.MethodPut, userPwdPath, bytes.NewBuffer(asJSON))
+
+	inboundDir := filepath.Join(user.GetHomeDir(), "inbound")
+	err = os.MkdirAll(inboundDir, os.ModePerm)
 	assert.NoError(t, err)
-	setBearerForReq(req, token)
-	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusForbidden, rr)
+	for _, name := range []string{"file.txt", "file.csv"} {
+		err = os.WriteFile(filepath.Join(inboundDir, name), []byte("content"), os.ModePerm)
+		assert.NoError(t, err)
+	}
+	// no rule matches these events
+	common.ExecuteActionNotification(&user, "upload", filepath.Join(inboundDir, "file.csv"), "/inbound/file.csv",
+		"", "", "", common.ProtocolSFTP, "127.0.0.1", 7, nil)
+	common.ExecuteActionNotification(&user, "upload", filepath.Join(inboundDir, "file.txt"), "/inbound/file.txt",
+		"", "", "", common.ProtocolFTP, "127.0.0.1", 7, nil)
+	common.ExecuteActionNotification(&user, "download", filepath.Join(inboundDir, "file.txt"), "/inbound/file.txt",
+		"", "", "", common.ProtocolSFTP, "127.0.0.1", 7, nil)
+	time.Sleep(200 * time.Millisecond)
+	assert.FileExists(t, filepath.Join(inboundDir, "file.txt"))
+	assert.NoDirExists(t, filepath.Join(user.GetHomeDir(), "archive"))
+	// now the file is copied, renamed and the last action fails since the file was already renamed
+	common.ExecuteActionNotification(&user, "upload", filepath.Join(inboundDir, "file.txt"), "/inbound/file.txt",
+		"", "", "", common.ProtocolSFTP, "127.0.0.1", 7, nil)
+	assert.Eventually(t, func() bool {
+		_, err := os.Stat(filepath.Join(user.GetHomeDir(), "processed", "upload.txt"))
+		return err == nil
+	}, 2*time.Second, 50*time.Millisecond)
+	assert.FileExists(t, filepath.Join(user.GetHomeDir(), "archive", user.Username, "file.txt"))
+	assert.NoFileExists(t, filepath.Join(inboundDir, "file.txt"))
+	assert.FileExists(t, filepath.Join(inboundDir, "file.csv"))
+	// for rename events the actions apply to the target file
+	ruleFs.Actions = []dataprovider.EventAction{
+		{
+			Type: dataprovider.EventActionTypeFsDelete,
+		},
+	}
+	ruleFs, _, err = httpdtest.UpdateEventRule(ruleFs, http.StatusOK)
+	assert.NoError(t, err)
+	err = os.WriteFile(filepath.Join(inboundDir, "renamed.txt"), []byte("content"), os.ModePerm)
+	assert.NoError(t, err)
+	common.ExecuteActionNotification(&user, "rename", filepath.Join(inboundDir, "file.txt"), "/inbound/file.txt",
+		filepath.Join(inboundDir, "renamed.txt"), "/inbound/renamed.txt", "", common.ProtocolSFTP, "127.0.0.1", 0, nil)
+	assert.Eventually(t, func() bool {
+		_, err := os.Stat(filepath.Join(inboundDir, "renamed.txt"))
+		return os.IsNotExist(err)
+	}, 2*time.Second, 50*time.Millisecond)
+	// the events generated by the actions are stored but do not trigger other rules
+	assert.FileExists(t, filepath.Join(user.GetHomeDir(), "processed", "upload.txt"))
 
+	u = getTestUser()
+	u.Username = "evt_user"
+	evtUser, _, err := httpdtest.AddUser(u, http.StatusCreated)
+	assert.NoError(t, err)
+	select {
+	case msg := <-received:
+		assert.Equal(t, "add user evt_user "+defaultTokenAuthUser, msg)
+	case <-time.After(2 * time.Second):
+		assert.Fail(t, "HTTP action not executed")
+	}
+	// update is not a configured event
+	_, _, err = httpdtest.UpdateUser(evtUser, http.StatusOK, "")
+	assert.NoError(t, err)
+	_, err = httpdtest.RemoveUser(evtUser, http.StatusOK)
+	assert.NoError(t, err)
+	select {
+	case msg := <-received:
+		assert.Equal(t, "delete user evt_user "+defaultTokenAuthUser, msg)
+	case <-time.After(2 * time.Second):
+		assert.Fail(t, "HTTP action not executed")
+	}
+	// the user does not match the configured names
 	_, err = httpdtest.RemoveUser(user, http.StatusOK)
 	assert.NoError(t, err)
+	time.Sleep(100 * time.Millisecond)
+	assert.Len(t, received, 0)
+
+	_, err = httpdtest.RemoveEventRule(ruleFs, http.StatusOK)
+	assert.NoError(t, err)
+	_, err = httpdtest.RemoveEventRule(ruleProvider, http.StatusOK)
+	assert.NoError(t, err)
 	err = os.RemoveAll(user.GetHomeDir())
 	assert.NoError(t, err)
 }
 
-func TestLoginInvalidPasswordMock(t *testing.T) {
-	_, err := getJWTAPITokenFromTestServer(defaultTokenAuthUser, defaultTokenAuthPass+"1")
-	assert.Error(t, err)
-	// now a login with no credentials
-	req, _ := http.NewRequest(http.MethodGet, "/api/v2/token", nil)
-	rr := executeRequest(req)
-	assert.Equal(t, http.StatusUnauthorized, rr.Code)
-}
+func TestEventRuleCommandAction(t *testing.T) {
+	if runtime.GOOS == osWindows {
+		t.Skip("this test is not available on Windows")
+	}
+	outFile := filepath.Join(os.TempDir(), "event_cmd_out")
+	cmdPath := filepath.Join(os.TempDir(), "event_cmd.sh")
+	err := os.WriteFile(cmdPath, []byte(fmt.Sprintf("#!/bin/sh\necho \"$SFTPGO_EVENT $SFTPGO_PATH\" > %v\n", outFile)),
+		os.ModePerm)
+	assert.NoError(t, err)
 
-func TestWebAPIChangeAdminProfileMock(t *testing.T) {
-	admin := getTestAdmin()
-	admin.Username = altAdminUsername
-	admin.Password = altAdminPassword
-	admin, _, err := httpdtest.AddAdmin(admin, http.StatusCreated)
+	user, _, err := httpdtest.AddUser(getTestUser(), http.StatusCreated)
 	assert.NoError(t, err)
-	assert.False(t, admin.Filters.AllowAPIKeyAuth)
+	rule := dataprovider.EventRule{
+		Name:    "cmd_rule",
+		Trigger: dataprovider.EventTriggerFsEvent,
+		Conditions: dataprovider.EventConditions{
+			FsEvents: []string{"mkdir"},
+			Options: dataprovider.ConditionOptions{
+				Statuses: []int{1},
+			},
+		},
+		Actions: []dataprovider.EventAction{
+			{
+				Type: dataprovider.EventActionTypeCommand,
+				CmdConfig: dataprovider.EventActionCommandConfig{
+					Cmd:     cmdPath,
+					Timeout: 10,
+					EnvVars: []dataprovider.KeyValue{
+						{
+							Key:   "SFTPGO_EVENT",
+							Value: "{{Event}}",
+						},
+						{
+							Key:   "SFTPGO_PATH",
+							Value: "{{VirtualPath}}",
+						},
+					},
+				},
+			},
+		},
+	}
+	rule, _, err = httpdtest.AddEventRule(rule, http.StatusCreated)
+	assert.NoError(t, err)
+	common.ExecuteActionNotification(&user, "mkdir", filepath.Join(user.GetHomeDir(), "adir"), "/adir", "", "", "",
+		common.ProtocolSFTP, "127.0.0.1", 0, errors.New("mkdir error"))
+	time.Sleep(200 * time.Millisecond)
+	assert.NoFileExists(t, outFile)
+	common.ExecuteActionNotification(&user, "mkdir", filepath.Join(user.GetHomeDir(), "adir"), "/adir", "", "", "",
+		common.ProtocolSFTP, "127.0.0.1", 0, nil)
+	assert.Eventually(t, func() bool {
+		content, err := os.ReadFile(outFile)
+		return err == nil && strings.TrimSpace(string(content)) == "mkdir /adir"
+	}, 2*time.Second, 50*time.Millisecond)
 
-	token, err := getJWTAPITokenFromTestServer(altAdminUsername, altAdminPassword)
+	_, err = httpdtest.RemoveEventRule(rule, http.StatusOK)
 	assert.NoError(t, err)
-	// invalid json
-	req, err := http.NewRequest(http.MethodPut, adminProfilePath, bytes.NewBuffer([]byte("{")))
+	_, err = httpdtest.RemoveUser(user, http.StatusOK)
 	assert.NoError(t, err)
-	setBearerForReq(req, token)
-	rr := executeRequest(req)
-	checkResponseCode(t, http.StatusBadRequest, rr)
+	err = os.RemoveAll(user.GetHomeDir())
+	assert.NoError(t, err)
+	err = os.Remove(cmdPath)
+	assert.NoError(t, err)
+	err = os.Remove(outFile)
+	assert.NoError(t, err)
+}
 
-	email := "adminapi@example.com"
-	description := "admin API description"
-	profileReq := make(map[string]interface{})
-	profileReq["allow_api_key_auth"] = true
-	profileReq["email"] = email
-	profileReq["description"] = description
-	asJSON, err := json.Marshal(profileReq)
+func TestSchedules(t *testing.T) {
+	schedule := dataprovider.Schedule{
+		Name:           "test_schedule",
+		Description:    "schedule desc",
+		Status:         dataprovider.ScheduleStatusEnabled,
+		CronExpression: "0  2 * *   *",
+		Task:           dataprovider.ScheduleTaskQuotaScan,
+		Options: dataprovider.ScheduleOptions{
+			Usernames:   []string{"user1", "user2"},
+			AllFolders:  true,
+			FolderNames: []string{"folder1"},
+			Retention: []sdk.DataRetentionPolicy{
+				{
+					Path:      "/",
+					Retention: 24,
+				},
+			},
+		},
+		LastRun:       123,
+		LastRunStatus: dataprovider.ScheduleRunStatusSuccess,
+	}
+	schedule, _, err := httpdtest.AddSchedule(schedule, http.StatusCreated)
 	assert.NoError(t, err)
-	req, err = http.NewRequest(http.MethodPut, adminProfilePath, bytes.NewBuffer(asJSON))
+	assert.Greater(t, schedule.CreatedAt, int64(0))
+	assert.Greater(t, schedule.UpdatedAt, int64(0))
+	assert.Equal(t, "0 2 * * *", schedule.CronExpression)
+	// the options not applicable for the task must be discarded
+	assert.Empty(t, schedule.Options.FolderNames)
+	assert.Empty(t, schedule.Options.Retention)
+	// the last run fields are read only
+	assert.Equal(t, int64(0), schedule.LastRun)
+	assert.Equal(t, dataprovider.ScheduleRunStatusNone, schedule.LastRunStatus)
+	_, _, err = httpdtest.AddSchedule(schedule, http.StatusInternalServerError)
 	assert.NoError(t, err)
-	setBearerForReq(req, token)
-	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusOK, rr)
-	assert.Contains(t, rr.Body.String(), "Profile updated")
 
-	profileReq = make(map[string]interface{})
-	req, err = http.NewRequest(http.MethodGet, adminProfilePath, nil)
+	schedules, _, err := httpdtest.GetSchedules(0, 0, http.StatusOK)
 	assert.NoError(t, err)
-	setBearerForReq(req, token)
-	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusOK, rr)
-	err = json.Unmarshal(rr.Body.Bytes(), &profileReq)
+	found := false
+	for _, s := range schedules {
+		if s.Name == schedule.Name {
+			found = true
+		}
+	}
+	assert.True(t, found)
+
+	schedule.Description = "updated desc"
+	schedule.Status = dataprovider.ScheduleStatusDisabled
+	schedule.CronExpression = "@daily"
+	schedule.Task = dataprovider.ScheduleTaskRetentionCheck
+	schedule.Options = dataprovider.ScheduleOptions{
+		AllUsers:   true,
+		Usernames:  []string{"user1"},
+		AllFolders: true,
+		Retention: []sdk.DataRetentionPolicy{
+			{
+				Path:      "/dir1",
+				Retention: 0,
+			},
+			{
+				Path:            "/",
+				Retention:       48,
+				DeleteEmptyDirs: true,
+			},
+		},
+	}
+	createdAt := schedule.CreatedAt
+	schedule, _, err = httpdtest.UpdateSchedule(schedule, http.StatusOK)
 	assert.NoError(t, err)
-	assert.Equal(t, email, profileReq["email"].(string))
-	assert.Equal(t, description, profileReq["description"].(string))
-	assert.True(t, profileReq["allow_api_key_auth"].(bool))
-	// set an invalid email
-	profileReq["email"] = "admin_invalid_email"
-	asJSON, err = json.Marshal(profileReq)
+	assert.Equal(t, createdAt, schedule.CreatedAt)
+	assert.Empty(t, schedule.Options.Usernames)
+	assert.False(t, schedule.Options.AllFolders)
+	assert.Len(t, schedule.Options.Retention, 2)
+
+	schedule.CronExpression = "* * *"
+	_, _, err = httpdtest.UpdateSchedule(schedule, http.StatusBadRequest)
 	assert.NoError(t, err)
-	req, err = http.NewRequest(http.MethodPut, adminProfilePath, bytes.NewBuffer(asJSON))
+	schedule.CronExpression = "@daily"
+
+	response, _, err := httpdtest.Dumpdata("", "1", "0", http.StatusOK)
 	assert.NoError(t, err)
-	setBearerForReq(req, token)
-	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusBadRequest, rr)
-	assert.Contains(t, rr.Body.String(), "Validation error: email")
+	var dumpedSchedules []dataprovider.Schedule
+	data, err := json.Marshal(response["schedules"])
+	assert.NoError(t, err)
+	err = json.Unmarshal(data, &dumpedSchedules)
+	assert.NoError(t, err)
+	found = false
+	for _, s := range dumpedSchedules {
+		if s.Name == schedule.Name {
+			found = true
+			assert.Equal(t, "updated desc", s.Description)
+		}
+	}
+	assert.True(t, found)
 
-	_, err = httpdtest.RemoveAdmin(admin, http.StatusOK)
+	backupData := dataprovider.BackupData{}
+	restoredSchedule := schedule
+	restoredSchedule.Description = "restored desc"
+	backupData.Schedules = append(backupData.Schedules, restoredSchedule)
+	backupContent, err := json.Marshal(backupData)
+	assert.NoError(t, err)
+	// mode 1 does not update existing schedules
+	_, _, err = httpdtest.LoaddataFromPostBody(backupContent, "0", "1", http.StatusOK)
 	assert.NoError(t, err)
+	schedule, _, err = httpdtest.GetScheduleByName(schedule.Name, http.StatusOK)
+	assert.NoError(t, err)
+	assert.Equal(t, "updated desc", schedule.Description)
+	_, _, err = httpdtest.LoaddataFromPostBody(backupContent, "0", "0", http.StatusOK)
+	assert.NoError(t, err)
+	schedule, _, err = httpdtest.GetScheduleByName(schedule.Name, http.StatusOK)
+	assert.NoError(t, err)
+	assert.Equal(t, "restored desc", schedule.Description)
 
-	req, err = http.NewRequest(http.MethodGet, adminProfilePath, nil)
+	_, err = httpdtest.RemoveSchedule(schedule, http.StatusOK)
 	assert.NoError(t, err)
-	setBearerForReq(req, token)
-	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusNotFound, rr)
+	_, err = httpdtest.RemoveSchedule(schedule, http.StatusNotFound)
+	assert.NoError(t, err)
+	_, _, err = httpdtest.UpdateSchedule(schedule, http.StatusNotFound)
+	assert.NoError(t, err)
+	_, _, err = httpdtest.GetScheduleByName(schedule.Name, http.StatusNotFound)
+	assert.NoError(t, err)
+	_, err = httpdtest.RunSchedule(schedule.Name, http.StatusNotFound)
+	assert.NoError(t, err)
+	// a restored schedule is added if missing
+	_, _, err = httpdtest.LoaddataFromPostBody(backupContent, "0", "1", http.StatusOK)
+	assert.NoError(t, err)
+	schedule, _, err = httpdtest.GetScheduleByName(schedule.Name, http.StatusOK)
+	assert.NoError(t, err)
+	_, err = httpdtest.RemoveSchedule(schedule, http.StatusOK)
+	assert.NoError(t, err)
+}
 
-	req, err = http.NewRequest(http.MethodPut, adminProfilePath, bytes.NewBuffer(asJSON))
+func TestScheduleValidation(t *testing.T) {
+	getSchedule := func() dataprovider.Schedule {
+		return dataprovider.Schedule{
+			Name:           "schedule_validation",
+			Status:         dataprovider.ScheduleStatusEnabled,
+			CronExpression: "*/10 * * * *",
+			Task:           dataprovider.ScheduleTaskRetentionCheck,
+			Options: dataprovider.ScheduleOptions{
+				Usernames: []string{"user"},
+				Retention: []sdk.DataRetentionPolicy{
+					{
+						Path:      "/",
+						Retention: 24,
+					},
+				},
+			},
+		}
+	}
+	schedule := getSchedule()
+	schedule.Name = ""
+	_, resp, err := httpdtest.AddSchedule(schedule, http.StatusBadRequest)
+	assert.NoError(t, err)
+	assert.Contains(t, string(resp), "schedule name is mandatory")
+	schedule = getSchedule()
+	schedule.Name = "schedule name"
+	_, resp, err = httpdtest.AddSchedule(schedule, http.StatusBadRequest)
+	assert.NoError(t, err)
+	assert.Contains(t, string(resp), "is not valid")
+	schedule = getSchedule()
+	schedule.Status = 2
+	_, resp, err = httpdtest.AddSchedule(schedule, http.StatusBadRequest)
+	assert.NoError(t, err)
+	assert.Contains(t, string(resp), "invalid schedule status")
+	schedule = getSchedule()
+	schedule.CronExpression = ""
+	_, resp, err = httpdtest.AddSchedule(schedule, http.StatusBadRequest)
+	assert.NoError(t, err)
+	assert.Contains(t, string(resp), "cron expression is mandatory")
+	schedule = getSchedule()
+	schedule.CronExpression = "60 * * * *"
+	_, resp, err = httpdtest.AddSchedule(schedule, http.StatusBadRequest)
+	assert.NoError(t, err)
+	assert.Contains(t, string(resp), "out of range")
+	schedule = getSchedule()
+	schedule.CronExpression = "0 0 31 feb *"
+	_, resp, err = httpdtest.AddSchedule(schedule, http.StatusBadRequest)
+	assert.NoError(t, err)
+	assert.Contains(t, string(resp), "never runs")
+	schedule = getSchedule()
+	schedule.Task = 100
+	_, resp, err = httpdtest.AddSchedule(schedule, http.StatusBadRequest)
+	assert.NoError(t, err)
+	assert.Contains(t, string(resp), "unsupported schedule task")
+	schedule = getSchedule()
+	schedule.Options.Usernames = nil
+	_, resp, err = httpdtest.AddSchedule(schedule, http.StatusBadRequest)
+	assert.NoError(t, err)
+	assert.Contains(t, string(resp), "at least a user is required")
+	schedule = getSchedule()
+	schedule.Options.Notifications = []string{"invalid"}
+	_, resp, err = httpdtest.AddSchedule(schedule, http.StatusBadRequest)
+	assert.NoError(t, err)
+	assert.Contains(t, string(resp), "invalid notification")
+	schedule = getSchedule()
+	schedule.Options.Notifications = []string{dataprovider.ScheduleNotificationEmail}
+	schedule.Options.Email = "not an email"
+	_, resp, err = httpdtest.AddSchedule(schedule, http.StatusBadRequest)
+	assert.NoError(t, err)
+	assert.Contains(t, string(resp), "is not valid")
+	// an empty retention means apply the stored policies
+	schedule = getSchedule()
+	schedule.Options.Retention = nil
+	schedule.Options.Notifications = []string{dataprovider.ScheduleNotificationHook}
+	schedule, _, err = httpdtest.AddSchedule(schedule, http.StatusCreated)
+	assert.NoError(t, err)
+	assert.Empty(t, schedule.Options.Retention)
+	_, err = httpdtest.RemoveSchedule(schedule, http.StatusOK)
+	assert.NoError(t, err)
+	schedule = getSchedule()
+	schedule.Options.Retention[0].Path = "relative"
+	_, resp, err = httpdtest.AddSchedule(schedule, http.StatusBadRequest)
+	assert.NoError(t, err)
+	assert.Contains(t, string(resp), "it must be an absolute virtual path")
+	schedule = getSchedule()
+	schedule.Options.Retention = append(schedule.Options.Retention, sdk.DataRetentionPolicy{
+		Path:      "/",
+		Retention: 12,
+	})
+	_, resp, err = httpdtest.AddSchedule(schedule, http.StatusBadRequest)
+	assert.NoError(t, err)
+	assert.Contains(t, string(resp), "duplicated retention path")
+	schedule = getSchedule()
+	schedule.Options.Retention[0].Retention = -1
+	_, resp, err = httpdtest.AddSchedule(schedule, http.StatusBadRequest)
+	assert.NoError(t, err)
+	assert.Contains(t, string(resp), "invalid retention")
+	schedule = getSchedule()
+	schedule.Options.Retention[0].Retention = 0
+	_, resp, err = httpdtest.AddSchedule(schedule, http.StatusBadRequest)
+	assert.NoError(t, err)
+	assert.Contains(t, string(resp), "nothing to delete")
+	schedule = getSchedule()
+	schedule.Task = dataprovider.ScheduleTaskQuotaScan
+	schedule.Options.Usernames = nil
+	_, resp, err = httpdtest.AddSchedule(schedule, http.StatusBadRequest)
+	assert.NoError(t, err)
+	assert.Contains(t, string(resp), "at least a user or a folder is required")
+	schedule.Task = dataprovider.ScheduleTaskBackup
+	schedule, _, err = httpdtest.AddSchedule(schedule, http.StatusCreated)
+	assert.NoError(t, err)
+	assert.Empty(t, schedule.Options.Retention)
+	_, err = httpdtest.RemoveSchedule(schedule, http.StatusOK)
 	assert.NoError(t, err)
-	setBearerForReq(req, token)
-	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusNotFound, rr)
 }
 
-func TestChangeAdminPwdMock(t *testing.T) {
-	token, err := getJWTAPITokenFromTestServer(defaultTokenAuthUser, defaultTokenAuthPass)
+func TestSchedulesPermissions(t *testing.T) {
+	a := getTestAdmin()
+	a.Username = altAdminUsername
+	a.Password = altAdminPassword
+	a.Permissions = []string{dataprovider.PermAdminAddUsers, dataprovider.PermAdminChangeUsers}
+	admin, _, err := httpdtest.AddAdmin(a, http.StatusCreated)
 	assert.NoError(t, err)
-	admin := getTestAdmin()
-	admin.Username = altAdminUsername
-	admin.Password = altAdminPassword
-	admin.Permissions = []string{dataprovider.PermAdminAddUsers, dataprovider.PermAdminDeleteUsers}
-	asJSON, err := json.Marshal(admin)
+
+	token, err := getJWTAPITokenFromTestServer(altAdminUsername, altAdminPassword)
+	assert.NoError(t, err)
+	req, err := http.NewRequest(http.MethodGet, schedulesPath, nil)
 	assert.NoError(t, err)
-	req, _ := http.NewRequest(http.MethodPost, adminPath, bytes.NewBuffer(asJSON))
 	setBearerForReq(req, token)
 	rr := executeRequest(req)
-	checkResponseCode(t, http.StatusCreated, rr)
+	checkResponseCode(t, http.StatusForbidden, rr)
 
-	altToken, err := getJWTAPITokenFromTestServer(altAdminUsername, altAdminPassword)
+	req, err = http.NewRequest(http.MethodPost, path.Join(schedulesPath, "missing", "run"), nil)
 	assert.NoError(t, err)
-	user := getTestUser()
-	userAsJSON := getUserAsJSON(t, user)
-	req, _ = http.NewRequest(http.MethodPost, userPath, bytes.NewBuffer(userAsJSON))
-	setBearerForReq(req, altToken)
-	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusCreated, rr)
-
-	req, _ = http.NewRequest(http.MethodPut, path.Join(userPath, user.Username), bytes.NewBuffer(userAsJSON))
-	setBearerForReq(req, altToken)
+	setBearerForReq(req, token)
 	rr = executeRequest(req)
 	checkResponseCode(t, http.StatusForbidden, rr)
 
-	pwd := make(map[string]string)
-	pwd["current_password"] = altAdminPassword
-	pwd["new_password"] = defaultTokenAuthPass
-	asJSON, err = json.Marshal(pwd)
+	webToken, err := getJWTWebTokenFromTestServer(altAdminUsername, altAdminPassword)
 	assert.NoError(t, err)
-	req, _ = http.NewRequest(http.MethodPut, adminPwdPath, bytes.NewBuffer(asJSON))
-	setBearerForReq(req, altToken)
-	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusOK, rr)
-
-	_, err = getJWTAPITokenFromTestServer(altAdminUsername, altAdminPassword)
-	assert.Error(t, err)
-
-	altToken, err = getJWTAPITokenFromTestServer(altAdminUsername, defaultTokenAuthPass)
+	req, err = http.NewRequest(http.MethodGet, webSchedulesPath, nil)
 	assert.NoError(t, err)
-	req, _ = http.NewRequest(http.MethodPut, adminPwdPath, bytes.NewBuffer(asJSON))
-	setBearerForReq(req, altToken)
+	setJWTCookieForReq(req, webToken)
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusBadRequest, rr) // current password does not match
+	checkResponseCode(t, http.StatusForbidden, rr)
 
-	req, _ = http.NewRequest(http.MethodDelete, path.Join(userPath, user.Username), nil)
-	setBearerForReq(req, altToken)
-	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusOK, rr)
+	admin.Permissions = append(admin.Permissions, dataprovider.PermAdminManageSchedules)
+	_, _, err = httpdtest.UpdateAdmin(admin, http.StatusOK)
+	assert.NoError(t, err)
+	// the permissions are stored inside the token
+	token, err = getJWTAPITokenFromTestServer(altAdminUsername, altAdminPassword)
+	assert.NoError(t, err)
 
-	req, _ = http.NewRequest(http.MethodDelete, path.Join(adminPath, altAdminUsername), nil)
+	req, err = http.NewRequest(http.MethodGet, schedulesPath, nil)
+	assert.NoError(t, err)
 	setBearerForReq(req, token)
 	rr = executeRequest(req)
 	checkResponseCode(t, http.StatusOK, rr)
-}
+	// admins with a scope cannot manage schedules
+	admin.Filters.Scope = dataprovider.AdminScope{
+		UsernamePrefixes: []string{"user"},
+	}
+	_, resp, err := httpdtest.UpdateAdmin(admin, http.StatusBadRequest)
+	assert.NoError(t, err)
+	assert.Contains(t, string(resp), "is not allowed for admins with a scope")
 
-func TestUpdateAdminMock(t *testing.T) {
-	token, err := getJWTAPITokenFromTestServer(defaultTokenAuthUser, defaultTokenAuthPass)
+	_, err = httpdtest.RemoveAdmin(admin, http.StatusOK)
 	assert.NoError(t, err)
-	_, err = getJWTAPITokenFromTestServer(altAdminUsername, defaultTokenAuthPass)
-	assert.Error(t, err)
-	admin := getTestAdmin()
-	admin.Username = altAdminUsername
-	admin.Permissions = []string{dataprovider.PermAdminManageAdmins}
-	asJSON, err := json.Marshal(admin)
+}
+
+func TestScheduleRunTasks(t *testing.T) {
+	u := getTestUser()
+	u.QuotaFiles = 100
+	user, _, err := httpdtest.AddUser(u, http.StatusCreated)
 	assert.NoError(t, err)
-	req, _ := http.NewRequest(http.MethodPost, adminPath, bytes.NewBuffer(asJSON))
-	setBearerForReq(req, token)
-	rr := executeRequest(req)
-	checkResponseCode(t, http.StatusCreated, rr)
-	_, err = getJWTAPITokenFromTestServer(altAdminUsername, defaultTokenAuthPass)
+	folderName := "schedule_folder"
+	folder := vfs.BaseVirtualFolder{
+		Name:       folderName,
+		MappedPath: filepath.Join(os.TempDir(), folderName),
+	}
+	folder, _, err = httpdtest.AddFolder(folder, http.StatusCreated)
 	assert.NoError(t, err)
 
-	req, _ = http.NewRequest(http.MethodPut, path.Join(adminPath, "abc"), bytes.NewBuffer(asJSON))
-	setBearerForReq(req, token)
-	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusNotFound, rr)
-	req, _ = http.NewRequest(http.MethodPut, path.Join(adminPath, altAdminUsername), bytes.NewBuffer([]byte("no json")))
-	setBearerForReq(req, token)
-	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusBadRequest, rr)
-	admin.Permissions = nil
-	asJSON, err = json.Marshal(admin)
-	assert.NoError(t, err)
-	req, _ = http.NewRequest(http.MethodPut, path.Join(adminPath, altAdminUsername), bytes.NewBuffer(asJSON))
-	setBearerForReq(req, token)
-	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusBadRequest, rr)
-	admin = getTestAdmin()
-	admin.Status = 0
-	asJSON, err = json.Marshal(admin)
+	userFilePath := filepath.Join(user.HomeDir, "dir", "file.txt")
+	err = createTestFile(userFilePath, 100)
 	assert.NoError(t, err)
-	req, _ = http.NewRequest(http.MethodPut, path.Join(adminPath, defaultTokenAuthUser), bytes.NewBuffer(asJSON))
-	setBearerForReq(req, token)
-	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusBadRequest, rr)
-	admin.Status = 1
-	admin.Permissions = []string{dataprovider.PermAdminAddUsers}
-	asJSON, err = json.Marshal(admin)
+	err = createTestFile(filepath.Join(folder.MappedPath, "file.dat"), 150)
 	assert.NoError(t, err)
-	req, _ = http.NewRequest(http.MethodPut, path.Join(adminPath, defaultTokenAuthUser), bytes.NewBuffer(asJSON))
-	setBearerForReq(req, token)
-	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusBadRequest, rr)
 
-	altToken, err := getJWTAPITokenFromTestServer(altAdminUsername, defaultTokenAuthPass)
+	waitForScheduleRun := func(name string) dataprovider.Schedule {
+		var schedule dataprovider.Schedule
+		assert.Eventually(t, func() bool {
+			schedule, _, err = httpdtest.GetScheduleByName(name, http.StatusOK)
+			if err != nil {
+				return false
+			}
+			return schedule.LastRunStatus != dataprovider.ScheduleRunStatusRunning
+		}, 2*time.Second, 50*time.Millisecond)
+		return schedule
+	}
+
+	schedule := dataprovider.Schedule{
+		Name:           "scan_schedule",
+		Status:         dataprovider.ScheduleStatusDisabled,
+		CronExpression: "@hourly",
+		Task:           dataprovider.ScheduleTaskQuotaScan,
+		Options: dataprovider.ScheduleOptions{
+			Usernames:   []string{user.Username, "missing_user"},
+			FolderNames: []string{folderName},
+		},
+	}
+	schedule, _, err = httpdtest.AddSchedule(schedule, http.StatusCreated)
 	assert.NoError(t, err)
-	admin.Password = "" // it must remain unchanged
-	admin.Permissions = []string{dataprovider.PermAdminManageAdmins, dataprovider.PermAdminCloseConnections}
-	asJSON, err = json.Marshal(admin)
+	// disabled schedules can be started on demand
+	_, err = httpdtest.RunSchedule(schedule.Name, http.StatusAccepted)
+	assert.NoError(t, err)
+	schedule = waitForScheduleRun(schedule.Name)
+	assert.Equal(t, dataprovider.ScheduleRunStatusFailed, schedule.LastRunStatus)
+	assert.Greater(t, schedule.LastRun, int64(0))
+	assert.Contains(t, schedule.LastRunError, "missing_user")
+	user, _, err = httpdtest.GetUserByUsername(user.Username, http.StatusOK)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, user.UsedQuotaFiles)
+	assert.Equal(t, int64(100), user.UsedQuotaSize)
+	folder, _, err = httpdtest.GetFolderByName(folderName, http.StatusOK)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, folder.UsedQuotaFiles)
+	assert.Equal(t, int64(150), folder.UsedQuotaSize)
+	// the last run details are preserved on update
+	lastRun := schedule.LastRun
+	schedule.Options.Usernames = []string{user.Username}
+	schedule, _, err = httpdtest.UpdateSchedule(schedule, http.StatusOK)
+	assert.NoError(t, err)
+	assert.Equal(t, lastRun, schedule.LastRun)
+	assert.Equal(t, dataprovider.ScheduleRunStatusFailed, schedule.LastRunStatus)
+	_, err = httpdtest.RunSchedule(schedule.Name, http.StatusAccepted)
+	assert.NoError(t, err)
+	schedule = waitForScheduleRun(schedule.Name)
+	assert.Equal(t, dataprovider.ScheduleRunStatusSuccess, schedule.LastRunStatus)
+	assert.Empty(t, schedule.LastRunError)
+	assert.GreaterOrEqual(t, schedule.LastRun, lastRun)
+	_, err = httpdtest.RemoveSchedule(schedule, http.StatusOK)
+	assert.NoError(t, err)
+
+	schedule = dataprovider.Schedule{
+		Name:           "retention_schedule",
+		Status:         dataprovider.ScheduleStatusEnabled,
+		CronExpression: "30 3 * * *",
+		Task:           dataprovider.ScheduleTaskRetentionCheck,
+		Options: dataprovider.ScheduleOptions{
+			Usernames: []string{user.Username},
+			Retention: []sdk.DataRetentionPolicy{
+				{
+					Path:            "/dir",
+					Retention:       24,
+					DeleteEmptyDirs: true,
+				},
+			},
+		},
+	}
+	schedule, _, err = httpdtest.AddSchedule(schedule, http.StatusCreated)
+	assert.NoError(t, err)
+	_, err = httpdtest.RunSchedule(schedule.Name, http.StatusAccepted)
+	assert.NoError(t, err)
+	schedule = waitForScheduleRun(schedule.Name)
+	assert.Equal(t, dataprovider.ScheduleRunStatusSuccess, schedule.LastRunStatus, schedule.LastRunError)
+	assert.FileExists(t, userFilePath)
+	err = os.Chtimes(userFilePath, time.Now().Add(-48*time.Hour), time.Now().Add(-48*time.Hour))
+	assert.NoError(t, err)
+	_, err = httpdtest.RunSchedule(schedule.Name, http.StatusAccepted)
+	assert.NoError(t, err)
+	schedule = waitForScheduleRun(schedule.Name)
+	assert.Equal(t, dataprovider.ScheduleRunStatusSuccess, schedule.LastRunStatus, schedule.LastRunError)
+	assert.NoFileExists(t, userFilePath)
+	assert.NoDirExists(t, filepath.Dir(userFilePath))
+	_, err = httpdtest.RemoveSchedule(schedule, http.StatusOK)
 	assert.NoError(t, err)
-	req, _ = http.NewRequest(http.MethodPut, path.Join(adminPath, altAdminUsername), bytes.NewBuffer(asJSON))
-	setBearerForReq(req, altToken)
-	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusOK, rr)
 
-	_, err = getJWTAPITokenFromTestServer(altAdminUsername, defaultTokenAuthPass)
+	schedule = dataprovider.Schedule{
+		Name:           "backup_schedule",
+		Status:         dataprovider.ScheduleStatusEnabled,
+		CronExpression: "0 4 * * sun",
+		Task:           dataprovider.ScheduleTaskBackup,
+	}
+	schedule, _, err = httpdtest.AddSchedule(schedule, http.StatusCreated)
+	assert.NoError(t, err)
+	_, err = httpdtest.RunSchedule(schedule.Name, http.StatusAccepted)
+	assert.NoError(t, err)
+	schedule = waitForScheduleRun(schedule.Name)
+	assert.Equal(t, dataprovider.ScheduleRunStatusSuccess, schedule.LastRunStatus, schedule.LastRunError)
+	backups, err := filepath.Glob(filepath.Join(backupsPath, "backup_backup_schedule_*.json"))
+	assert.NoError(t, err)
+	if assert.Len(t, backups, 1) {
+		content, err := os.ReadFile(backups[0])
+		assert.NoError(t, err)
+		var dump dataprovider.BackupData
+		err = json.Unmarshal(content, &dump)
+		assert.NoError(t, err)
+		found := false
+		for _, s := range dump.Schedules {
+			if s.Name == schedule.Name {
+				found = true
+			}
+		}
+		assert.True(t, found)
+		err = os.Remove(backups[0])
+		assert.NoError(t, err)
+	}
+	_, err = httpdtest.RemoveSchedule(schedule, http.StatusOK)
 	assert.NoError(t, err)
 
-	req, _ = http.NewRequest(http.MethodDelete, path.Join(adminPath, altAdminUsername), nil)
-	setBearerForReq(req, token)
-	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusOK, rr)
+	_, err = httpdtest.RemoveUser(user, http.StatusOK)
+	assert.NoError(t, err)
+	err = os.RemoveAll(user.GetHomeDir())
+	assert.NoError(t, err)
+	_, err = httpdtest.RemoveFolder(folder, http.StatusOK)
+	assert.NoError(t, err)
+	err = os.RemoveAll(folder.MappedPath)
+	assert.NoError(t, err)
 }
 
-func TestAdminLastLoginWithAPIKey(t *testing.T) {
-	admin := getTestAdmin()
-	admin.Username = altAdminUsername
-	admin.Filters.AllowAPIKeyAuth = true
-	admin, resp, err := httpdtest.AddAdmin(admin, http.StatusCreated)
-	assert.NoError(t, err, string(resp))
-	assert.Equal(t, int64(0), admin.LastLogin)
-
-	apiKey := dataprovider.APIKey{
-		Name:  "admin API key",
-		Scope: dataprovider.APIKeyScopeAdmin,
-		Admin: altAdminUsername,
+func TestDataRetentionPolicies(t *testing.T) {
+	folderName := "retention_folder"
+	f := vfs.BaseVirtualFolder{
+		Name:       folderName,
+		MappedPath: filepath.Join(os.TempDir(), folderName),
+		DataRetention: []sdk.DataRetentionPolicy{
+			{
+				Path:      "relative",
+				Retention: 24,
+			},
+		},
 	}
-
-	apiKey, resp, err = httpdtest.AddAPIKey(apiKey, http.StatusCreated)
-	assert.NoError(t, err, string(resp))
-
-	req, err := http.NewRequest(http.MethodGet, versionPath, nil)
+	_, resp, err := httpdtest.AddFolder(f, http.StatusBadRequest)
 	assert.NoError(t, err)
-	setAPIKeyForReq(req, apiKey.Key, admin.Username)
-	rr := executeRequest(req)
-	checkResponseCode(t, http.StatusOK, rr)
-
-	admin, _, err = httpdtest.GetAdminByUsername(altAdminUsername, http.StatusOK)
+	assert.Contains(t, string(resp), "it must be an absolute virtual path")
+	f.DataRetention = []sdk.DataRetentionPolicy{
+		{
+			Path:      "/",
+			Retention: 0,
+		},
+		{
+			Path:      "/old",
+			Retention: 24,
+		},
+	}
+	folder, _, err := httpdtest.AddFolder(f, http.StatusCreated)
 	assert.NoError(t, err)
-	assert.Greater(t, admin.LastLogin, int64(0))
+	assert.Len(t, folder.DataRetention, 2)
 
-	_, err = httpdtest.RemoveAdmin(admin, http.StatusOK)
+	g := dataprovider.Group{
+		Name: "retention_group",
+	}
+	g.UserSettings.Filters.DataRetention = []sdk.DataRetentionPolicy{
+		{
+			Path:      "/dir",
+			Retention: 48,
+		},
+		{
+			Path:      "/dir",
+			Retention: 12,
+		},
+	}
+	_, resp, err = httpdtest.AddGroup(g, http.StatusBadRequest)
+	assert.NoError(t, err)
+	assert.Contains(t, string(resp), "duplicated retention path")
+	g.UserSettings.Filters.DataRetention[1].Path = "/grp"
+	group, _, err := httpdtest.AddGroup(g, http.StatusCreated)
 	assert.NoError(t, err)
-}
 
-func TestUserLastLoginWithAPIKey(t *testing.T) {
-	user := getTestUser()
-	user.Filters.AllowAPIKeyAuth = true
-	user, resp, err := httpdtest.AddUser(user, http.StatusCreated)
-	assert.NoError(t, err, string(resp))
-	assert.Equal(t, int64(0), user.LastLogin)
+	u := getTestUser()
+	u.Filters.DataRetention = []sdk.DataRetentionPolicy{
+		{
+			Path:      "/dir",
+			Retention: -1,
+		},
+	}
+	_, resp, err = httpdtest.AddUser(u, http.StatusBadRequest)
+	assert.NoError(t, err)
+	assert.Contains(t, string(resp), "invalid retention")
+	u.Filters.DataRetention[0].Retention = 24
+	u.Filters.DataRetention[0].DeleteEmptyDirs = true
+	u.VirtualFolders = append(u.VirtualFolders, vfs.VirtualFolder{
+		BaseVirtualFolder: folder,
+		VirtualPath:       "/vdir",
+	})
+	u.Groups = []string{group.Name}
+	user, _, err := httpdtest.AddUser(u, http.StatusCreated)
+	assert.NoError(t, err)
 
-	apiKey := dataprovider.APIKey{
-		Name:  "user API key",
-		Scope: dataprovider.APIKeyScopeUser,
-		User:  user.Username,
+	userWithSettings, err := dataprovider.GetUserWithGroupSettings(user.Username)
+	assert.NoError(t, err)
+	retention := userWithSettings.GetDataRetention()
+	if assert.Len(t, retention, 4) {
+		policies := make(map[string]int)
+		for _, r := range retention {
+			policies[r.Path] = r.Retention
+		}
+		assert.Equal(t, 24, policies["/dir"])
+		assert.Equal(t, 12, policies["/grp"])
+		assert.Equal(t, 0, policies["/vdir"])
+		assert.Equal(t, 24, policies["/vdir/old"])
 	}
 
-	apiKey, resp, err = httpdtest.AddAPIKey(apiKey, http.StatusCreated)
-	assert.NoError(t, err, string(resp))
+	oldTime := time.Now().Add(-36 * time.Hour)
+	userFile := filepath.Join(user.HomeDir, "dir", "file.txt")
+	groupFile := filepath.Join(user.HomeDir, "grp", "file.txt")
+	folderOldFile := filepath.Join(folder.MappedPath, "old", "file.dat")
+	folderFile := filepath.Join(folder.MappedPath, "file.dat")
+	for _, p := range []string{userFile, groupFile, folderOldFile, folderFile} {
+		err = createTestFile(p, 100)
+		assert.NoError(t, err)
+		err = os.Chtimes(p, oldTime, oldTime)
+		assert.NoError(t, err)
+	}
+	err = os.Chtimes(groupFile, time.Now().Add(-6*time.Hour), time.Now().Add(-6*time.Hour))
+	assert.NoError(t, err)
 
-	req, err := http.NewRequest(http.MethodGet, userDirsPath, nil)
+	schedule := dataprovider.Schedule{
+		Name:           "stored_retention_schedule",
+		Status:         dataprovider.ScheduleStatusEnabled,
+		CronExpression: "30 3 * * *",
+		Task:           dataprovider.ScheduleTaskRetentionCheck,
+		Options: dataprovider.ScheduleOptions{
+			// users without data retention policies are skipped
+			Usernames: []string{user.Username, defaultTokenAuthUser},
+		},
+	}
+	schedule, _, err = httpdtest.AddSchedule(schedule, http.StatusCreated)
+	assert.NoError(t, err)
+	_, err = httpdtest.RunSchedule(schedule.Name, http.StatusAccepted)
+	assert.NoError(t, err)
+	assert.Eventually(t, func() bool {
+		schedule, _, err = httpdtest.GetScheduleByName(schedule.Name, http.StatusOK)
+		if err != nil {
+			return false
+		}
+		return schedule.LastRunStatus != dataprovider.ScheduleRunStatusRunning
+	}, 2*time.Second, 50*time.Millisecond)
+	assert.Equal(t, dataprovider.ScheduleRunStatusFailed, schedule.LastRunStatus)
+	assert.Contains(t, schedule.LastRunError, defaultTokenAuthUser)
+	assert.NotContains(t, schedule.LastRunError, user.Username+"\"")
+	// the user policy takes precedence over the group one
+	assert.NoFileExists(t, userFile)
+	assert.NoDirExists(t, filepath.Dir(userFile))
+	assert.FileExists(t, groupFile)
+	assert.NoFileExists(t, folderOldFile)
+	assert.FileExists(t, folderFile)
+	_, err = httpdtest.RemoveSchedule(schedule, http.StatusOK)
 	assert.NoError(t, err)
-	setAPIKeyForReq(req, apiKey.Key, "")
-	rr := executeRequest(req)
-	checkResponseCode(t, http.StatusOK, rr)
 
-	user, _, err = httpdtest.GetUserByUsername(user.Username, http.StatusOK)
+	// an empty list starts a retention check using the stored policies
+	err = os.Chtimes(groupFile, time.Now().Add(-24*time.Hour), time.Now().Add(-24*time.Hour))
 	assert.NoError(t, err)
-	assert.Greater(t, user.LastLogin, int64(0))
+	_, err = httpdtest.StartRetentionCheck(user.Username, []common.FolderRetention{}, http.StatusAccepted)
+	assert.NoError(t, err)
+	assert.Eventually(t, func() bool {
+		return len(common.RetentionChecks.Get()) == 0
+	}, 1000*time.Millisecond, 50*time.Millisecond)
+	assert.NoFileExists(t, groupFile)
+	assert.FileExists(t, folderFile)
 
 	_, err = httpdtest.RemoveUser(user, http.StatusOK)
 	assert.NoError(t, err)
 	err = os.RemoveAll(user.GetHomeDir())
 	assert.NoError(t, err)
+	_, err = httpdtest.RemoveGroup(group, http.StatusOK)
+	assert.NoError(t, err)
+	_, err = httpdtest.RemoveFolder(folder, http.StatusOK)
+	assert.NoError(t, err)
+	err = os.RemoveAll(folder.MappedPath)
+	assert.NoError(t, err)
 }
 
-func TestAdminHandlingWithAPIKeys(t *testing.T) {
-	sysAdmin, _, err := httpdtest.GetAdminByUsername(defaultTokenAuthUser, http.StatusOK)
+func TestMFAErrors(t *testing.T) {
+	user, _, err := httpdtest.AddUser(getTestUser(), http.StatusCreated)
 	assert.NoError(t, err)
-	sysAdmin.Filters.AllowAPIKeyAuth = true
-	sysAdmin, _, err = httpdtest.UpdateAdmin(sysAdmin, http.StatusOK)
+	assert.False(t, user.Filters.TOTPConfig.Enabled)
+	userToken, err := getJWTAPIUserTokenFromTestServer(defaultUsername, defaultPassword)
+	assert.NoError(t, err)
+	adminToken, err := getJWTAPITokenFromTestServer(defaultTokenAuthUser, defaultTokenAuthPass)
 	assert.NoError(t, err)
 
-	apiKey := dataprovider.APIKey{
-		Name:  "test admin API key",
-		Scope: dataprovider.APIKeyScopeAdmin,
-		Admin: defaultTokenAuthUser,
+	// invalid config name
+	totpReq := generateTOTPRequest{
+		ConfigName: "invalid config name",
 	}
-
-	apiKey, resp, err := httpdtest.AddAPIKey(apiKey, http.StatusCreated)
-	assert.NoError(t, err, string(resp))
-
-	admin := getTestAdmin()
-	admin.Username = altAdminUsername
-	asJSON, err := json.Marshal(admin)
+	asJSON, err := json.Marshal(totpReq)
 	assert.NoError(t, err)
-	req, err := http.NewRequest(http.MethodPost, adminPath, bytes.NewBuffer(asJSON))
+	req, err := http.NewRequest(http.MethodPost, userTOTPGeneratePath, bytes.NewBuffer(asJSON))
 	assert.NoError(t, err)
-	setAPIKeyForReq(req, apiKey.Key, "")
+	setBearerForReq(req, userToken)
 	rr := executeRequest(req)
-	checkResponseCode(t, http.StatusCreated, rr)
-	_, err = getJWTAPITokenFromTestServer(altAdminUsername, defaultTokenAuthPass)
+	checkResponseCode(t, http.StatusBadRequest, rr)
+	// invalid JSON
+	invalidJSON := []byte("not a JSON")
+	req, err = http.NewRequest(http.MethodPost, userTOTPGeneratePath, bytes.NewBuffer(invalidJSON))
 	assert.NoError(t, err)
+	setBearerForReq(req, userToken)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusBadRequest, rr)
 
-	admin.Filters.AllowAPIKeyAuth = true
-	asJSON, err = json.Marshal(admin)
-	assert.NoError(t, err)
-	req, err = http.NewRequest(http.MethodPut, path.Join(adminPath, altAdminUsername), bytes.NewBuffer(asJSON))
+	req, err = http.NewRequest(http.MethodPost, userTOTPSavePath, bytes.NewBuffer(invalidJSON))
 	assert.NoError(t, err)
-	setAPIKeyForReq(req, apiKey.Key, "")
+	setBearerForReq(req, userToken)
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusOK, rr)
+	checkResponseCode(t, http.StatusBadRequest, rr)
 
-	req, err = http.NewRequest(http.MethodGet, path.Join(adminPath, altAdminUsername), nil)
+	req, err = http.NewRequest(http.MethodPost, adminTOTPSavePath, bytes.NewBuffer(invalidJSON))
 	assert.NoError(t, err)
-	setAPIKeyForReq(req, apiKey.Key, "")
+	setBearerForReq(req, adminToken)
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusOK, rr)
-	var adminGet dataprovider.Admin
-	err = json.Unmarshal(rr.Body.Bytes(), &adminGet)
-	assert.NoError(t, err)
-	assert.True(t, adminGet.Filters.AllowAPIKeyAuth)
+	checkResponseCode(t, http.StatusBadRequest, rr)
 
-	req, err = http.NewRequest(http.MethodPut, path.Join(adminPath, defaultTokenAuthUser), bytes.NewBuffer(asJSON))
+	req, err = http.NewRequest(http.MethodPost, adminTOTPValidatePath, bytes.NewBuffer(invalidJSON))
 	assert.NoError(t, err)
-	setAPIKeyForReq(req, apiKey.Key, "")
+	setBearerForReq(req, adminToken)
 	rr = executeRequest(req)
 	checkResponseCode(t, http.StatusBadRequest, rr)
-	assert.Contains(t, rr.Body.String(), "updating the admin impersonated with an API key is not allowed")
-	// changing the password for the impersonated admin is not allowed
-	pwd := make(map[string]string)
-	pwd["current_password"] = defaultTokenAuthPass
-	pwd["new_password"] = altAdminPassword
-	asJSON, err = json.Marshal(pwd)
+	// invalid TOTP config name
+	userTOTPConfig := sdk.TOTPConfig{
+		Enabled:    true,
+		ConfigName: "missing name",
+		Secret:     kms.NewPlainSecret(xid.New().String()),
+		Protocols:  []string{common.ProtocolSSH},
+	}
+	asJSON, err = json.Marshal(userTOTPConfig)
 	assert.NoError(t, err)
-	req, _ = http.NewRequest(http.MethodPut, adminPwdPath, bytes.NewBuffer(asJSON))
-	setAPIKeyForReq(req, apiKey.Key, "")
+	req, err = http.NewRequest(http.MethodPost, userTOTPSavePath, bytes.NewBuffer(asJSON))
+	assert.NoError(t, err)
+	setBearerForReq(req, userToken)
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusForbidden, rr)
-	assert.Contains(t, rr.Body.String(), "API key authentication is not allowed")
+	checkResponseCode(t, http.StatusBadRequest, rr)
+	assert.Contains(t, rr.Body.String(), "totp: config name")
+	// invalid TOTP secret
+	userTOTPConfig = sdk.TOTPConfig{
+		Enabled:    true,
+		ConfigName: mfa.GetAvailableTOTPConfigNames()[0],
+		Secret:     nil,
+		Protocols:  []string{common.ProtocolSSH},
+	}
+	asJSON, err = json.Marshal(userTOTPConfig)
+	assert.NoError(t, err)
+	req, err = http.NewRequest(http.MethodPost, userTOTPSavePath, bytes.NewBuffer(asJSON))
+	assert.NoError(t, err)
+	setBearerForReq(req, userToken)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusBadRequest, rr)
+	assert.Contains(t, rr.Body.String(), "totp: secret is mandatory")
+	// no protocol
+	userTOTPConfig = sdk.TOTPConfig{
+		Enabled:    true,
+		ConfigName: mfa.GetAvailableTOTPConfigNames()[0],
+		Secret:     kms.NewPlainSecret(xid.New().String()),
+		Protocols:  nil,
+	}
+	asJSON, err = json.Marshal(userTOTPConfig)
+	assert.NoError(t, err)
+	req, err = http.NewRequest(http.MethodPost, userTOTPSavePath, bytes.NewBuffer(asJSON))
+	assert.NoError(t, err)
+	setBearerForReq(req, userToken)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusBadRequest, rr)
+	assert.Contains(t, rr.Body.String(), "totp: specify at least one protocol")
+	// invalid protocol
+	userTOTPConfig = sdk.TOTPConfig{
+		Enabled:    true,
+		ConfigName: mfa.GetAvailableTOTPConfigNames()[0],
+		Secret:     kms.NewPlainSecret(xid.New().String()),
+		Protocols:  []string{common.ProtocolWebDAV},
+	}
+	asJSON, err = json.Marshal(userTOTPConfig)
+	assert.NoError(t, err)
+	req, err = http.NewRequest(http.MethodPost, userTOTPSavePath, bytes.NewBuffer(asJSON))
+	assert.NoError(t, err)
+	setBearerForReq(req, userToken)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusBadRequest, rr)
+	assert.Contains(t, rr.Body.String(), "totp: invalid protocol")
 
-	req, err = http.NewRequest(http.MethodDelete, path.Join(adminPath, defaultTokenAuthUser), nil)
+	adminTOTPConfig := dataprovider.TOTPConfig{
+		Enabled:    true,
+		ConfigName: "",
+		Secret:     kms.NewPlainSecret("secret"),
+	}
+	asJSON, err = json.Marshal(adminTOTPConfig)
 	assert.NoError(t, err)
-	setAPIKeyForReq(req, apiKey.Key, "")
+	req, err = http.NewRequest(http.MethodPost, adminTOTPSavePath, bytes.NewBuffer(asJSON))
+	assert.NoError(t, err)
+	setBearerForReq(req, adminToken)
 	rr = executeRequest(req)
 	checkResponseCode(t, http.StatusBadRequest, rr)
-	assert.Contains(t, rr.Body.String(), "you cannot delete yourself")
+	assert.Contains(t, rr.Body.String(), "totp: config name is mandatory")
 
-	req, err = http.NewRequest(http.MethodDelete, path.Join(adminPath, altAdminUsername), nil)
+	adminTOTPConfig = dataprovider.TOTPConfig{
+		Enabled:    true,
+		ConfigName: mfa.GetAvailableTOTPConfigNames()[0],
+		Secret:     nil,
+	}
+	asJSON, err = json.Marshal(adminTOTPConfig)
 	assert.NoError(t, err)
-	setAPIKeyForReq(req, apiKey.Key, "")
+	req, err = http.NewRequest(http.MethodPost, adminTOTPSavePath, bytes.NewBuffer(asJSON))
+	assert.NoError(t, err)
+	setBearerForReq(req, adminToken)
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusOK, rr)
+	checkResponseCode(t, http.StatusBadRequest, rr)
+	assert.Contains(t, rr.Body.String(), "totp: secret is mandatory")
 
-	_, err = httpdtest.RemoveAPIKey(apiKey, http.StatusOK)
+	// invalid TOTP secret status
+	userTOTPConfig = sdk.TOTPConfig{
+		Enabled:    true,
+		ConfigName: mfa.GetAvailableTOTPConfigNames()[0],
+		Secret:     kms.NewSecret(kms.SecretStatusRedacted, "", "", ""),
+		Protocols:  []string{common.ProtocolSSH},
+	}
+	asJSON, err = json.Marshal(userTOTPConfig)
+	assert.NoError(t, err)
+	req, err = http.NewRequest(http.MethodPost, userTOTPSavePath, bytes.NewBuffer(asJSON))
 	assert.NoError(t, err)
+	setBearerForReq(req, userToken)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusBadRequest, rr)
+	// previous secret will be preserved and we have no secret saved
+	assert.Contains(t, rr.Body.String(), "totp: secret is mandatory")
 
-	dbAdmin, err := dataprovider.AdminExists(defaultTokenAuthUser)
+	req, err = http.NewRequest(http.MethodPost, adminTOTPSavePath, bytes.NewBuffer(asJSON))
 	assert.NoError(t, err)
-	dbAdmin.Filters.AllowAPIKeyAuth = false
-	err = dataprovider.UpdateAdmin(&dbAdmin, "", "")
+	setBearerForReq(req, adminToken)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusBadRequest, rr)
+	assert.Contains(t, rr.Body.String(), "totp: secret is mandatory")
+
+	_, err = httpdtest.RemoveUser(user, http.StatusOK)
 	assert.NoError(t, err)
-	sysAdmin, _, err = httpdtest.GetAdminByUsername(defaultTokenAuthUser, http.StatusOK)
+	err = os.RemoveAll(user.GetHomeDir())
 	assert.NoError(t, err)
-	assert.False(t, sysAdmin.Filters.AllowAPIKeyAuth)
 }
 
-func TestUserHandlingWithAPIKey(t *testing.T) {
-	admin := getTestAdmin()
-	admin.Username = altAdminUsername
-	admin.Filters.AllowAPIKeyAuth = true
-	admin, _, err := httpdtest.AddAdmin(admin, http.StatusCreated)
+func TestMFAInvalidSecret(t *testing.T) {
+	user, _, err := httpdtest.AddUser(getTestUser(), http.StatusCreated)
 	assert.NoError(t, err)
 
-	apiKey := dataprovider.APIKey{
-		Name:  "test admin API key",
-		Scope: dataprovider.APIKeyScopeAdmin,
-		Admin: admin.Username,
-	}
+	userToken, err := getJWTAPIUserTokenFromTestServer(defaultUsername, defaultPassword)
+	assert.NoError(t, err)
 
-	apiKey, _, err = httpdtest.AddAPIKey(apiKey, http.StatusCreated)
+	user.Password = defaultPassword
+	user.Filters.TOTPConfig = sdk.TOTPConfig{
+		Enabled:    true,
+		ConfigName: mfa.GetAvailableTOTPConfigNames()[0],
+		Secret:     kms.NewSecret(kms.SecretStatusSecretBox, "payload", "key", user.Username),
+		Protocols:  []string{common.ProtocolSSH, common.ProtocolHTTP},
+	}
+	user.Filters.RecoveryCodes = append(user.Filters.RecoveryCodes, sdk.RecoveryCode{
+		Used:   false,
+		Secret: kms.NewSecret(kms.SecretStatusSecretBox, "payload", "key", user.Username),
+	})
+	err = dataprovider.UpdateUser(&user, "", "")
 	assert.NoError(t, err)
 
-	user := getTestUser()
-	userAsJSON := getUserAsJSON(t, user)
-	req, err := http.NewRequest(http.MethodPost, userPath, bytes.NewBuffer(userAsJSON))
+	req, err := http.NewRequest(http.MethodGet, user2FARecoveryCodesPath, nil)
 	assert.NoError(t, err)
-	setAPIKeyForReq(req, apiKey.Key, "")
+	setBearerForReq(req, userToken)
 	rr := executeRequest(req)
-	checkResponseCode(t, http.StatusCreated, rr)
+	checkResponseCode(t, http.StatusInternalServerError, rr)
+	assert.Contains(t, rr.Body.String(), "Unable to decrypt recovery codes")
 
-	user.Filters.DisableFsChecks = true
-	user.Description = "desc"
-	userAsJSON = getUserAsJSON(t, user)
-	req, err = http.NewRequest(http.MethodPut, path.Join(userPath, user.Username), bytes.NewBuffer(userAsJSON))
+	csrfToken, err := getCSRFToken(httpBaseURL + webClientLoginPath)
 	assert.NoError(t, err)
-	setAPIKeyForReq(req, apiKey.Key, "")
+	form := getLoginForm(defaultUsername, defaultPassword, csrfToken)
+	req, err = http.NewRequest(http.MethodPost, webClientLoginPath, bytes.NewBuffer([]byte(form.Encode())))
+	assert.NoError(t, err)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusOK, rr)
+	assert.Equal(t, http.StatusFound, rr.Code)
+	assert.Equal(t, webClientTwoFactorPath, rr.Header().Get("Location"))
+	cookie, err := getCookieFromResponse(rr)
+	assert.NoError(t, err)
+	form = make(url.Values)
+	form.Set(csrfFormToken, csrfToken)
+	form.Set("passcode", "123456")
+	req, err = http.NewRequest(http.MethodPost, webClientTwoFactorPath, bytes.NewBuffer([]byte(form.Encode())))
+	assert.NoError(t, err)
+	setJWTCookieForReq(req, cookie)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rr = executeRequest(req)
+	assert.Equal(t, http.StatusInternalServerError, rr.Code)
 
-	req, err = http.NewRequest(http.MethodGet, path.Join(userPath, user.Username), nil)
+	form = make(url.Values)
+	form.Set(csrfFormToken, csrfToken)
+	form.Set("recovery_code", "RC-123456")
+	req, err = http.NewRequest(http.MethodPost, webClientTwoFactorRecoveryPath, bytes.NewBuffer([]byte(form.Encode())))
 	assert.NoError(t, err)
-	setAPIKeyForReq(req, apiKey.Key, "")
+	setJWTCookieForReq(req, cookie)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusOK, rr)
-	var updatedUser dataprovider.User
-	err = json.Unmarshal(rr.Body.Bytes(), &updatedUser)
+	assert.Equal(t, http.StatusInternalServerError, rr.Code)
+
+	req, err = http.NewRequest(http.MethodGet, fmt.Sprintf("%v%v", httpBaseURL, userTokenPath), nil)
+	assert.NoError(t, err)
+	req.Header.Set("X-SFTPGO-OTP", "authcode")
+	req.SetBasicAuth(defaultUsername, defaultPassword)
+	resp, err := httpclient.GetHTTPClient().Do(req)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+	err = resp.Body.Close()
 	assert.NoError(t, err)
-	assert.True(t, updatedUser.Filters.DisableFsChecks)
-	assert.Equal(t, user.Description, updatedUser.Description)
 
-	req, err = http.NewRequest(http.MethodDelete, path.Join(userPath, user.Username), nil)
+	_, err = httpdtest.RemoveUser(user, http.StatusOK)
 	assert.NoError(t, err)
-	setAPIKeyForReq(req, apiKey.Key, "")
-	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusOK, rr)
 	err = os.RemoveAll(user.GetHomeDir())
 	assert.NoError(t, err)
 
-	_, err = httpdtest.RemoveAdmin(admin, http.StatusOK)
+	admin := getTestAdmin()
+	admin.Username = altAdminUsername
+	admin.Password = altAdminPassword
+	admin, _, err = httpdtest.AddAdmin(admin, http.StatusCreated)
 	assert.NoError(t, err)
-	_, _, err = httpdtest.GetAPIKeyByID(apiKey.KeyID, http.StatusNotFound)
+
+	admin.Password = altAdminPassword
+	admin.Filters.TOTPConfig = dataprovider.TOTPConfig{
+		Enabled:    true,
+		ConfigName: mfa.GetAvailableTOTPConfigNames()[0],
+		Secret:     kms.NewSecret(kms.SecretStatusSecretBox, "payload", "key", user.Username),
+	}
+	admin.Filters.RecoveryCodes = append(user.Filters.RecoveryCodes, sdk.RecoveryCode{
+		Used:   false,
+		Secret: kms.NewSecret(kms.SecretStatusSecretBox, "payload", "key", user.Username),
+	})
+	err = dataprovider.UpdateAdmin(&admin, "", "")
 	assert.NoError(t, err)
-}
 
-func TestUpdateUserMock(t *testing.T) {
-	token, err := getJWTAPITokenFromTestServer(defaultTokenAuthUser, defaultTokenAuthPass)
+	csrfToken, err = getCSRFToken(httpBaseURL + webLoginPath)
 	assert.NoError(t, err)
-	user := getTestUser()
-	userAsJSON := getUserAsJSON(t, user)
-	req, _ := http.NewRequest(http.MethodPost, userPath, bytes.NewBuffer(userAsJSON))
-	setBearerForReq(req, token)
-	rr := executeRequest(req)
-	checkResponseCode(t, http.StatusCreated, rr)
-	err = render.DecodeJSON(rr.Body, &user)
+	form = getLoginForm(altAdminUsername, altAdminPassword, csrfToken)
+	req, err = http.NewRequest(http.MethodPost, webLoginPath, bytes.NewBuffer([]byte(form.Encode())))
 	assert.NoError(t, err)
-	// permissions should not change if empty or nil
-	permissions := user.Permissions
-	user.Permissions = make(map[string][]string)
-	userAsJSON = getUserAsJSON(t, user)
-	req, _ = http.NewRequest(http.MethodPut, userPath+"/"+user.Username, bytes.NewBuffer(userAsJSON))
-	setBearerForReq(req, token)
-	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusOK, rr)
-	req, _ = http.NewRequest(http.MethodGet, userPath+"/"+user.Username, nil)
-	setBearerForReq(req, token)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusOK, rr)
-	var updatedUser dataprovider.User
-	err = render.DecodeJSON(rr.Body, &updatedUser)
+	assert.Equal(t, http.StatusFound, rr.Code)
+	assert.Equal(t, webAdminTwoFactorPath, rr.Header().Get("Location"))
+	cookie, err = getCookieFromResponse(rr)
 	assert.NoError(t, err)
-	for dir, perms := range permissions {
-		if actualPerms, ok := updatedUser.Permissions[dir]; ok {
-			for _, v := range actualPerms {
-				assert.True(t, util.IsStringInSlice(v, perms))
-			}
-		} else {
-			assert.Fail(t, "Permissions directories mismatch")
-		}
-	}
-	req, _ = http.NewRequest(http.MethodDelete, path.Join(userPath, user.Username), nil)
-	setBearerForReq(req, token)
+	form = make(url.Values)
+	form.Set(csrfFormToken, csrfToken)
+	form.Set("passcode", "123456")
+	req, err = http.NewRequest(http.MethodPost, webAdminTwoFactorPath, bytes.NewBuffer([]byte(form.Encode())))
+	assert.NoError(t, err)
+	setJWTCookieForReq(req, cookie)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusOK, rr)
-}
+	assert.Equal(t, http.StatusInternalServerError, rr.Code)
 
-func TestUpdateUserQuotaUsageMock(t *testing.T) {
-	token, err := getJWTAPITokenFromTestServer(defaultTokenAuthUser, defaultTokenAuthPass)
+	form = make(url.Values)
+	form.Set(csrfFormToken, csrfToken)
+	form.Set("recovery_code", "RC-123456")
+	req, err = http.NewRequest(http.MethodPost, webAdminTwoFactorRecoveryPath, bytes.NewBuffer([]byte(form.Encode())))
 	assert.NoError(t, err)
-	var user dataprovider.User
+	setJWTCookieForReq(req, cookie)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rr = executeRequest(req)
+	assert.Equal(t, http.StatusInternalServerError, rr.Code)
+
+	req, err = http.NewRequest(http.MethodGet, fmt.Sprintf("%v%v", httpBaseURL, tokenPath), nil)
+	assert.NoError(t, err)
+	req.Header.Set("X-SFTPGO-OTP", "auth-code")
+	req.SetBasicAuth(altAdminUsername, altAdminPassword)
+	resp, err = httpclient.GetHTTPClient().Do(req)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+	err = resp.Body.Close()
+	assert.NoError(t, err)
+
+	_, err = httpdtest.RemoveAdmin(admin, http.StatusOK)
+	assert.NoError(t, err)
+}
+
+func TestWebUserTOTP(t *testing.T) {
 	u := getTestUser()
-	usedQuotaFiles := 1
-	usedQuotaSize := int64(65535)
-	u.UsedQuotaFiles = usedQuotaFiles
-	u.UsedQuotaSize = usedQuotaSize
-	u.QuotaFiles = 100
-	userAsJSON := getUserAsJSON(t, u)
-	req, _ := http.NewRequest(http.MethodPost, userPath, bytes.NewBuffer(userAsJSON))
+	// TOTPConfig will be ignored on add
+	u.Filters.TOTPConfig = sdk.TOTPConfig{
+		Enabled:    true,
+		ConfigName: "",
+		Secret:     kms.NewEmptySecret(),
+		Protocols:  []string{common.ProtocolSSH},
+	}
+	user, _, err := httpdtest.AddUser(u, http.StatusCreated)
+	assert.NoError(t, err)
+	assert.False(t, user.Filters.TOTPConfig.Enabled)
+	token, err := getJWTAPIUserTokenFromTestServer(defaultUsername, defaultPassword)
+	assert.NoError(t, err)
+
+	req, err := http.NewRequest(http.MethodGet, userTOTPConfigsPath, nil)
+	assert.NoError(t, err)
 	setBearerForReq(req, token)
 	rr := executeRequest(req)
-	checkResponseCode(t, http.StatusCreated, rr)
-	err = render.DecodeJSON(rr.Body, &user)
+	checkResponseCode(t, http.StatusOK, rr)
+	var configs []mfa.TOTPConfig
+	err = json.Unmarshal(rr.Body.Bytes(), &configs)
+	assert.NoError(t, err, rr.Body.String())
+	assert.Len(t, configs, len(mfa.GetAvailableTOTPConfigs()))
+	totpConfig := configs[0]
+	totpReq := generateTOTPRequest{
+		ConfigName: totpConfig.Name,
+	}
+	asJSON, err := json.Marshal(totpReq)
+	assert.NoError(t, err)
+	req, err = http.NewRequest(http.MethodPost, userTOTPGeneratePath, bytes.NewBuffer(asJSON))
 	assert.NoError(t, err)
-	req, _ = http.NewRequest(http.MethodPut, path.Join(quotasBasePath, "users", u.Username, "usage"), bytes.NewBuffer(userAsJSON))
 	setBearerForReq(req, token)
 	rr = executeRequest(req)
 	checkResponseCode(t, http.StatusOK, rr)
-	req, _ = http.NewRequest(http.MethodPut, updateUsedQuotaCompatPath, bytes.NewBuffer(userAsJSON))
+	var totpGenResp generateTOTPResponse
+	err = json.Unmarshal(rr.Body.Bytes(), &totpGenResp)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, totpGenResp.Secret)
+	assert.NotEmpty(t, totpGenResp.QRCode)
+
+	passcode, err := generateTOTPPasscode(totpGenResp.Secret)
+	assert.NoError(t, err)
+	validateReq := validateTOTPRequest{
+		ConfigName: totpGenResp.ConfigName,
+		Passcode:   passcode,
+		Secret:     totpGenResp.Secret,
+	}
+	asJSON, err = json.Marshal(validateReq)
+	assert.NoError(t, err)
+	req, err = http.NewRequest(http.MethodPost, userTOTPValidatePath, bytes.NewBuffer(asJSON))
+	assert.NoError(t, err)
 	setBearerForReq(req, token)
 	rr = executeRequest(req)
 	checkResponseCode(t, http.StatusOK, rr)
-	req, _ = http.NewRequest(http.MethodGet, path.Join(userPath, user.Username), nil)
+	// the same passcode cannot be reused
+	req, err = http.NewRequest(http.MethodPost, userTOTPValidatePath, bytes.NewBuffer(asJSON))
+	assert.NoError(t, err)
 	setBearerForReq(req, token)
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusOK, rr)
-	err = render.DecodeJSON(rr.Body, &user)
+	checkResponseCode(t, http.StatusBadRequest, rr)
+	assert.Contains(t, rr.Body.String(), "this passcode was already used")
+
+	userTOTPConfig := sdk.TOTPConfig{
+		Enabled:    true,
+		ConfigName: totpGenResp.ConfigName,
+		Secret:     kms.NewPlainSecret(totpGenResp.Secret),
+		Protocols:  []string{common.ProtocolSSH},
+	}
+	asJSON, err = json.Marshal(userTOTPConfig)
+	assert.NoError(t, err)
+	req, err = http.NewRequest(http.MethodPost, userTOTPSavePath, bytes.NewBuffer(asJSON))
 	assert.NoError(t, err)
-	assert.Equal(t, usedQuotaFiles, user.UsedQuotaFiles)
-	assert.Equal(t, usedQuotaSize, user.UsedQuotaSize)
-	// now update only quota size
-	u.UsedQuotaFiles = 0
-	userAsJSON = getUserAsJSON(t, u)
-	req, _ = http.NewRequest(http.MethodPut, path.Join(quotasBasePath, "users", u.Username, "usage")+"?mode=add", bytes.NewBuffer(userAsJSON))
 	setBearerForReq(req, token)
 	rr = executeRequest(req)
 	checkResponseCode(t, http.StatusOK, rr)
-	req, _ = http.NewRequest(http.MethodGet, path.Join(userPath, user.Username), nil)
+
+	user, _, err = httpdtest.GetUserByUsername(user.Username, http.StatusOK)
+	assert.NoError(t, err)
+	totpCfg := user.Filters.TOTPConfig
+	assert.True(t, totpCfg.Enabled)
+	secretPayload := totpCfg.Secret.GetPayload()
+	assert.Equal(t, totpGenResp.ConfigName, totpCfg.ConfigName)
+	assert.Empty(t, totpCfg.Secret.GetKey())
+	assert.Empty(t, totpCfg.Secret.GetAdditionalData())
+	assert.NotEmpty(t, secretPayload)
+	assert.Equal(t, kms.SecretStatusSecretBox, totpCfg.Secret.GetStatus())
+	assert.Len(t, totpCfg.Protocols, 1)
+	assert.Contains(t, totpCfg.Protocols, common.ProtocolSSH)
+	// update protocols only
+	userTOTPConfig = sdk.TOTPConfig{
+		Protocols: []string{common.ProtocolSSH, common.ProtocolFTP},
+		Secret:    kms.NewEmptySecret(),
+	}
+	asJSON, err = json.Marshal(userTOTPConfig)
+	assert.NoError(t, err)
+	req, err = http.NewRequest(http.MethodPost, userTOTPSavePath, bytes.NewBuffer(asJSON))
+	assert.NoError(t, err)
 	setBearerForReq(req, token)
 	rr = executeRequest(req)
 	checkResponseCode(t, http.StatusOK, rr)
-	err = render.DecodeJSON(rr.Body, &user)
+
+	// update the user, TOTP should not be affected
+	user.Filters.TOTPConfig = sdk.TOTPConfig{
+		Enabled: false,
+		Secret:  kms.NewEmptySecret(),
+	}
+	_, _, err = httpdtest.UpdateUser(user, http.StatusOK, "")
+	assert.NoError(t, err)
+
+	user, _, err = httpdtest.GetUserByUsername(user.Username, http.StatusOK)
+	assert.NoError(t, err)
+	assert.True(t, user.Filters.TOTPConfig.Enabled)
+	assert.Equal(t, totpCfg.ConfigName, user.Filters.TOTPConfig.ConfigName)
+	assert.Empty(t, user.Filters.TOTPConfig.Secret.GetKey())
+	assert.Empty(t, user.Filters.TOTPConfig.Secret.GetAdditionalData())
+	assert.Equal(t, secretPayload, user.Filters.TOTPConfig.Secret.GetPayload())
+	assert.Equal(t, kms.SecretStatusSecretBox, user.Filters.TOTPConfig.Secret.GetStatus())
+	assert.Len(t, user.Filters.TOTPConfig.Protocols, 2)
+	assert.Contains(t, user.Filters.TOTPConfig.Protocols, common.ProtocolSSH)
+	assert.Contains(t, user.Filters.TOTPConfig.Protocols, common.ProtocolFTP)
+
+	req, err = http.NewRequest(http.MethodGet, user2FARecoveryCodesPath, nil)
 	assert.NoError(t, err)
-	assert.Equal(t, usedQuotaFiles, user.UsedQuotaFiles)
-	assert.Equal(t, usedQuotaSize*2, user.UsedQuotaSize)
-	// only quota files
-	u.UsedQuotaFiles = usedQuotaFiles
-	u.UsedQuotaSize = 0
-	userAsJSON = getUserAsJSON(t, u)
-	req, _ = http.NewRequest(http.MethodPut, path.Join(quotasBasePath, "users", u.Username, "usage")+"?mode=add", bytes.NewBuffer(userAsJSON))
 	setBearerForReq(req, token)
 	rr = executeRequest(req)
 	checkResponseCode(t, http.StatusOK, rr)
-	req, _ = http.NewRequest(http.MethodGet, path.Join(userPath, user.Username), nil)
+	var recCodes []recoveryCode
+	err = json.Unmarshal(rr.Body.Bytes(), &recCodes)
+	assert.NoError(t, err)
+	assert.Len(t, recCodes, 12)
+	// regenerate recovery codes
+	req, err = http.NewRequest(http.MethodPost, user2FARecoveryCodesPath, nil)
+	assert.NoError(t, err)
 	setBearerForReq(req, token)
 	rr = executeRequest(req)
 	checkResponseCode(t, http.StatusOK, rr)
-	err = render.DecodeJSON(rr.Body, &user)
+	// check that recovery codes are different
+	req, err = http.NewRequest(http.MethodGet, user2FARecoveryCodesPath, nil)
 	assert.NoError(t, err)
-	assert.Equal(t, usedQuotaFiles*2, user.UsedQuotaFiles)
-	assert.Equal(t, usedQuotaSize*2, user.UsedQuotaSize)
-	req, _ = http.NewRequest(http.MethodPut, updateUsedQuotaCompatPath, bytes.NewBuffer([]byte("string")))
 	setBearerForReq(req, token)
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusBadRequest, rr)
-	req, _ = http.NewRequest(http.MethodPut, path.Join(quotasBasePath, "users", u.Username, "usage"), bytes.NewBuffer([]byte("string")))
+	checkResponseCode(t, http.StatusOK, rr)
+	var newRecCodes []recoveryCode
+	err = json.Unmarshal(rr.Body.Bytes(), &newRecCodes)
+	assert.NoError(t, err)
+	assert.Len(t, newRecCodes, 12)
+	assert.NotEqual(t, recCodes, newRecCodes)
+	// disable 2FA, the update user API should not work
+	adminToken, err := getJWTAPITokenFromTestServer(defaultTokenAuthUser, defaultTokenAuthPass)
+	assert.NoError(t, err)
+	user.Filters.TOTPConfig.Enabled = false
+	user.Filters.RecoveryCodes = nil
+	user, _, err = httpdtest.UpdateUser(user, http.StatusOK, "")
+	assert.NoError(t, err)
+	assert.Equal(t, defaultUsername, user.Username)
+	assert.True(t, user.Filters.TOTPConfig.Enabled)
+	assert.Len(t, user.Filters.RecoveryCodes, 12)
+	// use the dedicated API
+	req, err = http.NewRequest(http.MethodPut, userPath+"/"+defaultUsername+"/2fa/disable", nil)
+	assert.NoError(t, err)
+	setBearerForReq(req, adminToken)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
+	user, _, err = httpdtest.GetUserByUsername(defaultUsername, http.StatusOK)
+	assert.NoError(t, err)
+	assert.False(t, user.Filters.TOTPConfig.Enabled)
+	assert.Len(t, user.Filters.RecoveryCodes, 0)
+
+	_, err = httpdtest.RemoveUser(user, http.StatusOK)
+	assert.NoError(t, err)
+	err = os.RemoveAll(user.GetHomeDir())
+	assert.NoError(t, err)
+
+	req, err = http.NewRequest(http.MethodPut, userPath+"/"+defaultUsername+"/2fa/disable", nil)
+	assert.NoError(t, err)
+	setBearerForReq(req, adminToken)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusNotFound, rr)
+
+	req, err = http.NewRequest(http.MethodGet, user2FARecoveryCodesPath, nil)
+	assert.NoError(t, err)
 	setBearerForReq(req, token)
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusBadRequest, rr)
-	assert.True(t, common.QuotaScans.AddUserQuotaScan(user.Username))
-	req, _ = http.NewRequest(http.MethodPut, path.Join(quotasBasePath, "users", u.Username, "usage"), bytes.NewBuffer(userAsJSON))
+	checkResponseCode(t, http.StatusNotFound, rr)
+
+	req, err = http.NewRequest(http.MethodPost, user2FARecoveryCodesPath, nil)
+	assert.NoError(t, err)
 	setBearerForReq(req, token)
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusConflict, rr)
-	assert.True(t, common.QuotaScans.RemoveUserQuotaScan(user.Username))
-	req, _ = http.NewRequest(http.MethodDelete, path.Join(userPath, user.Username), nil)
+	checkResponseCode(t, http.StatusNotFound, rr)
+
+	req, err = http.NewRequest(http.MethodPost, userTOTPSavePath, bytes.NewBuffer(asJSON))
+	assert.NoError(t, err)
 	setBearerForReq(req, token)
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusOK, rr)
+	checkResponseCode(t, http.StatusNotFound, rr)
 }
 
-func TestUserPermissionsMock(t *testing.T) {
-	token, err := getJWTAPITokenFromTestServer(defaultTokenAuthUser, defaultTokenAuthPass)
+func TestWebAPIChangeUserProfileMock(t *testing.T) {
+	user, _, err := httpdtest.AddUser(getTestUser(), http.StatusCreated)
+	assert.NoError(t, err)
+	assert.False(t, user.Filters.AllowAPIKeyAuth)
+	token, err := getJWTAPIUserTokenFromTestServer(defaultUsername, defaultPassword)
+	assert.NoError(t, err)
+	// invalid json
+	req, err := http.NewRequest(http.MethodPut, userProfilePath, bytes.NewBuffer([]byte("{")))
 	assert.NoError(t, err)
-	user := getTestUser()
-	user.Permissions = make(map[string][]string)
-	user.Permissions["/somedir"] = []string{dataprovider.PermAny}
-	userAsJSON := getUserAsJSON(t, user)
-	req, _ := http.NewRequest(http.MethodPost, userPath, bytes.NewBuffer(userAsJSON))
 	setBearerForReq(req, token)
 	rr := executeRequest(req)
 	checkResponseCode(t, http.StatusBadRequest, rr)
-	user.Permissions = make(map[string][]string)
-	user.Permissions["/"] = []string{dataprovider.PermAny}
-	user.Permissions[".."] = []string{dataprovider.PermAny}
-	userAsJSON = getUserAsJSON(t, user)
-	req, _ = http.NewRequest(http.MethodPost, userPath, bytes.NewBuffer(userAsJSON))
-	setBearerForReq(req, token)
-	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusBadRequest, rr)
-	user.Permissions = make(map[string][]string)
-	user.Permissions["/"] = []string{dataprovider.PermAny}
-	userAsJSON = getUserAsJSON(t, user)
-	req, _ = http.NewRequest(http.MethodPost, userPath, bytes.NewBuffer(userAsJSON))
+
+	email := "userapi@example.com"
+	description := "user API description"
+	profileReq := make(map[string]interface{})
+	profileReq["allow_api_key_auth"] = true
+	profileReq["email"] = email
+	profileReq["description"] = description
+	profileReq["public_keys"] = []string{testPubKey, testPubKey1}
+	asJSON, err := json.Marshal(profileReq)
+	assert.NoError(t, err)
+	req, err = http.NewRequest(http.MethodPut, userProfilePath, bytes.NewBuffer(asJSON))
+	assert.NoError(t, err)
 	setBearerForReq(req, token)
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusCreated, rr)
-	err = render.DecodeJSON(rr.Body, &user)
+	checkResponseCode(t, http.StatusOK, rr)
+
+	profileReq = make(map[string]interface{})
+	req, err = http.NewRequest(http.MethodGet, userProfilePath, nil)
 	assert.NoError(t, err)
-	user.Permissions["/somedir"] = []string{"invalid"}
-	userAsJSON = getUserAsJSON(t, user)
-	req, _ = http.NewRequest(http.MethodPut, path.Join(userPath, user.Username), bytes.NewBuffer(userAsJSON))
 	setBearerForReq(req, token)
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusBadRequest, rr)
-	delete(user.Permissions, "/somedir")
-	user.Permissions["/somedir/.."] = []string{dataprovider.PermAny}
-	userAsJSON = getUserAsJSON(t, user)
-	req, _ = http.NewRequest(http.MethodPut, path.Join(userPath, user.Username), bytes.NewBuffer(userAsJSON))
+	checkResponseCode(t, http.StatusOK, rr)
+	err = json.Unmarshal(rr.Body.Bytes(), &profileReq)
+	assert.NoError(t, err)
+	assert.Equal(t, email, profileReq["email"].(string))
+	assert.Equal(t, description, profileReq["description"].(string))
+	assert.True(t, profileReq["allow_api_key_auth"].(bool))
+	assert.Len(t, profileReq["public_keys"].([]interface{}), 2)
+	// set an invalid email
+	profileReq = make(map[string]interface{})
+	profileReq["email"] = "notavalidemail"
+	asJSON, err = json.Marshal(profileReq)
+	assert.NoError(t, err)
+	req, err = http.NewRequest(http.MethodPut, userProfilePath, bytes.NewBuffer(asJSON))
+	assert.NoError(t, err)
 	setBearerForReq(req, token)
 	rr = executeRequest(req)
 	checkResponseCode(t, http.StatusBadRequest, rr)
-	delete(user.Permissions, "/somedir/..")
-	user.Permissions["not_abs_path"] = []string{dataprovider.PermAny}
-	userAsJSON = getUserAsJSON(t, user)
-	req, _ = http.NewRequest(http.MethodPut, path.Join(userPath, user.Username), bytes.NewBuffer(userAsJSON))
+	assert.Contains(t, rr.Body.String(), "Validation error: email")
+	// set an invalid public key
+	profileReq = make(map[string]interface{})
+	profileReq["public_keys"] = []string{"not a public key"}
+	asJSON, err = json.Marshal(profileReq)
+	assert.NoError(t, err)
+	req, err = http.NewRequest(http.MethodPut, userProfilePath, bytes.NewBuffer(asJSON))
+	assert.NoError(t, err)
 	setBearerForReq(req, token)
 	rr = executeRequest(req)
 	checkResponseCode(t, http.StatusBadRequest, rr)
-	delete(user.Permissions, "not_abs_path")
-	user.Permissions["/somedir/../otherdir/"] = []string{dataprovider.PermListItems}
-	userAsJSON = getUserAsJSON(t, user)
-	req, _ = http.NewRequest(http.MethodPut, path.Join(userPath, user.Username), bytes.NewBuffer(userAsJSON))
-	setBearerForReq(req, token)
-	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusOK, rr)
-	req, _ = http.NewRequest(http.MethodGet, path.Join(userPath, user.Username), nil)
+	assert.Contains(t, rr.Body.String(), "Validation error: could not parse key")
+
+	user.Filters.WebClient = []string{sdk.WebClientAPIKeyAuthChangeDisabled, sdk.WebClientPubKeyChangeDisabled}
+	user.Email = email
+	user.Description = description
+	user.Filters.AllowAPIKeyAuth = true
+	_, _, err = httpdtest.UpdateUser(user, http.StatusOK, "")
+	assert.NoError(t, err)
+	token, err = getJWTAPIUserTokenFromTestServer(defaultUsername, defaultPassword)
+	assert.NoError(t, err)
+
+	profileReq = make(map[string]interface{})
+	profileReq["allow_api_key_auth"] = false
+	profileReq["email"] = email
+	profileReq["description"] = description + "_mod"
+	profileReq["public_keys"] = []string{testPubKey}
+	asJSON, err = json.Marshal(profileReq)
+	assert.NoError(t, err)
+	req, err = http.NewRequest(http.MethodPut, userProfilePath, bytes.NewBuffer(asJSON))
+	assert.NoError(t, err)
 	setBearerForReq(req, token)
 	rr = executeRequest(req)
 	checkResponseCode(t, http.StatusOK, rr)
-	var updatedUser dataprovider.User
-	err = render.DecodeJSON(rr.Body, &updatedUser)
+	assert.Contains(t, rr.Body.String(), "Profile updated")
+	// check that api key auth and public keys were not changed
+	profileReq = make(map[string]interface{})
+	req, err = http.NewRequest(http.MethodGet, userProfilePath, nil)
 	assert.NoError(t, err)
-	if val, ok := updatedUser.Permissions["/otherdir"]; ok {
-		assert.True(t, util.IsStringInSlice(dataprovider.PermListItems, val))
-		assert.Equal(t, 1, len(val))
-	} else {
-		assert.Fail(t, "expected dir not found in permissions")
-	}
-	req, _ = http.NewRequest(http.MethodDelete, path.Join(userPath, user.Username), nil)
 	setBearerForReq(req, token)
 	rr = executeRequest(req)
 	checkResponseCode(t, http.StatusOK, rr)
-}
+	err = json.Unmarshal(rr.Body.Bytes(), &profileReq)
+	assert.NoError(t, err)
+	assert.Equal(t, email, profileReq["email"].(string))
+	assert.Equal(t, description+"_mod", profileReq["description"].(string))
+	assert.True(t, profileReq["allow_api_key_auth"].(bool))
+	assert.Len(t, profileReq["public_keys"].([]interface{}), 2)
 
-func TestUpdateUserInvalidJsonMock(t *testing.T) {
-	token, err := getJWTAPITokenFromTestServer(defaultTokenAuthUser, defaultTokenAuthPass)
+	user.Filters.WebClient = []string{sdk.WebClientAPIKeyAuthChangeDisabled, sdk.WebClientInfoChangeDisabled}
+	user.Description = description + "_mod"
+	_, _, err = httpdtest.UpdateUser(user, http.StatusOK, "")
 	assert.NoError(t, err)
-	user := getTestUser()
-	userAsJSON := getUserAsJSON(t, user)
-	req, _ := http.NewRequest(http.MethodPost, userPath, bytes.NewBuffer(userAsJSON))
-	setBearerForReq(req, token)
-	rr := executeRequest(req)
-	checkResponseCode(t, http.StatusCreated, rr)
-	err = render.DecodeJSON(rr.Body, &user)
+	token, err = getJWTAPIUserTokenFromTestServer(defaultUsername, defaultPassword)
+	assert.NoError(t, err)
+
+	profileReq = make(map[string]interface{})
+	profileReq["allow_api_key_auth"] = false
+	profileReq["email"] = "newemail@apiuser.com"
+	profileReq["description"] = description
+	profileReq["public_keys"] = []string{testPubKey}
+	asJSON, err = json.Marshal(profileReq)
+	assert.NoError(t, err)
+	req, err = http.NewRequest(http.MethodPut, userProfilePath, bytes.NewBuffer(asJSON))
 	assert.NoError(t, err)
-	req, _ = http.NewRequest(http.MethodPut, path.Join(userPath, user.Username), bytes.NewBuffer([]byte("Invalid json")))
 	setBearerForReq(req, token)
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusBadRequest, rr)
-	req, _ = http.NewRequest(http.MethodDelete, path.Join(userPath, user.Username), nil)
+	checkResponseCode(t, http.StatusOK, rr)
+	profileReq = make(map[string]interface{})
+	req, err = http.NewRequest(http.MethodGet, userProfilePath, nil)
+	assert.NoError(t, err)
 	setBearerForReq(req, token)
 	rr = executeRequest(req)
 	checkResponseCode(t, http.StatusOK, rr)
-}
-
-func TestUpdateUserInvalidParamsMock(t *testing.T) {
-	token, err := getJWTAPITokenFromTestServer(defaultTokenAuthUser, defaultTokenAuthPass)
+	err = json.Unmarshal(rr.Body.Bytes(), &profileReq)
 	assert.NoError(t, err)
-	user := getTestUser()
-	userAsJSON := getUserAsJSON(t, user)
-	req, _ := http.NewRequest(http.MethodPost, userPath, bytes.NewBuffer(userAsJSON))
-	setBearerForReq(req, token)
-	rr := executeRequest(req)
-	checkResponseCode(t, http.StatusCreated, rr)
-	err = render.DecodeJSON(rr.Body, &user)
+	assert.Equal(t, email, profileReq["email"].(string))
+	assert.Equal(t, description+"_mod", profileReq["description"].(string))
+	assert.True(t, profileReq["allow_api_key_auth"].(bool))
+	assert.Len(t, profileReq["public_keys"].([]interface{}), 1)
+	// finally disable all profile permissions
+	user.Filters.WebClient = []string{sdk.WebClientAPIKeyAuthChangeDisabled, sdk.WebClientInfoChangeDisabled,
+		sdk.WebClientPubKeyChangeDisabled}
+	_, _, err = httpdtest.UpdateUser(user, http.StatusOK, "")
+	assert.NoError(t, err)
+	req, err = http.NewRequest(http.MethodPut, userProfilePath, bytes.NewBuffer(asJSON))
 	assert.NoError(t, err)
-	user.HomeDir = ""
-	userAsJSON = getUserAsJSON(t, user)
-	req, _ = http.NewRequest(http.MethodPut, path.Join(userPath, user.Username), bytes.NewBuffer(userAsJSON))
-	setBearerForReq(req, token)
-	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusBadRequest, rr)
-	userID := user.ID
-	user.ID = 0
-	user.CreatedAt = 0
-	userAsJSON = getUserAsJSON(t, user)
-	req, _ = http.NewRequest(http.MethodPut, path.Join(userPath, user.Username), bytes.NewBuffer(userAsJSON))
 	setBearerForReq(req, token)
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusBadRequest, rr)
-	user.ID = userID
-	req, _ = http.NewRequest(http.MethodPut, userPath+"/0", bytes.NewBuffer(userAsJSON))
+	checkResponseCode(t, http.StatusForbidden, rr)
+	assert.Contains(t, rr.Body.String(), "You are not allowed to change anything")
+
+	_, err = httpdtest.RemoveUser(user, http.StatusOK)
+	assert.NoError(t, err)
+	err = os.RemoveAll(user.GetHomeDir())
+	assert.NoError(t, err)
+
+	req, err = http.NewRequest(http.MethodGet, userProfilePath, nil)
+	assert.NoError(t, err)
 	setBearerForReq(req, token)
 	rr = executeRequest(req)
 	checkResponseCode(t, http.StatusNotFound, rr)
-	req, _ = http.NewRequest(http.MethodDelete, path.Join(userPath, user.Username), nil)
+
+	req, err = http.NewRequest(http.MethodPut, userProfilePath, bytes.NewBuffer(asJSON))
+	assert.NoError(t, err)
 	setBearerForReq(req, token)
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusOK, rr)
+	checkResponseCode(t, http.StatusNotFound, rr)
 }
 
-func TestGetAdminsMock(t *testing.T) {
-	token, err := getJWTAPITokenFromTestServer(defaultTokenAuthUser, defaultTokenAuthPass)
+func TestWebAPIChangeUserPwdMock(t *testing.T) {
+	user, _, err := httpdtest.AddUser(getTestUser(), http.StatusCreated)
 	assert.NoError(t, err)
-	admin := getTestAdmin()
-	admin.Username = altAdminUsername
-	asJSON, err := json.Marshal(admin)
+	token, err := getJWTAPIUserTokenFromTestServer(defaultUsername, defaultPassword)
+	assert.NoError(t, err)
+	// invalid json
+	req, err := http.NewRequest(http.MethodPut, userPwdPath, bytes.NewBuffer([]byte("{")))
 	assert.NoError(t, err)
-	req, _ := http.NewRequest(http.MethodPost, adminPath, bytes.NewBuffer(asJSON))
 	setBearerForReq(req, token)
 	rr := executeRequest(req)
-	checkResponseCode(t, http.StatusCreated, rr)
+	checkResponseCode(t, http.StatusBadRequest, rr)
 
-	req, _ = http.NewRequest(http.MethodGet, adminPath+"?limit=510&offset=0&order=ASC", nil)
-	setBearerForReq(req, token)
-	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusOK, rr)
-	var admins []dataprovider.Admin
-	err = render.DecodeJSON(rr.Body, &admins)
+	pwd := make(map[string]string)
+	pwd["current_password"] = defaultPassword
+	pwd["new_password"] = defaultPassword
+	asJSON, err := json.Marshal(pwd)
+	assert.NoError(t, err)
+	req, err = http.NewRequest(http.MethodPut, userPwdPath, bytes.NewBuffer(asJSON))
 	assert.NoError(t, err)
-	assert.GreaterOrEqual(t, len(admins), 1)
-	firtAdmin := admins[0].Username
-	req, _ = http.NewRequest(http.MethodGet, adminPath+"?limit=510&offset=0&order=DESC", nil)
 	setBearerForReq(req, token)
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusOK, rr)
-	admins = nil
-	err = render.DecodeJSON(rr.Body, &admins)
-	assert.NoError(t, err)
-	assert.GreaterOrEqual(t, len(admins), 1)
-	assert.NotEqual(t, firtAdmin, admins[0].Username)
+	checkResponseCode(t, http.StatusBadRequest, rr)
+	assert.Contains(t, rr.Body.String(), "the new password must be different from the current one")
 
-	req, _ = http.NewRequest(http.MethodGet, adminPath+"?limit=510&offset=1&order=ASC", nil)
+	pwd["new_password"] = altAdminPassword
+	asJSON, err = json.Marshal(pwd)
+	assert.NoError(t, err)
+	req, err = http.NewRequest(http.MethodPut, userPwdPath, bytes.NewBuffer(asJSON))
+	assert.NoError(t, err)
 	setBearerForReq(req, token)
 	rr = executeRequest(req)
 	checkResponseCode(t, http.StatusOK, rr)
-	admins = nil
-	err = render.DecodeJSON(rr.Body, &admins)
+	_, err = getJWTAPIUserTokenFromTestServer(defaultUsername, defaultPassword)
+	assert.Error(t, err)
+	token, err = getJWTAPIUserTokenFromTestServer(defaultUsername, altAdminPassword)
 	assert.NoError(t, err)
-	assert.GreaterOrEqual(t, len(admins), 1)
-	assert.NotEqual(t, firtAdmin, admins[0].Username)
+	assert.NotEmpty(t, token)
 
-	req, _ = http.NewRequest(http.MethodGet, adminPath+"?limit=a&offset=0&order=ASC", nil)
-	setBearerForReq(req, token)
-	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusBadRequest, rr)
-	req, _ = http.NewRequest(http.MethodGet, adminPath+"?limit=1&offset=a&order=ASC", nil)
-	setBearerForReq(req, token)
-	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusBadRequest, rr)
-	req, _ = http.NewRequest(http.MethodGet, adminPath+"?limit=1&offset=0&order=ASCa", nil)
-	setBearerForReq(req, token)
-	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusBadRequest, rr)
+	// remove the change password permission
+	user.Filters.WebClient = []string{sdk.WebClientPasswordChangeDisabled}
+	user, _, err = httpdtest.UpdateUser(user, http.StatusOK, "")
+	assert.NoError(t, err)
+	assert.Len(t, user.Filters.WebClient, 1)
+	assert.Contains(t, user.Filters.WebClient, sdk.WebClientPasswordChangeDisabled)
 
-	req, _ = http.NewRequest(http.MethodDelete, path.Join(adminPath, admin.Username), nil)
+	token, err = getJWTAPIUserTokenFromTestServer(defaultUsername, altAdminPassword)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, token)
+
+	pwd["current_password"] = altAdminPassword
+	pwd["new_password"] = defaultPassword
+	asJSON, err = json.Marshal(pwd)
+	assert.NoError(t, err)
+	req, err = http.NewRequest(http.MethodPut, userPwdPath, bytes.NewBuffer(asJSON))
+	assert.NoError(t, err)
 	setBearerForReq(req, token)
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusOK, rr)
+	checkResponseCode(t, http.StatusForbidden, rr)
+
+	_, err = httpdtest.RemoveUser(user, http.StatusOK)
+	assert.NoError(t, err)
+	err = os.RemoveAll(user.GetHomeDir())
+	assert.NoError(t, err)
 }
 
-func TestGetUsersMock(t *testing.T) {
-	token, err := getJWTAPITokenFromTestServer(defaultTokenAuthUser, defaultTokenAuthPass)
+func TestLoginInvalidPasswordMock(t *testing.T) {
+	_, err := getJWTAPITokenFromTestServer(defaultTokenAuthUser, defaultTokenAuthPass+"1")
+	assert.Error(t, err)
+	// now a login with no credentials
+	req, _ := http.NewRequest(http.MethodGet, "/api/v2/token", nil)
+	rr := executeRequest(req)
+	assert.Equal(t, http.StatusUnauthorized, rr.Code)
+}
+
+func TestWebAPIChangeAdminProfileMock(t *testing.T) {
+	admin := getTestAdmin()
+	admin.Username = altAdminUsername
+	admin.Password = altAdminPassword
+	admin, _, err := httpdtest.AddAdmin(admin, http.StatusCreated)
+	assert.NoError(t, err)
+	assert.False(t, admin.Filters.AllowAPIKeyAuth)
+
+	token, err := getJWTAPITokenFromTestServer(altAdminUsername, altAdminPassword)
+	assert.NoError(t, err)
+	// invalid json
+	req, err := http.NewRequest(http.MethodPut, adminProfilePath, bytes.NewBuffer([]byte("{")))
 	assert.NoError(t, err)
-	user := getTestUser()
-	userAsJSON := getUserAsJSON(t, user)
-	req, _ := http.NewRequest(http.MethodPost, userPath, bytes.NewBuffer(userAsJSON))
 	setBearerForReq(req, token)
 	rr := executeRequest(req)
-	checkResponseCode(t, http.StatusCreated, rr)
-	err = render.DecodeJSON(rr.Body, &user)
+	checkResponseCode(t, http.StatusBadRequest, rr)
+
+	email := "adminapi@example.com"
+	description := "admin API description"
+	profileReq := make(map[string]interface{})
+	profileReq["allow_api_key_auth"] = true
+	profileReq["email"] = email
+	profileReq["description"] = description
+	asJSON, err := json.Marshal(profileReq)
+	assert.NoError(t, err)
+	req, err = http.NewRequest(http.MethodPut, adminProfilePath, bytes.NewBuffer(asJSON))
 	assert.NoError(t, err)
-	req, _ = http.NewRequest(http.MethodGet, userPath+"?limit=510&offset=0&order=ASC", nil)
 	setBearerForReq(req, token)
 	rr = executeRequest(req)
 	checkResponseCode(t, http.StatusOK, rr)
-	var users []dataprovider.User
-	err = render.DecodeJSON(rr.Body, &users)
+	assert.Contains(t, rr.Body.String(), "Profile updated")
+
+	profileReq = make(map[string]interface{})
+	req, err = http.NewRequest(http.MethodGet, adminProfilePath, nil)
 	assert.NoError(t, err)
-	assert.GreaterOrEqual(t, len(users), 1)
-	req, _ = http.NewRequest(http.MethodGet, userPath+"?limit=a&offset=0&order=ASC", nil)
-	setBearerForReq(req, token)
-	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusBadRequest, rr)
-	req, _ = http.NewRequest(http.MethodGet, userPath+"?limit=1&offset=a&order=ASC", nil)
 	setBearerForReq(req, token)
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusBadRequest, rr)
-	req, _ = http.NewRequest(http.MethodGet, userPath+"?limit=1&offset=0&order=ASCa", nil)
+	checkResponseCode(t, http.StatusOK, rr)
+	err = json.Unmarshal(rr.Body.Bytes(), &profileReq)
+	assert.NoError(t, err)
+	assert.Equal(t, email, profileReq["email"].(string))
+	assert.Equal(t, description, profileReq["description"].(string))
+	assert.True(t, profileReq["allow_api_key_auth"].(bool))
+	// set an invalid email
+	profileReq["email"] = "admin_invalid_email"
+	asJSON, err = json.Marshal(profileReq)
+	assert.NoError(t, err)
+	req, err = http.NewRequest(http.MethodPut, adminProfilePath, bytes.NewBuffer(asJSON))
+	assert.NoError(t, err)
 	setBearerForReq(req, token)
 	rr = executeRequest(req)
 	checkResponseCode(t, http.StatusBadRequest, rr)
+	assert.Contains(t, rr.Body.String(), "Validation error: email")
 
-	req, _ = http.NewRequest(http.MethodDelete, path.Join(userPath, user.Username), nil)
+	_, err = httpdtest.RemoveAdmin(admin, http.StatusOK)
+	assert.NoError(t, err)
+
+	req, err = http.NewRequest(http.MethodGet, adminProfilePath, nil)
+	assert.NoError(t, err)
 	setBearerForReq(req, token)
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusOK, rr)
-}
+	checkResponseCode(t, http.StatusNotFound, rr)
 
-func TestDeleteUserInvalidParamsMock(t *testing.T) {
-	token, err := getJWTAPITokenFromTestServer(defaultTokenAuthUser, defaultTokenAuthPass)
+	req, err = http.NewRequest(http.MethodPut, adminProfilePath, bytes.NewBuffer(asJSON))
 	assert.NoError(t, err)
-	req, _ := http.NewRequest(http.MethodDelete, userPath+"/0", nil)
 	setBearerForReq(req, token)
-	rr := executeRequest(req)
+	rr = executeRequest(req)
 	checkResponseCode(t, http.StatusNotFound, rr)
 }
 
-func TestGetQuotaScansMock(t *testing.T) {
+func TestChangeAdminPwdMock(t *testing.T) {
 	token, err := getJWTAPITokenFromTestServer(defaultTokenAuthUser, defaultTokenAuthPass)
 	assert.NoError(t, err)
-	req, err := http.NewRequest(http.MethodGet, quotaScanPath, nil)
+	admin := getTestAdmin()
+	admin.Username = altAdminUsername
+	admin.Password = altAdminPassword
+	admin.Permissions = []string{dataprovider.PermAdminAddUsers, dataprovider.PermAdminDeleteUsers}
+	asJSON, err := json.Marshal(admin)
 	assert.NoError(t, err)
+	req, _ := http.NewRequest(http.MethodPost, adminPath, bytes.NewBuffer(asJSON))
 	setBearerForReq(req, token)
 	rr := executeRequest(req)
-	checkResponseCode(t, http.StatusOK, rr)
-}
+	checkResponseCode(t, http.StatusCreated, rr)
 
-func TestStartQuotaScanMock(t *testing.T) {
-	token, err := getJWTAPITokenFromTestServer(defaultTokenAuthUser, defaultTokenAuthPass)
+	altToken, err := getJWTAPITokenFromTestServer(altAdminUsername, altAdminPassword)
 	assert.NoError(t, err)
 	user := getTestUser()
 	userAsJSON := getUserAsJSON(t, user)
-	req, _ := http.NewRequest(http.MethodPost, userPath, bytes.NewBuffer(userAsJSON))
-	setBearerForReq(req, token)
-	rr := executeRequest(req)
-	checkResponseCode(t, http.StatusCreated, rr)
-	err = render.DecodeJSON(rr.Body, &user)
-	assert.NoError(t, err)
-	_, err = os.Stat(user.HomeDir)
-	if err == nil {
-		err = os.Remove(user.HomeDir)
-		assert.NoError(t, err)
-	}
-	// simulate a duplicate quota scan
-	common.QuotaScans.AddUserQuotaScan(user.Username)
-	req, _ = http.NewRequest(http.MethodPost, path.Join(quotasBasePath, "users", user.Username, "scan"), nil)
-	setBearerForReq(req, token)
-	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusConflict, rr)
-	assert.True(t, common.QuotaScans.RemoveUserQuotaScan(user.Username))
-
-	req, _ = http.NewRequest(http.MethodPost, path.Join(quotasBasePath, "users", user.Username, "scan"), nil)
-	setBearerForReq(req, token)
+	req, _ = http.NewRequest(http.MethodPost, userPath, bytes.NewBuffer(userAsJSON))
+	setBearerForReq(req, altToken)
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusAccepted, rr)
-
-	waitForUsersQuotaScan(t, token)
+	checkResponseCode(t, http.StatusCreated, rr)
 
-	_, err = os.Stat(user.HomeDir)
-	if err != nil && os.IsNotExist(err) {
-		err = os.MkdirAll(user.HomeDir, os.ModePerm)
-		assert.NoError(t, err)
-	}
-	req, _ = http.NewRequest(http.MethodPost, path.Join(quotasBasePath, "users", user.Username, "scan"), nil)
-	setBearerForReq(req, token)
+	req, _ = http.NewRequest(http.MethodPut, path.Join(userPath, user.Username), bytes.NewBuffer(userAsJSON))
+	setBearerForReq(req, altToken)
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusAccepted, rr)
-
-	waitForUsersQuotaScan(t, token)
+	checkResponseCode(t, http.StatusForbidden, rr)
 
-	req, _ = http.NewRequest(http.MethodPost, path.Join(quotasBasePath, "users", user.Username, "scan"), nil)
-	setBearerForReq(req, token)
+	pwd := make(map[string]string)
+	pwd["current_password"] = altAdminPassword
+	pwd["new_password"] = defaultTokenAuthPass
+	asJSON, err = json.Marshal(pwd)
+	assert.NoError(t, err)
+	req, _ = http.NewRequest(http.MethodPut, adminPwdPath, bytes.NewBuffer(asJSON))
+	setBearerForReq(req, altToken)
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusAccepted, rr)
+	checkResponseCode(t, http.StatusOK, rr)
 
-	waitForUsersQuotaScan(t, token)
+	_, err = getJWTAPITokenFromTestServer(altAdminUsername, altAdminPassword)
+	assert.Error(t, err)
 
-	asJSON, err := json.Marshal(user)
+	altToken, err = getJWTAPITokenFromTestServer(altAdminUsername, defaultTokenAuthPass)
 	assert.NoError(t, err)
-	req, _ = http.NewRequest(http.MethodPost, quotaScanCompatPath, bytes.NewBuffer(asJSON))
-	setBearerForReq(req, token)
+	req, _ = http.NewRequest(http.MethodPut, adminPwdPath, bytes.NewBuffer(asJSON))
+	setBearerForReq(req, altToken)
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusAccepted, rr)
-
-	waitForUsersQuotaScan(t, token)
+	checkResponseCode(t, http.StatusBadRequest, rr) // current password does not match
 
 	req, _ = http.NewRequest(http.MethodDelete, path.Join(userPath, user.Username), nil)
+	setBearerForReq(req, altToken)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
+
+	req, _ = http.NewRequest(http.MethodDelete, path.Join(adminPath, altAdminUsername), nil)
 	setBearerForReq(req, token)
 	rr = executeRequest(req)
 	checkResponseCode(t, http.StatusOK, rr)
-	err = os.RemoveAll(user.GetHomeDir())
-	assert.NoError(t, err)
 }
 
-func TestUpdateFolderQuotaUsageMock(t *testing.T) {
+func TestUpdateAdminMock(t *testing.T) {
 	token, err := getJWTAPITokenFromTestServer(defaultTokenAuthUser, defaultTokenAuthPass)
 	assert.NoError(t, err)
-	mappedPath := filepath.Join(os.TempDir(), "vfolder")
-	folderName := filepath.Base(mappedPath)
-	f := vfs.BaseVirtualFolder{
-		MappedPath: mappedPath,
-		Name:       folderName,
-	}
-	usedQuotaFiles := 1
-	usedQuotaSize := int64(65535)
-	f.UsedQuotaFiles = usedQuotaFiles
-	f.UsedQuotaSize = usedQuotaSize
-	var folder vfs.BaseVirtualFolder
-	folderAsJSON, err := json.Marshal(f)
+	_, err = getJWTAPITokenFromTestServer(altAdminUsername, defaultTokenAuthPass)
+	assert.Error(t, err)
+	admin := getTestAdmin()
+	admin.Username = altAdminUsername
+	admin.Permissions = []string{dataprovider.PermAdminManageAdmins}
+	asJSON, err := json.Marshal(admin)
 	assert.NoError(t, err)
-	req, _ := http.NewRequest(http.MethodPost, folderPath, bytes.NewBuffer(folderAsJSON))
+	req, _ := http.NewRequest(http.MethodPost, adminPath, bytes.NewBuffer(asJSON))
 	setBearerForReq(req, token)
 	rr := executeRequest(req)
 	checkResponseCode(t, http.StatusCreated, rr)
-	err = render.DecodeJSON(rr.Body, &folder)
+	_, err = getJWTAPITokenFromTestServer(altAdminUsername, defaultTokenAuthPass)
 	assert.NoError(t, err)
-	req, _ = http.NewRequest(http.MethodPut, path.Join(quotasBasePath, "folders", folder.Name, "usage"), bytes.NewBuffer(folderAsJSON))
+
+	req, _ = http.NewRequest(http.MethodPut, path.Join(adminPath, "abc"), bytes.NewBuffer(asJSON))
 	setBearerForReq(req, token)
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusOK, rr)
-	req, _ = http.NewRequest(http.MethodPut, updateFolderUsedQuotaCompatPath, bytes.NewBuffer(folderAsJSON))
+	checkResponseCode(t, http.StatusNotFound, rr)
+	req, _ = http.NewRequest(http.MethodPut, path.Join(adminPath, altAdminUsername), bytes.NewBuffer([]byte("no json")))
 	setBearerForReq(req, token)
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusOK, rr)
-	var folderGet vfs.BaseVirtualFolder
-	req, _ = http.NewRequest(http.MethodGet, path.Join(folderPath, folderName), nil)
+	checkResponseCode(t, http.StatusBadRequest, rr)
+	admin.Permissions = nil
+	asJSON, err = json.Marshal(admin)
+	assert.NoError(t, err)
+	req, _ = http.NewRequest(http.MethodPut, path.Join(adminPath, altAdminUsername), bytes.NewBuffer(asJSON))
 	setBearerForReq(req, token)
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusOK, rr)
-	err = render.DecodeJSON(rr.Body, &folderGet)
+	checkResponseCode(t, http.StatusBadRequest, rr)
+	admin = getTestAdmin()
+	admin.Status = 0
+	asJSON, err = json.Marshal(admin)
 	assert.NoError(t, err)
-	assert.Equal(t, usedQuotaFiles, folderGet.UsedQuotaFiles)
-	assert.Equal(t, usedQuotaSize, folderGet.UsedQuotaSize)
-	// now update only quota size
-	f.UsedQuotaFiles = 0
-	folderAsJSON, err = json.Marshal(f)
-	assert.NoError(t, err)
-	req, _ = http.NewRequest(http.MethodPut, path.Join(quotasBasePath, "folders", folder.Name, "usage")+"?mode=add",
-		bytes.NewBuffer(folderAsJSON))
+	req, _ = http.NewRequest(http.MethodPut, path.Join(adminPath, defaultTokenAuthUser), bytes.NewBuffer(asJSON))
 	setBearerForReq(req, token)
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusOK, rr)
-	folderGet = vfs.BaseVirtualFolder{}
-	req, _ = http.NewRequest(http.MethodGet, path.Join(folderPath, folderName), nil)
+	checkResponseCode(t, http.StatusBadRequest, rr)
+	admin.Status = 1
+	admin.Permissions = []string{dataprovider.PermAdminAddUsers}
+	asJSON, err = json.Marshal(admin)
+	assert.NoError(t, err)
+	req, _ = http.NewRequest(http.MethodPut, path.Join(adminPath, defaultTokenAuthUser), bytes.NewBuffer(asJSON))
 	setBearerForReq(req, token)
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusOK, rr)
-	err = render.DecodeJSON(rr.Body, &folderGet)
+	checkResponseCode(t, http.StatusBadRequest, rr)
+
+	altToken, err := getJWTAPITokenFromTestServer(altAdminUsername, defaultTokenAuthPass)
 	assert.NoError(t, err)
-	assert.Equal(t, usedQuotaFiles, folderGet.UsedQuotaFiles)
-	assert.Equal(t, usedQuotaSize*2, folderGet.UsedQuotaSize)
-	// now update only quota files
-	f.UsedQuotaSize = 0
-	f.UsedQuotaFiles = 1
-	folderAsJSON, err = json.Marshal(f)
+	admin.Password = "" // it must remain unchanged
+	admin.Permissions = []string{dataprovider.PermAdminManageAdmins, dataprovider.PermAdminCloseConnections}
+	asJSON, err = json.Marshal(admin)
 	assert.NoError(t, err)
-	req, _ = http.NewRequest(http.MethodPut, path.Join(quotasBasePath, "folders", folder.Name, "usage")+"?mode=add",
-		bytes.NewBuffer(folderAsJSON))
-	setBearerForReq(req, token)
-	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusOK, rr)
-	folderGet = vfs.BaseVirtualFolder{}
-	req, _ = http.NewRequest(http.MethodGet, path.Join(folderPath, folderName), nil)
-	setBearerForReq(req, token)
+	req, _ = http.NewRequest(http.MethodPut, path.Join(adminPath, altAdminUsername), bytes.NewBuffer(asJSON))
+	setBearerForReq(req, altToken)
 	rr = executeRequest(req)
 	checkResponseCode(t, http.StatusOK, rr)
-	err = render.DecodeJSON(rr.Body, &folderGet)
-	assert.NoError(t, err)
-	assert.Equal(t, usedQuotaFiles*2, folderGet.UsedQuotaFiles)
-	assert.Equal(t, usedQuotaSize*2, folderGet.UsedQuotaSize)
-	req, _ = http.NewRequest(http.MethodPut, updateFolderUsedQuotaCompatPath, bytes.NewBuffer([]byte("string")))
-	setBearerForReq(req, token)
-	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusBadRequest, rr)
-	req, _ = http.NewRequest(http.MethodPut, path.Join(quotasBasePath, "folders", folder.Name, "usage"),
-		bytes.NewBuffer([]byte("not a json")))
-	setBearerForReq(req, token)
-	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusBadRequest, rr)
 
-	assert.True(t, common.QuotaScans.AddVFolderQuotaScan(folderName))
-	req, _ = http.NewRequest(http.MethodPut, path.Join(quotasBasePath, "folders", folder.Name, "usage"),
-		bytes.NewBuffer(folderAsJSON))
-	setBearerForReq(req, token)
-	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusConflict, rr)
-	assert.True(t, common.QuotaScans.RemoveVFolderQuotaScan(folderName))
+	_, err = getJWTAPITokenFromTestServer(altAdminUsername, defaultTokenAuthPass)
+	assert.NoError(t, err)
 
-	req, _ = http.NewRequest(http.MethodDelete, path.Join(folderPath, folderName), nil)
+	req, _ = http.NewRequest(http.MethodDelete, path.Join(adminPath, altAdminUsername), nil)
 	setBearerForReq(req, token)
 	rr = executeRequest(req)
 	checkResponseCode(t, http.StatusOK, rr)
 }
 
-func TestStartFolderQuotaScanMock(t *testing.T) {
-	token, err := getJWTAPITokenFromTestServer(defaultTokenAuthUser, defaultTokenAuthPass)
-	assert.NoError(t, err)
-	mappedPath := filepath.Join(os.TempDir(), "vfolder")
-	folderName := filepath.Base(mappedPath)
-	folder := vfs.BaseVirtualFolder{
-		Name:       folderName,
-		MappedPath: mappedPath,
+func TestAdminLastLoginWithAPIKey(t *testing.T) {
+	admin := getTestAdmin()
+	admin.Username = altAdminUsername
+	admin.Filters.AllowAPIKeyAuth = true
+	admin, resp, err := httpdtest.AddAdmin(admin, http.StatusCreated)
+	assert.NoError(t, err, string(resp))
+	assert.Equal(t, int64(0), admin.LastLogin)
+
+	apiKey := dataprovider.APIKey{
+		Name:  "admin API key",
+		Scope: dataprovider.APIKeyScopeAdmin,
+		Admin: altAdminUsername,
 	}
-	folderAsJSON, err := json.Marshal(folder)
+
+	apiKey, resp, err = httpdtest.AddAPIKey(apiKey, http.StatusCreated)
+	assert.NoError(t, err, string(resp))
+
+	req, err := http.NewRequest(http.MethodGet, versionPath, nil)
 	assert.NoError(t, err)
-	req, _ := http.NewRequest(http.MethodPost, folderPath, bytes.NewBuffer(folderAsJSON))
-	setBearerForReq(req, token)
+	setAPIKeyForReq(req, apiKey.Key, admin.Username)
 	rr := executeRequest(req)
-	checkResponseCode(t, http.StatusCreated, rr)
-	_, err = os.Stat(mappedPath)
-	if err == nil {
-		err = os.Remove(mappedPath)
-		assert.NoError(t, err)
-	}
-	// simulate a duplicate quota scan
-	common.QuotaScans.AddVFolderQuotaScan(folderName)
-	req, _ = http.NewRequest(http.MethodPost, path.Join(quotasBasePath, "folders", folder.Name, "scan"), nil)
-	setBearerForReq(req, token)
-	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusConflict, rr)
-	assert.True(t, common.QuotaScans.RemoveVFolderQuotaScan(folderName))
-	// and now a real quota scan
-	_, err = os.Stat(mappedPath)
-	if err != nil && os.IsNotExist(err) {
-		err = os.MkdirAll(mappedPath, os.ModePerm)
-		assert.NoError(t, err)
-	}
-	req, _ = http.NewRequest(http.MethodPost, path.Join(quotasBasePath, "folders", folder.Name, "scan"), nil)
-	setBearerForReq(req, token)
-	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusAccepted, rr)
-	waitForFoldersQuotaScanPath(t, token)
+	checkResponseCode(t, http.StatusOK, rr)
 
-	asJSON, err := json.Marshal(folder)
+	admin, _, err = httpdtest.GetAdminByUsername(altAdminUsername, http.StatusOK)
 	assert.NoError(t, err)
-	req, _ = http.NewRequest(http.MethodPost, quotaScanVFolderCompatPath, bytes.NewBuffer(asJSON))
-	setBearerForReq(req, token)
-	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusAccepted, rr)
-	waitForFoldersQuotaScanPath(t, token)
-
-	// cleanup
+	assert.Greater(t, admin.LastLogin, int64(0))
 
-	req, _ = http.NewRequest(http.MethodDelete, path.Join(folderPath, folderName), nil)
-	setBearerForReq(req, token)
-	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusOK, rr)
-	err = os.RemoveAll(folderPath)
-	assert.NoError(t, err)
-	err = os.RemoveAll(mappedPath)
+	_, err = httpdtest.RemoveAdmin(admin, http.StatusOK)
 	assert.NoError(t, err)
 }
 
-func TestStartQuotaScanNonExistentUserMock(t *testing.T) {
-	token, err := getJWTAPITokenFromTestServer(defaultTokenAuthUser, defaultTokenAuthPass)
-	assert.NoError(t, err)
+func TestUserLastLoginWithAPIKey(t *testing.T) {
 	user := getTestUser()
+	user.Filters.AllowAPIKeyAuth = true
+	user, resp, err := httpdtest.AddUser(user, http.StatusCreated)
+	assert.NoError(t, err, string(resp))
+	assert.Equal(t, int64(0), user.LastLogin)
 
-	req, _ := http.NewRequest(http.MethodPost, path.Join(quotasBasePath, "users", user.Username, "scan"), nil)
-	setBearerForReq(req, token)
-	rr := executeRequest(req)
-	checkResponseCode(t, http.StatusNotFound, rr)
-}
+	apiKey := dataprovider.APIKey{
+		Name:  "user API key",
+		Scope: dataprovider.APIKeyScopeUser,
+		User:  user.Username,
+	}
 
-func TestStartQuotaScanBadUserMock(t *testing.T) {
-	token, err := getJWTAPITokenFromTestServer(defaultTokenAuthUser, defaultTokenAuthPass)
+	apiKey, resp, err = httpdtest.AddAPIKey(apiKey, http.StatusCreated)
+	assert.NoError(t, err, string(resp))
+
+	req, err := http.NewRequest(http.MethodGet, userDirsPath, nil)
 	assert.NoError(t, err)
-	req, _ := http.NewRequest(http.MethodPost, quotaScanCompatPath, bytes.NewBuffer([]byte("invalid json")))
-	setBearerForReq(req, token)
+	setAPIKeyForReq(req, apiKey.Key, "")
 	rr := executeRequest(req)
-	checkResponseCode(t, http.StatusBadRequest, rr)
-}
+	checkResponseCode(t, http.StatusOK, rr)
 
-func TestStartQuotaScanBadFolderMock(t *testing.T) {
-	token, err := getJWTAPITokenFromTestServer(defaultTokenAuthUser, defaultTokenAuthPass)
+	user, _, err = httpdtest.GetUserByUsername(user.Username, http.StatusOK)
 	assert.NoError(t, err)
-	req, _ := http.NewRequest(http.MethodPost, quotaScanVFolderCompatPath, bytes.NewBuffer([]byte("invalid json")))
-	setBearerForReq(req, token)
-	rr := executeRequest(req)
-	checkResponseCode(t, http.StatusBadRequest, rr)
-}
+	assert.Greater(t, user.LastLogin, int64(0))
 
-func TestStartQuotaScanNonExistentFolderMock(t *testing.T) {
-	token, err := getJWTAPITokenFromTestServer(defaultTokenAuthUser, defaultTokenAuthPass)
+	_, err = httpdtest.RemoveUser(user, http.StatusOK)
+	assert.NoError(t, err)
+	err = os.RemoveAll(user.GetHomeDir())
 	assert.NoError(t, err)
-	folder := vfs.BaseVirtualFolder{
-		MappedPath: os.TempDir(),
-		Name:       "afolder",
-	}
-	req, _ := http.NewRequest(http.MethodPost, path.Join(quotasBasePath, "folders", folder.Name, "scan"), nil)
-	setBearerForReq(req, token)
-	rr := executeRequest(req)
-	checkResponseCode(t, http.StatusNotFound, rr)
 }
 
-func TestGetFoldersMock(t *testing.T) {
-	token, err := getJWTAPITokenFromTestServer(defaultTokenAuthUser, defaultTokenAuthPass)
+func TestAdminHandlingWithAPIKeys(t *testing.T) {
+	sysAdmin, _, err := httpdtest.GetAdminByUsername(defaultTokenAuthUser, http.StatusOK)
 	assert.NoError(t, err)
-	mappedPath := filepath.Join(os.TempDir(), "vfolder")
-	folderName := filepath.Base(mappedPath)
-	folder := vfs.BaseVirtualFolder{
-		Name:       folderName,
-		MappedPath: mappedPath,
+	sysAdmin.Filters.AllowAPIKeyAuth = true
+	sysAdmin, _, err = httpdtest.UpdateAdmin(sysAdmin, http.StatusOK)
+	assert.NoError(t, err)
+
+	apiKey := dataprovider.APIKey{
+		Name:  "test admin API key",
+		Scope: dataprovider.APIKeyScopeAdmin,
+		Admin: defaultTokenAuthUser,
 	}
-	folderAsJSON, err := json.Marshal(folder)
+
+	apiKey, resp, err := httpdtest.AddAPIKey(apiKey, http.StatusCreated)
+	assert.NoError(t, err, string(resp))
+
+	admin := getTestAdmin()
+	admin.Username = altAdminUsername
+	asJSON, err := json.Marshal(admin)
 	assert.NoError(t, err)
-	req, _ := http.NewRequest(http.MethodPost, folderPath, bytes.NewBuffer(folderAsJSON))
-	setBearerForReq(req, token)
+	req, err := http.NewRequest(http.MethodPost, adminPath, bytes.NewBuffer(asJSON))
+	assert.NoError(t, err)
+	setAPIKeyForReq(req, apiKey.Key, "")
 	rr := executeRequest(req)
 	checkResponseCode(t, http.StatusCreated, rr)
-	err = render.DecodeJSON(rr.Body, &folder)
+	_, err = getJWTAPITokenFromTestServer(altAdminUsername, defaultTokenAuthPass)
 	assert.NoError(t, err)
 
-	var folders []vfs.BaseVirtualFolder
-	url, err := url.Parse(folderPath + "?limit=510&offset=0&order=DESC")
+	admin.Filters.AllowAPIKeyAuth = true
+	asJSON, err = json.Marshal(admin)
 	assert.NoError(t, err)
-	req, _ = http.NewRequest(http.MethodGet, url.String(), nil)
-	setBearerForReq(req, token)
-	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusOK, rr)
-	err = render.DecodeJSON(rr.Body, &folders)
+	req, err = http.NewRequest(http.MethodPut, path.Join(adminPath, altAdminUsername), bytes.NewBuffer(asJSON))
 	assert.NoError(t, err)
-	assert.GreaterOrEqual(t, len(folders), 1)
-	req, _ = http.NewRequest(http.MethodGet, folderPath+"?limit=a&offset=0&order=ASC", nil)
-	setBearerForReq(req, token)
-	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusBadRequest, rr)
-	req, _ = http.NewRequest(http.MethodGet, folderPath+"?limit=1&offset=a&order=ASC", nil)
-	setBearerForReq(req, token)
-	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusBadRequest, rr)
-	req, _ = http.NewRequest(http.MethodGet, folderPath+"?limit=1&offset=0&order=ASCa", nil)
-	setBearerForReq(req, token)
-	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusBadRequest, rr)
-
-	req, _ = http.NewRequest(http.MethodDelete, path.Join(folderPath, folderName), nil)
-	setBearerForReq(req, token)
+	setAPIKeyForReq(req, apiKey.Key, "")
 	rr = executeRequest(req)
 	checkResponseCode(t, http.StatusOK, rr)
-}
 
-func TestGetVersionMock(t *testing.T) {
-	req, _ := http.NewRequest(http.MethodGet, versionPath, nil)
-	rr := executeRequest(req)
-	checkResponseCode(t, http.StatusUnauthorized, rr)
-	token, err := getJWTAPITokenFromTestServer(defaultTokenAuthUser, defaultTokenAuthPass)
+	req, err = http.NewRequest(http.MethodGet, path.Join(adminPath, altAdminUsername), nil)
 	assert.NoError(t, err)
-	req, _ = http.NewRequest(http.MethodGet, versionPath, nil)
-	setBearerForReq(req, token)
+	setAPIKeyForReq(req, apiKey.Key, "")
 	rr = executeRequest(req)
 	checkResponseCode(t, http.StatusOK, rr)
-	req, _ = http.NewRequest(http.MethodGet, versionPath, nil)
-	setBearerForReq(req, "abcde")
+	var adminGet dataprovider.Admin
+	err = json.Unmarshal(rr.Body.Bytes(), &adminGet)
+	assert.NoError(t, err)
+	assert.True(t, adminGet.Filters.AllowAPIKeyAuth)
+
+	req, err = http.NewRequest(http.MethodPut, path.Join(adminPath, defaultTokenAuthUser), bytes.NewBuffer(asJSON))
+	assert.NoError(t, err)
+	setAPIKeyForReq(req, apiKey.Key, "")
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusUnauthorized, rr)
-}
+	checkResponseCode(t, http.StatusBadRequest, rr)
+	assert.Contains(t, rr.Body.String(), "updating the admin impersonated with an API key is not allowed")
+	// changing the password for the impersonated admin is not allowed
+	pwd := make(map[string]string)
+	pwd["current_password"] = defaultTokenAuthPass
+	pwd["new_password"] = altAdminPassword
+	asJSON, err = json.Marshal(pwd)
+	assert.NoError(t, err)
+	req, _ = http.NewRequest(http.MethodPut, adminPwdPath, bytes.NewBuffer(asJSON))
+	setAPIKeyForReq(req, apiKey.Key, "")
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusForbidden, rr)
+	assert.Contains(t, rr.Body.String(), "API key authentication is not allowed")
 
-func TestGetConnectionsMock(t *testing.T) {
-	token, err := getJWTAPITokenFromTestServer(defaultTokenAuthUser, defaultTokenAuthPass)
+	req, err = http.NewRequest(http.MethodDelete, path.Join(adminPath, defaultTokenAuthUser), nil)
 	assert.NoError(t, err)
-	req, _ := http.NewRequest(http.MethodGet, activeConnectionsPath, nil)
-	setBearerForReq(req, token)
-	rr := executeRequest(req)
-	checkResponseCode(t, http.StatusOK, rr)
-}
+	setAPIKeyForReq(req, apiKey.Key, "")
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusBadRequest, rr)
+	assert.Contains(t, rr.Body.String(), "you cannot delete yourself")
 
-func TestGetStatusMock(t *testing.T) {
-	token, err := getJWTAPITokenFromTestServer(defaultTokenAuthUser, defaultTokenAuthPass)
+	req, err = http.NewRequest(http.MethodDelete, path.Join(adminPath, altAdminUsername), nil)
 	assert.NoError(t, err)
-	req, _ := http.NewRequest(http.MethodGet, serverStatusPath, nil)
-	setBearerForReq(req, token)
-	rr := executeRequest(req)
+	setAPIKeyForReq(req, apiKey.Key, "")
+	rr = executeRequest(req)
 	checkResponseCode(t, http.StatusOK, rr)
-}
 
-func TestDeleteActiveConnectionMock(t *testing.T) {
-	token, err := getJWTAPITokenFromTestServer(defaultTokenAuthUser, defaultTokenAuthPass)
+	_, err = httpdtest.RemoveAPIKey(apiKey, http.StatusOK)
 	assert.NoError(t, err)
-	req, _ := http.NewRequest(http.MethodDelete, activeConnectionsPath+"/connectionID", nil)
-	setBearerForReq(req, token)
-	rr := executeRequest(req)
-	checkResponseCode(t, http.StatusNotFound, rr)
-}
 
-func TestNotFoundMock(t *testing.T) {
-	token, err := getJWTAPITokenFromTestServer(defaultTokenAuthUser, defaultTokenAuthPass)
+	dbAdmin, err := dataprovider.AdminExists(defaultTokenAuthUser)
 	assert.NoError(t, err)
-	req, _ := http.NewRequest(http.MethodGet, "/non/existing/path", nil)
-	setBearerForReq(req, token)
-	rr := executeRequest(req)
-	checkResponseCode(t, http.StatusNotFound, rr)
+	dbAdmin.Filters.AllowAPIKeyAuth = false
+	err = dataprovider.UpdateAdmin(&dbAdmin, "", "")
+	assert.NoError(t, err)
+	sysAdmin, _, err = httpdtest.GetAdminByUsername(defaultTokenAuthUser, http.StatusOK)
+	assert.NoError(t, err)
+	assert.False(t, sysAdmin.Filters.AllowAPIKeyAuth)
 }
 
-func TestMethodNotAllowedMock(t *testing.T) {
-	req, _ := http.NewRequest(http.MethodPost, activeConnectionsPath, nil)
-	rr := executeRequest(req)
-	checkResponseCode(t, http.StatusMethodNotAllowed, rr)
-}
+func TestUserHandlingWithAPIKey(t *testing.T) {
+	admin := getTestAdmin()
+	admin.Username = altAdminUsername
+	admin.Filters.AllowAPIKeyAuth = true
+	admin, _, err := httpdtest.AddAdmin(admin, http.StatusCreated)
+	assert.NoError(t, err)
 
-func TestHealthCheck(t *testing.T) {
-	req, _ := http.NewRequest(http.MethodGet, "/healthz", nil)
-	rr := executeRequest(req)
-	checkResponseCode(t, http.StatusOK, rr)
-	assert.Equal(t, "ok", rr.Body.String())
-}
+	apiKey := dataprovider.APIKey{
+		Name:  "test admin API key",
+		Scope: dataprovider.APIKeyScopeAdmin,
+		Admin: admin.Username,
+	}
 
-func TestGetWebRootMock(t *testing.T) {
-	req, _ := http.NewRequest(http.MethodGet, "/", nil)
+	apiKey, _, err = httpdtest.AddAPIKey(apiKey, http.StatusCreated)
+	assert.NoError(t, err)
+
+	user := getTestUser()
+	userAsJSON := getUserAsJSON(t, user)
+	req, err := http.NewRequest(http.MethodPost, userPath, bytes.NewBuffer(userAsJSON))
+	assert.NoError(t, err)
+	setAPIKeyForReq(req, apiKey.Key, "")
 	rr := executeRequest(req)
-	checkResponseCode(t, http.StatusMovedPermanently, rr)
-	assert.Equal(t, webClientLoginPath, rr.Header().Get("Location"))
-	req, _ = http.NewRequest(http.MethodGet, webBasePath, nil)
-	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusMovedPermanently, rr)
-	assert.Equal(t, webClientLoginPath, rr.Header().Get("Location"))
-	req, _ = http.NewRequest(http.MethodGet, webBasePathAdmin, nil)
-	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusMovedPermanently, rr)
-	assert.Equal(t, webLoginPath, rr.Header().Get("Location"))
-	req, _ = http.NewRequest(http.MethodGet, webBasePathClient, nil)
-	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusMovedPermanently, rr)
-	assert.Equal(t, webClientLoginPath, rr.Header().Get("Location"))
-}
+	checkResponseCode(t, http.StatusCreated, rr)
 
-func TestWebNotFoundURI(t *testing.T) {
-	urlString := httpBaseURL + webBasePath + "/a"
-	req, err := http.NewRequest(http.MethodGet, urlString, nil)
+	user.Filters.DisableFsChecks = true
+	user.Description = "desc"
+	userAsJSON = getUserAsJSON(t, user)
+	req, err = http.NewRequest(http.MethodPut, path.Join(userPath, user.Username), bytes.NewBuffer(userAsJSON))
 	assert.NoError(t, err)
-	resp, err := httpclient.GetHTTPClient().Do(req)
-	require.NoError(t, err)
-	defer resp.Body.Close()
-	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	setAPIKeyForReq(req, apiKey.Key, "")
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
 
-	req, err = http.NewRequest(http.MethodGet, urlString, nil)
+	req, err = http.NewRequest(http.MethodGet, path.Join(userPath, user.Username), nil)
 	assert.NoError(t, err)
-	setJWTCookieForReq(req, "invalid token")
-	resp, err = httpclient.GetHTTPClient().Do(req)
-	require.NoError(t, err)
-	defer resp.Body.Close()
-	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	setAPIKeyForReq(req, apiKey.Key, "")
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
+	var updatedUser dataprovider.User
+	err = json.Unmarshal(rr.Body.Bytes(), &updatedUser)
+	assert.NoError(t, err)
+	assert.True(t, updatedUser.Filters.DisableFsChecks)
+	assert.Equal(t, user.Description, updatedUser.Description)
 
-	urlString = httpBaseURL + webBasePathClient + "/a"
-	req, err = http.NewRequest(http.MethodGet, urlString, nil)
+	req, err = http.NewRequest(http.MethodDelete, path.Join(userPath, user.Username), nil)
+	assert.NoError(t, err)
+	setAPIKeyForReq(req, apiKey.Key, "")
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
+	err = os.RemoveAll(user.GetHomeDir())
 	assert.NoError(t, err)
-	resp, err = httpclient.GetHTTPClient().Do(req)
-	require.NoError(t, err)
-	defer resp.Body.Close()
-	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
 
-	req, err = http.NewRequest(http.MethodGet, urlString, nil)
+	_, err = httpdtest.RemoveAdmin(admin, http.StatusOK)
+	assert.NoError(t, err)
+	_, _, err = httpdtest.GetAPIKeyByID(apiKey.KeyID, http.StatusNotFound)
 	assert.NoError(t, err)
-	setJWTCookieForReq(req, "invalid client token")
-	resp, err = httpclient.GetHTTPClient().Do(req)
-	require.NoError(t, err)
-	defer resp.Body.Close()
-	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
 }
 
-func TestLogout(t *testing.T) {
+func TestUpdateUserMock(t *testing.T) {
 	token, err := getJWTAPITokenFromTestServer(defaultTokenAuthUser, defaultTokenAuthPass)
 	assert.NoError(t, err)
-	req, _ := http.NewRequest(http.MethodGet, serverStatusPath, nil)
+	user := getTestUser()
+	userAsJSON := getUserAsJSON(t, user)
+	req, _ := http.NewRequest(http.MethodPost, userPath, bytes.NewBuffer(userAsJSON))
 	setBearerForReq(req, token)
 	rr := executeRequest(req)
+	checkResponseCode(t, http.StatusCreated, rr)
+	err = render.DecodeJSON(rr.Body, &user)
+	assert.NoError(t, err)
+	// permissions should not change if empty or nil
+	permissions := user.Permissions
+	user.Permissions = make(map[string][]string)
+	userAsJSON = getUserAsJSON(t, user)
+	req, _ = http.NewRequest(http.MethodPut, userPath+"/"+user.Username, bytes.NewBuffer(userAsJSON))
+	setBearerForReq(req, token)
+	rr = executeRequest(req)
 	checkResponseCode(t, http.StatusOK, rr)
-
-	req, _ = http.NewRequest(http.MethodGet, logoutPath, nil)
+	req, _ = http.NewRequest(http.MethodGet, userPath+"/"+user.Username, nil)
 	setBearerForReq(req, token)
 	rr = executeRequest(req)
 	checkResponseCode(t, http.StatusOK, rr)
-
-	req, _ = http.NewRequest(http.MethodGet, serverStatusPath, nil)
+	var updatedUser dataprovider.User
+	err = render.DecodeJSON(rr.Body, &updatedUser)
+	assert.NoError(t, err)
+	for dir, perms := range permissions {
+		if actualPerms, ok := updatedUser.Permissions[dir]; ok {
+			for _, v := range actualPerms {
+				assert.True(t, util.IsStringInSlice(v, perms))
+			}
+		} else {
+			assert.Fail(t, "Permissions directories mismatch")
+		}
+	}
+	req, _ = http.NewRequest(http.MethodDelete, path.Join(userPath, user.Username), nil)
 	setBearerForReq(req, token)
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusUnauthorized, rr)
-	assert.Contains(t, rr.Body.String(), "Your token is no longer valid")
+	checkResponseCode(t, http.StatusOK, rr)
 }
 
-func TestDefenderAPIInvalidIDMock(t *testing.T) {
+func TestUpdateUserQuotaUsageMock(t *testing.T) {
 	token, err := getJWTAPITokenFromTestServer(defaultTokenAuthUser, defaultTokenAuthPass)
 	assert.NoError(t, err)
-	req, _ := http.NewRequest(http.MethodGet, path.Join(defenderHosts, "abc"), nil) // not hex id
+	var user dataprovider.User
+	u := getTestUser()
+	usedQuotaFiles := 1
+	usedQuotaSize := int64(65535)
+	u.UsedQuotaFiles = usedQuotaFiles
+	u.UsedQuotaSize = usedQuotaSize
+	u.QuotaFiles = 100
+	userAsJSON := getUserAsJSON(t, u)
+	req, _ := http.NewRequest(http.MethodPost, userPath, bytes.NewBuffer(userAsJSON))
 	setBearerForReq(req, token)
 	rr := executeRequest(req)
-	checkResponseCode(t, http.StatusBadRequest, rr)
-	assert.Contains(t, rr.Body.String(), "invalid host id")
-}
-
-func TestTokenHeaderCookie(t *testing.T) {
-	apiToken, err := getJWTAPITokenFromTestServer(defaultTokenAuthUser, defaultTokenAuthPass)
-	assert.NoError(t, err)
-	webToken, err := getJWTWebTokenFromTestServer(defaultTokenAuthUser, defaultTokenAuthPass)
+	checkResponseCode(t, http.StatusCreated, rr)
+	err = render.DecodeJSON(rr.Body, &user)
 	assert.NoError(t, err)
-
-	req, _ := http.NewRequest(http.MethodGet, serverStatusPath, nil)
-	setJWTCookieForReq(req, apiToken)
-	rr := executeRequest(req)
-	checkResponseCode(t, http.StatusUnauthorized, rr)
-	assert.Contains(t, rr.Body.String(), "no token found")
-
-	req, _ = http.NewRequest(http.MethodGet, serverStatusPath, nil)
-	setBearerForReq(req, apiToken)
+	req, _ = http.NewRequest(http.MethodPut, path.Join(quotasBasePath, "users", u.Username, "usage"), bytes.NewBuffer(userAsJSON))
+	setBearerForReq(req, token)
 	rr = executeRequest(req)
 	checkResponseCode(t, http.StatusOK, rr)
-
-	req, _ = http.NewRequest(http.MethodGet, webStatusPath, nil)
-	setBearerForReq(req, webToken)
+	req, _ = http.NewRequest(http.MethodPut, updateUsedQuotaCompatPath, bytes.NewBuffer(userAsJSON))
+	setBearerForReq(req, token)
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusFound, rr)
-	assert.Equal(t, webLoginPath, rr.Header().Get("Location"))
-
-	req, _ = http.NewRequest(http.MethodGet, webStatusPath, nil)
-	setJWTCookieForReq(req, webToken)
+	checkResponseCode(t, http.StatusOK, rr)
+	req, _ = http.NewRequest(http.MethodGet, path.Join(userPath, user.Username), nil)
+	setBearerForReq(req, token)
 	rr = executeRequest(req)
 	checkResponseCode(t, http.StatusOK, rr)
-}
-
-func TestTokenAudience(t *testing.T) {
-	webToken, err := getJWTWebTokenFromTestServer(defaultTokenAuthUser, defaultTokenAuthPass)
-	assert.NoError(t, err)
-	apiToken, err := getJWTAPITokenFromTestServer(defaultTokenAuthUser, defaultTokenAuthPass)
+	err = render.DecodeJSON(rr.Body, &user)
 	assert.NoError(t, err)
-
-	req, _ := http.NewRequest(http.MethodGet, serverStatusPath, nil)
-	setBearerForReq(req, apiToken)
-	rr := executeRequest(req)
+	assert.Equal(t, usedQuotaFiles, user.UsedQuotaFiles)
+	assert.Equal(t, usedQuotaSize, user.UsedQuotaSize)
+	// now update only quota size
+	u.UsedQuotaFiles = 0
+	userAsJSON = getUserAsJSON(t, u)
+	req, _ = http.NewRequest(http.MethodPut, path.Join(quotasBasePath, "users", u.Username, "usage")+"?mode=add", bytes.NewBuffer(userAsJSON))
+	setBearerForReq(req, token)
+	rr = executeRequest(req)
 	checkResponseCode(t, http.StatusOK, rr)
-
-	req, _ = http.NewRequest(http.MethodGet, serverStatusPath, nil)
-	setBearerForReq(req, webToken)
+	req, _ = http.NewRequest(http.MethodGet, path.Join(userPath, user.Username), nil)
+	setBearerForReq(req, token)
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusUnauthorized, rr)
-	assert.Contains(t, rr.Body.String(), "Your token audience is not valid")
-
-	req, _ = http.NewRequest(http.MethodGet, webStatusPath, nil)
-	setJWTCookieForReq(req, webToken)
+	checkResponseCode(t, http.StatusOK, rr)
+	err = render.DecodeJSON(rr.Body, &user)
+	assert.NoError(t, err)
+	assert.Equal(t, usedQuotaFiles, user.UsedQuotaFiles)
+	assert.Equal(t, usedQuotaSize*2, user.UsedQuotaSize)
+	// only quota files
+	u.UsedQuotaFiles = usedQuotaFiles
+	u.UsedQuotaSize = 0
+	userAsJSON = getUserAsJSON(t, u)
+	req, _ = http.NewRequest(http.MethodPut, path.Join(quotasBasePath, "users", u.Username, "usage")+"?mode=add", bytes.NewBuffer(userAsJSON))
+	setBearerForReq(req, token)
 	rr = executeRequest(req)
 	checkResponseCode(t, http.StatusOK, rr)
-
-	req, _ = http.NewRequest(http.MethodGet, webStatusPath, nil)
-	setJWTCookieForReq(req, apiToken)
+	req, _ = http.NewRequest(http.MethodGet, path.Join(userPath, user.Username), nil)
+	setBearerForReq(req, token)
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusFound, rr)
-	assert.Equal(t, webLoginPath, rr.Header().Get("Location"))
+	checkResponseCode(t, http.StatusOK, rr)
+	err = render.DecodeJSON(rr.Body, &user)
+	assert.NoError(t, err)
+	assert.Equal(t, usedQuotaFiles*2, user.UsedQuotaFiles)
+	assert.Equal(t, usedQuotaSize*2, user.UsedQuotaSize)
+	req, _ = http.NewRequest(http.MethodPut, updateUsedQuotaCompatPath, bytes.NewBuffer([]byte("string")))
+	setBearerForReq(req, token)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusBadRequest, rr)
+	req, _ = http.NewRequest(http.MethodPut, path.Join(quotasBasePath, "users", u.Username, "usage"), bytes.NewBuffer([]byte("string")))
+	setBearerForReq(req, token)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusBadRequest, rr)
+	assert.True(t, common.QuotaScans.AddUserQuotaScan(user.Username))
+	req, _ = http.NewRequest(http.MethodPut, path.Join(quotasBasePath, "users", u.Username, "usage"), bytes.NewBuffer(userAsJSON))
+	setBearerForReq(req, token)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusConflict, rr)
+	assert.True(t, common.QuotaScans.RemoveUserQuotaScan(user.Username))
+	req, _ = http.NewRequest(http.MethodDelete, path.Join(userPath, user.Username), nil)
+	setBearerForReq(req, token)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
 }
 
-func TestWebAPILoginMock(t *testing.T) {
-	_, err := getJWTAPIUserTokenFromTestServer(defaultUsername, defaultPassword)
-	assert.Error(t, err)
-	user, _, err := httpdtest.AddUser(getTestUser(), http.StatusCreated)
-	assert.NoError(t, err)
-	_, err = getJWTAPIUserTokenFromTestServer(defaultUsername+"1", defaultPassword)
-	assert.Error(t, err)
-	_, err = getJWTAPIUserTokenFromTestServer(defaultUsername, defaultPassword+"1")
-	assert.Error(t, err)
-	apiToken, err := getJWTAPIUserTokenFromTestServer(defaultUsername, defaultPassword)
-	assert.NoError(t, err)
-	webToken, err := getJWTWebClientTokenFromTestServer(defaultUsername, defaultPassword)
-	assert.NoError(t, err)
-	// a web token is not valid for API usage
-	req, err := http.NewRequest(http.MethodGet, userDirsPath, nil)
+func TestUserPermissionsMock(t *testing.T) {
+	token, err := getJWTAPITokenFromTestServer(defaultTokenAuthUser, defaultTokenAuthPass)
 	assert.NoError(t, err)
-	setBearerForReq(req, webToken)
+	user := getTestUser()
+	user.Permissions = make(map[string][]string)
+	user.Permissions["/somedir"] = []string{dataprovider.PermAny}
+	userAsJSON := getUserAsJSON(t, user)
+	req, _ := http.NewRequest(http.MethodPost, userPath, bytes.NewBuffer(userAsJSON))
+	setBearerForReq(req, token)
 	rr := executeRequest(req)
-	checkResponseCode(t, http.StatusUnauthorized, rr)
-	assert.Contains(t, rr.Body.String(), "Your token audience is not valid")
-
-	req, err = http.NewRequest(http.MethodGet, userDirsPath+"/?path=%2F", nil)
+	checkResponseCode(t, http.StatusBadRequest, rr)
+	user.Permissions = make(map[string][]string)
+	user.Permissions["/"] = []string{dataprovider.PermAny}
+	user.Permissions[".."] = []string{dataprovider.PermAny}
+	userAsJSON = getUserAsJSON(t, user)
+	req, _ = http.NewRequest(http.MethodPost, userPath, bytes.NewBuffer(userAsJSON))
+	setBearerForReq(req, token)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusBadRequest, rr)
+	user.Permissions = make(map[string][]string)
+	user.Permissions["/"] = []string{dataprovider.PermAny}
+	userAsJSON = getUserAsJSON(t, user)
+	req, _ = http.NewRequest(http.MethodPost, userPath, bytes.NewBuffer(userAsJSON))
+	setBearerForReq(req, token)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusCreated, rr)
+	err = render.DecodeJSON(rr.Body, &user)
 	assert.NoError(t, err)
-	setBearerForReq(req, apiToken)
+	user.Permissions["/somedir"] = []string{"invalid"}
+	userAsJSON = getUserAsJSON(t, user)
+	req, _ = http.NewRequest(http.MethodPut, path.Join(userPath, user.Username), bytes.NewBuffer(userAsJSON))
+	setBearerForReq(req, token)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusBadRequest, rr)
+	delete(user.Permissions, "/somedir")
+	user.Permissions["/somedir/.."] = []string{dataprovider.PermAny}
+	userAsJSON = getUserAsJSON(t, user)
+	req, _ = http.NewRequest(http.MethodPut, path.Join(userPath, user.Username), bytes.NewBuffer(userAsJSON))
+	setBearerForReq(req, token)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusBadRequest, rr)
+	delete(user.Permissions, "/somedir/..")
+	user.Permissions["not_abs_path"] = []string{dataprovider.PermAny}
+	userAsJSON = getUserAsJSON(t, user)
+	req, _ = http.NewRequest(http.MethodPut, path.Join(userPath, user.Username), bytes.NewBuffer(userAsJSON))
+	setBearerForReq(req, token)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusBadRequest, rr)
+	delete(user.Permissions, "not_abs_path")
+	user.Permissions["/somedir/../otherdir/"] = []string{dataprovider.PermListItems}
+	userAsJSON = getUserAsJSON(t, user)
+	req, _ = http.NewRequest(http.MethodPut, path.Join(userPath, user.Username), bytes.NewBuffer(userAsJSON))
+	setBearerForReq(req, token)
 	rr = executeRequest(req)
 	checkResponseCode(t, http.StatusOK, rr)
-	// API token is not valid for web usage
-	req, _ = http.NewRequest(http.MethodGet, webClientProfilePath, nil)
-	setJWTCookieForReq(req, apiToken)
+	req, _ = http.NewRequest(http.MethodGet, path.Join(userPath, user.Username), nil)
+	setBearerForReq(req, token)
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusFound, rr)
-	assert.Equal(t, webClientLoginPath, rr.Header().Get("Location"))
-
-	req, _ = http.NewRequest(http.MethodGet, webClientProfilePath, nil)
-	setJWTCookieForReq(req, webToken)
+	checkResponseCode(t, http.StatusOK, rr)
+	var updatedUser dataprovider.User
+	err = render.DecodeJSON(rr.Body, &updatedUser)
+	assert.NoError(t, err)
+	if val, ok := updatedUser.Permissions["/otherdir"]; ok {
+		assert.True(t, util.IsStringInSlice(dataprovider.PermListItems, val))
+		assert.Equal(t, 1, len(val))
+	} else {
+		assert.Fail(t, "expected dir not found in permissions")
+	}
+	req, _ = http.NewRequest(http.MethodDelete, path.Join(userPath, user.Username), nil)
+	setBearerForReq(req, token)
 	rr = executeRequest(req)
 	checkResponseCode(t, http.StatusOK, rr)
+}
 
-	_, err = httpdtest.RemoveUser(user, http.StatusOK)
+func TestUpdateUserInvalidJsonMock(t *testing.T) {
+	token, err := getJWTAPITokenFromTestServer(defaultTokenAuthUser, defaultTokenAuthPass)
 	assert.NoError(t, err)
-	err = os.RemoveAll(user.GetHomeDir())
+	user := getTestUser()
+	userAsJSON := getUserAsJSON(t, user)
+	req, _ := http.NewRequest(http.MethodPost, userPath, bytes.NewBuffer(userAsJSON))
+	setBearerForReq(req, token)
+	rr := executeRequest(req)
+	checkResponseCode(t, http.StatusCreated, rr)
+	err = render.DecodeJSON(rr.Body, &user)
 	assert.NoError(t, err)
+	req, _ = http.NewRequest(http.MethodPut, path.Join(userPath, user.Username), bytes.NewBuffer([]byte("Invalid json")))
+	setBearerForReq(req, token)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusBadRequest, rr)
+	req, _ = http.NewRequest(http.MethodDelete, path.Join(userPath, user.Username), nil)
+	setBearerForReq(req, token)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
 }
 
-func TestWebClientLoginMock(t *testing.T) {
-	_, err := getJWTWebClientTokenFromTestServer(defaultUsername, defaultPassword)
-	assert.Error(t, err)
-	user, _, err := httpdtest.AddUser(getTestUser(), http.StatusCreated)
-	assert.NoError(t, err)
-	webToken, err := getJWTWebClientTokenFromTestServer(defaultUsername, defaultPassword)
+func TestUpdateUserInvalidParamsMock(t *testing.T) {
+	token, err := getJWTAPITokenFromTestServer(defaultTokenAuthUser, defaultTokenAuthPass)
 	assert.NoError(t, err)
-	// a web token is not valid for API or WebAdmin usage
-	req, _ := http.NewRequest(http.MethodGet, serverStatusPath, nil)
-	setBearerForReq(req, webToken)
+	user := getTestUser()
+	userAsJSON := getUserAsJSON(t, user)
+	req, _ := http.NewRequest(http.MethodPost, userPath, bytes.NewBuffer(userAsJSON))
+	setBearerForReq(req, token)
 	rr := executeRequest(req)
-	checkResponseCode(t, http.StatusUnauthorized, rr)
-	assert.Contains(t, rr.Body.String(), "Your token audience is not valid")
-	req, _ = http.NewRequest(http.MethodGet, webStatusPath, nil)
-	setJWTCookieForReq(req, webToken)
+	checkResponseCode(t, http.StatusCreated, rr)
+	err = render.DecodeJSON(rr.Body, &user)
+	assert.NoError(t, err)
+	user.HomeDir = ""
+	userAsJSON = getUserAsJSON(t, user)
+	req, _ = http.NewRequest(http.MethodPut, path.Join(userPath, user.Username), bytes.NewBuffer(userAsJSON))
+	setBearerForReq(req, token)
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusFound, rr)
-	assert.Equal(t, webLoginPath, rr.Header().Get("Location"))
-	// bearer should not work
-	req, _ = http.NewRequest(http.MethodGet, webClientProfilePath, nil)
-	setBearerForReq(req, webToken)
+	checkResponseCode(t, http.StatusBadRequest, rr)
+	userID := user.ID
+	user.ID = 0
+	user.CreatedAt = 0
+	userAsJSON = getUserAsJSON(t, user)
+	req, _ = http.NewRequest(http.MethodPut, path.Join(userPath, user.Username), bytes.NewBuffer(userAsJSON))
+	setBearerForReq(req, token)
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusFound, rr)
-	assert.Equal(t, webClientLoginPath, rr.Header().Get("Location"))
-	// now try to render client pages
-	req, _ = http.NewRequest(http.MethodGet, webClientProfilePath, nil)
-	setJWTCookieForReq(req, webToken)
+	checkResponseCode(t, http.StatusBadRequest, rr)
+	user.ID = userID
+	req, _ = http.NewRequest(http.MethodPut, userPath+"/0", bytes.NewBuffer(userAsJSON))
+	setBearerForReq(req, token)
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusOK, rr)
-	req, _ = http.NewRequest(http.MethodGet, webClientFilesPath, nil)
-	setJWTCookieForReq(req, webToken)
+	checkResponseCode(t, http.StatusNotFound, rr)
+	req, _ = http.NewRequest(http.MethodDelete, path.Join(userPath, user.Username), nil)
+	setBearerForReq(req, token)
 	rr = executeRequest(req)
 	checkResponseCode(t, http.StatusOK, rr)
-	// now logout
-	req, _ = http.NewRequest(http.MethodGet, webClientLogoutPath, nil)
-	setJWTCookieForReq(req, webToken)
-	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusFound, rr)
-	assert.Equal(t, webClientLoginPath, rr.Header().Get("Location"))
-	req, _ = http.NewRequest(http.MethodGet, webClientProfilePath, nil)
-	setJWTCookieForReq(req, webToken)
-	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusFound, rr)
-	assert.Equal(t, webClientLoginPath, rr.Header().Get("Location"))
+}
 
-	// get a new token and use it after removing the user
-	webToken, err = getJWTWebClientTokenFromTestServer(defaultUsername, defaultPassword)
-	assert.NoError(t, err)
-	apiUserToken, err := getJWTAPIUserTokenFromTestServer(defaultUsername, defaultPassword)
-	assert.NoError(t, err)
-	_, err = httpdtest.RemoveUser(user, http.StatusOK)
+func TestGetAdminsMock(t *testing.T) {
+	token, err := getJWTAPITokenFromTestServer(defaultTokenAuthUser, defaultTokenAuthPass)
 	assert.NoError(t, err)
-	err = os.RemoveAll(user.GetHomeDir())
+	admin := getTestAdmin()
+	admin.Username = altAdminUsername
+	asJSON, err := json.Marshal(admin)
 	assert.NoError(t, err)
+	req, _ := http.NewRequest(http.MethodPost, adminPath, bytes.NewBuffer(asJSON))
+	setBearerForReq(req, token)
+	rr := executeRequest(req)
+	checkResponseCode(t, http.StatusCreated, rr)
 
-	req, _ = http.NewRequest(http.MethodGet, webClientProfilePath, nil)
-	setJWTCookieForReq(req, webToken)
+	req, _ = http.NewRequest(http.MethodGet, adminPath+"?limit=510&offset=0&order=ASC", nil)
+	setBearerForReq(req, token)
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusInternalServerError, rr)
-	req, _ = http.NewRequest(http.MethodGet, webClientFilesPath, nil)
-	setJWTCookieForReq(req, webToken)
+	checkResponseCode(t, http.StatusOK, rr)
+	var admins []dataprovider.Admin
+	err = render.DecodeJSON(rr.Body, &admins)
+	assert.NoError(t, err)
+	assert.GreaterOrEqual(t, len(admins), 1)
+	firtAdmin := admins[0].Username
+	req, _ = http.NewRequest(http.MethodGet, adminPath+"?limit=510&offset=0&order=DESC", nil)
+	setBearerForReq(req, token)
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusNotFound, rr)
-	assert.Contains(t, rr.Body.String(), "Unable to retrieve your user")
+	checkResponseCode(t, http.StatusOK, rr)
+	admins = nil
+	err = render.DecodeJSON(rr.Body, &admins)
+	assert.NoError(t, err)
+	assert.GreaterOrEqual(t, len(admins), 1)
+	assert.NotEqual(t, firtAdmin, admins[0].Username)
 
-	req, _ = http.NewRequest(http.MethodGet, webClientDirsPath, nil)
-	setJWTCookieForReq(req, webToken)
+	req, _ = http.NewRequest(http.MethodGet, adminPath+"?limit=510&offset=1&order=ASC", nil)
+	setBearerForReq(req, token)
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusNotFound, rr)
-	assert.Contains(t, rr.Body.String(), "Unable to retrieve your user")
+	checkResponseCode(t, http.StatusOK, rr)
+	admins = nil
+	err = render.DecodeJSON(rr.Body, &admins)
+	assert.NoError(t, err)
+	assert.GreaterOrEqual(t, len(admins), 1)
+	assert.NotEqual(t, firtAdmin, admins[0].Username)
 
-	req, _ = http.NewRequest(http.MethodGet, webClientDownloadZipPath, nil)
-	setJWTCookieForReq(req, webToken)
+	req, _ = http.NewRequest(http.MethodGet, adminPath+"?limit=a&offset=0&order=ASC", nil)
+	setBearerForReq(req, token)
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusNotFound, rr)
-	assert.Contains(t, rr.Body.String(), "Unable to retrieve your user")
-
-	req, _ = http.NewRequest(http.MethodGet, userDirsPath, nil)
-	setBearerForReq(req, apiUserToken)
+	checkResponseCode(t, http.StatusBadRequest, rr)
+	req, _ = http.NewRequest(http.MethodGet, adminPath+"?limit=1&offset=a&order=ASC", nil)
+	setBearerForReq(req, token)
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusNotFound, rr)
-	assert.Contains(t, rr.Body.String(), "Unable to retrieve your user")
-
-	req, _ = http.NewRequest(http.MethodGet, userFilesPath, nil)
-	setBearerForReq(req, apiUserToken)
+	checkResponseCode(t, http.StatusBadRequest, rr)
+	req, _ = http.NewRequest(http.MethodGet, adminPath+"?limit=1&offset=0&order=ASCa", nil)
+	setBearerForReq(req, token)
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusNotFound, rr)
-	assert.Contains(t, rr.Body.String(), "Unable to retrieve your user")
+	checkResponseCode(t, http.StatusBadRequest, rr)
 
-	req, _ = http.NewRequest(http.MethodPost, userStreamZipPath, bytes.NewBuffer([]byte(`{}`)))
-	setBearerForReq(req, apiUserToken)
+	req, _ = http.NewRequest(http.MethodDelete, path.Join(adminPath, admin.Username), nil)
+	setBearerForReq(req, token)
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusNotFound, rr)
-	assert.Contains(t, rr.Body.String(), "Unable to retrieve your user")
+	checkResponseCode(t, http.StatusOK, rr)
+}
 
-	req, _ = http.NewRequest(http.MethodGet, userPublicKeysPath, nil)
-	setBearerForReq(req, apiUserToken)
+func TestGetUsersMock(t *testing.T) {
+	token, err := getJWTAPITokenFromTestServer(defaultTokenAuthUser, defaultTokenAuthPass)
+	assert.NoError(t, err)
+	user := getTestUser()
+	userAsJSON := getUserAsJSON(t, user)
+	req, _ := http.NewRequest(http.MethodPost, userPath, bytes.NewBuffer(userAsJSON))
+	setBearerForReq(req, token)
+	rr := executeRequest(req)
+	checkResponseCode(t, http.StatusCreated, rr)
+	err = render.DecodeJSON(rr.Body, &user)
+	assert.NoError(t, err)
+	req, _ = http.NewRequest(http.MethodGet, userPath+"?limit=510&offset=0&order=ASC", nil)
+	setBearerForReq(req, token)
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusNotFound, rr)
-	assert.Contains(t, rr.Body.String(), "Unable to retrieve your user")
-
-	req, _ = http.NewRequest(http.MethodPut, userPublicKeysPath, bytes.NewBuffer([]byte(`{}`)))
-	setBearerForReq(req, apiUserToken)
+	checkResponseCode(t, http.StatusOK, rr)
+	var users []dataprovider.User
+	err = render.DecodeJSON(rr.Body, &users)
+	assert.NoError(t, err)
+	assert.GreaterOrEqual(t, len(users), 1)
+	req, _ = http.NewRequest(http.MethodGet, userPath+"?limit=a&offset=0&order=ASC", nil)
+	setBearerForReq(req, token)
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusNotFound, rr)
-	assert.Contains(t, rr.Body.String(), "Unable to retrieve your user")
+	checkResponseCode(t, http.StatusBadRequest, rr)
+	req, _ = http.NewRequest(http.MethodGet, userPath+"?limit=1&offset=a&order=ASC", nil)
+	setBearerForReq(req, token)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusBadRequest, rr)
+	req, _ = http.NewRequest(http.MethodGet, userPath+"?limit=1&offset=0&order=ASCa", nil)
+	setBearerForReq(req, token)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusBadRequest, rr)
 
-	csrfToken, err := getCSRFToken(httpBaseURL + webClientLoginPath)
-	assert.NoError(t, err)
-	form := make(url.Values)
-	form.Set("public_keys", testPubKey)
-	form.Set(csrfFormToken, csrfToken)
-	req, _ = http.NewRequest(http.MethodPost, webClientProfilePath, bytes.NewBuffer([]byte(form.Encode())))
-	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
-	setJWTCookieForReq(req, webToken)
+	req, _ = http.NewRequest(http.MethodDelete, path.Join(userPath, user.Username), nil)
+	setBearerForReq(req, token)
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusInternalServerError, rr)
+	checkResponseCode(t, http.StatusOK, rr)
 }
 
-func TestWebClientLoginErrorsMock(t *testing.T) {
-	form := getLoginForm("", "", "")
-	req, _ := http.NewRequest(http.MethodPost, webClientLoginPath, bytes.NewBuffer([]byte(form.Encode())))
-	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+func TestDeleteUserInvalidParamsMock(t *testing.T) {
+	token, err := getJWTAPITokenFromTestServer(defaultTokenAuthUser, defaultTokenAuthPass)
+	assert.NoError(t, err)
+	req, _ := http.NewRequest(http.MethodDelete, userPath+"/0", nil)
+	setBearerForReq(req, token)
 	rr := executeRequest(req)
-	assert.Equal(t, http.StatusOK, rr.Code)
-	assert.Contains(t, rr.Body.String(), "Invalid credentials")
-
-	form = getLoginForm(defaultUsername, defaultPassword, "")
-	req, _ = http.NewRequest(http.MethodPost, webClientLoginPath, bytes.NewBuffer([]byte(form.Encode())))
-	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
-	rr = executeRequest(req)
-	assert.Equal(t, http.StatusOK, rr.Code)
-	assert.Contains(t, rr.Body.String(), "unable to verify form token")
+	checkResponseCode(t, http.StatusNotFound, rr)
 }
 
-func TestWebClientMaxConnections(t *testing.T) {
-	oldValue := common.Config.MaxTotalConnections
-	common.Config.MaxTotalConnections = 1
-
-	user, _, err := httpdtest.AddUser(getTestUser(), http.StatusCreated)
+func TestGetQuotaScansMock(t *testing.T) {
+	token, err := getJWTAPITokenFromTestServer(defaultTokenAuthUser, defaultTokenAuthPass)
 	assert.NoError(t, err)
-
-	webToken, err := getJWTWebClientTokenFromTestServer(defaultUsername, defaultPassword)
+	req, err := http.NewRequest(http.MethodGet, quotaScanPath, nil)
 	assert.NoError(t, err)
-	req, _ := http.NewRequest(http.MethodGet, webClientFilesPath, nil)
-	setJWTCookieForReq(req, webToken)
+	setBearerForReq(req, token)
 	rr := executeRequest(req)
 	checkResponseCode(t, http.StatusOK, rr)
+}
 
-	// now add a fake connection
-	fs := vfs.NewOsFs("id", os.TempDir(), "")
-	connection := &httpd.Connection{
-		BaseConnection: common.NewBaseConnection(fs.ConnectionID(), common.ProtocolHTTP, "", "", user),
+func TestStartQuotaScanMock(t *testing.T) {
+	token, err := getJWTAPITokenFromTestServer(defaultTokenAuthUser, defaultTokenAuthPass)
+	assert.NoError(t, err)
+	user := getTestUser()
+	userAsJSON := getUserAsJSON(t, user)
+	req, _ := http.NewRequest(http.MethodPost, userPath, bytes.NewBuffer(userAsJSON))
+	setBearerForReq(req, token)
+	rr := executeRequest(req)
+	checkResponseCode(t, http.StatusCreated, rr)
+	err = render.DecodeJSON(rr.Body, &user)
+	assert.NoError(t, err)
+	_, err = os.Stat(user.HomeDir)
+	if err == nil {
+		err = os.Remove(user.HomeDir)
+		assert.NoError(t, err)
 	}
-	common.Connections.Add(connection)
-
-	_, err = getJWTWebClientTokenFromTestServer(defaultUsername, defaultPassword)
-	assert.Error(t, err)
+	// simulate a duplicate quota scan
+	common.QuotaScans.AddUserQuotaScan(user.Username)
+	req, _ = http.NewRequest(http.MethodPost, path.Join(quotasBasePath, "users", user.Username, "scan"), nil)
+	setBearerForReq(req, token)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusConflict, rr)
+	assert.True(t, common.QuotaScans.RemoveUserQuotaScan(user.Username))
 
-	req, _ = http.NewRequest(http.MethodGet, webClientFilesPath, nil)
-	setJWTCookieForReq(req, webToken)
+	req, _ = http.NewRequest(http.MethodPost, path.Join(quotasBasePath, "users", user.Username, "scan"), nil)
+	setBearerForReq(req, token)
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusForbidden, rr)
-	assert.Contains(t, rr.Body.String(), "configured connections limit reached")
+	checkResponseCode(t, http.StatusAccepted, rr)
 
-	common.Connections.Remove(connection.GetID())
-	_, err = httpdtest.RemoveUser(user, http.StatusOK)
-	assert.NoError(t, err)
-	err = os.RemoveAll(user.GetHomeDir())
-	assert.NoError(t, err)
-	assert.Len(t, common.Connections.GetStats(), 0)
+	waitForUsersQuotaScan(t, token)
 
-	common.Config.MaxTotalConnections = oldValue
-}
+	_, err = os.Stat(user.HomeDir)
+	if err != nil && os.IsNotExist(err) {
+		err = os.MkdirAll(user.HomeDir, os.ModePerm)
+		assert.NoError(t, err)
+	}
+	req, _ = http.NewRequest(http.MethodPost, path.Join(quotasBasePath, "users", user.Username, "scan"), nil)
+	setBearerForReq(req, token)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusAccepted, rr)
 
-func TestDefender(t *testing.T) {
-	oldConfig := config.GetCommonConfig()
+	waitForUsersQuotaScan(t, token)
 
-	cfg := config.GetCommonConfig()
-	cfg.DefenderConfig.Enabled = true
-	cfg.DefenderConfig.Threshold = 3
-	cfg.DefenderConfig.ScoreLimitExceeded = 2
+	req, _ = http.NewRequest(http.MethodPost, path.Join(quotasBasePath, "users", user.Username, "scan"), nil)
+	setBearerForReq(req, token)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusAccepted, rr)
 
-	err := common.Initialize(cfg)
-	assert.NoError(t, err)
+	waitForUsersQuotaScan(t, token)
 
-	user, _, err := httpdtest.AddUser(getTestUser(), http.StatusCreated)
+	asJSON, err := json.Marshal(user)
 	assert.NoError(t, err)
+	req, _ = http.NewRequest(http.MethodPost, quotaScanCompatPath, bytes.NewBuffer(asJSON))
+	setBearerForReq(req, token)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusAccepted, rr)
 
-	remoteAddr := "172.16.5.6:9876"
-
-	webAdminToken, err := getJWTWebTokenFromTestServer(defaultTokenAuthUser, defaultTokenAuthPass)
-	assert.NoError(t, err)
-	webToken, err := getJWTWebClientTokenFromTestServerWithAddr(defaultUsername, defaultPassword, remoteAddr)
-	assert.NoError(t, err)
+	waitForUsersQuotaScan(t, token)
 
-	req, _ := http.NewRequest(http.MethodGet, webClientFilesPath, nil)
-	req.RemoteAddr = remoteAddr
-	req.RequestURI = webClientFilesPath
-	setJWTCookieForReq(req, webToken)
-	rr := executeRequest(req)
+	req, _ = http.NewRequest(http.MethodDelete, path.Join(userPath, user.Username), nil)
+	setBearerForReq(req, token)
+	rr = executeRequest(req)
 	checkResponseCode(t, http.StatusOK, rr)
+	err = os.RemoveAll(user.GetHomeDir())
+	assert.NoError(t, err)
+}
 
-	for i := 0; i < 3; i++ {
-		_, err = getJWTWebClientTokenFromTestServerWithAddr(defaultUsername, "wrong pwd", remoteAddr)
-		assert.Error(t, err)
+func TestUpdateFolderQuotaUsageMock(t *testing.T) {
+	token, err := getJWTAPITokenFromTestServer(defaultTokenAuthUser, defaultTokenAuthPass)
+	assert.NoError(t, err)
+	mappedPath := filepath.Join(os.TempDir(), "vfolder")
+	folderName := filepath.Base(mappedPath)
+	f := vfs.BaseVirtualFolder{
+		MappedPath: mappedPath,
+		Name:       folderName,
 	}
-
-	_, err = getJWTWebClientTokenFromTestServerWithAddr(defaultUsername, defaultPassword, remoteAddr)
-	assert.Error(t, err)
-	req, _ = http.NewRequest(http.MethodGet, webClientFilesPath, nil)
-	req.RemoteAddr = remoteAddr
-	req.RequestURI = webClientFilesPath
-	setJWTCookieForReq(req, webToken)
+	usedQuotaFiles := 1
+	usedQuotaSize := int64(65535)
+	f.UsedQuotaFiles = usedQuotaFiles
+	f.UsedQuotaSize = usedQuotaSize
+	var folder vfs.BaseVirtualFolder
+	folderAsJSON, err := json.Marshal(f)
+	assert.NoError(t, err)
+	req, _ := http.NewRequest(http.MethodPost, folderPath, bytes.NewBuffer(folderAsJSON))
+	setBearerForReq(req, token)
+	rr := executeRequest(req)
+	checkResponseCode(t, http.StatusCreated, rr)
+	err = render.DecodeJSON(rr.Body, &folder)
+	assert.NoError(t, err)
+	req, _ = http.NewRequest(http.MethodPut, path.Join(quotasBasePath, "folders", folder.Name, "usage"), bytes.NewBuffer(folderAsJSON))
+	setBearerForReq(req, token)
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusForbidden, rr)
-	assert.Contains(t, rr.Body.String(), "your IP address is banned")
-
-	req, _ = http.NewRequest(http.MethodGet, webUsersPath, nil)
-	req.RemoteAddr = remoteAddr
-	req.RequestURI = webUsersPath
-	setJWTCookieForReq(req, webAdminToken)
+	checkResponseCode(t, http.StatusOK, rr)
+	req, _ = http.NewRequest(http.MethodPut, updateFolderUsedQuotaCompatPath, bytes.NewBuffer(folderAsJSON))
+	setBearerForReq(req, token)
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusForbidden, rr)
-	assert.Contains(t, rr.Body.String(), "your IP address is banned")
-
-	req, _ = http.NewRequest(http.MethodGet, webClientFilesPath, nil)
-	req.RemoteAddr = remoteAddr
-	req.Header.Set("X-Real-IP", "127.0.0.1:2345")
-	setJWTCookieForReq(req, webToken)
+	checkResponseCode(t, http.StatusOK, rr)
+	var folderGet vfs.BaseVirtualFolder
+	req, _ = http.NewRequest(http.MethodGet, path.Join(folderPath, folderName), nil)
+	setBearerForReq(req, token)
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusForbidden, rr)
-	assert.Contains(t, rr.Body.String(), "your IP address is banned")
-
-	_, err = httpdtest.RemoveUser(user, http.StatusOK)
-	assert.NoError(t, err)
-	err = os.RemoveAll(user.GetHomeDir())
+	checkResponseCode(t, http.StatusOK, rr)
+	err = render.DecodeJSON(rr.Body, &folderGet)
 	assert.NoError(t, err)
-
-	err = common.Initialize(oldConfig)
+	assert.Equal(t, usedQuotaFiles, folderGet.UsedQuotaFiles)
+	assert.Equal(t, usedQuotaSize, folderGet.UsedQuotaSize)
+	// now update only quota size
+	f.UsedQuotaFiles = 0
+	folderAsJSON, err = json.Marshal(f)
 	assert.NoError(t, err)
-}
-
-func TestPostConnectHook(t *testing.T) {
-	if runtime.GOOS == osWindows {
-		t.Skip("this test is not available on Windows")
-	}
-	common.Config.PostConnectHook = postConnectPath
-
-	u := getTestUser()
-	u.Filters.AllowAPIKeyAuth = true
-	user, _, err := httpdtest.AddUser(u, http.StatusCreated)
+	req, _ = http.NewRequest(http.MethodPut, path.Join(quotasBasePath, "folders", folder.Name, "usage")+"?mode=add",
+		bytes.NewBuffer(folderAsJSON))
+	setBearerForReq(req, token)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
+	folderGet = vfs.BaseVirtualFolder{}
+	req, _ = http.NewRequest(http.MethodGet, path.Join(folderPath, folderName), nil)
+	setBearerForReq(req, token)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
+	err = render.DecodeJSON(rr.Body, &folderGet)
 	assert.NoError(t, err)
-	apiKey, _, err := httpdtest.AddAPIKey(dataprovider.APIKey{
-		Name:  "name",
-		Scope: dataprovider.APIKeyScopeUser,
-		User:  user.Username,
-	}, http.StatusCreated)
+	assert.Equal(t, usedQuotaFiles, folderGet.UsedQuotaFiles)
+	assert.Equal(t, usedQuotaSize*2, folderGet.UsedQuotaSize)
+	// now update only quota files
+	f.UsedQuotaSize = 0
+	f.UsedQuotaFiles = 1
+	folderAsJSON, err = json.Marshal(f)
 	assert.NoError(t, err)
-	err = os.WriteFile(postConnectPath, getExitCodeScriptContent(0), os.ModePerm)
+	req, _ = http.NewRequest(http.MethodPut, path.Join(quotasBasePath, "folders", folder.Name, "usage")+"?mode=add",
+		bytes.NewBuffer(folderAsJSON))
+	setBearerForReq(req, token)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
+	folderGet = vfs.BaseVirtualFolder{}
+	req, _ = http.NewRequest(http.MethodGet, path.Join(folderPath, folderName), nil)
+	setBearerForReq(req, token)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
+	err = render.DecodeJSON(rr.Body, &folderGet)
 	assert.NoError(t, err)
+	assert.Equal(t, usedQuotaFiles*2, folderGet.UsedQuotaFiles)
+	assert.Equal(t, usedQuotaSize*2, folderGet.UsedQuotaSize)
+	req, _ = http.NewRequest(http.MethodPut, updateFolderUsedQuotaCompatPath, bytes.NewBuffer([]byte("string")))
+	setBearerForReq(req, token)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusBadRequest, rr)
+	req, _ = http.NewRequest(http.MethodPut, path.Join(quotasBasePath, "folders", folder.Name, "usage"),
+		bytes.NewBuffer([]byte("not a json")))
+	setBearerForReq(req, token)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusBadRequest, rr)
 
-	_, err = getJWTWebClientTokenFromTestServer(defaultUsername, defaultPassword)
-	assert.NoError(t, err)
+	assert.True(t, common.QuotaScans.AddVFolderQuotaScan(folderName))
+	req, _ = http.NewRequest(http.MethodPut, path.Join(quotasBasePath, "folders", folder.Name, "usage"),
+		bytes.NewBuffer(folderAsJSON))
+	setBearerForReq(req, token)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusConflict, rr)
+	assert.True(t, common.QuotaScans.RemoveVFolderQuotaScan(folderName))
 
-	_, err = getJWTAPIUserTokenFromTestServer(defaultUsername, defaultPassword)
-	assert.NoError(t, err)
+	req, _ = http.NewRequest(http.MethodDelete, path.Join(folderPath, folderName), nil)
+	setBearerForReq(req, token)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
+}
 
-	req, err := http.NewRequest(http.MethodGet, userDirsPath, nil)
+func TestStartFolderQuotaScanMock(t *testing.T) {
+	token, err := getJWTAPITokenFromTestServer(defaultTokenAuthUser, defaultTokenAuthPass)
 	assert.NoError(t, err)
-	setAPIKeyForReq(req, apiKey.Key, "")
+	mappedPath := filepath.Join(os.TempDir(), "vfolder")
+	folderName := filepath.Base(mappedPath)
+	folder := vfs.BaseVirtualFolder{
+		Name:       folderName,
+		MappedPath: mappedPath,
+	}
+	folderAsJSON, err := json.Marshal(folder)
+	assert.NoError(t, err)
+	req, _ := http.NewRequest(http.MethodPost, folderPath, bytes.NewBuffer(folderAsJSON))
+	setBearerForReq(req, token)
 	rr := executeRequest(req)
-	checkResponseCode(t, http.StatusOK, rr)
+	checkResponseCode(t, http.StatusCreated, rr)
+	_, err = os.Stat(mappedPath)
+	if err == nil {
+		err = os.Remove(mappedPath)
+		assert.NoError(t, err)
+	}
+	// simulate a duplicate quota scan
+	common.QuotaScans.AddVFolderQuotaScan(folderName)
+	req, _ = http.NewRequest(http.MethodPost, path.Join(quotasBasePath, "folders", folder.Name, "scan"), nil)
+	setBearerForReq(req, token)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusConflict, rr)
+	assert.True(t, common.QuotaScans.RemoveVFolderQuotaScan(folderName))
+	// and now a real quota scan
+	_, err = os.Stat(mappedPath)
+	if err != nil && os.IsNotExist(err) {
+		err = os.MkdirAll(mappedPath, os.ModePerm)
+		assert.NoError(t, err)
+	}
+	req, _ = http.NewRequest(http.MethodPost, path.Join(quotasBasePath, "folders", folder.Name, "scan"), nil)
+	setBearerForReq(req, token)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusAccepted, rr)
+	waitForFoldersQuotaScanPath(t, token)
 
-	err = os.WriteFile(postConnectPath, getExitCodeScriptContent(1), os.ModePerm)
+	asJSON, err := json.Marshal(folder)
 	assert.NoError(t, err)
+	req, _ = http.NewRequest(http.MethodPost, quotaScanVFolderCompatPath, bytes.NewBuffer(asJSON))
+	setBearerForReq(req, token)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusAccepted, rr)
+	waitForFoldersQuotaScanPath(t, token)
 
-	_, err = getJWTWebClientTokenFromTestServer(defaultUsername, defaultPassword)
-	assert.Error(t, err)
-
-	_, err = getJWTAPIUserTokenFromTestServer(defaultUsername, defaultPassword)
-	assert.Error(t, err)
+	// cleanup
 
-	req, err = http.NewRequest(http.MethodGet, userDirsPath, nil)
-	assert.NoError(t, err)
-	setAPIKeyForReq(req, apiKey.Key, "")
+	req, _ = http.NewRequest(http.MethodDelete, path.Join(folderPath, folderName), nil)
+	setBearerForReq(req, token)
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusUnauthorized, rr)
-
-	_, err = httpdtest.RemoveUser(user, http.StatusOK)
+	checkResponseCode(t, http.StatusOK, rr)
+	err = os.RemoveAll(folderPath)
 	assert.NoError(t, err)
-	err = os.RemoveAll(user.GetHomeDir())
+	err = os.RemoveAll(mappedPath)
 	assert.NoError(t, err)
+}
 
-	common.Config.PostConnectHook = ""
+func TestStartQuotaScanNonExistentUserMock(t *testing.T) {
+	token, err := getJWTAPITokenFromTestServer(defaultTokenAuthUser, defaultTokenAuthPass)
+	assert.NoError(t, err)
+	user := getTestUser()
+
+	req, _ := http.NewRequest(http.MethodPost, path.Join(quotasBasePath, "users", user.Username, "scan"), nil)
+	setBearerForReq(req, token)
+	rr := executeRequest(req)
+	checkResponseCode(t, http.StatusNotFound, rr)
 }
 
-func TestMaxSessions(t *testing.T) {
-	u := getTestUser()
-	u.MaxSessions = 1
-	user, _, err := httpdtest.AddUser(u, http.StatusCreated)
+func TestStartQuotaScanBadUserMock(t *testing.T) {
+	token, err := getJWTAPITokenFromTestServer(defaultTokenAuthUser, defaultTokenAuthPass)
 	assert.NoError(t, err)
-	_, err = getJWTWebClientTokenFromTestServer(defaultUsername, defaultPassword)
+	req, _ := http.NewRequest(http.MethodPost, quotaScanCompatPath, bytes.NewBuffer([]byte("invalid json")))
+	setBearerForReq(req, token)
+	rr := executeRequest(req)
+	checkResponseCode(t, http.StatusBadRequest, rr)
+}
+
+func TestStartQuotaScanBadFolderMock(t *testing.T) {
+	token, err := getJWTAPITokenFromTestServer(defaultTokenAuthUser, defaultTokenAuthPass)
 	assert.NoError(t, err)
-	_, err = getJWTAPIUserTokenFromTestServer(defaultUsername, defaultPassword)
+	req, _ := http.NewRequest(http.MethodPost, quotaScanVFolderCompatPath, bytes.NewBuffer([]byte("invalid json")))
+	setBearerForReq(req, token)
+	rr := executeRequest(req)
+	checkResponseCode(t, http.StatusBadRequest, rr)
+}
+
+func TestStartQuotaScanNonExistentFolderMock(t *testing.T) {
+	token, err := getJWTAPITokenFromTestServer(defaultTokenAuthUser, defaultTokenAuthPass)
 	assert.NoError(t, err)
-	// now add a fake connection
-	fs := vfs.NewOsFs("id", os.TempDir(), "")
-	connection := &httpd.Connection{
-		BaseConnection: common.NewBaseConnection(fs.ConnectionID(), common.ProtocolHTTP, "", "", user),
+	folder := vfs.BaseVirtualFolder{
+		MappedPath: os.TempDir(),
+		Name:       "afolder",
 	}
-	common.Connections.Add(connection)
-	_, err = getJWTWebClientTokenFromTestServer(defaultUsername, defaultPassword)
-	assert.Error(t, err)
-	_, err = getJWTAPIUserTokenFromTestServer(defaultUsername, defaultPassword)
-	assert.Error(t, err)
-	common.Connections.Remove(connection.GetID())
-	_, err = httpdtest.RemoveUser(user, http.StatusOK)
-	assert.NoError(t, err)
-	err = os.RemoveAll(user.GetHomeDir())
-	assert.NoError(t, err)
-	assert.Len(t, common.Connections.GetStats(), 0)
+	req, _ := http.NewRequest(http.MethodPost, path.Join(quotasBasePath, "folders", folder.Name, "scan"), nil)
+	setBearerForReq(req, token)
+	rr := executeRequest(req)
+	checkResponseCode(t, http.StatusNotFound, rr)
 }
 
-func TestLoginInvalidFs(t *testing.T) {
-	u := getTestUser()
-	u.Filters.AllowAPIKeyAuth = true
-	u.FsConfig.Provider = sdk.GCSFilesystemProvider
-	u.FsConfig.GCSConfig.Bucket = "test"
-	u.FsConfig.GCSConfig.Credentials = kms.NewPlainSecret("invalid JSON for credentials")
-	user, _, err := httpdtest.AddUser(u, http.StatusCreated)
+func TestGetFoldersMock(t *testing.T) {
+	token, err := getJWTAPITokenFromTestServer(defaultTokenAuthUser, defaultTokenAuthPass)
 	assert.NoError(t, err)
-	apiKey, _, err := httpdtest.AddAPIKey(dataprovider.APIKey{
-		Name:  "testk",
-		Scope: dataprovider.APIKeyScopeUser,
-		User:  u.Username,
-	}, http.StatusCreated)
-	assert.NoError(t, err)
-
-	credentialsFile := filepath.Join(credentialsPath, fmt.Sprintf("%v_gcs_credentials.json", u.Username))
-	if !filepath.IsAbs(credentialsFile) {
-		credentialsFile = filepath.Join(configDir, credentialsFile)
+	mappedPath := filepath.Join(os.TempDir(), "vfolder")
+	folderName := filepath.Base(mappedPath)
+	folder := vfs.BaseVirtualFolder{
+		Name:       folderName,
+		MappedPath: mappedPath,
 	}
+	folderAsJSON, err := json.Marshal(folder)
+	assert.NoError(t, err)
+	req, _ := http.NewRequest(http.MethodPost, folderPath, bytes.NewBuffer(folderAsJSON))
+	setBearerForReq(req, token)
+	rr := executeRequest(req)
+	checkResponseCode(t, http.StatusCreated, rr)
+	err = render.DecodeJSON(rr.Body, &folder)
+	assert.NoError(t, err)
 
-	// now remove the credentials file so the filesystem creation will fail
-	err = os.Remove(credentialsFile)
+	var folders []vfs.BaseVirtualFolder
+	url, err := url.Parse(folderPath + "?limit=510&offset=0&order=DESC")
+	assert.NoError(t, err)
+	req, _ = http.NewRequest(http.MethodGet, url.String(), nil)
+	setBearerForReq(req, token)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
+	err = render.DecodeJSON(rr.Body, &folders)
 	assert.NoError(t, err)
+	assert.GreaterOrEqual(t, len(folders), 1)
+	req, _ = http.NewRequest(http.MethodGet, folderPath+"?limit=a&offset=0&order=ASC", nil)
+	setBearerForReq(req, token)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusBadRequest, rr)
+	req, _ = http.NewRequest(http.MethodGet, folderPath+"?limit=1&offset=a&order=ASC", nil)
+	setBearerForReq(req, token)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusBadRequest, rr)
+	req, _ = http.NewRequest(http.MethodGet, folderPath+"?limit=1&offset=0&order=ASCa", nil)
+	setBearerForReq(req, token)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusBadRequest, rr)
 
-	_, err = getJWTWebClientTokenFromTestServer(defaultUsername, defaultPassword)
-	assert.Error(t, err)
+	req, _ = http.NewRequest(http.MethodDelete, path.Join(folderPath, folderName), nil)
+	setBearerForReq(req, token)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
+}
 
-	_, err = getJWTAPIUserTokenFromTestServer(defaultUsername, defaultPassword)
-	assert.Error(t, err)
+func TestGetVersionMock(t *testing.T) {
+	req, _ := http.NewRequest(http.MethodGet, versionPath, nil)
+	rr := executeRequest(req)
+	checkResponseCode(t, http.StatusUnauthorized, rr)
+	token, err := getJWTAPITokenFromTestServer(defaultTokenAuthUser, defaultTokenAuthPass)
+	assert.NoError(t, err)
+	req, _ = http.NewRequest(http.MethodGet, versionPath, nil)
+	setBearerForReq(req, token)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
+	req, _ = http.NewRequest(http.MethodGet, versionPath, nil)
+	setBearerForReq(req, "abcde")
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusUnauthorized, rr)
+}
 
-	req, err := http.NewRequest(http.MethodGet, userDirsPath, nil)
+func TestGetConnectionsMock(t *testing.T) {
+	token, err := getJWTAPITokenFromTestServer(defaultTokenAuthUser, defaultTokenAuthPass)
 	assert.NoError(t, err)
-	setAPIKeyForReq(req, apiKey.Key, "")
+	req, _ := http.NewRequest(http.MethodGet, activeConnectionsPath, nil)
+	setBearerForReq(req, token)
 	rr := executeRequest(req)
-	checkResponseCode(t, http.StatusInternalServerError, rr)
+	checkResponseCode(t, http.StatusOK, rr)
+}
 
-	_, err = httpdtest.RemoveUser(user, http.StatusOK)
-	assert.NoError(t, err)
-	err = os.RemoveAll(user.GetHomeDir())
+func TestGetStatusMock(t *testing.T) {
+	token, err := getJWTAPITokenFromTestServer(defaultTokenAuthUser, defaultTokenAuthPass)
 	assert.NoError(t, err)
+	req, _ := http.NewRequest(http.MethodGet, serverStatusPath, nil)
+	setBearerForReq(req, token)
+	rr := executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
 }
 
-func TestWebClientChangePwd(t *testing.T) {
-	user, _, err := httpdtest.AddUser(getTestUser(), http.StatusCreated)
-	assert.NoError(t, err)
-	webToken, err := getJWTWebClientTokenFromTestServer(defaultUsername, defaultPassword)
-	assert.NoError(t, err)
-	csrfToken, err := getCSRFToken(httpBaseURL + webClientLoginPath)
+func TestDeleteActiveConnectionMock(t *testing.T) {
+	token, err := getJWTAPITokenFromTestServer(defaultTokenAuthUser, defaultTokenAuthPass)
 	assert.NoError(t, err)
+	req, _ := http.NewRequest(http.MethodDelete, activeConnectionsPath+"/connectionID", nil)
+	setBearerForReq(req, token)
+	rr := executeRequest(req)
+	checkResponseCode(t, http.StatusNotFound, rr)
+}
 
-	req, err := http.NewRequest(http.MethodGet, webChangeClientPwdPath, nil)
+func TestNotFoundMock(t *testing.T) {
+	token, err := getJWTAPITokenFromTestServer(defaultTokenAuthUser, defaultTokenAuthPass)
 	assert.NoError(t, err)
-	setJWTCookieForReq(req, webToken)
+	req, _ := http.NewRequest(http.MethodGet, "/non/existing/path", nil)
+	setBearerForReq(req, token)
 	rr := executeRequest(req)
-	checkResponseCode(t, http.StatusOK, rr)
+	checkResponseCode(t, http.StatusNotFound, rr)
+}
 
-	form := make(url.Values)
-	form.Set("current_password", defaultPassword)
-	form.Set("new_password1", defaultPassword)
-	form.Set("new_password2", defaultPassword)
-	// no csrf token
-	req, err = http.NewRequest(http.MethodPost, webChangeClientPwdPath, bytes.NewBuffer([]byte(form.Encode())))
-	assert.NoError(t, err)
-	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
-	setJWTCookieForReq(req, webToken)
-	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusForbidden, rr)
-	assert.Contains(t, rr.Body.String(), "unable to verify form token")
+func TestMethodNotAllowedMock(t *testing.T) {
+	req, _ := http.NewRequest(http.MethodPost, activeConnectionsPath, nil)
+	rr := executeRequest(req)
+	checkResponseCode(t, http.StatusMethodNotAllowed, rr)
+}
 
-	form.Set(csrfFormToken, csrfToken)
-	req, _ = http.NewRequest(http.MethodPost, webChangeClientPwdPath, bytes.NewBuffer([]byte(form.Encode())))
-	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
-	setJWTCookieForReq(req, webToken)
-	rr = executeRequest(req)
+func TestHealthCheck(t *testing.T) {
+	req, _ := http.NewRequest(http.MethodGet, "/healthz", nil)
+	rr := executeRequest(req)
 	checkResponseCode(t, http.StatusOK, rr)
-	assert.Contains(t, rr.Body.String(), "the new password must be different from the current one")
+	assert.Equal(t, "ok", rr.Body.String())
+}
 
-	form.Set("current_password", defaultPassword+"2")
-	form.Set("new_password1", defaultPassword+"1")
-	form.Set("new_password2", defaultPassword+"1")
-	req, _ = http.NewRequest(http.MethodPost, webChangeClientPwdPath, bytes.NewBuffer([]byte(form.Encode())))
-	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
-	setJWTCookieForReq(req, webToken)
+func TestGetWebRootMock(t *testing.T) {
+	req, _ := http.NewRequest(http.MethodGet, "/", nil)
+	rr := executeRequest(req)
+	checkResponseCode(t, http.StatusMovedPermanently, rr)
+	assert.Equal(t, webClientLoginPath, rr.Header().Get("Location"))
+	req, _ = http.NewRequest(http.MethodGet, webBasePath, nil)
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusOK, rr)
-	assert.Contains(t, rr.Body.String(), "current password does not match")
-
-	form.Set("current_password", defaultPassword)
-	form.Set("new_password1", defaultPassword+"1")
-	form.Set("new_password2", defaultPassword+"1")
-	req, _ = http.NewRequest(http.MethodPost, webChangeClientPwdPath, bytes.NewBuffer([]byte(form.Encode())))
-	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
-	setJWTCookieForReq(req, webToken)
+	checkResponseCode(t, http.StatusMovedPermanently, rr)
+	assert.Equal(t, webClientLoginPath, rr.Header().Get("Location"))
+	req, _ = http.NewRequest(http.MethodGet, webBasePathAdmin, nil)
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusFound, rr)
+	checkResponseCode(t, http.StatusMovedPermanently, rr)
+	assert.Equal(t, webLoginPath, rr.Header().Get("Location"))
+	req, _ = http.NewRequest(http.MethodGet, webBasePathClient, nil)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusMovedPermanently, rr)
 	assert.Equal(t, webClientLoginPath, rr.Header().Get("Location"))
+}
 
-	_, err = getJWTWebClientTokenFromTestServer(defaultUsername, defaultPassword)
-	assert.Error(t, err)
-	_, err = getJWTWebClientTokenFromTestServer(defaultUsername+"1", defaultPassword+"1")
-	assert.Error(t, err)
-	_, err = getJWTWebClientTokenFromTestServer(defaultUsername, defaultPassword+"1")
+func TestWebNotFoundURI(t *testing.T) {
+	urlString := httpBaseURL + webBasePath + "/a"
+	req, err := http.NewRequest(http.MethodGet, urlString, nil)
 	assert.NoError(t, err)
+	resp, err := httpclient.GetHTTPClient().Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
 
-	// remove the change password permission
-	user.Filters.WebClient = []string{sdk.WebClientPasswordChangeDisabled}
-	user, _, err = httpdtest.UpdateUser(user, http.StatusOK, "")
+	req, err = http.NewRequest(http.MethodGet, urlString, nil)
 	assert.NoError(t, err)
-	assert.Len(t, user.Filters.WebClient, 1)
-	assert.Contains(t, user.Filters.WebClient, sdk.WebClientPasswordChangeDisabled)
+	setJWTCookieForReq(req, "invalid token")
+	resp, err = httpclient.GetHTTPClient().Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
 
-	webToken, err = getJWTWebClientTokenFromTestServer(defaultUsername, defaultPassword+"1")
+	urlString = httpBaseURL + webBasePathClient + "/a"
+	req, err = http.NewRequest(http.MethodGet, urlString, nil)
 	assert.NoError(t, err)
-	form.Set("current_password", defaultPassword+"1")
-	form.Set("new_password1", defaultPassword)
-	form.Set("new_password2", defaultPassword)
-	req, _ = http.NewRequest(http.MethodPost, webChangeClientPwdPath, bytes.NewBuffer([]byte(form.Encode())))
-	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
-	setJWTCookieForReq(req, webToken)
-	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusForbidden, rr)
+	resp, err = httpclient.GetHTTPClient().Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
 
-	_, err = httpdtest.RemoveUser(user, http.StatusOK)
-	assert.NoError(t, err)
-	err = os.RemoveAll(user.GetHomeDir())
+	req, err = http.NewRequest(http.MethodGet, urlString, nil)
 	assert.NoError(t, err)
+	setJWTCookieForReq(req, "invalid client token")
+	resp, err = httpclient.GetHTTPClient().Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
 }
 
-func TestWebAPIPublicKeys(t *testing.T) {
-	user, _, err := httpdtest.AddUser(getTestUser(), http.StatusCreated)
-	assert.NoError(t, err)
-	apiToken, err := getJWTAPIUserTokenFromTestServer(defaultUsername, defaultPassword)
-	assert.NoError(t, err)
-
-	req, err := http.NewRequest(http.MethodGet, userPublicKeysPath, nil)
+func TestLogout(t *testing.T) {
+	token, err := getJWTAPITokenFromTestServer(defaultTokenAuthUser, defaultTokenAuthPass)
 	assert.NoError(t, err)
-	setBearerForReq(req, apiToken)
+	req, _ := http.NewRequest(http.MethodGet, serverStatusPath, nil)
+	setBearerForReq(req, token)
 	rr := executeRequest(req)
 	checkResponseCode(t, http.StatusOK, rr)
-	var keys []string
-	err = json.Unmarshal(rr.Body.Bytes(), &keys)
-	assert.NoError(t, err)
-	assert.Len(t, keys, 0)
 
-	keys = []string{testPubKey, testPubKey1}
-	asJSON, err := json.Marshal(keys)
-	assert.NoError(t, err)
-	req, err = http.NewRequest(http.MethodPut, userPublicKeysPath, bytes.NewBuffer(asJSON))
-	assert.NoError(t, err)
-	setBearerForReq(req, apiToken)
+	req, _ = http.NewRequest(http.MethodGet, logoutPath, nil)
+	setBearerForReq(req, token)
 	rr = executeRequest(req)
 	checkResponseCode(t, http.StatusOK, rr)
 
-	req, err = http.NewRequest(http.MethodGet, userPublicKeysPath, nil)
-	assert.NoError(t, err)
-	setBearerForReq(req, apiToken)
+	req, _ = http.NewRequest(http.MethodGet, serverStatusPath, nil)
+	setBearerForReq(req, token)
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusOK, rr)
-	keys = nil
-	err = json.Unmarshal(rr.Body.Bytes(), &keys)
-	assert.NoError(t, err)
-	assert.Len(t, keys, 2)
+	checkResponseCode(t, http.StatusUnauthorized, rr)
+	assert.Contains(t, rr.Body.String(), "Your token is no longer valid")
+}
 
-	req, err = http.NewRequest(http.MethodPut, userPublicKeysPath, bytes.NewBuffer([]byte(`invalid json`)))
+func TestDefenderAPIInvalidIDMock(t *testing.T) {
+	token, err := getJWTAPITokenFromTestServer(defaultTokenAuthUser, defaultTokenAuthPass)
 	assert.NoError(t, err)
-	setBearerForReq(req, apiToken)
-	rr = executeRequest(req)
+	req, _ := http.NewRequest(http.MethodGet, path.Join(defenderHosts, "abc"), nil) // not hex id
+	setBearerForReq(req, token)
+	rr := executeRequest(req)
 	checkResponseCode(t, http.StatusBadRequest, rr)
+	assert.Contains(t, rr.Body.String(), "invalid host id")
+}
 
-	keys = []string{`not a public key`}
-	asJSON, err = json.Marshal(keys)
+func TestTokenHeaderCookie(t *testing.T) {
+	apiToken, err := getJWTAPITokenFromTestServer(defaultTokenAuthUser, defaultTokenAuthPass)
 	assert.NoError(t, err)
-	req, err = http.NewRequest(http.MethodPut, userPublicKeysPath, bytes.NewBuffer(asJSON))
+	webToken, err := getJWTWebTokenFromTestServer(defaultTokenAuthUser, defaultTokenAuthPass)
 	assert.NoError(t, err)
+
+	req, _ := http.NewRequest(http.MethodGet, serverStatusPath, nil)
+	setJWTCookieForReq(req, apiToken)
+	rr := executeRequest(req)
+	checkResponseCode(t, http.StatusUnauthorized, rr)
+	assert.Contains(t, rr.Body.String(), "no token found")
+
+	req, _ = http.NewRequest(http.MethodGet, serverStatusPath, nil)
 	setBearerForReq(req, apiToken)
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusBadRequest, rr)
-	assert.Contains(t, rr.Body.String(), "could not parse key")
+	checkResponseCode(t, http.StatusOK, rr)
 
-	user.Filters.WebClient = append(user.Filters.WebClient, sdk.WebClientPubKeyChangeDisabled)
-	_, _, err = httpdtest.UpdateUser(user, http.StatusOK, "")
-	assert.NoError(t, err)
+	req, _ = http.NewRequest(http.MethodGet, webStatusPath, nil)
+	setBearerForReq(req, webToken)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusFound, rr)
+	assert.Equal(t, webLoginPath, rr.Header().Get("Location"))
 
-	apiToken, err = getJWTAPIUserTokenFromTestServer(defaultUsername, defaultPassword)
-	assert.NoError(t, err)
-	req, err = http.NewRequest(http.MethodGet, userPublicKeysPath, nil)
-	assert.NoError(t, err)
-	setBearerForReq(req, apiToken)
+	req, _ = http.NewRequest(http.MethodGet, webStatusPath, nil)
+	setJWTCookieForReq(req, webToken)
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusForbidden, rr)
+	checkResponseCode(t, http.StatusOK, rr)
+}
 
-	_, err = httpdtest.RemoveUser(user, http.StatusOK)
+func TestTokenAudience(t *testing.T) {
+	webToken, err := getJWTWebTokenFromTestServer(defaultTokenAuthUser, defaultTokenAuthPass)
 	assert.NoError(t, err)
-	err = os.RemoveAll(user.GetHomeDir())
+	apiToken, err := getJWTAPITokenFromTestServer(defaultTokenAuthUser, defaultTokenAuthPass)
 	assert.NoError(t, err)
-}
 
-func TestPreDownloadHook(t *testing.T) {
-	if runtime.GOOS == osWindows {
-		t.Skip("this test is not available on Windows")
-	}
-	oldExecuteOn := common.Config.Actions.ExecuteOn
-	oldHook := common.Config.Actions.Hook
+	req, _ := http.NewRequest(http.MethodGet, serverStatusPath, nil)
+	setBearerForReq(req, apiToken)
+	rr := executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
 
-	common.Config.Actions.ExecuteOn = []string{common.OperationPreDownload}
-	common.Config.Actions.Hook = preActionPath
+	req, _ = http.NewRequest(http.MethodGet, serverStatusPath, nil)
+	setBearerForReq(req, webToken)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusUnauthorized, rr)
+	assert.Contains(t, rr.Body.String(), "Your token audience is not valid")
 
-	u := getTestUser()
-	user, _, err := httpdtest.AddUser(u, http.StatusCreated)
-	assert.NoError(t, err)
-	err = os.WriteFile(preActionPath, getExitCodeScriptContent(0), os.ModePerm)
-	assert.NoError(t, err)
+	req, _ = http.NewRequest(http.MethodGet, webStatusPath, nil)
+	setJWTCookieForReq(req, webToken)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
 
-	testFileName := "testfile"
-	testFileContents := []byte("file contents")
-	err = os.MkdirAll(filepath.Join(user.GetHomeDir()), os.ModePerm)
+	req, _ = http.NewRequest(http.MethodGet, webStatusPath, nil)
+	setJWTCookieForReq(req, apiToken)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusFound, rr)
+	assert.Equal(t, webLoginPath, rr.Header().Get("Location"))
+}
+
+func TestWebAPILoginMock(t *testing.T) {
+	_, err := getJWTAPIUserTokenFromTestServer(defaultUsername, defaultPassword)
+	assert.Error(t, err)
+	user, _, err := httpdtest.AddUser(getTestUser(), http.StatusCreated)
 	assert.NoError(t, err)
-	err = os.WriteFile(filepath.Join(user.GetHomeDir(), testFileName), testFileContents, os.ModePerm)
+	_, err = getJWTAPIUserTokenFromTestServer(defaultUsername+"1", defaultPassword)
+	assert.Error(t, err)
+	_, err = getJWTAPIUserTokenFromTestServer(defaultUsername, defaultPassword+"1")
+	assert.Error(t, err)
+	apiToken, err := getJWTAPIUserTokenFromTestServer(defaultUsername, defaultPassword)
 	assert.NoError(t, err)
-
 	webToken, err := getJWTWebClientTokenFromTestServer(defaultUsername, defaultPassword)
 	assert.NoError(t, err)
-	webAPIToken, err := getJWTAPIUserTokenFromTestServer(defaultUsername, defaultPassword)
-	assert.NoError(t, err)
-	req, err := http.NewRequest(http.MethodGet, webClientFilesPath+"?path="+testFileName, nil)
+	// a web token is not valid for API usage
+	req, err := http.NewRequest(http.MethodGet, userDirsPath, nil)
 	assert.NoError(t, err)
-	setJWTCookieForReq(req, webToken)
+	setBearerForReq(req, webToken)
 	rr := executeRequest(req)
-	checkResponseCode(t, http.StatusOK, rr)
-	assert.Equal(t, testFileContents, rr.Body.Bytes())
+	checkResponseCode(t, http.StatusUnauthorized, rr)
+	assert.Contains(t, rr.Body.String(), "Your token audience is not valid")
 
-	req, err = http.NewRequest(http.MethodGet, userFilesPath+"?path="+testFileName, nil)
+	req, err = http.NewRequest(http.MethodGet, userDirsPath+"/?path=%2F", nil)
 	assert.NoError(t, err)
-	setBearerForReq(req, webAPIToken)
+	setBearerForReq(req, apiToken)
 	rr = executeRequest(req)
 	checkResponseCode(t, http.StatusOK, rr)
-	assert.Equal(t, testFileContents, rr.Body.Bytes())
+	// API token is not valid for web usage
+	req, _ = http.NewRequest(http.MethodGet, webClientProfilePath, nil)
+	setJWTCookieForReq(req, apiToken)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusFound, rr)
+	assert.Equal(t, webClientLoginPath, rr.Header().Get("Location"))
 
-	err = os.WriteFile(preActionPath, getExitCodeScriptContent(1), os.ModePerm)
-	assert.NoError(t, err)
-	req, err = http.NewRequest(http.MethodGet, webClientFilesPath+"?path="+testFileName, nil)
-	assert.NoError(t, err)
+	req, _ = http.NewRequest(http.MethodGet, webClientProfilePath, nil)
 	setJWTCookieForReq(req, webToken)
 	rr = executeRequest(req)
 	checkResponseCode(t, http.StatusOK, rr)
-	assert.Contains(t, rr.Body.String(), "permission denied")
-
-	req, err = http.NewRequest(http.MethodGet, userFilesPath+"?path="+testFileName, nil)
-	assert.NoError(t, err)
-	setBearerForReq(req, webAPIToken)
-	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusForbidden, rr)
-	assert.Contains(t, rr.Body.String(), "permission denied")
 
 	_, err = httpdtest.RemoveUser(user, http.StatusOK)
 	assert.NoError(t, err)
 	err = os.RemoveAll(user.GetHomeDir())
 	assert.NoError(t, err)
-
-	common.Config.Actions.ExecuteOn = oldExecuteOn
-	common.Config.Actions.Hook = oldHook
 }
 
-func TestPreUploadHook(t *testing.T) {
-	if runtime.GOOS == osWindows {
-		t.Skip("this test is not available on Windows")
-	}
-	oldExecuteOn := common.Config.Actions.ExecuteOn
-	oldHook := common.Config.Actions.Hook
-
-	common.Config.Actions.ExecuteOn = []string{common.OperationPreUpload}
-	common.Config.Actions.Hook = preActionPath
-
-	u := getTestUser()
-	user, _, err := httpdtest.AddUser(u, http.StatusCreated)
-	assert.NoError(t, err)
-	err = os.WriteFile(preActionPath, getExitCodeScriptContent(0), os.ModePerm)
-	assert.NoError(t, err)
-
-	webAPIToken, err := getJWTAPIUserTokenFromTestServer(defaultUsername, defaultPassword)
-	assert.NoError(t, err)
-
-	body := new(bytes.Buffer)
-	writer := multipart.NewWriter(body)
-	part, err := writer.CreateFormFile("filename", "filepre")
-	assert.NoError(t, err)
-	_, err = part.Write([]byte("file content"))
-	assert.NoError(t, err)
-	err = writer.Close()
-	assert.NoError(t, err)
-	reader := bytes.NewReader(body.Bytes())
-	_, err = reader.Seek(0, io.SeekStart)
+func TestWebClientLoginMock(t *testing.T) {
+	_, err := getJWTWebClientTokenFromTestServer(defaultUsername, defaultPassword)
+	assert.Error(t, err)
+	user, _, err := httpdtest.AddUser(getTestUser(), http.StatusCreated)
 	assert.NoError(t, err)
-	req, err := http.NewRequest(http.MethodPost, userFilesPath, reader)
+	webToken, err := getJWTWebClientTokenFromTestServer(defaultUsername, defaultPassword)
 	assert.NoError(t, err)
-	req.Header.Add("Content-Type", writer.FormDataContentType())
-	setBearerForReq(req, webAPIToken)
+	// a web token is not valid for API or WebAdmin usage
+	req, _ := http.NewRequest(http.MethodGet, serverStatusPath, nil)
+	setBearerForReq(req, webToken)
 	rr := executeRequest(req)
-	checkResponseCode(t, http.StatusCreated, rr)
+	checkResponseCode(t, http.StatusUnauthorized, rr)
+	assert.Contains(t, rr.Body.String(), "Your token audience is not valid")
+	req, _ = http.NewRequest(http.MethodGet, webStatusPath, nil)
+	setJWTCookieForReq(req, webToken)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusFound, rr)
+	assert.Equal(t, webLoginPath, rr.Header().Get("Location"))
+	// bearer should not work
+	req, _ = http.NewRequest(http.MethodGet, webClientProfilePath, nil)
+	setBearerForReq(req, webToken)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusFound, rr)
+	assert.Equal(t, webClientLoginPath, rr.Header().Get("Location"))
+	// now try to render client pages
+	req, _ = http.NewRequest(http.MethodGet, webClientProfilePath, nil)
+	setJWTCookieForReq(req, webToken)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
+	req, _ = http.NewRequest(http.MethodGet, webClientFilesPath, nil)
+	setJWTCookieForReq(req, webToken)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
+	// now logout
+	req, _ = http.NewRequest(http.MethodGet, webClientLogoutPath, nil)
+	setJWTCookieForReq(req, webToken)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusFound, rr)
+	assert.Equal(t, webClientLoginPath, rr.Header().Get("Location"))
+	req, _ = http.NewRequest(http.MethodGet, webClientProfilePath, nil)
+	setJWTCookieForReq(req, webToken)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusFound, rr)
+	assert.Equal(t, webClientLoginPath, rr.Header().Get("Location"))
 
-	err = os.WriteFile(preActionPath, getExitCodeScriptContent(1), os.ModePerm)
-	assert.NoError(t, err)
-	_, err = reader.Seek(0, io.SeekStart)
+	// get a new token and use it after removing the user
+	webToken, err = getJWTWebClientTokenFromTestServer(defaultUsername, defaultPassword)
 	assert.NoError(t, err)
-	req, err = http.NewRequest(http.MethodPost, userFilesPath, reader)
+	apiUserToken, err := getJWTAPIUserTokenFromTestServer(defaultUsername, defaultPassword)
 	assert.NoError(t, err)
-	req.Header.Add("Content-Type", writer.FormDataContentType())
-	setBearerForReq(req, webAPIToken)
-	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusForbidden, rr)
-
 	_, err = httpdtest.RemoveUser(user, http.StatusOK)
 	assert.NoError(t, err)
 	err = os.RemoveAll(user.GetHomeDir())
 	assert.NoError(t, err)
 
-	common.Config.Actions.ExecuteOn = oldExecuteOn
-	common.Config.Actions.Hook = oldHook
-}
+	req, _ = http.NewRequest(http.MethodGet, webClientProfilePath, nil)
+	setJWTCookieForReq(req, webToken)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusInternalServerError, rr)
+	req, _ = http.NewRequest(http.MethodGet, webClientFilesPath, nil)
+	setJWTCookieForReq(req, webToken)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusNotFound, rr)
+	assert.Contains(t, rr.Body.String(), "Unable to retrieve your user")
 
-func TestUserAPIKey(t *testing.T) {
-	u := getTestUser()
-	u.Filters.AllowAPIKeyAuth = true
-	user, _, err := httpdtest.AddUser(u, http.StatusCreated)
-	assert.NoError(t, err)
-	apiKey := dataprovider.APIKey{
-		Name:  "testkey",
-		User:  user.Username,
-		Scope: dataprovider.APIKeyScopeUser,
-	}
-	apiKey, _, err = httpdtest.AddAPIKey(apiKey, http.StatusCreated)
-	assert.NoError(t, err)
+	req, _ = http.NewRequest(http.MethodGet, webClientDirsPath, nil)
+	setJWTCookieForReq(req, webToken)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusNotFound, rr)
+	assert.Contains(t, rr.Body.String(), "Unable to retrieve your user")
 
-	body := new(bytes.Buffer)
-	writer := multipart.NewWriter(body)
-	part, err := writer.CreateFormFile("filename", "filenametest")
-	assert.NoError(t, err)
-	_, err = part.Write([]byte("test file content"))
-	assert.NoError(t, err)
-	err = writer.Close()
-	assert.NoError(t, err)
-	reader := bytes.NewReader(body.Bytes())
-	_, err = reader.Seek(0, io.SeekStart)
-	assert.NoError(t, err)
-	req, err := http.NewRequest(http.MethodPost, userFilesPath, reader)
-	assert.NoError(t, err)
-	req.Header.Add("Content-Type", writer.FormDataContentType())
-	setAPIKeyForReq(req, apiKey.Key, "")
-	rr := executeRequest(req)
-	checkResponseCode(t, http.StatusCreated, rr)
-
-	req, err = http.NewRequest(http.MethodGet, userDirsPath, nil)
-	assert.NoError(t, err)
-	setAPIKeyForReq(req, apiKey.Key, "")
+	req, _ = http.NewRequest(http.MethodGet, webClientDownloadZipPath, nil)
+	setJWTCookieForReq(req, webToken)
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusOK, rr)
-	var dirEntries []map[string]interface{}
-	err = json.Unmarshal(rr.Body.Bytes(), &dirEntries)
-	assert.NoError(t, err)
-	assert.Len(t, dirEntries, 1)
+	checkResponseCode(t, http.StatusNotFound, rr)
+	assert.Contains(t, rr.Body.String(), "Unable to retrieve your user")
 
-	user.Status = 0
-	user, _, err = httpdtest.UpdateUser(user, http.StatusOK, "")
-	assert.NoError(t, err)
-	req, err = http.NewRequest(http.MethodGet, userDirsPath, nil)
-	assert.NoError(t, err)
-	setAPIKeyForReq(req, apiKey.Key, "")
+	req, _ = http.NewRequest(http.MethodGet, userDirsPath, nil)
+	setBearerForReq(req, apiUserToken)
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusUnauthorized, rr)
-
-	user.Status = 1
-	user.Filters.DeniedProtocols = []string{common.ProtocolHTTP}
-	user, _, err = httpdtest.UpdateUser(user, http.StatusOK, "")
-	assert.NoError(t, err)
+	checkResponseCode(t, http.StatusNotFound, rr)
+	assert.Contains(t, rr.Body.String(), "Unable to retrieve your user")
 
-	req, err = http.NewRequest(http.MethodGet, userDirsPath, nil)
-	assert.NoError(t, err)
-	setAPIKeyForReq(req, apiKey.Key, "")
+	req, _ = http.NewRequest(http.MethodGet, userFilesPath, nil)
+	setBearerForReq(req, apiUserToken)
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusUnauthorized, rr)
-
-	user.Filters.DeniedProtocols = []string{common.ProtocolFTP}
-	user, _, err = httpdtest.UpdateUser(user, http.StatusOK, "")
-	assert.NoError(t, err)
+	checkResponseCode(t, http.StatusNotFound, rr)
+	assert.Contains(t, rr.Body.String(), "Unable to retrieve your user")
 
-	req, err = http.NewRequest(http.MethodGet, userDirsPath, nil)
-	assert.NoError(t, err)
-	setAPIKeyForReq(req, apiKey.Key, "")
+	req, _ = http.NewRequest(http.MethodPost, userStreamZipPath, bytes.NewBuffer([]byte(`{}`)))
+	setBearerForReq(req, apiUserToken)
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusOK, rr)
-	apiKeyNew := dataprovider.APIKey{
-		Name:  apiKey.Name,
-		Scope: dataprovider.APIKeyScopeUser,
-	}
+	checkResponseCode(t, http.StatusNotFound, rr)
+	assert.Contains(t, rr.Body.String(), "Unable to retrieve your user")
 
-	apiKeyNew, _, err = httpdtest.AddAPIKey(apiKeyNew, http.StatusCreated)
-	assert.NoError(t, err)
-	req, err = http.NewRequest(http.MethodGet, userDirsPath, nil)
-	assert.NoError(t, err)
-	setAPIKeyForReq(req, apiKeyNew.Key, "")
-	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusUnauthorized, rr)
-	// now associate a user
-	req, err = http.NewRequest(http.MethodGet, userDirsPath, nil)
-	assert.NoError(t, err)
-	setAPIKeyForReq(req, apiKeyNew.Key, user.Username)
-	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusOK, rr)
-	// now with a missing user
-	req, err = http.NewRequest(http.MethodGet, userDirsPath, nil)
-	assert.NoError(t, err)
-	setAPIKeyForReq(req, apiKeyNew.Key, user.Username+"1")
+	req, _ = http.NewRequest(http.MethodGet, userPublicKeysPath, nil)
+	setBearerForReq(req, apiUserToken)
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusUnauthorized, rr)
-	// empty user and key not associated to any user
-	req, err = http.NewRequest(http.MethodGet, userDirsPath, nil)
-	assert.NoError(t, err)
-	setAPIKeyForReq(req, apiKeyNew.Key, "")
+	checkResponseCode(t, http.StatusNotFound, rr)
+	assert.Contains(t, rr.Body.String(), "Unable to retrieve your user")
+
+	req, _ = http.NewRequest(http.MethodPut, userPublicKeysPath, bytes.NewBuffer([]byte(`{}`)))
+	setBearerForReq(req, apiUserToken)
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusUnauthorized, rr)
-	apiKeyNew.ExpiresAt = util.GetTimeAsMsSinceEpoch(time.Now().Add(-24 * time.Hour))
-	_, _, err = httpdtest.UpdateAPIKey(apiKeyNew, http.StatusOK)
-	assert.NoError(t, err)
-	// expired API key
-	req, err = http.NewRequest(http.MethodGet, userDirsPath, nil)
+	checkResponseCode(t, http.StatusNotFound, rr)
+	assert.Contains(t, rr.Body.String(), "Unable to retrieve your user")
+
+	csrfToken, err := getCSRFToken(httpBaseURL + webClientLoginPath)
 	assert.NoError(t, err)
-	setAPIKeyForReq(req, apiKeyNew.Key, user.Username)
+	form := make(url.Values)
+	form.Set("public_keys", testPubKey)
+	form.Set(csrfFormToken, csrfToken)
+	req, _ = http.NewRequest(http.MethodPost, webClientProfilePath, bytes.NewBuffer([]byte(form.Encode())))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	setJWTCookieForReq(req, webToken)
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusUnauthorized, rr)
+	checkResponseCode(t, http.StatusInternalServerError, rr)
+}
 
-	_, err = httpdtest.RemoveUser(user, http.StatusOK)
-	assert.NoError(t, err)
-	err = os.RemoveAll(user.GetHomeDir())
-	assert.NoError(t, err)
+func TestWebClientLoginErrorsMock(t *testing.T) {
+	form := getLoginForm("", "", "")
+	req, _ := http.NewRequest(http.MethodPost, webClientLoginPath, bytes.NewBuffer([]byte(form.Encode())))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rr := executeRequest(req)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Contains(t, rr.Body.String(), "Invalid credentials")
 
-	_, err = httpdtest.RemoveAPIKey(apiKeyNew, http.StatusOK)
-	assert.NoError(t, err)
+	form = getLoginForm(defaultUsername, defaultPassword, "")
+	req, _ = http.NewRequest(http.MethodPost, webClientLoginPath, bytes.NewBuffer([]byte(form.Encode())))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rr = executeRequest(req)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Contains(t, rr.Body.String(), "unable to verify form token")
 }
 
-func TestWebEditFile(t *testing.T) {
+func TestWebClientMaxConnections(t *testing.T) {
+	oldValue := common.Config.MaxTotalConnections
+	common.Config.MaxTotalConnections = 1
+
 	user, _, err := httpdtest.AddUser(getTestUser(), http.StatusCreated)
 	assert.NoError(t, err)
-	testFile1 := "testfile1.txt"
-	testFile2 := "testfile2"
-	file1Size := int64(65536)
-	file2Size := int64(655360)
-	err = createTestFile(filepath.Join(user.GetHomeDir(), testFile1), file1Size)
-	assert.NoError(t, err)
-	err = createTestFile(filepath.Join(user.GetHomeDir(), testFile2), file2Size)
-	assert.NoError(t, err)
 
 	webToken, err := getJWTWebClientTokenFromTestServer(defaultUsername, defaultPassword)
 	assert.NoError(t, err)
-
-	req, err := http.NewRequest(http.MethodGet, webClientEditFilePath+"?path="+testFile1, nil)
-	assert.NoError(t, err)
+	req, _ := http.NewRequest(http.MethodGet, webClientFilesPath, nil)
 	setJWTCookieForReq(req, webToken)
 	rr := executeRequest(req)
 	checkResponseCode(t, http.StatusOK, rr)
 
-	req, err = http.NewRequest(http.MethodGet, webClientEditFilePath+"?path="+testFile2, nil)
-	assert.NoError(t, err)
+	// now add a fake connection
+	fs := vfs.NewOsFs("id", os.TempDir(), "")
+	connection := &httpd.Connection{
+		BaseConnection: common.NewBaseConnection(fs.ConnectionID(), common.ProtocolHTTP, "", "", user),
+	}
+	common.Connections.Add(connection)
+
+	_, err = getJWTWebClientTokenFromTestServer(defaultUsername, defaultPassword)
+	assert.Error(t, err)
+
+	req, _ = http.NewRequest(http.MethodGet, webClientFilesPath, nil)
 	setJWTCookieForReq(req, webToken)
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusBadRequest, rr)
-	assert.Contains(t, rr.Body.String(), "exceeds the maximum allowed size")
+	checkResponseCode(t, http.StatusForbidden, rr)
+	assert.Contains(t, rr.Body.String(), "configured connections limit reached")
 
-	req, err = http.NewRequest(http.MethodGet, webClientEditFilePath+"?path=missing", nil)
+	common.Connections.Remove(connection.GetID())
+	_, err = httpdtest.RemoveUser(user, http.StatusOK)
 	assert.NoError(t, err)
-	setJWTCookieForReq(req, webToken)
-	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusBadRequest, rr)
-	assert.Contains(t, rr.Body.String(), "Unable to stat file")
+	err = os.RemoveAll(user.GetHomeDir())
+	assert.NoError(t, err)
+	assert.Len(t, common.Connections.GetStats(), 0)
 
-	req, err = http.NewRequest(http.MethodGet, webClientEditFilePath+"?path=%2F", nil)
+	common.Config.MaxTotalConnections = oldValue
+}
+
+func TestDefender(t *testing.T) {
+	oldConfig := config.GetCommonConfig()
+
+	cfg := config.GetCommonConfig()
+	cfg.DefenderConfig.Enabled = true
+	cfg.DefenderConfig.Threshold = 3
+	cfg.DefenderConfig.ScoreLimitExceeded = 2
+
+	err := common.Initialize(cfg)
 	assert.NoError(t, err)
-	setJWTCookieForReq(req, webToken)
-	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusBadRequest, rr)
-	assert.Contains(t, rr.Body.String(), "does not point to a file")
 
-	user.Filters.DeniedProtocols = []string{common.ProtocolHTTP}
-	_, _, err = httpdtest.UpdateUser(user, http.StatusOK, "")
+	user, _, err := httpdtest.AddUser(getTestUser(), http.StatusCreated)
 	assert.NoError(t, err)
 
-	req, err = http.NewRequest(http.MethodGet, webClientEditFilePath+"?path="+testFile1, nil)
+	remoteAddr := "172.16.5.6:9876"
+
+	webAdminToken, err := getJWTWebTokenFromTestServer(defaultTokenAuthUser, defaultTokenAuthPass)
+	assert.NoError(t, err)
+	webToken, err := getJWTWebClientTokenFromTestServerWithAddr(defaultUsername, defaultPassword, remoteAddr)
 	assert.NoError(t, err)
+
+	req, _ := http.NewRequest(http.MethodGet, webClientFilesPath, nil)
+	req.RemoteAddr = remoteAddr
+	req.RequestURI = webClientFilesPath
+	setJWTCookieForReq(req, webToken)
+	rr := executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
+
+	for i := 0; i < 3; i++ {
+		_, err = getJWTWebClientTokenFromTestServerWithAddr(defaultUsername, "wrong pwd", remoteAddr)
+		assert.Error(t, err)
+	}
+
+	_, err = getJWTWebClientTokenFromTestServerWithAddr(defaultUsername, defaultPassword, remoteAddr)
+	assert.Error(t, err)
+	req, _ = http.NewRequest(http.MethodGet, webClientFilesPath, nil)
+	req.RemoteAddr = remoteAddr
+	req.RequestURI = webClientFilesPath
 	setJWTCookieForReq(req, webToken)
 	rr = executeRequest(req)
 	checkResponseCode(t, http.StatusForbidden, rr)
+	assert.Contains(t, rr.Body.String(), "your IP address is banned")
 
-	user.Filters.DeniedProtocols = []string{common.ProtocolFTP}
-	user.Filters.FilePatterns = []sdk.PatternsFilter{
-		{
-			Path:           "/",
-			DeniedPatterns: []string{"*.txt"},
-		},
-	}
-	_, _, err = httpdtest.UpdateUser(user, http.StatusOK, "")
-	assert.NoError(t, err)
+	req, _ = http.NewRequest(http.MethodGet, webUsersPath, nil)
+	req.RemoteAddr = remoteAddr
+	req.RequestURI = webUsersPath
+	setJWTCookieForReq(req, webAdminToken)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusForbidden, rr)
+	assert.Contains(t, rr.Body.String(), "your IP address is banned")
 
-	req, err = http.NewRequest(http.MethodGet, webClientEditFilePath+"?path="+testFile1, nil)
-	assert.NoError(t, err)
+	req, _ = http.NewRequest(http.MethodGet, webClientFilesPath, nil)
+	req.RemoteAddr = remoteAddr
+	req.Header.Set("X-Real-IP", "127.0.0.1:2345")
 	setJWTCookieForReq(req, webToken)
 	rr = executeRequest(req)
 	checkResponseCode(t, http.StatusForbidden, rr)
-	assert.Contains(t, rr.Body.String(), "Unable to get a reader")
+	assert.Contains(t, rr.Body.String(), "your IP address is banned")
 
 	_, err = httpdtest.RemoveUser(user, http.StatusOK)
 	assert.NoError(t, err)
 	err = os.RemoveAll(user.GetHomeDir())
 	assert.NoError(t, err)
 
-	req, err = http.NewRequest(http.MethodGet, webClientEditFilePath+"?path="+testFile1, nil)
+	err = common.Initialize(oldConfig)
 	assert.NoError(t, err)
-	setJWTCookieForReq(req, webToken)
-	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusNotFound, rr)
 }
 
-func TestWebGetFiles(t *testing.T) {
-	user, _, err := httpdtest.AddUser(getTestUser(), http.StatusCreated)
-	assert.NoError(t, err)
-	testFileName := "testfile"
-	testDir := "testdir"
-	testFileContents := []byte("file contents")
-	err = os.MkdirAll(filepath.Join(user.GetHomeDir(), testDir), os.ModePerm)
-	assert.NoError(t, err)
-	extensions := []string{"", ".doc", ".ppt", ".xls", ".pdf", ".mkv", ".png", ".go", ".zip", ".txt"}
-	for _, ext := range extensions {
-		err = os.WriteFile(filepath.Join(user.GetHomeDir(), testFileName+ext), testFileContents, os.ModePerm)
-		assert.NoError(t, err)
+func TestPostConnectHook(t *testing.T) {
+	if runtime.GOOS == osWindows {
+		t.Skip("this test is not available on Windows")
 	}
-	err = os.Symlink(filepath.Join(user.GetHomeDir(), testFileName+".doc"), filepath.Join(user.GetHomeDir(), testDir, testFileName+".link"))
+	common.Config.PostConnectHook = postConnectPath
+
+	u := getTestUser()
+	u.Filters.AllowAPIKeyAuth = true
+	user, _, err := httpdtest.AddUser(u, http.StatusCreated)
 	assert.NoError(t, err)
-	webToken, err := getJWTWebClientTokenFromTestServer(defaultUsername, defaultPassword)
+	apiKey, _, err := httpdtest.AddAPIKey(dataprovider.APIKey{
+		Name:  "name",
+		Scope: dataprovider.APIKeyScopeUser,
+		User:  user.Username,
+	}, http.StatusCreated)
 	assert.NoError(t, err)
-	webAPIToken, err := getJWTAPIUserTokenFromTestServer(defaultUsername, defaultPassword)
+	err = os.WriteFile(postConnectPath, getExitCodeScriptContent(0), os.ModePerm)
 	assert.NoError(t, err)
-	req, _ := http.NewRequest(http.MethodGet, webClientFilesPath, nil)
-	setJWTCookieForReq(req, webToken)
-	rr := executeRequest(req)
-	checkResponseCode(t, http.StatusOK, rr)
 
-	req, _ = http.NewRequest(http.MethodGet, webClientFilesPath+"?path="+testDir, nil)
-	setJWTCookieForReq(req, webToken)
-	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusOK, rr)
+	_, err = getJWTWebClientTokenFromTestServer(defaultUsername, defaultPassword)
+	assert.NoError(t, err)
 
-	req, _ = http.NewRequest(http.MethodGet, webClientDirsPath+"?path="+testDir, nil)
-	setJWTCookieForReq(req, webToken)
-	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusOK, rr)
-	var dirContents []map[string]string
-	err = json.Unmarshal(rr.Body.Bytes(), &dirContents)
+	_, err = getJWTAPIUserTokenFromTestServer(defaultUsername, defaultPassword)
 	assert.NoError(t, err)
-	assert.Len(t, dirContents, 1)
 
-	req, _ = http.NewRequest(http.MethodGet, userDirsPath+"?path="+testDir, nil)
-	setBearerForReq(req, webAPIToken)
-	rr = executeRequest(req)
+	req, err := http.NewRequest(http.MethodGet, userDirsPath, nil)
+	assert.NoError(t, err)
+	setAPIKeyForReq(req, apiKey.Key, "")
+	rr := executeRequest(req)
 	checkResponseCode(t, http.StatusOK, rr)
-	var dirEntries []map[string]interface{}
-	err = json.Unmarshal(rr.Body.Bytes(), &dirEntries)
+
+	err = os.WriteFile(postConnectPath, getExitCodeScriptContent(1), os.ModePerm)
 	assert.NoError(t, err)
-	assert.Len(t, dirEntries, 1)
 
-	req, _ = http.NewRequest(http.MethodGet, webClientDownloadZipPath+"?path="+url.QueryEscape("/")+"&files="+
-		url.QueryEscape(fmt.Sprintf(`["%v","%v","%v"]`, testFileName, testDir, testFileName+extensions[2])), nil)
-	setJWTCookieForReq(req, webToken)
-	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusOK, rr)
+	_, err = getJWTWebClientTokenFromTestServer(defaultUsername, defaultPassword)
+	assert.Error(t, err)
 
-	filesList := []string{testFileName, testDir, testFileName + extensions[2]}
-	asJSON, err := json.Marshal(filesList)
+	_, err = getJWTAPIUserTokenFromTestServer(defaultUsername, defaultPassword)
+	assert.Error(t, err)
+
+	req, err = http.NewRequest(http.MethodGet, userDirsPath, nil)
 	assert.NoError(t, err)
-	req, _ = http.NewRequest(http.MethodPost, userStreamZipPath, bytes.NewBuffer(asJSON))
-	setBearerForReq(req, webAPIToken)
+	setAPIKeyForReq(req, apiKey.Key, "")
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusOK, rr)
+	checkResponseCode(t, http.StatusUnauthorized, rr)
 
+	_, err = httpdtest.RemoveUser(user, http.StatusOK)
+	assert.NoError(t, err)
+	err = os.RemoveAll(user.GetHomeDir())
 	assert.NoError(t, err)
-	req, _ = http.NewRequest(http.MethodPost, userStreamZipPath, bytes.NewBuffer([]byte(`file`)))
-	setBearerForReq(req, webAPIToken)
-	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusBadRequest, rr)
 
-	req, _ = http.NewRequest(http.MethodGet, webClientDownloadZipPath+"?path="+url.QueryEscape("/")+"&files="+
-		url.QueryEscape(fmt.Sprintf(`["%v"]`, testDir)), nil)
-	setJWTCookieForReq(req, webToken)
-	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusOK, rr)
+	common.Config.PostConnectHook = ""
+}
 
-	req, _ = http.NewRequest(http.MethodGet, webClientDownloadZipPath+"?path="+url.QueryEscape("/")+"&files=notalist", nil)
-	setJWTCookieForReq(req, webToken)
-	rr = executeRequest(req)
+func TestMaxSessions(t *testing.T) {
+	u := getTestUser()
+	u.MaxSessions = 1
+	user, _, err := httpdtest.AddUser(u, http.StatusCreated)
+	assert.NoError(t, err)
+	_, err = getJWTWebClientTokenFromTestServer(defaultUsername, defaultPassword)
+	assert.NoError(t, err)
+	_, err = getJWTAPIUserTokenFromTestServer(defaultUsername, defaultPassword)
+	assert.NoError(t, err)
+	// now add a fake connection
+	fs := vfs.NewOsFs("id", os.TempDir(), "")
+	connection := &httpd.Connection{
+		BaseConnection: common.NewBaseConnection(fs.ConnectionID(), common.ProtocolHTTP, "", "", user),
+	}
+	common.Connections.Add(connection)
+	_, err = getJWTWebClientTokenFromTestServer(defaultUsername, defaultPassword)
+	assert.Error(t, err)
+	_, err = getJWTAPIUserTokenFromTestServer(defaultUsername, defaultPassword)
+	assert.Error(t, err)
+	common.Connections.Remove(connection.GetID())
+	_, err = httpdtest.RemoveUser(user, http.StatusOK)
+	assert.NoError(t, err)
+	err = os.RemoveAll(user.GetHomeDir())
+	assert.NoError(t, err)
+	assert.Len(t, common.Connections.GetStats(), 0)
+}
+
+func TestLoginInvalidFs(t *testing.T) {
+	u := getTestUser()
+	u.Filters.AllowAPIKeyAuth = true
+	u.FsConfig.Provider = sdk.GCSFilesystemProvider
+	u.FsConfig.GCSConfig.Bucket = "test"
+	u.FsConfig.GCSConfig.Credentials = kms.NewPlainSecret("invalid JSON for credentials")
+	user, _, err := httpdtest.AddUser(u, http.StatusCreated)
+	assert.NoError(t, err)
+	apiKey, _, err := httpdtest.AddAPIKey(dataprovider.APIKey{
+		Name:  "testk",
+		Scope: dataprovider.APIKeyScopeUser,
+		User:  u.Username,
+	}, http.StatusCreated)
+	assert.NoError(t, err)
+
+	credentialsFile := filepath.Join(credentialsPath, fmt.Sprintf("%v_gcs_credentials.json", u.Username))
+	if !filepath.IsAbs(credentialsFile) {
+		credentialsFile = filepath.Join(configDir, credentialsFile)
+	}
+
+	// now remove the credentials file so the filesystem creation will fail
+	err = os.Remove(credentialsFile)
+	assert.NoError(t, err)
+
+	_, err = getJWTWebClientTokenFromTestServer(defaultUsername, defaultPassword)
+	assert.Error(t, err)
+
+	_, err = getJWTAPIUserTokenFromTestServer(defaultUsername, defaultPassword)
+	assert.Error(t, err)
+
+	req, err := http.NewRequest(http.MethodGet, userDirsPath, nil)
+	assert.NoError(t, err)
+	setAPIKeyForReq(req, apiKey.Key, "")
+	rr := executeRequest(req)
 	checkResponseCode(t, http.StatusInternalServerError, rr)
-	assert.Contains(t, rr.Body.String(), "Unable to get files list")
 
-	req, _ = http.NewRequest(http.MethodGet, webClientDirsPath+"?path=/", nil)
-	setJWTCookieForReq(req, webToken)
-	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusOK, rr)
-	dirContents = nil
-	err = json.Unmarshal(rr.Body.Bytes(), &dirContents)
+	_, err = httpdtest.RemoveUser(user, http.StatusOK)
 	assert.NoError(t, err)
-	assert.Len(t, dirContents, len(extensions)+1)
+	err = os.RemoveAll(user.GetHomeDir())
+	assert.NoError(t, err)
+}
 
-	req, _ = http.NewRequest(http.MethodGet, userDirsPath+"?path=/", nil)
-	setBearerForReq(req, webAPIToken)
-	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusOK, rr)
-	dirEntries = nil
-	err = json.Unmarshal(rr.Body.Bytes(), &dirEntries)
+func TestWebClientChangePwd(t *testing.T) {
+	user, _, err := httpdtest.AddUser(getTestUser(), http.StatusCreated)
+	assert.NoError(t, err)
+	webToken, err := getJWTWebClientTokenFromTestServer(defaultUsername, defaultPassword)
+	assert.NoError(t, err)
+	csrfToken, err := getCSRFToken(httpBaseURL + webClientLoginPath)
 	assert.NoError(t, err)
-	assert.Len(t, dirEntries, len(extensions)+1)
 
-	req, _ = http.NewRequest(http.MethodGet, webClientDirsPath+"?path=/missing", nil)
+	req, err := http.NewRequest(http.MethodGet, webChangeClientPwdPath, nil)
+	assert.NoError(t, err)
 	setJWTCookieForReq(req, webToken)
-	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusNotFound, rr)
-	assert.Contains(t, rr.Body.String(), "Unable to get directory contents")
+	rr := executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
 
-	req, _ = http.NewRequest(http.MethodGet, userDirsPath+"?path=missing", nil)
-	setBearerForReq(req, webAPIToken)
+	form := make(url.Values)
+	form.Set("current_password", defaultPassword)
+	form.Set("new_password1", defaultPassword)
+	form.Set("new_password2", defaultPassword)
+	// no csrf token
+	req, err = http.NewRequest(http.MethodPost, webChangeClientPwdPath, bytes.NewBuffer([]byte(form.Encode())))
+	assert.NoError(t, err)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	setJWTCookieForReq(req, webToken)
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusNotFound, rr)
-	assert.Contains(t, rr.Body.String(), "Unable to get directory contents")
+	checkResponseCode(t, http.StatusForbidden, rr)
+	assert.Contains(t, rr.Body.String(), "unable to verify form token")
 
-	req, _ = http.NewRequest(http.MethodGet, webClientFilesPath+"?path="+testFileName, nil)
+	form.Set(csrfFormToken, csrfToken)
+	req, _ = http.NewRequest(http.MethodPost, webChangeClientPwdPath, bytes.NewBuffer([]byte(form.Encode())))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 	setJWTCookieForReq(req, webToken)
 	rr = executeRequest(req)
 	checkResponseCode(t, http.StatusOK, rr)
-	assert.Equal(t, testFileContents, rr.Body.Bytes())
+	assert.Contains(t, rr.Body.String(), "the new password must be different from the current one")
 
-	req, _ = http.NewRequest(http.MethodGet, userFilesPath+"?path="+testFileName, nil)
-	setBearerForReq(req, webAPIToken)
+	form.Set("current_password", defaultPassword+"2")
+	form.Set("new_password1", defaultPassword+"1")
+	form.Set("new_password2", defaultPassword+"1")
+	req, _ = http.NewRequest(http.MethodPost, webChangeClientPwdPath, bytes.NewBuffer([]byte(form.Encode())))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	setJWTCookieForReq(req, webToken)
 	rr = executeRequest(req)
 	checkResponseCode(t, http.StatusOK, rr)
-	assert.Equal(t, testFileContents, rr.Body.Bytes())
+	assert.Contains(t, rr.Body.String(), "current password does not match")
 
-	req, _ = http.NewRequest(http.MethodGet, userFilesPath+"?path=", nil)
-	setBearerForReq(req, webAPIToken)
+	form.Set("current_password", defaultPassword)
+	form.Set("new_password1", defaultPassword+"1")
+	form.Set("new_password2", defaultPassword+"1")
+	req, _ = http.NewRequest(http.MethodPost, webChangeClientPwdPath, bytes.NewBuffer([]byte(form.Encode())))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	setJWTCookieForReq(req, webToken)
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusBadRequest, rr)
-	assert.Contains(t, rr.Body.String(), "Please set the path to a valid file")
+	checkResponseCode(t, http.StatusFound, rr)
+	assert.Equal(t, webClientLoginPath, rr.Header().Get("Location"))
 
-	req, _ = http.NewRequest(http.MethodGet, userFilesPath+"?path="+testDir, nil)
-	setBearerForReq(req, webAPIToken)
-	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusBadRequest, rr)
-	assert.Contains(t, rr.Body.String(), "is a directory")
+	_, err = getJWTWebClientTokenFromTestServer(defaultUsername, defaultPassword)
+	assert.Error(t, err)
+	_, err = getJWTWebClientTokenFromTestServer(defaultUsername+"1", defaultPassword+"1")
+	assert.Error(t, err)
+	_, err = getJWTWebClientTokenFromTestServer(defaultUsername, defaultPassword+"1")
+	assert.NoError(t, err)
 
-	req, _ = http.NewRequest(http.MethodGet, userFilesPath+"?path=notafile", nil)
-	setBearerForReq(req, webAPIToken)
-	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusNotFound, rr)
-	assert.Contains(t, rr.Body.String(), "Unable to stat the requested file")
+	// remove the change password permission
+	user.Filters.WebClient = []string{sdk.WebClientPasswordChangeDisabled}
+	user, _, err = httpdtest.UpdateUser(user, http.StatusOK, "")
+	assert.NoError(t, err)
+	assert.Len(t, user.Filters.WebClient, 1)
+	assert.Contains(t, user.Filters.WebClient, sdk.WebClientPasswordChangeDisabled)
 
-	req, _ = http.NewRequest(http.MethodGet, webClientFilesPath+"?path="+testFileName, nil)
-	req.Header.Set("Range", "bytes=2-")
+	webToken, err = getJWTWebClientTokenFromTestServer(defaultUsername, defaultPassword+"1")
+	assert.NoError(t, err)
+	form.Set("current_password", defaultPassword+"1")
+	form.Set("new_password1", defaultPassword)
+	form.Set("new_password2", defaultPassword)
+	req, _ = http.NewRequest(http.MethodPost, webChangeClientPwdPath, bytes.NewBuffer([]byte(form.Encode())))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 	setJWTCookieForReq(req, webToken)
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusPartialContent, rr)
-	assert.Equal(t, testFileContents[2:], rr.Body.Bytes())
+	checkResponseCode(t, http.StatusForbidden, rr)
 
-	req, _ = http.NewRequest(http.MethodGet, userFilesPath+"?path="+testFileName, nil)
-	req.Header.Set("Range", "bytes=2-")
-	setBearerForReq(req, webAPIToken)
-	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusPartialContent, rr)
-	assert.Equal(t, testFileContents[2:], rr.Body.Bytes())
-
-	req, _ = http.NewRequest(http.MethodGet, webClientFilesPath+"?path="+testFileName, nil)
-	req.Header.Set("Range", "bytes=-2")
-	setJWTCookieForReq(req, webToken)
-	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusPartialContent, rr)
-	assert.Equal(t, testFileContents[11:], rr.Body.Bytes())
-
-	req, _ = http.NewRequest(http.MethodGet, webClientFilesPath+"?path="+testFileName, nil)
-	req.Header.Set("Range", "bytes=-2,")
-	setJWTCookieForReq(req, webToken)
-	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusRequestedRangeNotSatisfiable, rr)
-
-	req, _ = http.NewRequest(http.MethodGet, webClientFilesPath+"?path="+testFileName, nil)
-	req.Header.Set("Range", "bytes=1a-")
-	setJWTCookieForReq(req, webToken)
-	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusRequestedRangeNotSatisfiable, rr)
-
-	req, _ = http.NewRequest(http.MethodGet, userFilesPath+"?path="+testFileName, nil)
-	req.Header.Set("Range", "bytes=2b-")
-	setBearerForReq(req, webAPIToken)
-	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusRequestedRangeNotSatisfiable, rr)
-
-	req, _ = http.NewRequest(http.MethodHead, webClientFilesPath+"?path="+testFileName, nil)
-	req.Header.Set("Range", "bytes=2-")
-	req.Header.Set("If-Range", time.Now().UTC().Add(120*time.Second).Format(http.TimeFormat))
-	setJWTCookieForReq(req, webToken)
-	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusPartialContent, rr)
+	_, err = httpdtest.RemoveUser(user, http.StatusOK)
+	assert.NoError(t, err)
+	err = os.RemoveAll(user.GetHomeDir())
+	assert.NoError(t, err)
+}
 
-	req, _ = http.NewRequest(http.MethodHead, webClientFilesPath+"?path="+testFileName, nil)
-	req.Header.Set("Range", "bytes=2-")
-	req.Header.Set("If-Range", time.Now().UTC().Add(-120*time.Second).Format(http.TimeFormat))
+func TestUserPasswordChangeRequired(t *testing.T) {
+	u := getTestUser()
+	u.Filters.RequirePasswordChange = true
+	u.Filters.WebClient = []string{sdk.WebClientPasswordChangeDisabled}
+	_, resp, err := httpdtest.AddUser(u, http.StatusBadRequest)
+	assert.NoError(t, err)
+	assert.Contains(t, string(resp), "password change is disabled")
+	u.Filters.WebClient = nil
+	u.Filters.RequirePasswordChange = false
+	u.Filters.PasswordExpiration = -1
+	_, _, err = httpdtest.AddUser(u, http.StatusBadRequest)
+	assert.NoError(t, err)
+	u.Filters.PasswordExpiration = 90
+	u.Filters.RequirePasswordChange = true
+	user, _, err := httpdtest.AddUser(u, http.StatusCreated)
+	assert.NoError(t, err)
+	assert.True(t, user.Filters.RequirePasswordChange)
+	assert.Equal(t, 90, user.Filters.PasswordExpiration)
+	assert.Greater(t, user.LastPasswordChange, int64(0))
+	lastPwdChange := user.LastPasswordChange
+	// the password change requirement does not allow other protocols
+	_, err = dataprovider.CheckUserAndPass(defaultUsername, defaultPassword, "127.0.0.1", common.ProtocolSSH)
+	assert.ErrorIs(t, err, dataprovider.ErrPasswordChangeRequired)
+	// the web client must redirect to the change password page
+	csrfToken, err := getCSRFToken(httpBaseURL + webClientLoginPath)
+	assert.NoError(t, err)
+	form := getLoginForm(defaultUsername, defaultPassword, csrfToken)
+	req, err := http.NewRequest(http.MethodPost, webClientLoginPath, bytes.NewBuffer([]byte(form.Encode())))
+	assert.NoError(t, err)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rr := executeRequest(req)
+	checkResponseCode(t, http.StatusFound, rr)
+	assert.Equal(t, webChangeClientPwdPath, rr.Header().Get("Location"))
+	webToken, err := getCookieFromResponse(rr)
+	assert.NoError(t, err)
+	req, err = http.NewRequest(http.MethodGet, webClientFilesPath, nil)
+	assert.NoError(t, err)
 	setJWTCookieForReq(req, webToken)
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusOK, rr)
-
-	req, _ = http.NewRequest(http.MethodHead, webClientFilesPath+"?path="+testFileName, nil)
-	req.Header.Set("If-Modified-Since", time.Now().UTC().Add(-120*time.Second).Format(http.TimeFormat))
+	checkResponseCode(t, http.StatusFound, rr)
+	assert.Equal(t, webChangeClientPwdPath, rr.Header().Get("Location"))
+	req, err = http.NewRequest(http.MethodGet, webChangeClientPwdPath, nil)
+	assert.NoError(t, err)
 	setJWTCookieForReq(req, webToken)
 	rr = executeRequest(req)
 	checkResponseCode(t, http.StatusOK, rr)
-
-	req, _ = http.NewRequest(http.MethodHead, webClientFilesPath+"?path="+testFileName, nil)
-	req.Header.Set("If-Modified-Since", time.Now().UTC().Add(120*time.Second).Format(http.TimeFormat))
-	setJWTCookieForReq(req, webToken)
+	// the REST API only allows to change the password
+	token, err := getJWTAPIUserTokenFromTestServer(defaultUsername, defaultPassword)
+	assert.NoError(t, err)
+	req, err = http.NewRequest(http.MethodGet, userDirsPath, nil)
+	assert.NoError(t, err)
+	setBearerForReq(req, token)
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusNotModified, rr)
+	checkResponseCode(t, http.StatusForbidden, rr)
+	assert.Contains(t, rr.Body.String(), "Password change required")
 
-	req, _ = http.NewRequest(http.MethodHead, webClientFilesPath+"?path="+testFileName, nil)
-	req.Header.Set("If-Unmodified-Since", time.Now().UTC().Add(-120*time.Second).Format(http.TimeFormat))
-	setJWTCookieForReq(req, webToken)
+	pwd := make(map[string]string)
+	pwd["current_password"] = defaultPassword
+	pwd["new_password"] = altAdminPassword
+	asJSON, err := json.Marshal(pwd)
+	assert.NoError(t, err)
+	req, err = http.NewRequest(http.MethodPut, userPwdPath, bytes.NewBuffer(asJSON))
+	assert.NoError(t, err)
+	setBearerForReq(req, token)
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusPreconditionFailed, rr)
+	checkResponseCode(t, http.StatusOK, rr)
 
-	req, _ = http.NewRequest(http.MethodHead, userFilesPath+"?path="+testFileName, nil)
-	req.Header.Set("If-Unmodified-Since", time.Now().UTC().Add(-120*time.Second).Format(http.TimeFormat))
-	setBearerForReq(req, webAPIToken)
-	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusPreconditionFailed, rr)
+	user, _, err = httpdtest.GetUserByUsername(defaultUsername, http.StatusOK)
+	assert.NoError(t, err)
+	assert.False(t, user.Filters.RequirePasswordChange)
+	assert.Equal(t, 90, user.Filters.PasswordExpiration)
+	assert.GreaterOrEqual(t, user.LastPasswordChange, lastPwdChange)
+	_, err = dataprovider.CheckUserAndPass(defaultUsername, altAdminPassword, "127.0.0.1", common.ProtocolSSH)
+	assert.NoError(t, err)
 
-	req, _ = http.NewRequest(http.MethodHead, webClientFilesPath+"?path="+testFileName, nil)
-	req.Header.Set("If-Unmodified-Since", time.Now().UTC().Add(120*time.Second).Format(http.TimeFormat))
-	setJWTCookieForReq(req, webToken)
+	token, err = getJWTAPIUserTokenFromTestServer(defaultUsername, altAdminPassword)
+	assert.NoError(t, err)
+	req, err = http.NewRequest(http.MethodGet, userDirsPath, nil)
+	assert.NoError(t, err)
+	setBearerForReq(req, token)
 	rr = executeRequest(req)
 	checkResponseCode(t, http.StatusOK, rr)
 
-	user.Filters.DeniedProtocols = []string{common.ProtocolHTTP}
-	_, resp, err := httpdtest.UpdateUser(user, http.StatusOK, "")
-	assert.NoError(t, err, string(resp))
+	_, err = httpdtest.RemoveUser(user, http.StatusOK)
+	assert.NoError(t, err)
+	err = os.RemoveAll(user.GetHomeDir())
+	assert.NoError(t, err)
+}
 
-	req, _ = http.NewRequest(http.MethodGet, webClientFilesPath, nil)
-	setJWTCookieForReq(req, webToken)
-	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusForbidden, rr)
+func TestAdminPasswordChangeRequired(t *testing.T) {
+	a := getTestAdmin()
+	a.Username = altAdminUsername
+	a.Password = altAdminPassword
+	a.Filters.PasswordExpiration = -1
+	_, _, err := httpdtest.AddAdmin(a, http.StatusBadRequest)
+	assert.NoError(t, err)
+	a.Filters.PasswordExpiration = 30
+	a.Filters.RequirePasswordChange = true
+	admin, _, err := httpdtest.AddAdmin(a, http.StatusCreated)
+	assert.NoError(t, err)
+	assert.True(t, admin.Filters.RequirePasswordChange)
+	assert.Equal(t, 30, admin.Filters.PasswordExpiration)
+	assert.Greater(t, admin.LastPasswordChange, int64(0))
 
-	req, _ = http.NewRequest(http.MethodGet, webClientDirsPath+"?path=/", nil)
+	csrfToken, err := getCSRFToken(httpBaseURL + webLoginPath)
+	assert.NoError(t, err)
+	form := getLoginForm(altAdminUsername, altAdminPassword, csrfToken)
+	req, err := http.NewRequest(http.MethodPost, webLoginPath, bytes.NewBuffer([]byte(form.Encode())))
+	assert.NoError(t, err)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rr := executeRequest(req)
+	checkResponseCode(t, http.StatusFound, rr)
+	assert.Equal(t, webChangeAdminPwdPath, rr.Header().Get("Location"))
+	webToken, err := getCookieFromResponse(rr)
+	assert.NoError(t, err)
+	req, err = http.NewRequest(http.MethodGet, webUsersPath, nil)
+	assert.NoError(t, err)
 	setJWTCookieForReq(req, webToken)
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusForbidden, rr)
-
-	req, _ = http.NewRequest(http.MethodGet, userFilesPath+"?path="+testFileName, nil)
-	setBearerForReq(req, webAPIToken)
-	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusForbidden, rr)
-
-	req, _ = http.NewRequest(http.MethodGet, userDirsPath+"?path="+testDir, nil)
-	setBearerForReq(req, webAPIToken)
-	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusForbidden, rr)
+	checkResponseCode(t, http.StatusFound, rr)
+	assert.Equal(t, webChangeAdminPwdPath, rr.Header().Get("Location"))
 
-	filesList = []string{testDir}
-	asJSON, err = json.Marshal(filesList)
+	token, err := getJWTAPITokenFromTestServer(altAdminUsername, altAdminPassword)
 	assert.NoError(t, err)
-	req, _ = http.NewRequest(http.MethodPost, userStreamZipPath, bytes.NewBuffer(asJSON))
-	setBearerForReq(req, webAPIToken)
+	req, err = http.NewRequest(http.MethodGet, userPath, nil)
+	assert.NoError(t, err)
+	setBearerForReq(req, token)
 	rr = executeRequest(req)
 	checkResponseCode(t, http.StatusForbidden, rr)
 
-	user.Filters.DeniedProtocols = []string{common.ProtocolFTP}
-	user.Filters.DeniedLoginMethods = []string{dataprovider.LoginMethodPassword}
-	_, resp, err = httpdtest.UpdateUser(user, http.StatusOK, "")
-	assert.NoError(t, err, string(resp))
-
-	req, _ = http.NewRequest(http.MethodGet, webClientFilesPath, nil)
-	setJWTCookieForReq(req, webToken)
+	pwd := make(map[string]string)
+	pwd["current_password"] = altAdminPassword
+	pwd["new_password"] = defaultTokenAuthPass
+	asJSON, err := json.Marshal(pwd)
+	assert.NoError(t, err)
+	req, err = http.NewRequest(http.MethodPut, adminPwdPath, bytes.NewBuffer(asJSON))
+	assert.NoError(t, err)
+	setBearerForReq(req, token)
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusForbidden, rr)
+	checkResponseCode(t, http.StatusOK, rr)
 
-	req, _ = http.NewRequest(http.MethodGet, webClientDownloadZipPath, nil)
-	setJWTCookieForReq(req, webToken)
-	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusForbidden, rr)
+	admin, _, err = httpdtest.GetAdminByUsername(altAdminUsername, http.StatusOK)
+	assert.NoError(t, err)
+	assert.False(t, admin.Filters.RequirePasswordChange)
+	assert.Equal(t, 30, admin.Filters.PasswordExpiration)
 
-	req, _ = http.NewRequest(http.MethodGet, userDirsPath+"?path="+testDir, nil)
-	setBearerForReq(req, webAPIToken)
+	token, err = getJWTAPITokenFromTestServer(altAdminUsername, defaultTokenAuthPass)
+	assert.NoError(t, err)
+	req, err = http.NewRequest(http.MethodGet, userPath, nil)
+	assert.NoError(t, err)
+	setBearerForReq(req, token)
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusForbidden, rr)
+	checkResponseCode(t, http.StatusOK, rr)
 
-	_, err = httpdtest.RemoveUser(user, http.StatusOK)
-	assert.NoError(t, err)
-	err = os.RemoveAll(user.GetHomeDir())
+	_, err = httpdtest.RemoveAdmin(admin, http.StatusOK)
 	assert.NoError(t, err)
 }
 
-func TestWebDirsAPI(t *testing.T) {
+func TestWebAPIPublicKeys(t *testing.T) {
 	user, _, err := httpdtest.AddUser(getTestUser(), http.StatusCreated)
 	assert.NoError(t, err)
-	webAPIToken, err := getJWTAPIUserTokenFromTestServer(defaultUsername, defaultPassword)
+	apiToken, err := getJWTAPIUserTokenFromTestServer(defaultUsername, defaultPassword)
 	assert.NoError(t, err)
-	testDir := "testdir"
 
-	req, err := http.NewRequest(http.MethodGet, userDirsPath, nil)
+	req, err := http.NewRequest(http.MethodGet, userPublicKeysPath, nil)
 	assert.NoError(t, err)
-	setBearerForReq(req, webAPIToken)
+	setBearerForReq(req, apiToken)
 	rr := executeRequest(req)
 	checkResponseCode(t, http.StatusOK, rr)
-	var contents []map[string]interface{}
-	err = json.NewDecoder(rr.Body).Decode(&contents)
+	var keys []string
+	err = json.Unmarshal(rr.Body.Bytes(), &keys)
 	assert.NoError(t, err)
-	assert.Len(t, contents, 0)
+	assert.Len(t, keys, 0)
 
-	// rename a missing folder
-	req, err = http.NewRequest(http.MethodPatch, userDirsPath+"?path="+testDir+"&target="+testDir+"new", nil)
-	assert.NoError(t, err)
-	setBearerForReq(req, webAPIToken)
-	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusNotFound, rr)
-	// delete a missing folder
-	req, err = http.NewRequest(http.MethodDelete, userDirsPath+"?path="+testDir, nil)
-	assert.NoError(t, err)
-	setBearerForReq(req, webAPIToken)
-	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusNotFound, rr)
-	// create a dir
-	req, err = http.NewRequest(http.MethodPost, userDirsPath+"?path="+testDir, nil)
+	keys = []string{testPubKey, testPubKey1}
+	asJSON, err := json.Marshal(keys)
 	assert.NoError(t, err)
-	setBearerForReq(req, webAPIToken)
-	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusCreated, rr)
-	// check the dir was created
-	req, err = http.NewRequest(http.MethodGet, userDirsPath, nil)
+	req, err = http.NewRequest(http.MethodPut, userPublicKeysPath, bytes.NewBuffer(asJSON))
 	assert.NoError(t, err)
-	setBearerForReq(req, webAPIToken)
+	setBearerForReq(req, apiToken)
 	rr = executeRequest(req)
 	checkResponseCode(t, http.StatusOK, rr)
-	contents = nil
-	err = json.NewDecoder(rr.Body).Decode(&contents)
-	assert.NoError(t, err)
-	if assert.Len(t, contents, 1) {
-		assert.Equal(t, testDir, contents[0]["name"])
-	}
-	// rename the dir
-	req, err = http.NewRequest(http.MethodPatch, userDirsPath+"?path="+testDir+"&target="+testDir+"new", nil)
+
+	req, err = http.NewRequest(http.MethodGet, userPublicKeysPath, nil)
 	assert.NoError(t, err)
-	setBearerForReq(req, webAPIToken)
+	setBearerForReq(req, apiToken)
 	rr = executeRequest(req)
 	checkResponseCode(t, http.StatusOK, rr)
-	// delete the dir
-	req, err = http.NewRequest(http.MethodDelete, userDirsPath+"?path="+testDir+"new", nil)
+	keys = nil
+	err = json.Unmarshal(rr.Body.Bytes(), &keys)
 	assert.NoError(t, err)
-	setBearerForReq(req, webAPIToken)
-	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusOK, rr)
-	// the root dir cannot be created
-	req, err = http.NewRequest(http.MethodPost, userDirsPath, nil)
+	assert.Len(t, keys, 2)
+
+	req, err = http.NewRequest(http.MethodPut, userPublicKeysPath, bytes.NewBuffer([]byte(`invalid json`)))
 	assert.NoError(t, err)
-	setBearerForReq(req, webAPIToken)
+	setBearerForReq(req, apiToken)
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusInternalServerError, rr)
+	checkResponseCode(t, http.StatusBadRequest, rr)
 
-	user.Permissions["/"] = []string{dataprovider.PermListItems}
-	user, _, err = httpdtest.UpdateUser(user, http.StatusOK, "")
+	keys = []string{`not a public key`}
+	asJSON, err = json.Marshal(keys)
 	assert.NoError(t, err)
-	// the user has no more the permission to create the directory
-	req, err = http.NewRequest(http.MethodPost, userDirsPath+"?path="+testDir, nil)
+	req, err = http.NewRequest(http.MethodPut, userPublicKeysPath, bytes.NewBuffer(asJSON))
 	assert.NoError(t, err)
-	setBearerForReq(req, webAPIToken)
+	setBearerForReq(req, apiToken)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusBadRequest, rr)
+	assert.Contains(t, rr.Body.String(), "could not parse key")
+
+	user.Filters.WebClient = append(user.Filters.WebClient, sdk.WebClientPubKeyChangeDisabled)
+	_, _, err = httpdtest.UpdateUser(user, http.StatusOK, "")
+	assert.NoError(t, err)
+
+	apiToken, err = getJWTAPIUserTokenFromTestServer(defaultUsername, defaultPassword)
+	assert.NoError(t, err)
+	req, err = http.NewRequest(http.MethodGet, userPublicKeysPath, nil)
+	assert.NoError(t, err)
+	setBearerForReq(req, apiToken)
 	rr = executeRequest(req)
 	checkResponseCode(t, http.StatusForbidden, rr)
 
@@ -8889,516 +11730,601 @@ func TestWebDirsAPI(t *testing.T) {
 	assert.NoError(t, err)
 	err = os.RemoveAll(user.GetHomeDir())
 	assert.NoError(t, err)
+}
 
-	// the user is deleted, any API call should fail
-	req, err = http.NewRequest(http.MethodPost, userDirsPath+"?path="+testDir, nil)
+func TestPreDownloadHook(t *testing.T) {
+	if runtime.GOOS == osWindows {
+		t.Skip("this test is not available on Windows")
+	}
+	oldExecuteOn := common.Config.Actions.ExecuteOn
+	oldHook := common.Config.Actions.Hook
+
+	common.Config.Actions.ExecuteOn = []string{common.OperationPreDownload}
+	common.Config.Actions.Hook = preActionPath
+
+	u := getTestUser()
+	user, _, err := httpdtest.AddUser(u, http.StatusCreated)
+	assert.NoError(t, err)
+	err = os.WriteFile(preActionPath, getExitCodeScriptContent(0), os.ModePerm)
+	assert.NoError(t, err)
+
+	testFileName := "testfile"
+	testFileContents := []byte("file contents")
+	err = os.MkdirAll(filepath.Join(user.GetHomeDir()), os.ModePerm)
+	assert.NoError(t, err)
+	err = os.WriteFile(filepath.Join(user.GetHomeDir(), testFileName), testFileContents, os.ModePerm)
+	assert.NoError(t, err)
+
+	webToken, err := getJWTWebClientTokenFromTestServer(defaultUsername, defaultPassword)
+	assert.NoError(t, err)
+	webAPIToken, err := getJWTAPIUserTokenFromTestServer(defaultUsername, defaultPassword)
+	assert.NoError(t, err)
+	req, err := http.NewRequest(http.MethodGet, webClientFilesPath+"?path="+testFileName, nil)
+	assert.NoError(t, err)
+	setJWTCookieForReq(req, webToken)
+	rr := executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
+	assert.Equal(t, testFileContents, rr.Body.Bytes())
+
+	req, err = http.NewRequest(http.MethodGet, userFilesPath+"?path="+testFileName, nil)
 	assert.NoError(t, err)
 	setBearerForReq(req, webAPIToken)
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusNotFound, rr)
+	checkResponseCode(t, http.StatusOK, rr)
+	assert.Equal(t, testFileContents, rr.Body.Bytes())
 
-	req, err = http.NewRequest(http.MethodPatch, userDirsPath+"?path="+testDir+"&target="+testDir+"new", nil)
+	err = os.WriteFile(preActionPath, getExitCodeScriptContent(1), os.ModePerm)
 	assert.NoError(t, err)
-	setBearerForReq(req, webAPIToken)
+	req, err = http.NewRequest(http.MethodGet, webClientFilesPath+"?path="+testFileName, nil)
+	assert.NoError(t, err)
+	setJWTCookieForReq(req, webToken)
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusNotFound, rr)
+	checkResponseCode(t, http.StatusOK, rr)
+	assert.Contains(t, rr.Body.String(), "permission denied")
 
-	req, err = http.NewRequest(http.MethodDelete, userDirsPath+"?path="+testDir+"new", nil)
+	req, err = http.NewRequest(http.MethodGet, userFilesPath+"?path="+testFileName, nil)
 	assert.NoError(t, err)
 	setBearerForReq(req, webAPIToken)
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusNotFound, rr)
+	checkResponseCode(t, http.StatusForbidden, rr)
+	assert.Contains(t, rr.Body.String(), "permission denied")
+
+	_, err = httpdtest.RemoveUser(user, http.StatusOK)
+	assert.NoError(t, err)
+	err = os.RemoveAll(user.GetHomeDir())
+	assert.NoError(t, err)
+
+	common.Config.Actions.ExecuteOn = oldExecuteOn
+	common.Config.Actions.Hook = oldHook
 }
 
-func TestWebFilesAPI(t *testing.T) {
-	user, _, err := httpdtest.AddUser(getTestUser(), http.StatusCreated)
+func TestPreUploadHook(t *testing.T) {
+	if runtime.GOOS == osWindows {
+		t.Skip("this test is not available on Windows")
+	}
+	oldExecuteOn := common.Config.Actions.ExecuteOn
+	oldHook := common.Config.Actions.Hook
+
+	common.Config.Actions.ExecuteOn = []string{common.OperationPreUpload}
+	common.Config.Actions.Hook = preActionPath
+
+	u := getTestUser()
+	user, _, err := httpdtest.AddUser(u, http.StatusCreated)
+	assert.NoError(t, err)
+	err = os.WriteFile(preActionPath, getExitCodeScriptContent(0), os.ModePerm)
 	assert.NoError(t, err)
+
 	webAPIToken, err := getJWTAPIUserTokenFromTestServer(defaultUsername, defaultPassword)
 	assert.NoError(t, err)
 
 	body := new(bytes.Buffer)
 	writer := multipart.NewWriter(body)
-	part1, err := writer.CreateFormFile("filename", "file1.txt")
-	assert.NoError(t, err)
-	_, err = part1.Write([]byte("file1 content"))
-	assert.NoError(t, err)
-	part2, err := writer.CreateFormFile("filename", "file2.txt")
+	part, err := writer.CreateFormFile("filename", "filepre")
 	assert.NoError(t, err)
-	_, err = part2.Write([]byte("file2 content"))
+	_, err = part.Write([]byte("file content"))
 	assert.NoError(t, err)
 	err = writer.Close()
 	assert.NoError(t, err)
 	reader := bytes.NewReader(body.Bytes())
-
+	_, err = reader.Seek(0, io.SeekStart)
+	assert.NoError(t, err)
 	req, err := http.NewRequest(http.MethodPost, userFilesPath, reader)
 	assert.NoError(t, err)
+	req.Header.Add("Content-Type", writer.FormDataContentType())
 	setBearerForReq(req, webAPIToken)
 	rr := executeRequest(req)
-	checkResponseCode(t, http.StatusBadRequest, rr)
-	assert.Contains(t, rr.Body.String(), "Unable to parse multipart form")
+	checkResponseCode(t, http.StatusCreated, rr)
+
+	err = os.WriteFile(preActionPath, getExitCodeScriptContent(1), os.ModePerm)
+	assert.NoError(t, err)
 	_, err = reader.Seek(0, io.SeekStart)
 	assert.NoError(t, err)
-	// set the proper content type
 	req, err = http.NewRequest(http.MethodPost, userFilesPath, reader)
 	assert.NoError(t, err)
 	req.Header.Add("Content-Type", writer.FormDataContentType())
 	setBearerForReq(req, webAPIToken)
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusCreated, rr)
-	// check we have 2 files
-	req, err = http.NewRequest(http.MethodGet, userDirsPath, nil)
+	checkResponseCode(t, http.StatusForbidden, rr)
+
+	_, err = httpdtest.RemoveUser(user, http.StatusOK)
 	assert.NoError(t, err)
-	setBearerForReq(req, webAPIToken)
-	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusOK, rr)
-	var contents []map[string]interface{}
-	err = json.NewDecoder(rr.Body).Decode(&contents)
+	err = os.RemoveAll(user.GetHomeDir())
 	assert.NoError(t, err)
-	assert.Len(t, contents, 2)
-	// overwrite the existing files
+
+	common.Config.Actions.ExecuteOn = oldExecuteOn
+	common.Config.Actions.Hook = oldHook
+}
+
+func TestUserAPIKey(t *testing.T) {
+	u := getTestUser()
+	u.Filters.AllowAPIKeyAuth = true
+	user, _, err := httpdtest.AddUser(u, http.StatusCreated)
+	assert.NoError(t, err)
+	apiKey := dataprovider.APIKey{
+		Name:  "testkey",
+		User:  user.Username,
+		Scope: dataprovider.APIKeyScopeUser,
+	}
+	apiKey, _, err = httpdtest.AddAPIKey(apiKey, http.StatusCreated)
+	assert.NoError(t, err)
+
+	body := new(bytes.Buffer)
+	writer := multipart.NewWriter(body)
+	part, err := writer.CreateFormFile("filename", "filenametest")
+	assert.NoError(t, err)
+	_, err = part.Write([]byte("test file content"))
+	assert.NoError(t, err)
+	err = writer.Close()
+	assert.NoError(t, err)
+	reader := bytes.NewReader(body.Bytes())
 	_, err = reader.Seek(0, io.SeekStart)
 	assert.NoError(t, err)
-	req, err = http.NewRequest(http.MethodPost, userFilesPath, reader)
+	req, err := http.NewRequest(http.MethodPost, userFilesPath, reader)
 	assert.NoError(t, err)
 	req.Header.Add("Content-Type", writer.FormDataContentType())
-	setBearerForReq(req, webAPIToken)
-	rr = executeRequest(req)
+	setAPIKeyForReq(req, apiKey.Key, "")
+	rr := executeRequest(req)
 	checkResponseCode(t, http.StatusCreated, rr)
+
 	req, err = http.NewRequest(http.MethodGet, userDirsPath, nil)
 	assert.NoError(t, err)
-	setBearerForReq(req, webAPIToken)
+	setAPIKeyForReq(req, apiKey.Key, "")
 	rr = executeRequest(req)
 	checkResponseCode(t, http.StatusOK, rr)
-	contents = nil
-	err = json.NewDecoder(rr.Body).Decode(&contents)
+	var dirEntries []map[string]interface{}
+	err = json.Unmarshal(rr.Body.Bytes(), &dirEntries)
 	assert.NoError(t, err)
-	assert.Len(t, contents, 2)
-	// now create a dir and upload to that dir
-	testDir := "tdir"
-	req, err = http.NewRequest(http.MethodPost, userDirsPath+"?path="+testDir, nil)
+	assert.Len(t, dirEntries, 1)
+
+	user.Status = 0
+	user, _, err = httpdtest.UpdateUser(user, http.StatusOK, "")
 	assert.NoError(t, err)
-	setBearerForReq(req, webAPIToken)
+	req, err = http.NewRequest(http.MethodGet, userDirsPath, nil)
+	assert.NoError(t, err)
+	setAPIKeyForReq(req, apiKey.Key, "")
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusCreated, rr)
-	_, err = reader.Seek(0, io.SeekStart)
+	checkResponseCode(t, http.StatusUnauthorized, rr)
+
+	user.Status = 1
+	user.Filters.DeniedProtocols = []string{common.ProtocolHTTP}
+	user, _, err = httpdtest.UpdateUser(user, http.StatusOK, "")
 	assert.NoError(t, err)
-	req, err = http.NewRequest(http.MethodPost, userFilesPath+"?path="+testDir, reader)
+
+	req, err = http.NewRequest(http.MethodGet, userDirsPath, nil)
 	assert.NoError(t, err)
-	req.Header.Add("Content-Type", writer.FormDataContentType())
-	setBearerForReq(req, webAPIToken)
+	setAPIKeyForReq(req, apiKey.Key, "")
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusCreated, rr)
+	checkResponseCode(t, http.StatusUnauthorized, rr)
+
+	user.Filters.DeniedProtocols = []string{common.ProtocolFTP}
+	user, _, err = httpdtest.UpdateUser(user, http.StatusOK, "")
+	assert.NoError(t, err)
+
 	req, err = http.NewRequest(http.MethodGet, userDirsPath, nil)
 	assert.NoError(t, err)
-	setBearerForReq(req, webAPIToken)
+	setAPIKeyForReq(req, apiKey.Key, "")
 	rr = executeRequest(req)
 	checkResponseCode(t, http.StatusOK, rr)
-	contents = nil
-	err = json.NewDecoder(rr.Body).Decode(&contents)
+	apiKeyNew := dataprovider.APIKey{
+		Name:  apiKey.Name,
+		Scope: dataprovider.APIKeyScopeUser,
+	}
+
+	apiKeyNew, _, err = httpdtest.AddAPIKey(apiKeyNew, http.StatusCreated)
 	assert.NoError(t, err)
-	assert.Len(t, contents, 3)
-	req, err = http.NewRequest(http.MethodGet, userDirsPath+"?path="+testDir, nil)
+	req, err = http.NewRequest(http.MethodGet, userDirsPath, nil)
 	assert.NoError(t, err)
-	setBearerForReq(req, webAPIToken)
+	setAPIKeyForReq(req, apiKeyNew.Key, "")
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusOK, rr)
-	contents = nil
-	err = json.NewDecoder(rr.Body).Decode(&contents)
-	assert.NoError(t, err)
-	assert.Len(t, contents, 2)
-	// rename a file
-	req, err = http.NewRequest(http.MethodPatch, userFilesPath+"?path=file1.txt&target=%2Ftdir%2Ffile3.txt", nil)
+	checkResponseCode(t, http.StatusUnauthorized, rr)
+	// now associate a user
+	req, err = http.NewRequest(http.MethodGet, userDirsPath, nil)
 	assert.NoError(t, err)
-	setBearerForReq(req, webAPIToken)
+	setAPIKeyForReq(req, apiKeyNew.Key, user.Username)
 	rr = executeRequest(req)
 	checkResponseCode(t, http.StatusOK, rr)
-	// rename a missing file
-	req, err = http.NewRequest(http.MethodPatch, userFilesPath+"?path=file1.txt&target=%2Ftdir%2Ffile3.txt", nil)
+	// now with a missing user
+	req, err = http.NewRequest(http.MethodGet, userDirsPath, nil)
 	assert.NoError(t, err)
-	setBearerForReq(req, webAPIToken)
+	setAPIKeyForReq(req, apiKeyNew.Key, user.Username+"1")
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusNotFound, rr)
-	// delete a file
-	req, err = http.NewRequest(http.MethodDelete, userFilesPath+"?path=file2.txt", nil)
+	checkResponseCode(t, http.StatusUnauthorized, rr)
+	// empty user and key not associated to any user
+	req, err = http.NewRequest(http.MethodGet, userDirsPath, nil)
 	assert.NoError(t, err)
-	setBearerForReq(req, webAPIToken)
+	setAPIKeyForReq(req, apiKeyNew.Key, "")
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusOK, rr)
-	// delete a missing file
-	req, err = http.NewRequest(http.MethodDelete, userFilesPath+"?path=file2.txt", nil)
+	checkResponseCode(t, http.StatusUnauthorized, rr)
+	apiKeyNew.ExpiresAt = util.GetTimeAsMsSinceEpoch(time.Now().Add(-24 * time.Hour))
+	_, _, err = httpdtest.UpdateAPIKey(apiKeyNew, http.StatusOK)
 	assert.NoError(t, err)
-	setBearerForReq(req, webAPIToken)
-	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusNotFound, rr)
-	// delete a directory
-	req, err = http.NewRequest(http.MethodDelete, userFilesPath+"?path=tdir", nil)
+	// expired API key
+	req, err = http.NewRequest(http.MethodGet, userDirsPath, nil)
 	assert.NoError(t, err)
-	setBearerForReq(req, webAPIToken)
+	setAPIKeyForReq(req, apiKeyNew.Key, user.Username)
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusBadRequest, rr)
-	// make a symlink outside the home dir and then try to delete it
-	extPath := filepath.Join(os.TempDir(), "file")
-	err = os.WriteFile(extPath, []byte("contents"), os.ModePerm)
-	assert.NoError(t, err)
-	err = os.Symlink(extPath, filepath.Join(user.GetHomeDir(), "file"))
+	checkResponseCode(t, http.StatusUnauthorized, rr)
+
+	_, err = httpdtest.RemoveUser(user, http.StatusOK)
 	assert.NoError(t, err)
-	req, err = http.NewRequest(http.MethodDelete, userFilesPath+"?path=file", nil)
+	err = os.RemoveAll(user.GetHomeDir())
 	assert.NoError(t, err)
-	setBearerForReq(req, webAPIToken)
-	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusForbidden, rr)
-	err = os.Remove(extPath)
+
+	_, err = httpdtest.RemoveAPIKey(apiKeyNew, http.StatusOK)
 	assert.NoError(t, err)
-	// remove delete and overwrite permissions
-	user.Permissions["/"] = []string{dataprovider.PermListItems, dataprovider.PermDownload, dataprovider.PermUpload}
-	user, _, err = httpdtest.UpdateUser(user, http.StatusOK, "")
+}
+
+func TestWebEditFile(t *testing.T) {
+	user, _, err := httpdtest.AddUser(getTestUser(), http.StatusCreated)
 	assert.NoError(t, err)
-	_, err = reader.Seek(0, io.SeekStart)
+	testFile1 := "testfile1.txt"
+	testFile2 := "testfile2"
+	file1Size := int64(65536)
+	file2Size := int64(655360)
+	err = createTestFile(filepath.Join(user.GetHomeDir(), testFile1), file1Size)
 	assert.NoError(t, err)
-	req, err = http.NewRequest(http.MethodPost, userFilesPath+"?path=tdir", reader)
+	err = createTestFile(filepath.Join(user.GetHomeDir(), testFile2), file2Size)
 	assert.NoError(t, err)
-	req.Header.Add("Content-Type", writer.FormDataContentType())
-	setBearerForReq(req, webAPIToken)
-	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusForbidden, rr)
 
-	req, err = http.NewRequest(http.MethodDelete, userFilesPath+"?path=%2Ftdir%2Ffile1.txt", nil)
+	webToken, err := getJWTWebClientTokenFromTestServer(defaultUsername, defaultPassword)
 	assert.NoError(t, err)
-	setBearerForReq(req, webAPIToken)
-	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusForbidden, rr)
 
-	_, err = httpdtest.RemoveUser(user, http.StatusOK)
-	assert.NoError(t, err)
-	err = os.RemoveAll(user.GetHomeDir())
+	req, err := http.NewRequest(http.MethodGet, webClientEditFilePath+"?path="+testFile1, nil)
 	assert.NoError(t, err)
-	// the user is deleted, any API call should fail
-	_, err = reader.Seek(0, io.SeekStart)
+	setJWTCookieForReq(req, webToken)
+	rr := executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
+
+	req, err = http.NewRequest(http.MethodGet, webClientEditFilePath+"?path="+testFile2, nil)
 	assert.NoError(t, err)
-	req, err = http.NewRequest(http.MethodPost, userFilesPath, reader)
+	setJWTCookieForReq(req, webToken)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusBadRequest, rr)
+	assert.Contains(t, rr.Body.String(), "exceeds the maximum allowed size")
+
+	req, err = http.NewRequest(http.MethodGet, webClientEditFilePath+"?path=missing", nil)
 	assert.NoError(t, err)
-	req.Header.Add("Content-Type", writer.FormDataContentType())
-	setBearerForReq(req, webAPIToken)
+	setJWTCookieForReq(req, webToken)
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusNotFound, rr)
+	checkResponseCode(t, http.StatusBadRequest, rr)
+	assert.Contains(t, rr.Body.String(), "Unable to stat file")
 
-	req, err = http.NewRequest(http.MethodPatch, userFilesPath+"?path=file1.txt&target=%2Ftdir%2Ffile3.txt", nil)
+	req, err = http.NewRequest(http.MethodGet, webClientEditFilePath+"?path=%2F", nil)
 	assert.NoError(t, err)
-	setBearerForReq(req, webAPIToken)
+	setJWTCookieForReq(req, webToken)
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusNotFound, rr)
+	checkResponseCode(t, http.StatusBadRequest, rr)
+	assert.Contains(t, rr.Body.String(), "does not point to a file")
 
-	req, err = http.NewRequest(http.MethodDelete, userFilesPath+"?path=file2.txt", nil)
+	user.Filters.DeniedProtocols = []string{common.ProtocolHTTP}
+	_, _, err = httpdtest.UpdateUser(user, http.StatusOK, "")
 	assert.NoError(t, err)
-	setBearerForReq(req, webAPIToken)
+
+	req, err = http.NewRequest(http.MethodGet, webClientEditFilePath+"?path="+testFile1, nil)
+	assert.NoError(t, err)
+	setJWTCookieForReq(req, webToken)
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusNotFound, rr)
-}
+	checkResponseCode(t, http.StatusForbidden, rr)
 
-func TestWebUploadErrors(t *testing.T) {
-	u := getTestUser()
-	u.QuotaSize = 65535
-	subDir1 := "sub1"
-	subDir2 := "sub2"
-	u.Permissions[path.Join("/", subDir1)] = []string{dataprovider.PermListItems}
-	u.Permissions[path.Join("/", subDir2)] = []string{dataprovider.PermListItems, dataprovider.PermUpload,
-		dataprovider.PermDelete}
-	u.Filters.FilePatterns = []sdk.PatternsFilter{
+	user.Filters.DeniedProtocols = []string{common.ProtocolFTP}
+	user.Filters.FilePatterns = []sdk.PatternsFilter{
 		{
-			Path:            "/sub2",
-			AllowedPatterns: []string{},
-			DeniedPatterns:  []string{"*.zip"},
+			Path:           "/",
+			DeniedPatterns: []string{"*.txt"},
 		},
 	}
-	user, _, err := httpdtest.AddUser(u, http.StatusCreated)
-	assert.NoError(t, err)
-	webAPIToken, err := getJWTAPIUserTokenFromTestServer(defaultUsername, defaultPassword)
+	_, _, err = httpdtest.UpdateUser(user, http.StatusOK, "")
 	assert.NoError(t, err)
 
-	body := new(bytes.Buffer)
-	writer := multipart.NewWriter(body)
-	part, err := writer.CreateFormFile("filename", "file.zip")
-	assert.NoError(t, err)
-	_, err = part.Write([]byte("file content"))
-	assert.NoError(t, err)
-	err = writer.Close()
-	assert.NoError(t, err)
-	reader := bytes.NewReader(body.Bytes())
-	_, err = reader.Seek(0, io.SeekStart)
-	assert.NoError(t, err)
-	// zip file are not allowed within sub2
-	req, err := http.NewRequest(http.MethodPost, userFilesPath+"?path=sub2", reader)
+	req, err = http.NewRequest(http.MethodGet, webClientEditFilePath+"?path="+testFile1, nil)
 	assert.NoError(t, err)
-	req.Header.Add("Content-Type", writer.FormDataContentType())
-	setBearerForReq(req, webAPIToken)
-	rr := executeRequest(req)
+	setJWTCookieForReq(req, webToken)
+	rr = executeRequest(req)
 	checkResponseCode(t, http.StatusForbidden, rr)
+	assert.Contains(t, rr.Body.String(), "Unable to get a reader")
 
-	_, err = reader.Seek(0, io.SeekStart)
+	_, err = httpdtest.RemoveUser(user, http.StatusOK)
 	assert.NoError(t, err)
-	// we have no upload permissions within sub1
-	req, err = http.NewRequest(http.MethodPost, userFilesPath+"?path=sub1", reader)
+	err = os.RemoveAll(user.GetHomeDir())
 	assert.NoError(t, err)
-	req.Header.Add("Content-Type", writer.FormDataContentType())
-	setBearerForReq(req, webAPIToken)
-	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusForbidden, rr)
 
-	// create a dir and try to overwrite it with a file
-	req, err = http.NewRequest(http.MethodPost, userDirsPath+"?path=file.zip", nil)
+	req, err = http.NewRequest(http.MethodGet, webClientEditFilePath+"?path="+testFile1, nil)
 	assert.NoError(t, err)
-	setBearerForReq(req, webAPIToken)
+	setJWTCookieForReq(req, webToken)
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusCreated, rr)
+	checkResponseCode(t, http.StatusNotFound, rr)
+}
 
-	_, err = reader.Seek(0, io.SeekStart)
+func TestWebGetFiles(t *testing.T) {
+	user, _, err := httpdtest.AddUser(getTestUser(), http.StatusCreated)
 	assert.NoError(t, err)
-	req, err = http.NewRequest(http.MethodPost, userFilesPath, reader)
+	testFileName := "testfile"
+	testDir := "testdir"
+	testFileContents := []byte("file contents")
+	err = os.MkdirAll(filepath.Join(user.GetHomeDir(), testDir), os.ModePerm)
 	assert.NoError(t, err)
-	req.Header.Add("Content-Type", writer.FormDataContentType())
-	setBearerForReq(req, webAPIToken)
-	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusInternalServerError, rr)
-	assert.Contains(t, rr.Body.String(), "operation unsupported")
-	// try to upload to a missing parent directory
-	_, err = reader.Seek(0, io.SeekStart)
+	extensions := []string{"", ".doc", ".ppt", ".xls", ".pdf", ".mkv", ".png", ".go", ".zip", ".txt"}
+	for _, ext := range extensions {
+		err = os.WriteFile(filepath.Join(user.GetHomeDir(), testFileName+ext), testFileContents, os.ModePerm)
+		assert.NoError(t, err)
+	}
+	err = os.Symlink(filepath.Join(user.GetHomeDir(), testFileName+".doc"), filepath.Join(user.GetHomeDir(), testDir, testFileName+".link"))
 	assert.NoError(t, err)
-	req, err = http.NewRequest(http.MethodPost, userFilesPath+"?path=missingdir", reader)
+	webToken, err := getJWTWebClientTokenFromTestServer(defaultUsername, defaultPassword)
 	assert.NoError(t, err)
-	req.Header.Add("Content-Type", writer.FormDataContentType())
-	setBearerForReq(req, webAPIToken)
+	webAPIToken, err := getJWTAPIUserTokenFromTestServer(defaultUsername, defaultPassword)
+	assert.NoError(t, err)
+	req, _ := http.NewRequest(http.MethodGet, webClientFilesPath, nil)
+	setJWTCookieForReq(req, webToken)
+	rr := executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
+
+	req, _ = http.NewRequest(http.MethodGet, webClientFilesPath+"?path="+testDir, nil)
+	setJWTCookieForReq(req, webToken)
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusNotFound, rr)
+	checkResponseCode(t, http.StatusOK, rr)
 
-	req, err = http.NewRequest(http.MethodDelete, userDirsPath+"?path=file.zip", nil)
+	req, _ = http.NewRequest(http.MethodGet, webClientDirsPath+"?path="+testDir, nil)
+	setJWTCookieForReq(req, webToken)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
+	var dirContents []map[string]string
+	err = json.Unmarshal(rr.Body.Bytes(), &dirContents)
 	assert.NoError(t, err)
+	assert.Len(t, dirContents, 1)
+
+	req, _ = http.NewRequest(http.MethodGet, userDirsPath+"?path="+testDir, nil)
 	setBearerForReq(req, webAPIToken)
 	rr = executeRequest(req)
 	checkResponseCode(t, http.StatusOK, rr)
-	// upload will work now
-	_, err = reader.Seek(0, io.SeekStart)
+	var dirEntries []map[string]interface{}
+	err = json.Unmarshal(rr.Body.Bytes(), &dirEntries)
 	assert.NoError(t, err)
-	req, err = http.NewRequest(http.MethodPost, userFilesPath, reader)
+	assert.Len(t, dirEntries, 1)
+
+	req, _ = http.NewRequest(http.MethodGet, webClientDownloadZipPath+"?path="+url.QueryEscape("/")+"&files="+
+		url.QueryEscape(fmt.Sprintf(`["%v","%v","%v"]`, testFileName, testDir, testFileName+extensions[2])), nil)
+	setJWTCookieForReq(req, webToken)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
+
+	filesList := []string{testFileName, testDir, testFileName + extensions[2]}
+	asJSON, err := json.Marshal(filesList)
 	assert.NoError(t, err)
-	req.Header.Add("Content-Type", writer.FormDataContentType())
+	req, _ = http.NewRequest(http.MethodPost, userStreamZipPath, bytes.NewBuffer(asJSON))
 	setBearerForReq(req, webAPIToken)
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusCreated, rr)
-	// overwrite the file
-	_, err = reader.Seek(0, io.SeekStart)
-	assert.NoError(t, err)
-	req, err = http.NewRequest(http.MethodPost, userFilesPath, reader)
+	checkResponseCode(t, http.StatusOK, rr)
+
 	assert.NoError(t, err)
-	req.Header.Add("Content-Type", writer.FormDataContentType())
+	req, _ = http.NewRequest(http.MethodPost, userStreamZipPath, bytes.NewBuffer([]byte(`file`)))
 	setBearerForReq(req, webAPIToken)
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusCreated, rr)
+	checkResponseCode(t, http.StatusBadRequest, rr)
 
-	vfs.SetTempPath(filepath.Join(os.TempDir(), "missingpath"))
+	req, _ = http.NewRequest(http.MethodGet, webClientDownloadZipPath+"?path="+url.QueryEscape("/")+"&files="+
+		url.QueryEscape(fmt.Sprintf(`["%v"]`, testDir)), nil)
+	setJWTCookieForReq(req, webToken)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
 
-	_, err = reader.Seek(0, io.SeekStart)
-	assert.NoError(t, err)
-	req, err = http.NewRequest(http.MethodPost, userFilesPath, reader)
+	req, _ = http.NewRequest(http.MethodGet, webClientDownloadZipPath+"?path="+url.QueryEscape("/")+"&files=notalist", nil)
+	setJWTCookieForReq(req, webToken)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusInternalServerError, rr)
+	assert.Contains(t, rr.Body.String(), "Unable to get files list")
+
+	req, _ = http.NewRequest(http.MethodGet, webClientDirsPath+"?path=/", nil)
+	setJWTCookieForReq(req, webToken)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
+	dirContents = nil
+	err = json.Unmarshal(rr.Body.Bytes(), &dirContents)
 	assert.NoError(t, err)
-	req.Header.Add("Content-Type", writer.FormDataContentType())
+	assert.Len(t, dirContents, len(extensions)+1)
+
+	req, _ = http.NewRequest(http.MethodGet, userDirsPath+"?path=/", nil)
 	setBearerForReq(req, webAPIToken)
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusNotFound, rr)
+	checkResponseCode(t, http.StatusOK, rr)
+	dirEntries = nil
+	err = json.Unmarshal(rr.Body.Bytes(), &dirEntries)
+	assert.NoError(t, err)
+	assert.Len(t, dirEntries, len(extensions)+1)
 
-	if runtime.GOOS != osWindows {
-		req, err = http.NewRequest(http.MethodDelete, userFilesPath+"?path=file.zip", reader)
-		assert.NoError(t, err)
-		setBearerForReq(req, webAPIToken)
-		rr = executeRequest(req)
-		checkResponseCode(t, http.StatusOK, rr)
-
-		vfs.SetTempPath(filepath.Clean(os.TempDir()))
-		err = os.Chmod(user.GetHomeDir(), 0555)
-		assert.NoError(t, err)
+	req, _ = http.NewRequest(http.MethodGet, webClientDirsPath+"?path=/missing", nil)
+	setJWTCookieForReq(req, webToken)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusNotFound, rr)
+	assert.Contains(t, rr.Body.String(), "Unable to get directory contents")
 
-		_, err = reader.Seek(0, io.SeekStart)
-		assert.NoError(t, err)
-		req, err = http.NewRequest(http.MethodPost, userFilesPath, reader)
-		assert.NoError(t, err)
-		req.Header.Add("Content-Type", writer.FormDataContentType())
-		setBearerForReq(req, webAPIToken)
-		rr = executeRequest(req)
-		checkResponseCode(t, http.StatusForbidden, rr)
-		assert.Contains(t, rr.Body.String(), "Error closing file")
+	req, _ = http.NewRequest(http.MethodGet, userDirsPath+"?path=missing", nil)
+	setBearerForReq(req, webAPIToken)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusNotFound, rr)
+	assert.Contains(t, rr.Body.String(), "Unable to get directory contents")
 
-		err = os.Chmod(user.GetHomeDir(), os.ModePerm)
-		assert.NoError(t, err)
-	}
+	req, _ = http.NewRequest(http.MethodGet, webClientFilesPath+"?path="+testFileName, nil)
+	setJWTCookieForReq(req, webToken)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
+	assert.Equal(t, testFileContents, rr.Body.Bytes())
 
-	vfs.SetTempPath("")
+	req, _ = http.NewRequest(http.MethodGet, userFilesPath+"?path="+testFileName, nil)
+	setBearerForReq(req, webAPIToken)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
+	assert.Equal(t, testFileContents, rr.Body.Bytes())
 
-	// upload a multipart form with no files
-	body = new(bytes.Buffer)
-	writer = multipart.NewWriter(body)
-	err = writer.Close()
-	assert.NoError(t, err)
-	reader = bytes.NewReader(body.Bytes())
-	_, err = reader.Seek(0, io.SeekStart)
-	assert.NoError(t, err)
-	req, err = http.NewRequest(http.MethodPost, userFilesPath+"?path=sub2", reader)
-	assert.NoError(t, err)
-	req.Header.Add("Content-Type", writer.FormDataContentType())
+	req, _ = http.NewRequest(http.MethodGet, userFilesPath+"?path=", nil)
 	setBearerForReq(req, webAPIToken)
 	rr = executeRequest(req)
 	checkResponseCode(t, http.StatusBadRequest, rr)
-	assert.Contains(t, rr.Body.String(), "No files uploaded!")
-
-	_, err = httpdtest.RemoveUser(user, http.StatusOK)
-	assert.NoError(t, err)
-	err = os.RemoveAll(user.GetHomeDir())
-	assert.NoError(t, err)
-}
-
-func TestWebAPIVFolder(t *testing.T) {
-	u := getTestUser()
-	u.QuotaSize = 65535
-	vdir := "/vdir"
-	mappedPath := filepath.Join(os.TempDir(), "vdir")
-	folderName := filepath.Base(mappedPath)
-	u.VirtualFolders = append(u.VirtualFolders, vfs.VirtualFolder{
-		BaseVirtualFolder: vfs.BaseVirtualFolder{
-			Name:       folderName,
-			MappedPath: mappedPath,
-		},
-		VirtualPath: vdir,
-		QuotaSize:   -1,
-		QuotaFiles:  -1,
-	})
-	user, _, err := httpdtest.AddUser(u, http.StatusCreated)
-	assert.NoError(t, err)
+	assert.Contains(t, rr.Body.String(), "Please set the path to a valid file")
 
-	webAPIToken, err := getJWTAPIUserTokenFromTestServer(user.Username, defaultPassword)
-	assert.NoError(t, err)
+	req, _ = http.NewRequest(http.MethodGet, userFilesPath+"?path="+testDir, nil)
+	setBearerForReq(req, webAPIToken)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusBadRequest, rr)
+	assert.Contains(t, rr.Body.String(), "is a directory")
 
-	fileContents := []byte("test contents")
+	req, _ = http.NewRequest(http.MethodGet, userFilesPath+"?path=notafile", nil)
+	setBearerForReq(req, webAPIToken)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusNotFound, rr)
+	assert.Contains(t, rr.Body.String(), "Unable to stat the requested file")
 
-	body := new(bytes.Buffer)
-	writer := multipart.NewWriter(body)
-	part, err := writer.CreateFormFile("filename", "file.txt")
-	assert.NoError(t, err)
-	_, err = part.Write(fileContents)
-	assert.NoError(t, err)
-	err = writer.Close()
-	assert.NoError(t, err)
-	reader := bytes.NewReader(body.Bytes())
+	req, _ = http.NewRequest(http.MethodGet, webClientFilesPath+"?path="+testFileName, nil)
+	req.Header.Set("Range", "bytes=2-")
+	setJWTCookieForReq(req, webToken)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusPartialContent, rr)
+	assert.Equal(t, testFileContents[2:], rr.Body.Bytes())
 
-	req, err := http.NewRequest(http.MethodPost, userFilesPath+"?path=vdir", reader)
-	assert.NoError(t, err)
-	req.Header.Add("Content-Type", writer.FormDataContentType())
+	req, _ = http.NewRequest(http.MethodGet, userFilesPath+"?path="+testFileName, nil)
+	req.Header.Set("Range", "bytes=2-")
 	setBearerForReq(req, webAPIToken)
-	rr := executeRequest(req)
-	checkResponseCode(t, http.StatusCreated, rr)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusPartialContent, rr)
+	assert.Equal(t, testFileContents[2:], rr.Body.Bytes())
 
-	user, _, err = httpdtest.GetUserByUsername(user.Username, http.StatusOK)
-	assert.NoError(t, err)
-	assert.Equal(t, int64(len(fileContents)), user.UsedQuotaSize)
+	req, _ = http.NewRequest(http.MethodGet, webClientFilesPath+"?path="+testFileName, nil)
+	req.Header.Set("Range", "bytes=-2")
+	setJWTCookieForReq(req, webToken)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusPartialContent, rr)
+	assert.Equal(t, testFileContents[11:], rr.Body.Bytes())
 
-	folder, _, err := httpdtest.GetFolderByName(folderName, http.StatusOK)
-	assert.NoError(t, err)
-	assert.Equal(t, int64(len(fileContents)), folder.UsedQuotaSize)
+	req, _ = http.NewRequest(http.MethodGet, webClientFilesPath+"?path="+testFileName, nil)
+	req.Header.Set("Range", "bytes=-2,")
+	setJWTCookieForReq(req, webToken)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusRequestedRangeNotSatisfiable, rr)
 
-	_, err = reader.Seek(0, io.SeekStart)
-	assert.NoError(t, err)
-	req, err = http.NewRequest(http.MethodPost, userFilesPath+"?path=vdir", reader)
-	assert.NoError(t, err)
-	req.Header.Add("Content-Type", writer.FormDataContentType())
+	req, _ = http.NewRequest(http.MethodGet, webClientFilesPath+"?path="+testFileName, nil)
+	req.Header.Set("Range", "bytes=1a-")
+	setJWTCookieForReq(req, webToken)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusRequestedRangeNotSatisfiable, rr)
+
+	req, _ = http.NewRequest(http.MethodGet, userFilesPath+"?path="+testFileName, nil)
+	req.Header.Set("Range", "bytes=2b-")
 	setBearerForReq(req, webAPIToken)
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusCreated, rr)
+	checkResponseCode(t, http.StatusRequestedRangeNotSatisfiable, rr)
 
-	user, _, err = httpdtest.GetUserByUsername(user.Username, http.StatusOK)
-	assert.NoError(t, err)
-	assert.Equal(t, int64(len(fileContents)), user.UsedQuotaSize)
+	req, _ = http.NewRequest(http.MethodHead, webClientFilesPath+"?path="+testFileName, nil)
+	req.Header.Set("Range", "bytes=2-")
+	req.Header.Set("If-Range", time.Now().UTC().Add(120*time.Second).Format(http.TimeFormat))
+	setJWTCookieForReq(req, webToken)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusPartialContent, rr)
 
-	folder, _, err = httpdtest.GetFolderByName(folderName, http.StatusOK)
-	assert.NoError(t, err)
-	assert.Equal(t, int64(len(fileContents)), folder.UsedQuotaSize)
+	req, _ = http.NewRequest(http.MethodHead, webClientFilesPath+"?path="+testFileName, nil)
+	req.Header.Set("Range", "bytes=2-")
+	req.Header.Set("If-Range", time.Now().UTC().Add(-120*time.Second).Format(http.TimeFormat))
+	setJWTCookieForReq(req, webToken)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
 
-	_, err = httpdtest.RemoveUser(user, http.StatusOK)
-	assert.NoError(t, err)
-	_, err = httpdtest.RemoveFolder(vfs.BaseVirtualFolder{Name: folderName}, http.StatusOK)
-	assert.NoError(t, err)
-	err = os.RemoveAll(user.GetHomeDir())
-	assert.NoError(t, err)
-	err = os.RemoveAll(mappedPath)
-	assert.NoError(t, err)
-}
+	req, _ = http.NewRequest(http.MethodHead, webClientFilesPath+"?path="+testFileName, nil)
+	req.Header.Set("If-Modified-Since", time.Now().UTC().Add(-120*time.Second).Format(http.TimeFormat))
+	setJWTCookieForReq(req, webToken)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
 
-func TestWebAPIWritePermission(t *testing.T) {
-	u := getTestUser()
-	u.Filters.WebClient = append(u.Filters.WebClient, sdk.WebClientWriteDisabled)
-	user, _, err := httpdtest.AddUser(u, http.StatusCreated)
-	assert.NoError(t, err)
-	webAPIToken, err := getJWTAPIUserTokenFromTestServer(defaultUsername, defaultPassword)
-	assert.NoError(t, err)
+	req, _ = http.NewRequest(http.MethodHead, webClientFilesPath+"?path="+testFileName, nil)
+	req.Header.Set("If-Modified-Since", time.Now().UTC().Add(120*time.Second).Format(http.TimeFormat))
+	setJWTCookieForReq(req, webToken)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusNotModified, rr)
 
-	body := new(bytes.Buffer)
-	writer := multipart.NewWriter(body)
-	part, err := writer.CreateFormFile("filename", "file.txt")
-	assert.NoError(t, err)
-	_, err = part.Write([]byte(""))
-	assert.NoError(t, err)
-	err = writer.Close()
-	assert.NoError(t, err)
-	reader := bytes.NewReader(body.Bytes())
+	req, _ = http.NewRequest(http.MethodHead, webClientFilesPath+"?path="+testFileName, nil)
+	req.Header.Set("If-Unmodified-Since", time.Now().UTC().Add(-120*time.Second).Format(http.TimeFormat))
+	setJWTCookieForReq(req, webToken)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusPreconditionFailed, rr)
 
-	req, err := http.NewRequest(http.MethodPost, userFilesPath, reader)
-	assert.NoError(t, err)
-	req.Header.Add("Content-Type", writer.FormDataContentType())
+	req, _ = http.NewRequest(http.MethodHead, userFilesPath+"?path="+testFileName, nil)
+	req.Header.Set("If-Unmodified-Since", time.Now().UTC().Add(-120*time.Second).Format(http.TimeFormat))
 	setBearerForReq(req, webAPIToken)
-	rr := executeRequest(req)
-	checkResponseCode(t, http.StatusForbidden, rr)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusPreconditionFailed, rr)
 
-	req, err = http.NewRequest(http.MethodPatch, userFilesPath+"?path=a&target=b", nil)
-	assert.NoError(t, err)
-	req.Header.Add("Content-Type", writer.FormDataContentType())
-	setBearerForReq(req, webAPIToken)
+	req, _ = http.NewRequest(http.MethodHead, webClientFilesPath+"?path="+testFileName, nil)
+	req.Header.Set("If-Unmodified-Since", time.Now().UTC().Add(120*time.Second).Format(http.TimeFormat))
+	setJWTCookieForReq(req, webToken)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
+
+	user.Filters.DeniedProtocols = []string{common.ProtocolHTTP}
+	_, resp, err := httpdtest.UpdateUser(user, http.StatusOK, "")
+	assert.NoError(t, err, string(resp))
+
+	req, _ = http.NewRequest(http.MethodGet, webClientFilesPath, nil)
+	setJWTCookieForReq(req, webToken)
 	rr = executeRequest(req)
 	checkResponseCode(t, http.StatusForbidden, rr)
 
-	req, err = http.NewRequest(http.MethodDelete, userFilesPath+"?path=a", nil)
-	assert.NoError(t, err)
-	req.Header.Add("Content-Type", writer.FormDataContentType())
-	setBearerForReq(req, webAPIToken)
+	req, _ = http.NewRequest(http.MethodGet, webClientDirsPath+"?path=/", nil)
+	setJWTCookieForReq(req, webToken)
 	rr = executeRequest(req)
 	checkResponseCode(t, http.StatusForbidden, rr)
 
-	req, err = http.NewRequest(http.MethodGet, userFilesPath+"?path=a.txt", nil)
-	assert.NoError(t, err)
-	req.Header.Add("Content-Type", writer.FormDataContentType())
+	req, _ = http.NewRequest(http.MethodGet, userFilesPath+"?path="+testFileName, nil)
 	setBearerForReq(req, webAPIToken)
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusNotFound, rr)
+	checkResponseCode(t, http.StatusForbidden, rr)
 
-	req, err = http.NewRequest(http.MethodGet, userDirsPath, nil)
-	assert.NoError(t, err)
-	req.Header.Add("Content-Type", writer.FormDataContentType())
+	req, _ = http.NewRequest(http.MethodGet, userDirsPath+"?path="+testDir, nil)
 	setBearerForReq(req, webAPIToken)
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusOK, rr)
+	checkResponseCode(t, http.StatusForbidden, rr)
 
-	req, err = http.NewRequest(http.MethodPost, userDirsPath+"?path=dir", nil)
+	filesList = []string{testDir}
+	asJSON, err = json.Marshal(filesList)
 	assert.NoError(t, err)
-	req.Header.Add("Content-Type", writer.FormDataContentType())
+	req, _ = http.NewRequest(http.MethodPost, userStreamZipPath, bytes.NewBuffer(asJSON))
 	setBearerForReq(req, webAPIToken)
 	rr = executeRequest(req)
 	checkResponseCode(t, http.StatusForbidden, rr)
 
-	req, err = http.NewRequest(http.MethodPatch, userDirsPath+"?path=dir&target=dir1", nil)
-	assert.NoError(t, err)
-	req.Header.Add("Content-Type", writer.FormDataContentType())
-	setBearerForReq(req, webAPIToken)
+	user.Filters.DeniedProtocols = []string{common.ProtocolFTP}
+	user.Filters.DeniedLoginMethods = []string{dataprovider.LoginMethodPassword}
+	_, resp, err = httpdtest.UpdateUser(user, http.StatusOK, "")
+	assert.NoError(t, err, string(resp))
+
+	req, _ = http.NewRequest(http.MethodGet, webClientFilesPath, nil)
+	setJWTCookieForReq(req, webToken)
 	rr = executeRequest(req)
 	checkResponseCode(t, http.StatusForbidden, rr)
 
-	req, err = http.NewRequest(http.MethodDelete, userDirsPath+"?path=dir", nil)
-	assert.NoError(t, err)
-	req.Header.Add("Content-Type", writer.FormDataContentType())
+	req, _ = http.NewRequest(http.MethodGet, webClientDownloadZipPath, nil)
+	setJWTCookieForReq(req, webToken)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusForbidden, rr)
+
+	req, _ = http.NewRequest(http.MethodGet, userDirsPath+"?path="+testDir, nil)
 	setBearerForReq(req, webAPIToken)
 	rr = executeRequest(req)
 	checkResponseCode(t, http.StatusForbidden, rr)
@@ -9409,41 +12335,32 @@ func TestWebAPIWritePermission(t *testing.T) {
 	assert.NoError(t, err)
 }
 
-func TestWebAPICryptFs(t *testing.T) {
+func TestUserAPISharesDisabled(t *testing.T) {
 	u := getTestUser()
-	u.QuotaSize = 65535
-	u.FsConfig.Provider = sdk.CryptedFilesystemProvider
-	u.FsConfig.CryptConfig.Passphrase = kms.NewPlainSecret(defaultPassword)
+	u.Filters.WebClient = []string{sdk.WebClientSharesDisabled}
 	user, _, err := httpdtest.AddUser(u, http.StatusCreated)
 	assert.NoError(t, err)
-	webAPIToken, err := getJWTAPIUserTokenFromTestServer(defaultUsername, defaultPassword)
+	token, err := getJWTAPIUserTokenFromTestServer(defaultUsername, defaultPassword)
 	assert.NoError(t, err)
 
-	body := new(bytes.Buffer)
-	writer := multipart.NewWriter(body)
-	part, err := writer.CreateFormFile("filename", "file.txt")
-	assert.NoError(t, err)
-	_, err = part.Write([]byte("content"))
-	assert.NoError(t, err)
-	err = writer.Close()
+	share := dataprovider.Share{
+		Name:  "test share",
+		Scope: dataprovider.ShareScopeRead,
+		Paths: []string{"/"},
+	}
+	asJSON, err := json.Marshal(share)
 	assert.NoError(t, err)
-	reader := bytes.NewReader(body.Bytes())
-
-	req, err := http.NewRequest(http.MethodPost, userFilesPath, reader)
+	req, err := http.NewRequest(http.MethodPost, userSharesPath, bytes.NewBuffer(asJSON))
 	assert.NoError(t, err)
-	req.Header.Add("Content-Type", writer.FormDataContentType())
-	setBearerForReq(req, webAPIToken)
+	setBearerForReq(req, token)
 	rr := executeRequest(req)
-	checkResponseCode(t, http.StatusCreated, rr)
+	checkResponseCode(t, http.StatusForbidden, rr)
 
-	_, err = reader.Seek(0, io.SeekStart)
-	assert.NoError(t, err)
-	req, err = http.NewRequest(http.MethodPost, userFilesPath, reader)
+	req, err = http.NewRequest(http.MethodGet, userSharesPath, nil)
 	assert.NoError(t, err)
-	req.Header.Add("Content-Type", writer.FormDataContentType())
-	setBearerForReq(req, webAPIToken)
+	setBearerForReq(req, token)
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusCreated, rr)
+	checkResponseCode(t, http.StatusForbidden, rr)
 
 	_, err = httpdtest.RemoveUser(user, http.StatusOK)
 	assert.NoError(t, err)
@@ -9451,103 +12368,152 @@ func TestWebAPICryptFs(t *testing.T) {
 	assert.NoError(t, err)
 }
 
-func TestWebUploadSFTP(t *testing.T) {
-	u := getTestUser()
-	localUser, _, err := httpdtest.AddUser(u, http.StatusCreated)
+func TestUserAPIShares(t *testing.T) {
+	user, _, err := httpdtest.AddUser(getTestUser(), http.StatusCreated)
 	assert.NoError(t, err)
-	u = getTestSFTPUser()
-	u.QuotaFiles = 100
-	u.FsConfig.SFTPConfig.BufferSize = 2
-	u.HomeDir = filepath.Join(os.TempDir(), u.Username)
-	sftpUser, _, err := httpdtest.AddUser(u, http.StatusCreated)
+	token, err := getJWTAPIUserTokenFromTestServer(defaultUsername, defaultPassword)
 	assert.NoError(t, err)
 
-	webAPIToken, err := getJWTAPIUserTokenFromTestServer(sftpUser.Username, defaultPassword)
+	// invalid json
+	req, err := http.NewRequest(http.MethodPost, userSharesPath, bytes.NewBuffer([]byte("{")))
 	assert.NoError(t, err)
-
-	body := new(bytes.Buffer)
-	writer := multipart.NewWriter(body)
-	part, err := writer.CreateFormFile("filename", "file.txt")
+	setBearerForReq(req, token)
+	rr := executeRequest(req)
+	checkResponseCode(t, http.StatusBadRequest, rr)
+	// no paths
+	share := dataprovider.Share{
+		Name:  "test share",
+		Scope: dataprovider.ShareScopeRead,
+	}
+	asJSON, err := json.Marshal(share)
 	assert.NoError(t, err)
-	_, err = part.Write([]byte("test file content"))
+	req, err = http.NewRequest(http.MethodPost, userSharesPath, bytes.NewBuffer(asJSON))
 	assert.NoError(t, err)
-	err = writer.Close()
+	setBearerForReq(req, token)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusBadRequest, rr)
+	// nested paths
+	share.Paths = []string{"/dir", "/dir/sub"}
+	asJSON, err = json.Marshal(share)
 	assert.NoError(t, err)
-	reader := bytes.NewReader(body.Bytes())
-
-	req, err := http.NewRequest(http.MethodPost, userFilesPath, reader)
+	req, err = http.NewRequest(http.MethodPost, userSharesPath, bytes.NewBuffer(asJSON))
 	assert.NoError(t, err)
-	req.Header.Add("Content-Type", writer.FormDataContentType())
-	setBearerForReq(req, webAPIToken)
-	rr := executeRequest(req)
-	checkResponseCode(t, http.StatusCreated, rr)
-
-	expectedQuotaSize := int64(17)
-	expectedQuotaFiles := 1
-	user, _, err := httpdtest.GetUserByUsername(sftpUser.Username, http.StatusOK)
+	setBearerForReq(req, token)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusBadRequest, rr)
+	// expiration in the past
+	share.Paths = []string{"/"}
+	share.ExpiresAt = util.GetTimeAsMsSinceEpoch(time.Now().Add(-1 * time.Hour))
+	asJSON, err = json.Marshal(share)
 	assert.NoError(t, err)
-	assert.Equal(t, expectedQuotaFiles, user.UsedQuotaFiles)
-	assert.Equal(t, expectedQuotaSize, user.UsedQuotaSize)
-
-	user.QuotaSize = 10
-	user, _, err = httpdtest.UpdateUser(user, http.StatusOK, "")
+	req, err = http.NewRequest(http.MethodPost, userSharesPath, bytes.NewBuffer(asJSON))
 	assert.NoError(t, err)
-	// we are now overquota on overwrite
-	_, err = reader.Seek(0, io.SeekStart)
+	setBearerForReq(req, token)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusBadRequest, rr)
+	// invalid allow from
+	share.ExpiresAt = 0
+	share.AllowFrom = []string{"not a cidr"}
+	asJSON, err = json.Marshal(share)
 	assert.NoError(t, err)
-	req, err = http.NewRequest(http.MethodPost, userFilesPath, reader)
+	req, err = http.NewRequest(http.MethodPost, userSharesPath, bytes.NewBuffer(asJSON))
 	assert.NoError(t, err)
-	req.Header.Add("Content-Type", writer.FormDataContentType())
-	setBearerForReq(req, webAPIToken)
+	setBearerForReq(req, token)
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusInternalServerError, rr)
-	assert.Contains(t, rr.Body.String(), "denying write due to space limit")
-	// delete the file
-	req, err = http.NewRequest(http.MethodDelete, userFilesPath+"?path=file.txt", nil)
+	checkResponseCode(t, http.StatusBadRequest, rr)
+
+	share.AllowFrom = []string{"127.0.0.1/8", "127.0.0.1/8"}
+	share.Password = defaultPassword
+	share.Description = "test share description"
+	share.ExpiresAt = util.GetTimeAsMsSinceEpoch(time.Now().Add(2 * time.Hour))
+	share.MaxTokens = 10
+	asJSON, err = json.Marshal(share)
 	assert.NoError(t, err)
-	setBearerForReq(req, webAPIToken)
+	req, err = http.NewRequest(http.MethodPost, userSharesPath, bytes.NewBuffer(asJSON))
+	assert.NoError(t, err)
+	setBearerForReq(req, token)
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusOK, rr)
+	checkResponseCode(t, http.StatusCreated, rr)
+	location := rr.Header().Get("Location")
+	assert.NotEmpty(t, location)
+	objectID := rr.Header().Get("X-Object-ID")
+	assert.NotEmpty(t, objectID)
+	assert.Equal(t, fmt.Sprintf("%v/%v", userSharesPath, objectID), location)
 
-	_, err = reader.Seek(0, io.SeekStart)
+	req, err = http.NewRequest(http.MethodGet, location, nil)
 	assert.NoError(t, err)
-	req, err = http.NewRequest(http.MethodPost, userFilesPath, reader)
+	setBearerForReq(req, token)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
+	var shareGet dataprovider.Share
+	err = json.Unmarshal(rr.Body.Bytes(), &shareGet)
+	assert.NoError(t, err)
+	assert.Equal(t, objectID, shareGet.ShareID)
+	assert.Equal(t, share.Name, shareGet.Name)
+	assert.Equal(t, share.Description, shareGet.Description)
+	assert.Equal(t, share.Scope, shareGet.Scope)
+	assert.Equal(t, share.Paths, shareGet.Paths)
+	assert.Equal(t, user.Username, shareGet.Username)
+	assert.Equal(t, share.ExpiresAt, shareGet.ExpiresAt)
+	assert.Equal(t, share.MaxTokens, shareGet.MaxTokens)
+	assert.Equal(t, 0, shareGet.UsedTokens)
+	assert.Equal(t, int64(0), shareGet.LastUseAt)
+	assert.Greater(t, shareGet.CreatedAt, int64(0))
+	assert.Greater(t, shareGet.UpdatedAt, int64(0))
+	assert.Equal(t, []string{"127.0.0.1/8"}, shareGet.AllowFrom)
+	assert.True(t, shareGet.HasRedactedPassword())
+	// the password must be preserved if we send the redacted one
+	shareGet.Description = "updated description"
+	asJSON, err = json.Marshal(shareGet)
 	assert.NoError(t, err)
-	req.Header.Add("Content-Type", writer.FormDataContentType())
-	setBearerForReq(req, webAPIToken)
+	req, err = http.NewRequest(http.MethodPut, location, bytes.NewBuffer(asJSON))
+	assert.NoError(t, err)
+	setBearerForReq(req, token)
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusInternalServerError, rr)
-	assert.Contains(t, rr.Body.String(), "denying write due to space limit")
+	checkResponseCode(t, http.StatusOK, rr)
 
-	_, err = httpdtest.RemoveUser(sftpUser, http.StatusOK)
+	s, err := dataprovider.ShareExists(objectID, defaultUsername)
 	assert.NoError(t, err)
-	_, err = httpdtest.RemoveUser(localUser, http.StatusOK)
+	match, err := s.CheckPassword(defaultPassword)
+	assert.True(t, match)
 	assert.NoError(t, err)
-	err = os.RemoveAll(localUser.GetHomeDir())
+	assert.Equal(t, "updated description", s.Description)
+	// a share cannot be retrieved by other users
+	_, err = dataprovider.ShareExists(objectID, altAdminUsername)
+	assert.Error(t, err)
+	_, ok := err.(*util.RecordNotFoundError)
+	assert.True(t, ok)
+
+	req, err = http.NewRequest(http.MethodGet, userSharesPath+"?limit=1&order=DESC", nil)
 	assert.NoError(t, err)
-	err = os.RemoveAll(sftpUser.GetHomeDir())
+	setBearerForReq(req, token)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
+	var shares []dataprovider.Share
+	err = json.Unmarshal(rr.Body.Bytes(), &shares)
 	assert.NoError(t, err)
-}
+	if assert.Len(t, shares, 1) {
+		assert.Equal(t, objectID, shares[0].ShareID)
+		assert.True(t, shares[0].HasRedactedPassword())
+	}
 
-func TestWebUploadMultipartFormReadError(t *testing.T) {
-	user, _, err := httpdtest.AddUser(getTestUser(), http.StatusCreated)
-	assert.NoError(t, err)
-	webAPIToken, err := getJWTAPIUserTokenFromTestServer(defaultUsername, defaultPassword)
+	req, err = http.NewRequest(http.MethodDelete, location, nil)
 	assert.NoError(t, err)
+	setBearerForReq(req, token)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
 
-	req, err := http.NewRequest(http.MethodPost, userFilesPath, nil)
+	req, err = http.NewRequest(http.MethodDelete, location, nil)
 	assert.NoError(t, err)
+	setBearerForReq(req, token)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusNotFound, rr)
 
-	mpartForm := &multipart.Form{
-		File: make(map[string][]*multipart.FileHeader),
-	}
-	mpartForm.File["filename"] = append(mpartForm.File["filename"], &multipart.FileHeader{Filename: "missing"})
-	req.MultipartForm = mpartForm
-	req.Header.Add("Content-Type", "multipart/form-data")
-	setBearerForReq(req, webAPIToken)
-	rr := executeRequest(req)
-	checkResponseCode(t, http.StatusInternalServerError, rr)
-	assert.Contains(t, rr.Body.String(), "Unable to read uploaded file")
+	req, err = http.NewRequest(http.MethodGet, location, nil)
+	assert.NoError(t, err)
+	setBearerForReq(req, token)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusNotFound, rr)
 
 	_, err = httpdtest.RemoveUser(user, http.StatusOK)
 	assert.NoError(t, err)
@@ -9555,1905 +12521,4398 @@ func TestWebUploadMultipartFormReadError(t *testing.T) {
 	assert.NoError(t, err)
 }
 
-func TestCompressionErrorMock(t *testing.T) {
+func TestSharesUsage(t *testing.T) {
 	user, _, err := httpdtest.AddUser(getTestUser(), http.StatusCreated)
 	assert.NoError(t, err)
-
-	defer func() {
-		rcv := recover()
-		assert.Equal(t, http.ErrAbortHandler, rcv)
-		_, err := httpdtest.RemoveUser(user, http.StatusOK)
-		assert.NoError(t, err)
-		err = os.RemoveAll(user.GetHomeDir())
-		assert.NoError(t, err)
-	}()
-
-	webToken, err := getJWTWebClientTokenFromTestServer(defaultUsername, defaultPassword)
+	token, err := getJWTAPIUserTokenFromTestServer(defaultUsername, defaultPassword)
 	assert.NoError(t, err)
 
-	req, _ := http.NewRequest(http.MethodGet, webClientDownloadZipPath+"?path="+url.QueryEscape("/")+"&files="+
-		url.QueryEscape(`["missing"]`), nil)
-	setJWTCookieForReq(req, webToken)
-	executeRequest(req)
-}
-
-func TestGetFilesSFTPBackend(t *testing.T) {
-	user, _, err := httpdtest.AddUser(getTestUser(), http.StatusCreated)
+	testFileName := "testfile.dat"
+	testFileSize := int64(65536)
+	testFilePath := filepath.Join(user.GetHomeDir(), testFileName)
+	err = createTestFile(testFilePath, testFileSize)
 	assert.NoError(t, err)
-	u := getTestSFTPUser()
-	u.FsConfig.SFTPConfig.BufferSize = 2
-	u.Permissions["/adir"] = nil
-	u.Permissions["/adir1"] = []string{dataprovider.PermListItems}
-	u.Filters.FilePatterns = []sdk.PatternsFilter{
-		{
-			Path:           "/adir2",
-			DeniedPatterns: []string{"*.txt"},
-		},
-	}
-	sftpUser, _, err := httpdtest.AddUser(u, http.StatusCreated)
+	err = os.MkdirAll(filepath.Join(user.GetHomeDir(), "adir"), os.ModePerm)
 	assert.NoError(t, err)
-
-	testFileName := "testsftpfile"
-	testDir := "testsftpdir"
-	testFileContents := []byte("sftp file contents")
-	err = os.MkdirAll(filepath.Join(user.GetHomeDir(), testDir, "sub"), os.ModePerm)
+	err = createTestFile(filepath.Join(user.GetHomeDir(), "adir", testFileName), testFileSize)
 	assert.NoError(t, err)
-	err = os.MkdirAll(filepath.Join(user.GetHomeDir(), "adir1"), os.ModePerm)
+
+	share := dataprovider.Share{
+		Name:      "test share",
+		Scope:     dataprovider.ShareScopeRead,
+		Paths:     []string{"/" + testFileName},
+		Password:  defaultPassword,
+		MaxTokens: 3,
+	}
+	asJSON, err := json.Marshal(share)
 	assert.NoError(t, err)
-	err = os.MkdirAll(filepath.Join(user.GetHomeDir(), "adir2"), os.ModePerm)
+	req, err := http.NewRequest(http.MethodPost, userSharesPath, bytes.NewBuffer(asJSON))
 	assert.NoError(t, err)
-	err = os.WriteFile(filepath.Join(user.GetHomeDir(), testFileName), testFileContents, os.ModePerm)
+	setBearerForReq(req, token)
+	rr := executeRequest(req)
+	checkResponseCode(t, http.StatusCreated, rr)
+	objectID := rr.Header().Get("X-Object-ID")
+	assert.NotEmpty(t, objectID)
+
+	req, err = http.NewRequest(http.MethodGet, sharesPath+"/unknownid", nil)
 	assert.NoError(t, err)
-	err = os.WriteFile(filepath.Join(user.GetHomeDir(), "adir1", "afile"), testFileContents, os.ModePerm)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusNotFound, rr)
+	// password required
+	req, err = http.NewRequest(http.MethodGet, sharesPath+"/"+objectID, nil)
 	assert.NoError(t, err)
-	err = os.WriteFile(filepath.Join(user.GetHomeDir(), "adir2", "afile.txt"), testFileContents, os.ModePerm)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusUnauthorized, rr)
+	assert.NotEmpty(t, rr.Header().Get("WWW-Authenticate"))
+
+	req, err = http.NewRequest(http.MethodGet, sharesPath+"/"+objectID, nil)
 	assert.NoError(t, err)
-	webToken, err := getJWTWebClientTokenFromTestServer(sftpUser.Username, defaultPassword)
+	req.SetBasicAuth(defaultUsername, "wrong password")
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusUnauthorized, rr)
+
+	req, err = http.NewRequest(http.MethodGet, sharesPath+"/"+objectID+"?compress=false", nil)
 	assert.NoError(t, err)
-	req, _ := http.NewRequest(http.MethodGet, webClientFilesPath, nil)
-	setJWTCookieForReq(req, webToken)
-	rr := executeRequest(req)
+	req.SetBasicAuth(defaultUsername, defaultPassword)
+	rr = executeRequest(req)
 	checkResponseCode(t, http.StatusOK, rr)
+	assert.Equal(t, testFileSize, int64(rr.Body.Len()))
+	contentDisposition := rr.Header().Get("Content-Disposition")
+	assert.Contains(t, contentDisposition, testFileName)
 
-	req, _ = http.NewRequest(http.MethodGet, webClientFilesPath+"?path="+path.Join(testDir, "sub"), nil)
-	setJWTCookieForReq(req, webToken)
+	req, err = http.NewRequest(http.MethodGet, sharesPath+"/"+objectID, nil)
+	assert.NoError(t, err)
+	req.SetBasicAuth(defaultUsername, defaultPassword)
 	rr = executeRequest(req)
 	checkResponseCode(t, http.StatusOK, rr)
+	contentDisposition = rr.Header().Get("Content-Disposition")
+	assert.Contains(t, contentDisposition, ".zip")
 
-	req, _ = http.NewRequest(http.MethodGet, webClientFilesPath+"?path="+path.Join(testDir, "missing"), nil)
-	setJWTCookieForReq(req, webToken)
+	share, err = dataprovider.ShareExists(objectID, defaultUsername)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, share.UsedTokens)
+	assert.Greater(t, share.LastUseAt, int64(0))
+	// the web client link works too
+	req, err = http.NewRequest(http.MethodGet, webClientPubSharesPath+"/"+objectID, nil)
+	assert.NoError(t, err)
+	req.SetBasicAuth(defaultUsername, defaultPassword)
 	rr = executeRequest(req)
 	checkResponseCode(t, http.StatusOK, rr)
-	assert.Contains(t, rr.Body.String(), "card-body text-form-error")
-	req, _ = http.NewRequest(http.MethodGet, webClientFilesPath+"?path=adir/sub", nil)
-	setJWTCookieForReq(req, webToken)
+	// max usage exceeded
+	req, err = http.NewRequest(http.MethodGet, sharesPath+"/"+objectID, nil)
+	assert.NoError(t, err)
+	req.SetBasicAuth(defaultUsername, defaultPassword)
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusOK, rr)
-	assert.Contains(t, rr.Body.String(), "card-body text-form-error")
+	checkResponseCode(t, http.StatusNotFound, rr)
 
-	req, _ = http.NewRequest(http.MethodGet, webClientFilesPath+"?path=adir1/afile", nil)
-	setJWTCookieForReq(req, webToken)
+	share.MaxTokens = 0
+	share.Password = ""
+	share.AllowFrom = []string{"192.168.1.0/24"}
+	share.Paths = []string{"/adir", "/" + testFileName}
+	err = dataprovider.UpdateShare(&share, user.Username, "")
+	assert.NoError(t, err)
+	// not allowed from this IP
+	req, err = http.NewRequest(http.MethodGet, sharesPath+"/"+objectID, nil)
+	assert.NoError(t, err)
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusOK, rr)
-	assert.Contains(t, rr.Body.String(), "card-body text-form-error")
+	checkResponseCode(t, http.StatusForbidden, rr)
 
-	req, _ = http.NewRequest(http.MethodGet, webClientFilesPath+"?path=adir2/afile.txt", nil)
-	setJWTCookieForReq(req, webToken)
-	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusOK, rr)
-	assert.Contains(t, rr.Body.String(), "card-body text-form-error")
+	share.AllowFrom = nil
+	err = dataprovider.UpdateShare(&share, user.Username, "")
+	assert.NoError(t, err)
 
-	req, _ = http.NewRequest(http.MethodGet, webClientFilesPath+"?path="+testFileName, nil)
-	setJWTCookieForReq(req, webToken)
+	req, err = http.NewRequest(http.MethodGet, sharesPath+"/"+objectID, nil)
+	assert.NoError(t, err)
 	rr = executeRequest(req)
 	checkResponseCode(t, http.StatusOK, rr)
-	assert.Equal(t, testFileContents, rr.Body.Bytes())
-
-	req, _ = http.NewRequest(http.MethodGet, webClientFilesPath+"?path="+testFileName, nil)
-	req.Header.Set("Range", "bytes=2-")
-	setJWTCookieForReq(req, webToken)
+	// the owner cannot share files anymore
+	user.Filters.WebClient = []string{sdk.WebClientSharesDisabled}
+	user, _, err = httpdtest.UpdateUser(user, http.StatusOK, "")
+	assert.NoError(t, err)
+	req, err = http.NewRequest(http.MethodGet, sharesPath+"/"+objectID, nil)
+	assert.NoError(t, err)
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusPartialContent, rr)
-	assert.Equal(t, testFileContents[2:], rr.Body.Bytes())
+	checkResponseCode(t, http.StatusForbidden, rr)
 
-	_, err = httpdtest.RemoveUser(sftpUser, http.StatusOK)
+	user.Filters.WebClient = []string{sdk.WebClientPubKeyChangeDisabled}
+	user.Filters.DeniedProtocols = []string{common.ProtocolHTTP}
+	user, _, err = httpdtest.UpdateUser(user, http.StatusOK, "")
 	assert.NoError(t, err)
-	err = os.RemoveAll(sftpUser.GetHomeDir())
+	req, err = http.NewRequest(http.MethodGet, sharesPath+"/"+objectID, nil)
 	assert.NoError(t, err)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusForbidden, rr)
+
 	_, err = httpdtest.RemoveUser(user, http.StatusOK)
 	assert.NoError(t, err)
 	err = os.RemoveAll(user.GetHomeDir())
 	assert.NoError(t, err)
+	// shares are removed with the user
+	_, err = dataprovider.ShareExists(objectID, "")
+	assert.Error(t, err)
+
+	req, err = http.NewRequest(http.MethodGet, sharesPath+"/"+objectID, nil)
+	assert.NoError(t, err)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusNotFound, rr)
 }
 
-func TestClientUserClose(t *testing.T) {
+func TestShareUploads(t *testing.T) {
 	u := getTestUser()
-	u.UploadBandwidth = 32
-	u.DownloadBandwidth = 32
+	u.QuotaFiles = 2
 	user, _, err := httpdtest.AddUser(u, http.StatusCreated)
 	assert.NoError(t, err)
-	testFileName := "file.dat"
-	testFileSize := int64(524288)
-	testFilePath := filepath.Join(user.GetHomeDir(), testFileName)
-	err = createTestFile(testFilePath, testFileSize)
-	assert.NoError(t, err)
-	uploadContent := make([]byte, testFileSize)
-	_, err = rand.Read(uploadContent)
+	token, err := getJWTAPIUserTokenFromTestServer(defaultUsername, defaultPassword)
 	assert.NoError(t, err)
 	webToken, err := getJWTWebClientTokenFromTestServer(defaultUsername, defaultPassword)
 	assert.NoError(t, err)
-	webAPIToken, err := getJWTAPIUserTokenFromTestServer(defaultUsername, defaultPassword)
-	assert.NoError(t, err)
-
-	var wg sync.WaitGroup
-	wg.Add(1)
-	go func() {
-		defer wg.Done()
-		req, _ := http.NewRequest(http.MethodGet, webClientFilesPath+"?path="+testFileName, nil)
-		setJWTCookieForReq(req, webToken)
-		rr := executeRequest(req)
-		checkResponseCode(t, http.StatusOK, rr)
-	}()
-	wg.Add(1)
-	go func() {
-		defer wg.Done()
-		req, _ := http.NewRequest(http.MethodGet, webClientEditFilePath+"?path="+testFileName, nil)
-		setJWTCookieForReq(req, webToken)
-		rr := executeRequest(req)
-		checkResponseCode(t, http.StatusInternalServerError, rr)
-		assert.Contains(t, rr.Body.String(), "Unable to read the file")
-	}()
-	wg.Add(1)
-	go func() {
-		defer wg.Done()
-		body := new(bytes.Buffer)
-		writer := multipart.NewWriter(body)
-		part, err := writer.CreateFormFile("filename", "upload.dat")
-		assert.NoError(t, err)
-		n, err := part.Write(uploadContent)
-		assert.NoError(t, err)
-		assert.Equal(t, testFileSize, int64(n))
-		err = writer.Close()
-		assert.NoError(t, err)
-		reader := bytes.NewReader(body.Bytes())
-		req, err := http.NewRequest(http.MethodPost, userFilesPath, reader)
-		assert.NoError(t, err)
-		req.Header.Add("Content-Type", writer.FormDataContentType())
-		setBearerForReq(req, webAPIToken)
-		rr := executeRequest(req)
-		checkResponseCode(t, http.StatusInternalServerError, rr)
-		assert.Contains(t, rr.Body.String(), "transfer aborted")
-	}()
-	// wait for the transfers
-	assert.Eventually(t, func() bool {
-		stats := common.Connections.GetStats()
-		if len(stats) == 3 {
-			if len(stats[0].Transfers) > 0 && len(stats[1].Transfers) > 0 {
-				return true
-			}
-		}
-		return false
-	}, 1*time.Second, 50*time.Millisecond)
 
-	for _, stat := range common.Connections.GetStats() {
-		// close all the active transfers
-		common.Connections.Close(stat.ConnectionID)
+	share := dataprovider.Share{
+		Name:  "upload share",
+		Scope: dataprovider.ShareScopeWrite,
+		Paths: []string{"/dropbox", "/adir"},
 	}
-	wg.Wait()
-	assert.Eventually(t, func() bool { return len(common.Connections.GetStats()) == 0 },
-		1*time.Second, 100*time.Millisecond)
+	asJSON, err := json.Marshal(share)
+	assert.NoError(t, err)
+	req, err := http.NewRequest(http.MethodPost, userSharesPath, bytes.NewBuffer(asJSON))
+	assert.NoError(t, err)
+	setBearerForReq(req, token)
+	rr := executeRequest(req)
+	checkResponseCode(t, http.StatusBadRequest, rr)
+	assert.Contains(t, rr.Body.String(), "exactly one path")
 
-	_, err = httpdtest.RemoveUser(user, http.StatusOK)
+	share.Paths = []string{"/dropbox"}
+	share.Password = defaultPassword
+	share.MaxTokens = 3
+	asJSON, err = json.Marshal(share)
 	assert.NoError(t, err)
-	err = os.RemoveAll(user.GetHomeDir())
+	req, err = http.NewRequest(http.MethodPost, userSharesPath, bytes.NewBuffer(asJSON))
 	assert.NoError(t, err)
-}
+	setBearerForReq(req, token)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusCreated, rr)
+	objectID := rr.Header().Get("X-Object-ID")
+	assert.NotEmpty(t, objectID)
 
-func TestWebAdminSetupMock(t *testing.T) {
-	req, err := http.NewRequest(http.MethodGet, webAdminSetupPath, nil)
+	err = os.MkdirAll(filepath.Join(user.GetHomeDir(), "dropbox"), os.ModePerm)
 	assert.NoError(t, err)
-	rr := executeRequest(req)
-	checkResponseCode(t, http.StatusFound, rr)
-	assert.Equal(t, webLoginPath, rr.Header().Get("Location"))
-	// now delete all the admins
-	admins, err := dataprovider.GetAdmins(100, 0, dataprovider.OrderASC)
+	// the contents of an upload share cannot be downloaded
+	req, err = http.NewRequest(http.MethodGet, sharesPath+"/"+objectID, nil)
 	assert.NoError(t, err)
-	for _, admin := range admins {
-		err = dataprovider.DeleteAdmin(admin.Username, "", "")
-		assert.NoError(t, err)
-	}
-	// close the provider and initializes it without creating the default admin
-	os.Setenv("SFTPGO_DATA_PROVIDER__CREATE_DEFAULT_ADMIN", "0")
-	err = dataprovider.Close()
+	req.SetBasicAuth(defaultUsername, defaultPassword)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusForbidden, rr)
+	// the web client shows the upload page
+	req, err = http.NewRequest(http.MethodGet, webClientPubSharesPath+"/"+objectID, nil)
 	assert.NoError(t, err)
-	err = config.LoadConfig(configDir, "")
+	req.SetBasicAuth(defaultUsername, defaultPassword)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
+	assert.Contains(t, rr.Body.String(), "upload_files_form")
+
+	body := new(bytes.Buffer)
+	writer := multipart.NewWriter(body)
+	err = writer.WriteField("uploader", "external user")
 	assert.NoError(t, err)
-	providerConf := config.GetProviderConf()
-	providerConf.CredentialsPath = credentialsPath
-	err = os.RemoveAll(credentialsPath)
+	part1, err := writer.CreateFormFile("filename", "file1.txt")
 	assert.NoError(t, err)
-	err = dataprovider.Initialize(providerConf, configDir, true)
+	_, err = part1.Write([]byte("file1 content"))
 	assert.NoError(t, err)
-	// now the setup page must be rendered
-	req, err = http.NewRequest(http.MethodGet, webAdminSetupPath, nil)
+	part2, err := writer.CreateFormFile("filename", "file2.txt")
 	assert.NoError(t, err)
-	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusOK, rr)
-	// check redirects to the setup page
-	req, err = http.NewRequest(http.MethodGet, "/", nil)
+	_, err = part2.Write([]byte("file2"))
 	assert.NoError(t, err)
-	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusFound, rr)
-	assert.Equal(t, webAdminSetupPath, rr.Header().Get("Location"))
-	req, err = http.NewRequest(http.MethodGet, webBasePath, nil)
+	err = writer.Close()
 	assert.NoError(t, err)
-	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusFound, rr)
-	assert.Equal(t, webAdminSetupPath, rr.Header().Get("Location"))
-	req, err = http.NewRequest(http.MethodGet, webBasePathAdmin, nil)
+	reader := bytes.NewReader(body.Bytes())
+
+	req, err = http.NewRequest(http.MethodPost, sharesPath+"/"+objectID, reader)
 	assert.NoError(t, err)
+	req.Header.Add("Content-Type", writer.FormDataContentType())
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusFound, rr)
-	assert.Equal(t, webAdminSetupPath, rr.Header().Get("Location"))
-	req, err = http.NewRequest(http.MethodGet, webLoginPath, nil)
+	checkResponseCode(t, http.StatusUnauthorized, rr)
+
+	_, err = reader.Seek(0, io.SeekStart)
 	assert.NoError(t, err)
-	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusFound, rr)
-	assert.Equal(t, webAdminSetupPath, rr.Header().Get("Location"))
-	req, err = http.NewRequest(http.MethodGet, webClientLoginPath, nil)
+	req, err = http.NewRequest(http.MethodPost, sharesPath+"/"+objectID, reader)
 	assert.NoError(t, err)
+	req.Header.Add("Content-Type", writer.FormDataContentType())
+	req.SetBasicAuth(defaultUsername, defaultPassword)
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusFound, rr)
-	assert.Equal(t, webAdminSetupPath, rr.Header().Get("Location"))
+	checkResponseCode(t, http.StatusCreated, rr)
+	assert.FileExists(t, filepath.Join(user.GetHomeDir(), "dropbox", "file1.txt"))
+	assert.FileExists(t, filepath.Join(user.GetHomeDir(), "dropbox", "file2.txt"))
 
-	csrfToken, err := getCSRFToken(httpBaseURL + webAdminSetupPath)
+	share, err = dataprovider.ShareExists(objectID, defaultUsername)
 	assert.NoError(t, err)
-	form := make(url.Values)
-	req, err = http.NewRequest(http.MethodPost, webAdminSetupPath, bytes.NewBuffer([]byte(form.Encode())))
+	assert.Equal(t, 2, share.UsedTokens)
+	// two files exceed the remaining tokens
+	_, err = reader.Seek(0, io.SeekStart)
 	assert.NoError(t, err)
-	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
-	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusForbidden, rr)
-	form.Set(csrfFormToken, csrfToken)
-	req, err = http.NewRequest(http.MethodPost, webAdminSetupPath, bytes.NewBuffer([]byte(form.Encode())))
+	req, err = http.NewRequest(http.MethodPost, webClientPubSharesPath+"/"+objectID, reader)
 	assert.NoError(t, err)
-	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Add("Content-Type", writer.FormDataContentType())
+	req.SetBasicAuth(defaultUsername, defaultPassword)
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusOK, rr)
-	assert.Contains(t, rr.Body.String(), "Please set a username")
-	form.Set("username", defaultTokenAuthUser)
-	req, err = http.NewRequest(http.MethodPost, webAdminSetupPath, bytes.NewBuffer([]byte(form.Encode())))
+	checkResponseCode(t, http.StatusBadRequest, rr)
+	assert.Contains(t, rr.Body.String(), "Allowed usage exceeded")
+	// quota limits apply
+	share.MaxTokens = 0
+	err = dataprovider.UpdateShare(&share, user.Username, "")
 	assert.NoError(t, err)
-	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	body = new(bytes.Buffer)
+	writer = multipart.NewWriter(body)
+	part1, err = writer.CreateFormFile("filename", "../file3.txt")
+	assert.NoError(t, err)
+	_, err = part1.Write([]byte("file3 content"))
+	assert.NoError(t, err)
+	err = writer.Close()
+	assert.NoError(t, err)
+	reader = bytes.NewReader(body.Bytes())
+	req, err = http.NewRequest(http.MethodPost, sharesPath+"/"+objectID, reader)
+	assert.NoError(t, err)
+	req.Header.Add("Content-Type", writer.FormDataContentType())
+	req.SetBasicAuth(defaultUsername, defaultPassword)
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusOK, rr)
-	assert.Contains(t, rr.Body.String(), "Please set a password")
-	form.Set("password", defaultTokenAuthPass)
-	req, err = http.NewRequest(http.MethodPost, webAdminSetupPath, bytes.NewBuffer([]byte(form.Encode())))
+	checkResponseCode(t, http.StatusInternalServerError, rr)
+	assert.Contains(t, rr.Body.String(), "denying write due to space limit")
+
+	user.QuotaFiles = 0
+	user, _, err = httpdtest.UpdateUser(user, http.StatusOK, "")
 	assert.NoError(t, err)
-	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	_, err = reader.Seek(0, io.SeekStart)
+	assert.NoError(t, err)
+	req, err = http.NewRequest(http.MethodPost, sharesPath+"/"+objectID, reader)
+	assert.NoError(t, err)
+	req.Header.Add("Content-Type", writer.FormDataContentType())
+	req.SetBasicAuth(defaultUsername, defaultPassword)
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusOK, rr)
-	assert.Contains(t, rr.Body.String(), "Passwords mismatch")
-	form.Set("confirm_password", defaultTokenAuthPass)
-	// test a parse form error
-	req, err = http.NewRequest(http.MethodPost, webAdminSetupPath+"?param=p%C3%AO%GH", bytes.NewBuffer([]byte(form.Encode())))
+	checkResponseCode(t, http.StatusCreated, rr)
+	// uploads cannot escape from the shared directory
+	assert.FileExists(t, filepath.Join(user.GetHomeDir(), "dropbox", "file3.txt"))
+	assert.NoFileExists(t, filepath.Join(user.GetHomeDir(), "file3.txt"))
+
+	req, err = http.NewRequest(http.MethodGet, userSharesPath+"/"+objectID+"/uploads", nil)
 	assert.NoError(t, err)
-	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	setBearerForReq(req, token)
 	rr = executeRequest(req)
 	checkResponseCode(t, http.StatusOK, rr)
-	// test a dataprovider error
-	err = dataprovider.Close()
+	var uploads []dataprovider.ShareUpload
+	err = json.Unmarshal(rr.Body.Bytes(), &uploads)
 	assert.NoError(t, err)
-	req, err = http.NewRequest(http.MethodPost, webAdminSetupPath, bytes.NewBuffer([]byte(form.Encode())))
+	if assert.Len(t, uploads, 3) {
+		assert.Equal(t, objectID, uploads[0].ShareID)
+		assert.Equal(t, "/dropbox/file1.txt", uploads[0].Path)
+		assert.Equal(t, int64(13), uploads[0].Size)
+		assert.Equal(t, "external user", uploads[0].Uploader)
+		assert.Greater(t, uploads[0].UploadedAt, int64(0))
+		assert.Equal(t, "/dropbox/file2.txt", uploads[1].Path)
+		assert.Equal(t, "/dropbox/file3.txt", uploads[2].Path)
+		assert.Empty(t, uploads[2].Uploader)
+	}
+	req, err = http.NewRequest(http.MethodGet, userSharesPath+"/"+objectID+"/uploads?order=DESC&limit=1&offset=1", nil)
 	assert.NoError(t, err)
-	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	setBearerForReq(req, token)
 	rr = executeRequest(req)
 	checkResponseCode(t, http.StatusOK, rr)
-	// finally initialize the provider and create the default admin
-	err = config.LoadConfig(configDir, "")
+	uploads = nil
+	err = json.Unmarshal(rr.Body.Bytes(), &uploads)
 	assert.NoError(t, err)
-	providerConf = config.GetProviderConf()
-	providerConf.CredentialsPath = credentialsPath
-	err = os.RemoveAll(credentialsPath)
+	if assert.Len(t, uploads, 1) {
+		assert.Equal(t, "/dropbox/file2.txt", uploads[0].Path)
+	}
+	// existing files cannot be overwritten, only the stored files are recorded
+	body = new(bytes.Buffer)
+	writer = multipart.NewWriter(body)
+	part1, err = writer.CreateFormFile("filename", "file4.txt")
 	assert.NoError(t, err)
-	err = dataprovider.Initialize(providerConf, configDir, true)
+	_, err = part1.Write([]byte("file4 content"))
 	assert.NoError(t, err)
-	req, err = http.NewRequest(http.MethodPost, webAdminSetupPath, bytes.NewBuffer([]byte(form.Encode())))
+	part2, err = writer.CreateFormFile("filename", "file1.txt")
 	assert.NoError(t, err)
-	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
-	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusFound, rr)
-	assert.Equal(t, webUsersPath, rr.Header().Get("Location"))
-	// if we resubmit the form we get a bad request, an admin already exists
-	req, err = http.NewRequest(http.MethodPost, webAdminSetupPath, bytes.NewBuffer([]byte(form.Encode())))
+	_, err = part2.Write([]byte("overwritten"))
 	assert.NoError(t, err)
-	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	err = writer.Close()
+	assert.NoError(t, err)
+	req, err = http.NewRequest(http.MethodPost, sharesPath+"/"+objectID, bytes.NewReader(body.Bytes()))
+	assert.NoError(t, err)
+	req.Header.Add("Content-Type", writer.FormDataContentType())
+	req.SetBasicAuth(defaultUsername, defaultPassword)
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusBadRequest, rr)
-	assert.Contains(t, rr.Body.String(), "an admin user already exists")
-	os.Setenv("SFTPGO_DATA_PROVIDER__CREATE_DEFAULT_ADMIN", "1")
-}
-
-func TestWebAdminLoginMock(t *testing.T) {
-	webToken, err := getJWTWebTokenFromTestServer(defaultTokenAuthUser, defaultTokenAuthPass)
+	checkResponseCode(t, http.StatusConflict, rr)
+	assert.FileExists(t, filepath.Join(user.GetHomeDir(), "dropbox", "file4.txt"))
+	content, err := os.ReadFile(filepath.Join(user.GetHomeDir(), "dropbox", "file1.txt"))
 	assert.NoError(t, err)
-	apiToken, err := getJWTAPITokenFromTestServer(defaultTokenAuthUser, defaultTokenAuthPass)
+	assert.Equal(t, "file1 content", string(content))
+	uploads, err = dataprovider.GetShareUploads(objectID, 100, 0, dataprovider.OrderASC)
 	assert.NoError(t, err)
-
-	req, _ := http.NewRequest(http.MethodGet, serverStatusPath, nil)
-	setBearerForReq(req, apiToken)
-	rr := executeRequest(req)
-	checkResponseCode(t, http.StatusOK, rr)
-
-	req, _ = http.NewRequest(http.MethodGet, webStatusPath+"notfound", nil)
-	req.RequestURI = webStatusPath + "notfound"
-	setJWTCookieForReq(req, webToken)
+	if assert.Len(t, uploads, 4) {
+		assert.Equal(t, "/dropbox/file4.txt", uploads[3].Path)
+	}
+	req, err = http.NewRequest(http.MethodGet, userSharesPath+"/unknown/uploads", nil)
+	assert.NoError(t, err)
+	setBearerForReq(req, token)
 	rr = executeRequest(req)
 	checkResponseCode(t, http.StatusNotFound, rr)
 
-	req, _ = http.NewRequest(http.MethodGet, webStatusPath, nil)
+	req, err = http.NewRequest(http.MethodGet, webClientSharesPath+"/"+objectID+"/uploads", nil)
+	assert.NoError(t, err)
 	setJWTCookieForReq(req, webToken)
 	rr = executeRequest(req)
 	checkResponseCode(t, http.StatusOK, rr)
+	assert.Contains(t, rr.Body.String(), "/dropbox/file1.txt")
+	assert.Contains(t, rr.Body.String(), "external user")
 
-	req, _ = http.NewRequest(http.MethodGet, webLogoutPath, nil)
+	req, err = http.NewRequest(http.MethodGet, webClientSharesPath+"/unknown/uploads", nil)
+	assert.NoError(t, err)
 	setJWTCookieForReq(req, webToken)
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusFound, rr)
-	cookie := rr.Header().Get("Cookie")
-	assert.Empty(t, cookie)
-
-	req, _ = http.NewRequest(http.MethodGet, logoutPath, nil)
-	setBearerForReq(req, apiToken)
+	checkResponseCode(t, http.StatusNotFound, rr)
+	// uploads are removed with the share
+	req, err = http.NewRequest(http.MethodDelete, userSharesPath+"/"+objectID, nil)
+	assert.NoError(t, err)
+	setBearerForReq(req, token)
 	rr = executeRequest(req)
 	checkResponseCode(t, http.StatusOK, rr)
+	uploads, err = dataprovider.GetShareUploads(objectID, 100, 0, dataprovider.OrderASC)
+	assert.NoError(t, err)
+	assert.Len(t, uploads, 0)
 
-	req, _ = http.NewRequest(http.MethodGet, serverStatusPath, nil)
-	setBearerForReq(req, apiToken)
-	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusUnauthorized, rr)
-	assert.Contains(t, rr.Body.String(), "Your token is no longer valid")
-
-	req, _ = http.NewRequest(http.MethodGet, webStatusPath, nil)
-	setJWTCookieForReq(req, webToken)
-	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusFound, rr)
+	_, err = httpdtest.RemoveUser(user, http.StatusOK)
+	assert.NoError(t, err)
+	err = os.RemoveAll(user.GetHomeDir())
+	assert.NoError(t, err)
+}
 
-	csrfToken, err := getCSRFToken(httpBaseURL + webLoginPath)
+func TestTusUploads(t *testing.T) {
+	u := getTestUser()
+	u.QuotaSize = 100
+	user, _, err := httpdtest.AddUser(u, http.StatusCreated)
+	assert.NoError(t, err)
+	token, err := getJWTAPIUserTokenFromTestServer(defaultUsername, defaultPassword)
 	assert.NoError(t, err)
-	// now try using wrong credentials
-	form := getLoginForm(defaultTokenAuthUser, "wrong pwd", csrfToken)
-	req, _ = http.NewRequest(http.MethodPost, webLoginPath, bytes.NewBuffer([]byte(form.Encode())))
-	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
-	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusOK, rr)
-	// try from an ip not allowed
-	a := getTestAdmin()
-	a.Username = altAdminUsername
-	a.Password = altAdminPassword
-	a.Filters.AllowList = []string{"10.0.0.0/8"}
 
-	_, _, err = httpdtest.AddAdmin(a, http.StatusCreated)
+	req, err := http.NewRequest(http.MethodOptions, userUploadsPath, nil)
 	assert.NoError(t, err)
+	setBearerForReq(req, token)
+	rr := executeRequest(req)
+	checkResponseCode(t, http.StatusNoContent, rr)
+	assert.Equal(t, "1.0.0", rr.Header().Get("Tus-Version"))
+	assert.Contains(t, rr.Header().Get("Tus-Extension"), "termination")
 
-	form = getLoginForm(altAdminUsername, altAdminPassword, csrfToken)
-	req, _ = http.NewRequest(http.MethodPost, webLoginPath, bytes.NewBuffer([]byte(form.Encode())))
-	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
-	req.RemoteAddr = "127.1.1.1:1234"
+	metadata := "filename " + base64.StdEncoding.EncodeToString([]byte("file.txt"))
+	req, err = http.NewRequest(http.MethodPost, userUploadsPath, nil)
+	assert.NoError(t, err)
+	setBearerForReq(req, token)
+	req.Header.Set("Upload-Length", "12")
+	req.Header.Set("Upload-Metadata", metadata)
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusOK, rr)
-	assert.Contains(t, rr.Body.String(), "login from IP 127.1.1.1 not allowed")
+	checkResponseCode(t, http.StatusPreconditionFailed, rr)
+	assert.Equal(t, "1.0.0", rr.Header().Get("Tus-Version"))
 
-	req, _ = http.NewRequest(http.MethodPost, webLoginPath, bytes.NewBuffer([]byte(form.Encode())))
-	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
-	req.RemoteAddr = "10.9.9.9:1234"
+	req.Header.Set("Tus-Resumable", "1.0.0")
+	req.Header.Set("Upload-Length", "a")
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusFound, rr)
+	checkResponseCode(t, http.StatusBadRequest, rr)
 
-	req, _ = http.NewRequest(http.MethodPost, webLoginPath, bytes.NewBuffer([]byte(form.Encode())))
-	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
-	req.RemoteAddr = "127.0.1.1:4567"
-	req.Header.Set("X-Forwarded-For", "10.9.9.9")
+	req.Header.Set("Upload-Length", "12")
+	req.Header.Set("Upload-Metadata", "name "+base64.StdEncoding.EncodeToString([]byte("file.txt")))
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusOK, rr)
-	assert.Contains(t, rr.Body.String(), "login from IP 127.0.1.1 not allowed")
+	checkResponseCode(t, http.StatusBadRequest, rr)
+	assert.Contains(t, rr.Body.String(), "filename is required")
 
-	// invalid csrf token
-	form = getLoginForm(altAdminUsername, altAdminPassword, "invalid csrf")
-	req, _ = http.NewRequest(http.MethodPost, webLoginPath, bytes.NewBuffer([]byte(form.Encode())))
-	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
-	req.RemoteAddr = "10.9.9.8:1234"
+	req.Header.Set("Upload-Metadata", "filename invalid base64")
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusOK, rr)
-	assert.Contains(t, rr.Body.String(), "unable to verify form token")
+	checkResponseCode(t, http.StatusBadRequest, rr)
 
-	req, _ = http.NewRequest(http.MethodGet, webLoginPath, nil)
+	req.Header.Set("Upload-Length", "101")
+	req.Header.Set("Upload-Metadata", metadata)
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusOK, rr)
+	checkResponseCode(t, http.StatusRequestEntityTooLarge, rr)
 
-	_, err = httpdtest.RemoveAdmin(a, http.StatusOK)
+	req, err = http.NewRequest(http.MethodPost, userUploadsPath+"?path=%2Fmissing", nil)
 	assert.NoError(t, err)
-}
-
-func TestAdminNoToken(t *testing.T) {
-	req, _ := http.NewRequest(http.MethodGet, webAdminProfilePath, nil)
-	rr := executeRequest(req)
-	checkResponseCode(t, http.StatusFound, rr)
-	assert.Equal(t, webLoginPath, rr.Header().Get("Location"))
-
-	req, _ = http.NewRequest(http.MethodGet, webUserPath, nil)
+	setBearerForReq(req, token)
+	req.Header.Set("Tus-Resumable", "1.0.0")
+	req.Header.Set("Upload-Length", "12")
+	req.Header.Set("Upload-Metadata", metadata)
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusFound, rr)
-	assert.Equal(t, webLoginPath, rr.Header().Get("Location"))
-
-	req, _ = http.NewRequest(http.MethodGet, userPath, nil)
+	checkResponseCode(t, http.StatusCreated, rr)
+	// the parent directory does not exist, the chunk write will fail
+	location := rr.Header().Get("Location")
+	req, err = http.NewRequest(http.MethodPatch, location, bytes.NewBuffer([]byte("file")))
+	assert.NoError(t, err)
+	setBearerForReq(req, token)
+	req.Header.Set("Tus-Resumable", "1.0.0")
+	req.Header.Set("Upload-Offset", "0")
+	req.Header.Set("Content-Type", "application/offset+octet-stream")
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusUnauthorized, rr)
-
-	req, _ = http.NewRequest(http.MethodGet, activeConnectionsPath, nil)
+	checkResponseCode(t, http.StatusNotFound, rr)
+	req, err = http.NewRequest(http.MethodDelete, location, nil)
+	assert.NoError(t, err)
+	setBearerForReq(req, token)
+	req.Header.Set("Tus-Resumable", "1.0.0")
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusUnauthorized, rr)
-}
+	checkResponseCode(t, http.StatusNoContent, rr)
 
-func TestWebUserProfile(t *testing.T) {
-	user, _, err := httpdtest.AddUser(getTestUser(), http.StatusCreated)
+	req, err = http.NewRequest(http.MethodPost, userUploadsPath, nil)
 	assert.NoError(t, err)
+	setBearerForReq(req, token)
+	req.Header.Set("Tus-Resumable", "1.0.0")
+	req.Header.Set("Upload-Length", "12")
+	req.Header.Set("Upload-Metadata", metadata)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusCreated, rr)
+	assert.Equal(t, "1.0.0", rr.Header().Get("Tus-Resumable"))
+	assert.NotEmpty(t, rr.Header().Get("Upload-Expires"))
+	location = rr.Header().Get("Location")
+	assert.True(t, strings.HasPrefix(location, userUploadsPath+"/"))
 
-	csrfToken, err := getCSRFToken(httpBaseURL + webClientLoginPath)
-	assert.NoError(t, err)
-	token, err := getJWTWebClientTokenFromTestServer(defaultUsername, defaultPassword)
+	req, err = http.NewRequest(http.MethodHead, location, nil)
 	assert.NoError(t, err)
+	setBearerForReq(req, token)
+	req.Header.Set("Tus-Resumable", "1.0.0")
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
+	assert.Equal(t, "0", rr.Header().Get("Upload-Offset"))
+	assert.Equal(t, "12", rr.Header().Get("Upload-Length"))
 
-	email := "user@user.com"
-	description := "User"
-
-	form := make(url.Values)
-	form.Set("allow_api_key_auth", "1")
-	form.Set("email", email)
-	form.Set("description", description)
-	form.Set("public_keys", testPubKey)
-	form.Add("public_keys", testPubKey1)
-	// no csrf token
-	req, err := http.NewRequest(http.MethodPost, webClientProfilePath, bytes.NewBuffer([]byte(form.Encode())))
+	req, err = http.NewRequest(http.MethodPatch, location, bytes.NewBuffer([]byte("file ")))
 	assert.NoError(t, err)
-	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
-	setJWTCookieForReq(req, token)
-	rr := executeRequest(req)
-	checkResponseCode(t, http.StatusForbidden, rr)
-	assert.Contains(t, rr.Body.String(), "unable to verify form token")
+	setBearerForReq(req, token)
+	req.Header.Set("Tus-Resumable", "1.0.0")
+	req.Header.Set("Upload-Offset", "0")
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusUnsupportedMediaType, rr)
 
-	form.Set(csrfFormToken, csrfToken)
-	req, _ = http.NewRequest(http.MethodPost, webClientProfilePath, bytes.NewBuffer([]byte(form.Encode())))
-	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
-	setJWTCookieForReq(req, token)
+	req.Header.Set("Content-Type", "application/offset+octet-stream")
+	req.Header.Set("Upload-Offset", "-1")
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusOK, rr)
-	assert.Contains(t, rr.Body.String(), "Your profile has been successfully updated")
+	checkResponseCode(t, http.StatusBadRequest, rr)
 
+	req.Header.Set("Upload-Offset", "0")
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusNoContent, rr)
+	assert.Equal(t, "5", rr.Header().Get("Upload-Offset"))
+	assert.NotEmpty(t, rr.Header().Get("Upload-Expires"))
+	// the file is not visible until the upload is complete
+	assert.NoFileExists(t, filepath.Join(user.GetHomeDir(), "file.txt"))
 	user, _, err = httpdtest.GetUserByUsername(user.Username, http.StatusOK)
 	assert.NoError(t, err)
-	assert.True(t, user.Filters.AllowAPIKeyAuth)
-	assert.Len(t, user.PublicKeys, 2)
-	assert.Equal(t, email, user.Email)
-	assert.Equal(t, description, user.Description)
+	assert.Equal(t, 0, user.UsedQuotaFiles)
+	assert.Equal(t, int64(0), user.UsedQuotaSize)
 
-	// set an invalid email
-	form.Set("email", "not an email")
-	req, _ = http.NewRequest(http.MethodPost, webClientProfilePath, bytes.NewBuffer([]byte(form.Encode())))
-	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
-	setJWTCookieForReq(req, token)
+	req, err = http.NewRequest(http.MethodPatch, location, bytes.NewBuffer([]byte("content")))
+	assert.NoError(t, err)
+	setBearerForReq(req, token)
+	req.Header.Set("Tus-Resumable", "1.0.0")
+	req.Header.Set("Content-Type", "application/offset+octet-stream")
+	req.Header.Set("Upload-Offset", "3")
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusOK, rr)
-	assert.Contains(t, rr.Body.String(), "Validation error: email")
-	// invalid public key
-	form.Set("email", email)
-	form.Set("public_keys", "invalid")
-	req, _ = http.NewRequest(http.MethodPost, webClientProfilePath, bytes.NewBuffer([]byte(form.Encode())))
-	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
-	setJWTCookieForReq(req, token)
+	checkResponseCode(t, http.StatusConflict, rr)
+
+	req, err = http.NewRequest(http.MethodHead, location, nil)
+	assert.NoError(t, err)
+	setBearerForReq(req, token)
+	req.Header.Set("Tus-Resumable", "1.0.0")
 	rr = executeRequest(req)
 	checkResponseCode(t, http.StatusOK, rr)
-	assert.Contains(t, rr.Body.String(), "Validation error: could not parse key")
-	// now remove permissions
-	form.Set("public_keys", testPubKey)
-	user.Filters.WebClient = []string{sdk.WebClientAPIKeyAuthChangeDisabled}
-	_, _, err = httpdtest.UpdateUser(user, http.StatusOK, "")
-	assert.NoError(t, err)
-	token, err = getJWTWebClientTokenFromTestServer(defaultUsername, defaultPassword)
+	assert.Equal(t, "5", rr.Header().Get("Upload-Offset"))
+	// data exceeding the upload length is ignored
+	req, err = http.NewRequest(http.MethodPatch, location, bytes.NewBuffer([]byte("contentextra")))
 	assert.NoError(t, err)
-
-	form.Set("allow_api_key_auth", "0")
-	form.Set(csrfFormToken, csrfToken)
-	req, _ = http.NewRequest(http.MethodPost, webClientProfilePath, bytes.NewBuffer([]byte(form.Encode())))
-	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
-	setJWTCookieForReq(req, token)
+	setBearerForReq(req, token)
+	req.Header.Set("Tus-Resumable", "1.0.0")
+	req.Header.Set("Content-Type", "application/offset+octet-stream")
+	req.Header.Set("Upload-Offset", "5")
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusOK, rr)
-	assert.Contains(t, rr.Body.String(), "Your profile has been successfully updated")
+	checkResponseCode(t, http.StatusNoContent, rr)
+	assert.Equal(t, "12", rr.Header().Get("Upload-Offset"))
+
+	content, err := os.ReadFile(filepath.Join(user.GetHomeDir(), "file.txt"))
+	assert.NoError(t, err)
+	assert.Equal(t, "file content", string(content))
 	user, _, err = httpdtest.GetUserByUsername(user.Username, http.StatusOK)
 	assert.NoError(t, err)
-	assert.True(t, user.Filters.AllowAPIKeyAuth)
-	assert.Len(t, user.PublicKeys, 1)
-	assert.Equal(t, email, user.Email)
-	assert.Equal(t, description, user.Description)
-
-	user.Filters.WebClient = []string{sdk.WebClientAPIKeyAuthChangeDisabled, sdk.WebClientPubKeyChangeDisabled}
-	_, _, err = httpdtest.UpdateUser(user, http.StatusOK, "")
+	assert.Equal(t, 1, user.UsedQuotaFiles)
+	assert.Equal(t, int64(12), user.UsedQuotaSize)
+	// the upload is complete and no longer available
+	req, err = http.NewRequest(http.MethodHead, location, nil)
 	assert.NoError(t, err)
-	token, err = getJWTWebClientTokenFromTestServer(defaultUsername, defaultPassword)
+	setBearerForReq(req, token)
+	req.Header.Set("Tus-Resumable", "1.0.0")
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusNotFound, rr)
+	// overwrite the existing file sending the data within the creation request
+	req, err = http.NewRequest(http.MethodPost, userUploadsPath, bytes.NewBuffer([]byte("new")))
 	assert.NoError(t, err)
-	form.Set("public_keys", testPubKey)
-	form.Add("public_keys", testPubKey1)
-	req, _ = http.NewRequest(http.MethodPost, webClientProfilePath, bytes.NewBuffer([]byte(form.Encode())))
-	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
-	setJWTCookieForReq(req, token)
+	setBearerForReq(req, token)
+	req.Header.Set("Tus-Resumable", "1.0.0")
+	req.Header.Set("Content-Type", "application/offset+octet-stream")
+	req.Header.Set("Upload-Length", "3")
+	req.Header.Set("Upload-Metadata", metadata)
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusOK, rr)
-	assert.Contains(t, rr.Body.String(), "Your profile has been successfully updated")
-	user, _, err = httpdtest.GetUserByUsername(user.Username, http.StatusOK)
+	checkResponseCode(t, http.StatusCreated, rr)
+	assert.Equal(t, "3", rr.Header().Get("Upload-Offset"))
+	content, err = os.ReadFile(filepath.Join(user.GetHomeDir(), "file.txt"))
 	assert.NoError(t, err)
-	assert.True(t, user.Filters.AllowAPIKeyAuth)
-	assert.Len(t, user.PublicKeys, 1)
-	assert.Equal(t, email, user.Email)
-	assert.Equal(t, description, user.Description)
-
-	user.Filters.WebClient = []string{sdk.WebClientAPIKeyAuthChangeDisabled, sdk.WebClientInfoChangeDisabled}
-	_, _, err = httpdtest.UpdateUser(user, http.StatusOK, "")
+	assert.Equal(t, "new", string(content))
+	user, _, err = httpdtest.GetUserByUsername(user.Username, http.StatusOK)
 	assert.NoError(t, err)
-	token, err = getJWTWebClientTokenFromTestServer(defaultUsername, defaultPassword)
+	assert.Equal(t, 1, user.UsedQuotaFiles)
+	assert.Equal(t, int64(3), user.UsedQuotaSize)
+	// an empty file is completed on creation
+	req, err = http.NewRequest(http.MethodPost, userUploadsPath, nil)
 	assert.NoError(t, err)
-	form.Set("email", "newemail@user.com")
-	form.Set("description", "new description")
-	req, _ = http.NewRequest(http.MethodPost, webClientProfilePath, bytes.NewBuffer([]byte(form.Encode())))
-	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
-	setJWTCookieForReq(req, token)
+	setBearerForReq(req, token)
+	req.Header.Set("Tus-Resumable", "1.0.0")
+	req.Header.Set("Upload-Length", "0")
+	req.Header.Set("Upload-Metadata", "filename "+base64.StdEncoding.EncodeToString([]byte("empty.txt")))
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusOK, rr)
-	assert.Contains(t, rr.Body.String(), "Your profile has been successfully updated")
-	user, _, err = httpdtest.GetUserByUsername(user.Username, http.StatusOK)
+	checkResponseCode(t, http.StatusCreated, rr)
+	assert.FileExists(t, filepath.Join(user.GetHomeDir(), "empty.txt"))
+	// terminate an upload
+	req, err = http.NewRequest(http.MethodPost, userUploadsPath, bytes.NewBuffer([]byte("partial")))
 	assert.NoError(t, err)
-	assert.True(t, user.Filters.AllowAPIKeyAuth)
-	assert.Len(t, user.PublicKeys, 2)
-	assert.Equal(t, email, user.Email)
-	assert.Equal(t, description, user.Description)
-	// finally disable all profile permissions
-	user.Filters.WebClient = []string{sdk.WebClientAPIKeyAuthChangeDisabled, sdk.WebClientInfoChangeDisabled,
-		sdk.WebClientPubKeyChangeDisabled}
+	setBearerForReq(req, token)
+	req.Header.Set("Tus-Resumable", "1.0.0")
+	req.Header.Set("Content-Type", "application/offset+octet-stream")
+	req.Header.Set("Upload-Length", "20")
+	req.Header.Set("Upload-Metadata", "filename "+base64.StdEncoding.EncodeToString([]byte("partial.txt")))
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusCreated, rr)
+	assert.Equal(t, "7", rr.Header().Get("Upload-Offset"))
+	location = rr.Header().Get("Location")
+	// uploads are private to the user who created them
+	u.Username += "1"
+	user1, _, err := httpdtest.AddUser(u, http.StatusCreated)
+	assert.NoError(t, err)
+	token1, err := getJWTAPIUserTokenFromTestServer(user1.Username, defaultPassword)
+	assert.NoError(t, err)
+	req, err = http.NewRequest(http.MethodDelete, location, nil)
+	assert.NoError(t, err)
+	setBearerForReq(req, token1)
+	req.Header.Set("Tus-Resumable", "1.0.0")
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusNotFound, rr)
+
+	req, err = http.NewRequest(http.MethodDelete, location, nil)
+	assert.NoError(t, err)
+	setBearerForReq(req, token)
+	req.Header.Set("Tus-Resumable", "1.0.0")
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusNoContent, rr)
+	assert.NoFileExists(t, filepath.Join(user.GetHomeDir(), "partial.txt"))
+	files, err := os.ReadDir(user.GetHomeDir())
+	assert.NoError(t, err)
+	assert.Len(t, files, 2)
+	// check permissions
+	user.Permissions["/"] = []string{dataprovider.PermListItems, dataprovider.PermUpload}
 	_, _, err = httpdtest.UpdateUser(user, http.StatusOK, "")
 	assert.NoError(t, err)
-	token, err = getJWTWebClientTokenFromTestServer(defaultUsername, defaultPassword)
+	req, err = http.NewRequest(http.MethodPost, userUploadsPath, nil)
 	assert.NoError(t, err)
-	req, _ = http.NewRequest(http.MethodPost, webClientProfilePath, bytes.NewBuffer([]byte(form.Encode())))
-	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
-	setJWTCookieForReq(req, token)
+	setBearerForReq(req, token)
+	req.Header.Set("Tus-Resumable", "1.0.0")
+	req.Header.Set("Upload-Length", "3")
+	req.Header.Set("Upload-Metadata", metadata)
 	rr = executeRequest(req)
 	checkResponseCode(t, http.StatusForbidden, rr)
 
-	err = os.RemoveAll(user.GetHomeDir())
+	_, err = httpdtest.RemoveUser(user1, http.StatusOK)
+	assert.NoError(t, err)
+	err = os.RemoveAll(user1.GetHomeDir())
 	assert.NoError(t, err)
 	_, err = httpdtest.RemoveUser(user, http.StatusOK)
 	assert.NoError(t, err)
-
-	form = make(url.Values)
-	form.Set(csrfFormToken, csrfToken)
-	req, _ = http.NewRequest(http.MethodPost, webClientProfilePath, bytes.NewBuffer([]byte(form.Encode())))
-	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
-	setJWTCookieForReq(req, token)
-	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusInternalServerError, rr)
+	err = os.RemoveAll(user.GetHomeDir())
+	assert.NoError(t, err)
 }
 
-func TestWebAdminProfile(t *testing.T) {
-	admin := getTestAdmin()
-	admin.Username = altAdminUsername
-	admin.Password = altAdminPassword
-	admin, _, err := httpdtest.AddAdmin(admin, http.StatusCreated)
+func TestWebClientTusUploads(t *testing.T) {
+	user, _, err := httpdtest.AddUser(getTestUser(), http.StatusCreated)
 	assert.NoError(t, err)
-	token, err := getJWTWebTokenFromTestServer(admin.Username, altAdminPassword)
+	webToken, err := getJWTWebClientTokenFromTestServer(defaultUsername, defaultPassword)
 	assert.NoError(t, err)
-	csrfToken, err := getCSRFToken(httpBaseURL + webLoginPath)
+	csrfToken, err := getCSRFToken(httpBaseURL + webClientLoginPath)
 	assert.NoError(t, err)
-	req, err := http.NewRequest(http.MethodGet, webAdminProfilePath, nil)
+
+	metadata := "filename " + base64.StdEncoding.EncodeToString([]byte("web file.txt"))
+	req, err := http.NewRequest(http.MethodPost, webClientUploadsPath, nil)
 	assert.NoError(t, err)
-	setJWTCookieForReq(req, token)
+	setJWTCookieForReq(req, webToken)
+	req.Header.Set("Tus-Resumable", "1.0.0")
+	req.Header.Set("Upload-Length", "4")
+	req.Header.Set("Upload-Metadata", metadata)
 	rr := executeRequest(req)
-	checkResponseCode(t, http.StatusOK, rr)
+	checkResponseCode(t, http.StatusForbidden, rr)
 
-	form := make(url.Values)
-	form.Set("allow_api_key_auth", "1")
-	form.Set("email", "admin@example.com")
-	form.Set("description", "admin desc")
-	// no csrf token
-	req, err = http.NewRequest(http.MethodPost, webAdminProfilePath, bytes.NewBuffer([]byte(form.Encode())))
-	assert.NoError(t, err)
-	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
-	setJWTCookieForReq(req, token)
+	req.Header.Set("X-CSRF-TOKEN", csrfToken)
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusForbidden, rr)
-	assert.Contains(t, rr.Body.String(), "unable to verify form token")
+	checkResponseCode(t, http.StatusCreated, rr)
+	location := rr.Header().Get("Location")
+	assert.True(t, strings.HasPrefix(location, webClientUploadsPath+"/"))
 
-	form.Set(csrfFormToken, csrfToken)
-	req, _ = http.NewRequest(http.MethodPost, webAdminProfilePath, bytes.NewBuffer([]byte(form.Encode())))
-	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
-	setJWTCookieForReq(req, token)
+	req, err = http.NewRequest(http.MethodPatch, location, bytes.NewBuffer([]byte("data")))
+	assert.NoError(t, err)
+	setJWTCookieForReq(req, webToken)
+	req.Header.Set("Tus-Resumable", "1.0.0")
+	req.Header.Set("Content-Type", "application/offset+octet-stream")
+	req.Header.Set("Upload-Offset", "0")
+	req.Header.Set("X-CSRF-TOKEN", csrfToken)
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusOK, rr)
-	assert.Contains(t, rr.Body.String(), "Your profile has been successfully updated")
+	checkResponseCode(t, http.StatusNoContent, rr)
+	assert.Equal(t, "4", rr.Header().Get("Upload-Offset"))
 
-	admin, _, err = httpdtest.GetAdminByUsername(admin.Username, http.StatusOK)
+	content, err := os.ReadFile(filepath.Join(user.GetHomeDir(), "web file.txt"))
 	assert.NoError(t, err)
-	assert.True(t, admin.Filters.AllowAPIKeyAuth)
-	assert.Equal(t, "admin@example.com", admin.Email)
-	assert.Equal(t, "admin desc", admin.Description)
+	assert.Equal(t, "data", string(content))
 
-	form = make(url.Values)
-	form.Set(csrfFormToken, csrfToken)
-	req, _ = http.NewRequest(http.MethodPost, webAdminProfilePath, bytes.NewBuffer([]byte(form.Encode())))
-	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
-	setJWTCookieForReq(req, token)
+	req, err = http.NewRequest(http.MethodGet, webClientFilesPath, nil)
+	assert.NoError(t, err)
+	setJWTCookieForReq(req, webToken)
 	rr = executeRequest(req)
 	checkResponseCode(t, http.StatusOK, rr)
-	assert.Contains(t, rr.Body.String(), "Your profile has been successfully updated")
+	assert.Contains(t, rr.Body.String(), "Tus-Resumable")
 
-	admin, _, err = httpdtest.GetAdminByUsername(admin.Username, http.StatusOK)
+	_, err = httpdtest.RemoveUser(user, http.StatusOK)
 	assert.NoError(t, err)
-	assert.False(t, admin.Filters.AllowAPIKeyAuth)
-	assert.Empty(t, admin.Email)
-	assert.Empty(t, admin.Description)
-
-	_, err = httpdtest.RemoveAdmin(admin, http.StatusOK)
+	err = os.RemoveAll(user.GetHomeDir())
 	assert.NoError(t, err)
-
-	form = make(url.Values)
-	form.Set(csrfFormToken, csrfToken)
-	req, _ = http.NewRequest(http.MethodPost, webAdminProfilePath, bytes.NewBuffer([]byte(form.Encode())))
-	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
-	setJWTCookieForReq(req, token)
-	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusInternalServerError, rr)
 }
 
-func TestWebAdminPwdChange(t *testing.T) {
-	admin := getTestAdmin()
-	admin.Username = altAdminUsername
-	admin.Password = altAdminPassword
-	admin, _, err := httpdtest.AddAdmin(admin, http.StatusCreated)
+func TestWebClientTusUploadsFallback(t *testing.T) {
+	// resumable uploads are refused for the encrypted virtual folder, the web
+	// client uploads these files using multipart requests
+	mappedPath := filepath.Join(os.TempDir(), "tus_crypted")
+	folderName := filepath.Base(mappedPath)
+	u := getTestUser()
+	u.VirtualFolders = append(u.VirtualFolders, vfs.VirtualFolder{
+		BaseVirtualFolder: vfs.BaseVirtualFolder{
+			Name:       folderName,
+			MappedPath: mappedPath,
+			FsConfig: vfs.Filesystem{
+				Provider: sdk.CryptedFilesystemProvider,
+				CryptConfig: vfs.CryptFsConfig{
+					CryptFsConfig: sdk.CryptFsConfig{
+						Passphrase: kms.NewPlainSecret("crypted secret"),
+					},
+				},
+			},
+		},
+		VirtualPath: "/vdir",
+	})
+	user, _, err := httpdtest.AddUser(u, http.StatusCreated)
+	assert.NoError(t, err)
+	webToken, err := getJWTWebClientTokenFromTestServer(defaultUsername, defaultPassword)
+	assert.NoError(t, err)
+	csrfToken, err := getCSRFToken(httpBaseURL + webClientLoginPath)
 	assert.NoError(t, err)
 
-	token, err := getJWTWebTokenFromTestServer(admin.Username, altAdminPassword)
+	for _, p := range []string{"/", "/vdir"} {
+		req, err := http.NewRequest(http.MethodPost, webClientUploadsPath+"?path="+url.QueryEscape(p), nil)
+		assert.NoError(t, err)
+		setJWTCookieForReq(req, webToken)
+		req.Header.Set("Tus-Resumable", "1.0.0")
+		req.Header.Set("Upload-Length", "0")
+		req.Header.Set("Upload-Metadata", "filename "+base64.StdEncoding.EncodeToString([]byte("file.txt")))
+		req.Header.Set("X-CSRF-TOKEN", csrfToken)
+		rr := executeRequest(req)
+		if p == "/" {
+			checkResponseCode(t, http.StatusCreated, rr)
+		} else {
+			checkResponseCode(t, http.StatusNotImplemented, rr)
+		}
+	}
+	assert.FileExists(t, filepath.Join(user.GetHomeDir(), "file.txt"))
+
+	body := new(bytes.Buffer)
+	writer := multipart.NewWriter(body)
+	part, err := writer.CreateFormFile("filename", "file.txt")
 	assert.NoError(t, err)
-	csrfToken, err := getCSRFToken(httpBaseURL + webLoginPath)
+	_, err = part.Write([]byte("file content"))
 	assert.NoError(t, err)
-	req, err := http.NewRequest(http.MethodGet, webChangeAdminPwdPath, nil)
+	err = writer.Close()
 	assert.NoError(t, err)
-	setJWTCookieForReq(req, token)
+	req, err := http.NewRequest(http.MethodPost, webClientFilesPath+"?path=%2Fvdir", bytes.NewReader(body.Bytes()))
+	assert.NoError(t, err)
+	setJWTCookieForReq(req, webToken)
+	req.Header.Add("Content-Type", writer.FormDataContentType())
+	req.Header.Set("X-CSRF-TOKEN", csrfToken)
 	rr := executeRequest(req)
-	checkResponseCode(t, http.StatusOK, rr)
-	form := make(url.Values)
-	form.Set("current_password", altAdminPassword)
-	form.Set("new_password1", altAdminPassword)
-	form.Set("new_password2", altAdminPassword)
-	// no csrf token
-	req, _ = http.NewRequest(http.MethodPost, webChangeAdminPwdPath, bytes.NewBuffer([]byte(form.Encode())))
-	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
-	setJWTCookieForReq(req, token)
-	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusForbidden, rr)
-	assert.Contains(t, rr.Body.String(), "unable to verify form token")
+	checkResponseCode(t, http.StatusCreated, rr)
 
-	form.Set(csrfFormToken, csrfToken)
-	req, _ = http.NewRequest(http.MethodPost, webChangeAdminPwdPath, bytes.NewBuffer([]byte(form.Encode())))
-	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
-	setJWTCookieForReq(req, token)
+	req, err = http.NewRequest(http.MethodGet, webClientFilesPath+"?path=%2Fvdir%2Ffile.txt", nil)
+	assert.NoError(t, err)
+	setJWTCookieForReq(req, webToken)
 	rr = executeRequest(req)
 	checkResponseCode(t, http.StatusOK, rr)
-	assert.Contains(t, rr.Body.String(), "the new password must be different from the current one")
-
-	form.Set("new_password1", altAdminPassword+"1")
-	form.Set("new_password2", altAdminPassword+"1")
-	req, _ = http.NewRequest(http.MethodPost, webChangeAdminPwdPath, bytes.NewBuffer([]byte(form.Encode())))
-	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
-	setJWTCookieForReq(req, token)
-	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusFound, rr)
-	assert.Equal(t, webLoginPath, rr.Header().Get("Location"))
+	assert.Equal(t, "file content", rr.Body.String())
 
-	_, err = httpdtest.RemoveAdmin(admin, http.StatusOK)
+	_, err = httpdtest.RemoveUser(user, http.StatusOK)
+	assert.NoError(t, err)
+	_, err = httpdtest.RemoveFolder(vfs.BaseVirtualFolder{Name: folderName}, http.StatusOK)
+	assert.NoError(t, err)
+	err = os.RemoveAll(user.GetHomeDir())
+	assert.NoError(t, err)
+	err = os.RemoveAll(mappedPath)
 	assert.NoError(t, err)
 }
 
-func TestAPIKeysManagement(t *testing.T) {
-	admin := getTestAdmin()
-	admin.Username = altAdminUsername
-	admin.Password = altAdminPassword
-	admin, _, err := httpdtest.AddAdmin(admin, http.StatusCreated)
+func TestWebClientShares(t *testing.T) {
+	user, _, err := httpdtest.AddUser(getTestUser(), http.StatusCreated)
 	assert.NoError(t, err)
-	token, err := getJWTAPITokenFromTestServer(defaultTokenAuthUser, defaultTokenAuthPass)
+	webToken, err := getJWTWebClientTokenFromTestServer(defaultUsername, defaultPassword)
 	assert.NoError(t, err)
-	apiKey := dataprovider.APIKey{
-		Name:  "test key",
-		Scope: dataprovider.APIKeyScopeAdmin,
-	}
-	asJSON, err := json.Marshal(apiKey)
+	csrfToken, err := getCSRFToken(httpBaseURL + webClientLoginPath)
 	assert.NoError(t, err)
-	req, err := http.NewRequest(http.MethodPost, apiKeysPath, bytes.NewBuffer(asJSON))
+
+	req, err := http.NewRequest(http.MethodGet, webClientSharesPath, nil)
 	assert.NoError(t, err)
-	setBearerForReq(req, token)
+	setJWTCookieForReq(req, webToken)
 	rr := executeRequest(req)
-	checkResponseCode(t, http.StatusCreated, rr)
-	location := rr.Header().Get("Location")
-	assert.NotEmpty(t, location)
-	objectID := rr.Header().Get("X-Object-ID")
-	assert.NotEmpty(t, objectID)
-	assert.Equal(t, fmt.Sprintf("%v/%v", apiKeysPath, objectID), location)
-	apiKey.KeyID = objectID
-	response := make(map[string]string)
-	err = json.Unmarshal(rr.Body.Bytes(), &response)
-	assert.NoError(t, err)
-	key := response["key"]
-	assert.NotEmpty(t, key)
-	assert.True(t, strings.HasPrefix(key, apiKey.KeyID+"."))
+	checkResponseCode(t, http.StatusOK, rr)
 
-	req, err = http.NewRequest(http.MethodGet, location, nil)
+	req, err = http.NewRequest(http.MethodGet, webClientSharePath+"?path=%2F&files=%5B%22afile%22%5D", nil)
 	assert.NoError(t, err)
-	setBearerForReq(req, token)
+	setJWTCookieForReq(req, webToken)
 	rr = executeRequest(req)
 	checkResponseCode(t, http.StatusOK, rr)
-	var keyGet dataprovider.APIKey
-	err = json.Unmarshal(rr.Body.Bytes(), &keyGet)
-	assert.NoError(t, err)
-	assert.Empty(t, keyGet.Key)
-	assert.Equal(t, apiKey.KeyID, keyGet.KeyID)
-	assert.Equal(t, apiKey.Scope, keyGet.Scope)
-	assert.Equal(t, apiKey.Name, keyGet.Name)
-	assert.Equal(t, int64(0), keyGet.ExpiresAt)
-	assert.Equal(t, int64(0), keyGet.LastUseAt)
-	assert.Greater(t, keyGet.CreatedAt, int64(0))
-	assert.Greater(t, keyGet.UpdatedAt, int64(0))
-	assert.Empty(t, keyGet.Description)
-	assert.Empty(t, keyGet.User)
-	assert.Empty(t, keyGet.Admin)
 
-	// API key is not enabled for the admin user so this request should fail
-	req, err = http.NewRequest(http.MethodGet, versionPath, nil)
+	req, err = http.NewRequest(http.MethodGet, webClientSharePath+"?path=%2F&files=notalist", nil)
 	assert.NoError(t, err)
-	setAPIKeyForReq(req, key, admin.Username)
+	setJWTCookieForReq(req, webToken)
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusUnauthorized, rr)
-	assert.Contains(t, rr.Body.String(), "the admin associated with the provided api key cannot be authenticated")
+	checkResponseCode(t, http.StatusBadRequest, rr)
 
-	admin.Filters.AllowAPIKeyAuth = true
-	admin, _, err = httpdtest.UpdateAdmin(admin, http.StatusOK)
-	assert.NoError(t, err)
-	req, err = http.NewRequest(http.MethodGet, versionPath, nil)
+	form := make(url.Values)
+	form.Set("name", "web share")
+	form.Set("scope", "1")
+	form.Add("paths", "/afile")
+	form.Add("paths", "/adir")
+	form.Set("password", defaultPassword)
+	form.Set("max_tokens", "")
+	form.Set("expiration_date", time.Now().Add(1*time.Hour).UTC().Format("2006-01-02 15:04:05"))
+	form.Set("allowed_ip", "127.0.0.1/8")
+	req, err = http.NewRequest(http.MethodPost, webClientSharePath, bytes.NewBuffer([]byte(form.Encode())))
 	assert.NoError(t, err)
-	setAPIKeyForReq(req, key, admin.Username)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	setJWTCookieForReq(req, webToken)
 	rr = executeRequest(req)
 	checkResponseCode(t, http.StatusOK, rr)
-
-	req, err = http.NewRequest(http.MethodGet, versionPath, nil)
-	assert.NoError(t, err)
-	setAPIKeyForReq(req, key, admin.Username+"1")
-	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusUnauthorized, rr)
-
-	req, err = http.NewRequest(http.MethodGet, versionPath, nil)
-	assert.NoError(t, err)
-	setAPIKeyForReq(req, key, "")
-	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusUnauthorized, rr)
-
-	// now associate the key directly to the admin
-	apiKey.Admin = admin.Username
-	apiKey.Description = "test description"
-	asJSON, err = json.Marshal(apiKey)
-	assert.NoError(t, err)
-	req, err = http.NewRequest(http.MethodPut, location, bytes.NewBuffer(asJSON))
-	assert.NoError(t, err)
-	setBearerForReq(req, token)
-	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusOK, rr)
-
-	req, err = http.NewRequest(http.MethodGet, apiKeysPath, nil)
+	assert.Contains(t, rr.Body.String(), "invalid syntax")
+	form.Set("max_tokens", "2")
+	form.Set("scope", "a")
+	req, err = http.NewRequest(http.MethodPost, webClientSharePath, bytes.NewBuffer([]byte(form.Encode())))
 	assert.NoError(t, err)
-	setBearerForReq(req, token)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	setJWTCookieForReq(req, webToken)
 	rr = executeRequest(req)
 	checkResponseCode(t, http.StatusOK, rr)
-	var keys []dataprovider.APIKey
-	err = json.Unmarshal(rr.Body.Bytes(), &keys)
-	assert.NoError(t, err)
-	if assert.GreaterOrEqual(t, len(keys), 1) {
-		found := false
-		for _, k := range keys {
-			if k.KeyID == apiKey.KeyID {
-				found = true
-				assert.Empty(t, k.Key)
-				assert.Equal(t, apiKey.Scope, k.Scope)
-				assert.Equal(t, apiKey.Name, k.Name)
-				assert.Equal(t, int64(0), k.ExpiresAt)
-				assert.Greater(t, k.LastUseAt, int64(0))
-				assert.Equal(t, k.CreatedAt, keyGet.CreatedAt)
-				assert.Greater(t, k.UpdatedAt, keyGet.UpdatedAt)
-				assert.Equal(t, apiKey.Description, k.Description)
-				assert.Empty(t, k.User)
-				assert.Equal(t, admin.Username, k.Admin)
-			}
-		}
-		assert.True(t, found)
-	}
-	req, err = http.NewRequest(http.MethodGet, versionPath, nil)
+	assert.Contains(t, rr.Body.String(), "invalid syntax")
+	form.Set("scope", "1")
+	form.Set("expiration_date", "invalid date")
+	req, err = http.NewRequest(http.MethodPost, webClientSharePath, bytes.NewBuffer([]byte(form.Encode())))
 	assert.NoError(t, err)
-	setAPIKeyForReq(req, key, "")
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	setJWTCookieForReq(req, webToken)
 	rr = executeRequest(req)
 	checkResponseCode(t, http.StatusOK, rr)
-	// invalid API keys
-	req, err = http.NewRequest(http.MethodGet, versionPath, nil)
-	assert.NoError(t, err)
-	setAPIKeyForReq(req, key+"invalid", "")
-	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusUnauthorized, rr)
-	assert.Contains(t, rr.Body.String(), "the provided api key cannot be authenticated")
-	req, err = http.NewRequest(http.MethodGet, versionPath, nil)
-	assert.NoError(t, err)
-	setAPIKeyForReq(req, "invalid", "")
-	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusBadRequest, rr)
-	// using an API key we cannot modify/get API keys
-	req, err = http.NewRequest(http.MethodPut, location, bytes.NewBuffer(asJSON))
+	assert.Contains(t, rr.Body.String(), "cannot parse")
+	form.Set("expiration_date", time.Now().Add(1*time.Hour).UTC().Format("2006-01-02 15:04:05"))
+	// missing CSRF token
+	req, err = http.NewRequest(http.MethodPost, webClientSharePath, bytes.NewBuffer([]byte(form.Encode())))
 	assert.NoError(t, err)
-	setAPIKeyForReq(req, key, "")
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	setJWTCookieForReq(req, webToken)
 	rr = executeRequest(req)
 	checkResponseCode(t, http.StatusForbidden, rr)
-
-	req, err = http.NewRequest(http.MethodGet, location, nil)
+	form.Set(csrfFormToken, csrfToken)
+	req, err = http.NewRequest(http.MethodPost, webClientSharePath, bytes.NewBuffer([]byte(form.Encode())))
 	assert.NoError(t, err)
-	setAPIKeyForReq(req, key, "")
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	setJWTCookieForReq(req, webToken)
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusForbidden, rr)
+	checkResponseCode(t, http.StatusSeeOther, rr)
 
-	admin.Filters.AllowList = []string{"172.16.18.0/24"}
-	admin, _, err = httpdtest.UpdateAdmin(admin, http.StatusOK)
-	assert.NoError(t, err)
-	req, err = http.NewRequest(http.MethodGet, versionPath, nil)
+	shares, err := dataprovider.GetShares(10, 0, dataprovider.OrderASC, defaultUsername)
 	assert.NoError(t, err)
-	setAPIKeyForReq(req, key, "")
-	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusUnauthorized, rr)
+	if assert.Len(t, shares, 1) {
+		share := shares[0]
+		assert.Equal(t, "web share", share.Name)
+		assert.Equal(t, 2, share.MaxTokens)
+		assert.Len(t, share.Paths, 2)
+		assert.Greater(t, share.ExpiresAt, int64(0))
+		assert.True(t, share.HasRedactedPassword())
+		share, err = dataprovider.ShareExists(share.ShareID, defaultUsername)
+		assert.NoError(t, err)
+		match, err := share.CheckPassword(defaultPassword)
+		assert.True(t, match)
+		assert.NoError(t, err)
 
-	req, err = http.NewRequest(http.MethodDelete, location, nil)
-	assert.NoError(t, err)
-	setBearerForReq(req, token)
-	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusOK, rr)
+		req, err = http.NewRequest(http.MethodGet, webClientSharePath+"/"+share.ShareID, nil)
+		assert.NoError(t, err)
+		setJWTCookieForReq(req, webToken)
+		rr = executeRequest(req)
+		checkResponseCode(t, http.StatusOK, rr)
 
-	req, err = http.NewRequest(http.MethodGet, versionPath, nil)
-	assert.NoError(t, err)
-	setAPIKeyForReq(req, key, "")
-	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusBadRequest, rr)
-	assert.Contains(t, rr.Body.String(), "the provided api key is not valid")
+		req, err = http.NewRequest(http.MethodGet, webClientSharePath+"/unknown", nil)
+		assert.NoError(t, err)
+		setJWTCookieForReq(req, webToken)
+		rr = executeRequest(req)
+		checkResponseCode(t, http.StatusNotFound, rr)
 
-	_, err = httpdtest.RemoveAdmin(admin, http.StatusOK)
-	assert.NoError(t, err)
-}
+		form.Set("name", "updated web share")
+		form.Set("password", "[**redacted**]")
+		req, err = http.NewRequest(http.MethodPost, webClientSharePath+"/"+share.ShareID,
+			bytes.NewBuffer([]byte(form.Encode())))
+		assert.NoError(t, err)
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		setJWTCookieForReq(req, webToken)
+		rr = executeRequest(req)
+		checkResponseCode(t, http.StatusSeeOther, rr)
 
-func TestAPIKeySearch(t *testing.T) {
-	token, err := getJWTAPITokenFromTestServer(defaultTokenAuthUser, defaultTokenAuthPass)
-	assert.NoError(t, err)
-	apiKey := dataprovider.APIKey{
-		Scope: dataprovider.APIKeyScopeAdmin,
-	}
-	for i := 1; i < 5; i++ {
-		apiKey.Name = fmt.Sprintf("testapikey%v", i)
-		asJSON, err := json.Marshal(apiKey)
+		share, err = dataprovider.ShareExists(share.ShareID, defaultUsername)
 		assert.NoError(t, err)
-		req, err := http.NewRequest(http.MethodPost, apiKeysPath, bytes.NewBuffer(asJSON))
+		assert.Equal(t, "updated web share", share.Name)
+		match, err = share.CheckPassword(defaultPassword)
+		assert.True(t, match)
 		assert.NoError(t, err)
-		setBearerForReq(req, token)
-		rr := executeRequest(req)
-		checkResponseCode(t, http.StatusCreated, rr)
+
+		req, err = http.NewRequest(http.MethodDelete, webClientSharePath+"/"+share.ShareID, nil)
+		assert.NoError(t, err)
+		setJWTCookieForReq(req, webToken)
+		rr = executeRequest(req)
+		checkResponseCode(t, http.StatusForbidden, rr)
+
+		req, err = http.NewRequest(http.MethodDelete, webClientSharePath+"/"+share.ShareID, nil)
+		assert.NoError(t, err)
+		setJWTCookieForReq(req, webToken)
+		setCSRFHeaderForReq(req, csrfToken)
+		rr = executeRequest(req)
+		checkResponseCode(t, http.StatusOK, rr)
 	}
 
-	req, err := http.NewRequest(http.MethodGet, apiKeysPath+"?limit=1&order=ASC", nil)
+	_, err = httpdtest.RemoveUser(user, http.StatusOK)
 	assert.NoError(t, err)
-	setBearerForReq(req, token)
-	rr := executeRequest(req)
-	checkResponseCode(t, http.StatusOK, rr)
-	var keys []dataprovider.APIKey
-	err = json.Unmarshal(rr.Body.Bytes(), &keys)
+	err = os.RemoveAll(user.GetHomeDir())
 	assert.NoError(t, err)
-	assert.Len(t, keys, 1)
-	firstKey := keys[0]
+}
 
-	req, err = http.NewRequest(http.MethodGet, apiKeysPath+"?limit=1&order=DESC", nil)
+func TestWebDirsAPI(t *testing.T) {
+	user, _, err := httpdtest.AddUser(getTestUser(), http.StatusCreated)
 	assert.NoError(t, err)
-	setBearerForReq(req, token)
-	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusOK, rr)
-	keys = nil
-	err = json.Unmarshal(rr.Body.Bytes(), &keys)
+	webAPIToken, err := getJWTAPIUserTokenFromTestServer(defaultUsername, defaultPassword)
 	assert.NoError(t, err)
-	if assert.Len(t, keys, 1) {
-		assert.NotEqual(t, firstKey.KeyID, keys[0].KeyID)
-	}
+	testDir := "testdir"
 
-	req, err = http.NewRequest(http.MethodGet, apiKeysPath+"?limit=1&offset=100", nil)
+	req, err := http.NewRequest(http.MethodGet, userDirsPath, nil)
 	assert.NoError(t, err)
-	setBearerForReq(req, token)
-	rr = executeRequest(req)
+	setBearerForReq(req, webAPIToken)
+	rr := executeRequest(req)
 	checkResponseCode(t, http.StatusOK, rr)
-	keys = nil
-	err = json.Unmarshal(rr.Body.Bytes(), &keys)
+	var contents []map[string]interface{}
+	err = json.NewDecoder(rr.Body).Decode(&contents)
 	assert.NoError(t, err)
-	assert.Len(t, keys, 0)
+	assert.Len(t, contents, 0)
 
-	req, err = http.NewRequest(http.MethodGet, apiKeysPath+"?limit=a", nil)
+	// rename a missing folder
+	req, err = http.NewRequest(http.MethodPatch, userDirsPath+"?path="+testDir+"&target="+testDir+"new", nil)
 	assert.NoError(t, err)
-	setBearerForReq(req, token)
+	setBearerForReq(req, webAPIToken)
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusBadRequest, rr)
-
-	req, err = http.NewRequest(http.MethodGet, fmt.Sprintf("%v/%v", apiKeysPath, "missingid"), nil)
+	checkResponseCode(t, http.StatusNotFound, rr)
+	// delete a missing folder
+	req, err = http.NewRequest(http.MethodDelete, userDirsPath+"?path="+testDir, nil)
 	assert.NoError(t, err)
-	setBearerForReq(req, token)
+	setBearerForReq(req, webAPIToken)
 	rr = executeRequest(req)
 	checkResponseCode(t, http.StatusNotFound, rr)
-
-	req, err = http.NewRequest(http.MethodGet, apiKeysPath, nil)
+	// create a dir
+	req, err = http.NewRequest(http.MethodPost, userDirsPath+"?path="+testDir, nil)
 	assert.NoError(t, err)
-	setBearerForReq(req, token)
+	setBearerForReq(req, webAPIToken)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusCreated, rr)
+	// check the dir was created
+	req, err = http.NewRequest(http.MethodGet, userDirsPath, nil)
+	assert.NoError(t, err)
+	setBearerForReq(req, webAPIToken)
 	rr = executeRequest(req)
 	checkResponseCode(t, http.StatusOK, rr)
-	keys = nil
-	err = json.Unmarshal(rr.Body.Bytes(), &keys)
+	contents = nil
+	err = json.NewDecoder(rr.Body).Decode(&contents)
 	assert.NoError(t, err)
-	counter := 0
-	for _, k := range keys {
-		if strings.HasPrefix(k.Name, "testapikey") {
-			req, err = http.NewRequest(http.MethodDelete, fmt.Sprintf("%v/%v", apiKeysPath, k.KeyID), nil)
-			assert.NoError(t, err)
-			setBearerForReq(req, token)
-			rr = executeRequest(req)
-			checkResponseCode(t, http.StatusOK, rr)
-			counter++
-		}
+	if assert.Len(t, contents, 1) {
+		assert.Equal(t, testDir, contents[0]["name"])
 	}
-	assert.Equal(t, 4, counter)
-}
-
-func TestAPIKeyErrors(t *testing.T) {
-	token, err := getJWTAPITokenFromTestServer(defaultTokenAuthUser, defaultTokenAuthPass)
+	// rename the dir
+	req, err = http.NewRequest(http.MethodPatch, userDirsPath+"?path="+testDir+"&target="+testDir+"new", nil)
 	assert.NoError(t, err)
-	apiKey := dataprovider.APIKey{
-		Name:  "testkey",
-		Scope: dataprovider.APIKeyScopeUser,
-	}
-	asJSON, err := json.Marshal(apiKey)
+	setBearerForReq(req, webAPIToken)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
+	// delete the dir
+	req, err = http.NewRequest(http.MethodDelete, userDirsPath+"?path="+testDir+"new", nil)
 	assert.NoError(t, err)
-	req, err := http.NewRequest(http.MethodPost, apiKeysPath, bytes.NewBuffer(asJSON))
+	setBearerForReq(req, webAPIToken)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
+	// the root dir cannot be created
+	req, err = http.NewRequest(http.MethodPost, userDirsPath, nil)
 	assert.NoError(t, err)
-	setBearerForReq(req, token)
-	rr := executeRequest(req)
-	checkResponseCode(t, http.StatusCreated, rr)
-	location := rr.Header().Get("Location")
-	assert.NotEmpty(t, location)
+	setBearerForReq(req, webAPIToken)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusInternalServerError, rr)
 
-	// invalid API scope
-	apiKey.Scope = 1000
-	asJSON, err = json.Marshal(apiKey)
+	user.Permissions["/"] = []string{dataprovider.PermListItems}
+	user, _, err = httpdtest.UpdateUser(user, http.StatusOK, "")
 	assert.NoError(t, err)
-	req, err = http.NewRequest(http.MethodPost, apiKeysPath, bytes.NewBuffer(asJSON))
+	// the user has no more the permission to create the directory
+	req, err = http.NewRequest(http.MethodPost, userDirsPath+"?path="+testDir, nil)
 	assert.NoError(t, err)
-	setBearerForReq(req, token)
+	setBearerForReq(req, webAPIToken)
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusBadRequest, rr)
+	checkResponseCode(t, http.StatusForbidden, rr)
 
-	req, err = http.NewRequest(http.MethodPut, location, bytes.NewBuffer(asJSON))
-	assert.NoError(t, err)
-	setBearerForReq(req, token)
-	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusBadRequest, rr)
-
-	// invalid JSON
-	req, err = http.NewRequest(http.MethodPost, apiKeysPath, bytes.NewBuffer([]byte(`invalid JSON`)))
+	_, err = httpdtest.RemoveUser(user, http.StatusOK)
 	assert.NoError(t, err)
-	setBearerForReq(req, token)
-	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusBadRequest, rr)
-
-	req, err = http.NewRequest(http.MethodPut, location, bytes.NewBuffer([]byte(`invalid JSON`)))
+	err = os.RemoveAll(user.GetHomeDir())
 	assert.NoError(t, err)
-	setBearerForReq(req, token)
-	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusBadRequest, rr)
 
-	req, err = http.NewRequest(http.MethodDelete, location, nil)
+	// the user is deleted, any API call should fail
+	req, err = http.NewRequest(http.MethodPost, userDirsPath+"?path="+testDir, nil)
 	assert.NoError(t, err)
-	setBearerForReq(req, token)
+	setBearerForReq(req, webAPIToken)
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusOK, rr)
+	checkResponseCode(t, http.StatusNotFound, rr)
 
-	req, err = http.NewRequest(http.MethodDelete, location, nil)
+	req, err = http.NewRequest(http.MethodPatch, userDirsPath+"?path="+testDir+"&target="+testDir+"new", nil)
 	assert.NoError(t, err)
-	setBearerForReq(req, token)
+	setBearerForReq(req, webAPIToken)
 	rr = executeRequest(req)
 	checkResponseCode(t, http.StatusNotFound, rr)
 
-	req, err = http.NewRequest(http.MethodPut, location, bytes.NewBuffer(asJSON))
+	req, err = http.NewRequest(http.MethodDelete, userDirsPath+"?path="+testDir+"new", nil)
 	assert.NoError(t, err)
-	setBearerForReq(req, token)
+	setBearerForReq(req, webAPIToken)
 	rr = executeRequest(req)
 	checkResponseCode(t, http.StatusNotFound, rr)
 }
 
-func TestAPIKeyOnDeleteCascade(t *testing.T) {
+func TestWebFilesAPI(t *testing.T) {
 	user, _, err := httpdtest.AddUser(getTestUser(), http.StatusCreated)
 	assert.NoError(t, err)
-	admin := getTestAdmin()
-	admin.Username = altAdminUsername
-	admin.Password = altAdminPassword
-	admin, _, err = httpdtest.AddAdmin(admin, http.StatusCreated)
+	webAPIToken, err := getJWTAPIUserTokenFromTestServer(defaultUsername, defaultPassword)
 	assert.NoError(t, err)
 
-	apiKey := dataprovider.APIKey{
-		Name:  "user api key",
-		Scope: dataprovider.APIKeyScopeUser,
-		User:  user.Username,
-	}
-
-	apiKey, _, err = httpdtest.AddAPIKey(apiKey, http.StatusCreated)
+	body := new(bytes.Buffer)
+	writer := multipart.NewWriter(body)
+	part1, err := writer.CreateFormFile("filename", "file1.txt")
+	assert.NoError(t, err)
+	_, err = part1.Write([]byte("file1 content"))
+	assert.NoError(t, err)
+	part2, err := writer.CreateFormFile("filename", "file2.txt")
+	assert.NoError(t, err)
+	_, err = part2.Write([]byte("file2 content"))
+	assert.NoError(t, err)
+	err = writer.Close()
 	assert.NoError(t, err)
+	reader := bytes.NewReader(body.Bytes())
 
-	req, err := http.NewRequest(http.MethodGet, userDirsPath, nil)
+	req, err := http.NewRequest(http.MethodPost, userFilesPath, reader)
 	assert.NoError(t, err)
-	setAPIKeyForReq(req, apiKey.Key, "")
+	setBearerForReq(req, webAPIToken)
 	rr := executeRequest(req)
-	checkResponseCode(t, http.StatusUnauthorized, rr)
-
-	user.Filters.AllowAPIKeyAuth = true
-	user, _, err = httpdtest.UpdateUser(user, http.StatusOK, "")
+	checkResponseCode(t, http.StatusBadRequest, rr)
+	assert.Contains(t, rr.Body.String(), "Unable to parse multipart form")
+	_, err = reader.Seek(0, io.SeekStart)
+	assert.NoError(t, err)
+	// set the proper content type
+	req, err = http.NewRequest(http.MethodPost, userFilesPath, reader)
 	assert.NoError(t, err)
+	req.Header.Add("Content-Type", writer.FormDataContentType())
+	setBearerForReq(req, webAPIToken)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusCreated, rr)
+	// check we have 2 files
 	req, err = http.NewRequest(http.MethodGet, userDirsPath, nil)
 	assert.NoError(t, err)
-	setAPIKeyForReq(req, apiKey.Key, "")
+	setBearerForReq(req, webAPIToken)
 	rr = executeRequest(req)
 	checkResponseCode(t, http.StatusOK, rr)
 	var contents []map[string]interface{}
 	err = json.NewDecoder(rr.Body).Decode(&contents)
 	assert.NoError(t, err)
-	assert.Len(t, contents, 0)
-
-	_, err = httpdtest.RemoveUser(user, http.StatusOK)
-	assert.NoError(t, err)
-	err = os.RemoveAll(user.GetHomeDir())
-	assert.NoError(t, err)
-
-	_, _, err = httpdtest.GetAPIKeyByID(apiKey.KeyID, http.StatusNotFound)
-	assert.NoError(t, err)
-
-	apiKey.User = ""
-	apiKey.Admin = admin.Username
-	apiKey.Scope = dataprovider.APIKeyScopeAdmin
-
-	apiKey, _, err = httpdtest.AddAPIKey(apiKey, http.StatusCreated)
+	assert.Len(t, contents, 2)
+	// overwrite the existing files
+	_, err = reader.Seek(0, io.SeekStart)
 	assert.NoError(t, err)
-
-	_, err = httpdtest.RemoveAdmin(admin, http.StatusOK)
+	req, err = http.NewRequest(http.MethodPost, userFilesPath, reader)
 	assert.NoError(t, err)
-
-	_, _, err = httpdtest.GetAPIKeyByID(apiKey.KeyID, http.StatusNotFound)
+	req.Header.Add("Content-Type", writer.FormDataContentType())
+	setBearerForReq(req, webAPIToken)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusCreated, rr)
+	req, err = http.NewRequest(http.MethodGet, userDirsPath, nil)
 	assert.NoError(t, err)
-}
-
-func TestBasicWebUsersMock(t *testing.T) {
-	token, err := getJWTAPITokenFromTestServer(defaultTokenAuthUser, defaultTokenAuthPass)
+	setBearerForReq(req, webAPIToken)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
+	contents = nil
+	err = json.NewDecoder(rr.Body).Decode(&contents)
 	assert.NoError(t, err)
-	user := getTestUser()
-	userAsJSON := getUserAsJSON(t, user)
-	req, _ := http.NewRequest(http.MethodPost, userPath, bytes.NewBuffer(userAsJSON))
-	setBearerForReq(req, token)
-	rr := executeRequest(req)
-	checkResponseCode(t, http.StatusCreated, rr)
-	err = render.DecodeJSON(rr.Body, &user)
+	assert.Len(t, contents, 2)
+	// now create a dir and upload to that dir
+	testDir := "tdir"
+	req, err = http.NewRequest(http.MethodPost, userDirsPath+"?path="+testDir, nil)
 	assert.NoError(t, err)
-	user1 := getTestUser()
-	user1.Username += "1"
-	user1AsJSON := getUserAsJSON(t, user1)
-	req, _ = http.NewRequest(http.MethodPost, userPath, bytes.NewBuffer(user1AsJSON))
-	setBearerForReq(req, token)
+	setBearerForReq(req, webAPIToken)
 	rr = executeRequest(req)
 	checkResponseCode(t, http.StatusCreated, rr)
-	err = render.DecodeJSON(rr.Body, &user1)
+	_, err = reader.Seek(0, io.SeekStart)
 	assert.NoError(t, err)
-	webToken, err := getJWTWebTokenFromTestServer(defaultTokenAuthUser, defaultTokenAuthPass)
+	req, err = http.NewRequest(http.MethodPost, userFilesPath+"?path="+testDir, reader)
 	assert.NoError(t, err)
-	req, _ = http.NewRequest(http.MethodGet, webUsersPath, nil)
-	setJWTCookieForReq(req, webToken)
-	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusOK, rr)
-	req, _ = http.NewRequest(http.MethodGet, webUsersPath+"?qlimit=a", nil)
-	setJWTCookieForReq(req, webToken)
+	req.Header.Add("Content-Type", writer.FormDataContentType())
+	setBearerForReq(req, webAPIToken)
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusOK, rr)
-	req, _ = http.NewRequest(http.MethodGet, webUsersPath+"?qlimit=1", nil)
-	setJWTCookieForReq(req, webToken)
+	checkResponseCode(t, http.StatusCreated, rr)
+	req, err = http.NewRequest(http.MethodGet, userDirsPath, nil)
+	assert.NoError(t, err)
+	setBearerForReq(req, webAPIToken)
 	rr = executeRequest(req)
 	checkResponseCode(t, http.StatusOK, rr)
-	req, _ = http.NewRequest(http.MethodGet, webUserPath, nil)
-	setJWTCookieForReq(req, webToken)
+	contents = nil
+	err = json.NewDecoder(rr.Body).Decode(&contents)
+	assert.NoError(t, err)
+	assert.Len(t, contents, 3)
+	req, err = http.NewRequest(http.MethodGet, userDirsPath+"?path="+testDir, nil)
+	assert.NoError(t, err)
+	setBearerForReq(req, webAPIToken)
 	rr = executeRequest(req)
 	checkResponseCode(t, http.StatusOK, rr)
-	req, _ = http.NewRequest(http.MethodGet, path.Join(webUserPath, user.Username), nil)
-	setJWTCookieForReq(req, webToken)
+	contents = nil
+	err = json.NewDecoder(rr.Body).Decode(&contents)
+	assert.NoError(t, err)
+	assert.Len(t, contents, 2)
+	// rename a file
+	req, err = http.NewRequest(http.MethodPatch, userFilesPath+"?path=file1.txt&target=%2Ftdir%2Ffile3.txt", nil)
+	assert.NoError(t, err)
+	setBearerForReq(req, webAPIToken)
 	rr = executeRequest(req)
 	checkResponseCode(t, http.StatusOK, rr)
-	req, _ = http.NewRequest(http.MethodGet, webUserPath+"/0", nil)
-	setJWTCookieForReq(req, webToken)
+	// rename a missing file
+	req, err = http.NewRequest(http.MethodPatch, userFilesPath+"?path=file1.txt&target=%2Ftdir%2Ffile3.txt", nil)
+	assert.NoError(t, err)
+	setBearerForReq(req, webAPIToken)
 	rr = executeRequest(req)
 	checkResponseCode(t, http.StatusNotFound, rr)
-	csrfToken, err := getCSRFToken(httpBaseURL + webLoginPath)
+	// delete a file
+	req, err = http.NewRequest(http.MethodDelete, userFilesPath+"?path=file2.txt", nil)
 	assert.NoError(t, err)
-	form := make(url.Values)
-	form.Set("username", user.Username)
-	form.Set(csrfFormToken, csrfToken)
-	b, contentType, _ := getMultipartFormData(form, "", "")
-	req, _ = http.NewRequest(http.MethodPost, webUserPath, &b)
-	setJWTCookieForReq(req, webToken)
-	req.Header.Set("Content-Type", contentType)
-	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusOK, rr)
-	b, contentType, _ = getMultipartFormData(form, "", "")
-	req, _ = http.NewRequest(http.MethodPost, path.Join(webUserPath, user.Username), &b)
-	setJWTCookieForReq(req, webToken)
-	req.Header.Set("Content-Type", contentType)
+	setBearerForReq(req, webAPIToken)
 	rr = executeRequest(req)
 	checkResponseCode(t, http.StatusOK, rr)
-	b, contentType, _ = getMultipartFormData(form, "", "")
-	req, _ = http.NewRequest(http.MethodPost, webUserPath+"/0", &b)
-	setJWTCookieForReq(req, webToken)
-	req.Header.Set("Content-Type", contentType)
+	// delete a missing file
+	req, err = http.NewRequest(http.MethodDelete, userFilesPath+"?path=file2.txt", nil)
+	assert.NoError(t, err)
+	setBearerForReq(req, webAPIToken)
 	rr = executeRequest(req)
 	checkResponseCode(t, http.StatusNotFound, rr)
-	req, _ = http.NewRequest(http.MethodPost, webUserPath+"/aaa", &b)
-	setJWTCookieForReq(req, webToken)
-	req.Header.Set("Content-Type", contentType)
+	// delete a directory
+	req, err = http.NewRequest(http.MethodDelete, userFilesPath+"?path=tdir", nil)
+	assert.NoError(t, err)
+	setBearerForReq(req, webAPIToken)
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusNotFound, rr)
-	req, _ = http.NewRequest(http.MethodDelete, path.Join(webUserPath, user.Username), nil)
-	setJWTCookieForReq(req, webToken)
+	checkResponseCode(t, http.StatusBadRequest, rr)
+	// make a symlink outside the home dir and then try to delete it
+	extPath := filepath.Join(os.TempDir(), "file")
+	err = os.WriteFile(extPath, []byte("contents"), os.ModePerm)
+	assert.NoError(t, err)
+	err = os.Symlink(extPath, filepath.Join(user.GetHomeDir(), "file"))
+	assert.NoError(t, err)
+	req, err = http.NewRequest(http.MethodDelete, userFilesPath+"?path=file", nil)
+	assert.NoError(t, err)
+	setBearerForReq(req, webAPIToken)
 	rr = executeRequest(req)
 	checkResponseCode(t, http.StatusForbidden, rr)
-	assert.Contains(t, rr.Body.String(), "Invalid token")
-	req, _ = http.NewRequest(http.MethodDelete, path.Join(webUserPath, user.Username), nil)
-	setJWTCookieForReq(req, webToken)
-	setCSRFHeaderForReq(req, csrfToken)
+	err = os.Remove(extPath)
+	assert.NoError(t, err)
+	// remove delete and overwrite permissions
+	user.Permissions["/"] = []string{dataprovider.PermListItems, dataprovider.PermDownload, dataprovider.PermUpload}
+	user, _, err = httpdtest.UpdateUser(user, http.StatusOK, "")
+	assert.NoError(t, err)
+	_, err = reader.Seek(0, io.SeekStart)
+	assert.NoError(t, err)
+	req, err = http.NewRequest(http.MethodPost, userFilesPath+"?path=tdir", reader)
+	assert.NoError(t, err)
+	req.Header.Add("Content-Type", writer.FormDataContentType())
+	setBearerForReq(req, webAPIToken)
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusOK, rr)
-	req, _ = http.NewRequest(http.MethodDelete, path.Join(webUserPath, user1.Username), nil)
-	setJWTCookieForReq(req, webToken)
-	setCSRFHeaderForReq(req, csrfToken)
+	checkResponseCode(t, http.StatusForbidden, rr)
+
+	req, err = http.NewRequest(http.MethodDelete, userFilesPath+"?path=%2Ftdir%2Ffile1.txt", nil)
+	assert.NoError(t, err)
+	setBearerForReq(req, webAPIToken)
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusOK, rr)
-}
+	checkResponseCode(t, http.StatusForbidden, rr)
 
-func TestRenderDefenderPageMock(t *testing.T) {
-	token, err := getJWTWebTokenFromTestServer(defaultTokenAuthUser, defaultTokenAuthPass)
+	_, err = httpdtest.RemoveUser(user, http.StatusOK)
 	assert.NoError(t, err)
-	req, err := http.NewRequest(http.MethodGet, webDefenderPath, nil)
+	err = os.RemoveAll(user.GetHomeDir())
 	assert.NoError(t, err)
-	setJWTCookieForReq(req, token)
-	rr := executeRequest(req)
-	checkResponseCode(t, http.StatusOK, rr)
-	assert.Contains(t, rr.Body.String(), "View and manage blocklist")
-}
-
-func TestWebAdminBasicMock(t *testing.T) {
-	token, err := getJWTWebTokenFromTestServer(defaultTokenAuthUser, defaultTokenAuthPass)
+	// the user is deleted, any API call should fail
+	_, err = reader.Seek(0, io.SeekStart)
 	assert.NoError(t, err)
-	admin := getTestAdmin()
-	admin.Username = altAdminUsername
-	admin.Password = altAdminPassword
-	csrfToken, err := getCSRFToken(httpBaseURL + webLoginPath)
+	req, err = http.NewRequest(http.MethodPost, userFilesPath, reader)
 	assert.NoError(t, err)
-	form := make(url.Values)
-	form.Set("username", admin.Username)
-	form.Set("password", "")
-	form.Set("status", "1")
-	form.Set("permissions", "*")
-	form.Set("description", admin.Description)
-	req, _ := http.NewRequest(http.MethodPost, webAdminPath, bytes.NewBuffer([]byte(form.Encode())))
-	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
-	setJWTCookieForReq(req, token)
-	rr := executeRequest(req)
-	checkResponseCode(t, http.StatusForbidden, rr)
-	assert.Contains(t, rr.Body.String(), "unable to verify form token")
-
-	form.Set(csrfFormToken, csrfToken)
-	form.Set("status", "a")
-	req, _ = http.NewRequest(http.MethodPost, webAdminPath, bytes.NewBuffer([]byte(form.Encode())))
-	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
-	setJWTCookieForReq(req, token)
+	req.Header.Add("Content-Type", writer.FormDataContentType())
+	setBearerForReq(req, webAPIToken)
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusOK, rr)
+	checkResponseCode(t, http.StatusNotFound, rr)
 
-	form.Set("status", "1")
-	req, _ = http.NewRequest(http.MethodPost, webAdminPath, bytes.NewBuffer([]byte(form.Encode())))
-	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
-	setJWTCookieForReq(req, token)
+	req, err = http.NewRequest(http.MethodPatch, userFilesPath+"?path=file1.txt&target=%2Ftdir%2Ffile3.txt", nil)
+	assert.NoError(t, err)
+	setBearerForReq(req, webAPIToken)
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusOK, rr)
+	checkResponseCode(t, http.StatusNotFound, rr)
 
-	form.Set("password", admin.Password)
-	req, _ = http.NewRequest(http.MethodPost, webAdminPath, bytes.NewBuffer([]byte(form.Encode())))
-	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
-	setJWTCookieForReq(req, token)
+	req, err = http.NewRequest(http.MethodDelete, userFilesPath+"?path=file2.txt", nil)
+	assert.NoError(t, err)
+	setBearerForReq(req, webAPIToken)
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusSeeOther, rr)
+	checkResponseCode(t, http.StatusNotFound, rr)
+}
 
-	// add TOTP config
-	configName, _, secret, _, err := mfa.GenerateTOTPSecret(mfa.GetAvailableTOTPConfigNames()[0], altAdminUsername)
+func TestWebUploadErrors(t *testing.T) {
+	u := getTestUser()
+	u.QuotaSize = 65535
+	subDir1 := "sub1"
+	subDir2 := "sub2"
+	u.Permissions[path.Join("/", subDir1)] = []string{dataprovider.PermListItems}
+	u.Permissions[path.Join("/", subDir2)] = []string{dataprovider.PermListItems, dataprovider.PermUpload,
+		dataprovider.PermDelete}
+	u.Filters.FilePatterns = []sdk.PatternsFilter{
+		{
+			Path:            "/sub2",
+			AllowedPatterns: []string{},
+			DeniedPatterns:  []string{"*.zip"},
+		},
+	}
+	user, _, err := httpdtest.AddUser(u, http.StatusCreated)
 	assert.NoError(t, err)
-	altToken, err := getJWTWebTokenFromTestServer(altAdminUsername, altAdminPassword)
+	webAPIToken, err := getJWTAPIUserTokenFromTestServer(defaultUsername, defaultPassword)
 	assert.NoError(t, err)
-	adminTOTPConfig := dataprovider.TOTPConfig{
-		Enabled:    true,
-		ConfigName: configName,
-		Secret:     kms.NewPlainSecret(secret),
-	}
-	asJSON, err := json.Marshal(adminTOTPConfig)
+
+	body := new(bytes.Buffer)
+	writer := multipart.NewWriter(body)
+	part, err := writer.CreateFormFile("filename", "file.zip")
 	assert.NoError(t, err)
-	// no CSRF token
-	req, err = http.NewRequest(http.MethodPost, webAdminTOTPSavePath, bytes.NewBuffer(asJSON))
+	_, err = part.Write([]byte("file content"))
 	assert.NoError(t, err)
-	setJWTCookieForReq(req, altToken)
-	rr = executeRequest(req)
+	err = writer.Close()
+	assert.NoError(t, err)
+	reader := bytes.NewReader(body.Bytes())
+	_, err = reader.Seek(0, io.SeekStart)
+	assert.NoError(t, err)
+	// zip file are not allowed within sub2
+	req, err := http.NewRequest(http.MethodPost, userFilesPath+"?path=sub2", reader)
+	assert.NoError(t, err)
+	req.Header.Add("Content-Type", writer.FormDataContentType())
+	setBearerForReq(req, webAPIToken)
+	rr := executeRequest(req)
 	checkResponseCode(t, http.StatusForbidden, rr)
-	assert.Contains(t, rr.Body.String(), "Invalid token")
 
-	req, err = http.NewRequest(http.MethodPost, webAdminTOTPSavePath, bytes.NewBuffer(asJSON))
+	_, err = reader.Seek(0, io.SeekStart)
 	assert.NoError(t, err)
-	setJWTCookieForReq(req, altToken)
-	setCSRFHeaderForReq(req, csrfToken)
+	// we have no upload permissions within sub1
+	req, err = http.NewRequest(http.MethodPost, userFilesPath+"?path=sub1", reader)
+	assert.NoError(t, err)
+	req.Header.Add("Content-Type", writer.FormDataContentType())
+	setBearerForReq(req, webAPIToken)
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusOK, rr)
+	checkResponseCode(t, http.StatusForbidden, rr)
 
-	admin, _, err = httpdtest.GetAdminByUsername(altAdminUsername, http.StatusOK)
+	// create a dir and try to overwrite it with a file
+	req, err = http.NewRequest(http.MethodPost, userDirsPath+"?path=file.zip", nil)
 	assert.NoError(t, err)
-	assert.True(t, admin.Filters.TOTPConfig.Enabled)
-	secretPayload := admin.Filters.TOTPConfig.Secret.GetPayload()
-	assert.NotEmpty(t, secretPayload)
+	setBearerForReq(req, webAPIToken)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusCreated, rr)
 
-	adminTOTPConfig = dataprovider.TOTPConfig{
-		Enabled:    true,
-		ConfigName: configName,
-		Secret:     kms.NewEmptySecret(),
-	}
-	asJSON, err = json.Marshal(adminTOTPConfig)
+	_, err = reader.Seek(0, io.SeekStart)
 	assert.NoError(t, err)
-	req, err = http.NewRequest(http.MethodPost, webAdminTOTPSavePath, bytes.NewBuffer(asJSON))
+	req, err = http.NewRequest(http.MethodPost, userFilesPath, reader)
 	assert.NoError(t, err)
-	setJWTCookieForReq(req, altToken)
-	setCSRFHeaderForReq(req, csrfToken)
+	req.Header.Add("Content-Type", writer.FormDataContentType())
+	setBearerForReq(req, webAPIToken)
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusOK, rr)
-
-	admin, _, err = httpdtest.GetAdminByUsername(altAdminUsername, http.StatusOK)
-	assert.NoError(t, err)
-	assert.True(t, admin.Filters.TOTPConfig.Enabled)
-	assert.Equal(t, secretPayload, admin.Filters.TOTPConfig.Secret.GetPayload())
-
-	adminTOTPConfig = dataprovider.TOTPConfig{
-		Enabled:    true,
-		ConfigName: configName,
-		Secret:     nil,
-	}
-	asJSON, err = json.Marshal(adminTOTPConfig)
+	checkResponseCode(t, http.StatusInternalServerError, rr)
+	assert.Contains(t, rr.Body.String(), "operation unsupported")
+	// try to upload to a missing parent directory
+	_, err = reader.Seek(0, io.SeekStart)
 	assert.NoError(t, err)
-	req, err = http.NewRequest(http.MethodPost, webAdminTOTPSavePath, bytes.NewBuffer(asJSON))
+	req, err = http.NewRequest(http.MethodPost, userFilesPath+"?path=missingdir", reader)
 	assert.NoError(t, err)
-	setJWTCookieForReq(req, altToken)
-	setCSRFHeaderForReq(req, csrfToken)
+	req.Header.Add("Content-Type", writer.FormDataContentType())
+	setBearerForReq(req, webAPIToken)
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusOK, rr)
+	checkResponseCode(t, http.StatusNotFound, rr)
 
-	req, _ = http.NewRequest(http.MethodGet, webAdminsPath+"?qlimit=a", nil)
-	setJWTCookieForReq(req, token)
+	req, err = http.NewRequest(http.MethodDelete, userDirsPath+"?path=file.zip", nil)
+	assert.NoError(t, err)
+	setBearerForReq(req, webAPIToken)
 	rr = executeRequest(req)
 	checkResponseCode(t, http.StatusOK, rr)
-	req, _ = http.NewRequest(http.MethodGet, webAdminsPath+"?qlimit=1", nil)
-	setJWTCookieForReq(req, token)
+	// upload will work now
+	_, err = reader.Seek(0, io.SeekStart)
+	assert.NoError(t, err)
+	req, err = http.NewRequest(http.MethodPost, userFilesPath, reader)
+	assert.NoError(t, err)
+	req.Header.Add("Content-Type", writer.FormDataContentType())
+	setBearerForReq(req, webAPIToken)
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusOK, rr)
-
-	req, _ = http.NewRequest(http.MethodGet, webAdminPath, nil)
-	setJWTCookieForReq(req, token)
+	checkResponseCode(t, http.StatusCreated, rr)
+	// overwrite the file
+	_, err = reader.Seek(0, io.SeekStart)
+	assert.NoError(t, err)
+	req, err = http.NewRequest(http.MethodPost, userFilesPath, reader)
+	assert.NoError(t, err)
+	req.Header.Add("Content-Type", writer.FormDataContentType())
+	setBearerForReq(req, webAPIToken)
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusOK, rr)
+	checkResponseCode(t, http.StatusCreated, rr)
 
-	form.Set("password", "")
-	req, _ = http.NewRequest(http.MethodPost, path.Join(webAdminPath, altAdminUsername), bytes.NewBuffer([]byte(form.Encode())))
-	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
-	setJWTCookieForReq(req, token)
-	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusSeeOther, rr)
+	vfs.SetTempPath(filepath.Join(os.TempDir(), "missingpath"))
 
-	form.Set(csrfFormToken, "invalid csrf")
-	req, _ = http.NewRequest(http.MethodPost, path.Join(webAdminPath, altAdminUsername), bytes.NewBuffer([]byte(form.Encode())))
-	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
-	setJWTCookieForReq(req, token)
+	_, err = reader.Seek(0, io.SeekStart)
+	assert.NoError(t, err)
+	req, err = http.NewRequest(http.MethodPost, userFilesPath, reader)
+	assert.NoError(t, err)
+	req.Header.Add("Content-Type", writer.FormDataContentType())
+	setBearerForReq(req, webAPIToken)
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusForbidden, rr)
-	assert.Contains(t, rr.Body.String(), "unable to verify form token")
+	checkResponseCode(t, http.StatusNotFound, rr)
 
-	form.Set(csrfFormToken, csrfToken)
-	form.Set("email", "not-an-email")
-	req, _ = http.NewRequest(http.MethodPost, path.Join(webAdminPath, altAdminUsername), bytes.NewBuffer([]byte(form.Encode())))
-	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
-	setJWTCookieForReq(req, token)
-	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusOK, rr)
+	if runtime.GOOS != osWindows {
+		req, err = http.NewRequest(http.MethodDelete, userFilesPath+"?path=file.zip", reader)
+		assert.NoError(t, err)
+		setBearerForReq(req, webAPIToken)
+		rr = executeRequest(req)
+		checkResponseCode(t, http.StatusOK, rr)
 
-	form.Set("email", "")
-	form.Set("status", "b")
-	req, _ = http.NewRequest(http.MethodPost, path.Join(webAdminPath, altAdminUsername), bytes.NewBuffer([]byte(form.Encode())))
-	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
-	setJWTCookieForReq(req, token)
-	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusOK, rr)
+		vfs.SetTempPath(filepath.Clean(os.TempDir()))
+		err = os.Chmod(user.GetHomeDir(), 0555)
+		assert.NoError(t, err)
 
-	form.Set("email", "admin@example.com")
-	form.Set("status", "0")
-	req, _ = http.NewRequest(http.MethodPost, path.Join(webAdminPath, altAdminUsername), bytes.NewBuffer([]byte(form.Encode())))
-	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
-	setJWTCookieForReq(req, token)
-	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusSeeOther, rr)
+		_, err = reader.Seek(0, io.SeekStart)
+		assert.NoError(t, err)
+		req, err = http.NewRequest(http.MethodPost, userFilesPath, reader)
+		assert.NoError(t, err)
+		req.Header.Add("Content-Type", writer.FormDataContentType())
+		setBearerForReq(req, webAPIToken)
+		rr = executeRequest(req)
+		checkResponseCode(t, http.StatusForbidden, rr)
+		assert.Contains(t, rr.Body.String(), "Error closing file")
 
-	admin, _, err = httpdtest.GetAdminByUsername(altAdminUsername, http.StatusOK)
-	assert.NoError(t, err)
-	assert.True(t, admin.Filters.TOTPConfig.Enabled)
-	assert.Equal(t, "admin@example.com", admin.Email)
-	assert.Equal(t, 0, admin.Status)
+		err = os.Chmod(user.GetHomeDir(), os.ModePerm)
+		assert.NoError(t, err)
+	}
 
-	req, _ = http.NewRequest(http.MethodPost, path.Join(webAdminPath, altAdminUsername+"1"), bytes.NewBuffer([]byte(form.Encode())))
-	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
-	setJWTCookieForReq(req, token)
-	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusNotFound, rr)
-
-	req, _ = http.NewRequest(http.MethodGet, path.Join(webAdminPath, altAdminUsername), nil)
-	setJWTCookieForReq(req, token)
-	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusOK, rr)
-
-	req, _ = http.NewRequest(http.MethodGet, path.Join(webAdminPath, altAdminUsername+"1"), nil)
-	setJWTCookieForReq(req, token)
-	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusNotFound, rr)
-
-	req, _ = http.NewRequest(http.MethodDelete, path.Join(webAdminPath, altAdminUsername), nil)
-	setJWTCookieForReq(req, token)
-	setCSRFHeaderForReq(req, csrfToken)
-	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusOK, rr)
+	vfs.SetTempPath("")
 
-	_, err = httpdtest.RemoveAdmin(admin, http.StatusNotFound)
+	// upload a multipart form with no files
+	body = new(bytes.Buffer)
+	writer = multipart.NewWriter(body)
+	err = writer.Close()
 	assert.NoError(t, err)
-
-	req, _ = http.NewRequest(http.MethodDelete, path.Join(webAdminPath, defaultTokenAuthUser), nil)
-	setJWTCookieForReq(req, token)
-	setCSRFHeaderForReq(req, csrfToken)
+	reader = bytes.NewReader(body.Bytes())
+	_, err = reader.Seek(0, io.SeekStart)
+	assert.NoError(t, err)
+	req, err = http.NewRequest(http.MethodPost, userFilesPath+"?path=sub2", reader)
+	assert.NoError(t, err)
+	req.Header.Add("Content-Type", writer.FormDataContentType())
+	setBearerForReq(req, webAPIToken)
 	rr = executeRequest(req)
 	checkResponseCode(t, http.StatusBadRequest, rr)
-	assert.Contains(t, rr.Body.String(), "you cannot delete yourself")
+	assert.Contains(t, rr.Body.String(), "No files uploaded!")
 
-	req, _ = http.NewRequest(http.MethodDelete, path.Join(webAdminPath, defaultTokenAuthUser), nil)
-	setJWTCookieForReq(req, token)
-	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusForbidden, rr)
-	assert.Contains(t, rr.Body.String(), "Invalid token")
+	_, err = httpdtest.RemoveUser(user, http.StatusOK)
+	assert.NoError(t, err)
+	err = os.RemoveAll(user.GetHomeDir())
+	assert.NoError(t, err)
 }
 
-func TestWebAdminPermissions(t *testing.T) {
-	admin := getTestAdmin()
-	admin.Username = altAdminUsername
-	admin.Password = altAdminPassword
-	admin.Permissions = []string{dataprovider.PermAdminAddUsers}
-	_, _, err := httpdtest.AddAdmin(admin, http.StatusCreated)
+func TestWebAPIVFolder(t *testing.T) {
+	u := getTestUser()
+	u.QuotaSize = 65535
+	vdir := "/vdir"
+	mappedPath := filepath.Join(os.TempDir(), "vdir")
+	folderName := filepath.Base(mappedPath)
+	u.VirtualFolders = append(u.VirtualFolders, vfs.VirtualFolder{
+		BaseVirtualFolder: vfs.BaseVirtualFolder{
+			Name:       folderName,
+			MappedPath: mappedPath,
+		},
+		VirtualPath: vdir,
+		QuotaSize:   -1,
+		QuotaFiles:  -1,
+	})
+	user, _, err := httpdtest.AddUser(u, http.StatusCreated)
 	assert.NoError(t, err)
 
-	token, err := getJWTWebToken(altAdminUsername, altAdminPassword)
-	require.NoError(t, err)
+	webAPIToken, err := getJWTAPIUserTokenFromTestServer(user.Username, defaultPassword)
+	assert.NoError(t, err)
 
-	req, err := http.NewRequest(http.MethodGet, httpBaseURL+webUserPath, nil)
+	fileContents := []byte("test contents")
+
+	body := new(bytes.Buffer)
+	writer := multipart.NewWriter(body)
+	part, err := writer.CreateFormFile("filename", "file.txt")
 	assert.NoError(t, err)
-	setJWTCookieForReq(req, token)
-	resp, err := httpclient.GetHTTPClient().Do(req)
-	require.NoError(t, err)
-	defer resp.Body.Close()
-	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	_, err = part.Write(fileContents)
+	assert.NoError(t, err)
+	err = writer.Close()
+	assert.NoError(t, err)
+	reader := bytes.NewReader(body.Bytes())
 
-	req, err = http.NewRequest(http.MethodGet, httpBaseURL+path.Join(webUserPath, "auser"), nil)
+	req, err := http.NewRequest(http.MethodPost, userFilesPath+"?path=vdir", reader)
 	assert.NoError(t, err)
-	setJWTCookieForReq(req, token)
-	resp, err = httpclient.GetHTTPClient().Do(req)
-	require.NoError(t, err)
-	defer resp.Body.Close()
-	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+	req.Header.Add("Content-Type", writer.FormDataContentType())
+	setBearerForReq(req, webAPIToken)
+	rr := executeRequest(req)
+	checkResponseCode(t, http.StatusCreated, rr)
 
-	req, err = http.NewRequest(http.MethodGet, httpBaseURL+webFolderPath, nil)
+	user, _, err = httpdtest.GetUserByUsername(user.Username, http.StatusOK)
 	assert.NoError(t, err)
-	setJWTCookieForReq(req, token)
-	resp, err = httpclient.GetHTTPClient().Do(req)
-	require.NoError(t, err)
-	defer resp.Body.Close()
-	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, int64(len(fileContents)), user.UsedQuotaSize)
 
-	req, err = http.NewRequest(http.MethodGet, httpBaseURL+webStatusPath, nil)
+	folder, _, err := httpdtest.GetFolderByName(folderName, http.StatusOK)
 	assert.NoError(t, err)
-	setJWTCookieForReq(req, token)
-	resp, err = httpclient.GetHTTPClient().Do(req)
-	require.NoError(t, err)
-	defer resp.Body.Close()
-	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+	assert.Equal(t, int64(len(fileContents)), folder.UsedQuotaSize)
 
-	req, err = http.NewRequest(http.MethodGet, httpBaseURL+webConnectionsPath, nil)
+	_, err = reader.Seek(0, io.SeekStart)
 	assert.NoError(t, err)
-	setJWTCookieForReq(req, token)
-	resp, err = httpclient.GetHTTPClient().Do(req)
-	require.NoError(t, err)
-	defer resp.Body.Close()
-	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+	req, err = http.NewRequest(http.MethodPost, userFilesPath+"?path=vdir", reader)
+	assert.NoError(t, err)
+	req.Header.Add("Content-Type", writer.FormDataContentType())
+	setBearerForReq(req, webAPIToken)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusCreated, rr)
 
-	req, err = http.NewRequest(http.MethodGet, httpBaseURL+webAdminPath, nil)
+	user, _, err = httpdtest.GetUserByUsername(user.Username, http.StatusOK)
 	assert.NoError(t, err)
-	setJWTCookieForReq(req, token)
-	resp, err = httpclient.GetHTTPClient().Do(req)
-	require.NoError(t, err)
-	defer resp.Body.Close()
-	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+	assert.Equal(t, int64(len(fileContents)), user.UsedQuotaSize)
 
-	req, err = http.NewRequest(http.MethodGet, httpBaseURL+path.Join(webAdminPath, "a"), nil)
+	folder, _, err = httpdtest.GetFolderByName(folderName, http.StatusOK)
 	assert.NoError(t, err)
-	setJWTCookieForReq(req, token)
-	resp, err = httpclient.GetHTTPClient().Do(req)
-	require.NoError(t, err)
-	defer resp.Body.Close()
-	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+	assert.Equal(t, int64(len(fileContents)), folder.UsedQuotaSize)
 
-	_, err = httpdtest.RemoveAdmin(admin, http.StatusOK)
+	_, err = httpdtest.RemoveUser(user, http.StatusOK)
+	assert.NoError(t, err)
+	_, err = httpdtest.RemoveFolder(vfs.BaseVirtualFolder{Name: folderName}, http.StatusOK)
+	assert.NoError(t, err)
+	err = os.RemoveAll(user.GetHomeDir())
+	assert.NoError(t, err)
+	err = os.RemoveAll(mappedPath)
 	assert.NoError(t, err)
 }
 
-func TestAdminUpdateSelfMock(t *testing.T) {
-	admin, _, err := httpdtest.GetAdminByUsername(defaultTokenAuthUser, http.StatusOK)
-	assert.NoError(t, err)
-	token, err := getJWTWebTokenFromTestServer(defaultTokenAuthUser, defaultTokenAuthPass)
+func TestWebAPIWritePermission(t *testing.T) {
+	u := getTestUser()
+	u.Filters.WebClient = append(u.Filters.WebClient, sdk.WebClientWriteDisabled)
+	user, _, err := httpdtest.AddUser(u, http.StatusCreated)
 	assert.NoError(t, err)
-	csrfToken, err := getCSRFToken(httpBaseURL + webLoginPath)
+	webAPIToken, err := getJWTAPIUserTokenFromTestServer(defaultUsername, defaultPassword)
 	assert.NoError(t, err)
-	form := make(url.Values)
-	form.Set("username", admin.Username)
-	form.Set("password", admin.Password)
-	form.Set("status", "0")
-	form.Set("permissions", dataprovider.PermAdminAddUsers)
-	form.Set("permissions", dataprovider.PermAdminCloseConnections)
-	form.Set(csrfFormToken, csrfToken)
-	req, _ := http.NewRequest(http.MethodPost, path.Join(webAdminPath, defaultTokenAuthUser), bytes.NewBuffer([]byte(form.Encode())))
-	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
-	setJWTCookieForReq(req, token)
-	rr := executeRequest(req)
-	checkResponseCode(t, http.StatusOK, rr)
-	assert.Contains(t, rr.Body.String(), "You cannot remove these permissions to yourself")
 
-	form.Set("permissions", dataprovider.PermAdminAny)
-	req, _ = http.NewRequest(http.MethodPost, path.Join(webAdminPath, defaultTokenAuthUser), bytes.NewBuffer([]byte(form.Encode())))
-	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
-	setJWTCookieForReq(req, token)
-	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusOK, rr)
-	assert.Contains(t, rr.Body.String(), "You cannot disable yourself")
-}
+	body := new(bytes.Buffer)
+	writer := multipart.NewWriter(body)
+	part, err := writer.CreateFormFile("filename", "file.txt")
+	assert.NoError(t, err)
+	_, err = part.Write([]byte(""))
+	assert.NoError(t, err)
+	err = writer.Close()
+	assert.NoError(t, err)
+	reader := bytes.NewReader(body.Bytes())
 
-func TestWebMaintenanceMock(t *testing.T) {
-	token, err := getJWTWebTokenFromTestServer(defaultTokenAuthUser, defaultTokenAuthPass)
+	req, err := http.NewRequest(http.MethodPost, userFilesPath, reader)
 	assert.NoError(t, err)
-	req, _ := http.NewRequest(http.MethodGet, webMaintenancePath, nil)
-	setJWTCookieForReq(req, token)
+	req.Header.Add("Content-Type", writer.FormDataContentType())
+	setBearerForReq(req, webAPIToken)
 	rr := executeRequest(req)
-	checkResponseCode(t, http.StatusOK, rr)
-	csrfToken, err := getCSRFToken(httpBaseURL + webLoginPath)
-	assert.NoError(t, err)
+	checkResponseCode(t, http.StatusForbidden, rr)
 
-	form := make(url.Values)
-	form.Set("mode", "a")
-	b, contentType, _ := getMultipartFormData(form, "", "")
-	req, _ = http.NewRequest(http.MethodPost, webRestorePath, &b)
-	setJWTCookieForReq(req, token)
-	req.Header.Set("Content-Type", contentType)
+	req, err = http.NewRequest(http.MethodPatch, userFilesPath+"?path=a&target=b", nil)
+	assert.NoError(t, err)
+	req.Header.Add("Content-Type", writer.FormDataContentType())
+	setBearerForReq(req, webAPIToken)
 	rr = executeRequest(req)
 	checkResponseCode(t, http.StatusForbidden, rr)
-	assert.Contains(t, rr.Body.String(), "unable to verify form token")
 
-	form.Set(csrfFormToken, csrfToken)
-	b, contentType, _ = getMultipartFormData(form, "", "")
-	req, _ = http.NewRequest(http.MethodPost, webRestorePath, &b)
-	setJWTCookieForReq(req, token)
-	req.Header.Set("Content-Type", contentType)
+	req, err = http.NewRequest(http.MethodDelete, userFilesPath+"?path=a", nil)
+	assert.NoError(t, err)
+	req.Header.Add("Content-Type", writer.FormDataContentType())
+	setBearerForReq(req, webAPIToken)
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusOK, rr)
+	checkResponseCode(t, http.StatusForbidden, rr)
 
-	form.Set("mode", "0")
-	form.Set("quota", "a")
-	b, contentType, _ = getMultipartFormData(form, "", "")
-	req, _ = http.NewRequest(http.MethodPost, webRestorePath, &b)
-	setJWTCookieForReq(req, token)
-	req.Header.Set("Content-Type", contentType)
+	req, err = http.NewRequest(http.MethodGet, userFilesPath+"?path=a.txt", nil)
+	assert.NoError(t, err)
+	req.Header.Add("Content-Type", writer.FormDataContentType())
+	setBearerForReq(req, webAPIToken)
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusOK, rr)
+	checkResponseCode(t, http.StatusNotFound, rr)
 
-	form.Set("quota", "0")
-	b, contentType, _ = getMultipartFormData(form, "", "")
-	req, _ = http.NewRequest(http.MethodPost, webRestorePath, &b)
-	setJWTCookieForReq(req, token)
-	req.Header.Set("Content-Type", contentType)
+	req, err = http.NewRequest(http.MethodGet, userDirsPath, nil)
+	assert.NoError(t, err)
+	req.Header.Add("Content-Type", writer.FormDataContentType())
+	setBearerForReq(req, webAPIToken)
 	rr = executeRequest(req)
 	checkResponseCode(t, http.StatusOK, rr)
 
-	req, _ = http.NewRequest(http.MethodPost, webRestorePath+"?a=%3", &b)
-	setJWTCookieForReq(req, token)
-	req.Header.Set("Content-Type", contentType)
+	req, err = http.NewRequest(http.MethodPost, userDirsPath+"?path=dir", nil)
+	assert.NoError(t, err)
+	req.Header.Add("Content-Type", writer.FormDataContentType())
+	setBearerForReq(req, webAPIToken)
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusOK, rr)
+	checkResponseCode(t, http.StatusForbidden, rr)
 
-	backupFilePath := filepath.Join(os.TempDir(), "backup.json")
-	err = createTestFile(backupFilePath, 0)
+	req, err = http.NewRequest(http.MethodPatch, userDirsPath+"?path=dir&target=dir1", nil)
 	assert.NoError(t, err)
+	req.Header.Add("Content-Type", writer.FormDataContentType())
+	setBearerForReq(req, webAPIToken)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusForbidden, rr)
 
-	b, contentType, _ = getMultipartFormData(form, "backup_file", backupFilePath)
-	req, _ = http.NewRequest(http.MethodPost, webRestorePath, &b)
-	setJWTCookieForReq(req, token)
-	req.Header.Set("Content-Type", contentType)
+	req, err = http.NewRequest(http.MethodDelete, userDirsPath+"?path=dir", nil)
+	assert.NoError(t, err)
+	req.Header.Add("Content-Type", writer.FormDataContentType())
+	setBearerForReq(req, webAPIToken)
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusOK, rr)
+	checkResponseCode(t, http.StatusForbidden, rr)
 
-	err = createTestFile(backupFilePath, 10)
+	_, err = httpdtest.RemoveUser(user, http.StatusOK)
 	assert.NoError(t, err)
+	err = os.RemoveAll(user.GetHomeDir())
+	assert.NoError(t, err)
+}
 
-	b, contentType, _ = getMultipartFormData(form, "backup_file", backupFilePath)
-	req, _ = http.NewRequest(http.MethodPost, webRestorePath, &b)
-	setJWTCookieForReq(req, token)
-	req.Header.Set("Content-Type", contentType)
+func TestWebAPICryptFs(t *testing.T) {
+	u := getTestUser()
+	u.QuotaSize = 65535
+	u.FsConfig.Provider = sdk.CryptedFilesystemProvider
+	u.FsConfig.CryptConfig.Passphrase = kms.NewPlainSecret(defaultPassword)
+	user, _, err := httpdtest.AddUser(u, http.StatusCreated)
+	assert.NoError(t, err)
+	webAPIToken, err := getJWTAPIUserTokenFromTestServer(defaultUsername, defaultPassword)
+	assert.NoError(t, err)
+
+	body := new(bytes.Buffer)
+	writer := multipart.NewWriter(body)
+	part, err := writer.CreateFormFile("filename", "file.txt")
+	assert.NoError(t, err)
+	_, err = part.Write([]byte("content"))
+	assert.NoError(t, err)
+	err = writer.Close()
+	assert.NoError(t, err)
+	reader := bytes.NewReader(body.Bytes())
+
+	req, err := http.NewRequest(http.MethodPost, userFilesPath, reader)
+	assert.NoError(t, err)
+	req.Header.Add("Content-Type", writer.FormDataContentType())
+	setBearerForReq(req, webAPIToken)
+	rr := executeRequest(req)
+	checkResponseCode(t, http.StatusCreated, rr)
+
+	_, err = reader.Seek(0, io.SeekStart)
+	assert.NoError(t, err)
+	req, err = http.NewRequest(http.MethodPost, userFilesPath, reader)
+	assert.NoError(t, err)
+	req.Header.Add("Content-Type", writer.FormDataContentType())
+	setBearerForReq(req, webAPIToken)
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusOK, rr)
+	checkResponseCode(t, http.StatusCreated, rr)
 
-	user := getTestUser()
-	user.ID = 1
-	user.Username = "test_user_web_restore"
-	admin := getTestAdmin()
-	admin.ID = 1
-	admin.Username = "test_admin_web_restore"
+	_, err = httpdtest.RemoveUser(user, http.StatusOK)
+	assert.NoError(t, err)
+	err = os.RemoveAll(user.GetHomeDir())
+	assert.NoError(t, err)
+}
 
-	apiKey := dataprovider.APIKey{
-		Name:  "key name",
-		KeyID: shortuuid.New(),
-		Key:   fmt.Sprintf("%v.%v", shortuuid.New(), shortuuid.New()),
-		Scope: dataprovider.APIKeyScopeAdmin,
-	}
-	backupData := dataprovider.BackupData{}
-	backupData.Users = append(backupData.Users, user)
-	backupData.Admins = append(backupData.Admins, admin)
-	backupData.APIKeys = append(backupData.APIKeys, apiKey)
-	backupContent, err := json.Marshal(backupData)
+func TestWebUploadSFTP(t *testing.T) {
+	u := getTestUser()
+	localUser, _, err := httpdtest.AddUser(u, http.StatusCreated)
 	assert.NoError(t, err)
-	err = os.WriteFile(backupFilePath, backupContent, os.ModePerm)
+	u = getTestSFTPUser()
+	u.QuotaFiles = 100
+	u.FsConfig.SFTPConfig.BufferSize = 2
+	u.HomeDir = filepath.Join(os.TempDir(), u.Username)
+	sftpUser, _, err := httpdtest.AddUser(u, http.StatusCreated)
 	assert.NoError(t, err)
 
-	b, contentType, _ = getMultipartFormData(form, "backup_file", backupFilePath)
-	req, _ = http.NewRequest(http.MethodPost, webRestorePath, &b)
-	setJWTCookieForReq(req, token)
-	req.Header.Set("Content-Type", contentType)
+	webAPIToken, err := getJWTAPIUserTokenFromTestServer(sftpUser.Username, defaultPassword)
+	assert.NoError(t, err)
+
+	body := new(bytes.Buffer)
+	writer := multipart.NewWriter(body)
+	part, err := writer.CreateFormFile("filename", "file.txt")
+	assert.NoError(t, err)
+	_, err = part.Write([]byte("test file content"))
+	assert.NoError(t, err)
+	err = writer.Close()
+	assert.NoError(t, err)
+	reader := bytes.NewReader(body.Bytes())
+
+	req, err := http.NewRequest(http.MethodPost, userFilesPath, reader)
+	assert.NoError(t, err)
+	req.Header.Add("Content-Type", writer.FormDataContentType())
+	setBearerForReq(req, webAPIToken)
+	rr := executeRequest(req)
+	checkResponseCode(t, http.StatusCreated, rr)
+
+	expectedQuotaSize := int64(17)
+	expectedQuotaFiles := 1
+	user, _, err := httpdtest.GetUserByUsername(sftpUser.Username, http.StatusOK)
+	assert.NoError(t, err)
+	assert.Equal(t, expectedQuotaFiles, user.UsedQuotaFiles)
+	assert.Equal(t, expectedQuotaSize, user.UsedQuotaSize)
+
+	user.QuotaSize = 10
+	user, _, err = httpdtest.UpdateUser(user, http.StatusOK, "")
+	assert.NoError(t, err)
+	// we are now overquota on overwrite
+	_, err = reader.Seek(0, io.SeekStart)
+	assert.NoError(t, err)
+	req, err = http.NewRequest(http.MethodPost, userFilesPath, reader)
+	assert.NoError(t, err)
+	req.Header.Add("Content-Type", writer.FormDataContentType())
+	setBearerForReq(req, webAPIToken)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusInternalServerError, rr)
+	assert.Contains(t, rr.Body.String(), "denying write due to space limit")
+	// delete the file
+	req, err = http.NewRequest(http.MethodDelete, userFilesPath+"?path=file.txt", nil)
+	assert.NoError(t, err)
+	setBearerForReq(req, webAPIToken)
 	rr = executeRequest(req)
 	checkResponseCode(t, http.StatusOK, rr)
-	assert.Contains(t, rr.Body.String(), "Your backup was successfully restored")
 
-	user, _, err = httpdtest.GetUserByUsername(user.Username, http.StatusOK)
+	_, err = reader.Seek(0, io.SeekStart)
 	assert.NoError(t, err)
-	_, err = httpdtest.RemoveUser(user, http.StatusOK)
+	req, err = http.NewRequest(http.MethodPost, userFilesPath, reader)
 	assert.NoError(t, err)
+	req.Header.Add("Content-Type", writer.FormDataContentType())
+	setBearerForReq(req, webAPIToken)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusInternalServerError, rr)
+	assert.Contains(t, rr.Body.String(), "denying write due to space limit")
 
-	admin, _, err = httpdtest.GetAdminByUsername(admin.Username, http.StatusOK)
+	_, err = httpdtest.RemoveUser(sftpUser, http.StatusOK)
 	assert.NoError(t, err)
-	_, err = httpdtest.RemoveAdmin(admin, http.StatusOK)
+	_, err = httpdtest.RemoveUser(localUser, http.StatusOK)
+	assert.NoError(t, err)
+	err = os.RemoveAll(localUser.GetHomeDir())
+	assert.NoError(t, err)
+	err = os.RemoveAll(sftpUser.GetHomeDir())
 	assert.NoError(t, err)
+}
 
-	_, _, err = httpdtest.GetAPIKeyByID(apiKey.KeyID, http.StatusOK)
+func TestWebUploadMultipartFormReadError(t *testing.T) {
+	user, _, err := httpdtest.AddUser(getTestUser(), http.StatusCreated)
 	assert.NoError(t, err)
-	_, err = httpdtest.RemoveAPIKey(apiKey, http.StatusOK)
+	webAPIToken, err := getJWTAPIUserTokenFromTestServer(defaultUsername, defaultPassword)
 	assert.NoError(t, err)
 
-	err = os.Remove(backupFilePath)
+	req, err := http.NewRequest(http.MethodPost, userFilesPath, nil)
+	assert.NoError(t, err)
+
+	mpartForm := &multipart.Form{
+		File: make(map[string][]*multipart.FileHeader),
+	}
+	mpartForm.File["filename"] = append(mpartForm.File["filename"], &multipart.FileHeader{Filename: "missing"})
+	req.MultipartForm = mpartForm
+	req.Header.Add("Content-Type", "multipart/form-data")
+	setBearerForReq(req, webAPIToken)
+	rr := executeRequest(req)
+	checkResponseCode(t, http.StatusInternalServerError, rr)
+	assert.Contains(t, rr.Body.String(), "Unable to read uploaded file")
+
+	_, err = httpdtest.RemoveUser(user, http.StatusOK)
+	assert.NoError(t, err)
+	err = os.RemoveAll(user.GetHomeDir())
 	assert.NoError(t, err)
 }
 
-func TestWebUserAddMock(t *testing.T) {
-	webToken, err := getJWTWebTokenFromTestServer(defaultTokenAuthUser, defaultTokenAuthPass)
+func TestCompressionErrorMock(t *testing.T) {
+	user, _, err := httpdtest.AddUser(getTestUser(), http.StatusCreated)
 	assert.NoError(t, err)
-	apiToken, err := getJWTAPITokenFromTestServer(defaultTokenAuthUser, defaultTokenAuthPass)
+
+	defer func() {
+		rcv := recover()
+		assert.Equal(t, http.ErrAbortHandler, rcv)
+		_, err := httpdtest.RemoveUser(user, http.StatusOK)
+		assert.NoError(t, err)
+		err = os.RemoveAll(user.GetHomeDir())
+		assert.NoError(t, err)
+	}()
+
+	webToken, err := getJWTWebClientTokenFromTestServer(defaultUsername, defaultPassword)
 	assert.NoError(t, err)
-	csrfToken, err := getCSRFToken(httpBaseURL + webLoginPath)
+
+	req, _ := http.NewRequest(http.MethodGet, webClientDownloadZipPath+"?path="+url.QueryEscape("/")+"&files="+
+		url.QueryEscape(`["missing"]`), nil)
+	setJWTCookieForReq(req, webToken)
+	executeRequest(req)
+}
+
+func TestGetFilesSFTPBackend(t *testing.T) {
+	user, _, err := httpdtest.AddUser(getTestUser(), http.StatusCreated)
 	assert.NoError(t, err)
-	user := getTestUser()
-	user.UploadBandwidth = 32
-	user.DownloadBandwidth = 64
-	user.UID = 1000
-	user.AdditionalInfo = "info"
-	user.Description = "user dsc"
-	user.Email = "test@test.com"
-	mappedDir := filepath.Join(os.TempDir(), "mapped")
-	folderName := filepath.Base(mappedDir)
-	f := vfs.BaseVirtualFolder{
-		Name:       folderName,
-		MappedPath: mappedDir,
+	u := getTestSFTPUser()
+	u.FsConfig.SFTPConfig.BufferSize = 2
+	u.Permissions["/adir"] = nil
+	u.Permissions["/adir1"] = []string{dataprovider.PermListItems}
+	u.Filters.FilePatterns = []sdk.PatternsFilter{
+		{
+			Path:           "/adir2",
+			DeniedPatterns: []string{"*.txt"},
+		},
 	}
-	folderAsJSON, err := json.Marshal(f)
+	sftpUser, _, err := httpdtest.AddUser(u, http.StatusCreated)
 	assert.NoError(t, err)
-	req, _ := http.NewRequest(http.MethodPost, folderPath, bytes.NewBuffer(folderAsJSON))
-	setBearerForReq(req, apiToken)
+
+	testFileName := "testsftpfile"
+	testDir := "testsftpdir"
+	testFileContents := []byte("sftp file contents")
+	err = os.MkdirAll(filepath.Join(user.GetHomeDir(), testDir, "sub"), os.ModePerm)
+	assert.NoError(t, err)
+	err = os.MkdirAll(filepath.Join(user.GetHomeDir(), "adir1"), os.ModePerm)
+	assert.NoError(t, err)
+	err = os.MkdirAll(filepath.Join(user.GetHomeDir(), "adir2"), os.ModePerm)
+	assert.NoError(t, err)
+	err = os.WriteFile(filepath.Join(user.GetHomeDir(), testFileName), testFileContents, os.ModePerm)
+	assert.NoError(t, err)
+	err = os.WriteFile(filepath.Join(user.GetHomeDir(), "adir1", "afile"), testFileContents, os.ModePerm)
+	assert.NoError(t, err)
+	err = os.WriteFile(filepath.Join(user.GetHomeDir(), "adir2", "afile.txt"), testFileContents, os.ModePerm)
+	assert.NoError(t, err)
+	webToken, err := getJWTWebClientTokenFromTestServer(sftpUser.Username, defaultPassword)
+	assert.NoError(t, err)
+	req, _ := http.NewRequest(http.MethodGet, webClientFilesPath, nil)
+	setJWTCookieForReq(req, webToken)
 	rr := executeRequest(req)
-	checkResponseCode(t, http.StatusCreated, rr)
+	checkResponseCode(t, http.StatusOK, rr)
 
+	req, _ = http.NewRequest(http.MethodGet, webClientFilesPath+"?path="+path.Join(testDir, "sub"), nil)
+	setJWTCookieForReq(req, webToken)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
+
+	req, _ = http.NewRequest(http.MethodGet, webClientFilesPath+"?path="+path.Join(testDir, "missing"), nil)
+	setJWTCookieForReq(req, webToken)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
+	assert.Contains(t, rr.Body.String(), "card-body text-form-error")
+	req, _ = http.NewRequest(http.MethodGet, webClientFilesPath+"?path=adir/sub", nil)
+	setJWTCookieForReq(req, webToken)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
+	assert.Contains(t, rr.Body.String(), "card-body text-form-error")
+
+	req, _ = http.NewRequest(http.MethodGet, webClientFilesPath+"?path=adir1/afile", nil)
+	setJWTCookieForReq(req, webToken)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
+	assert.Contains(t, rr.Body.String(), "card-body text-form-error")
+
+	req, _ = http.NewRequest(http.MethodGet, webClientFilesPath+"?path=adir2/afile.txt", nil)
+	setJWTCookieForReq(req, webToken)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
+	assert.Contains(t, rr.Body.String(), "card-body text-form-error")
+
+	req, _ = http.NewRequest(http.MethodGet, webClientFilesPath+"?path="+testFileName, nil)
+	setJWTCookieForReq(req, webToken)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
+	assert.Equal(t, testFileContents, rr.Body.Bytes())
+
+	req, _ = http.NewRequest(http.MethodGet, webClientFilesPath+"?path="+testFileName, nil)
+	req.Header.Set("Range", "bytes=2-")
+	setJWTCookieForReq(req, webToken)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusPartialContent, rr)
+	assert.Equal(t, testFileContents[2:], rr.Body.Bytes())
+
+	_, err = httpdtest.RemoveUser(sftpUser, http.StatusOK)
+	assert.NoError(t, err)
+	err = os.RemoveAll(sftpUser.GetHomeDir())
+	assert.NoError(t, err)
+	_, err = httpdtest.RemoveUser(user, http.StatusOK)
+	assert.NoError(t, err)
+	err = os.RemoveAll(user.GetHomeDir())
+	assert.NoError(t, err)
+}
+
+func TestClientUserClose(t *testing.T) {
+	u := getTestUser()
+	u.UploadBandwidth = 32
+	u.DownloadBandwidth = 32
+	user, _, err := httpdtest.AddUser(u, http.StatusCreated)
+	assert.NoError(t, err)
+	testFileName := "file.dat"
+	testFileSize := int64(524288)
+	testFilePath := filepath.Join(user.GetHomeDir(), testFileName)
+	err = createTestFile(testFilePath, testFileSize)
+	assert.NoError(t, err)
+	uploadContent := make([]byte, testFileSize)
+	_, err = rand.Read(uploadContent)
+	assert.NoError(t, err)
+	webToken, err := getJWTWebClientTokenFromTestServer(defaultUsername, defaultPassword)
+	assert.NoError(t, err)
+	webAPIToken, err := getJWTAPIUserTokenFromTestServer(defaultUsername, defaultPassword)
+	assert.NoError(t, err)
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		req, _ := http.NewRequest(http.MethodGet, webClientFilesPath+"?path="+testFileName, nil)
+		setJWTCookieForReq(req, webToken)
+		rr := executeRequest(req)
+		checkResponseCode(t, http.StatusOK, rr)
+	}()
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		req, _ := http.NewRequest(http.MethodGet, webClientEditFilePath+"?path="+testFileName, nil)
+		setJWTCookieForReq(req, webToken)
+		rr := executeRequest(req)
+		checkResponseCode(t, http.StatusInternalServerError, rr)
+		assert.Contains(t, rr.Body.String(), "Unable to read the file")
+	}()
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		body := new(bytes.Buffer)
+		writer := multipart.NewWriter(body)
+		part, err := writer.CreateFormFile("filename", "upload.dat")
+		assert.NoError(t, err)
+		n, err := part.Write(uploadContent)
+		assert.NoError(t, err)
+		assert.Equal(t, testFileSize, int64(n))
+		err = writer.Close()
+		assert.NoError(t, err)
+		reader := bytes.NewReader(body.Bytes())
+		req, err := http.NewRequest(http.MethodPost, userFilesPath, reader)
+		assert.NoError(t, err)
+		req.Header.Add("Content-Type", writer.FormDataContentType())
+		setBearerForReq(req, webAPIToken)
+		rr := executeRequest(req)
+		checkResponseCode(t, http.StatusInternalServerError, rr)
+		assert.Contains(t, rr.Body.String(), "transfer aborted")
+	}()
+	// wait for the transfers
+	assert.Eventually(t, func() bool {
+		stats := common.Connections.GetStats()
+		if len(stats) == 3 {
+			if len(stats[0].Transfers) > 0 && len(stats[1].Transfers) > 0 {
+				return true
+			}
+		}
+		return false
+	}, 1*time.Second, 50*time.Millisecond)
+
+	for _, stat := range common.Connections.GetStats() {
+		// close all the active transfers
+		common.Connections.Close(stat.ConnectionID)
+	}
+	wg.Wait()
+	assert.Eventually(t, func() bool { return len(common.Connections.GetStats()) == 0 },
+		1*time.Second, 100*time.Millisecond)
+
+	_, err = httpdtest.RemoveUser(user, http.StatusOK)
+	assert.NoError(t, err)
+	err = os.RemoveAll(user.GetHomeDir())
+	assert.NoError(t, err)
+}
+
+func TestWebAdminSetupMock(t *testing.T) {
+	req, err := http.NewRequest(http.MethodGet, webAdminSetupPath, nil)
+	assert.NoError(t, err)
+	rr := executeRequest(req)
+	checkResponseCode(t, http.StatusFound, rr)
+	assert.Equal(t, webLoginPath, rr.Header().Get("Location"))
+	// now delete all the admins
+	admins, err := dataprovider.GetAdmins(100, 0, dataprovider.OrderASC)
+	assert.NoError(t, err)
+	for _, admin := range admins {
+		err = dataprovider.DeleteAdmin(admin.Username, "", "")
+		assert.NoError(t, err)
+	}
+	// close the provider and initializes it without creating the default admin
+	os.Setenv("SFTPGO_DATA_PROVIDER__CREATE_DEFAULT_ADMIN", "0")
+	err = dataprovider.Close()
+	assert.NoError(t, err)
+	err = config.LoadConfig(configDir, "")
+	assert.NoError(t, err)
+	providerConf := config.GetProviderConf()
+	providerConf.CredentialsPath = credentialsPath
+	err = os.RemoveAll(credentialsPath)
+	assert.NoError(t, err)
+	err = dataprovider.Initialize(providerConf, configDir, true)
+	assert.NoError(t, err)
+	// now the setup page must be rendered
+	req, err = http.NewRequest(http.MethodGet, webAdminSetupPath, nil)
+	assert.NoError(t, err)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
+	// check redirects to the setup page
+	req, err = http.NewRequest(http.MethodGet, "/", nil)
+	assert.NoError(t, err)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusFound, rr)
+	assert.Equal(t, webAdminSetupPath, rr.Header().Get("Location"))
+	req, err = http.NewRequest(http.MethodGet, webBasePath, nil)
+	assert.NoError(t, err)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusFound, rr)
+	assert.Equal(t, webAdminSetupPath, rr.Header().Get("Location"))
+	req, err = http.NewRequest(http.MethodGet, webBasePathAdmin, nil)
+	assert.NoError(t, err)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusFound, rr)
+	assert.Equal(t, webAdminSetupPath, rr.Header().Get("Location"))
+	req, err = http.NewRequest(http.MethodGet, webLoginPath, nil)
+	assert.NoError(t, err)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusFound, rr)
+	assert.Equal(t, webAdminSetupPath, rr.Header().Get("Location"))
+	req, err = http.NewRequest(http.MethodGet, webClientLoginPath, nil)
+	assert.NoError(t, err)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusFound, rr)
+	assert.Equal(t, webAdminSetupPath, rr.Header().Get("Location"))
+
+	csrfToken, err := getCSRFToken(httpBaseURL + webAdminSetupPath)
+	assert.NoError(t, err)
+	form := make(url.Values)
+	req, err = http.NewRequest(http.MethodPost, webAdminSetupPath, bytes.NewBuffer([]byte(form.Encode())))
+	assert.NoError(t, err)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusForbidden, rr)
+	form.Set(csrfFormToken, csrfToken)
+	req, err = http.NewRequest(http.MethodPost, webAdminSetupPath, bytes.NewBuffer([]byte(form.Encode())))
+	assert.NoError(t, err)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
+	assert.Contains(t, rr.Body.String(), "Please set a username")
+	form.Set("username", defaultTokenAuthUser)
+	req, err = http.NewRequest(http.MethodPost, webAdminSetupPath, bytes.NewBuffer([]byte(form.Encode())))
+	assert.NoError(t, err)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
+	assert.Contains(t, rr.Body.String(), "Please set a password")
+	form.Set("password", defaultTokenAuthPass)
+	req, err = http.NewRequest(http.MethodPost, webAdminSetupPath, bytes.NewBuffer([]byte(form.Encode())))
+	assert.NoError(t, err)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
+	assert.Contains(t, rr.Body.String(), "Passwords mismatch")
+	form.Set("confirm_password", defaultTokenAuthPass)
+	// test a parse form error
+	req, err = http.NewRequest(http.MethodPost, webAdminSetupPath+"?param=p%C3%AO%GH", bytes.NewBuffer([]byte(form.Encode())))
+	assert.NoError(t, err)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
+	// test a dataprovider error
+	err = dataprovider.Close()
+	assert.NoError(t, err)
+	req, err = http.NewRequest(http.MethodPost, webAdminSetupPath, bytes.NewBuffer([]byte(form.Encode())))
+	assert.NoError(t, err)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
+	// finally initialize the provider and create the default admin
+	err = config.LoadConfig(configDir, "")
+	assert.NoError(t, err)
+	providerConf = config.GetProviderConf()
+	providerConf.CredentialsPath = credentialsPath
+	err = os.RemoveAll(credentialsPath)
+	assert.NoError(t, err)
+	err = dataprovider.Initialize(providerConf, configDir, true)
+	assert.NoError(t, err)
+	req, err = http.NewRequest(http.MethodPost, webAdminSetupPath, bytes.NewBuffer([]byte(form.Encode())))
+	assert.NoError(t, err)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusFound, rr)
+	assert.Equal(t, webUsersPath, rr.Header().Get("Location"))
+	// if we resubmit the form we get a bad request, an admin already exists
+	req, err = http.NewRequest(http.MethodPost, webAdminSetupPath, bytes.NewBuffer([]byte(form.Encode())))
+	assert.NoError(t, err)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusBadRequest, rr)
+	assert.Contains(t, rr.Body.String(), "an admin user already exists")
+	os.Setenv("SFTPGO_DATA_PROVIDER__CREATE_DEFAULT_ADMIN", "1")
+}
+
+func TestWebAdminLoginMock(t *testing.T) {
+	webToken, err := getJWTWebTokenFromTestServer(defaultTokenAuthUser, defaultTokenAuthPass)
+	assert.NoError(t, err)
+	apiToken, err := getJWTAPITokenFromTestServer(defaultTokenAuthUser, defaultTokenAuthPass)
+	assert.NoError(t, err)
+
+	req, _ := http.NewRequest(http.MethodGet, serverStatusPath, nil)
+	setBearerForReq(req, apiToken)
+	rr := executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
+
+	req, _ = http.NewRequest(http.MethodGet, webStatusPath+"notfound", nil)
+	req.RequestURI = webStatusPath + "notfound"
+	setJWTCookieForReq(req, webToken)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusNotFound, rr)
+
+	req, _ = http.NewRequest(http.MethodGet, webStatusPath, nil)
+	setJWTCookieForReq(req, webToken)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
+
+	req, _ = http.NewRequest(http.MethodGet, webLogoutPath, nil)
+	setJWTCookieForReq(req, webToken)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusFound, rr)
+	cookie := rr.Header().Get("Cookie")
+	assert.Empty(t, cookie)
+
+	req, _ = http.NewRequest(http.MethodGet, logoutPath, nil)
+	setBearerForReq(req, apiToken)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
+
+	req, _ = http.NewRequest(http.MethodGet, serverStatusPath, nil)
+	setBearerForReq(req, apiToken)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusUnauthorized, rr)
+	assert.Contains(t, rr.Body.String(), "Your token is no longer valid")
+
+	req, _ = http.NewRequest(http.MethodGet, webStatusPath, nil)
+	setJWTCookieForReq(req, webToken)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusFound, rr)
+
+	csrfToken, err := getCSRFToken(httpBaseURL + webLoginPath)
+	assert.NoError(t, err)
+	// now try using wrong credentials
+	form := getLoginForm(defaultTokenAuthUser, "wrong pwd", csrfToken)
+	req, _ = http.NewRequest(http.MethodPost, webLoginPath, bytes.NewBuffer([]byte(form.Encode())))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
+	// try from an ip not allowed
+	a := getTestAdmin()
+	a.Username = altAdminUsername
+	a.Password = altAdminPassword
+	a.Filters.AllowList = []string{"10.0.0.0/8"}
+
+	_, _, err = httpdtest.AddAdmin(a, http.StatusCreated)
+	assert.NoError(t, err)
+
+	form = getLoginForm(altAdminUsername, altAdminPassword, csrfToken)
+	req, _ = http.NewRequest(http.MethodPost, webLoginPath, bytes.NewBuffer([]byte(form.Encode())))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.RemoteAddr = "127.1.1.1:1234"
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
+	assert.Contains(t, rr.Body.String(), "login from IP 127.1.1.1 not allowed")
+
+	req, _ = http.NewRequest(http.MethodPost, webLoginPath, bytes.NewBuffer([]byte(form.Encode())))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.RemoteAddr = "10.9.9.9:1234"
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusFound, rr)
+
+	req, _ = http.NewRequest(http.MethodPost, webLoginPath, bytes.NewBuffer([]byte(form.Encode())))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.RemoteAddr = "127.0.1.1:4567"
+	req.Header.Set("X-Forwarded-For", "10.9.9.9")
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
+	assert.Contains(t, rr.Body.String(), "login from IP 127.0.1.1 not allowed")
+
+	// invalid csrf token
+	form = getLoginForm(altAdminUsername, altAdminPassword, "invalid csrf")
+	req, _ = http.NewRequest(http.MethodPost, webLoginPath, bytes.NewBuffer([]byte(form.Encode())))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.RemoteAddr = "10.9.9.8:1234"
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
+	assert.Contains(t, rr.Body.String(), "unable to verify form token")
+
+	req, _ = http.NewRequest(http.MethodGet, webLoginPath, nil)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
+
+	_, err = httpdtest.RemoveAdmin(a, http.StatusOK)
+	assert.NoError(t, err)
+}
+
+func TestAdminNoToken(t *testing.T) {
+	req, _ := http.NewRequest(http.MethodGet, webAdminProfilePath, nil)
+	rr := executeRequest(req)
+	checkResponseCode(t, http.StatusFound, rr)
+	assert.Equal(t, webLoginPath, rr.Header().Get("Location"))
+
+	req, _ = http.NewRequest(http.MethodGet, webUserPath, nil)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusFound, rr)
+	assert.Equal(t, webLoginPath, rr.Header().Get("Location"))
+
+	req, _ = http.NewRequest(http.MethodGet, userPath, nil)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusUnauthorized, rr)
+
+	req, _ = http.NewRequest(http.MethodGet, activeConnectionsPath, nil)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusUnauthorized, rr)
+}
+
+func TestWebUserProfile(t *testing.T) {
+	user, _, err := httpdtest.AddUser(getTestUser(), http.StatusCreated)
+	assert.NoError(t, err)
+
+	csrfToken, err := getCSRFToken(httpBaseURL + webClientLoginPath)
+	assert.NoError(t, err)
+	token, err := getJWTWebClientTokenFromTestServer(defaultUsername, defaultPassword)
+	assert.NoError(t, err)
+
+	email := "user@user.com"
+	description := "User"
+
+	form := make(url.Values)
+	form.Set("allow_api_key_auth", "1")
+	form.Set("email", email)
+	form.Set("description", description)
+	form.Set("public_keys", testPubKey)
+	form.Add("public_keys", testPubKey1)
+	// no csrf token
+	req, err := http.NewRequest(http.MethodPost, webClientProfilePath, bytes.NewBuffer([]byte(form.Encode())))
+	assert.NoError(t, err)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	setJWTCookieForReq(req, token)
+	rr := executeRequest(req)
+	checkResponseCode(t, http.StatusForbidden, rr)
+	assert.Contains(t, rr.Body.String(), "unable to verify form token")
+
+	form.Set(csrfFormToken, csrfToken)
+	req, _ = http.NewRequest(http.MethodPost, webClientProfilePath, bytes.NewBuffer([]byte(form.Encode())))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	setJWTCookieForReq(req, token)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
+	assert.Contains(t, rr.Body.String(), "Your profile has been successfully updated")
+
+	user, _, err = httpdtest.GetUserByUsername(user.Username, http.StatusOK)
+	assert.NoError(t, err)
+	assert.True(t, user.Filters.AllowAPIKeyAuth)
+	assert.Len(t, user.PublicKeys, 2)
+	assert.Equal(t, email, user.Email)
+	assert.Equal(t, description, user.Description)
+
+	// set an invalid email
+	form.Set("email", "not an email")
+	req, _ = http.NewRequest(http.MethodPost, webClientProfilePath, bytes.NewBuffer([]byte(form.Encode())))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	setJWTCookieForReq(req, token)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
+	assert.Contains(t, rr.Body.String(), "Validation error: email")
+	// invalid public key
+	form.Set("email", email)
+	form.Set("public_keys", "invalid")
+	req, _ = http.NewRequest(http.MethodPost, webClientProfilePath, bytes.NewBuffer([]byte(form.Encode())))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	setJWTCookieForReq(req, token)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
+	assert.Contains(t, rr.Body.String(), "Validation error: could not parse key")
+	// now remove permissions
+	form.Set("public_keys", testPubKey)
+	user.Filters.WebClient = []string{sdk.WebClientAPIKeyAuthChangeDisabled}
+	_, _, err = httpdtest.UpdateUser(user, http.StatusOK, "")
+	assert.NoError(t, err)
+	token, err = getJWTWebClientTokenFromTestServer(defaultUsername, defaultPassword)
+	assert.NoError(t, err)
+
+	form.Set("allow_api_key_auth", "0")
+	form.Set(csrfFormToken, csrfToken)
+	req, _ = http.NewRequest(http.MethodPost, webClientProfilePath, bytes.NewBuffer([]byte(form.Encode())))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	setJWTCookieForReq(req, token)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
+	assert.Contains(t, rr.Body.String(), "Your profile has been successfully updated")
+	user, _, err = httpdtest.GetUserByUsername(user.Username, http.StatusOK)
+	assert.NoError(t, err)
+	assert.True(t, user.Filters.AllowAPIKeyAuth)
+	assert.Len(t, user.PublicKeys, 1)
+	assert.Equal(t, email, user.Email)
+	assert.Equal(t, description, user.Description)
+
+	user.Filters.WebClient = []string{sdk.WebClientAPIKeyAuthChangeDisabled, sdk.WebClientPubKeyChangeDisabled}
+	_, _, err = httpdtest.UpdateUser(user, http.StatusOK, "")
+	assert.NoError(t, err)
+	token, err = getJWTWebClientTokenFromTestServer(defaultUsername, defaultPassword)
+	assert.NoError(t, err)
+	form.Set("public_keys", testPubKey)
+	form.Add("public_keys", testPubKey1)
+	req, _ = http.NewRequest(http.MethodPost, webClientProfilePath, bytes.NewBuffer([]byte(form.Encode())))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	setJWTCookieForReq(req, token)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
+	assert.Contains(t, rr.Body.String(), "Your profile has been successfully updated")
+	user, _, err = httpdtest.GetUserByUsername(user.Username, http.StatusOK)
+	assert.NoError(t, err)
+	assert.True(t, user.Filters.AllowAPIKeyAuth)
+	assert.Len(t, user.PublicKeys, 1)
+	assert.Equal(t, email, user.Email)
+	assert.Equal(t, description, user.Description)
+
+	user.Filters.WebClient = []string{sdk.WebClientAPIKeyAuthChangeDisabled, sdk.WebClientInfoChangeDisabled}
+	_, _, err = httpdtest.UpdateUser(user, http.StatusOK, "")
+	assert.NoError(t, err)
+	token, err = getJWTWebClientTokenFromTestServer(defaultUsername, defaultPassword)
+	assert.NoError(t, err)
+	form.Set("email", "newemail@user.com")
+	form.Set("description", "new description")
+	req, _ = http.NewRequest(http.MethodPost, webClientProfilePath, bytes.NewBuffer([]byte(form.Encode())))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	setJWTCookieForReq(req, token)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
+	assert.Contains(t, rr.Body.String(), "Your profile has been successfully updated")
+	user, _, err = httpdtest.GetUserByUsername(user.Username, http.StatusOK)
+	assert.NoError(t, err)
+	assert.True(t, user.Filters.AllowAPIKeyAuth)
+	assert.Len(t, user.PublicKeys, 2)
+	assert.Equal(t, email, user.Email)
+	assert.Equal(t, description, user.Description)
+	// finally disable all profile permissions
+	user.Filters.WebClient = []string{sdk.WebClientAPIKeyAuthChangeDisabled, sdk.WebClientInfoChangeDisabled,
+		sdk.WebClientPubKeyChangeDisabled}
+	_, _, err = httpdtest.UpdateUser(user, http.StatusOK, "")
+	assert.NoError(t, err)
+	token, err = getJWTWebClientTokenFromTestServer(defaultUsername, defaultPassword)
+	assert.NoError(t, err)
+	req, _ = http.NewRequest(http.MethodPost, webClientProfilePath, bytes.NewBuffer([]byte(form.Encode())))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	setJWTCookieForReq(req, token)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusForbidden, rr)
+
+	err = os.RemoveAll(user.GetHomeDir())
+	assert.NoError(t, err)
+	_, err = httpdtest.RemoveUser(user, http.StatusOK)
+	assert.NoError(t, err)
+
+	form = make(url.Values)
+	form.Set(csrfFormToken, csrfToken)
+	req, _ = http.NewRequest(http.MethodPost, webClientProfilePath, bytes.NewBuffer([]byte(form.Encode())))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	setJWTCookieForReq(req, token)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusInternalServerError, rr)
+}
+
+func TestWebAdminProfile(t *testing.T) {
+	admin := getTestAdmin()
+	admin.Username = altAdminUsername
+	admin.Password = altAdminPassword
+	admin, _, err := httpdtest.AddAdmin(admin, http.StatusCreated)
+	assert.NoError(t, err)
+	token, err := getJWTWebTokenFromTestServer(admin.Username, altAdminPassword)
+	assert.NoError(t, err)
+	csrfToken, err := getCSRFToken(httpBaseURL + webLoginPath)
+	assert.NoError(t, err)
+	req, err := http.NewRequest(http.MethodGet, webAdminProfilePath, nil)
+	assert.NoError(t, err)
+	setJWTCookieForReq(req, token)
+	rr := executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
+
+	form := make(url.Values)
+	form.Set("allow_api_key_auth", "1")
+	form.Set("email", "admin@example.com")
+	form.Set("description", "admin desc")
+	// no csrf token
+	req, err = http.NewRequest(http.MethodPost, webAdminProfilePath, bytes.NewBuffer([]byte(form.Encode())))
+	assert.NoError(t, err)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	setJWTCookieForReq(req, token)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusForbidden, rr)
+	assert.Contains(t, rr.Body.String(), "unable to verify form token")
+
+	form.Set(csrfFormToken, csrfToken)
+	req, _ = http.NewRequest(http.MethodPost, webAdminProfilePath, bytes.NewBuffer([]byte(form.Encode())))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	setJWTCookieForReq(req, token)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
+	assert.Contains(t, rr.Body.String(), "Your profile has been successfully updated")
+
+	admin, _, err = httpdtest.GetAdminByUsername(admin.Username, http.StatusOK)
+	assert.NoError(t, err)
+	assert.True(t, admin.Filters.AllowAPIKeyAuth)
+	assert.Equal(t, "admin@example.com", admin.Email)
+	assert.Equal(t, "admin desc", admin.Description)
+
+	form = make(url.Values)
+	form.Set(csrfFormToken, csrfToken)
+	req, _ = http.NewRequest(http.MethodPost, webAdminProfilePath, bytes.NewBuffer([]byte(form.Encode())))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	setJWTCookieForReq(req, token)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
+	assert.Contains(t, rr.Body.String(), "Your profile has been successfully updated")
+
+	admin, _, err = httpdtest.GetAdminByUsername(admin.Username, http.StatusOK)
+	assert.NoError(t, err)
+	assert.False(t, admin.Filters.AllowAPIKeyAuth)
+	assert.Empty(t, admin.Email)
+	assert.Empty(t, admin.Description)
+
+	_, err = httpdtest.RemoveAdmin(admin, http.StatusOK)
+	assert.NoError(t, err)
+
+	form = make(url.Values)
+	form.Set(csrfFormToken, csrfToken)
+	req, _ = http.NewRequest(http.MethodPost, webAdminProfilePath, bytes.NewBuffer([]byte(form.Encode())))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	setJWTCookieForReq(req, token)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusInternalServerError, rr)
+}
+
+func TestWebAdminPwdChange(t *testing.T) {
+	admin := getTestAdmin()
+	admin.Username = altAdminUsername
+	admin.Password = altAdminPassword
+	admin, _, err := httpdtest.AddAdmin(admin, http.StatusCreated)
+	assert.NoError(t, err)
+
+	token, err := getJWTWebTokenFromTestServer(admin.Username, altAdminPassword)
+	assert.NoError(t, err)
+	csrfToken, err := getCSRFToken(httpBaseURL + webLoginPath)
+	assert.NoError(t, err)
+	req, err := http.NewRequest(http.MethodGet, webChangeAdminPwdPath, nil)
+	assert.NoError(t, err)
+	setJWTCookieForReq(req, token)
+	rr := executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
+	form := make(url.Values)
+	form.Set("current_password", altAdminPassword)
+	form.Set("new_password1", altAdminPassword)
+	form.Set("new_password2", altAdminPassword)
+	// no csrf token
+	req, _ = http.NewRequest(http.MethodPost, webChangeAdminPwdPath, bytes.NewBuffer([]byte(form.Encode())))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	setJWTCookieForReq(req, token)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusForbidden, rr)
+	assert.Contains(t, rr.Body.String(), "unable to verify form token")
+
+	form.Set(csrfFormToken, csrfToken)
+	req, _ = http.NewRequest(http.MethodPost, webChangeAdminPwdPath, bytes.NewBuffer([]byte(form.Encode())))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	setJWTCookieForReq(req, token)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
+	assert.Contains(t, rr.Body.String(), "the new password must be different from the current one")
+
+	form.Set("new_password1", altAdminPassword+"1")
+	form.Set("new_password2", altAdminPassword+"1")
+	req, _ = http.NewRequest(http.MethodPost, webChangeAdminPwdPath, bytes.NewBuffer([]byte(form.Encode())))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	setJWTCookieForReq(req, token)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusFound, rr)
+	assert.Equal(t, webLoginPath, rr.Header().Get("Location"))
+
+	_, err = httpdtest.RemoveAdmin(admin, http.StatusOK)
+	assert.NoError(t, err)
+}
+
+func TestAPIKeysManagement(t *testing.T) {
+	admin := getTestAdmin()
+	admin.Username = altAdminUsername
+	admin.Password = altAdminPassword
+	admin, _, err := httpdtest.AddAdmin(admin, http.StatusCreated)
+	assert.NoError(t, err)
+	token, err := getJWTAPITokenFromTestServer(defaultTokenAuthUser, defaultTokenAuthPass)
+	assert.NoError(t, err)
+	apiKey := dataprovider.APIKey{
+		Name:  "test key",
+		Scope: dataprovider.APIKeyScopeAdmin,
+	}
+	asJSON, err := json.Marshal(apiKey)
+	assert.NoError(t, err)
+	req, err := http.NewRequest(http.MethodPost, apiKeysPath, bytes.NewBuffer(asJSON))
+	assert.NoError(t, err)
+	setBearerForReq(req, token)
+	rr := executeRequest(req)
+	checkResponseCode(t, http.StatusCreated, rr)
+	location := rr.Header().Get("Location")
+	assert.NotEmpty(t, location)
+	objectID := rr.Header().Get("X-Object-ID")
+	assert.NotEmpty(t, objectID)
+	assert.Equal(t, fmt.Sprintf("%v/%v", apiKeysPath, objectID), location)
+	apiKey.KeyID = objectID
+	response := make(map[string]string)
+	err = json.Unmarshal(rr.Body.Bytes(), &response)
+	assert.NoError(t, err)
+	key := response["key"]
+	assert.NotEmpty(t, key)
+	assert.True(t, strings.HasPrefix(key, apiKey.KeyID+"."))
+
+	req, err = http.NewRequest(http.MethodGet, location, nil)
+	assert.NoError(t, err)
+	setBearerForReq(req, token)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
+	var keyGet dataprovider.APIKey
+	err = json.Unmarshal(rr.Body.Bytes(), &keyGet)
+	assert.NoError(t, err)
+	assert.Empty(t, keyGet.Key)
+	assert.Equal(t, apiKey.KeyID, keyGet.KeyID)
+	assert.Equal(t, apiKey.Scope, keyGet.Scope)
+	assert.Equal(t, apiKey.Name, keyGet.Name)
+	assert.Equal(t, int64(0), keyGet.ExpiresAt)
+	assert.Equal(t, int64(0), keyGet.LastUseAt)
+	assert.Greater(t, keyGet.CreatedAt, int64(0))
+	assert.Greater(t, keyGet.UpdatedAt, int64(0))
+	assert.Empty(t, keyGet.Description)
+	assert.Empty(t, keyGet.User)
+	assert.Empty(t, keyGet.Admin)
+
+	// API key is not enabled for the admin user so this request should fail
+	req, err = http.NewRequest(http.MethodGet, versionPath, nil)
+	assert.NoError(t, err)
+	setAPIKeyForReq(req, key, admin.Username)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusUnauthorized, rr)
+	assert.Contains(t, rr.Body.String(), "the admin associated with the provided api key cannot be authenticated")
+
+	admin.Filters.AllowAPIKeyAuth = true
+	admin, _, err = httpdtest.UpdateAdmin(admin, http.StatusOK)
+	assert.NoError(t, err)
+	req, err = http.NewRequest(http.MethodGet, versionPath, nil)
+	assert.NoError(t, err)
+	setAPIKeyForReq(req, key, admin.Username)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
+
+	req, err = http.NewRequest(http.MethodGet, versionPath, nil)
+	assert.NoError(t, err)
+	setAPIKeyForReq(req, key, admin.Username+"1")
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusUnauthorized, rr)
+
+	req, err = http.NewRequest(http.MethodGet, versionPath, nil)
+	assert.NoError(t, err)
+	setAPIKeyForReq(req, key, "")
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusUnauthorized, rr)
+
+	// now associate the key directly to the admin
+	apiKey.Admin = admin.Username
+	apiKey.Description = "test description"
+	asJSON, err = json.Marshal(apiKey)
+	assert.NoError(t, err)
+	req, err = http.NewRequest(http.MethodPut, location, bytes.NewBuffer(asJSON))
+	assert.NoError(t, err)
+	setBearerForReq(req, token)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
+
+	req, err = http.NewRequest(http.MethodGet, apiKeysPath, nil)
+	assert.NoError(t, err)
+	setBearerForReq(req, token)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
+	var keys []dataprovider.APIKey
+	err = json.Unmarshal(rr.Body.Bytes(), &keys)
+	assert.NoError(t, err)
+	if assert.GreaterOrEqual(t, len(keys), 1) {
+		found := false
+		for _, k := range keys {
+			if k.KeyID == apiKey.KeyID {
+				found = true
+				assert.Empty(t, k.Key)
+				assert.Equal(t, apiKey.Scope, k.Scope)
+				assert.Equal(t, apiKey.Name, k.Name)
+				assert.Equal(t, int64(0), k.ExpiresAt)
+				assert.Greater(t, k.LastUseAt, int64(0))
+				assert.Equal(t, k.CreatedAt, keyGet.CreatedAt)
+				assert.Greater(t, k.UpdatedAt, keyGet.UpdatedAt)
+				assert.Equal(t, apiKey.Description, k.Description)
+				assert.Empty(t, k.User)
+				assert.Equal(t, admin.Username, k.Admin)
+			}
+		}
+		assert.True(t, found)
+	}
+	req, err = http.NewRequest(http.MethodGet, versionPath, nil)
+	assert.NoError(t, err)
+	setAPIKeyForReq(req, key, "")
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
+	// invalid API keys
+	req, err = http.NewRequest(http.MethodGet, versionPath, nil)
+	assert.NoError(t, err)
+	setAPIKeyForReq(req, key+"invalid", "")
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusUnauthorized, rr)
+	assert.Contains(t, rr.Body.String(), "the provided api key cannot be authenticated")
+	req, err = http.NewRequest(http.MethodGet, versionPath, nil)
+	assert.NoError(t, err)
+	setAPIKeyForReq(req, "invalid", "")
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusBadRequest, rr)
+	// using an API key we cannot modify/get API keys
+	req, err = http.NewRequest(http.MethodPut, location, bytes.NewBuffer(asJSON))
+	assert.NoError(t, err)
+	setAPIKeyForReq(req, key, "")
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusForbidden, rr)
+
+	req, err = http.NewRequest(http.MethodGet, location, nil)
+	assert.NoError(t, err)
+	setAPIKeyForReq(req, key, "")
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusForbidden, rr)
+
+	admin.Filters.AllowList = []string{"172.16.18.0/24"}
+	admin, _, err = httpdtest.UpdateAdmin(admin, http.StatusOK)
+	assert.NoError(t, err)
+	req, err = http.NewRequest(http.MethodGet, versionPath, nil)
+	assert.NoError(t, err)
+	setAPIKeyForReq(req, key, "")
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusUnauthorized, rr)
+
+	req, err = http.NewRequest(http.MethodDelete, location, nil)
+	assert.NoError(t, err)
+	setBearerForReq(req, token)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
+
+	req, err = http.NewRequest(http.MethodGet, versionPath, nil)
+	assert.NoError(t, err)
+	setAPIKeyForReq(req, key, "")
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusBadRequest, rr)
+	assert.Contains(t, rr.Body.String(), "the provided api key is not valid")
+
+	_, err = httpdtest.RemoveAdmin(admin, http.StatusOK)
+	assert.NoError(t, err)
+}
+
+func TestAPIKeySearch(t *testing.T) {
+	token, err := getJWTAPITokenFromTestServer(defaultTokenAuthUser, defaultTokenAuthPass)
+	assert.NoError(t, err)
+	apiKey := dataprovider.APIKey{
+		Scope: dataprovider.APIKeyScopeAdmin,
+	}
+	for i := 1; i < 5; i++ {
+		apiKey.Name = fmt.Sprintf("testapikey%v", i)
+		asJSON, err := json.Marshal(apiKey)
+		assert.NoError(t, err)
+		req, err := http.NewRequest(http.MethodPost, apiKeysPath, bytes.NewBuffer(asJSON))
+		assert.NoError(t, err)
+		setBearerForReq(req, token)
+		rr := executeRequest(req)
+		checkResponseCode(t, http.StatusCreated, rr)
+	}
+
+	req, err := http.NewRequest(http.MethodGet, apiKeysPath+"?limit=1&order=ASC", nil)
+	assert.NoError(t, err)
+	setBearerForReq(req, token)
+	rr := executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
+	var keys []dataprovider.APIKey
+	err = json.Unmarshal(rr.Body.Bytes(), &keys)
+	assert.NoError(t, err)
+	assert.Len(t, keys, 1)
+	firstKey := keys[0]
+
+	req, err = http.NewRequest(http.MethodGet, apiKeysPath+"?limit=1&order=DESC", nil)
+	assert.NoError(t, err)
+	setBearerForReq(req, token)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
+	keys = nil
+	err = json.Unmarshal(rr.Body.Bytes(), &keys)
+	assert.NoError(t, err)
+	if assert.Len(t, keys, 1) {
+		assert.NotEqual(t, firstKey.KeyID, keys[0].KeyID)
+	}
+
+	req, err = http.NewRequest(http.MethodGet, apiKeysPath+"?limit=1&offset=100", nil)
+	assert.NoError(t, err)
+	setBearerForReq(req, token)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
+	keys = nil
+	err = json.Unmarshal(rr.Body.Bytes(), &keys)
+	assert.NoError(t, err)
+	assert.Len(t, keys, 0)
+
+	req, err = http.NewRequest(http.MethodGet, apiKeysPath+"?limit=a", nil)
+	assert.NoError(t, err)
+	setBearerForReq(req, token)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusBadRequest, rr)
+
+	req, err = http.NewRequest(http.MethodGet, fmt.Sprintf("%v/%v", apiKeysPath, "missingid"), nil)
+	assert.NoError(t, err)
+	setBearerForReq(req, token)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusNotFound, rr)
+
+	req, err = http.NewRequest(http.MethodGet, apiKeysPath, nil)
+	assert.NoError(t, err)
+	setBearerForReq(req, token)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
+	keys = nil
+	err = json.Unmarshal(rr.Body.Bytes(), &keys)
+	assert.NoError(t, err)
+	counter := 0
+	for _, k := range keys {
+		if strings.HasPrefix(k.Name, "testapikey") {
+			req, err = http.NewRequest(http.MethodDelete, fmt.Sprintf("%v/%v", apiKeysPath, k.KeyID), nil)
+			assert.NoError(t, err)
+			setBearerForReq(req, token)
+			rr = executeRequest(req)
+			checkResponseCode(t, http.StatusOK, rr)
+			counter++
+		}
+	}
+	assert.Equal(t, 4, counter)
+}
+
+func TestAPIKeyErrors(t *testing.T) {
+	token, err := getJWTAPITokenFromTestServer(defaultTokenAuthUser, defaultTokenAuthPass)
+	assert.NoError(t, err)
+	apiKey := dataprovider.APIKey{
+		Name:  "testkey",
+		Scope: dataprovider.APIKeyScopeUser,
+	}
+	asJSON, err := json.Marshal(apiKey)
+	assert.NoError(t, err)
+	req, err := http.NewRequest(http.MethodPost, apiKeysPath, bytes.NewBuffer(asJSON))
+	assert.NoError(t, err)
+	setBearerForReq(req, token)
+	rr := executeRequest(req)
+	checkResponseCode(t, http.StatusCreated, rr)
+	location := rr.Header().Get("Location")
+	assert.NotEmpty(t, location)
+
+	// invalid API scope
+	apiKey.Scope = 1000
+	asJSON, err = json.Marshal(apiKey)
+	assert.NoError(t, err)
+	req, err = http.NewRequest(http.MethodPost, apiKeysPath, bytes.NewBuffer(asJSON))
+	assert.NoError(t, err)
+	setBearerForReq(req, token)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusBadRequest, rr)
+
+	req, err = http.NewRequest(http.MethodPut, location, bytes.NewBuffer(asJSON))
+	assert.NoError(t, err)
+	setBearerForReq(req, token)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusBadRequest, rr)
+
+	// invalid JSON
+	req, err = http.NewRequest(http.MethodPost, apiKeysPath, bytes.NewBuffer([]byte(`invalid JSON`)))
+	assert.NoError(t, err)
+	setBearerForReq(req, token)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusBadRequest, rr)
+
+	req, err = http.NewRequest(http.MethodPut, location, bytes.NewBuffer([]byte(`invalid JSON`)))
+	assert.NoError(t, err)
+	setBearerForReq(req, token)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusBadRequest, rr)
+
+	req, err = http.NewRequest(http.MethodDelete, location, nil)
+	assert.NoError(t, err)
+	setBearerForReq(req, token)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
+
+	req, err = http.NewRequest(http.MethodDelete, location, nil)
+	assert.NoError(t, err)
+	setBearerForReq(req, token)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusNotFound, rr)
+
+	req, err = http.NewRequest(http.MethodPut, location, bytes.NewBuffer(asJSON))
+	assert.NoError(t, err)
+	setBearerForReq(req, token)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusNotFound, rr)
+}
+
+func TestAPIKeyOnDeleteCascade(t *testing.T) {
+	user, _, err := httpdtest.AddUser(getTestUser(), http.StatusCreated)
+	assert.NoError(t, err)
+	admin := getTestAdmin()
+	admin.Username = altAdminUsername
+	admin.Password = altAdminPassword
+	admin, _, err = httpdtest.AddAdmin(admin, http.StatusCreated)
+	assert.NoError(t, err)
+
+	apiKey := dataprovider.APIKey{
+		Name:  "user api key",
+		Scope: dataprovider.APIKeyScopeUser,
+		User:  user.Username,
+	}
+
+	apiKey, _, err = httpdtest.AddAPIKey(apiKey, http.StatusCreated)
+	assert.NoError(t, err)
+
+	req, err := http.NewRequest(http.MethodGet, userDirsPath, nil)
+	assert.NoError(t, err)
+	setAPIKeyForReq(req, apiKey.Key, "")
+	rr := executeRequest(req)
+	checkResponseCode(t, http.StatusUnauthorized, rr)
+
+	user.Filters.AllowAPIKeyAuth = true
+	user, _, err = httpdtest.UpdateUser(user, http.StatusOK, "")
+	assert.NoError(t, err)
+	req, err = http.NewRequest(http.MethodGet, userDirsPath, nil)
+	assert.NoError(t, err)
+	setAPIKeyForReq(req, apiKey.Key, "")
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
+	var contents []map[string]interface{}
+	err = json.NewDecoder(rr.Body).Decode(&contents)
+	assert.NoError(t, err)
+	assert.Len(t, contents, 0)
+
+	_, err = httpdtest.RemoveUser(user, http.StatusOK)
+	assert.NoError(t, err)
+	err = os.RemoveAll(user.GetHomeDir())
+	assert.NoError(t, err)
+
+	_, _, err = httpdtest.GetAPIKeyByID(apiKey.KeyID, http.StatusNotFound)
+	assert.NoError(t, err)
+
+	apiKey.User = ""
+	apiKey.Admin = admin.Username
+	apiKey.Scope = dataprovider.APIKeyScopeAdmin
+
+	apiKey, _, err = httpdtest.AddAPIKey(apiKey, http.StatusCreated)
+	assert.NoError(t, err)
+
+	_, err = httpdtest.RemoveAdmin(admin, http.StatusOK)
+	assert.NoError(t, err)
+
+	_, _, err = httpdtest.GetAPIKeyByID(apiKey.KeyID, http.StatusNotFound)
+	assert.NoError(t, err)
+}
+
+func TestBasicWebUsersMock(t *testing.T) {
+	token, err := getJWTAPITokenFromTestServer(defaultTokenAuthUser, defaultTokenAuthPass)
+	assert.NoError(t, err)
+	user := getTestUser()
+	userAsJSON := getUserAsJSON(t, user)
+	req, _ := http.NewRequest(http.MethodPost, userPath, bytes.NewBuffer(userAsJSON))
+	setBearerForReq(req, token)
+	rr := executeRequest(req)
+	checkResponseCode(t, http.StatusCreated, rr)
+	err = render.DecodeJSON(rr.Body, &user)
+	assert.NoError(t, err)
+	user1 := getTestUser()
+	user1.Username += "1"
+	user1AsJSON := getUserAsJSON(t, user1)
+	req, _ = http.NewRequest(http.MethodPost, userPath, bytes.NewBuffer(user1AsJSON))
+	setBearerForReq(req, token)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusCreated, rr)
+	err = render.DecodeJSON(rr.Body, &user1)
+	assert.NoError(t, err)
+	webToken, err := getJWTWebTokenFromTestServer(defaultTokenAuthUser, defaultTokenAuthPass)
+	assert.NoError(t, err)
+	req, _ = http.NewRequest(http.MethodGet, webUsersPath, nil)
+	setJWTCookieForReq(req, webToken)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
+	req, _ = http.NewRequest(http.MethodGet, webUsersPath+"?qlimit=a", nil)
+	setJWTCookieForReq(req, webToken)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
+	req, _ = http.NewRequest(http.MethodGet, webUsersPath+"?qlimit=1", nil)
+	setJWTCookieForReq(req, webToken)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
+	req, _ = http.NewRequest(http.MethodGet, webUserPath, nil)
+	setJWTCookieForReq(req, webToken)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
+	req, _ = http.NewRequest(http.MethodGet, path.Join(webUserPath, user.Username), nil)
+	setJWTCookieForReq(req, webToken)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
+	req, _ = http.NewRequest(http.MethodGet, webUserPath+"/0", nil)
+	setJWTCookieForReq(req, webToken)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusNotFound, rr)
+	csrfToken, err := getCSRFToken(httpBaseURL + webLoginPath)
+	assert.NoError(t, err)
+	form := make(url.Values)
+	form.Set("username", user.Username)
+	form.Set(csrfFormToken, csrfToken)
+	b, contentType, _ := getMultipartFormData(form, "", "")
+	req, _ = http.NewRequest(http.MethodPost, webUserPath, &b)
+	setJWTCookieForReq(req, webToken)
+	req.Header.Set("Content-Type", contentType)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
+	b, contentType, _ = getMultipartFormData(form, "", "")
+	req, _ = http.NewRequest(http.MethodPost, path.Join(webUserPath, user.Username), &b)
+	setJWTCookieForReq(req, webToken)
+	req.Header.Set("Content-Type", contentType)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
+	b, contentType, _ = getMultipartFormData(form, "", "")
+	req, _ = http.NewRequest(http.MethodPost, webUserPath+"/0", &b)
+	setJWTCookieForReq(req, webToken)
+	req.Header.Set("Content-Type", contentType)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusNotFound, rr)
+	req, _ = http.NewRequest(http.MethodPost, webUserPath+"/aaa", &b)
+	setJWTCookieForReq(req, webToken)
+	req.Header.Set("Content-Type", contentType)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusNotFound, rr)
+	req, _ = http.NewRequest(http.MethodDelete, path.Join(webUserPath, user.Username), nil)
+	setJWTCookieForReq(req, webToken)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusForbidden, rr)
+	assert.Contains(t, rr.Body.String(), "Invalid token")
+	req, _ = http.NewRequest(http.MethodDelete, path.Join(webUserPath, user.Username), nil)
+	setJWTCookieForReq(req, webToken)
+	setCSRFHeaderForReq(req, csrfToken)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
+	req, _ = http.NewRequest(http.MethodDelete, path.Join(webUserPath, user1.Username), nil)
+	setJWTCookieForReq(req, webToken)
+	setCSRFHeaderForReq(req, csrfToken)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
+}
+
+func TestRenderDefenderPageMock(t *testing.T) {
+	token, err := getJWTWebTokenFromTestServer(defaultTokenAuthUser, defaultTokenAuthPass)
+	assert.NoError(t, err)
+	req, err := http.NewRequest(http.MethodGet, webDefenderPath, nil)
+	assert.NoError(t, err)
+	setJWTCookieForReq(req, token)
+	rr := executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
+	assert.Contains(t, rr.Body.String(), "View and manage blocklist")
+}
+
+func TestWebAccountLockoutsMock(t *testing.T) {
+	err := dataprovider.Close()
+	assert.NoError(t, err)
+	err = config.LoadConfig(configDir, "")
+	assert.NoError(t, err)
+	providerConf := config.GetProviderConf()
+	providerConf.CredentialsPath = credentialsPath
+	providerConf.AccountLockout.Enabled = true
+	providerConf.AccountLockout.MaxFailures = 2
+	err = dataprovider.Initialize(providerConf, configDir, true)
+	assert.NoError(t, err)
+
+	user, _, err := httpdtest.AddUser(getTestUser(), http.StatusCreated)
+	assert.NoError(t, err)
+	for i := 0; i < 2; i++ {
+		_, err = dataprovider.CheckUserAndPass(user.Username, "wrong", "127.0.0.1", common.ProtocolSSH)
+		assert.ErrorIs(t, err, dataprovider.ErrInvalidCredentials)
+	}
+
+	webToken, err := getJWTWebTokenFromTestServer(defaultTokenAuthUser, defaultTokenAuthPass)
+	assert.NoError(t, err)
+	csrfToken, err := getCSRFToken(httpBaseURL + webLoginPath)
+	assert.NoError(t, err)
+	req, err := http.NewRequest(http.MethodGet, webAccountLockoutsPath, nil)
+	assert.NoError(t, err)
+	setJWTCookieForReq(req, webToken)
+	rr := executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
+	assert.Contains(t, rr.Body.String(), "View and unlock accounts")
+
+	req, err = http.NewRequest(http.MethodGet, webAccountLockoutsListPath, nil)
+	assert.NoError(t, err)
+	setJWTCookieForReq(req, webToken)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
+	var lockouts []dataprovider.AccountLockout
+	err = json.Unmarshal(rr.Body.Bytes(), &lockouts)
+	assert.NoError(t, err)
+	if assert.Len(t, lockouts, 1) {
+		assert.True(t, lockouts[0].IsLocked())
+		assert.Greater(t, lockouts[0].LockedUntil, lockouts[0].LockedAt)
+	}
+
+	req, err = http.NewRequest(http.MethodDelete, path.Join(webAccountLockoutsListPath, dataprovider.AccountTypeUser,
+		user.Username), nil)
+	assert.NoError(t, err)
+	setJWTCookieForReq(req, webToken)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusForbidden, rr)
+	assert.Contains(t, rr.Body.String(), "Invalid token")
+
+	req, err = http.NewRequest(http.MethodDelete, path.Join(webAccountLockoutsListPath, dataprovider.AccountTypeUser,
+		user.Username), nil)
+	assert.NoError(t, err)
+	setJWTCookieForReq(req, webToken)
+	setCSRFHeaderForReq(req, csrfToken)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
+	_, err = dataprovider.CheckUserAndPass(user.Username, defaultPassword, "127.0.0.1", common.ProtocolSSH)
+	assert.NoError(t, err)
+
+	_, err = httpdtest.RemoveUser(user, http.StatusOK)
+	assert.NoError(t, err)
+	err = os.RemoveAll(user.GetHomeDir())
+	assert.NoError(t, err)
+
+	err = dataprovider.Close()
+	assert.NoError(t, err)
+	err = config.LoadConfig(configDir, "")
+	assert.NoError(t, err)
+	providerConf = config.GetProviderConf()
+	providerConf.CredentialsPath = credentialsPath
+	err = os.RemoveAll(credentialsPath)
+	assert.NoError(t, err)
+	err = dataprovider.Initialize(providerConf, configDir, true)
+	assert.NoError(t, err)
+}
+
+func TestWebAdminBasicMock(t *testing.T) {
+	token, err := getJWTWebTokenFromTestServer(defaultTokenAuthUser, defaultTokenAuthPass)
+	assert.NoError(t, err)
+	admin := getTestAdmin()
+	admin.Username = altAdminUsername
+	admin.Password = altAdminPassword
+	csrfToken, err := getCSRFToken(httpBaseURL + webLoginPath)
+	assert.NoError(t, err)
+	form := make(url.Values)
+	form.Set("username", admin.Username)
+	form.Set("password", "")
+	form.Set("status", "1")
+	form.Set("permissions", "*")
+	form.Set("description", admin.Description)
+	req, _ := http.NewRequest(http.MethodPost, webAdminPath, bytes.NewBuffer([]byte(form.Encode())))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	setJWTCookieForReq(req, token)
+	rr := executeRequest(req)
+	checkResponseCode(t, http.StatusForbidden, rr)
+	assert.Contains(t, rr.Body.String(), "unable to verify form token")
+
+	form.Set(csrfFormToken, csrfToken)
+	form.Set("status", "a")
+	req, _ = http.NewRequest(http.MethodPost, webAdminPath, bytes.NewBuffer([]byte(form.Encode())))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	setJWTCookieForReq(req, token)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
+
+	form.Set("status", "1")
+	req, _ = http.NewRequest(http.MethodPost, webAdminPath, bytes.NewBuffer([]byte(form.Encode())))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	setJWTCookieForReq(req, token)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
+
+	form.Set("password", admin.Password)
+	req, _ = http.NewRequest(http.MethodPost, webAdminPath, bytes.NewBuffer([]byte(form.Encode())))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	setJWTCookieForReq(req, token)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusSeeOther, rr)
+
+	// add TOTP config
+	configName, _, secret, _, err := mfa.GenerateTOTPSecret(mfa.GetAvailableTOTPConfigNames()[0], altAdminUsername)
+	assert.NoError(t, err)
+	altToken, err := getJWTWebTokenFromTestServer(altAdminUsername, altAdminPassword)
+	assert.NoError(t, err)
+	adminTOTPConfig := dataprovider.TOTPConfig{
+		Enabled:    true,
+		ConfigName: configName,
+		Secret:     kms.NewPlainSecret(secret),
+	}
+	asJSON, err := json.Marshal(adminTOTPConfig)
+	assert.NoError(t, err)
+	// no CSRF token
+	req, err = http.NewRequest(http.MethodPost, webAdminTOTPSavePath, bytes.NewBuffer(asJSON))
+	assert.NoError(t, err)
+	setJWTCookieForReq(req, altToken)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusForbidden, rr)
+	assert.Contains(t, rr.Body.String(), "Invalid token")
+
+	req, err = http.NewRequest(http.MethodPost, webAdminTOTPSavePath, bytes.NewBuffer(asJSON))
+	assert.NoError(t, err)
+	setJWTCookieForReq(req, altToken)
+	setCSRFHeaderForReq(req, csrfToken)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
+
+	admin, _, err = httpdtest.GetAdminByUsername(altAdminUsername, http.StatusOK)
+	assert.NoError(t, err)
+	assert.True(t, admin.Filters.TOTPConfig.Enabled)
+	secretPayload := admin.Filters.TOTPConfig.Secret.GetPayload()
+	assert.NotEmpty(t, secretPayload)
+
+	adminTOTPConfig = dataprovider.TOTPConfig{
+		Enabled:    true,
+		ConfigName: configName,
+		Secret:     kms.NewEmptySecret(),
+	}
+	asJSON, err = json.Marshal(adminTOTPConfig)
+	assert.NoError(t, err)
+	req, err = http.NewRequest(http.MethodPost, webAdminTOTPSavePath, bytes.NewBuffer(asJSON))
+	assert.NoError(t, err)
+	setJWTCookieForReq(req, altToken)
+	setCSRFHeaderForReq(req, csrfToken)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
+
+	admin, _, err = httpdtest.GetAdminByUsername(altAdminUsername, http.StatusOK)
+	assert.NoError(t, err)
+	assert.True(t, admin.Filters.TOTPConfig.Enabled)
+	assert.Equal(t, secretPayload, admin.Filters.TOTPConfig.Secret.GetPayload())
+
+	adminTOTPConfig = dataprovider.TOTPConfig{
+		Enabled:    true,
+		ConfigName: configName,
+		Secret:     nil,
+	}
+	asJSON, err = json.Marshal(adminTOTPConfig)
+	assert.NoError(t, err)
+	req, err = http.NewRequest(http.MethodPost, webAdminTOTPSavePath, bytes.NewBuffer(asJSON))
+	assert.NoError(t, err)
+	setJWTCookieForReq(req, altToken)
+	setCSRFHeaderForReq(req, csrfToken)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
+
+	req, _ = http.NewRequest(http.MethodGet, webAdminsPath+"?qlimit=a", nil)
+	setJWTCookieForReq(req, token)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
+	req, _ = http.NewRequest(http.MethodGet, webAdminsPath+"?qlimit=1", nil)
+	setJWTCookieForReq(req, token)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
+
+	req, _ = http.NewRequest(http.MethodGet, webAdminPath, nil)
+	setJWTCookieForReq(req, token)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
+
+	form.Set("password", "")
+	req, _ = http.NewRequest(http.MethodPost, path.Join(webAdminPath, altAdminUsername), bytes.NewBuffer([]byte(form.Encode())))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	setJWTCookieForReq(req, token)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusSeeOther, rr)
+
+	form.Set(csrfFormToken, "invalid csrf")
+	req, _ = http.NewRequest(http.MethodPost, path.Join(webAdminPath, altAdminUsername), bytes.NewBuffer([]byte(form.Encode())))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	setJWTCookieForReq(req, token)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusForbidden, rr)
+	assert.Contains(t, rr.Body.String(), "unable to verify form token")
+
+	form.Set(csrfFormToken, csrfToken)
+	form.Set("email", "not-an-email")
+	req, _ = http.NewRequest(http.MethodPost, path.Join(webAdminPath, altAdminUsername), bytes.NewBuffer([]byte(form.Encode())))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	setJWTCookieForReq(req, token)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
+
+	form.Set("email", "")
+	form.Set("status", "b")
+	req, _ = http.NewRequest(http.MethodPost, path.Join(webAdminPath, altAdminUsername), bytes.NewBuffer([]byte(form.Encode())))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	setJWTCookieForReq(req, token)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
+
+	form.Set("email", "admin@example.com")
+	form.Set("status", "0")
+	req, _ = http.NewRequest(http.MethodPost, path.Join(webAdminPath, altAdminUsername), bytes.NewBuffer([]byte(form.Encode())))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	setJWTCookieForReq(req, token)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusSeeOther, rr)
+
+	admin, _, err = httpdtest.GetAdminByUsername(altAdminUsername, http.StatusOK)
+	assert.NoError(t, err)
+	assert.True(t, admin.Filters.TOTPConfig.Enabled)
+	assert.Equal(t, "admin@example.com", admin.Email)
+	assert.Equal(t, 0, admin.Status)
+
+	req, _ = http.NewRequest(http.MethodPost, path.Join(webAdminPath, altAdminUsername+"1"), bytes.NewBuffer([]byte(form.Encode())))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	setJWTCookieForReq(req, token)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusNotFound, rr)
+
+	req, _ = http.NewRequest(http.MethodGet, path.Join(webAdminPath, altAdminUsername), nil)
+	setJWTCookieForReq(req, token)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
+
+	req, _ = http.NewRequest(http.MethodGet, path.Join(webAdminPath, altAdminUsername+"1"), nil)
+	setJWTCookieForReq(req, token)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusNotFound, rr)
+
+	req, _ = http.NewRequest(http.MethodDelete, path.Join(webAdminPath, altAdminUsername), nil)
+	setJWTCookieForReq(req, token)
+	setCSRFHeaderForReq(req, csrfToken)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
+
+	_, err = httpdtest.RemoveAdmin(admin, http.StatusNotFound)
+	assert.NoError(t, err)
+
+	req, _ = http.NewRequest(http.MethodDelete, path.Join(webAdminPath, defaultTokenAuthUser), nil)
+	setJWTCookieForReq(req, token)
+	setCSRFHeaderForReq(req, csrfToken)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusBadRequest, rr)
+	assert.Contains(t, rr.Body.String(), "you cannot delete yourself")
+
+	req, _ = http.NewRequest(http.MethodDelete, path.Join(webAdminPath, defaultTokenAuthUser), nil)
+	setJWTCookieForReq(req, token)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusForbidden, rr)
+	assert.Contains(t, rr.Body.String(), "Invalid token")
+}
+
+func TestWebScopedAdminMock(t *testing.T) {
+	token, err := getJWTWebTokenFromTestServer(defaultTokenAuthUser, defaultTokenAuthPass)
+	assert.NoError(t, err)
+	group, _, err := httpdtest.AddGroup(dataprovider.Group{Name: "scope_web_group"}, http.StatusCreated)
+	assert.NoError(t, err)
+	u1 := getTestUser()
+	u1.Username = "scope_web_user1"
+	user1, _, err := httpdtest.AddUser(u1, http.StatusCreated)
+	assert.NoError(t, err)
+	u2 := getTestUser()
+	u2.Username = "other_web_user2"
+	user2, _, err := httpdtest.AddUser(u2, http.StatusCreated)
+	assert.NoError(t, err)
+
+	req, _ := http.NewRequest(http.MethodGet, webAdminPath, nil)
+	setJWTCookieForReq(req, token)
+	rr := executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
+	assert.Contains(t, rr.Body.String(), group.Name)
+
+	csrfToken, err := getCSRFToken(httpBaseURL + webLoginPath)
+	assert.NoError(t, err)
+	form := make(url.Values)
+	form.Set(csrfFormToken, csrfToken)
+	form.Set("username", altAdminUsername)
+	form.Set("password", altAdminPassword)
+	form.Set("status", "1")
+	form.Set("permissions", "*")
+	form.Set("scope_groups", group.Name)
+	form.Set("scope_username_prefixes", "scope_web_, ,scope_web_")
+	form.Set("scope_created_users", "1")
+	req, _ = http.NewRequest(http.MethodPost, webAdminPath, bytes.NewBuffer([]byte(form.Encode())))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	setJWTCookieForReq(req, token)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
+	assert.Contains(t, rr.Body.String(), "is not allowed for admins with a scope")
+
+	form["permissions"] = []string{dataprovider.PermAdminViewUsers, dataprovider.PermAdminAddUsers,
+		dataprovider.PermAdminChangeUsers, dataprovider.PermAdminViewConnections, dataprovider.PermAdminManageSystem}
+	req, _ = http.NewRequest(http.MethodPost, webAdminPath, bytes.NewBuffer([]byte(form.Encode())))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	setJWTCookieForReq(req, token)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusSeeOther, rr)
+
+	admin, _, err := httpdtest.GetAdminByUsername(altAdminUsername, http.StatusOK)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{group.Name}, admin.Filters.Scope.Groups)
+	assert.Equal(t, []string{"scope_web_"}, admin.Filters.Scope.UsernamePrefixes)
+	assert.True(t, admin.Filters.Scope.CreatedUsers)
+
+	req, _ = http.NewRequest(http.MethodGet, path.Join(webAdminPath, altAdminUsername), nil)
+	setJWTCookieForReq(req, token)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
+	assert.Contains(t, rr.Body.String(), "scope_web_")
+
+	altToken, err := getJWTWebTokenFromTestServer(altAdminUsername, altAdminPassword)
+	assert.NoError(t, err)
+	req, _ = http.NewRequest(http.MethodGet, webUsersPath, nil)
+	setJWTCookieForReq(req, altToken)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
+	assert.Contains(t, rr.Body.String(), user1.Username)
+	assert.NotContains(t, rr.Body.String(), user2.Username)
+
+	req, _ = http.NewRequest(http.MethodGet, path.Join(webUserPath, user1.Username), nil)
+	setJWTCookieForReq(req, altToken)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
+
+	req, _ = http.NewRequest(http.MethodGet, path.Join(webUserPath, user2.Username), nil)
+	setJWTCookieForReq(req, altToken)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusNotFound, rr)
+
+	req, _ = http.NewRequest(http.MethodGet, webUserPath+"?clone-from="+user2.Username, nil)
+	setJWTCookieForReq(req, altToken)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusNotFound, rr)
+
+	form = make(url.Values)
+	form.Set(csrfFormToken, csrfToken)
+	b, contentType, _ := getMultipartFormData(form, "", "")
+	req, _ = http.NewRequest(http.MethodPost, path.Join(webUserPath, user2.Username), &b)
+	setJWTCookieForReq(req, altToken)
+	req.Header.Set("Content-Type", contentType)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusNotFound, rr)
+
+	req, _ = http.NewRequest(http.MethodGet, webGroupPath, nil)
+	setJWTCookieForReq(req, altToken)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusForbidden, rr)
+
+	req, _ = http.NewRequest(http.MethodGet, webGroupsPath, nil)
+	setJWTCookieForReq(req, altToken)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
+	assert.Contains(t, rr.Body.String(), group.Name)
+
+	req, _ = http.NewRequest(http.MethodGet, webConnectionsPath, nil)
+	setJWTCookieForReq(req, altToken)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
+
+	form = make(url.Values)
+	form.Set(csrfFormToken, csrfToken)
+	form.Set("mode", "0")
+	b, contentType, _ = getMultipartFormData(form, "backup_file", "")
+	req, _ = http.NewRequest(http.MethodPost, webRestorePath, &b)
+	setJWTCookieForReq(req, altToken)
+	req.Header.Set("Content-Type", contentType)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusForbidden, rr)
+	assert.Contains(t, rr.Body.String(), "Admins with a scope cannot restore backups")
+
+	_, err = httpdtest.RemoveAdmin(admin, http.StatusOK)
+	assert.NoError(t, err)
+	for _, u := range []dataprovider.User{user1, user2} {
+		_, err = httpdtest.RemoveUser(u, http.StatusOK)
+		assert.NoError(t, err)
+		err = os.RemoveAll(u.GetHomeDir())
+		assert.NoError(t, err)
+	}
+	_, err = httpdtest.RemoveGroup(group, http.StatusOK)
+	assert.NoError(t, err)
+}
+
+func TestWebAdminPermissions(t *testing.T) {
+	admin := getTestAdmin()
+	admin.Username = altAdminUsername
+	admin.Password = altAdminPassword
+	admin.Permissions = []string{dataprovider.PermAdminAddUsers}
+	_, _, err := httpdtest.AddAdmin(admin, http.StatusCreated)
+	assert.NoError(t, err)
+
+	token, err := getJWTWebToken(altAdminUsername, altAdminPassword)
+	require.NoError(t, err)
+
+	req, err := http.NewRequest(http.MethodGet, httpBaseURL+webUserPath, nil)
+	assert.NoError(t, err)
+	setJWTCookieForReq(req, token)
+	resp, err := httpclient.GetHTTPClient().Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	req, err = http.NewRequest(http.MethodGet, httpBaseURL+path.Join(webUserPath, "auser"), nil)
+	assert.NoError(t, err)
+	setJWTCookieForReq(req, token)
+	resp, err = httpclient.GetHTTPClient().Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+	req, err = http.NewRequest(http.MethodGet, httpBaseURL+webFolderPath, nil)
+	assert.NoError(t, err)
+	setJWTCookieForReq(req, token)
+	resp, err = httpclient.GetHTTPClient().Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	req, err = http.NewRequest(http.MethodGet, httpBaseURL+webStatusPath, nil)
+	assert.NoError(t, err)
+	setJWTCookieForReq(req, token)
+	resp, err = httpclient.GetHTTPClient().Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+	req, err = http.NewRequest(http.MethodGet, httpBaseURL+webConnectionsPath, nil)
+	assert.NoError(t, err)
+	setJWTCookieForReq(req, token)
+	resp, err = httpclient.GetHTTPClient().Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+	req, err = http.NewRequest(http.MethodGet, httpBaseURL+webAdminPath, nil)
+	assert.NoError(t, err)
+	setJWTCookieForReq(req, token)
+	resp, err = httpclient.GetHTTPClient().Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+	req, err = http.NewRequest(http.MethodGet, httpBaseURL+path.Join(webAdminPath, "a"), nil)
+	assert.NoError(t, err)
+	setJWTCookieForReq(req, token)
+	resp, err = httpclient.GetHTTPClient().Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+	_, err = httpdtest.RemoveAdmin(admin, http.StatusOK)
+	assert.NoError(t, err)
+}
+
+func TestAdminUpdateSelfMock(t *testing.T) {
+	admin, _, err := httpdtest.GetAdminByUsername(defaultTokenAuthUser, http.StatusOK)
+	assert.NoError(t, err)
+	token, err := getJWTWebTokenFromTestServer(defaultTokenAuthUser, defaultTokenAuthPass)
+	assert.NoError(t, err)
+	csrfToken, err := getCSRFToken(httpBaseURL + webLoginPath)
+	assert.NoError(t, err)
+	form := make(url.Values)
+	form.Set("username", admin.Username)
+	form.Set("password", admin.Password)
+	form.Set("status", "0")
+	form.Set("permissions", dataprovider.PermAdminAddUsers)
+	form.Set("permissions", dataprovider.PermAdminCloseConnections)
+	form.Set(csrfFormToken, csrfToken)
+	req, _ := http.NewRequest(http.MethodPost, path.Join(webAdminPath, defaultTokenAuthUser), bytes.NewBuffer([]byte(form.Encode())))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	setJWTCookieForReq(req, token)
+	rr := executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
+	assert.Contains(t, rr.Body.String(), "You cannot remove these permissions to yourself")
+
+	form.Set("permissions", dataprovider.PermAdminAny)
+	req, _ = http.NewRequest(http.MethodPost, path.Join(webAdminPath, defaultTokenAuthUser), bytes.NewBuffer([]byte(form.Encode())))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	setJWTCookieForReq(req, token)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
+	assert.Contains(t, rr.Body.String(), "You cannot disable yourself")
+}
+
+func TestWebMaintenanceMock(t *testing.T) {
+	token, err := getJWTWebTokenFromTestServer(defaultTokenAuthUser, defaultTokenAuthPass)
+	assert.NoError(t, err)
+	req, _ := http.NewRequest(http.MethodGet, webMaintenancePath, nil)
+	setJWTCookieForReq(req, token)
+	rr := executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
+	csrfToken, err := getCSRFToken(httpBaseURL + webLoginPath)
+	assert.NoError(t, err)
+
+	form := make(url.Values)
+	form.Set("mode", "a")
+	b, contentType, _ := getMultipartFormData(form, "", "")
+	req, _ = http.NewRequest(http.MethodPost, webRestorePath, &b)
+	setJWTCookieForReq(req, token)
+	req.Header.Set("Content-Type", contentType)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusForbidden, rr)
+	assert.Contains(t, rr.Body.String(), "unable to verify form token")
+
+	form.Set(csrfFormToken, csrfToken)
+	b, contentType, _ = getMultipartFormData(form, "", "")
+	req, _ = http.NewRequest(http.MethodPost, webRestorePath, &b)
+	setJWTCookieForReq(req, token)
+	req.Header.Set("Content-Type", contentType)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
+
+	form.Set("mode", "0")
+	form.Set("quota", "a")
+	b, contentType, _ = getMultipartFormData(form, "", "")
+	req, _ = http.NewRequest(http.MethodPost, webRestorePath, &b)
+	setJWTCookieForReq(req, token)
+	req.Header.Set("Content-Type", contentType)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
+
+	form.Set("quota", "0")
+	b, contentType, _ = getMultipartFormData(form, "", "")
+	req, _ = http.NewRequest(http.MethodPost, webRestorePath, &b)
+	setJWTCookieForReq(req, token)
+	req.Header.Set("Content-Type", contentType)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
+
+	req, _ = http.NewRequest(http.MethodPost, webRestorePath+"?a=%3", &b)
+	setJWTCookieForReq(req, token)
+	req.Header.Set("Content-Type", contentType)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
+
+	backupFilePath := filepath.Join(os.TempDir(), "backup.json")
+	err = createTestFile(backupFilePath, 0)
+	assert.NoError(t, err)
+
+	b, contentType, _ = getMultipartFormData(form, "backup_file", backupFilePath)
+	req, _ = http.NewRequest(http.MethodPost, webRestorePath, &b)
+	setJWTCookieForReq(req, token)
+	req.Header.Set("Content-Type", contentType)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
+
+	err = createTestFile(backupFilePath, 10)
+	assert.NoError(t, err)
+
+	b, contentType, _ = getMultipartFormData(form, "backup_file", backupFilePath)
+	req, _ = http.NewRequest(http.MethodPost, webRestorePath, &b)
+	setJWTCookieForReq(req, token)
+	req.Header.Set("Content-Type", contentType)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
+
+	user := getTestUser()
+	user.ID = 1
+	user.Username = "test_user_web_restore"
+	admin := getTestAdmin()
+	admin.ID = 1
+	admin.Username = "test_admin_web_restore"
+
+	apiKey := dataprovider.APIKey{
+		Name:  "key name",
+		KeyID: shortuuid.New(),
+		Key:   fmt.Sprintf("%v.%v", shortuuid.New(), shortuuid.New()),
+		Scope: dataprovider.APIKeyScopeAdmin,
+	}
+	backupData := dataprovider.BackupData{}
+	backupData.Users = append(backupData.Users, user)
+	backupData.Admins = append(backupData.Admins, admin)
+	backupData.APIKeys = append(backupData.APIKeys, apiKey)
+	backupContent, err := json.Marshal(backupData)
+	assert.NoError(t, err)
+	err = os.WriteFile(backupFilePath, backupContent, os.ModePerm)
+	assert.NoError(t, err)
+
+	b, contentType, _ = getMultipartFormData(form, "backup_file", backupFilePath)
+	req, _ = http.NewRequest(http.MethodPost, webRestorePath, &b)
+	setJWTCookieForReq(req, token)
+	req.Header.Set("Content-Type", contentType)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
+	assert.Contains(t, rr.Body.String(), "Your backup was successfully restored")
+
+	user, _, err = httpdtest.GetUserByUsername(user.Username, http.StatusOK)
+	assert.NoError(t, err)
+	_, err = httpdtest.RemoveUser(user, http.StatusOK)
+	assert.NoError(t, err)
+
+	admin, _, err = httpdtest.GetAdminByUsername(admin.Username, http.StatusOK)
+	assert.NoError(t, err)
+	_, err = httpdtest.RemoveAdmin(admin, http.StatusOK)
+	assert.NoError(t, err)
+
+	_, _, err = httpdtest.GetAPIKeyByID(apiKey.KeyID, http.StatusOK)
+	assert.NoError(t, err)
+	_, err = httpdtest.RemoveAPIKey(apiKey, http.StatusOK)
+	assert.NoError(t, err)
+
+	err = os.Remove(backupFilePath)
+	assert.NoError(t, err)
+}
+
+func TestWebUserAddMock(t *testing.T) {
+	webToken, err := getJWTWebTokenFromTestServer(defaultTokenAuthUser, defaultTokenAuthPass)
+	assert.NoError(t, err)
+	apiToken, err := getJWTAPITokenFromTestServer(defaultTokenAuthUser, defaultTokenAuthPass)
+	assert.NoError(t, err)
+	csrfToken, err := getCSRFToken(httpBaseURL + webLoginPath)
+	assert.NoError(t, err)
+	user := getTestUser()
+	user.UploadBandwidth = 32
+	user.DownloadBandwidth = 64
+	user.UID = 1000
+	user.AdditionalInfo = "info"
+	user.Description = "user dsc"
+	user.Email = "test@test.com"
+	user.UploadDataTransfer = 100
+	user.TotalDataTransfer = 200
+	user.DataTransferResetPeriod = sdk.DataTransferResetWeekly
+	mappedDir := filepath.Join(os.TempDir(), "mapped")
+	folderName := filepath.Base(mappedDir)
+	f := vfs.BaseVirtualFolder{
+		Name:       folderName,
+		MappedPath: mappedDir,
+	}
+	folderAsJSON, err := json.Marshal(f)
+	assert.NoError(t, err)
+	req, _ := http.NewRequest(http.MethodPost, folderPath, bytes.NewBuffer(folderAsJSON))
+	setBearerForReq(req, apiToken)
+	rr := executeRequest(req)
+	checkResponseCode(t, http.StatusCreated, rr)
+
+	form := make(url.Values)
+	form.Set(csrfFormToken, csrfToken)
+	form.Set("username", user.Username)
+	form.Set("email", user.Email)
+	form.Set("home_dir", user.HomeDir)
+	form.Set("password", user.Password)
+	form.Set("status", strconv.Itoa(user.Status))
+	form.Set("expiration_date", "")
+	form.Set("permissions", "*")
+	form.Set("sub_perm_path0", "/subdir")
+	form.Set("sub_perm_permissions0", "list")
+	form.Add("sub_perm_permissions0", "download")
+	form.Set("vfolder_path", " /vdir")
+	form.Set("vfolder_name", folderName)
+	form.Set("vfolder_quota_size", "1024")
+	form.Set("vfolder_quota_files", "2")
+	form.Set("pattern_path0", "/dir2")
+	form.Set("patterns0", "*.jpg,*.png")
+	form.Set("pattern_type0", "allowed")
+	form.Set("pattern_path1", "/dir1")
+	form.Set("patterns1", "*.png")
+	form.Set("pattern_type1", "allowed")
+	form.Set("pattern_path2", "/dir1")
+	form.Set("patterns2", "*.zip")
+	form.Set("pattern_type2", "denied")
+	form.Set("pattern_path3", "/dir3")
+	form.Set("patterns3", "*.rar")
+	form.Set("pattern_type3", "denied")
+	form.Set("pattern_path4", "/dir2")
+	form.Set("patterns4", "*.mkv")
+	form.Set("pattern_type4", "denied")
+	form.Set("additional_info", user.AdditionalInfo)
+	form.Set("description", user.Description)
+	form.Add("hooks", "external_auth_disabled")
+	form.Set("disable_fs_checks", "checked")
+	form.Set("upload_data_transfer", strconv.FormatInt(user.UploadDataTransfer, 10))
+	form.Set("data_transfer_reset_period", string(user.DataTransferResetPeriod))
+	b, contentType, _ := getMultipartFormData(form, "", "")
+	// test invalid url escape
+	req, _ = http.NewRequest(http.MethodPost, webUserPath+"?a=%2", &b)
+	setJWTCookieForReq(req, webToken)
+	req.Header.Set("Content-Type", contentType)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
+	form.Set("public_keys", testPubKey)
+	form.Add("public_keys", testPubKey1)
+	form.Set("uid", strconv.FormatInt(int64(user.UID), 10))
+	form.Set("gid", "a")
+	b, contentType, _ = getMultipartFormData(form, "", "")
+	// test invalid gid
+	req, _ = http.NewRequest(http.MethodPost, webUserPath, &b)
+	setJWTCookieForReq(req, webToken)
+	req.Header.Set("Content-Type", contentType)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
+	form.Set("gid", "0")
+	form.Set("max_sessions", "a")
+	b, contentType, _ = getMultipartFormData(form, "", "")
+	// test invalid max sessions
+	req, _ = http.NewRequest(http.MethodPost, webUserPath, &b)
+	setJWTCookieForReq(req, webToken)
+	req.Header.Set("Content-Type", contentType)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
+	form.Set("max_sessions", "0")
+	form.Set("quota_size", "a")
+	b, contentType, _ = getMultipartFormData(form, "", "")
+	// test invalid quota size
+	req, _ = http.NewRequest(http.MethodPost, webUserPath, &b)
+	setJWTCookieForReq(req, webToken)
+	req.Header.Set("Content-Type", contentType)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
+	form.Set("quota_size", "0")
+	form.Set("quota_files", "a")
+	b, contentType, _ = getMultipartFormData(form, "", "")
+	// test invalid quota files
+	req, _ = http.NewRequest(http.MethodPost, webUserPath, &b)
+	setJWTCookieForReq(req, webToken)
+	req.Header.Set("Content-Type", contentType)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
+	form.Set("quota_files", "0")
+	form.Set("upload_bandwidth", "a")
+	b, contentType, _ = getMultipartFormData(form, "", "")
+	// test invalid upload bandwidth
+	req, _ = http.NewRequest(http.MethodPost, webUserPath, &b)
+	setJWTCookieForReq(req, webToken)
+	req.Header.Set("Content-Type", contentType)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
+	form.Set("upload_bandwidth", strconv.FormatInt(user.UploadBandwidth, 10))
+	form.Set("download_bandwidth", "a")
+	b, contentType, _ = getMultipartFormData(form, "", "")
+	// test invalid download bandwidth
+	req, _ = http.NewRequest(http.MethodPost, webUserPath, &b)
+	setJWTCookieForReq(req, webToken)
+	req.Header.Set("Content-Type", contentType)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
+	form.Set("download_bandwidth", strconv.FormatInt(user.DownloadBandwidth, 10))
+	form.Set("total_data_transfer", "a")
+	b, contentType, _ = getMultipartFormData(form, "", "")
+	// test invalid total data transfer
+	req, _ = http.NewRequest(http.MethodPost, webUserPath, &b)
+	setJWTCookieForReq(req, webToken)
+	req.Header.Set("Content-Type", contentType)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
+	form.Set("total_data_transfer", strconv.FormatInt(user.TotalDataTransfer, 10))
+	form.Set("status", "a")
+	b, contentType, _ = getMultipartFormData(form, "", "")
+	// test invalid status
+	req, _ = http.NewRequest(http.MethodPost, webUserPath, &b)
+	setJWTCookieForReq(req, webToken)
+	req.Header.Set("Content-Type", contentType)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
+	form.Set("status", strconv.Itoa(user.Status))
+	form.Set("expiration_date", "123")
+	b, contentType, _ = getMultipartFormData(form, "", "")
+	// test invalid expiration date
+	req, _ = http.NewRequest(http.MethodPost, webUserPath, &b)
+	setJWTCookieForReq(req, webToken)
+	req.Header.Set("Content-Type", contentType)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
+	form.Set("expiration_date", "")
+	form.Set("allowed_ip", "invalid,ip")
+	b, contentType, _ = getMultipartFormData(form, "", "")
+	// test invalid allowed_ip
+	req, _ = http.NewRequest(http.MethodPost, webUserPath, &b)
+	setJWTCookieForReq(req, webToken)
+	req.Header.Set("Content-Type", contentType)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
+	form.Set("allowed_ip", "")
+	form.Set("denied_ip", "192.168.1.2") // it should be 192.168.1.2/32
+	b, contentType, _ = getMultipartFormData(form, "", "")
+	// test invalid denied_ip
+	req, _ = http.NewRequest(http.MethodPost, webUserPath, &b)
+	setJWTCookieForReq(req, webToken)
+	req.Header.Set("Content-Type", contentType)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
+	form.Set("denied_ip", "")
+	// test invalid max file upload size
+	form.Set("max_upload_file_size", "a")
+	b, contentType, _ = getMultipartFormData(form, "", "")
+	req, _ = http.NewRequest(http.MethodPost, webUserPath, &b)
+	setJWTCookieForReq(req, webToken)
+	req.Header.Set("Content-Type", contentType)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
+	form.Set("max_upload_file_size", "1000")
+	// test invalid data retention
+	form.Set("retention_path0", "/inbound")
+	form.Set("retention_value0", "a")
+	b, contentType, _ = getMultipartFormData(form, "", "")
+	req, _ = http.NewRequest(http.MethodPost, webUserPath, &b)
+	setJWTCookieForReq(req, webToken)
+	req.Header.Set("Content-Type", contentType)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
+	assert.Contains(t, rr.Body.String(), "invalid retention for path")
+	form.Set("retention_value0", "24")
+	form.Add("retention_options0", "ignore_user_permissions")
+	// test invalid tls username
+	form.Set("tls_username", "username")
+	b, contentType, _ = getMultipartFormData(form, "", "")
+	req, _ = http.NewRequest(http.MethodPost, webUserPath, &b)
+	setJWTCookieForReq(req, webToken)
+	req.Header.Set("Content-Type", contentType)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
+	assert.Contains(t, rr.Body.String(), "Validation error: invalid TLS username")
+	form.Set("tls_username", string(sdk.TLSUsernameNone))
+	form.Set(csrfFormToken, "invalid form token")
+	b, contentType, _ = getMultipartFormData(form, "", "")
+	req, _ = http.NewRequest(http.MethodPost, webUserPath, &b)
+	setJWTCookieForReq(req, webToken)
+	req.Header.Set("Content-Type", contentType)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusForbidden, rr)
+	assert.Contains(t, rr.Body.String(), "unable to verify form token")
+
+	form.Set(csrfFormToken, csrfToken)
+	b, contentType, _ = getMultipartFormData(form, "", "")
+	req, _ = http.NewRequest(http.MethodPost, webUserPath, &b)
+	setJWTCookieForReq(req, webToken)
+	req.Header.Set("Content-Type", contentType)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusSeeOther, rr)
+
+	dbUser, err := dataprovider.UserExists(user.Username)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, dbUser.Password)
+	assert.True(t, dbUser.IsPasswordHashed())
+	// the user already exists, was created with the above request
+	b, contentType, _ = getMultipartFormData(form, "", "")
+	req, _ = http.NewRequest(http.MethodPost, webUserPath, &b)
+	setJWTCookieForReq(req, webToken)
+	req.Header.Set("Content-Type", contentType)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
+	req, _ = http.NewRequest(http.MethodGet, path.Join(userPath, user.Username), nil)
+	setBearerForReq(req, apiToken)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
+	newUser := dataprovider.User{}
+	err = render.DecodeJSON(rr.Body, &newUser)
+	assert.NoError(t, err)
+	assert.Equal(t, user.UID, newUser.UID)
+	assert.Equal(t, user.UploadBandwidth, newUser.UploadBandwidth)
+	assert.Equal(t, user.DownloadBandwidth, newUser.DownloadBandwidth)
+	assert.Equal(t, user.UploadDataTransfer, newUser.UploadDataTransfer)
+	assert.Equal(t, int64(0), newUser.DownloadDataTransfer)
+	assert.Equal(t, user.TotalDataTransfer, newUser.TotalDataTransfer)
+	assert.Equal(t, user.DataTransferResetPeriod, newUser.DataTransferResetPeriod)
+	assert.Equal(t, int64(1000), newUser.Filters.MaxUploadFileSize)
+	assert.Equal(t, user.AdditionalInfo, newUser.AdditionalInfo)
+	assert.Equal(t, user.Description, newUser.Description)
+	assert.True(t, newUser.Filters.Hooks.ExternalAuthDisabled)
+	assert.False(t, newUser.Filters.Hooks.PreLoginDisabled)
+	assert.False(t, newUser.Filters.Hooks.CheckPasswordDisabled)
+	assert.True(t, newUser.Filters.DisableFsChecks)
+	assert.False(t, newUser.Filters.AllowAPIKeyAuth)
+	assert.Equal(t, user.Email, newUser.Email)
+	assert.True(t, util.IsStringInSlice(testPubKey, newUser.PublicKeys))
+	if val, ok := newUser.Permissions["/subdir"]; ok {
+		assert.True(t, util.IsStringInSlice(dataprovider.PermListItems, val))
+		assert.True(t, util.IsStringInSlice(dataprovider.PermDownload, val))
+	} else {
+		assert.Fail(t, "user permissions must contain /somedir", "actual: %v", newUser.Permissions)
+	}
+	assert.Len(t, newUser.PublicKeys, 2)
+	assert.Len(t, newUser.VirtualFolders, 1)
+	for _, v := range newUser.VirtualFolders {
+		assert.Equal(t, v.VirtualPath, "/vdir")
+		assert.Equal(t, v.Name, folderName)
+		assert.Equal(t, v.MappedPath, mappedDir)
+		assert.Equal(t, v.QuotaFiles, 2)
+		assert.Equal(t, v.QuotaSize, int64(1024))
+	}
+	assert.Len(t, newUser.Filters.FilePatterns, 3)
+	for _, filter := range newUser.Filters.FilePatterns {
+		if filter.Path == "/dir1" {
+			assert.Len(t, filter.DeniedPatterns, 1)
+			assert.Len(t, filter.AllowedPatterns, 1)
+			assert.True(t, util.IsStringInSlice("*.png", filter.AllowedPatterns))
+			assert.True(t, util.IsStringInSlice("*.zip", filter.DeniedPatterns))
+		}
+		if filter.Path == "/dir2" {
+			assert.Len(t, filter.DeniedPatterns, 1)
+			assert.Len(t, filter.AllowedPatterns, 2)
+			assert.True(t, util.IsStringInSlice("*.jpg", filter.AllowedPatterns))
+			assert.True(t, util.IsStringInSlice("*.png", filter.AllowedPatterns))
+			assert.True(t, util.IsStringInSlice("*.mkv", filter.DeniedPatterns))
+		}
+		if filter.Path == "/dir3" {
+			assert.Len(t, filter.DeniedPatterns, 1)
+			assert.Len(t, filter.AllowedPatterns, 0)
+			assert.True(t, util.IsStringInSlice("*.rar", filter.DeniedPatterns))
+		}
+	}
+	assert.Equal(t, sdk.TLSUsernameNone, newUser.Filters.TLSUsername)
+	if assert.Len(t, newUser.Filters.DataRetention, 1) {
+		assert.Equal(t, "/inbound", newUser.Filters.DataRetention[0].Path)
+		assert.Equal(t, 24, newUser.Filters.DataRetention[0].Retention)
+		assert.False(t, newUser.Filters.DataRetention[0].DeleteEmptyDirs)
+		assert.True(t, newUser.Filters.DataRetention[0].IgnoreUserPermissions)
+	}
+	req, _ = http.NewRequest(http.MethodDelete, path.Join(userPath, newUser.Username), nil)
+	setBearerForReq(req, apiToken)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
+	req, _ = http.NewRequest(http.MethodDelete, path.Join(folderPath, folderName), nil)
+	setBearerForReq(req, apiToken)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
+}
+
+func TestWebUserUpdateMock(t *testing.T) {
+	webToken, err := getJWTWebTokenFromTestServer(defaultTokenAuthUser, defaultTokenAuthPass)
+	assert.NoError(t, err)
+	apiToken, err := getJWTAPITokenFromTestServer(defaultTokenAuthUser, defaultTokenAuthPass)
+	assert.NoError(t, err)
+	csrfToken, err := getCSRFToken(httpBaseURL + webLoginPath)
+	assert.NoError(t, err)
+	user := getTestUser()
+	userAsJSON := getUserAsJSON(t, user)
+	req, _ := http.NewRequest(http.MethodPost, userPath, bytes.NewBuffer(userAsJSON))
+	setBearerForReq(req, apiToken)
+	rr := executeRequest(req)
+	checkResponseCode(t, http.StatusCreated, rr)
+	// add TOTP config
+	configName, _, secret, _, err := mfa.GenerateTOTPSecret(mfa.GetAvailableTOTPConfigNames()[0], user.Username)
+	assert.NoError(t, err)
+	userToken, err := getJWTWebClientTokenFromTestServer(defaultUsername, defaultPassword)
+	assert.NoError(t, err)
+	userTOTPConfig := sdk.TOTPConfig{
+		Enabled:    true,
+		ConfigName: configName,
+		Secret:     kms.NewPlainSecret(secret),
+		Protocols:  []string{common.ProtocolSSH, common.ProtocolFTP},
+	}
+	asJSON, err := json.Marshal(userTOTPConfig)
+	assert.NoError(t, err)
+	req, err = http.NewRequest(http.MethodPost, webClientTOTPSavePath, bytes.NewBuffer(asJSON))
+	assert.NoError(t, err)
+	setJWTCookieForReq(req, userToken)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusForbidden, rr)
+	assert.Contains(t, rr.Body.String(), "Invalid token")
+
+	req, err = http.NewRequest(http.MethodPost, webClientTOTPSavePath, bytes.NewBuffer(asJSON))
+	assert.NoError(t, err)
+	setJWTCookieForReq(req, userToken)
+	setCSRFHeaderForReq(req, csrfToken)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
+
+	user, _, err = httpdtest.GetUserByUsername(user.Username, http.StatusOK)
+	assert.NoError(t, err)
+	assert.True(t, user.Filters.TOTPConfig.Enabled)
+
+	dbUser, err := dataprovider.UserExists(user.Username)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, dbUser.Password)
+	assert.True(t, dbUser.IsPasswordHashed())
+	err = render.DecodeJSON(rr.Body, &user)
+	assert.NoError(t, err)
+	user.MaxSessions = 1
+	user.QuotaFiles = 2
+	user.QuotaSize = 3
+	user.GID = 1000
+	user.Filters.AllowAPIKeyAuth = true
+	user.AdditionalInfo = "new additional info"
+	user.Email = "user@example.com"
 	form := make(url.Values)
-	form.Set(csrfFormToken, csrfToken)
 	form.Set("username", user.Username)
 	form.Set("email", user.Email)
+	form.Set("password", "")
+	form.Set("public_keys", testPubKey)
 	form.Set("home_dir", user.HomeDir)
-	form.Set("password", user.Password)
-	form.Set("status", strconv.Itoa(user.Status))
-	form.Set("expiration_date", "")
+	form.Set("uid", "0")
+	form.Set("gid", strconv.FormatInt(int64(user.GID), 10))
+	form.Set("max_sessions", strconv.FormatInt(int64(user.MaxSessions), 10))
+	form.Set("quota_size", strconv.FormatInt(user.QuotaSize, 10))
+	form.Set("quota_files", strconv.FormatInt(int64(user.QuotaFiles), 10))
+	form.Set("upload_bandwidth", "0")
+	form.Set("download_bandwidth", "0")
 	form.Set("permissions", "*")
-	form.Set("sub_perm_path0", "/subdir")
+	form.Set("sub_perm_path0", "/otherdir")
 	form.Set("sub_perm_permissions0", "list")
-	form.Add("sub_perm_permissions0", "download")
-	form.Set("vfolder_path", " /vdir")
-	form.Set("vfolder_name", folderName)
-	form.Set("vfolder_quota_size", "1024")
-	form.Set("vfolder_quota_files", "2")
-	form.Set("pattern_path0", "/dir2")
-	form.Set("patterns0", "*.jpg,*.png")
-	form.Set("pattern_type0", "allowed")
-	form.Set("pattern_path1", "/dir1")
-	form.Set("patterns1", "*.png")
-	form.Set("pattern_type1", "allowed")
-	form.Set("pattern_path2", "/dir1")
-	form.Set("patterns2", "*.zip")
-	form.Set("pattern_type2", "denied")
-	form.Set("pattern_path3", "/dir3")
-	form.Set("patterns3", "*.rar")
-	form.Set("pattern_type3", "denied")
-	form.Set("pattern_path4", "/dir2")
-	form.Set("patterns4", "*.mkv")
-	form.Set("pattern_type4", "denied")
+	form.Add("sub_perm_permissions0", "upload")
+	form.Set("status", strconv.Itoa(user.Status))
+	form.Set("expiration_date", "2020-01-01 00:00:00")
+	form.Set("allowed_ip", " 192.168.1.3/32, 192.168.2.0/24 ")
+	form.Set("denied_ip", " 10.0.0.2/32 ")
+	form.Set("pattern_path0", "/dir1")
+	form.Set("patterns0", "*.zip")
+	form.Set("pattern_type0", "denied")
+	form.Set("ssh_login_methods", dataprovider.SSHLoginMethodKeyboardInteractive)
+	form.Set("denied_protocols", common.ProtocolFTP)
+	form.Set("max_upload_file_size", "100")
+	form.Set("disconnect", "1")
 	form.Set("additional_info", user.AdditionalInfo)
 	form.Set("description", user.Description)
-	form.Add("hooks", "external_auth_disabled")
-	form.Set("disable_fs_checks", "checked")
+	form.Set("tls_username", string(sdk.TLSUsernameCN))
+	form.Set("allow_api_key_auth", "1")
+	b, contentType, _ := getMultipartFormData(form, "", "")
+	req, _ = http.NewRequest(http.MethodPost, path.Join(webUserPath, user.Username), &b)
+	setJWTCookieForReq(req, webToken)
+	req.Header.Set("Content-Type", contentType)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusForbidden, rr)
+	assert.Contains(t, rr.Body.String(), "unable to verify form token")
+
+	form.Set(csrfFormToken, csrfToken)
+	b, contentType, _ = getMultipartFormData(form, "", "")
+	req, _ = http.NewRequest(http.MethodPost, path.Join(webUserPath, user.Username), &b)
+	setJWTCookieForReq(req, webToken)
+	req.Header.Set("Content-Type", contentType)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusSeeOther, rr)
+	dbUser, err = dataprovider.UserExists(user.Username)
+	assert.NoError(t, err)
+	assert.Empty(t, dbUser.Password)
+	assert.False(t, dbUser.IsPasswordHashed())
+
+	form.Set("password", defaultPassword)
+	b, contentType, _ = getMultipartFormData(form, "", "")
+	req, _ = http.NewRequest(http.MethodPost, path.Join(webUserPath, user.Username), &b)
+	setJWTCookieForReq(req, webToken)
+	req.Header.Set("Content-Type", contentType)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusSeeOther, rr)
+	dbUser, err = dataprovider.UserExists(user.Username)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, dbUser.Password)
+	assert.True(t, dbUser.IsPasswordHashed())
+	prevPwd := dbUser.Password
+
+	form.Set("password", redactedSecret)
+	b, contentType, _ = getMultipartFormData(form, "", "")
+	req, _ = http.NewRequest(http.MethodPost, path.Join(webUserPath, user.Username), &b)
+	setJWTCookieForReq(req, webToken)
+	req.Header.Set("Content-Type", contentType)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusSeeOther, rr)
+	dbUser, err = dataprovider.UserExists(user.Username)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, dbUser.Password)
+	assert.True(t, dbUser.IsPasswordHashed())
+	assert.Equal(t, prevPwd, dbUser.Password)
+	assert.True(t, dbUser.Filters.TOTPConfig.Enabled)
+
+	req, _ = http.NewRequest(http.MethodGet, path.Join(userPath, user.Username), nil)
+	setBearerForReq(req, apiToken)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
+	var updateUser dataprovider.User
+	err = render.DecodeJSON(rr.Body, &updateUser)
+	assert.NoError(t, err)
+	assert.Equal(t, user.Email, updateUser.Email)
+	assert.Equal(t, user.HomeDir, updateUser.HomeDir)
+	assert.Equal(t, user.MaxSessions, updateUser.MaxSessions)
+	assert.Equal(t, user.QuotaFiles, updateUser.QuotaFiles)
+	assert.Equal(t, user.QuotaSize, updateUser.QuotaSize)
+	assert.Equal(t, user.UID, updateUser.UID)
+	assert.Equal(t, user.GID, updateUser.GID)
+	assert.Equal(t, user.AdditionalInfo, updateUser.AdditionalInfo)
+	assert.Equal(t, user.Description, updateUser.Description)
+	assert.Equal(t, int64(100), updateUser.Filters.MaxUploadFileSize)
+	assert.Equal(t, sdk.TLSUsernameCN, updateUser.Filters.TLSUsername)
+	assert.True(t, updateUser.Filters.AllowAPIKeyAuth)
+	assert.True(t, updateUser.Filters.TOTPConfig.Enabled)
+
+	if val, ok := updateUser.Permissions["/otherdir"]; ok {
+		assert.True(t, util.IsStringInSlice(dataprovider.PermListItems, val))
+		assert.True(t, util.IsStringInSlice(dataprovider.PermUpload, val))
+	} else {
+		assert.Fail(t, "user permissions must contains /otherdir", "actual: %v", updateUser.Permissions)
+	}
+	assert.True(t, util.IsStringInSlice("192.168.1.3/32", updateUser.Filters.AllowedIP))
+	assert.True(t, util.IsStringInSlice("10.0.0.2/32", updateUser.Filters.DeniedIP))
+	assert.True(t, util.IsStringInSlice(dataprovider.SSHLoginMethodKeyboardInteractive, updateUser.Filters.DeniedLoginMethods))
+	assert.True(t, util.IsStringInSlice(common.ProtocolFTP, updateUser.Filters.DeniedProtocols))
+	assert.True(t, util.IsStringInSlice("*.zip", updateUser.Filters.FilePatterns[0].DeniedPatterns))
+	req, err = http.NewRequest(http.MethodDelete, path.Join(userPath, user.Username), nil)
+	assert.NoError(t, err)
+	setBearerForReq(req, apiToken)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
+}
+
+func TestRenderFolderTemplateMock(t *testing.T) {
+	token, err := getJWTWebTokenFromTestServer(defaultTokenAuthUser, defaultTokenAuthPass)
+	assert.NoError(t, err)
+	req, err := http.NewRequest(http.MethodGet, webTemplateFolder, nil)
+	assert.NoError(t, err)
+	setJWTCookieForReq(req, token)
+	rr := executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
+
+	folder := vfs.BaseVirtualFolder{
+		Name:        "templatefolder",
+		MappedPath:  filepath.Join(os.TempDir(), "mapped"),
+		Description: "template folder desc",
+	}
+	folder, _, err = httpdtest.AddFolder(folder, http.StatusCreated)
+	assert.NoError(t, err)
+
+	req, err = http.NewRequest(http.MethodGet, webTemplateFolder+fmt.Sprintf("?from=%v", folder.Name), nil)
+	assert.NoError(t, err)
+	setJWTCookieForReq(req, token)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
+
+	req, err = http.NewRequest(http.MethodGet, webTemplateFolder+"?from=unknown-folder", nil)
+	assert.NoError(t, err)
+	setJWTCookieForReq(req, token)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusNotFound, rr)
+
+	_, err = httpdtest.RemoveFolder(folder, http.StatusOK)
+	assert.NoError(t, err)
+}
+
+func TestRenderUserTemplateMock(t *testing.T) {
+	token, err := getJWTWebTokenFromTestServer(defaultTokenAuthUser, defaultTokenAuthPass)
+	assert.NoError(t, err)
+	req, err := http.NewRequest(http.MethodGet, webTemplateUser, nil)
+	assert.NoError(t, err)
+	setJWTCookieForReq(req, token)
+	rr := executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
+
+	user, _, err := httpdtest.AddUser(getTestUser(), http.StatusCreated)
+	assert.NoError(t, err)
+
+	req, err = http.NewRequest(http.MethodGet, webTemplateUser+fmt.Sprintf("?from=%v", user.Username), nil)
+	assert.NoError(t, err)
+	setJWTCookieForReq(req, token)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
+
+	req, err = http.NewRequest(http.MethodGet, webTemplateUser+"?from=unknown", nil)
+	assert.NoError(t, err)
+	setJWTCookieForReq(req, token)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusNotFound, rr)
+
+	_, err = httpdtest.RemoveUser(user, http.StatusOK)
+	assert.NoError(t, err)
+}
+
+func TestRenderWebCloneUserMock(t *testing.T) {
+	token, err := getJWTWebTokenFromTestServer(defaultTokenAuthUser, defaultTokenAuthPass)
+	assert.NoError(t, err)
+	user, _, err := httpdtest.AddUser(getTestUser(), http.StatusCreated)
+	assert.NoError(t, err)
+
+	req, err := http.NewRequest(http.MethodGet, webUserPath+fmt.Sprintf("?clone-from=%v", user.Username), nil)
+	assert.NoError(t, err)
+	setJWTCookieForReq(req, token)
+	rr := executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
+
+	req, err = http.NewRequest(http.MethodGet, webUserPath+fmt.Sprintf("?clone-from=%v", altAdminPassword), nil)
+	assert.NoError(t, err)
+	setJWTCookieForReq(req, token)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusNotFound, rr)
+
+	_, err = httpdtest.RemoveUser(user, http.StatusOK)
+	assert.NoError(t, err)
+}
+
+func TestUserTemplateWithFoldersMock(t *testing.T) {
+	folder := vfs.BaseVirtualFolder{
+		Name:        "vfolder",
+		MappedPath:  filepath.Join(os.TempDir(), "mapped"),
+		Description: "vfolder desc with spéciàl ch@rs",
+	}
+
+	token, err := getJWTWebTokenFromTestServer(defaultTokenAuthUser, defaultTokenAuthPass)
+	assert.NoError(t, err)
+	csrfToken, err := getCSRFToken(httpBaseURL + webLoginPath)
+	assert.NoError(t, err)
+	user := getTestUser()
+	form := make(url.Values)
+	form.Set("username", user.Username)
+	form.Set("home_dir", filepath.Join(os.TempDir(), "%username%"))
+	form.Set("uid", strconv.FormatInt(int64(user.UID), 10))
+	form.Set("gid", strconv.FormatInt(int64(user.GID), 10))
+	form.Set("max_sessions", strconv.FormatInt(int64(user.MaxSessions), 10))
+	form.Set("quota_size", strconv.FormatInt(user.QuotaSize, 10))
+	form.Set("quota_files", strconv.FormatInt(int64(user.QuotaFiles), 10))
+	form.Set("upload_bandwidth", "0")
+	form.Set("download_bandwidth", "0")
+	form.Set("permissions", "*")
+	form.Set("status", strconv.Itoa(user.Status))
+	form.Set("expiration_date", "2020-01-01 00:00:00")
+	form.Set("fs_provider", "0")
+	form.Set("max_upload_file_size", "0")
+	form.Set("description", "desc %username% %password%")
+	form.Set("vfolder_path", "/vdir%username%")
+	form.Set("vfolder_name", folder.Name)
+	form.Set("vfolder_quota_size", "-1")
+	form.Set("vfolder_quota_files", "-1")
+	form.Add("tpl_username", "auser1")
+	form.Add("tpl_password", "password1")
+	form.Add("tpl_public_keys", " ")
+	form.Add("tpl_username", "auser2")
+	form.Add("tpl_password", "password2")
+	form.Add("tpl_public_keys", testPubKey)
+	form.Add("tpl_username", "auser1")
+	form.Add("tpl_password", "password")
+	form.Add("tpl_public_keys", "")
 	b, contentType, _ := getMultipartFormData(form, "", "")
-	// test invalid url escape
-	req, _ = http.NewRequest(http.MethodPost, webUserPath+"?a=%2", &b)
-	setJWTCookieForReq(req, webToken)
-	req.Header.Set("Content-Type", contentType)
-	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusOK, rr)
-	form.Set("public_keys", testPubKey)
-	form.Add("public_keys", testPubKey1)
-	form.Set("uid", strconv.FormatInt(int64(user.UID), 10))
-	form.Set("gid", "a")
-	b, contentType, _ = getMultipartFormData(form, "", "")
-	// test invalid gid
-	req, _ = http.NewRequest(http.MethodPost, webUserPath, &b)
-	setJWTCookieForReq(req, webToken)
+	req, _ := http.NewRequest(http.MethodPost, path.Join(webTemplateUser), &b)
+	setJWTCookieForReq(req, token)
 	req.Header.Set("Content-Type", contentType)
-	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusOK, rr)
-	form.Set("gid", "0")
-	form.Set("max_sessions", "a")
+	rr := executeRequest(req)
+	checkResponseCode(t, http.StatusForbidden, rr)
+	require.Contains(t, rr.Body.String(), "unable to verify form token")
+
+	form.Set(csrfFormToken, csrfToken)
 	b, contentType, _ = getMultipartFormData(form, "", "")
-	// test invalid max sessions
-	req, _ = http.NewRequest(http.MethodPost, webUserPath, &b)
-	setJWTCookieForReq(req, webToken)
+	req, _ = http.NewRequest(http.MethodPost, path.Join(webTemplateUser), &b)
+	setJWTCookieForReq(req, token)
 	req.Header.Set("Content-Type", contentType)
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusOK, rr)
-	form.Set("max_sessions", "0")
-	form.Set("quota_size", "a")
+	checkResponseCode(t, http.StatusBadRequest, rr)
+	require.Contains(t, rr.Body.String(), "invalid folder mapped path")
+
+	folder, resp, err := httpdtest.AddFolder(folder, http.StatusCreated)
+	assert.NoError(t, err, string(resp))
+
 	b, contentType, _ = getMultipartFormData(form, "", "")
-	// test invalid quota size
-	req, _ = http.NewRequest(http.MethodPost, webUserPath, &b)
-	setJWTCookieForReq(req, webToken)
+	req, _ = http.NewRequest(http.MethodPost, path.Join(webTemplateUser), &b)
+	setJWTCookieForReq(req, token)
 	req.Header.Set("Content-Type", contentType)
 	rr = executeRequest(req)
 	checkResponseCode(t, http.StatusOK, rr)
-	form.Set("quota_size", "0")
-	form.Set("quota_files", "a")
-	b, contentType, _ = getMultipartFormData(form, "", "")
-	// test invalid quota files
-	req, _ = http.NewRequest(http.MethodPost, webUserPath, &b)
-	setJWTCookieForReq(req, webToken)
+
+	var dump dataprovider.BackupData
+	err = json.Unmarshal(rr.Body.Bytes(), &dump)
+	assert.NoError(t, err)
+	assert.Len(t, dump.Users, 2)
+	assert.Len(t, dump.Folders, 1)
+	user1 := dump.Users[0]
+	user2 := dump.Users[1]
+	folder1 := dump.Folders[0]
+	assert.Equal(t, "auser1", user1.Username)
+	assert.Equal(t, "auser2", user2.Username)
+	assert.Equal(t, "desc auser1 password1", user1.Description)
+	assert.Equal(t, "desc auser2 password2", user2.Description)
+	assert.Equal(t, filepath.Join(os.TempDir(), user1.Username), user1.HomeDir)
+	assert.Equal(t, filepath.Join(os.TempDir(), user2.Username), user2.HomeDir)
+	assert.Equal(t, folder.Name, folder1.Name)
+	assert.Equal(t, folder.MappedPath, folder1.MappedPath)
+	assert.Equal(t, folder.Description, folder1.Description)
+	assert.Len(t, user1.PublicKeys, 0)
+	assert.Len(t, user2.PublicKeys, 1)
+	assert.Len(t, user1.VirtualFolders, 1)
+	assert.Len(t, user2.VirtualFolders, 1)
+	assert.Equal(t, "/vdirauser1", user1.VirtualFolders[0].VirtualPath)
+	assert.Equal(t, "/vdirauser2", user2.VirtualFolders[0].VirtualPath)
+
+	_, err = httpdtest.RemoveFolder(folder, http.StatusOK)
+	assert.NoError(t, err)
+}
+
+func TestUserTemplateMock(t *testing.T) {
+	token, err := getJWTWebTokenFromTestServer(defaultTokenAuthUser, defaultTokenAuthPass)
+	assert.NoError(t, err)
+	user := getTestUser()
+	user.FsConfig.Provider = sdk.S3FilesystemProvider
+	user.FsConfig.S3Config.Bucket = "test"
+	user.FsConfig.S3Config.Region = "eu-central-1"
+	user.FsConfig.S3Config.AccessKey = "%username%"
+	user.FsConfig.S3Config.KeyPrefix = "somedir/subdir/"
+	user.FsConfig.S3Config.UploadPartSize = 5
+	user.FsConfig.S3Config.UploadConcurrency = 4
+	user.FsConfig.S3Config.DownloadPartSize = 6
+	user.FsConfig.S3Config.DownloadConcurrency = 3
+	csrfToken, err := getCSRFToken(httpBaseURL + webLoginPath)
+	assert.NoError(t, err)
+	form := make(url.Values)
+	form.Set(csrfFormToken, csrfToken)
+	form.Set("username", user.Username)
+	form.Set("home_dir", filepath.Join(os.TempDir(), "%username%"))
+	form.Set("uid", "0")
+	form.Set("gid", strconv.FormatInt(int64(user.GID), 10))
+	form.Set("max_sessions", strconv.FormatInt(int64(user.MaxSessions), 10))
+	form.Set("quota_size", strconv.FormatInt(user.QuotaSize, 10))
+	form.Set("quota_files", strconv.FormatInt(int64(user.QuotaFiles), 10))
+	form.Set("upload_bandwidth", "0")
+	form.Set("download_bandwidth", "0")
+	form.Set("permissions", "*")
+	form.Set("status", strconv.Itoa(user.Status))
+	form.Set("expiration_date", "2020-01-01 00:00:00")
+	form.Set("allowed_ip", "")
+	form.Set("denied_ip", "")
+	form.Set("fs_provider", "1")
+	form.Set("s3_bucket", user.FsConfig.S3Config.Bucket)
+	form.Set("s3_region", user.FsConfig.S3Config.Region)
+	form.Set("s3_access_key", "%username%")
+	form.Set("s3_access_secret", "%password%")
+	form.Set("s3_key_prefix", "base/%username%")
+	form.Set("allowed_extensions", "/dir1::.jpg,.png")
+	form.Set("denied_extensions", "/dir2::.zip")
+	form.Set("max_upload_file_size", "0")
+	form.Add("hooks", "external_auth_disabled")
+	form.Add("hooks", "check_password_disabled")
+	form.Set("disable_fs_checks", "checked")
+	form.Set("s3_download_part_max_time", "0")
+	// test invalid s3_upload_part_size
+	form.Set("s3_upload_part_size", "a")
+	b, contentType, _ := getMultipartFormData(form, "", "")
+	req, _ := http.NewRequest(http.MethodPost, webTemplateUser, &b)
+	setJWTCookieForReq(req, token)
 	req.Header.Set("Content-Type", contentType)
-	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusOK, rr)
-	form.Set("quota_files", "0")
-	form.Set("upload_bandwidth", "a")
+	rr := executeRequest(req)
+	checkResponseCode(t, http.StatusBadRequest, rr)
+	form.Set("s3_upload_part_size", strconv.FormatInt(user.FsConfig.S3Config.UploadPartSize, 10))
+	form.Set("s3_upload_concurrency", strconv.Itoa(user.FsConfig.S3Config.UploadConcurrency))
+	form.Set("s3_download_part_size", strconv.FormatInt(user.FsConfig.S3Config.DownloadPartSize, 10))
+	form.Set("s3_download_concurrency", strconv.Itoa(user.FsConfig.S3Config.DownloadConcurrency))
+
 	b, contentType, _ = getMultipartFormData(form, "", "")
-	// test invalid upload bandwidth
-	req, _ = http.NewRequest(http.MethodPost, webUserPath, &b)
-	setJWTCookieForReq(req, webToken)
+	req, _ = http.NewRequest(http.MethodPost, webTemplateUser, &b)
+	setJWTCookieForReq(req, token)
 	req.Header.Set("Content-Type", contentType)
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusOK, rr)
-	form.Set("upload_bandwidth", strconv.FormatInt(user.UploadBandwidth, 10))
-	form.Set("download_bandwidth", "a")
+	checkResponseCode(t, http.StatusBadRequest, rr)
+
+	form.Set("tpl_username", "user1")
+	form.Set("tpl_password", "password1")
+	form.Set("tpl_public_keys", "invalid-pkey")
 	b, contentType, _ = getMultipartFormData(form, "", "")
-	// test invalid download bandwidth
-	req, _ = http.NewRequest(http.MethodPost, webUserPath, &b)
-	setJWTCookieForReq(req, webToken)
+	req, _ = http.NewRequest(http.MethodPost, webTemplateUser, &b)
+	setJWTCookieForReq(req, token)
 	req.Header.Set("Content-Type", contentType)
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusOK, rr)
-	form.Set("download_bandwidth", strconv.FormatInt(user.DownloadBandwidth, 10))
-	form.Set("status", "a")
+	checkResponseCode(t, http.StatusBadRequest, rr)
+	require.Contains(t, rr.Body.String(), "Error validating user")
+
+	form.Set("tpl_username", "user1")
+	form.Set("tpl_password", " ")
+	form.Set("tpl_public_keys", "")
 	b, contentType, _ = getMultipartFormData(form, "", "")
-	// test invalid status
-	req, _ = http.NewRequest(http.MethodPost, webUserPath, &b)
-	setJWTCookieForReq(req, webToken)
+	req, _ = http.NewRequest(http.MethodPost, webTemplateUser, &b)
+	setJWTCookieForReq(req, token)
 	req.Header.Set("Content-Type", contentType)
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusOK, rr)
-	form.Set("status", strconv.Itoa(user.Status))
-	form.Set("expiration_date", "123")
+	checkResponseCode(t, http.StatusBadRequest, rr)
+	require.Contains(t, rr.Body.String(), "No valid users found, export is not possible")
+
+	form.Set("tpl_username", "user1")
+	form.Set("tpl_password", "password1")
+	form.Set("tpl_public_keys", " ")
+	form.Add("tpl_username", "user2")
+	form.Add("tpl_password", "password2")
+	form.Add("tpl_public_keys", testPubKey)
+	form.Add("tpl_username", "user3")
+	form.Add("tpl_password", "")
+	form.Add("tpl_public_keys", "")
 	b, contentType, _ = getMultipartFormData(form, "", "")
-	// test invalid expiration date
-	req, _ = http.NewRequest(http.MethodPost, webUserPath, &b)
-	setJWTCookieForReq(req, webToken)
+	req, _ = http.NewRequest(http.MethodPost, webTemplateUser, &b)
+	setJWTCookieForReq(req, token)
 	req.Header.Set("Content-Type", contentType)
 	rr = executeRequest(req)
 	checkResponseCode(t, http.StatusOK, rr)
-	form.Set("expiration_date", "")
-	form.Set("allowed_ip", "invalid,ip")
-	b, contentType, _ = getMultipartFormData(form, "", "")
-	// test invalid allowed_ip
-	req, _ = http.NewRequest(http.MethodPost, webUserPath, &b)
-	setJWTCookieForReq(req, webToken)
+
+	var dump dataprovider.BackupData
+	err = json.Unmarshal(rr.Body.Bytes(), &dump)
+	require.NoError(t, err)
+	require.Len(t, dump.Users, 2)
+	require.Len(t, dump.Admins, 0)
+	require.Len(t, dump.Folders, 0)
+	user1 := dump.Users[0]
+	user2 := dump.Users[1]
+	require.Equal(t, "user1", user1.Username)
+	require.Equal(t, sdk.S3FilesystemProvider, user1.FsConfig.Provider)
+	require.Equal(t, "user2", user2.Username)
+	require.Equal(t, sdk.S3FilesystemProvider, user2.FsConfig.Provider)
+	require.Len(t, user2.PublicKeys, 1)
+	require.Equal(t, filepath.Join(os.TempDir(), user1.Username), user1.HomeDir)
+	require.Equal(t, filepath.Join(os.TempDir(), user2.Username), user2.HomeDir)
+	require.Equal(t, user1.Username, user1.FsConfig.S3Config.AccessKey)
+	require.Equal(t, user2.Username, user2.FsConfig.S3Config.AccessKey)
+	require.Equal(t, path.Join("base", user1.Username)+"/", user1.FsConfig.S3Config.KeyPrefix)
+	require.Equal(t, path.Join("base", user2.Username)+"/", user2.FsConfig.S3Config.KeyPrefix)
+	require.True(t, user1.FsConfig.S3Config.AccessSecret.IsEncrypted())
+	err = user1.FsConfig.S3Config.AccessSecret.Decrypt()
+	require.NoError(t, err)
+	require.Equal(t, "password1", user1.FsConfig.S3Config.AccessSecret.GetPayload())
+	require.True(t, user2.FsConfig.S3Config.AccessSecret.IsEncrypted())
+	err = user2.FsConfig.S3Config.AccessSecret.Decrypt()
+	require.NoError(t, err)
+	require.Equal(t, "password2", user2.FsConfig.S3Config.AccessSecret.GetPayload())
+	require.True(t, user1.Filters.Hooks.ExternalAuthDisabled)
+	require.True(t, user1.Filters.Hooks.CheckPasswordDisabled)
+	require.False(t, user1.Filters.Hooks.PreLoginDisabled)
+	require.True(t, user2.Filters.Hooks.ExternalAuthDisabled)
+	require.True(t, user2.Filters.Hooks.CheckPasswordDisabled)
+	require.False(t, user2.Filters.Hooks.PreLoginDisabled)
+	require.True(t, user1.Filters.DisableFsChecks)
+	require.True(t, user2.Filters.DisableFsChecks)
+}
+
+func TestFolderTemplateMock(t *testing.T) {
+	folderName := "vfolder-template"
+	mappedPath := filepath.Join(os.TempDir(), "%name%mapped%name%path")
+	token, err := getJWTWebTokenFromTestServer(defaultTokenAuthUser, defaultTokenAuthPass)
+	assert.NoError(t, err)
+	csrfToken, err := getCSRFToken(httpBaseURL + webLoginPath)
+	assert.NoError(t, err)
+	form := make(url.Values)
+	form.Set("name", folderName)
+	form.Set("mapped_path", mappedPath)
+	form.Set("description", "desc folder %name%")
+	form.Add("tpl_foldername", "folder1")
+	form.Add("tpl_foldername", "folder2")
+	form.Add("tpl_foldername", "folder3")
+	form.Add("tpl_foldername", "folder1 ")
+	form.Add("tpl_foldername", " ")
+	b, contentType, _ := getMultipartFormData(form, "", "")
+	req, _ := http.NewRequest(http.MethodPost, webTemplateFolder, &b)
+	setJWTCookieForReq(req, token)
 	req.Header.Set("Content-Type", contentType)
-	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusOK, rr)
-	form.Set("allowed_ip", "")
-	form.Set("denied_ip", "192.168.1.2") // it should be 192.168.1.2/32
+	rr := executeRequest(req)
+	checkResponseCode(t, http.StatusForbidden, rr)
+	assert.Contains(t, rr.Body.String(), "unable to verify form token")
+
+	form.Set(csrfFormToken, csrfToken)
 	b, contentType, _ = getMultipartFormData(form, "", "")
-	// test invalid denied_ip
-	req, _ = http.NewRequest(http.MethodPost, webUserPath, &b)
-	setJWTCookieForReq(req, webToken)
+	req, _ = http.NewRequest(http.MethodPost, webTemplateFolder+"?param=p%C3%AO%GG", &b)
+	setJWTCookieForReq(req, token)
 	req.Header.Set("Content-Type", contentType)
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusOK, rr)
-	form.Set("denied_ip", "")
-	// test invalid max file upload size
-	form.Set("max_upload_file_size", "a")
+	checkResponseCode(t, http.StatusBadRequest, rr)
+	assert.Contains(t, rr.Body.String(), "Error parsing folders fields")
+
+	folder1 := "folder1"
+	folder2 := "folder2"
+	folder3 := "folder3"
 	b, contentType, _ = getMultipartFormData(form, "", "")
-	req, _ = http.NewRequest(http.MethodPost, webUserPath, &b)
-	setJWTCookieForReq(req, webToken)
+	req, _ = http.NewRequest(http.MethodPost, webTemplateFolder, &b)
+	setJWTCookieForReq(req, token)
 	req.Header.Set("Content-Type", contentType)
 	rr = executeRequest(req)
 	checkResponseCode(t, http.StatusOK, rr)
-	form.Set("max_upload_file_size", "1000")
-	// test invalid tls username
-	form.Set("tls_username", "username")
+
+	var dump dataprovider.BackupData
+	err = json.Unmarshal(rr.Body.Bytes(), &dump)
+	require.NoError(t, err)
+	require.Len(t, dump.Users, 0)
+	require.Len(t, dump.Admins, 0)
+	require.Len(t, dump.Folders, 3)
+	require.Equal(t, folder1, dump.Folders[0].Name)
+	require.Equal(t, "desc folder folder1", dump.Folders[0].Description)
+	require.True(t, strings.HasSuffix(dump.Folders[0].MappedPath, "folder1mappedfolder1path"))
+	require.Equal(t, folder2, dump.Folders[1].Name)
+	require.Equal(t, "desc folder folder2", dump.Folders[1].Description)
+	require.True(t, strings.HasSuffix(dump.Folders[1].MappedPath, "folder2mappedfolder2path"))
+	require.Equal(t, folder3, dump.Folders[2].Name)
+	require.Equal(t, "desc folder folder3", dump.Folders[2].Description)
+	require.True(t, strings.HasSuffix(dump.Folders[2].MappedPath, "folder3mappedfolder3path"))
+
+	form.Set("fs_provider", "1")
+	form.Set("s3_bucket", "bucket")
+	form.Set("s3_region", "us-east-1")
+	form.Set("s3_access_key", "%name%")
+	form.Set("s3_access_secret", "pwd%name%")
+	form.Set("s3_key_prefix", "base/%name%")
+
 	b, contentType, _ = getMultipartFormData(form, "", "")
-	req, _ = http.NewRequest(http.MethodPost, webUserPath, &b)
-	setJWTCookieForReq(req, webToken)
+	req, _ = http.NewRequest(http.MethodPost, webTemplateFolder, &b)
+	setJWTCookieForReq(req, token)
 	req.Header.Set("Content-Type", contentType)
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusOK, rr)
-	assert.Contains(t, rr.Body.String(), "Validation error: invalid TLS username")
-	form.Set("tls_username", string(sdk.TLSUsernameNone))
-	form.Set(csrfFormToken, "invalid form token")
+	checkResponseCode(t, http.StatusBadRequest, rr)
+	assert.Contains(t, rr.Body.String(), "Error parsing folders fields")
+
+	form.Set("s3_upload_part_size", "5")
+	form.Set("s3_upload_concurrency", "4")
+	form.Set("s3_download_part_max_time", "0")
+	form.Set("s3_download_part_size", "6")
+	form.Set("s3_download_concurrency", "2")
 	b, contentType, _ = getMultipartFormData(form, "", "")
-	req, _ = http.NewRequest(http.MethodPost, webUserPath, &b)
-	setJWTCookieForReq(req, webToken)
+	req, _ = http.NewRequest(http.MethodPost, webTemplateFolder, &b)
+	setJWTCookieForReq(req, token)
 	req.Header.Set("Content-Type", contentType)
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusForbidden, rr)
-	assert.Contains(t, rr.Body.String(), "unable to verify form token")
+	checkResponseCode(t, http.StatusOK, rr)
 
-	form.Set(csrfFormToken, csrfToken)
+	dump = dataprovider.BackupData{}
+	err = json.Unmarshal(rr.Body.Bytes(), &dump)
+	require.NoError(t, err)
+	require.Len(t, dump.Users, 0)
+	require.Len(t, dump.Admins, 0)
+	require.Len(t, dump.Folders, 3)
+	require.Equal(t, folder1, dump.Folders[0].Name)
+	require.Equal(t, folder1, dump.Folders[0].FsConfig.S3Config.AccessKey)
+	err = dump.Folders[0].FsConfig.S3Config.AccessSecret.Decrypt()
+	require.NoError(t, err)
+	require.Equal(t, "pwd"+folder1, dump.Folders[0].FsConfig.S3Config.AccessSecret.GetPayload())
+	require.Equal(t, "base/"+folder1+"/", dump.Folders[0].FsConfig.S3Config.KeyPrefix)
+	require.Equal(t, folder2, dump.Folders[1].Name)
+	require.Equal(t, folder2, dump.Folders[1].FsConfig.S3Config.AccessKey)
+	err = dump.Folders[1].FsConfig.S3Config.AccessSecret.Decrypt()
+	require.NoError(t, err)
+	require.Equal(t, "pwd"+folder2, dump.Folders[1].FsConfig.S3Config.AccessSecret.GetPayload())
+	require.Equal(t, "base/"+folder2+"/", dump.Folders[1].FsConfig.S3Config.KeyPrefix)
+	require.Equal(t, folder3, dump.Folders[2].Name)
+	require.Equal(t, folder3, dump.Folders[2].FsConfig.S3Config.AccessKey)
+	err = dump.Folders[2].FsConfig.S3Config.AccessSecret.Decrypt()
+	require.NoError(t, err)
+	require.Equal(t, "pwd"+folder3, dump.Folders[2].FsConfig.S3Config.AccessSecret.GetPayload())
+	require.Equal(t, "base/"+folder3+"/", dump.Folders[2].FsConfig.S3Config.KeyPrefix)
+
+	form.Set("tpl_foldername", " ")
 	b, contentType, _ = getMultipartFormData(form, "", "")
-	req, _ = http.NewRequest(http.MethodPost, webUserPath, &b)
-	setJWTCookieForReq(req, webToken)
+	req, _ = http.NewRequest(http.MethodPost, webTemplateFolder, &b)
+	setJWTCookieForReq(req, token)
 	req.Header.Set("Content-Type", contentType)
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusSeeOther, rr)
+	checkResponseCode(t, http.StatusBadRequest, rr)
+	assert.Contains(t, rr.Body.String(), "No folders to export")
 
-	dbUser, err := dataprovider.UserExists(user.Username)
-	assert.NoError(t, err)
-	assert.NotEmpty(t, dbUser.Password)
-	assert.True(t, dbUser.IsPasswordHashed())
-	// the user already exists, was created with the above request
+	form.Set("tpl_foldername", "name")
+	form.Set("mapped_path", "relative-path")
 	b, contentType, _ = getMultipartFormData(form, "", "")
-	req, _ = http.NewRequest(http.MethodPost, webUserPath, &b)
-	setJWTCookieForReq(req, webToken)
+	req, _ = http.NewRequest(http.MethodPost, webTemplateFolder, &b)
+	setJWTCookieForReq(req, token)
 	req.Header.Set("Content-Type", contentType)
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusOK, rr)
-	req, _ = http.NewRequest(http.MethodGet, path.Join(userPath, user.Username), nil)
-	setBearerForReq(req, apiToken)
-	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusOK, rr)
-	newUser := dataprovider.User{}
-	err = render.DecodeJSON(rr.Body, &newUser)
-	assert.NoError(t, err)
-	assert.Equal(t, user.UID, newUser.UID)
-	assert.Equal(t, user.UploadBandwidth, newUser.UploadBandwidth)
-	assert.Equal(t, user.DownloadBandwidth, newUser.DownloadBandwidth)
-	assert.Equal(t, int64(1000), newUser.Filters.MaxUploadFileSize)
-	assert.Equal(t, user.AdditionalInfo, newUser.AdditionalInfo)
-	assert.Equal(t, user.Description, newUser.Description)
-	assert.True(t, newUser.Filters.Hooks.ExternalAuthDisabled)
-	assert.False(t, newUser.Filters.Hooks.PreLoginDisabled)
-	assert.False(t, newUser.Filters.Hooks.CheckPasswordDisabled)
-	assert.True(t, newUser.Filters.DisableFsChecks)
-	assert.False(t, newUser.Filters.AllowAPIKeyAuth)
-	assert.Equal(t, user.Email, newUser.Email)
-	assert.True(t, util.IsStringInSlice(testPubKey, newUser.PublicKeys))
-	if val, ok := newUser.Permissions["/subdir"]; ok {
-		assert.True(t, util.IsStringInSlice(dataprovider.PermListItems, val))
-		assert.True(t, util.IsStringInSlice(dataprovider.PermDownload, val))
-	} else {
-		assert.Fail(t, "user permissions must contain /somedir", "actual: %v", newUser.Permissions)
-	}
-	assert.Len(t, newUser.PublicKeys, 2)
-	assert.Len(t, newUser.VirtualFolders, 1)
-	for _, v := range newUser.VirtualFolders {
-		assert.Equal(t, v.VirtualPath, "/vdir")
-		assert.Equal(t, v.Name, folderName)
-		assert.Equal(t, v.MappedPath, mappedDir)
-		assert.Equal(t, v.QuotaFiles, 2)
-		assert.Equal(t, v.QuotaSize, int64(1024))
-	}
-	assert.Len(t, newUser.Filters.FilePatterns, 3)
-	for _, filter := range newUser.Filters.FilePatterns {
-		if filter.Path == "/dir1" {
-			assert.Len(t, filter.DeniedPatterns, 1)
-			assert.Len(t, filter.AllowedPatterns, 1)
-			assert.True(t, util.IsStringInSlice("*.png", filter.AllowedPatterns))
-			assert.True(t, util.IsStringInSlice("*.zip", filter.DeniedPatterns))
-		}
-		if filter.Path == "/dir2" {
-			assert.Len(t, filter.DeniedPatterns, 1)
-			assert.Len(t, filter.AllowedPatterns, 2)
-			assert.True(t, util.IsStringInSlice("*.jpg", filter.AllowedPatterns))
-			assert.True(t, util.IsStringInSlice("*.png", filter.AllowedPatterns))
-			assert.True(t, util.IsStringInSlice("*.mkv", filter.DeniedPatterns))
-		}
-		if filter.Path == "/dir3" {
-			assert.Len(t, filter.DeniedPatterns, 1)
-			assert.Len(t, filter.AllowedPatterns, 0)
-			assert.True(t, util.IsStringInSlice("*.rar", filter.DeniedPatterns))
-		}
-	}
-	assert.Equal(t, sdk.TLSUsernameNone, newUser.Filters.TLSUsername)
-	req, _ = http.NewRequest(http.MethodDelete, path.Join(userPath, newUser.Username), nil)
-	setBearerForReq(req, apiToken)
-	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusOK, rr)
-	req, _ = http.NewRequest(http.MethodDelete, path.Join(folderPath, folderName), nil)
-	setBearerForReq(req, apiToken)
-	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusOK, rr)
+	checkResponseCode(t, http.StatusBadRequest, rr)
+	assert.Contains(t, rr.Body.String(), "Error validating folder")
 }
 
-func TestWebUserUpdateMock(t *testing.T) {
+func TestWebUserS3Mock(t *testing.T) {
 	webToken, err := getJWTWebTokenFromTestServer(defaultTokenAuthUser, defaultTokenAuthPass)
 	assert.NoError(t, err)
 	apiToken, err := getJWTAPITokenFromTestServer(defaultTokenAuthUser, defaultTokenAuthPass)
@@ -11466,55 +16925,27 @@ func TestWebUserUpdateMock(t *testing.T) {
 	setBearerForReq(req, apiToken)
 	rr := executeRequest(req)
 	checkResponseCode(t, http.StatusCreated, rr)
-	// add TOTP config
-	configName, _, secret, _, err := mfa.GenerateTOTPSecret(mfa.GetAvailableTOTPConfigNames()[0], user.Username)
-	assert.NoError(t, err)
-	userToken, err := getJWTWebClientTokenFromTestServer(defaultUsername, defaultPassword)
-	assert.NoError(t, err)
-	userTOTPConfig := sdk.TOTPConfig{
-		Enabled:    true,
-		ConfigName: configName,
-		Secret:     kms.NewPlainSecret(secret),
-		Protocols:  []string{common.ProtocolSSH, common.ProtocolFTP},
-	}
-	asJSON, err := json.Marshal(userTOTPConfig)
-	assert.NoError(t, err)
-	req, err = http.NewRequest(http.MethodPost, webClientTOTPSavePath, bytes.NewBuffer(asJSON))
-	assert.NoError(t, err)
-	setJWTCookieForReq(req, userToken)
-	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusForbidden, rr)
-	assert.Contains(t, rr.Body.String(), "Invalid token")
-
-	req, err = http.NewRequest(http.MethodPost, webClientTOTPSavePath, bytes.NewBuffer(asJSON))
-	assert.NoError(t, err)
-	setJWTCookieForReq(req, userToken)
-	setCSRFHeaderForReq(req, csrfToken)
-	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusOK, rr)
-
-	user, _, err = httpdtest.GetUserByUsername(user.Username, http.StatusOK)
-	assert.NoError(t, err)
-	assert.True(t, user.Filters.TOTPConfig.Enabled)
-
-	dbUser, err := dataprovider.UserExists(user.Username)
-	assert.NoError(t, err)
-	assert.NotEmpty(t, dbUser.Password)
-	assert.True(t, dbUser.IsPasswordHashed())
 	err = render.DecodeJSON(rr.Body, &user)
 	assert.NoError(t, err)
-	user.MaxSessions = 1
-	user.QuotaFiles = 2
-	user.QuotaSize = 3
-	user.GID = 1000
-	user.Filters.AllowAPIKeyAuth = true
-	user.AdditionalInfo = "new additional info"
-	user.Email = "user@example.com"
+	user.FsConfig.Provider = sdk.S3FilesystemProvider
+	user.FsConfig.S3Config.Bucket = "test"
+	user.FsConfig.S3Config.Region = "eu-west-1"
+	user.FsConfig.S3Config.AccessKey = "access-key"
+	user.FsConfig.S3Config.AccessSecret = kms.NewPlainSecret("access-secret")
+	user.FsConfig.S3Config.Endpoint = "http://127.0.0.1:9000/path?a=b"
+	user.FsConfig.S3Config.StorageClass = "Standard"
+	user.FsConfig.S3Config.KeyPrefix = "somedir/subdir/"
+	user.FsConfig.S3Config.UploadPartSize = 5
+	user.FsConfig.S3Config.UploadConcurrency = 4
+	user.FsConfig.S3Config.DownloadPartMaxTime = 60
+	user.FsConfig.S3Config.DownloadPartSize = 6
+	user.FsConfig.S3Config.DownloadConcurrency = 3
+	user.FsConfig.S3Config.ForcePathStyle = true
+	user.Description = "s3 tèst user"
 	form := make(url.Values)
+	form.Set(csrfFormToken, csrfToken)
 	form.Set("username", user.Username)
-	form.Set("email", user.Email)
-	form.Set("password", "")
-	form.Set("public_keys", testPubKey)
+	form.Set("password", redactedSecret)
 	form.Set("home_dir", user.HomeDir)
 	form.Set("uid", "0")
 	form.Set("gid", strconv.FormatInt(int64(user.GID), 10))
@@ -11524,71 +16955,81 @@ func TestWebUserUpdateMock(t *testing.T) {
 	form.Set("upload_bandwidth", "0")
 	form.Set("download_bandwidth", "0")
 	form.Set("permissions", "*")
-	form.Set("sub_perm_path0", "/otherdir")
-	form.Set("sub_perm_permissions0", "list")
-	form.Add("sub_perm_permissions0", "upload")
 	form.Set("status", strconv.Itoa(user.Status))
 	form.Set("expiration_date", "2020-01-01 00:00:00")
-	form.Set("allowed_ip", " 192.168.1.3/32, 192.168.2.0/24 ")
-	form.Set("denied_ip", " 10.0.0.2/32 ")
+	form.Set("allowed_ip", "")
+	form.Set("denied_ip", "")
+	form.Set("fs_provider", "1")
+	form.Set("s3_bucket", user.FsConfig.S3Config.Bucket)
+	form.Set("s3_region", user.FsConfig.S3Config.Region)
+	form.Set("s3_access_key", user.FsConfig.S3Config.AccessKey)
+	form.Set("s3_access_secret", user.FsConfig.S3Config.AccessSecret.GetPayload())
+	form.Set("s3_storage_class", user.FsConfig.S3Config.StorageClass)
+	form.Set("s3_endpoint", user.FsConfig.S3Config.Endpoint)
+	form.Set("s3_key_prefix", user.FsConfig.S3Config.KeyPrefix)
 	form.Set("pattern_path0", "/dir1")
-	form.Set("patterns0", "*.zip")
-	form.Set("pattern_type0", "denied")
-	form.Set("ssh_login_methods", dataprovider.SSHLoginMethodKeyboardInteractive)
-	form.Set("denied_protocols", common.ProtocolFTP)
-	form.Set("max_upload_file_size", "100")
-	form.Set("disconnect", "1")
-	form.Set("additional_info", user.AdditionalInfo)
+	form.Set("patterns0", "*.jpg,*.png")
+	form.Set("pattern_type0", "allowed")
+	form.Set("pattern_path1", "/dir2")
+	form.Set("patterns1", "*.zip")
+	form.Set("pattern_type1", "denied")
+	form.Set("max_upload_file_size", "0")
+	form.Set("s3_force_path_style", "checked")
 	form.Set("description", user.Description)
-	form.Set("tls_username", string(sdk.TLSUsernameCN))
-	form.Set("allow_api_key_auth", "1")
+	form.Add("hooks", "pre_login_disabled")
+	form.Add("allow_api_key_auth", "1")
+	// test invalid s3_upload_part_size
+	form.Set("s3_upload_part_size", "a")
 	b, contentType, _ := getMultipartFormData(form, "", "")
 	req, _ = http.NewRequest(http.MethodPost, path.Join(webUserPath, user.Username), &b)
 	setJWTCookieForReq(req, webToken)
 	req.Header.Set("Content-Type", contentType)
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusForbidden, rr)
-	assert.Contains(t, rr.Body.String(), "unable to verify form token")
-
-	form.Set(csrfFormToken, csrfToken)
+	checkResponseCode(t, http.StatusOK, rr)
+	// test invalid s3_upload_concurrency
+	form.Set("s3_upload_part_size", strconv.FormatInt(user.FsConfig.S3Config.UploadPartSize, 10))
+	form.Set("s3_upload_concurrency", "a")
 	b, contentType, _ = getMultipartFormData(form, "", "")
 	req, _ = http.NewRequest(http.MethodPost, path.Join(webUserPath, user.Username), &b)
 	setJWTCookieForReq(req, webToken)
 	req.Header.Set("Content-Type", contentType)
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusSeeOther, rr)
-	dbUser, err = dataprovider.UserExists(user.Username)
-	assert.NoError(t, err)
-	assert.Empty(t, dbUser.Password)
-	assert.False(t, dbUser.IsPasswordHashed())
-
-	form.Set("password", defaultPassword)
+	checkResponseCode(t, http.StatusOK, rr)
+	// test invalid s3_download_part_size
+	form.Set("s3_upload_concurrency", strconv.Itoa(user.FsConfig.S3Config.UploadConcurrency))
+	form.Set("s3_download_part_size", "a")
 	b, contentType, _ = getMultipartFormData(form, "", "")
 	req, _ = http.NewRequest(http.MethodPost, path.Join(webUserPath, user.Username), &b)
 	setJWTCookieForReq(req, webToken)
 	req.Header.Set("Content-Type", contentType)
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusSeeOther, rr)
-	dbUser, err = dataprovider.UserExists(user.Username)
-	assert.NoError(t, err)
-	assert.NotEmpty(t, dbUser.Password)
-	assert.True(t, dbUser.IsPasswordHashed())
-	prevPwd := dbUser.Password
-
-	form.Set("password", redactedSecret)
+	checkResponseCode(t, http.StatusOK, rr)
+	// test invalid s3_download_concurrency
+	form.Set("s3_download_part_size", strconv.FormatInt(user.FsConfig.S3Config.DownloadPartSize, 10))
+	form.Set("s3_download_concurrency", "a")
+	b, contentType, _ = getMultipartFormData(form, "", "")
+	req, _ = http.NewRequest(http.MethodPost, path.Join(webUserPath, user.Username), &b)
+	setJWTCookieForReq(req, webToken)
+	req.Header.Set("Content-Type", contentType)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
+	// test invalid s3_download_part_max_time
+	form.Set("s3_download_concurrency", strconv.Itoa(user.FsConfig.S3Config.DownloadConcurrency))
+	form.Set("s3_download_part_max_time", "a")
+	b, contentType, _ = getMultipartFormData(form, "", "")
+	req, _ = http.NewRequest(http.MethodPost, path.Join(webUserPath, user.Username), &b)
+	setJWTCookieForReq(req, webToken)
+	req.Header.Set("Content-Type", contentType)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
+	// now add the user
+	form.Set("s3_download_part_max_time", strconv.Itoa(user.FsConfig.S3Config.DownloadPartMaxTime))
 	b, contentType, _ = getMultipartFormData(form, "", "")
 	req, _ = http.NewRequest(http.MethodPost, path.Join(webUserPath, user.Username), &b)
 	setJWTCookieForReq(req, webToken)
 	req.Header.Set("Content-Type", contentType)
 	rr = executeRequest(req)
 	checkResponseCode(t, http.StatusSeeOther, rr)
-	dbUser, err = dataprovider.UserExists(user.Username)
-	assert.NoError(t, err)
-	assert.NotEmpty(t, dbUser.Password)
-	assert.True(t, dbUser.IsPasswordHashed())
-	assert.Equal(t, prevPwd, dbUser.Password)
-	assert.True(t, dbUser.Filters.TOTPConfig.Enabled)
-
 	req, _ = http.NewRequest(http.MethodGet, path.Join(userPath, user.Username), nil)
 	setBearerForReq(req, apiToken)
 	rr = executeRequest(req)
@@ -11596,137 +17037,213 @@ func TestWebUserUpdateMock(t *testing.T) {
 	var updateUser dataprovider.User
 	err = render.DecodeJSON(rr.Body, &updateUser)
 	assert.NoError(t, err)
-	assert.Equal(t, user.Email, updateUser.Email)
-	assert.Equal(t, user.HomeDir, updateUser.HomeDir)
-	assert.Equal(t, user.MaxSessions, updateUser.MaxSessions)
-	assert.Equal(t, user.QuotaFiles, updateUser.QuotaFiles)
-	assert.Equal(t, user.QuotaSize, updateUser.QuotaSize)
-	assert.Equal(t, user.UID, updateUser.UID)
-	assert.Equal(t, user.GID, updateUser.GID)
-	assert.Equal(t, user.AdditionalInfo, updateUser.AdditionalInfo)
+	assert.Equal(t, int64(1577836800000), updateUser.ExpirationDate)
+	assert.Equal(t, updateUser.FsConfig.S3Config.Bucket, user.FsConfig.S3Config.Bucket)
+	assert.Equal(t, updateUser.FsConfig.S3Config.Region, user.FsConfig.S3Config.Region)
+	assert.Equal(t, updateUser.FsConfig.S3Config.AccessKey, user.FsConfig.S3Config.AccessKey)
+	assert.Equal(t, updateUser.FsConfig.S3Config.StorageClass, user.FsConfig.S3Config.StorageClass)
+	assert.Equal(t, updateUser.FsConfig.S3Config.Endpoint, user.FsConfig.S3Config.Endpoint)
+	assert.Equal(t, updateUser.FsConfig.S3Config.KeyPrefix, user.FsConfig.S3Config.KeyPrefix)
+	assert.Equal(t, updateUser.FsConfig.S3Config.UploadPartSize, user.FsConfig.S3Config.UploadPartSize)
+	assert.Equal(t, updateUser.FsConfig.S3Config.UploadConcurrency, user.FsConfig.S3Config.UploadConcurrency)
+	assert.Equal(t, updateUser.FsConfig.S3Config.DownloadPartMaxTime, user.FsConfig.S3Config.DownloadPartMaxTime)
+	assert.Equal(t, updateUser.FsConfig.S3Config.DownloadPartSize, user.FsConfig.S3Config.DownloadPartSize)
+	assert.Equal(t, updateUser.FsConfig.S3Config.DownloadConcurrency, user.FsConfig.S3Config.DownloadConcurrency)
+	assert.True(t, updateUser.FsConfig.S3Config.ForcePathStyle)
+	assert.Equal(t, 2, len(updateUser.Filters.FilePatterns))
+	assert.Equal(t, kms.SecretStatusSecretBox, updateUser.FsConfig.S3Config.AccessSecret.GetStatus())
+	assert.NotEmpty(t, updateUser.FsConfig.S3Config.AccessSecret.GetPayload())
+	assert.Empty(t, updateUser.FsConfig.S3Config.AccessSecret.GetKey())
+	assert.Empty(t, updateUser.FsConfig.S3Config.AccessSecret.GetAdditionalData())
 	assert.Equal(t, user.Description, updateUser.Description)
-	assert.Equal(t, int64(100), updateUser.Filters.MaxUploadFileSize)
-	assert.Equal(t, sdk.TLSUsernameCN, updateUser.Filters.TLSUsername)
+	assert.True(t, updateUser.Filters.Hooks.PreLoginDisabled)
+	assert.False(t, updateUser.Filters.Hooks.ExternalAuthDisabled)
+	assert.False(t, updateUser.Filters.Hooks.CheckPasswordDisabled)
+	assert.False(t, updateUser.Filters.DisableFsChecks)
 	assert.True(t, updateUser.Filters.AllowAPIKeyAuth)
-	assert.True(t, updateUser.Filters.TOTPConfig.Enabled)
-
-	if val, ok := updateUser.Permissions["/otherdir"]; ok {
-		assert.True(t, util.IsStringInSlice(dataprovider.PermListItems, val))
-		assert.True(t, util.IsStringInSlice(dataprovider.PermUpload, val))
-	} else {
-		assert.Fail(t, "user permissions must contains /otherdir", "actual: %v", updateUser.Permissions)
-	}
-	assert.True(t, util.IsStringInSlice("192.168.1.3/32", updateUser.Filters.AllowedIP))
-	assert.True(t, util.IsStringInSlice("10.0.0.2/32", updateUser.Filters.DeniedIP))
-	assert.True(t, util.IsStringInSlice(dataprovider.SSHLoginMethodKeyboardInteractive, updateUser.Filters.DeniedLoginMethods))
-	assert.True(t, util.IsStringInSlice(common.ProtocolFTP, updateUser.Filters.DeniedProtocols))
-	assert.True(t, util.IsStringInSlice("*.zip", updateUser.Filters.FilePatterns[0].DeniedPatterns))
-	req, err = http.NewRequest(http.MethodDelete, path.Join(userPath, user.Username), nil)
-	assert.NoError(t, err)
+	// now check that a redacted password is not saved
+	form.Set("s3_access_secret", redactedSecret)
+	b, contentType, _ = getMultipartFormData(form, "", "")
+	req, _ = http.NewRequest(http.MethodPost, path.Join(webUserPath, user.Username), &b)
+	setJWTCookieForReq(req, webToken)
+	req.Header.Set("Content-Type", contentType)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusSeeOther, rr)
+	req, _ = http.NewRequest(http.MethodGet, path.Join(userPath, user.Username), nil)
 	setBearerForReq(req, apiToken)
 	rr = executeRequest(req)
 	checkResponseCode(t, http.StatusOK, rr)
-}
 
-func TestRenderFolderTemplateMock(t *testing.T) {
-	token, err := getJWTWebTokenFromTestServer(defaultTokenAuthUser, defaultTokenAuthPass)
-	assert.NoError(t, err)
-	req, err := http.NewRequest(http.MethodGet, webTemplateFolder, nil)
+	var lastUpdatedUser dataprovider.User
+	err = render.DecodeJSON(rr.Body, &lastUpdatedUser)
 	assert.NoError(t, err)
-	setJWTCookieForReq(req, token)
-	rr := executeRequest(req)
+	assert.Equal(t, kms.SecretStatusSecretBox, lastUpdatedUser.FsConfig.S3Config.AccessSecret.GetStatus())
+	assert.Equal(t, updateUser.FsConfig.S3Config.AccessSecret.GetPayload(), lastUpdatedUser.FsConfig.S3Config.AccessSecret.GetPayload())
+	assert.Empty(t, lastUpdatedUser.FsConfig.S3Config.AccessSecret.GetKey())
+	assert.Empty(t, lastUpdatedUser.FsConfig.S3Config.AccessSecret.GetAdditionalData())
+	// now clear credentials
+	form.Set("s3_access_key", "")
+	form.Set("s3_access_secret", "")
+	b, contentType, _ = getMultipartFormData(form, "", "")
+	req, _ = http.NewRequest(http.MethodPost, path.Join(webUserPath, user.Username), &b)
+	setJWTCookieForReq(req, webToken)
+	req.Header.Set("Content-Type", contentType)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusSeeOther, rr)
+	req, _ = http.NewRequest(http.MethodGet, path.Join(userPath, user.Username), nil)
+	setBearerForReq(req, apiToken)
+	rr = executeRequest(req)
 	checkResponseCode(t, http.StatusOK, rr)
-
-	folder := vfs.BaseVirtualFolder{
-		Name:        "templatefolder",
-		MappedPath:  filepath.Join(os.TempDir(), "mapped"),
-		Description: "template folder desc",
-	}
-	folder, _, err = httpdtest.AddFolder(folder, http.StatusCreated)
+	var userGet dataprovider.User
+	err = render.DecodeJSON(rr.Body, &userGet)
 	assert.NoError(t, err)
+	assert.Nil(t, userGet.FsConfig.S3Config.AccessSecret)
 
-	req, err = http.NewRequest(http.MethodGet, webTemplateFolder+fmt.Sprintf("?from=%v", folder.Name), nil)
-	assert.NoError(t, err)
-	setJWTCookieForReq(req, token)
+	req, _ = http.NewRequest(http.MethodDelete, path.Join(userPath, user.Username), nil)
+	setBearerForReq(req, apiToken)
 	rr = executeRequest(req)
 	checkResponseCode(t, http.StatusOK, rr)
+}
 
-	req, err = http.NewRequest(http.MethodGet, webTemplateFolder+"?from=unknown-folder", nil)
+func TestWebUserGCSMock(t *testing.T) {
+	webToken, err := getJWTWebTokenFromTestServer(defaultTokenAuthUser, defaultTokenAuthPass)
 	assert.NoError(t, err)
-	setJWTCookieForReq(req, token)
-	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusNotFound, rr)
-
-	_, err = httpdtest.RemoveFolder(folder, http.StatusOK)
+	apiToken, err := getJWTAPITokenFromTestServer(defaultTokenAuthUser, defaultTokenAuthPass)
 	assert.NoError(t, err)
-}
-
-func TestRenderUserTemplateMock(t *testing.T) {
-	token, err := getJWTWebTokenFromTestServer(defaultTokenAuthUser, defaultTokenAuthPass)
+	csrfToken, err := getCSRFToken(httpBaseURL + webLoginPath)
 	assert.NoError(t, err)
-	req, err := http.NewRequest(http.MethodGet, webTemplateUser, nil)
+	user := getTestUser()
+	userAsJSON := getUserAsJSON(t, user)
+	req, err := http.NewRequest(http.MethodPost, userPath, bytes.NewBuffer(userAsJSON))
 	assert.NoError(t, err)
-	setJWTCookieForReq(req, token)
+	setBearerForReq(req, apiToken)
 	rr := executeRequest(req)
-	checkResponseCode(t, http.StatusOK, rr)
-
-	user, _, err := httpdtest.AddUser(getTestUser(), http.StatusCreated)
+	checkResponseCode(t, http.StatusCreated, rr)
+	err = render.DecodeJSON(rr.Body, &user)
 	assert.NoError(t, err)
-
-	req, err = http.NewRequest(http.MethodGet, webTemplateUser+fmt.Sprintf("?from=%v", user.Username), nil)
+	credentialsFilePath := filepath.Join(os.TempDir(), "gcs.json")
+	err = createTestFile(credentialsFilePath, 0)
 	assert.NoError(t, err)
-	setJWTCookieForReq(req, token)
+	user.FsConfig.Provider = sdk.GCSFilesystemProvider
+	user.FsConfig.GCSConfig.Bucket = "test"
+	user.FsConfig.GCSConfig.KeyPrefix = "somedir/subdir/"
+	user.FsConfig.GCSConfig.StorageClass = "standard"
+	form := make(url.Values)
+	form.Set(csrfFormToken, csrfToken)
+	form.Set("username", user.Username)
+	form.Set("password", redactedSecret)
+	form.Set("home_dir", user.HomeDir)
+	form.Set("uid", "0")
+	form.Set("gid", strconv.FormatInt(int64(user.GID), 10))
+	form.Set("max_sessions", strconv.FormatInt(int64(user.MaxSessions), 10))
+	form.Set("quota_size", strconv.FormatInt(user.QuotaSize, 10))
+	form.Set("quota_files", strconv.FormatInt(int64(user.QuotaFiles), 10))
+	form.Set("upload_bandwidth", "0")
+	form.Set("download_bandwidth", "0")
+	form.Set("permissions", "*")
+	form.Set("status", strconv.Itoa(user.Status))
+	form.Set("expiration_date", "2020-01-01 00:00:00")
+	form.Set("allowed_ip", "")
+	form.Set("denied_ip", "")
+	form.Set("fs_provider", "2")
+	form.Set("gcs_bucket", user.FsConfig.GCSConfig.Bucket)
+	form.Set("gcs_storage_class", user.FsConfig.GCSConfig.StorageClass)
+	form.Set("gcs_key_prefix", user.FsConfig.GCSConfig.KeyPrefix)
+	form.Set("pattern_path0", "/dir1")
+	form.Set("patterns0", "*.jpg,*.png")
+	form.Set("pattern_type0", "allowed")
+	form.Set("max_upload_file_size", "0")
+	b, contentType, _ := getMultipartFormData(form, "", "")
+	req, _ = http.NewRequest(http.MethodPost, path.Join(webUserPath, user.Username), &b)
+	setJWTCookieForReq(req, webToken)
+	req.Header.Set("Content-Type", contentType)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
+	b, contentType, _ = getMultipartFormData(form, "gcs_credential_file", credentialsFilePath)
+	req, _ = http.NewRequest(http.MethodPost, path.Join(webUserPath, user.Username), &b)
+	setJWTCookieForReq(req, webToken)
+	req.Header.Set("Content-Type", contentType)
 	rr = executeRequest(req)
 	checkResponseCode(t, http.StatusOK, rr)
-
-	req, err = http.NewRequest(http.MethodGet, webTemplateUser+"?from=unknown", nil)
+	err = createTestFile(credentialsFilePath, 4096)
 	assert.NoError(t, err)
-	setJWTCookieForReq(req, token)
+	b, contentType, _ = getMultipartFormData(form, "gcs_credential_file", credentialsFilePath)
+	req, _ = http.NewRequest(http.MethodPost, path.Join(webUserPath, user.Username), &b)
+	setJWTCookieForReq(req, webToken)
+	req.Header.Set("Content-Type", contentType)
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusNotFound, rr)
-
-	_, err = httpdtest.RemoveUser(user, http.StatusOK)
-	assert.NoError(t, err)
-}
-
-func TestRenderWebCloneUserMock(t *testing.T) {
-	token, err := getJWTWebTokenFromTestServer(defaultTokenAuthUser, defaultTokenAuthPass)
-	assert.NoError(t, err)
-	user, _, err := httpdtest.AddUser(getTestUser(), http.StatusCreated)
-	assert.NoError(t, err)
-
-	req, err := http.NewRequest(http.MethodGet, webUserPath+fmt.Sprintf("?clone-from=%v", user.Username), nil)
+	checkResponseCode(t, http.StatusSeeOther, rr)
+	req, _ = http.NewRequest(http.MethodGet, path.Join(userPath, user.Username), nil)
+	setBearerForReq(req, apiToken)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
+	var updateUser dataprovider.User
+	err = render.DecodeJSON(rr.Body, &updateUser)
 	assert.NoError(t, err)
-	setJWTCookieForReq(req, token)
-	rr := executeRequest(req)
+	assert.Equal(t, int64(1577836800000), updateUser.ExpirationDate)
+	assert.Equal(t, user.FsConfig.Provider, updateUser.FsConfig.Provider)
+	assert.Equal(t, user.FsConfig.GCSConfig.Bucket, updateUser.FsConfig.GCSConfig.Bucket)
+	assert.Equal(t, user.FsConfig.GCSConfig.StorageClass, updateUser.FsConfig.GCSConfig.StorageClass)
+	assert.Equal(t, user.FsConfig.GCSConfig.KeyPrefix, updateUser.FsConfig.GCSConfig.KeyPrefix)
+	if assert.Len(t, updateUser.Filters.FilePatterns, 1) {
+		assert.Equal(t, "/dir1", updateUser.Filters.FilePatterns[0].Path)
+		assert.Len(t, updateUser.Filters.FilePatterns[0].AllowedPatterns, 2)
+		assert.Contains(t, updateUser.Filters.FilePatterns[0].AllowedPatterns, "*.png")
+		assert.Contains(t, updateUser.Filters.FilePatterns[0].AllowedPatterns, "*.jpg")
+	}
+	form.Set("gcs_auto_credentials", "on")
+	b, contentType, _ = getMultipartFormData(form, "", "")
+	req, _ = http.NewRequest(http.MethodPost, path.Join(webUserPath, user.Username), &b)
+	setJWTCookieForReq(req, webToken)
+	req.Header.Set("Content-Type", contentType)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusSeeOther, rr)
+	req, _ = http.NewRequest(http.MethodGet, path.Join(userPath, user.Username), nil)
+	setBearerForReq(req, apiToken)
+	rr = executeRequest(req)
 	checkResponseCode(t, http.StatusOK, rr)
-
-	req, err = http.NewRequest(http.MethodGet, webUserPath+fmt.Sprintf("?clone-from=%v", altAdminPassword), nil)
+	updateUser = dataprovider.User{}
+	err = render.DecodeJSON(rr.Body, &updateUser)
 	assert.NoError(t, err)
-	setJWTCookieForReq(req, token)
+	assert.Equal(t, 1, updateUser.FsConfig.GCSConfig.AutomaticCredentials)
+	req, _ = http.NewRequest(http.MethodDelete, path.Join(userPath, user.Username), nil)
+	setBearerForReq(req, apiToken)
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusNotFound, rr)
-
-	_, err = httpdtest.RemoveUser(user, http.StatusOK)
+	checkResponseCode(t, http.StatusOK, rr)
+	err = os.Remove(credentialsFilePath)
 	assert.NoError(t, err)
 }
 
-func TestUserTemplateWithFoldersMock(t *testing.T) {
-	folder := vfs.BaseVirtualFolder{
-		Name:        "vfolder",
-		MappedPath:  filepath.Join(os.TempDir(), "mapped"),
-		Description: "vfolder desc with spéciàl ch@rs",
-	}
-
-	token, err := getJWTWebTokenFromTestServer(defaultTokenAuthUser, defaultTokenAuthPass)
+func TestWebUserAzureBlobMock(t *testing.T) {
+	webToken, err := getJWTWebTokenFromTestServer(defaultTokenAuthUser, defaultTokenAuthPass)
+	assert.NoError(t, err)
+	apiToken, err := getJWTAPITokenFromTestServer(defaultTokenAuthUser, defaultTokenAuthPass)
 	assert.NoError(t, err)
 	csrfToken, err := getCSRFToken(httpBaseURL + webLoginPath)
 	assert.NoError(t, err)
 	user := getTestUser()
+	userAsJSON := getUserAsJSON(t, user)
+	req, _ := http.NewRequest(http.MethodPost, userPath, bytes.NewBuffer(userAsJSON))
+	setBearerForReq(req, apiToken)
+	rr := executeRequest(req)
+	checkResponseCode(t, http.StatusCreated, rr)
+	err = render.DecodeJSON(rr.Body, &user)
+	assert.NoError(t, err)
+	user.FsConfig.Provider = sdk.AzureBlobFilesystemProvider
+	user.FsConfig.AzBlobConfig.Container = "container"
+	user.FsConfig.AzBlobConfig.AccountName = "aname"
+	user.FsConfig.AzBlobConfig.AccountKey = kms.NewPlainSecret("access-skey")
+	user.FsConfig.AzBlobConfig.Endpoint = "http://127.0.0.1:9000/path?b=c"
+	user.FsConfig.AzBlobConfig.KeyPrefix = "somedir/subdir/"
+	user.FsConfig.AzBlobConfig.UploadPartSize = 5
+	user.FsConfig.AzBlobConfig.UploadConcurrency = 4
+	user.FsConfig.AzBlobConfig.UseEmulator = true
 	form := make(url.Values)
+	form.Set(csrfFormToken, csrfToken)
 	form.Set("username", user.Username)
-	form.Set("home_dir", filepath.Join(os.TempDir(), "%username%"))
-	form.Set("uid", strconv.FormatInt(int64(user.UID), 10))
+	form.Set("password", redactedSecret)
+	form.Set("home_dir", user.HomeDir)
+	form.Set("uid", "0")
 	form.Set("gid", strconv.FormatInt(int64(user.GID), 10))
 	form.Set("max_sessions", strconv.FormatInt(int64(user.MaxSessions), 10))
 	form.Set("quota_size", strconv.FormatInt(user.QuotaSize, 10))
@@ -11736,96 +17253,156 @@ func TestUserTemplateWithFoldersMock(t *testing.T) {
 	form.Set("permissions", "*")
 	form.Set("status", strconv.Itoa(user.Status))
 	form.Set("expiration_date", "2020-01-01 00:00:00")
-	form.Set("fs_provider", "0")
+	form.Set("allowed_ip", "")
+	form.Set("denied_ip", "")
+	form.Set("fs_provider", "3")
+	form.Set("az_container", user.FsConfig.AzBlobConfig.Container)
+	form.Set("az_account_name", user.FsConfig.AzBlobConfig.AccountName)
+	form.Set("az_account_key", user.FsConfig.AzBlobConfig.AccountKey.GetPayload())
+	form.Set("az_endpoint", user.FsConfig.AzBlobConfig.Endpoint)
+	form.Set("az_key_prefix", user.FsConfig.AzBlobConfig.KeyPrefix)
+	form.Set("az_use_emulator", "checked")
+	form.Set("pattern_path0", "/dir1")
+	form.Set("patterns0", "*.jpg,*.png")
+	form.Set("pattern_type0", "allowed")
+	form.Set("pattern_path1", "/dir2")
+	form.Set("patterns1", "*.zip")
+	form.Set("pattern_type1", "denied")
 	form.Set("max_upload_file_size", "0")
-	form.Set("description", "desc %username% %password%")
-	form.Set("vfolder_path", "/vdir%username%")
-	form.Set("vfolder_name", folder.Name)
-	form.Set("vfolder_quota_size", "-1")
-	form.Set("vfolder_quota_files", "-1")
-	form.Add("tpl_username", "auser1")
-	form.Add("tpl_password", "password1")
-	form.Add("tpl_public_keys", " ")
-	form.Add("tpl_username", "auser2")
-	form.Add("tpl_password", "password2")
-	form.Add("tpl_public_keys", testPubKey)
-	form.Add("tpl_username", "auser1")
-	form.Add("tpl_password", "password")
-	form.Add("tpl_public_keys", "")
+	// test invalid az_upload_part_size
+	form.Set("az_upload_part_size", "a")
 	b, contentType, _ := getMultipartFormData(form, "", "")
-	req, _ := http.NewRequest(http.MethodPost, path.Join(webTemplateUser), &b)
-	setJWTCookieForReq(req, token)
+	req, _ = http.NewRequest(http.MethodPost, path.Join(webUserPath, user.Username), &b)
+	setJWTCookieForReq(req, webToken)
 	req.Header.Set("Content-Type", contentType)
-	rr := executeRequest(req)
-	checkResponseCode(t, http.StatusForbidden, rr)
-	require.Contains(t, rr.Body.String(), "unable to verify form token")
-
-	form.Set(csrfFormToken, csrfToken)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
+	// test invalid az_upload_concurrency
+	form.Set("az_upload_part_size", strconv.FormatInt(user.FsConfig.AzBlobConfig.UploadPartSize, 10))
+	form.Set("az_upload_concurrency", "a")
 	b, contentType, _ = getMultipartFormData(form, "", "")
-	req, _ = http.NewRequest(http.MethodPost, path.Join(webTemplateUser), &b)
-	setJWTCookieForReq(req, token)
+	req, _ = http.NewRequest(http.MethodPost, path.Join(webUserPath, user.Username), &b)
+	setJWTCookieForReq(req, webToken)
 	req.Header.Set("Content-Type", contentType)
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusBadRequest, rr)
-	require.Contains(t, rr.Body.String(), "invalid folder mapped path")
-
-	folder, resp, err := httpdtest.AddFolder(folder, http.StatusCreated)
-	assert.NoError(t, err, string(resp))
-
+	checkResponseCode(t, http.StatusOK, rr)
+	// now add the user
+	form.Set("az_upload_concurrency", strconv.Itoa(user.FsConfig.AzBlobConfig.UploadConcurrency))
 	b, contentType, _ = getMultipartFormData(form, "", "")
-	req, _ = http.NewRequest(http.MethodPost, path.Join(webTemplateUser), &b)
-	setJWTCookieForReq(req, token)
+	req, _ = http.NewRequest(http.MethodPost, path.Join(webUserPath, user.Username), &b)
+	setJWTCookieForReq(req, webToken)
 	req.Header.Set("Content-Type", contentType)
 	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusSeeOther, rr)
+	req, _ = http.NewRequest(http.MethodGet, path.Join(userPath, user.Username), nil)
+	setBearerForReq(req, apiToken)
+	rr = executeRequest(req)
 	checkResponseCode(t, http.StatusOK, rr)
-
-	var dump dataprovider.BackupData
-	err = json.Unmarshal(rr.Body.Bytes(), &dump)
+	var updateUser dataprovider.User
+	err = render.DecodeJSON(rr.Body, &updateUser)
 	assert.NoError(t, err)
-	assert.Len(t, dump.Users, 2)
-	assert.Len(t, dump.Folders, 1)
-	user1 := dump.Users[0]
-	user2 := dump.Users[1]
-	folder1 := dump.Folders[0]
-	assert.Equal(t, "auser1", user1.Username)
-	assert.Equal(t, "auser2", user2.Username)
-	assert.Equal(t, "desc auser1 password1", user1.Description)
-	assert.Equal(t, "desc auser2 password2", user2.Description)
-	assert.Equal(t, filepath.Join(os.TempDir(), user1.Username), user1.HomeDir)
-	assert.Equal(t, filepath.Join(os.TempDir(), user2.Username), user2.HomeDir)
-	assert.Equal(t, folder.Name, folder1.Name)
-	assert.Equal(t, folder.MappedPath, folder1.MappedPath)
-	assert.Equal(t, folder.Description, folder1.Description)
-	assert.Len(t, user1.PublicKeys, 0)
-	assert.Len(t, user2.PublicKeys, 1)
-	assert.Len(t, user1.VirtualFolders, 1)
-	assert.Len(t, user2.VirtualFolders, 1)
-	assert.Equal(t, "/vdirauser1", user1.VirtualFolders[0].VirtualPath)
-	assert.Equal(t, "/vdirauser2", user2.VirtualFolders[0].VirtualPath)
-
-	_, err = httpdtest.RemoveFolder(folder, http.StatusOK)
+	assert.Equal(t, int64(1577836800000), updateUser.ExpirationDate)
+	assert.Equal(t, updateUser.FsConfig.AzBlobConfig.Container, user.FsConfig.AzBlobConfig.Container)
+	assert.Equal(t, updateUser.FsConfig.AzBlobConfig.AccountName, user.FsConfig.AzBlobConfig.AccountName)
+	assert.Equal(t, updateUser.FsConfig.AzBlobConfig.Endpoint, user.FsConfig.AzBlobConfig.Endpoint)
+	assert.Equal(t, updateUser.FsConfig.AzBlobConfig.KeyPrefix, user.FsConfig.AzBlobConfig.KeyPrefix)
+	assert.Equal(t, updateUser.FsConfig.AzBlobConfig.UploadPartSize, user.FsConfig.AzBlobConfig.UploadPartSize)
+	assert.Equal(t, updateUser.FsConfig.AzBlobConfig.UploadConcurrency, user.FsConfig.AzBlobConfig.UploadConcurrency)
+	assert.Equal(t, 2, len(updateUser.Filters.FilePatterns))
+	assert.Equal(t, kms.SecretStatusSecretBox, updateUser.FsConfig.AzBlobConfig.AccountKey.GetStatus())
+	assert.NotEmpty(t, updateUser.FsConfig.AzBlobConfig.AccountKey.GetPayload())
+	assert.Empty(t, updateUser.FsConfig.AzBlobConfig.AccountKey.GetKey())
+	assert.Empty(t, updateUser.FsConfig.AzBlobConfig.AccountKey.GetAdditionalData())
+	// now check that a redacted password is not saved
+	form.Set("az_account_key", redactedSecret+" ")
+	b, contentType, _ = getMultipartFormData(form, "", "")
+	req, _ = http.NewRequest(http.MethodPost, path.Join(webUserPath, user.Username), &b)
+	setJWTCookieForReq(req, webToken)
+	req.Header.Set("Content-Type", contentType)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusSeeOther, rr)
+	req, _ = http.NewRequest(http.MethodGet, path.Join(userPath, user.Username), nil)
+	setBearerForReq(req, apiToken)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
+	var lastUpdatedUser dataprovider.User
+	err = render.DecodeJSON(rr.Body, &lastUpdatedUser)
+	assert.NoError(t, err)
+	assert.Equal(t, kms.SecretStatusSecretBox, lastUpdatedUser.FsConfig.AzBlobConfig.AccountKey.GetStatus())
+	assert.Equal(t, updateUser.FsConfig.AzBlobConfig.AccountKey.GetPayload(), lastUpdatedUser.FsConfig.AzBlobConfig.AccountKey.GetPayload())
+	assert.Empty(t, lastUpdatedUser.FsConfig.AzBlobConfig.AccountKey.GetKey())
+	assert.Empty(t, lastUpdatedUser.FsConfig.AzBlobConfig.AccountKey.GetAdditionalData())
+	// test SAS url
+	user.FsConfig.AzBlobConfig.SASURL = kms.NewPlainSecret("sasurl")
+	form.Set("az_account_name", "")
+	form.Set("az_account_key", "")
+	form.Set("az_container", "")
+	form.Set("az_sas_url", user.FsConfig.AzBlobConfig.SASURL.GetPayload())
+	b, contentType, _ = getMultipartFormData(form, "", "")
+	req, _ = http.NewRequest(http.MethodPost, path.Join(webUserPath, user.Username), &b)
+	setJWTCookieForReq(req, webToken)
+	req.Header.Set("Content-Type", contentType)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusSeeOther, rr)
+	req, _ = http.NewRequest(http.MethodGet, path.Join(userPath, user.Username), nil)
+	setBearerForReq(req, apiToken)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
+	updateUser = dataprovider.User{}
+	err = render.DecodeJSON(rr.Body, &updateUser)
+	assert.NoError(t, err)
+	assert.Equal(t, kms.SecretStatusSecretBox, updateUser.FsConfig.AzBlobConfig.SASURL.GetStatus())
+	assert.NotEmpty(t, updateUser.FsConfig.AzBlobConfig.SASURL.GetPayload())
+	assert.Empty(t, updateUser.FsConfig.AzBlobConfig.SASURL.GetKey())
+	assert.Empty(t, updateUser.FsConfig.AzBlobConfig.SASURL.GetAdditionalData())
+	// now check that a redacted sas url is not saved
+	form.Set("az_sas_url", redactedSecret)
+	b, contentType, _ = getMultipartFormData(form, "", "")
+	req, _ = http.NewRequest(http.MethodPost, path.Join(webUserPath, user.Username), &b)
+	setJWTCookieForReq(req, webToken)
+	req.Header.Set("Content-Type", contentType)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusSeeOther, rr)
+	req, _ = http.NewRequest(http.MethodGet, path.Join(userPath, user.Username), nil)
+	setBearerForReq(req, apiToken)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
+	lastUpdatedUser = dataprovider.User{}
+	err = render.DecodeJSON(rr.Body, &lastUpdatedUser)
 	assert.NoError(t, err)
+	assert.Equal(t, kms.SecretStatusSecretBox, lastUpdatedUser.FsConfig.AzBlobConfig.SASURL.GetStatus())
+	assert.Equal(t, updateUser.FsConfig.AzBlobConfig.SASURL.GetPayload(), lastUpdatedUser.FsConfig.AzBlobConfig.SASURL.GetPayload())
+	assert.Empty(t, lastUpdatedUser.FsConfig.AzBlobConfig.SASURL.GetKey())
+	assert.Empty(t, lastUpdatedUser.FsConfig.AzBlobConfig.SASURL.GetAdditionalData())
+
+	req, _ = http.NewRequest(http.MethodDelete, path.Join(userPath, user.Username), nil)
+	setBearerForReq(req, apiToken)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
 }
 
-func TestUserTemplateMock(t *testing.T) {
-	token, err := getJWTWebTokenFromTestServer(defaultTokenAuthUser, defaultTokenAuthPass)
+func TestWebUserCryptMock(t *testing.T) {
+	webToken, err := getJWTWebTokenFromTestServer(defaultTokenAuthUser, defaultTokenAuthPass)
+	assert.NoError(t, err)
+	apiToken, err := getJWTAPITokenFromTestServer(defaultTokenAuthUser, defaultTokenAuthPass)
 	assert.NoError(t, err)
-	user := getTestUser()
-	user.FsConfig.Provider = sdk.S3FilesystemProvider
-	user.FsConfig.S3Config.Bucket = "test"
-	user.FsConfig.S3Config.Region = "eu-central-1"
-	user.FsConfig.S3Config.AccessKey = "%username%"
-	user.FsConfig.S3Config.KeyPrefix = "somedir/subdir/"
-	user.FsConfig.S3Config.UploadPartSize = 5
-	user.FsConfig.S3Config.UploadConcurrency = 4
-	user.FsConfig.S3Config.DownloadPartSize = 6
-	user.FsConfig.S3Config.DownloadConcurrency = 3
 	csrfToken, err := getCSRFToken(httpBaseURL + webLoginPath)
 	assert.NoError(t, err)
+	user := getTestUser()
+	userAsJSON := getUserAsJSON(t, user)
+	req, _ := http.NewRequest(http.MethodPost, userPath, bytes.NewBuffer(userAsJSON))
+	setBearerForReq(req, apiToken)
+	rr := executeRequest(req)
+	checkResponseCode(t, http.StatusCreated, rr)
+	err = render.DecodeJSON(rr.Body, &user)
+	assert.NoError(t, err)
+	user.FsConfig.Provider = sdk.CryptedFilesystemProvider
+	user.FsConfig.CryptConfig.Passphrase = kms.NewPlainSecret("crypted passphrase")
 	form := make(url.Values)
 	form.Set(csrfFormToken, csrfToken)
 	form.Set("username", user.Username)
-	form.Set("home_dir", filepath.Join(os.TempDir(), "%username%"))
+	form.Set("password", redactedSecret)
+	form.Set("home_dir", user.HomeDir)
 	form.Set("uid", "0")
 	form.Set("gid", strconv.FormatInt(int64(user.GID), 10))
 	form.Set("max_sessions", strconv.FormatInt(int64(user.MaxSessions), 10))
@@ -11838,246 +17415,193 @@ func TestUserTemplateMock(t *testing.T) {
 	form.Set("expiration_date", "2020-01-01 00:00:00")
 	form.Set("allowed_ip", "")
 	form.Set("denied_ip", "")
-	form.Set("fs_provider", "1")
-	form.Set("s3_bucket", user.FsConfig.S3Config.Bucket)
-	form.Set("s3_region", user.FsConfig.S3Config.Region)
-	form.Set("s3_access_key", "%username%")
-	form.Set("s3_access_secret", "%password%")
-	form.Set("s3_key_prefix", "base/%username%")
-	form.Set("allowed_extensions", "/dir1::.jpg,.png")
-	form.Set("denied_extensions", "/dir2::.zip")
+	form.Set("fs_provider", "4")
+	form.Set("crypt_passphrase", "")
+	form.Set("pattern_path0", "/dir1")
+	form.Set("patterns0", "*.jpg,*.png")
+	form.Set("pattern_type0", "allowed")
+	form.Set("pattern_path1", "/dir2")
+	form.Set("patterns1", "*.zip")
+	form.Set("pattern_type1", "denied")
 	form.Set("max_upload_file_size", "0")
-	form.Add("hooks", "external_auth_disabled")
-	form.Add("hooks", "check_password_disabled")
-	form.Set("disable_fs_checks", "checked")
-	form.Set("s3_download_part_max_time", "0")
-	// test invalid s3_upload_part_size
-	form.Set("s3_upload_part_size", "a")
+	// passphrase cannot be empty
 	b, contentType, _ := getMultipartFormData(form, "", "")
-	req, _ := http.NewRequest(http.MethodPost, webTemplateUser, &b)
-	setJWTCookieForReq(req, token)
-	req.Header.Set("Content-Type", contentType)
-	rr := executeRequest(req)
-	checkResponseCode(t, http.StatusBadRequest, rr)
-	form.Set("s3_upload_part_size", strconv.FormatInt(user.FsConfig.S3Config.UploadPartSize, 10))
-	form.Set("s3_upload_concurrency", strconv.Itoa(user.FsConfig.S3Config.UploadConcurrency))
-	form.Set("s3_download_part_size", strconv.FormatInt(user.FsConfig.S3Config.DownloadPartSize, 10))
-	form.Set("s3_download_concurrency", strconv.Itoa(user.FsConfig.S3Config.DownloadConcurrency))
-
-	b, contentType, _ = getMultipartFormData(form, "", "")
-	req, _ = http.NewRequest(http.MethodPost, webTemplateUser, &b)
-	setJWTCookieForReq(req, token)
+	req, _ = http.NewRequest(http.MethodPost, path.Join(webUserPath, user.Username), &b)
+	setJWTCookieForReq(req, webToken)
 	req.Header.Set("Content-Type", contentType)
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusBadRequest, rr)
-
-	form.Set("tpl_username", "user1")
-	form.Set("tpl_password", "password1")
-	form.Set("tpl_public_keys", "invalid-pkey")
+	checkResponseCode(t, http.StatusOK, rr)
+	form.Set("crypt_passphrase", user.FsConfig.CryptConfig.Passphrase.GetPayload())
 	b, contentType, _ = getMultipartFormData(form, "", "")
-	req, _ = http.NewRequest(http.MethodPost, webTemplateUser, &b)
-	setJWTCookieForReq(req, token)
+	req, _ = http.NewRequest(http.MethodPost, path.Join(webUserPath, user.Username), &b)
+	setJWTCookieForReq(req, webToken)
 	req.Header.Set("Content-Type", contentType)
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusBadRequest, rr)
-	require.Contains(t, rr.Body.String(), "Error validating user")
-
-	form.Set("tpl_username", "user1")
-	form.Set("tpl_password", " ")
-	form.Set("tpl_public_keys", "")
-	b, contentType, _ = getMultipartFormData(form, "", "")
-	req, _ = http.NewRequest(http.MethodPost, webTemplateUser, &b)
-	setJWTCookieForReq(req, token)
-	req.Header.Set("Content-Type", contentType)
+	checkResponseCode(t, http.StatusSeeOther, rr)
+	req, _ = http.NewRequest(http.MethodGet, path.Join(userPath, user.Username), nil)
+	setBearerForReq(req, apiToken)
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusBadRequest, rr)
-	require.Contains(t, rr.Body.String(), "No valid users found, export is not possible")
-
-	form.Set("tpl_username", "user1")
-	form.Set("tpl_password", "password1")
-	form.Set("tpl_public_keys", " ")
-	form.Add("tpl_username", "user2")
-	form.Add("tpl_password", "password2")
-	form.Add("tpl_public_keys", testPubKey)
-	form.Add("tpl_username", "user3")
-	form.Add("tpl_password", "")
-	form.Add("tpl_public_keys", "")
+	checkResponseCode(t, http.StatusOK, rr)
+	var updateUser dataprovider.User
+	err = render.DecodeJSON(rr.Body, &updateUser)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1577836800000), updateUser.ExpirationDate)
+	assert.Equal(t, 2, len(updateUser.Filters.FilePatterns))
+	assert.Equal(t, kms.SecretStatusSecretBox, updateUser.FsConfig.CryptConfig.Passphrase.GetStatus())
+	assert.NotEmpty(t, updateUser.FsConfig.CryptConfig.Passphrase.GetPayload())
+	assert.Empty(t, updateUser.FsConfig.CryptConfig.Passphrase.GetKey())
+	assert.Empty(t, updateUser.FsConfig.CryptConfig.Passphrase.GetAdditionalData())
+	// now check that a redacted password is not saved
+	form.Set("crypt_passphrase", redactedSecret+" ")
 	b, contentType, _ = getMultipartFormData(form, "", "")
-	req, _ = http.NewRequest(http.MethodPost, webTemplateUser, &b)
-	setJWTCookieForReq(req, token)
+	req, _ = http.NewRequest(http.MethodPost, path.Join(webUserPath, user.Username), &b)
+	setJWTCookieForReq(req, webToken)
 	req.Header.Set("Content-Type", contentType)
 	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusSeeOther, rr)
+	req, _ = http.NewRequest(http.MethodGet, path.Join(userPath, user.Username), nil)
+	setBearerForReq(req, apiToken)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
+	var lastUpdatedUser dataprovider.User
+	err = render.DecodeJSON(rr.Body, &lastUpdatedUser)
+	assert.NoError(t, err)
+	assert.Equal(t, kms.SecretStatusSecretBox, lastUpdatedUser.FsConfig.CryptConfig.Passphrase.GetStatus())
+	assert.Equal(t, updateUser.FsConfig.CryptConfig.Passphrase.GetPayload(), lastUpdatedUser.FsConfig.CryptConfig.Passphrase.GetPayload())
+	assert.Empty(t, lastUpdatedUser.FsConfig.CryptConfig.Passphrase.GetKey())
+	assert.Empty(t, lastUpdatedUser.FsConfig.CryptConfig.Passphrase.GetAdditionalData())
+	req, _ = http.NewRequest(http.MethodDelete, path.Join(userPath, user.Username), nil)
+	setBearerForReq(req, apiToken)
+	rr = executeRequest(req)
 	checkResponseCode(t, http.StatusOK, rr)
-
-	var dump dataprovider.BackupData
-	err = json.Unmarshal(rr.Body.Bytes(), &dump)
-	require.NoError(t, err)
-	require.Len(t, dump.Users, 2)
-	require.Len(t, dump.Admins, 0)
-	require.Len(t, dump.Folders, 0)
-	user1 := dump.Users[0]
-	user2 := dump.Users[1]
-	require.Equal(t, "user1", user1.Username)
-	require.Equal(t, sdk.S3FilesystemProvider, user1.FsConfig.Provider)
-	require.Equal(t, "user2", user2.Username)
-	require.Equal(t, sdk.S3FilesystemProvider, user2.FsConfig.Provider)
-	require.Len(t, user2.PublicKeys, 1)
-	require.Equal(t, filepath.Join(os.TempDir(), user1.Username), user1.HomeDir)
-	require.Equal(t, filepath.Join(os.TempDir(), user2.Username), user2.HomeDir)
-	require.Equal(t, user1.Username, user1.FsConfig.S3Config.AccessKey)
-	require.Equal(t, user2.Username, user2.FsConfig.S3Config.AccessKey)
-	require.Equal(t, path.Join("base", user1.Username)+"/", user1.FsConfig.S3Config.KeyPrefix)
-	require.Equal(t, path.Join("base", user2.Username)+"/", user2.FsConfig.S3Config.KeyPrefix)
-	require.True(t, user1.FsConfig.S3Config.AccessSecret.IsEncrypted())
-	err = user1.FsConfig.S3Config.AccessSecret.Decrypt()
-	require.NoError(t, err)
-	require.Equal(t, "password1", user1.FsConfig.S3Config.AccessSecret.GetPayload())
-	require.True(t, user2.FsConfig.S3Config.AccessSecret.IsEncrypted())
-	err = user2.FsConfig.S3Config.AccessSecret.Decrypt()
-	require.NoError(t, err)
-	require.Equal(t, "password2", user2.FsConfig.S3Config.AccessSecret.GetPayload())
-	require.True(t, user1.Filters.Hooks.ExternalAuthDisabled)
-	require.True(t, user1.Filters.Hooks.CheckPasswordDisabled)
-	require.False(t, user1.Filters.Hooks.PreLoginDisabled)
-	require.True(t, user2.Filters.Hooks.ExternalAuthDisabled)
-	require.True(t, user2.Filters.Hooks.CheckPasswordDisabled)
-	require.False(t, user2.Filters.Hooks.PreLoginDisabled)
-	require.True(t, user1.Filters.DisableFsChecks)
-	require.True(t, user2.Filters.DisableFsChecks)
 }
 
-func TestFolderTemplateMock(t *testing.T) {
-	folderName := "vfolder-template"
-	mappedPath := filepath.Join(os.TempDir(), "%name%mapped%name%path")
-	token, err := getJWTWebTokenFromTestServer(defaultTokenAuthUser, defaultTokenAuthPass)
+func TestWebUserSFTPFsMock(t *testing.T) {
+	webToken, err := getJWTWebTokenFromTestServer(defaultTokenAuthUser, defaultTokenAuthPass)
+	assert.NoError(t, err)
+	apiToken, err := getJWTAPITokenFromTestServer(defaultTokenAuthUser, defaultTokenAuthPass)
 	assert.NoError(t, err)
 	csrfToken, err := getCSRFToken(httpBaseURL + webLoginPath)
 	assert.NoError(t, err)
-	form := make(url.Values)
-	form.Set("name", folderName)
-	form.Set("mapped_path", mappedPath)
-	form.Set("description", "desc folder %name%")
-	form.Add("tpl_foldername", "folder1")
-	form.Add("tpl_foldername", "folder2")
-	form.Add("tpl_foldername", "folder3")
-	form.Add("tpl_foldername", "folder1 ")
-	form.Add("tpl_foldername", " ")
-	b, contentType, _ := getMultipartFormData(form, "", "")
-	req, _ := http.NewRequest(http.MethodPost, webTemplateFolder, &b)
-	setJWTCookieForReq(req, token)
-	req.Header.Set("Content-Type", contentType)
+	user := getTestUser()
+	userAsJSON := getUserAsJSON(t, user)
+	req, _ := http.NewRequest(http.MethodPost, userPath, bytes.NewBuffer(userAsJSON))
+	setBearerForReq(req, apiToken)
 	rr := executeRequest(req)
-	checkResponseCode(t, http.StatusForbidden, rr)
-	assert.Contains(t, rr.Body.String(), "unable to verify form token")
-
+	checkResponseCode(t, http.StatusCreated, rr)
+	err = render.DecodeJSON(rr.Body, &user)
+	assert.NoError(t, err)
+	user.FsConfig.Provider = sdk.SFTPFilesystemProvider
+	user.FsConfig.SFTPConfig.Endpoint = "127.0.0.1:22"
+	user.FsConfig.SFTPConfig.Username = "sftpuser"
+	user.FsConfig.SFTPConfig.Password = kms.NewPlainSecret("pwd")
+	user.FsConfig.SFTPConfig.PrivateKey = kms.NewPlainSecret(sftpPrivateKey)
+	user.FsConfig.SFTPConfig.Fingerprints = []string{sftpPkeyFingerprint}
+	user.FsConfig.SFTPConfig.Prefix = "/home/sftpuser"
+	user.FsConfig.SFTPConfig.DisableCouncurrentReads = true
+	user.FsConfig.SFTPConfig.BufferSize = 5
+	form := make(url.Values)
 	form.Set(csrfFormToken, csrfToken)
-	b, contentType, _ = getMultipartFormData(form, "", "")
-	req, _ = http.NewRequest(http.MethodPost, webTemplateFolder+"?param=p%C3%AO%GG", &b)
-	setJWTCookieForReq(req, token)
-	req.Header.Set("Content-Type", contentType)
-	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusBadRequest, rr)
-	assert.Contains(t, rr.Body.String(), "Error parsing folders fields")
-
-	folder1 := "folder1"
-	folder2 := "folder2"
-	folder3 := "folder3"
-	b, contentType, _ = getMultipartFormData(form, "", "")
-	req, _ = http.NewRequest(http.MethodPost, webTemplateFolder, &b)
-	setJWTCookieForReq(req, token)
+	form.Set("username", user.Username)
+	form.Set("password", redactedSecret)
+	form.Set("home_dir", user.HomeDir)
+	form.Set("uid", "0")
+	form.Set("gid", strconv.FormatInt(int64(user.GID), 10))
+	form.Set("max_sessions", strconv.FormatInt(int64(user.MaxSessions), 10))
+	form.Set("quota_size", strconv.FormatInt(user.QuotaSize, 10))
+	form.Set("quota_files", strconv.FormatInt(int64(user.QuotaFiles), 10))
+	form.Set("upload_bandwidth", "0")
+	form.Set("download_bandwidth", "0")
+	form.Set("permissions", "*")
+	form.Set("status", strconv.Itoa(user.Status))
+	form.Set("expiration_date", "2020-01-01 00:00:00")
+	form.Set("allowed_ip", "")
+	form.Set("denied_ip", "")
+	form.Set("fs_provider", "5")
+	form.Set("crypt_passphrase", "")
+	form.Set("pattern_path0", "/dir1")
+	form.Set("patterns0", "*.jpg,*.png")
+	form.Set("pattern_type0", "allowed")
+	form.Set("pattern_path1", "/dir2")
+	form.Set("patterns1", "*.zip")
+	form.Set("pattern_type1", "denied")
+	form.Set("max_upload_file_size", "0")
+	// empty sftpconfig
+	b, contentType, _ := getMultipartFormData(form, "", "")
+	req, _ = http.NewRequest(http.MethodPost, path.Join(webUserPath, user.Username), &b)
+	setJWTCookieForReq(req, webToken)
 	req.Header.Set("Content-Type", contentType)
 	rr = executeRequest(req)
 	checkResponseCode(t, http.StatusOK, rr)
-
-	var dump dataprovider.BackupData
-	err = json.Unmarshal(rr.Body.Bytes(), &dump)
-	require.NoError(t, err)
-	require.Len(t, dump.Users, 0)
-	require.Len(t, dump.Admins, 0)
-	require.Len(t, dump.Folders, 3)
-	require.Equal(t, folder1, dump.Folders[0].Name)
-	require.Equal(t, "desc folder folder1", dump.Folders[0].Description)
-	require.True(t, strings.HasSuffix(dump.Folders[0].MappedPath, "folder1mappedfolder1path"))
-	require.Equal(t, folder2, dump.Folders[1].Name)
-	require.Equal(t, "desc folder folder2", dump.Folders[1].Description)
-	require.True(t, strings.HasSuffix(dump.Folders[1].MappedPath, "folder2mappedfolder2path"))
-	require.Equal(t, folder3, dump.Folders[2].Name)
-	require.Equal(t, "desc folder folder3", dump.Folders[2].Description)
-	require.True(t, strings.HasSuffix(dump.Folders[2].MappedPath, "folder3mappedfolder3path"))
-
-	form.Set("fs_provider", "1")
-	form.Set("s3_bucket", "bucket")
-	form.Set("s3_region", "us-east-1")
-	form.Set("s3_access_key", "%name%")
-	form.Set("s3_access_secret", "pwd%name%")
-	form.Set("s3_key_prefix", "base/%name%")
-
+	form.Set("sftp_endpoint", user.FsConfig.SFTPConfig.Endpoint)
+	form.Set("sftp_username", user.FsConfig.SFTPConfig.Username)
+	form.Set("sftp_password", user.FsConfig.SFTPConfig.Password.GetPayload())
+	form.Set("sftp_private_key", user.FsConfig.SFTPConfig.PrivateKey.GetPayload())
+	form.Set("sftp_fingerprints", user.FsConfig.SFTPConfig.Fingerprints[0])
+	form.Set("sftp_prefix", user.FsConfig.SFTPConfig.Prefix)
+	form.Set("sftp_disable_concurrent_reads", "true")
+	form.Set("sftp_buffer_size", strconv.FormatInt(user.FsConfig.SFTPConfig.BufferSize, 10))
 	b, contentType, _ = getMultipartFormData(form, "", "")
-	req, _ = http.NewRequest(http.MethodPost, webTemplateFolder, &b)
-	setJWTCookieForReq(req, token)
+	req, _ = http.NewRequest(http.MethodPost, path.Join(webUserPath, user.Username), &b)
+	setJWTCookieForReq(req, webToken)
 	req.Header.Set("Content-Type", contentType)
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusBadRequest, rr)
-	assert.Contains(t, rr.Body.String(), "Error parsing folders fields")
-
-	form.Set("s3_upload_part_size", "5")
-	form.Set("s3_upload_concurrency", "4")
-	form.Set("s3_download_part_max_time", "0")
-	form.Set("s3_download_part_size", "6")
-	form.Set("s3_download_concurrency", "2")
-	b, contentType, _ = getMultipartFormData(form, "", "")
-	req, _ = http.NewRequest(http.MethodPost, webTemplateFolder, &b)
-	setJWTCookieForReq(req, token)
-	req.Header.Set("Content-Type", contentType)
+	checkResponseCode(t, http.StatusSeeOther, rr)
+	req, _ = http.NewRequest(http.MethodGet, path.Join(userPath, user.Username), nil)
+	setBearerForReq(req, apiToken)
 	rr = executeRequest(req)
 	checkResponseCode(t, http.StatusOK, rr)
-
-	dump = dataprovider.BackupData{}
-	err = json.Unmarshal(rr.Body.Bytes(), &dump)
-	require.NoError(t, err)
-	require.Len(t, dump.Users, 0)
-	require.Len(t, dump.Admins, 0)
-	require.Len(t, dump.Folders, 3)
-	require.Equal(t, folder1, dump.Folders[0].Name)
-	require.Equal(t, folder1, dump.Folders[0].FsConfig.S3Config.AccessKey)
-	err = dump.Folders[0].FsConfig.S3Config.AccessSecret.Decrypt()
-	require.NoError(t, err)
-	require.Equal(t, "pwd"+folder1, dump.Folders[0].FsConfig.S3Config.AccessSecret.GetPayload())
-	require.Equal(t, "base/"+folder1+"/", dump.Folders[0].FsConfig.S3Config.KeyPrefix)
-	require.Equal(t, folder2, dump.Folders[1].Name)
-	require.Equal(t, folder2, dump.Folders[1].FsConfig.S3Config.AccessKey)
-	err = dump.Folders[1].FsConfig.S3Config.AccessSecret.Decrypt()
-	require.NoError(t, err)
-	require.Equal(t, "pwd"+folder2, dump.Folders[1].FsConfig.S3Config.AccessSecret.GetPayload())
-	require.Equal(t, "base/"+folder2+"/", dump.Folders[1].FsConfig.S3Config.KeyPrefix)
-	require.Equal(t, folder3, dump.Folders[2].Name)
-	require.Equal(t, folder3, dump.Folders[2].FsConfig.S3Config.AccessKey)
-	err = dump.Folders[2].FsConfig.S3Config.AccessSecret.Decrypt()
-	require.NoError(t, err)
-	require.Equal(t, "pwd"+folder3, dump.Folders[2].FsConfig.S3Config.AccessSecret.GetPayload())
-	require.Equal(t, "base/"+folder3+"/", dump.Folders[2].FsConfig.S3Config.KeyPrefix)
-
-	form.Set("tpl_foldername", " ")
+	var updateUser dataprovider.User
+	err = render.DecodeJSON(rr.Body, &updateUser)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1577836800000), updateUser.ExpirationDate)
+	assert.Equal(t, 2, len(updateUser.Filters.FilePatterns))
+	assert.Equal(t, kms.SecretStatusSecretBox, updateUser.FsConfig.SFTPConfig.Password.GetStatus())
+	assert.NotEmpty(t, updateUser.FsConfig.SFTPConfig.Password.GetPayload())
+	assert.Empty(t, updateUser.FsConfig.SFTPConfig.Password.GetKey())
+	assert.Empty(t, updateUser.FsConfig.SFTPConfig.Password.GetAdditionalData())
+	assert.Equal(t, kms.SecretStatusSecretBox, updateUser.FsConfig.SFTPConfig.PrivateKey.GetStatus())
+	assert.NotEmpty(t, updateUser.FsConfig.SFTPConfig.PrivateKey.GetPayload())
+	assert.Empty(t, updateUser.FsConfig.SFTPConfig.PrivateKey.GetKey())
+	assert.Empty(t, updateUser.FsConfig.SFTPConfig.PrivateKey.GetAdditionalData())
+	assert.Equal(t, updateUser.FsConfig.SFTPConfig.Prefix, user.FsConfig.SFTPConfig.Prefix)
+	assert.Equal(t, updateUser.FsConfig.SFTPConfig.Username, user.FsConfig.SFTPConfig.Username)
+	assert.Equal(t, updateUser.FsConfig.SFTPConfig.Endpoint, user.FsConfig.SFTPConfig.Endpoint)
+	assert.True(t, updateUser.FsConfig.SFTPConfig.DisableCouncurrentReads)
+	assert.Len(t, updateUser.FsConfig.SFTPConfig.Fingerprints, 1)
+	assert.Equal(t, user.FsConfig.SFTPConfig.BufferSize, updateUser.FsConfig.SFTPConfig.BufferSize)
+	assert.Contains(t, updateUser.FsConfig.SFTPConfig.Fingerprints, sftpPkeyFingerprint)
+	// now check that a redacted credentials are not saved
+	form.Set("sftp_password", redactedSecret+" ")
+	form.Set("sftp_private_key", redactedSecret)
 	b, contentType, _ = getMultipartFormData(form, "", "")
-	req, _ = http.NewRequest(http.MethodPost, webTemplateFolder, &b)
-	setJWTCookieForReq(req, token)
+	req, _ = http.NewRequest(http.MethodPost, path.Join(webUserPath, user.Username), &b)
+	setJWTCookieForReq(req, webToken)
 	req.Header.Set("Content-Type", contentType)
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusBadRequest, rr)
-	assert.Contains(t, rr.Body.String(), "No folders to export")
-
-	form.Set("tpl_foldername", "name")
-	form.Set("mapped_path", "relative-path")
-	b, contentType, _ = getMultipartFormData(form, "", "")
-	req, _ = http.NewRequest(http.MethodPost, webTemplateFolder, &b)
-	setJWTCookieForReq(req, token)
-	req.Header.Set("Content-Type", contentType)
+	checkResponseCode(t, http.StatusSeeOther, rr)
+	req, _ = http.NewRequest(http.MethodGet, path.Join(userPath, user.Username), nil)
+	setBearerForReq(req, apiToken)
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusBadRequest, rr)
-	assert.Contains(t, rr.Body.String(), "Error validating folder")
+	checkResponseCode(t, http.StatusOK, rr)
+	var lastUpdatedUser dataprovider.User
+	err = render.DecodeJSON(rr.Body, &lastUpdatedUser)
+	assert.NoError(t, err)
+	assert.Equal(t, kms.SecretStatusSecretBox, lastUpdatedUser.FsConfig.SFTPConfig.Password.GetStatus())
+	assert.Equal(t, updateUser.FsConfig.SFTPConfig.Password.GetPayload(), lastUpdatedUser.FsConfig.SFTPConfig.Password.GetPayload())
+	assert.Empty(t, lastUpdatedUser.FsConfig.SFTPConfig.Password.GetKey())
+	assert.Empty(t, lastUpdatedUser.FsConfig.SFTPConfig.Password.GetAdditionalData())
+	assert.Equal(t, kms.SecretStatusSecretBox, lastUpdatedUser.FsConfig.SFTPConfig.PrivateKey.GetStatus())
+	assert.Equal(t, updateUser.FsConfig.SFTPConfig.PrivateKey.GetPayload(), lastUpdatedUser.FsConfig.SFTPConfig.PrivateKey.GetPayload())
+	assert.Empty(t, lastUpdatedUser.FsConfig.SFTPConfig.PrivateKey.GetKey())
+	assert.Empty(t, lastUpdatedUser.FsConfig.SFTPConfig.PrivateKey.GetAdditionalData())
+	req, _ = http.NewRequest(http.MethodDelete, path.Join(userPath, user.Username), nil)
+	setBearerForReq(req, apiToken)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
 }
 
-func TestWebUserS3Mock(t *testing.T) {
+func TestWebUserFTPFsMock(t *testing.T) {
 	webToken, err := getJWTWebTokenFromTestServer(defaultTokenAuthUser, defaultTokenAuthPass)
 	assert.NoError(t, err)
 	apiToken, err := getJWTAPITokenFromTestServer(defaultTokenAuthUser, defaultTokenAuthPass)
@@ -12092,21 +17616,12 @@ func TestWebUserS3Mock(t *testing.T) {
 	checkResponseCode(t, http.StatusCreated, rr)
 	err = render.DecodeJSON(rr.Body, &user)
 	assert.NoError(t, err)
-	user.FsConfig.Provider = sdk.S3FilesystemProvider
-	user.FsConfig.S3Config.Bucket = "test"
-	user.FsConfig.S3Config.Region = "eu-west-1"
-	user.FsConfig.S3Config.AccessKey = "access-key"
-	user.FsConfig.S3Config.AccessSecret = kms.NewPlainSecret("access-secret")
-	user.FsConfig.S3Config.Endpoint = "http://127.0.0.1:9000/path?a=b"
-	user.FsConfig.S3Config.StorageClass = "Standard"
-	user.FsConfig.S3Config.KeyPrefix = "somedir/subdir/"
-	user.FsConfig.S3Config.UploadPartSize = 5
-	user.FsConfig.S3Config.UploadConcurrency = 4
-	user.FsConfig.S3Config.DownloadPartMaxTime = 60
-	user.FsConfig.S3Config.DownloadPartSize = 6
-	user.FsConfig.S3Config.DownloadConcurrency = 3
-	user.FsConfig.S3Config.ForcePathStyle = true
-	user.Description = "s3 tèst user"
+	user.FsConfig.Provider = sdk.FTPFilesystemProvider
+	user.FsConfig.FTPConfig.Endpoint = "127.0.0.1:21"
+	user.FsConfig.FTPConfig.Username = "ftpuser"
+	user.FsConfig.FTPConfig.Password = kms.NewPlainSecret("pwd")
+	user.FsConfig.FTPConfig.Prefix = "/home/ftpuser"
+	user.FsConfig.FTPConfig.TLSMode = 2
 	form := make(url.Values)
 	form.Set(csrfFormToken, csrfToken)
 	form.Set("username", user.Username)
@@ -12124,71 +17639,150 @@ func TestWebUserS3Mock(t *testing.T) {
 	form.Set("expiration_date", "2020-01-01 00:00:00")
 	form.Set("allowed_ip", "")
 	form.Set("denied_ip", "")
-	form.Set("fs_provider", "1")
-	form.Set("s3_bucket", user.FsConfig.S3Config.Bucket)
-	form.Set("s3_region", user.FsConfig.S3Config.Region)
-	form.Set("s3_access_key", user.FsConfig.S3Config.AccessKey)
-	form.Set("s3_access_secret", user.FsConfig.S3Config.AccessSecret.GetPayload())
-	form.Set("s3_storage_class", user.FsConfig.S3Config.StorageClass)
-	form.Set("s3_endpoint", user.FsConfig.S3Config.Endpoint)
-	form.Set("s3_key_prefix", user.FsConfig.S3Config.KeyPrefix)
-	form.Set("pattern_path0", "/dir1")
-	form.Set("patterns0", "*.jpg,*.png")
-	form.Set("pattern_type0", "allowed")
-	form.Set("pattern_path1", "/dir2")
-	form.Set("patterns1", "*.zip")
-	form.Set("pattern_type1", "denied")
+	form.Set("fs_provider", "6")
+	form.Set("crypt_passphrase", "")
 	form.Set("max_upload_file_size", "0")
-	form.Set("s3_force_path_style", "checked")
-	form.Set("description", user.Description)
-	form.Add("hooks", "pre_login_disabled")
-	form.Add("allow_api_key_auth", "1")
-	// test invalid s3_upload_part_size
-	form.Set("s3_upload_part_size", "a")
+	// empty ftpconfig
 	b, contentType, _ := getMultipartFormData(form, "", "")
 	req, _ = http.NewRequest(http.MethodPost, path.Join(webUserPath, user.Username), &b)
 	setJWTCookieForReq(req, webToken)
 	req.Header.Set("Content-Type", contentType)
 	rr = executeRequest(req)
 	checkResponseCode(t, http.StatusOK, rr)
-	// test invalid s3_upload_concurrency
-	form.Set("s3_upload_part_size", strconv.FormatInt(user.FsConfig.S3Config.UploadPartSize, 10))
-	form.Set("s3_upload_concurrency", "a")
+	form.Set("ftp_endpoint", user.FsConfig.FTPConfig.Endpoint)
+	form.Set("ftp_username", user.FsConfig.FTPConfig.Username)
+	form.Set("ftp_password", user.FsConfig.FTPConfig.Password.GetPayload())
+	form.Set("ftp_prefix", user.FsConfig.FTPConfig.Prefix)
+	form.Set("ftp_tls_mode", "a")
+	form.Set("ftp_skip_tls_verify", "true")
+	form.Set("ftp_disable_epsv", "true")
+	// invalid tls mode
 	b, contentType, _ = getMultipartFormData(form, "", "")
 	req, _ = http.NewRequest(http.MethodPost, path.Join(webUserPath, user.Username), &b)
 	setJWTCookieForReq(req, webToken)
 	req.Header.Set("Content-Type", contentType)
 	rr = executeRequest(req)
 	checkResponseCode(t, http.StatusOK, rr)
-	// test invalid s3_download_part_size
-	form.Set("s3_upload_concurrency", strconv.Itoa(user.FsConfig.S3Config.UploadConcurrency))
-	form.Set("s3_download_part_size", "a")
+	form.Set("ftp_tls_mode", strconv.Itoa(user.FsConfig.FTPConfig.TLSMode))
 	b, contentType, _ = getMultipartFormData(form, "", "")
 	req, _ = http.NewRequest(http.MethodPost, path.Join(webUserPath, user.Username), &b)
 	setJWTCookieForReq(req, webToken)
 	req.Header.Set("Content-Type", contentType)
 	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusSeeOther, rr)
+	req, _ = http.NewRequest(http.MethodGet, path.Join(userPath, user.Username), nil)
+	setBearerForReq(req, apiToken)
+	rr = executeRequest(req)
 	checkResponseCode(t, http.StatusOK, rr)
-	// test invalid s3_download_concurrency
-	form.Set("s3_download_part_size", strconv.FormatInt(user.FsConfig.S3Config.DownloadPartSize, 10))
-	form.Set("s3_download_concurrency", "a")
+	var updateUser dataprovider.User
+	err = render.DecodeJSON(rr.Body, &updateUser)
+	assert.NoError(t, err)
+	assert.Equal(t, sdk.FTPFilesystemProvider, updateUser.FsConfig.Provider)
+	assert.Equal(t, kms.SecretStatusSecretBox, updateUser.FsConfig.FTPConfig.Password.GetStatus())
+	assert.NotEmpty(t, updateUser.FsConfig.FTPConfig.Password.GetPayload())
+	assert.Empty(t, updateUser.FsConfig.FTPConfig.Password.GetKey())
+	assert.Empty(t, updateUser.FsConfig.FTPConfig.Password.GetAdditionalData())
+	assert.Equal(t, user.FsConfig.FTPConfig.Prefix, updateUser.FsConfig.FTPConfig.Prefix)
+	assert.Equal(t, user.FsConfig.FTPConfig.Username, updateUser.FsConfig.FTPConfig.Username)
+	assert.Equal(t, user.FsConfig.FTPConfig.Endpoint, updateUser.FsConfig.FTPConfig.Endpoint)
+	assert.Equal(t, user.FsConfig.FTPConfig.TLSMode, updateUser.FsConfig.FTPConfig.TLSMode)
+	assert.True(t, updateUser.FsConfig.FTPConfig.SkipTLSVerify)
+	assert.True(t, updateUser.FsConfig.FTPConfig.DisableEPSV)
+	// the user page must render the FTP config
+	req, _ = http.NewRequest(http.MethodGet, path.Join(webUserPath, user.Username), nil)
+	setJWTCookieForReq(req, webToken)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
+	assert.Contains(t, rr.Body.String(), user.FsConfig.FTPConfig.Prefix)
+	// now check that a redacted password is not saved
+	form.Set("ftp_password", redactedSecret)
+	form.Set("ftp_disable_epsv", "")
 	b, contentType, _ = getMultipartFormData(form, "", "")
 	req, _ = http.NewRequest(http.MethodPost, path.Join(webUserPath, user.Username), &b)
 	setJWTCookieForReq(req, webToken)
 	req.Header.Set("Content-Type", contentType)
 	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusSeeOther, rr)
+	req, _ = http.NewRequest(http.MethodGet, path.Join(userPath, user.Username), nil)
+	setBearerForReq(req, apiToken)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
+	var lastUpdatedUser dataprovider.User
+	err = render.DecodeJSON(rr.Body, &lastUpdatedUser)
+	assert.NoError(t, err)
+	assert.Equal(t, kms.SecretStatusSecretBox, lastUpdatedUser.FsConfig.FTPConfig.Password.GetStatus())
+	assert.Equal(t, updateUser.FsConfig.FTPConfig.Password.GetPayload(), lastUpdatedUser.FsConfig.FTPConfig.Password.GetPayload())
+	assert.Empty(t, lastUpdatedUser.FsConfig.FTPConfig.Password.GetKey())
+	assert.Empty(t, lastUpdatedUser.FsConfig.FTPConfig.Password.GetAdditionalData())
+	assert.False(t, lastUpdatedUser.FsConfig.FTPConfig.DisableEPSV)
+	req, _ = http.NewRequest(http.MethodDelete, path.Join(userPath, user.Username), nil)
+	setBearerForReq(req, apiToken)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
+}
+
+func TestWebUserWebDAVFsMock(t *testing.T) {
+	webToken, err := getJWTWebTokenFromTestServer(defaultTokenAuthUser, defaultTokenAuthPass)
+	assert.NoError(t, err)
+	apiToken, err := getJWTAPITokenFromTestServer(defaultTokenAuthUser, defaultTokenAuthPass)
+	assert.NoError(t, err)
+	csrfToken, err := getCSRFToken(httpBaseURL + webLoginPath)
+	assert.NoError(t, err)
+	user := getTestUser()
+	userAsJSON := getUserAsJSON(t, user)
+	req, _ := http.NewRequest(http.MethodPost, userPath, bytes.NewBuffer(userAsJSON))
+	setBearerForReq(req, apiToken)
+	rr := executeRequest(req)
+	checkResponseCode(t, http.StatusCreated, rr)
+	err = render.DecodeJSON(rr.Body, &user)
+	assert.NoError(t, err)
+	user.FsConfig.Provider = sdk.WebDAVFilesystemProvider
+	user.FsConfig.WebDAVConfig.Endpoint = "https://cloud.example.com/remote.php/dav/files/davuser/"
+	user.FsConfig.WebDAVConfig.Username = "davuser"
+	user.FsConfig.WebDAVConfig.Password = kms.NewPlainSecret("pwd")
+	user.FsConfig.WebDAVConfig.Prefix = "/home/davuser"
+	form := make(url.Values)
+	form.Set(csrfFormToken, csrfToken)
+	form.Set("username", user.Username)
+	form.Set("password", redactedSecret)
+	form.Set("home_dir", user.HomeDir)
+	form.Set("uid", "0")
+	form.Set("gid", strconv.FormatInt(int64(user.GID), 10))
+	form.Set("max_sessions", strconv.FormatInt(int64(user.MaxSessions), 10))
+	form.Set("quota_size", strconv.FormatInt(user.QuotaSize, 10))
+	form.Set("quota_files", strconv.FormatInt(int64(user.QuotaFiles), 10))
+	form.Set("upload_bandwidth", "0")
+	form.Set("download_bandwidth", "0")
+	form.Set("permissions", "*")
+	form.Set("status", strconv.Itoa(user.Status))
+	form.Set("expiration_date", "2020-01-01 00:00:00")
+	form.Set("allowed_ip", "")
+	form.Set("denied_ip", "")
+	form.Set("fs_provider", "7")
+	form.Set("crypt_passphrase", "")
+	form.Set("max_upload_file_size", "0")
+	// empty webdavconfig
+	b, contentType, _ := getMultipartFormData(form, "", "")
+	req, _ = http.NewRequest(http.MethodPost, path.Join(webUserPath, user.Username), &b)
+	setJWTCookieForReq(req, webToken)
+	req.Header.Set("Content-Type", contentType)
+	rr = executeRequest(req)
 	checkResponseCode(t, http.StatusOK, rr)
-	// test invalid s3_download_part_max_time
-	form.Set("s3_download_concurrency", strconv.Itoa(user.FsConfig.S3Config.DownloadConcurrency))
-	form.Set("s3_download_part_max_time", "a")
+	form.Set("webdav_endpoint", user.FsConfig.WebDAVConfig.Endpoint)
+	form.Set("webdav_username", user.FsConfig.WebDAVConfig.Username)
+	form.Set("webdav_password", user.FsConfig.WebDAVConfig.Password.GetPayload())
+	form.Set("webdav_bearer_token", "token")
+	form.Set("webdav_prefix", user.FsConfig.WebDAVConfig.Prefix)
+	form.Set("webdav_skip_tls_verify", "true")
+	// password and bearer token cannot be used together
 	b, contentType, _ = getMultipartFormData(form, "", "")
 	req, _ = http.NewRequest(http.MethodPost, path.Join(webUserPath, user.Username), &b)
 	setJWTCookieForReq(req, webToken)
 	req.Header.Set("Content-Type", contentType)
 	rr = executeRequest(req)
 	checkResponseCode(t, http.StatusOK, rr)
-	// now add the user
-	form.Set("s3_download_part_max_time", strconv.Itoa(user.FsConfig.S3Config.DownloadPartMaxTime))
+	assert.Contains(t, rr.Body.String(), "cannot be used together")
+	form.Set("webdav_bearer_token", "")
 	b, contentType, _ = getMultipartFormData(form, "", "")
 	req, _ = http.NewRequest(http.MethodPost, path.Join(webUserPath, user.Username), &b)
 	setJWTCookieForReq(req, webToken)
@@ -12202,53 +17796,25 @@ func TestWebUserS3Mock(t *testing.T) {
 	var updateUser dataprovider.User
 	err = render.DecodeJSON(rr.Body, &updateUser)
 	assert.NoError(t, err)
-	assert.Equal(t, int64(1577836800000), updateUser.ExpirationDate)
-	assert.Equal(t, updateUser.FsConfig.S3Config.Bucket, user.FsConfig.S3Config.Bucket)
-	assert.Equal(t, updateUser.FsConfig.S3Config.Region, user.FsConfig.S3Config.Region)
-	assert.Equal(t, updateUser.FsConfig.S3Config.AccessKey, user.FsConfig.S3Config.AccessKey)
-	assert.Equal(t, updateUser.FsConfig.S3Config.StorageClass, user.FsConfig.S3Config.StorageClass)
-	assert.Equal(t, updateUser.FsConfig.S3Config.Endpoint, user.FsConfig.S3Config.Endpoint)
-	assert.Equal(t, updateUser.FsConfig.S3Config.KeyPrefix, user.FsConfig.S3Config.KeyPrefix)
-	assert.Equal(t, updateUser.FsConfig.S3Config.UploadPartSize, user.FsConfig.S3Config.UploadPartSize)
-	assert.Equal(t, updateUser.FsConfig.S3Config.UploadConcurrency, user.FsConfig.S3Config.UploadConcurrency)
-	assert.Equal(t, updateUser.FsConfig.S3Config.DownloadPartMaxTime, user.FsConfig.S3Config.DownloadPartMaxTime)
-	assert.Equal(t, updateUser.FsConfig.S3Config.DownloadPartSize, user.FsConfig.S3Config.DownloadPartSize)
-	assert.Equal(t, updateUser.FsConfig.S3Config.DownloadConcurrency, user.FsConfig.S3Config.DownloadConcurrency)
-	assert.True(t, updateUser.FsConfig.S3Config.ForcePathStyle)
-	assert.Equal(t, 2, len(updateUser.Filters.FilePatterns))
-	assert.Equal(t, kms.SecretStatusSecretBox, updateUser.FsConfig.S3Config.AccessSecret.GetStatus())
-	assert.NotEmpty(t, updateUser.FsConfig.S3Config.AccessSecret.GetPayload())
-	assert.Empty(t, updateUser.FsConfig.S3Config.AccessSecret.GetKey())
-	assert.Empty(t, updateUser.FsConfig.S3Config.AccessSecret.GetAdditionalData())
-	assert.Equal(t, user.Description, updateUser.Description)
-	assert.True(t, updateUser.Filters.Hooks.PreLoginDisabled)
-	assert.False(t, updateUser.Filters.Hooks.ExternalAuthDisabled)
-	assert.False(t, updateUser.Filters.Hooks.CheckPasswordDisabled)
-	assert.False(t, updateUser.Filters.DisableFsChecks)
-	assert.True(t, updateUser.Filters.AllowAPIKeyAuth)
-	// now check that a redacted password is not saved
-	form.Set("s3_access_secret", redactedSecret)
-	b, contentType, _ = getMultipartFormData(form, "", "")
-	req, _ = http.NewRequest(http.MethodPost, path.Join(webUserPath, user.Username), &b)
+	assert.Equal(t, sdk.WebDAVFilesystemProvider, updateUser.FsConfig.Provider)
+	assert.Equal(t, kms.SecretStatusSecretBox, updateUser.FsConfig.WebDAVConfig.Password.GetStatus())
+	assert.NotEmpty(t, updateUser.FsConfig.WebDAVConfig.Password.GetPayload())
+	assert.Empty(t, updateUser.FsConfig.WebDAVConfig.Password.GetKey())
+	assert.Empty(t, updateUser.FsConfig.WebDAVConfig.Password.GetAdditionalData())
+	assert.Nil(t, updateUser.FsConfig.WebDAVConfig.BearerToken)
+	assert.Equal(t, user.FsConfig.WebDAVConfig.Prefix, updateUser.FsConfig.WebDAVConfig.Prefix)
+	assert.Equal(t, user.FsConfig.WebDAVConfig.Username, updateUser.FsConfig.WebDAVConfig.Username)
+	assert.Equal(t, user.FsConfig.WebDAVConfig.Endpoint, updateUser.FsConfig.WebDAVConfig.Endpoint)
+	assert.True(t, updateUser.FsConfig.WebDAVConfig.SkipTLSVerify)
+	// the user page must render the WebDAV config
+	req, _ = http.NewRequest(http.MethodGet, path.Join(webUserPath, user.Username), nil)
 	setJWTCookieForReq(req, webToken)
-	req.Header.Set("Content-Type", contentType)
-	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusSeeOther, rr)
-	req, _ = http.NewRequest(http.MethodGet, path.Join(userPath, user.Username), nil)
-	setBearerForReq(req, apiToken)
 	rr = executeRequest(req)
 	checkResponseCode(t, http.StatusOK, rr)
-
-	var lastUpdatedUser dataprovider.User
-	err = render.DecodeJSON(rr.Body, &lastUpdatedUser)
-	assert.NoError(t, err)
-	assert.Equal(t, kms.SecretStatusSecretBox, lastUpdatedUser.FsConfig.S3Config.AccessSecret.GetStatus())
-	assert.Equal(t, updateUser.FsConfig.S3Config.AccessSecret.GetPayload(), lastUpdatedUser.FsConfig.S3Config.AccessSecret.GetPayload())
-	assert.Empty(t, lastUpdatedUser.FsConfig.S3Config.AccessSecret.GetKey())
-	assert.Empty(t, lastUpdatedUser.FsConfig.S3Config.AccessSecret.GetAdditionalData())
-	// now clear credentials
-	form.Set("s3_access_key", "")
-	form.Set("s3_access_secret", "")
+	assert.Contains(t, rr.Body.String(), user.FsConfig.WebDAVConfig.Endpoint)
+	// now check that a redacted password is not saved
+	form.Set("webdav_password", redactedSecret)
+	form.Set("webdav_skip_tls_verify", "")
 	b, contentType, _ = getMultipartFormData(form, "", "")
 	req, _ = http.NewRequest(http.MethodPost, path.Join(webUserPath, user.Username), &b)
 	setJWTCookieForReq(req, webToken)
@@ -12259,18 +17825,21 @@ func TestWebUserS3Mock(t *testing.T) {
 	setBearerForReq(req, apiToken)
 	rr = executeRequest(req)
 	checkResponseCode(t, http.StatusOK, rr)
-	var userGet dataprovider.User
-	err = render.DecodeJSON(rr.Body, &userGet)
+	var lastUpdatedUser dataprovider.User
+	err = render.DecodeJSON(rr.Body, &lastUpdatedUser)
 	assert.NoError(t, err)
-	assert.Nil(t, userGet.FsConfig.S3Config.AccessSecret)
-
+	assert.Equal(t, kms.SecretStatusSecretBox, lastUpdatedUser.FsConfig.WebDAVConfig.Password.GetStatus())
+	assert.Equal(t, updateUser.FsConfig.WebDAVConfig.Password.GetPayload(), lastUpdatedUser.FsConfig.WebDAVConfig.Password.GetPayload())
+	assert.Empty(t, lastUpdatedUser.FsConfig.WebDAVConfig.Password.GetKey())
+	assert.Empty(t, lastUpdatedUser.FsConfig.WebDAVConfig.Password.GetAdditionalData())
+	assert.False(t, lastUpdatedUser.FsConfig.WebDAVConfig.SkipTLSVerify)
 	req, _ = http.NewRequest(http.MethodDelete, path.Join(userPath, user.Username), nil)
 	setBearerForReq(req, apiToken)
 	rr = executeRequest(req)
 	checkResponseCode(t, http.StatusOK, rr)
 }
 
-func TestWebUserGCSMock(t *testing.T) {
+func TestWebUserReadCacheMock(t *testing.T) {
 	webToken, err := getJWTWebTokenFromTestServer(defaultTokenAuthUser, defaultTokenAuthPass)
 	assert.NoError(t, err)
 	apiToken, err := getJWTAPITokenFromTestServer(defaultTokenAuthUser, defaultTokenAuthPass)
@@ -12279,20 +17848,12 @@ func TestWebUserGCSMock(t *testing.T) {
 	assert.NoError(t, err)
 	user := getTestUser()
 	userAsJSON := getUserAsJSON(t, user)
-	req, err := http.NewRequest(http.MethodPost, userPath, bytes.NewBuffer(userAsJSON))
-	assert.NoError(t, err)
+	req, _ := http.NewRequest(http.MethodPost, userPath, bytes.NewBuffer(userAsJSON))
 	setBearerForReq(req, apiToken)
 	rr := executeRequest(req)
 	checkResponseCode(t, http.StatusCreated, rr)
 	err = render.DecodeJSON(rr.Body, &user)
 	assert.NoError(t, err)
-	credentialsFilePath := filepath.Join(os.TempDir(), "gcs.json")
-	err = createTestFile(credentialsFilePath, 0)
-	assert.NoError(t, err)
-	user.FsConfig.Provider = sdk.GCSFilesystemProvider
-	user.FsConfig.GCSConfig.Bucket = "test"
-	user.FsConfig.GCSConfig.KeyPrefix = "somedir/subdir/"
-	user.FsConfig.GCSConfig.StorageClass = "standard"
 	form := make(url.Values)
 	form.Set(csrfFormToken, csrfToken)
 	form.Set("username", user.Username)
@@ -12311,28 +17872,38 @@ func TestWebUserGCSMock(t *testing.T) {
 	form.Set("allowed_ip", "")
 	form.Set("denied_ip", "")
 	form.Set("fs_provider", "2")
-	form.Set("gcs_bucket", user.FsConfig.GCSConfig.Bucket)
-	form.Set("gcs_storage_class", user.FsConfig.GCSConfig.StorageClass)
-	form.Set("gcs_key_prefix", user.FsConfig.GCSConfig.KeyPrefix)
-	form.Set("pattern_path0", "/dir1")
-	form.Set("patterns0", "*.jpg,*.png")
-	form.Set("pattern_type0", "allowed")
+	form.Set("gcs_bucket", "test")
+	form.Set("gcs_auto_credentials", "on")
+	form.Set("crypt_passphrase", "")
 	form.Set("max_upload_file_size", "0")
+	form.Set("read_cache_max_size", "a")
 	b, contentType, _ := getMultipartFormData(form, "", "")
 	req, _ = http.NewRequest(http.MethodPost, path.Join(webUserPath, user.Username), &b)
 	setJWTCookieForReq(req, webToken)
 	req.Header.Set("Content-Type", contentType)
 	rr = executeRequest(req)
 	checkResponseCode(t, http.StatusOK, rr)
-	b, contentType, _ = getMultipartFormData(form, "gcs_credential_file", credentialsFilePath)
+	assert.Contains(t, rr.Body.String(), "invalid syntax")
+	form.Set("read_cache_max_size", "200")
+	form.Set("read_cache_ttl", "b")
+	b, contentType, _ = getMultipartFormData(form, "", "")
 	req, _ = http.NewRequest(http.MethodPost, path.Join(webUserPath, user.Username), &b)
 	setJWTCookieForReq(req, webToken)
 	req.Header.Set("Content-Type", contentType)
 	rr = executeRequest(req)
 	checkResponseCode(t, http.StatusOK, rr)
-	err = createTestFile(credentialsFilePath, 4096)
-	assert.NoError(t, err)
-	b, contentType, _ = getMultipartFormData(form, "gcs_credential_file", credentialsFilePath)
+	assert.Contains(t, rr.Body.String(), "invalid syntax")
+	form.Set("read_cache_ttl", "300")
+	form.Set("read_cache_eviction", "random")
+	b, contentType, _ = getMultipartFormData(form, "", "")
+	req, _ = http.NewRequest(http.MethodPost, path.Join(webUserPath, user.Username), &b)
+	setJWTCookieForReq(req, webToken)
+	req.Header.Set("Content-Type", contentType)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
+	assert.Contains(t, rr.Body.String(), "invalid eviction policy")
+	form.Set("read_cache_eviction", "lfu")
+	b, contentType, _ = getMultipartFormData(form, "", "")
 	req, _ = http.NewRequest(http.MethodPost, path.Join(webUserPath, user.Username), &b)
 	setJWTCookieForReq(req, webToken)
 	req.Header.Set("Content-Type", contentType)
@@ -12345,18 +17916,20 @@ func TestWebUserGCSMock(t *testing.T) {
 	var updateUser dataprovider.User
 	err = render.DecodeJSON(rr.Body, &updateUser)
 	assert.NoError(t, err)
-	assert.Equal(t, int64(1577836800000), updateUser.ExpirationDate)
-	assert.Equal(t, user.FsConfig.Provider, updateUser.FsConfig.Provider)
-	assert.Equal(t, user.FsConfig.GCSConfig.Bucket, updateUser.FsConfig.GCSConfig.Bucket)
-	assert.Equal(t, user.FsConfig.GCSConfig.StorageClass, updateUser.FsConfig.GCSConfig.StorageClass)
-	assert.Equal(t, user.FsConfig.GCSConfig.KeyPrefix, updateUser.FsConfig.GCSConfig.KeyPrefix)
-	if assert.Len(t, updateUser.Filters.FilePatterns, 1) {
-		assert.Equal(t, "/dir1", updateUser.Filters.FilePatterns[0].Path)
-		assert.Len(t, updateUser.Filters.FilePatterns[0].AllowedPatterns, 2)
-		assert.Contains(t, updateUser.Filters.FilePatterns[0].AllowedPatterns, "*.png")
-		assert.Contains(t, updateUser.Filters.FilePatterns[0].AllowedPatterns, "*.jpg")
-	}
-	form.Set("gcs_auto_credentials", "on")
+	assert.Equal(t, sdk.GCSFilesystemProvider, updateUser.FsConfig.Provider)
+	assert.Equal(t, int64(200), updateUser.FsConfig.ReadCache.MaxSize)
+	assert.Equal(t, 300, updateUser.FsConfig.ReadCache.TTL)
+	assert.Equal(t, "lfu", updateUser.FsConfig.ReadCache.Eviction)
+	// the user page must render the read cache config
+	req, _ = http.NewRequest(http.MethodGet, path.Join(webUserPath, user.Username), nil)
+	setJWTCookieForReq(req, webToken)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
+	assert.Contains(t, rr.Body.String(), `name="read_cache_max_size"`)
+	// the read cache fields are optional
+	form.Del("read_cache_max_size")
+	form.Del("read_cache_ttl")
+	form.Del("read_cache_eviction")
 	b, contentType, _ = getMultipartFormData(form, "", "")
 	req, _ = http.NewRequest(http.MethodPost, path.Join(webUserPath, user.Username), &b)
 	setJWTCookieForReq(req, webToken)
@@ -12370,16 +17943,15 @@ func TestWebUserGCSMock(t *testing.T) {
 	updateUser = dataprovider.User{}
 	err = render.DecodeJSON(rr.Body, &updateUser)
 	assert.NoError(t, err)
-	assert.Equal(t, 1, updateUser.FsConfig.GCSConfig.AutomaticCredentials)
+	assert.Equal(t, int64(0), updateUser.FsConfig.ReadCache.MaxSize)
+	assert.Empty(t, updateUser.FsConfig.ReadCache.Eviction)
 	req, _ = http.NewRequest(http.MethodDelete, path.Join(userPath, user.Username), nil)
 	setBearerForReq(req, apiToken)
 	rr = executeRequest(req)
 	checkResponseCode(t, http.StatusOK, rr)
-	err = os.Remove(credentialsFilePath)
-	assert.NoError(t, err)
 }
 
-func TestWebUserAzureBlobMock(t *testing.T) {
+func TestWebUserEncryptionMock(t *testing.T) {
 	webToken, err := getJWTWebTokenFromTestServer(defaultTokenAuthUser, defaultTokenAuthPass)
 	assert.NoError(t, err)
 	apiToken, err := getJWTAPITokenFromTestServer(defaultTokenAuthUser, defaultTokenAuthPass)
@@ -12394,15 +17966,6 @@ func TestWebUserAzureBlobMock(t *testing.T) {
 	checkResponseCode(t, http.StatusCreated, rr)
 	err = render.DecodeJSON(rr.Body, &user)
 	assert.NoError(t, err)
-	user.FsConfig.Provider = sdk.AzureBlobFilesystemProvider
-	user.FsConfig.AzBlobConfig.Container = "container"
-	user.FsConfig.AzBlobConfig.AccountName = "aname"
-	user.FsConfig.AzBlobConfig.AccountKey = kms.NewPlainSecret("access-skey")
-	user.FsConfig.AzBlobConfig.Endpoint = "http://127.0.0.1:9000/path?b=c"
-	user.FsConfig.AzBlobConfig.KeyPrefix = "somedir/subdir/"
-	user.FsConfig.AzBlobConfig.UploadPartSize = 5
-	user.FsConfig.AzBlobConfig.UploadConcurrency = 4
-	user.FsConfig.AzBlobConfig.UseEmulator = true
 	form := make(url.Values)
 	form.Set(csrfFormToken, csrfToken)
 	form.Set("username", user.Username)
@@ -12420,424 +17983,706 @@ func TestWebUserAzureBlobMock(t *testing.T) {
 	form.Set("expiration_date", "2020-01-01 00:00:00")
 	form.Set("allowed_ip", "")
 	form.Set("denied_ip", "")
-	form.Set("fs_provider", "3")
-	form.Set("az_container", user.FsConfig.AzBlobConfig.Container)
-	form.Set("az_account_name", user.FsConfig.AzBlobConfig.AccountName)
-	form.Set("az_account_key", user.FsConfig.AzBlobConfig.AccountKey.GetPayload())
-	form.Set("az_endpoint", user.FsConfig.AzBlobConfig.Endpoint)
-	form.Set("az_key_prefix", user.FsConfig.AzBlobConfig.KeyPrefix)
-	form.Set("az_use_emulator", "checked")
-	form.Set("pattern_path0", "/dir1")
-	form.Set("patterns0", "*.jpg,*.png")
-	form.Set("pattern_type0", "allowed")
-	form.Set("pattern_path1", "/dir2")
-	form.Set("patterns1", "*.zip")
-	form.Set("pattern_type1", "denied")
+	form.Set("fs_provider", "2")
+	form.Set("gcs_bucket", "test")
+	form.Set("gcs_auto_credentials", "on")
+	form.Set("crypt_passphrase", "")
 	form.Set("max_upload_file_size", "0")
-	// test invalid az_upload_part_size
-	form.Set("az_upload_part_size", "a")
+	form.Set("encryption_passphrase", "web passphrase")
 	b, contentType, _ := getMultipartFormData(form, "", "")
 	req, _ = http.NewRequest(http.MethodPost, path.Join(webUserPath, user.Username), &b)
 	setJWTCookieForReq(req, webToken)
 	req.Header.Set("Content-Type", contentType)
-	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusOK, rr)
-	// test invalid az_upload_concurrency
-	form.Set("az_upload_part_size", strconv.FormatInt(user.FsConfig.AzBlobConfig.UploadPartSize, 10))
-	form.Set("az_upload_concurrency", "a")
-	b, contentType, _ = getMultipartFormData(form, "", "")
-	req, _ = http.NewRequest(http.MethodPost, path.Join(webUserPath, user.Username), &b)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusSeeOther, rr)
+	dbUser, err := dataprovider.UserExists(user.Username)
+	assert.NoError(t, err)
+	assert.Equal(t, kms.SecretStatusSecretBox, dbUser.FsConfig.Encryption.Passphrase.GetStatus())
+	initialPayload := dbUser.FsConfig.Encryption.Passphrase.GetPayload()
+	assert.NotEmpty(t, initialPayload)
+	// the user page must render the redacted passphrase
+	req, _ = http.NewRequest(http.MethodGet, path.Join(webUserPath, user.Username), nil)
+	setJWTCookieForReq(req, webToken)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
+	assert.Contains(t, rr.Body.String(), `name="encryption_passphrase"`)
+	assert.NotContains(t, rr.Body.String(), "web passphrase")
+	// the existing passphrase is preserved if the redacted value is posted
+	form.Set("encryption_passphrase", redactedSecret)
+	b, contentType, _ = getMultipartFormData(form, "", "")
+	req, _ = http.NewRequest(http.MethodPost, path.Join(webUserPath, user.Username), &b)
+	setJWTCookieForReq(req, webToken)
+	req.Header.Set("Content-Type", contentType)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusSeeOther, rr)
+	dbUser, err = dataprovider.UserExists(user.Username)
+	assert.NoError(t, err)
+	assert.Equal(t, initialPayload, dbUser.FsConfig.Encryption.Passphrase.GetPayload())
+	// an empty passphrase disables the encryption
+	form.Set("encryption_passphrase", "")
+	b, contentType, _ = getMultipartFormData(form, "", "")
+	req, _ = http.NewRequest(http.MethodPost, path.Join(webUserPath, user.Username), &b)
+	setJWTCookieForReq(req, webToken)
+	req.Header.Set("Content-Type", contentType)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusSeeOther, rr)
+	dbUser, err = dataprovider.UserExists(user.Username)
+	assert.NoError(t, err)
+	assert.True(t, dbUser.FsConfig.Encryption.Passphrase.IsEmpty())
+	req, _ = http.NewRequest(http.MethodDelete, path.Join(userPath, user.Username), nil)
+	setBearerForReq(req, apiToken)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
+}
+
+func TestAddWebFoldersMock(t *testing.T) {
+	webToken, err := getJWTWebTokenFromTestServer(defaultTokenAuthUser, defaultTokenAuthPass)
+	assert.NoError(t, err)
+	apiToken, err := getJWTAPITokenFromTestServer(defaultTokenAuthUser, defaultTokenAuthPass)
+	assert.NoError(t, err)
+	csrfToken, err := getCSRFToken(httpBaseURL + webLoginPath)
+	assert.NoError(t, err)
+	mappedPath := filepath.Clean(os.TempDir())
+	folderName := filepath.Base(mappedPath)
+	folderDesc := "a simple desc"
+	form := make(url.Values)
+	form.Set("mapped_path", mappedPath)
+	form.Set("name", folderName)
+	form.Set("description", folderDesc)
+	form.Set("download_data_transfer", "10")
+	form.Set("data_transfer_reset_period", "daily")
+	form.Set("retention_path0", "/old")
+	form.Set("retention_value0", "72")
+	form.Add("retention_options0", "delete_empty_dirs")
+	b, contentType, err := getMultipartFormData(form, "", "")
+	assert.NoError(t, err)
+	req, err := http.NewRequest(http.MethodPost, webFolderPath, &b)
+	assert.NoError(t, err)
+	req.Header.Set("Content-Type", contentType)
+	setJWTCookieForReq(req, webToken)
+	rr := executeRequest(req)
+	checkResponseCode(t, http.StatusForbidden, rr)
+	assert.Contains(t, rr.Body.String(), "unable to verify form token")
+
+	form.Set(csrfFormToken, csrfToken)
+	b, contentType, err = getMultipartFormData(form, "", "")
+	assert.NoError(t, err)
+	req, err = http.NewRequest(http.MethodPost, webFolderPath, &b)
+	assert.NoError(t, err)
+	req.Header.Set("Content-Type", contentType)
+	setJWTCookieForReq(req, webToken)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusSeeOther, rr)
+	// adding the same folder will fail since the name must be unique
+	b, contentType, err = getMultipartFormData(form, "", "")
+	assert.NoError(t, err)
+	req, err = http.NewRequest(http.MethodPost, webFolderPath, &b)
+	assert.NoError(t, err)
+	setJWTCookieForReq(req, webToken)
+	req.Header.Set("Content-Type", contentType)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
+	// invalid form
+	req, err = http.NewRequest(http.MethodPost, webFolderPath, strings.NewReader(form.Encode()))
+	assert.NoError(t, err)
+	setJWTCookieForReq(req, webToken)
+	req.Header.Set("Content-Type", "text/plain; boundary=")
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
+
+	// now render the add folder page
+	req, err = http.NewRequest(http.MethodGet, webFolderPath, nil)
+	assert.NoError(t, err)
+	setJWTCookieForReq(req, webToken)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
+
+	var folder vfs.BaseVirtualFolder
+	req, _ = http.NewRequest(http.MethodGet, path.Join(folderPath, folderName), nil)
+	setBearerForReq(req, apiToken)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
+	err = render.DecodeJSON(rr.Body, &folder)
+	assert.NoError(t, err)
+	assert.Equal(t, mappedPath, folder.MappedPath)
+	assert.Equal(t, folderName, folder.Name)
+	assert.Equal(t, folderDesc, folder.Description)
+	assert.Equal(t, int64(10), folder.DownloadDataTransfer)
+	assert.Equal(t, sdk.DataTransferResetDaily, folder.DataTransferResetPeriod)
+	if assert.Len(t, folder.DataRetention, 1) {
+		assert.Equal(t, "/old", folder.DataRetention[0].Path)
+		assert.Equal(t, 72, folder.DataRetention[0].Retention)
+		assert.True(t, folder.DataRetention[0].DeleteEmptyDirs)
+		assert.False(t, folder.DataRetention[0].IgnoreUserPermissions)
+	}
+	// cleanup
+	req, _ = http.NewRequest(http.MethodDelete, path.Join(folderPath, folderName), nil)
+	setBearerForReq(req, apiToken)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
+}
+
+func TestWebGroupsMock(t *testing.T) {
+	webToken, err := getJWTWebTokenFromTestServer(defaultTokenAuthUser, defaultTokenAuthPass)
+	assert.NoError(t, err)
+	csrfToken, err := getCSRFToken(httpBaseURL + webLoginPath)
+	assert.NoError(t, err)
+	groupName := "web_group"
+	form := make(url.Values)
+	form.Set("name", groupName)
+	form.Set("description", "web group desc")
+	form.Set("max_sessions", "3")
+	form.Set("quota_size", "0")
+	form.Set("quota_files", "10")
+	form.Set("upload_bandwidth", "0")
+	form.Set("download_bandwidth", "0")
+	form.Set("max_upload_file_size", "0")
+	form.Set("sub_perm_path0", "/sub")
+	form.Add("sub_perm_permissions0", "list")
+	form.Add("denied_protocols", common.ProtocolWebDAV)
+	b, contentType, err := getMultipartFormData(form, "", "")
+	assert.NoError(t, err)
+	req, err := http.NewRequest(http.MethodPost, webGroupPath, &b)
+	assert.NoError(t, err)
+	req.Header.Set("Content-Type", contentType)
 	setJWTCookieForReq(req, webToken)
+	rr := executeRequest(req)
+	checkResponseCode(t, http.StatusForbidden, rr)
+	assert.Contains(t, rr.Body.String(), "unable to verify form token")
+
+	form.Set(csrfFormToken, csrfToken)
+	form.Set("max_sessions", "a")
+	b, contentType, err = getMultipartFormData(form, "", "")
+	assert.NoError(t, err)
+	req, err = http.NewRequest(http.MethodPost, webGroupPath, &b)
+	assert.NoError(t, err)
 	req.Header.Set("Content-Type", contentType)
+	setJWTCookieForReq(req, webToken)
 	rr = executeRequest(req)
 	checkResponseCode(t, http.StatusOK, rr)
-	// now add the user
-	form.Set("az_upload_concurrency", strconv.Itoa(user.FsConfig.AzBlobConfig.UploadConcurrency))
-	b, contentType, _ = getMultipartFormData(form, "", "")
-	req, _ = http.NewRequest(http.MethodPost, path.Join(webUserPath, user.Username), &b)
-	setJWTCookieForReq(req, webToken)
+	assert.Contains(t, rr.Body.String(), "invalid syntax")
+
+	form.Set("max_sessions", "3")
+	b, contentType, err = getMultipartFormData(form, "", "")
+	assert.NoError(t, err)
+	req, err = http.NewRequest(http.MethodPost, webGroupPath, &b)
+	assert.NoError(t, err)
 	req.Header.Set("Content-Type", contentType)
+	setJWTCookieForReq(req, webToken)
 	rr = executeRequest(req)
 	checkResponseCode(t, http.StatusSeeOther, rr)
-	req, _ = http.NewRequest(http.MethodGet, path.Join(userPath, user.Username), nil)
-	setBearerForReq(req, apiToken)
+
+	group, _, err := httpdtest.GetGroupByName(groupName, http.StatusOK)
+	assert.NoError(t, err)
+	assert.Equal(t, "web group desc", group.Description)
+	assert.Equal(t, 3, group.UserSettings.MaxSessions)
+	assert.Equal(t, 10, group.UserSettings.QuotaFiles)
+	assert.Len(t, group.UserSettings.Permissions, 1)
+	assert.Equal(t, []string{dataprovider.PermListItems}, group.UserSettings.Permissions["/sub"])
+	assert.Equal(t, []string{common.ProtocolWebDAV}, group.UserSettings.Filters.DeniedProtocols)
+
+	req, err = http.NewRequest(http.MethodGet, webGroupsPath, nil)
+	assert.NoError(t, err)
+	setJWTCookieForReq(req, webToken)
 	rr = executeRequest(req)
 	checkResponseCode(t, http.StatusOK, rr)
-	var updateUser dataprovider.User
-	err = render.DecodeJSON(rr.Body, &updateUser)
+	assert.Contains(t, rr.Body.String(), groupName)
+
+	req, err = http.NewRequest(http.MethodGet, webGroupPath, nil)
 	assert.NoError(t, err)
-	assert.Equal(t, int64(1577836800000), updateUser.ExpirationDate)
-	assert.Equal(t, updateUser.FsConfig.AzBlobConfig.Container, user.FsConfig.AzBlobConfig.Container)
-	assert.Equal(t, updateUser.FsConfig.AzBlobConfig.AccountName, user.FsConfig.AzBlobConfig.AccountName)
-	assert.Equal(t, updateUser.FsConfig.AzBlobConfig.Endpoint, user.FsConfig.AzBlobConfig.Endpoint)
-	assert.Equal(t, updateUser.FsConfig.AzBlobConfig.KeyPrefix, user.FsConfig.AzBlobConfig.KeyPrefix)
-	assert.Equal(t, updateUser.FsConfig.AzBlobConfig.UploadPartSize, user.FsConfig.AzBlobConfig.UploadPartSize)
-	assert.Equal(t, updateUser.FsConfig.AzBlobConfig.UploadConcurrency, user.FsConfig.AzBlobConfig.UploadConcurrency)
-	assert.Equal(t, 2, len(updateUser.Filters.FilePatterns))
-	assert.Equal(t, kms.SecretStatusSecretBox, updateUser.FsConfig.AzBlobConfig.AccountKey.GetStatus())
-	assert.NotEmpty(t, updateUser.FsConfig.AzBlobConfig.AccountKey.GetPayload())
-	assert.Empty(t, updateUser.FsConfig.AzBlobConfig.AccountKey.GetKey())
-	assert.Empty(t, updateUser.FsConfig.AzBlobConfig.AccountKey.GetAdditionalData())
-	// now check that a redacted password is not saved
-	form.Set("az_account_key", redactedSecret+" ")
-	b, contentType, _ = getMultipartFormData(form, "", "")
-	req, _ = http.NewRequest(http.MethodPost, path.Join(webUserPath, user.Username), &b)
 	setJWTCookieForReq(req, webToken)
-	req.Header.Set("Content-Type", contentType)
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusSeeOther, rr)
-	req, _ = http.NewRequest(http.MethodGet, path.Join(userPath, user.Username), nil)
-	setBearerForReq(req, apiToken)
+	checkResponseCode(t, http.StatusOK, rr)
+
+	req, err = http.NewRequest(http.MethodGet, path.Join(webGroupPath, groupName), nil)
+	assert.NoError(t, err)
+	setJWTCookieForReq(req, webToken)
 	rr = executeRequest(req)
 	checkResponseCode(t, http.StatusOK, rr)
-	var lastUpdatedUser dataprovider.User
-	err = render.DecodeJSON(rr.Body, &lastUpdatedUser)
+
+	req, err = http.NewRequest(http.MethodGet, path.Join(webGroupPath, "missing"), nil)
 	assert.NoError(t, err)
-	assert.Equal(t, kms.SecretStatusSecretBox, lastUpdatedUser.FsConfig.AzBlobConfig.AccountKey.GetStatus())
-	assert.Equal(t, updateUser.FsConfig.AzBlobConfig.AccountKey.GetPayload(), lastUpdatedUser.FsConfig.AzBlobConfig.AccountKey.GetPayload())
-	assert.Empty(t, lastUpdatedUser.FsConfig.AzBlobConfig.AccountKey.GetKey())
-	assert.Empty(t, lastUpdatedUser.FsConfig.AzBlobConfig.AccountKey.GetAdditionalData())
-	// test SAS url
-	user.FsConfig.AzBlobConfig.SASURL = kms.NewPlainSecret("sasurl")
-	form.Set("az_account_name", "")
-	form.Set("az_account_key", "")
-	form.Set("az_container", "")
-	form.Set("az_sas_url", user.FsConfig.AzBlobConfig.SASURL.GetPayload())
-	b, contentType, _ = getMultipartFormData(form, "", "")
-	req, _ = http.NewRequest(http.MethodPost, path.Join(webUserPath, user.Username), &b)
 	setJWTCookieForReq(req, webToken)
-	req.Header.Set("Content-Type", contentType)
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusSeeOther, rr)
-	req, _ = http.NewRequest(http.MethodGet, path.Join(userPath, user.Username), nil)
-	setBearerForReq(req, apiToken)
+	checkResponseCode(t, http.StatusNotFound, rr)
+	// the user page must allow to select the group
+	req, err = http.NewRequest(http.MethodGet, webUserPath, nil)
+	assert.NoError(t, err)
+	setJWTCookieForReq(req, webToken)
 	rr = executeRequest(req)
 	checkResponseCode(t, http.StatusOK, rr)
-	updateUser = dataprovider.User{}
-	err = render.DecodeJSON(rr.Body, &updateUser)
+	assert.Contains(t, rr.Body.String(), groupName)
+
+	form.Set("description", "")
+	form.Set("quota_files", "0")
+	form.Set("permissions", "*")
+	b, contentType, err = getMultipartFormData(form, "", "")
+	assert.NoError(t, err)
+	req, err = http.NewRequest(http.MethodPost, path.Join(webGroupPath, groupName), &b)
 	assert.NoError(t, err)
-	assert.Equal(t, kms.SecretStatusSecretBox, updateUser.FsConfig.AzBlobConfig.SASURL.GetStatus())
-	assert.NotEmpty(t, updateUser.FsConfig.AzBlobConfig.SASURL.GetPayload())
-	assert.Empty(t, updateUser.FsConfig.AzBlobConfig.SASURL.GetKey())
-	assert.Empty(t, updateUser.FsConfig.AzBlobConfig.SASURL.GetAdditionalData())
-	// now check that a redacted sas url is not saved
-	form.Set("az_sas_url", redactedSecret)
-	b, contentType, _ = getMultipartFormData(form, "", "")
-	req, _ = http.NewRequest(http.MethodPost, path.Join(webUserPath, user.Username), &b)
-	setJWTCookieForReq(req, webToken)
 	req.Header.Set("Content-Type", contentType)
+	setJWTCookieForReq(req, webToken)
 	rr = executeRequest(req)
 	checkResponseCode(t, http.StatusSeeOther, rr)
-	req, _ = http.NewRequest(http.MethodGet, path.Join(userPath, user.Username), nil)
-	setBearerForReq(req, apiToken)
+
+	group, _, err = httpdtest.GetGroupByName(groupName, http.StatusOK)
+	assert.NoError(t, err)
+	assert.Empty(t, group.Description)
+	assert.Equal(t, 0, group.UserSettings.QuotaFiles)
+	assert.Equal(t, []string{dataprovider.PermAny}, group.UserSettings.Permissions["/"])
+
+	req, err = http.NewRequest(http.MethodPost, path.Join(webGroupPath, "missing"), &b)
+	assert.NoError(t, err)
+	req.Header.Set("Content-Type", contentType)
+	setJWTCookieForReq(req, webToken)
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusOK, rr)
-	lastUpdatedUser = dataprovider.User{}
-	err = render.DecodeJSON(rr.Body, &lastUpdatedUser)
+	checkResponseCode(t, http.StatusNotFound, rr)
+
+	req, err = http.NewRequest(http.MethodDelete, path.Join(webGroupPath, groupName), nil)
 	assert.NoError(t, err)
-	assert.Equal(t, kms.SecretStatusSecretBox, lastUpdatedUser.FsConfig.AzBlobConfig.SASURL.GetStatus())
-	assert.Equal(t, updateUser.FsConfig.AzBlobConfig.SASURL.GetPayload(), lastUpdatedUser.FsConfig.AzBlobConfig.SASURL.GetPayload())
-	assert.Empty(t, lastUpdatedUser.FsConfig.AzBlobConfig.SASURL.GetKey())
-	assert.Empty(t, lastUpdatedUser.FsConfig.AzBlobConfig.SASURL.GetAdditionalData())
+	setJWTCookieForReq(req, webToken)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusForbidden, rr)
+	assert.Contains(t, rr.Body.String(), "Invalid token")
 
-	req, _ = http.NewRequest(http.MethodDelete, path.Join(userPath, user.Username), nil)
-	setBearerForReq(req, apiToken)
+	req, err = http.NewRequest(http.MethodDelete, path.Join(webGroupPath, groupName), nil)
+	assert.NoError(t, err)
+	setJWTCookieForReq(req, webToken)
+	setCSRFHeaderForReq(req, csrfToken)
 	rr = executeRequest(req)
 	checkResponseCode(t, http.StatusOK, rr)
+
+	_, _, err = httpdtest.GetGroupByName(groupName, http.StatusNotFound)
+	assert.NoError(t, err)
 }
 
-func TestWebUserCryptMock(t *testing.T) {
+func TestWebEventRulesMock(t *testing.T) {
 	webToken, err := getJWTWebTokenFromTestServer(defaultTokenAuthUser, defaultTokenAuthPass)
 	assert.NoError(t, err)
-	apiToken, err := getJWTAPITokenFromTestServer(defaultTokenAuthUser, defaultTokenAuthPass)
-	assert.NoError(t, err)
 	csrfToken, err := getCSRFToken(httpBaseURL + webLoginPath)
 	assert.NoError(t, err)
-	user := getTestUser()
-	userAsJSON := getUserAsJSON(t, user)
-	req, _ := http.NewRequest(http.MethodPost, userPath, bytes.NewBuffer(userAsJSON))
-	setBearerForReq(req, apiToken)
-	rr := executeRequest(req)
-	checkResponseCode(t, http.StatusCreated, rr)
-	err = render.DecodeJSON(rr.Body, &user)
-	assert.NoError(t, err)
-	user.FsConfig.Provider = sdk.CryptedFilesystemProvider
-	user.FsConfig.CryptConfig.Passphrase = kms.NewPlainSecret("crypted passphrase")
+	ruleName := "web_rule"
 	form := make(url.Values)
+	form.Set("name", ruleName)
+	form.Set("description", "web rule desc")
+	form.Set("trigger", "1")
+	form.Add("fs_events", "upload")
+	form.Add("fs_events", "download")
+	form.Add("protocols", common.ProtocolSFTP)
+	form.Add("statuses", "1")
+	form.Set("min_size", "0")
+	form.Set("max_size", "1024")
+	form.Set("condition_pattern0", "/inbound/*")
+	form.Set("condition_type0", "fs_path")
+	form.Set("condition_patterng0", "group*")
+	form.Set("condition_typeg0", "group_name")
+	form.Set("condition_matchg0", "inverse")
+	form.Set("condition_pattern1", "user1")
+	form.Set("condition_type1", "name")
+	form.Set("action_type1", "1")
+	form.Set("http_endpoint1", "http://127.0.0.1:8082/hook")
+	form.Set("http_method1", http.MethodPost)
+	form.Set("http_timeout1", "10")
+	form.Set("http_headers1", "Content-Type: application/json\r\nX-Event: {{Event}}")
+	form.Set("http_body1", `{"path": "{{VirtualPath}}"}`)
+	form.Set("action_type0", "2")
+	form.Set("cmd_path0", filepath.Join(os.TempDir(), "cmd"))
+	form.Set("cmd_timeout0", "20")
+	form.Set("cmd_env_vars0", "KEY1=val1\nKEY2={{Name}}")
+	form.Set("action_type2", "4")
+	form.Set("fs_target2", "/archive/")
+	form.Set("http_endpoint2", "http://127.0.0.1/ignored")
+	req, err := http.NewRequest(http.MethodPost, webEventRulePath, bytes.NewBuffer([]byte(form.Encode())))
+	assert.NoError(t, err)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	setJWTCookieForReq(req, webToken)
+	rr := executeRequest(req)
+	checkResponseCode(t, http.StatusForbidden, rr)
+	assert.Contains(t, rr.Body.String(), "unable to verify form token")
+
 	form.Set(csrfFormToken, csrfToken)
-	form.Set("username", user.Username)
-	form.Set("password", redactedSecret)
-	form.Set("home_dir", user.HomeDir)
-	form.Set("uid", "0")
-	form.Set("gid", strconv.FormatInt(int64(user.GID), 10))
-	form.Set("max_sessions", strconv.FormatInt(int64(user.MaxSessions), 10))
-	form.Set("quota_size", strconv.FormatInt(user.QuotaSize, 10))
-	form.Set("quota_files", strconv.FormatInt(int64(user.QuotaFiles), 10))
-	form.Set("upload_bandwidth", "0")
-	form.Set("download_bandwidth", "0")
-	form.Set("permissions", "*")
-	form.Set("status", strconv.Itoa(user.Status))
-	form.Set("expiration_date", "2020-01-01 00:00:00")
-	form.Set("allowed_ip", "")
-	form.Set("denied_ip", "")
-	form.Set("fs_provider", "4")
-	form.Set("crypt_passphrase", "")
-	form.Set("pattern_path0", "/dir1")
-	form.Set("patterns0", "*.jpg,*.png")
-	form.Set("pattern_type0", "allowed")
-	form.Set("pattern_path1", "/dir2")
-	form.Set("patterns1", "*.zip")
-	form.Set("pattern_type1", "denied")
-	form.Set("max_upload_file_size", "0")
-	// passphrase cannot be empty
-	b, contentType, _ := getMultipartFormData(form, "", "")
-	req, _ = http.NewRequest(http.MethodPost, path.Join(webUserPath, user.Username), &b)
+	form.Set("min_size", "a")
+	req, err = http.NewRequest(http.MethodPost, webEventRulePath, bytes.NewBuffer([]byte(form.Encode())))
+	assert.NoError(t, err)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 	setJWTCookieForReq(req, webToken)
-	req.Header.Set("Content-Type", contentType)
 	rr = executeRequest(req)
 	checkResponseCode(t, http.StatusOK, rr)
-	form.Set("crypt_passphrase", user.FsConfig.CryptConfig.Passphrase.GetPayload())
-	b, contentType, _ = getMultipartFormData(form, "", "")
-	req, _ = http.NewRequest(http.MethodPost, path.Join(webUserPath, user.Username), &b)
+	assert.Contains(t, rr.Body.String(), "invalid min file size")
+
+	form.Set("min_size", "10")
+	form.Set("http_timeout1", "a")
+	req, err = http.NewRequest(http.MethodPost, webEventRulePath, bytes.NewBuffer([]byte(form.Encode())))
+	assert.NoError(t, err)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	setJWTCookieForReq(req, webToken)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
+	assert.Contains(t, rr.Body.String(), "invalid HTTP timeout")
+
+	form.Set("http_timeout1", "10")
+	form.Set("trigger", "2")
+	req, err = http.NewRequest(http.MethodPost, webEventRulePath, bytes.NewBuffer([]byte(form.Encode())))
+	assert.NoError(t, err)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	setJWTCookieForReq(req, webToken)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
+	assert.Contains(t, rr.Body.String(), "at least one provider event is required")
+
+	form.Set("trigger", "1")
+	req, err = http.NewRequest(http.MethodPost, webEventRulePath, bytes.NewBuffer([]byte(form.Encode())))
+	assert.NoError(t, err)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 	setJWTCookieForReq(req, webToken)
-	req.Header.Set("Content-Type", contentType)
 	rr = executeRequest(req)
 	checkResponseCode(t, http.StatusSeeOther, rr)
-	req, _ = http.NewRequest(http.MethodGet, path.Join(userPath, user.Username), nil)
-	setBearerForReq(req, apiToken)
+
+	rule, _, err := httpdtest.GetEventRuleByName(ruleName, http.StatusOK)
+	assert.NoError(t, err)
+	assert.Equal(t, "web rule desc", rule.Description)
+	assert.Equal(t, dataprovider.EventTriggerFsEvent, rule.Trigger)
+	assert.Len(t, rule.Conditions.FsEvents, 2)
+	assert.Empty(t, rule.Conditions.ProviderEvents)
+	assert.Equal(t, []string{common.ProtocolSFTP}, rule.Conditions.Options.Protocols)
+	assert.Equal(t, []int{1}, rule.Conditions.Options.Statuses)
+	assert.Equal(t, int64(10), rule.Conditions.Options.MinFileSize)
+	assert.Equal(t, int64(1024), rule.Conditions.Options.MaxFileSize)
+	if assert.Len(t, rule.Conditions.Options.FsPaths, 1) {
+		assert.Equal(t, "/inbound/*", rule.Conditions.Options.FsPaths[0].Pattern)
+		assert.False(t, rule.Conditions.Options.FsPaths[0].InverseMatch)
+	}
+	if assert.Len(t, rule.Conditions.Options.GroupNames, 1) {
+		assert.Equal(t, "group*", rule.Conditions.Options.GroupNames[0].Pattern)
+		assert.True(t, rule.Conditions.Options.GroupNames[0].InverseMatch)
+	}
+	if assert.Len(t, rule.Conditions.Options.Names, 1) {
+		assert.Equal(t, "user1", rule.Conditions.Options.Names[0].Pattern)
+	}
+	if assert.Len(t, rule.Actions, 3) {
+		// the actions are ordered by index
+		assert.Equal(t, dataprovider.EventActionTypeCommand, rule.Actions[0].Type)
+		assert.Equal(t, filepath.Join(os.TempDir(), "cmd"), rule.Actions[0].CmdConfig.Cmd)
+		assert.Equal(t, 20, rule.Actions[0].CmdConfig.Timeout)
+		assert.Equal(t, []dataprovider.KeyValue{{Key: "KEY1", Value: "val1"}, {Key: "KEY2", Value: "{{Name}}"}},
+			rule.Actions[0].CmdConfig.EnvVars)
+		assert.Equal(t, dataprovider.EventActionTypeHTTP, rule.Actions[1].Type)
+		assert.Equal(t, "http://127.0.0.1:8082/hook", rule.Actions[1].HTTPConfig.Endpoint)
+		assert.Equal(t, http.MethodPost, rule.Actions[1].HTTPConfig.Method)
+		assert.Equal(t, `{"path": "{{VirtualPath}}"}`, rule.Actions[1].HTTPConfig.Body)
+		assert.Equal(t, []dataprovider.KeyValue{{Key: "Content-Type", Value: "application/json"},
+			{Key: "X-Event", Value: "{{Event}}"}}, rule.Actions[1].HTTPConfig.Headers)
+		assert.Equal(t, dataprovider.EventActionTypeFsCopy, rule.Actions[2].Type)
+		assert.Equal(t, "/archive/", rule.Actions[2].FsConfig.Target)
+		assert.Empty(t, rule.Actions[2].HTTPConfig.Endpoint)
+	}
+
+	req, err = http.NewRequest(http.MethodGet, webEventRulesPath, nil)
+	assert.NoError(t, err)
+	setJWTCookieForReq(req, webToken)
 	rr = executeRequest(req)
 	checkResponseCode(t, http.StatusOK, rr)
-	var updateUser dataprovider.User
-	err = render.DecodeJSON(rr.Body, &updateUser)
+	assert.Contains(t, rr.Body.String(), ruleName)
+
+	req, err = http.NewRequest(http.MethodGet, webEventRulePath, nil)
 	assert.NoError(t, err)
-	assert.Equal(t, int64(1577836800000), updateUser.ExpirationDate)
-	assert.Equal(t, 2, len(updateUser.Filters.FilePatterns))
-	assert.Equal(t, kms.SecretStatusSecretBox, updateUser.FsConfig.CryptConfig.Passphrase.GetStatus())
-	assert.NotEmpty(t, updateUser.FsConfig.CryptConfig.Passphrase.GetPayload())
-	assert.Empty(t, updateUser.FsConfig.CryptConfig.Passphrase.GetKey())
-	assert.Empty(t, updateUser.FsConfig.CryptConfig.Passphrase.GetAdditionalData())
-	// now check that a redacted password is not saved
-	form.Set("crypt_passphrase", redactedSecret+" ")
-	b, contentType, _ = getMultipartFormData(form, "", "")
-	req, _ = http.NewRequest(http.MethodPost, path.Join(webUserPath, user.Username), &b)
 	setJWTCookieForReq(req, webToken)
-	req.Header.Set("Content-Type", contentType)
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusSeeOther, rr)
-	req, _ = http.NewRequest(http.MethodGet, path.Join(userPath, user.Username), nil)
-	setBearerForReq(req, apiToken)
+	checkResponseCode(t, http.StatusOK, rr)
+
+	req, err = http.NewRequest(http.MethodGet, path.Join(webEventRulePath, ruleName), nil)
+	assert.NoError(t, err)
+	setJWTCookieForReq(req, webToken)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
+	assert.Contains(t, rr.Body.String(), "web rule desc")
+	assert.Contains(t, rr.Body.String(), "KEY2={{Name}}")
+
+	req, err = http.NewRequest(http.MethodGet, path.Join(webEventRulePath, "missing"), nil)
+	assert.NoError(t, err)
+	setJWTCookieForReq(req, webToken)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusNotFound, rr)
+
+	form = make(url.Values)
+	form.Set(csrfFormToken, csrfToken)
+	form.Set("name", "a different name")
+	form.Set("trigger", "2")
+	form.Add("provider_events", "add")
+	form.Add("provider_objects", "user")
+	form.Set("min_size", "0")
+	form.Set("max_size", "0")
+	form.Set("action_type0", "3")
+	form.Set("email_recipients0", "a@example.com, b@example.com")
+	form.Set("email_subject0", "{{ObjectName}} added")
+	form.Set("email_body0", "added by {{Name}}")
+	req, err = http.NewRequest(http.MethodPost, path.Join(webEventRulePath, ruleName),
+		bytes.NewBuffer([]byte(form.Encode())))
+	assert.NoError(t, err)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	setJWTCookieForReq(req, webToken)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusSeeOther, rr)
+
+	rule, _, err = httpdtest.GetEventRuleByName(ruleName, http.StatusOK)
+	assert.NoError(t, err)
+	assert.Empty(t, rule.Description)
+	assert.Equal(t, dataprovider.EventTriggerProviderEvent, rule.Trigger)
+	assert.Equal(t, []string{"add"}, rule.Conditions.ProviderEvents)
+	assert.Equal(t, []string{"user"}, rule.Conditions.Options.ProviderObjects)
+	assert.Empty(t, rule.Conditions.FsEvents)
+	if assert.Len(t, rule.Actions, 1) {
+		assert.Equal(t, dataprovider.EventActionTypeEmail, rule.Actions[0].Type)
+		assert.Equal(t, []string{"a@example.com", "b@example.com"}, rule.Actions[0].EmailConfig.Recipients)
+		assert.Equal(t, "{{ObjectName}} added", rule.Actions[0].EmailConfig.Subject)
+	}
+
+	form.Set("action_type0", "6")
+	req, err = http.NewRequest(http.MethodPost, path.Join(webEventRulePath, ruleName),
+		bytes.NewBuffer([]byte(form.Encode())))
+	assert.NoError(t, err)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	setJWTCookieForReq(req, webToken)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
+	assert.Contains(t, rr.Body.String(), "only supported for filesystem events")
+
+	req, err = http.NewRequest(http.MethodPost, path.Join(webEventRulePath, "missing"),
+		bytes.NewBuffer([]byte(form.Encode())))
+	assert.NoError(t, err)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	setJWTCookieForReq(req, webToken)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusNotFound, rr)
+
+	req, err = http.NewRequest(http.MethodDelete, path.Join(webEventRulePath, ruleName), nil)
+	assert.NoError(t, err)
+	setJWTCookieForReq(req, webToken)
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusOK, rr)
-	var lastUpdatedUser dataprovider.User
-	err = render.DecodeJSON(rr.Body, &lastUpdatedUser)
+	checkResponseCode(t, http.StatusForbidden, rr)
+	assert.Contains(t, rr.Body.String(), "Invalid token")
+
+	req, err = http.NewRequest(http.MethodDelete, path.Join(webEventRulePath, ruleName), nil)
 	assert.NoError(t, err)
-	assert.Equal(t, kms.SecretStatusSecretBox, lastUpdatedUser.FsConfig.CryptConfig.Passphrase.GetStatus())
-	assert.Equal(t, updateUser.FsConfig.CryptConfig.Passphrase.GetPayload(), lastUpdatedUser.FsConfig.CryptConfig.Passphrase.GetPayload())
-	assert.Empty(t, lastUpdatedUser.FsConfig.CryptConfig.Passphrase.GetKey())
-	assert.Empty(t, lastUpdatedUser.FsConfig.CryptConfig.Passphrase.GetAdditionalData())
-	req, _ = http.NewRequest(http.MethodDelete, path.Join(userPath, user.Username), nil)
-	setBearerForReq(req, apiToken)
+	setJWTCookieForReq(req, webToken)
+	setCSRFHeaderForReq(req, csrfToken)
 	rr = executeRequest(req)
 	checkResponseCode(t, http.StatusOK, rr)
+
+	_, _, err = httpdtest.GetEventRuleByName(ruleName, http.StatusNotFound)
+	assert.NoError(t, err)
 }
 
-func TestWebUserSFTPFsMock(t *testing.T) {
+func TestWebSchedulesMock(t *testing.T) {
 	webToken, err := getJWTWebTokenFromTestServer(defaultTokenAuthUser, defaultTokenAuthPass)
 	assert.NoError(t, err)
-	apiToken, err := getJWTAPITokenFromTestServer(defaultTokenAuthUser, defaultTokenAuthPass)
-	assert.NoError(t, err)
 	csrfToken, err := getCSRFToken(httpBaseURL + webLoginPath)
 	assert.NoError(t, err)
-	user := getTestUser()
-	userAsJSON := getUserAsJSON(t, user)
-	req, _ := http.NewRequest(http.MethodPost, userPath, bytes.NewBuffer(userAsJSON))
-	setBearerForReq(req, apiToken)
-	rr := executeRequest(req)
-	checkResponseCode(t, http.StatusCreated, rr)
-	err = render.DecodeJSON(rr.Body, &user)
-	assert.NoError(t, err)
-	user.FsConfig.Provider = sdk.SFTPFilesystemProvider
-	user.FsConfig.SFTPConfig.Endpoint = "127.0.0.1:22"
-	user.FsConfig.SFTPConfig.Username = "sftpuser"
-	user.FsConfig.SFTPConfig.Password = kms.NewPlainSecret("pwd")
-	user.FsConfig.SFTPConfig.PrivateKey = kms.NewPlainSecret(sftpPrivateKey)
-	user.FsConfig.SFTPConfig.Fingerprints = []string{sftpPkeyFingerprint}
-	user.FsConfig.SFTPConfig.Prefix = "/home/sftpuser"
-	user.FsConfig.SFTPConfig.DisableCouncurrentReads = true
-	user.FsConfig.SFTPConfig.BufferSize = 5
+	scheduleName := "web_schedule"
 	form := make(url.Values)
+	form.Set("name", scheduleName)
+	form.Set("description", "web schedule desc")
+	form.Set("status", "1")
+	form.Set("cron_expression", "15 1 * * *")
+	form.Set("task", "2")
+	form.Set("usernames", "user1, user2")
+	form.Set("folder_names", "folder1")
+	form.Set("retention_path0", "/dir2")
+	form.Set("retention_value0", "48")
+	form.Add("retention_options0", "delete_empty_dirs")
+	form.Add("retention_options0", "ignore_user_permissions")
+	form.Set("retention_path1", "/dir1")
+	form.Set("retention_value1", "24")
+	form.Set("retention_path2", "")
+	form.Set("retention_value2", "a")
+	form.Add("notifications", dataprovider.ScheduleNotificationHook)
+	form.Add("notifications", dataprovider.ScheduleNotificationEmail)
+	form.Set("notifications_email", "notify@example.com")
+	req, err := http.NewRequest(http.MethodPost, webSchedulePath, bytes.NewBuffer([]byte(form.Encode())))
+	assert.NoError(t, err)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	setJWTCookieForReq(req, webToken)
+	rr := executeRequest(req)
+	checkResponseCode(t, http.StatusForbidden, rr)
+	assert.Contains(t, rr.Body.String(), "unable to verify form token")
+
 	form.Set(csrfFormToken, csrfToken)
-	form.Set("username", user.Username)
-	form.Set("password", redactedSecret)
-	form.Set("home_dir", user.HomeDir)
-	form.Set("uid", "0")
-	form.Set("gid", strconv.FormatInt(int64(user.GID), 10))
-	form.Set("max_sessions", strconv.FormatInt(int64(user.MaxSessions), 10))
-	form.Set("quota_size", strconv.FormatInt(user.QuotaSize, 10))
-	form.Set("quota_files", strconv.FormatInt(int64(user.QuotaFiles), 10))
-	form.Set("upload_bandwidth", "0")
-	form.Set("download_bandwidth", "0")
-	form.Set("permissions", "*")
-	form.Set("status", strconv.Itoa(user.Status))
-	form.Set("expiration_date", "2020-01-01 00:00:00")
-	form.Set("allowed_ip", "")
-	form.Set("denied_ip", "")
-	form.Set("fs_provider", "5")
-	form.Set("crypt_passphrase", "")
-	form.Set("pattern_path0", "/dir1")
-	form.Set("patterns0", "*.jpg,*.png")
-	form.Set("pattern_type0", "allowed")
-	form.Set("pattern_path1", "/dir2")
-	form.Set("patterns1", "*.zip")
-	form.Set("pattern_type1", "denied")
-	form.Set("max_upload_file_size", "0")
-	// empty sftpconfig
-	b, contentType, _ := getMultipartFormData(form, "", "")
-	req, _ = http.NewRequest(http.MethodPost, path.Join(webUserPath, user.Username), &b)
+	form.Set("task", "a")
+	req, err = http.NewRequest(http.MethodPost, webSchedulePath, bytes.NewBuffer([]byte(form.Encode())))
+	assert.NoError(t, err)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 	setJWTCookieForReq(req, webToken)
-	req.Header.Set("Content-Type", contentType)
 	rr = executeRequest(req)
 	checkResponseCode(t, http.StatusOK, rr)
-	form.Set("sftp_endpoint", user.FsConfig.SFTPConfig.Endpoint)
-	form.Set("sftp_username", user.FsConfig.SFTPConfig.Username)
-	form.Set("sftp_password", user.FsConfig.SFTPConfig.Password.GetPayload())
-	form.Set("sftp_private_key", user.FsConfig.SFTPConfig.PrivateKey.GetPayload())
-	form.Set("sftp_fingerprints", user.FsConfig.SFTPConfig.Fingerprints[0])
-	form.Set("sftp_prefix", user.FsConfig.SFTPConfig.Prefix)
-	form.Set("sftp_disable_concurrent_reads", "true")
-	form.Set("sftp_buffer_size", strconv.FormatInt(user.FsConfig.SFTPConfig.BufferSize, 10))
-	b, contentType, _ = getMultipartFormData(form, "", "")
-	req, _ = http.NewRequest(http.MethodPost, path.Join(webUserPath, user.Username), &b)
+	assert.Contains(t, rr.Body.String(), "invalid task")
+
+	form.Set("task", "2")
+	form.Set("status", "a")
+	req, err = http.NewRequest(http.MethodPost, webSchedulePath, bytes.NewBuffer([]byte(form.Encode())))
+	assert.NoError(t, err)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 	setJWTCookieForReq(req, webToken)
-	req.Header.Set("Content-Type", contentType)
-	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusSeeOther, rr)
-	req, _ = http.NewRequest(http.MethodGet, path.Join(userPath, user.Username), nil)
-	setBearerForReq(req, apiToken)
 	rr = executeRequest(req)
 	checkResponseCode(t, http.StatusOK, rr)
-	var updateUser dataprovider.User
-	err = render.DecodeJSON(rr.Body, &updateUser)
+	assert.Contains(t, rr.Body.String(), "invalid status")
+
+	form.Set("status", "1")
+	form.Set("retention_value1", "a")
+	req, err = http.NewRequest(http.MethodPost, webSchedulePath, bytes.NewBuffer([]byte(form.Encode())))
 	assert.NoError(t, err)
-	assert.Equal(t, int64(1577836800000), updateUser.ExpirationDate)
-	assert.Equal(t, 2, len(updateUser.Filters.FilePatterns))
-	assert.Equal(t, kms.SecretStatusSecretBox, updateUser.FsConfig.SFTPConfig.Password.GetStatus())
-	assert.NotEmpty(t, updateUser.FsConfig.SFTPConfig.Password.GetPayload())
-	assert.Empty(t, updateUser.FsConfig.SFTPConfig.Password.GetKey())
-	assert.Empty(t, updateUser.FsConfig.SFTPConfig.Password.GetAdditionalData())
-	assert.Equal(t, kms.SecretStatusSecretBox, updateUser.FsConfig.SFTPConfig.PrivateKey.GetStatus())
-	assert.NotEmpty(t, updateUser.FsConfig.SFTPConfig.PrivateKey.GetPayload())
-	assert.Empty(t, updateUser.FsConfig.SFTPConfig.PrivateKey.GetKey())
-	assert.Empty(t, updateUser.FsConfig.SFTPConfig.PrivateKey.GetAdditionalData())
-	assert.Equal(t, updateUser.FsConfig.SFTPConfig.Prefix, user.FsConfig.SFTPConfig.Prefix)
-	assert.Equal(t, updateUser.FsConfig.SFTPConfig.Username, user.FsConfig.SFTPConfig.Username)
-	assert.Equal(t, updateUser.FsConfig.SFTPConfig.Endpoint, user.FsConfig.SFTPConfig.Endpoint)
-	assert.True(t, updateUser.FsConfig.SFTPConfig.DisableCouncurrentReads)
-	assert.Len(t, updateUser.FsConfig.SFTPConfig.Fingerprints, 1)
-	assert.Equal(t, user.FsConfig.SFTPConfig.BufferSize, updateUser.FsConfig.SFTPConfig.BufferSize)
-	assert.Contains(t, updateUser.FsConfig.SFTPConfig.Fingerprints, sftpPkeyFingerprint)
-	// now check that a redacted credentials are not saved
-	form.Set("sftp_password", redactedSecret+" ")
-	form.Set("sftp_private_key", redactedSecret)
-	b, contentType, _ = getMultipartFormData(form, "", "")
-	req, _ = http.NewRequest(http.MethodPost, path.Join(webUserPath, user.Username), &b)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 	setJWTCookieForReq(req, webToken)
-	req.Header.Set("Content-Type", contentType)
-	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusSeeOther, rr)
-	req, _ = http.NewRequest(http.MethodGet, path.Join(userPath, user.Username), nil)
-	setBearerForReq(req, apiToken)
 	rr = executeRequest(req)
 	checkResponseCode(t, http.StatusOK, rr)
-	var lastUpdatedUser dataprovider.User
-	err = render.DecodeJSON(rr.Body, &lastUpdatedUser)
+	assert.Contains(t, rr.Body.String(), "invalid retention for path")
+
+	form.Set("retention_value1", "24")
+	form.Set("cron_expression", "* * * * * *")
+	req, err = http.NewRequest(http.MethodPost, webSchedulePath, bytes.NewBuffer([]byte(form.Encode())))
 	assert.NoError(t, err)
-	assert.Equal(t, kms.SecretStatusSecretBox, lastUpdatedUser.FsConfig.SFTPConfig.Password.GetStatus())
-	assert.Equal(t, updateUser.FsConfig.SFTPConfig.Password.GetPayload(), lastUpdatedUser.FsConfig.SFTPConfig.Password.GetPayload())
-	assert.Empty(t, lastUpdatedUser.FsConfig.SFTPConfig.Password.GetKey())
-	assert.Empty(t, lastUpdatedUser.FsConfig.SFTPConfig.Password.GetAdditionalData())
-	assert.Equal(t, kms.SecretStatusSecretBox, lastUpdatedUser.FsConfig.SFTPConfig.PrivateKey.GetStatus())
-	assert.Equal(t, updateUser.FsConfig.SFTPConfig.PrivateKey.GetPayload(), lastUpdatedUser.FsConfig.SFTPConfig.PrivateKey.GetPayload())
-	assert.Empty(t, lastUpdatedUser.FsConfig.SFTPConfig.PrivateKey.GetKey())
-	assert.Empty(t, lastUpdatedUser.FsConfig.SFTPConfig.PrivateKey.GetAdditionalData())
-	req, _ = http.NewRequest(http.MethodDelete, path.Join(userPath, user.Username), nil)
-	setBearerForReq(req, apiToken)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	setJWTCookieForReq(req, webToken)
 	rr = executeRequest(req)
 	checkResponseCode(t, http.StatusOK, rr)
-}
+	assert.Contains(t, rr.Body.String(), "invalid cron expression")
 
-func TestAddWebFoldersMock(t *testing.T) {
-	webToken, err := getJWTWebTokenFromTestServer(defaultTokenAuthUser, defaultTokenAuthPass)
+	form.Set("cron_expression", "15 1 * * *")
+	req, err = http.NewRequest(http.MethodPost, webSchedulePath, bytes.NewBuffer([]byte(form.Encode())))
 	assert.NoError(t, err)
-	apiToken, err := getJWTAPITokenFromTestServer(defaultTokenAuthUser, defaultTokenAuthPass)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	setJWTCookieForReq(req, webToken)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusSeeOther, rr)
+
+	schedule, _, err := httpdtest.GetScheduleByName(scheduleName, http.StatusOK)
+	assert.NoError(t, err)
+	assert.Equal(t, "web schedule desc", schedule.Description)
+	assert.Equal(t, dataprovider.ScheduleStatusEnabled, schedule.Status)
+	assert.Equal(t, "15 1 * * *", schedule.CronExpression)
+	assert.Equal(t, dataprovider.ScheduleTaskRetentionCheck, schedule.Task)
+	assert.Equal(t, []string{"user1", "user2"}, schedule.Options.Usernames)
+	assert.Empty(t, schedule.Options.FolderNames)
+	if assert.Len(t, schedule.Options.Retention, 2) {
+		assert.Equal(t, "/dir1", schedule.Options.Retention[0].Path)
+		assert.Equal(t, 24, schedule.Options.Retention[0].Retention)
+		assert.False(t, schedule.Options.Retention[0].DeleteEmptyDirs)
+		assert.False(t, schedule.Options.Retention[0].IgnoreUserPermissions)
+		assert.Equal(t, "/dir2", schedule.Options.Retention[1].Path)
+		assert.Equal(t, 48, schedule.Options.Retention[1].Retention)
+		assert.True(t, schedule.Options.Retention[1].DeleteEmptyDirs)
+		assert.True(t, schedule.Options.Retention[1].IgnoreUserPermissions)
+	}
+	assert.Equal(t, []string{dataprovider.ScheduleNotificationHook, dataprovider.ScheduleNotificationEmail},
+		schedule.Options.Notifications)
+	assert.Equal(t, "notify@example.com", schedule.Options.Email)
+
+	req, err = http.NewRequest(http.MethodGet, webSchedulesPath, nil)
 	assert.NoError(t, err)
-	csrfToken, err := getCSRFToken(httpBaseURL + webLoginPath)
+	setJWTCookieForReq(req, webToken)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
+	assert.Contains(t, rr.Body.String(), scheduleName)
+
+	req, err = http.NewRequest(http.MethodGet, webSchedulePath, nil)
 	assert.NoError(t, err)
-	mappedPath := filepath.Clean(os.TempDir())
-	folderName := filepath.Base(mappedPath)
-	folderDesc := "a simple desc"
-	form := make(url.Values)
-	form.Set("mapped_path", mappedPath)
-	form.Set("name", folderName)
-	form.Set("description", folderDesc)
-	b, contentType, err := getMultipartFormData(form, "", "")
+	setJWTCookieForReq(req, webToken)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
+
+	req, err = http.NewRequest(http.MethodGet, path.Join(webSchedulePath, scheduleName), nil)
 	assert.NoError(t, err)
-	req, err := http.NewRequest(http.MethodPost, webFolderPath, &b)
+	setJWTCookieForReq(req, webToken)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, rr)
+	assert.Contains(t, rr.Body.String(), "web schedule desc")
+	assert.Contains(t, rr.Body.String(), "Never run")
+
+	req, err = http.NewRequest(http.MethodGet, path.Join(webSchedulePath, "missing"), nil)
 	assert.NoError(t, err)
-	req.Header.Set("Content-Type", contentType)
 	setJWTCookieForReq(req, webToken)
-	rr := executeRequest(req)
-	checkResponseCode(t, http.StatusForbidden, rr)
-	assert.Contains(t, rr.Body.String(), "unable to verify form token")
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusNotFound, rr)
 
+	form = make(url.Values)
 	form.Set(csrfFormToken, csrfToken)
-	b, contentType, err = getMultipartFormData(form, "", "")
-	assert.NoError(t, err)
-	req, err = http.NewRequest(http.MethodPost, webFolderPath, &b)
+	form.Set("name", "a different name")
+	form.Set("status", "0")
+	form.Set("cron_expression", "@weekly")
+	form.Set("task", "1")
+	form.Set("usernames", "user1")
+	form.Set("all_folders", "1")
+	form.Set("folder_names", "folder1")
+	form.Set("retention_path0", "/")
+	form.Set("retention_value0", "a")
+	req, err = http.NewRequest(http.MethodPost, path.Join(webSchedulePath, scheduleName),
+		bytes.NewBuffer([]byte(form.Encode())))
 	assert.NoError(t, err)
-	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 	setJWTCookieForReq(req, webToken)
 	rr = executeRequest(req)
 	checkResponseCode(t, http.StatusSeeOther, rr)
-	// adding the same folder will fail since the name must be unique
-	b, contentType, err = getMultipartFormData(form, "", "")
-	assert.NoError(t, err)
-	req, err = http.NewRequest(http.MethodPost, webFolderPath, &b)
+
+	schedule, _, err = httpdtest.GetScheduleByName(scheduleName, http.StatusOK)
+	assert.NoError(t, err)
+	assert.Empty(t, schedule.Description)
+	assert.Equal(t, dataprovider.ScheduleStatusDisabled, schedule.Status)
+	assert.Equal(t, "@weekly", schedule.CronExpression)
+	assert.Equal(t, dataprovider.ScheduleTaskQuotaScan, schedule.Task)
+	assert.Equal(t, []string{"user1"}, schedule.Options.Usernames)
+	assert.True(t, schedule.Options.AllFolders)
+	assert.Empty(t, schedule.Options.FolderNames)
+	assert.Empty(t, schedule.Options.Retention)
+
+	form.Set("usernames", "")
+	form.Del("all_folders")
+	form.Set("folder_names", "")
+	req, err = http.NewRequest(http.MethodPost, path.Join(webSchedulePath, scheduleName),
+		bytes.NewBuffer([]byte(form.Encode())))
 	assert.NoError(t, err)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 	setJWTCookieForReq(req, webToken)
-	req.Header.Set("Content-Type", contentType)
 	rr = executeRequest(req)
 	checkResponseCode(t, http.StatusOK, rr)
-	// invalid form
-	req, err = http.NewRequest(http.MethodPost, webFolderPath, strings.NewReader(form.Encode()))
+	assert.Contains(t, rr.Body.String(), "at least a user or a folder is required")
+
+	req, err = http.NewRequest(http.MethodPost, path.Join(webSchedulePath, "missing"),
+		bytes.NewBuffer([]byte(form.Encode())))
 	assert.NoError(t, err)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 	setJWTCookieForReq(req, webToken)
-	req.Header.Set("Content-Type", "text/plain; boundary=")
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusOK, rr)
+	checkResponseCode(t, http.StatusNotFound, rr)
 
-	// now render the add folder page
-	req, err = http.NewRequest(http.MethodGet, webFolderPath, nil)
+	req, err = http.NewRequest(http.MethodPost, path.Join(webSchedulePath, scheduleName, "run"), nil)
 	assert.NoError(t, err)
 	setJWTCookieForReq(req, webToken)
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusOK, rr)
+	checkResponseCode(t, http.StatusForbidden, rr)
+	assert.Contains(t, rr.Body.String(), "Invalid token")
 
-	var folder vfs.BaseVirtualFolder
-	req, _ = http.NewRequest(http.MethodGet, path.Join(folderPath, folderName), nil)
-	setBearerForReq(req, apiToken)
+	req, err = http.NewRequest(http.MethodPost, path.Join(webSchedulePath, "missing", "run"), nil)
+	assert.NoError(t, err)
+	setJWTCookieForReq(req, webToken)
+	setCSRFHeaderForReq(req, csrfToken)
 	rr = executeRequest(req)
-	checkResponseCode(t, http.StatusOK, rr)
-	err = render.DecodeJSON(rr.Body, &folder)
+	checkResponseCode(t, http.StatusNotFound, rr)
+
+	req, err = http.NewRequest(http.MethodDelete, path.Join(webSchedulePath, scheduleName), nil)
 	assert.NoError(t, err)
-	assert.Equal(t, mappedPath, folder.MappedPath)
-	assert.Equal(t, folderName, folder.Name)
-	assert.Equal(t, folderDesc, folder.Description)
-	// cleanup
-	req, _ = http.NewRequest(http.MethodDelete, path.Join(folderPath, folderName), nil)
-	setBearerForReq(req, apiToken)
+	setJWTCookieForReq(req, webToken)
+	rr = executeRequest(req)
+	checkResponseCode(t, http.StatusForbidden, rr)
+	assert.Contains(t, rr.Body.String(), "Invalid token")
+
+	req, err = http.NewRequest(http.MethodDelete, path.Join(webSchedulePath, scheduleName), nil)
+	assert.NoError(t, err)
+	setJWTCookieForReq(req, webToken)
+	setCSRFHeaderForReq(req, csrfToken)
 	rr = executeRequest(req)
 	checkResponseCode(t, http.StatusOK, rr)
+
+	_, _, err = httpdtest.GetScheduleByName(scheduleName, http.StatusNotFound)
+	assert.NoError(t, err)
 }
 
 func TestS3WebFolderMock(t *testing.T) {
@@ -13335,6 +19180,20 @@ func getTestAdmin() dataprovider.Admin {
 	}
 }
 
+func getTestPluginsConfig(wdPath string) []plugin.Config {
+	pluginsConfig := []plugin.Config{
+		{
+			Type:     "eventsearcher",
+			Cmd:      filepath.Join(wdPath, "..", "tests", "eventsearcher", "eventsearcher"),
+			AutoMTLS: true,
+		},
+	}
+	if runtime.GOOS == osWindows {
+		pluginsConfig[0].Cmd += ".exe"
+	}
+	return pluginsConfig
+}
+
 func getTestUser() dataprovider.User {
 	user := dataprovider.User{
 		BaseUser: sdk.BaseUser{
@@ -13413,6 +19272,17 @@ func getCSRFToken(url string) (string, error) {
 	return csrfToken, nil
 }
 
+func getResetCodeFromEmail(t *testing.T, to string) string {
+	email := lastReceivedEmail.get()
+	require.Len(t, email.To, 1)
+	require.Equal(t, to, email.To[0])
+	// remove quoted-printable soft line breaks
+	body := strings.ReplaceAll(string(email.Data), "=\r\n", "")
+	match := regexp.MustCompile(`<code>([^<]+)</code>`).FindStringSubmatch(body)
+	require.Len(t, match, 2)
+	return match[1]
+}
+
 func getLoginForm(username, password, csrfToken string) url.Values {
 	form := make(url.Values)
 	form.Set("username", username)
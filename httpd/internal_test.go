@@ -613,6 +613,69 @@ func TestRetentionInvalidTokenClaims(t *testing.T) {
 	assert.NoError(t, err)
 }
 
+func TestResetCode(t *testing.T) {
+	code, err := createResetCode("admin", "hashed password", true)
+	assert.NoError(t, err)
+	assert.Len(t, code, 16)
+	// the code is not stored in plain text
+	_, err = dataprovider.GetResetCode(code)
+	assert.Error(t, err)
+	resetCode, err := verifyResetCode(code, true)
+	assert.NoError(t, err)
+	assert.Equal(t, "admin", resetCode.Username)
+	assert.Equal(t, dataprovider.AccountTypeAdmin, resetCode.Type)
+	assert.True(t, isPasswordFingerprintValid(resetCode.Username, "hashed password", resetCode.PasswordFingerprint))
+	assert.False(t, isPasswordFingerprintValid(resetCode.Username, "new hashed password", resetCode.PasswordFingerprint))
+	assert.False(t, isPasswordFingerprintValid("user", "hashed password", resetCode.PasswordFingerprint))
+	count, err := dataprovider.GetPendingResetCodesCount("admin", dataprovider.AccountTypeAdmin)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, count)
+	count, err = dataprovider.GetPendingResetCodesCount("admin", dataprovider.AccountTypeUser)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, count)
+	// an admin code cannot be used for users
+	_, err = verifyResetCode(code, false)
+	assert.ErrorIs(t, err, errInvalidResetCode)
+	// a CSRF token is not a valid reset code
+	_, err = verifyResetCode(createCSRFToken(), true)
+	assert.ErrorIs(t, err, errInvalidResetCode)
+	deleteResetCode(&resetCode)
+	_, err = verifyResetCode(code, true)
+	assert.ErrorIs(t, err, errInvalidResetCode)
+	// expired code
+	now := time.Now()
+	err = dataprovider.AddResetCode(&dataprovider.ResetCode{
+		Code:                getResetCodeHash("expired"),
+		Username:            "user",
+		Type:                dataprovider.AccountTypeUser,
+		PasswordFingerprint: getPasswordFingerprint("user", "hashed password"),
+		ExpiresAt:           util.GetTimeAsMsSinceEpoch(now.Add(-1 * time.Minute)),
+		CreatedAt:           util.GetTimeAsMsSinceEpoch(now.Add(-resetCodeDuration)),
+	})
+	assert.NoError(t, err)
+	_, err = verifyResetCode("expired", false)
+	assert.ErrorIs(t, err, errInvalidResetCode)
+	count, err = dataprovider.GetPendingResetCodesCount("user", dataprovider.AccountTypeUser)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, count)
+	err = dataprovider.AddResetCode(&dataprovider.ResetCode{
+		Code:     getResetCodeHash("invalid"),
+		Username: "user",
+		Type:     "invalid",
+	})
+	assert.Error(t, err)
+	cleanupExpiredResetCodes()
+	_, err = dataprovider.GetResetCode(getResetCodeHash("expired"))
+	assert.Error(t, err)
+
+	req, err := http.NewRequest(http.MethodPost, webClientForgotPwdPath, nil)
+	assert.NoError(t, err)
+	err = handleForgotPassword(req, "user", false)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "no SMTP server configured")
+	}
+}
+
 func TestCSRFToken(t *testing.T) {
 	// invalid token
 	err := verifyCSRFToken("token")
@@ -1093,6 +1156,39 @@ func TestChangePwdValidationErrors(t *testing.T) {
 	assert.Error(t, err)
 }
 
+func TestPasswordExpiration(t *testing.T) {
+	user := dataprovider.User{}
+	user.LastPasswordChange = util.GetTimeAsMsSinceEpoch(time.Now().Add(-10 * 24 * time.Hour))
+	assert.False(t, user.MustChangePassword())
+	assert.True(t, user.GetPasswordExpirationTime().IsZero())
+	user.Filters.PasswordExpiration = 20
+	assert.False(t, user.MustChangePassword())
+	assert.False(t, user.GetPasswordExpirationTime().IsZero())
+	user.Filters.PasswordExpiration = 5
+	assert.True(t, user.MustChangePassword())
+
+	admin := dataprovider.Admin{}
+	admin.LastPasswordChange = user.LastPasswordChange
+	assert.False(t, admin.MustChangePassword())
+	admin.Filters.PasswordExpiration = 5
+	assert.True(t, admin.MustChangePassword())
+	admin.Filters.PasswordExpiration = 0
+	admin.Filters.RequirePasswordChange = true
+	assert.True(t, admin.MustChangePassword())
+
+	c := jwtTokenClaims{
+		Username:           admin.Username,
+		MustChangePassword: admin.MustChangePassword(),
+	}
+	claims := jwtTokenClaims{}
+	claims.Decode(c.asMap())
+	assert.True(t, claims.MustChangePassword)
+	c.MustChangePassword = false
+	claims = jwtTokenClaims{}
+	claims.Decode(c.asMap())
+	assert.False(t, claims.MustChangePassword)
+}
+
 func TestRenderUnexistingFolder(t *testing.T) {
 	rr := httptest.NewRecorder()
 	req, _ := http.NewRequest(http.MethodPost, folderPath, nil)
@@ -1713,6 +1809,43 @@ func TestConnection(t *testing.T) {
 	assert.ErrorIs(t, err, os.ErrNotExist)
 }
 
+func TestReserveUploadPath(t *testing.T) {
+	user := dataprovider.User{
+		BaseUser: sdk.BaseUser{
+			Username: "test_httpd_user",
+			HomeDir:  filepath.Clean(os.TempDir()),
+		},
+	}
+	user.Permissions = make(map[string][]string)
+	user.Permissions["/"] = []string{dataprovider.PermAny}
+	connection := &Connection{
+		BaseConnection: common.NewBaseConnection(xid.New().String(), common.ProtocolHTTP, "", "", user),
+		request:        nil,
+	}
+	filePath := "/" + xid.New().String()
+	release, err := reserveUploadPath(connection, filePath)
+	assert.NoError(t, err)
+	_, err = reserveUploadPath(connection, filePath)
+	assert.ErrorIs(t, err, errUploadInProgress)
+	assert.Equal(t, http.StatusConflict, getReservedPathStatus(err))
+	release()
+	release, err = reserveUploadPath(connection, filePath)
+	assert.NoError(t, err)
+	release()
+
+	err = os.WriteFile(filepath.Join(user.HomeDir, filePath), []byte("data"), os.ModePerm)
+	assert.NoError(t, err)
+	_, err = reserveUploadPath(connection, filePath)
+	assert.ErrorIs(t, err, os.ErrExist)
+	assert.Equal(t, http.StatusConflict, getReservedPathStatus(err))
+	// a failed reservation is released
+	_, ok := reservedUploadPaths.Load(user.Username + ":" + filePath)
+	assert.False(t, ok)
+	err = os.Remove(filepath.Join(user.HomeDir, filePath))
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusForbidden, getReservedPathStatus(os.ErrPermission))
+}
+
 func TestGetFileWriterErrors(t *testing.T) {
 	user := dataprovider.User{
 		BaseUser: sdk.BaseUser{
@@ -1773,7 +1906,7 @@ func TestHTTPDFile(t *testing.T) {
 	assert.NoError(t, err)
 
 	baseTransfer := common.NewBaseTransfer(file, connection.BaseConnection, nil, p, p, name, common.TransferDownload,
-		0, 0, 0, false, fs)
+		0, 0, 0, false, fs, dataprovider.TransferQuota{})
 	httpdFile := newHTTPDFile(baseTransfer, nil, nil)
 	// the file is closed, read should fail
 	buf := make([]byte, 100)
@@ -1979,3 +2112,297 @@ func TestLoginLinks(t *testing.T) {
 	assert.False(t, b.showAdminLoginURL())
 	assert.True(t, b.showClientLoginURL())
 }
+
+func TestTusUploadsCleanup(t *testing.T) {
+	metadata, err := parseTusMetadata("filename ZmlsZS50eHQ=, is_confidential")
+	assert.NoError(t, err)
+	assert.Equal(t, "file.txt", metadata["filename"])
+	assert.Contains(t, metadata, "is_confidential")
+	_, err = parseTusMetadata("filename invalid")
+	assert.Error(t, err)
+
+	user := dataprovider.User{
+		BaseUser: sdk.BaseUser{
+			Username: "test_tus_user",
+			Password: "pwd",
+			HomeDir:  filepath.Join(os.TempDir(), "test_tus_user"),
+			Status:   1,
+		},
+	}
+	user.Permissions = make(map[string][]string)
+	user.Permissions["/"] = []string{dataprovider.PermAny}
+	err = dataprovider.AddUser(&user, "", "")
+	assert.NoError(t, err)
+	err = os.MkdirAll(user.GetHomeDir(), os.ModePerm)
+	assert.NoError(t, err)
+
+	tempFile := filepath.Join(user.GetHomeDir(), ".sftpgo-upload.test")
+	err = os.WriteFile(tempFile, []byte("data"), os.ModePerm)
+	assert.NoError(t, err)
+	now := time.Now()
+	upload := &dataprovider.TusUpload{
+		ID:          "expired",
+		Username:    user.Username,
+		VirtualPath: "/file.txt",
+		FsPath:      tempFile,
+		Length:      10,
+		ExpiresAt:   util.GetTimeAsMsSinceEpoch(now.Add(-1 * time.Minute)),
+		CreatedAt:   util.GetTimeAsMsSinceEpoch(now.Add(-1 * time.Hour)),
+	}
+	err = dataprovider.AddTusUpload(upload)
+	assert.NoError(t, err)
+	_, err = getTusUpload(upload.ID, user.Username)
+	assert.Error(t, err)
+	_, _, err = getAndLockTusUpload(upload.ID, user.Username)
+	assert.Error(t, err)
+	_, ok := tusUploadLocks.Load(upload.ID)
+	assert.False(t, ok)
+	err = dataprovider.AddTusUpload(&dataprovider.TusUpload{
+		ID:          "missing user",
+		Username:    "missing user",
+		VirtualPath: "/file.txt",
+		FsPath:      "/file.txt",
+		ExpiresAt:   util.GetTimeAsMsSinceEpoch(now.Add(-1 * time.Minute)),
+		CreatedAt:   util.GetTimeAsMsSinceEpoch(now.Add(-1 * time.Hour)),
+	})
+	assert.NoError(t, err)
+	active := &dataprovider.TusUpload{
+		ID:          "active",
+		Username:    user.Username,
+		VirtualPath: "/file1.txt",
+		FsPath:      filepath.Join(user.GetHomeDir(), ".sftpgo-upload.active"),
+		Length:      10,
+		ExpiresAt:   util.GetTimeAsMsSinceEpoch(now.Add(1 * time.Minute)),
+		CreatedAt:   util.GetTimeAsMsSinceEpoch(now),
+	}
+	err = dataprovider.AddTusUpload(active)
+	assert.NoError(t, err)
+	err = dataprovider.AddTusUpload(active)
+	assert.Error(t, err)
+	err = dataprovider.AddTusUpload(&dataprovider.TusUpload{ID: "invalid"})
+	assert.Error(t, err)
+	count, err := dataprovider.GetTusUploadsCount(user.Username)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, count)
+	loaded, unlock, err := getAndLockTusUpload(active.ID, user.Username)
+	if assert.NoError(t, err) {
+		assert.Equal(t, active.FsPath, loaded.FsPath)
+		unlock()
+	}
+	_, err = getTusUpload(active.ID, "another user")
+	assert.Error(t, err)
+	expiresAt := now.Add(2 * time.Minute)
+	err = dataprovider.UpdateTusUploadOffset(active.ID, 5, expiresAt)
+	assert.NoError(t, err)
+	loaded, err = dataprovider.GetTusUpload(active.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(5), loaded.Offset)
+	assert.Equal(t, util.GetTimeAsMsSinceEpoch(expiresAt), loaded.ExpiresAt)
+	assert.Greater(t, loaded.UpdatedAt, int64(0))
+	err = dataprovider.UpdateTusUploadOffset("missing", 5, expiresAt)
+	assert.Error(t, err)
+
+	cleanupExpiredTusUploads()
+	assert.NoFileExists(t, tempFile)
+	for _, id := range []string{"expired", "missing user"} {
+		_, err = dataprovider.GetTusUpload(id)
+		assert.Error(t, err)
+	}
+	_, err = dataprovider.GetTusUpload(active.ID)
+	assert.NoError(t, err)
+	// the uploads are removed with the user
+	err = dataprovider.DeleteUser(user.Username, "", "")
+	assert.NoError(t, err)
+	_, err = dataprovider.GetTusUpload(active.ID)
+	assert.Error(t, err)
+	err = os.RemoveAll(user.GetHomeDir())
+	assert.NoError(t, err)
+}
+
+func TestTusUploadExpirationExtended(t *testing.T) {
+	user := dataprovider.User{
+		BaseUser: sdk.BaseUser{
+			Username: "test_tus_expiration_user",
+			Password: "pwd",
+			HomeDir:  filepath.Join(os.TempDir(), "test_tus_expiration_user"),
+			Status:   1,
+		},
+	}
+	user.Permissions = make(map[string][]string)
+	user.Permissions["/"] = []string{dataprovider.PermAny}
+	err := dataprovider.AddUser(&user, "", "")
+	assert.NoError(t, err)
+	user, err = dataprovider.UserExists(user.Username)
+	assert.NoError(t, err)
+	err = os.MkdirAll(user.GetHomeDir(), os.ModePerm)
+	assert.NoError(t, err)
+	connection := &Connection{
+		BaseConnection: common.NewBaseConnection(xid.New().String(), common.ProtocolHTTP, "", "", user),
+		request:        nil,
+	}
+
+	fsPath, err := connection.getTusUploadPath("/file.dat", 6)
+	assert.NoError(t, err)
+	now := time.Now()
+	upload := &dataprovider.TusUpload{
+		ID:          "sliding",
+		Username:    user.Username,
+		VirtualPath: "/file.dat",
+		FsPath:      fsPath,
+		Length:      6,
+		ExpiresAt:   util.GetTimeAsMsSinceEpoch(now.Add(500 * time.Millisecond)),
+		CreatedAt:   util.GetTimeAsMsSinceEpoch(now),
+		UpdatedAt:   util.GetTimeAsMsSinceEpoch(now),
+	}
+	err = dataprovider.AddTusUpload(upload)
+	assert.NoError(t, err)
+	offset, err := writeTusChunk(connection, upload, 0, bytes.NewReader([]byte("abc")))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), offset)
+	// the stored chunk moves the expiration forward
+	loaded, err := dataprovider.GetTusUpload(upload.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), loaded.Offset)
+	assert.Greater(t, loaded.ExpiresAt, util.GetTimeAsMsSinceEpoch(now.Add(tusUploadExpiration-time.Minute)))
+	assert.Equal(t, loaded.ExpiresAt, upload.ExpiresAt)
+	// the original deadline is now in the past, the upload is still active
+	time.Sleep(600 * time.Millisecond)
+	cleanupExpiredTusUploads()
+	assert.FileExists(t, fsPath)
+	loaded, unlock, err := getAndLockTusUpload(upload.ID, user.Username)
+	if assert.NoError(t, err) {
+		unlock()
+	}
+	offset, err = writeTusChunk(connection, &loaded, 3, bytes.NewReader([]byte("def")))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(6), offset)
+	content, err := os.ReadFile(filepath.Join(user.GetHomeDir(), "file.dat"))
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("abcdef"), content)
+	_, err = dataprovider.GetTusUpload(upload.ID)
+	assert.Error(t, err)
+
+	err = dataprovider.DeleteUser(user.Username, "", "")
+	assert.NoError(t, err)
+	err = os.RemoveAll(user.GetHomeDir())
+	assert.NoError(t, err)
+}
+
+func TestCronExpression(t *testing.T) {
+	start := time.Date(2022, 6, 4, 10, 0, 0, 0, time.UTC) // Saturday
+	cron, err := util.ParseCronExpression("*/15 8-18 * * mon-fri")
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2022, 6, 6, 8, 0, 0, 0, time.UTC), cron.Next(start))
+	cron, err = util.ParseCronExpression("5/20 * * * *")
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2022, 6, 4, 10, 5, 0, 0, time.UTC), cron.Next(start))
+	assert.Equal(t, time.Date(2022, 6, 4, 11, 5, 0, 0, time.UTC), cron.Next(start.Add(45*time.Minute)))
+	cron, err = util.ParseCronExpression("@hourly")
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2022, 6, 4, 11, 0, 0, 0, time.UTC), cron.Next(start.Add(30*time.Minute)))
+	// the next activation is strictly after the given time
+	assert.Equal(t, time.Date(2022, 6, 4, 11, 0, 0, 0, time.UTC), cron.Next(start))
+	cron, err = util.ParseCronExpression("0 0 * * 7")
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2022, 6, 5, 0, 0, 0, 0, time.UTC), cron.Next(start))
+	// day of month or day of week
+	cron, err = util.ParseCronExpression("0 0 1,15 * fri")
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2022, 6, 3, 0, 0, 0, 0, time.UTC), cron.Next(time.Date(2022, 6, 1, 0, 0, 0, 0, time.UTC)))
+	assert.Equal(t, time.Date(2022, 6, 15, 0, 0, 0, 0, time.UTC), cron.Next(time.Date(2022, 6, 14, 0, 0, 0, 0, time.UTC)))
+	cron, err = util.ParseCronExpression("30 2 29 feb *")
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2024, 2, 29, 2, 30, 0, 0, time.UTC), cron.Next(start))
+	cron, err = util.ParseCronExpression("0 0 31 2 *")
+	require.NoError(t, err)
+	assert.True(t, cron.Next(start).IsZero())
+
+	for _, expr := range []string{"", "* * * *", "* * * * * *", "a * * * *", "60 * * * *", "5-1 * * * *",
+		"*/0 * * * *", "1,,2 * * * *", "* * * 13 *", "* * 0 * *", "* * * * 8", "@every"} {
+		_, err = util.ParseCronExpression(expr)
+		assert.Error(t, err, "expression %#v must fail", expr)
+	}
+}
+
+func TestSchedulerCheck(t *testing.T) {
+	schedule := dataprovider.Schedule{
+		Name:           "internal_schedule",
+		Status:         dataprovider.ScheduleStatusEnabled,
+		CronExpression: "* * * * *",
+		Task:           dataprovider.ScheduleTaskBackup,
+	}
+	err := dataprovider.AddSchedule(&schedule, "", "")
+	require.NoError(t, err)
+	schedule, err = dataprovider.ScheduleExists(schedule.Name)
+	require.NoError(t, err)
+
+	now := time.Now().UTC()
+	s := newScheduler()
+	s.setLastCheck(now.Add(-2 * time.Minute))
+	s.checkSchedules(now)
+	assert.Eventually(t, func() bool {
+		s.Lock()
+		defer s.Unlock()
+		return len(s.running) == 0
+	}, 2*time.Second, 50*time.Millisecond)
+	schedule, err = dataprovider.ScheduleExists(schedule.Name)
+	require.NoError(t, err)
+	assert.Equal(t, dataprovider.ScheduleRunStatusSuccess, schedule.LastRunStatus, schedule.LastRunError)
+	runAt := util.GetTimeFromMsecSinceEpoch(schedule.LastRun)
+	assert.True(t, runAt.Before(now))
+	// the same activation time cannot be executed twice
+	started, err := s.startSchedule(schedule, runAt)
+	assert.NoError(t, err)
+	assert.False(t, started)
+	// nothing to do, the activation time is already checked
+	s.checkSchedules(now)
+	schedule, err = dataprovider.ScheduleExists(schedule.Name)
+	require.NoError(t, err)
+	assert.Equal(t, runAt, util.GetTimeFromMsecSinceEpoch(schedule.LastRun))
+
+	assert.True(t, s.addRunning(schedule.Name))
+	_, err = s.startSchedule(schedule, time.Now())
+	assert.ErrorIs(t, err, errScheduleRunning)
+	s.removeRunning(schedule.Name)
+
+	backups, err := filepath.Glob(filepath.Join(backupsPath, "backup_internal_schedule_*.json"))
+	assert.NoError(t, err)
+	assert.Len(t, backups, 1)
+	for _, b := range backups {
+		err = os.Remove(b)
+		assert.NoError(t, err)
+	}
+
+	oldBackupsPath := backupsPath
+	backupsPath = ""
+	err = executeScheduledTask(&schedule, now)
+	assert.Error(t, err)
+	backupsPath = oldBackupsPath
+
+	schedule.Task = 100
+	err = executeScheduledTask(&schedule, now)
+	assert.Error(t, err)
+	// all the users and folders
+	schedule.Task = dataprovider.ScheduleTaskQuotaScan
+	schedule.Options = dataprovider.ScheduleOptions{
+		AllUsers:   true,
+		AllFolders: true,
+	}
+	err = executeScheduledTask(&schedule, now)
+	assert.NoError(t, err)
+	schedule.Task = dataprovider.ScheduleTaskRetentionCheck
+	schedule.Options = dataprovider.ScheduleOptions{
+		Usernames: []string{"missing_user"},
+		Retention: []sdk.DataRetentionPolicy{
+			{
+				Path:      "/",
+				Retention: 1,
+			},
+		},
+	}
+	err = executeScheduledTask(&schedule, now)
+	assert.Error(t, err)
+
+	err = dataprovider.DeleteSchedule(schedule.Name, "", "")
+	assert.NoError(t, err)
+}
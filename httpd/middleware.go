@@ -34,7 +34,7 @@ func (k *contextKey) String() string {
 }
 
 func validateJWTToken(w http.ResponseWriter, r *http.Request, audience tokenAudience) error {
-	token, _, err := jwtauth.FromContext(r.Context())
+	token, claims, err := jwtauth.FromContext(r.Context())
 
 	var redirectPath string
 	if audience == tokenAudienceWebAdmin {
@@ -87,7 +87,8 @@ func validateJWTToken(w http.ResponseWriter, r *http.Request, audience tokenAudi
 		}
 		return errInvalidToken
 	}
-	return nil
+	// a user that must change the password can only change it or logout
+	return checkPasswordChangeAuth(w, r, audience, claims)
 }
 
 func validateJWTPartialToken(w http.ResponseWriter, r *http.Request, audience tokenAudience) error {
@@ -397,7 +398,7 @@ func authenticateUserWithAPIKey(username, keyID string, tokenAuth *jwtauth.JWTAu
 	if err := common.Config.ExecutePostConnectHook(ipAddr, common.ProtocolHTTP); err != nil {
 		return err
 	}
-	user, err := dataprovider.UserExists(username)
+	user, err := dataprovider.GetUserWithGroupSettings(username)
 	if err != nil {
 		updateLoginMetrics(&dataprovider.User{BaseUser: sdk.BaseUser{Username: username}}, ipAddr, err)
 		return err
@@ -456,3 +457,36 @@ func checkPartialAuth(w http.ResponseWriter, r *http.Request, audience string, t
 	}
 	return nil
 }
+
+func checkPasswordChangeAuth(w http.ResponseWriter, r *http.Request, audience tokenAudience, claims map[string]interface{}) error {
+	tokenClaims := jwtTokenClaims{}
+	tokenClaims.Decode(claims)
+	if !tokenClaims.MustChangePassword {
+		return nil
+	}
+	var allowedPaths []string
+	var redirectPath string
+	switch audience {
+	case tokenAudienceWebAdmin:
+		allowedPaths = []string{webChangeAdminPwdPath, webLogoutPath}
+		redirectPath = webChangeAdminPwdPath
+	case tokenAudienceWebClient:
+		allowedPaths = []string{webChangeClientPwdPath, webClientLogoutPath}
+		redirectPath = webChangeClientPwdPath
+	case tokenAudienceAPI:
+		allowedPaths = []string{adminPwdPath, adminPwdCompatPath, logoutPath}
+	default:
+		allowedPaths = []string{userPwdPath, userLogoutPath}
+	}
+	if util.IsStringInSlice(strings.TrimSuffix(r.URL.Path, "/"), allowedPaths) {
+		return nil
+	}
+	logger.Debug(logSender, "", "password change required for %#v, request to %#v denied", tokenClaims.Username,
+		r.URL.Path)
+	if redirectPath != "" {
+		http.Redirect(w, r, redirectPath, http.StatusFound)
+	} else {
+		sendAPIResponse(w, r, nil, "Password change required", http.StatusForbidden)
+	}
+	return errInvalidToken
+}
@@ -0,0 +1,311 @@
+package httpd
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/drakkan/sftpgo/v2/common"
+	"github.com/drakkan/sftpgo/v2/dataprovider"
+	"github.com/drakkan/sftpgo/v2/logger"
+	"github.com/drakkan/sftpgo/v2/smtp"
+	"github.com/drakkan/sftpgo/v2/util"
+)
+
+const resetCodeLength = 10
+
+var (
+	errInvalidResetCode = util.NewValidationError("the reset code is invalid or expired")
+	resetCodeDuration   = 10 * time.Minute
+)
+
+type resetCodeEmailData struct {
+	Username  string
+	Code      string
+	ExpiresIn int
+}
+
+// getPasswordFingerprint returns a fingerprint for the stored password hash.
+// The fingerprint is stored with the reset code so a code can be used
+// only once and it is invalidated by any other password change
+func getPasswordFingerprint(username, hashedPassword string) string {
+	h := sha256.Sum256([]byte(username + ":" + hashedPassword))
+	return hex.EncodeToString(h[:])
+}
+
+// getResetCodeHash returns the hash used to store the reset code, the code
+// sent via email is never stored in plain text
+func getResetCodeHash(code string) string {
+	h := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(h[:])
+}
+
+func getResetCodeAccountType(isAdmin bool) string {
+	if isAdmin {
+		return dataprovider.AccountTypeAdmin
+	}
+	return dataprovider.AccountTypeUser
+}
+
+// createResetCode generates a random reset code and stores its hash within the
+// data provider, so the code can be verified by any instance and after a restart
+func createResetCode(username, hashedPassword string, isAdmin bool) (string, error) {
+	code := base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(util.GenerateRandomBytes(resetCodeLength))
+	now := time.Now()
+	resetCode := &dataprovider.ResetCode{
+		Code:                getResetCodeHash(code),
+		Username:            username,
+		Type:                getResetCodeAccountType(isAdmin),
+		PasswordFingerprint: getPasswordFingerprint(username, hashedPassword),
+		ExpiresAt:           util.GetTimeAsMsSinceEpoch(now.Add(resetCodeDuration)),
+		CreatedAt:           util.GetTimeAsMsSinceEpoch(now),
+	}
+	if err := dataprovider.AddResetCode(resetCode); err != nil {
+		return "", err
+	}
+	return code, nil
+}
+
+// verifyResetCode checks that the reset code exists, it is not expired and it
+// was issued for the requested account type
+func verifyResetCode(code string, isAdmin bool) (dataprovider.ResetCode, error) {
+	resetCode, err := dataprovider.GetResetCode(getResetCodeHash(code))
+	if err != nil {
+		logger.Debug(logSender, "", "error validating reset code: %v", err)
+		return resetCode, errInvalidResetCode
+	}
+	if resetCode.IsExpired(time.Now()) {
+		logger.Debug(logSender, "", "reset code for %#v expired", resetCode.Username)
+		return resetCode, errInvalidResetCode
+	}
+	if resetCode.Type != getResetCodeAccountType(isAdmin) {
+		logger.Debug(logSender, "", "error validating reset code account type")
+		return resetCode, errInvalidResetCode
+	}
+	return resetCode, nil
+}
+
+// deleteResetCode removes a used reset code
+func deleteResetCode(resetCode *dataprovider.ResetCode) {
+	if err := dataprovider.DeleteResetCode(resetCode.Code); err != nil {
+		logger.Warn(logSender, "", "unable to delete reset code for %#v: %v", resetCode.Username, err)
+	}
+}
+
+func isPasswordFingerprintValid(username, hashedPassword, fingerprint string) bool {
+	expected := getPasswordFingerprint(username, hashedPassword)
+	return subtle.ConstantTimeCompare([]byte(expected), []byte(fingerprint)) == 1
+}
+
+// cleanupExpiredResetCodes removes the expired reset codes from the data provider
+func cleanupExpiredResetCodes() {
+	if err := dataprovider.DeleteExpiredResetCodes(time.Now()); err != nil {
+		logger.Warn(logSender, "", "unable to remove expired reset codes: %v", err)
+	}
+}
+
+func sendResetCode(username, email, hashedPassword string, isAdmin bool) error {
+	code, err := createResetCode(username, hashedPassword, isAdmin)
+	if err != nil {
+		logger.Warn(logSender, "", "unable to create reset code for %#v: %v", username, err)
+		return err
+	}
+	data := resetCodeEmailData{
+		Username:  username,
+		Code:      code,
+		ExpiresIn: int(resetCodeDuration / time.Minute),
+	}
+	body := new(bytes.Buffer)
+	if err := smtp.RenderPasswordResetTemplate(body, data); err != nil {
+		logger.Warn(logSender, "", "unable to render password reset template: %v", err)
+		return err
+	}
+	subject := fmt.Sprintf("SFTPGo password reset code for %#v", username)
+	if err := smtp.SendEmail(email, subject, body.String(), smtp.EmailContentTypeTextHTML); err != nil {
+		logger.Warn(logSender, "", "unable to send password reset code to %#v: %v", username, err)
+		return err
+	}
+	logger.Info(logSender, "", "password reset code sent to %#v, email: %#v", username, email)
+	return nil
+}
+
+// handleForgotPassword sends a password reset code to the email address of the
+// specified account. No error is returned if the account does not exist, does not
+// have an email address or is not allowed to reset its password, so the response
+// does not disclose if an account exists
+func handleForgotPassword(r *http.Request, username string, isAdmin bool) error {
+	if !smtp.IsEnabled() {
+		return util.NewValidationError("no SMTP server configured, unable to send the reset code")
+	}
+	if username == "" {
+		return util.NewValidationError("username is mandatory")
+	}
+	ipAddr := util.GetIPFromRemoteAddress(r.RemoteAddr)
+	if isAdmin {
+		admin, err := dataprovider.AdminExists(username)
+		if err != nil {
+			logger.Debug(logSender, "", "password reset requested for admin %#v from ip %v: %v", username, ipAddr, err)
+			return ignoreRecordNotFoundError(addForgotPasswordDefenderEvent(ipAddr, err))
+		}
+		if admin.Email == "" || admin.Status != 1 {
+			logger.Debug(logSender, "", "password reset not allowed for admin %#v, disabled or without email", username)
+			return nil
+		}
+		if !isResetCodeRequestAllowed(admin.Username, isAdmin, ipAddr) {
+			return nil
+		}
+		return sendResetCode(admin.Username, admin.Email, admin.Password, isAdmin)
+	}
+	user, err := dataprovider.GetUserWithGroupSettings(username)
+	if err != nil {
+		logger.Debug(logSender, "", "password reset requested for user %#v from ip %v: %v", username, ipAddr, err)
+		return ignoreRecordNotFoundError(addForgotPasswordDefenderEvent(ipAddr, err))
+	}
+	if user.Email == "" || user.Status != 1 || !user.CanResetPassword() {
+		logger.Debug(logSender, "", "password reset not allowed for user %#v, disabled, without email or restricted",
+			username)
+		return nil
+	}
+	if !isResetCodeRequestAllowed(user.Username, isAdmin, ipAddr) {
+		return nil
+	}
+	return sendResetCode(user.Username, user.Email, user.Password, isAdmin)
+}
+
+// addForgotPasswordDefenderEvent adds a defender event if a reset code is requested
+// for a missing account, the given error is returned unchanged
+func addForgotPasswordDefenderEvent(ipAddr string, err error) error {
+	if _, ok := err.(*util.RecordNotFoundError); ok {
+		common.AddDefenderEvent(ipAddr, common.HostEventUserNotFound)
+	}
+	return err
+}
+
+// isResetCodeRequestAllowed returns false if a valid reset code was already sent
+// for the specified account, this way repeated requests cannot flood the mailbox
+func isResetCodeRequestAllowed(username string, isAdmin bool, ipAddr string) bool {
+	accountType := getResetCodeAccountType(isAdmin)
+	count, err := dataprovider.GetPendingResetCodesCount(username, accountType)
+	if err != nil {
+		logger.Warn(logSender, "", "unable to get pending reset codes for %v %#v: %v", accountType, username, err)
+		return false
+	}
+	if count > 0 {
+		logger.Debug(logSender, "", "reset code already sent to %v %#v, new code requested from ip %v", accountType,
+			username, ipAddr)
+		common.AddDefenderEvent(ipAddr, common.HostEventLimitExceeded)
+		return false
+	}
+	return true
+}
+
+// handleResetAdminPassword sets the new password for the admin identified by the reset code.
+// If username is not empty it must match the one the code was issued for
+func handleResetAdminPassword(r *http.Request, username, code, newPassword string) (*dataprovider.Admin, error) {
+	ipAddr := util.GetIPFromRemoteAddress(r.RemoteAddr)
+	if code == "" || newPassword == "" {
+		return nil, util.NewValidationError("please provide the reset code and the new password")
+	}
+	resetCode, err := verifyResetCode(code, true)
+	if err == nil && username != "" && username != resetCode.Username {
+		err = errInvalidResetCode
+	}
+	if err != nil {
+		common.AddDefenderEvent(ipAddr, common.HostEventLoginFailed)
+		return nil, err
+	}
+	admin, err := dataprovider.AdminExists(resetCode.Username)
+	if err != nil {
+		common.AddDefenderEvent(ipAddr, common.HostEventLoginFailed)
+		return nil, errInvalidResetCode
+	}
+	if !isPasswordFingerprintValid(admin.Username, admin.Password, resetCode.PasswordFingerprint) {
+		common.AddDefenderEvent(ipAddr, common.HostEventLoginFailed)
+		return nil, errInvalidResetCode
+	}
+	if admin.Status != 1 {
+		return nil, util.NewValidationError("password reset is not allowed for a disabled account")
+	}
+	admin.Password = newPassword
+	admin.Filters.RequirePasswordChange = false
+	if err := dataprovider.UpdateAdmin(&admin, dataprovider.ActionExecutorSelf, ipAddr); err != nil {
+		return nil, err
+	}
+	deleteResetCode(&resetCode)
+	resetAccountLockout(admin.Username, dataprovider.AccountTypeAdmin)
+	logger.Info(logSender, "", "password reset completed for admin %#v, ip: %v", admin.Username, ipAddr)
+	return &admin, nil
+}
+
+// handleResetUserPassword sets the new password for the user identified by the reset code.
+// If username is not empty it must match the one the code was issued for
+func handleResetUserPassword(r *http.Request, username, code, newPassword string) (*dataprovider.User, error) {
+	ipAddr := util.GetIPFromRemoteAddress(r.RemoteAddr)
+	if code == "" || newPassword == "" {
+		return nil, util.NewValidationError("please provide the reset code and the new password")
+	}
+	resetCode, err := verifyResetCode(code, false)
+	if err == nil && username != "" && username != resetCode.Username {
+		err = errInvalidResetCode
+	}
+	if err != nil {
+		common.AddDefenderEvent(ipAddr, common.HostEventLoginFailed)
+		return nil, err
+	}
+	user, err := dataprovider.GetUserWithGroupSettings(resetCode.Username)
+	if err != nil {
+		common.AddDefenderEvent(ipAddr, common.HostEventLoginFailed)
+		return nil, errInvalidResetCode
+	}
+	if !isPasswordFingerprintValid(user.Username, user.Password, resetCode.PasswordFingerprint) {
+		common.AddDefenderEvent(ipAddr, common.HostEventLoginFailed)
+		return nil, errInvalidResetCode
+	}
+	if user.Status != 1 {
+		return nil, util.NewValidationError("password reset is not allowed for a disabled account")
+	}
+	if !user.CanResetPassword() {
+		return nil, util.NewValidationError("password reset is not allowed for this account")
+	}
+	// the checked user has the group settings applied, we need the stored one to update it
+	user, err = dataprovider.UserExists(resetCode.Username)
+	if err != nil {
+		return nil, err
+	}
+	user.Password = newPassword
+	user.Filters.RequirePasswordChange = false
+	if err := dataprovider.UpdateUser(&user, dataprovider.ActionExecutorSelf, ipAddr); err != nil {
+		return nil, err
+	}
+	deleteResetCode(&resetCode)
+	resetAccountLockout(user.Username, dataprovider.AccountTypeUser)
+	logger.Info(logSender, "", "password reset completed for user %#v, ip: %v", user.Username, ipAddr)
+	return &user, nil
+}
+
+// resetAccountLockout unlocks an account after a successful password reset
+func resetAccountLockout(username, accountType string) {
+	if !dataprovider.IsAccountLockoutEnabled() {
+		return
+	}
+	err := dataprovider.DeleteAccountLockout(username, accountType)
+	if err != nil {
+		if _, ok := err.(*util.RecordNotFoundError); !ok {
+			logger.Warn(logSender, "", "unable to reset failed logins for %v %#v: %v", accountType, username, err)
+		}
+	}
+}
+
+func ignoreRecordNotFoundError(err error) error {
+	var notFoundErr *util.RecordNotFoundError
+	if errors.As(err, &notFoundErr) {
+		return nil
+	}
+	return err
+}
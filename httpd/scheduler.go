@@ -0,0 +1,350 @@
+package httpd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/drakkan/sftpgo/v2/common"
+	"github.com/drakkan/sftpgo/v2/dataprovider"
+	"github.com/drakkan/sftpgo/v2/logger"
+	"github.com/drakkan/sftpgo/v2/sdk"
+	"github.com/drakkan/sftpgo/v2/util"
+)
+
+const (
+	scheduleCheckInterval = 1 * time.Minute
+	scheduleListPageSize  = 100
+)
+
+var (
+	taskScheduler      = newScheduler()
+	schedulerTicker    *time.Ticker
+	schedulerDone      chan bool
+	errScheduleRunning = errors.New("the schedule is already running")
+)
+
+// scheduler executes the tasks defined in the data provider schedules.
+// The schedules are reloaded from the data provider on each check, so changes
+// made from other instances sharing the same data provider are picked up
+// automatically. Before executing a task the run is claimed within the data
+// provider, this way a task is executed only once even if multiple instances
+// share the same data provider
+type scheduler struct {
+	sync.Mutex
+	lastCheck time.Time
+	running   map[string]bool
+}
+
+func newScheduler() *scheduler {
+	return &scheduler{
+		running: make(map[string]bool),
+	}
+}
+
+func startSchedulerTicker() {
+	stopSchedulerTicker()
+	taskScheduler.setLastCheck(time.Now().UTC())
+	schedulerTicker = time.NewTicker(scheduleCheckInterval)
+	schedulerDone = make(chan bool)
+
+	go func() {
+		for {
+			select {
+			case <-schedulerDone:
+				return
+			case t := <-schedulerTicker.C:
+				taskScheduler.checkSchedules(t.UTC())
+			}
+		}
+	}()
+}
+
+func stopSchedulerTicker() {
+	if schedulerTicker != nil {
+		schedulerTicker.Stop()
+		schedulerDone <- true
+		schedulerTicker = nil
+	}
+}
+
+func (s *scheduler) setLastCheck(t time.Time) {
+	s.Lock()
+	defer s.Unlock()
+
+	s.lastCheck = t
+}
+
+func (s *scheduler) addRunning(name string) bool {
+	s.Lock()
+	defer s.Unlock()
+
+	if s.running[name] {
+		return false
+	}
+	s.running[name] = true
+	return true
+}
+
+func (s *scheduler) removeRunning(name string) {
+	s.Lock()
+	defer s.Unlock()
+
+	delete(s.running, name)
+}
+
+// checkSchedules starts the enabled schedules with an activation time
+// after the previous check and not after the given time
+func (s *scheduler) checkSchedules(now time.Time) {
+	s.Lock()
+	lastCheck := s.lastCheck
+	s.lastCheck = now
+	s.Unlock()
+
+	schedules, err := dataprovider.DumpSchedules()
+	if err != nil {
+		logger.Warn(logSender, "", "unable to get schedules: %v", err)
+		return
+	}
+	for idx := range schedules {
+		schedule := schedules[idx]
+		nextRun := schedule.GetNextRun(lastCheck)
+		if nextRun.IsZero() || nextRun.After(now) {
+			continue
+		}
+		if _, err := s.startSchedule(schedule, nextRun); err != nil {
+			logger.Warn(logSender, "", "unable to start schedule %#v, activation time %v: %v", schedule.Name,
+				nextRun, err)
+		}
+	}
+}
+
+// startSchedule claims the run for the specified activation time and executes
+// the schedule task in background. It returns false if the run was already
+// claimed by another instance
+func (s *scheduler) startSchedule(schedule dataprovider.Schedule, runAt time.Time) (bool, error) {
+	if !s.addRunning(schedule.Name) {
+		return false, errScheduleRunning
+	}
+	claimed, err := dataprovider.ClaimScheduleRun(schedule.Name, runAt)
+	if err != nil || !claimed {
+		s.removeRunning(schedule.Name)
+		if err == nil {
+			logger.Debug(logSender, "", "schedule %#v, activation time %v, already executed", schedule.Name, runAt)
+		}
+		return false, err
+	}
+
+	go func() {
+		defer s.removeRunning(schedule.Name)
+
+		logger.Info(logSender, "", "executing schedule %#v, task %#v", schedule.Name, schedule.Task.GetTaskAsString())
+		startTime := time.Now()
+		errTask := executeScheduledTask(&schedule, runAt)
+		logger.Info(logSender, "", "schedule %#v executed, elapsed: %v, error: %v", schedule.Name,
+			time.Since(startTime), errTask)
+		if err := dataprovider.SetScheduleRunResult(schedule.Name, errTask); err != nil {
+			logger.Warn(logSender, "", "unable to save the run result for schedule %#v: %v", schedule.Name, err)
+		}
+	}()
+	return true, nil
+}
+
+func executeScheduledTask(schedule *dataprovider.Schedule, runAt time.Time) error {
+	switch schedule.Task {
+	case dataprovider.ScheduleTaskQuotaScan:
+		return executeScheduledQuotaScan(&schedule.Options)
+	case dataprovider.ScheduleTaskRetentionCheck:
+		return executeScheduledRetentionCheck(&schedule.Options)
+	case dataprovider.ScheduleTaskBackup:
+		return executeScheduledBackup(schedule.Name, runAt)
+	default:
+		return fmt.Errorf("unsupported schedule task: %v", schedule.Task)
+	}
+}
+
+func getScheduledUsernames(options *dataprovider.ScheduleOptions) ([]string, error) {
+	if !options.AllUsers {
+		return options.Usernames, nil
+	}
+	var usernames []string
+	for offset := 0; ; offset += scheduleListPageSize {
+		users, err := dataprovider.GetUsers(scheduleListPageSize, offset, dataprovider.OrderASC)
+		if err != nil {
+			return nil, err
+		}
+		for _, user := range users {
+			usernames = append(usernames, user.Username)
+		}
+		if len(users) < scheduleListPageSize {
+			return usernames, nil
+		}
+	}
+}
+
+func getScheduledFolderNames(options *dataprovider.ScheduleOptions) ([]string, error) {
+	if !options.AllFolders {
+		return options.FolderNames, nil
+	}
+	var names []string
+	for offset := 0; ; offset += scheduleListPageSize {
+		folders, err := dataprovider.GetFolders(scheduleListPageSize, offset, dataprovider.OrderASC)
+		if err != nil {
+			return nil, err
+		}
+		for _, folder := range folders {
+			names = append(names, folder.Name)
+		}
+		if len(folders) < scheduleListPageSize {
+			return names, nil
+		}
+	}
+}
+
+func getScheduledTaskError(errs []string) error {
+	if len(errs) == 0 {
+		return nil
+	}
+	return errors.New(strings.Join(errs, "; "))
+}
+
+func executeScheduledQuotaScan(options *dataprovider.ScheduleOptions) error {
+	if dataprovider.GetQuotaTracking() == 0 {
+		return errors.New("quota tracking is disabled")
+	}
+	usernames, err := getScheduledUsernames(options)
+	if err != nil {
+		return err
+	}
+	folderNames, err := getScheduledFolderNames(options)
+	if err != nil {
+		return err
+	}
+	var errs []string
+	for _, username := range usernames {
+		user, err := dataprovider.GetUserWithGroupSettings(username)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("user %#v: %v", username, err))
+			continue
+		}
+		if !common.QuotaScans.AddUserQuotaScan(user.Username) {
+			errs = append(errs, fmt.Sprintf("user %#v: another scan is already in progress", username))
+			continue
+		}
+		if err := doUserQuotaScan(user); err != nil {
+			errs = append(errs, fmt.Sprintf("user %#v: %v", username, err))
+		}
+	}
+	for _, name := range folderNames {
+		folder, err := dataprovider.GetFolderByName(name)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("folder %#v: %v", name, err))
+			continue
+		}
+		if !common.QuotaScans.AddVFolderQuotaScan(folder.Name) {
+			errs = append(errs, fmt.Sprintf("folder %#v: another scan is already in progress", name))
+			continue
+		}
+		if err := doFolderQuotaScan(folder); err != nil {
+			errs = append(errs, fmt.Sprintf("folder %#v: %v", name, err))
+		}
+	}
+	return getScheduledTaskError(errs)
+}
+
+func executeScheduledRetentionCheck(options *dataprovider.ScheduleOptions) error {
+	usernames, err := getScheduledUsernames(options)
+	if err != nil {
+		return err
+	}
+	var errs []string
+	for _, username := range usernames {
+		user, err := dataprovider.GetUserWithGroupSettings(username)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("user %#v: %v", username, err))
+			continue
+		}
+		retention := options.Retention
+		if len(retention) == 0 {
+			retention = user.GetDataRetention()
+			if !hasDataRetentionToApply(retention) {
+				logger.Debug(logSender, "", "no data retention policy defined for user %#v, retention check skipped",
+					username)
+				continue
+			}
+		}
+		check := common.RetentionCheck{
+			Folders:       getFoldersRetention(retention),
+			Notifications: options.Notifications,
+		}
+		if util.IsStringInSlice(common.RetentionCheckNotificationEmail, check.Notifications) {
+			check.Email = options.Email
+			if check.Email == "" {
+				check.Email = user.Email
+			}
+		}
+		if err := check.Validate(); err != nil {
+			errs = append(errs, fmt.Sprintf("user %#v: %v", username, err))
+			continue
+		}
+		c := common.RetentionChecks.Add(check, &user)
+		if c == nil {
+			errs = append(errs, fmt.Sprintf("user %#v: another check is already in progress", username))
+			continue
+		}
+		if err := c.Start(); err != nil {
+			errs = append(errs, fmt.Sprintf("user %#v: %v", username, err))
+		}
+	}
+	return getScheduledTaskError(errs)
+}
+
+func hasDataRetentionToApply(retention []sdk.DataRetentionPolicy) bool {
+	for _, r := range retention {
+		if r.Retention > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func getFoldersRetention(retention []sdk.DataRetentionPolicy) []common.FolderRetention {
+	folders := make([]common.FolderRetention, 0, len(retention))
+	for _, r := range retention {
+		folders = append(folders, common.FolderRetention{
+			Path:                  r.Path,
+			Retention:             r.Retention,
+			DeleteEmptyDirs:       r.DeleteEmptyDirs,
+			IgnoreUserPermissions: r.IgnoreUserPermissions,
+		})
+	}
+	return folders
+}
+
+func executeScheduledBackup(name string, runAt time.Time) error {
+	if backupsPath == "" {
+		return errors.New("backups path is not configured")
+	}
+	outputFile := filepath.Join(backupsPath, fmt.Sprintf("backup_%s_%s.json", name, runAt.Format("20060102T150405")))
+	if err := os.MkdirAll(backupsPath, 0700); err != nil {
+		return err
+	}
+	backup, err := dataprovider.DumpData()
+	if err != nil {
+		return err
+	}
+	dump, err := json.Marshal(backup)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(outputFile, dump, 0600); err != nil {
+		return err
+	}
+	logger.Debug(logSender, "", "scheduled backup completed, output file: %#v", outputFile)
+	return nil
+}
@@ -23,6 +23,7 @@ import (
 	"github.com/drakkan/sftpgo/v2/logger"
 	"github.com/drakkan/sftpgo/v2/mfa"
 	"github.com/drakkan/sftpgo/v2/sdk"
+	"github.com/drakkan/sftpgo/v2/smtp"
 	"github.com/drakkan/sftpgo/v2/util"
 	"github.com/drakkan/sftpgo/v2/version"
 )
@@ -128,6 +129,9 @@ func (s *httpdServer) renderClientLoginPage(w http.ResponseWriter, error string)
 	if s.binding.showAdminLoginURL() {
 		data.AltLoginURL = webLoginPath
 	}
+	if smtp.IsEnabled() {
+		data.ForgotPwdURL = webClientForgotPwdPath
+	}
 	renderClientTemplate(w, templateClientLogin, data)
 }
 
@@ -160,7 +164,11 @@ func (s *httpdServer) handleWebClientLoginPost(w http.ResponseWriter, r *http.Re
 		s.renderClientLoginPage(w, err.Error())
 		return
 	}
+	s.doWebClientLogin(w, r, username, password)
+}
 
+func (s *httpdServer) doWebClientLogin(w http.ResponseWriter, r *http.Request, username, password string) {
+	ipAddr := util.GetIPFromRemoteAddress(r.RemoteAddr)
 	if err := common.Config.ExecutePostConnectHook(ipAddr, common.ProtocolHTTP); err != nil {
 		s.renderClientLoginPage(w, fmt.Sprintf("access denied by post connect hook: %v", err))
 		return
@@ -237,6 +245,10 @@ func (s *httpdServer) handleWebClientTwoFactorRecoveryPost(w http.ResponseWriter
 				renderClientInternalServerErrorPage(w, r, errors.New("unable to set the recovery code as used"))
 				return
 			}
+			if err := user.LoadAndApplyGroupSettings(); err != nil {
+				renderClientInternalServerErrorPage(w, r, err)
+				return
+			}
 			connectionID := fmt.Sprintf("%v_%v", common.ProtocolHTTP, xid.New().String())
 			s.loginUser(w, r, &user, connectionID, util.GetIPFromRemoteAddress(r.RemoteAddr), true,
 				renderClientTwoFactorRecoveryPage)
@@ -267,7 +279,7 @@ func (s *httpdServer) handleWebClientTwoFactorPost(w http.ResponseWriter, r *htt
 		renderClientTwoFactorPage(w, err.Error())
 		return
 	}
-	user, err := dataprovider.UserExists(username)
+	user, err := dataprovider.GetUserWithGroupSettings(username)
 	if err != nil {
 		renderClientTwoFactorPage(w, "Invalid credentials")
 		return
@@ -405,6 +417,10 @@ func (s *httpdServer) handleWebAdminLoginPost(w http.ResponseWriter, r *http.Req
 		s.renderAdminLoginPage(w, err.Error())
 		return
 	}
+	s.doWebAdminLogin(w, r, username, password)
+}
+
+func (s *httpdServer) doWebAdminLogin(w http.ResponseWriter, r *http.Request, username, password string) {
 	admin, err := dataprovider.CheckAdminAndPass(username, password, util.GetIPFromRemoteAddress(r.RemoteAddr))
 	if err != nil {
 		s.renderAdminLoginPage(w, err.Error())
@@ -424,6 +440,9 @@ func (s *httpdServer) renderAdminLoginPage(w http.ResponseWriter, error string)
 	if s.binding.showClientLoginURL() {
 		data.AltLoginURL = webClientLoginPath
 	}
+	if smtp.IsEnabled() {
+		data.ForgotPwdURL = webAdminForgotPwdPath
+	}
 	renderAdminTemplate(w, templateLogin, data)
 }
 
@@ -436,6 +455,122 @@ func (s *httpdServer) handleWebAdminLogin(w http.ResponseWriter, r *http.Request
 	s.renderAdminLoginPage(w, "")
 }
 
+func handleWebAdminForgotPwd(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxLoginBodySize)
+	if !smtp.IsEnabled() {
+		renderNotFoundPage(w, r, errors.New("this page does not exist"))
+		return
+	}
+	renderForgotPwdPage(w, "")
+}
+
+func handleWebAdminForgotPwdPost(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxLoginBodySize)
+	if err := r.ParseForm(); err != nil {
+		renderForgotPwdPage(w, err.Error())
+		return
+	}
+	if err := verifyCSRFToken(r.Form.Get(csrfFormToken)); err != nil {
+		renderForbiddenPage(w, r, err.Error())
+		return
+	}
+	if err := handleForgotPassword(r, r.Form.Get("username"), true); err != nil {
+		renderForgotPwdPage(w, err.Error())
+		return
+	}
+	http.Redirect(w, r, webAdminResetPwdPath, http.StatusFound)
+}
+
+func handleWebAdminResetPwd(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxLoginBodySize)
+	if !smtp.IsEnabled() {
+		renderNotFoundPage(w, r, errors.New("this page does not exist"))
+		return
+	}
+	renderResetPwdPage(w, "")
+}
+
+func (s *httpdServer) handleWebAdminResetPwdPost(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxLoginBodySize)
+	if err := r.ParseForm(); err != nil {
+		renderResetPwdPage(w, err.Error())
+		return
+	}
+	if err := verifyCSRFToken(r.Form.Get(csrfFormToken)); err != nil {
+		renderForbiddenPage(w, r, err.Error())
+		return
+	}
+	password := r.Form.Get("password")
+	if password != r.Form.Get("confirm_password") {
+		renderResetPwdPage(w, "The two password fields do not match")
+		return
+	}
+	admin, err := handleResetAdminPassword(r, "", r.Form.Get("code"), password)
+	if err != nil {
+		renderResetPwdPage(w, err.Error())
+		return
+	}
+	s.doWebAdminLogin(w, r, admin.Username, password)
+}
+
+func handleWebClientForgotPwd(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxLoginBodySize)
+	if !smtp.IsEnabled() {
+		renderClientNotFoundPage(w, r, errors.New("this page does not exist"))
+		return
+	}
+	renderClientForgotPwdPage(w, "")
+}
+
+func handleWebClientForgotPwdPost(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxLoginBodySize)
+	if err := r.ParseForm(); err != nil {
+		renderClientForgotPwdPage(w, err.Error())
+		return
+	}
+	if err := verifyCSRFToken(r.Form.Get(csrfFormToken)); err != nil {
+		renderClientForbiddenPage(w, r, err.Error())
+		return
+	}
+	if err := handleForgotPassword(r, r.Form.Get("username"), false); err != nil {
+		renderClientForgotPwdPage(w, err.Error())
+		return
+	}
+	http.Redirect(w, r, webClientResetPwdPath, http.StatusFound)
+}
+
+func handleWebClientResetPwd(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxLoginBodySize)
+	if !smtp.IsEnabled() {
+		renderClientNotFoundPage(w, r, errors.New("this page does not exist"))
+		return
+	}
+	renderClientResetPwdPage(w, "")
+}
+
+func (s *httpdServer) handleWebClientResetPwdPost(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxLoginBodySize)
+	if err := r.ParseForm(); err != nil {
+		renderClientResetPwdPage(w, err.Error())
+		return
+	}
+	if err := verifyCSRFToken(r.Form.Get(csrfFormToken)); err != nil {
+		renderClientForbiddenPage(w, r, err.Error())
+		return
+	}
+	password := r.Form.Get("password")
+	if password != r.Form.Get("confirm_password") {
+		renderClientResetPwdPage(w, "The two password fields do not match")
+		return
+	}
+	user, err := handleResetUserPassword(r, "", r.Form.Get("code"), password)
+	if err != nil {
+		renderClientResetPwdPage(w, err.Error())
+		return
+	}
+	s.doWebClientLogin(w, r, user.Username, password)
+}
+
 func (s *httpdServer) handleWebAdminSetupPost(w http.ResponseWriter, r *http.Request) {
 	r.Body = http.MaxBytesReader(w, r.Body, maxLoginBodySize)
 	if dataprovider.HasAdmin() {
@@ -485,9 +620,10 @@ func (s *httpdServer) loginUser(
 	isSecondFactorAuth bool, errorFunc func(w http.ResponseWriter, error string),
 ) {
 	c := jwtTokenClaims{
-		Username:    user.Username,
-		Permissions: user.Filters.WebClient,
-		Signature:   user.GetSignature(),
+		Username:           user.Username,
+		Permissions:        user.Filters.WebClient,
+		Signature:          user.GetSignature(),
+		MustChangePassword: user.MustChangePassword(),
 	}
 
 	audience := tokenAudienceWebClient
@@ -512,6 +648,10 @@ func (s *httpdServer) loginUser(
 	}
 	updateLoginMetrics(user, ipAddr, err)
 	dataprovider.UpdateLastLogin(user)
+	if c.MustChangePassword {
+		http.Redirect(w, r, webChangeClientPwdPath, http.StatusFound)
+		return
+	}
 	http.Redirect(w, r, webClientFilesPath, http.StatusFound)
 }
 
@@ -520,9 +660,10 @@ func (s *httpdServer) loginAdmin(
 	isSecondFactorAuth bool, errorFunc func(w http.ResponseWriter, error string),
 ) {
 	c := jwtTokenClaims{
-		Username:    admin.Username,
-		Permissions: admin.Permissions,
-		Signature:   admin.GetSignature(),
+		Username:           admin.Username,
+		Permissions:        admin.Permissions,
+		Signature:          admin.GetSignature(),
+		MustChangePassword: admin.MustChangePassword(),
 	}
 
 	audience := tokenAudienceWebAdmin
@@ -548,6 +689,10 @@ func (s *httpdServer) loginAdmin(
 		return
 	}
 	dataprovider.UpdateAdminLastLogin(admin)
+	if c.MustChangePassword {
+		http.Redirect(w, r, webChangeAdminPwdPath, http.StatusFound)
+		return
+	}
 	http.Redirect(w, r, webUsersPath, http.StatusFound)
 }
 
@@ -634,9 +779,10 @@ func (s *httpdServer) getUserToken(w http.ResponseWriter, r *http.Request) {
 
 func (s *httpdServer) generateAndSendUserToken(w http.ResponseWriter, r *http.Request, ipAddr string, user dataprovider.User) {
 	c := jwtTokenClaims{
-		Username:    user.Username,
-		Permissions: user.Filters.WebClient,
-		Signature:   user.GetSignature(),
+		Username:           user.Username,
+		Permissions:        user.Filters.WebClient,
+		Signature:          user.GetSignature(),
+		MustChangePassword: user.MustChangePassword(),
 	}
 
 	resp, err := c.createTokenResponse(s.tokenAuth, tokenAudienceAPIUser)
@@ -695,9 +841,10 @@ func (s *httpdServer) getToken(w http.ResponseWriter, r *http.Request) {
 
 func (s *httpdServer) generateAndSendToken(w http.ResponseWriter, r *http.Request, admin dataprovider.Admin) {
 	c := jwtTokenClaims{
-		Username:    admin.Username,
-		Permissions: admin.Permissions,
-		Signature:   admin.GetSignature(),
+		Username:           admin.Username,
+		Permissions:        admin.Permissions,
+		Signature:          admin.GetSignature(),
+		MustChangePassword: admin.MustChangePassword(),
 	}
 
 	resp, err := c.createTokenResponse(s.tokenAuth, tokenAudienceAPI)
@@ -732,7 +879,7 @@ func (s *httpdServer) checkCookieExpiration(w http.ResponseWriter, r *http.Reque
 }
 
 func (s *httpdServer) refreshClientToken(w http.ResponseWriter, r *http.Request, tokenClaims jwtTokenClaims) {
-	user, err := dataprovider.UserExists(tokenClaims.Username)
+	user, err := dataprovider.GetUserWithGroupSettings(tokenClaims.Username)
 	if err != nil {
 		return
 	}
@@ -897,6 +1044,8 @@ func (s *httpdServer) initializeRouter() {
 	})
 
 	s.router.Get(tokenPath, s.getToken)
+	s.router.Post(adminPath+"/{username}/forgot-password", forgotAdminPassword)
+	s.router.Post(adminPath+"/{username}/reset-password", resetAdminPassword)
 
 	s.router.Group(func(router chi.Router) {
 		router.Use(checkAPIKeyAuth(s.tokenAuth, dataprovider.APIKeyScopeAdmin))
@@ -931,7 +1080,11 @@ func (s *httpdServer) initializeRouter() {
 		router.With(checkPerm(dataprovider.PermAdminViewConnections)).
 			Get(activeConnectionsPath, func(w http.ResponseWriter, r *http.Request) {
 				r.Body = http.MaxBytesReader(w, r.Body, maxRequestSize)
-				render.JSON(w, r, common.Connections.GetStats())
+				admin, err := getCurrentAPIAdmin(w, r)
+				if err != nil {
+					return
+				}
+				render.JSON(w, r, getConnectionsInScope(&admin))
 			})
 
 		router.With(checkPerm(dataprovider.PermAdminCloseConnections)).
@@ -950,11 +1103,20 @@ func (s *httpdServer) initializeRouter() {
 		router.With(checkPerm(dataprovider.PermAdminChangeUsers)).Put(userPath+"/{username}", updateUser)
 		router.With(checkPerm(dataprovider.PermAdminDeleteUsers)).Delete(userPath+"/{username}", deleteUser)
 		router.With(checkPerm(dataprovider.PermAdminChangeUsers)).Put(userPath+"/{username}/2fa/disable", disableUser2FA)
+		router.With(checkPerm(dataprovider.PermAdminViewUsers)).Get(userPath+"/{username}/s3keys", getUserS3AccessKeys)
+		router.With(checkPerm(dataprovider.PermAdminChangeUsers)).Post(userPath+"/{username}/s3keys", addUserS3AccessKey)
+		router.With(checkPerm(dataprovider.PermAdminChangeUsers)).Delete(userPath+"/{username}/s3keys/{id}",
+			deleteUserS3AccessKey)
 		router.With(checkPerm(dataprovider.PermAdminViewUsers)).Get(folderPath, getFolders)
 		router.With(checkPerm(dataprovider.PermAdminViewUsers)).Get(folderPath+"/{name}", getFolderByName)
 		router.With(checkPerm(dataprovider.PermAdminAddUsers)).Post(folderPath, addFolder)
 		router.With(checkPerm(dataprovider.PermAdminChangeUsers)).Put(folderPath+"/{name}", updateFolder)
 		router.With(checkPerm(dataprovider.PermAdminDeleteUsers)).Delete(folderPath+"/{name}", deleteFolder)
+		router.With(checkPerm(dataprovider.PermAdminViewUsers)).Get(groupPath, getGroups)
+		router.With(checkPerm(dataprovider.PermAdminViewUsers)).Get(groupPath+"/{name}", getGroupByName)
+		router.With(checkPerm(dataprovider.PermAdminAddUsers)).Post(groupPath, addGroup)
+		router.With(checkPerm(dataprovider.PermAdminChangeUsers)).Put(groupPath+"/{name}", updateGroup)
+		router.With(checkPerm(dataprovider.PermAdminDeleteUsers)).Delete(groupPath+"/{name}", deleteGroup)
 		router.With(checkPerm(dataprovider.PermAdminManageSystem)).Get(dumpDataPath, dumpData)
 		router.With(checkPerm(dataprovider.PermAdminManageSystem)).Get(loadDataPath, loadData)
 		router.With(checkPerm(dataprovider.PermAdminManageSystem)).Post(loadDataPath, loadDataFromRequest)
@@ -962,12 +1124,25 @@ func (s *httpdServer) initializeRouter() {
 		router.With(checkPerm(dataprovider.PermAdminChangeUsers)).Put(quotasBasePath+"/users/{username}/usage", updateUserQuotaUsage)
 		router.With(checkPerm(dataprovider.PermAdminChangeUsers)).Put(updateFolderUsedQuotaPath, updateFolderQuotaUsageCompat)
 		router.With(checkPerm(dataprovider.PermAdminChangeUsers)).Put(quotasBasePath+"/folders/{name}/usage", updateFolderQuotaUsage)
+		router.With(checkPerm(dataprovider.PermAdminViewUsers)).Get(quotasBasePath+"/users/{username}/transfer-usage",
+			getUserTransferQuotaUsage)
+		router.With(checkPerm(dataprovider.PermAdminChangeUsers)).Put(quotasBasePath+"/users/{username}/transfer-usage",
+			updateUserTransferQuotaUsage)
+		router.With(checkPerm(dataprovider.PermAdminViewUsers)).Get(quotasBasePath+"/folders/{name}/transfer-usage",
+			getFolderTransferQuotaUsage)
+		router.With(checkPerm(dataprovider.PermAdminChangeUsers)).Put(quotasBasePath+"/folders/{name}/transfer-usage",
+			updateFolderTransferQuotaUsage)
 		router.With(checkPerm(dataprovider.PermAdminViewDefender)).Get(defenderHosts, getDefenderHosts)
 		router.With(checkPerm(dataprovider.PermAdminViewDefender)).Get(defenderHosts+"/{id}", getDefenderHostByID)
 		router.With(checkPerm(dataprovider.PermAdminManageDefender)).Delete(defenderHosts+"/{id}", deleteDefenderHostByID)
 		router.With(checkPerm(dataprovider.PermAdminViewDefender)).Get(defenderBanTime, getBanTime)
 		router.With(checkPerm(dataprovider.PermAdminViewDefender)).Get(defenderScore, getScore)
 		router.With(checkPerm(dataprovider.PermAdminManageDefender)).Post(defenderUnban, unban)
+		router.With(checkPerm(dataprovider.PermAdminViewDefender)).Get(accountLockoutsPath, getAccountLockouts)
+		router.With(checkPerm(dataprovider.PermAdminViewDefender)).Get(accountLockoutsPath+"/{type}/{username}",
+			getAccountLockout)
+		router.With(checkPerm(dataprovider.PermAdminManageDefender)).Delete(accountLockoutsPath+"/{type}/{username}",
+			deleteAccountLockout)
 		router.With(checkPerm(dataprovider.PermAdminManageAdmins)).Get(adminPath, getAdmins)
 		router.With(checkPerm(dataprovider.PermAdminManageAdmins)).Post(adminPath, addAdmin)
 		router.With(checkPerm(dataprovider.PermAdminManageAdmins)).Get(adminPath+"/{username}", getAdminByUsername)
@@ -981,6 +1156,17 @@ func (s *httpdServer) initializeRouter() {
 			Get(fsEventsPath, searchFsEvents)
 		router.With(checkPerm(dataprovider.PermAdminViewEvents), compressor.Handler).
 			Get(providerEventsPath, searchProviderEvents)
+		router.With(checkPerm(dataprovider.PermAdminManageEventRules)).Get(eventRulesPath, getEventRules)
+		router.With(checkPerm(dataprovider.PermAdminManageEventRules)).Get(eventRulesPath+"/{name}", getEventRuleByName)
+		router.With(checkPerm(dataprovider.PermAdminManageEventRules)).Post(eventRulesPath, addEventRule)
+		router.With(checkPerm(dataprovider.PermAdminManageEventRules)).Put(eventRulesPath+"/{name}", updateEventRule)
+		router.With(checkPerm(dataprovider.PermAdminManageEventRules)).Delete(eventRulesPath+"/{name}", deleteEventRule)
+		router.With(checkPerm(dataprovider.PermAdminManageSchedules)).Get(schedulesPath, getSchedules)
+		router.With(checkPerm(dataprovider.PermAdminManageSchedules)).Get(schedulesPath+"/{name}", getScheduleByName)
+		router.With(checkPerm(dataprovider.PermAdminManageSchedules)).Post(schedulesPath, addSchedule)
+		router.With(checkPerm(dataprovider.PermAdminManageSchedules)).Put(schedulesPath+"/{name}", updateSchedule)
+		router.With(checkPerm(dataprovider.PermAdminManageSchedules)).Delete(schedulesPath+"/{name}", deleteSchedule)
+		router.With(checkPerm(dataprovider.PermAdminManageSchedules)).Post(schedulesPath+"/{name}/run", runSchedule)
 		router.With(forbidAPIKeyAuthentication, checkPerm(dataprovider.PermAdminManageAPIKeys)).
 			Get(apiKeysPath, getAPIKeys)
 		router.With(forbidAPIKeyAuthentication, checkPerm(dataprovider.PermAdminManageAPIKeys)).
@@ -994,6 +1180,10 @@ func (s *httpdServer) initializeRouter() {
 	})
 
 	s.router.Get(userTokenPath, s.getUserToken)
+	s.router.Post(userPath+"/{username}/forgot-password", forgotUserPassword)
+	s.router.Post(userPath+"/{username}/reset-password", resetUserPassword)
+	s.router.Get(sharesPath+"/{id}", downloadFromShare)
+	s.router.Post(sharesPath+"/{id}", uploadToShare)
 
 	s.router.Group(func(router chi.Router) {
 		router.Use(checkAPIKeyAuth(s.tokenAuth, dataprovider.APIKeyScopeUser))
@@ -1035,7 +1225,18 @@ func (s *httpdServer) initializeRouter() {
 		router.With(checkHTTPUserPerm(sdk.WebClientWriteDisabled)).Post(userFilesPath, uploadUserFiles)
 		router.With(checkHTTPUserPerm(sdk.WebClientWriteDisabled)).Patch(userFilesPath, renameUserFile)
 		router.With(checkHTTPUserPerm(sdk.WebClientWriteDisabled)).Delete(userFilesPath, deleteUserFile)
+		router.With(checkHTTPUserPerm(sdk.WebClientWriteDisabled)).Options(userUploadsPath, getTusUploadsOptions)
+		router.With(checkHTTPUserPerm(sdk.WebClientWriteDisabled)).Post(userUploadsPath, createTusUpload)
+		router.With(checkHTTPUserPerm(sdk.WebClientWriteDisabled)).Head(userUploadsPath+"/{id}", getTusUploadInfo)
+		router.With(checkHTTPUserPerm(sdk.WebClientWriteDisabled)).Patch(userUploadsPath+"/{id}", uploadTusChunk)
+		router.With(checkHTTPUserPerm(sdk.WebClientWriteDisabled)).Delete(userUploadsPath+"/{id}", deleteTusUpload)
 		router.Post(userStreamZipPath, getUserFilesAsZipStream)
+		router.With(checkHTTPUserPerm(sdk.WebClientSharesDisabled)).Get(userSharesPath, getShares)
+		router.With(checkHTTPUserPerm(sdk.WebClientSharesDisabled)).Post(userSharesPath, addShare)
+		router.With(checkHTTPUserPerm(sdk.WebClientSharesDisabled)).Get(userSharesPath+"/{id}", getShareByID)
+		router.With(checkHTTPUserPerm(sdk.WebClientSharesDisabled)).Put(userSharesPath+"/{id}", updateShare)
+		router.With(checkHTTPUserPerm(sdk.WebClientSharesDisabled)).Delete(userSharesPath+"/{id}", deleteShare)
+		router.With(checkHTTPUserPerm(sdk.WebClientSharesDisabled)).Get(userSharesPath+"/{id}/uploads", getShareUploads)
 	})
 
 	if s.enableWebAdmin || s.enableWebClient {
@@ -1071,6 +1272,12 @@ func (s *httpdServer) initializeRouter() {
 		})
 		s.router.Get(webClientLoginPath, s.handleClientWebLogin)
 		s.router.Post(webClientLoginPath, s.handleWebClientLoginPost)
+		s.router.Get(webClientForgotPwdPath, handleWebClientForgotPwd)
+		s.router.Post(webClientForgotPwdPath, handleWebClientForgotPwdPost)
+		s.router.Get(webClientResetPwdPath, handleWebClientResetPwd)
+		s.router.Post(webClientResetPwdPath, s.handleWebClientResetPwdPost)
+		s.router.Get(webClientPubSharesPath+"/{id}", handleClientGetPublicShare)
+		s.router.Post(webClientPubSharesPath+"/{id}", uploadToShare)
 		s.router.With(jwtauth.Verify(s.tokenAuth, jwtauth.TokenFromCookie),
 			jwtAuthenticatorPartial(tokenAudienceWebClientPartial)).
 			Get(webClientTwoFactorPath, handleWebClientTwoFactor)
@@ -1098,6 +1305,14 @@ func (s *httpdServer) initializeRouter() {
 				Patch(webClientFilesPath, renameUserFile)
 			router.With(checkHTTPUserPerm(sdk.WebClientWriteDisabled), verifyCSRFHeader).
 				Delete(webClientFilesPath, deleteUserFile)
+			router.With(checkHTTPUserPerm(sdk.WebClientWriteDisabled), verifyCSRFHeader).
+				Post(webClientUploadsPath, createTusUpload)
+			router.With(checkHTTPUserPerm(sdk.WebClientWriteDisabled)).
+				Head(webClientUploadsPath+"/{id}", getTusUploadInfo)
+			router.With(checkHTTPUserPerm(sdk.WebClientWriteDisabled), verifyCSRFHeader).
+				Patch(webClientUploadsPath+"/{id}", uploadTusChunk)
+			router.With(checkHTTPUserPerm(sdk.WebClientWriteDisabled), verifyCSRFHeader).
+				Delete(webClientUploadsPath+"/{id}", deleteTusUpload)
 			router.With(compressor.Handler, s.refreshCookie).Get(webClientDirsPath, handleClientGetDirContents)
 			router.With(checkHTTPUserPerm(sdk.WebClientWriteDisabled), verifyCSRFHeader).
 				Post(webClientDirsPath, createUserDir)
@@ -1124,6 +1339,20 @@ func (s *httpdServer) initializeRouter() {
 				Get(webClientRecoveryCodesPath, getRecoveryCodes)
 			router.With(checkHTTPUserPerm(sdk.WebClientMFADisabled), verifyCSRFHeader).
 				Post(webClientRecoveryCodesPath, generateRecoveryCodes)
+			router.With(checkHTTPUserPerm(sdk.WebClientSharesDisabled), s.refreshCookie).
+				Get(webClientSharesPath, handleClientGetShares)
+			router.With(checkHTTPUserPerm(sdk.WebClientSharesDisabled), s.refreshCookie).
+				Get(webClientSharesPath+"/{id}/uploads", handleClientGetShareUploads)
+			router.With(checkHTTPUserPerm(sdk.WebClientSharesDisabled), s.refreshCookie).
+				Get(webClientSharePath, handleClientAddShareGet)
+			router.With(checkHTTPUserPerm(sdk.WebClientSharesDisabled)).Post(webClientSharePath,
+				handleClientAddSharePost)
+			router.With(checkHTTPUserPerm(sdk.WebClientSharesDisabled), s.refreshCookie).
+				Get(webClientSharePath+"/{id}", handleClientUpdateShareGet)
+			router.With(checkHTTPUserPerm(sdk.WebClientSharesDisabled)).
+				Post(webClientSharePath+"/{id}", handleClientUpdateSharePost)
+			router.With(checkHTTPUserPerm(sdk.WebClientSharesDisabled), verifyCSRFHeader).
+				Delete(webClientSharePath+"/{id}", deleteShare)
 		})
 	}
 
@@ -1136,6 +1365,10 @@ func (s *httpdServer) initializeRouter() {
 		s.router.Post(webLoginPath, s.handleWebAdminLoginPost)
 		s.router.Get(webAdminSetupPath, handleWebAdminSetupGet)
 		s.router.Post(webAdminSetupPath, s.handleWebAdminSetupPost)
+		s.router.Get(webAdminForgotPwdPath, handleWebAdminForgotPwd)
+		s.router.Post(webAdminForgotPwdPath, handleWebAdminForgotPwdPost)
+		s.router.Get(webAdminResetPwdPath, handleWebAdminResetPwd)
+		s.router.Post(webAdminResetPwdPath, s.handleWebAdminResetPwdPost)
 		s.router.With(jwtauth.Verify(s.tokenAuth, jwtauth.TokenFromCookie),
 			jwtAuthenticatorPartial(tokenAudienceWebAdminPartial)).
 			Get(webAdminTwoFactorPath, handleWebAdminTwoFactor)
@@ -1202,6 +1435,43 @@ func (s *httpdServer) initializeRouter() {
 				handleWebUpdateFolderPost)
 			router.With(checkPerm(dataprovider.PermAdminDeleteUsers), verifyCSRFHeader).
 				Delete(webFolderPath+"/{name}", deleteFolder)
+			router.With(checkPerm(dataprovider.PermAdminViewUsers), s.refreshCookie).
+				Get(webGroupsPath, handleWebGetGroups)
+			router.With(checkPerm(dataprovider.PermAdminAddUsers), s.refreshCookie).
+				Get(webGroupPath, handleWebAddGroupGet)
+			router.With(checkPerm(dataprovider.PermAdminAddUsers)).Post(webGroupPath, handleWebAddGroupPost)
+			router.With(checkPerm(dataprovider.PermAdminChangeUsers), s.refreshCookie).
+				Get(webGroupPath+"/{name}", handleWebUpdateGroupGet)
+			router.With(checkPerm(dataprovider.PermAdminChangeUsers)).Post(webGroupPath+"/{name}",
+				handleWebUpdateGroupPost)
+			router.With(checkPerm(dataprovider.PermAdminDeleteUsers), verifyCSRFHeader).
+				Delete(webGroupPath+"/{name}", deleteGroup)
+			router.With(checkPerm(dataprovider.PermAdminManageEventRules), s.refreshCookie).
+				Get(webEventRulesPath, handleWebGetEventRules)
+			router.With(checkPerm(dataprovider.PermAdminManageEventRules), s.refreshCookie).
+				Get(webEventRulePath, handleWebAddEventRuleGet)
+			router.With(checkPerm(dataprovider.PermAdminManageEventRules)).Post(webEventRulePath,
+				handleWebAddEventRulePost)
+			router.With(checkPerm(dataprovider.PermAdminManageEventRules), s.refreshCookie).
+				Get(webEventRulePath+"/{name}", handleWebUpdateEventRuleGet)
+			router.With(checkPerm(dataprovider.PermAdminManageEventRules)).Post(webEventRulePath+"/{name}",
+				handleWebUpdateEventRulePost)
+			router.With(checkPerm(dataprovider.PermAdminManageEventRules), verifyCSRFHeader).
+				Delete(webEventRulePath+"/{name}", deleteEventRule)
+			router.With(checkPerm(dataprovider.PermAdminManageSchedules), s.refreshCookie).
+				Get(webSchedulesPath, handleWebGetSchedules)
+			router.With(checkPerm(dataprovider.PermAdminManageSchedules), s.refreshCookie).
+				Get(webSchedulePath, handleWebAddScheduleGet)
+			router.With(checkPerm(dataprovider.PermAdminManageSchedules)).Post(webSchedulePath,
+				handleWebAddSchedulePost)
+			router.With(checkPerm(dataprovider.PermAdminManageSchedules), s.refreshCookie).
+				Get(webSchedulePath+"/{name}", handleWebUpdateScheduleGet)
+			router.With(checkPerm(dataprovider.PermAdminManageSchedules)).Post(webSchedulePath+"/{name}",
+				handleWebUpdateSchedulePost)
+			router.With(checkPerm(dataprovider.PermAdminManageSchedules), verifyCSRFHeader).
+				Delete(webSchedulePath+"/{name}", deleteSchedule)
+			router.With(checkPerm(dataprovider.PermAdminManageSchedules), verifyCSRFHeader).
+				Post(webSchedulePath+"/{name}/run", runSchedule)
 			router.With(checkPerm(dataprovider.PermAdminQuotaScans), verifyCSRFHeader).
 				Post(webScanVFolderPath+"/{name}", startFolderQuotaScan)
 			router.With(checkPerm(dataprovider.PermAdminDeleteUsers), verifyCSRFHeader).
@@ -1221,6 +1491,17 @@ func (s *httpdServer) initializeRouter() {
 			router.With(checkPerm(dataprovider.PermAdminViewDefender)).Get(webDefenderHostsPath, getDefenderHosts)
 			router.With(checkPerm(dataprovider.PermAdminManageDefender)).Delete(webDefenderHostsPath+"/{id}",
 				deleteDefenderHostByID)
+			router.With(checkPerm(dataprovider.PermAdminViewDefender)).Get(webAccountLockoutsPath,
+				handleWebAccountLockoutsPage)
+			router.With(checkPerm(dataprovider.PermAdminViewDefender)).Get(webAccountLockoutsListPath,
+				getAccountLockouts)
+			router.With(checkPerm(dataprovider.PermAdminManageDefender), verifyCSRFHeader).
+				Delete(webAccountLockoutsListPath+"/{type}/{username}", deleteAccountLockout)
+			router.With(checkPerm(dataprovider.PermAdminViewEvents)).Get(webEventsPath, handleWebEventsPage)
+			router.With(checkPerm(dataprovider.PermAdminViewEvents), compressor.Handler).
+				Get(webEventsFsSearchPath, searchFsEvents)
+			router.With(checkPerm(dataprovider.PermAdminViewEvents), compressor.Handler).
+				Get(webEventsProviderSearchPath, searchProviderEvents)
 		})
 	}
 }
@@ -18,15 +18,18 @@ const (
 	csrfHeaderToken           = "X-CSRF-TOKEN"
 	templateTwoFactor         = "twofactor.html"
 	templateTwoFactorRecovery = "twofactor-recovery.html"
+	templateForgotPassword    = "forgot-password.html"
+	templateResetPassword     = "reset-password.html"
 )
 
 type loginPage struct {
-	CurrentURL  string
-	Version     string
-	Error       string
-	CSRFToken   string
-	StaticURL   string
-	AltLoginURL string
+	CurrentURL   string
+	Version      string
+	Error        string
+	CSRFToken    string
+	StaticURL    string
+	AltLoginURL  string
+	ForgotPwdURL string
 }
 
 type twoFactorPage struct {
@@ -38,6 +41,16 @@ type twoFactorPage struct {
 	RecoveryURL string
 }
 
+type resetPwdPage struct {
+	CurrentURL   string
+	Version      string
+	Error        string
+	CSRFToken    string
+	StaticURL    string
+	LoginURL     string
+	ForgotPwdURL string
+}
+
 func getSliceFromDelimitedValues(values, delimiter string) []string {
 	result := []string{}
 	for _, v := range strings.Split(values, delimiter) {
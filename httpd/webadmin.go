@@ -8,6 +8,7 @@ import (
 	"net/http"
 	"net/url"
 	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -40,42 +41,81 @@ const (
 	folderPageModeTemplate
 )
 
+type groupPageMode int
+
+const (
+	groupPageModeAdd groupPageMode = iota + 1
+	groupPageModeUpdate
+)
+
+type eventRulePageMode int
+
+const (
+	eventRulePageModeAdd eventRulePageMode = iota + 1
+	eventRulePageModeUpdate
+)
+
+type schedulePageMode int
+
+const (
+	schedulePageModeAdd schedulePageMode = iota + 1
+	schedulePageModeUpdate
+)
+
 const (
-	templateAdminDir     = "webadmin"
-	templateBase         = "base.html"
-	templateBaseLogin    = "baselogin.html"
-	templateFsConfig     = "fsconfig.html"
-	templateUsers        = "users.html"
-	templateUser         = "user.html"
-	templateAdmins       = "admins.html"
-	templateAdmin        = "admin.html"
-	templateConnections  = "connections.html"
-	templateFolders      = "folders.html"
-	templateFolder       = "folder.html"
-	templateMessage      = "message.html"
-	templateStatus       = "status.html"
-	templateLogin        = "login.html"
-	templateDefender     = "defender.html"
-	templateProfile      = "profile.html"
-	templateChangePwd    = "changepassword.html"
-	templateMaintenance  = "maintenance.html"
-	templateMFA          = "mfa.html"
-	templateSetup        = "adminsetup.html"
-	pageUsersTitle       = "Users"
-	pageAdminsTitle      = "Admins"
-	pageConnectionsTitle = "Connections"
-	pageStatusTitle      = "Status"
-	pageFoldersTitle     = "Folders"
-	pageProfileTitle     = "My profile"
-	pageChangePwdTitle   = "Change password"
-	pageMaintenanceTitle = "Maintenance"
-	pageDefenderTitle    = "Defender"
-	pageSetupTitle       = "Create first admin user"
-	defaultQueryLimit    = 500
+	templateAdminDir      = "webadmin"
+	templateBase          = "base.html"
+	templateBaseLogin     = "baselogin.html"
+	templateFsConfig      = "fsconfig.html"
+	templateUsers         = "users.html"
+	templateUser          = "user.html"
+	templateAdmins        = "admins.html"
+	templateAdmin         = "admin.html"
+	templateConnections   = "connections.html"
+	templateFolders       = "folders.html"
+	templateFolder        = "folder.html"
+	templateGroups        = "groups.html"
+	templateGroup         = "group.html"
+	templateMessage       = "message.html"
+	templateStatus        = "status.html"
+	templateLogin         = "login.html"
+	templateDefender      = "defender.html"
+	templateLockouts      = "lockouts.html"
+	templateEvents        = "events.html"
+	templateEventRules    = "eventrules.html"
+	templateEventRule     = "eventrule.html"
+	templateSchedules     = "schedules.html"
+	templateSchedule      = "schedule.html"
+	templateDataRetention = "dataretention.html"
+	templateProfile       = "profile.html"
+	templateChangePwd     = "changepassword.html"
+	templateMaintenance   = "maintenance.html"
+	templateMFA           = "mfa.html"
+	templateSetup         = "adminsetup.html"
+	pageUsersTitle        = "Users"
+	pageAdminsTitle       = "Admins"
+	pageConnectionsTitle  = "Connections"
+	pageStatusTitle       = "Status"
+	pageFoldersTitle      = "Folders"
+	pageGroupsTitle       = "Groups"
+	pageProfileTitle      = "My profile"
+	pageChangePwdTitle    = "Change password"
+	pageMaintenanceTitle  = "Maintenance"
+	pageDefenderTitle     = "Defender"
+	pageLockoutsTitle     = "Locked accounts"
+	pageEventsTitle       = "Events"
+	pageEventRulesTitle   = "Event rules"
+	pageSchedulesTitle    = "Schedules"
+	pageSetupTitle        = "Create first admin user"
+	defaultQueryLimit     = 500
 )
 
 var (
 	adminTemplates = make(map[string]*template.Template)
+	// protocols that can be used as event rule conditions
+	eventRuleProtocols = []string{common.ProtocolSFTP, common.ProtocolSCP, common.ProtocolSSH, common.ProtocolFTP,
+		common.ProtocolWebDAV, common.ProtocolHTTP, common.ProtocolHTTPShare, common.ProtocolS3,
+		common.ProtocolDataRetention}
 )
 
 type basePage struct {
@@ -91,7 +131,15 @@ type basePage struct {
 	FoldersURL         string
 	FolderURL          string
 	FolderTemplateURL  string
+	GroupsURL          string
+	GroupURL           string
 	DefenderURL        string
+	LockoutsURL        string
+	EventsURL          string
+	EventRulesURL      string
+	EventRuleURL       string
+	SchedulesURL       string
+	ScheduleURL        string
 	LogoutURL          string
 	ProfileURL         string
 	ChangePwdURL       string
@@ -104,12 +152,19 @@ type basePage struct {
 	AdminsTitle        string
 	ConnectionsTitle   string
 	FoldersTitle       string
+	GroupsTitle        string
 	StatusTitle        string
 	MaintenanceTitle   string
 	DefenderTitle      string
+	LockoutsTitle      string
+	EventsTitle        string
+	EventRulesTitle    string
+	SchedulesTitle     string
 	Version            string
 	CSRFToken          string
 	HasDefender        bool
+	HasLockouts        bool
+	HasEvents          bool
 	LoggedAdmin        *dataprovider.Admin
 }
 
@@ -128,6 +183,21 @@ type foldersPage struct {
 	Folders []vfs.BaseVirtualFolder
 }
 
+type groupsPage struct {
+	basePage
+	Groups []dataprovider.Group
+}
+
+type eventRulesPage struct {
+	basePage
+	Rules []dataprovider.EventRule
+}
+
+type schedulesPage struct {
+	basePage
+	Schedules []dataprovider.Schedule
+}
+
 type connectionsPage struct {
 	basePage
 	Connections []*common.ConnectionStatus
@@ -151,13 +221,15 @@ type userPage struct {
 	RedactedSecret    string
 	Mode              userPageMode
 	VirtualFolders    []vfs.BaseVirtualFolder
+	Groups            []dataprovider.Group
 }
 
 type adminPage struct {
 	basePage
-	Admin *dataprovider.Admin
-	Error string
-	IsAdd bool
+	Admin  *dataprovider.Admin
+	Groups []dataprovider.Group
+	Error  string
+	IsAdd  bool
 }
 
 type profilePage struct {
@@ -195,6 +267,17 @@ type defenderHostsPage struct {
 	DefenderHostsURL string
 }
 
+type lockoutsPage struct {
+	basePage
+	LockoutsListURL string
+}
+
+type eventsPage struct {
+	basePage
+	FsEventsURL       string
+	ProviderEventsURL string
+}
+
 type setupPage struct {
 	basePage
 	Username string
@@ -208,6 +291,40 @@ type folderPage struct {
 	Mode   folderPageMode
 }
 
+type groupPage struct {
+	basePage
+	Group             *dataprovider.Group
+	Error             string
+	Mode              groupPageMode
+	ValidPerms        []string
+	ValidLoginMethods []string
+	ValidProtocols    []string
+	WebClientOptions  []string
+	RootDirPerms      []string
+	VirtualFolders    []vfs.BaseVirtualFolder
+}
+
+type eventRulePage struct {
+	basePage
+	Rule            *dataprovider.EventRule
+	Error           string
+	Mode            eventRulePageMode
+	FsEvents        []string
+	ProviderEvents  []string
+	ProviderObjects []string
+	Protocols       []string
+	HTTPMethods     []string
+}
+
+type schedulePage struct {
+	basePage
+	Schedule      *dataprovider.Schedule
+	Error         string
+	Mode          schedulePageMode
+	Tasks         []dataprovider.ScheduleTask
+	Notifications []string
+}
+
 type messagePage struct {
 	basePage
 	Error   string
@@ -228,6 +345,7 @@ func loadAdminTemplates(templatesPath string) {
 	userPaths := []string{
 		filepath.Join(templatesPath, templateAdminDir, templateBase),
 		filepath.Join(templatesPath, templateAdminDir, templateFsConfig),
+		filepath.Join(templatesPath, templateAdminDir, templateDataRetention),
 		filepath.Join(templatesPath, templateAdminDir, templateUser),
 	}
 	adminsPaths := []string{
@@ -261,8 +379,19 @@ func loadAdminTemplates(templatesPath string) {
 	folderPath := []string{
 		filepath.Join(templatesPath, templateAdminDir, templateBase),
 		filepath.Join(templatesPath, templateAdminDir, templateFsConfig),
+		filepath.Join(templatesPath, templateAdminDir, templateDataRetention),
 		filepath.Join(templatesPath, templateAdminDir, templateFolder),
 	}
+	groupsPath := []string{
+		filepath.Join(templatesPath, templateAdminDir, templateBase),
+		filepath.Join(templatesPath, templateAdminDir, templateGroups),
+	}
+	groupPath := []string{
+		filepath.Join(templatesPath, templateAdminDir, templateBase),
+		filepath.Join(templatesPath, templateAdminDir, templateFsConfig),
+		filepath.Join(templatesPath, templateAdminDir, templateDataRetention),
+		filepath.Join(templatesPath, templateAdminDir, templateGroup),
+	}
 	statusPath := []string{
 		filepath.Join(templatesPath, templateAdminDir, templateBase),
 		filepath.Join(templatesPath, templateAdminDir, templateStatus),
@@ -279,6 +408,31 @@ func loadAdminTemplates(templatesPath string) {
 		filepath.Join(templatesPath, templateAdminDir, templateBase),
 		filepath.Join(templatesPath, templateAdminDir, templateDefender),
 	}
+	lockoutsPath := []string{
+		filepath.Join(templatesPath, templateAdminDir, templateBase),
+		filepath.Join(templatesPath, templateAdminDir, templateLockouts),
+	}
+	eventsPath := []string{
+		filepath.Join(templatesPath, templateAdminDir, templateBase),
+		filepath.Join(templatesPath, templateAdminDir, templateEvents),
+	}
+	eventRulesPath := []string{
+		filepath.Join(templatesPath, templateAdminDir, templateBase),
+		filepath.Join(templatesPath, templateAdminDir, templateEventRules),
+	}
+	eventRulePath := []string{
+		filepath.Join(templatesPath, templateAdminDir, templateBase),
+		filepath.Join(templatesPath, templateAdminDir, templateEventRule),
+	}
+	schedulesPath := []string{
+		filepath.Join(templatesPath, templateAdminDir, templateBase),
+		filepath.Join(templatesPath, templateAdminDir, templateSchedules),
+	}
+	schedulePath := []string{
+		filepath.Join(templatesPath, templateAdminDir, templateBase),
+		filepath.Join(templatesPath, templateAdminDir, templateDataRetention),
+		filepath.Join(templatesPath, templateAdminDir, templateSchedule),
+	}
 	mfaPath := []string{
 		filepath.Join(templatesPath, templateAdminDir, templateBase),
 		filepath.Join(templatesPath, templateAdminDir, templateMFA),
@@ -295,6 +449,14 @@ func loadAdminTemplates(templatesPath string) {
 		filepath.Join(templatesPath, templateAdminDir, templateBaseLogin),
 		filepath.Join(templatesPath, templateAdminDir, templateSetup),
 	}
+	forgotPwdPath := []string{
+		filepath.Join(templatesPath, templateAdminDir, templateBaseLogin),
+		filepath.Join(templatesPath, templateAdminDir, templateForgotPassword),
+	}
+	resetPwdPath := []string{
+		filepath.Join(templatesPath, templateAdminDir, templateBaseLogin),
+		filepath.Join(templatesPath, templateAdminDir, templateResetPassword),
+	}
 
 	fsBaseTpl := template.New("fsBaseTemplate").Funcs(template.FuncMap{
 		"ListFSProviders": sdk.ListProviders,
@@ -307,16 +469,26 @@ func loadAdminTemplates(templatesPath string) {
 	messageTmpl := util.LoadTemplate(nil, messagePath...)
 	foldersTmpl := util.LoadTemplate(nil, foldersPath...)
 	folderTmpl := util.LoadTemplate(fsBaseTpl, folderPath...)
+	groupsTmpl := util.LoadTemplate(nil, groupsPath...)
+	groupTmpl := util.LoadTemplate(fsBaseTpl, groupPath...)
 	statusTmpl := util.LoadTemplate(nil, statusPath...)
 	loginTmpl := util.LoadTemplate(nil, loginPath...)
 	profileTmpl := util.LoadTemplate(nil, profilePaths...)
 	changePwdTmpl := util.LoadTemplate(nil, changePwdPaths...)
 	maintenanceTmpl := util.LoadTemplate(nil, maintenancePath...)
 	defenderTmpl := util.LoadTemplate(nil, defenderPath...)
+	lockoutsTmpl := util.LoadTemplate(nil, lockoutsPath...)
+	eventsTmpl := util.LoadTemplate(nil, eventsPath...)
+	eventRulesTmpl := util.LoadTemplate(nil, eventRulesPath...)
+	eventRuleTmpl := util.LoadTemplate(nil, eventRulePath...)
+	schedulesTmpl := util.LoadTemplate(nil, schedulesPath...)
+	scheduleTmpl := util.LoadTemplate(nil, schedulePath...)
 	mfaTmpl := util.LoadTemplate(nil, mfaPath...)
 	twoFactorTmpl := util.LoadTemplate(nil, twoFactorPath...)
 	twoFactorRecoveryTmpl := util.LoadTemplate(nil, twoFactorRecoveryPath...)
 	setupTmpl := util.LoadTemplate(nil, setupPath...)
+	forgotPwdTmpl := util.LoadTemplate(nil, forgotPwdPath...)
+	resetPwdTmpl := util.LoadTemplate(nil, resetPwdPath...)
 
 	adminTemplates[templateUsers] = usersTmpl
 	adminTemplates[templateUser] = userTmpl
@@ -326,16 +498,26 @@ func loadAdminTemplates(templatesPath string) {
 	adminTemplates[templateMessage] = messageTmpl
 	adminTemplates[templateFolders] = foldersTmpl
 	adminTemplates[templateFolder] = folderTmpl
+	adminTemplates[templateGroups] = groupsTmpl
+	adminTemplates[templateGroup] = groupTmpl
 	adminTemplates[templateStatus] = statusTmpl
 	adminTemplates[templateLogin] = loginTmpl
 	adminTemplates[templateProfile] = profileTmpl
 	adminTemplates[templateChangePwd] = changePwdTmpl
 	adminTemplates[templateMaintenance] = maintenanceTmpl
 	adminTemplates[templateDefender] = defenderTmpl
+	adminTemplates[templateLockouts] = lockoutsTmpl
+	adminTemplates[templateEvents] = eventsTmpl
+	adminTemplates[templateEventRules] = eventRulesTmpl
+	adminTemplates[templateEventRule] = eventRuleTmpl
+	adminTemplates[templateSchedules] = schedulesTmpl
+	adminTemplates[templateSchedule] = scheduleTmpl
 	adminTemplates[templateMFA] = mfaTmpl
 	adminTemplates[templateTwoFactor] = twoFactorTmpl
 	adminTemplates[templateTwoFactorRecovery] = twoFactorRecoveryTmpl
 	adminTemplates[templateSetup] = setupTmpl
+	adminTemplates[templateForgotPassword] = forgotPwdTmpl
+	adminTemplates[templateResetPassword] = resetPwdTmpl
 }
 
 func getBasePageData(title, currentURL string, r *http.Request) basePage {
@@ -354,7 +536,15 @@ func getBasePageData(title, currentURL string, r *http.Request) basePage {
 		FoldersURL:         webFoldersPath,
 		FolderURL:          webFolderPath,
 		FolderTemplateURL:  webTemplateFolder,
+		GroupsURL:          webGroupsPath,
+		GroupURL:           webGroupPath,
 		DefenderURL:        webDefenderPath,
+		LockoutsURL:        webAccountLockoutsPath,
+		EventsURL:          webEventsPath,
+		EventRulesURL:      webEventRulesPath,
+		EventRuleURL:       webEventRulePath,
+		SchedulesURL:       webSchedulesPath,
+		ScheduleURL:        webSchedulePath,
 		LogoutURL:          webLogoutPath,
 		ProfileURL:         webAdminProfilePath,
 		ChangePwdURL:       webChangeAdminPwdPath,
@@ -369,12 +559,19 @@ func getBasePageData(title, currentURL string, r *http.Request) basePage {
 		AdminsTitle:        pageAdminsTitle,
 		ConnectionsTitle:   pageConnectionsTitle,
 		FoldersTitle:       pageFoldersTitle,
+		GroupsTitle:        pageGroupsTitle,
 		StatusTitle:        pageStatusTitle,
 		MaintenanceTitle:   pageMaintenanceTitle,
 		DefenderTitle:      pageDefenderTitle,
+		LockoutsTitle:      pageLockoutsTitle,
+		EventsTitle:        pageEventsTitle,
+		EventRulesTitle:    pageEventRulesTitle,
+		SchedulesTitle:     pageSchedulesTitle,
 		Version:            version.GetAsString(),
 		LoggedAdmin:        getAdminFromToken(r),
 		HasDefender:        common.Config.DefenderConfig.Enabled,
+		HasLockouts:        dataprovider.IsAccountLockoutEnabled(),
+		HasEvents:          isEventSearchAvailable(),
 		CSRFToken:          csrfToken,
 	}
 }
@@ -442,6 +639,31 @@ func renderTwoFactorRecoveryPage(w http.ResponseWriter, error string) {
 	renderAdminTemplate(w, templateTwoFactorRecovery, data)
 }
 
+func renderForgotPwdPage(w http.ResponseWriter, error string) {
+	data := resetPwdPage{
+		CurrentURL: webAdminForgotPwdPath,
+		Version:    version.Get().Version,
+		Error:      error,
+		CSRFToken:  createCSRFToken(),
+		StaticURL:  webStaticFilesPath,
+		LoginURL:   webLoginPath,
+	}
+	renderAdminTemplate(w, templateForgotPassword, data)
+}
+
+func renderResetPwdPage(w http.ResponseWriter, error string) {
+	data := resetPwdPage{
+		CurrentURL:   webAdminResetPwdPath,
+		Version:      version.Get().Version,
+		Error:        error,
+		CSRFToken:    createCSRFToken(),
+		StaticURL:    webStaticFilesPath,
+		LoginURL:     webLoginPath,
+		ForgotPwdURL: webAdminForgotPwdPath,
+	}
+	renderAdminTemplate(w, templateResetPassword, data)
+}
+
 func renderMFAPage(w http.ResponseWriter, r *http.Request) {
 	data := mfaPage{
 		basePage:        getBasePageData(pageMFATitle, webAdminMFAPath, r),
@@ -509,6 +731,11 @@ func renderAdminSetupPage(w http.ResponseWriter, r *http.Request, username, erro
 
 func renderAddUpdateAdminPage(w http.ResponseWriter, r *http.Request, admin *dataprovider.Admin,
 	error string, isAdd bool) {
+	// admins with a scope cannot manage admins, all the groups are available here
+	groups, err := getWebGroupsForAdmin(w, r, &dataprovider.Admin{}, defaultQueryLimit)
+	if err != nil {
+		return
+	}
 	currentURL := webAdminPath
 	if !isAdd {
 		currentURL = fmt.Sprintf("%v/%v", webAdminPath, url.PathEscape(admin.Username))
@@ -516,6 +743,7 @@ func renderAddUpdateAdminPage(w http.ResponseWriter, r *http.Request, admin *dat
 	data := adminPage{
 		basePage: getBasePageData("Add a new user", currentURL, r),
 		Admin:    admin,
+		Groups:   groups,
 		Error:    error,
 		IsAdd:    isAdd,
 	}
@@ -528,6 +756,10 @@ func renderUserPage(w http.ResponseWriter, r *http.Request, user *dataprovider.U
 	if err != nil {
 		return
 	}
+	groups, err := getWebGroups(w, r, defaultQueryLimit)
+	if err != nil {
+		return
+	}
 	user.SetEmptySecretsIfNil()
 	var title, currentURL string
 	switch mode {
@@ -556,6 +788,7 @@ func renderUserPage(w http.ResponseWriter, r *http.Request, user *dataprovider.U
 		WebClientOptions:  sdk.WebClientOptions,
 		RootDirPerms:      user.GetPermissionsForPath("/"),
 		VirtualFolders:    folders,
+		Groups:            groups,
 	}
 	renderAdminTemplate(w, templateUser, data)
 }
@@ -585,6 +818,96 @@ func renderFolderPage(w http.ResponseWriter, r *http.Request, folder vfs.BaseVir
 	renderAdminTemplate(w, templateFolder, data)
 }
 
+func renderGroupPage(w http.ResponseWriter, r *http.Request, group dataprovider.Group, mode groupPageMode, error string) {
+	folders, err := getWebVirtualFolders(w, r, defaultQueryLimit)
+	if err != nil {
+		return
+	}
+	var title, currentURL string
+	switch mode {
+	case groupPageModeAdd:
+		title = "Add a new group"
+		currentURL = webGroupPath
+	case groupPageModeUpdate:
+		title = "Update group"
+		currentURL = fmt.Sprintf("%v/%v", webGroupPath, url.PathEscape(group.Name))
+	}
+	group.SetEmptySecretsIfNil()
+	group.UserSettings.FsConfig.RedactedSecret = redactedSecret
+
+	data := groupPage{
+		basePage:          getBasePageData(title, currentURL, r),
+		Group:             &group,
+		Error:             error,
+		Mode:              mode,
+		ValidPerms:        dataprovider.ValidPerms,
+		ValidLoginMethods: dataprovider.ValidLoginMethods,
+		ValidProtocols:    dataprovider.ValidProtocols,
+		WebClientOptions:  sdk.WebClientOptions,
+		RootDirPerms:      group.GetRootDirPermissions(),
+		VirtualFolders:    folders,
+	}
+	renderAdminTemplate(w, templateGroup, data)
+}
+
+func renderEventRulePage(w http.ResponseWriter, r *http.Request, rule dataprovider.EventRule, mode eventRulePageMode,
+	error string,
+) {
+	var title, currentURL string
+	switch mode {
+	case eventRulePageModeAdd:
+		title = "Add a new event rule"
+		currentURL = webEventRulePath
+	case eventRulePageModeUpdate:
+		title = "Update event rule"
+		currentURL = fmt.Sprintf("%v/%v", webEventRulePath, url.PathEscape(rule.Name))
+	}
+	if rule.Trigger == 0 {
+		rule.Trigger = dataprovider.EventTriggerFsEvent
+	}
+
+	data := eventRulePage{
+		basePage:        getBasePageData(title, currentURL, r),
+		Rule:            &rule,
+		Error:           error,
+		Mode:            mode,
+		FsEvents:        dataprovider.SupportedRuleFsEvents,
+		ProviderEvents:  dataprovider.SupportedRuleProviderEvents,
+		ProviderObjects: dataprovider.SupportedRuleProviderObjects,
+		Protocols:       eventRuleProtocols,
+		HTTPMethods:     []string{http.MethodPost, http.MethodGet, http.MethodPut},
+	}
+	renderAdminTemplate(w, templateEventRule, data)
+}
+
+func renderSchedulePage(w http.ResponseWriter, r *http.Request, schedule dataprovider.Schedule, mode schedulePageMode,
+	error string,
+) {
+	var title, currentURL string
+	switch mode {
+	case schedulePageModeAdd:
+		title = "Add a new schedule"
+		currentURL = webSchedulePath
+	case schedulePageModeUpdate:
+		title = "Update schedule"
+		currentURL = fmt.Sprintf("%v/%v", webSchedulePath, url.PathEscape(schedule.Name))
+	}
+	if schedule.Task == 0 {
+		schedule.Task = dataprovider.ScheduleTaskQuotaScan
+	}
+
+	data := schedulePage{
+		basePage: getBasePageData(title, currentURL, r),
+		Schedule: &schedule,
+		Error:    error,
+		Mode:     mode,
+		Tasks: []dataprovider.ScheduleTask{dataprovider.ScheduleTaskQuotaScan, dataprovider.ScheduleTaskRetentionCheck,
+			dataprovider.ScheduleTaskBackup},
+		Notifications: []string{dataprovider.ScheduleNotificationHook, dataprovider.ScheduleNotificationEmail},
+	}
+	renderAdminTemplate(w, templateSchedule, data)
+}
+
 func getFoldersForTemplate(r *http.Request) []string {
 	var res []string
 	folderNames := r.Form["tpl_foldername"]
@@ -770,6 +1093,7 @@ func getFiltersFromUserPostFields(r *http.Request) sdk.UserFilters {
 	}
 	filters.DisableFsChecks = len(r.Form.Get("disable_fs_checks")) > 0
 	filters.AllowAPIKeyAuth = len(r.Form.Get("allow_api_key_auth")) > 0
+	filters.RequirePasswordChange = len(r.Form.Get("require_password_change")) > 0
 	return filters
 }
 
@@ -863,6 +1187,47 @@ func getSFTPConfig(r *http.Request) (vfs.SFTPFsConfig, error) {
 	return config, err
 }
 
+func getFTPConfig(r *http.Request) (vfs.FTPFsConfig, error) {
+	var err error
+	config := vfs.FTPFsConfig{}
+	config.Endpoint = r.Form.Get("ftp_endpoint")
+	config.Username = r.Form.Get("ftp_username")
+	config.Password = getSecretFromFormField(r, "ftp_password")
+	config.Prefix = r.Form.Get("ftp_prefix")
+	config.SkipTLSVerify = len(r.Form.Get("ftp_skip_tls_verify")) > 0
+	config.DisableEPSV = len(r.Form.Get("ftp_disable_epsv")) > 0
+	config.TLSMode, err = strconv.Atoi(r.Form.Get("ftp_tls_mode"))
+	return config, err
+}
+
+func getWebDAVConfig(r *http.Request) vfs.WebDAVFsConfig {
+	config := vfs.WebDAVFsConfig{}
+	config.Endpoint = r.Form.Get("webdav_endpoint")
+	config.Username = r.Form.Get("webdav_username")
+	config.Password = getSecretFromFormField(r, "webdav_password")
+	config.BearerToken = getSecretFromFormField(r, "webdav_bearer_token")
+	config.Prefix = r.Form.Get("webdav_prefix")
+	config.SkipTLSVerify = len(r.Form.Get("webdav_skip_tls_verify")) > 0
+	return config
+}
+
+func getReadCacheConfig(r *http.Request) (vfs.ReadCacheConfig, error) {
+	var err error
+	config := vfs.ReadCacheConfig{}
+	config.Eviction = r.Form.Get("read_cache_eviction")
+	// the read cache fields are optional
+	if val := r.Form.Get("read_cache_max_size"); val != "" {
+		config.MaxSize, err = strconv.ParseInt(val, 10, 64)
+		if err != nil {
+			return config, err
+		}
+	}
+	if val := r.Form.Get("read_cache_ttl"); val != "" {
+		config.TTL, err = strconv.Atoi(val)
+	}
+	return config, err
+}
+
 func getAzureConfig(r *http.Request) (vfs.AzBlobFsConfig, error) {
 	var err error
 	config := vfs.AzBlobFsConfig{}
@@ -912,6 +1277,27 @@ func getFsConfigFromPostFields(r *http.Request) (vfs.Filesystem, error) {
 			return fs, err
 		}
 		fs.SFTPConfig = config
+	case sdk.FTPFilesystemProvider:
+		config, err := getFTPConfig(r)
+		if err != nil {
+			return fs, err
+		}
+		fs.FTPConfig = config
+	case sdk.WebDAVFilesystemProvider:
+		fs.WebDAVConfig = getWebDAVConfig(r)
+	}
+	switch fs.Provider {
+	case sdk.S3FilesystemProvider, sdk.GCSFilesystemProvider, sdk.AzureBlobFilesystemProvider:
+		config, err := getReadCacheConfig(r)
+		if err != nil {
+			return fs, err
+		}
+		fs.ReadCache = config
+	}
+	switch fs.Provider {
+	case sdk.S3FilesystemProvider, sdk.GCSFilesystemProvider, sdk.AzureBlobFilesystemProvider,
+		sdk.SFTPFilesystemProvider:
+		fs.Encryption.Passphrase = getSecretFromFormField(r, "encryption_passphrase")
 	}
 	return fs, nil
 }
@@ -926,6 +1312,10 @@ func getAdminFromPostFields(r *http.Request) (dataprovider.Admin, error) {
 	if err != nil {
 		return admin, err
 	}
+	pwdExpiration, err := getPasswordExpirationFromPostField(r)
+	if err != nil {
+		return admin, err
+	}
 	admin.Username = r.Form.Get("username")
 	admin.Password = r.Form.Get("password")
 	admin.Permissions = r.Form["permissions"]
@@ -933,6 +1323,11 @@ func getAdminFromPostFields(r *http.Request) (dataprovider.Admin, error) {
 	admin.Status = status
 	admin.Filters.AllowList = getSliceFromDelimitedValues(r.Form.Get("allowed_ip"), ",")
 	admin.Filters.AllowAPIKeyAuth = len(r.Form.Get("allow_api_key_auth")) > 0
+	admin.Filters.RequirePasswordChange = len(r.Form.Get("require_password_change")) > 0
+	admin.Filters.PasswordExpiration = pwdExpiration
+	admin.Filters.Scope.Groups = r.Form["scope_groups"]
+	admin.Filters.Scope.UsernamePrefixes = getSliceFromDelimitedValues(r.Form.Get("scope_username_prefixes"), ",")
+	admin.Filters.Scope.CreatedUsers = len(r.Form.Get("scope_created_users")) > 0
 	admin.AdditionalInfo = r.Form.Get("additional_info")
 	admin.Description = r.Form.Get("description")
 	return admin, nil
@@ -963,7 +1358,12 @@ func getFolderFromTemplate(folder vfs.BaseVirtualFolder, name string) vfs.BaseVi
 		folder.FsConfig.AzBlobConfig = getAzBlobFsFromTemplate(folder.FsConfig.AzBlobConfig, replacements)
 	case sdk.SFTPFilesystemProvider:
 		folder.FsConfig.SFTPConfig = getSFTPFsFromTemplate(folder.FsConfig.SFTPConfig, replacements)
+	case sdk.FTPFilesystemProvider:
+		folder.FsConfig.FTPConfig = getFTPFsFromTemplate(folder.FsConfig.FTPConfig, replacements)
+	case sdk.WebDAVFilesystemProvider:
+		folder.FsConfig.WebDAVConfig = getWebDAVFsFromTemplate(folder.FsConfig.WebDAVConfig, replacements)
 	}
+	folder.FsConfig.Encryption = getEncryptionFromTemplate(folder.FsConfig.Encryption, replacements)
 
 	return folder
 }
@@ -978,6 +1378,16 @@ func getCryptFsFromTemplate(fsConfig vfs.CryptFsConfig, replacements map[string]
 	return fsConfig
 }
 
+func getEncryptionFromTemplate(config vfs.EncryptionConfig, replacements map[string]string) vfs.EncryptionConfig {
+	if config.Passphrase != nil {
+		if config.Passphrase.IsPlain() {
+			payload := replacePlaceholders(config.Passphrase.GetPayload(), replacements)
+			config.Passphrase = kms.NewPlainSecret(payload)
+		}
+	}
+	return config
+}
+
 func getS3FsFromTemplate(fsConfig vfs.S3FsConfig, replacements map[string]string) vfs.S3FsConfig {
 	fsConfig.KeyPrefix = replacePlaceholders(fsConfig.KeyPrefix, replacements)
 	fsConfig.AccessKey = replacePlaceholders(fsConfig.AccessKey, replacements)
@@ -1013,6 +1423,28 @@ func getSFTPFsFromTemplate(fsConfig vfs.SFTPFsConfig, replacements map[string]st
 	return fsConfig
 }
 
+func getFTPFsFromTemplate(fsConfig vfs.FTPFsConfig, replacements map[string]string) vfs.FTPFsConfig {
+	fsConfig.Prefix = replacePlaceholders(fsConfig.Prefix, replacements)
+	fsConfig.Username = replacePlaceholders(fsConfig.Username, replacements)
+	if fsConfig.Password != nil && fsConfig.Password.IsPlain() {
+		payload := replacePlaceholders(fsConfig.Password.GetPayload(), replacements)
+		fsConfig.Password = kms.NewPlainSecret(payload)
+	}
+	return fsConfig
+}
+
+func getWebDAVFsFromTemplate(fsConfig vfs.WebDAVFsConfig, replacements map[string]string) vfs.WebDAVFsConfig {
+	// the endpoint often includes the remote username, for example for Nextcloud
+	fsConfig.Endpoint = replacePlaceholders(fsConfig.Endpoint, replacements)
+	fsConfig.Prefix = replacePlaceholders(fsConfig.Prefix, replacements)
+	fsConfig.Username = replacePlaceholders(fsConfig.Username, replacements)
+	if fsConfig.Password != nil && fsConfig.Password.IsPlain() {
+		payload := replacePlaceholders(fsConfig.Password.GetPayload(), replacements)
+		fsConfig.Password = kms.NewPlainSecret(payload)
+	}
+	return fsConfig
+}
+
 func getUserFromTemplate(user dataprovider.User, template userTemplateFields) dataprovider.User {
 	user.Username = template.Username
 	user.Password = template.Password
@@ -1047,11 +1479,45 @@ func getUserFromTemplate(user dataprovider.User, template userTemplateFields) da
 		user.FsConfig.AzBlobConfig = getAzBlobFsFromTemplate(user.FsConfig.AzBlobConfig, replacements)
 	case sdk.SFTPFilesystemProvider:
 		user.FsConfig.SFTPConfig = getSFTPFsFromTemplate(user.FsConfig.SFTPConfig, replacements)
+	case sdk.FTPFilesystemProvider:
+		user.FsConfig.FTPConfig = getFTPFsFromTemplate(user.FsConfig.FTPConfig, replacements)
+	case sdk.WebDAVFilesystemProvider:
+		user.FsConfig.WebDAVConfig = getWebDAVFsFromTemplate(user.FsConfig.WebDAVConfig, replacements)
 	}
+	user.FsConfig.Encryption = getEncryptionFromTemplate(user.FsConfig.Encryption, replacements)
 
 	return user
 }
 
+// getDataTransferLimitsFromPostFields returns the upload, download and total data transfer
+// limits and the reset period, empty limits are considered as not set
+func getDataTransferLimitsFromPostFields(r *http.Request) (int64, int64, int64, sdk.DataTransferResetPeriod, error) {
+	var limits [3]int64
+	for idx, field := range []string{"upload_data_transfer", "download_data_transfer", "total_data_transfer"} {
+		val := strings.TrimSpace(r.Form.Get(field))
+		if val == "" {
+			continue
+		}
+		limit, err := strconv.ParseInt(val, 10, 64)
+		if err != nil {
+			return 0, 0, 0, "", err
+		}
+		limits[idx] = limit
+	}
+	resetPeriod := sdk.DataTransferResetPeriod(r.Form.Get("data_transfer_reset_period"))
+	return limits[0], limits[1], limits[2], resetPeriod, nil
+}
+
+// getPasswordExpirationFromPostField returns the password expiration in days,
+// an empty value means that the password never expires
+func getPasswordExpirationFromPostField(r *http.Request) (int, error) {
+	val := strings.TrimSpace(r.Form.Get("password_expiration"))
+	if val == "" {
+		return 0, nil
+	}
+	return strconv.Atoi(val)
+}
+
 func getUserFromPostFields(r *http.Request) (dataprovider.User, error) {
 	var user dataprovider.User
 	err := r.ParseMultipartForm(maxRequestSize)
@@ -1087,6 +1553,10 @@ func getUserFromPostFields(r *http.Request) (dataprovider.User, error) {
 	if err != nil {
 		return user, err
 	}
+	dataTransferUL, dataTransferDL, dataTransferTotal, dataTransferReset, err := getDataTransferLimitsFromPostFields(r)
+	if err != nil {
+		return user, err
+	}
 	status, err := strconv.Atoi(r.Form.Get("status"))
 	if err != nil {
 		return user, err
@@ -1106,106 +1576,403 @@ func getUserFromPostFields(r *http.Request) (dataprovider.User, error) {
 	}
 	user = dataprovider.User{
 		BaseUser: sdk.BaseUser{
-			Username:          r.Form.Get("username"),
-			Email:             r.Form.Get("email"),
-			Password:          r.Form.Get("password"),
-			PublicKeys:        r.Form["public_keys"],
-			HomeDir:           r.Form.Get("home_dir"),
-			UID:               uid,
-			GID:               gid,
-			Permissions:       getUserPermissionsFromPostFields(r),
-			MaxSessions:       maxSessions,
-			QuotaSize:         quotaSize,
-			QuotaFiles:        quotaFiles,
-			UploadBandwidth:   bandwidthUL,
-			DownloadBandwidth: bandwidthDL,
-			Status:            status,
-			ExpirationDate:    expirationDateMillis,
-			Filters:           getFiltersFromUserPostFields(r),
-			AdditionalInfo:    r.Form.Get("additional_info"),
-			Description:       r.Form.Get("description"),
+			Username:                r.Form.Get("username"),
+			Email:                   r.Form.Get("email"),
+			Password:                r.Form.Get("password"),
+			PublicKeys:              r.Form["public_keys"],
+			HomeDir:                 r.Form.Get("home_dir"),
+			UID:                     uid,
+			GID:                     gid,
+			Permissions:             getUserPermissionsFromPostFields(r),
+			MaxSessions:             maxSessions,
+			QuotaSize:               quotaSize,
+			QuotaFiles:              quotaFiles,
+			UploadBandwidth:         bandwidthUL,
+			DownloadBandwidth:       bandwidthDL,
+			Status:                  status,
+			ExpirationDate:          expirationDateMillis,
+			Filters:                 getFiltersFromUserPostFields(r),
+			AdditionalInfo:          r.Form.Get("additional_info"),
+			Description:             r.Form.Get("description"),
+			UploadDataTransfer:      dataTransferUL,
+			DownloadDataTransfer:    dataTransferDL,
+			TotalDataTransfer:       dataTransferTotal,
+			DataTransferResetPeriod: dataTransferReset,
 		},
 		VirtualFolders: getVirtualFoldersFromPostFields(r),
 		FsConfig:       fsConfig,
+		Groups:         util.RemoveDuplicates(r.Form["groups"]),
+	}
+	pwdExpiration, err := getPasswordExpirationFromPostField(r)
+	if err != nil {
+		return user, err
+	}
+	user.Filters.PasswordExpiration = pwdExpiration
+	user.Filters.DataRetention, err = getDataRetentionFromPostFields(r)
+	if err != nil {
+		return user, err
 	}
 	maxFileSize, err := strconv.ParseInt(r.Form.Get("max_upload_file_size"), 10, 64)
 	user.Filters.MaxUploadFileSize = maxFileSize
 	return user, err
 }
 
-func handleWebAdminTwoFactor(w http.ResponseWriter, r *http.Request) {
-	r.Body = http.MaxBytesReader(w, r.Body, maxRequestSize)
-	renderTwoFactorPage(w, "")
-}
-
-func handleWebAdminTwoFactorRecovery(w http.ResponseWriter, r *http.Request) {
-	r.Body = http.MaxBytesReader(w, r.Body, maxRequestSize)
-	renderTwoFactorRecoveryPage(w, "")
-}
-
-func handleWebAdminMFA(w http.ResponseWriter, r *http.Request) {
-	r.Body = http.MaxBytesReader(w, r.Body, maxRequestSize)
-	renderMFAPage(w, r)
-}
-
-func handleWebAdminProfile(w http.ResponseWriter, r *http.Request) {
-	r.Body = http.MaxBytesReader(w, r.Body, maxRequestSize)
-	renderProfilePage(w, r, "")
-}
-
-func handleWebAdminChangePwd(w http.ResponseWriter, r *http.Request) {
-	r.Body = http.MaxBytesReader(w, r.Body, maxRequestSize)
-	renderChangePasswordPage(w, r, "")
-}
+func getGroupFromPostFields(r *http.Request) (dataprovider.Group, error) {
+	var group dataprovider.Group
+	err := r.ParseMultipartForm(maxRequestSize)
+	if err != nil {
+		return group, err
+	}
+	defer r.MultipartForm.RemoveAll() //nolint:errcheck
 
-func handleWebAdminChangePwdPost(w http.ResponseWriter, r *http.Request) {
-	r.Body = http.MaxBytesReader(w, r.Body, maxRequestSize)
-	err := r.ParseForm()
+	maxSessions, err := strconv.Atoi(r.Form.Get("max_sessions"))
 	if err != nil {
-		renderChangePasswordPage(w, r, err.Error())
-		return
+		return group, err
 	}
-	if err := verifyCSRFToken(r.Form.Get(csrfFormToken)); err != nil {
-		renderForbiddenPage(w, r, err.Error())
-		return
+	quotaSize, err := strconv.ParseInt(r.Form.Get("quota_size"), 10, 64)
+	if err != nil {
+		return group, err
 	}
-	err = doChangeAdminPassword(r, r.Form.Get("current_password"), r.Form.Get("new_password1"),
-		r.Form.Get("new_password2"))
+	quotaFiles, err := strconv.Atoi(r.Form.Get("quota_files"))
 	if err != nil {
-		renderChangePasswordPage(w, r, err.Error())
-		return
+		return group, err
 	}
-	handleWebLogout(w, r)
-}
-
-func handleWebAdminProfilePost(w http.ResponseWriter, r *http.Request) {
-	r.Body = http.MaxBytesReader(w, r.Body, maxRequestSize)
-	err := r.ParseForm()
+	bandwidthUL, err := strconv.ParseInt(r.Form.Get("upload_bandwidth"), 10, 64)
 	if err != nil {
-		renderProfilePage(w, r, err.Error())
-		return
+		return group, err
 	}
-	if err := verifyCSRFToken(r.Form.Get(csrfFormToken)); err != nil {
-		renderForbiddenPage(w, r, err.Error())
-		return
+	bandwidthDL, err := strconv.ParseInt(r.Form.Get("download_bandwidth"), 10, 64)
+	if err != nil {
+		return group, err
 	}
-	claims, err := getTokenClaims(r)
-	if err != nil || claims.Username == "" {
-		renderProfilePage(w, r, "Invalid token claims")
-		return
+	dataTransferUL, dataTransferDL, dataTransferTotal, dataTransferReset, err := getDataTransferLimitsFromPostFields(r)
+	if err != nil {
+		return group, err
 	}
-	admin, err := dataprovider.AdminExists(claims.Username)
+	maxFileSize, err := strconv.ParseInt(r.Form.Get("max_upload_file_size"), 10, 64)
 	if err != nil {
-		renderProfilePage(w, r, err.Error())
-		return
+		return group, err
 	}
-	admin.Filters.AllowAPIKeyAuth = len(r.Form.Get("allow_api_key_auth")) > 0
-	admin.Email = r.Form.Get("email")
-	admin.Description = r.Form.Get("description")
-	err = dataprovider.UpdateAdmin(&admin, dataprovider.ActionExecutorSelf, util.GetIPFromRemoteAddress(r.RemoteAddr))
+	pwdExpiration, err := getPasswordExpirationFromPostField(r)
 	if err != nil {
-		renderProfilePage(w, r, err.Error())
-		return
+		return group, err
+	}
+	dataRetention, err := getDataRetentionFromPostFields(r)
+	if err != nil {
+		return group, err
+	}
+	fsConfig, err := getFsConfigFromPostFields(r)
+	if err != nil {
+		return group, err
+	}
+	permissions := getUserPermissionsFromPostFields(r)
+	if len(permissions["/"]) == 0 {
+		delete(permissions, "/")
+	}
+	group = dataprovider.Group{
+		Name:        r.Form.Get("name"),
+		Description: r.Form.Get("description"),
+		UserSettings: dataprovider.GroupUserSettings{
+			Permissions:             permissions,
+			MaxSessions:             maxSessions,
+			QuotaSize:               quotaSize,
+			QuotaFiles:              quotaFiles,
+			UploadBandwidth:         bandwidthUL,
+			DownloadBandwidth:       bandwidthDL,
+			Filters:                 getFiltersFromUserPostFields(r),
+			FsConfig:                fsConfig,
+			UploadDataTransfer:      dataTransferUL,
+			DownloadDataTransfer:    dataTransferDL,
+			TotalDataTransfer:       dataTransferTotal,
+			DataTransferResetPeriod: dataTransferReset,
+		},
+		VirtualFolders: getVirtualFoldersFromPostFields(r),
+	}
+	group.UserSettings.Filters.MaxUploadFileSize = maxFileSize
+	group.UserSettings.Filters.PasswordExpiration = pwdExpiration
+	group.UserSettings.Filters.DataRetention = dataRetention
+	return group, nil
+}
+
+func getKeyValuesFromPostField(r *http.Request, field, separator string) []dataprovider.KeyValue {
+	var res []dataprovider.KeyValue
+	for _, line := range getSliceFromDelimitedValues(r.Form.Get(field), "\n") {
+		kv := strings.SplitN(line, separator, 2)
+		item := dataprovider.KeyValue{
+			Key: strings.TrimSpace(kv[0]),
+		}
+		if len(kv) == 2 {
+			item.Value = strings.TrimSpace(kv[1])
+		}
+		res = append(res, item)
+	}
+	return res
+}
+
+func getEventRuleConditionsFromPostFields(r *http.Request) (dataprovider.EventConditions, error) {
+	var conditions dataprovider.EventConditions
+
+	for k := range r.Form {
+		if strings.HasPrefix(k, "condition_pattern") {
+			pattern := strings.TrimSpace(r.Form.Get(k))
+			if pattern == "" {
+				continue
+			}
+			idx := strings.TrimPrefix(k, "condition_pattern")
+			cp := dataprovider.ConditionPattern{
+				Pattern:      pattern,
+				InverseMatch: r.Form.Get(fmt.Sprintf("condition_match%v", idx)) == "inverse",
+			}
+			switch r.Form.Get(fmt.Sprintf("condition_type%v", idx)) {
+			case "group_name":
+				conditions.Options.GroupNames = append(conditions.Options.GroupNames, cp)
+			case "fs_path":
+				conditions.Options.FsPaths = append(conditions.Options.FsPaths, cp)
+			default:
+				conditions.Options.Names = append(conditions.Options.Names, cp)
+			}
+		}
+	}
+	conditions.FsEvents = r.Form["fs_events"]
+	conditions.ProviderEvents = r.Form["provider_events"]
+	conditions.Options.Protocols = r.Form["protocols"]
+	conditions.Options.ProviderObjects = r.Form["provider_objects"]
+	for _, val := range r.Form["statuses"] {
+		status, err := strconv.Atoi(val)
+		if err != nil {
+			return conditions, fmt.Errorf("invalid status: %w", err)
+		}
+		conditions.Options.Statuses = append(conditions.Options.Statuses, status)
+	}
+	var err error
+	conditions.Options.MinFileSize, err = strconv.ParseInt(r.Form.Get("min_size"), 10, 64)
+	if err != nil {
+		return conditions, fmt.Errorf("invalid min file size: %w", err)
+	}
+	conditions.Options.MaxFileSize, err = strconv.ParseInt(r.Form.Get("max_size"), 10, 64)
+	if err != nil {
+		return conditions, fmt.Errorf("invalid max file size: %w", err)
+	}
+	return conditions, nil
+}
+
+func getEventRuleActionsFromPostFields(r *http.Request) ([]dataprovider.EventAction, error) {
+	var indexes []int
+
+	for k := range r.Form {
+		if strings.HasPrefix(k, "action_type") {
+			idx, err := strconv.Atoi(strings.TrimPrefix(k, "action_type"))
+			if err != nil {
+				return nil, fmt.Errorf("invalid action index: %w", err)
+			}
+			indexes = append(indexes, idx)
+		}
+	}
+	// actions are executed in order
+	sort.Ints(indexes)
+	actions := make([]dataprovider.EventAction, 0, len(indexes))
+	for _, idx := range indexes {
+		actionType, err := strconv.Atoi(r.Form.Get(fmt.Sprintf("action_type%v", idx)))
+		if err != nil {
+			return actions, fmt.Errorf("invalid action type: %w", err)
+		}
+		action := dataprovider.EventAction{
+			Type: dataprovider.EventActionType(actionType),
+		}
+		switch action.Type {
+		case dataprovider.EventActionTypeHTTP:
+			timeout, err := strconv.Atoi(r.Form.Get(fmt.Sprintf("http_timeout%v", idx)))
+			if err != nil {
+				return actions, fmt.Errorf("invalid HTTP timeout: %w", err)
+			}
+			action.HTTPConfig = dataprovider.EventActionHTTPConfig{
+				Endpoint: strings.TrimSpace(r.Form.Get(fmt.Sprintf("http_endpoint%v", idx))),
+				Method:   r.Form.Get(fmt.Sprintf("http_method%v", idx)),
+				Headers:  getKeyValuesFromPostField(r, fmt.Sprintf("http_headers%v", idx), ":"),
+				Body:     r.Form.Get(fmt.Sprintf("http_body%v", idx)),
+				Timeout:  timeout,
+			}
+		case dataprovider.EventActionTypeCommand:
+			timeout, err := strconv.Atoi(r.Form.Get(fmt.Sprintf("cmd_timeout%v", idx)))
+			if err != nil {
+				return actions, fmt.Errorf("invalid command timeout: %w", err)
+			}
+			action.CmdConfig = dataprovider.EventActionCommandConfig{
+				Cmd:     strings.TrimSpace(r.Form.Get(fmt.Sprintf("cmd_path%v", idx))),
+				Timeout: timeout,
+				EnvVars: getKeyValuesFromPostField(r, fmt.Sprintf("cmd_env_vars%v", idx), "="),
+			}
+		case dataprovider.EventActionTypeEmail:
+			action.EmailConfig = dataprovider.EventActionEmailConfig{
+				Recipients: getSliceFromDelimitedValues(r.Form.Get(fmt.Sprintf("email_recipients%v", idx)), ","),
+				Subject:    r.Form.Get(fmt.Sprintf("email_subject%v", idx)),
+				Body:       r.Form.Get(fmt.Sprintf("email_body%v", idx)),
+			}
+		default:
+			action.FsConfig = dataprovider.EventActionFsConfig{
+				Target: strings.TrimSpace(r.Form.Get(fmt.Sprintf("fs_target%v", idx))),
+			}
+		}
+		actions = append(actions, action)
+	}
+	return actions, nil
+}
+
+func getEventRuleFromPostFields(r *http.Request) (dataprovider.EventRule, error) {
+	var rule dataprovider.EventRule
+	err := r.ParseForm()
+	if err != nil {
+		return rule, err
+	}
+	trigger, err := strconv.Atoi(r.Form.Get("trigger"))
+	if err != nil {
+		return rule, fmt.Errorf("invalid trigger: %w", err)
+	}
+	rule.Name = r.Form.Get("name")
+	rule.Description = r.Form.Get("description")
+	rule.Trigger = dataprovider.EventTrigger(trigger)
+	rule.Conditions, err = getEventRuleConditionsFromPostFields(r)
+	if err != nil {
+		return rule, err
+	}
+	rule.Actions, err = getEventRuleActionsFromPostFields(r)
+	return rule, err
+}
+
+func getDataRetentionFromPostFields(r *http.Request) ([]sdk.DataRetentionPolicy, error) {
+	var res []sdk.DataRetentionPolicy
+
+	for k := range r.Form {
+		if strings.HasPrefix(k, "retention_path") {
+			p := strings.TrimSpace(r.Form.Get(k))
+			if p == "" {
+				continue
+			}
+			idx := strings.TrimPrefix(k, "retention_path")
+			retention, err := strconv.Atoi(r.Form.Get(fmt.Sprintf("retention_value%v", idx)))
+			if err != nil {
+				return res, fmt.Errorf("invalid retention for path %#v: %w", p, err)
+			}
+			res = append(res, sdk.DataRetentionPolicy{
+				Path:      p,
+				Retention: retention,
+				DeleteEmptyDirs: util.IsStringInSlice("delete_empty_dirs",
+					r.Form[fmt.Sprintf("retention_options%v", idx)]),
+				IgnoreUserPermissions: util.IsStringInSlice("ignore_user_permissions",
+					r.Form[fmt.Sprintf("retention_options%v", idx)]),
+			})
+		}
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Path < res[j].Path
+	})
+	return res, nil
+}
+
+func getScheduleFromPostFields(r *http.Request) (dataprovider.Schedule, error) {
+	var schedule dataprovider.Schedule
+	err := r.ParseForm()
+	if err != nil {
+		return schedule, err
+	}
+	task, err := strconv.Atoi(r.Form.Get("task"))
+	if err != nil {
+		return schedule, fmt.Errorf("invalid task: %w", err)
+	}
+	status, err := strconv.Atoi(r.Form.Get("status"))
+	if err != nil {
+		return schedule, fmt.Errorf("invalid status: %w", err)
+	}
+	schedule.Name = r.Form.Get("name")
+	schedule.Description = r.Form.Get("description")
+	schedule.Status = status
+	schedule.CronExpression = strings.TrimSpace(r.Form.Get("cron_expression"))
+	schedule.Task = dataprovider.ScheduleTask(task)
+	schedule.Options.AllUsers = r.Form.Get("all_users") != ""
+	schedule.Options.Usernames = getSliceFromDelimitedValues(r.Form.Get("usernames"), ",")
+	schedule.Options.AllFolders = r.Form.Get("all_folders") != ""
+	schedule.Options.FolderNames = getSliceFromDelimitedValues(r.Form.Get("folder_names"), ",")
+	if schedule.Task == dataprovider.ScheduleTaskRetentionCheck {
+		schedule.Options.Notifications = r.Form["notifications"]
+		schedule.Options.Email = strings.TrimSpace(r.Form.Get("notifications_email"))
+		schedule.Options.Retention, err = getDataRetentionFromPostFields(r)
+	}
+	return schedule, err
+}
+
+func handleWebAdminTwoFactor(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxRequestSize)
+	renderTwoFactorPage(w, "")
+}
+
+func handleWebAdminTwoFactorRecovery(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxRequestSize)
+	renderTwoFactorRecoveryPage(w, "")
+}
+
+func handleWebAdminMFA(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxRequestSize)
+	renderMFAPage(w, r)
+}
+
+func handleWebAdminProfile(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxRequestSize)
+	renderProfilePage(w, r, "")
+}
+
+func handleWebAdminChangePwd(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxRequestSize)
+	renderChangePasswordPage(w, r, "")
+}
+
+func handleWebAdminChangePwdPost(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxRequestSize)
+	err := r.ParseForm()
+	if err != nil {
+		renderChangePasswordPage(w, r, err.Error())
+		return
+	}
+	if err := verifyCSRFToken(r.Form.Get(csrfFormToken)); err != nil {
+		renderForbiddenPage(w, r, err.Error())
+		return
+	}
+	err = doChangeAdminPassword(r, r.Form.Get("current_password"), r.Form.Get("new_password1"),
+		r.Form.Get("new_password2"))
+	if err != nil {
+		renderChangePasswordPage(w, r, err.Error())
+		return
+	}
+	handleWebLogout(w, r)
+}
+
+func handleWebAdminProfilePost(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxRequestSize)
+	err := r.ParseForm()
+	if err != nil {
+		renderProfilePage(w, r, err.Error())
+		return
+	}
+	if err := verifyCSRFToken(r.Form.Get(csrfFormToken)); err != nil {
+		renderForbiddenPage(w, r, err.Error())
+		return
+	}
+	claims, err := getTokenClaims(r)
+	if err != nil || claims.Username == "" {
+		renderProfilePage(w, r, "Invalid token claims")
+		return
+	}
+	admin, err := dataprovider.AdminExists(claims.Username)
+	if err != nil {
+		renderProfilePage(w, r, err.Error())
+		return
+	}
+	admin.Filters.AllowAPIKeyAuth = len(r.Form.Get("allow_api_key_auth")) > 0
+	admin.Email = r.Form.Get("email")
+	admin.Description = r.Form.Get("description")
+	err = dataprovider.UpdateAdmin(&admin, dataprovider.ActionExecutorSelf, util.GetIPFromRemoteAddress(r.RemoteAddr))
+	if err != nil {
+		renderProfilePage(w, r, err.Error())
+		return
 	}
 	renderMessagePage(w, r, "Profile updated", "", http.StatusOK, nil,
 		"Your profile has been successfully updated")
@@ -1231,6 +1998,14 @@ func handleWebRestore(w http.ResponseWriter, r *http.Request) {
 		renderBadRequestPage(w, r, errors.New("invalid token claims"))
 		return
 	}
+	admin, err := getCurrentWebAdmin(w, r)
+	if err != nil {
+		return
+	}
+	if admin.HasScope() {
+		renderForbiddenPage(w, r, "Admins with a scope cannot restore backups")
+		return
+	}
 	err = r.ParseMultipartForm(MaxRestoreSize)
 	if err != nil {
 		renderMaintenancePage(w, r, err.Error())
@@ -1418,6 +2193,31 @@ func handleWebDefenderPage(w http.ResponseWriter, r *http.Request) {
 	renderAdminTemplate(w, templateDefender, data)
 }
 
+func handleWebAccountLockoutsPage(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxRequestSize)
+	data := lockoutsPage{
+		basePage:        getBasePageData(pageLockoutsTitle, webAccountLockoutsPath, r),
+		LockoutsListURL: webAccountLockoutsListPath,
+	}
+
+	renderAdminTemplate(w, templateLockouts, data)
+}
+
+func handleWebEventsPage(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxRequestSize)
+	if !isEventSearchAvailable() {
+		renderNotFoundPage(w, r, errors.New("events search is not available"))
+		return
+	}
+	data := eventsPage{
+		basePage:          getBasePageData(pageEventsTitle, webEventsPath, r),
+		FsEventsURL:       webEventsFsSearchPath,
+		ProviderEventsURL: webEventsProviderSearchPath,
+	}
+
+	renderAdminTemplate(w, templateEvents, data)
+}
+
 func handleGetWebUsers(w http.ResponseWriter, r *http.Request) {
 	r.Body = http.MaxBytesReader(w, r.Body, maxRequestSize)
 	limit := defaultQueryLimit
@@ -1428,9 +2228,13 @@ func handleGetWebUsers(w http.ResponseWriter, r *http.Request) {
 			limit = defaultQueryLimit
 		}
 	}
+	admin, err := getCurrentWebAdmin(w, r)
+	if err != nil {
+		return
+	}
 	users := make([]dataprovider.User, 0, limit)
 	for {
-		u, err := dataprovider.GetUsers(limit, len(users), dataprovider.OrderASC)
+		u, err := dataprovider.GetUsersForAdmin(&admin, limit, len(users), dataprovider.OrderASC)
 		if err != nil {
 			renderInternalServerErrorPage(w, r, err)
 			return
@@ -1450,8 +2254,12 @@ func handleGetWebUsers(w http.ResponseWriter, r *http.Request) {
 func handleWebTemplateFolderGet(w http.ResponseWriter, r *http.Request) {
 	r.Body = http.MaxBytesReader(w, r.Body, maxRequestSize)
 	if r.URL.Query().Get("from") != "" {
+		admin, err := getCurrentWebAdmin(w, r)
+		if err != nil {
+			return
+		}
 		name := r.URL.Query().Get("from")
-		folder, err := dataprovider.GetFolderByName(name)
+		folder, err := getFolderInScope(&admin, name)
 		if err == nil {
 			renderFolderPage(w, r, folder, folderPageModeTemplate, "")
 		} else if _, ok := err.(*util.RecordNotFoundError); ok {
@@ -1488,6 +2296,17 @@ func handleWebTemplateFolderPost(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	templateFolder.FsConfig = fsConfig
+	templateFolder.UploadDataTransfer, templateFolder.DownloadDataTransfer, templateFolder.TotalDataTransfer,
+		templateFolder.DataTransferResetPeriod, err = getDataTransferLimitsFromPostFields(r)
+	if err != nil {
+		renderMessagePage(w, r, "Error parsing folders fields", "", http.StatusBadRequest, err, "")
+		return
+	}
+	templateFolder.DataRetention, err = getDataRetentionFromPostFields(r)
+	if err != nil {
+		renderMessagePage(w, r, "Error parsing folders fields", "", http.StatusBadRequest, err, "")
+		return
+	}
 
 	var dump dataprovider.BackupData
 	dump.Version = dataprovider.DumpVersion
@@ -1514,8 +2333,12 @@ func handleWebTemplateFolderPost(w http.ResponseWriter, r *http.Request) {
 func handleWebTemplateUserGet(w http.ResponseWriter, r *http.Request) {
 	r.Body = http.MaxBytesReader(w, r.Body, maxRequestSize)
 	if r.URL.Query().Get("from") != "" {
+		admin, err := getCurrentWebAdmin(w, r)
+		if err != nil {
+			return
+		}
 		username := r.URL.Query().Get("from")
-		user, err := dataprovider.UserExists(username)
+		user, err := getUserInScope(&admin, username)
 		if err == nil {
 			user.SetEmptySecrets()
 			user.Email = ""
@@ -1574,8 +2397,12 @@ func handleWebTemplateUserPost(w http.ResponseWriter, r *http.Request) {
 func handleWebAddUserGet(w http.ResponseWriter, r *http.Request) {
 	r.Body = http.MaxBytesReader(w, r.Body, maxRequestSize)
 	if r.URL.Query().Get("clone-from") != "" {
+		admin, err := getCurrentWebAdmin(w, r)
+		if err != nil {
+			return
+		}
 		username := r.URL.Query().Get("clone-from")
-		user, err := dataprovider.UserExists(username)
+		user, err := getUserInScope(&admin, username)
 		if err == nil {
 			user.ID = 0
 			user.Username = ""
@@ -1595,8 +2422,12 @@ func handleWebAddUserGet(w http.ResponseWriter, r *http.Request) {
 
 func handleWebUpdateUserGet(w http.ResponseWriter, r *http.Request) {
 	r.Body = http.MaxBytesReader(w, r.Body, maxRequestSize)
+	admin, err := getCurrentWebAdmin(w, r)
+	if err != nil {
+		return
+	}
 	username := getURLParam(r, "username")
-	user, err := dataprovider.UserExists(username)
+	user, err := getUserInScope(&admin, username)
 	if err == nil {
 		renderUserPage(w, r, &user, userPageModeUpdate, "")
 	} else if _, ok := err.(*util.RecordNotFoundError); ok {
@@ -1622,6 +2453,15 @@ func handleWebAddUserPost(w http.ResponseWriter, r *http.Request) {
 		renderForbiddenPage(w, r, err.Error())
 		return
 	}
+	admin, err := getCurrentWebAdmin(w, r)
+	if err != nil {
+		return
+	}
+	user.Filters.CreatedBy = claims.Username
+	if err := admin.CheckUserScope(&user); err != nil {
+		renderUserPage(w, r, &user, userPageModeAdd, err.Error())
+		return
+	}
 	err = dataprovider.AddUser(&user, claims.Username, util.GetIPFromRemoteAddress(r.RemoteAddr))
 	if err == nil {
 		http.Redirect(w, r, webUsersPath, http.StatusSeeOther)
@@ -1637,8 +2477,12 @@ func handleWebUpdateUserPost(w http.ResponseWriter, r *http.Request) {
 		renderBadRequestPage(w, r, errors.New("invalid token claims"))
 		return
 	}
+	admin, err := getCurrentWebAdmin(w, r)
+	if err != nil {
+		return
+	}
 	username := getURLParam(r, "username")
-	user, err := dataprovider.UserExists(username)
+	user, err := getUserInScope(&admin, username)
 	if _, ok := err.(*util.RecordNotFoundError); ok {
 		renderNotFoundPage(w, r, err)
 		return
@@ -1659,13 +2503,19 @@ func handleWebUpdateUserPost(w http.ResponseWriter, r *http.Request) {
 	updatedUser.Username = user.Username
 	updatedUser.Filters.RecoveryCodes = user.Filters.RecoveryCodes
 	updatedUser.Filters.TOTPConfig = user.Filters.TOTPConfig
+	updatedUser.Filters.CreatedBy = user.Filters.CreatedBy
 	updatedUser.SetEmptySecretsIfNil()
 	if updatedUser.Password == redactedSecret {
 		updatedUser.Password = user.Password
 	}
 	updateEncryptedSecrets(&updatedUser.FsConfig, user.FsConfig.S3Config.AccessSecret, user.FsConfig.AzBlobConfig.AccountKey,
 		user.FsConfig.AzBlobConfig.SASURL, user.FsConfig.GCSConfig.Credentials, user.FsConfig.CryptConfig.Passphrase,
-		user.FsConfig.SFTPConfig.Password, user.FsConfig.SFTPConfig.PrivateKey)
+		user.FsConfig.SFTPConfig.Password, user.FsConfig.SFTPConfig.PrivateKey, user.FsConfig.FTPConfig.Password,
+		user.FsConfig.WebDAVConfig.Password, user.FsConfig.WebDAVConfig.BearerToken, user.FsConfig.Encryption.Passphrase)
+	if err := admin.CheckUserScope(&updatedUser); err != nil {
+		renderUserPage(w, r, &user, userPageModeUpdate, err.Error())
+		return
+	}
 
 	err = dataprovider.UpdateUser(&updatedUser, claims.Username, util.GetIPFromRemoteAddress(r.RemoteAddr))
 	if err == nil {
@@ -1689,7 +2539,11 @@ func handleWebGetStatus(w http.ResponseWriter, r *http.Request) {
 
 func handleWebGetConnections(w http.ResponseWriter, r *http.Request) {
 	r.Body = http.MaxBytesReader(w, r.Body, maxRequestSize)
-	connectionStats := common.Connections.GetStats()
+	admin, err := getCurrentWebAdmin(w, r)
+	if err != nil {
+		return
+	}
+	connectionStats := getConnectionsInScope(&admin)
 	data := connectionsPage{
 		basePage:    getBasePageData(pageConnectionsTitle, webConnectionsPath, r),
 		Connections: connectionStats,
@@ -1725,6 +2579,17 @@ func handleWebAddFolderPost(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	folder.FsConfig = fsConfig
+	folder.UploadDataTransfer, folder.DownloadDataTransfer, folder.TotalDataTransfer, folder.DataTransferResetPeriod,
+		err = getDataTransferLimitsFromPostFields(r)
+	if err != nil {
+		renderFolderPage(w, r, folder, folderPageModeAdd, err.Error())
+		return
+	}
+	folder.DataRetention, err = getDataRetentionFromPostFields(r)
+	if err != nil {
+		renderFolderPage(w, r, folder, folderPageModeAdd, err.Error())
+		return
+	}
 
 	err = dataprovider.AddFolder(&folder)
 	if err == nil {
@@ -1736,8 +2601,12 @@ func handleWebAddFolderPost(w http.ResponseWriter, r *http.Request) {
 
 func handleWebUpdateFolderGet(w http.ResponseWriter, r *http.Request) {
 	r.Body = http.MaxBytesReader(w, r.Body, maxRequestSize)
+	admin, err := getCurrentWebAdmin(w, r)
+	if err != nil {
+		return
+	}
 	name := getURLParam(r, "name")
-	folder, err := dataprovider.GetFolderByName(name)
+	folder, err := getFolderInScope(&admin, name)
 	if err == nil {
 		renderFolderPage(w, r, folder, folderPageModeUpdate, "")
 	} else if _, ok := err.(*util.RecordNotFoundError); ok {
@@ -1754,8 +2623,12 @@ func handleWebUpdateFolderPost(w http.ResponseWriter, r *http.Request) {
 		renderBadRequestPage(w, r, errors.New("invalid token claims"))
 		return
 	}
+	admin, err := getCurrentWebAdmin(w, r)
+	if err != nil {
+		return
+	}
 	name := getURLParam(r, "name")
-	folder, err := dataprovider.GetFolderByName(name)
+	folder, err := getFolderInScope(&admin, name)
 	if _, ok := err.(*util.RecordNotFoundError); ok {
 		renderNotFoundPage(w, r, err)
 		return
@@ -1780,17 +2653,35 @@ func handleWebUpdateFolderPost(w http.ResponseWriter, r *http.Request) {
 		renderFolderPage(w, r, folder, folderPageModeUpdate, err.Error())
 		return
 	}
+	dataTransferUL, dataTransferDL, dataTransferTotal, dataTransferReset, err := getDataTransferLimitsFromPostFields(r)
+	if err != nil {
+		renderFolderPage(w, r, folder, folderPageModeUpdate, err.Error())
+		return
+	}
+	dataRetention, err := getDataRetentionFromPostFields(r)
+	if err != nil {
+		renderFolderPage(w, r, folder, folderPageModeUpdate, err.Error())
+		return
+	}
 	updatedFolder := &vfs.BaseVirtualFolder{
-		MappedPath:  r.Form.Get("mapped_path"),
-		Description: r.Form.Get("description"),
+		MappedPath:              r.Form.Get("mapped_path"),
+		Description:             r.Form.Get("description"),
+		UploadDataTransfer:      dataTransferUL,
+		DownloadDataTransfer:    dataTransferDL,
+		TotalDataTransfer:       dataTransferTotal,
+		DataTransferResetPeriod: dataTransferReset,
+		DataRetention:           dataRetention,
 	}
 	updatedFolder.ID = folder.ID
 	updatedFolder.Name = folder.Name
+	updatedFolder.Groups = folder.Groups
 	updatedFolder.FsConfig = fsConfig
 	updatedFolder.FsConfig.SetEmptySecretsIfNil()
 	updateEncryptedSecrets(&updatedFolder.FsConfig, folder.FsConfig.S3Config.AccessSecret, folder.FsConfig.AzBlobConfig.AccountKey,
 		folder.FsConfig.AzBlobConfig.SASURL, folder.FsConfig.GCSConfig.Credentials, folder.FsConfig.CryptConfig.Passphrase,
-		folder.FsConfig.SFTPConfig.Password, folder.FsConfig.SFTPConfig.PrivateKey)
+		folder.FsConfig.SFTPConfig.Password, folder.FsConfig.SFTPConfig.PrivateKey, folder.FsConfig.FTPConfig.Password,
+		folder.FsConfig.WebDAVConfig.Password, folder.FsConfig.WebDAVConfig.BearerToken,
+		folder.FsConfig.Encryption.Passphrase)
 
 	err = dataprovider.UpdateFolder(updatedFolder, folder.Users, claims.Username, util.GetIPFromRemoteAddress(r.RemoteAddr))
 	if err != nil {
@@ -1801,9 +2692,13 @@ func handleWebUpdateFolderPost(w http.ResponseWriter, r *http.Request) {
 }
 
 func getWebVirtualFolders(w http.ResponseWriter, r *http.Request, limit int) ([]vfs.BaseVirtualFolder, error) {
+	admin, err := getCurrentWebAdmin(w, r)
+	if err != nil {
+		return nil, err
+	}
 	folders := make([]vfs.BaseVirtualFolder, 0, limit)
 	for {
-		f, err := dataprovider.GetFolders(limit, len(folders), dataprovider.OrderASC)
+		f, err := dataprovider.GetFoldersForAdmin(&admin, limit, len(folders), dataprovider.OrderASC)
 		if err != nil {
 			renderInternalServerErrorPage(w, r, err)
 			return folders, err
@@ -1837,3 +2732,388 @@ func handleWebGetFolders(w http.ResponseWriter, r *http.Request) {
 	}
 	renderAdminTemplate(w, templateFolders, data)
 }
+
+func getWebGroups(w http.ResponseWriter, r *http.Request, limit int) ([]dataprovider.Group, error) {
+	admin, err := getCurrentWebAdmin(w, r)
+	if err != nil {
+		return nil, err
+	}
+	return getWebGroupsForAdmin(w, r, &admin, limit)
+}
+
+func getWebGroupsForAdmin(w http.ResponseWriter, r *http.Request, admin *dataprovider.Admin, limit int) ([]dataprovider.Group, error) {
+	groups := make([]dataprovider.Group, 0, limit)
+	for {
+		g, err := dataprovider.GetGroupsForAdmin(admin, limit, len(groups), dataprovider.OrderASC)
+		if err != nil {
+			renderInternalServerErrorPage(w, r, err)
+			return groups, err
+		}
+		groups = append(groups, g...)
+		if len(g) < limit {
+			break
+		}
+	}
+	return groups, nil
+}
+
+func handleWebGetGroups(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxRequestSize)
+	limit := defaultQueryLimit
+	if _, ok := r.URL.Query()["qlimit"]; ok {
+		var err error
+		limit, err = strconv.Atoi(r.URL.Query().Get("qlimit"))
+		if err != nil {
+			limit = defaultQueryLimit
+		}
+	}
+	groups, err := getWebGroups(w, r, limit)
+	if err != nil {
+		return
+	}
+
+	data := groupsPage{
+		basePage: getBasePageData(pageGroupsTitle, webGroupsPath, r),
+		Groups:   groups,
+	}
+	renderAdminTemplate(w, templateGroups, data)
+}
+
+func handleWebAddGroupGet(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxRequestSize)
+	if !checkWebGroupAddAllowed(w, r) {
+		return
+	}
+	renderGroupPage(w, r, dataprovider.Group{}, groupPageModeAdd, "")
+}
+
+func checkWebGroupAddAllowed(w http.ResponseWriter, r *http.Request) bool {
+	admin, err := getCurrentWebAdmin(w, r)
+	if err != nil {
+		return false
+	}
+	if admin.HasScope() {
+		renderForbiddenPage(w, r, "Admins with a scope cannot add groups")
+		return false
+	}
+	return true
+}
+
+func handleWebAddGroupPost(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxRequestSize)
+	claims, err := getTokenClaims(r)
+	if err != nil || claims.Username == "" {
+		renderBadRequestPage(w, r, errors.New("invalid token claims"))
+		return
+	}
+	if !checkWebGroupAddAllowed(w, r) {
+		return
+	}
+	group, err := getGroupFromPostFields(r)
+	if err != nil {
+		renderGroupPage(w, r, group, groupPageModeAdd, err.Error())
+		return
+	}
+	if err := verifyCSRFToken(r.Form.Get(csrfFormToken)); err != nil {
+		renderForbiddenPage(w, r, err.Error())
+		return
+	}
+	err = dataprovider.AddGroup(&group, claims.Username, util.GetIPFromRemoteAddress(r.RemoteAddr))
+	if err == nil {
+		http.Redirect(w, r, webGroupsPath, http.StatusSeeOther)
+	} else {
+		renderGroupPage(w, r, group, groupPageModeAdd, err.Error())
+	}
+}
+
+func handleWebUpdateGroupGet(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxRequestSize)
+	admin, err := getCurrentWebAdmin(w, r)
+	if err != nil {
+		return
+	}
+	name := getURLParam(r, "name")
+	group, err := getGroupInScope(&admin, name)
+	if err == nil {
+		renderGroupPage(w, r, group, groupPageModeUpdate, "")
+	} else if _, ok := err.(*util.RecordNotFoundError); ok {
+		renderNotFoundPage(w, r, err)
+	} else {
+		renderInternalServerErrorPage(w, r, err)
+	}
+}
+
+func handleWebUpdateGroupPost(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxRequestSize)
+	claims, err := getTokenClaims(r)
+	if err != nil || claims.Username == "" {
+		renderBadRequestPage(w, r, errors.New("invalid token claims"))
+		return
+	}
+	admin, err := getCurrentWebAdmin(w, r)
+	if err != nil {
+		return
+	}
+	name := getURLParam(r, "name")
+	group, err := getGroupInScope(&admin, name)
+	if _, ok := err.(*util.RecordNotFoundError); ok {
+		renderNotFoundPage(w, r, err)
+		return
+	} else if err != nil {
+		renderInternalServerErrorPage(w, r, err)
+		return
+	}
+	updatedGroup, err := getGroupFromPostFields(r)
+	if err != nil {
+		renderGroupPage(w, r, group, groupPageModeUpdate, err.Error())
+		return
+	}
+	if err := verifyCSRFToken(r.Form.Get(csrfFormToken)); err != nil {
+		renderForbiddenPage(w, r, err.Error())
+		return
+	}
+	updatedGroup.ID = group.ID
+	updatedGroup.Name = group.Name
+	updatedGroup.CreatedAt = group.CreatedAt
+	updatedGroup.Users = group.Users
+	updatedGroup.SetEmptySecretsIfNil()
+	fsConfig := group.UserSettings.FsConfig
+	updateEncryptedSecrets(&updatedGroup.UserSettings.FsConfig, fsConfig.S3Config.AccessSecret,
+		fsConfig.AzBlobConfig.AccountKey, fsConfig.AzBlobConfig.SASURL, fsConfig.GCSConfig.Credentials,
+		fsConfig.CryptConfig.Passphrase, fsConfig.SFTPConfig.Password, fsConfig.SFTPConfig.PrivateKey,
+		fsConfig.FTPConfig.Password, fsConfig.WebDAVConfig.Password, fsConfig.WebDAVConfig.BearerToken,
+		fsConfig.Encryption.Passphrase)
+	// group GCS credentials are stored inside the data provider, keep the existing ones if no new file is uploaded
+	gcsConfig := &updatedGroup.UserSettings.FsConfig.GCSConfig
+	if updatedGroup.UserSettings.FsConfig.Provider == sdk.GCSFilesystemProvider && gcsConfig.AutomaticCredentials == 0 &&
+		gcsConfig.Credentials.IsEmpty() && fsConfig.GCSConfig.Credentials != nil {
+		gcsConfig.Credentials = fsConfig.GCSConfig.Credentials
+	}
+	if err := admin.CheckGroupScope(&updatedGroup); err != nil {
+		renderGroupPage(w, r, group, groupPageModeUpdate, err.Error())
+		return
+	}
+
+	err = dataprovider.UpdateGroup(&updatedGroup, group.Users, claims.Username, util.GetIPFromRemoteAddress(r.RemoteAddr))
+	if err != nil {
+		renderGroupPage(w, r, group, groupPageModeUpdate, err.Error())
+		return
+	}
+	http.Redirect(w, r, webGroupsPath, http.StatusSeeOther)
+}
+
+func handleWebGetEventRules(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxRequestSize)
+	limit := defaultQueryLimit
+	if _, ok := r.URL.Query()["qlimit"]; ok {
+		var err error
+		limit, err = strconv.Atoi(r.URL.Query().Get("qlimit"))
+		if err != nil {
+			limit = defaultQueryLimit
+		}
+	}
+	rules := make([]dataprovider.EventRule, 0, limit)
+	for {
+		res, err := dataprovider.GetEventRules(limit, len(rules), dataprovider.OrderASC)
+		if err != nil {
+			renderInternalServerErrorPage(w, r, err)
+			return
+		}
+		rules = append(rules, res...)
+		if len(res) < limit {
+			break
+		}
+	}
+
+	data := eventRulesPage{
+		basePage: getBasePageData(pageEventRulesTitle, webEventRulesPath, r),
+		Rules:    rules,
+	}
+	renderAdminTemplate(w, templateEventRules, data)
+}
+
+func handleWebAddEventRuleGet(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxRequestSize)
+	renderEventRulePage(w, r, dataprovider.EventRule{}, eventRulePageModeAdd, "")
+}
+
+func handleWebAddEventRulePost(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxRequestSize)
+	claims, err := getTokenClaims(r)
+	if err != nil || claims.Username == "" {
+		renderBadRequestPage(w, r, errors.New("invalid token claims"))
+		return
+	}
+	rule, err := getEventRuleFromPostFields(r)
+	if err != nil {
+		renderEventRulePage(w, r, rule, eventRulePageModeAdd, err.Error())
+		return
+	}
+	if err := verifyCSRFToken(r.Form.Get(csrfFormToken)); err != nil {
+		renderForbiddenPage(w, r, err.Error())
+		return
+	}
+	err = dataprovider.AddEventRule(&rule, claims.Username, util.GetIPFromRemoteAddress(r.RemoteAddr))
+	if err != nil {
+		renderEventRulePage(w, r, rule, eventRulePageModeAdd, err.Error())
+		return
+	}
+	http.Redirect(w, r, webEventRulesPath, http.StatusSeeOther)
+}
+
+func handleWebUpdateEventRuleGet(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxRequestSize)
+	name := getURLParam(r, "name")
+	rule, err := dataprovider.EventRuleExists(name)
+	if err == nil {
+		renderEventRulePage(w, r, rule, eventRulePageModeUpdate, "")
+	} else if _, ok := err.(*util.RecordNotFoundError); ok {
+		renderNotFoundPage(w, r, err)
+	} else {
+		renderInternalServerErrorPage(w, r, err)
+	}
+}
+
+func handleWebUpdateEventRulePost(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxRequestSize)
+	claims, err := getTokenClaims(r)
+	if err != nil || claims.Username == "" {
+		renderBadRequestPage(w, r, errors.New("invalid token claims"))
+		return
+	}
+	name := getURLParam(r, "name")
+	rule, err := dataprovider.EventRuleExists(name)
+	if _, ok := err.(*util.RecordNotFoundError); ok {
+		renderNotFoundPage(w, r, err)
+		return
+	} else if err != nil {
+		renderInternalServerErrorPage(w, r, err)
+		return
+	}
+	updatedRule, err := getEventRuleFromPostFields(r)
+	if err != nil {
+		renderEventRulePage(w, r, rule, eventRulePageModeUpdate, err.Error())
+		return
+	}
+	if err := verifyCSRFToken(r.Form.Get(csrfFormToken)); err != nil {
+		renderForbiddenPage(w, r, err.Error())
+		return
+	}
+	updatedRule.ID = rule.ID
+	updatedRule.Name = rule.Name
+	updatedRule.CreatedAt = rule.CreatedAt
+	err = dataprovider.UpdateEventRule(&updatedRule, claims.Username, util.GetIPFromRemoteAddress(r.RemoteAddr))
+	if err != nil {
+		renderEventRulePage(w, r, updatedRule, eventRulePageModeUpdate, err.Error())
+		return
+	}
+	http.Redirect(w, r, webEventRulesPath, http.StatusSeeOther)
+}
+
+func handleWebGetSchedules(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxRequestSize)
+	limit := defaultQueryLimit
+	if _, ok := r.URL.Query()["qlimit"]; ok {
+		var err error
+		limit, err = strconv.Atoi(r.URL.Query().Get("qlimit"))
+		if err != nil {
+			limit = defaultQueryLimit
+		}
+	}
+	schedules := make([]dataprovider.Schedule, 0, limit)
+	for {
+		res, err := dataprovider.GetSchedules(limit, len(schedules), dataprovider.OrderASC)
+		if err != nil {
+			renderInternalServerErrorPage(w, r, err)
+			return
+		}
+		schedules = append(schedules, res...)
+		if len(res) < limit {
+			break
+		}
+	}
+
+	data := schedulesPage{
+		basePage:  getBasePageData(pageSchedulesTitle, webSchedulesPath, r),
+		Schedules: schedules,
+	}
+	renderAdminTemplate(w, templateSchedules, data)
+}
+
+func handleWebAddScheduleGet(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxRequestSize)
+	renderSchedulePage(w, r, dataprovider.Schedule{Status: dataprovider.ScheduleStatusEnabled}, schedulePageModeAdd, "")
+}
+
+func handleWebAddSchedulePost(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxRequestSize)
+	claims, err := getTokenClaims(r)
+	if err != nil || claims.Username == "" {
+		renderBadRequestPage(w, r, errors.New("invalid token claims"))
+		return
+	}
+	schedule, err := getScheduleFromPostFields(r)
+	if err != nil {
+		renderSchedulePage(w, r, schedule, schedulePageModeAdd, err.Error())
+		return
+	}
+	if err := verifyCSRFToken(r.Form.Get(csrfFormToken)); err != nil {
+		renderForbiddenPage(w, r, err.Error())
+		return
+	}
+	err = dataprovider.AddSchedule(&schedule, claims.Username, util.GetIPFromRemoteAddress(r.RemoteAddr))
+	if err != nil {
+		renderSchedulePage(w, r, schedule, schedulePageModeAdd, err.Error())
+		return
+	}
+	http.Redirect(w, r, webSchedulesPath, http.StatusSeeOther)
+}
+
+func handleWebUpdateScheduleGet(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxRequestSize)
+	name := getURLParam(r, "name")
+	schedule, err := dataprovider.ScheduleExists(name)
+	if err == nil {
+		renderSchedulePage(w, r, schedule, schedulePageModeUpdate, "")
+	} else if _, ok := err.(*util.RecordNotFoundError); ok {
+		renderNotFoundPage(w, r, err)
+	} else {
+		renderInternalServerErrorPage(w, r, err)
+	}
+}
+
+func handleWebUpdateSchedulePost(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxRequestSize)
+	claims, err := getTokenClaims(r)
+	if err != nil || claims.Username == "" {
+		renderBadRequestPage(w, r, errors.New("invalid token claims"))
+		return
+	}
+	name := getURLParam(r, "name")
+	schedule, err := dataprovider.ScheduleExists(name)
+	if _, ok := err.(*util.RecordNotFoundError); ok {
+		renderNotFoundPage(w, r, err)
+		return
+	} else if err != nil {
+		renderInternalServerErrorPage(w, r, err)
+		return
+	}
+	updatedSchedule, err := getScheduleFromPostFields(r)
+	if err != nil {
+		renderSchedulePage(w, r, schedule, schedulePageModeUpdate, err.Error())
+		return
+	}
+	if err := verifyCSRFToken(r.Form.Get(csrfFormToken)); err != nil {
+		renderForbiddenPage(w, r, err.Error())
+		return
+	}
+	updatedSchedule.ID = schedule.ID
+	updatedSchedule.Name = schedule.Name
+	updatedSchedule.CreatedAt = schedule.CreatedAt
+	err = dataprovider.UpdateSchedule(&updatedSchedule, claims.Username, util.GetIPFromRemoteAddress(r.RemoteAddr))
+	if err != nil {
+		renderSchedulePage(w, r, updatedSchedule, schedulePageModeUpdate, err.Error())
+		return
+	}
+	http.Redirect(w, r, webSchedulesPath, http.StatusSeeOther)
+}
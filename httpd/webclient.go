@@ -11,6 +11,7 @@ import (
 	"os"
 	"path"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
 
@@ -39,11 +40,19 @@ const (
 	templateClientTwoFactorRecovery = "twofactor-recovery.html"
 	templateClientMFA               = "mfa.html"
 	templateClientEditFile          = "editfile.html"
+	templateClientShare             = "share.html"
+	templateClientShares            = "shares.html"
+	templateClientShareUploads      = "shareuploads.html"
+	templateClientUploadToShare     = "uploadtoshare.html"
 	pageClientFilesTitle            = "My Files"
 	pageClientProfileTitle          = "My Profile"
 	pageClientChangePwdTitle        = "Change password"
 	pageClient2FATitle              = "Two-factor auth"
 	pageClientEditFileTitle         = "Edit file"
+	pageClientSharesTitle           = "Shares"
+	pageClientAddShareTitle         = "Add share"
+	pageClientUpdateShareTitle      = "Update share"
+	pageClientShareUploadsTitle     = "Share uploads"
 )
 
 // condResult is the result of an HTTP request precondition check.
@@ -78,6 +87,9 @@ type baseClientPage struct {
 	MFATitle     string
 	FilesTitle   string
 	ProfileTitle string
+	SharesURL    string
+	ShareURL     string
+	SharesTitle  string
 	Version      string
 	CSRFToken    string
 	LoggedUser   *dataprovider.User
@@ -101,15 +113,44 @@ type filesPage struct {
 	CurrentDir    string
 	DirsURL       string
 	DownloadURL   string
+	UploadsURL    string
 	CanAddFiles   bool
 	CanCreateDirs bool
 	CanRename     bool
 	CanDelete     bool
 	CanDownload   bool
+	CanShare      bool
 	Error         string
 	Paths         []dirMapping
 }
 
+type clientSharesPage struct {
+	baseClientPage
+	Shares              []dataprovider.Share
+	BasePublicSharesURL string
+}
+
+type clientShareUploadsPage struct {
+	baseClientPage
+	Share   dataprovider.Share
+	Uploads []dataprovider.ShareUpload
+}
+
+type clientUploadToSharePage struct {
+	CurrentURL  string
+	Version     string
+	StaticURL   string
+	Name        string
+	Description string
+}
+
+type clientSharePage struct {
+	baseClientPage
+	Share *dataprovider.Share
+	Error string
+	IsAdd bool
+}
+
 type clientMessagePage struct {
 	baseClientPage
 	Error   string
@@ -190,6 +231,30 @@ func loadClientTemplates(templatesPath string) {
 		filepath.Join(templatesPath, templateClientDir, templateClientBaseLogin),
 		filepath.Join(templatesPath, templateClientDir, templateClientTwoFactorRecovery),
 	}
+	sharesPaths := []string{
+		filepath.Join(templatesPath, templateClientDir, templateClientBase),
+		filepath.Join(templatesPath, templateClientDir, templateClientShares),
+	}
+	sharePaths := []string{
+		filepath.Join(templatesPath, templateClientDir, templateClientBase),
+		filepath.Join(templatesPath, templateClientDir, templateClientShare),
+	}
+	shareUploadsPaths := []string{
+		filepath.Join(templatesPath, templateClientDir, templateClientBase),
+		filepath.Join(templatesPath, templateClientDir, templateClientShareUploads),
+	}
+	uploadToSharePaths := []string{
+		filepath.Join(templatesPath, templateClientDir, templateClientBaseLogin),
+		filepath.Join(templatesPath, templateClientDir, templateClientUploadToShare),
+	}
+	forgotPwdPaths := []string{
+		filepath.Join(templatesPath, templateClientDir, templateClientBaseLogin),
+		filepath.Join(templatesPath, templateClientDir, templateForgotPassword),
+	}
+	resetPwdPaths := []string{
+		filepath.Join(templatesPath, templateClientDir, templateClientBaseLogin),
+		filepath.Join(templatesPath, templateClientDir, templateResetPassword),
+	}
 
 	filesTmpl := util.LoadTemplate(nil, filesPaths...)
 	profileTmpl := util.LoadTemplate(nil, profilePaths...)
@@ -200,6 +265,12 @@ func loadClientTemplates(templatesPath string) {
 	twoFactorTmpl := util.LoadTemplate(nil, twoFactorPath...)
 	twoFactorRecoveryTmpl := util.LoadTemplate(nil, twoFactorRecoveryPath...)
 	editFileTmpl := util.LoadTemplate(nil, editFilePath...)
+	sharesTmpl := util.LoadTemplate(nil, sharesPaths...)
+	shareTmpl := util.LoadTemplate(nil, sharePaths...)
+	shareUploadsTmpl := util.LoadTemplate(nil, shareUploadsPaths...)
+	uploadToShareTmpl := util.LoadTemplate(nil, uploadToSharePaths...)
+	forgotPwdTmpl := util.LoadTemplate(nil, forgotPwdPaths...)
+	resetPwdTmpl := util.LoadTemplate(nil, resetPwdPaths...)
 
 	clientTemplates[templateClientFiles] = filesTmpl
 	clientTemplates[templateClientProfile] = profileTmpl
@@ -210,6 +281,12 @@ func loadClientTemplates(templatesPath string) {
 	clientTemplates[templateClientTwoFactor] = twoFactorTmpl
 	clientTemplates[templateClientTwoFactorRecovery] = twoFactorRecoveryTmpl
 	clientTemplates[templateClientEditFile] = editFileTmpl
+	clientTemplates[templateClientShares] = sharesTmpl
+	clientTemplates[templateClientShare] = shareTmpl
+	clientTemplates[templateClientShareUploads] = shareUploadsTmpl
+	clientTemplates[templateClientUploadToShare] = uploadToShareTmpl
+	clientTemplates[templateForgotPassword] = forgotPwdTmpl
+	clientTemplates[templateResetPassword] = resetPwdTmpl
 }
 
 func getBaseClientPageData(title, currentURL string, r *http.Request) baseClientPage {
@@ -231,6 +308,9 @@ func getBaseClientPageData(title, currentURL string, r *http.Request) baseClient
 		MFATitle:     pageClient2FATitle,
 		FilesTitle:   pageClientFilesTitle,
 		ProfileTitle: pageClientProfileTitle,
+		SharesURL:    webClientSharesPath,
+		ShareURL:     webClientSharePath,
+		SharesTitle:  pageClientSharesTitle,
 		Version:      fmt.Sprintf("%v-%v", v.Version, v.CommitHash),
 		CSRFToken:    csrfToken,
 		LoggedUser:   getUserFromToken(r),
@@ -277,6 +357,17 @@ func renderClientNotFoundPage(w http.ResponseWriter, r *http.Request, err error)
 	renderClientMessagePage(w, r, page404Title, page404Body, http.StatusNotFound, err, "")
 }
 
+func renderUploadToSharePage(w http.ResponseWriter, r *http.Request, share *dataprovider.Share) {
+	data := clientUploadToSharePage{
+		CurrentURL:  path.Join(webClientPubSharesPath, share.ShareID),
+		Version:     version.Get().Version,
+		StaticURL:   webStaticFilesPath,
+		Name:        share.Name,
+		Description: share.Description,
+	}
+	renderClientTemplate(w, templateClientUploadToShare, data)
+}
+
 func renderClientTwoFactorPage(w http.ResponseWriter, error string) {
 	data := twoFactorPage{
 		CurrentURL:  webClientTwoFactorPath,
@@ -300,6 +391,31 @@ func renderClientTwoFactorRecoveryPage(w http.ResponseWriter, error string) {
 	renderClientTemplate(w, templateTwoFactorRecovery, data)
 }
 
+func renderClientForgotPwdPage(w http.ResponseWriter, error string) {
+	data := resetPwdPage{
+		CurrentURL: webClientForgotPwdPath,
+		Version:    version.Get().Version,
+		Error:      error,
+		CSRFToken:  createCSRFToken(),
+		StaticURL:  webStaticFilesPath,
+		LoginURL:   webClientLoginPath,
+	}
+	renderClientTemplate(w, templateForgotPassword, data)
+}
+
+func renderClientResetPwdPage(w http.ResponseWriter, error string) {
+	data := resetPwdPage{
+		CurrentURL:   webClientResetPwdPath,
+		Version:      version.Get().Version,
+		Error:        error,
+		CSRFToken:    createCSRFToken(),
+		StaticURL:    webStaticFilesPath,
+		LoginURL:     webClientLoginPath,
+		ForgotPwdURL: webClientForgotPwdPath,
+	}
+	renderClientTemplate(w, templateResetPassword, data)
+}
+
 func renderClientMFAPage(w http.ResponseWriter, r *http.Request) {
 	data := clientMFAPage{
 		baseClientPage:  getBaseClientPageData(pageMFATitle, webClientMFAPath, r),
@@ -338,11 +454,13 @@ func renderFilesPage(w http.ResponseWriter, r *http.Request, dirName, error stri
 		CurrentDir:     url.QueryEscape(dirName),
 		DownloadURL:    webClientDownloadZipPath,
 		DirsURL:        webClientDirsPath,
+		UploadsURL:     webClientUploadsPath,
 		CanAddFiles:    user.CanAddFilesFromWeb(dirName),
 		CanCreateDirs:  user.CanAddDirsFromWeb(dirName),
 		CanRename:      user.CanRenameFromWeb(dirName, dirName),
 		CanDelete:      user.CanDeleteFromWeb(dirName),
 		CanDownload:    user.HasPerm(dataprovider.PermDownload, dirName),
+		CanShare:       user.CanManageShares(),
 	}
 	paths := []dirMapping{}
 	if dirName != "/" {
@@ -365,6 +483,24 @@ func renderFilesPage(w http.ResponseWriter, r *http.Request, dirName, error stri
 	renderClientTemplate(w, templateClientFiles, data)
 }
 
+func renderAddUpdateSharePage(w http.ResponseWriter, r *http.Request, share *dataprovider.Share,
+	error string, isAdd bool) {
+	currentURL := webClientSharePath
+	title := pageClientAddShareTitle
+	if !isAdd {
+		currentURL = fmt.Sprintf("%v/%v", webClientSharePath, url.PathEscape(share.ShareID))
+		title = pageClientUpdateShareTitle
+	}
+	data := clientSharePage{
+		baseClientPage: getBaseClientPageData(title, currentURL, r),
+		Share:          share,
+		Error:          error,
+		IsAdd:          isAdd,
+	}
+
+	renderClientTemplate(w, templateClientShare, data)
+}
+
 func renderClientProfilePage(w http.ResponseWriter, r *http.Request, error string) {
 	data := clientProfilePage{
 		baseClientPage: getBaseClientPageData(pageClientProfileTitle, webClientProfilePath, r),
@@ -408,7 +544,7 @@ func handleWebClientDownloadZip(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	user, err := dataprovider.UserExists(claims.Username)
+	user, err := dataprovider.GetUserWithGroupSettings(claims.Username)
 	if err != nil {
 		renderClientMessagePage(w, r, "Unable to retrieve your user", "", getRespStatus(err), nil, "")
 		return
@@ -453,7 +589,7 @@ func handleClientGetDirContents(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	user, err := dataprovider.UserExists(claims.Username)
+	user, err := dataprovider.GetUserWithGroupSettings(claims.Username)
 	if err != nil {
 		sendAPIResponse(w, r, nil, "Unable to retrieve your user", getRespStatus(err))
 		return
@@ -521,7 +657,7 @@ func handleClientGetFiles(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	user, err := dataprovider.UserExists(claims.Username)
+	user, err := dataprovider.GetUserWithGroupSettings(claims.Username)
 	if err != nil {
 		renderClientMessagePage(w, r, "Unable to retrieve your user", "", getRespStatus(err), nil, "")
 		return
@@ -578,7 +714,7 @@ func handleClientEditFile(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	user, err := dataprovider.UserExists(claims.Username)
+	user, err := dataprovider.GetUserWithGroupSettings(claims.Username)
 	if err != nil {
 		renderClientMessagePage(w, r, "Unable to retrieve your user", "", getRespStatus(err), nil, "")
 		return
@@ -723,3 +859,219 @@ func handleWebClientTwoFactorRecovery(w http.ResponseWriter, r *http.Request) {
 	r.Body = http.MaxBytesReader(w, r.Body, maxRequestSize)
 	renderClientTwoFactorRecoveryPage(w, "")
 }
+
+func handleClientAddShareGet(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxRequestSize)
+	share := &dataprovider.Share{Scope: dataprovider.ShareScopeRead}
+	dirName := "/"
+	if _, ok := r.URL.Query()["path"]; ok {
+		dirName = util.CleanPath(r.URL.Query().Get("path"))
+	}
+
+	if _, ok := r.URL.Query()["files"]; ok {
+		files := r.URL.Query().Get("files")
+		var filesList []string
+		err := json.Unmarshal([]byte(files), &filesList)
+		if err != nil {
+			renderClientMessagePage(w, r, "Invalid share list", "", http.StatusBadRequest, err, "")
+			return
+		}
+		for _, f := range filesList {
+			if f != "" {
+				share.Paths = append(share.Paths, path.Join(dirName, f))
+			}
+		}
+	}
+
+	renderAddUpdateSharePage(w, r, share, "", true)
+}
+
+func handleClientUpdateShareGet(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxRequestSize)
+	claims, err := getTokenClaims(r)
+	if err != nil || claims.Username == "" {
+		renderClientForbiddenPage(w, r, "Invalid token claims")
+		return
+	}
+	shareID := getURLParam(r, "id")
+	share, err := dataprovider.ShareExists(shareID, claims.Username)
+	if err == nil {
+		share.HideConfidentialData()
+		renderAddUpdateSharePage(w, r, &share, "", false)
+	} else if _, ok := err.(*util.RecordNotFoundError); ok {
+		renderClientNotFoundPage(w, r, err)
+	} else {
+		renderClientInternalServerErrorPage(w, r, err)
+	}
+}
+
+func handleClientAddSharePost(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxRequestSize)
+	claims, err := getTokenClaims(r)
+	if err != nil || claims.Username == "" {
+		renderClientForbiddenPage(w, r, "Invalid token claims")
+		return
+	}
+	share, err := getShareFromPostFields(r)
+	if err != nil {
+		renderAddUpdateSharePage(w, r, share, err.Error(), true)
+		return
+	}
+	if err := verifyCSRFToken(r.Form.Get(csrfFormToken)); err != nil {
+		renderClientForbiddenPage(w, r, err.Error())
+		return
+	}
+	share.ID = 0
+	share.ShareID = ""
+	share.LastUseAt = 0
+	share.Username = claims.Username
+	err = dataprovider.AddShare(share, claims.Username, util.GetIPFromRemoteAddress(r.RemoteAddr))
+	if err == nil {
+		http.Redirect(w, r, webClientSharesPath, http.StatusSeeOther)
+	} else {
+		renderAddUpdateSharePage(w, r, share, err.Error(), true)
+	}
+}
+
+func handleClientUpdateSharePost(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxRequestSize)
+	claims, err := getTokenClaims(r)
+	if err != nil || claims.Username == "" {
+		renderClientForbiddenPage(w, r, "Invalid token claims")
+		return
+	}
+	shareID := getURLParam(r, "id")
+	share, err := dataprovider.ShareExists(shareID, claims.Username)
+	if _, ok := err.(*util.RecordNotFoundError); ok {
+		renderClientNotFoundPage(w, r, err)
+		return
+	} else if err != nil {
+		renderClientInternalServerErrorPage(w, r, err)
+		return
+	}
+	updatedShare, err := getShareFromPostFields(r)
+	if err != nil {
+		renderAddUpdateSharePage(w, r, updatedShare, err.Error(), false)
+		return
+	}
+	if err := verifyCSRFToken(r.Form.Get(csrfFormToken)); err != nil {
+		renderClientForbiddenPage(w, r, err.Error())
+		return
+	}
+	updatedShare.ShareID = shareID
+	updatedShare.Username = claims.Username
+	if updatedShare.Password == redactedSecret {
+		updatedShare.Password = share.Password
+	}
+	err = dataprovider.UpdateShare(updatedShare, claims.Username, util.GetIPFromRemoteAddress(r.RemoteAddr))
+	if err == nil {
+		http.Redirect(w, r, webClientSharesPath, http.StatusSeeOther)
+	} else {
+		renderAddUpdateSharePage(w, r, updatedShare, err.Error(), false)
+	}
+}
+
+func handleClientGetShares(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxRequestSize)
+	claims, err := getTokenClaims(r)
+	if err != nil || claims.Username == "" {
+		renderClientForbiddenPage(w, r, "Invalid token claims")
+		return
+	}
+	limit := defaultQueryLimit
+	if _, ok := r.URL.Query()["qlimit"]; ok {
+		var err error
+		limit, err = strconv.Atoi(r.URL.Query().Get("qlimit"))
+		if err != nil {
+			limit = defaultQueryLimit
+		}
+	}
+	shares := make([]dataprovider.Share, 0, limit)
+	for {
+		sh, err := dataprovider.GetShares(limit, len(shares), dataprovider.OrderASC, claims.Username)
+		if err != nil {
+			renderClientInternalServerErrorPage(w, r, err)
+			return
+		}
+		shares = append(shares, sh...)
+		if len(sh) < limit {
+			break
+		}
+	}
+	data := clientSharesPage{
+		baseClientPage:      getBaseClientPageData(pageClientSharesTitle, webClientSharesPath, r),
+		Shares:              shares,
+		BasePublicSharesURL: webClientPubSharesPath,
+	}
+	renderClientTemplate(w, templateClientShares, data)
+}
+
+func handleClientGetShareUploads(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxRequestSize)
+	claims, err := getTokenClaims(r)
+	if err != nil || claims.Username == "" {
+		renderClientForbiddenPage(w, r, "Invalid token claims")
+		return
+	}
+	share, err := dataprovider.ShareExists(getURLParam(r, "id"), claims.Username)
+	if err != nil {
+		if _, ok := err.(*util.RecordNotFoundError); ok {
+			renderClientNotFoundPage(w, r, err)
+		} else {
+			renderClientInternalServerErrorPage(w, r, err)
+		}
+		return
+	}
+	share.HideConfidentialData()
+	uploads := make([]dataprovider.ShareUpload, 0, defaultQueryLimit)
+	for {
+		u, err := dataprovider.GetShareUploads(share.ShareID, defaultQueryLimit, len(uploads), dataprovider.OrderDESC)
+		if err != nil {
+			renderClientInternalServerErrorPage(w, r, err)
+			return
+		}
+		uploads = append(uploads, u...)
+		if len(u) < defaultQueryLimit {
+			break
+		}
+	}
+	data := clientShareUploadsPage{
+		baseClientPage: getBaseClientPageData(pageClientShareUploadsTitle, webClientSharesPath, r),
+		Share:          share,
+		Uploads:        uploads,
+	}
+	renderClientTemplate(w, templateClientShareUploads, data)
+}
+
+func getShareFromPostFields(r *http.Request) (*dataprovider.Share, error) {
+	share := &dataprovider.Share{}
+	if err := r.ParseForm(); err != nil {
+		return share, err
+	}
+	share.Name = r.Form.Get("name")
+	share.Description = r.Form.Get("description")
+	share.Paths = r.Form["paths"]
+	share.Password = r.Form.Get("password")
+	share.AllowFrom = getSliceFromDelimitedValues(r.Form.Get("allowed_ip"), ",")
+	scope, err := strconv.Atoi(r.Form.Get("scope"))
+	if err != nil {
+		return share, err
+	}
+	share.Scope = dataprovider.ShareScope(scope)
+	maxTokens, err := strconv.Atoi(r.Form.Get("max_tokens"))
+	if err != nil {
+		return share, err
+	}
+	share.MaxTokens = maxTokens
+	expirationDateMillis := int64(0)
+	expirationDateString := r.Form.Get("expiration_date")
+	if strings.TrimSpace(expirationDateString) != "" {
+		expirationDate, err := time.Parse(webDateTimeFormat, expirationDateString)
+		if err != nil {
+			return share, err
+		}
+		expirationDateMillis = util.GetTimeAsMsSinceEpoch(expirationDate)
+	}
+	share.ExpiresAt = expirationDateMillis
+	return share, nil
+}
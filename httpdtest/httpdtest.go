@@ -21,6 +21,7 @@ import (
 	"github.com/drakkan/sftpgo/v2/httpclient"
 	"github.com/drakkan/sftpgo/v2/httpd"
 	"github.com/drakkan/sftpgo/v2/kms"
+	"github.com/drakkan/sftpgo/v2/sdk"
 	"github.com/drakkan/sftpgo/v2/util"
 	"github.com/drakkan/sftpgo/v2/version"
 	"github.com/drakkan/sftpgo/v2/vfs"
@@ -35,6 +36,9 @@ const (
 	userPath              = "/api/v2/users"
 	versionPath           = "/api/v2/version"
 	folderPath            = "/api/v2/folders"
+	groupPath             = "/api/v2/groups"
+	eventRulesPath        = "/api/v2/eventrules"
+	schedulesPath         = "/api/v2/schedules"
 	serverStatusPath      = "/api/v2/status"
 	dumpDataPath          = "/api/v2/dumpdata"
 	loadDataPath          = "/api/v2/loaddata"
@@ -42,6 +46,7 @@ const (
 	defenderBanTime       = "/api/v2/defender/bantime"
 	defenderUnban         = "/api/v2/defender/unban"
 	defenderScore         = "/api/v2/defender/score"
+	accountLockoutsPath   = "/api/v2/lockouts"
 	adminPath             = "/api/v2/admins"
 	adminPwdPath          = "/api/v2/admin/changepwd"
 	apiKeysPath           = "/api/v2/apikeys"
@@ -375,6 +380,62 @@ func ChangeAdminPassword(currentPassword, newPassword string, expectedStatusCode
 	return body, err
 }
 
+// AdminForgotPassword requests a password reset code for the specified admin
+func AdminForgotPassword(username string, expectedStatusCode int) ([]byte, error) {
+	return forgotPassword(adminPath, username, expectedStatusCode)
+}
+
+// AdminResetPassword sets a new password for the specified admin using the reset code received via email
+func AdminResetPassword(username, code, newPassword string, expectedStatusCode int) ([]byte, error) {
+	return resetPassword(adminPath, username, code, newPassword, expectedStatusCode)
+}
+
+// UserForgotPassword requests a password reset code for the specified user
+func UserForgotPassword(username string, expectedStatusCode int) ([]byte, error) {
+	return forgotPassword(userPath, username, expectedStatusCode)
+}
+
+// UserResetPassword sets a new password for the specified user using the reset code received via email
+func UserResetPassword(username, code, newPassword string, expectedStatusCode int) ([]byte, error) {
+	return resetPassword(userPath, username, code, newPassword, expectedStatusCode)
+}
+
+func forgotPassword(basePath, username string, expectedStatusCode int) ([]byte, error) {
+	var body []byte
+	resp, err := sendHTTPRequest(http.MethodPost, buildURLRelativeToBase(basePath, url.PathEscape(username), "forgot-password"),
+		nil, "", "")
+	if err != nil {
+		return body, err
+	}
+	defer resp.Body.Close()
+
+	err = checkResponse(resp.StatusCode, expectedStatusCode)
+	body, _ = getResponseBody(resp)
+
+	return body, err
+}
+
+func resetPassword(basePath, username, code, newPassword string, expectedStatusCode int) ([]byte, error) {
+	var body []byte
+
+	pwdReset := make(map[string]string)
+	pwdReset["code"] = code
+	pwdReset["password"] = newPassword
+
+	asJSON, _ := json.Marshal(&pwdReset)
+	resp, err := sendHTTPRequest(http.MethodPost, buildURLRelativeToBase(basePath, url.PathEscape(username), "reset-password"),
+		bytes.NewBuffer(asJSON), "application/json", "")
+	if err != nil {
+		return body, err
+	}
+	defer resp.Body.Close()
+
+	err = checkResponse(resp.StatusCode, expectedStatusCode)
+	body, _ = getResponseBody(resp)
+
+	return body, err
+}
+
 // GetAPIKeys returns a list of API keys and checks the received HTTP Status code against expectedStatusCode.
 // The number of results can be limited specifying a limit.
 // Some results can be skipped specifying an offset.
@@ -471,6 +532,70 @@ func RemoveAPIKey(apiKey dataprovider.APIKey, expectedStatusCode int) ([]byte, e
 	return body, checkResponse(resp.StatusCode, expectedStatusCode)
 }
 
+// GetUserS3AccessKeys returns the S3 access keys for the given user and checks the received HTTP Status code
+// against expectedStatusCode.
+func GetUserS3AccessKeys(username string, expectedStatusCode int) ([]dataprovider.S3AccessKey, []byte, error) {
+	var keys []dataprovider.S3AccessKey
+	var body []byte
+	resp, err := sendHTTPRequest(http.MethodGet, buildURLRelativeToBase(userPath, url.PathEscape(username), "s3keys"),
+		nil, "", getDefaultToken())
+	if err != nil {
+		return keys, body, err
+	}
+	defer resp.Body.Close()
+	err = checkResponse(resp.StatusCode, expectedStatusCode)
+	if err == nil && expectedStatusCode == http.StatusOK {
+		err = render.DecodeJSON(resp.Body, &keys)
+	} else {
+		body, _ = getResponseBody(resp)
+	}
+	return keys, body, err
+}
+
+// AddUserS3AccessKey adds a new S3 access key for the given user and checks the received HTTP Status code
+// against expectedStatusCode. The access key id and the secret access key are returned
+func AddUserS3AccessKey(username, description string, expectedStatusCode int) (string, string, []byte, error) {
+	var body []byte
+	asJSON, _ := json.Marshal(map[string]string{"description": description})
+	resp, err := sendHTTPRequest(http.MethodPost, buildURLRelativeToBase(userPath, url.PathEscape(username), "s3keys"),
+		bytes.NewBuffer(asJSON), "application/json", getDefaultToken())
+	if err != nil {
+		return "", "", body, err
+	}
+	defer resp.Body.Close()
+	err = checkResponse(resp.StatusCode, expectedStatusCode)
+	if expectedStatusCode != http.StatusCreated || err != nil {
+		body, _ = getResponseBody(resp)
+		return "", "", body, err
+	}
+	response := make(map[string]string)
+	err = render.DecodeJSON(resp.Body, &response)
+	if err != nil {
+		return "", "", body, err
+	}
+	if response["access_key_id"] == "" || response["secret_access_key"] == "" {
+		return "", "", body, errors.New("access key id and secret access key must be returned")
+	}
+	if resp.Header.Get("X-Object-ID") != response["access_key_id"] {
+		return "", "", body, errors.New("X-Object-ID header does not match the access key id")
+	}
+	return response["access_key_id"], response["secret_access_key"], body, nil
+}
+
+// RemoveUserS3AccessKey removes an existing S3 access key and checks the received HTTP Status code
+// against expectedStatusCode.
+func RemoveUserS3AccessKey(username, accessKeyID string, expectedStatusCode int) ([]byte, error) {
+	var body []byte
+	resp, err := sendHTTPRequest(http.MethodDelete, buildURLRelativeToBase(userPath, url.PathEscape(username), "s3keys",
+		url.PathEscape(accessKeyID)), nil, "", getDefaultToken())
+	if err != nil {
+		return body, err
+	}
+	defer resp.Body.Close()
+	body, _ = getResponseBody(resp)
+	return body, checkResponse(resp.StatusCode, expectedStatusCode)
+}
+
 // GetAPIKeyByID gets a API key by ID and checks the received HTTP Status code against expectedStatusCode.
 func GetAPIKeyByID(keyID string, expectedStatusCode int) (dataprovider.APIKey, []byte, error) {
 	var apiKey dataprovider.APIKey
@@ -539,6 +664,31 @@ func UpdateQuotaUsage(user dataprovider.User, mode string, expectedStatusCode in
 	return body, checkResponse(resp.StatusCode, expectedStatusCode)
 }
 
+// UpdateTransferQuotaUsage updates the user used data transfer and checks the received HTTP Status code against expectedStatusCode.
+func UpdateTransferQuotaUsage(user dataprovider.User, mode string, expectedStatusCode int) ([]byte, error) {
+	var body []byte
+	userAsJSON, _ := json.Marshal(user)
+	url, err := addModeQueryParam(buildURLRelativeToBase(quotasBasePath, "users", user.Username, "transfer-usage"), mode)
+	if err != nil {
+		return body, err
+	}
+	resp, err := sendHTTPRequest(http.MethodPut, url.String(), bytes.NewBuffer(userAsJSON), "application/json",
+		getDefaultToken())
+	if err != nil {
+		return body, err
+	}
+	defer resp.Body.Close()
+	body, _ = getResponseBody(resp)
+	return body, checkResponse(resp.StatusCode, expectedStatusCode)
+}
+
+// GetTransferQuotaUsage returns the uploaded and downloaded bytes, in the current reset period,
+// for the given user and checks the received HTTP Status code against expectedStatusCode.
+func GetTransferQuotaUsage(username string, expectedStatusCode int) (int64, int64, []byte, error) {
+	return getTransferQuotaUsage(buildURLRelativeToBase(quotasBasePath, "users", username, "transfer-usage"),
+		expectedStatusCode)
+}
+
 // GetRetentionChecks returns the active retention checks
 func GetRetentionChecks(expectedStatusCode int) ([]common.ActiveRetentionChecks, []byte, error) {
 	var checks []common.ActiveRetentionChecks
@@ -714,6 +864,349 @@ func GetFolders(limit int64, offset int64, expectedStatusCode int) ([]vfs.BaseVi
 	return folders, body, err
 }
 
+// AddGroup adds a new group and checks the received HTTP Status code against expectedStatusCode
+func AddGroup(group dataprovider.Group, expectedStatusCode int) (dataprovider.Group, []byte, error) {
+	var newGroup dataprovider.Group
+	var body []byte
+	groupAsJSON, _ := json.Marshal(group)
+	resp, err := sendHTTPRequest(http.MethodPost, buildURLRelativeToBase(groupPath), bytes.NewBuffer(groupAsJSON),
+		"application/json", getDefaultToken())
+	if err != nil {
+		return newGroup, body, err
+	}
+	defer resp.Body.Close()
+	err = checkResponse(resp.StatusCode, expectedStatusCode)
+	if expectedStatusCode != http.StatusCreated {
+		body, _ = getResponseBody(resp)
+		return newGroup, body, err
+	}
+	if err == nil {
+		err = render.DecodeJSON(resp.Body, &newGroup)
+	} else {
+		body, _ = getResponseBody(resp)
+	}
+	if err == nil {
+		err = checkGroup(&group, &newGroup)
+	}
+	return newGroup, body, err
+}
+
+// UpdateGroup updates an existing group and checks the received HTTP Status code against expectedStatusCode.
+func UpdateGroup(group dataprovider.Group, expectedStatusCode int) (dataprovider.Group, []byte, error) {
+	var updatedGroup dataprovider.Group
+	var body []byte
+
+	groupAsJSON, _ := json.Marshal(group)
+	resp, err := sendHTTPRequest(http.MethodPut, buildURLRelativeToBase(groupPath, url.PathEscape(group.Name)),
+		bytes.NewBuffer(groupAsJSON), "application/json", getDefaultToken())
+	if err != nil {
+		return updatedGroup, body, err
+	}
+	defer resp.Body.Close()
+	body, _ = getResponseBody(resp)
+
+	err = checkResponse(resp.StatusCode, expectedStatusCode)
+	if expectedStatusCode != http.StatusOK {
+		return updatedGroup, body, err
+	}
+	if err == nil {
+		updatedGroup, body, err = GetGroupByName(group.Name, expectedStatusCode)
+	}
+	if err == nil {
+		err = checkGroup(&group, &updatedGroup)
+	}
+	return updatedGroup, body, err
+}
+
+// RemoveGroup removes an existing group and checks the received HTTP Status code against expectedStatusCode.
+func RemoveGroup(group dataprovider.Group, expectedStatusCode int) ([]byte, error) {
+	var body []byte
+	resp, err := sendHTTPRequest(http.MethodDelete, buildURLRelativeToBase(groupPath, url.PathEscape(group.Name)),
+		nil, "", getDefaultToken())
+	if err != nil {
+		return body, err
+	}
+	defer resp.Body.Close()
+	body, _ = getResponseBody(resp)
+	return body, checkResponse(resp.StatusCode, expectedStatusCode)
+}
+
+// GetGroupByName gets a group by name and checks the received HTTP Status code against expectedStatusCode.
+func GetGroupByName(name string, expectedStatusCode int) (dataprovider.Group, []byte, error) {
+	var group dataprovider.Group
+	var body []byte
+	resp, err := sendHTTPRequest(http.MethodGet, buildURLRelativeToBase(groupPath, url.PathEscape(name)),
+		nil, "", getDefaultToken())
+	if err != nil {
+		return group, body, err
+	}
+	defer resp.Body.Close()
+	err = checkResponse(resp.StatusCode, expectedStatusCode)
+	if err == nil && expectedStatusCode == http.StatusOK {
+		err = render.DecodeJSON(resp.Body, &group)
+	} else {
+		body, _ = getResponseBody(resp)
+	}
+	return group, body, err
+}
+
+// GetGroups returns a list of groups and checks the received HTTP Status code against expectedStatusCode.
+// The number of results can be limited specifying a limit.
+// Some results can be skipped specifying an offset.
+func GetGroups(limit int64, offset int64, expectedStatusCode int) ([]dataprovider.Group, []byte, error) {
+	var groups []dataprovider.Group
+	var body []byte
+	url, err := addLimitAndOffsetQueryParams(buildURLRelativeToBase(groupPath), limit, offset)
+	if err != nil {
+		return groups, body, err
+	}
+	resp, err := sendHTTPRequest(http.MethodGet, url.String(), nil, "", getDefaultToken())
+	if err != nil {
+		return groups, body, err
+	}
+	defer resp.Body.Close()
+	err = checkResponse(resp.StatusCode, expectedStatusCode)
+	if err == nil && expectedStatusCode == http.StatusOK {
+		err = render.DecodeJSON(resp.Body, &groups)
+	} else {
+		body, _ = getResponseBody(resp)
+	}
+	return groups, body, err
+}
+
+// AddEventRule adds a new event rule and checks the received HTTP Status code against expectedStatusCode
+func AddEventRule(rule dataprovider.EventRule, expectedStatusCode int) (dataprovider.EventRule, []byte, error) {
+	var newRule dataprovider.EventRule
+	var body []byte
+	ruleAsJSON, _ := json.Marshal(rule)
+	resp, err := sendHTTPRequest(http.MethodPost, buildURLRelativeToBase(eventRulesPath), bytes.NewBuffer(ruleAsJSON),
+		"application/json", getDefaultToken())
+	if err != nil {
+		return newRule, body, err
+	}
+	defer resp.Body.Close()
+	err = checkResponse(resp.StatusCode, expectedStatusCode)
+	if expectedStatusCode != http.StatusCreated {
+		body, _ = getResponseBody(resp)
+		return newRule, body, err
+	}
+	if err == nil {
+		err = render.DecodeJSON(resp.Body, &newRule)
+	} else {
+		body, _ = getResponseBody(resp)
+	}
+	if err == nil {
+		err = checkEventRule(&rule, &newRule)
+	}
+	return newRule, body, err
+}
+
+// UpdateEventRule updates an existing event rule and checks the received HTTP Status code against expectedStatusCode.
+func UpdateEventRule(rule dataprovider.EventRule, expectedStatusCode int) (dataprovider.EventRule, []byte, error) {
+	var updatedRule dataprovider.EventRule
+	var body []byte
+
+	ruleAsJSON, _ := json.Marshal(rule)
+	resp, err := sendHTTPRequest(http.MethodPut, buildURLRelativeToBase(eventRulesPath, url.PathEscape(rule.Name)),
+		bytes.NewBuffer(ruleAsJSON), "application/json", getDefaultToken())
+	if err != nil {
+		return updatedRule, body, err
+	}
+	defer resp.Body.Close()
+	body, _ = getResponseBody(resp)
+
+	err = checkResponse(resp.StatusCode, expectedStatusCode)
+	if expectedStatusCode != http.StatusOK {
+		return updatedRule, body, err
+	}
+	if err == nil {
+		updatedRule, body, err = GetEventRuleByName(rule.Name, expectedStatusCode)
+	}
+	if err == nil {
+		err = checkEventRule(&rule, &updatedRule)
+	}
+	return updatedRule, body, err
+}
+
+// RemoveEventRule removes an existing event rule and checks the received HTTP Status code against expectedStatusCode.
+func RemoveEventRule(rule dataprovider.EventRule, expectedStatusCode int) ([]byte, error) {
+	var body []byte
+	resp, err := sendHTTPRequest(http.MethodDelete, buildURLRelativeToBase(eventRulesPath, url.PathEscape(rule.Name)),
+		nil, "", getDefaultToken())
+	if err != nil {
+		return body, err
+	}
+	defer resp.Body.Close()
+	body, _ = getResponseBody(resp)
+	return body, checkResponse(resp.StatusCode, expectedStatusCode)
+}
+
+// GetEventRuleByName gets an event rule by name and checks the received HTTP Status code against expectedStatusCode.
+func GetEventRuleByName(name string, expectedStatusCode int) (dataprovider.EventRule, []byte, error) {
+	var rule dataprovider.EventRule
+	var body []byte
+	resp, err := sendHTTPRequest(http.MethodGet, buildURLRelativeToBase(eventRulesPath, url.PathEscape(name)),
+		nil, "", getDefaultToken())
+	if err != nil {
+		return rule, body, err
+	}
+	defer resp.Body.Close()
+	err = checkResponse(resp.StatusCode, expectedStatusCode)
+	if err == nil && expectedStatusCode == http.StatusOK {
+		err = render.DecodeJSON(resp.Body, &rule)
+	} else {
+		body, _ = getResponseBody(resp)
+	}
+	return rule, body, err
+}
+
+// GetEventRules returns a list of event rules and checks the received HTTP Status code against expectedStatusCode.
+// The number of results can be limited specifying a limit.
+// Some results can be skipped specifying an offset.
+func GetEventRules(limit int64, offset int64, expectedStatusCode int) ([]dataprovider.EventRule, []byte, error) {
+	var rules []dataprovider.EventRule
+	var body []byte
+	url, err := addLimitAndOffsetQueryParams(buildURLRelativeToBase(eventRulesPath), limit, offset)
+	if err != nil {
+		return rules, body, err
+	}
+	resp, err := sendHTTPRequest(http.MethodGet, url.String(), nil, "", getDefaultToken())
+	if err != nil {
+		return rules, body, err
+	}
+	defer resp.Body.Close()
+	err = checkResponse(resp.StatusCode, expectedStatusCode)
+	if err == nil && expectedStatusCode == http.StatusOK {
+		err = render.DecodeJSON(resp.Body, &rules)
+	} else {
+		body, _ = getResponseBody(resp)
+	}
+	return rules, body, err
+}
+
+// AddSchedule adds a new schedule and checks the received HTTP Status code against expectedStatusCode
+func AddSchedule(schedule dataprovider.Schedule, expectedStatusCode int) (dataprovider.Schedule, []byte, error) {
+	var newSchedule dataprovider.Schedule
+	var body []byte
+	scheduleAsJSON, _ := json.Marshal(schedule)
+	resp, err := sendHTTPRequest(http.MethodPost, buildURLRelativeToBase(schedulesPath), bytes.NewBuffer(scheduleAsJSON),
+		"application/json", getDefaultToken())
+	if err != nil {
+		return newSchedule, body, err
+	}
+	defer resp.Body.Close()
+	err = checkResponse(resp.StatusCode, expectedStatusCode)
+	if expectedStatusCode != http.StatusCreated {
+		body, _ = getResponseBody(resp)
+		return newSchedule, body, err
+	}
+	if err == nil {
+		err = render.DecodeJSON(resp.Body, &newSchedule)
+	} else {
+		body, _ = getResponseBody(resp)
+	}
+	if err == nil {
+		err = checkSchedule(&schedule, &newSchedule)
+	}
+	return newSchedule, body, err
+}
+
+// UpdateSchedule updates an existing schedule and checks the received HTTP Status code against expectedStatusCode.
+func UpdateSchedule(schedule dataprovider.Schedule, expectedStatusCode int) (dataprovider.Schedule, []byte, error) {
+	var updatedSchedule dataprovider.Schedule
+	var body []byte
+
+	scheduleAsJSON, _ := json.Marshal(schedule)
+	resp, err := sendHTTPRequest(http.MethodPut, buildURLRelativeToBase(schedulesPath, url.PathEscape(schedule.Name)),
+		bytes.NewBuffer(scheduleAsJSON), "application/json", getDefaultToken())
+	if err != nil {
+		return updatedSchedule, body, err
+	}
+	defer resp.Body.Close()
+	body, _ = getResponseBody(resp)
+
+	err = checkResponse(resp.StatusCode, expectedStatusCode)
+	if expectedStatusCode != http.StatusOK {
+		return updatedSchedule, body, err
+	}
+	if err == nil {
+		updatedSchedule, body, err = GetScheduleByName(schedule.Name, expectedStatusCode)
+	}
+	if err == nil {
+		err = checkSchedule(&schedule, &updatedSchedule)
+	}
+	return updatedSchedule, body, err
+}
+
+// RemoveSchedule removes an existing schedule and checks the received HTTP Status code against expectedStatusCode.
+func RemoveSchedule(schedule dataprovider.Schedule, expectedStatusCode int) ([]byte, error) {
+	var body []byte
+	resp, err := sendHTTPRequest(http.MethodDelete, buildURLRelativeToBase(schedulesPath, url.PathEscape(schedule.Name)),
+		nil, "", getDefaultToken())
+	if err != nil {
+		return body, err
+	}
+	defer resp.Body.Close()
+	body, _ = getResponseBody(resp)
+	return body, checkResponse(resp.StatusCode, expectedStatusCode)
+}
+
+// GetScheduleByName gets a schedule by name and checks the received HTTP Status code against expectedStatusCode.
+func GetScheduleByName(name string, expectedStatusCode int) (dataprovider.Schedule, []byte, error) {
+	var schedule dataprovider.Schedule
+	var body []byte
+	resp, err := sendHTTPRequest(http.MethodGet, buildURLRelativeToBase(schedulesPath, url.PathEscape(name)),
+		nil, "", getDefaultToken())
+	if err != nil {
+		return schedule, body, err
+	}
+	defer resp.Body.Close()
+	err = checkResponse(resp.StatusCode, expectedStatusCode)
+	if err == nil && expectedStatusCode == http.StatusOK {
+		err = render.DecodeJSON(resp.Body, &schedule)
+	} else {
+		body, _ = getResponseBody(resp)
+	}
+	return schedule, body, err
+}
+
+// GetSchedules returns a list of schedules and checks the received HTTP Status code against expectedStatusCode.
+// The number of results can be limited specifying a limit.
+// Some results can be skipped specifying an offset.
+func GetSchedules(limit int64, offset int64, expectedStatusCode int) ([]dataprovider.Schedule, []byte, error) {
+	var schedules []dataprovider.Schedule
+	var body []byte
+	url, err := addLimitAndOffsetQueryParams(buildURLRelativeToBase(schedulesPath), limit, offset)
+	if err != nil {
+		return schedules, body, err
+	}
+	resp, err := sendHTTPRequest(http.MethodGet, url.String(), nil, "", getDefaultToken())
+	if err != nil {
+		return schedules, body, err
+	}
+	defer resp.Body.Close()
+	err = checkResponse(resp.StatusCode, expectedStatusCode)
+	if err == nil && expectedStatusCode == http.StatusOK {
+		err = render.DecodeJSON(resp.Body, &schedules)
+	} else {
+		body, _ = getResponseBody(resp)
+	}
+	return schedules, body, err
+}
+
+// RunSchedule starts the task for the specified schedule and checks the received HTTP Status code against expectedStatusCode.
+func RunSchedule(name string, expectedStatusCode int) ([]byte, error) {
+	var body []byte
+	resp, err := sendHTTPRequest(http.MethodPost, buildURLRelativeToBase(schedulesPath, url.PathEscape(name), "run"),
+		nil, "", getDefaultToken())
+	if err != nil {
+		return body, err
+	}
+	defer resp.Body.Close()
+	body, _ = getResponseBody(resp)
+	return body, checkResponse(resp.StatusCode, expectedStatusCode)
+}
+
 // GetFoldersQuotaScans gets active quota scans for folders and checks the received HTTP Status code against expectedStatusCode.
 func GetFoldersQuotaScans(expectedStatusCode int) ([]common.ActiveVirtualFolderQuotaScan, []byte, error) {
 	var quotaScans []common.ActiveVirtualFolderQuotaScan
@@ -762,6 +1255,51 @@ func UpdateFolderQuotaUsage(folder vfs.BaseVirtualFolder, mode string, expectedS
 	return body, checkResponse(resp.StatusCode, expectedStatusCode)
 }
 
+// UpdateFolderTransferQuotaUsage updates the folder used data transfer and checks the received HTTP Status code
+// against expectedStatusCode.
+func UpdateFolderTransferQuotaUsage(folder vfs.BaseVirtualFolder, mode string, expectedStatusCode int) ([]byte, error) {
+	var body []byte
+	folderAsJSON, _ := json.Marshal(folder)
+	url, err := addModeQueryParam(buildURLRelativeToBase(quotasBasePath, "folders", folder.Name, "transfer-usage"), mode)
+	if err != nil {
+		return body, err
+	}
+	resp, err := sendHTTPRequest(http.MethodPut, url.String(), bytes.NewBuffer(folderAsJSON), "", getDefaultToken())
+	if err != nil {
+		return body, err
+	}
+	defer resp.Body.Close()
+	body, _ = getResponseBody(resp)
+	return body, checkResponse(resp.StatusCode, expectedStatusCode)
+}
+
+// GetFolderTransferQuotaUsage returns the uploaded and downloaded bytes, in the current reset period,
+// for the given folder and checks the received HTTP Status code against expectedStatusCode.
+func GetFolderTransferQuotaUsage(name string, expectedStatusCode int) (int64, int64, []byte, error) {
+	return getTransferQuotaUsage(buildURLRelativeToBase(quotasBasePath, "folders", name, "transfer-usage"),
+		expectedStatusCode)
+}
+
+func getTransferQuotaUsage(url string, expectedStatusCode int) (int64, int64, []byte, error) {
+	var usage struct {
+		UsedUploadDataTransfer   int64 `json:"used_upload_data_transfer"`
+		UsedDownloadDataTransfer int64 `json:"used_download_data_transfer"`
+	}
+	var body []byte
+	resp, err := sendHTTPRequest(http.MethodGet, url, nil, "", getDefaultToken())
+	if err != nil {
+		return 0, 0, body, err
+	}
+	defer resp.Body.Close()
+	err = checkResponse(resp.StatusCode, expectedStatusCode)
+	if err == nil && expectedStatusCode == http.StatusOK {
+		err = render.DecodeJSON(resp.Body, &usage)
+	} else {
+		body, _ = getResponseBody(resp)
+	}
+	return usage.UsedUploadDataTransfer, usage.UsedDownloadDataTransfer, body, err
+}
+
 // GetVersion returns version details
 func GetVersion(expectedStatusCode int) (version.Info, []byte, error) {
 	var appVersion version.Info
@@ -811,13 +1349,67 @@ func GetDefenderHosts(expectedStatusCode int) ([]common.DefenderEntry, []byte, e
 		return response, body, err
 	}
 	defer resp.Body.Close()
-	err = checkResponse(resp.StatusCode, expectedStatusCode)
-	if err == nil && expectedStatusCode == http.StatusOK {
-		err = render.DecodeJSON(resp.Body, &response)
-	} else {
-		body, _ = getResponseBody(resp)
-	}
-	return response, body, err
+	err = checkResponse(resp.StatusCode, expectedStatusCode)
+	if err == nil && expectedStatusCode == http.StatusOK {
+		err = render.DecodeJSON(resp.Body, &response)
+	} else {
+		body, _ = getResponseBody(resp)
+	}
+	return response, body, err
+}
+
+// GetAccountLockouts returns the tracked failed logins for users and admins
+func GetAccountLockouts(limit, offset int64, expectedStatusCode int) ([]dataprovider.AccountLockout, []byte, error) {
+	var lockouts []dataprovider.AccountLockout
+	var body []byte
+	url, err := addLimitAndOffsetQueryParams(buildURLRelativeToBase(accountLockoutsPath), limit, offset)
+	if err != nil {
+		return lockouts, body, err
+	}
+	resp, err := sendHTTPRequest(http.MethodGet, url.String(), nil, "", getDefaultToken())
+	if err != nil {
+		return lockouts, body, err
+	}
+	defer resp.Body.Close()
+	err = checkResponse(resp.StatusCode, expectedStatusCode)
+	if err == nil && expectedStatusCode == http.StatusOK {
+		err = render.DecodeJSON(resp.Body, &lockouts)
+	} else {
+		body, _ = getResponseBody(resp)
+	}
+	return lockouts, body, err
+}
+
+// GetAccountLockout returns the failed logins tracking for the given account, if any
+func GetAccountLockout(accountType, username string, expectedStatusCode int) (dataprovider.AccountLockout, []byte, error) {
+	var lockout dataprovider.AccountLockout
+	var body []byte
+	resp, err := sendHTTPRequest(http.MethodGet, buildURLRelativeToBase(accountLockoutsPath, accountType, username),
+		nil, "", getDefaultToken())
+	if err != nil {
+		return lockout, body, err
+	}
+	defer resp.Body.Close()
+	err = checkResponse(resp.StatusCode, expectedStatusCode)
+	if err == nil && expectedStatusCode == http.StatusOK {
+		err = render.DecodeJSON(resp.Body, &lockout)
+	} else {
+		body, _ = getResponseBody(resp)
+	}
+	return lockout, body, err
+}
+
+// RemoveAccountLockout resets the failed logins for the given account and unlocks it
+func RemoveAccountLockout(accountType, username string, expectedStatusCode int) ([]byte, error) {
+	var body []byte
+	resp, err := sendHTTPRequest(http.MethodDelete, buildURLRelativeToBase(accountLockoutsPath, accountType, username),
+		nil, "", getDefaultToken())
+	if err != nil {
+		return body, err
+	}
+	defer resp.Body.Close()
+	body, _ = getResponseBody(resp)
+	return body, checkResponse(resp.StatusCode, expectedStatusCode)
 }
 
 // GetDefenderHostByIP returns the host with the given IP, if it exists
@@ -1042,9 +1634,339 @@ func checkFolder(expected *vfs.BaseVirtualFolder, actual *vfs.BaseVirtualFolder)
 	if expected.Description != actual.Description {
 		return errors.New("description mismatch")
 	}
+	if expected.UploadDataTransfer != actual.UploadDataTransfer {
+		return errors.New("upload_data_transfer mismatch")
+	}
+	if expected.DownloadDataTransfer != actual.DownloadDataTransfer {
+		return errors.New("download_data_transfer mismatch")
+	}
+	if expected.TotalDataTransfer != actual.TotalDataTransfer {
+		return errors.New("total_data_transfer mismatch")
+	}
+	if expected.DataTransferResetPeriod != actual.DataTransferResetPeriod {
+		return errors.New("data_transfer_reset_period mismatch")
+	}
+	if err := compareDataRetention(expected.DataRetention, actual.DataRetention); err != nil {
+		return err
+	}
 	return compareFsConfig(&expected.FsConfig, &actual.FsConfig)
 }
 
+func checkGroup(expected, actual *dataprovider.Group) error {
+	if expected.ID <= 0 {
+		if actual.ID <= 0 {
+			return errors.New("actual group ID must be > 0")
+		}
+	} else {
+		if actual.ID != expected.ID {
+			return errors.New("group ID mismatch")
+		}
+	}
+	if expected.Name != actual.Name {
+		return errors.New("name mismatch")
+	}
+	if expected.Description != actual.Description {
+		return errors.New("description mismatch")
+	}
+	// the user settings are compared reusing the user checks
+	expectedUser := getUserFromGroupSettings(expected)
+	actualUser := getUserFromGroupSettings(actual)
+	if err := compareUserPermissions(expectedUser, actualUser); err != nil {
+		return err
+	}
+	if err := compareUserFilters(expectedUser, actualUser); err != nil {
+		return err
+	}
+	if err := compareFsConfig(&expectedUser.FsConfig, &actualUser.FsConfig); err != nil {
+		return err
+	}
+	if err := compareUserVirtualFolders(expectedUser, actualUser); err != nil {
+		return err
+	}
+	if err := compareUserDataTransferLimits(expectedUser, actualUser); err != nil {
+		return err
+	}
+	return compareEqualsUserFields(expectedUser, actualUser)
+}
+
+func checkEventRule(expected, actual *dataprovider.EventRule) error {
+	if expected.ID <= 0 {
+		if actual.ID <= 0 {
+			return errors.New("actual event rule ID must be > 0")
+		}
+	} else {
+		if actual.ID != expected.ID {
+			return errors.New("event rule ID mismatch")
+		}
+	}
+	if expected.Name != actual.Name {
+		return errors.New("name mismatch")
+	}
+	if expected.Description != actual.Description {
+		return errors.New("description mismatch")
+	}
+	if expected.Trigger != actual.Trigger {
+		return errors.New("trigger mismatch")
+	}
+	if err := compareEventRuleConditions(&expected.Conditions, &actual.Conditions); err != nil {
+		return err
+	}
+	if len(expected.Actions) != len(actual.Actions) {
+		return errors.New("actions mismatch")
+	}
+	for idx := range expected.Actions {
+		if err := compareEventAction(&expected.Actions[idx], &actual.Actions[idx]); err != nil {
+			return fmt.Errorf("action %v: %w", idx, err)
+		}
+	}
+	return nil
+}
+
+func checkSchedule(expected, actual *dataprovider.Schedule) error {
+	if expected.ID <= 0 {
+		if actual.ID <= 0 {
+			return errors.New("actual schedule ID must be > 0")
+		}
+	} else {
+		if actual.ID != expected.ID {
+			return errors.New("schedule ID mismatch")
+		}
+	}
+	if expected.Name != actual.Name {
+		return errors.New("name mismatch")
+	}
+	if expected.Description != actual.Description {
+		return errors.New("description mismatch")
+	}
+	if expected.Status != actual.Status {
+		return errors.New("status mismatch")
+	}
+	if strings.Join(strings.Fields(expected.CronExpression), " ") != actual.CronExpression {
+		return errors.New("cron expression mismatch")
+	}
+	if expected.Task != actual.Task {
+		return errors.New("task mismatch")
+	}
+	return compareScheduleOptions(expected.Task, &expected.Options, &actual.Options)
+}
+
+func compareScheduleOptions(task dataprovider.ScheduleTask, expected, actual *dataprovider.ScheduleOptions) error {
+	if task == dataprovider.ScheduleTaskBackup {
+		return nil
+	}
+	if expected.AllUsers != actual.AllUsers {
+		return errors.New("all users mismatch")
+	}
+	if !expected.AllUsers {
+		if err := compareStringSlices(expected.Usernames, actual.Usernames); err != nil {
+			return fmt.Errorf("usernames mismatch: %w", err)
+		}
+	}
+	if task == dataprovider.ScheduleTaskQuotaScan {
+		if expected.AllFolders != actual.AllFolders {
+			return errors.New("all folders mismatch")
+		}
+		if !expected.AllFolders {
+			if err := compareStringSlices(expected.FolderNames, actual.FolderNames); err != nil {
+				return fmt.Errorf("folder names mismatch: %w", err)
+			}
+		}
+		return nil
+	}
+	if err := compareDataRetention(expected.Retention, actual.Retention); err != nil {
+		return err
+	}
+	if err := compareStringSlices(expected.Notifications, actual.Notifications); err != nil {
+		return fmt.Errorf("notifications mismatch: %w", err)
+	}
+	if expected.Email != actual.Email {
+		return errors.New("email mismatch")
+	}
+	return nil
+}
+
+func compareDataRetention(expected, actual []sdk.DataRetentionPolicy) error {
+	if len(expected) != len(actual) {
+		return errors.New("retention mismatch")
+	}
+	for _, r := range expected {
+		found := false
+		for _, a := range actual {
+			if r.Path == a.Path {
+				found = true
+				if r.Retention != a.Retention || r.DeleteEmptyDirs != a.DeleteEmptyDirs ||
+					r.IgnoreUserPermissions != a.IgnoreUserPermissions {
+					return fmt.Errorf("retention for path %#v mismatch", r.Path)
+				}
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("retention path %#v not found", r.Path)
+		}
+	}
+	return nil
+}
+
+func compareEventRuleConditions(expected, actual *dataprovider.EventConditions) error {
+	if err := compareStringSlices(expected.FsEvents, actual.FsEvents); err != nil {
+		return fmt.Errorf("fs events mismatch: %w", err)
+	}
+	if err := compareStringSlices(expected.ProviderEvents, actual.ProviderEvents); err != nil {
+		return fmt.Errorf("provider events mismatch: %w", err)
+	}
+	if err := compareStringSlices(expected.Options.Protocols, actual.Options.Protocols); err != nil {
+		return fmt.Errorf("protocols mismatch: %w", err)
+	}
+	if err := compareStringSlices(expected.Options.ProviderObjects, actual.Options.ProviderObjects); err != nil {
+		return fmt.Errorf("provider objects mismatch: %w", err)
+	}
+	if err := compareConditionPatterns(expected.Options.Names, actual.Options.Names); err != nil {
+		return fmt.Errorf("names mismatch: %w", err)
+	}
+	if err := compareConditionPatterns(expected.Options.GroupNames, actual.Options.GroupNames); err != nil {
+		return fmt.Errorf("group names mismatch: %w", err)
+	}
+	if err := compareConditionPatterns(expected.Options.FsPaths, actual.Options.FsPaths); err != nil {
+		return fmt.Errorf("fs paths mismatch: %w", err)
+	}
+	if len(expected.Options.Statuses) != len(actual.Options.Statuses) {
+		return errors.New("statuses mismatch")
+	}
+	for _, s := range expected.Options.Statuses {
+		found := false
+		for _, a := range actual.Options.Statuses {
+			if s == a {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return errors.New("statuses content mismatch")
+		}
+	}
+	if expected.Options.MinFileSize != actual.Options.MinFileSize {
+		return errors.New("min file size mismatch")
+	}
+	if expected.Options.MaxFileSize != actual.Options.MaxFileSize {
+		return errors.New("max file size mismatch")
+	}
+	return nil
+}
+
+func compareConditionPatterns(expected, actual []dataprovider.ConditionPattern) error {
+	if len(expected) != len(actual) {
+		return errors.New("size mismatch")
+	}
+	for _, p := range expected {
+		found := false
+		for _, a := range actual {
+			if p.Pattern == a.Pattern && p.InverseMatch == a.InverseMatch {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("pattern %#v not found", p.Pattern)
+		}
+	}
+	return nil
+}
+
+func compareEventAction(expected, actual *dataprovider.EventAction) error {
+	if expected.Type != actual.Type {
+		return errors.New("type mismatch")
+	}
+	switch expected.Type {
+	case dataprovider.EventActionTypeHTTP:
+		if expected.HTTPConfig.Endpoint != actual.HTTPConfig.Endpoint {
+			return errors.New("HTTP endpoint mismatch")
+		}
+		if expected.HTTPConfig.Method != actual.HTTPConfig.Method {
+			return errors.New("HTTP method mismatch")
+		}
+		if expected.HTTPConfig.Body != actual.HTTPConfig.Body {
+			return errors.New("HTTP body mismatch")
+		}
+		if expected.HTTPConfig.Timeout != actual.HTTPConfig.Timeout {
+			return errors.New("HTTP timeout mismatch")
+		}
+		if err := compareKeyValues(expected.HTTPConfig.Headers, actual.HTTPConfig.Headers); err != nil {
+			return fmt.Errorf("HTTP headers mismatch: %w", err)
+		}
+	case dataprovider.EventActionTypeCommand:
+		if expected.CmdConfig.Cmd != actual.CmdConfig.Cmd {
+			return errors.New("command mismatch")
+		}
+		if expected.CmdConfig.Timeout != actual.CmdConfig.Timeout {
+			return errors.New("command timeout mismatch")
+		}
+		if err := compareKeyValues(expected.CmdConfig.EnvVars, actual.CmdConfig.EnvVars); err != nil {
+			return fmt.Errorf("command env vars mismatch: %w", err)
+		}
+	case dataprovider.EventActionTypeEmail:
+		if err := compareStringSlices(expected.EmailConfig.Recipients, actual.EmailConfig.Recipients); err != nil {
+			return fmt.Errorf("email recipients mismatch: %w", err)
+		}
+		if expected.EmailConfig.Subject != actual.EmailConfig.Subject {
+			return errors.New("email subject mismatch")
+		}
+		if expected.EmailConfig.Body != actual.EmailConfig.Body {
+			return errors.New("email body mismatch")
+		}
+	case dataprovider.EventActionTypeFsCopy, dataprovider.EventActionTypeFsRename:
+		if expected.FsConfig.Target != actual.FsConfig.Target {
+			return errors.New("fs target mismatch")
+		}
+	}
+	return nil
+}
+
+func compareKeyValues(expected, actual []dataprovider.KeyValue) error {
+	if len(expected) != len(actual) {
+		return errors.New("size mismatch")
+	}
+	for idx := range expected {
+		if expected[idx].Key != actual[idx].Key || expected[idx].Value != actual[idx].Value {
+			return fmt.Errorf("key %#v mismatch", expected[idx].Key)
+		}
+	}
+	return nil
+}
+
+func compareStringSlices(expected, actual []string) error {
+	if len(expected) != len(actual) {
+		return errors.New("size mismatch")
+	}
+	for _, v := range expected {
+		if !util.IsStringInSlice(v, actual) {
+			return fmt.Errorf("%#v not found", v)
+		}
+	}
+	return nil
+}
+
+func getUserFromGroupSettings(group *dataprovider.Group) *dataprovider.User {
+	return &dataprovider.User{
+		BaseUser: sdk.BaseUser{
+			Username:                group.Name,
+			Permissions:             group.UserSettings.Permissions,
+			MaxSessions:             group.UserSettings.MaxSessions,
+			QuotaSize:               group.UserSettings.QuotaSize,
+			QuotaFiles:              group.UserSettings.QuotaFiles,
+			UploadBandwidth:         group.UserSettings.UploadBandwidth,
+			DownloadBandwidth:       group.UserSettings.DownloadBandwidth,
+			Filters:                 group.UserSettings.Filters,
+			UploadDataTransfer:      group.UserSettings.UploadDataTransfer,
+			DownloadDataTransfer:    group.UserSettings.DownloadDataTransfer,
+			TotalDataTransfer:       group.UserSettings.TotalDataTransfer,
+			DataTransferResetPeriod: group.UserSettings.DataTransferResetPeriod,
+		},
+		VirtualFolders: group.VirtualFolders,
+		FsConfig:       group.UserSettings.FsConfig,
+	}
+}
+
 func checkAPIKey(expected, actual *dataprovider.APIKey) error {
 	if actual.Key != "" {
 		return errors.New("key must not be visible")
@@ -1115,12 +2037,41 @@ func checkAdmin(expected, actual *dataprovider.Admin) error {
 	if expected.Filters.AllowAPIKeyAuth != actual.Filters.AllowAPIKeyAuth {
 		return errors.New("allow_api_key_auth mismatch")
 	}
+	if expected.Filters.RequirePasswordChange != actual.Filters.RequirePasswordChange {
+		return errors.New("require_password_change mismatch")
+	}
+	if expected.Filters.PasswordExpiration != actual.Filters.PasswordExpiration {
+		return errors.New("password_expiration mismatch")
+	}
 	for _, v := range expected.Filters.AllowList {
 		if !util.IsStringInSlice(v, actual.Filters.AllowList) {
 			return errors.New("allow list content mismatch")
 		}
 	}
 
+	return compareAdminScope(expected, actual)
+}
+
+func compareAdminScope(expected *dataprovider.Admin, actual *dataprovider.Admin) error {
+	if expected.Filters.Scope.CreatedUsers != actual.Filters.Scope.CreatedUsers {
+		return errors.New("scope created_users mismatch")
+	}
+	if len(expected.Filters.Scope.Groups) != len(actual.Filters.Scope.Groups) {
+		return errors.New("scope groups mismatch")
+	}
+	for _, v := range expected.Filters.Scope.Groups {
+		if !util.IsStringInSlice(v, actual.Filters.Scope.Groups) {
+			return errors.New("scope groups content mismatch")
+		}
+	}
+	if len(expected.Filters.Scope.UsernamePrefixes) != len(actual.Filters.Scope.UsernamePrefixes) {
+		return errors.New("scope username_prefixes mismatch")
+	}
+	for _, v := range expected.Filters.Scope.UsernamePrefixes {
+		if !util.IsStringInSlice(v, actual.Filters.Scope.UsernamePrefixes) {
+			return errors.New("scope username_prefixes content mismatch")
+		}
+	}
 	return nil
 }
 
@@ -1177,9 +2128,28 @@ func checkUser(expected *dataprovider.User, actual *dataprovider.User) error {
 	if err := compareUserVirtualFolders(expected, actual); err != nil {
 		return err
 	}
+	if err := compareUserDataTransferLimits(expected, actual); err != nil {
+		return err
+	}
 	return compareEqualsUserFields(expected, actual)
 }
 
+func compareUserDataTransferLimits(expected *dataprovider.User, actual *dataprovider.User) error {
+	if expected.UploadDataTransfer != actual.UploadDataTransfer {
+		return errors.New("upload_data_transfer mismatch")
+	}
+	if expected.DownloadDataTransfer != actual.DownloadDataTransfer {
+		return errors.New("download_data_transfer mismatch")
+	}
+	if expected.TotalDataTransfer != actual.TotalDataTransfer {
+		return errors.New("total_data_transfer mismatch")
+	}
+	if expected.DataTransferResetPeriod != actual.DataTransferResetPeriod {
+		return errors.New("data_transfer_reset_period mismatch")
+	}
+	return nil
+}
+
 func compareUserPermissions(expected *dataprovider.User, actual *dataprovider.User) error {
 	if len(expected.Permissions) != len(actual.Permissions) {
 		return errors.New("permissions mismatch")
@@ -1242,7 +2212,58 @@ func compareFsConfig(expected *vfs.Filesystem, actual *vfs.Filesystem) error {
 	if err := checkEncryptedSecret(expected.CryptConfig.Passphrase, actual.CryptConfig.Passphrase); err != nil {
 		return err
 	}
-	return compareSFTPFsConfig(expected, actual)
+	if err := compareSFTPFsConfig(expected, actual); err != nil {
+		return err
+	}
+	if err := compareFTPFsConfig(expected, actual); err != nil {
+		return err
+	}
+	if err := compareWebDAVFsConfig(expected, actual); err != nil {
+		return err
+	}
+	if err := compareReadCacheConfig(expected, actual); err != nil {
+		return err
+	}
+	return compareEncryptionConfig(expected, actual)
+}
+
+func compareEncryptionConfig(expected *vfs.Filesystem, actual *vfs.Filesystem) error {
+	switch expected.Provider {
+	case sdk.S3FilesystemProvider, sdk.GCSFilesystemProvider, sdk.AzureBlobFilesystemProvider,
+		sdk.SFTPFilesystemProvider:
+	default:
+		// the encryption configuration is reset for the other providers
+		return nil
+	}
+	if err := checkEncryptedSecret(expected.Encryption.Passphrase, actual.Encryption.Passphrase); err != nil {
+		return fmt.Errorf("encryption passphrase mismatch: %w", err)
+	}
+	return nil
+}
+
+func compareReadCacheConfig(expected *vfs.Filesystem, actual *vfs.Filesystem) error {
+	switch expected.Provider {
+	case sdk.S3FilesystemProvider, sdk.GCSFilesystemProvider, sdk.AzureBlobFilesystemProvider:
+	default:
+		// the read cache configuration is reset for the other providers
+		return nil
+	}
+	if expected.ReadCache.MaxSize != actual.ReadCache.MaxSize {
+		return errors.New("read cache max size mismatch")
+	}
+	if expected.ReadCache.MaxSize == 0 {
+		// the other fields are reset if the read cache is disabled
+		return nil
+	}
+	if expected.ReadCache.TTL != actual.ReadCache.TTL {
+		return errors.New("read cache ttl mismatch")
+	}
+	if !strings.EqualFold(expected.ReadCache.Eviction, actual.ReadCache.Eviction) {
+		if expected.ReadCache.Eviction != "" {
+			return errors.New("read cache eviction mismatch")
+		}
+	}
+	return nil
 }
 
 func compareS3Config(expected *vfs.Filesystem, actual *vfs.Filesystem) error {
@@ -1306,6 +2327,57 @@ func compareGCSConfig(expected *vfs.Filesystem, actual *vfs.Filesystem) error {
 	return nil
 }
 
+func compareFTPFsConfig(expected *vfs.Filesystem, actual *vfs.Filesystem) error {
+	if expected.FTPConfig.Endpoint != actual.FTPConfig.Endpoint {
+		return errors.New("FTPFs endpoint mismatch")
+	}
+	if expected.FTPConfig.Username != actual.FTPConfig.Username {
+		return errors.New("FTPFs username mismatch")
+	}
+	if expected.FTPConfig.TLSMode != actual.FTPConfig.TLSMode {
+		return errors.New("FTPFs tls_mode mismatch")
+	}
+	if expected.FTPConfig.SkipTLSVerify != actual.FTPConfig.SkipTLSVerify {
+		return errors.New("FTPFs skip_tls_verify mismatch")
+	}
+	if expected.FTPConfig.DisableEPSV != actual.FTPConfig.DisableEPSV {
+		return errors.New("FTPFs disable_epsv mismatch")
+	}
+	if err := checkEncryptedSecret(expected.FTPConfig.Password, actual.FTPConfig.Password); err != nil {
+		return fmt.Errorf("FTPFs password mismatch: %v", err)
+	}
+	if expected.FTPConfig.Prefix != actual.FTPConfig.Prefix {
+		if expected.FTPConfig.Prefix != "" && actual.FTPConfig.Prefix != "/" {
+			return errors.New("FTPFs prefix mismatch")
+		}
+	}
+	return nil
+}
+
+func compareWebDAVFsConfig(expected *vfs.Filesystem, actual *vfs.Filesystem) error {
+	if expected.WebDAVConfig.Endpoint != actual.WebDAVConfig.Endpoint {
+		return errors.New("WebDAVFs endpoint mismatch")
+	}
+	if expected.WebDAVConfig.Username != actual.WebDAVConfig.Username {
+		return errors.New("WebDAVFs username mismatch")
+	}
+	if expected.WebDAVConfig.SkipTLSVerify != actual.WebDAVConfig.SkipTLSVerify {
+		return errors.New("WebDAVFs skip_tls_verify mismatch")
+	}
+	if err := checkEncryptedSecret(expected.WebDAVConfig.Password, actual.WebDAVConfig.Password); err != nil {
+		return fmt.Errorf("WebDAVFs password mismatch: %v", err)
+	}
+	if err := checkEncryptedSecret(expected.WebDAVConfig.BearerToken, actual.WebDAVConfig.BearerToken); err != nil {
+		return fmt.Errorf("WebDAVFs bearer token mismatch: %v", err)
+	}
+	if expected.WebDAVConfig.Prefix != actual.WebDAVConfig.Prefix {
+		if expected.WebDAVConfig.Prefix != "" && actual.WebDAVConfig.Prefix != "/" {
+			return errors.New("WebDAVFs prefix mismatch")
+		}
+	}
+	return nil
+}
+
 func compareSFTPFsConfig(expected *vfs.Filesystem, actual *vfs.Filesystem) error {
 	if expected.SFTPConfig.Endpoint != actual.SFTPConfig.Endpoint {
 		return errors.New("SFTPFs endpoint mismatch")
@@ -1483,6 +2555,15 @@ func compareUserFilters(expected *dataprovider.User, actual *dataprovider.User)
 	if expected.Filters.AllowAPIKeyAuth != actual.Filters.AllowAPIKeyAuth {
 		return errors.New("allow_api_key_auth mismatch")
 	}
+	if expected.Filters.RequirePasswordChange != actual.Filters.RequirePasswordChange {
+		return errors.New("require_password_change mismatch")
+	}
+	if expected.Filters.PasswordExpiration != actual.Filters.PasswordExpiration {
+		return errors.New("password_expiration mismatch")
+	}
+	if err := compareDataRetention(expected.Filters.DataRetention, actual.Filters.DataRetention); err != nil {
+		return err
+	}
 	if err := compareUserFilterSubStructs(expected, actual); err != nil {
 		return err
 	}
@@ -1566,6 +2647,14 @@ func compareEqualsUserFields(expected *dataprovider.User, actual *dataprovider.U
 	if expected.Description != actual.Description {
 		return errors.New("description mismatch")
 	}
+	if len(expected.Groups) != len(actual.Groups) {
+		return errors.New("groups mismatch")
+	}
+	for idx, g := range expected.Groups {
+		if actual.Groups[idx] != g {
+			return errors.New("groups contents mismatch")
+		}
+	}
 	return nil
 }
 
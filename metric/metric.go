@@ -566,6 +566,24 @@ var (
 		Name: "sftpgo_az_head_container_errors",
 		Help: "The total number of Azure head container errors",
 	})
+
+	// totalReadCacheHits is the metric that reports the total number of downloads served from the read cache
+	totalReadCacheHits = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "sftpgo_read_cache_hits",
+		Help: "The total number of downloads served from the local read cache",
+	})
+
+	// totalReadCacheMisses is the metric that reports the total number of downloads not served from the read cache
+	totalReadCacheMisses = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "sftpgo_read_cache_misses",
+		Help: "The total number of downloads not served from the local read cache",
+	})
+
+	// totalReadCacheHitSize is the metric that reports the total size as bytes served from the read cache
+	totalReadCacheHitSize = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "sftpgo_read_cache_hit_size",
+		Help: "The total size as bytes served from the local read cache",
+	})
 )
 
 // AddMetricsEndpoint exposes metrics to the specified endpoint
@@ -792,6 +810,17 @@ func AZHeadContainerCompleted(err error) {
 	}
 }
 
+// ReadCacheHit updates metrics after a download served from the read cache
+func ReadCacheHit(bytes int64) {
+	totalReadCacheHits.Inc()
+	totalReadCacheHitSize.Add(float64(bytes))
+}
+
+// ReadCacheMiss updates metrics after a download not served from the read cache
+func ReadCacheMiss() {
+	totalReadCacheMisses.Inc()
+}
+
 // SSHCommandCompleted update metrics after an SSH command terminates
 func SSHCommandCompleted(err error) {
 	if err == nil {
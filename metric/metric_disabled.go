@@ -49,6 +49,12 @@ func GCSDeleteObjectCompleted(err error) {}
 // GCSHeadBucketCompleted updates metrics after a GCS head bucket request terminates
 func GCSHeadBucketCompleted(err error) {}
 
+// ReadCacheHit updates metrics after a download served from the read cache
+func ReadCacheHit(bytes int64) {}
+
+// ReadCacheMiss updates metrics after a download not served from the read cache
+func ReadCacheMiss() {}
+
 // SSHCommandCompleted update metrics after an SSH command terminates
 func SSHCommandCompleted(err error) {}
 
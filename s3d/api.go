@@ -0,0 +1,721 @@
+package s3d
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/drakkan/sftpgo/v2/logger"
+	"github.com/drakkan/sftpgo/v2/util"
+)
+
+const (
+	xmlNamespace      = "http://s3.amazonaws.com/doc/2006-03-01/"
+	storageClass      = "STANDARD"
+	iso8601Format     = "2006-01-02T15:04:05.000Z"
+	directoryMimeType = "application/x-directory"
+	// MD5 of an empty object
+	emptyObjectETag = "d41d8cd98f00b204e9800998ecf8427e"
+	maxXMLBodySize  = 2 * 1024 * 1024
+	maxDeleteKeys   = 1000
+)
+
+type owner struct {
+	ID          string `xml:"ID"`
+	DisplayName string `xml:"DisplayName"`
+}
+
+type bucketInfo struct {
+	Name         string `xml:"Name"`
+	CreationDate string `xml:"CreationDate"`
+}
+
+type listAllMyBucketsResult struct {
+	XMLName xml.Name     `xml:"http://s3.amazonaws.com/doc/2006-03-01/ ListAllMyBucketsResult"`
+	Owner   owner        `xml:"Owner"`
+	Buckets []bucketInfo `xml:"Buckets>Bucket"`
+}
+
+type locationConstraint struct {
+	XMLName  xml.Name `xml:"http://s3.amazonaws.com/doc/2006-03-01/ LocationConstraint"`
+	Location string   `xml:",chardata"`
+}
+
+type objectInfo struct {
+	Key          string `xml:"Key"`
+	LastModified string `xml:"LastModified"`
+	ETag         string `xml:"ETag"`
+	Size         int64  `xml:"Size"`
+	StorageClass string `xml:"StorageClass"`
+}
+
+type commonPrefix struct {
+	Prefix string `xml:"Prefix"`
+}
+
+type listBucketResult struct {
+	XMLName        xml.Name       `xml:"http://s3.amazonaws.com/doc/2006-03-01/ ListBucketResult"`
+	Name           string         `xml:"Name"`
+	Prefix         string         `xml:"Prefix"`
+	Marker         string         `xml:"Marker"`
+	NextMarker     string         `xml:"NextMarker,omitempty"`
+	MaxKeys        int            `xml:"MaxKeys"`
+	Delimiter      string         `xml:"Delimiter,omitempty"`
+	EncodingType   string         `xml:"EncodingType,omitempty"`
+	IsTruncated    bool           `xml:"IsTruncated"`
+	Contents       []objectInfo   `xml:"Contents"`
+	CommonPrefixes []commonPrefix `xml:"CommonPrefixes"`
+}
+
+type listBucketV2Result struct {
+	XMLName               xml.Name       `xml:"http://s3.amazonaws.com/doc/2006-03-01/ ListBucketResult"`
+	Name                  string         `xml:"Name"`
+	Prefix                string         `xml:"Prefix"`
+	Delimiter             string         `xml:"Delimiter,omitempty"`
+	MaxKeys               int            `xml:"MaxKeys"`
+	KeyCount              int            `xml:"KeyCount"`
+	EncodingType          string         `xml:"EncodingType,omitempty"`
+	IsTruncated           bool           `xml:"IsTruncated"`
+	ContinuationToken     string         `xml:"ContinuationToken,omitempty"`
+	NextContinuationToken string         `xml:"NextContinuationToken,omitempty"`
+	StartAfter            string         `xml:"StartAfter,omitempty"`
+	Contents              []objectInfo   `xml:"Contents"`
+	CommonPrefixes        []commonPrefix `xml:"CommonPrefixes"`
+}
+
+type deleteRequest struct {
+	XMLName xml.Name `xml:"Delete"`
+	Quiet   bool     `xml:"Quiet"`
+	Objects []struct {
+		Key string `xml:"Key"`
+	} `xml:"Object"`
+}
+
+type deletedObject struct {
+	Key string `xml:"Key"`
+}
+
+type deleteError struct {
+	Key     string `xml:"Key"`
+	Code    string `xml:"Code"`
+	Message string `xml:"Message"`
+}
+
+type deleteResult struct {
+	XMLName xml.Name        `xml:"http://s3.amazonaws.com/doc/2006-03-01/ DeleteResult"`
+	Deleted []deletedObject `xml:"Deleted"`
+	Errors  []deleteError   `xml:"Error"`
+}
+
+// requestHandler handles the S3 API requests for an authenticated connection
+type requestHandler struct {
+	config     *Configuration
+	connection *Connection
+}
+
+func (h *requestHandler) handle(w http.ResponseWriter, r *http.Request) {
+	bucket, key := parseRequestPath(r.URL.Path)
+	if bucket == "" {
+		if r.Method != http.MethodGet {
+			sendAPIError(w, r, errMethodNotAllowed)
+			return
+		}
+		h.listBuckets(w, r)
+		return
+	}
+	if bucket != h.connection.User.Username {
+		if r.Method == http.MethodPut && key == "" {
+			sendAPIError(w, r, errAccessDenied)
+			return
+		}
+		sendAPIError(w, r, errNoSuchBucket)
+		return
+	}
+	if key == "" {
+		h.handleBucketRequest(w, r)
+		return
+	}
+	name, isDir, err := getVirtualPath(key)
+	if err != nil {
+		sendAPIError(w, r, errInvalidObjectName)
+		return
+	}
+	h.handleObjectRequest(w, r, key, name, isDir)
+}
+
+func (h *requestHandler) handleBucketRequest(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	switch r.Method {
+	case http.MethodHead:
+		w.Header().Set("x-amz-bucket-region", h.config.Region)
+		w.Header().Set("x-amz-request-id", getRequestID(r))
+		w.WriteHeader(http.StatusOK)
+		writeLog(r, http.StatusOK, nil)
+	case http.MethodGet:
+		if _, ok := query["location"]; ok {
+			h.getBucketLocation(w, r)
+			return
+		}
+		if hasUnsupportedSubresource(query) {
+			sendAPIError(w, r, errNotImplemented)
+			return
+		}
+		if query.Get("list-type") == "2" {
+			h.listObjectsV2(w, r)
+			return
+		}
+		h.listObjects(w, r)
+	case http.MethodPut:
+		sendAPIError(w, r, errBucketAlreadyOwnedByYou)
+	case http.MethodPost:
+		if _, ok := query["delete"]; ok {
+			h.deleteObjects(w, r)
+			return
+		}
+		sendAPIError(w, r, errNotImplemented)
+	default:
+		sendAPIError(w, r, errMethodNotAllowed)
+	}
+}
+
+func (h *requestHandler) handleObjectRequest(w http.ResponseWriter, r *http.Request, key, name string, isDir bool) {
+	query := r.URL.Query()
+	_, isMultipartUpload := query["uploadId"]
+
+	switch r.Method {
+	case http.MethodHead:
+		h.getObject(w, r, name, isDir)
+	case http.MethodGet:
+		if isMultipartUpload || hasUnsupportedSubresource(query) {
+			sendAPIError(w, r, errNotImplemented)
+			return
+		}
+		h.getObject(w, r, name, isDir)
+	case http.MethodPut:
+		if r.Header.Get("X-Amz-Copy-Source") != "" || hasUnsupportedSubresource(query) {
+			sendAPIError(w, r, errNotImplemented)
+			return
+		}
+		if isMultipartUpload {
+			h.uploadPart(w, r, name, isDir, query.Get("uploadId"), query.Get("partNumber"))
+			return
+		}
+		h.putObject(w, r, name, isDir)
+	case http.MethodPost:
+		if _, ok := query["uploads"]; ok {
+			h.createMultipartUpload(w, r, key, name, isDir)
+			return
+		}
+		if isMultipartUpload {
+			h.completeMultipartUpload(w, r, key, name, isDir, query.Get("uploadId"))
+			return
+		}
+		sendAPIError(w, r, errNotImplemented)
+	case http.MethodDelete:
+		if isMultipartUpload {
+			h.abortMultipartUpload(w, r, name, query.Get("uploadId"))
+			return
+		}
+		if hasUnsupportedSubresource(query) {
+			sendAPIError(w, r, errNotImplemented)
+			return
+		}
+		h.deleteObject(w, r, name, isDir)
+	default:
+		sendAPIError(w, r, errMethodNotAllowed)
+	}
+}
+
+func (h *requestHandler) listBuckets(w http.ResponseWriter, r *http.Request) {
+	user := &h.connection.User
+	resp := listAllMyBucketsResult{
+		Owner: owner{
+			ID:          user.Username,
+			DisplayName: user.Username,
+		},
+		Buckets: []bucketInfo{
+			{
+				Name:         user.Username,
+				CreationDate: util.GetTimeFromMsecSinceEpoch(user.CreatedAt).UTC().Format(iso8601Format),
+			},
+		},
+	}
+	sendXMLResponse(w, r, http.StatusOK, resp)
+	writeLog(r, http.StatusOK, nil)
+}
+
+func (h *requestHandler) getBucketLocation(w http.ResponseWriter, r *http.Request) {
+	resp := locationConstraint{}
+	// an empty location constraint means us-east-1
+	if h.config.Region != defaultRegion {
+		resp.Location = h.config.Region
+	}
+	sendXMLResponse(w, r, http.StatusOK, resp)
+	writeLog(r, http.StatusOK, nil)
+}
+
+func (h *requestHandler) listObjectsV2(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	params, err := getListParams(query)
+	if err != nil {
+		sendAPIError(w, r, getAPIError(err, errNoSuchBucket))
+		return
+	}
+	startAfter := query.Get("start-after")
+	continuationToken := query.Get("continuation-token")
+	listFrom := startAfter
+	if continuationToken != "" {
+		token, err := base64.StdEncoding.DecodeString(continuationToken)
+		if err != nil || len(token) == 0 {
+			sendAPIError(w, r, errInvalidToken)
+			return
+		}
+		listFrom = string(token)
+	}
+	entries, isTruncated, err := h.list(params.prefix, params.delimiter, listFrom, params.maxKeys)
+	if err != nil {
+		sendAPIError(w, r, getAPIError(err, errNoSuchBucket))
+		return
+	}
+	resp := listBucketV2Result{
+		Name:              h.connection.User.Username,
+		Prefix:            params.encode(params.prefix),
+		Delimiter:         params.encode(params.delimiter),
+		MaxKeys:           params.maxKeys,
+		KeyCount:          len(entries),
+		EncodingType:      params.encodingType,
+		IsTruncated:       isTruncated,
+		ContinuationToken: continuationToken,
+		StartAfter:        params.encode(startAfter),
+	}
+	resp.Contents, resp.CommonPrefixes = getListResults(entries, params)
+	if isTruncated {
+		resp.NextContinuationToken = base64.StdEncoding.EncodeToString([]byte(entries[len(entries)-1].key))
+	}
+	sendXMLResponse(w, r, http.StatusOK, resp)
+	writeLog(r, http.StatusOK, nil)
+}
+
+func (h *requestHandler) listObjects(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	params, err := getListParams(query)
+	if err != nil {
+		sendAPIError(w, r, getAPIError(err, errNoSuchBucket))
+		return
+	}
+	marker := query.Get("marker")
+	entries, isTruncated, err := h.list(params.prefix, params.delimiter, marker, params.maxKeys)
+	if err != nil {
+		sendAPIError(w, r, getAPIError(err, errNoSuchBucket))
+		return
+	}
+	resp := listBucketResult{
+		Name:         h.connection.User.Username,
+		Prefix:       params.encode(params.prefix),
+		Marker:       params.encode(marker),
+		MaxKeys:      params.maxKeys,
+		Delimiter:    params.encode(params.delimiter),
+		EncodingType: params.encodingType,
+		IsTruncated:  isTruncated,
+	}
+	resp.Contents, resp.CommonPrefixes = getListResults(entries, params)
+	if isTruncated && params.delimiter != "" {
+		resp.NextMarker = params.encode(entries[len(entries)-1].key)
+	}
+	sendXMLResponse(w, r, http.StatusOK, resp)
+	writeLog(r, http.StatusOK, nil)
+}
+
+// list returns up to maxKeys entries with the given prefix and a key greater than startAfter,
+// it also returns true if there are more entries
+func (h *requestHandler) list(prefix, delimiter, startAfter string, maxKeys int) ([]listEntry, bool, error) {
+	lister := objectsLister{
+		connection: h.connection,
+		prefix:     prefix,
+		delimiter:  delimiter,
+		startAfter: startAfter,
+		limit:      maxKeys + 1,
+	}
+	if maxKeys == 0 {
+		return nil, false, nil
+	}
+	if err := lister.list(); err != nil {
+		return nil, false, err
+	}
+	if len(lister.entries) > maxKeys {
+		return lister.entries[:maxKeys], true, nil
+	}
+	return lister.entries, false, nil
+}
+
+func (h *requestHandler) getObject(w http.ResponseWriter, r *http.Request, name string, isDir bool) {
+	info, err := h.connection.Stat(name)
+	if err != nil {
+		sendAPIError(w, r, getAPIError(err, errNoSuchKey))
+		return
+	}
+	if info.IsDir() != isDir {
+		sendAPIError(w, r, errNoSuchKey)
+		return
+	}
+	etag := getObjectETag(info)
+	w.Header().Set("Last-Modified", info.ModTime().UTC().Format(http.TimeFormat))
+	w.Header().Set("ETag", etag)
+	w.Header().Set("x-amz-request-id", getRequestID(r))
+	if isDir {
+		w.Header().Set("Content-Length", "0")
+		w.Header().Set("Content-Type", directoryMimeType)
+		w.WriteHeader(http.StatusOK)
+		writeLog(r, http.StatusOK, nil)
+		return
+	}
+	if err := h.connection.checkDownload(name); err != nil {
+		sendAPIError(w, r, getAPIError(err, errNoSuchKey))
+		return
+	}
+	if status := checkPreconditions(r, etag, info.ModTime()); status != 0 {
+		w.WriteHeader(status)
+		writeLog(r, status, nil)
+		return
+	}
+	offset := int64(0)
+	size := info.Size()
+	responseStatus := http.StatusOK
+	if rangeHeader := r.Header.Get("Range"); rangeHeader != "" {
+		rangeOffset, rangeSize, ok, err := parseRange(rangeHeader, info.Size())
+		if err != nil {
+			w.Header().Set("Content-Range", fmt.Sprintf("bytes */%d", info.Size()))
+			sendAPIError(w, r, errInvalidRange)
+			return
+		}
+		if ok {
+			offset = rangeOffset
+			size = rangeSize
+			responseStatus = http.StatusPartialContent
+			w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", offset, offset+size-1, info.Size()))
+		}
+	}
+	ctype := mime.TypeByExtension(path.Ext(name))
+	if ctype == "" {
+		ctype = "application/octet-stream"
+	}
+	w.Header().Set("Content-Type", ctype)
+	w.Header().Set("Accept-Ranges", "bytes")
+	w.Header().Set("Content-Length", strconv.FormatInt(size, 10))
+	if r.Method == http.MethodHead {
+		w.WriteHeader(responseStatus)
+		writeLog(r, responseStatus, nil)
+		return
+	}
+
+	reader, err := h.connection.getFileReader(name, offset)
+	if err != nil {
+		w.Header().Del("Content-Range")
+		sendAPIError(w, r, getAPIError(err, errNoSuchKey))
+		return
+	}
+	w.WriteHeader(responseStatus)
+	_, err = io.CopyN(w, reader, size)
+	if err != nil {
+		reader.TransferError(err)
+	}
+	errClose := reader.Close()
+	if err == nil {
+		err = errClose
+	}
+	writeLog(r, responseStatus, err)
+}
+
+func (h *requestHandler) putObject(w http.ResponseWriter, r *http.Request, name string, isDir bool) {
+	if isDir {
+		if r.ContentLength > 0 {
+			sendAPIError(w, r, errDirectoryNotEmpty)
+			return
+		}
+		if err := h.connection.createDirs(name); err != nil {
+			sendAPIError(w, r, getAPIError(err, errNoSuchKey))
+			return
+		}
+		sendETagResponse(w, r, fmt.Sprintf("%#v", emptyObjectETag))
+		return
+	}
+	expectedMD5, err := getContentMD5(r)
+	if err != nil {
+		sendAPIError(w, r, err.(*apiError))
+		return
+	}
+	if err := h.connection.createDirs(path.Dir(name)); err != nil {
+		sendAPIError(w, r, getAPIError(err, errNoSuchKey))
+		return
+	}
+	writer, err := h.connection.getFileWriter(name)
+	if err != nil {
+		sendAPIError(w, r, getAPIError(err, errNoSuchKey))
+		return
+	}
+	hasher := md5.New()
+	_, err = io.Copy(writer, io.TeeReader(r.Body, hasher))
+	checksum := hasher.Sum(nil)
+	if err == nil && expectedMD5 != nil && !bytes.Equal(expectedMD5, checksum) {
+		err = errBadDigest
+	}
+	if err != nil {
+		writer.TransferError(err)
+		writer.Close() //nolint:errcheck
+		sendAPIError(w, r, getAPIError(err, errNoSuchKey))
+		return
+	}
+	if err := writer.Close(); err != nil {
+		sendAPIError(w, r, getAPIError(err, errNoSuchKey))
+		return
+	}
+	sendETagResponse(w, r, fmt.Sprintf("%#v", hex.EncodeToString(checksum)))
+}
+
+func (h *requestHandler) deleteObject(w http.ResponseWriter, r *http.Request, name string, isDir bool) {
+	var err error
+	if isDir {
+		err = h.connection.removeDirObject(name)
+	} else {
+		err = h.connection.removeObject(name)
+	}
+	if err != nil {
+		sendAPIError(w, r, getAPIError(err, errNoSuchKey))
+		return
+	}
+	w.Header().Set("x-amz-request-id", getRequestID(r))
+	w.WriteHeader(http.StatusNoContent)
+	writeLog(r, http.StatusNoContent, nil)
+}
+
+func (h *requestHandler) deleteObjects(w http.ResponseWriter, r *http.Request) {
+	var req deleteRequest
+	if err := readXMLBody(r, &req); err != nil {
+		sendAPIError(w, r, err.(*apiError))
+		return
+	}
+	if len(req.Objects) == 0 || len(req.Objects) > maxDeleteKeys {
+		sendAPIError(w, r, errMalformedXML)
+		return
+	}
+	resp := deleteResult{}
+	for _, object := range req.Objects {
+		name, isDir, err := getVirtualPath(object.Key)
+		if err == nil {
+			if isDir {
+				err = h.connection.removeDirObject(name)
+			} else {
+				err = h.connection.removeObject(name)
+			}
+		}
+		if err != nil {
+			apiErr := errInvalidObjectName
+			if name != "" {
+				apiErr = getAPIError(err, errNoSuchKey)
+			}
+			resp.Errors = append(resp.Errors, deleteError{
+				Key:     object.Key,
+				Code:    apiErr.code,
+				Message: apiErr.message,
+			})
+			continue
+		}
+		if !req.Quiet {
+			resp.Deleted = append(resp.Deleted, deletedObject{Key: object.Key})
+		}
+	}
+	sendXMLResponse(w, r, http.StatusOK, resp)
+	writeLog(r, http.StatusOK, nil)
+}
+
+func sendETagResponse(w http.ResponseWriter, r *http.Request, etag string) {
+	w.Header().Set("ETag", etag)
+	w.Header().Set("x-amz-request-id", getRequestID(r))
+	w.WriteHeader(http.StatusOK)
+	writeLog(r, http.StatusOK, nil)
+}
+
+// parseRequestPath returns the bucket and the object key for a path-style request
+func parseRequestPath(p string) (string, string) {
+	p = strings.TrimPrefix(p, "/")
+	idx := strings.Index(p, "/")
+	if idx < 0 {
+		return p, ""
+	}
+	return p[:idx], p[idx+1:]
+}
+
+// getVirtualPath returns the virtual path for the given object key and true if the key
+// identifies a directory. Keys that do not map to a clean path are not allowed
+func getVirtualPath(key string) (string, bool, error) {
+	isDir := strings.HasSuffix(key, "/")
+	name := "/" + strings.TrimSuffix(key, "/")
+	if name == "/" || util.CleanPath(name) != name {
+		return "", isDir, fmt.Errorf("invalid object key %#v", key)
+	}
+	return name, isDir, nil
+}
+
+// getObjectETag returns the entity tag for an existing object. We don't know the object
+// checksum so the returned tag is based on size and modification time. It contains
+// a "-" so the clients, as for multipart uploads, will not handle it as an MD5 digest
+func getObjectETag(info os.FileInfo) string {
+	h := md5.Sum([]byte(fmt.Sprintf("%v:%v", info.Size(), info.ModTime().UnixNano())))
+	return fmt.Sprintf("%#v", hex.EncodeToString(h[:])+"-1")
+}
+
+// getContentMD5 returns the decoded Content-MD5 header, if any
+func getContentMD5(r *http.Request) ([]byte, error) {
+	value := r.Header.Get("Content-MD5")
+	if value == "" {
+		return nil, nil
+	}
+	digest, err := base64.StdEncoding.DecodeString(value)
+	if err != nil || len(digest) != md5.Size {
+		return nil, errInvalidDigest
+	}
+	return digest, nil
+}
+
+// readXMLBody decodes the XML request body verifying the Content-MD5 header, if any
+func readXMLBody(r *http.Request, v interface{}) error {
+	expectedMD5, err := getContentMD5(r)
+	if err != nil {
+		return err
+	}
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxXMLBodySize+1))
+	if err != nil {
+		var apiErr *apiError
+		if errors.As(err, &apiErr) {
+			return apiErr
+		}
+		return errIncompleteBody
+	}
+	if len(body) > maxXMLBodySize {
+		return errMalformedXML
+	}
+	if expectedMD5 != nil {
+		checksum := md5.Sum(body)
+		if !bytes.Equal(expectedMD5, checksum[:]) {
+			return errBadDigest
+		}
+	}
+	if err := xml.Unmarshal(body, v); err != nil {
+		logger.Debug(logSender, getRequestID(r), "unable to decode XML body: %v", err)
+		return errMalformedXML
+	}
+	return nil
+}
+
+// hasUnsupportedSubresource returns true if the query string references a sub-resource,
+// such as acl or tagging, we don't support
+func hasUnsupportedSubresource(query map[string][]string) bool {
+	for _, name := range []string{"acl", "cors", "lifecycle", "policy", "tagging", "torrent", "versioning",
+		"versions", "website", "logging", "notification", "replication", "encryption", "object-lock",
+		"retention", "legal-hold", "restore", "select", "attributes"} {
+		if _, ok := query[name]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// checkPreconditions evaluates the conditional request headers, a non zero
+// status code is returned if the request must not be served
+func checkPreconditions(r *http.Request, etag string, modTime time.Time) int {
+	if value := r.Header.Get("If-Match"); value != "" && !isETagMatching(value, etag) {
+		return http.StatusPreconditionFailed
+	}
+	if value := r.Header.Get("If-Unmodified-Since"); value != "" && r.Header.Get("If-Match") == "" {
+		if t, err := http.ParseTime(value); err == nil && modTime.Truncate(time.Second).After(t) {
+			return http.StatusPreconditionFailed
+		}
+	}
+	if value := r.Header.Get("If-None-Match"); value != "" {
+		if isETagMatching(value, etag) {
+			return http.StatusNotModified
+		}
+		return 0
+	}
+	if value := r.Header.Get("If-Modified-Since"); value != "" {
+		if t, err := http.ParseTime(value); err == nil && !modTime.Truncate(time.Second).After(t) {
+			return http.StatusNotModified
+		}
+	}
+	return 0
+}
+
+func isETagMatching(header, etag string) bool {
+	for _, value := range strings.Split(header, ",") {
+		value = strings.TrimSpace(value)
+		if value == "*" || strings.Trim(value, `"`) == strings.Trim(etag, `"`) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseRange parses a single byte range as defined in RFC 7233 and returns the offset
+// and the size to send. A false value is returned if the range header must be ignored,
+// an error is returned if the range is not satisfiable
+func parseRange(value string, size int64) (int64, int64, bool, error) {
+	if !strings.HasPrefix(value, "bytes=") {
+		return 0, 0, false, nil
+	}
+	spec := strings.TrimSpace(strings.TrimPrefix(value, "bytes="))
+	// multiple ranges are not supported, the whole object is returned as S3 does
+	if strings.Contains(spec, ",") {
+		return 0, 0, false, nil
+	}
+	idx := strings.Index(spec, "-")
+	if idx < 0 {
+		return 0, 0, false, nil
+	}
+	start := strings.TrimSpace(spec[:idx])
+	end := strings.TrimSpace(spec[idx+1:])
+	if start == "" {
+		// suffix range, the last N bytes
+		length, err := strconv.ParseInt(end, 10, 64)
+		if err != nil || length < 0 {
+			return 0, 0, false, nil
+		}
+		if length == 0 || size == 0 {
+			return 0, 0, false, errInvalidRange
+		}
+		if length > size {
+			length = size
+		}
+		return size - length, length, true, nil
+	}
+	offset, err := strconv.ParseInt(start, 10, 64)
+	if err != nil || offset < 0 {
+		return 0, 0, false, nil
+	}
+	last := size - 1
+	if end != "" {
+		last, err = strconv.ParseInt(end, 10, 64)
+		if err != nil || last < offset {
+			return 0, 0, false, nil
+		}
+	}
+	if offset >= size {
+		return 0, 0, false, errInvalidRange
+	}
+	if last >= size {
+		last = size - 1
+	}
+	return offset, last - offset + 1, true, nil
+}
@@ -0,0 +1,136 @@
+package s3d
+
+import (
+	"encoding/xml"
+	"errors"
+	"net/http"
+	"os"
+
+	"github.com/drakkan/sftpgo/v2/common"
+	"github.com/drakkan/sftpgo/v2/dataprovider"
+	"github.com/drakkan/sftpgo/v2/util"
+)
+
+// apiError defines an error as returned by the S3 REST API
+type apiError struct {
+	code       string
+	message    string
+	statusCode int
+}
+
+func (e *apiError) Error() string {
+	return e.message
+}
+
+func newAPIError(code, message string, statusCode int) *apiError {
+	return &apiError{
+		code:       code,
+		message:    message,
+		statusCode: statusCode,
+	}
+}
+
+var (
+	errAccessDenied       = newAPIError("AccessDenied", "Access Denied", http.StatusForbidden)
+	errInvalidAccessKeyID = newAPIError("InvalidAccessKeyId",
+		"The AWS access key Id you provided does not exist in our records", http.StatusForbidden)
+	errSignatureDoesNotMatch = newAPIError("SignatureDoesNotMatch",
+		"The request signature we calculated does not match the signature you provided", http.StatusForbidden)
+	errAuthorizationHeaderMalformed = newAPIError("AuthorizationHeaderMalformed",
+		"The authorization header is malformed", http.StatusBadRequest)
+	errUnsupportedSignature = newAPIError("AccessDenied",
+		"Only AWS Signature Version 4 in the Authorization header is supported", http.StatusForbidden)
+	errMissingDateHeader = newAPIError("AccessDenied",
+		"AWS authentication requires a valid Date or x-amz-date header", http.StatusForbidden)
+	errRequestTimeTooSkewed = newAPIError("RequestTimeTooSkewed",
+		"The difference between the request time and the server's time is too large", http.StatusForbidden)
+	errMissingContentSHA256 = newAPIError("InvalidRequest",
+		"Missing required header for this request: x-amz-content-sha256", http.StatusBadRequest)
+	errContentSHA256Mismatch = newAPIError("XAmzContentSHA256Mismatch",
+		"The provided 'x-amz-content-sha256' header does not match what was computed", http.StatusBadRequest)
+	errInvalidChunk = newAPIError("InvalidRequest", "The aws-chunked encoded body is not valid",
+		http.StatusBadRequest)
+	errIncompleteBody = newAPIError("IncompleteBody",
+		"You did not provide the number of bytes specified by the Content-Length HTTP header", http.StatusBadRequest)
+	errInvalidDigest = newAPIError("InvalidDigest", "The Content-MD5 you specified is not valid",
+		http.StatusBadRequest)
+	errBadDigest = newAPIError("BadDigest", "The Content-MD5 you specified did not match what we received",
+		http.StatusBadRequest)
+	errNoSuchBucket = newAPIError("NoSuchBucket", "The specified bucket does not exist", http.StatusNotFound)
+	errNoSuchKey    = newAPIError("NoSuchKey", "The specified key does not exist", http.StatusNotFound)
+	errNoSuchUpload = newAPIError("NoSuchUpload",
+		"The specified multipart upload does not exist. The upload ID might not be valid, or the multipart upload might have been aborted or completed",
+		http.StatusNotFound)
+	errInvalidPart = newAPIError("InvalidPart",
+		"One or more of the specified parts could not be found. The part might not have been uploaded, or the specified entity tag might not have matched the part's entity tag",
+		http.StatusBadRequest)
+	errInvalidPartOrder = newAPIError("InvalidPartOrder",
+		"The list of parts was not in ascending order. The parts list must be specified in order by part number",
+		http.StatusBadRequest)
+	errInvalidPartNumber = newAPIError("InvalidArgument", "Part number must be an integer between 1 and 10000, inclusive",
+		http.StatusBadRequest)
+	errInvalidObjectName = newAPIError("InvalidArgument", "The specified key is not valid", http.StatusBadRequest)
+	errInvalidMaxKeys    = newAPIError("InvalidArgument", "The max-keys parameter is not valid", http.StatusBadRequest)
+	errInvalidToken      = newAPIError("InvalidArgument", "The continuation token provided is incorrect",
+		http.StatusBadRequest)
+	errInvalidEncoding = newAPIError("InvalidArgument", "Invalid Encoding Method specified in Request",
+		http.StatusBadRequest)
+	errUnsupportedDelimiter = newAPIError("NotImplemented", "Only the \"/\" delimiter is supported",
+		http.StatusNotImplemented)
+	errDirectoryNotEmpty = newAPIError("InvalidRequest", "A directory can be created only with an empty body",
+		http.StatusBadRequest)
+	errInvalidRange = newAPIError("InvalidRange", "The requested range is not satisfiable",
+		http.StatusRequestedRangeNotSatisfiable)
+	errMalformedXML = newAPIError("MalformedXML",
+		"The XML you provided was not well-formed or did not validate against our published schema",
+		http.StatusBadRequest)
+	errNotImplemented = newAPIError("NotImplemented",
+		"A header or query you provided implies functionality that is not implemented", http.StatusNotImplemented)
+	errMethodNotAllowed = newAPIError("MethodNotAllowed",
+		"The specified method is not allowed against this resource", http.StatusMethodNotAllowed)
+	errBucketAlreadyOwnedByYou = newAPIError("BucketAlreadyOwnedByYou",
+		"The bucket you tried to create already exists, and you own it", http.StatusConflict)
+	errQuotaExceeded     = newAPIError("QuotaExceeded", "Denying write due to space limit", http.StatusForbidden)
+	errReadQuotaExceeded = newAPIError("QuotaExceeded", "Denying read due to transfer quota limit",
+		http.StatusForbidden)
+	errInvalidRequest = newAPIError("InvalidRequest", "The operation is not supported for the specified resource",
+		http.StatusBadRequest)
+	errInternalError = newAPIError("InternalError",
+		"We encountered an internal error. Please try again.", http.StatusInternalServerError)
+)
+
+// errorResponse defines the XML body for S3 errors
+type errorResponse struct {
+	XMLName   xml.Name `xml:"Error"`
+	Code      string   `xml:"Code"`
+	Message   string   `xml:"Message"`
+	Resource  string   `xml:"Resource,omitempty"`
+	RequestID string   `xml:"RequestId"`
+}
+
+// getAPIError maps the given error to an S3 error, notExist is returned for not found errors
+func getAPIError(err error, notExist *apiError) *apiError {
+	var apiErr *apiError
+	if errors.As(err, &apiErr) {
+		return apiErr
+	}
+	if _, ok := err.(*util.RecordNotFoundError); ok {
+		return notExist
+	}
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		return notExist
+	case errors.Is(err, os.ErrPermission):
+		return errAccessDenied
+	case errors.Is(err, common.ErrQuotaExceeded):
+		return errQuotaExceeded
+	case errors.Is(err, common.ErrReadQuotaExceeded):
+		return errReadQuotaExceeded
+	case errors.Is(err, common.ErrOpUnsupported):
+		return errInvalidRequest
+	case errors.Is(err, dataprovider.ErrInvalidCredentials):
+		return errSignatureDoesNotMatch
+	default:
+		return errInternalError
+	}
+}
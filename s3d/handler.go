@@ -0,0 +1,336 @@
+package s3d
+
+import (
+	"errors"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/drakkan/sftpgo/v2/common"
+	"github.com/drakkan/sftpgo/v2/dataprovider"
+	"github.com/drakkan/sftpgo/v2/logger"
+	"github.com/drakkan/sftpgo/v2/util"
+	"github.com/drakkan/sftpgo/v2/vfs"
+)
+
+// Connection details for an S3 API connection used to interact with an SFTPGo filesystem
+type Connection struct {
+	*common.BaseConnection
+	request *http.Request
+}
+
+// GetClientVersion returns the connected client's version.
+func (c *Connection) GetClientVersion() string {
+	if c.request != nil {
+		return c.request.UserAgent()
+	}
+	return ""
+}
+
+// GetLocalAddress returns local connection address
+func (c *Connection) GetLocalAddress() string {
+	return util.GetHTTPLocalAddress(c.request)
+}
+
+// GetRemoteAddress returns the connected client's address
+func (c *Connection) GetRemoteAddress() string {
+	if c.request != nil {
+		return c.request.RemoteAddr
+	}
+	return ""
+}
+
+// Disconnect closes the active transfer
+func (c *Connection) Disconnect() (err error) {
+	return c.SignalTransfersAbort()
+}
+
+// GetCommand returns the request method
+func (c *Connection) GetCommand() string {
+	if c.request != nil {
+		return strings.ToUpper(c.request.Method)
+	}
+	return ""
+}
+
+// Stat returns a FileInfo describing the named file/directory, or an error,
+// if any happens
+func (c *Connection) Stat(name string) (os.FileInfo, error) {
+	c.UpdateLastActivity()
+
+	if !c.User.HasPerm(dataprovider.PermListItems, path.Dir(name)) {
+		return nil, c.GetPermissionDeniedError()
+	}
+
+	fi, err := c.DoStat(name, 0)
+	if err != nil {
+		c.Log(logger.LevelDebug, "error running stat on path %#v: %+v", name, err)
+		return nil, err
+	}
+	return fi, err
+}
+
+// checkDownload returns an error if downloading the named file is not allowed
+func (c *Connection) checkDownload(name string) error {
+	if !c.User.HasPerm(dataprovider.PermDownload, path.Dir(name)) {
+		return c.GetPermissionDeniedError()
+	}
+	if !c.User.IsFileAllowed(name) {
+		c.Log(logger.LevelWarn, "reading file %#v is not allowed", name)
+		return c.GetPermissionDeniedError()
+	}
+	return nil
+}
+
+func (c *Connection) getFileReader(name string, offset int64) (*s3File, error) {
+	c.UpdateLastActivity()
+
+	if err := c.checkDownload(name); err != nil {
+		return nil, err
+	}
+
+	fs, p, err := c.GetFsAndResolvedPath(name)
+	if err != nil {
+		return nil, err
+	}
+
+	transferQuota := c.GetTransferQuota(name)
+	if !transferQuota.HasDownloadSpace() {
+		c.Log(logger.LevelInfo, "denying file read due to quota limits")
+		return nil, c.GetReadQuotaExceededError()
+	}
+
+	if err := common.ExecutePreAction(&c.User, common.OperationPreDownload, p, name, c.GetProtocol(), c.GetRemoteIP(), 0, 0); err != nil {
+		c.Log(logger.LevelDebug, "download for file %#v denied by pre action: %v", name, err)
+		return nil, c.GetPermissionDeniedError()
+	}
+
+	file, r, cancelFn, err := fs.Open(p, offset)
+	if err != nil {
+		c.Log(logger.LevelWarn, "could not open file %#v for reading: %+v", p, err)
+		return nil, c.GetFsError(fs, err)
+	}
+
+	baseTransfer := common.NewBaseTransfer(file, c.BaseConnection, cancelFn, p, p, name, common.TransferDownload,
+		0, 0, 0, false, fs, transferQuota)
+	return newS3File(baseTransfer, nil, r), nil
+}
+
+// checkUpload returns an error if writing the named file is not allowed.
+// The allowed size for the upload, 0 means unlimited, is returned
+func (c *Connection) checkUpload(name string) (int64, error) {
+	c.UpdateLastActivity()
+
+	if !c.User.IsFileAllowed(name) {
+		c.Log(logger.LevelWarn, "writing file %#v is not allowed", name)
+		return 0, c.GetPermissionDeniedError()
+	}
+
+	fs, p, err := c.GetFsAndResolvedPath(name)
+	if err != nil {
+		return 0, err
+	}
+	isNewFile, fileSize, err := c.checkUploadTarget(fs, p, name)
+	if err != nil {
+		return 0, err
+	}
+	quotaResult := c.HasSpace(isNewFile, false, name)
+	if !quotaResult.HasSpace {
+		c.Log(logger.LevelInfo, "denying file write due to quota limits")
+		return 0, common.ErrQuotaExceeded
+	}
+	transferQuota := c.GetTransferQuota(name)
+	if !transferQuota.HasUploadSpace() {
+		c.Log(logger.LevelInfo, "denying file write due to transfer quota limits")
+		return 0, common.ErrQuotaExceeded
+	}
+	maxWriteSize, _ := c.GetMaxWriteSize(quotaResult, false, fileSize, fs.IsUploadResumeSupported())
+	if maxWriteSize > 0 && !isNewFile {
+		maxWriteSize += fileSize
+	}
+	return maxWriteSize, nil
+}
+
+func (c *Connection) getFileWriter(name string) (*s3File, error) {
+	c.UpdateLastActivity()
+
+	if !c.User.IsFileAllowed(name) {
+		c.Log(logger.LevelWarn, "writing file %#v is not allowed", name)
+		return nil, c.GetPermissionDeniedError()
+	}
+
+	fs, p, err := c.GetFsAndResolvedPath(name)
+	if err != nil {
+		return nil, err
+	}
+	filePath := p
+	if common.Config.IsAtomicUploadEnabled() && fs.IsAtomicUploadSupported() {
+		filePath = fs.GetAtomicUploadPath(p)
+	}
+
+	isNewFile, fileSize, err := c.checkUploadTarget(fs, p, name)
+	if err != nil {
+		return nil, err
+	}
+
+	if !isNewFile && common.Config.IsAtomicUploadEnabled() && fs.IsAtomicUploadSupported() {
+		err = fs.Rename(p, filePath)
+		if err != nil {
+			c.Log(logger.LevelWarn, "error renaming existing file for atomic upload, source: %#v, dest: %#v, err: %+v",
+				p, filePath, err)
+			return nil, c.GetFsError(fs, err)
+		}
+	}
+
+	return c.handleUploadFile(fs, p, filePath, name, isNewFile, fileSize)
+}
+
+// checkUploadTarget checks the permissions to write the named file,
+// it returns if the target is a new file and the current size
+func (c *Connection) checkUploadTarget(fs vfs.Fs, fsPath, name string) (bool, int64, error) {
+	stat, err := fs.Lstat(fsPath)
+	if (err == nil && stat.Mode()&os.ModeSymlink != 0) || fs.IsNotExist(err) {
+		if !c.User.HasPerm(dataprovider.PermUpload, path.Dir(name)) {
+			return false, 0, c.GetPermissionDeniedError()
+		}
+		return true, 0, nil
+	}
+	if err != nil {
+		c.Log(logger.LevelError, "error performing file stat %#v: %+v", fsPath, err)
+		return false, 0, c.GetFsError(fs, err)
+	}
+	// This happen if we upload a file that has the same name of an existing directory
+	if stat.IsDir() {
+		c.Log(logger.LevelWarn, "attempted to open a directory for writing to: %#v", fsPath)
+		return false, 0, c.GetOpUnsupportedError()
+	}
+	if !c.User.HasPerm(dataprovider.PermOverwrite, path.Dir(name)) {
+		return false, 0, c.GetPermissionDeniedError()
+	}
+	return false, stat.Size(), nil
+}
+
+func (c *Connection) handleUploadFile(fs vfs.Fs, resolvedPath, filePath, requestPath string, isNewFile bool, fileSize int64) (*s3File, error) {
+	quotaResult := c.HasSpace(isNewFile, false, requestPath)
+	if !quotaResult.HasSpace {
+		c.Log(logger.LevelInfo, "denying file write due to quota limits")
+		return nil, common.ErrQuotaExceeded
+	}
+	transferQuota := c.GetTransferQuota(requestPath)
+	if !transferQuota.HasUploadSpace() {
+		c.Log(logger.LevelInfo, "denying file write due to transfer quota limits")
+		return nil, common.ErrQuotaExceeded
+	}
+	err := common.ExecutePreAction(&c.User, common.OperationPreUpload, resolvedPath, requestPath, c.GetProtocol(), c.GetRemoteIP(), fileSize, os.O_TRUNC)
+	if err != nil {
+		c.Log(logger.LevelDebug, "upload for file %#v denied by pre action: %v", requestPath, err)
+		return nil, c.GetPermissionDeniedError()
+	}
+
+	maxWriteSize, _ := c.GetMaxWriteSize(quotaResult, false, fileSize, fs.IsUploadResumeSupported())
+
+	file, w, cancelFn, err := fs.Create(filePath, 0)
+	if err != nil {
+		c.Log(logger.LevelWarn, "error opening existing file, source: %#v, err: %+v", filePath, err)
+		return nil, c.GetFsError(fs, err)
+	}
+
+	initialSize := int64(0)
+	if !isNewFile {
+		if vfs.IsLocalOrSFTPFs(fs) {
+			c.releaseOverwrittenSize(requestPath, fileSize)
+		} else {
+			initialSize = fileSize
+		}
+		if maxWriteSize > 0 {
+			maxWriteSize += fileSize
+		}
+	}
+
+	vfs.SetPathPermissions(fs, filePath, c.User.GetUID(), c.User.GetGID())
+
+	baseTransfer := common.NewBaseTransfer(file, c.BaseConnection, cancelFn, resolvedPath, filePath, requestPath,
+		common.TransferUpload, 0, initialSize, maxWriteSize, isNewFile, fs, transferQuota)
+	return newS3File(baseTransfer, w, nil), nil
+}
+
+// releaseOverwrittenSize removes the size of a file that will be overwritten
+// from the used quota
+func (c *Connection) releaseOverwrittenSize(requestPath string, fileSize int64) {
+	vfolder, err := c.User.GetVirtualFolderForPath(path.Dir(requestPath))
+	if err == nil {
+		dataprovider.UpdateVirtualFolderQuota(&vfolder.BaseVirtualFolder, 0, -fileSize, false) //nolint:errcheck
+		if vfolder.IsIncludedInUserQuota() {
+			dataprovider.UpdateUserQuota(&c.User, 0, -fileSize, false) //nolint:errcheck
+		}
+	} else {
+		dataprovider.UpdateUserQuota(&c.User, 0, -fileSize, false) //nolint:errcheck
+	}
+}
+
+// createDirs creates the named directory and any missing parent, S3 has no directories
+// so they are implicitly created when an object is stored
+func (c *Connection) createDirs(name string) error {
+	if name == "/" {
+		return nil
+	}
+	info, err := c.DoStat(name, 0)
+	if err == nil {
+		if !info.IsDir() {
+			c.Log(logger.LevelDebug, "unable to create directory %#v, a file with the same name exists", name)
+			return c.GetOpUnsupportedError()
+		}
+		return nil
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	if err := c.createDirs(path.Dir(name)); err != nil {
+		return err
+	}
+	if err := c.CreateDir(name); err != nil {
+		// the directory could be created by a concurrent request
+		if info, errStat := c.DoStat(name, 0); errStat == nil && info.IsDir() {
+			return nil
+		}
+		return err
+	}
+	return nil
+}
+
+// removeObject removes the named file. Removing a missing file is not an error
+func (c *Connection) removeObject(name string) error {
+	c.UpdateLastActivity()
+
+	fs, p, err := c.GetFsAndResolvedPath(name)
+	if err != nil {
+		return err
+	}
+	info, err := fs.Lstat(p)
+	if err != nil {
+		if fs.IsNotExist(err) {
+			return nil
+		}
+		c.Log(logger.LevelWarn, "failed to remove file %#v: stat error: %+v", p, err)
+		return c.GetFsError(fs, err)
+	}
+	if info.IsDir() {
+		// a directory is not an object, a key with a trailing slash is required to remove it
+		return nil
+	}
+	return c.RemoveFile(fs, p, name, info)
+}
+
+// removeDirObject removes the named directory. Removing a missing directory is not an error
+func (c *Connection) removeDirObject(name string) error {
+	c.UpdateLastActivity()
+
+	if err := c.RemoveDir(name); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	return nil
+}
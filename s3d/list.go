@@ -0,0 +1,198 @@
+package s3d
+
+import (
+	"errors"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/drakkan/sftpgo/v2/logger"
+)
+
+const (
+	defaultMaxKeys = 1000
+)
+
+type listParams struct {
+	prefix       string
+	delimiter    string
+	encodingType string
+	maxKeys      int
+}
+
+// encode returns the given value encoded as requested by the client
+func (p *listParams) encode(value string) string {
+	if p.encodingType == "" {
+		return value
+	}
+	return awsURIEncode(value, false)
+}
+
+func getListParams(query url.Values) (listParams, error) {
+	params := listParams{
+		prefix:       query.Get("prefix"),
+		delimiter:    query.Get("delimiter"),
+		encodingType: query.Get("encoding-type"),
+		maxKeys:      defaultMaxKeys,
+	}
+	if params.encodingType != "" && params.encodingType != "url" {
+		return params, errInvalidEncoding
+	}
+	if params.delimiter != "" && params.delimiter != "/" {
+		return params, errUnsupportedDelimiter
+	}
+	if value := query.Get("max-keys"); value != "" {
+		maxKeys, err := strconv.Atoi(value)
+		if err != nil || maxKeys < 0 {
+			return params, errInvalidMaxKeys
+		}
+		if maxKeys < defaultMaxKeys {
+			params.maxKeys = maxKeys
+		}
+	}
+	return params, nil
+}
+
+// listEntry is an object or, if a delimiter is used, a common prefix
+type listEntry struct {
+	key      string
+	info     os.FileInfo
+	isPrefix bool
+}
+
+// objectsLister walks the user's virtual filesystem returning the entries sorted
+// by key as S3 does
+type objectsLister struct {
+	connection *Connection
+	prefix     string
+	delimiter  string
+	startAfter string
+	limit      int
+	entries    []listEntry
+}
+
+func (l *objectsLister) isFull() bool {
+	return len(l.entries) >= l.limit
+}
+
+func (l *objectsLister) list() error {
+	dirKey := ""
+	if idx := strings.LastIndex(l.prefix, "/"); idx >= 0 {
+		dirKey = l.prefix[:idx+1]
+	}
+	if dirKey != "" {
+		info, err := l.connection.DoStat("/"+strings.TrimSuffix(dirKey, "/"), 0)
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if !info.IsDir() {
+			return nil
+		}
+	}
+	return l.walk(dirKey)
+}
+
+// walk lists the entries inside the directory identified by dirKey, an empty
+// key or a key ending with "/"
+func (l *objectsLister) walk(dirKey string) error {
+	name := "/" + strings.TrimSuffix(dirKey, "/")
+	contents, err := l.connection.ListDir(name)
+	if err != nil {
+		return err
+	}
+	entries := make([]listEntry, 0, len(contents))
+	for _, info := range contents {
+		key := dirKey + info.Name()
+		if info.IsDir() {
+			key += "/"
+		}
+		entries = append(entries, listEntry{key: key, info: info})
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].key < entries[j].key
+	})
+
+	for _, entry := range entries {
+		if l.isFull() {
+			return nil
+		}
+		if !l.isCandidate(entry.key) {
+			continue
+		}
+		if !entry.info.IsDir() {
+			if entry.key > l.startAfter && strings.HasPrefix(entry.key, l.prefix) {
+				l.entries = append(l.entries, entry)
+			}
+			continue
+		}
+		if l.delimiter != "" && len(entry.key) > len(l.prefix) {
+			// the directory key contains the delimiter after the prefix
+			if entry.key > l.startAfter {
+				entry.isPrefix = true
+				l.entries = append(l.entries, entry)
+			}
+			continue
+		}
+		numEntries := len(l.entries)
+		if err := l.walk(entry.key); err != nil {
+			if errors.Is(err, os.ErrPermission) || errors.Is(err, os.ErrNotExist) {
+				l.connection.Log(logger.LevelDebug, "skipping directory %#v: %v", entry.key, err)
+				continue
+			}
+			return err
+		}
+		// empty directories are returned as zero size objects
+		if l.delimiter == "" && len(l.entries) == numEntries && entry.key > l.startAfter &&
+			strings.HasPrefix(entry.key, l.prefix) && !l.isFull() {
+			l.entries = append(l.entries, entry)
+		}
+	}
+	return nil
+}
+
+// isCandidate returns false if the given key, and any key inside it for directories,
+// cannot be included in the results
+func (l *objectsLister) isCandidate(key string) bool {
+	if !strings.HasPrefix(key, l.prefix) && !strings.HasPrefix(l.prefix, key) {
+		return false
+	}
+	if strings.HasSuffix(key, "/") {
+		// all the keys inside this directory sort before startAfter
+		if key <= l.startAfter && !strings.HasPrefix(l.startAfter, key) {
+			return false
+		}
+		return true
+	}
+	return key > l.startAfter
+}
+
+func getListResults(entries []listEntry, params listParams) ([]objectInfo, []commonPrefix) {
+	var contents []objectInfo
+	var prefixes []commonPrefix
+
+	for _, entry := range entries {
+		if entry.isPrefix {
+			prefixes = append(prefixes, commonPrefix{Prefix: params.encode(entry.key)})
+			continue
+		}
+		size := entry.info.Size()
+		etag := getObjectETag(entry.info)
+		if entry.info.IsDir() {
+			size = 0
+			etag = `"` + emptyObjectETag + `"`
+		}
+		contents = append(contents, objectInfo{
+			Key:          params.encode(entry.key),
+			LastModified: entry.info.ModTime().UTC().Format(iso8601Format),
+			ETag:         etag,
+			Size:         size,
+			StorageClass: storageClass,
+		})
+	}
+	return contents, prefixes
+}
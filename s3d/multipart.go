@@ -0,0 +1,565 @@
+package s3d
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/xid"
+
+	"github.com/drakkan/sftpgo/v2/logger"
+	"github.com/drakkan/sftpgo/v2/util"
+	"github.com/drakkan/sftpgo/v2/vfs"
+)
+
+const (
+	multipartUploadsDir         = "s3d_uploads"
+	multipartUploadFile         = "upload.json"
+	multipartUploadExpiration   = 24 * time.Hour
+	multipartCleanupInterval    = 1 * time.Hour
+	maxPartNumber               = 10000
+	multipartUploadIDLength     = 16
+	multipartPartMetadataSuffix = ".json"
+)
+
+var (
+	multipartUploads   = &multipartUploadsStore{}
+	uploadIDRegex      = regexp.MustCompile(`^[0-9a-f]{32}$`)
+	multipartTickerMu  sync.Mutex
+	multipartTicker    *time.Ticker
+	multipartTickerEnd chan bool
+)
+
+type initiateMultipartUploadResult struct {
+	XMLName  xml.Name `xml:"http://s3.amazonaws.com/doc/2006-03-01/ InitiateMultipartUploadResult"`
+	Bucket   string   `xml:"Bucket"`
+	Key      string   `xml:"Key"`
+	UploadID string   `xml:"UploadId"`
+}
+
+type completeMultipartUploadRequest struct {
+	XMLName xml.Name `xml:"CompleteMultipartUpload"`
+	Parts   []struct {
+		PartNumber int    `xml:"PartNumber"`
+		ETag       string `xml:"ETag"`
+	} `xml:"Part"`
+}
+
+type completeMultipartUploadResult struct {
+	XMLName  xml.Name `xml:"http://s3.amazonaws.com/doc/2006-03-01/ CompleteMultipartUploadResult"`
+	Location string   `xml:"Location"`
+	Bucket   string   `xml:"Bucket"`
+	Key      string   `xml:"Key"`
+	ETag     string   `xml:"ETag"`
+}
+
+// multipartUpload defines an in progress multipart upload
+type multipartUpload struct {
+	ID        string `json:"id"`
+	Username  string `json:"username"`
+	Key       string `json:"key"`
+	CreatedAt int64  `json:"created_at"`
+}
+
+// multipartPart defines an uploaded part
+type multipartPart struct {
+	Number   int    `json:"number"`
+	ETag     string `json:"etag"`
+	Size     int64  `json:"size"`
+	DataFile string `json:"data_file"`
+}
+
+// multipartUploadsStore stores the uploaded parts inside a local directory
+// until the upload is completed or aborted
+type multipartUploadsStore struct {
+	sync.Mutex
+	basePath   string
+	completing map[string]bool
+}
+
+func (s *multipartUploadsStore) initialize() error {
+	tempPath := vfs.GetTempPath()
+	if tempPath == "" {
+		tempPath = os.TempDir()
+	}
+	s.Lock()
+	defer s.Unlock()
+
+	s.basePath = filepath.Join(tempPath, multipartUploadsDir)
+	s.completing = make(map[string]bool)
+	return os.MkdirAll(s.basePath, 0700)
+}
+
+func (s *multipartUploadsStore) getUploadPath(id string) string {
+	return filepath.Join(s.basePath, id)
+}
+
+func (s *multipartUploadsStore) create(username, key string) (multipartUpload, error) {
+	upload := multipartUpload{
+		ID:        hex.EncodeToString(util.GenerateRandomBytes(multipartUploadIDLength)),
+		Username:  username,
+		Key:       key,
+		CreatedAt: util.GetTimeAsMsSinceEpoch(time.Now()),
+	}
+	uploadPath := s.getUploadPath(upload.ID)
+	if err := os.Mkdir(uploadPath, 0700); err != nil {
+		return upload, err
+	}
+	data, err := json.Marshal(upload)
+	if err != nil {
+		return upload, err
+	}
+	if err := writeFileAtomic(filepath.Join(uploadPath, multipartUploadFile), data); err != nil {
+		os.RemoveAll(uploadPath) //nolint:errcheck
+		return upload, err
+	}
+	return upload, nil
+}
+
+// get returns the multipart upload with the given id, it must belong to the given user and key
+func (s *multipartUploadsStore) get(id, username, key string) (multipartUpload, error) {
+	var upload multipartUpload
+
+	if !uploadIDRegex.MatchString(id) {
+		return upload, errNoSuchUpload
+	}
+	data, err := os.ReadFile(filepath.Join(s.getUploadPath(id), multipartUploadFile))
+	if err != nil {
+		return upload, errNoSuchUpload
+	}
+	if err := json.Unmarshal(data, &upload); err != nil {
+		return upload, errNoSuchUpload
+	}
+	if upload.ID != id || upload.Username != username || upload.Key != key {
+		return upload, errNoSuchUpload
+	}
+	return upload, nil
+}
+
+func (s *multipartUploadsStore) isCompleting(id string) bool {
+	s.Lock()
+	defer s.Unlock()
+
+	return s.completing[id]
+}
+
+// setCompleting marks the upload as completing, it returns false if the upload
+// is already completing
+func (s *multipartUploadsStore) setCompleting(id string, completing bool) bool {
+	s.Lock()
+	defer s.Unlock()
+
+	if !completing {
+		delete(s.completing, id)
+		return true
+	}
+	if s.completing[id] {
+		return false
+	}
+	s.completing[id] = true
+	return true
+}
+
+// addPart stores a part reading at most maxSize bytes, 0 means unlimited, from the given reader
+func (s *multipartUploadsStore) addPart(upload multipartUpload, number int, reader io.Reader, maxSize int64) (multipartPart, error) {
+	part := multipartPart{
+		Number:   number,
+		DataFile: fmt.Sprintf("%05d.%v", number, xid.New().String()),
+	}
+	uploadPath := s.getUploadPath(upload.ID)
+	dataPath := filepath.Join(uploadPath, part.DataFile)
+	f, err := os.OpenFile(dataPath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+	if err != nil {
+		return part, err
+	}
+	if maxSize > 0 {
+		reader = io.LimitReader(reader, maxSize+1)
+	}
+	hasher := md5.New()
+	part.Size, err = io.Copy(f, io.TeeReader(reader, hasher))
+	errClose := f.Close()
+	if err == nil {
+		err = errClose
+	}
+	if err == nil && maxSize > 0 && part.Size > maxSize {
+		err = errQuotaExceeded
+	}
+	if err != nil {
+		os.Remove(dataPath) //nolint:errcheck
+		return part, err
+	}
+	part.ETag = hex.EncodeToString(hasher.Sum(nil))
+
+	data, err := json.Marshal(part)
+	if err != nil {
+		os.Remove(dataPath) //nolint:errcheck
+		return part, err
+	}
+
+	s.Lock()
+	defer s.Unlock()
+
+	if s.completing[upload.ID] {
+		os.Remove(dataPath) //nolint:errcheck
+		return part, errNoSuchUpload
+	}
+	metadataPath := filepath.Join(uploadPath, fmt.Sprintf("%05d%v", number, multipartPartMetadataSuffix))
+	var oldPart multipartPart
+	if oldData, err := os.ReadFile(metadataPath); err == nil {
+		if err := json.Unmarshal(oldData, &oldPart); err != nil {
+			oldPart.DataFile = ""
+		}
+	}
+	if err := writeFileAtomic(metadataPath, data); err != nil {
+		os.Remove(dataPath) //nolint:errcheck
+		return part, err
+	}
+	if oldPart.DataFile != "" && oldPart.DataFile == filepath.Base(oldPart.DataFile) {
+		os.Remove(filepath.Join(uploadPath, oldPart.DataFile)) //nolint:errcheck
+	}
+	return part, nil
+}
+
+// getParts returns the uploaded parts indexed by part number
+func (s *multipartUploadsStore) getParts(upload multipartUpload) (map[int]multipartPart, error) {
+	uploadPath := s.getUploadPath(upload.ID)
+	entries, err := os.ReadDir(uploadPath)
+	if err != nil {
+		return nil, err
+	}
+	parts := make(map[int]multipartPart)
+	for _, entry := range entries {
+		name := entry.Name()
+		if !strings.HasSuffix(name, multipartPartMetadataSuffix) || name == multipartUploadFile {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(uploadPath, name))
+		if err != nil {
+			return nil, err
+		}
+		var part multipartPart
+		if err := json.Unmarshal(data, &part); err != nil {
+			return nil, err
+		}
+		if part.DataFile != filepath.Base(part.DataFile) {
+			return nil, fmt.Errorf("invalid data file %#v for part %v", part.DataFile, part.Number)
+		}
+		parts[part.Number] = part
+	}
+	return parts, nil
+}
+
+func (s *multipartUploadsStore) getPartPath(upload multipartUpload, part multipartPart) string {
+	return filepath.Join(s.getUploadPath(upload.ID), part.DataFile)
+}
+
+func (s *multipartUploadsStore) remove(id string) error {
+	if !uploadIDRegex.MatchString(id) {
+		return errNoSuchUpload
+	}
+	return os.RemoveAll(s.getUploadPath(id))
+}
+
+// cleanup removes the expired multipart uploads
+func (s *multipartUploadsStore) cleanup() {
+	entries, err := os.ReadDir(s.basePath)
+	if err != nil {
+		logger.Warn(logSender, "", "unable to list multipart uploads: %v", err)
+		return
+	}
+	limit := time.Now().Add(-multipartUploadExpiration)
+	for _, entry := range entries {
+		if !entry.IsDir() || !uploadIDRegex.MatchString(entry.Name()) || s.isCompleting(entry.Name()) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil || info.ModTime().After(limit) {
+			continue
+		}
+		var upload multipartUpload
+		data, err := os.ReadFile(filepath.Join(s.getUploadPath(entry.Name()), multipartUploadFile))
+		if err == nil {
+			err = json.Unmarshal(data, &upload)
+		}
+		if err == nil && util.GetTimeFromMsecSinceEpoch(upload.CreatedAt).After(limit) {
+			continue
+		}
+		logger.Debug(logSender, "", "removing expired multipart upload %#v, user %#v, key %#v", entry.Name(),
+			upload.Username, upload.Key)
+		if err := s.remove(entry.Name()); err != nil {
+			logger.Warn(logSender, "", "unable to remove expired multipart upload %#v: %v", entry.Name(), err)
+		}
+	}
+}
+
+func startMultipartUploadsCleanupTimer() {
+	multipartTickerMu.Lock()
+	defer multipartTickerMu.Unlock()
+
+	if multipartTicker != nil {
+		return
+	}
+	multipartTicker = time.NewTicker(multipartCleanupInterval)
+	multipartTickerEnd = make(chan bool)
+
+	go func() {
+		for {
+			select {
+			case <-multipartTickerEnd:
+				return
+			case <-multipartTicker.C:
+				multipartUploads.cleanup()
+			}
+		}
+	}()
+}
+
+func writeFileAtomic(name string, data []byte) error {
+	tmpName := name + "." + xid.New().String() + ".tmp"
+	if err := os.WriteFile(tmpName, data, 0600); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpName, name); err != nil {
+		os.Remove(tmpName) //nolint:errcheck
+		return err
+	}
+	return nil
+}
+
+func (h *requestHandler) createMultipartUpload(w http.ResponseWriter, r *http.Request, key, name string, isDir bool) {
+	if isDir {
+		sendAPIError(w, r, errInvalidObjectName)
+		return
+	}
+	if _, err := h.connection.checkUpload(name); err != nil {
+		sendAPIError(w, r, getAPIError(err, errNoSuchKey))
+		return
+	}
+	upload, err := multipartUploads.create(h.connection.User.Username, key)
+	if err != nil {
+		h.connection.Log(logger.LevelError, "unable to create multipart upload for %#v: %v", name, err)
+		sendAPIError(w, r, errInternalError)
+		return
+	}
+	h.connection.Log(logger.LevelDebug, "multipart upload %#v created for %#v", upload.ID, name)
+	resp := initiateMultipartUploadResult{
+		Bucket:   h.connection.User.Username,
+		Key:      key,
+		UploadID: upload.ID,
+	}
+	sendXMLResponse(w, r, http.StatusOK, resp)
+	writeLog(r, http.StatusOK, nil)
+}
+
+func (h *requestHandler) uploadPart(w http.ResponseWriter, r *http.Request, name string, isDir bool, uploadID,
+	partNumber string,
+) {
+	key := strings.TrimPrefix(name, "/")
+	if isDir {
+		sendAPIError(w, r, errNoSuchUpload)
+		return
+	}
+	number, err := strconv.Atoi(partNumber)
+	if err != nil || number < 1 || number > maxPartNumber {
+		sendAPIError(w, r, errInvalidPartNumber)
+		return
+	}
+	upload, err := multipartUploads.get(uploadID, h.connection.User.Username, key)
+	if err != nil || multipartUploads.isCompleting(upload.ID) {
+		sendAPIError(w, r, errNoSuchUpload)
+		return
+	}
+	expectedMD5, err := getContentMD5(r)
+	if err != nil {
+		sendAPIError(w, r, err.(*apiError))
+		return
+	}
+	maxWriteSize, err := h.connection.checkUpload(name)
+	if err != nil {
+		sendAPIError(w, r, getAPIError(err, errNoSuchKey))
+		return
+	}
+	if maxWriteSize > 0 {
+		parts, err := multipartUploads.getParts(upload)
+		if err != nil {
+			h.connection.Log(logger.LevelError, "unable to get parts for multipart upload %#v: %v", upload.ID, err)
+			sendAPIError(w, r, errNoSuchUpload)
+			return
+		}
+		for _, part := range parts {
+			if part.Number != number {
+				maxWriteSize -= part.Size
+			}
+		}
+		if maxWriteSize <= 0 {
+			sendAPIError(w, r, errQuotaExceeded)
+			return
+		}
+	}
+	h.connection.UpdateLastActivity()
+	part, err := multipartUploads.addPart(upload, number, r.Body, maxWriteSize)
+	if err != nil {
+		h.connection.Log(logger.LevelDebug, "unable to store part %v for multipart upload %#v: %v", number,
+			upload.ID, err)
+		sendAPIError(w, r, getAPIError(err, errNoSuchUpload))
+		return
+	}
+	if expectedMD5 != nil && hex.EncodeToString(expectedMD5) != part.ETag {
+		sendAPIError(w, r, errBadDigest)
+		return
+	}
+	sendETagResponse(w, r, fmt.Sprintf("%#v", part.ETag))
+}
+
+func (h *requestHandler) completeMultipartUpload(w http.ResponseWriter, r *http.Request, key, name string, isDir bool,
+	uploadID string,
+) {
+	if isDir {
+		sendAPIError(w, r, errNoSuchUpload)
+		return
+	}
+	upload, err := multipartUploads.get(uploadID, h.connection.User.Username, key)
+	if err != nil {
+		sendAPIError(w, r, errNoSuchUpload)
+		return
+	}
+	var req completeMultipartUploadRequest
+	if err := readXMLBody(r, &req); err != nil {
+		sendAPIError(w, r, err.(*apiError))
+		return
+	}
+	if len(req.Parts) == 0 {
+		sendAPIError(w, r, errMalformedXML)
+		return
+	}
+	if !multipartUploads.setCompleting(upload.ID, true) {
+		sendAPIError(w, r, errNoSuchUpload)
+		return
+	}
+	defer multipartUploads.setCompleting(upload.ID, false)
+
+	parts, err := getCompletedParts(upload, req)
+	if err != nil {
+		sendAPIError(w, r, getAPIError(err, errInvalidPart))
+		return
+	}
+	etag, err := h.writeMultipartObject(upload, name, parts)
+	if err != nil {
+		sendAPIError(w, r, getAPIError(err, errNoSuchKey))
+		return
+	}
+	if err := multipartUploads.remove(upload.ID); err != nil {
+		h.connection.Log(logger.LevelWarn, "unable to remove completed multipart upload %#v: %v", upload.ID, err)
+	}
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	resp := completeMultipartUploadResult{
+		Location: fmt.Sprintf("%v://%v/%v/%v", scheme, r.Host, h.connection.User.Username, awsURIEncode(key, false)),
+		Bucket:   h.connection.User.Username,
+		Key:      key,
+		ETag:     etag,
+	}
+	sendXMLResponse(w, r, http.StatusOK, resp)
+	writeLog(r, http.StatusOK, nil)
+}
+
+// getCompletedParts returns the parts to use to complete the given upload
+func getCompletedParts(upload multipartUpload, req completeMultipartUploadRequest) ([]multipartPart, error) {
+	uploaded, err := multipartUploads.getParts(upload)
+	if err != nil {
+		return nil, err
+	}
+	parts := make([]multipartPart, 0, len(req.Parts))
+	for idx, p := range req.Parts {
+		if idx > 0 && p.PartNumber <= req.Parts[idx-1].PartNumber {
+			return nil, errInvalidPartOrder
+		}
+		part, ok := uploaded[p.PartNumber]
+		if !ok || part.ETag != strings.Trim(strings.TrimSpace(p.ETag), `"`) {
+			return nil, errInvalidPart
+		}
+		parts = append(parts, part)
+	}
+	return parts, nil
+}
+
+// writeMultipartObject concatenates the given parts to the named file, it returns the object's entity tag
+func (h *requestHandler) writeMultipartObject(upload multipartUpload, name string, parts []multipartPart) (string, error) {
+	if err := h.connection.createDirs(path.Dir(name)); err != nil {
+		return "", err
+	}
+	writer, err := h.connection.getFileWriter(name)
+	if err != nil {
+		return "", err
+	}
+	hasher := md5.New()
+	for _, part := range parts {
+		err = h.copyPart(writer, upload, part)
+		if err != nil {
+			break
+		}
+		checksum, _ := hex.DecodeString(part.ETag)
+		hasher.Write(checksum) //nolint:errcheck
+	}
+	if err != nil {
+		writer.TransferError(err)
+		writer.Close() //nolint:errcheck
+		return "", err
+	}
+	if err := writer.Close(); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%#v", fmt.Sprintf("%v-%v", hex.EncodeToString(hasher.Sum(nil)), len(parts))), nil
+}
+
+func (h *requestHandler) copyPart(writer io.Writer, upload multipartUpload, part multipartPart) error {
+	h.connection.UpdateLastActivity()
+
+	f, err := os.Open(multipartUploads.getPartPath(upload, part))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return errInvalidPart
+		}
+		return err
+	}
+	defer f.Close()
+
+	n, err := io.Copy(writer, f)
+	if err == nil && n != part.Size {
+		err = fmt.Errorf("unexpected size for part %v: %v, expected: %v", part.Number, n, part.Size)
+	}
+	return err
+}
+
+func (h *requestHandler) abortMultipartUpload(w http.ResponseWriter, r *http.Request, name, uploadID string) {
+	upload, err := multipartUploads.get(uploadID, h.connection.User.Username, strings.TrimPrefix(name, "/"))
+	if err != nil || !multipartUploads.setCompleting(upload.ID, true) {
+		sendAPIError(w, r, errNoSuchUpload)
+		return
+	}
+	defer multipartUploads.setCompleting(upload.ID, false)
+
+	if err := multipartUploads.remove(upload.ID); err != nil {
+		h.connection.Log(logger.LevelWarn, "unable to remove multipart upload %#v: %v", upload.ID, err)
+		sendAPIError(w, r, errInternalError)
+		return
+	}
+	w.Header().Set("x-amz-request-id", getRequestID(r))
+	w.WriteHeader(http.StatusNoContent)
+	writeLog(r, http.StatusNoContent, nil)
+}
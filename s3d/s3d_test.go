@@ -0,0 +1,690 @@
+package s3d_test
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/drakkan/sftpgo/v2/common"
+	"github.com/drakkan/sftpgo/v2/config"
+	"github.com/drakkan/sftpgo/v2/dataprovider"
+	"github.com/drakkan/sftpgo/v2/httpdtest"
+	"github.com/drakkan/sftpgo/v2/logger"
+	"github.com/drakkan/sftpgo/v2/s3d"
+	"github.com/drakkan/sftpgo/v2/sdk"
+)
+
+const (
+	logSender       = "s3dTesting"
+	s3ServerAddr    = "127.0.0.1:9100"
+	s3ServerPort    = 9100
+	defaultUsername = "test_user_s3"
+	defaultPassword = "test_password"
+	configDir       = ".."
+)
+
+var (
+	allPerms     = []string{dataprovider.PermAny}
+	homeBasePath string
+	logFilePath  string
+)
+
+func TestMain(m *testing.M) {
+	logFilePath = filepath.Join(configDir, "sftpgo_s3d_test.log")
+	logger.InitLogger(logFilePath, 5, 1, 28, false, zerolog.DebugLevel)
+	os.Setenv("SFTPGO_DATA_PROVIDER__CREATE_DEFAULT_ADMIN", "1")
+	os.Setenv("SFTPGO_DEFAULT_ADMIN_USERNAME", "admin")
+	os.Setenv("SFTPGO_DEFAULT_ADMIN_PASSWORD", "password")
+	err := config.LoadConfig(configDir, "")
+	if err != nil {
+		logger.ErrorToConsole("error loading configuration: %v", err)
+		os.Exit(1)
+	}
+	providerConf := config.GetProviderConf()
+	logger.InfoToConsole("Starting S3D tests, provider: %v", providerConf.Driver)
+	commonConf := config.GetCommonConfig()
+	homeBasePath = os.TempDir()
+
+	err = common.Initialize(commonConf)
+	if err != nil {
+		logger.WarnToConsole("error initializing common: %v", err)
+		os.Exit(1)
+	}
+
+	err = dataprovider.Initialize(providerConf, configDir, true)
+	if err != nil {
+		logger.ErrorToConsole("error initializing data provider: %v", err)
+		os.Exit(1)
+	}
+
+	httpConfig := config.GetHTTPConfig()
+	httpConfig.Initialize(configDir) //nolint:errcheck
+	kmsConfig := config.GetKMSConfig()
+	err = kmsConfig.Initialize()
+	if err != nil {
+		logger.ErrorToConsole("error initializing kms: %v", err)
+		os.Exit(1)
+	}
+
+	httpdConf := config.GetHTTPDConfig()
+	httpdConf.Bindings[0].Port = 8077
+	httpdtest.SetBaseURL("http://127.0.0.1:8077")
+
+	s3dConf := config.GetS3DConfig()
+	s3dConf.Bindings = []s3d.Binding{
+		{
+			Port: s3ServerPort,
+		},
+	}
+
+	status := s3d.GetStatus()
+	if status.IsActive {
+		logger.ErrorToConsole("S3 server is already active")
+		os.Exit(1)
+	}
+
+	go func() {
+		logger.Debug(logSender, "", "initializing S3 server with config %+v", s3dConf)
+		if err := s3dConf.Initialize(configDir); err != nil {
+			logger.ErrorToConsole("could not start S3 server: %v", err)
+			os.Exit(1)
+		}
+	}()
+
+	go func() {
+		if err := httpdConf.Initialize(configDir); err != nil {
+			logger.ErrorToConsole("could not start HTTP server: %v", err)
+			os.Exit(1)
+		}
+	}()
+
+	waitTCPListening(s3dConf.Bindings[0].GetAddress())
+	waitTCPListening(httpdConf.Bindings[0].GetAddress())
+
+	exitCode := m.Run()
+	os.Remove(logFilePath)
+	os.Exit(exitCode)
+}
+
+func TestInitialization(t *testing.T) {
+	cfg := s3d.Configuration{
+		Bindings: []s3d.Binding{
+			{
+				Port: 0,
+			},
+		},
+	}
+	err := cfg.Initialize(configDir)
+	assert.ErrorIs(t, err, common.ErrNoBinding)
+	cfg.Bindings[0].Port = s3ServerPort
+	cfg.CertificateFile = "missing path"
+	cfg.CertificateKeyFile = "missing path"
+	err = cfg.Initialize(configDir)
+	assert.Error(t, err)
+	cfg.CertificateFile = ""
+	cfg.CertificateKeyFile = ""
+	cfg.Bindings[0].ProxyAllowed = []string{"invalid ip"}
+	err = cfg.Initialize(configDir)
+	assert.Error(t, err)
+	// the port is already in use
+	cfg.Bindings[0].ProxyAllowed = nil
+	err = cfg.Initialize(configDir)
+	assert.Error(t, err)
+	assert.NoError(t, s3d.ReloadCertificateMgr())
+
+	status := s3d.GetStatus()
+	assert.True(t, status.IsActive)
+}
+
+func TestBasicHandling(t *testing.T) {
+	u := getTestUser()
+	u.QuotaSize = 6553600
+	user, _, err := httpdtest.AddUser(u, http.StatusCreated)
+	assert.NoError(t, err)
+	client, keyID := getS3Client(t, user)
+
+	buckets, err := client.ListBuckets(&s3.ListBucketsInput{})
+	if assert.NoError(t, err) {
+		if assert.Len(t, buckets.Buckets, 1) {
+			assert.Equal(t, user.Username, aws.StringValue(buckets.Buckets[0].Name))
+		}
+	}
+	_, err = client.HeadBucket(&s3.HeadBucketInput{Bucket: aws.String(user.Username)})
+	assert.NoError(t, err)
+	_, err = client.HeadBucket(&s3.HeadBucketInput{Bucket: aws.String("missing")})
+	assert.Error(t, err)
+	_, err = client.ListObjectsV2(&s3.ListObjectsV2Input{Bucket: aws.String("missing")})
+	assertS3ErrorCode(t, err, "NoSuchBucket")
+	location, err := client.GetBucketLocation(&s3.GetBucketLocationInput{Bucket: aws.String(user.Username)})
+	if assert.NoError(t, err) {
+		assert.Empty(t, aws.StringValue(location.LocationConstraint))
+	}
+
+	content := make([]byte, 65535)
+	_, err = rand.Read(content)
+	assert.NoError(t, err)
+	_, err = client.PutObject(&s3.PutObjectInput{
+		Bucket: aws.String(user.Username),
+		Key:    aws.String("dir/sub/file.dat"),
+		Body:   bytes.NewReader(content),
+	})
+	assert.NoError(t, err)
+	_, err = client.PutObject(&s3.PutObjectInput{
+		Bucket: aws.String(user.Username),
+		Key:    aws.String("file.txt"),
+		Body:   strings.NewReader("content"),
+	})
+	assert.NoError(t, err)
+	_, err = client.PutObject(&s3.PutObjectInput{
+		Bucket: aws.String(user.Username),
+		Key:    aws.String("empty/"),
+		Body:   bytes.NewReader(nil),
+	})
+	assert.NoError(t, err)
+	_, err = client.PutObject(&s3.PutObjectInput{
+		Bucket: aws.String(user.Username),
+		Key:    aws.String("file.txt/sub"),
+		Body:   strings.NewReader("content"),
+	})
+	assertS3ErrorCode(t, err, "InvalidRequest")
+	assert.FileExists(t, filepath.Join(user.GetHomeDir(), "dir", "sub", "file.dat"))
+	assert.DirExists(t, filepath.Join(user.GetHomeDir(), "empty"))
+
+	user, _, err = httpdtest.GetUserByUsername(user.Username, http.StatusOK)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, user.UsedQuotaFiles)
+	assert.Equal(t, int64(len(content)+7), user.UsedQuotaSize)
+
+	head, err := client.HeadObject(&s3.HeadObjectInput{
+		Bucket: aws.String(user.Username),
+		Key:    aws.String("dir/sub/file.dat"),
+	})
+	if assert.NoError(t, err) {
+		assert.Equal(t, int64(len(content)), aws.Int64Value(head.ContentLength))
+	}
+	_, err = client.HeadObject(&s3.HeadObjectInput{
+		Bucket: aws.String(user.Username),
+		Key:    aws.String("dir/missing"),
+	})
+	assert.Error(t, err)
+
+	obj, err := client.GetObject(&s3.GetObjectInput{
+		Bucket: aws.String(user.Username),
+		Key:    aws.String("dir/sub/file.dat"),
+	})
+	if assert.NoError(t, err) {
+		data, err := io.ReadAll(obj.Body)
+		assert.NoError(t, err)
+		assert.Equal(t, content, data)
+		obj.Body.Close()
+	}
+	obj, err = client.GetObject(&s3.GetObjectInput{
+		Bucket: aws.String(user.Username),
+		Key:    aws.String("dir/sub/file.dat"),
+		Range:  aws.String("bytes=100-199"),
+	})
+	if assert.NoError(t, err) {
+		data, err := io.ReadAll(obj.Body)
+		assert.NoError(t, err)
+		assert.Equal(t, content[100:200], data)
+		assert.Equal(t, fmt.Sprintf("bytes 100-199/%v", len(content)), aws.StringValue(obj.ContentRange))
+		obj.Body.Close()
+	}
+	_, err = client.GetObject(&s3.GetObjectInput{
+		Bucket: aws.String(user.Username),
+		Key:    aws.String("dir/sub/file.dat"),
+		Range:  aws.String("bytes=70000-"),
+	})
+	assertS3ErrorCode(t, err, "InvalidRange")
+	_, err = client.GetObject(&s3.GetObjectInput{
+		Bucket: aws.String(user.Username),
+		Key:    aws.String("dir/sub"),
+	})
+	assertS3ErrorCode(t, err, s3.ErrCodeNoSuchKey)
+
+	list, err := client.ListObjectsV2(&s3.ListObjectsV2Input{
+		Bucket: aws.String(user.Username),
+	})
+	if assert.NoError(t, err) {
+		assert.Equal(t, []string{"dir/sub/file.dat", "empty/", "file.txt"}, getListedKeys(list.Contents))
+		assert.Len(t, list.CommonPrefixes, 0)
+		assert.False(t, aws.BoolValue(list.IsTruncated))
+	}
+	list, err = client.ListObjectsV2(&s3.ListObjectsV2Input{
+		Bucket:    aws.String(user.Username),
+		Delimiter: aws.String("/"),
+	})
+	if assert.NoError(t, err) {
+		assert.Equal(t, []string{"file.txt"}, getListedKeys(list.Contents))
+		if assert.Len(t, list.CommonPrefixes, 2) {
+			assert.Equal(t, "dir/", aws.StringValue(list.CommonPrefixes[0].Prefix))
+			assert.Equal(t, "empty/", aws.StringValue(list.CommonPrefixes[1].Prefix))
+		}
+	}
+	list, err = client.ListObjectsV2(&s3.ListObjectsV2Input{
+		Bucket:    aws.String(user.Username),
+		Prefix:    aws.String("dir/s"),
+		Delimiter: aws.String("/"),
+	})
+	if assert.NoError(t, err) {
+		assert.Len(t, list.Contents, 0)
+		if assert.Len(t, list.CommonPrefixes, 1) {
+			assert.Equal(t, "dir/sub/", aws.StringValue(list.CommonPrefixes[0].Prefix))
+		}
+	}
+	list, err = client.ListObjectsV2(&s3.ListObjectsV2Input{
+		Bucket: aws.String(user.Username),
+		Prefix: aws.String("missing/dir/"),
+	})
+	if assert.NoError(t, err) {
+		assert.Len(t, list.Contents, 0)
+	}
+	// pagination
+	var keys []string
+	err = client.ListObjectsV2Pages(&s3.ListObjectsV2Input{
+		Bucket:  aws.String(user.Username),
+		MaxKeys: aws.Int64(1),
+	}, func(page *s3.ListObjectsV2Output, lastPage bool) bool {
+		assert.Len(t, page.Contents, 1)
+		keys = append(keys, getListedKeys(page.Contents)...)
+		return true
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"dir/sub/file.dat", "empty/", "file.txt"}, keys)
+	keys = nil
+	err = client.ListObjectsPages(&s3.ListObjectsInput{
+		Bucket:  aws.String(user.Username),
+		MaxKeys: aws.Int64(2),
+	}, func(page *s3.ListObjectsOutput, lastPage bool) bool {
+		keys = append(keys, getListedKeys(page.Contents)...)
+		return true
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"dir/sub/file.dat", "empty/", "file.txt"}, keys)
+
+	_, err = client.DeleteObject(&s3.DeleteObjectInput{
+		Bucket: aws.String(user.Username),
+		Key:    aws.String("file.txt"),
+	})
+	assert.NoError(t, err)
+	// deleting a missing object is not an error
+	_, err = client.DeleteObject(&s3.DeleteObjectInput{
+		Bucket: aws.String(user.Username),
+		Key:    aws.String("file.txt"),
+	})
+	assert.NoError(t, err)
+	deleteResult, err := client.DeleteObjects(&s3.DeleteObjectsInput{
+		Bucket: aws.String(user.Username),
+		Delete: &s3.Delete{
+			Objects: []*s3.ObjectIdentifier{
+				{Key: aws.String("dir/sub/file.dat")},
+				{Key: aws.String("empty/")},
+				{Key: aws.String("dir/")},
+			},
+		},
+	})
+	if assert.NoError(t, err) {
+		assert.Len(t, deleteResult.Deleted, 2)
+		if assert.Len(t, deleteResult.Errors, 1) {
+			assert.Equal(t, "dir/", aws.StringValue(deleteResult.Errors[0].Key))
+		}
+	}
+	assert.NoFileExists(t, filepath.Join(user.GetHomeDir(), "dir", "sub", "file.dat"))
+	assert.NoDirExists(t, filepath.Join(user.GetHomeDir(), "empty"))
+
+	user, _, err = httpdtest.GetUserByUsername(user.Username, http.StatusOK)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, user.UsedQuotaFiles)
+	assert.Equal(t, int64(0), user.UsedQuotaSize)
+
+	assert.Len(t, common.Connections.GetStats(), 0)
+
+	_, err = httpdtest.RemoveUserS3AccessKey(user.Username, keyID, http.StatusOK)
+	assert.NoError(t, err)
+	_, err = client.ListBuckets(&s3.ListBucketsInput{})
+	assertS3ErrorCode(t, err, "InvalidAccessKeyId")
+
+	_, err = httpdtest.RemoveUser(user, http.StatusOK)
+	assert.NoError(t, err)
+	err = os.RemoveAll(user.GetHomeDir())
+	assert.NoError(t, err)
+}
+
+func TestMultipartUpload(t *testing.T) {
+	u := getTestUser()
+	u.QuotaFiles = 100
+	user, _, err := httpdtest.AddUser(u, http.StatusCreated)
+	assert.NoError(t, err)
+	client, _ := getS3Client(t, user)
+
+	upload, err := client.CreateMultipartUpload(&s3.CreateMultipartUploadInput{
+		Bucket: aws.String(user.Username),
+		Key:    aws.String("dir/multipart.dat"),
+	})
+	require.NoError(t, err)
+	var parts []*s3.CompletedPart
+	var expected []byte
+	for idx := 1; idx <= 3; idx++ {
+		content := make([]byte, 32768+idx)
+		_, err = rand.Read(content)
+		assert.NoError(t, err)
+		expected = append(expected, content...)
+		part, err := client.UploadPart(&s3.UploadPartInput{
+			Bucket:     aws.String(user.Username),
+			Key:        aws.String("dir/multipart.dat"),
+			UploadId:   upload.UploadId,
+			PartNumber: aws.Int64(int64(idx)),
+			Body:       bytes.NewReader(content),
+		})
+		require.NoError(t, err)
+		parts = append(parts, &s3.CompletedPart{
+			ETag:       part.ETag,
+			PartNumber: aws.Int64(int64(idx)),
+		})
+	}
+	_, err = client.UploadPart(&s3.UploadPartInput{
+		Bucket:     aws.String(user.Username),
+		Key:        aws.String("dir/multipart.dat"),
+		UploadId:   upload.UploadId,
+		PartNumber: aws.Int64(10001),
+		Body:       strings.NewReader("data"),
+	})
+	assert.Error(t, err)
+	_, err = client.UploadPart(&s3.UploadPartInput{
+		Bucket:     aws.String(user.Username),
+		Key:        aws.String("dir/other.dat"),
+		UploadId:   upload.UploadId,
+		PartNumber: aws.Int64(1),
+		Body:       strings.NewReader("data"),
+	})
+	assertS3ErrorCode(t, err, s3.ErrCodeNoSuchUpload)
+	_, err = client.CompleteMultipartUpload(&s3.CompleteMultipartUploadInput{
+		Bucket:   aws.String(user.Username),
+		Key:      aws.String("dir/multipart.dat"),
+		UploadId: upload.UploadId,
+		MultipartUpload: &s3.CompletedMultipartUpload{
+			Parts: []*s3.CompletedPart{parts[1], parts[0]},
+		},
+	})
+	assertS3ErrorCode(t, err, "InvalidPartOrder")
+	_, err = client.CompleteMultipartUpload(&s3.CompleteMultipartUploadInput{
+		Bucket:   aws.String(user.Username),
+		Key:      aws.String("dir/multipart.dat"),
+		UploadId: upload.UploadId,
+		MultipartUpload: &s3.CompletedMultipartUpload{
+			Parts: []*s3.CompletedPart{
+				{
+					ETag:       parts[1].ETag,
+					PartNumber: aws.Int64(1),
+				},
+			},
+		},
+	})
+	assertS3ErrorCode(t, err, "InvalidPart")
+	result, err := client.CompleteMultipartUpload(&s3.CompleteMultipartUploadInput{
+		Bucket:   aws.String(user.Username),
+		Key:      aws.String("dir/multipart.dat"),
+		UploadId: upload.UploadId,
+		MultipartUpload: &s3.CompletedMultipartUpload{
+			Parts: parts,
+		},
+	})
+	if assert.NoError(t, err) {
+		assert.True(t, strings.HasSuffix(aws.StringValue(result.ETag), `-3"`))
+	}
+	data, err := os.ReadFile(filepath.Join(user.GetHomeDir(), "dir", "multipart.dat"))
+	assert.NoError(t, err)
+	assert.Equal(t, expected, data)
+	// the upload is removed after completion
+	_, err = client.CompleteMultipartUpload(&s3.CompleteMultipartUploadInput{
+		Bucket:   aws.String(user.Username),
+		Key:      aws.String("dir/multipart.dat"),
+		UploadId: upload.UploadId,
+		MultipartUpload: &s3.CompletedMultipartUpload{
+			Parts: parts,
+		},
+	})
+	assertS3ErrorCode(t, err, s3.ErrCodeNoSuchUpload)
+
+	upload, err = client.CreateMultipartUpload(&s3.CreateMultipartUploadInput{
+		Bucket: aws.String(user.Username),
+		Key:    aws.String("aborted.dat"),
+	})
+	require.NoError(t, err)
+	_, err = client.AbortMultipartUpload(&s3.AbortMultipartUploadInput{
+		Bucket:   aws.String(user.Username),
+		Key:      aws.String("aborted.dat"),
+		UploadId: upload.UploadId,
+	})
+	assert.NoError(t, err)
+	_, err = client.AbortMultipartUpload(&s3.AbortMultipartUploadInput{
+		Bucket:   aws.String(user.Username),
+		Key:      aws.String("aborted.dat"),
+		UploadId: upload.UploadId,
+	})
+	assertS3ErrorCode(t, err, s3.ErrCodeNoSuchUpload)
+	assert.NoFileExists(t, filepath.Join(user.GetHomeDir(), "aborted.dat"))
+
+	user, _, err = httpdtest.GetUserByUsername(user.Username, http.StatusOK)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, user.UsedQuotaFiles)
+	assert.Equal(t, int64(len(expected)), user.UsedQuotaSize)
+
+	_, err = httpdtest.RemoveUser(user, http.StatusOK)
+	assert.NoError(t, err)
+	err = os.RemoveAll(user.GetHomeDir())
+	assert.NoError(t, err)
+}
+
+func TestPermissionsAndQuota(t *testing.T) {
+	u := getTestUser()
+	u.Permissions["/"] = []string{dataprovider.PermListItems, dataprovider.PermDownload}
+	u.Permissions["/sub"] = allPerms
+	u.QuotaFiles = 1
+	user, _, err := httpdtest.AddUser(u, http.StatusCreated)
+	assert.NoError(t, err)
+	client, _ := getS3Client(t, user)
+	// the user cannot create directories inside the root one
+	_, err = client.PutObject(&s3.PutObjectInput{
+		Bucket: aws.String(user.Username),
+		Key:    aws.String("sub/file.txt"),
+		Body:   strings.NewReader("content"),
+	})
+	assertS3ErrorCode(t, err, "AccessDenied")
+	err = os.MkdirAll(filepath.Join(user.GetHomeDir(), "sub"), os.ModePerm)
+	assert.NoError(t, err)
+	_, err = client.PutObject(&s3.PutObjectInput{
+		Bucket: aws.String(user.Username),
+		Key:    aws.String("file.txt"),
+		Body:   strings.NewReader("content"),
+	})
+	assertS3ErrorCode(t, err, "AccessDenied")
+	_, err = client.CreateMultipartUpload(&s3.CreateMultipartUploadInput{
+		Bucket: aws.String(user.Username),
+		Key:    aws.String("file.txt"),
+	})
+	assertS3ErrorCode(t, err, "AccessDenied")
+	_, err = client.PutObject(&s3.PutObjectInput{
+		Bucket: aws.String(user.Username),
+		Key:    aws.String("sub/file.txt"),
+		Body:   strings.NewReader("content"),
+	})
+	assert.NoError(t, err)
+	_, err = client.PutObject(&s3.PutObjectInput{
+		Bucket: aws.String(user.Username),
+		Key:    aws.String("sub/file1.txt"),
+		Body:   strings.NewReader("content"),
+	})
+	assertS3ErrorCode(t, err, "QuotaExceeded")
+	_, err = client.PutObject(&s3.PutObjectInput{
+		Bucket:     aws.String(user.Username),
+		Key:        aws.String("sub/file.txt"),
+		Body:       strings.NewReader("content"),
+		ContentMD5: aws.String("Xr4ilOzQ4PCOq3aQ0qbuaQ=="),
+	})
+	assertS3ErrorCode(t, err, "BadDigest")
+
+	_, err = httpdtest.RemoveUser(user, http.StatusOK)
+	assert.NoError(t, err)
+	err = os.RemoveAll(user.GetHomeDir())
+	assert.NoError(t, err)
+}
+
+func TestLoginErrors(t *testing.T) {
+	u := getTestUser()
+	u.Filters.DeniedProtocols = []string{common.ProtocolS3}
+	user, _, err := httpdtest.AddUser(u, http.StatusCreated)
+	assert.NoError(t, err)
+	client, keyID := getS3Client(t, user)
+	_, err = client.ListBuckets(&s3.ListBucketsInput{})
+	assertS3ErrorCode(t, err, "AccessDenied")
+
+	user.Filters.DeniedProtocols = []string{common.ProtocolFTP}
+	user.Filters.DeniedLoginMethods = []string{dataprovider.LoginMethodS3AccessKey}
+	_, _, err = httpdtest.UpdateUser(user, http.StatusOK, "")
+	assert.NoError(t, err)
+	_, err = client.ListBuckets(&s3.ListBucketsInput{})
+	assertS3ErrorCode(t, err, "AccessDenied")
+
+	wrongClient := newS3Client(keyID, "wrong secret")
+	_, err = wrongClient.ListBuckets(&s3.ListBucketsInput{})
+	assertS3ErrorCode(t, err, "SignatureDoesNotMatch")
+
+	resp, err := http.Get(fmt.Sprintf("http://%v/", s3ServerAddr))
+	if assert.NoError(t, err) {
+		assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+		resp.Body.Close()
+	}
+
+	_, err = httpdtest.RemoveUser(user, http.StatusOK)
+	assert.NoError(t, err)
+	err = os.RemoveAll(user.GetHomeDir())
+	assert.NoError(t, err)
+	// the access keys are removed with the user
+	_, err = client.ListBuckets(&s3.ListBucketsInput{})
+	assertS3ErrorCode(t, err, "InvalidAccessKeyId")
+}
+
+func TestAccessKeysAPI(t *testing.T) {
+	user, _, err := httpdtest.AddUser(getTestUser(), http.StatusCreated)
+	assert.NoError(t, err)
+
+	_, _, _, err = httpdtest.AddUserS3AccessKey("missing", "", http.StatusNotFound)
+	assert.NoError(t, err)
+	_, _, err = httpdtest.GetUserS3AccessKeys("missing", http.StatusNotFound)
+	assert.NoError(t, err)
+
+	var keyIDs []string
+	for idx := 0; idx < 10; idx++ {
+		keyID, secret, _, err := httpdtest.AddUserS3AccessKey(user.Username, fmt.Sprintf("key %v", idx), http.StatusCreated)
+		assert.NoError(t, err)
+		assert.NotEmpty(t, secret)
+		keyIDs = append(keyIDs, keyID)
+	}
+	_, _, _, err = httpdtest.AddUserS3AccessKey(user.Username, "", http.StatusBadRequest)
+	assert.NoError(t, err)
+
+	keys, _, err := httpdtest.GetUserS3AccessKeys(user.Username, http.StatusOK)
+	assert.NoError(t, err)
+	if assert.Len(t, keys, 10) {
+		for idx, key := range keys {
+			assert.Equal(t, keyIDs[idx], key.AccessKeyID)
+			assert.Equal(t, fmt.Sprintf("key %v", idx), key.Description)
+			assert.Equal(t, user.Username, key.Username)
+			assert.Nil(t, key.SecretAccessKey)
+		}
+	}
+	_, err = httpdtest.RemoveUserS3AccessKey(user.Username, keyIDs[0], http.StatusOK)
+	assert.NoError(t, err)
+	_, err = httpdtest.RemoveUserS3AccessKey(user.Username, keyIDs[0], http.StatusNotFound)
+	assert.NoError(t, err)
+	_, err = httpdtest.RemoveUserS3AccessKey("missing", keyIDs[1], http.StatusNotFound)
+	assert.NoError(t, err)
+	keys, _, err = httpdtest.GetUserS3AccessKeys(user.Username, http.StatusOK)
+	assert.NoError(t, err)
+	assert.Len(t, keys, 9)
+
+	_, err = httpdtest.RemoveUser(user, http.StatusOK)
+	assert.NoError(t, err)
+	err = os.RemoveAll(user.GetHomeDir())
+	assert.NoError(t, err)
+}
+
+func getS3Client(t *testing.T, user dataprovider.User) (*s3.S3, string) {
+	keyID, secret, _, err := httpdtest.AddUserS3AccessKey(user.Username, "", http.StatusCreated)
+	require.NoError(t, err)
+	return newS3Client(keyID, secret), keyID
+}
+
+func newS3Client(keyID, secret string) *s3.S3 {
+	sess := session.Must(session.NewSession(&aws.Config{
+		Credentials:      credentials.NewStaticCredentials(keyID, secret, ""),
+		Endpoint:         aws.String("http://" + s3ServerAddr),
+		Region:           aws.String("us-east-1"),
+		S3ForcePathStyle: aws.Bool(true),
+		MaxRetries:       aws.Int(0),
+	}))
+	return s3.New(sess)
+}
+
+func assertS3ErrorCode(t *testing.T, err error, code string) {
+	var awsErr awserr.Error
+	if assert.True(t, errors.As(err, &awsErr), "unexpected error: %v", err) {
+		assert.Equal(t, code, awsErr.Code())
+	}
+}
+
+func getListedKeys(objects []*s3.Object) []string {
+	var keys []string
+	for _, obj := range objects {
+		keys = append(keys, aws.StringValue(obj.Key))
+	}
+	return keys
+}
+
+func waitTCPListening(address string) {
+	for {
+		conn, err := net.Dial("tcp", address)
+		if err != nil {
+			logger.WarnToConsole("tcp server %v not listening: %v", address, err)
+			time.Sleep(100 * time.Millisecond)
+			continue
+		}
+		logger.InfoToConsole("tcp server %v now listening", address)
+		conn.Close()
+		break
+	}
+}
+
+func getTestUser() dataprovider.User {
+	user := dataprovider.User{
+		BaseUser: sdk.BaseUser{
+			Username:       defaultUsername,
+			Password:       defaultPassword,
+			HomeDir:        filepath.Join(homeBasePath, defaultUsername),
+			Status:         1,
+			ExpirationDate: 0,
+		},
+	}
+	user.Permissions = make(map[string][]string)
+	user.Permissions["/"] = allPerms
+	return user
+}
@@ -0,0 +1,305 @@
+package s3d
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"path/filepath"
+	"runtime/debug"
+	"time"
+
+	"github.com/rs/xid"
+
+	"github.com/drakkan/sftpgo/v2/common"
+	"github.com/drakkan/sftpgo/v2/dataprovider"
+	"github.com/drakkan/sftpgo/v2/logger"
+	"github.com/drakkan/sftpgo/v2/metric"
+	"github.com/drakkan/sftpgo/v2/util"
+)
+
+type s3Server struct {
+	config  *Configuration
+	binding Binding
+}
+
+func (s *s3Server) listenAndServe() error {
+	httpServer := &http.Server{
+		Handler:           s,
+		ReadHeaderTimeout: 30 * time.Second,
+		ReadTimeout:       60 * time.Second,
+		WriteTimeout:      60 * time.Second,
+		IdleTimeout:       60 * time.Second,
+		MaxHeaderBytes:    1 << 16, // 64KB
+		ErrorLog:          log.New(&logger.StdLoggerWrapper{Sender: logSender}, "", 0),
+	}
+	if certMgr != nil && s.binding.EnableHTTPS {
+		serviceStatus.Bindings = append(serviceStatus.Bindings, s.binding)
+		httpServer.TLSConfig = &tls.Config{
+			GetCertificate:           certMgr.GetCertificateFunc(),
+			MinVersion:               tls.VersionTLS12,
+			CipherSuites:             util.GetTLSCiphersFromNames(s.binding.TLSCipherSuites),
+			PreferServerCipherSuites: true,
+		}
+		logger.Debug(logSender, "", "configured TLS cipher suites for binding %#v: %v", s.binding.GetAddress(),
+			httpServer.TLSConfig.CipherSuites)
+		return util.HTTPListenAndServe(httpServer, s.binding.Address, s.binding.Port, true, logSender)
+	}
+	s.binding.EnableHTTPS = false
+	serviceStatus.Bindings = append(serviceStatus.Bindings, s.binding)
+	return util.HTTPListenAndServe(httpServer, s.binding.Address, s.binding.Port, false, logSender)
+}
+
+// ServeHTTP implements the http.Handler interface
+func (s *s3Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	defer func() {
+		if r := recover(); r != nil {
+			logger.Error(logSender, "", "panic in ServeHTTP: %#v stack strace: %v", r, string(debug.Stack()))
+			http.Error(w, common.ErrGenericFailure.Error(), http.StatusInternalServerError)
+		}
+	}()
+
+	ipAddr := s.checkRemoteAddress(r)
+	connID := xid.New().String()
+	ctx := context.WithValue(r.Context(), requestIDKey, connID)
+	ctx = context.WithValue(ctx, requestStartKey, time.Now())
+	r = r.WithContext(ctx)
+
+	common.Connections.AddClientConnection(ipAddr)
+	defer common.Connections.RemoveClientConnection(ipAddr)
+
+	if !common.Connections.IsNewConnectionAllowed(ipAddr) {
+		logger.Log(logger.LevelDebug, common.ProtocolS3, "", "connection refused, configured limit reached")
+		sendAPIError(w, r, newAPIError("SlowDown", common.ErrConnectionDenied.Error(), http.StatusServiceUnavailable))
+		return
+	}
+	if common.IsBanned(ipAddr) {
+		sendAPIError(w, r, newAPIError("AccessDenied", common.ErrConnectionDenied.Error(), http.StatusForbidden))
+		return
+	}
+	delay, err := common.LimitRate(common.ProtocolS3, ipAddr)
+	if err != nil {
+		delay += 499999999 * time.Nanosecond
+		w.Header().Set("Retry-After", fmt.Sprintf("%.0f", delay.Seconds()))
+		w.Header().Set("X-Retry-In", delay.String())
+		sendAPIError(w, r, newAPIError("SlowDown", err.Error(), http.StatusServiceUnavailable))
+		return
+	}
+	if err := common.Config.ExecutePostConnectHook(ipAddr, common.ProtocolS3); err != nil {
+		sendAPIError(w, r, newAPIError("AccessDenied", common.ErrConnectionDenied.Error(), http.StatusForbidden))
+		return
+	}
+	user, signature, err := s.authenticate(r, ipAddr)
+	if err != nil {
+		sendAPIError(w, r, getAuthError(err))
+		return
+	}
+
+	if err = s.validateUser(&user, r, connID); err != nil {
+		updateLoginMetrics(&user, ipAddr, err)
+		sendAPIError(w, r, newAPIError("AccessDenied", err.Error(), http.StatusForbidden))
+		return
+	}
+
+	if err = user.CheckFsRoot(connID); err != nil {
+		errClose := user.CloseFs()
+		logger.Warn(logSender, connID, "unable to check fs root: %v close fs error: %v", err, errClose)
+		updateLoginMetrics(&user, ipAddr, common.ErrInternalFailure)
+		sendAPIError(w, r, errInternalError)
+		return
+	}
+
+	updateLoginMetrics(&user, ipAddr, nil)
+
+	signature.wrapBody(r)
+	connection := &Connection{
+		BaseConnection: common.NewBaseConnection(connID, common.ProtocolS3, util.GetHTTPLocalAddress(r),
+			r.RemoteAddr, user),
+		request: r,
+	}
+	common.Connections.Add(connection)
+	defer common.Connections.Remove(connection.GetID())
+
+	dataprovider.UpdateLastLogin(&user)
+
+	handler := requestHandler{
+		config:     s.config,
+		connection: connection,
+	}
+	handler.handle(w, r)
+}
+
+// authenticate verifies the request signature and returns the user owning the access key
+func (s *s3Server) authenticate(r *http.Request, ip string) (dataprovider.User, *signatureV4, error) {
+	var user dataprovider.User
+
+	signature, err := newSignatureV4(r, time.Now())
+	if err != nil {
+		if !errors.Is(err, errAccessDenied) {
+			logger.Debug(logSender, "", "unable to parse the authentication data from %v: %v", ip, err)
+		}
+		return user, nil, err
+	}
+	user, err = dataprovider.CheckUserAndS3AccessKey(signature.auth.accessKeyID, func(secret string) error {
+		return signature.verify(r, secret)
+	})
+	if err != nil {
+		updateLoginMetrics(&user, ip, err)
+		return user, nil, err
+	}
+	return user, signature, nil
+}
+
+func (s *s3Server) validateUser(user *dataprovider.User, r *http.Request, connID string) error {
+	connectionID := fmt.Sprintf("%v_%v", common.ProtocolS3, connID)
+
+	if !filepath.IsAbs(user.HomeDir) {
+		logger.Warn(logSender, connectionID, "user %#v has an invalid home dir: %#v. Home dir must be an absolute path, login not allowed",
+			user.Username, user.HomeDir)
+		return fmt.Errorf("cannot login user with invalid home dir: %#v", user.HomeDir)
+	}
+	if util.IsStringInSlice(common.ProtocolS3, user.Filters.DeniedProtocols) {
+		logger.Debug(logSender, connectionID, "cannot login user %#v, protocol S3 is not allowed", user.Username)
+		return fmt.Errorf("protocol S3 is not allowed for user %#v", user.Username)
+	}
+	if !user.IsLoginMethodAllowed(dataprovider.LoginMethodS3AccessKey, nil) {
+		logger.Debug(logSender, connectionID, "cannot login user %#v, %v login method is not allowed", user.Username,
+			dataprovider.LoginMethodS3AccessKey)
+		return fmt.Errorf("login method %v is not allowed for user %#v", dataprovider.LoginMethodS3AccessKey,
+			user.Username)
+	}
+	if user.MaxSessions > 0 {
+		activeSessions := common.Connections.GetActiveSessions(user.Username)
+		if activeSessions >= user.MaxSessions {
+			logger.Debug(logSender, connectionID, "authentication refused for user: %#v, too many open sessions: %v/%v",
+				user.Username, activeSessions, user.MaxSessions)
+			return fmt.Errorf("too many open sessions: %v", activeSessions)
+		}
+	}
+	if !user.IsLoginFromAddrAllowed(r.RemoteAddr) {
+		logger.Debug(logSender, connectionID, "cannot login user %#v, remote address is not allowed: %v", user.Username, r.RemoteAddr)
+		return fmt.Errorf("login for user %#v is not allowed from this address: %v", user.Username, r.RemoteAddr)
+	}
+	return nil
+}
+
+func (s *s3Server) checkRemoteAddress(r *http.Request) string {
+	ipAddr := util.GetIPFromRemoteAddress(r.RemoteAddr)
+	ip := net.ParseIP(ipAddr)
+	if ip != nil {
+		for _, allow := range s.binding.allowHeadersFrom {
+			if allow(ip) {
+				parsedIP := util.GetRealIP(r)
+				if parsedIP != "" {
+					ipAddr = parsedIP
+					r.RemoteAddr = ipAddr
+				}
+				break
+			}
+		}
+	}
+	return ipAddr
+}
+
+// getAuthError maps an authentication error to an S3 error
+func getAuthError(err error) *apiError {
+	var apiErr *apiError
+	if errors.As(err, &apiErr) {
+		return apiErr
+	}
+	if _, ok := err.(*util.RecordNotFoundError); ok {
+		return errInvalidAccessKeyID
+	}
+	if errors.Is(err, dataprovider.ErrInvalidCredentials) {
+		return errSignatureDoesNotMatch
+	}
+	return newAPIError("AccessDenied", err.Error(), http.StatusForbidden)
+}
+
+func getRequestID(r *http.Request) string {
+	if reqID, ok := r.Context().Value(requestIDKey).(string); ok {
+		return reqID
+	}
+	return ""
+}
+
+func sendAPIError(w http.ResponseWriter, r *http.Request, err *apiError) {
+	resp := errorResponse{
+		Code:      err.code,
+		Message:   err.message,
+		Resource:  r.URL.Path,
+		RequestID: getRequestID(r),
+	}
+	if r.Method == http.MethodHead {
+		w.Header().Set("x-amz-request-id", resp.RequestID)
+		w.WriteHeader(err.statusCode)
+		writeLog(r, err.statusCode, err)
+		return
+	}
+	sendXMLResponse(w, r, err.statusCode, resp)
+	writeLog(r, err.statusCode, err)
+}
+
+func sendXMLResponse(w http.ResponseWriter, r *http.Request, statusCode int, resp interface{}) {
+	data, err := xml.Marshal(resp)
+	if err != nil {
+		logger.Warn(logSender, getRequestID(r), "unable to marshal XML response: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/xml")
+	w.Header().Set("x-amz-request-id", getRequestID(r))
+	w.WriteHeader(statusCode)
+	w.Write([]byte(xml.Header)) //nolint:errcheck
+	w.Write(data)               //nolint:errcheck
+}
+
+func writeLog(r *http.Request, status int, err error) {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	fields := map[string]interface{}{
+		"remote_addr": r.RemoteAddr,
+		"proto":       r.Proto,
+		"method":      r.Method,
+		"user_agent":  r.UserAgent(),
+		"uri":         fmt.Sprintf("%s://%s%s", scheme, r.Host, r.RequestURI)}
+	if reqID, ok := r.Context().Value(requestIDKey).(string); ok {
+		fields["request_id"] = reqID
+	}
+	if reqStart, ok := r.Context().Value(requestStartKey).(time.Time); ok {
+		fields["elapsed_ms"] = time.Since(reqStart).Nanoseconds() / 1000000
+	}
+	if contentLength := r.Header.Get("Content-Length"); contentLength != "" {
+		fields["content_length"] = contentLength
+	}
+	if status != 0 {
+		fields["resp_status"] = status
+	}
+	logger.GetLogger().Info().
+		Timestamp().
+		Str("sender", logSender).
+		Fields(fields).
+		Err(err).
+		Send()
+}
+
+func updateLoginMetrics(user *dataprovider.User, ip string, err error) {
+	loginMethod := dataprovider.LoginMethodS3AccessKey
+	metric.AddLoginAttempt(loginMethod)
+	if err != nil && err != common.ErrInternalFailure {
+		logger.ConnectionFailedLog(user.Username, ip, loginMethod, common.ProtocolS3, err.Error())
+		event := common.HostEventLoginFailed
+		if _, ok := err.(*util.RecordNotFoundError); ok {
+			event = common.HostEventUserNotFound
+		}
+		common.AddDefenderEvent(ip, event)
+	}
+	metric.AddLoginResult(loginMethod, err)
+	dataprovider.ExecutePostLoginHook(user, loginMethod, ip, common.ProtocolS3, err)
+}
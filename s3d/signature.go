@@ -0,0 +1,502 @@
+package s3d
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"hash"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/drakkan/sftpgo/v2/dataprovider"
+)
+
+const (
+	signatureAlgorithm              = "AWS4-HMAC-SHA256"
+	chunkSignatureAlgorithm         = "AWS4-HMAC-SHA256-PAYLOAD"
+	signatureTerminator             = "aws4_request"
+	signatureService                = "s3"
+	defaultRegion                   = "us-east-1"
+	amzDateFormat                   = "20060102T150405Z"
+	scopeDateFormat                 = "20060102"
+	unsignedPayload                 = "UNSIGNED-PAYLOAD"
+	streamingPayload                = "STREAMING-AWS4-HMAC-SHA256-PAYLOAD"
+	streamingUnsignedPayloadTrailer = "STREAMING-UNSIGNED-PAYLOAD-TRAILER"
+	emptyPayloadHash                = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+	maxClockSkew                    = 15 * time.Minute
+	maxChunkHeaderSize              = 4096
+)
+
+// authHeader defines the parsed AWS Signature Version 4 Authorization header
+type authHeader struct {
+	accessKeyID   string
+	date          string
+	region        string
+	service       string
+	signedHeaders []string
+	signature     string
+}
+
+func (a *authHeader) getScope() string {
+	return strings.Join([]string{a.date, a.region, a.service, signatureTerminator}, "/")
+}
+
+// parseAuthHeader parses an Authorization header like this one:
+//
+// AWS4-HMAC-SHA256 Credential=AKID/20130524/us-east-1/s3/aws4_request, SignedHeaders=host;x-amz-date, Signature=sig
+func parseAuthHeader(value string) (authHeader, error) {
+	var auth authHeader
+
+	if !strings.HasPrefix(value, signatureAlgorithm+" ") {
+		return auth, errUnsupportedSignature
+	}
+	for _, field := range strings.Split(strings.TrimPrefix(value, signatureAlgorithm+" "), ",") {
+		field = strings.TrimSpace(field)
+		idx := strings.Index(field, "=")
+		if idx < 0 {
+			return auth, errAuthorizationHeaderMalformed
+		}
+		fieldValue := field[idx+1:]
+		switch field[:idx] {
+		case "Credential":
+			credentials := strings.Split(fieldValue, "/")
+			if len(credentials) != 5 || credentials[0] == "" {
+				return auth, errAuthorizationHeaderMalformed
+			}
+			if credentials[3] != signatureService || credentials[4] != signatureTerminator {
+				return auth, errAuthorizationHeaderMalformed
+			}
+			if _, err := time.Parse(scopeDateFormat, credentials[1]); err != nil {
+				return auth, errAuthorizationHeaderMalformed
+			}
+			auth.accessKeyID = credentials[0]
+			auth.date = credentials[1]
+			auth.region = credentials[2]
+			auth.service = credentials[3]
+		case "SignedHeaders":
+			auth.signedHeaders = strings.Split(fieldValue, ";")
+		case "Signature":
+			auth.signature = fieldValue
+		}
+	}
+	if auth.accessKeyID == "" || len(auth.signedHeaders) == 0 || auth.signature == "" {
+		return auth, errAuthorizationHeaderMalformed
+	}
+	hasHost := false
+	for _, h := range auth.signedHeaders {
+		if h == "host" {
+			hasHost = true
+			break
+		}
+	}
+	if !hasHost {
+		return auth, errAuthorizationHeaderMalformed
+	}
+	return auth, nil
+}
+
+// signatureV4 defines the data required to verify a request signed using AWS Signature Version 4
+type signatureV4 struct {
+	auth        authHeader
+	amzDate     time.Time
+	payloadHash string
+	signingKey  []byte
+}
+
+// newSignatureV4 parses the authentication data for the given request
+func newSignatureV4(r *http.Request, now time.Time) (*signatureV4, error) {
+	authorization := r.Header.Get("Authorization")
+	if authorization == "" {
+		if r.URL.Query().Get("X-Amz-Algorithm") != "" {
+			return nil, errUnsupportedSignature
+		}
+		return nil, errAccessDenied
+	}
+	auth, err := parseAuthHeader(authorization)
+	if err != nil {
+		return nil, err
+	}
+	var amzDate time.Time
+	if value := r.Header.Get("X-Amz-Date"); value != "" {
+		amzDate, err = time.Parse(amzDateFormat, value)
+	} else if value := r.Header.Get("Date"); value != "" {
+		amzDate, err = http.ParseTime(value)
+	} else {
+		return nil, errMissingDateHeader
+	}
+	if err != nil {
+		return nil, errMissingDateHeader
+	}
+	amzDate = amzDate.UTC()
+	if amzDate.Format(scopeDateFormat) != auth.date {
+		return nil, errAuthorizationHeaderMalformed
+	}
+	if skew := now.Sub(amzDate); skew > maxClockSkew || skew < -maxClockSkew {
+		return nil, errRequestTimeTooSkewed
+	}
+	payloadHash := r.Header.Get("X-Amz-Content-Sha256")
+	switch payloadHash {
+	case "":
+		return nil, errMissingContentSHA256
+	case unsignedPayload, streamingPayload, streamingUnsignedPayloadTrailer:
+	default:
+		if len(payloadHash) != 2*sha256.Size {
+			return nil, errContentSHA256Mismatch
+		}
+		if _, err := hex.DecodeString(payloadHash); err != nil {
+			return nil, errContentSHA256Mismatch
+		}
+	}
+	return &signatureV4{
+		auth:        auth,
+		amzDate:     amzDate,
+		payloadHash: payloadHash,
+	}, nil
+}
+
+// verify checks the request signature using the given secret access key
+func (s *signatureV4) verify(r *http.Request, secret string) error {
+	signingKey := getSigningKey(secret, s.auth.date, s.auth.region, s.auth.service)
+	stringToSign := strings.Join([]string{
+		signatureAlgorithm,
+		s.amzDate.Format(amzDateFormat),
+		s.auth.getScope(),
+		getSHA256Hex([]byte(s.getCanonicalRequest(r))),
+	}, "\n")
+	signature := hex.EncodeToString(getHMAC(signingKey, []byte(stringToSign)))
+	if !hmac.Equal([]byte(signature), []byte(s.auth.signature)) {
+		return dataprovider.ErrInvalidCredentials
+	}
+	s.signingKey = signingKey
+	return nil
+}
+
+func (s *signatureV4) getCanonicalRequest(r *http.Request) string {
+	return strings.Join([]string{
+		r.Method,
+		getCanonicalURI(r.URL.Path),
+		getCanonicalQueryString(r.URL.RawQuery),
+		s.getCanonicalHeaders(r),
+		strings.Join(s.auth.signedHeaders, ";"),
+		s.payloadHash,
+	}, "\n")
+}
+
+func (s *signatureV4) getCanonicalHeaders(r *http.Request) string {
+	var sb strings.Builder
+
+	for _, name := range s.auth.signedHeaders {
+		var values []string
+		switch name {
+		case "host":
+			values = []string{r.Host}
+		case "content-length":
+			values = r.Header.Values(name)
+			if len(values) == 0 {
+				values = []string{strconv.FormatInt(r.ContentLength, 10)}
+			}
+		case "transfer-encoding":
+			values = r.Header.Values(name)
+			if len(values) == 0 {
+				values = r.TransferEncoding
+			}
+		default:
+			values = r.Header.Values(name)
+		}
+		normalized := make([]string, 0, len(values))
+		for _, value := range values {
+			normalized = append(normalized, strings.Join(strings.Fields(value), " "))
+		}
+		sb.WriteString(name)
+		sb.WriteString(":")
+		sb.WriteString(strings.Join(normalized, ","))
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
+
+// wrapBody replaces the request body with a reader that verifies the payload while reading it
+func (s *signatureV4) wrapBody(r *http.Request) {
+	switch s.payloadHash {
+	case unsignedPayload:
+	case streamingPayload, streamingUnsignedPayloadTrailer:
+		decodedLength := int64(-1)
+		if value := r.Header.Get("X-Amz-Decoded-Content-Length"); value != "" {
+			if length, err := strconv.ParseInt(value, 10, 64); err == nil {
+				decodedLength = length
+			}
+		}
+		reader := &chunkedReader{
+			reader:        bufio.NewReaderSize(r.Body, maxChunkHeaderSize),
+			closer:        r.Body,
+			decodedLength: decodedLength,
+			hasher:        sha256.New(),
+		}
+		if s.payloadHash == streamingPayload {
+			reader.signature = s
+			reader.previousSignature = s.auth.signature
+		}
+		r.Body = reader
+		r.ContentLength = decodedLength
+	default:
+		r.Body = &hashVerifyingReader{
+			reader:   r.Body,
+			closer:   r.Body,
+			hasher:   sha256.New(),
+			expected: s.payloadHash,
+		}
+	}
+}
+
+func (s *signatureV4) getChunkSignature(previousSignature string, chunkHash []byte) string {
+	stringToSign := strings.Join([]string{
+		chunkSignatureAlgorithm,
+		s.amzDate.Format(amzDateFormat),
+		s.auth.getScope(),
+		previousSignature,
+		emptyPayloadHash,
+		hex.EncodeToString(chunkHash),
+	}, "\n")
+	return hex.EncodeToString(getHMAC(s.signingKey, []byte(stringToSign)))
+}
+
+// hashVerifyingReader returns an error at EOF if the SHA256 of the read data does not match the expected one
+type hashVerifyingReader struct {
+	reader   io.Reader
+	closer   io.Closer
+	hasher   hash.Hash
+	expected string
+}
+
+func (r *hashVerifyingReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	r.hasher.Write(p[:n])
+	if err == io.EOF && hex.EncodeToString(r.hasher.Sum(nil)) != r.expected {
+		return n, errContentSHA256Mismatch
+	}
+	return n, err
+}
+
+func (r *hashVerifyingReader) Close() error {
+	return r.closer.Close()
+}
+
+// chunkedReader decodes an aws-chunked encoded body verifying the chunk signatures, if any
+type chunkedReader struct {
+	reader            *bufio.Reader
+	closer            io.Closer
+	signature         *signatureV4
+	previousSignature string
+	chunkSignature    string
+	hasher            hash.Hash
+	remaining         int64
+	inChunk           bool
+	decodedLength     int64
+	read              int64
+	err               error
+}
+
+func (r *chunkedReader) Read(p []byte) (int, error) {
+	if r.err != nil {
+		return 0, r.err
+	}
+	if r.remaining == 0 {
+		if r.inChunk {
+			if err := r.finishChunk(); err != nil {
+				r.err = err
+				return 0, err
+			}
+		}
+		if err := r.readChunkHeader(); err != nil {
+			r.err = err
+			return 0, err
+		}
+		if r.remaining == 0 {
+			r.err = r.finishStream()
+			return 0, r.err
+		}
+	}
+	if int64(len(p)) > r.remaining {
+		p = p[:r.remaining]
+	}
+	n, err := r.reader.Read(p)
+	r.hasher.Write(p[:n])
+	r.remaining -= int64(n)
+	r.read += int64(n)
+	if err == io.EOF {
+		err = errIncompleteBody
+	}
+	if err != nil {
+		r.err = err
+	}
+	return n, err
+}
+
+func (r *chunkedReader) Close() error {
+	return r.closer.Close()
+}
+
+func (r *chunkedReader) readLine() (string, error) {
+	line, err := r.reader.ReadSlice('\n')
+	if err != nil {
+		if err == io.EOF {
+			return "", errIncompleteBody
+		}
+		return "", errInvalidChunk
+	}
+	return string(bytes.TrimRight(line, "\r\n")), nil
+}
+
+func (r *chunkedReader) readChunkHeader() error {
+	line, err := r.readLine()
+	if err != nil {
+		return err
+	}
+	size := line
+	extensions := ""
+	if idx := strings.Index(line, ";"); idx >= 0 {
+		size = line[:idx]
+		extensions = line[idx+1:]
+	}
+	chunkSize, err := strconv.ParseInt(strings.TrimSpace(size), 16, 64)
+	if err != nil || chunkSize < 0 {
+		return errInvalidChunk
+	}
+	r.chunkSignature = ""
+	for _, extension := range strings.Split(extensions, ";") {
+		if strings.HasPrefix(extension, "chunk-signature=") {
+			r.chunkSignature = strings.TrimPrefix(extension, "chunk-signature=")
+		}
+	}
+	if r.signature != nil && r.chunkSignature == "" {
+		return errInvalidChunk
+	}
+	r.remaining = chunkSize
+	r.inChunk = true
+	r.hasher.Reset()
+	return nil
+}
+
+func (r *chunkedReader) finishChunk() error {
+	if r.signature != nil {
+		signature := r.signature.getChunkSignature(r.previousSignature, r.hasher.Sum(nil))
+		if !hmac.Equal([]byte(signature), []byte(r.chunkSignature)) {
+			return errSignatureDoesNotMatch
+		}
+		r.previousSignature = signature
+	}
+	r.inChunk = false
+	line, err := r.readLine()
+	if err != nil {
+		return err
+	}
+	if line != "" {
+		return errInvalidChunk
+	}
+	return nil
+}
+
+// finishStream verifies the final, zero sized, chunk and discards the trailing headers, if any
+func (r *chunkedReader) finishStream() error {
+	if r.signature != nil {
+		signature := r.signature.getChunkSignature(r.previousSignature, r.hasher.Sum(nil))
+		if !hmac.Equal([]byte(signature), []byte(r.chunkSignature)) {
+			return errSignatureDoesNotMatch
+		}
+	}
+	r.inChunk = false
+	for {
+		line, err := r.readLine()
+		if err != nil {
+			if err == errIncompleteBody && r.signature != nil {
+				// the final CRLF is optional for some clients
+				break
+			}
+			return err
+		}
+		if line == "" {
+			break
+		}
+	}
+	if r.decodedLength >= 0 && r.read != r.decodedLength {
+		return errIncompleteBody
+	}
+	return io.EOF
+}
+
+func getSigningKey(secret, date, region, service string) []byte {
+	key := getHMAC([]byte("AWS4"+secret), []byte(date))
+	key = getHMAC(key, []byte(region))
+	key = getHMAC(key, []byte(service))
+	return getHMAC(key, []byte(signatureTerminator))
+}
+
+func getHMAC(key, data []byte) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write(data)
+	return h.Sum(nil)
+}
+
+func getSHA256Hex(data []byte) string {
+	h := sha256.Sum256(data)
+	return hex.EncodeToString(h[:])
+}
+
+func getCanonicalURI(p string) string {
+	if p == "" {
+		return "/"
+	}
+	return awsURIEncode(p, false)
+}
+
+func getCanonicalQueryString(rawQuery string) string {
+	// invalid parameters are skipped, the signature will not match if something is wrong
+	values, _ := url.ParseQuery(rawQuery) //nolint:errcheck
+	type queryParam struct {
+		key   string
+		value string
+	}
+	params := make([]queryParam, 0, len(values))
+	for key, vals := range values {
+		encodedKey := awsURIEncode(key, true)
+		for _, val := range vals {
+			params = append(params, queryParam{key: encodedKey, value: awsURIEncode(val, true)})
+		}
+	}
+	sort.Slice(params, func(i, j int) bool {
+		if params[i].key == params[j].key {
+			return params[i].value < params[j].value
+		}
+		return params[i].key < params[j].key
+	})
+	result := make([]string, 0, len(params))
+	for _, param := range params {
+		result = append(result, param.key+"="+param.value)
+	}
+	return strings.Join(result, "&")
+}
+
+// awsURIEncode encodes the given string as required by AWS Signature Version 4,
+// all the characters except the unreserved ones are percent-encoded
+func awsURIEncode(s string, encodeSlash bool) string {
+	var sb strings.Builder
+
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if (c >= 'A' && c <= 'Z') || (c >= 'a' && c <= 'z') || (c >= '0' && c <= '9') ||
+			c == '-' || c == '_' || c == '.' || c == '~' || (c == '/' && !encodeSlash) {
+			sb.WriteByte(c)
+			continue
+		}
+		sb.WriteString("%")
+		sb.WriteString(strings.ToUpper(hex.EncodeToString([]byte{c})))
+	}
+	return sb.String()
+}
@@ -13,6 +13,8 @@ const (
 	AzureBlobFilesystemProvider                           // Azure Blob Storage
 	CryptedFilesystemProvider                             // Local encrypted
 	SFTPFilesystemProvider                                // SFTP
+	FTPFilesystemProvider                                 // FTP/FTPS
+	WebDAVFilesystemProvider                              // WebDAV
 )
 
 // GetProviderByName returns the FilesystemProvider matching a given name
@@ -31,6 +33,10 @@ func GetProviderByName(name string) FilesystemProvider {
 		return CryptedFilesystemProvider
 	case "5", "sftpfs":
 		return SFTPFilesystemProvider
+	case "6", "ftpfs":
+		return FTPFilesystemProvider
+	case "7", "webdavfs":
+		return WebDAVFilesystemProvider
 	}
 
 	// TODO think about returning an error value instead of silently defaulting to LocalFilesystemProvider
@@ -52,6 +58,10 @@ func (p FilesystemProvider) Name() string {
 		return "cryptfs"
 	case SFTPFilesystemProvider:
 		return "sftpfs"
+	case FTPFilesystemProvider:
+		return "ftpfs"
+	case WebDAVFilesystemProvider:
+		return "webdavfs"
 	}
 	return "" // let's not claim to be
 }
@@ -71,6 +81,10 @@ func (p FilesystemProvider) ShortInfo() string {
 		return "Local encrypted"
 	case SFTPFilesystemProvider:
 		return "SFTP"
+	case FTPFilesystemProvider:
+		return "FTP/FTPS"
+	case WebDAVFilesystemProvider:
+		return "WebDAV"
 	}
 	return ""
 }
@@ -81,6 +95,7 @@ func ListProviders() []FilesystemProvider {
 		LocalFilesystemProvider, S3FilesystemProvider,
 		GCSFilesystemProvider, AzureBlobFilesystemProvider,
 		CryptedFilesystemProvider, SFTPFilesystemProvider,
+		FTPFilesystemProvider, WebDAVFilesystemProvider,
 	}
 }
 
@@ -205,6 +220,67 @@ type SFTPFsConfig struct {
 	BufferSize int64 `json:"buffer_size,omitempty"`
 }
 
+// FTPFsConfig defines the configuration for FTP based filesystem
+type FTPFsConfig struct {
+	Endpoint string      `json:"endpoint,omitempty"`
+	Username string      `json:"username,omitempty"`
+	Password *kms.Secret `json:"password,omitempty"`
+	// Prefix is the path prefix to strip from FTP resource paths.
+	Prefix string `json:"prefix,omitempty"`
+	// TLSMode defines the TLS mode to use to connect to the remote server:
+	// 0 plain FTP, 1 explicit TLS (AUTH TLS), 2 implicit TLS
+	TLSMode int `json:"tls_mode,omitempty"`
+	// Set to true to skip the verification of the certificate presented by
+	// the remote server, this is a security risk
+	SkipTLSVerify bool `json:"skip_tls_verify,omitempty"`
+	// Data connections always use the passive mode. EPSV is used if supported
+	// by the remote server, set to true to always use PASV.
+	// Some servers behind a NAT advertise EPSV but don't support it properly
+	DisableEPSV bool `json:"disable_epsv,omitempty"`
+}
+
+// WebDAVFsConfig defines the configuration for WebDAV based filesystem
+type WebDAVFsConfig struct {
+	// Endpoint is the URL of the remote WebDAV share, for example
+	// https://cloud.example.com/remote.php/dav/files/user/
+	Endpoint string `json:"endpoint,omitempty"`
+	// Username and Password are used for basic authentication
+	Username string      `json:"username,omitempty"`
+	Password *kms.Secret `json:"password,omitempty"`
+	// BearerToken is used for bearer authentication, it cannot be
+	// used together with a password
+	BearerToken *kms.Secret `json:"bearer_token,omitempty"`
+	// Prefix is the path prefix to strip from WebDAV resource paths.
+	Prefix string `json:"prefix,omitempty"`
+	// Set to true to skip the verification of the certificate presented by
+	// the remote server, this is a security risk
+	SkipTLSVerify bool `json:"skip_tls_verify,omitempty"`
+}
+
+// ReadCacheConfig defines the configuration for the local disk cache used to
+// serve downloads for S3, Google Cloud Storage and Azure Blob filesystems
+type ReadCacheConfig struct {
+	// Maximum size, as MB, for the cached contents. 0 means disabled.
+	// Files bigger than this size are never cached
+	MaxSize int64 `json:"max_size,omitempty"`
+	// Cached contents older than the specified number of seconds are downloaded
+	// again, 0 means no expiration. Cached contents are always validated against
+	// the size, modification time and ETag reported by the remote storage
+	TTL int `json:"ttl,omitempty"`
+	// Eviction policy to use when the cache is full: "lru" (least recently used)
+	// or "lfu" (least frequently used). Default "lru"
+	Eviction string `json:"eviction,omitempty"`
+}
+
+// EncryptionConfig defines the configuration to store files encrypted at rest
+// on S3, Google Cloud Storage, Azure Blob and SFTP filesystems.
+// Files are encrypted client side, before uploading them, using the same
+// format as the local encrypted filesystem
+type EncryptionConfig struct {
+	// Passphrase used to derive the encryption keys. Empty means disabled
+	Passphrase *kms.Secret `json:"passphrase,omitempty"`
+}
+
 // Filesystem defines filesystem details
 type Filesystem struct {
 	Provider     FilesystemProvider `json:"provider"`
@@ -213,4 +289,8 @@ type Filesystem struct {
 	AzBlobConfig AzBlobFsConfig     `json:"azblobconfig,omitempty"`
 	CryptConfig  CryptFsConfig      `json:"cryptconfig,omitempty"`
 	SFTPConfig   SFTPFsConfig       `json:"sftpconfig,omitempty"`
+	FTPConfig    FTPFsConfig        `json:"ftpconfig,omitempty"`
+	WebDAVConfig WebDAVFsConfig     `json:"webdavconfig,omitempty"`
+	ReadCache    ReadCacheConfig    `json:"read_cache,omitempty"`
+	Encryption   EncryptionConfig   `json:"encryption,omitempty"`
 }
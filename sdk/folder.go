@@ -13,8 +13,26 @@ type BaseVirtualFolder struct {
 	UsedQuotaFiles int `json:"used_quota_files"`
 	// Last quota update as unix timestamp in milliseconds
 	LastQuotaUpdate int64 `json:"last_quota_update"`
+	// Maximum data transfer allowed for uploads as MB. 0 means no limit.
+	// Transfers inside the folder are also counted for the user data transfer limits
+	UploadDataTransfer int64 `json:"upload_data_transfer"`
+	// Maximum data transfer allowed for downloads as MB. 0 means no limit
+	DownloadDataTransfer int64 `json:"download_data_transfer"`
+	// Maximum total data transfer as MB. If set, the upload and download limits are ignored.
+	// 0 means no limit
+	TotalDataTransfer int64 `json:"total_data_transfer"`
+	// Uploaded data as bytes since the last data transfer reset
+	UsedUploadDataTransfer int64 `json:"used_upload_data_transfer"`
+	// Downloaded data as bytes since the last data transfer reset
+	UsedDownloadDataTransfer int64 `json:"used_download_data_transfer"`
+	// Last data transfer reset as unix timestamp in milliseconds
+	LastDataTransferReset int64 `json:"last_data_transfer_reset"`
+	// Data transfer reset period, empty means never
+	DataTransferResetPeriod DataTransferResetPeriod `json:"data_transfer_reset_period,omitempty"`
 	// list of usernames associated with this virtual folder
 	Users []string `json:"users,omitempty"`
+	// list of group names associated with this virtual folder
+	Groups []string `json:"groups,omitempty"`
 	// Filesystem configuration details
 	FsConfig Filesystem `json:"filesystem"`
 }
@@ -209,6 +209,11 @@ func (m *Manager) NotifyProviderEvent(timestamp int64, action, username, objectT
 	}
 }
 
+// HasSearcher returns true if an events searcher plugin is defined
+func (m *Manager) HasSearcher() bool {
+	return m.hasSearcher
+}
+
 // SearchFsEvents returns the filesystem events matching the specified filter and a continuation token
 // to use for cursor based pagination
 func (m *Manager) SearchFsEvents(startTimestamp, endTimestamp int64, username, ip, sshCmd string, actions,
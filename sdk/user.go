@@ -2,6 +2,7 @@ package sdk
 
 import (
 	"strings"
+	"time"
 
 	"github.com/drakkan/sftpgo/v2/kms"
 	"github.com/drakkan/sftpgo/v2/util"
@@ -15,12 +16,15 @@ const (
 	WebClientPasswordChangeDisabled   = "password-change-disabled"
 	WebClientAPIKeyAuthChangeDisabled = "api-key-auth-change-disabled"
 	WebClientInfoChangeDisabled       = "info-change-disabled"
+	WebClientSharesDisabled           = "shares-disabled"
+	WebClientPasswordResetDisabled    = "password-reset-disabled"
 )
 
 var (
 	// WebClientOptions defines the available options for the web client interface/user REST API
 	WebClientOptions = []string{WebClientWriteDisabled, WebClientPasswordChangeDisabled, WebClientPubKeyChangeDisabled,
-		WebClientMFADisabled, WebClientAPIKeyAuthChangeDisabled, WebClientInfoChangeDisabled}
+		WebClientMFADisabled, WebClientAPIKeyAuthChangeDisabled, WebClientInfoChangeDisabled, WebClientSharesDisabled,
+		WebClientPasswordResetDisabled}
 	// UserTypes defines the supported user type hints for auth plugins
 	UserTypes = []string{string(UserTypeLDAP), string(UserTypeOS)}
 )
@@ -44,6 +48,47 @@ const (
 	UserTypeOS   UserType = "OSUser"
 )
 
+// DataTransferResetPeriod defines how often the used data transfer is automatically reset
+type DataTransferResetPeriod string
+
+// Supported data transfer reset periods. Periods start at midnight UTC,
+// weeks start on Monday
+const (
+	DataTransferResetNever   DataTransferResetPeriod = ""
+	DataTransferResetDaily   DataTransferResetPeriod = "daily"
+	DataTransferResetWeekly  DataTransferResetPeriod = "weekly"
+	DataTransferResetMonthly DataTransferResetPeriod = "monthly"
+)
+
+// IsValid returns true if the data transfer reset period is supported
+func (p DataTransferResetPeriod) IsValid() bool {
+	switch p {
+	case DataTransferResetNever, DataTransferResetDaily, DataTransferResetWeekly, DataTransferResetMonthly:
+		return true
+	default:
+		return false
+	}
+}
+
+// GetCurrentPeriodStart returns the start of the current period as unix timestamp
+// in milliseconds. 0 is returned if the used data transfer is never reset
+func (p DataTransferResetPeriod) GetCurrentPeriodStart(now time.Time) int64 {
+	now = now.UTC()
+	var start time.Time
+	switch p {
+	case DataTransferResetDaily:
+		start = time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	case DataTransferResetWeekly:
+		daysFromMonday := (int(now.Weekday()) + 6) % 7
+		start = time.Date(now.Year(), now.Month(), now.Day()-daysFromMonday, 0, 0, 0, 0, time.UTC)
+	case DataTransferResetMonthly:
+		start = time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	default:
+		return 0
+	}
+	return util.GetTimeAsMsSinceEpoch(start)
+}
+
 // DirectoryPermissions defines permissions for a directory virtual path
 type DirectoryPermissions struct {
 	Path        string
@@ -94,6 +139,21 @@ func (p *PatternsFilter) IsAllowed() bool {
 	return len(p.AllowedPatterns) > 0
 }
 
+// DataRetentionPolicy defines a data retention policy for a virtual path.
+// The policies are stored within the data provider and applied by the retention
+// check schedules
+type DataRetentionPolicy struct {
+	// Virtual path, the retention is applied recursively
+	Path string `json:"path"`
+	// Retention time in hours. All the files with a modification time older
+	// than the defined value will be deleted. 0 means exclude this path
+	Retention int `json:"retention"`
+	// DeleteEmptyDirs defines if empty directories will be deleted
+	DeleteEmptyDirs bool `json:"delete_empty_dirs,omitempty"`
+	// IgnoreUserPermissions defines if delete files even if the user does not have the delete permission
+	IgnoreUserPermissions bool `json:"ignore_user_permissions,omitempty"`
+}
+
 // HooksFilter defines user specific overrides for global hooks
 type HooksFilter struct {
 	ExternalAuthDisabled  bool `json:"external_auth_disabled"`
@@ -170,6 +230,17 @@ type UserFilters struct {
 	// UserType is an hint for authentication plugins.
 	// It is ignored when using SFTPGo internal authentication
 	UserType string `json:"user_type,omitempty"`
+	// If set the user must change the password before using any protocol other than HTTP.
+	// The flag is automatically cleared after a password change
+	RequirePasswordChange bool `json:"require_password_change,omitempty"`
+	// Password expiration as number of days, the password must be changed after the
+	// specified number of days since the last change. 0 means no expiration
+	PasswordExpiration int `json:"password_expiration,omitempty"`
+	// Username of the admin that created this user. It is set automatically
+	// and it is used to restrict scoped admins to the users they created
+	CreatedBy string `json:"created_by,omitempty"`
+	// Data retention policies, they are applied by the scheduled retention checks
+	DataRetention []DataRetentionPolicy `json:"data_retention,omitempty"`
 }
 
 type BaseUser struct {
@@ -214,8 +285,26 @@ type BaseUser struct {
 	UploadBandwidth int64 `json:"upload_bandwidth"`
 	// Maximum download bandwidth as KB/s, 0 means unlimited
 	DownloadBandwidth int64 `json:"download_bandwidth"`
+	// Maximum data transfer allowed for uploads as MB. 0 means no limit
+	UploadDataTransfer int64 `json:"upload_data_transfer"`
+	// Maximum data transfer allowed for downloads as MB. 0 means no limit
+	DownloadDataTransfer int64 `json:"download_data_transfer"`
+	// Maximum total data transfer as MB, uploads and downloads are added together.
+	// If set, the upload and download limits are ignored. 0 means no limit
+	TotalDataTransfer int64 `json:"total_data_transfer"`
+	// Uploaded data as bytes since the last data transfer reset
+	UsedUploadDataTransfer int64 `json:"used_upload_data_transfer"`
+	// Downloaded data as bytes since the last data transfer reset
+	UsedDownloadDataTransfer int64 `json:"used_download_data_transfer"`
+	// Last data transfer reset as unix timestamp in milliseconds
+	LastDataTransferReset int64 `json:"last_data_transfer_reset"`
+	// Data transfer reset period, empty means that the used data transfer is never
+	// automatically reset
+	DataTransferResetPeriod DataTransferResetPeriod `json:"data_transfer_reset_period,omitempty"`
 	// Last login as unix timestamp in milliseconds
 	LastLogin int64 `json:"last_login"`
+	// Last password change as unix timestamp in milliseconds
+	LastPasswordChange int64 `json:"last_password_change"`
 	// Creation time as unix timestamp in milliseconds. It will be 0 for admins created before v2.2.0
 	CreatedAt int64 `json:"created_at"`
 	// last update time as unix timestamp in milliseconds
@@ -161,6 +161,7 @@ func (s *Service) startServices() {
 	ftpdConf := config.GetFTPDConfig()
 	httpdConf := config.GetHTTPDConfig()
 	webDavDConf := config.GetWebDAVDConfig()
+	s3dConf := config.GetS3DConfig()
 	telemetryConf := config.GetTelemetryConfig()
 
 	if sftpdConf.ShouldBind() {
@@ -218,6 +219,18 @@ func (s *Service) startServices() {
 	} else {
 		logger.Debug(logSender, "", "WebDAV server not started, disabled in config file")
 	}
+	if s3dConf.ShouldBind() {
+		go func() {
+			if err := s3dConf.Initialize(s.ConfigDir); err != nil {
+				logger.Error(logSender, "", "could not start S3 compatible API server: %v", err)
+				logger.ErrorToConsole("could not start S3 compatible API server: %v", err)
+				s.Error = err
+			}
+			s.Shutdown <- true
+		}()
+	} else {
+		logger.Debug(logSender, "", "S3 compatible API server not started, disabled in config file")
+	}
 	if telemetryConf.ShouldBind() {
 		go func() {
 			if err := telemetryConf.Initialize(s.ConfigDir); err != nil {
@@ -304,6 +317,10 @@ func (s *Service) restoreDump(dump *dataprovider.BackupData) error {
 	if err != nil {
 		return fmt.Errorf("unable to restore folders from file %#v: %v", s.LoadDataFrom, err)
 	}
+	err = httpd.RestoreGroups(dump.Groups, s.LoadDataFrom, s.LoadDataMode, dataprovider.ActionExecutorSystem, "")
+	if err != nil {
+		return fmt.Errorf("unable to restore groups from file %#v: %v", s.LoadDataFrom, err)
+	}
 	err = httpd.RestoreUsers(dump.Users, s.LoadDataFrom, s.LoadDataMode, s.LoadDataQuotaScan, dataprovider.ActionExecutorSystem, "")
 	if err != nil {
 		return fmt.Errorf("unable to restore users from file %#v: %v", s.LoadDataFrom, err)
@@ -316,5 +333,21 @@ func (s *Service) restoreDump(dump *dataprovider.BackupData) error {
 	if err != nil {
 		return fmt.Errorf("unable to restore API keys from file %#v: %v", s.LoadDataFrom, err)
 	}
+	err = httpd.RestoreShares(dump.Shares, s.LoadDataFrom, s.LoadDataMode, dataprovider.ActionExecutorSystem, "")
+	if err != nil {
+		return fmt.Errorf("unable to restore shares from file %#v: %v", s.LoadDataFrom, err)
+	}
+	err = httpd.RestoreEventRules(dump.EventRules, s.LoadDataFrom, s.LoadDataMode, dataprovider.ActionExecutorSystem, "")
+	if err != nil {
+		return fmt.Errorf("unable to restore event rules from file %#v: %v", s.LoadDataFrom, err)
+	}
+	err = httpd.RestoreSchedules(dump.Schedules, s.LoadDataFrom, s.LoadDataMode, dataprovider.ActionExecutorSystem, "")
+	if err != nil {
+		return fmt.Errorf("unable to restore schedules from file %#v: %v", s.LoadDataFrom, err)
+	}
+	err = httpd.RestoreS3AccessKeys(dump.S3AccessKeys, s.LoadDataFrom, s.LoadDataMode)
+	if err != nil {
+		return fmt.Errorf("unable to restore S3 access keys from file %#v: %v", s.LoadDataFrom, err)
+	}
 	return nil
 }
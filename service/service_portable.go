@@ -305,5 +305,22 @@ func (s *Service) configurePortableSecrets() {
 		if payload != "" {
 			s.PortableUser.FsConfig.SFTPConfig.PrivateKey = kms.NewPlainSecret(payload)
 		}
+	case sdk.FTPFilesystemProvider:
+		payload := s.PortableUser.FsConfig.FTPConfig.Password.GetPayload()
+		s.PortableUser.FsConfig.FTPConfig.Password = kms.NewEmptySecret()
+		if payload != "" {
+			s.PortableUser.FsConfig.FTPConfig.Password = kms.NewPlainSecret(payload)
+		}
+	case sdk.WebDAVFilesystemProvider:
+		payload := s.PortableUser.FsConfig.WebDAVConfig.Password.GetPayload()
+		s.PortableUser.FsConfig.WebDAVConfig.Password = kms.NewEmptySecret()
+		if payload != "" {
+			s.PortableUser.FsConfig.WebDAVConfig.Password = kms.NewPlainSecret(payload)
+		}
+		payload = s.PortableUser.FsConfig.WebDAVConfig.BearerToken.GetPayload()
+		s.PortableUser.FsConfig.WebDAVConfig.BearerToken = kms.NewEmptySecret()
+		if payload != "" {
+			s.PortableUser.FsConfig.WebDAVConfig.BearerToken = kms.NewPlainSecret(payload)
+		}
 	}
 }
@@ -16,6 +16,7 @@ import (
 	"github.com/drakkan/sftpgo/v2/ftpd"
 	"github.com/drakkan/sftpgo/v2/httpd"
 	"github.com/drakkan/sftpgo/v2/logger"
+	"github.com/drakkan/sftpgo/v2/s3d"
 	"github.com/drakkan/sftpgo/v2/sdk/plugin"
 	"github.com/drakkan/sftpgo/v2/telemetry"
 	"github.com/drakkan/sftpgo/v2/webdavd"
@@ -132,6 +133,10 @@ loop:
 			if err != nil {
 				logger.Warn(logSender, "", "error reloading WebDAV cert manager: %v", err)
 			}
+			err = s3d.ReloadCertificateMgr()
+			if err != nil {
+				logger.Warn(logSender, "", "error reloading S3 cert manager: %v", err)
+			}
 			err = telemetry.ReloadCertificateMgr()
 			if err != nil {
 				logger.Warn(logSender, "", "error reloading telemetry cert manager: %v", err)
@@ -0,0 +1,730 @@
+package sftpd
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+	"sync"
+
+	"github.com/pkg/sftp"
+
+	"github.com/drakkan/sftpgo/v2/logger"
+	"github.com/drakkan/sftpgo/v2/util"
+)
+
+const (
+	sftpPacketVersion       = 2
+	sftpPacketOpen          = 3
+	sftpPacketClose         = 4
+	sftpPacketStatus        = 101
+	sftpPacketHandle        = 102
+	sftpPacketName          = 104
+	sftpPacketExtended      = 200
+	sftpPacketExtendedReply = 201
+
+	sftpStatusOK               = 0
+	sftpStatusEOF              = 1
+	sftpStatusNoSuchFile       = 2
+	sftpStatusPermissionDenied = 3
+	sftpStatusFailure          = 4
+	sftpStatusBadMessage       = 5
+	sftpStatusOpUnsupported    = 8
+
+	// same limit used in pkg/sftp
+	sftpMaxPacketLength = 256 * 1024
+	// pkg/sftp returns at most 32768 bytes for each read request
+	sftpMaxReadLength = 32768
+	// same value used in OpenSSH, we leave some space for the other write packet fields
+	sftpMaxWriteLength = sftpMaxPacketLength - 1024
+
+	sftpExtensionCopyFile         = "copy-file"
+	sftpExtensionCopyData         = "copy-data"
+	sftpExtensionExpandPath       = "expand-path@openssh.com"
+	sftpExtensionHomeDirectory    = "home-directory"
+	sftpExtensionLimits           = "limits@openssh.com"
+	sftpExtensionFsync            = "fsync@openssh.com"
+	sftpExtensionUsersGroupsByID  = "users-groups-by-id@openssh.com"
+	sftpUsersGroupsByIDMaxEntries = 1000
+)
+
+// extensions handled by sftpExtensionsChannel as name, version pairs
+var sftpChannelExtensions = [][2]string{
+	{sftpExtensionCopyFile, "1"},
+	{sftpExtensionCopyData, "1"},
+	{sftpExtensionExpandPath, "1"},
+	{sftpExtensionHomeDirectory, "1"},
+	{sftpExtensionLimits, "1"},
+	{sftpExtensionFsync, "1"},
+	{sftpExtensionUsersGroupsByID, "1"},
+}
+
+var (
+	errSFTPBadMessage    = errors.New("bad message")
+	errSFTPInvalidHandle = errors.New("invalid handle")
+)
+
+// sftpExtensionsChannel wraps the SFTP channel and handles the extensions not
+// supported by pkg/sftp:
+//
+// - "copy-file", copies a file server side
+// - "copy-data", copies data between two open handles server side
+// - "expand-path@openssh.com", expands "~" and relative paths
+// - "home-directory", returns the home directory for the connected user
+// - "limits@openssh.com", returns the server limits for packets, reads and writes
+// - "fsync@openssh.com", commits an open file to stable storage
+// - "users-groups-by-id@openssh.com", maps the user and group ids configured for the connected user to names
+//
+// These extensions are advertised by adding them to the version packet sent
+// by pkg/sftp. Any other packet is forwarded unchanged.
+// To resolve the handles used by "copy-data" and "fsync@openssh.com" we track
+// the pending open requests by ID. pkg/sftp does not expose the request ID to
+// the handlers, so a transfer opened by a handler is assigned to the oldest
+// pending open request for the same path and, when the response for that
+// request is sent, the transfer is associated to the returned handle.
+// pkg/sftp processes the open requests sequentially, so if a request fails
+// after a later request for the same path got its transfer, the transfers
+// are moved to the following requests for that path
+type sftpExtensionsChannel struct {
+	io.ReadWriteCloser
+	connection *Connection
+	readBuf    bytes.Buffer
+	writeMu    sync.Mutex
+	writeBuf   []byte
+	mu         sync.Mutex
+	// pending open requests, in the order they are received
+	openRequests []*sftpOpenRequest
+	handles      map[string]*transfer
+}
+
+// sftpOpenRequest defines a pending open request
+type sftpOpenRequest struct {
+	id uint32
+	// virtual path, as seen by the handlers
+	virtualPath string
+	// transfer opened for this request, if any
+	transfer *transfer
+}
+
+func newSFTPExtensionsChannel(channel io.ReadWriteCloser, connection *Connection) *sftpExtensionsChannel {
+	c := &sftpExtensionsChannel{
+		ReadWriteCloser: channel,
+		connection:      connection,
+		handles:         make(map[string]*transfer),
+	}
+	connection.sftpExtensions = c
+	return c
+}
+
+// Read implements the io.Reader interface. The packets for the extensions
+// handled here are not returned
+func (c *sftpExtensionsChannel) Read(p []byte) (int, error) {
+	for c.readBuf.Len() == 0 {
+		packet, err := c.readPacket()
+		if err != nil {
+			return 0, err
+		}
+		if len(packet) > 4 && c.handleIncomingPacket(packet) {
+			continue
+		}
+		c.readBuf.Write(packet)
+	}
+	return c.readBuf.Read(p)
+}
+
+// Write implements the io.Writer interface. pkg/sftp can write a single packet
+// using multiple writes so we buffer the data until a full packet is available,
+// this way the packets sent from pkg/sftp and the ones sent from here cannot
+// be interleaved
+func (c *sftpExtensionsChannel) Write(p []byte) (int, error) {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	c.writeBuf = append(c.writeBuf, p...)
+	consumed := 0
+	for len(c.writeBuf)-consumed >= 4 {
+		length := int(binary.BigEndian.Uint32(c.writeBuf[consumed:]))
+		if len(c.writeBuf)-consumed-4 < length {
+			break
+		}
+		packet := c.handleOutgoingPacket(c.writeBuf[consumed : consumed+4+length])
+		if _, err := c.ReadWriteCloser.Write(packet); err != nil {
+			return 0, err
+		}
+		consumed += 4 + length
+	}
+	n := copy(c.writeBuf, c.writeBuf[consumed:])
+	c.writeBuf = c.writeBuf[:n]
+	return len(p), nil
+}
+
+func (c *sftpExtensionsChannel) writePacket(packet []byte) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	_, err := c.ReadWriteCloser.Write(packet)
+	return err
+}
+
+// readPacket reads a full packet, including the length, from the underlying channel.
+// If the length is not valid only the length is returned, pkg/sftp will handle the error
+func (c *sftpExtensionsChannel) readPacket() ([]byte, error) {
+	header := make([]byte, 4)
+	if _, err := io.ReadFull(c.ReadWriteCloser, header); err != nil {
+		return nil, err
+	}
+	length := binary.BigEndian.Uint32(header)
+	if length == 0 || length > sftpMaxPacketLength {
+		return header, nil
+	}
+	packet := make([]byte, 4+length)
+	copy(packet, header)
+	if _, err := io.ReadFull(c.ReadWriteCloser, packet[4:]); err != nil {
+		return nil, err
+	}
+	return packet, nil
+}
+
+func (c *sftpExtensionsChannel) addOpenRequest(id uint32, sftpPath string) {
+	// the handlers are not called for paths outside the folder prefix
+	virtualPath, err := c.resolvePath(sftpPath)
+	if err != nil {
+		virtualPath = ""
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.openRequests = append(c.openRequests, &sftpOpenRequest{
+		id:          id,
+		virtualPath: virtualPath,
+	})
+}
+
+// removeOpenRequest removes the open request with the specified ID and
+// returns the transfer assigned to it, if any
+func (c *sftpExtensionsChannel) removeOpenRequest(id uint32) (*sftpOpenRequest, int) {
+	for idx, r := range c.openRequests {
+		if r.id == id {
+			c.openRequests = append(c.openRequests[:idx], c.openRequests[idx+1:]...)
+			return r, idx
+		}
+	}
+	return nil, -1
+}
+
+func (c *sftpExtensionsChannel) addOpenedTransfer(t *transfer) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, r := range c.openRequests {
+		if r.transfer == nil && r.virtualPath == t.GetVirtualPath() {
+			r.transfer = t
+			return
+		}
+	}
+	c.connection.Log(logger.LevelDebug, "no pending open request for transfer %#v", t.GetVirtualPath())
+}
+
+// handleOpenResponse associates the transfer assigned to the open request with
+// the specified ID to the returned handle. An empty handle means that the open
+// request failed
+func (c *sftpExtensionsChannel) handleOpenResponse(id uint32, handle string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	r, idx := c.removeOpenRequest(id)
+	if r == nil || r.transfer == nil {
+		return
+	}
+	if handle != "" {
+		c.handles[handle] = r.transfer
+		return
+	}
+	// a failed request has no transfer, this one belongs to the next request for
+	// the same path. The transfers assigned to the following requests for this
+	// path must be moved too
+	t := r.transfer
+	for _, next := range c.openRequests[idx:] {
+		if t == nil {
+			return
+		}
+		if next.virtualPath == r.virtualPath {
+			next.transfer, t = t, next.transfer
+		}
+	}
+}
+
+func (c *sftpExtensionsChannel) getTransfer(handle string) *transfer {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.handles[handle]
+}
+
+// handleIncomingPacket returns true if the packet is handled here and
+// so it must not be forwarded to pkg/sftp
+func (c *sftpExtensionsChannel) handleIncomingPacket(packet []byte) bool {
+	data := packet[5:]
+	switch packet[4] {
+	case sftpPacketOpen:
+		if id, data, err := unmarshalSFTPUint32(data); err == nil {
+			if sftpPath, _, err := unmarshalSFTPString(data); err == nil {
+				c.addOpenRequest(id, sftpPath)
+			}
+		}
+	case sftpPacketClose:
+		if _, data, err := unmarshalSFTPUint32(data); err == nil {
+			if handle, _, err := unmarshalSFTPString(data); err == nil {
+				c.mu.Lock()
+				delete(c.handles, handle)
+				c.mu.Unlock()
+			}
+		}
+	case sftpPacketExtended:
+		id, data, err := unmarshalSFTPUint32(data)
+		if err != nil {
+			return false
+		}
+		extension, data, err := unmarshalSFTPString(data)
+		if err != nil {
+			return false
+		}
+		switch extension {
+		case sftpExtensionCopyFile:
+			c.sendStatus(id, c.handleCopyFile(data))
+			return true
+		case sftpExtensionCopyData:
+			// copying data can take a while, we don't want to block the other requests.
+			// The packet is not reused so we can safely pass its data to the goroutine
+			go func() {
+				c.sendStatus(id, c.handleCopyData(data))
+			}()
+			return true
+		case sftpExtensionFsync:
+			c.sendStatus(id, c.handleFsync(data))
+			return true
+		case sftpExtensionExpandPath:
+			name, err := c.handleExpandPath(data)
+			c.sendName(id, name, err)
+			return true
+		case sftpExtensionHomeDirectory:
+			name, err := c.handleHomeDirectory(data)
+			c.sendName(id, name, err)
+			return true
+		case sftpExtensionLimits:
+			c.sendExtendedReply(id, c.handleLimits(), nil)
+			return true
+		case sftpExtensionUsersGroupsByID:
+			reply, err := c.handleUsersGroupsByID(data)
+			c.sendExtendedReply(id, reply, err)
+			return true
+		}
+	}
+	return false
+}
+
+func (c *sftpExtensionsChannel) handleOutgoingPacket(packet []byte) []byte {
+	data := packet[5:]
+	switch packet[4] {
+	case sftpPacketVersion:
+		// packet is a slice of the write buffer, we need a copy to append the extensions
+		packet = append([]byte(nil), packet...)
+		for _, extension := range sftpChannelExtensions {
+			packet = marshalSFTPString(packet, extension[0])
+			packet = marshalSFTPString(packet, extension[1])
+		}
+		binary.BigEndian.PutUint32(packet, uint32(len(packet)-4))
+	case sftpPacketHandle:
+		if id, data, err := unmarshalSFTPUint32(data); err == nil {
+			handle, _, err := unmarshalSFTPString(data)
+			if err == nil && handle != "" {
+				c.handleOpenResponse(id, handle)
+			}
+		}
+	case sftpPacketStatus:
+		if id, _, err := unmarshalSFTPUint32(data); err == nil {
+			c.handleOpenResponse(id, "")
+		}
+	}
+	return packet
+}
+
+func (c *sftpExtensionsChannel) resolvePath(sftpPath string) (string, error) {
+	virtualPath := util.CleanPath(sftpPath)
+	if c.connection.folderPrefix == "" {
+		return virtualPath, nil
+	}
+	p := &prefixMiddleware{
+		prefix: c.connection.folderPrefix,
+	}
+	if getPrefixHierarchy(p.prefix, virtualPath) != pathContainsPrefix {
+		return "", sftp.ErrSSHFxPermissionDenied
+	}
+	virtualPath, _ = p.removeFolderPrefix(virtualPath)
+	return virtualPath, nil
+}
+
+// handleCopyFile handles the "copy-file" extension, the request data are:
+// string source, string destination, bool overwrite destination
+func (c *sftpExtensionsChannel) handleCopyFile(data []byte) error {
+	source, data, err := unmarshalSFTPString(data)
+	if err != nil {
+		return errSFTPBadMessage
+	}
+	target, data, err := unmarshalSFTPString(data)
+	if err != nil {
+		return errSFTPBadMessage
+	}
+	if len(data) < 1 {
+		return errSFTPBadMessage
+	}
+	overwrite := data[0] != 0
+	c.connection.UpdateLastActivity()
+
+	source, err = c.resolvePath(source)
+	if err != nil {
+		return err
+	}
+	target, err = c.resolvePath(target)
+	if err != nil {
+		return err
+	}
+	c.connection.Log(logger.LevelDebug, "copy-file requested, source: %#v target: %#v overwrite: %v",
+		source, target, overwrite)
+	if !overwrite {
+		fs, fsTargetPath, err := c.connection.GetFsAndResolvedPath(target)
+		if err != nil {
+			return err
+		}
+		if _, err := fs.Lstat(fsTargetPath); err == nil {
+			return fmt.Errorf("%w: the destination %#v already exists", sftp.ErrSSHFxFailure, target)
+		}
+	}
+	return c.connection.Copy(source, target)
+}
+
+// handleCopyData handles the "copy-data" extension, the request data are:
+// string read handle, uint64 read offset, uint64 read length,
+// string write handle, uint64 write offset.
+// If the read length is 0 data are copied until EOF
+func (c *sftpExtensionsChannel) handleCopyData(data []byte) error {
+	readHandle, data, err := unmarshalSFTPString(data)
+	if err != nil {
+		return errSFTPBadMessage
+	}
+	readOffset, data, err := unmarshalSFTPUint64(data)
+	if err != nil {
+		return errSFTPBadMessage
+	}
+	readLength, data, err := unmarshalSFTPUint64(data)
+	if err != nil {
+		return errSFTPBadMessage
+	}
+	writeHandle, data, err := unmarshalSFTPString(data)
+	if err != nil {
+		return errSFTPBadMessage
+	}
+	writeOffset, _, err := unmarshalSFTPUint64(data)
+	if err != nil {
+		return errSFTPBadMessage
+	}
+	reader := c.getTransfer(readHandle)
+	writer := c.getTransfer(writeHandle)
+	if reader == nil || writer == nil || reader.readerAt == nil || writer.writerAt == nil {
+		return errSFTPInvalidHandle
+	}
+	if readHandle == writeHandle {
+		if readLength == 0 || (readOffset < writeOffset+readLength && writeOffset < readOffset+readLength) {
+			return fmt.Errorf("%w: overlapping copy range", sftp.ErrSSHFxFailure)
+		}
+	}
+	c.connection.Log(logger.LevelDebug, "copy-data requested, source: %#v offset: %v length: %v, target: %#v offset: %v",
+		reader.GetVirtualPath(), readOffset, readLength, writer.GetVirtualPath(), writeOffset)
+
+	copyUntilEOF := readLength == 0
+	buf := make([]byte, 32768)
+	for copyUntilEOF || readLength > 0 {
+		toRead := uint64(len(buf))
+		if !copyUntilEOF && readLength < toRead {
+			toRead = readLength
+		}
+		n, err := reader.ReadAt(buf[:toRead], int64(readOffset))
+		if n > 0 {
+			if _, errWrite := writer.WriteAt(buf[:n], int64(writeOffset)); errWrite != nil {
+				return errWrite
+			}
+			readOffset += uint64(n)
+			writeOffset += uint64(n)
+			if !copyUntilEOF {
+				readLength -= uint64(n)
+			}
+		}
+		if err == io.EOF {
+			if copyUntilEOF || readLength == 0 {
+				return nil
+			}
+			return io.EOF
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// handleFsync handles the "fsync@openssh.com" extension, the request data are:
+// string handle
+func (c *sftpExtensionsChannel) handleFsync(data []byte) error {
+	handle, _, err := unmarshalSFTPString(data)
+	if err != nil {
+		return errSFTPBadMessage
+	}
+	t := c.getTransfer(handle)
+	if t == nil {
+		return errSFTPInvalidHandle
+	}
+	c.connection.UpdateLastActivity()
+	if err := t.Sync(); err != nil {
+		c.connection.Log(logger.LevelDebug, "unable to fsync file %#v: %v", t.GetVirtualPath(), err)
+		return err
+	}
+	return nil
+}
+
+// handleExpandPath handles the "expand-path@openssh.com" extension, the request data are:
+// string path.
+// Relative paths and paths starting with "~" are expanded using the user home
+// directory, "~username" is only allowed for the connected user
+func (c *sftpExtensionsChannel) handleExpandPath(data []byte) (string, error) {
+	p, _, err := unmarshalSFTPString(data)
+	if err != nil {
+		return "", errSFTPBadMessage
+	}
+	c.connection.UpdateLastActivity()
+
+	if strings.HasPrefix(p, "~") {
+		name := strings.TrimPrefix(p, "~")
+		rel := ""
+		if idx := strings.Index(name, "/"); idx >= 0 {
+			rel = name[idx+1:]
+			name = name[:idx]
+		}
+		home, err := c.getHomeDirectory(name)
+		if err != nil {
+			return "", err
+		}
+		return path.Join(home, rel), nil
+	}
+	if path.IsAbs(p) {
+		return util.CleanPath(p), nil
+	}
+	home, err := c.getHomeDirectory("")
+	if err != nil {
+		return "", err
+	}
+	return path.Join(home, p), nil
+}
+
+// handleHomeDirectory handles the "home-directory" extension, the request data are:
+// string username
+func (c *sftpExtensionsChannel) handleHomeDirectory(data []byte) (string, error) {
+	username, _, err := unmarshalSFTPString(data)
+	if err != nil {
+		return "", errSFTPBadMessage
+	}
+	c.connection.UpdateLastActivity()
+
+	return c.getHomeDirectory(username)
+}
+
+// getHomeDirectory returns the home directory, as seen by the SFTP client, for
+// the specified username. Only the home directory for the connected user is
+// returned, an empty username means the connected user.
+// If a folder prefix is configured the home directory is the prefix itself
+func (c *sftpExtensionsChannel) getHomeDirectory(username string) (string, error) {
+	if username != "" && username != c.connection.User.Username {
+		c.connection.Log(logger.LevelDebug, "home directory requested for user %#v, not allowed", username)
+		return "", fmt.Errorf("%w: unable to get the home directory for user %#v", sftp.ErrSSHFxNoSuchFile, username)
+	}
+	if c.connection.folderPrefix != "" {
+		return c.connection.folderPrefix, nil
+	}
+	return "/", nil
+}
+
+// handleLimits handles the "limits@openssh.com" extension, there are no request data.
+// The reply data are:
+// uint64 max packet length, uint64 max read length, uint64 max write length,
+// uint64 max open handles, 0 means no limit
+func (c *sftpExtensionsChannel) handleLimits() []byte {
+	c.connection.UpdateLastActivity()
+
+	maxWriteLength := uint64(sftpMaxWriteLength)
+	if c.connection.User.Filters.MaxUploadFileSize > 0 && uint64(c.connection.User.Filters.MaxUploadFileSize) < maxWriteLength {
+		maxWriteLength = uint64(c.connection.User.Filters.MaxUploadFileSize)
+	}
+	reply := marshalSFTPUint64(nil, sftpMaxPacketLength)
+	reply = marshalSFTPUint64(reply, sftpMaxReadLength)
+	reply = marshalSFTPUint64(reply, maxWriteLength)
+	reply = marshalSFTPUint64(reply, 0)
+	return reply
+}
+
+// handleUsersGroupsByID handles the "users-groups-by-id@openssh.com" extension, the request data are:
+// string uids, string gids.
+// The uids and gids strings contain a list of uint32 values.
+// The reply data are:
+// string usernames, string groupnames.
+// The usernames and groupnames strings contain a list of strings, in the same
+// order as the requested ids, an empty string is returned for unknown ids.
+// The accounts of the host system are never disclosed: only the uid and gid
+// configured for the connected user are resolved, to its username
+func (c *sftpExtensionsChannel) handleUsersGroupsByID(data []byte) ([]byte, error) {
+	uids, data, err := unmarshalSFTPString(data)
+	if err != nil {
+		return nil, errSFTPBadMessage
+	}
+	gids, _, err := unmarshalSFTPString(data)
+	if err != nil {
+		return nil, errSFTPBadMessage
+	}
+	if len(uids)%4 != 0 || len(gids)%4 != 0 {
+		return nil, errSFTPBadMessage
+	}
+	if len(uids)/4+len(gids)/4 > sftpUsersGroupsByIDMaxEntries {
+		return nil, fmt.Errorf("%w: too many ids requested", sftp.ErrSSHFxFailure)
+	}
+	c.connection.UpdateLastActivity()
+
+	var usernames, groupnames []byte
+	for b := []byte(uids); len(b) > 0; b = b[4:] {
+		usernames = marshalSFTPString(usernames, c.lookupID(binary.BigEndian.Uint32(b), c.connection.User.GetUID()))
+	}
+	for b := []byte(gids); len(b) > 0; b = b[4:] {
+		groupnames = marshalSFTPString(groupnames, c.lookupID(binary.BigEndian.Uint32(b), c.connection.User.GetGID()))
+	}
+	reply := marshalSFTPString(nil, string(usernames))
+	reply = marshalSFTPString(reply, string(groupnames))
+	return reply, nil
+}
+
+// lookupID returns the connected username if id matches the configured one,
+// configuredID is -1 if no id is configured
+func (c *sftpExtensionsChannel) lookupID(id uint32, configuredID int) string {
+	if configuredID < 0 || int64(id) != int64(configuredID) {
+		return ""
+	}
+	return c.connection.User.Username
+}
+
+func (c *sftpExtensionsChannel) sendName(id uint32, name string, err error) {
+	if err != nil {
+		c.sendStatus(id, err)
+		return
+	}
+	packet := make([]byte, 4, 32+2*len(name))
+	packet = append(packet, sftpPacketName)
+	packet = marshalSFTPUint32(packet, id)
+	packet = marshalSFTPUint32(packet, 1)
+	packet = marshalSFTPString(packet, name)
+	packet = marshalSFTPString(packet, name)
+	// attributes flags, no attributes
+	packet = marshalSFTPUint32(packet, 0)
+	binary.BigEndian.PutUint32(packet, uint32(len(packet)-4))
+
+	if errWrite := c.writePacket(packet); errWrite != nil {
+		c.connection.Log(logger.LevelDebug, "unable to send name packet: %v", errWrite)
+	}
+}
+
+func (c *sftpExtensionsChannel) sendExtendedReply(id uint32, reply []byte, err error) {
+	if err != nil {
+		c.sendStatus(id, err)
+		return
+	}
+	packet := make([]byte, 4, 16+len(reply))
+	packet = append(packet, sftpPacketExtendedReply)
+	packet = marshalSFTPUint32(packet, id)
+	packet = append(packet, reply...)
+	binary.BigEndian.PutUint32(packet, uint32(len(packet)-4))
+
+	if errWrite := c.writePacket(packet); errWrite != nil {
+		c.connection.Log(logger.LevelDebug, "unable to send extended reply packet: %v", errWrite)
+	}
+}
+
+func (c *sftpExtensionsChannel) sendStatus(id uint32, err error) {
+	code, message := getSFTPStatusFromError(err)
+	packet := make([]byte, 4, 32+len(message))
+	packet = append(packet, sftpPacketStatus)
+	packet = marshalSFTPUint32(packet, id)
+	packet = marshalSFTPUint32(packet, code)
+	packet = marshalSFTPString(packet, message)
+	packet = marshalSFTPString(packet, "")
+	binary.BigEndian.PutUint32(packet, uint32(len(packet)-4))
+
+	if errWrite := c.writePacket(packet); errWrite != nil {
+		c.connection.Log(logger.LevelDebug, "unable to send status packet: %v", errWrite)
+	}
+}
+
+func getSFTPStatusFromError(err error) (uint32, string) {
+	switch {
+	case err == nil:
+		return sftpStatusOK, "OK"
+	case errors.Is(err, io.EOF):
+		return sftpStatusEOF, "EOF"
+	case errors.Is(err, sftp.ErrSSHFxNoSuchFile), errors.Is(err, os.ErrNotExist):
+		return sftpStatusNoSuchFile, err.Error()
+	case errors.Is(err, sftp.ErrSSHFxPermissionDenied), errors.Is(err, os.ErrPermission):
+		return sftpStatusPermissionDenied, err.Error()
+	case errors.Is(err, sftp.ErrSSHFxOpUnsupported):
+		return sftpStatusOpUnsupported, err.Error()
+	case errors.Is(err, errSFTPBadMessage):
+		return sftpStatusBadMessage, err.Error()
+	default:
+		return sftpStatusFailure, err.Error()
+	}
+}
+
+func marshalSFTPUint32(b []byte, v uint32) []byte {
+	return append(b, byte(v>>24), byte(v>>16), byte(v>>8), byte(v))
+}
+
+func marshalSFTPUint64(b []byte, v uint64) []byte {
+	return marshalSFTPUint32(marshalSFTPUint32(b, uint32(v>>32)), uint32(v))
+}
+
+func marshalSFTPString(b []byte, v string) []byte {
+	return append(marshalSFTPUint32(b, uint32(len(v))), v...)
+}
+
+func unmarshalSFTPUint32(b []byte) (uint32, []byte, error) {
+	if len(b) < 4 {
+		return 0, nil, errSFTPBadMessage
+	}
+	return binary.BigEndian.Uint32(b), b[4:], nil
+}
+
+func unmarshalSFTPUint64(b []byte) (uint64, []byte, error) {
+	if len(b) < 8 {
+		return 0, nil, errSFTPBadMessage
+	}
+	return binary.BigEndian.Uint64(b), b[8:], nil
+}
+
+func unmarshalSFTPString(b []byte) (string, []byte, error) {
+	length, b, err := unmarshalSFTPUint32(b)
+	if err != nil {
+		return "", nil, err
+	}
+	if uint32(len(b)) < length {
+		return "", nil, errSFTPBadMessage
+	}
+	return string(b[:length]), b[length:], nil
+}
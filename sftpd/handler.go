@@ -27,6 +27,8 @@ type Connection struct {
 	channel      io.ReadWriteCloser
 	command      string
 	folderPrefix string
+	// handles the SFTP extensions not supported by pkg/sftp, nil for SSH commands
+	sftpExtensions *sftpExtensionsChannel
 }
 
 // GetClientVersion returns the connected client's version
@@ -73,6 +75,12 @@ func (c *Connection) Fileread(request *sftp.Request) (io.ReaderAt, error) {
 		return nil, err
 	}
 
+	transferQuota := c.GetTransferQuota(request.Filepath)
+	if !transferQuota.HasDownloadSpace() {
+		c.Log(logger.LevelInfo, "denying file read due to quota limits")
+		return nil, c.GetReadQuotaExceededError()
+	}
+
 	if err := common.ExecutePreAction(&c.User, common.OperationPreDownload, p, request.Filepath, c.GetProtocol(), c.GetRemoteIP(), 0, 0); err != nil {
 		c.Log(logger.LevelDebug, "download for file %#v denied by pre action: %v", request.Filepath, err)
 		return nil, c.GetPermissionDeniedError()
@@ -85,20 +93,40 @@ func (c *Connection) Fileread(request *sftp.Request) (io.ReaderAt, error) {
 	}
 
 	baseTransfer := common.NewBaseTransfer(file, c.BaseConnection, cancelFn, p, p, request.Filepath, common.TransferDownload,
-		0, 0, 0, false, fs)
+		0, 0, 0, false, fs, transferQuota)
 	t := newTransfer(baseTransfer, nil, r, nil)
+	c.addOpenedTransfer(t)
 
 	return t, nil
 }
 
 // OpenFile implements OpenFileWriter interface
 func (c *Connection) OpenFile(request *sftp.Request) (sftp.WriterAtReaderAt, error) {
-	return c.handleFilewrite(request)
+	w, err := c.handleFilewrite(request)
+	if err != nil {
+		return nil, err
+	}
+	c.addOpenedTransfer(w)
+	return w, nil
 }
 
 // Filewrite handles the write actions for a file on the system.
 func (c *Connection) Filewrite(request *sftp.Request) (io.WriterAt, error) {
-	return c.handleFilewrite(request)
+	w, err := c.handleFilewrite(request)
+	if err != nil {
+		return nil, err
+	}
+	c.addOpenedTransfer(w)
+	return w, nil
+}
+
+func (c *Connection) addOpenedTransfer(w sftp.WriterAtReaderAt) {
+	if c.sftpExtensions == nil {
+		return
+	}
+	if t, ok := w.(*transfer); ok {
+		c.sftpExtensions.addOpenedTransfer(t)
+	}
 }
 
 func (c *Connection) handleFilewrite(request *sftp.Request) (sftp.WriterAtReaderAt, error) {
@@ -136,7 +164,7 @@ func (c *Connection) handleFilewrite(request *sftp.Request) (sftp.WriterAtReader
 		if !c.User.HasPerm(dataprovider.PermUpload, path.Dir(request.Filepath)) {
 			return nil, sftp.ErrSSHFxPermissionDenied
 		}
-		return c.handleSFTPUploadToNewFile(fs, p, filePath, request.Filepath, errForRead)
+		return c.handleSFTPUploadToNewFile(fs, request.Pflags(), p, filePath, request.Filepath, errForRead)
 	}
 
 	if statErr != nil {
@@ -342,19 +370,31 @@ func (c *Connection) handleSFTPRemove(request *sftp.Request) error {
 	return c.RemoveFile(fs, fsPath, request.Filepath, fi)
 }
 
-func (c *Connection) handleSFTPUploadToNewFile(fs vfs.Fs, resolvedPath, filePath, requestPath string, errForRead error) (sftp.WriterAtReaderAt, error) {
+func (c *Connection) handleSFTPUploadToNewFile(fs vfs.Fs, pflags sftp.FileOpenFlags, resolvedPath, filePath,
+	requestPath string, errForRead error) (sftp.WriterAtReaderAt, error) {
 	quotaResult := c.HasSpace(true, false, requestPath)
 	if !quotaResult.HasSpace {
 		c.Log(logger.LevelInfo, "denying file write due to quota limits")
 		return nil, c.GetQuotaExceededError()
 	}
+	transferQuota := c.GetTransferQuota(requestPath)
+	if !transferQuota.HasUploadSpace() {
+		c.Log(logger.LevelInfo, "denying file write due to transfer quota limits")
+		return nil, c.GetQuotaExceededError()
+	}
 
 	if err := common.ExecutePreAction(&c.User, common.OperationPreUpload, resolvedPath, requestPath, c.GetProtocol(), c.GetRemoteIP(), 0, 0); err != nil {
 		c.Log(logger.LevelDebug, "upload for file %#v denied by pre action: %v", requestPath, err)
 		return nil, c.GetPermissionDeniedError()
 	}
 
-	file, w, cancelFn, err := fs.Create(filePath, 0)
+	osFlags := 0
+	// interrupted uploads to Cloud Storage backends are not visible as files,
+	// we resume them if the client explicitly asks to append to the file
+	if pflags.Append && !pflags.Trunc && vfs.HasPendingUploadResume(fs) {
+		osFlags = getOSOpenFlags(pflags)
+	}
+	file, w, cancelFn, err := fs.Create(filePath, osFlags)
 	if err != nil {
 		c.Log(logger.LevelWarn, "error creating file %#v: %+v", resolvedPath, err)
 		return nil, c.GetFsError(fs, err)
@@ -364,9 +404,21 @@ func (c *Connection) handleSFTPUploadToNewFile(fs vfs.Fs, resolvedPath, filePath
 
 	// we can get an error only for resume
 	maxWriteSize, _ := c.GetMaxWriteSize(quotaResult, false, 0, fs.IsUploadResumeSupported())
+	minWriteOffset := int64(0)
+	if w != nil && w.GetOffset() > 0 {
+		minWriteOffset = w.GetOffset()
+		c.Log(logger.LevelDebug, "resuming interrupted upload, file path %#v offset: %v", filePath, minWriteOffset)
+		maxWriteSize, err = c.GetMaxWriteSizeForPendingUpload(maxWriteSize, minWriteOffset)
+		if err != nil {
+			c.Log(logger.LevelInfo, "denying resume for interrupted upload due to quota limits")
+			cancelFn()
+			w.Close() //nolint:errcheck
+			return nil, err
+		}
+	}
 
 	baseTransfer := common.NewBaseTransfer(file, c.BaseConnection, cancelFn, resolvedPath, filePath, requestPath,
-		common.TransferUpload, 0, 0, maxWriteSize, true, fs)
+		common.TransferUpload, minWriteOffset, 0, maxWriteSize, true, fs, transferQuota)
 	t := newTransfer(baseTransfer, w, nil, errForRead)
 
 	return t, nil
@@ -380,6 +432,11 @@ func (c *Connection) handleSFTPUploadToExistingFile(fs vfs.Fs, pflags sftp.FileO
 		c.Log(logger.LevelInfo, "denying file write due to quota limits")
 		return nil, c.GetQuotaExceededError()
 	}
+	transferQuota := c.GetTransferQuota(requestPath)
+	if !transferQuota.HasUploadSpace() {
+		c.Log(logger.LevelInfo, "denying file write due to transfer quota limits")
+		return nil, c.GetQuotaExceededError()
+	}
 
 	osFlags := getOSOpenFlags(pflags)
 	minWriteOffset := int64(0)
@@ -388,8 +445,7 @@ func (c *Connection) handleSFTPUploadToExistingFile(fs vfs.Fs, pflags sftp.FileO
 	// so we suppose this is an upload resume if the TRUNCATE flag is not set
 	isResume := !isTruncate
 	// if there is a size limit the remaining size cannot be 0 here, since quotaResult.HasSpace
-	// will return false in this case and we deny the upload before.
-	// For Cloud FS GetMaxWriteSize will return unsupported operation
+	// will return false in this case and we deny the upload before
 	maxWriteSize, err := c.GetMaxWriteSize(quotaResult, isResume, fileSize, fs.IsUploadResumeSupported())
 	if err != nil {
 		c.Log(logger.LevelDebug, "unable to get max write size: %v", err)
@@ -444,7 +500,7 @@ func (c *Connection) handleSFTPUploadToExistingFile(fs vfs.Fs, pflags sftp.FileO
 	vfs.SetPathPermissions(fs, filePath, c.User.GetUID(), c.User.GetGID())
 
 	baseTransfer := common.NewBaseTransfer(file, c.BaseConnection, cancelFn, resolvedPath, filePath, requestPath,
-		common.TransferUpload, minWriteOffset, initialSize, maxWriteSize, false, fs)
+		common.TransferUpload, minWriteOffset, initialSize, maxWriteSize, false, fs, transferQuota)
 	t := newTransfer(baseTransfer, w, nil, errForRead)
 
 	return t, nil
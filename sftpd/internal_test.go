@@ -2,6 +2,7 @@ package sftpd
 
 import (
 	"bytes"
+	"encoding/binary"
 	"errors"
 	"fmt"
 	"io"
@@ -133,6 +134,47 @@ func newMockOsFs(err, statErr error, atomicUpload bool, connectionID, rootDir st
 	}
 }
 
+// mockCloudFs simulates a Cloud Storage filesystem with an interrupted upload.
+// The interrupted upload is not visible as a file and it is resumed if Create
+// is called with a resume flag, otherwise it is discarded
+type mockCloudFs struct {
+	vfs.Fs
+	pendingSize int64
+	createFlags int
+}
+
+// Name returns the name for the Fs implementation
+func (fs *mockCloudFs) Name() string {
+	return "mockCloudFs"
+}
+
+// IsUploadResumeSupported returns true if resuming uploads is supported
+func (*mockCloudFs) IsUploadResumeSupported() bool {
+	return true
+}
+
+// Create simulates an upload to a Cloud Storage backend
+func (fs *mockCloudFs) Create(name string, flag int) (vfs.File, *vfs.PipeWriter, func(), error) {
+	fs.createFlags = flag
+	r, w, err := pipeat.Pipe()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	var p *vfs.PipeWriter
+	if flag > 0 && flag&os.O_TRUNC == 0 {
+		p = vfs.NewPipeWriterAtOffset(w, fs.pendingSize)
+	} else {
+		fs.pendingSize = 0
+		p = vfs.NewPipeWriter(w)
+	}
+	go func() {
+		_, err := io.Copy(io.Discard, r)
+		r.Close() //nolint:errcheck
+		p.Done(err)
+	}()
+	return nil, p, func() {}, nil
+}
+
 func TestRemoveNonexistentQuotaScan(t *testing.T) {
 	assert.False(t, common.QuotaScans.RemoveUserQuotaScan("username"))
 }
@@ -162,7 +204,7 @@ func TestUploadResumeInvalidOffset(t *testing.T) {
 	}
 	fs := vfs.NewOsFs("", os.TempDir(), "")
 	conn := common.NewBaseConnection("", common.ProtocolSFTP, "", "", user)
-	baseTransfer := common.NewBaseTransfer(file, conn, nil, file.Name(), file.Name(), testfile, common.TransferUpload, 10, 0, 0, false, fs)
+	baseTransfer := common.NewBaseTransfer(file, conn, nil, file.Name(), file.Name(), testfile, common.TransferUpload, 10, 0, 0, false, fs, dataprovider.TransferQuota{})
 	transfer := newTransfer(baseTransfer, nil, nil, nil)
 	_, err = transfer.WriteAt([]byte("test"), 0)
 	assert.Error(t, err, "upload with invalid offset must fail")
@@ -193,7 +235,7 @@ func TestReadWriteErrors(t *testing.T) {
 	}
 	fs := vfs.NewOsFs("", os.TempDir(), "")
 	conn := common.NewBaseConnection("", common.ProtocolSFTP, "", "", user)
-	baseTransfer := common.NewBaseTransfer(file, conn, nil, file.Name(), file.Name(), testfile, common.TransferDownload, 0, 0, 0, false, fs)
+	baseTransfer := common.NewBaseTransfer(file, conn, nil, file.Name(), file.Name(), testfile, common.TransferDownload, 0, 0, 0, false, fs, dataprovider.TransferQuota{})
 	transfer := newTransfer(baseTransfer, nil, nil, nil)
 	err = file.Close()
 	assert.NoError(t, err)
@@ -207,7 +249,7 @@ func TestReadWriteErrors(t *testing.T) {
 
 	r, _, err := pipeat.Pipe()
 	assert.NoError(t, err)
-	baseTransfer = common.NewBaseTransfer(nil, conn, nil, file.Name(), file.Name(), testfile, common.TransferDownload, 0, 0, 0, false, fs)
+	baseTransfer = common.NewBaseTransfer(nil, conn, nil, file.Name(), file.Name(), testfile, common.TransferDownload, 0, 0, 0, false, fs, dataprovider.TransferQuota{})
 	transfer = newTransfer(baseTransfer, nil, r, nil)
 	err = transfer.Close()
 	assert.NoError(t, err)
@@ -217,7 +259,7 @@ func TestReadWriteErrors(t *testing.T) {
 	r, w, err := pipeat.Pipe()
 	assert.NoError(t, err)
 	pipeWriter := vfs.NewPipeWriter(w)
-	baseTransfer = common.NewBaseTransfer(nil, conn, nil, file.Name(), file.Name(), testfile, common.TransferDownload, 0, 0, 0, false, fs)
+	baseTransfer = common.NewBaseTransfer(nil, conn, nil, file.Name(), file.Name(), testfile, common.TransferDownload, 0, 0, 0, false, fs, dataprovider.TransferQuota{})
 	transfer = newTransfer(baseTransfer, pipeWriter, nil, nil)
 
 	err = r.Close()
@@ -264,7 +306,7 @@ func TestTransferCancelFn(t *testing.T) {
 	}
 	fs := vfs.NewOsFs("", os.TempDir(), "")
 	conn := common.NewBaseConnection("", common.ProtocolSFTP, "", "", user)
-	baseTransfer := common.NewBaseTransfer(file, conn, cancelFn, file.Name(), file.Name(), testfile, common.TransferDownload, 0, 0, 0, false, fs)
+	baseTransfer := common.NewBaseTransfer(file, conn, cancelFn, file.Name(), file.Name(), testfile, common.TransferDownload, 0, 0, 0, false, fs, dataprovider.TransferQuota{})
 	transfer := newTransfer(baseTransfer, nil, nil, nil)
 
 	errFake := errors.New("fake error, this will trigger cancelFn")
@@ -304,7 +346,7 @@ func TestUploadFiles(t *testing.T) {
 	if runtime.GOOS == osWindows {
 		missingFile = "missing\\relative\\file.txt"
 	}
-	_, err = c.handleSFTPUploadToNewFile(fs, ".", missingFile, "/missing", nil)
+	_, err = c.handleSFTPUploadToNewFile(fs, sftp.FileOpenFlags{}, ".", missingFile, "/missing", nil)
 	assert.Error(t, err, "upload new file in missing path must fail")
 
 	fs = newMockOsFs(nil, nil, false, "123", os.TempDir())
@@ -330,6 +372,54 @@ func TestUploadFiles(t *testing.T) {
 	common.Config.UploadMode = common.UploadModeAtomicWithResume
 }
 
+func TestUploadInterruptedCloudUpload(t *testing.T) {
+	u := dataprovider.User{}
+	c := Connection{
+		BaseConnection: common.NewBaseConnection("", common.ProtocolSFTP, "", "", u),
+	}
+	fs := &mockCloudFs{
+		Fs:          vfs.NewOsFs("123", os.TempDir(), ""),
+		pendingSize: 1024,
+	}
+	// the interrupted upload is resumed if the client asks to append
+	flags := sftp.FileOpenFlags{Write: true, Creat: true, Append: true}
+	tr, err := c.handleSFTPUploadToNewFile(fs, flags, "file.dat", "file.dat", "/file.dat", nil)
+	require.NoError(t, err)
+	upload := tr.(*transfer)
+	assert.Equal(t, os.O_WRONLY|os.O_CREATE, fs.createFlags)
+	assert.Equal(t, int64(1024), upload.MinWriteOffset)
+	assert.Equal(t, int64(0), upload.InitialSize)
+	_, err = upload.WriteAt([]byte("data"), 512)
+	assert.Error(t, err)
+	err = upload.Close()
+	assert.Error(t, err)
+	tr, err = c.handleSFTPUploadToNewFile(fs, flags, "file.dat", "file.dat", "/file.dat", nil)
+	require.NoError(t, err)
+	upload = tr.(*transfer)
+	_, err = upload.WriteAt([]byte("data"), 1024)
+	assert.NoError(t, err)
+	err = upload.Close()
+	assert.NoError(t, err)
+	// otherwise the upload starts from the beginning
+	for _, flags := range []sftp.FileOpenFlags{
+		{Write: true, Creat: true, Trunc: true},
+		{Write: true, Creat: true},
+	} {
+		fs.pendingSize = 1024
+		tr, err = c.handleSFTPUploadToNewFile(fs, flags, "file.dat", "file.dat", "/file.dat", nil)
+		require.NoError(t, err)
+		upload = tr.(*transfer)
+		assert.Equal(t, 0, fs.createFlags)
+		assert.Equal(t, int64(0), fs.pendingSize)
+		assert.Equal(t, int64(0), upload.MinWriteOffset)
+		_, err = upload.WriteAt([]byte("data"), 0)
+		assert.NoError(t, err)
+		err = upload.Close()
+		assert.NoError(t, err)
+	}
+	assert.Len(t, c.GetTransfers(), 0)
+}
+
 func TestWithInvalidHome(t *testing.T) {
 	u := dataprovider.User{}
 	u.HomeDir = "home_rel_path" //nolint:goconst
@@ -972,7 +1062,7 @@ func TestSystemCommandErrors(t *testing.T) {
 	}
 	sshCmd.connection.channel = &mockSSHChannel
 	baseTransfer := common.NewBaseTransfer(nil, sshCmd.connection.BaseConnection, nil, "", "", "", common.TransferDownload,
-		0, 0, 0, false, fs)
+		0, 0, 0, false, fs, dataprovider.TransferQuota{})
 	transfer := newTransfer(baseTransfer, nil, nil, nil)
 	destBuff := make([]byte, 65535)
 	dst := bytes.NewBuffer(destBuff)
@@ -1639,7 +1729,7 @@ func TestSCPUploadFiledata(t *testing.T) {
 	assert.NoError(t, err)
 
 	baseTransfer := common.NewBaseTransfer(file, scpCommand.connection.BaseConnection, nil, file.Name(), file.Name(),
-		"/"+testfile, common.TransferDownload, 0, 0, 0, true, fs)
+		"/"+testfile, common.TransferDownload, 0, 0, 0, true, fs, dataprovider.TransferQuota{})
 	transfer := newTransfer(baseTransfer, nil, nil, nil)
 
 	err = scpCommand.getUploadFileData(2, transfer)
@@ -1724,7 +1814,7 @@ func TestUploadError(t *testing.T) {
 	file, err := os.Create(fileTempName)
 	assert.NoError(t, err)
 	baseTransfer := common.NewBaseTransfer(file, connection.BaseConnection, nil, testfile, file.Name(),
-		testfile, common.TransferUpload, 0, 0, 0, true, fs)
+		testfile, common.TransferUpload, 0, 0, 0, true, fs, dataprovider.TransferQuota{})
 	transfer := newTransfer(baseTransfer, nil, nil, nil)
 
 	errFake := errors.New("fake error")
@@ -1784,7 +1874,7 @@ func TestTransferFailingReader(t *testing.T) {
 
 	r, _, err := pipeat.Pipe()
 	assert.NoError(t, err)
-	baseTransfer := common.NewBaseTransfer(nil, connection.BaseConnection, nil, fsPath, fsPath, filepath.Base(fsPath), common.TransferUpload, 0, 0, 0, false, fs)
+	baseTransfer := common.NewBaseTransfer(nil, connection.BaseConnection, nil, fsPath, fsPath, filepath.Base(fsPath), common.TransferUpload, 0, 0, 0, false, fs, dataprovider.TransferQuota{})
 	errRead := errors.New("read is not allowed")
 	tr := newTransfer(baseTransfer, nil, r, errRead)
 	_, err = tr.ReadAt(buf, 0)
@@ -2064,6 +2154,31 @@ func newFakeListener(err error) net.Listener {
 	}
 }
 
+func getSFTPOpenPacket(id uint32, name string) []byte {
+	data := marshalSFTPUint32(nil, id)
+	data = marshalSFTPString(data, name)
+	data = marshalSFTPUint32(data, 1)
+	data = marshalSFTPUint32(data, 0)
+	return append(append(marshalSFTPUint32(nil, uint32(len(data)+1)), sftpPacketOpen), data...)
+}
+
+func getSFTPHandlePacket(id uint32, handle string) []byte {
+	data := marshalSFTPString(marshalSFTPUint32(nil, id), handle)
+	return append(append(marshalSFTPUint32(nil, uint32(len(data)+1)), sftpPacketHandle), data...)
+}
+
+func getSFTPStatusPacket(id uint32) []byte {
+	data := marshalSFTPUint32(marshalSFTPUint32(nil, id), sftpStatusNoSuchFile)
+	data = marshalSFTPString(marshalSFTPString(data, "no such file"), "")
+	return append(append(marshalSFTPUint32(nil, uint32(len(data)+1)), sftpPacketStatus), data...)
+}
+
+func newTestExtensionsTransfer(connection *Connection, virtualPath string) *transfer {
+	baseTransfer := common.NewBaseTransfer(nil, connection.BaseConnection, nil, "", "", virtualPath,
+		common.TransferDownload, 0, 0, 0, false, vfs.NewOsFs("", os.TempDir(), ""), dataprovider.TransferQuota{})
+	return newTransfer(baseTransfer, nil, nil, nil)
+}
+
 func TestFolderPrefix(t *testing.T) {
 	c := Configuration{
 		FolderPrefix: "files",
@@ -2086,3 +2201,251 @@ func TestFolderPrefix(t *testing.T) {
 	c.checkFolderPrefix()
 	assert.Empty(t, c.FolderPrefix)
 }
+
+func TestSFTPExtensionsChannel(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	mockSSHChannel := &MockChannel{
+		Buffer: buf,
+	}
+	connection := &Connection{
+		BaseConnection: common.NewBaseConnection("", common.ProtocolSFTP, "", "", dataprovider.User{}),
+	}
+	channel := newSFTPExtensionsChannel(mockSSHChannel, connection)
+	assert.Equal(t, channel, connection.sftpExtensions)
+	// version packet written using multiple writes
+	versionPacket := []byte{0, 0, 0, 5, sftpPacketVersion, 0, 0, 0, 3}
+	for _, data := range [][]byte{versionPacket[:3], versionPacket[3:6], versionPacket[6:]} {
+		n, err := channel.Write(data)
+		assert.NoError(t, err)
+		assert.Equal(t, len(data), n)
+	}
+	packet, err := channel.readPacket()
+	assert.NoError(t, err)
+	assert.Equal(t, byte(sftpPacketVersion), packet[4])
+	assert.Contains(t, string(packet), sftpExtensionCopyFile)
+	assert.Contains(t, string(packet), sftpExtensionCopyData)
+	assert.Equal(t, 0, buf.Len())
+	assert.Len(t, channel.writeBuf, 0)
+	// extended packets are handled, the other ones are forwarded
+	data := marshalSFTPUint32(nil, 10)
+	data = marshalSFTPString(data, sftpExtensionCopyFile)
+	data = marshalSFTPString(data, "handle")
+	buf.Write(marshalSFTPUint32(nil, uint32(len(data)+1)))
+	buf.WriteByte(sftpPacketExtended)
+	buf.Write(data)
+	openPacket := getSFTPOpenPacket(11, "/file")
+	buf.Write(openPacket)
+	readBuf := make([]byte, len(openPacket))
+	n, err := io.ReadFull(channel, readBuf)
+	assert.NoError(t, err)
+	assert.Equal(t, len(openPacket), n)
+	assert.Equal(t, openPacket, readBuf)
+	if assert.Len(t, channel.openRequests, 1) {
+		assert.Equal(t, uint32(11), channel.openRequests[0].id)
+		assert.Equal(t, "/file", channel.openRequests[0].virtualPath)
+	}
+	// we should have the status packet for the extended request
+	packet, err = channel.readPacket()
+	assert.NoError(t, err)
+	assert.Equal(t, byte(sftpPacketStatus), packet[4])
+	id, data, err := unmarshalSFTPUint32(packet[5:])
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(10), id)
+	code, _, err := unmarshalSFTPUint32(data)
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(sftpStatusBadMessage), code)
+	// a handle for an open request is associated to the opened transfer
+	tr := newTestExtensionsTransfer(connection, "/file")
+	connection.addOpenedTransfer(tr)
+	_, err = channel.Write(getSFTPHandlePacket(11, "handle1"))
+	assert.NoError(t, err)
+	assert.Len(t, channel.openRequests, 0)
+	assert.Equal(t, tr, channel.getTransfer("handle1"))
+	buf.Reset()
+	// packets with an invalid length are forwarded to pkg/sftp
+	buf.Write(marshalSFTPUint32(nil, sftpMaxPacketLength+1))
+	n, err = channel.Read(readBuf)
+	assert.NoError(t, err)
+	assert.Equal(t, marshalSFTPUint32(nil, sftpMaxPacketLength+1), readBuf[:n])
+	_, err = channel.Read(readBuf)
+	assert.ErrorIs(t, err, io.EOF)
+	mockSSHChannel.WriteError = errors.New("write error")
+	_, err = channel.Write(openPacket)
+	assert.Error(t, err)
+}
+
+func TestSFTPExtensionsInterleavedOpens(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	connection := &Connection{
+		BaseConnection: common.NewBaseConnection("", common.ProtocolSFTP, "", "", dataprovider.User{}),
+	}
+	channel := newSFTPExtensionsChannel(&MockChannel{Buffer: buf}, connection)
+	for id, p := range []string{"/a", "/a", "/b", "/a", "/a"} {
+		channel.handleIncomingPacket(getSFTPOpenPacket(uint32(id+1), p))
+	}
+	assert.Len(t, channel.openRequests, 5)
+	// the first and the fourth requests fail, the handlers run before sending the responses
+	tr2 := newTestExtensionsTransfer(connection, "/a")
+	tr3 := newTestExtensionsTransfer(connection, "/b")
+	tr5 := newTestExtensionsTransfer(connection, "/a")
+	for _, tr := range []*transfer{tr2, tr3, tr5} {
+		connection.addOpenedTransfer(tr)
+	}
+	// a transfer without a pending open request is ignored
+	connection.addOpenedTransfer(newTestExtensionsTransfer(connection, "/c"))
+	for _, packet := range [][]byte{getSFTPStatusPacket(1), getSFTPHandlePacket(2, "h2"), getSFTPHandlePacket(3, "h3"),
+		getSFTPStatusPacket(4), getSFTPHandlePacket(5, "h5")} {
+		_, err := channel.Write(packet)
+		assert.NoError(t, err)
+	}
+	assert.Len(t, channel.openRequests, 0)
+	assert.Len(t, channel.handles, 3)
+	assert.Equal(t, tr2, channel.getTransfer("h2"))
+	assert.Equal(t, tr3, channel.getTransfer("h3"))
+	assert.Equal(t, tr5, channel.getTransfer("h5"))
+	// the failure response is sent before the handler for the next request is executed
+	channel.handleIncomingPacket(getSFTPOpenPacket(6, "/a"))
+	channel.handleIncomingPacket(getSFTPOpenPacket(7, "/a"))
+	_, err := channel.Write(getSFTPStatusPacket(6))
+	assert.NoError(t, err)
+	tr7 := newTestExtensionsTransfer(connection, "/a")
+	connection.addOpenedTransfer(tr7)
+	_, err = channel.Write(getSFTPHandlePacket(7, "h7"))
+	assert.NoError(t, err)
+	assert.Len(t, channel.openRequests, 0)
+	assert.Equal(t, tr7, channel.getTransfer("h7"))
+	// responses for other requests are ignored
+	_, err = channel.Write(getSFTPHandlePacket(8, "h8"))
+	assert.NoError(t, err)
+	assert.Nil(t, channel.getTransfer("h8"))
+}
+
+func TestSFTPExtensionsErrors(t *testing.T) {
+	connection := &Connection{
+		BaseConnection: common.NewBaseConnection("", common.ProtocolSFTP, "", "", dataprovider.User{}),
+		folderPrefix:   "/prefix",
+	}
+	channel := newSFTPExtensionsChannel(&MockChannel{Buffer: bytes.NewBuffer(nil)}, connection)
+	_, err := channel.resolvePath("/other")
+	assert.ErrorIs(t, err, sftp.ErrSSHFxPermissionDenied)
+	p, err := channel.resolvePath("/prefix/file")
+	assert.NoError(t, err)
+	assert.Equal(t, "/file", p)
+
+	err = channel.handleCopyFile(nil)
+	assert.ErrorIs(t, err, errSFTPBadMessage)
+	err = channel.handleCopyFile(marshalSFTPString(marshalSFTPString(nil, "/prefix/a"), "/prefix/b"))
+	assert.ErrorIs(t, err, errSFTPBadMessage)
+	err = channel.handleCopyFile(append(marshalSFTPString(marshalSFTPString(nil, "/a"), "/prefix/b"), 1))
+	assert.ErrorIs(t, err, sftp.ErrSSHFxPermissionDenied)
+	err = channel.handleCopyData(marshalSFTPString(nil, "handle"))
+	assert.ErrorIs(t, err, errSFTPBadMessage)
+	err = channel.handleFsync(nil)
+	assert.ErrorIs(t, err, errSFTPBadMessage)
+	err = channel.handleFsync(marshalSFTPString(nil, "handle"))
+	assert.ErrorIs(t, err, errSFTPInvalidHandle)
+	_, err = channel.handleExpandPath(nil)
+	assert.ErrorIs(t, err, errSFTPBadMessage)
+	_, err = channel.handleHomeDirectory(nil)
+	assert.ErrorIs(t, err, errSFTPBadMessage)
+	_, err = channel.handleUsersGroupsByID(nil)
+	assert.ErrorIs(t, err, errSFTPBadMessage)
+	_, err = channel.handleUsersGroupsByID(marshalSFTPString(nil, ""))
+	assert.ErrorIs(t, err, errSFTPBadMessage)
+	_, err = channel.handleUsersGroupsByID(marshalSFTPString(marshalSFTPString(nil, ""), "a"))
+	assert.ErrorIs(t, err, errSFTPBadMessage)
+	_, err = channel.handleUsersGroupsByID(marshalSFTPString(marshalSFTPString(nil,
+		string(make([]byte, 4*sftpUsersGroupsByIDMaxEntries))), string(make([]byte, 4))))
+	assert.ErrorIs(t, err, sftp.ErrSSHFxFailure)
+	reply := channel.handleLimits()
+	assert.Equal(t, uint64(sftpMaxWriteLength), binary.BigEndian.Uint64(reply[16:]))
+
+	for _, test := range []struct {
+		err  error
+		code uint32
+	}{
+		{nil, sftpStatusOK},
+		{io.EOF, sftpStatusEOF},
+		{os.ErrNotExist, sftpStatusNoSuchFile},
+		{sftp.ErrSSHFxPermissionDenied, sftpStatusPermissionDenied},
+		{sftp.ErrSSHFxOpUnsupported, sftpStatusOpUnsupported},
+		{errSFTPBadMessage, sftpStatusBadMessage},
+		{errSFTPInvalidHandle, sftpStatusFailure},
+	} {
+		code, _ := getSFTPStatusFromError(test.err)
+		assert.Equal(t, test.code, code, "unexpected code for error %v", test.err)
+	}
+	_, _, err = unmarshalSFTPString(marshalSFTPUint32(nil, 10))
+	assert.ErrorIs(t, err, errSFTPBadMessage)
+	_, _, err = unmarshalSFTPUint64([]byte{1})
+	assert.ErrorIs(t, err, errSFTPBadMessage)
+}
+
+func TestSFTPExtensionsUserInfo(t *testing.T) {
+	connection := &Connection{
+		BaseConnection: common.NewBaseConnection("", common.ProtocolSFTP, "", "", dataprovider.User{
+			BaseUser: sdk.BaseUser{
+				Username: "test_user",
+				UID:      2000,
+				GID:      2001,
+			},
+		}),
+		folderPrefix: "/prefix/files",
+	}
+	channel := newSFTPExtensionsChannel(&MockChannel{Buffer: bytes.NewBuffer(nil)}, connection)
+	// the folder prefix is the home directory
+	name, err := channel.handleHomeDirectory(marshalSFTPString(nil, ""))
+	assert.NoError(t, err)
+	assert.Equal(t, "/prefix/files", name)
+	name, err = channel.handleHomeDirectory(marshalSFTPString(nil, "test_user"))
+	assert.NoError(t, err)
+	assert.Equal(t, "/prefix/files", name)
+	_, err = channel.handleHomeDirectory(marshalSFTPString(nil, "other_user"))
+	assert.ErrorIs(t, err, sftp.ErrSSHFxNoSuchFile)
+	for _, test := range []struct {
+		path     string
+		expanded string
+	}{
+		{"~", "/prefix/files"},
+		{"~/dir", "/prefix/files/dir"},
+		{"~test_user/dir", "/prefix/files/dir"},
+		{"dir/../file", "/prefix/files/file"},
+		{"/prefix/files/dir", "/prefix/files/dir"},
+	} {
+		name, err = channel.handleExpandPath(marshalSFTPString(nil, test.path))
+		assert.NoError(t, err)
+		assert.Equal(t, test.expanded, name, "unexpected expansion for %#v", test.path)
+	}
+	// only the configured uid and gid are resolved, the host accounts are never used
+	uids := marshalSFTPUint32(marshalSFTPUint32(marshalSFTPUint32(nil, 2000), 0), uint32(os.Getuid()))
+	gids := marshalSFTPUint32(marshalSFTPUint32(nil, 2001), 2000)
+	reply, err := channel.handleUsersGroupsByID(marshalSFTPString(marshalSFTPString(nil, string(uids)), string(gids)))
+	assert.NoError(t, err)
+	usernames, reply, err := unmarshalSFTPString(reply)
+	assert.NoError(t, err)
+	groupnames, _, err := unmarshalSFTPString(reply)
+	assert.NoError(t, err)
+	for _, test := range []struct {
+		names    string
+		expected []string
+	}{
+		{usernames, []string{"test_user", "", ""}},
+		{groupnames, []string{"test_user", ""}},
+	} {
+		data := []byte(test.names)
+		for _, expected := range test.expected {
+			name, data, err = unmarshalSFTPString(data)
+			assert.NoError(t, err)
+			assert.Equal(t, expected, name)
+		}
+		assert.Len(t, data, 0)
+	}
+	// no configured ids
+	connection.User.UID = 0
+	connection.User.GID = 0
+	reply, err = channel.handleUsersGroupsByID(marshalSFTPString(marshalSFTPString(nil,
+		string(marshalSFTPUint32(nil, 0))), string(marshalSFTPUint32(nil, 0))))
+	assert.NoError(t, err)
+	assert.Equal(t, marshalSFTPString(marshalSFTPString(nil, string(marshalSFTPString(nil, ""))),
+		string(marshalSFTPString(nil, ""))), reply)
+}
@@ -33,6 +33,8 @@ const (
 )
 
 var (
+	// extensions handled by pkg/sftp, the extensions not supported by pkg/sftp
+	// are handled in sftpExtensionsChannel, see sftpChannelExtensions
 	sftpExtensions = []string{"statvfs@openssh.com"}
 )
 
@@ -170,7 +172,7 @@ func (c *Configuration) Initialize(configDir string) error {
 		},
 		NextAuthMethodsCallback: func(conn ssh.ConnMetadata) []string {
 			var nextMethods []string
-			user, err := dataprovider.UserExists(conn.User())
+			user, err := dataprovider.GetUserWithGroupSettings(conn.User())
 			if err == nil {
 				nextMethods = user.GetNextAuthMethods(conn.PartialSuccessMethods(), c.PasswordAuthentication)
 			}
@@ -490,7 +492,8 @@ func (c *Configuration) handleSftpConnection(channel ssh.Channel, connection *Co
 	defer common.Connections.Remove(connection.GetID())
 
 	// Create the server instance for the channel using the handler we created above.
-	server := sftp.NewRequestServer(channel, c.createHandlers(connection), sftp.WithRSAllocator())
+	server := sftp.NewRequestServer(newSFTPExtensionsChannel(channel, connection), c.createHandlers(connection),
+		sftp.WithRSAllocator())
 
 	defer server.Close()
 	if err := server.Serve(); err == io.EOF {
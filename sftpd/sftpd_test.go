@@ -9,6 +9,7 @@ import (
 	"encoding/base64"
 	"encoding/binary"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"hash"
 	"io"
@@ -505,6 +506,87 @@ func TestBasicSFTPFsHandling(t *testing.T) {
 	assert.NoError(t, err)
 }
 
+func TestSFTPFsEncryption(t *testing.T) {
+	usePubKey := true
+	baseUser, _, err := httpdtest.AddUser(getTestUser(usePubKey), http.StatusCreated)
+	assert.NoError(t, err)
+	for _, bufferSize := range []int64{0, 2} {
+		u := getTestSFTPUser(usePubKey)
+		u.QuotaSize = 6553600
+		u.FsConfig.SFTPConfig.BufferSize = bufferSize
+		u.FsConfig.Encryption.Passphrase = kms.NewPlainSecret("sftp encryption passphrase")
+		user, _, err := httpdtest.AddUser(u, http.StatusCreated)
+		assert.NoError(t, err)
+		conn, client, err := getSftpClient(user, usePubKey)
+		if assert.NoError(t, err) {
+			testFilePath := filepath.Join(homeBasePath, testFileName)
+			testFileSize := int64(200000)
+			err = createTestFile(testFilePath, testFileSize)
+			assert.NoError(t, err)
+			err = sftpUploadFile(testFilePath, testFileName, testFileSize, client)
+			assert.NoError(t, err)
+			// the file is stored encrypted on the remote SFTP server
+			info, err := os.Stat(filepath.Join(baseUser.GetHomeDir(), testFileName))
+			if assert.NoError(t, err) {
+				assert.Greater(t, info.Size(), testFileSize)
+			}
+			info, err = client.Stat(testFileName)
+			if assert.NoError(t, err) {
+				assert.Equal(t, testFileSize, info.Size())
+			}
+			localDownloadPath := filepath.Join(homeBasePath, testDLFileName)
+			err = sftpDownloadFile(testFileName, localDownloadPath, testFileSize, client)
+			assert.NoError(t, err)
+			expected, err := os.ReadFile(testFilePath)
+			assert.NoError(t, err)
+			downloaded, err := os.ReadFile(localDownloadPath)
+			assert.NoError(t, err)
+			assert.Equal(t, expected, downloaded)
+			// ranged read
+			f, err := client.Open(testFileName)
+			if assert.NoError(t, err) {
+				buf := make([]byte, 1000)
+				n, err := f.ReadAt(buf, 131000)
+				assert.NoError(t, err)
+				assert.Equal(t, expected[131000:131000+n], buf[:n])
+				err = f.Close()
+				assert.NoError(t, err)
+			}
+			// the quota is computed on the decrypted size
+			user, _, err = httpdtest.GetUserByUsername(user.Username, http.StatusOK)
+			assert.NoError(t, err)
+			assert.Equal(t, 1, user.UsedQuotaFiles)
+			assert.Equal(t, testFileSize, user.UsedQuotaSize)
+			_, err = httpdtest.StartQuotaScan(user, http.StatusAccepted)
+			assert.NoError(t, err)
+			assert.Eventually(t, func() bool {
+				scans, _, err := httpdtest.GetQuotaScans(http.StatusOK)
+				if err == nil {
+					return len(scans) == 0
+				}
+				return false
+			}, 1*time.Second, 50*time.Millisecond)
+			user, _, err = httpdtest.GetUserByUsername(user.Username, http.StatusOK)
+			assert.NoError(t, err)
+			assert.Equal(t, 1, user.UsedQuotaFiles)
+			assert.Equal(t, testFileSize, user.UsedQuotaSize)
+
+			err = os.Remove(testFilePath)
+			assert.NoError(t, err)
+			err = os.Remove(localDownloadPath)
+			assert.NoError(t, err)
+			conn.Close()
+			client.Close()
+		}
+		_, err = httpdtest.RemoveUser(user, http.StatusOK)
+		assert.NoError(t, err)
+		err = os.RemoveAll(baseUser.GetHomeDir())
+		assert.NoError(t, err)
+	}
+	_, err = httpdtest.RemoveUser(baseUser, http.StatusOK)
+	assert.NoError(t, err)
+}
+
 func TestFolderPrefix(t *testing.T) {
 	usePubKey := true
 	u := getTestUser(usePubKey)
@@ -1963,6 +2045,76 @@ func TestLoginUserExpiration(t *testing.T) {
 	assert.NoError(t, err)
 }
 
+func TestLoginPasswordChangeRequired(t *testing.T) {
+	u := getTestUser(true)
+	u.Password = defaultPassword
+	u.Filters.RequirePasswordChange = true
+	user, _, err := httpdtest.AddUser(u, http.StatusCreated)
+	assert.NoError(t, err)
+	conn, client, err := getSftpClient(user, false)
+	if !assert.Error(t, err, "password login must fail if a password change is required") {
+		client.Close()
+		conn.Close()
+	}
+	// public key login is still allowed
+	conn, client, err = getSftpClient(user, true)
+	if assert.NoError(t, err) {
+		defer conn.Close()
+		defer client.Close()
+		assert.NoError(t, checkBasicSFTP(client))
+	}
+	_, err = httpdtest.RemoveUser(user, http.StatusOK)
+	assert.NoError(t, err)
+	err = os.RemoveAll(user.GetHomeDir())
+	assert.NoError(t, err)
+}
+
+func TestLoginAccountLockout(t *testing.T) {
+	assert.NoError(t, dataprovider.Close())
+	assert.NoError(t, config.LoadConfig(configDir, ""))
+	providerConf := config.GetProviderConf()
+	providerConf.AccountLockout.Enabled = true
+	providerConf.AccountLockout.MaxFailures = 2
+	assert.NoError(t, dataprovider.Initialize(providerConf, configDir, true))
+
+	u := getTestUser(true)
+	u.Password = defaultPassword
+	user, _, err := httpdtest.AddUser(u, http.StatusCreated)
+	assert.NoError(t, err)
+	user.Password = "wrong password"
+	for i := 0; i < 2; i++ {
+		conn, client, err := getSftpClient(user, false)
+		if !assert.Error(t, err, "login with wrong password must fail") {
+			client.Close()
+			conn.Close()
+		}
+	}
+	// the account is locked, public key login is denied too
+	conn, client, err := getSftpClient(user, true)
+	if !assert.Error(t, err, "login to a locked account must fail") {
+		client.Close()
+		conn.Close()
+	}
+	_, err = httpdtest.RemoveAccountLockout(dataprovider.AccountTypeUser, user.Username, http.StatusOK)
+	assert.NoError(t, err)
+	conn, client, err = getSftpClient(user, true)
+	if assert.NoError(t, err) {
+		defer conn.Close()
+		defer client.Close()
+		assert.NoError(t, checkBasicSFTP(client))
+	}
+
+	_, err = httpdtest.RemoveUser(user, http.StatusOK)
+	assert.NoError(t, err)
+	err = os.RemoveAll(user.GetHomeDir())
+	assert.NoError(t, err)
+
+	assert.NoError(t, dataprovider.Close())
+	assert.NoError(t, config.LoadConfig(configDir, ""))
+	providerConf = config.GetProviderConf()
+	assert.NoError(t, dataprovider.Initialize(providerConf, configDir, true))
+}
+
 func TestLoginWithDatabaseCredentials(t *testing.T) {
 	usePubKey := true
 	u := getTestUser(usePubKey)
@@ -7054,10 +7206,11 @@ func TestUserAllowedLoginMethods(t *testing.T) {
 		dataprovider.SSHLoginMethodKeyboardInteractive,
 	}
 	allowedMethods = user.GetAllowedLoginMethods()
-	assert.Equal(t, 4, len(allowedMethods))
+	assert.Equal(t, 5, len(allowedMethods))
 
 	assert.True(t, util.IsStringInSlice(dataprovider.SSHLoginMethodKeyAndKeyboardInt, allowedMethods))
 	assert.True(t, util.IsStringInSlice(dataprovider.SSHLoginMethodKeyAndPassword, allowedMethods))
+	assert.True(t, util.IsStringInSlice(dataprovider.LoginMethodS3AccessKey, allowedMethods))
 }
 
 func TestUserPartialAuth(t *testing.T) {
@@ -7376,6 +7529,454 @@ func TestStatVFSCloudBackend(t *testing.T) {
 	assert.NoError(t, err)
 }
 
+func TestCopyExtensions(t *testing.T) {
+	usePubKey := false
+	u := getTestUser(usePubKey)
+	u.QuotaFiles = 100
+	u.Permissions["/sub"] = []string{dataprovider.PermListItems, dataprovider.PermDownload}
+	user, _, err := httpdtest.AddUser(u, http.StatusCreated)
+	assert.NoError(t, err)
+	testFileSize := int64(65535)
+	testFilePath := filepath.Join(homeBasePath, testFileName)
+	err = createTestFile(testFilePath, testFileSize)
+	assert.NoError(t, err)
+	conn, client, err := getSftpClient(user, usePubKey)
+	if assert.NoError(t, err) {
+		defer conn.Close()
+		defer client.Close()
+
+		for _, extension := range []string{"copy-file", "copy-data"} {
+			_, ok := client.HasExtension(extension)
+			assert.True(t, ok, "extension %v not advertised", extension)
+		}
+		err = sftpUploadFile(testFilePath, testFileName, testFileSize, client)
+		assert.NoError(t, err)
+		err = client.Mkdir("sub")
+		assert.NoError(t, err)
+	}
+	conn, rawClient, err := getRawSFTPClient(user, usePubKey)
+	if assert.NoError(t, err) {
+		defer conn.Close()
+		defer rawClient.Close()
+
+		copyFile := func(source, target string, overwrite bool) uint32 {
+			data := marshalRawSFTPString(nil, "copy-file")
+			data = marshalRawSFTPString(data, source)
+			data = marshalRawSFTPString(data, target)
+			if overwrite {
+				data = append(data, 1)
+			} else {
+				data = append(data, 0)
+			}
+			code, err := rawClient.sendExtendedRequest(data)
+			assert.NoError(t, err)
+			return code
+		}
+		assert.Equal(t, uint32(sftp.ErrSSHFxOk), copyFile(testFileName, testFileName+"_copy", false))
+		assert.Equal(t, uint32(sftp.ErrSSHFxFailure), copyFile(testFileName, testFileName+"_copy", false))
+		assert.Equal(t, uint32(sftp.ErrSSHFxOk), copyFile(testFileName, testFileName+"_copy", true))
+		assert.Equal(t, uint32(sftp.ErrSSHFxNoSuchFile), copyFile("missing", testFileName+"_copy1", true))
+		assert.Equal(t, uint32(sftp.ErrSSHFxPermissionDenied), copyFile(testFileName, "/sub/"+testFileName, false))
+		assert.Equal(t, uint32(sftp.ErrSSHFxOpUnsupported), copyFile(testFileName, testFileName, true))
+		assert.Equal(t, uint32(sftp.ErrSSHFxOpUnsupported), copyFile("/sub", "/sub1", true))
+
+		info, err := os.Stat(filepath.Join(user.GetHomeDir(), testFileName+"_copy"))
+		if assert.NoError(t, err) {
+			assert.Equal(t, testFileSize, info.Size())
+		}
+		readHandle, err := rawClient.open(testFileName, sftpFlagRead)
+		assert.NoError(t, err)
+		writeHandle, err := rawClient.open(testFileName+"_data", sftpFlagWrite|sftpFlagCreate|sftpFlagTruncate)
+		assert.NoError(t, err)
+
+		copyData := func(readHandle string, readOffset, readLength uint64, writeHandle string, writeOffset uint64) uint32 {
+			data := marshalRawSFTPString(nil, "copy-data")
+			data = marshalRawSFTPString(data, readHandle)
+			data = marshalRawSFTPUint64(data, readOffset)
+			data = marshalRawSFTPUint64(data, readLength)
+			data = marshalRawSFTPString(data, writeHandle)
+			data = marshalRawSFTPUint64(data, writeOffset)
+			code, err := rawClient.sendExtendedRequest(data)
+			assert.NoError(t, err)
+			return code
+		}
+		assert.Equal(t, uint32(sftp.ErrSSHFxOk), copyData(readHandle, 0, 1000, writeHandle, 0))
+		assert.Equal(t, uint32(sftp.ErrSSHFxOk), copyData(readHandle, 1000, 0, writeHandle, 1000))
+		assert.Equal(t, uint32(sftp.ErrSSHFxEOF), copyData(readHandle, uint64(testFileSize)-10, 100, writeHandle, 0))
+		assert.Equal(t, uint32(sftp.ErrSSHFxFailure), copyData(writeHandle, 0, 0, writeHandle, 10))
+		assert.Equal(t, uint32(sftp.ErrSSHFxFailure), copyData("invalid", 0, 0, writeHandle, 0))
+		err = rawClient.close(readHandle)
+		assert.NoError(t, err)
+		err = rawClient.close(writeHandle)
+		assert.NoError(t, err)
+		// closed handles cannot be used
+		assert.Equal(t, uint32(sftp.ErrSSHFxFailure), copyData(readHandle, 0, 0, writeHandle, 0))
+
+		info, err = os.Stat(filepath.Join(user.GetHomeDir(), testFileName+"_data"))
+		if assert.NoError(t, err) {
+			assert.Equal(t, testFileSize, info.Size())
+		}
+	}
+	user, _, err = httpdtest.GetUserByUsername(user.Username, http.StatusOK)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, user.UsedQuotaFiles)
+	assert.Equal(t, 3*testFileSize, user.UsedQuotaSize)
+
+	err = os.Remove(testFilePath)
+	assert.NoError(t, err)
+	_, err = httpdtest.RemoveUser(user, http.StatusOK)
+	assert.NoError(t, err)
+	err = os.RemoveAll(user.GetHomeDir())
+	assert.NoError(t, err)
+}
+
+func TestCopyDataInterleavedOpens(t *testing.T) {
+	usePubKey := true
+	user, _, err := httpdtest.AddUser(getTestUser(usePubKey), http.StatusCreated)
+	assert.NoError(t, err)
+	testFileSize := int64(65535)
+	testFilePath := filepath.Join(homeBasePath, testFileName)
+	err = createTestFile(testFilePath, testFileSize)
+	assert.NoError(t, err)
+	conn, client, err := getSftpClient(user, usePubKey)
+	if assert.NoError(t, err) {
+		defer conn.Close()
+		defer client.Close()
+
+		err = sftpUploadFile(testFilePath, testFileName, testFileSize, client)
+		assert.NoError(t, err)
+	}
+	conn, rawClient, err := getRawSFTPClient(user, usePubKey)
+	if assert.NoError(t, err) {
+		defer conn.Close()
+		defer rawClient.Close()
+
+		// failed and successful opens, also for the same path, are sent before reading the responses
+		handles, err := rawClient.openPipelined([]string{"missing", testFileName, testFileName + "_data", "missing",
+			"/missing/" + testFileName, testFileName + "_data1", testFileName},
+			[]uint32{sftpFlagRead, sftpFlagRead, sftpFlagWrite | sftpFlagCreate | sftpFlagTruncate, sftpFlagRead,
+				sftpFlagWrite | sftpFlagCreate, sftpFlagWrite | sftpFlagCreate | sftpFlagTruncate, sftpFlagRead})
+		if assert.NoError(t, err) {
+			for _, idx := range []int{0, 3, 4} {
+				assert.Empty(t, handles[idx])
+			}
+			for _, idx := range []int{1, 2, 5, 6} {
+				assert.NotEmpty(t, handles[idx])
+			}
+			copyData := func(readHandle, writeHandle string) uint32 {
+				data := marshalRawSFTPString(nil, "copy-data")
+				data = marshalRawSFTPString(data, readHandle)
+				data = marshalRawSFTPUint64(data, 0)
+				data = marshalRawSFTPUint64(data, 0)
+				data = marshalRawSFTPString(data, writeHandle)
+				data = marshalRawSFTPUint64(data, 0)
+				code, err := rawClient.sendExtendedRequest(data)
+				assert.NoError(t, err)
+				return code
+			}
+			assert.Equal(t, uint32(sftp.ErrSSHFxOk), copyData(handles[1], handles[2]))
+			assert.Equal(t, uint32(sftp.ErrSSHFxOk), copyData(handles[6], handles[5]))
+			assert.Equal(t, uint32(sftp.ErrSSHFxFailure), copyData(handles[0], handles[5]))
+			for _, idx := range []int{1, 2, 5, 6} {
+				err = rawClient.close(handles[idx])
+				assert.NoError(t, err)
+			}
+		}
+	}
+	for _, name := range []string{testFileName + "_data", testFileName + "_data1"} {
+		info, err := os.Stat(filepath.Join(user.GetHomeDir(), name))
+		if assert.NoError(t, err) {
+			assert.Equal(t, testFileSize, info.Size())
+		}
+	}
+
+	err = os.Remove(testFilePath)
+	assert.NoError(t, err)
+	_, err = httpdtest.RemoveUser(user, http.StatusOK)
+	assert.NoError(t, err)
+	err = os.RemoveAll(user.GetHomeDir())
+	assert.NoError(t, err)
+}
+
+func TestCopyExtensionsVirtualFolder(t *testing.T) {
+	usePubKey := false
+	mappedPath := filepath.Join(os.TempDir(), "vdir")
+	folderName := filepath.Base(mappedPath)
+	vdirPath := "/vdir"
+	u := getTestUser(usePubKey)
+	u.QuotaFiles = 100
+	u.VirtualFolders = append(u.VirtualFolders, vfs.VirtualFolder{
+		BaseVirtualFolder: vfs.BaseVirtualFolder{
+			Name:       folderName,
+			MappedPath: mappedPath,
+		},
+		VirtualPath: vdirPath,
+		QuotaFiles:  100,
+	})
+	user, _, err := httpdtest.AddUser(u, http.StatusCreated)
+	assert.NoError(t, err)
+	testFileSize := int64(131072)
+	testFilePath := filepath.Join(homeBasePath, testFileName)
+	err = createTestFile(testFilePath, testFileSize)
+	assert.NoError(t, err)
+	conn, client, err := getSftpClient(user, usePubKey)
+	if assert.NoError(t, err) {
+		defer conn.Close()
+		defer client.Close()
+
+		err = sftpUploadFile(testFilePath, testFileName, testFileSize, client)
+		assert.NoError(t, err)
+	}
+	conn, rawClient, err := getRawSFTPClient(user, usePubKey)
+	if assert.NoError(t, err) {
+		defer conn.Close()
+		defer rawClient.Close()
+
+		copyFile := func(source, target string) uint32 {
+			data := marshalRawSFTPString(nil, "copy-file")
+			data = marshalRawSFTPString(data, source)
+			data = marshalRawSFTPString(data, target)
+			data = append(data, 1)
+			code, err := rawClient.sendExtendedRequest(data)
+			assert.NoError(t, err)
+			return code
+		}
+		assert.Equal(t, uint32(sftp.ErrSSHFxOk), copyFile(testFileName, path.Join(vdirPath, testFileName)))
+		assert.Equal(t, uint32(sftp.ErrSSHFxOk), copyFile(path.Join(vdirPath, testFileName),
+			path.Join(vdirPath, testFileName+"_copy")))
+		assert.Equal(t, uint32(sftp.ErrSSHFxOk), copyFile(path.Join(vdirPath, testFileName+"_copy"),
+			testFileName+"_copy"))
+		assert.Equal(t, uint32(sftp.ErrSSHFxPermissionDenied), copyFile(testFileName, vdirPath))
+	}
+	for _, p := range []string{filepath.Join(mappedPath, testFileName), filepath.Join(mappedPath, testFileName+"_copy"),
+		filepath.Join(user.GetHomeDir(), testFileName+"_copy")} {
+		info, err := os.Stat(p)
+		if assert.NoError(t, err) {
+			assert.Equal(t, testFileSize, info.Size())
+		}
+	}
+	user, _, err = httpdtest.GetUserByUsername(user.Username, http.StatusOK)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, user.UsedQuotaFiles)
+	assert.Equal(t, 2*testFileSize, user.UsedQuotaSize)
+	folder, _, err := httpdtest.GetFolderByName(folderName, http.StatusOK)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, folder.UsedQuotaFiles)
+	assert.Equal(t, 2*testFileSize, folder.UsedQuotaSize)
+
+	err = os.Remove(testFilePath)
+	assert.NoError(t, err)
+	_, err = httpdtest.RemoveUser(user, http.StatusOK)
+	assert.NoError(t, err)
+	_, err = httpdtest.RemoveFolder(vfs.BaseVirtualFolder{Name: folderName}, http.StatusOK)
+	assert.NoError(t, err)
+	err = os.RemoveAll(user.GetHomeDir())
+	assert.NoError(t, err)
+	err = os.RemoveAll(mappedPath)
+	assert.NoError(t, err)
+}
+
+func TestOpenSSHExtensions(t *testing.T) {
+	usePubKey := true
+	u := getTestUser(usePubKey)
+	u.Filters.MaxUploadFileSize = 1024
+	user, _, err := httpdtest.AddUser(u, http.StatusCreated)
+	assert.NoError(t, err)
+	conn, client, err := getSftpClient(user, usePubKey)
+	if assert.NoError(t, err) {
+		defer conn.Close()
+		defer client.Close()
+
+		for _, extension := range []string{"expand-path@openssh.com", "home-directory", "limits@openssh.com",
+			"fsync@openssh.com", "users-groups-by-id@openssh.com"} {
+			_, ok := client.HasExtension(extension)
+			assert.True(t, ok, "extension %v not advertised", extension)
+		}
+		f, err := client.Create(testFileName)
+		if assert.NoError(t, err) {
+			_, err = f.Write([]byte("test data"))
+			assert.NoError(t, err)
+			err = f.Sync()
+			assert.NoError(t, err)
+			err = f.Close()
+			assert.NoError(t, err)
+		}
+		f, err = client.Open(testFileName)
+		if assert.NoError(t, err) {
+			err = f.Sync()
+			assert.NoError(t, err)
+			err = f.Close()
+			assert.NoError(t, err)
+		}
+	}
+	conn, rawClient, err := getRawSFTPClient(user, usePubKey)
+	if assert.NoError(t, err) {
+		defer conn.Close()
+		defer rawClient.Close()
+
+		expandPath := func(p string) (string, uint32) {
+			data := marshalRawSFTPString(nil, "expand-path@openssh.com")
+			data = marshalRawSFTPString(data, p)
+			name, code, err := rawClient.sendNameRequest(data)
+			assert.NoError(t, err)
+			return name, code
+		}
+		for _, test := range []struct {
+			path     string
+			expanded string
+		}{
+			{"~", "/"},
+			{"~/dir/file", "/dir/file"},
+			{"~" + user.Username + "/dir", "/dir"},
+			{"dir/../file", "/file"},
+			{".", "/"},
+			{"/dir/./file", "/dir/file"},
+			{"/../file", "/file"},
+		} {
+			name, code := expandPath(test.path)
+			assert.Equal(t, uint32(sftp.ErrSSHFxOk), code)
+			assert.Equal(t, test.expanded, name, "unexpected expansion for %#v", test.path)
+		}
+		_, code := expandPath("~otheruser/dir")
+		assert.Equal(t, uint32(sftp.ErrSSHFxNoSuchFile), code)
+
+		data := marshalRawSFTPString(nil, "home-directory")
+		data = marshalRawSFTPString(data, user.Username)
+		name, code, err := rawClient.sendNameRequest(data)
+		assert.NoError(t, err)
+		assert.Equal(t, uint32(sftp.ErrSSHFxOk), code)
+		assert.Equal(t, "/", name)
+		data = marshalRawSFTPString(nil, "home-directory")
+		data = marshalRawSFTPString(data, "otheruser")
+		_, code, err = rawClient.sendNameRequest(data)
+		assert.NoError(t, err)
+		assert.Equal(t, uint32(sftp.ErrSSHFxNoSuchFile), code)
+
+		reply, code, err := rawClient.sendExtendedReplyRequest(marshalRawSFTPString(nil, "limits@openssh.com"))
+		assert.NoError(t, err)
+		assert.Equal(t, uint32(sftp.ErrSSHFxOk), code)
+		if assert.Len(t, reply, 32) {
+			assert.Equal(t, uint64(256*1024), binary.BigEndian.Uint64(reply))
+			assert.Equal(t, uint64(32768), binary.BigEndian.Uint64(reply[8:]))
+			assert.Equal(t, uint64(user.Filters.MaxUploadFileSize), binary.BigEndian.Uint64(reply[16:]))
+			assert.Equal(t, uint64(0), binary.BigEndian.Uint64(reply[24:]))
+		}
+
+		// the host accounts are not resolved, no uid/gid is configured for this user
+		uid := 0
+		gid := 0
+		if runtime.GOOS != osWindows {
+			uid = os.Getuid()
+			gid = os.Getgid()
+		}
+		data = marshalRawSFTPString(nil, "users-groups-by-id@openssh.com")
+		data = marshalRawSFTPString(data, string(marshalRawSFTPUint32(marshalRawSFTPUint32(nil, uint32(uid)), 4294967290)))
+		data = marshalRawSFTPString(data, string(marshalRawSFTPUint32(nil, uint32(gid))))
+		reply, code, err = rawClient.sendExtendedReplyRequest(data)
+		assert.NoError(t, err)
+		assert.Equal(t, uint32(sftp.ErrSSHFxOk), code)
+		usernames, reply, err := unmarshalRawSFTPString(reply)
+		assert.NoError(t, err)
+		groupnames, _, err := unmarshalRawSFTPString(reply)
+		assert.NoError(t, err)
+		username, b, err := unmarshalRawSFTPString([]byte(usernames))
+		assert.NoError(t, err)
+		assert.Empty(t, username)
+		missingUsername, _, err := unmarshalRawSFTPString(b)
+		assert.NoError(t, err)
+		assert.Empty(t, missingUsername)
+		groupname, _, err := unmarshalRawSFTPString([]byte(groupnames))
+		assert.NoError(t, err)
+		assert.Empty(t, groupname)
+		// invalid ids list
+		data = marshalRawSFTPString(nil, "users-groups-by-id@openssh.com")
+		data = marshalRawSFTPString(data, "abc")
+		data = marshalRawSFTPString(data, "")
+		_, code, err = rawClient.sendExtendedReplyRequest(data)
+		assert.NoError(t, err)
+		assert.Equal(t, uint32(sftp.ErrSSHFxBadMessage), code)
+	}
+	_, err = httpdtest.RemoveUser(user, http.StatusOK)
+	assert.NoError(t, err)
+	err = os.RemoveAll(user.GetHomeDir())
+	assert.NoError(t, err)
+}
+
+func TestFsyncCryptFs(t *testing.T) {
+	usePubKey := false
+	u := getTestUser(usePubKey)
+	u.FsConfig.Provider = sdk.CryptedFilesystemProvider
+	u.FsConfig.CryptConfig.Passphrase = kms.NewPlainSecret(defaultPassword)
+	user, _, err := httpdtest.AddUser(u, http.StatusCreated)
+	assert.NoError(t, err)
+	conn, client, err := getSftpClient(user, usePubKey)
+	if assert.NoError(t, err) {
+		defer conn.Close()
+		defer client.Close()
+
+		f, err := client.Create(testFileName)
+		if assert.NoError(t, err) {
+			_, err = f.Write([]byte("test data"))
+			assert.NoError(t, err)
+			// the encrypted file is committed on close
+			err = f.Sync()
+			assert.Error(t, err)
+			err = f.Close()
+			assert.NoError(t, err)
+		}
+		info, err := client.Stat(testFileName)
+		if assert.NoError(t, err) {
+			assert.Equal(t, int64(9), info.Size())
+		}
+	}
+	_, err = httpdtest.RemoveUser(user, http.StatusOK)
+	assert.NoError(t, err)
+	err = os.RemoveAll(user.GetHomeDir())
+	assert.NoError(t, err)
+}
+
+func TestCopyExtensionsQuota(t *testing.T) {
+	usePubKey := true
+	u := getTestUser(usePubKey)
+	u.QuotaSize = 100000
+	user, _, err := httpdtest.AddUser(u, http.StatusCreated)
+	assert.NoError(t, err)
+	testFileSize := int64(65535)
+	testFilePath := filepath.Join(homeBasePath, testFileName)
+	err = createTestFile(testFilePath, testFileSize)
+	assert.NoError(t, err)
+	conn, client, err := getSftpClient(user, usePubKey)
+	if assert.NoError(t, err) {
+		defer conn.Close()
+		defer client.Close()
+
+		err = sftpUploadFile(testFilePath, testFileName, testFileSize, client)
+		assert.NoError(t, err)
+	}
+	conn, rawClient, err := getRawSFTPClient(user, usePubKey)
+	if assert.NoError(t, err) {
+		defer conn.Close()
+		defer rawClient.Close()
+
+		data := marshalRawSFTPString(nil, "copy-file")
+		data = marshalRawSFTPString(data, testFileName)
+		data = marshalRawSFTPString(data, testFileName+"_copy")
+		data = append(data, 0)
+		code, err := rawClient.sendExtendedRequest(data)
+		assert.NoError(t, err)
+		assert.Equal(t, uint32(sftp.ErrSSHFxFailure), code)
+	}
+	_, err = os.Stat(filepath.Join(user.GetHomeDir(), testFileName+"_copy"))
+	assert.True(t, os.IsNotExist(err))
+
+	err = os.Remove(testFilePath)
+	assert.NoError(t, err)
+	_, err = httpdtest.RemoveUser(user, http.StatusOK)
+	assert.NoError(t, err)
+	err = os.RemoveAll(user.GetHomeDir())
+	assert.NoError(t, err)
+}
+
 func TestSSHCommands(t *testing.T) {
 	usePubKey := false
 	user, _, err := httpdtest.AddUser(getTestUser(usePubKey), http.StatusCreated)
@@ -9991,3 +10592,255 @@ func createInitialFiles(scriptArgs string) {
 		logger.WarnToConsole("unable to save trusted CA user key: %v", err)
 	}
 }
+
+const (
+	sftpFlagRead     = 0x00000001
+	sftpFlagWrite    = 0x00000002
+	sftpFlagCreate   = 0x00000008
+	sftpFlagTruncate = 0x00000010
+)
+
+// rawSFTPClient allows to send SFTP packets not supported by pkg/sftp client
+type rawSFTPClient struct {
+	session *ssh.Session
+	stdin   io.WriteCloser
+	stdout  io.Reader
+	id      uint32
+}
+
+func getRawSFTPClient(user dataprovider.User, usePubKey bool) (*ssh.Client, *rawSFTPClient, error) {
+	conn, client, err := getSftpClient(user, usePubKey)
+	if err != nil {
+		return conn, nil, err
+	}
+	client.Close()
+	session, err := conn.NewSession()
+	if err != nil {
+		conn.Close()
+		return conn, nil, err
+	}
+	stdin, err := session.StdinPipe()
+	if err != nil {
+		conn.Close()
+		return conn, nil, err
+	}
+	stdout, err := session.StdoutPipe()
+	if err != nil {
+		conn.Close()
+		return conn, nil, err
+	}
+	if err = session.RequestSubsystem("sftp"); err != nil {
+		conn.Close()
+		return conn, nil, err
+	}
+	c := &rawSFTPClient{
+		session: session,
+		stdin:   stdin,
+		stdout:  stdout,
+	}
+	// init packet, version 3
+	if err = c.sendPacket(1, marshalRawSFTPUint32(nil, 3)); err != nil {
+		conn.Close()
+		return conn, nil, err
+	}
+	if _, _, err = c.recvPacket(); err != nil {
+		conn.Close()
+		return conn, nil, err
+	}
+	return conn, c, nil
+}
+
+func (c *rawSFTPClient) Close() error {
+	return c.session.Close()
+}
+
+func (c *rawSFTPClient) sendPacket(packetType byte, data []byte) error {
+	packet := marshalRawSFTPUint32(nil, uint32(len(data)+1))
+	packet = append(packet, packetType)
+	packet = append(packet, data...)
+	_, err := c.stdin.Write(packet)
+	return err
+}
+
+func (c *rawSFTPClient) recvPacket() (byte, []byte, error) {
+	header := make([]byte, 4)
+	if _, err := io.ReadFull(c.stdout, header); err != nil {
+		return 0, nil, err
+	}
+	packet := make([]byte, binary.BigEndian.Uint32(header))
+	if _, err := io.ReadFull(c.stdout, packet); err != nil {
+		return 0, nil, err
+	}
+	if len(packet) == 0 {
+		return 0, nil, errors.New("empty packet")
+	}
+	return packet[0], packet[1:], nil
+}
+
+func (c *rawSFTPClient) sendRequest(packetType byte, data []byte) (byte, []byte, error) {
+	c.id++
+	if err := c.sendPacket(packetType, append(marshalRawSFTPUint32(nil, c.id), data...)); err != nil {
+		return 0, nil, err
+	}
+	respType, resp, err := c.recvPacket()
+	if err != nil {
+		return 0, nil, err
+	}
+	if len(resp) < 4 || binary.BigEndian.Uint32(resp) != c.id {
+		return 0, nil, errors.New("unexpected response id")
+	}
+	return respType, resp[4:], nil
+}
+
+func (c *rawSFTPClient) sendStatusRequest(packetType byte, data []byte) (uint32, error) {
+	respType, resp, err := c.sendRequest(packetType, data)
+	if err != nil {
+		return 0, err
+	}
+	if respType != 101 || len(resp) < 4 {
+		return 0, fmt.Errorf("unexpected response type: %v", respType)
+	}
+	return binary.BigEndian.Uint32(resp), nil
+}
+
+func (c *rawSFTPClient) sendExtendedRequest(data []byte) (uint32, error) {
+	return c.sendStatusRequest(200, data)
+}
+
+// sendNameRequest sends an extended request expecting a name packet with a
+// single entry, the status code is returned if the request fails
+func (c *rawSFTPClient) sendNameRequest(data []byte) (string, uint32, error) {
+	respType, resp, err := c.sendRequest(200, data)
+	if err != nil {
+		return "", 0, err
+	}
+	switch respType {
+	case 101:
+		if len(resp) < 4 {
+			return "", 0, errors.New("invalid status packet")
+		}
+		return "", binary.BigEndian.Uint32(resp), nil
+	case 104:
+		if len(resp) < 4 || binary.BigEndian.Uint32(resp) != 1 {
+			return "", 0, errors.New("invalid name packet")
+		}
+		name, _, err := unmarshalRawSFTPString(resp[4:])
+		return name, 0, err
+	default:
+		return "", 0, fmt.Errorf("unexpected response type: %v", respType)
+	}
+}
+
+// sendExtendedReplyRequest sends an extended request expecting an extended
+// reply packet, the status code is returned if the request fails
+func (c *rawSFTPClient) sendExtendedReplyRequest(data []byte) ([]byte, uint32, error) {
+	respType, resp, err := c.sendRequest(200, data)
+	if err != nil {
+		return nil, 0, err
+	}
+	switch respType {
+	case 101:
+		if len(resp) < 4 {
+			return nil, 0, errors.New("invalid status packet")
+		}
+		return nil, binary.BigEndian.Uint32(resp), nil
+	case 201:
+		return resp, 0, nil
+	default:
+		return nil, 0, fmt.Errorf("unexpected response type: %v", respType)
+	}
+}
+
+func (c *rawSFTPClient) open(name string, flags uint32) (string, error) {
+	data := marshalRawSFTPString(nil, name)
+	data = marshalRawSFTPUint32(data, flags)
+	data = marshalRawSFTPUint32(data, 0)
+	respType, resp, err := c.sendRequest(3, data)
+	if err != nil {
+		return "", err
+	}
+	if respType != 102 || len(resp) < 4 {
+		return "", fmt.Errorf("unexpected response type: %v", respType)
+	}
+	length := binary.BigEndian.Uint32(resp)
+	if uint32(len(resp)-4) < length {
+		return "", errors.New("invalid handle")
+	}
+	return string(resp[4 : 4+length]), nil
+}
+
+// openPipelined sends all the open requests before reading the responses,
+// an empty handle is returned for the failed requests
+func (c *rawSFTPClient) openPipelined(names []string, flags []uint32) ([]string, error) {
+	firstID := c.id + 1
+	for idx, name := range names {
+		c.id++
+		data := marshalRawSFTPUint32(nil, c.id)
+		data = marshalRawSFTPString(data, name)
+		data = marshalRawSFTPUint32(data, flags[idx])
+		data = marshalRawSFTPUint32(data, 0)
+		if err := c.sendPacket(3, data); err != nil {
+			return nil, err
+		}
+	}
+	handles := make([]string, len(names))
+	for range names {
+		respType, resp, err := c.recvPacket()
+		if err != nil {
+			return nil, err
+		}
+		if len(resp) < 4 {
+			return nil, errors.New("invalid response")
+		}
+		id := binary.BigEndian.Uint32(resp)
+		if id < firstID || id >= firstID+uint32(len(names)) {
+			return nil, errors.New("unexpected response id")
+		}
+		switch respType {
+		case 101:
+		case 102:
+			handle, _, err := unmarshalRawSFTPString(resp[4:])
+			if err != nil {
+				return nil, err
+			}
+			handles[id-firstID] = handle
+		default:
+			return nil, fmt.Errorf("unexpected response type: %v", respType)
+		}
+	}
+	return handles, nil
+}
+
+func (c *rawSFTPClient) close(handle string) error {
+	code, err := c.sendStatusRequest(4, marshalRawSFTPString(nil, handle))
+	if err != nil {
+		return err
+	}
+	if code != 0 {
+		return fmt.Errorf("unexpected status code: %v", code)
+	}
+	return nil
+}
+
+func marshalRawSFTPUint32(b []byte, v uint32) []byte {
+	return append(b, byte(v>>24), byte(v>>16), byte(v>>8), byte(v))
+}
+
+func marshalRawSFTPUint64(b []byte, v uint64) []byte {
+	return marshalRawSFTPUint32(marshalRawSFTPUint32(b, uint32(v>>32)), uint32(v))
+}
+
+func marshalRawSFTPString(b []byte, v string) []byte {
+	return append(marshalRawSFTPUint32(b, uint32(len(v))), v...)
+}
+
+func unmarshalRawSFTPString(b []byte) (string, []byte, error) {
+	if len(b) < 4 {
+		return "", nil, errors.New("invalid string")
+	}
+	length := binary.BigEndian.Uint32(b)
+	if uint32(len(b)-4) < length {
+		return "", nil, errors.New("invalid string")
+	}
+	return string(b[4 : 4+length]), b[4+length:], nil
+}
@@ -55,7 +55,7 @@ func ServeSubSystemConnection(user *dataprovider.User, connectionID string, read
 	common.Connections.Add(connection)
 	defer common.Connections.Remove(connection.GetID())
 
-	server := sftp.NewRequestServer(connection.channel, sftp.Handlers{
+	server := sftp.NewRequestServer(newSFTPExtensionsChannel(connection.channel, connection), sftp.Handlers{
 		FileGet:  connection,
 		FilePut:  connection,
 		FileCmd:  connection,
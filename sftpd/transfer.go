@@ -22,6 +22,10 @@ type readerAtCloser interface {
 	io.Closer
 }
 
+type fileSyncer interface {
+	Sync() error
+}
+
 type failingReader struct {
 	innerReader readerAtCloser
 	errRead     error
@@ -95,6 +99,9 @@ func (t *transfer) ReadAt(p []byte, off int64) (n int, err error) {
 	n, err = t.readerAt.ReadAt(p, off)
 	atomic.AddInt64(&t.BytesSent, int64(n))
 
+	if err == nil {
+		err = t.CheckRead()
+	}
 	if err != nil && err != io.EOF {
 		if t.GetType() == common.TransferDownload {
 			t.TransferError(err)
@@ -118,8 +125,8 @@ func (t *transfer) WriteAt(p []byte, off int64) (n int, err error) {
 	n, err = t.writerAt.WriteAt(p, off)
 	atomic.AddInt64(&t.BytesReceived, int64(n))
 
-	if t.MaxWriteSize > 0 && err == nil && atomic.LoadInt64(&t.BytesReceived) > t.MaxWriteSize {
-		err = common.ErrQuotaExceeded
+	if err == nil {
+		err = t.CheckWrite()
 	}
 	if err != nil {
 		t.TransferError(err)
@@ -164,6 +171,20 @@ func (t *transfer) closeIO() error {
 	return err
 }
 
+// Sync commits the current contents of the file to stable storage.
+// It is supported only if the transfer writes directly to a file, for example
+// for the local filesystem. The other backends, including the encrypted one,
+// write to a pipe and the contents are committed when the file is closed
+func (t *transfer) Sync() error {
+	if t.GetType() != common.TransferUpload {
+		return nil
+	}
+	if f, ok := t.File.(fileSyncer); ok {
+		return t.Connection.GetFsError(t.Fs, f.Sync())
+	}
+	return t.Connection.GetOpUnsupportedError()
+}
+
 func (t *transfer) setFinished() error {
 	t.Lock()
 	defer t.Unlock()
@@ -204,6 +225,14 @@ func (t *transfer) copyFromReaderToWriter(dst io.Writer, src io.Reader) (int64,
 					err = common.ErrQuotaExceeded
 					break
 				}
+				if isDownload {
+					err = t.CheckRead()
+				} else {
+					err = t.CheckWrite()
+				}
+				if err != nil {
+					break
+				}
 			}
 			if ew != nil {
 				err = ew
@@ -31,6 +31,8 @@ const (
 const (
 	templateEmailDir             = "email"
 	templateRetentionCheckResult = "retention-check-report.html"
+	templatePasswordExpiration   = "password-expiration.html"
+	templatePasswordReset        = "reset-password.html"
 )
 
 var (
@@ -147,6 +149,12 @@ func loadTemplates(templatesPath string) {
 	retentionCheckPath := filepath.Join(templatesPath, templateRetentionCheckResult)
 	retentionTmpl := util.LoadTemplate(nil, retentionCheckPath)
 	emailTemplates[templateRetentionCheckResult] = retentionTmpl
+	pwdExpirationPath := filepath.Join(templatesPath, templatePasswordExpiration)
+	pwdExpirationTmpl := util.LoadTemplate(nil, pwdExpirationPath)
+	emailTemplates[templatePasswordExpiration] = pwdExpirationTmpl
+	pwdResetPath := filepath.Join(templatesPath, templatePasswordReset)
+	pwdResetTmpl := util.LoadTemplate(nil, pwdResetPath)
+	emailTemplates[templatePasswordReset] = pwdResetTmpl
 }
 
 // RenderRetentionReportTemplate executes the retention report template
@@ -157,6 +165,22 @@ func RenderRetentionReportTemplate(buf *bytes.Buffer, data interface{}) error {
 	return emailTemplates[templateRetentionCheckResult].Execute(buf, data)
 }
 
+// RenderPasswordExpirationTemplate executes the password expiration reminder template
+func RenderPasswordExpirationTemplate(buf *bytes.Buffer, data interface{}) error {
+	if smtpServer == nil {
+		return errors.New("smtp: not configured")
+	}
+	return emailTemplates[templatePasswordExpiration].Execute(buf, data)
+}
+
+// RenderPasswordResetTemplate executes the password reset template
+func RenderPasswordResetTemplate(buf *bytes.Buffer, data interface{}) error {
+	if smtpServer == nil {
+		return errors.New("smtp: not configured")
+	}
+	return emailTemplates[templatePasswordReset].Execute(buf, data)
+}
+
 // SendEmail tries to send an email using the specified parameters.
 func SendEmail(to, subject, body string, contentType EmailContentType) error {
 	if smtpServer == nil {
@@ -0,0 +1,192 @@
+package util
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxCronSearchYears defines how far in the future we search for the next activation
+const maxCronSearchYears = 5
+
+var (
+	cronMonthNames = map[string]int{"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6, "jul": 7,
+		"aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12}
+	cronDayNames  = map[string]int{"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6}
+	cronShortcuts = map[string]string{
+		"@yearly":   "0 0 1 1 *",
+		"@annually": "0 0 1 1 *",
+		"@monthly":  "0 0 1 * *",
+		"@weekly":   "0 0 * * 0",
+		"@daily":    "0 0 * * *",
+		"@midnight": "0 0 * * *",
+		"@hourly":   "0 * * * *",
+	}
+)
+
+type cronField struct {
+	name  string
+	min   int
+	max   int
+	names map[string]int
+}
+
+var cronFields = []cronField{
+	{name: "minute", min: 0, max: 59},
+	{name: "hour", min: 0, max: 23},
+	{name: "day of month", min: 1, max: 31},
+	{name: "month", min: 1, max: 12, names: cronMonthNames},
+	{name: "day of week", min: 0, max: 7, names: cronDayNames},
+}
+
+// CronSchedule defines a parsed cron expression
+type CronSchedule struct {
+	minutes  uint64
+	hours    uint64
+	days     uint64
+	months   uint64
+	weekdays uint64
+	// true if the day of month/day of week field is "*"
+	anyDay     bool
+	anyWeekday bool
+}
+
+// ParseCronExpression parses a standard cron expression with five space separated fields:
+// minute, hour, day of month, month and day of week. Each field supports "*", lists,
+// ranges and steps, for example "*/15 8-18 * * mon-fri". Month and day of week names
+// and the @yearly, @monthly, @weekly, @daily and @hourly shortcuts are supported too
+func ParseCronExpression(expr string) (*CronSchedule, error) {
+	expr = strings.TrimSpace(expr)
+	if shortcut, ok := cronShortcuts[strings.ToLower(expr)]; ok {
+		expr = shortcut
+	}
+	fields := strings.Fields(expr)
+	if len(fields) != len(cronFields) {
+		return nil, fmt.Errorf("invalid cron expression %#v: %d fields expected, got %d", expr, len(cronFields),
+			len(fields))
+	}
+	var bits [5]uint64
+	for idx, field := range fields {
+		val, err := parseCronField(field, &cronFields[idx])
+		if err != nil {
+			return nil, fmt.Errorf("invalid cron expression %#v: %w", expr, err)
+		}
+		bits[idx] = val
+	}
+	// 7 is an alias for sunday
+	if bits[4]&(1<<7) != 0 {
+		bits[4] |= 1
+		bits[4] &^= 1 << 7
+	}
+	return &CronSchedule{
+		minutes:    bits[0],
+		hours:      bits[1],
+		days:       bits[2],
+		months:     bits[3],
+		weekdays:   bits[4],
+		anyDay:     strings.HasPrefix(fields[2], "*"),
+		anyWeekday: strings.HasPrefix(fields[4], "*"),
+	}, nil
+}
+
+func parseCronField(field string, def *cronField) (uint64, error) {
+	var result uint64
+	for _, item := range strings.Split(field, ",") {
+		if item == "" {
+			return 0, fmt.Errorf("empty value in %s field", def.name)
+		}
+		rangeExpr := item
+		step := 1
+		if idx := strings.Index(item, "/"); idx >= 0 {
+			rangeExpr = item[:idx]
+			val, err := strconv.Atoi(item[idx+1:])
+			if err != nil || val <= 0 {
+				return 0, fmt.Errorf("invalid step %#v in %s field", item[idx+1:], def.name)
+			}
+			step = val
+		}
+		start, end := def.min, def.max
+		if rangeExpr != "*" {
+			var err error
+			if idx := strings.Index(rangeExpr, "-"); idx >= 0 {
+				if start, err = parseCronValue(rangeExpr[:idx], def); err != nil {
+					return 0, err
+				}
+				if end, err = parseCronValue(rangeExpr[idx+1:], def); err != nil {
+					return 0, err
+				}
+				if start > end {
+					return 0, fmt.Errorf("invalid range %#v in %s field", rangeExpr, def.name)
+				}
+			} else {
+				if start, err = parseCronValue(rangeExpr, def); err != nil {
+					return 0, err
+				}
+				end = start
+				if step > 1 {
+					// "n/step" means from n to the field max
+					end = def.max
+				}
+			}
+		}
+		for val := start; val <= end; val += step {
+			result |= 1 << uint(val)
+		}
+	}
+	return result, nil
+}
+
+func parseCronValue(value string, def *cronField) (int, error) {
+	if val, ok := def.names[strings.ToLower(value)]; ok {
+		return val, nil
+	}
+	val, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value %#v in %s field", value, def.name)
+	}
+	if val < def.min || val > def.max {
+		return 0, fmt.Errorf("value %d out of range [%d-%d] in %s field", val, def.min, def.max, def.name)
+	}
+	return val, nil
+}
+
+// Next returns the first activation time strictly after the given time.
+// The zero time is returned if there is no activation within the next five years,
+// for example for an expression such as "0 0 31 2 *"
+func (s *CronSchedule) Next(t time.Time) time.Time {
+	loc := t.Location()
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(maxCronSearchYears, 0, 0)
+
+	for t.Before(limit) {
+		if s.months&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc)
+			continue
+		}
+		if !s.matchDay(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc)
+			continue
+		}
+		if s.hours&(1<<uint(t.Hour())) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, loc)
+			continue
+		}
+		if s.minutes&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+func (s *CronSchedule) matchDay(t time.Time) bool {
+	dayMatch := s.days&(1<<uint(t.Day())) != 0
+	weekdayMatch := s.weekdays&(1<<uint(t.Weekday())) != 0
+	// if both fields are restricted a match on either field is enough, as in the standard cron
+	if !s.anyDay && !s.anyWeekday {
+		return dayMatch || weekdayMatch
+	}
+	return dayMatch && weekdayMatch
+}
@@ -29,12 +29,22 @@ import (
 	"github.com/drakkan/sftpgo/v2/version"
 )
 
-const azureDefaultEndpoint = "blob.core.windows.net"
+const (
+	azureDefaultEndpoint = "blob.core.windows.net"
+	// max size for a block staged using a source URL
+	azureMaxStageBlockFromURLSize = 100 * 1024 * 1024
+)
 
 // max time of an azure web request response window (whether or not data is flowing)
 // this is the same value used in rclone
 var maxTryTimeout = time.Hour * 24 * 365
 
+// azBlobMultipartUploadState defines the state of an interrupted upload,
+// the blocks are staged but not committed
+type azBlobMultipartUploadState struct {
+	Blocks []string `json:"blocks"`
+}
+
 // AzureBlobFs is a Fs implementation for Azure Blob storage.
 type AzureBlobFs struct {
 	connectionID string
@@ -178,7 +188,9 @@ func (fs *AzureBlobFs) Stat(name string) (os.FileInfo, error) {
 	if err == nil {
 		isDir := (attrs.ContentType() == dirMimeType)
 		metric.AZListObjectsCompleted(nil)
-		return NewFileInfo(name, isDir, attrs.ContentLength(), attrs.LastModified(), false), nil
+		info := NewFileInfo(name, isDir, attrs.ContentLength(), attrs.LastModified(), false)
+		info.etag = string(attrs.ETag())
+		return info, nil
 	}
 	if !fs.IsNotExist(err) {
 		return nil, err
@@ -234,11 +246,36 @@ func (fs *AzureBlobFs) Open(name string, offset int64) (File, *pipeat.PipeReader
 
 // Create creates or opens the named file for writing
 func (fs *AzureBlobFs) Create(name string, flag int) (File, *PipeWriter, func(), error) {
+	var resumeOffset int64
+	blobExists := false
+	if isUploadResumeRequested(flag) {
+		attrs, err := fs.headObject(name)
+		if err == nil {
+			resumeOffset = attrs.ContentLength()
+			blobExists = true
+		} else if !fs.IsNotExist(err) {
+			return nil, nil, nil, err
+		}
+	}
+	var pendingUpload *MultipartUpload
+	var pendingState azBlobMultipartUploadState
+	if flag != -1 {
+		pendingUpload, _ = getMultipartUpload(fs, fs.getMultipartUploadKey(name), &pendingState)
+		if pendingUpload != nil && (blobExists || !isUploadResumeRequested(flag) || pendingUpload.IsExpired(time.Now())) {
+			// the uncommitted blocks are discarded by Azure
+			deleteMultipartUpload(fs, fs.getMultipartUploadKey(name))
+			pendingUpload = nil
+			pendingState.Blocks = nil
+		}
+		if pendingUpload != nil {
+			resumeOffset = pendingUpload.Size
+		}
+	}
 	r, w, err := pipeat.PipeInDir(fs.localTempDir)
 	if err != nil {
 		return nil, nil, nil, err
 	}
-	p := NewPipeWriter(w)
+	p := NewPipeWriterAtOffset(w, resumeOffset)
 	blobBlockURL := fs.containerURL.NewBlockBlobURL(name)
 	ctx, cancelFn := context.WithCancel(context.Background())
 
@@ -265,10 +302,16 @@ func (fs *AzureBlobFs) Create(name string, flag int) (File, *PipeWriter, func(),
 		// if we shutdown Azurite while uploading it hangs, so we use our own wrapper for
 		// the low level functions
 		_, err := azblob.UploadStreamToBlockBlob(ctx, r, blobBlockURL, uploadOptions)*/
-		err := fs.handleMultipartUpload(ctx, r, &blobBlockURL, &headers)
+		var err error
+		if blobExists && resumeOffset > 0 {
+			err = fs.handleResumedUpload(ctx, r, &blobBlockURL, &headers)
+		} else {
+			err = fs.handleResumableUpload(ctx, r, &blobBlockURL, &headers, name, pendingUpload, &pendingState)
+		}
 		r.CloseWithError(err) //nolint:errcheck
 		p.Done(err)
-		fsLog(fs, logger.LevelDebug, "upload completed, path: %#v, readed bytes: %v, err: %v", name, r.GetReadedBytes(), err)
+		fsLog(fs, logger.LevelDebug, "upload completed, path: %#v, resume offset: %v, readed bytes: %v, err: %v",
+			name, resumeOffset, r.GetReadedBytes(), err)
 		metric.AZTransferCompleted(r.GetReadedBytes(), 0, err)
 	}()
 
@@ -297,6 +340,114 @@ func (fs *AzureBlobFs) Rename(source, target string) error {
 			return fmt.Errorf("cannot rename non empty directory: %#v", source)
 		}
 	}
+	if err := fs.copyBlob(source, target); err != nil {
+		return err
+	}
+	return fs.Remove(source, fi.IsDir())
+}
+
+// CopyFile implements the FsFileCopier interface.
+// The file is copied using a server side copy
+func (fs *AzureBlobFs) CopyFile(source, target string) error {
+	if source == target {
+		return nil
+	}
+	fi, err := fs.Stat(source)
+	if err != nil {
+		return err
+	}
+	if fi.IsDir() {
+		return fmt.Errorf("cannot copy %#v, it is a directory", source)
+	}
+	return fs.copyBlob(source, target)
+}
+
+// CombineFiles implements the FsFileCombiner interface.
+// The blocks for the target blob are staged server side from the source blobs
+// and then committed. If the first source is the target itself its committed
+// blocks are kept
+func (fs *AzureBlobFs) CombineFiles(target string, sources []string) error {
+	if len(sources) == 0 {
+		return errors.New("no source files to combine")
+	}
+	blobBlockURL := fs.containerURL.NewBlockBlobURL(target)
+	ctx, cancelFn := context.WithDeadline(context.Background(), time.Now().Add(fs.ctxTimeout))
+	defer cancelFn()
+
+	// all the block IDs within a blob must have the same length
+	binaryBlockID := make([]byte, 8)
+	existingBlocks := make(map[string]bool)
+	var committedBlocks []string
+	blockList, err := blobBlockURL.GetBlockList(ctx, azblob.BlockListCommitted, azblob.LeaseAccessConditions{})
+	if err == nil {
+		for _, block := range blockList.CommittedBlocks {
+			existingBlocks[block.Name] = true
+			committedBlocks = append(committedBlocks, block.Name)
+		}
+		if len(committedBlocks) > 0 {
+			decodedID, err := base64.StdEncoding.DecodeString(committedBlocks[0])
+			if err != nil || len(decodedID) == 0 {
+				return fmt.Errorf("unable to combine files, invalid block ID %#v: %v", committedBlocks[0], err)
+			}
+			binaryBlockID = make([]byte, len(decodedID))
+		}
+	} else if !fs.IsNotExist(err) {
+		return err
+	}
+
+	var blocks []string
+	for idx, source := range sources {
+		if idx == 0 && source == target && len(committedBlocks) > 0 {
+			blocks = append(blocks, committedBlocks...)
+			continue
+		}
+		props, err := fs.headObject(source)
+		if err != nil {
+			return err
+		}
+		size := props.ContentLength()
+		srcURL := fs.containerURL.NewBlobURL(source).URL()
+		for offset := int64(0); offset < size; offset += azureMaxStageBlockFromURLSize {
+			count := size - offset
+			if count > azureMaxStageBlockFromURLSize {
+				count = azureMaxStageBlockFromURLSize
+			}
+			blockID := fs.getNextBlockID(binaryBlockID, existingBlocks)
+			if err := fs.stageBlockFromURL(&blobBlockURL, blockID, srcURL, offset, count, props.ETag()); err != nil {
+				metric.AZCopyObjectCompleted(err)
+				return err
+			}
+			blocks = append(blocks, blockID)
+		}
+	}
+
+	headers := azblob.BlobHTTPHeaders{
+		ContentType: mime.TypeByExtension(path.Ext(target)),
+	}
+	commitCtx, commitCancelFn := context.WithDeadline(context.Background(), time.Now().Add(fs.ctxTimeout))
+	defer commitCancelFn()
+
+	_, err = blobBlockURL.CommitBlockList(commitCtx, blocks, headers, azblob.Metadata{}, azblob.BlobAccessConditions{},
+		azblob.AccessTierType(fs.config.AccessTier), nil, azblob.ClientProvidedKeyOptions{})
+	fsLog(fs, logger.LevelDebug, "combine completed, target: %#v, sources: %v, blocks: %v, err: %v",
+		target, len(sources), len(blocks), err)
+	metric.AZCopyObjectCompleted(err)
+	return err
+}
+
+func (fs *AzureBlobFs) stageBlockFromURL(blockBlobURL *azblob.BlockBlobURL, blockID string, srcURL url.URL,
+	offset, count int64, eTag azblob.ETag,
+) error {
+	blockCtxTimeout := time.Duration(count/(1024*1024)+1) * time.Minute
+	ctx, cancelFn := context.WithDeadline(context.Background(), time.Now().Add(blockCtxTimeout))
+	defer cancelFn()
+
+	_, err := blockBlobURL.StageBlockFromURL(ctx, blockID, srcURL, offset, count, azblob.LeaseAccessConditions{},
+		azblob.ModifiedAccessConditions{IfMatch: eTag}, azblob.ClientProvidedKeyOptions{})
+	return err
+}
+
+func (fs *AzureBlobFs) copyBlob(source, target string) error {
 	dstBlobURL := fs.containerURL.NewBlobURL(target)
 	srcURL := fs.containerURL.NewBlobURL(source).URL()
 
@@ -335,7 +486,7 @@ func (fs *AzureBlobFs) Rename(source, target string) error {
 		return err
 	}
 	metric.AZCopyObjectCompleted(nil)
-	return fs.Remove(source, fi.IsDir())
+	return nil
 }
 
 // Remove removes the named file or (empty) directory.
@@ -349,12 +500,25 @@ func (fs *AzureBlobFs) Remove(name string, isDir bool) error {
 			return fmt.Errorf("cannot remove non empty directory: %#v", name)
 		}
 	}
+	var pendingUpload *MultipartUpload
+	if !isDir {
+		var state azBlobMultipartUploadState
+		pendingUpload, _ = getMultipartUpload(fs, fs.getMultipartUploadKey(name), &state)
+		if pendingUpload != nil {
+			// the uncommitted blocks are discarded by Azure
+			deleteMultipartUpload(fs, fs.getMultipartUploadKey(name))
+		}
+	}
 	blobBlockURL := fs.containerURL.NewBlockBlobURL(name)
 	ctx, cancelFn := context.WithDeadline(context.Background(), time.Now().Add(fs.ctxTimeout))
 	defer cancelFn()
 
 	_, err := blobBlockURL.Delete(ctx, azblob.DeleteSnapshotsOptionNone, azblob.BlobAccessConditions{})
 	metric.AZDeleteObjectCompleted(err)
+	if err != nil && pendingUpload != nil && fs.IsNotExist(err) {
+		// only the interrupted upload existed
+		return nil
+	}
 	return err
 }
 
@@ -482,9 +646,10 @@ func (fs *AzureBlobFs) ReadDir(dirname string) ([]os.FileInfo, error) {
 }
 
 // IsUploadResumeSupported returns true if resuming uploads is supported.
-// Resuming uploads is not supported on Azure Blob
+// Resumed uploads keep the committed blocks of the existing blob and add
+// the new blocks after them
 func (*AzureBlobFs) IsUploadResumeSupported() bool {
-	return false
+	return true
 }
 
 // IsAtomicUploadSupported returns true if atomic upload is supported.
@@ -802,8 +967,72 @@ func (fs *AzureBlobFs) hasContents(name string) (bool, error) {
 	return result, err
 }
 
-func (fs *AzureBlobFs) handleMultipartUpload(ctx context.Context, reader io.Reader, blockBlobURL *azblob.BlockBlobURL,
+// handleResumedUpload appends the data read from reader to an existing blob.
+// If the blob was uploaded using blocks we keep the committed blocks and we
+// stage only the new data, otherwise the existing contents are uploaded again
+func (fs *AzureBlobFs) handleResumedUpload(ctx context.Context, reader io.Reader, blockBlobURL *azblob.BlockBlobURL,
 	httpHeaders *azblob.BlobHTTPHeaders) error {
+	blockList, err := blockBlobURL.GetBlockList(ctx, azblob.BlockListCommitted, azblob.LeaseAccessConditions{})
+	if err != nil {
+		return err
+	}
+	if len(blockList.CommittedBlocks) > 0 {
+		committedBlocks := make([]string, 0, len(blockList.CommittedBlocks))
+		for _, block := range blockList.CommittedBlocks {
+			committedBlocks = append(committedBlocks, block.Name)
+		}
+		fsLog(fs, logger.LevelDebug, "resuming upload, committed blocks: %v", len(committedBlocks))
+		_, _, err = fs.handleMultipartUpload(ctx, reader, blockBlobURL, httpHeaders, committedBlocks)
+		return err
+	}
+	fsLog(fs, logger.LevelDebug, "resuming upload for a blob without committed blocks, the existing data will be uploaded again")
+	resp, err := blockBlobURL.Download(ctx, 0, azblob.CountToEnd, azblob.BlobAccessConditions{}, false,
+		azblob.ClientProvidedKeyOptions{})
+	if err != nil {
+		return err
+	}
+	body := resp.Body(azblob.RetryReaderOptions{
+		MaxRetryRequests: 3,
+	})
+	defer body.Close()
+
+	_, _, err = fs.handleMultipartUpload(ctx, io.MultiReader(body, reader), blockBlobURL, httpHeaders, nil)
+	return err
+}
+
+// handleResumableUpload uploads the data read from reader after the blocks staged
+// for an interrupted upload, if any. If the upload is interrupted again the staged
+// blocks are saved, so the upload can be resumed later
+func (fs *AzureBlobFs) handleResumableUpload(ctx context.Context, reader io.Reader, blockBlobURL *azblob.BlockBlobURL,
+	httpHeaders *azblob.BlobHTTPHeaders, name string, upload *MultipartUpload, state *azBlobMultipartUploadState,
+) error {
+	key := fs.getMultipartUploadKey(name)
+	if upload != nil {
+		fsLog(fs, logger.LevelDebug, "resuming interrupted upload, size: %v, staged blocks: %v", upload.Size,
+			len(state.Blocks))
+	}
+	blocks, stagedSize, err := fs.handleMultipartUpload(ctx, reader, blockBlobURL, httpHeaders, state.Blocks)
+	if err == nil {
+		if upload != nil {
+			deleteMultipartUpload(fs, key)
+		}
+		return nil
+	}
+	if stagedSize > 0 {
+		if upload != nil {
+			stagedSize += upload.Size
+		}
+		saveMultipartUpload(fs, key, stagedSize, &azBlobMultipartUploadState{Blocks: blocks}) //nolint:errcheck
+	}
+	return err
+}
+
+// handleMultipartUpload stages the data read from reader as blocks and commits them
+// after the specified previous blocks, if any. The previous blocks can be committed
+// or staged by an interrupted upload. On error the previous blocks and the new ones
+// staged, consecutively, before the error are returned with the size of the new blocks
+func (fs *AzureBlobFs) handleMultipartUpload(ctx context.Context, reader io.Reader, blockBlobURL *azblob.BlockBlobURL,
+	httpHeaders *azblob.BlobHTTPHeaders, previousBlocks []string) ([]string, int64, error) {
 	partSize := fs.config.UploadPartSize
 	guard := make(chan struct{}, fs.config.UploadConcurrency)
 	blockCtxTimeout := time.Duration(fs.config.UploadPartSize/(1024*1024)) * time.Minute
@@ -813,10 +1042,28 @@ func (fs *AzureBlobFs) handleMultipartUpload(ctx context.Context, reader io.Read
 	pool := newBufferAllocator(int(partSize))
 	finished := false
 	binaryBlockID := make([]byte, 8)
-	var blocks []string
+	blocks := make([]string, 0, len(previousBlocks))
+	existingBlocks := make(map[string]bool)
+	if len(previousBlocks) > 0 {
+		// all the block IDs within a blob must have the same length
+		decodedID, err := base64.StdEncoding.DecodeString(previousBlocks[0])
+		if err != nil || len(decodedID) == 0 {
+			return nil, 0, fmt.Errorf("unable to resume upload, invalid block ID %#v: %v", previousBlocks[0], err)
+		}
+		binaryBlockID = make([]byte, len(decodedID))
+		for _, blockID := range previousBlocks {
+			existingBlocks[blockID] = true
+			blocks = append(blocks, blockID)
+		}
+	}
 	var wg sync.WaitGroup
 	var errOnce sync.Once
 	var poolError error
+	var readError error
+	// size of the new blocks and staging result, by part
+	var blockSizes []int64
+	var stagedMu sync.Mutex
+	stagedParts := make(map[int]bool)
 
 	poolCtx, poolCancel := context.WithCancel(ctx)
 	defer poolCancel()
@@ -824,7 +1071,7 @@ func (fs *AzureBlobFs) handleMultipartUpload(ctx context.Context, reader io.Read
 	for part := 0; !finished; part++ {
 		buf := pool.getBuffer()
 
-		n, err := fs.readFill(reader, buf)
+		n, err := readFill(reader, buf)
 		if err == io.EOF {
 			// read finished, if n > 0 we need to process the last data chunck
 			if n == 0 {
@@ -834,13 +1081,13 @@ func (fs *AzureBlobFs) handleMultipartUpload(ctx context.Context, reader io.Read
 			finished = true
 		} else if err != nil {
 			pool.releaseBuffer(buf)
-			pool.free()
-			return err
+			readError = err
+			break
 		}
 
-		fs.incrementBlockID(binaryBlockID)
-		blockID := base64.StdEncoding.EncodeToString(binaryBlockID)
+		blockID := fs.getNextBlockID(binaryBlockID, existingBlocks)
 		blocks = append(blocks, blockID)
+		blockSizes = append(blockSizes, int64(n))
 
 		guard <- struct{}{}
 		if poolError != nil {
@@ -850,7 +1097,7 @@ func (fs *AzureBlobFs) handleMultipartUpload(ctx context.Context, reader io.Read
 		}
 
 		wg.Add(1)
-		go func(blockID string, buf []byte, bufSize int) {
+		go func(part int, blockID string, buf []byte, bufSize int) {
 			defer wg.Done()
 			bufferReader := bytes.NewReader(buf[:bufSize])
 			innerCtx, cancelFn := context.WithDeadline(poolCtx, time.Now().Add(blockCtxTimeout))
@@ -864,33 +1111,60 @@ func (fs *AzureBlobFs) handleMultipartUpload(ctx context.Context, reader io.Read
 					fsLog(fs, logger.LevelDebug, "multipart upload error: %v", poolError)
 					poolCancel()
 				})
+			} else {
+				stagedMu.Lock()
+				stagedParts[part] = true
+				stagedMu.Unlock()
 			}
 			pool.releaseBuffer(buf)
 			<-guard
-		}(blockID, buf, n)
+		}(part, blockID, buf, n)
 	}
 
 	wg.Wait()
 	close(guard)
 	pool.free()
 
-	if poolError != nil {
-		return poolError
+	if readError == nil {
+		readError = poolError
+	}
+	if readError != nil {
+		numStaged := 0
+		var stagedSize int64
+		for part, size := range blockSizes {
+			if !stagedParts[part] {
+				break
+			}
+			numStaged++
+			stagedSize += size
+		}
+		return blocks[:len(previousBlocks)+numStaged], stagedSize, readError
+	}
+	var stagedSize int64
+	for _, size := range blockSizes {
+		stagedSize += size
 	}
 
 	_, err := blockBlobURL.CommitBlockList(ctx, blocks, *httpHeaders, azblob.Metadata{}, azblob.BlobAccessConditions{},
 		azblob.AccessTierType(fs.config.AccessTier), nil, azblob.ClientProvidedKeyOptions{})
-	return err
+	return blocks, stagedSize, err
 }
 
-// copied from rclone
-func (fs *AzureBlobFs) readFill(r io.Reader, buf []byte) (n int, err error) {
-	var nn int
-	for n < len(buf) && err == nil {
-		nn, err = r.Read(buf[n:])
-		n += nn
+func (fs *AzureBlobFs) getMultipartUploadKey(name string) string {
+	u := fs.containerURL.URL()
+	return getMultipartUploadKey("azblob", u.Host, u.Path, name)
+}
+
+// getNextBlockID increments the binary block ID and returns it base64 encoded.
+// The block IDs already committed are skipped
+func (fs *AzureBlobFs) getNextBlockID(binaryBlockID []byte, existingBlocks map[string]bool) string {
+	for {
+		fs.incrementBlockID(binaryBlockID)
+		blockID := base64.StdEncoding.EncodeToString(binaryBlockID)
+		if !existingBlocks[blockID] {
+			return blockID
+		}
 	}
-	return n, err
 }
 
 // copied from rclone
@@ -263,17 +263,7 @@ func (fs *CryptFs) ConvertFileInfo(info os.FileInfo) os.FileInfo {
 	if !info.Mode().IsRegular() {
 		return info
 	}
-	size := info.Size()
-	if size >= headerV10Size {
-		size -= headerV10Size
-		decryptedSize, err := sio.DecryptedSize(uint64(size))
-		if err == nil {
-			size = int64(decryptedSize)
-		}
-	} else {
-		size = 0
-	}
-	return NewFileInfo(info.Name(), info.IsDir(), size, info.ModTime(), false)
+	return NewFileInfo(info.Name(), info.IsDir(), getDecryptedSize(info.Size()), info.ModTime(), false)
 }
 
 func (fs *CryptFs) getFileAndEncryptionKey(name string) (*os.File, [32]byte, error) {
@@ -317,12 +307,25 @@ func isZeroBytesDownload(f *os.File, offset int64) (bool, error) {
 	return false, nil
 }
 
+// getDecryptedSize returns the plaintext size for an encrypted file of the given size
+func getDecryptedSize(size int64) int64 {
+	if size < headerV10Size {
+		return 0
+	}
+	size -= headerV10Size
+	decryptedSize, err := sio.DecryptedSize(uint64(size))
+	if err == nil {
+		size = int64(decryptedSize)
+	}
+	return size
+}
+
 type encryptedFileHeader struct {
 	version byte
 	nonce   []byte
 }
 
-func (h *encryptedFileHeader) Store(f *os.File) error {
+func (h *encryptedFileHeader) Store(f io.Writer) error {
 	buf := make([]byte, 0, headerV10Size)
 	buf = append(buf, version10)
 	buf = append(buf, h.nonce...)
@@ -330,7 +333,7 @@ func (h *encryptedFileHeader) Store(f *os.File) error {
 	return err
 }
 
-func (h *encryptedFileHeader) Load(f *os.File) error {
+func (h *encryptedFileHeader) Load(f io.Reader) error {
 	header := make([]byte, 1+nonceV10Size)
 	_, err := io.ReadFull(f, header)
 	if err != nil {
@@ -0,0 +1,471 @@
+package vfs
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"github.com/eikenb/pipeat"
+	"github.com/minio/sio"
+	"golang.org/x/crypto/hkdf"
+
+	"github.com/drakkan/sftpgo/v2/kms"
+	"github.com/drakkan/sftpgo/v2/logger"
+	"github.com/drakkan/sftpgo/v2/sdk"
+)
+
+const (
+	// sio payload and package sizes for Version20, a package is
+	// 16 (header) + 65536 (payload) + 16 (tag)
+	sioPayloadSize int64 = 65536
+	sioPackageSize int64 = 65568
+)
+
+// EncryptionConfig defines the configuration to store files encrypted at rest
+// on remote filesystems
+type EncryptionConfig struct {
+	sdk.EncryptionConfig
+}
+
+// IsEnabled returns true if a passphrase is configured
+func (c *EncryptionConfig) IsEnabled() bool {
+	return c.Passphrase != nil && !c.Passphrase.IsEmpty()
+}
+
+// HideConfidentialData hides confidential data
+func (c *EncryptionConfig) HideConfidentialData() {
+	if c.Passphrase != nil {
+		c.Passphrase.Hide()
+	}
+}
+
+func (c *EncryptionConfig) isEqual(other *EncryptionConfig) bool {
+	if c.Passphrase == nil {
+		c.Passphrase = kms.NewEmptySecret()
+	}
+	if other.Passphrase == nil {
+		other.Passphrase = kms.NewEmptySecret()
+	}
+	return c.Passphrase.IsEqual(other.Passphrase)
+}
+
+// EncryptCredentials encrypts the passphrase if it is in plain text
+func (c *EncryptionConfig) EncryptCredentials(additionalData string) error {
+	if c.IsEnabled() && c.Passphrase.IsPlain() {
+		c.Passphrase.SetAdditionalData(additionalData)
+		if err := c.Passphrase.Encrypt(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Validate returns an error if the configuration is not valid
+func (c *EncryptionConfig) Validate() error {
+	if !c.IsEnabled() {
+		return nil
+	}
+	if !c.Passphrase.IsValidInput() {
+		return errors.New("passphrase cannot be empty or invalid")
+	}
+	if c.Passphrase.IsEncrypted() && !c.Passphrase.IsValid() {
+		return errors.New("invalid encrypted passphrase")
+	}
+	return nil
+}
+
+// EncryptedFs wraps a remote Fs and encrypts/decrypts the files client side.
+// The encrypted files have the same format used by CryptFs so the reported
+// sizes, and so the quota usage, refer to the plaintext contents
+type EncryptedFs struct {
+	Fs
+	masterKey    []byte
+	localTempDir string
+}
+
+// encryptedCopierFs is an EncryptedFs wrapping a Fs that can copy files server side
+type encryptedCopierFs struct {
+	*EncryptedFs
+}
+
+// NewEncryptedFs returns fs wrapped inside an EncryptedFs if encryption is
+// enabled for the given filesystem configuration, fs is returned unchanged otherwise.
+// Encryption is supported for S3, Google Cloud Storage, Azure Blob and SFTP filesystems
+func NewEncryptedFs(fs Fs, fsConfig *Filesystem) (Fs, error) {
+	if !fsConfig.Encryption.IsEnabled() {
+		return fs, nil
+	}
+	switch fsConfig.Provider {
+	case sdk.S3FilesystemProvider, sdk.GCSFilesystemProvider, sdk.AzureBlobFilesystemProvider,
+		sdk.SFTPFilesystemProvider:
+	default:
+		return fs, nil
+	}
+	if err := fsConfig.Encryption.Validate(); err != nil {
+		return nil, err
+	}
+	if err := fsConfig.Encryption.Passphrase.TryDecrypt(); err != nil {
+		return nil, err
+	}
+	localTempDir := tempPath
+	if localTempDir == "" {
+		localTempDir = filepath.Clean(os.TempDir())
+	}
+	encryptedFs := &EncryptedFs{
+		Fs:           fs,
+		masterKey:    []byte(fsConfig.Encryption.Passphrase.GetPayload()),
+		localTempDir: localTempDir,
+	}
+	if _, ok := fs.(FsFileCopier); ok {
+		return &encryptedCopierFs{EncryptedFs: encryptedFs}, nil
+	}
+	return encryptedFs, nil
+}
+
+// Stat returns a FileInfo describing the named file, the size is the decrypted one
+func (fs *EncryptedFs) Stat(name string) (os.FileInfo, error) {
+	info, err := fs.Fs.Stat(name)
+	if err != nil {
+		return info, err
+	}
+	return fs.ConvertFileInfo(info), nil
+}
+
+// Lstat returns a FileInfo describing the named file, the size is the decrypted one
+func (fs *EncryptedFs) Lstat(name string) (os.FileInfo, error) {
+	info, err := fs.Fs.Lstat(name)
+	if err != nil {
+		return info, err
+	}
+	return fs.ConvertFileInfo(info), nil
+}
+
+// Open opens the named file for reading, the contents are decrypted on the fly.
+// Only the encrypted packages needed to serve the requested offset are downloaded
+func (fs *EncryptedFs) Open(name string, offset int64) (File, *pipeat.PipeReaderAt, func(), error) {
+	info, err := fs.Stat(name)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	r, w, err := pipeat.PipeInDir(fs.localTempDir)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if offset >= info.Size() {
+		go func() {
+			w.Close()
+			fsLog(fs, logger.LevelDebug, "zero bytes download completed, path: %#v", name)
+		}()
+		return nil, r, nil, nil
+	}
+	var key [32]byte
+	var reader io.ReadCloser
+	var cancelFn func()
+	sequenceNumber := offset / sioPayloadSize
+	if sequenceNumber == 0 {
+		reader, cancelFn, err = fs.openEncryptedFile(name, 0)
+		if err == nil {
+			key, err = fs.loadEncryptionKey(reader)
+		}
+	} else {
+		key, err = fs.getEncryptionKey(name)
+		if err == nil {
+			reader, cancelFn, err = fs.openEncryptedFile(name, headerV10Size+sequenceNumber*sioPackageSize)
+		}
+	}
+	if err != nil {
+		if cancelFn != nil {
+			cancelFn()
+		}
+		if reader != nil {
+			reader.Close()
+		}
+		r.Close()
+		w.Close()
+		return nil, nil, nil, err
+	}
+
+	go func() {
+		var n int64
+		config := fs.getSIOConfig(key)
+		config.SequenceNumber = uint32(sequenceNumber)
+		decReader, err := sio.DecryptReader(reader, config)
+		if err == nil {
+			_, err = io.CopyN(io.Discard, decReader, offset%sioPayloadSize)
+			if err == nil {
+				n, err = io.Copy(w, decReader)
+			}
+		}
+		reader.Close()
+		w.CloseWithError(err) //nolint:errcheck
+		fsLog(fs, logger.LevelDebug, "download completed, path: %#v offset: %v size: %v, err: %v", name, offset, n, err)
+	}()
+
+	return nil, r, cancelFn, nil
+}
+
+// Create creates or opens the named file for writing, the contents are encrypted
+// on the fly before writing them to the wrapped filesystem
+func (fs *EncryptedFs) Create(name string, flag int) (File, *PipeWriter, func(), error) {
+	header := encryptedFileHeader{
+		version: version10,
+		nonce:   make([]byte, nonceV10Size),
+	}
+	_, err := io.ReadFull(rand.Reader, header.nonce)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	key, err := fs.deriveEncryptionKey(header.nonce)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	writer, cancelFn, err := fs.createEncryptedFile(name, flag)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	r, w, err := pipeat.PipeInDir(fs.localTempDir)
+	if err != nil {
+		if cancelFn != nil {
+			cancelFn()
+		}
+		writer.Close()
+		return nil, nil, nil, err
+	}
+	p := NewPipeWriter(w)
+
+	go func() {
+		var n int64
+		err := header.Store(writer)
+		if err == nil {
+			n, err = sio.Encrypt(writer, r, fs.getSIOConfig(key))
+		}
+		if err != nil && cancelFn != nil {
+			cancelFn()
+		}
+		errClose := writer.Close()
+		if err == nil && errClose != nil {
+			err = errClose
+		}
+		r.CloseWithError(err) //nolint:errcheck
+		p.Done(err)
+		fsLog(fs, logger.LevelDebug, "upload completed, path: %#v, readed bytes: %v, err: %v", name, n, err)
+	}()
+
+	return nil, p, cancelFn, nil
+}
+
+// Truncate changes the size of the named file
+func (*EncryptedFs) Truncate(name string, size int64) error {
+	return ErrVfsUnsupported
+}
+
+// ReadDir reads the directory named by dirname and returns
+// a list of directory entries, the sizes are the decrypted ones
+func (fs *EncryptedFs) ReadDir(dirname string) ([]os.FileInfo, error) {
+	list, err := fs.Fs.ReadDir(dirname)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]os.FileInfo, 0, len(list))
+	for _, info := range list {
+		result = append(result, fs.ConvertFileInfo(info))
+	}
+	return result, nil
+}
+
+// IsUploadResumeSupported returns false sio does not support random access writes
+func (*EncryptedFs) IsUploadResumeSupported() bool {
+	return false
+}
+
+// ScanRootDirContents returns the number of files contained in the root
+// directory and their decrypted size
+func (fs *EncryptedFs) ScanRootDirContents() (int, int64, error) {
+	root, err := fs.Fs.ResolvePath("/")
+	if err != nil {
+		return 0, 0, err
+	}
+	return fs.getDirSize(root)
+}
+
+// GetDirSize returns the number of files and the decrypted size for a folder
+// including any subfolders
+func (fs *EncryptedFs) GetDirSize(dirname string) (int, int64, error) {
+	if !IsSFTPFs(fs.Fs) {
+		// not supported for cloud storage backends
+		return fs.Fs.GetDirSize(dirname)
+	}
+	isDir, err := IsDirectory(fs, dirname)
+	if err != nil || !isDir {
+		return 0, 0, err
+	}
+	return fs.getDirSize(dirname)
+}
+
+// Walk walks the file tree rooted at root, calling walkFn for each file or
+// directory in the tree, including root. The sizes are the decrypted ones
+func (fs *EncryptedFs) Walk(root string, walkFn filepath.WalkFunc) error {
+	return fs.Fs.Walk(root, func(walkedPath string, info os.FileInfo, err error) error {
+		if info != nil {
+			info = fs.ConvertFileInfo(info)
+		}
+		return walkFn(walkedPath, info, err)
+	})
+}
+
+// GetMimeType returns the content type
+func (fs *EncryptedFs) GetMimeType(name string) (string, error) {
+	reader, cancelFn, err := fs.openEncryptedFile(name, 0)
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		if cancelFn != nil {
+			cancelFn()
+		}
+		reader.Close()
+	}()
+
+	key, err := fs.loadEncryptionKey(reader)
+	if err != nil {
+		return "", err
+	}
+	decReader, err := sio.DecryptReader(reader, fs.getSIOConfig(key))
+	if err != nil {
+		return "", err
+	}
+	buf := make([]byte, 512)
+	n, err := io.ReadFull(decReader, buf)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return "", err
+	}
+	return http.DetectContentType(buf[:n]), nil
+}
+
+// ConvertFileInfo returns a FileInfo with the decrypted size
+func (*EncryptedFs) ConvertFileInfo(info os.FileInfo) os.FileInfo {
+	if !info.Mode().IsRegular() {
+		return info
+	}
+	return &encryptedFileInfo{
+		FileInfo: info,
+		size:     getDecryptedSize(info.Size()),
+	}
+}
+
+// CopyFile implements the FsFileCopier interface, the encrypted contents
+// are copied server side by the wrapped filesystem
+func (fs *encryptedCopierFs) CopyFile(source, target string) error {
+	return fs.Fs.(FsFileCopier).CopyFile(source, target)
+}
+
+func (fs *EncryptedFs) getDirSize(dirname string) (int, int64, error) {
+	numFiles := 0
+	size := int64(0)
+	list, err := fs.ReadDir(dirname)
+	if err != nil {
+		return numFiles, size, err
+	}
+	for _, info := range list {
+		if info.IsDir() {
+			dirFiles, dirSize, err := fs.getDirSize(fs.Join(dirname, info.Name()))
+			if err != nil {
+				return numFiles, size, err
+			}
+			numFiles += dirFiles
+			size += dirSize
+			continue
+		}
+		if info.Mode().IsRegular() {
+			numFiles++
+			size += info.Size()
+		}
+	}
+	return numFiles, size, nil
+}
+
+// openEncryptedFile opens the named file on the wrapped filesystem and returns
+// a reader for the encrypted contents starting at the specified offset
+func (fs *EncryptedFs) openEncryptedFile(name string, offset int64) (io.ReadCloser, func(), error) {
+	f, r, cancelFn, err := fs.Fs.Open(name, offset)
+	if err != nil {
+		return nil, nil, err
+	}
+	if f == nil {
+		return r, cancelFn, nil
+	}
+	// unbuffered SFTP returns the remote file without seeking
+	if offset > 0 {
+		if _, err := f.Seek(offset, io.SeekStart); err != nil {
+			f.Close()
+			return nil, nil, err
+		}
+	}
+	return f, cancelFn, nil
+}
+
+// createEncryptedFile creates the named file on the wrapped filesystem and returns
+// a writer for the encrypted contents. Closing the writer waits for the upload to end
+func (fs *EncryptedFs) createEncryptedFile(name string, flag int) (io.WriteCloser, func(), error) {
+	f, w, cancelFn, err := fs.Fs.Create(name, flag)
+	if err != nil {
+		return nil, nil, err
+	}
+	if f != nil {
+		return f, cancelFn, nil
+	}
+	return w, cancelFn, nil
+}
+
+// getEncryptionKey reads the header for the named file and returns the derived key
+func (fs *EncryptedFs) getEncryptionKey(name string) ([32]byte, error) {
+	reader, cancelFn, err := fs.openEncryptedFile(name, 0)
+	if err != nil {
+		return [32]byte{}, err
+	}
+	key, err := fs.loadEncryptionKey(reader)
+	if cancelFn != nil {
+		cancelFn()
+	}
+	reader.Close()
+	return key, err
+}
+
+func (fs *EncryptedFs) loadEncryptionKey(reader io.Reader) ([32]byte, error) {
+	header := encryptedFileHeader{}
+	if err := header.Load(reader); err != nil {
+		return [32]byte{}, err
+	}
+	return fs.deriveEncryptionKey(header.nonce)
+}
+
+func (fs *EncryptedFs) deriveEncryptionKey(nonce []byte) ([32]byte, error) {
+	var key [32]byte
+	kdf := hkdf.New(sha256.New, fs.masterKey, nonce, nil)
+	_, err := io.ReadFull(kdf, key[:])
+	return key, err
+}
+
+func (*EncryptedFs) getSIOConfig(key [32]byte) sio.Config {
+	return sio.Config{
+		MinVersion: sio.Version20,
+		MaxVersion: sio.Version20,
+		Key:        key[:],
+	}
+}
+
+// encryptedFileInfo reports the decrypted size and preserves all the other
+// attributes returned by the wrapped filesystem
+type encryptedFileInfo struct {
+	os.FileInfo
+	size int64
+}
+
+// Size returns the decrypted size
+func (fi *encryptedFileInfo) Size() int64 {
+	return fi.size
+}
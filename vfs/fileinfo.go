@@ -12,6 +12,8 @@ type FileInfo struct {
 	sizeInBytes int64
 	modTime     time.Time
 	mode        os.FileMode
+	// etag is set for cloud storage objects, if available
+	etag string
 }
 
 // NewFileInfo creates file info.
@@ -24,6 +24,10 @@ type Filesystem struct {
 	AzBlobConfig   AzBlobFsConfig         `json:"azblobconfig,omitempty"`
 	CryptConfig    CryptFsConfig          `json:"cryptconfig,omitempty"`
 	SFTPConfig     SFTPFsConfig           `json:"sftpconfig,omitempty"`
+	FTPConfig      FTPFsConfig            `json:"ftpconfig,omitempty"`
+	WebDAVConfig   WebDAVFsConfig         `json:"webdavconfig,omitempty"`
+	ReadCache      ReadCacheConfig        `json:"read_cache,omitempty"`
+	Encryption     EncryptionConfig       `json:"encryption,omitempty"`
 }
 
 // SetEmptySecretsIfNil sets the secrets to empty if nil
@@ -49,6 +53,18 @@ func (f *Filesystem) SetEmptySecretsIfNil() {
 	if f.SFTPConfig.PrivateKey == nil {
 		f.SFTPConfig.PrivateKey = kms.NewEmptySecret()
 	}
+	if f.FTPConfig.Password == nil {
+		f.FTPConfig.Password = kms.NewEmptySecret()
+	}
+	if f.WebDAVConfig.Password == nil {
+		f.WebDAVConfig.Password = kms.NewEmptySecret()
+	}
+	if f.WebDAVConfig.BearerToken == nil {
+		f.WebDAVConfig.BearerToken = kms.NewEmptySecret()
+	}
+	if f.Encryption.Passphrase == nil {
+		f.Encryption.Passphrase = kms.NewEmptySecret()
+	}
 }
 
 // SetNilSecretsIfEmpty set the secrets to nil if empty.
@@ -76,6 +92,18 @@ func (f *Filesystem) SetNilSecretsIfEmpty() {
 	if f.SFTPConfig.PrivateKey != nil && f.SFTPConfig.PrivateKey.IsEmpty() {
 		f.SFTPConfig.PrivateKey = nil
 	}
+	if f.FTPConfig.Password != nil && f.FTPConfig.Password.IsEmpty() {
+		f.FTPConfig.Password = nil
+	}
+	if f.WebDAVConfig.Password != nil && f.WebDAVConfig.Password.IsEmpty() {
+		f.WebDAVConfig.Password = nil
+	}
+	if f.WebDAVConfig.BearerToken != nil && f.WebDAVConfig.BearerToken.IsEmpty() {
+		f.WebDAVConfig.BearerToken = nil
+	}
+	if f.Encryption.Passphrase != nil && f.Encryption.Passphrase.IsEmpty() {
+		f.Encryption.Passphrase = nil
+	}
 }
 
 // IsEqual returns true if the fs is equal to other
@@ -85,15 +113,22 @@ func (f *Filesystem) IsEqual(other *Filesystem) bool {
 	}
 	switch f.Provider {
 	case sdk.S3FilesystemProvider:
-		return f.S3Config.isEqual(&other.S3Config)
+		return f.S3Config.isEqual(&other.S3Config) && f.ReadCache.isEqual(&other.ReadCache) &&
+			f.Encryption.isEqual(&other.Encryption)
 	case sdk.GCSFilesystemProvider:
-		return f.GCSConfig.isEqual(&other.GCSConfig)
+		return f.GCSConfig.isEqual(&other.GCSConfig) && f.ReadCache.isEqual(&other.ReadCache) &&
+			f.Encryption.isEqual(&other.Encryption)
 	case sdk.AzureBlobFilesystemProvider:
-		return f.AzBlobConfig.isEqual(&other.AzBlobConfig)
+		return f.AzBlobConfig.isEqual(&other.AzBlobConfig) && f.ReadCache.isEqual(&other.ReadCache) &&
+			f.Encryption.isEqual(&other.Encryption)
 	case sdk.CryptedFilesystemProvider:
 		return f.CryptConfig.isEqual(&other.CryptConfig)
 	case sdk.SFTPFilesystemProvider:
-		return f.SFTPConfig.isEqual(&other.SFTPConfig)
+		return f.SFTPConfig.isEqual(&other.SFTPConfig) && f.Encryption.isEqual(&other.Encryption)
+	case sdk.FTPFilesystemProvider:
+		return f.FTPConfig.isEqual(&other.FTPConfig)
+	case sdk.WebDAVFilesystemProvider:
+		return f.WebDAVConfig.isEqual(&other.WebDAVConfig)
 	default:
 		return true
 	}
@@ -107,6 +142,15 @@ func (f *Filesystem) Validate(helper ValidatorHelper) error {
 		if err := f.S3Config.Validate(); err != nil {
 			return util.NewValidationError(fmt.Sprintf("could not validate s3config: %v", err))
 		}
+		if err := f.ReadCache.Validate(); err != nil {
+			return util.NewValidationError(fmt.Sprintf("could not validate read cache config: %v", err))
+		}
+		if err := f.Encryption.Validate(); err != nil {
+			return util.NewValidationError(fmt.Sprintf("could not validate encryption config: %v", err))
+		}
+		if err := f.Encryption.EncryptCredentials(helper.GetEncryptionAdditionalData()); err != nil {
+			return util.NewValidationError(fmt.Sprintf("could not encrypt encryption passphrase: %v", err))
+		}
 		if err := f.S3Config.EncryptCredentials(helper.GetEncryptionAdditionalData()); err != nil {
 			return util.NewValidationError(fmt.Sprintf("could not encrypt s3 access secret: %v", err))
 		}
@@ -114,20 +158,42 @@ func (f *Filesystem) Validate(helper ValidatorHelper) error {
 		f.AzBlobConfig = AzBlobFsConfig{}
 		f.CryptConfig = CryptFsConfig{}
 		f.SFTPConfig = SFTPFsConfig{}
+		f.FTPConfig = FTPFsConfig{}
+		f.WebDAVConfig = WebDAVFsConfig{}
 		return nil
 	case sdk.GCSFilesystemProvider:
 		if err := f.GCSConfig.Validate(helper.GetGCSCredentialsFilePath()); err != nil {
 			return util.NewValidationError(fmt.Sprintf("could not validate GCS config: %v", err))
 		}
+		if err := f.ReadCache.Validate(); err != nil {
+			return util.NewValidationError(fmt.Sprintf("could not validate read cache config: %v", err))
+		}
+		if err := f.Encryption.Validate(); err != nil {
+			return util.NewValidationError(fmt.Sprintf("could not validate encryption config: %v", err))
+		}
+		if err := f.Encryption.EncryptCredentials(helper.GetEncryptionAdditionalData()); err != nil {
+			return util.NewValidationError(fmt.Sprintf("could not encrypt encryption passphrase: %v", err))
+		}
 		f.S3Config = S3FsConfig{}
 		f.AzBlobConfig = AzBlobFsConfig{}
 		f.CryptConfig = CryptFsConfig{}
 		f.SFTPConfig = SFTPFsConfig{}
+		f.FTPConfig = FTPFsConfig{}
+		f.WebDAVConfig = WebDAVFsConfig{}
 		return nil
 	case sdk.AzureBlobFilesystemProvider:
 		if err := f.AzBlobConfig.Validate(); err != nil {
 			return util.NewValidationError(fmt.Sprintf("could not validate Azure Blob config: %v", err))
 		}
+		if err := f.ReadCache.Validate(); err != nil {
+			return util.NewValidationError(fmt.Sprintf("could not validate read cache config: %v", err))
+		}
+		if err := f.Encryption.Validate(); err != nil {
+			return util.NewValidationError(fmt.Sprintf("could not validate encryption config: %v", err))
+		}
+		if err := f.Encryption.EncryptCredentials(helper.GetEncryptionAdditionalData()); err != nil {
+			return util.NewValidationError(fmt.Sprintf("could not encrypt encryption passphrase: %v", err))
+		}
 		if err := f.AzBlobConfig.EncryptCredentials(helper.GetEncryptionAdditionalData()); err != nil {
 			return util.NewValidationError(fmt.Sprintf("could not encrypt Azure blob account key: %v", err))
 		}
@@ -135,6 +201,8 @@ func (f *Filesystem) Validate(helper ValidatorHelper) error {
 		f.GCSConfig = GCSFsConfig{}
 		f.CryptConfig = CryptFsConfig{}
 		f.SFTPConfig = SFTPFsConfig{}
+		f.FTPConfig = FTPFsConfig{}
+		f.WebDAVConfig = WebDAVFsConfig{}
 		return nil
 	case sdk.CryptedFilesystemProvider:
 		if err := f.CryptConfig.Validate(); err != nil {
@@ -147,6 +215,10 @@ func (f *Filesystem) Validate(helper ValidatorHelper) error {
 		f.GCSConfig = GCSFsConfig{}
 		f.AzBlobConfig = AzBlobFsConfig{}
 		f.SFTPConfig = SFTPFsConfig{}
+		f.FTPConfig = FTPFsConfig{}
+		f.WebDAVConfig = WebDAVFsConfig{}
+		f.ReadCache = ReadCacheConfig{}
+		f.Encryption = EncryptionConfig{}
 		return nil
 	case sdk.SFTPFilesystemProvider:
 		if err := f.SFTPConfig.Validate(); err != nil {
@@ -155,10 +227,51 @@ func (f *Filesystem) Validate(helper ValidatorHelper) error {
 		if err := f.SFTPConfig.EncryptCredentials(helper.GetEncryptionAdditionalData()); err != nil {
 			return util.NewValidationError(fmt.Sprintf("could not encrypt SFTP fs credentials: %v", err))
 		}
+		if err := f.Encryption.Validate(); err != nil {
+			return util.NewValidationError(fmt.Sprintf("could not validate encryption config: %v", err))
+		}
+		if err := f.Encryption.EncryptCredentials(helper.GetEncryptionAdditionalData()); err != nil {
+			return util.NewValidationError(fmt.Sprintf("could not encrypt encryption passphrase: %v", err))
+		}
+		f.S3Config = S3FsConfig{}
+		f.GCSConfig = GCSFsConfig{}
+		f.AzBlobConfig = AzBlobFsConfig{}
+		f.CryptConfig = CryptFsConfig{}
+		f.FTPConfig = FTPFsConfig{}
+		f.WebDAVConfig = WebDAVFsConfig{}
+		f.ReadCache = ReadCacheConfig{}
+		return nil
+	case sdk.FTPFilesystemProvider:
+		if err := f.FTPConfig.Validate(); err != nil {
+			return util.NewValidationError(fmt.Sprintf("could not validate FTP fs config: %v", err))
+		}
+		if err := f.FTPConfig.EncryptCredentials(helper.GetEncryptionAdditionalData()); err != nil {
+			return util.NewValidationError(fmt.Sprintf("could not encrypt FTP fs credentials: %v", err))
+		}
 		f.S3Config = S3FsConfig{}
 		f.GCSConfig = GCSFsConfig{}
 		f.AzBlobConfig = AzBlobFsConfig{}
 		f.CryptConfig = CryptFsConfig{}
+		f.SFTPConfig = SFTPFsConfig{}
+		f.WebDAVConfig = WebDAVFsConfig{}
+		f.ReadCache = ReadCacheConfig{}
+		f.Encryption = EncryptionConfig{}
+		return nil
+	case sdk.WebDAVFilesystemProvider:
+		if err := f.WebDAVConfig.Validate(); err != nil {
+			return util.NewValidationError(fmt.Sprintf("could not validate WebDAV fs config: %v", err))
+		}
+		if err := f.WebDAVConfig.EncryptCredentials(helper.GetEncryptionAdditionalData()); err != nil {
+			return util.NewValidationError(fmt.Sprintf("could not encrypt WebDAV fs credentials: %v", err))
+		}
+		f.S3Config = S3FsConfig{}
+		f.GCSConfig = GCSFsConfig{}
+		f.AzBlobConfig = AzBlobFsConfig{}
+		f.CryptConfig = CryptFsConfig{}
+		f.SFTPConfig = SFTPFsConfig{}
+		f.FTPConfig = FTPFsConfig{}
+		f.ReadCache = ReadCacheConfig{}
+		f.Encryption = EncryptionConfig{}
 		return nil
 	default:
 		f.Provider = sdk.LocalFilesystemProvider
@@ -167,6 +280,10 @@ func (f *Filesystem) Validate(helper ValidatorHelper) error {
 		f.AzBlobConfig = AzBlobFsConfig{}
 		f.CryptConfig = CryptFsConfig{}
 		f.SFTPConfig = SFTPFsConfig{}
+		f.FTPConfig = FTPFsConfig{}
+		f.WebDAVConfig = WebDAVFsConfig{}
+		f.ReadCache = ReadCacheConfig{}
+		f.Encryption = EncryptionConfig{}
 		return nil
 	}
 }
@@ -179,10 +296,16 @@ func (f *Filesystem) HasRedactedSecret() bool {
 		if f.S3Config.AccessSecret.IsRedacted() {
 			return true
 		}
+		if f.Encryption.Passphrase.IsRedacted() {
+			return true
+		}
 	case sdk.GCSFilesystemProvider:
 		if f.GCSConfig.Credentials.IsRedacted() {
 			return true
 		}
+		if f.Encryption.Passphrase.IsRedacted() {
+			return true
+		}
 	case sdk.AzureBlobFilesystemProvider:
 		if f.AzBlobConfig.AccountKey.IsRedacted() {
 			return true
@@ -190,6 +313,9 @@ func (f *Filesystem) HasRedactedSecret() bool {
 		if f.AzBlobConfig.SASURL.IsRedacted() {
 			return true
 		}
+		if f.Encryption.Passphrase.IsRedacted() {
+			return true
+		}
 	case sdk.CryptedFilesystemProvider:
 		if f.CryptConfig.Passphrase.IsRedacted() {
 			return true
@@ -201,6 +327,20 @@ func (f *Filesystem) HasRedactedSecret() bool {
 		if f.SFTPConfig.PrivateKey.IsRedacted() {
 			return true
 		}
+		if f.Encryption.Passphrase.IsRedacted() {
+			return true
+		}
+	case sdk.FTPFilesystemProvider:
+		if f.FTPConfig.Password.IsRedacted() {
+			return true
+		}
+	case sdk.WebDAVFilesystemProvider:
+		if f.WebDAVConfig.Password.IsRedacted() {
+			return true
+		}
+		if f.WebDAVConfig.BearerToken.IsRedacted() {
+			return true
+		}
 	}
 
 	return false
@@ -211,14 +351,22 @@ func (f *Filesystem) HideConfidentialData() {
 	switch f.Provider {
 	case sdk.S3FilesystemProvider:
 		f.S3Config.HideConfidentialData()
+		f.Encryption.HideConfidentialData()
 	case sdk.GCSFilesystemProvider:
 		f.GCSConfig.HideConfidentialData()
+		f.Encryption.HideConfidentialData()
 	case sdk.AzureBlobFilesystemProvider:
 		f.AzBlobConfig.HideConfidentialData()
+		f.Encryption.HideConfidentialData()
 	case sdk.CryptedFilesystemProvider:
 		f.CryptConfig.HideConfidentialData()
 	case sdk.SFTPFilesystemProvider:
 		f.SFTPConfig.HideConfidentialData()
+		f.Encryption.HideConfidentialData()
+	case sdk.FTPFilesystemProvider:
+		f.FTPConfig.HideConfidentialData()
+	case sdk.WebDAVFilesystemProvider:
+		f.WebDAVConfig.HideConfidentialData()
 	}
 }
 
@@ -284,6 +432,39 @@ func (f *Filesystem) GetACopy() Filesystem {
 				BufferSize:              f.SFTPConfig.BufferSize,
 			},
 		},
+		FTPConfig: FTPFsConfig{
+			FTPFsConfig: sdk.FTPFsConfig{
+				Endpoint:      f.FTPConfig.Endpoint,
+				Username:      f.FTPConfig.Username,
+				Password:      f.FTPConfig.Password.Clone(),
+				Prefix:        f.FTPConfig.Prefix,
+				TLSMode:       f.FTPConfig.TLSMode,
+				SkipTLSVerify: f.FTPConfig.SkipTLSVerify,
+				DisableEPSV:   f.FTPConfig.DisableEPSV,
+			},
+		},
+		WebDAVConfig: WebDAVFsConfig{
+			WebDAVFsConfig: sdk.WebDAVFsConfig{
+				Endpoint:      f.WebDAVConfig.Endpoint,
+				Username:      f.WebDAVConfig.Username,
+				Password:      f.WebDAVConfig.Password.Clone(),
+				BearerToken:   f.WebDAVConfig.BearerToken.Clone(),
+				Prefix:        f.WebDAVConfig.Prefix,
+				SkipTLSVerify: f.WebDAVConfig.SkipTLSVerify,
+			},
+		},
+		ReadCache: ReadCacheConfig{
+			ReadCacheConfig: sdk.ReadCacheConfig{
+				MaxSize:  f.ReadCache.MaxSize,
+				TTL:      f.ReadCache.TTL,
+				Eviction: f.ReadCache.Eviction,
+			},
+		},
+		Encryption: EncryptionConfig{
+			EncryptionConfig: sdk.EncryptionConfig{
+				Passphrase: f.Encryption.Passphrase.Clone(),
+			},
+		},
 	}
 	if len(f.SFTPConfig.Fingerprints) > 0 {
 		fs.SFTPConfig.Fingerprints = make([]string, len(f.SFTPConfig.Fingerprints))
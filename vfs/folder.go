@@ -23,8 +23,30 @@ type BaseVirtualFolder struct {
 	UsedQuotaFiles int `json:"used_quota_files"`
 	// Last quota update as unix timestamp in milliseconds
 	LastQuotaUpdate int64 `json:"last_quota_update"`
+	// Maximum data transfer allowed for uploads as MB. 0 means no limit.
+	// Transfers inside the folder are also counted for the user data transfer limits
+	UploadDataTransfer int64 `json:"upload_data_transfer"`
+	// Maximum data transfer allowed for downloads as MB. 0 means no limit
+	DownloadDataTransfer int64 `json:"download_data_transfer"`
+	// Maximum total data transfer as MB. If set, the upload and download limits are ignored.
+	// 0 means no limit
+	TotalDataTransfer int64 `json:"total_data_transfer"`
+	// Uploaded data as bytes since the last data transfer reset
+	UsedUploadDataTransfer int64 `json:"used_upload_data_transfer"`
+	// Downloaded data as bytes since the last data transfer reset
+	UsedDownloadDataTransfer int64 `json:"used_download_data_transfer"`
+	// Last data transfer reset as unix timestamp in milliseconds
+	LastDataTransferReset int64 `json:"last_data_transfer_reset"`
+	// Data transfer reset period, empty means never
+	DataTransferResetPeriod sdk.DataTransferResetPeriod `json:"data_transfer_reset_period,omitempty"`
 	// list of usernames associated with this virtual folder
 	Users []string `json:"users,omitempty"`
+	// list of group names associated with this virtual folder
+	Groups []string `json:"groups,omitempty"`
+	// Data retention policies, the paths are relative to the folder root.
+	// They are applied by the scheduled retention checks for the users
+	// mapping this folder
+	DataRetention []sdk.DataRetentionPolicy `json:"data_retention,omitempty"`
 	// Filesystem configuration details
 	FsConfig Filesystem `json:"filesystem"`
 }
@@ -43,24 +65,71 @@ func (v *BaseVirtualFolder) GetGCSCredentialsFilePath() string {
 func (v *BaseVirtualFolder) GetACopy() BaseVirtualFolder {
 	users := make([]string, len(v.Users))
 	copy(users, v.Users)
+	groups := make([]string, len(v.Groups))
+	copy(groups, v.Groups)
+	var retention []sdk.DataRetentionPolicy
+	if len(v.DataRetention) > 0 {
+		retention = make([]sdk.DataRetentionPolicy, len(v.DataRetention))
+		copy(retention, v.DataRetention)
+	}
 	return BaseVirtualFolder{
-		ID:              v.ID,
-		Name:            v.Name,
-		Description:     v.Description,
-		MappedPath:      v.MappedPath,
-		UsedQuotaSize:   v.UsedQuotaSize,
-		UsedQuotaFiles:  v.UsedQuotaFiles,
-		LastQuotaUpdate: v.LastQuotaUpdate,
-		Users:           users,
-		FsConfig:        v.FsConfig.GetACopy(),
+		ID:                       v.ID,
+		Name:                     v.Name,
+		Description:              v.Description,
+		MappedPath:               v.MappedPath,
+		UsedQuotaSize:            v.UsedQuotaSize,
+		UsedQuotaFiles:           v.UsedQuotaFiles,
+		LastQuotaUpdate:          v.LastQuotaUpdate,
+		UploadDataTransfer:       v.UploadDataTransfer,
+		DownloadDataTransfer:     v.DownloadDataTransfer,
+		TotalDataTransfer:        v.TotalDataTransfer,
+		UsedUploadDataTransfer:   v.UsedUploadDataTransfer,
+		UsedDownloadDataTransfer: v.UsedDownloadDataTransfer,
+		LastDataTransferReset:    v.LastDataTransferReset,
+		DataTransferResetPeriod:  v.DataTransferResetPeriod,
+		Users:                    users,
+		Groups:                   groups,
+		DataRetention:            retention,
+		FsConfig:                 v.FsConfig.GetACopy(),
 	}
 }
 
+// HasTransferQuotaRestrictions returns true if there are data transfer limits for this folder
+func (v *BaseVirtualFolder) HasTransferQuotaRestrictions() bool {
+	return v.UploadDataTransfer > 0 || v.DownloadDataTransfer > 0 || v.TotalDataTransfer > 0
+}
+
+// GetDataTransferSummary returns the used data transfer and the configured limits as string
+func (v *BaseVirtualFolder) GetDataTransferSummary() string {
+	if v.TotalDataTransfer > 0 {
+		return fmt.Sprintf("Transfer: %v/%v", util.ByteCountIEC(v.UsedUploadDataTransfer+v.UsedDownloadDataTransfer),
+			util.ByteCountIEC(v.TotalDataTransfer*1048576))
+	}
+	var result string
+	if v.UploadDataTransfer > 0 {
+		result = fmt.Sprintf("Upload: %v/%v", util.ByteCountIEC(v.UsedUploadDataTransfer),
+			util.ByteCountIEC(v.UploadDataTransfer*1048576))
+	}
+	if v.DownloadDataTransfer > 0 {
+		if result != "" {
+			result += ". "
+		}
+		result += fmt.Sprintf("Download: %v/%v", util.ByteCountIEC(v.UsedDownloadDataTransfer),
+			util.ByteCountIEC(v.DownloadDataTransfer*1048576))
+	}
+	return result
+}
+
 // GetUsersAsString returns the list of users as comma separated string
 func (v *BaseVirtualFolder) GetUsersAsString() string {
 	return strings.Join(v.Users, ",")
 }
 
+// GetGroupsAsString returns the list of groups as comma separated string
+func (v *BaseVirtualFolder) GetGroupsAsString() string {
+	return strings.Join(v.Groups, ",")
+}
+
 // GetQuotaSummary returns used quota and last update as string
 func (v *BaseVirtualFolder) GetQuotaSummary() string {
 	var result string
@@ -90,6 +159,10 @@ func (v *BaseVirtualFolder) GetStorageDescrition() string {
 		return fmt.Sprintf("Encrypted: %v", v.MappedPath)
 	case sdk.SFTPFilesystemProvider:
 		return fmt.Sprintf("SFTP: %v", v.FsConfig.SFTPConfig.Endpoint)
+	case sdk.FTPFilesystemProvider:
+		return fmt.Sprintf("FTP: %v", v.FsConfig.FTPConfig.Endpoint)
+	case sdk.WebDAVFilesystemProvider:
+		return fmt.Sprintf("WebDAV: %v", v.FsConfig.WebDAVConfig.Endpoint)
 	default:
 		return ""
 	}
@@ -105,14 +178,22 @@ func (v *BaseVirtualFolder) hideConfidentialData() {
 	switch v.FsConfig.Provider {
 	case sdk.S3FilesystemProvider:
 		v.FsConfig.S3Config.HideConfidentialData()
+		v.FsConfig.Encryption.HideConfidentialData()
 	case sdk.GCSFilesystemProvider:
 		v.FsConfig.GCSConfig.HideConfidentialData()
+		v.FsConfig.Encryption.HideConfidentialData()
 	case sdk.AzureBlobFilesystemProvider:
 		v.FsConfig.AzBlobConfig.HideConfidentialData()
+		v.FsConfig.Encryption.HideConfidentialData()
 	case sdk.CryptedFilesystemProvider:
 		v.FsConfig.CryptConfig.HideConfidentialData()
 	case sdk.SFTPFilesystemProvider:
 		v.FsConfig.SFTPConfig.HideConfidentialData()
+		v.FsConfig.Encryption.HideConfidentialData()
+	case sdk.FTPFilesystemProvider:
+		v.FsConfig.FTPConfig.HideConfidentialData()
+	case sdk.WebDAVFilesystemProvider:
+		v.FsConfig.WebDAVConfig.HideConfidentialData()
 	}
 }
 
@@ -131,10 +212,16 @@ func (v *BaseVirtualFolder) HasRedactedSecret() bool {
 		if v.FsConfig.S3Config.AccessSecret.IsRedacted() {
 			return true
 		}
+		if v.FsConfig.Encryption.Passphrase.IsRedacted() {
+			return true
+		}
 	case sdk.GCSFilesystemProvider:
 		if v.FsConfig.GCSConfig.Credentials.IsRedacted() {
 			return true
 		}
+		if v.FsConfig.Encryption.Passphrase.IsRedacted() {
+			return true
+		}
 	case sdk.AzureBlobFilesystemProvider:
 		if v.FsConfig.AzBlobConfig.AccountKey.IsRedacted() {
 			return true
@@ -142,6 +229,9 @@ func (v *BaseVirtualFolder) HasRedactedSecret() bool {
 		if v.FsConfig.AzBlobConfig.SASURL.IsRedacted() {
 			return true
 		}
+		if v.FsConfig.Encryption.Passphrase.IsRedacted() {
+			return true
+		}
 	case sdk.CryptedFilesystemProvider:
 		if v.FsConfig.CryptConfig.Passphrase.IsRedacted() {
 			return true
@@ -153,6 +243,20 @@ func (v *BaseVirtualFolder) HasRedactedSecret() bool {
 		if v.FsConfig.SFTPConfig.PrivateKey.IsRedacted() {
 			return true
 		}
+		if v.FsConfig.Encryption.Passphrase.IsRedacted() {
+			return true
+		}
+	case sdk.FTPFilesystemProvider:
+		if v.FsConfig.FTPConfig.Password.IsRedacted() {
+			return true
+		}
+	case sdk.WebDAVFilesystemProvider:
+		if v.FsConfig.WebDAVConfig.Password.IsRedacted() {
+			return true
+		}
+		if v.FsConfig.WebDAVConfig.BearerToken.IsRedacted() {
+			return true
+		}
 	}
 	return false
 }
@@ -174,22 +278,35 @@ type VirtualFolder struct {
 
 // GetFilesystem returns the filesystem for this folder
 func (v *VirtualFolder) GetFilesystem(connectionID string, forbiddenSelfUsers []string) (Fs, error) {
+	var fs Fs
+	var err error
+
 	switch v.FsConfig.Provider {
 	case sdk.S3FilesystemProvider:
-		return NewS3Fs(connectionID, v.MappedPath, v.VirtualPath, v.FsConfig.S3Config)
+		fs, err = NewS3Fs(connectionID, v.MappedPath, v.VirtualPath, v.FsConfig.S3Config)
 	case sdk.GCSFilesystemProvider:
 		config := v.FsConfig.GCSConfig
 		config.CredentialFile = v.GetGCSCredentialsFilePath()
-		return NewGCSFs(connectionID, v.MappedPath, v.VirtualPath, config)
+		fs, err = NewGCSFs(connectionID, v.MappedPath, v.VirtualPath, config)
 	case sdk.AzureBlobFilesystemProvider:
-		return NewAzBlobFs(connectionID, v.MappedPath, v.VirtualPath, v.FsConfig.AzBlobConfig)
+		fs, err = NewAzBlobFs(connectionID, v.MappedPath, v.VirtualPath, v.FsConfig.AzBlobConfig)
 	case sdk.CryptedFilesystemProvider:
 		return NewCryptFs(connectionID, v.MappedPath, v.VirtualPath, v.FsConfig.CryptConfig)
 	case sdk.SFTPFilesystemProvider:
-		return NewSFTPFs(connectionID, v.VirtualPath, v.MappedPath, forbiddenSelfUsers, v.FsConfig.SFTPConfig)
+		fs, err = NewSFTPFs(connectionID, v.VirtualPath, v.MappedPath, forbiddenSelfUsers, v.FsConfig.SFTPConfig)
+	case sdk.FTPFilesystemProvider:
+		return NewFTPFs(connectionID, v.VirtualPath, v.MappedPath, v.FsConfig.FTPConfig)
+	case sdk.WebDAVFilesystemProvider:
+		return NewWebDAVFs(connectionID, v.VirtualPath, v.MappedPath, v.FsConfig.WebDAVConfig)
 	default:
 		return NewOsFs(connectionID, v.MappedPath, v.VirtualPath), nil
 	}
+	// cloud filesystems can be wrapped by the local read cache, remote
+	// filesystems can be wrapped by the client side encryption
+	if err != nil {
+		return fs, err
+	}
+	return NewEncryptedFs(NewReadCacheFs(fs, &v.FsConfig), &v.FsConfig)
 }
 
 // ScanQuota scans the folder and returns the number of files and their size
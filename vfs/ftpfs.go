@@ -0,0 +1,770 @@
+package vfs
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/textproto"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/eikenb/pipeat"
+	"github.com/jlaffaye/ftp"
+	"github.com/pkg/sftp"
+	"github.com/rs/xid"
+
+	"github.com/drakkan/sftpgo/v2/kms"
+	"github.com/drakkan/sftpgo/v2/logger"
+	"github.com/drakkan/sftpgo/v2/sdk"
+	"github.com/drakkan/sftpgo/v2/util"
+)
+
+const (
+	// ftpFsName is the name for the FTP Fs implementation
+	ftpFsName = "ftpfs"
+	// max number of idle control connections to keep for reuse
+	ftpMaxIdleConns = 4
+	// idle control connections are closed after this time
+	ftpConnMaxIdleTime = 60 * time.Second
+	// idle control connections older than this are checked using NOOP before reuse
+	ftpConnCheckInterval = 10 * time.Second
+	ftpDialTimeout       = 15 * time.Second
+)
+
+// supported TLS modes for the FTP backend
+const (
+	ftpTLSModeDisabled = iota
+	ftpTLSModeExplicit
+	ftpTLSModeImplicit
+)
+
+var errFTPTransferAborted = errors.New("transfer aborted")
+
+// FTPFsConfig defines the configuration for FTP based filesystem
+type FTPFsConfig struct {
+	sdk.FTPFsConfig
+}
+
+// HideConfidentialData hides confidential data
+func (c *FTPFsConfig) HideConfidentialData() {
+	if c.Password != nil {
+		c.Password.Hide()
+	}
+}
+
+func (c *FTPFsConfig) isEqual(other *FTPFsConfig) bool {
+	if c.Endpoint != other.Endpoint {
+		return false
+	}
+	if c.Username != other.Username {
+		return false
+	}
+	if c.Prefix != other.Prefix {
+		return false
+	}
+	if c.TLSMode != other.TLSMode {
+		return false
+	}
+	if c.SkipTLSVerify != other.SkipTLSVerify {
+		return false
+	}
+	if c.DisableEPSV != other.DisableEPSV {
+		return false
+	}
+	c.setEmptyCredentialsIfNil()
+	other.setEmptyCredentialsIfNil()
+	return c.Password.IsEqual(other.Password)
+}
+
+func (c *FTPFsConfig) setEmptyCredentialsIfNil() {
+	if c.Password == nil {
+		c.Password = kms.NewEmptySecret()
+	}
+}
+
+// Validate returns an error if the configuration is not valid
+func (c *FTPFsConfig) Validate() error {
+	c.setEmptyCredentialsIfNil()
+	if c.Endpoint == "" {
+		return errors.New("endpoint cannot be empty")
+	}
+	_, _, err := net.SplitHostPort(c.Endpoint)
+	if err != nil {
+		return fmt.Errorf("invalid endpoint: %v", err)
+	}
+	if c.Username == "" {
+		return errors.New("username cannot be empty")
+	}
+	if c.TLSMode < ftpTLSModeDisabled || c.TLSMode > ftpTLSModeImplicit {
+		return fmt.Errorf("invalid tls_mode %v, valid values are 0, 1, 2", c.TLSMode)
+	}
+	if c.Password.IsEncrypted() && !c.Password.IsValid() {
+		return errors.New("invalid encrypted password")
+	}
+	if !c.Password.IsEmpty() && !c.Password.IsValidInput() {
+		return errors.New("invalid password")
+	}
+	if c.Prefix != "" {
+		c.Prefix = util.CleanPath(c.Prefix)
+	} else {
+		c.Prefix = "/"
+	}
+	return nil
+}
+
+// EncryptCredentials encrypts the password if it is in plain text
+func (c *FTPFsConfig) EncryptCredentials(additionalData string) error {
+	if c.Password.IsPlain() {
+		c.Password.SetAdditionalData(additionalData)
+		if err := c.Password.Encrypt(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type ftpIdleConn struct {
+	conn     *ftp.ServerConn
+	lastUsed time.Time
+}
+
+// FTPFs is a Fs implementation for FTP/FTPS backends.
+// A ServerConn can handle a single command/data transfer at a time,
+// so each operation uses its own control connection. Idle control
+// connections are reused for subsequent operations
+type FTPFs struct {
+	sync.Mutex
+	connectionID string
+	// if not empty this fs is mouted as virtual folder in the specified path
+	mountPath    string
+	localTempDir string
+	config       *FTPFsConfig
+	idleConns    []ftpIdleConn
+	isClosed     bool
+}
+
+// NewFTPFs returns an FTPFs object that allows to interact with an FTP server
+func NewFTPFs(connectionID, mountPath, localTempDir string, config FTPFsConfig) (Fs, error) {
+	if localTempDir == "" {
+		if tempPath != "" {
+			localTempDir = tempPath
+		} else {
+			localTempDir = filepath.Clean(os.TempDir())
+		}
+	}
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	if !config.Password.IsEmpty() {
+		if err := config.Password.TryDecrypt(); err != nil {
+			return nil, err
+		}
+	}
+	ftpFs := &FTPFs{
+		connectionID: connectionID,
+		mountPath:    mountPath,
+		localTempDir: localTempDir,
+		config:       &config,
+	}
+	// connect now so configuration errors are reported as soon as possible
+	conn, err := ftpFs.dial()
+	if err != nil {
+		return ftpFs, err
+	}
+	ftpFs.putConnection(conn, nil)
+	return ftpFs, nil
+}
+
+// Name returns the name for the Fs implementation
+func (fs *FTPFs) Name() string {
+	return fmt.Sprintf("%v %#v", ftpFsName, fs.config.Endpoint)
+}
+
+// ConnectionID returns the connection ID associated to this Fs implementation
+func (fs *FTPFs) ConnectionID() string {
+	return fs.connectionID
+}
+
+// Stat returns a FileInfo describing the named file
+func (fs *FTPFs) Stat(name string) (os.FileInfo, error) {
+	if name == "/" || name == "." || name == "" {
+		return NewFileInfo(name, true, 0, time.Now(), false), nil
+	}
+	var info os.FileInfo
+	err := fs.withConnection(func(c *ftp.ServerConn) error {
+		entries, err := c.List(path.Dir(name))
+		if err == nil {
+			baseName := path.Base(name)
+			for _, entry := range entries {
+				if entry.Name == baseName {
+					info = fs.getFileInfo(entry)
+					return nil
+				}
+			}
+		}
+		// some servers don't list the requested directory itself, so
+		// we check if the path is a directory changing into it
+		if errCwd := c.ChangeDir(name); errCwd == nil {
+			info = NewFileInfo(name, true, 0, time.Now(), false)
+			return nil
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if info == nil {
+		return nil, &os.PathError{Op: "stat", Path: name, Err: os.ErrNotExist}
+	}
+	return info, nil
+}
+
+// Lstat returns a FileInfo describing the named file
+func (fs *FTPFs) Lstat(name string) (os.FileInfo, error) {
+	return fs.Stat(name)
+}
+
+// Open opens the named file for reading
+func (fs *FTPFs) Open(name string, offset int64) (File, *pipeat.PipeReaderAt, func(), error) {
+	c, err := fs.getConnection()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	resp, err := c.RetrFrom(name, uint64(offset))
+	if err != nil {
+		fs.putConnection(c, err)
+		return nil, nil, nil, err
+	}
+	r, w, err := pipeat.PipeInDir(fs.localTempDir)
+	if err != nil {
+		errClose := resp.Close()
+		fs.putConnection(c, errClose)
+		return nil, nil, nil, err
+	}
+	cancelFn := func() {
+		resp.SetDeadline(time.Now()) //nolint:errcheck
+	}
+	go func() {
+		n, err := io.Copy(w, resp)
+		errClose := resp.Close()
+		if err == nil && errClose != nil {
+			err = errClose
+		}
+		w.CloseWithError(err) //nolint:errcheck
+		fs.putConnection(c, err)
+		fsLog(fs, logger.LevelDebug, "download completed, path: %#v size: %v, err: %v", name, n, err)
+	}()
+
+	return nil, r, cancelFn, nil
+}
+
+// Create creates or opens the named file for writing
+func (fs *FTPFs) Create(name string, flag int) (File, *PipeWriter, func(), error) {
+	var resumeOffset int64
+	if isUploadResumeRequested(flag) {
+		info, err := fs.Stat(name)
+		if err == nil {
+			resumeOffset = info.Size()
+		} else if !fs.IsNotExist(err) {
+			return nil, nil, nil, err
+		}
+	}
+	c, err := fs.getConnection()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	r, w, err := pipeat.PipeInDir(fs.localTempDir)
+	if err != nil {
+		fs.putConnection(c, nil)
+		return nil, nil, nil, err
+	}
+	var p *PipeWriter
+	if resumeOffset > 0 {
+		p = NewPipeWriterAtOffset(w, resumeOffset)
+	} else {
+		p = NewPipeWriter(w)
+	}
+	var closeOnce sync.Once
+	closeReader := func(err error) {
+		closeOnce.Do(func() {
+			r.CloseWithError(err) //nolint:errcheck
+		})
+	}
+	cancelFn := func() {
+		closeReader(errFTPTransferAborted)
+	}
+	go func() {
+		var err error
+		if resumeOffset > 0 {
+			err = c.Append(name, r)
+		} else {
+			err = c.Stor(name, r)
+		}
+		closeReader(err)
+		p.Done(err)
+		fs.putConnection(c, err)
+		fsLog(fs, logger.LevelDebug, "upload completed, path: %#v, readed bytes: %v, resume offset: %v, err: %v",
+			name, r.GetReadedBytes(), resumeOffset, err)
+	}()
+
+	return nil, p, cancelFn, nil
+}
+
+// Rename renames (moves) source to target.
+func (fs *FTPFs) Rename(source, target string) error {
+	return fs.withConnection(func(c *ftp.ServerConn) error {
+		return c.Rename(source, target)
+	})
+}
+
+// Remove removes the named file or (empty) directory.
+func (fs *FTPFs) Remove(name string, isDir bool) error {
+	return fs.withConnection(func(c *ftp.ServerConn) error {
+		if isDir {
+			return c.RemoveDir(name)
+		}
+		return c.Delete(name)
+	})
+}
+
+// Mkdir creates a new directory with the specified name and default permissions
+func (fs *FTPFs) Mkdir(name string) error {
+	return fs.withConnection(func(c *ftp.ServerConn) error {
+		return c.MakeDir(name)
+	})
+}
+
+// MkdirAll creates a directory named path, along with any necessary parents,
+// and returns nil, or else returns an error.
+// If path is already a directory, MkdirAll does nothing and returns nil.
+func (fs *FTPFs) MkdirAll(name string, uid int, gid int) error {
+	return fs.withConnection(func(c *ftp.ServerConn) error {
+		dirPath := "/"
+		for _, dir := range strings.Split(path.Clean(name), "/") {
+			if dir == "" {
+				continue
+			}
+			dirPath = path.Join(dirPath, dir)
+			if err := c.ChangeDir(dirPath); err == nil {
+				continue
+			}
+			if err := c.MakeDir(dirPath); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Symlink creates source as a symbolic link to target.
+func (*FTPFs) Symlink(source, target string) error {
+	return ErrVfsUnsupported
+}
+
+// Readlink returns the destination of the named symbolic link
+func (*FTPFs) Readlink(name string) (string, error) {
+	return "", ErrVfsUnsupported
+}
+
+// Chown changes the numeric uid and gid of the named file.
+func (*FTPFs) Chown(name string, uid int, gid int) error {
+	return ErrVfsUnsupported
+}
+
+// Chmod changes the mode of the named file to mode.
+func (*FTPFs) Chmod(name string, mode os.FileMode) error {
+	return ErrVfsUnsupported
+}
+
+// Chtimes changes the access and modification times of the named file.
+func (*FTPFs) Chtimes(name string, atime, mtime time.Time) error {
+	return ErrVfsUnsupported
+}
+
+// Truncate changes the size of the named file.
+// Truncate by path is not supported, while truncating an opened
+// file is handled inside base transfer
+func (*FTPFs) Truncate(name string, size int64) error {
+	return ErrVfsUnsupported
+}
+
+// ReadDir reads the directory named by dirname and returns
+// a list of directory entries.
+func (fs *FTPFs) ReadDir(dirname string) ([]os.FileInfo, error) {
+	var result []os.FileInfo
+	err := fs.withConnection(func(c *ftp.ServerConn) error {
+		entries, err := c.List(dirname)
+		if err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			if entry.Name == "." || entry.Name == ".." {
+				continue
+			}
+			result = append(result, fs.getFileInfo(entry))
+		}
+		return nil
+	})
+	return result, err
+}
+
+// IsUploadResumeSupported returns true if resuming uploads is supported.
+// Uploads are resumed using the APPE command
+func (*FTPFs) IsUploadResumeSupported() bool {
+	return true
+}
+
+// IsAtomicUploadSupported returns true if atomic upload is supported.
+func (*FTPFs) IsAtomicUploadSupported() bool {
+	return true
+}
+
+// IsNotExist returns a boolean indicating whether the error is known to
+// report that a file or directory does not exist
+func (*FTPFs) IsNotExist(err error) bool {
+	if err == nil {
+		return false
+	}
+	if os.IsNotExist(err) {
+		return true
+	}
+	var tpErr *textproto.Error
+	if errors.As(err, &tpErr) {
+		return tpErr.Code == ftp.StatusFileUnavailable && !isFTPPermissionError(tpErr)
+	}
+	return false
+}
+
+// IsPermission returns a boolean indicating whether the error is known to
+// report that permission is denied.
+func (*FTPFs) IsPermission(err error) bool {
+	if _, ok := err.(*pathResolutionError); ok {
+		return true
+	}
+	if os.IsPermission(err) {
+		return true
+	}
+	var tpErr *textproto.Error
+	if errors.As(err, &tpErr) {
+		return isFTPPermissionError(tpErr)
+	}
+	return false
+}
+
+// IsNotSupported returns true if the error indicate an unsupported operation
+func (*FTPFs) IsNotSupported(err error) bool {
+	if err == nil {
+		return false
+	}
+	if err == ErrVfsUnsupported {
+		return true
+	}
+	var tpErr *textproto.Error
+	if errors.As(err, &tpErr) {
+		return tpErr.Code == ftp.StatusNotImplemented || tpErr.Code == ftp.StatusNotImplementedParameter
+	}
+	return false
+}
+
+// CheckRootPath creates the specified local root directory if it does not exists
+func (fs *FTPFs) CheckRootPath(username string, uid int, gid int) bool {
+	// we need a local directory for temporary files
+	osFs := NewOsFs(fs.ConnectionID(), fs.localTempDir, "")
+	osFs.CheckRootPath(username, uid, gid)
+	if fs.config.Prefix == "/" {
+		return true
+	}
+	if err := fs.MkdirAll(fs.config.Prefix, uid, gid); err != nil {
+		fsLog(fs, logger.LevelDebug, "error creating root directory %#v for user %#v: %v", fs.config.Prefix, username, err)
+		return false
+	}
+	return true
+}
+
+// ScanRootDirContents returns the number of files contained in a directory and
+// their size
+func (fs *FTPFs) ScanRootDirContents() (int, int64, error) {
+	return fs.GetDirSize(fs.config.Prefix)
+}
+
+// GetAtomicUploadPath returns the path to use for an atomic upload
+func (*FTPFs) GetAtomicUploadPath(name string) string {
+	dir := path.Dir(name)
+	guid := xid.New().String()
+	return path.Join(dir, ".sftpgo-upload."+guid+"."+path.Base(name))
+}
+
+// GetRelativePath returns the path for a file relative to the ftp prefix if any.
+// This is the path as seen by SFTPGo users
+func (fs *FTPFs) GetRelativePath(name string) string {
+	rel := path.Clean(name)
+	if rel == "." {
+		rel = ""
+	}
+	if !path.IsAbs(rel) {
+		return "/" + rel
+	}
+	if fs.config.Prefix != "/" {
+		if !strings.HasPrefix(rel, fs.config.Prefix) {
+			rel = "/"
+		}
+		rel = path.Clean("/" + strings.TrimPrefix(rel, fs.config.Prefix))
+	}
+	if fs.mountPath != "" {
+		rel = path.Join(fs.mountPath, rel)
+	}
+	return rel
+}
+
+// Walk walks the file tree rooted at root, calling walkFn for each file or
+// directory in the tree, including root
+func (fs *FTPFs) Walk(root string, walkFn filepath.WalkFunc) error {
+	info, err := fs.Stat(root)
+	if err != nil {
+		return walkFn(root, nil, err)
+	}
+	err = fs.walk(root, info, walkFn)
+	if err == filepath.SkipDir {
+		return nil
+	}
+	return err
+}
+
+func (fs *FTPFs) walk(name string, info os.FileInfo, walkFn filepath.WalkFunc) error {
+	if !info.IsDir() {
+		return walkFn(name, info, nil)
+	}
+	contents, err := fs.ReadDir(name)
+	err1 := walkFn(name, info, err)
+	if err != nil || err1 != nil {
+		return err1
+	}
+	for _, fi := range contents {
+		err = fs.walk(path.Join(name, fi.Name()), fi, walkFn)
+		if err != nil {
+			if !fi.IsDir() || err != filepath.SkipDir {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Join joins any number of path elements into a single path
+func (*FTPFs) Join(elem ...string) string {
+	return path.Join(elem...)
+}
+
+// HasVirtualFolders returns true if folders are emulated
+func (*FTPFs) HasVirtualFolders() bool {
+	return false
+}
+
+// ResolvePath returns the matching filesystem path for the specified virtual path
+func (fs *FTPFs) ResolvePath(virtualPath string) (string, error) {
+	if fs.mountPath != "" {
+		virtualPath = strings.TrimPrefix(virtualPath, fs.mountPath)
+	}
+	// the FTP protocol has no way to resolve symlinks, cleaning the virtual
+	// path before joining it with the prefix is enough to stay inside the prefix
+	virtualPath = path.Clean("/" + virtualPath)
+	return fs.Join(fs.config.Prefix, virtualPath), nil
+}
+
+// GetDirSize returns the number of files and the size for a folder
+// including any subfolders
+func (fs *FTPFs) GetDirSize(dirname string) (int, int64, error) {
+	numFiles := 0
+	size := int64(0)
+	isDir, err := IsDirectory(fs, dirname)
+	if err == nil && isDir {
+		err = fs.Walk(dirname, func(_ string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if info != nil && info.Mode().IsRegular() {
+				size += info.Size()
+				numFiles++
+			}
+			return err
+		})
+	}
+	return numFiles, size, err
+}
+
+// GetMimeType returns the content type
+func (fs *FTPFs) GetMimeType(name string) (string, error) {
+	var ctype string
+	err := fs.withConnection(func(c *ftp.ServerConn) error {
+		resp, err := c.RetrFrom(name, 0)
+		if err != nil {
+			return err
+		}
+		var buf [512]byte
+		n, err := io.ReadFull(resp, buf[:])
+		errClose := resp.Close()
+		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+			return err
+		}
+		ctype = http.DetectContentType(buf[:n])
+		// closing the data connection before the end of the transfer
+		// could return an error for an aborted transfer, this is expected
+		var tpErr *textproto.Error
+		if errors.As(errClose, &tpErr) {
+			return nil
+		}
+		return errClose
+	})
+	return ctype, err
+}
+
+// GetAvailableDiskSize return the available size for the specified path
+func (*FTPFs) GetAvailableDiskSize(dirName string) (*sftp.StatVFS, error) {
+	return nil, ErrStorageSizeUnavailable
+}
+
+// Close closes the idle connections, the connections used by in progress
+// transfers will be closed as soon as they end
+func (fs *FTPFs) Close() error {
+	fs.Lock()
+	idleConns := fs.idleConns
+	fs.idleConns = nil
+	fs.isClosed = true
+	fs.Unlock()
+
+	var err error
+	for _, idle := range idleConns {
+		if errQuit := idle.conn.Quit(); errQuit != nil {
+			err = errQuit
+		}
+	}
+	return err
+}
+
+func (fs *FTPFs) getFileInfo(entry *ftp.Entry) os.FileInfo {
+	isDir := entry.Type == ftp.EntryTypeFolder
+	var size int64
+	if !isDir {
+		size = int64(entry.Size)
+	}
+	return NewFileInfo(entry.Name, isDir, size, entry.Time, false)
+}
+
+// withConnection executes fn using a connection from the pool.
+// fn must return the errors returned by the FTP client as is, so
+// we can decide if the connection can be reused
+func (fs *FTPFs) withConnection(fn func(c *ftp.ServerConn) error) error {
+	c, err := fs.getConnection()
+	if err != nil {
+		return err
+	}
+	err = fn(c)
+	fs.putConnection(c, err)
+	return err
+}
+
+// getConnection returns an idle connection, if any, or a new one
+func (fs *FTPFs) getConnection() (*ftp.ServerConn, error) {
+	for {
+		fs.Lock()
+		if len(fs.idleConns) == 0 {
+			fs.Unlock()
+			return fs.dial()
+		}
+		idle := fs.idleConns[len(fs.idleConns)-1]
+		fs.idleConns = fs.idleConns[:len(fs.idleConns)-1]
+		fs.Unlock()
+
+		idleTime := time.Since(idle.lastUsed)
+		if idleTime > ftpConnMaxIdleTime {
+			idle.conn.Quit() //nolint:errcheck
+			continue
+		}
+		if idleTime > ftpConnCheckInterval {
+			if err := idle.conn.NoOp(); err != nil {
+				fsLog(fs, logger.LevelDebug, "discarding idle connection, err: %v", err)
+				idle.conn.Quit() //nolint:errcheck
+				continue
+			}
+		}
+		return idle.conn, nil
+	}
+}
+
+// putConnection returns a connection to the pool if it can be reused.
+// FTP protocol errors leave the connection in a usable state, while
+// network errors, including timeouts, do not
+func (fs *FTPFs) putConnection(c *ftp.ServerConn, err error) {
+	if err != nil {
+		var tpErr *textproto.Error
+		if !errors.As(err, &tpErr) || tpErr.Code == ftp.StatusNotAvailable {
+			c.Quit() //nolint:errcheck
+			return
+		}
+	}
+	fs.Lock()
+	if fs.isClosed || len(fs.idleConns) >= ftpMaxIdleConns {
+		fs.Unlock()
+		c.Quit() //nolint:errcheck
+		return
+	}
+	fs.idleConns = append(fs.idleConns, ftpIdleConn{
+		conn:     c,
+		lastUsed: time.Now(),
+	})
+	fs.Unlock()
+}
+
+func (fs *FTPFs) dial() (*ftp.ServerConn, error) {
+	options := []ftp.DialOption{
+		ftp.DialWithTimeout(ftpDialTimeout),
+		ftp.DialWithDisabledEPSV(fs.config.DisableEPSV),
+	}
+	if fs.config.TLSMode != ftpTLSModeDisabled {
+		host, _, err := net.SplitHostPort(fs.config.Endpoint)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig := &tls.Config{
+			ServerName:         host,
+			InsecureSkipVerify: fs.config.SkipTLSVerify, //nolint:gosec
+			MinVersion:         tls.VersionTLS12,
+			// many servers require TLS session resumption for data connections
+			ClientSessionCache: tls.NewLRUClientSessionCache(0),
+		}
+		if fs.config.TLSMode == ftpTLSModeExplicit {
+			options = append(options, ftp.DialWithExplicitTLS(tlsConfig))
+		} else {
+			options = append(options, ftp.DialWithTLS(tlsConfig))
+		}
+	}
+	c, err := ftp.Dial(fs.config.Endpoint, options...)
+	if err != nil {
+		fsLog(fs, logger.LevelWarn, "unable to connect to %#v: %v", fs.config.Endpoint, err)
+		return nil, err
+	}
+	if err := c.Login(fs.config.Username, fs.config.Password.GetPayload()); err != nil {
+		fsLog(fs, logger.LevelWarn, "unable to login to %#v as %#v: %v", fs.config.Endpoint, fs.config.Username, err)
+		c.Quit() //nolint:errcheck
+		return nil, err
+	}
+	return c, nil
+}
+
+func isFTPPermissionError(err *textproto.Error) bool {
+	switch err.Code {
+	case ftp.StatusBadFileName, ftp.StatusNotLoggedIn:
+		return true
+	case ftp.StatusFileUnavailable:
+		// 550 is used for both missing files and denied permissions
+		return strings.Contains(strings.ToLower(err.Msg), "permission")
+	}
+	return false
+}
@@ -4,24 +4,30 @@
 package vfs
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"mime"
 	"net/http"
+	"net/url"
 	"os"
 	"path"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
 
 	"cloud.google.com/go/storage"
 	"github.com/eikenb/pipeat"
 	"github.com/pkg/sftp"
+	"github.com/rs/xid"
 	"google.golang.org/api/googleapi"
 	"google.golang.org/api/iterator"
 	"google.golang.org/api/option"
+	htransport "google.golang.org/api/transport/http"
 
 	"github.com/drakkan/sftpgo/v2/kms"
 	"github.com/drakkan/sftpgo/v2/logger"
@@ -29,10 +35,25 @@ import (
 	"github.com/drakkan/sftpgo/v2/version"
 )
 
+const (
+	// max number of source objects for a compose request
+	gcsMaxComposeSources = 32
+	// size of the chunks uploaded within a resumable upload session,
+	// it must be a multiple of 256KB
+	gcsUploadChunkSize      = googleapi.DefaultUploadChunkSize
+	gcsDefaultUploadBaseURL = "https://storage.googleapis.com/upload/storage/v1/"
+)
+
 var (
 	gcsDefaultFieldsSelection = []string{"Name", "Size", "Deleted", "Updated", "ContentType"}
 )
 
+// gcsMultipartUploadState defines the state of an interrupted upload,
+// the data are uploaded using a resumable upload session
+type gcsMultipartUploadState struct {
+	SessionURI string `json:"session_uri"`
+}
+
 // GCSFs is a Fs implementation for Google Cloud Storage.
 type GCSFs struct {
 	connectionID string
@@ -43,6 +64,10 @@ type GCSFs struct {
 	svc            *storage.Client
 	ctxTimeout     time.Duration
 	ctxLongTimeout time.Duration
+	// the resumable upload sessions are handled using the JSON API directly,
+	// the client library cannot resume an upload session
+	httpClient    *http.Client
+	uploadBaseURL string
 }
 
 func init() {
@@ -72,6 +97,7 @@ func NewGCSFs(connectionID, localTempDir, mountPath string, config GCSFsConfig)
 		return fs, err
 	}
 	ctx := context.Background()
+	var opts []option.ClientOption
 	if fs.config.AutomaticCredentials > 0 {
 		fs.svc, err = storage.NewClient(ctx)
 	} else if !fs.config.Credentials.IsEmpty() {
@@ -79,7 +105,8 @@ func NewGCSFs(connectionID, localTempDir, mountPath string, config GCSFsConfig)
 		if err != nil {
 			return fs, err
 		}
-		fs.svc, err = storage.NewClient(ctx, option.WithCredentialsJSON([]byte(fs.config.Credentials.GetPayload())))
+		opts = append(opts, option.WithCredentialsJSON([]byte(fs.config.Credentials.GetPayload())))
+		fs.svc, err = storage.NewClient(ctx, opts...)
 	} else {
 		var creds []byte
 		creds, err = os.ReadFile(fs.config.CredentialFile)
@@ -95,11 +122,35 @@ func NewGCSFs(connectionID, localTempDir, mountPath string, config GCSFsConfig)
 		if err != nil {
 			return fs, err
 		}
-		fs.svc, err = storage.NewClient(ctx, option.WithCredentialsJSON([]byte(secret.GetPayload())))
+		opts = append(opts, option.WithCredentialsJSON([]byte(secret.GetPayload())))
+		fs.svc, err = storage.NewClient(ctx, opts...)
+	}
+	if err != nil {
+		return fs, err
 	}
+	err = fs.setUploadClient(ctx, opts)
 	return fs, err
 }
 
+// setUploadClient sets the HTTP client for the resumable upload sessions.
+// The storage emulator is used, without authentication, if STORAGE_EMULATOR_HOST
+// is set, as for the client library
+func (fs *GCSFs) setUploadClient(ctx context.Context, opts []option.ClientOption) error {
+	if host := os.Getenv("STORAGE_EMULATOR_HOST"); host != "" {
+		if !strings.Contains(host, "://") {
+			host = "http://" + host
+		}
+		fs.uploadBaseURL = strings.TrimSuffix(host, "/") + "/upload/storage/v1/"
+		opts = append(opts, option.WithoutAuthentication())
+	} else {
+		fs.uploadBaseURL = gcsDefaultUploadBaseURL
+		opts = append(opts, option.WithScopes(storage.ScopeFullControl))
+	}
+	var err error
+	fs.httpClient, _, err = htransport.NewClient(ctx, opts...)
+	return err
+}
+
 // Name returns the name for the Fs implementation
 func (fs *GCSFs) Name() string {
 	return fmt.Sprintf("GCSFs bucket %#v", fs.config.Bucket)
@@ -164,34 +215,54 @@ func (fs *GCSFs) Open(name string, offset int64) (File, *pipeat.PipeReaderAt, fu
 
 // Create creates or opens the named file for writing
 func (fs *GCSFs) Create(name string, flag int) (File, *PipeWriter, func(), error) {
+	var resumeAttrs *storage.ObjectAttrs
+	if isUploadResumeRequested(flag) {
+		attrs, err := fs.headObject(name)
+		if err == nil {
+			if attrs.Size > 0 {
+				resumeAttrs = attrs
+			}
+		} else if !fs.IsNotExist(err) {
+			return nil, nil, nil, err
+		}
+	}
+	var pendingUpload *MultipartUpload
+	var pendingState gcsMultipartUploadState
+	if flag != -1 {
+		pendingUpload, _ = getMultipartUpload(fs, fs.getMultipartUploadKey(name), &pendingState)
+		if pendingUpload != nil && (resumeAttrs != nil || !isUploadResumeRequested(flag) || pendingUpload.IsExpired(time.Now())) {
+			fs.discardMultipartUpload(name, &pendingState)
+			pendingUpload = nil
+		}
+	}
 	r, w, err := pipeat.PipeInDir(fs.localTempDir)
 	if err != nil {
 		return nil, nil, nil, err
 	}
-	p := NewPipeWriter(w)
-	bkt := fs.svc.Bucket(fs.config.Bucket)
-	obj := bkt.Object(name)
+	var p *PipeWriter
+	if resumeAttrs != nil {
+		p = NewPipeWriterAtOffset(w, resumeAttrs.Size)
+	} else if pendingUpload != nil {
+		p = NewPipeWriterAtOffset(w, pendingUpload.Size)
+	} else {
+		p = NewPipeWriter(w)
+	}
 	ctx, cancelFn := context.WithCancel(context.Background())
-	objectWriter := obj.NewWriter(ctx)
 	var contentType string
 	if flag == -1 {
 		contentType = dirMimeType
 	} else {
 		contentType = mime.TypeByExtension(path.Ext(name))
 	}
-	if contentType != "" {
-		objectWriter.ObjectAttrs.ContentType = contentType
-	}
-	if fs.config.StorageClass != "" {
-		objectWriter.ObjectAttrs.StorageClass = fs.config.StorageClass
-	}
 	go func() {
 		defer cancelFn()
 
-		n, err := io.Copy(objectWriter, r)
-		closeErr := objectWriter.Close()
-		if err == nil {
-			err = closeErr
+		var n int64
+		var err error
+		if resumeAttrs != nil {
+			n, err = fs.resumeUpload(ctx, r, resumeAttrs, contentType)
+		} else {
+			n, err = fs.uploadResumable(ctx, r, name, contentType, pendingUpload, &pendingState)
 		}
 		r.CloseWithError(err) //nolint:errcheck
 		p.Done(err)
@@ -201,6 +272,249 @@ func (fs *GCSFs) Create(name string, flag int) (File, *PipeWriter, func(), error
 	return nil, p, cancelFn, nil
 }
 
+func (fs *GCSFs) uploadObject(ctx context.Context, r io.Reader, name, contentType string) (int64, error) {
+	objectWriter := fs.svc.Bucket(fs.config.Bucket).Object(name).NewWriter(ctx)
+	if contentType != "" {
+		objectWriter.ObjectAttrs.ContentType = contentType
+	}
+	if fs.config.StorageClass != "" {
+		objectWriter.ObjectAttrs.StorageClass = fs.config.StorageClass
+	}
+	n, err := io.Copy(objectWriter, r)
+	closeErr := objectWriter.Close()
+	if err == nil {
+		err = closeErr
+	}
+	return n, err
+}
+
+// uploadResumable uploads the data read from r using a resumable upload session.
+// If an interrupted upload is provided its session is used and the upload continues
+// from the persisted data. If the upload is interrupted the session is saved, so
+// the upload can be resumed later
+func (fs *GCSFs) uploadResumable(ctx context.Context, r io.Reader, name, contentType string, upload *MultipartUpload,
+	state *gcsMultipartUploadState,
+) (int64, error) {
+	var readed, offset int64
+	sessionURI := state.SessionURI
+	if upload != nil {
+		persisted, _, err := fs.getUploadSessionStatus(ctx, sessionURI)
+		if err == nil && persisted < upload.Size {
+			err = fmt.Errorf("unexpected persisted size: %v, expected at least: %v", persisted, upload.Size)
+		}
+		if err != nil {
+			fsLog(fs, logger.LevelWarn, "unable to resume upload for %#v: %v", name, err)
+			deleteMultipartUpload(fs, fs.getMultipartUploadKey(name))
+			return 0, err
+		}
+		// the data persisted after the saved size were sent again, we skip them
+		readed, err = io.CopyN(io.Discard, r, persisted-upload.Size)
+		if err != nil {
+			return readed, err
+		}
+		offset = persisted
+		fsLog(fs, logger.LevelDebug, "resuming interrupted upload for %#v, size: %v, persisted: %v", name,
+			upload.Size, persisted)
+	} else {
+		var err error
+		sessionURI, err = fs.createUploadSession(ctx, name, contentType)
+		if err != nil {
+			return 0, err
+		}
+	}
+	buf := make([]byte, gcsUploadChunkSize)
+	buffered := 0
+	for {
+		n, err := readFill(r, buf[buffered:])
+		readed += int64(n)
+		buffered += n
+		if err != nil && err != io.EOF {
+			fs.saveInterruptedUpload(name, sessionURI, offset)
+			return readed, err
+		}
+		isLast := err == io.EOF
+		persisted, errUpload := fs.uploadChunk(ctx, sessionURI, buf[:buffered], offset, isLast)
+		if errUpload != nil {
+			fs.saveInterruptedUpload(name, sessionURI, offset)
+			return readed, errUpload
+		}
+		if isLast {
+			if upload != nil {
+				deleteMultipartUpload(fs, fs.getMultipartUploadKey(name))
+			}
+			return readed, nil
+		}
+		// a part of the chunk may not be persisted, it will be sent again
+		sent := int(persisted - offset)
+		if sent <= 0 || sent > buffered {
+			fs.saveInterruptedUpload(name, sessionURI, offset)
+			return readed, fmt.Errorf("unexpected persisted size: %v, offset: %v, chunk size: %v", persisted, offset,
+				buffered)
+		}
+		buffered = copy(buf, buf[sent:buffered])
+		offset = persisted
+	}
+}
+
+func (fs *GCSFs) createUploadSession(ctx context.Context, name, contentType string) (string, error) {
+	metadata := struct {
+		Name         string `json:"name"`
+		ContentType  string `json:"contentType,omitempty"`
+		StorageClass string `json:"storageClass,omitempty"`
+	}{
+		Name:         name,
+		ContentType:  contentType,
+		StorageClass: fs.config.StorageClass,
+	}
+	body, err := json.Marshal(&metadata)
+	if err != nil {
+		return "", err
+	}
+	uploadURL := fmt.Sprintf("%vb/%v/o?uploadType=resumable", fs.uploadBaseURL, url.PathEscape(fs.config.Bucket))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, uploadURL, bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json; charset=UTF-8")
+	if contentType != "" {
+		req.Header.Set("X-Upload-Content-Type", contentType)
+	}
+	resp, err := fs.httpClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if err := googleapi.CheckResponse(resp); err != nil {
+		return "", err
+	}
+	sessionURI := resp.Header.Get("Location")
+	if sessionURI == "" {
+		return "", errors.New("unable to create upload session, no session URI returned")
+	}
+	return sessionURI, nil
+}
+
+// uploadChunk uploads a chunk starting at the specified offset and returns the
+// total bytes persisted within the upload session. The upload is completed after
+// the last chunk
+func (fs *GCSFs) uploadChunk(ctx context.Context, sessionURI string, data []byte, offset int64, isLast bool,
+) (int64, error) {
+	totalSize := "*"
+	if isLast {
+		totalSize = strconv.FormatInt(offset+int64(len(data)), 10)
+	}
+	contentRange := fmt.Sprintf("bytes */%v", totalSize)
+	if len(data) > 0 {
+		contentRange = fmt.Sprintf("bytes %v-%v/%v", offset, offset+int64(len(data))-1, totalSize)
+	}
+	persisted, completed, err := fs.doUploadSessionRequest(ctx, sessionURI, data, contentRange)
+	if err == nil && isLast && !completed {
+		err = fmt.Errorf("upload not completed, persisted size: %v", persisted)
+	}
+	return persisted, err
+}
+
+// getUploadSessionStatus returns the bytes persisted within an upload session
+// and if the upload is completed
+func (fs *GCSFs) getUploadSessionStatus(ctx context.Context, sessionURI string) (int64, bool, error) {
+	return fs.doUploadSessionRequest(ctx, sessionURI, nil, "bytes */*")
+}
+
+func (fs *GCSFs) doUploadSessionRequest(ctx context.Context, sessionURI string, data []byte, contentRange string,
+) (int64, bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, sessionURI, bytes.NewReader(data))
+	if err != nil {
+		return 0, false, err
+	}
+	req.Header.Set("Content-Range", contentRange)
+	resp, err := fs.httpClient.Do(req)
+	if err != nil {
+		return 0, false, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusCreated:
+		return 0, true, nil
+	case http.StatusPermanentRedirect:
+		// the upload is incomplete, the Range header, if any, contains the persisted bytes
+		persistedRange := resp.Header.Get("Range")
+		if persistedRange == "" {
+			return 0, false, nil
+		}
+		var first, last int64
+		if _, err := fmt.Sscanf(persistedRange, "bytes=%d-%d", &first, &last); err != nil || first != 0 {
+			return 0, false, fmt.Errorf("invalid persisted range %#v", persistedRange)
+		}
+		return last + 1, false, nil
+	default:
+		return 0, false, googleapi.CheckResponse(resp)
+	}
+}
+
+// saveInterruptedUpload saves the upload session for an interrupted upload,
+// so it can be resumed later. Nothing is saved if no data was persisted
+func (fs *GCSFs) saveInterruptedUpload(name, sessionURI string, size int64) {
+	if size > 0 {
+		saveMultipartUpload(fs, fs.getMultipartUploadKey(name), size, //nolint:errcheck
+			&gcsMultipartUploadState{SessionURI: sessionURI})
+	}
+}
+
+// discardMultipartUpload cancels the upload session for an interrupted upload
+// and removes its state
+func (fs *GCSFs) discardMultipartUpload(name string, state *gcsMultipartUploadState) {
+	deleteMultipartUpload(fs, fs.getMultipartUploadKey(name))
+
+	ctx, cancelFn := context.WithDeadline(context.Background(), time.Now().Add(fs.ctxTimeout))
+	defer cancelFn()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, state.SessionURI, nil)
+	if err != nil {
+		return
+	}
+	resp, err := fs.httpClient.Do(req)
+	if err != nil {
+		fsLog(fs, logger.LevelDebug, "unable to cancel the upload session for %#v: %v", name, err)
+		return
+	}
+	resp.Body.Close()
+}
+
+func (fs *GCSFs) getMultipartUploadKey(name string) string {
+	return getMultipartUploadKey("gcs", fs.config.Bucket, name)
+}
+
+// resumeUpload uploads the new data to a temporary object, using a resumable
+// upload session, and then composes the existing object and the temporary one.
+// The existing object is replaced only if it was not modified in the meantime
+func (fs *GCSFs) resumeUpload(ctx context.Context, r io.Reader, attrs *storage.ObjectAttrs, contentType string) (int64, error) {
+	tempName := fs.getTempObjectName(attrs.Name, "resume")
+	n, err := fs.uploadObject(ctx, r, tempName, contentType)
+	defer func() {
+		if errRemove := fs.Remove(tempName, false); errRemove != nil && !fs.IsNotExist(errRemove) {
+			fsLog(fs, logger.LevelWarn, "unable to remove temporary object %#v: %v", tempName, errRemove)
+		}
+	}()
+	if err != nil {
+		return n, err
+	}
+	bkt := fs.svc.Bucket(fs.config.Bucket)
+	dst := bkt.Object(attrs.Name).If(storage.Conditions{GenerationMatch: attrs.Generation})
+	composer := dst.ComposerFrom(bkt.Object(attrs.Name).Generation(attrs.Generation), bkt.Object(tempName))
+	if contentType != "" {
+		composer.ContentType = contentType
+	}
+	if fs.config.StorageClass != "" {
+		composer.StorageClass = fs.config.StorageClass
+	}
+	_, err = composer.Run(ctx)
+	fsLog(fs, logger.LevelDebug, "upload resumed, path: %#v, initial size: %v, temporary object: %#v, err: %v",
+		attrs.Name, attrs.Size, tempName, err)
+	return n, err
+}
+
 // Rename renames (moves) source to target.
 // We don't support renaming non empty directories since we should
 // rename all the contents too and this could take long time: think
@@ -226,7 +540,103 @@ func (fs *GCSFs) Rename(source, target string) error {
 			target += "/"
 		}
 	}
-	src := fs.svc.Bucket(fs.config.Bucket).Object(realSourceName)
+	var contentType string
+	if fi.IsDir() {
+		contentType = dirMimeType
+	} else {
+		contentType = mime.TypeByExtension(path.Ext(source))
+	}
+	if err := fs.copyObject(realSourceName, target, contentType); err != nil {
+		return err
+	}
+	return fs.Remove(source, fi.IsDir())
+}
+
+// CopyFile implements the FsFileCopier interface.
+// The file is copied using a server side copy
+func (fs *GCSFs) CopyFile(source, target string) error {
+	if source == target {
+		return nil
+	}
+	realSourceName, fi, err := fs.getObjectStat(source)
+	if err != nil {
+		return err
+	}
+	if fi.IsDir() {
+		return fmt.Errorf("cannot copy %#v, it is a directory", source)
+	}
+	return fs.copyObject(realSourceName, target, mime.TypeByExtension(path.Ext(source)))
+}
+
+// CombineFiles implements the FsFileCombiner interface.
+// The sources are combined using compose requests, each request accepts up to
+// 32 sources so more sources are composed into temporary objects first
+func (fs *GCSFs) CombineFiles(target string, sources []string) error {
+	if len(sources) == 0 {
+		return errors.New("no source files to combine")
+	}
+	var tempObjects []string
+	defer func() {
+		for _, name := range tempObjects {
+			if err := fs.Remove(name, false); err != nil && !fs.IsNotExist(err) {
+				fsLog(fs, logger.LevelWarn, "unable to remove temporary object %#v: %v", name, err)
+			}
+		}
+	}()
+
+	for len(sources) > gcsMaxComposeSources {
+		composed := make([]string, 0, len(sources)/gcsMaxComposeSources+1)
+		for start := 0; start < len(sources); start += gcsMaxComposeSources {
+			end := start + gcsMaxComposeSources
+			if end > len(sources) {
+				end = len(sources)
+			}
+			if end-start == 1 {
+				composed = append(composed, sources[start])
+				continue
+			}
+			tempName := fs.getTempObjectName(target, "combine")
+			tempObjects = append(tempObjects, tempName)
+			if err := fs.composeObjects(tempName, sources[start:end], ""); err != nil {
+				return err
+			}
+			composed = append(composed, tempName)
+		}
+		sources = composed
+	}
+	return fs.composeObjects(target, sources, mime.TypeByExtension(path.Ext(target)))
+}
+
+func (fs *GCSFs) composeObjects(target string, sources []string, contentType string) error {
+	bkt := fs.svc.Bucket(fs.config.Bucket)
+	srcs := make([]*storage.ObjectHandle, 0, len(sources))
+	for _, source := range sources {
+		srcs = append(srcs, bkt.Object(source))
+	}
+	ctx, cancelFn := context.WithDeadline(context.Background(), time.Now().Add(fs.ctxTimeout))
+	defer cancelFn()
+
+	composer := bkt.Object(target).ComposerFrom(srcs...)
+	if contentType != "" {
+		composer.ContentType = contentType
+	}
+	if fs.config.StorageClass != "" {
+		composer.StorageClass = fs.config.StorageClass
+	}
+	_, err := composer.Run(ctx)
+	fsLog(fs, logger.LevelDebug, "compose completed, target: %#v, sources: %v, err: %v", target, len(sources), err)
+	metric.GCSCopyObjectCompleted(err)
+	return err
+}
+
+// getTempObjectName returns the name for a temporary object in the same
+// directory as the specified one
+func (*GCSFs) getTempObjectName(name, kind string) string {
+	return path.Join(path.Dir(name), fmt.Sprintf(".sftpgo-%v.%v.%v", kind, xid.New().String(), path.Base(name)))
+}
+
+func (fs *GCSFs) copyObject(source, target, contentType string) error {
+	src := fs.svc.Bucket(fs.config.Bucket).Object(source)
 	dst := fs.svc.Bucket(fs.config.Bucket).Object(target)
 	ctx, cancelFn := context.WithDeadline(context.Background(), time.Now().Add(fs.ctxTimeout))
 	defer cancelFn()
@@ -234,21 +644,12 @@ func (fs *GCSFs) Rename(source, target string) error {
 	if fs.config.StorageClass != "" {
 		copier.StorageClass = fs.config.StorageClass
 	}
-	var contentType string
-	if fi.IsDir() {
-		contentType = dirMimeType
-	} else {
-		contentType = mime.TypeByExtension(path.Ext(source))
-	}
 	if contentType != "" {
 		copier.ContentType = contentType
 	}
-	_, err = copier.Run(ctx)
+	_, err := copier.Run(ctx)
 	metric.GCSCopyObjectCompleted(err)
-	if err != nil {
-		return err
-	}
-	return fs.Remove(source, fi.IsDir())
+	return err
 }
 
 // Remove removes the named file or (empty) directory.
@@ -265,6 +666,14 @@ func (fs *GCSFs) Remove(name string, isDir bool) error {
 			name += "/"
 		}
 	}
+	var pendingUpload *MultipartUpload
+	if !isDir {
+		var state gcsMultipartUploadState
+		pendingUpload, _ = getMultipartUpload(fs, fs.getMultipartUploadKey(name), &state)
+		if pendingUpload != nil {
+			fs.discardMultipartUpload(name, &state)
+		}
+	}
 	ctx, cancelFn := context.WithDeadline(context.Background(), time.Now().Add(fs.ctxTimeout))
 	defer cancelFn()
 
@@ -274,6 +683,10 @@ func (fs *GCSFs) Remove(name string, isDir bool) error {
 		err = fs.svc.Bucket(fs.config.Bucket).Object(strings.TrimSuffix(name, "/")).Delete(ctx)
 	}
 	metric.GCSDeleteObjectCompleted(err)
+	if err != nil && pendingUpload != nil && fs.IsNotExist(err) {
+		// only the interrupted upload existed
+		return nil
+	}
 	return err
 }
 
@@ -395,9 +808,9 @@ func (fs *GCSFs) ReadDir(dirname string) ([]os.FileInfo, error) {
 }
 
 // IsUploadResumeSupported returns true if resuming uploads is supported.
-// Resuming uploads is not supported on GCS
+// Resumed uploads are composed with the existing object
 func (*GCSFs) IsUploadResumeSupported() bool {
-	return false
+	return true
 }
 
 // IsAtomicUploadSupported returns true if atomic upload is supported.
@@ -614,7 +1027,9 @@ func (fs *GCSFs) getObjectStat(name string) (string, os.FileInfo, error) {
 		objSize := attrs.Size
 		objectModTime := attrs.Updated
 		isDir := attrs.ContentType == dirMimeType || strings.HasSuffix(attrs.Name, "/")
-		return name, NewFileInfo(name, isDir, objSize, objectModTime, false), nil
+		info := NewFileInfo(name, isDir, objSize, objectModTime, false)
+		info.etag = attrs.Etag
+		return name, info, nil
 	}
 	if !fs.IsNotExist(err) {
 		return "", nil, err
@@ -0,0 +1,1842 @@
+package vfs
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-storage-blob-go/azblob"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+	"github.com/eikenb/pipeat"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/drakkan/sftpgo/v2/kms"
+	"github.com/drakkan/sftpgo/v2/sdk"
+	"github.com/drakkan/sftpgo/v2/util"
+)
+
+const (
+	testBucket         = "bucket"
+	testAzAccount      = "devstoreaccount1"
+	testFileName       = "file.dat"
+	gcsUploadChunkUnit = 256 * 1024
+)
+
+var errTestRead = errors.New("test read error")
+
+type testMultipartUploadStore struct {
+	sync.Mutex
+	uploads map[string]MultipartUpload
+}
+
+func newTestMultipartUploadStore() *testMultipartUploadStore {
+	return &testMultipartUploadStore{
+		uploads: make(map[string]MultipartUpload),
+	}
+}
+
+func (s *testMultipartUploadStore) GetMultipartUpload(key string) (MultipartUpload, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	upload, ok := s.uploads[key]
+	if !ok {
+		return upload, util.NewRecordNotFoundError("multipart upload does not exist")
+	}
+	return upload, nil
+}
+
+func (s *testMultipartUploadStore) SaveMultipartUpload(upload *MultipartUpload) error {
+	s.Lock()
+	defer s.Unlock()
+
+	stored := *upload
+	if oldUpload, ok := s.uploads[upload.Key]; ok {
+		stored.CreatedAt = oldUpload.CreatedAt
+	}
+	s.uploads[upload.Key] = stored
+	return nil
+}
+
+func (s *testMultipartUploadStore) DeleteMultipartUpload(key string) error {
+	s.Lock()
+	defer s.Unlock()
+
+	if _, ok := s.uploads[key]; !ok {
+		return util.NewRecordNotFoundError("multipart upload does not exist")
+	}
+	delete(s.uploads, key)
+	return nil
+}
+
+func (s *testMultipartUploadStore) setSize(key string, size int64) {
+	s.Lock()
+	defer s.Unlock()
+
+	upload := s.uploads[key]
+	upload.Size = size
+	s.uploads[key] = upload
+}
+
+func (s *testMultipartUploadStore) count() int {
+	s.Lock()
+	defer s.Unlock()
+
+	return len(s.uploads)
+}
+
+// testErrReader returns the data read from r and then an error instead of io.EOF,
+// waitFn, if set, is called before returning the error
+type testErrReader struct {
+	r      io.Reader
+	waitFn func()
+}
+
+func (r *testErrReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	if err == io.EOF {
+		if r.waitFn != nil {
+			r.waitFn()
+		}
+		return n, errTestRead
+	}
+	return n, err
+}
+
+func getRandomTestData(t *testing.T, size int) []byte {
+	data := make([]byte, size)
+	_, err := rand.Read(data)
+	require.NoError(t, err)
+	return data
+}
+
+func getTestETag(data []byte) string {
+	h := md5.Sum(data)
+	return fmt.Sprintf("%#v", hex.EncodeToString(h[:]))
+}
+
+func writeTestXML(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(status)
+	w.Write([]byte(xml.Header)) //nolint:errcheck
+	xml.NewEncoder(w).Encode(v) //nolint:errcheck
+}
+
+func writeTestJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v) //nolint:errcheck
+}
+
+// uploadTestData uploads data, starting at the specified offset, using the
+// Create method as the protocol handlers do
+func getTestPendingUploadSize(t *testing.T, fs Fs, key string) int64 {
+	var state json.RawMessage
+	upload, err := getMultipartUpload(fs, key, &state)
+	require.NoError(t, err)
+	require.NotNil(t, upload)
+	return upload.Size
+}
+
+func uploadTestData(fs Fs, name string, flag int, data []byte, offset int64) error {
+	_, w, cancelFn, err := fs.Create(name, flag)
+	if err != nil {
+		return err
+	}
+	defer cancelFn()
+
+	if len(data) > 0 {
+		if _, err := w.WriteAt(data, offset); err != nil {
+			w.Close()
+			return err
+		}
+	}
+	return w.Close()
+}
+
+func TestIsUploadResumeRequested(t *testing.T) {
+	assert.False(t, isUploadResumeRequested(0))
+	assert.False(t, isUploadResumeRequested(-1))
+	assert.False(t, isUploadResumeRequested(os.O_WRONLY|os.O_CREATE|os.O_TRUNC))
+	assert.False(t, isUploadResumeRequested(os.O_RDWR|os.O_TRUNC))
+	assert.True(t, isUploadResumeRequested(os.O_WRONLY))
+	assert.True(t, isUploadResumeRequested(os.O_WRONLY|os.O_APPEND))
+	assert.True(t, isUploadResumeRequested(os.O_RDWR|os.O_CREATE))
+}
+
+func TestPipeWriterAtOffset(t *testing.T) {
+	r, w, err := pipeat.PipeInDir(os.TempDir())
+	require.NoError(t, err)
+	p := NewPipeWriterAtOffset(w, 10)
+	ch := make(chan []byte, 1)
+	go func() {
+		data, err := io.ReadAll(r)
+		r.CloseWithError(err) //nolint:errcheck
+		p.Done(err)
+		ch <- data
+	}()
+
+	_, err = p.WriteAt([]byte("data"), 5)
+	assert.Error(t, err)
+	n, err := p.WriteAt([]byte("data"), 10)
+	assert.NoError(t, err)
+	assert.Equal(t, 4, n)
+	n, err = p.WriteAt([]byte("more"), 14)
+	assert.NoError(t, err)
+	assert.Equal(t, 4, n)
+	err = p.Close()
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("datamore"), <-ch)
+
+	r, w, err = pipeat.PipeInDir(os.TempDir())
+	require.NoError(t, err)
+	p = NewPipeWriter(w)
+	go func() {
+		data, err := io.ReadAll(r)
+		r.CloseWithError(err) //nolint:errcheck
+		p.Done(err)
+		ch <- data
+	}()
+	_, err = p.WriteAt([]byte("data"), 0)
+	assert.NoError(t, err)
+	err = p.Close()
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("data"), <-ch)
+}
+
+type s3TestObject struct {
+	data []byte
+	// objects without data are used to test the copy of big objects
+	size int64
+	eTag string
+}
+
+type s3TestPart struct {
+	data []byte
+	size int64
+	eTag string
+}
+
+type s3TestError struct {
+	XMLName xml.Name `xml:"Error"`
+	Code    string   `xml:"Code"`
+	Message string   `xml:"Message"`
+}
+
+type s3TestInitiateResult struct {
+	XMLName  xml.Name `xml:"InitiateMultipartUploadResult"`
+	Bucket   string   `xml:"Bucket"`
+	Key      string   `xml:"Key"`
+	UploadID string   `xml:"UploadId"`
+}
+
+type s3TestCopyPartResult struct {
+	XMLName      xml.Name `xml:"CopyPartResult"`
+	ETag         string   `xml:"ETag"`
+	LastModified string   `xml:"LastModified"`
+}
+
+type s3TestCompleteUpload struct {
+	Parts []struct {
+		ETag       string `xml:"ETag"`
+		PartNumber int64  `xml:"PartNumber"`
+	} `xml:"Part"`
+}
+
+type s3TestCompleteResult struct {
+	XMLName xml.Name `xml:"CompleteMultipartUploadResult"`
+	Bucket  string   `xml:"Bucket"`
+	Key     string   `xml:"Key"`
+	ETag    string   `xml:"ETag"`
+}
+
+type s3TestListedPart struct {
+	PartNumber int64  `xml:"PartNumber"`
+	ETag       string `xml:"ETag"`
+	Size       int64  `xml:"Size"`
+}
+
+type s3TestListPartsResult struct {
+	XMLName     xml.Name           `xml:"ListPartsResult"`
+	Bucket      string             `xml:"Bucket"`
+	Key         string             `xml:"Key"`
+	UploadID    string             `xml:"UploadId"`
+	IsTruncated bool               `xml:"IsTruncated"`
+	Parts       []s3TestListedPart `xml:"Part"`
+}
+
+type s3TestListedObject struct {
+	Key  string `xml:"Key"`
+	Size int64  `xml:"Size"`
+}
+
+type s3TestListObjectsResult struct {
+	XMLName     xml.Name             `xml:"ListBucketResult"`
+	Name        string               `xml:"Name"`
+	Prefix      string               `xml:"Prefix"`
+	KeyCount    int                  `xml:"KeyCount"`
+	IsTruncated bool                 `xml:"IsTruncated"`
+	Contents    []s3TestListedObject `xml:"Contents"`
+}
+
+// s3TestServer is a minimal in memory implementation of the S3 API used by S3Fs
+type s3TestServer struct {
+	sync.Mutex
+	objects       map[string]*s3TestObject
+	uploads       map[string]map[int64]*s3TestPart
+	uploadCounter int
+	uploadedParts int
+	copyRanges    []string
+	aborted       []string
+	server        *httptest.Server
+}
+
+func newS3TestServer(t *testing.T) *s3TestServer {
+	s := &s3TestServer{
+		objects: make(map[string]*s3TestObject),
+		uploads: make(map[string]map[int64]*s3TestPart),
+	}
+	s.server = httptest.NewServer(s)
+	t.Cleanup(s.server.Close)
+	return s
+}
+
+func (s *s3TestServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.Lock()
+	defer s.Unlock()
+
+	key := strings.TrimPrefix(strings.TrimPrefix(r.URL.Path, "/"+testBucket), "/")
+	query := r.URL.Query()
+	uploadID := query.Get("uploadId")
+	_, isCreateUpload := query["uploads"]
+
+	switch {
+	case key == "" && r.Method == http.MethodGet:
+		s.listObjects(w, query.Get("prefix"))
+	case r.Method == http.MethodHead:
+		obj, ok := s.objects[key]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Length", strconv.FormatInt(obj.size, 10))
+		w.Header().Set("ETag", obj.eTag)
+		w.Header().Set("Last-Modified", time.Now().UTC().Format(http.TimeFormat))
+		w.WriteHeader(http.StatusOK)
+	case r.Method == http.MethodGet && uploadID != "":
+		s.listParts(w, key, uploadID)
+	case r.Method == http.MethodGet:
+		s.getObject(w, r, key)
+	case r.Method == http.MethodPut && uploadID != "":
+		s.uploadPart(w, r, uploadID, query.Get("partNumber"))
+	case r.Method == http.MethodPut:
+		data, err := io.ReadAll(r.Body)
+		if err != nil {
+			writeTestXML(w, http.StatusBadRequest, &s3TestError{Code: "IncompleteBody"})
+			return
+		}
+		s.objects[key] = &s3TestObject{data: data, size: int64(len(data)), eTag: getTestETag(data)}
+		w.Header().Set("ETag", s.objects[key].eTag)
+		w.WriteHeader(http.StatusOK)
+	case r.Method == http.MethodPost && isCreateUpload:
+		s.uploadCounter++
+		id := fmt.Sprintf("upload%v", s.uploadCounter)
+		s.uploads[id] = make(map[int64]*s3TestPart)
+		writeTestXML(w, http.StatusOK, &s3TestInitiateResult{Bucket: testBucket, Key: key, UploadID: id})
+	case r.Method == http.MethodPost && uploadID != "":
+		s.completeUpload(w, r, key, uploadID)
+	case r.Method == http.MethodDelete && uploadID != "":
+		delete(s.uploads, uploadID)
+		s.aborted = append(s.aborted, uploadID)
+		w.WriteHeader(http.StatusNoContent)
+	case r.Method == http.MethodDelete:
+		delete(s.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		writeTestXML(w, http.StatusBadRequest, &s3TestError{Code: "NotImplemented"})
+	}
+}
+
+func (s *s3TestServer) listObjects(w http.ResponseWriter, prefix string) {
+	result := s3TestListObjectsResult{
+		Name:   testBucket,
+		Prefix: prefix,
+	}
+	for key, obj := range s.objects {
+		if strings.HasPrefix(key, prefix) {
+			result.Contents = append(result.Contents, s3TestListedObject{Key: key, Size: obj.size})
+		}
+	}
+	result.KeyCount = len(result.Contents)
+	writeTestXML(w, http.StatusOK, &result)
+}
+
+func (s *s3TestServer) listParts(w http.ResponseWriter, key, uploadID string) {
+	parts, ok := s.uploads[uploadID]
+	if !ok {
+		writeTestXML(w, http.StatusNotFound, &s3TestError{Code: "NoSuchUpload"})
+		return
+	}
+	result := s3TestListPartsResult{
+		Bucket:   testBucket,
+		Key:      key,
+		UploadID: uploadID,
+	}
+	for number := int64(1); number <= 10000; number++ {
+		if part, ok := parts[number]; ok {
+			result.Parts = append(result.Parts, s3TestListedPart{PartNumber: number, ETag: part.eTag, Size: part.size})
+		}
+	}
+	writeTestXML(w, http.StatusOK, &result)
+}
+
+func (s *s3TestServer) getObject(w http.ResponseWriter, r *http.Request, key string) {
+	obj, ok := s.objects[key]
+	if !ok {
+		writeTestXML(w, http.StatusNotFound, &s3TestError{Code: "NoSuchKey"})
+		return
+	}
+	if ifMatch := r.Header.Get("If-Match"); ifMatch != "" && ifMatch != obj.eTag {
+		writeTestXML(w, http.StatusPreconditionFailed, &s3TestError{Code: "PreconditionFailed"})
+		return
+	}
+	data := obj.data
+	status := http.StatusOK
+	if rangeHeader := r.Header.Get("Range"); rangeHeader != "" {
+		var first, last int64
+		if _, err := fmt.Sscanf(rangeHeader, "bytes=%d-%d", &first, &last); err != nil || last >= int64(len(data)) {
+			writeTestXML(w, http.StatusRequestedRangeNotSatisfiable, &s3TestError{Code: "InvalidRange"})
+			return
+		}
+		data = data[first : last+1]
+		status = http.StatusPartialContent
+	}
+	w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+	w.Header().Set("ETag", obj.eTag)
+	w.WriteHeader(status)
+	w.Write(data) //nolint:errcheck
+}
+
+func (s *s3TestServer) uploadPart(w http.ResponseWriter, r *http.Request, uploadID, partNumber string) {
+	parts, ok := s.uploads[uploadID]
+	if !ok {
+		writeTestXML(w, http.StatusNotFound, &s3TestError{Code: "NoSuchUpload"})
+		return
+	}
+	number, err := strconv.ParseInt(partNumber, 10, 64)
+	if err != nil || number < 1 || number > 10000 {
+		writeTestXML(w, http.StatusBadRequest, &s3TestError{Code: "InvalidArgument"})
+		return
+	}
+	copySource := r.Header.Get("x-amz-copy-source")
+	if copySource == "" {
+		data, err := io.ReadAll(r.Body)
+		if err != nil {
+			writeTestXML(w, http.StatusBadRequest, &s3TestError{Code: "IncompleteBody"})
+			return
+		}
+		parts[number] = &s3TestPart{data: data, size: int64(len(data)), eTag: getTestETag(data)}
+		s.uploadedParts++
+		w.Header().Set("ETag", parts[number].eTag)
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	source, err := url.PathUnescape(copySource)
+	if err != nil {
+		writeTestXML(w, http.StatusBadRequest, &s3TestError{Code: "InvalidArgument"})
+		return
+	}
+	obj, ok := s.objects[strings.TrimPrefix(strings.TrimPrefix(source, "/"), testBucket+"/")]
+	if !ok {
+		writeTestXML(w, http.StatusNotFound, &s3TestError{Code: "NoSuchKey"})
+		return
+	}
+	if ifMatch := r.Header.Get("x-amz-copy-source-if-match"); ifMatch != "" && ifMatch != obj.eTag {
+		writeTestXML(w, http.StatusPreconditionFailed, &s3TestError{Code: "PreconditionFailed"})
+		return
+	}
+	copyRange := r.Header.Get("x-amz-copy-source-range")
+	var first, last int64
+	if _, err := fmt.Sscanf(copyRange, "bytes=%d-%d", &first, &last); err != nil || first > last || last >= obj.size {
+		writeTestXML(w, http.StatusBadRequest, &s3TestError{Code: "InvalidRange"})
+		return
+	}
+	if last-first+1 > s3MaxCopyPartSize {
+		writeTestXML(w, http.StatusBadRequest, &s3TestError{Code: "InvalidRequest"})
+		return
+	}
+	s.copyRanges = append(s.copyRanges, copyRange)
+	part := &s3TestPart{size: last - first + 1, eTag: getTestETag([]byte(copyRange))}
+	if obj.data != nil {
+		part.data = obj.data[first : last+1]
+		part.eTag = getTestETag(part.data)
+	}
+	parts[number] = part
+	writeTestXML(w, http.StatusOK, &s3TestCopyPartResult{
+		ETag:         part.eTag,
+		LastModified: time.Now().UTC().Format(time.RFC3339),
+	})
+}
+
+func (s *s3TestServer) completeUpload(w http.ResponseWriter, r *http.Request, key, uploadID string) {
+	parts, ok := s.uploads[uploadID]
+	if !ok {
+		writeTestXML(w, http.StatusNotFound, &s3TestError{Code: "NoSuchUpload"})
+		return
+	}
+	var req s3TestCompleteUpload
+	if err := xml.NewDecoder(r.Body).Decode(&req); err != nil || len(req.Parts) == 0 {
+		writeTestXML(w, http.StatusBadRequest, &s3TestError{Code: "MalformedXML"})
+		return
+	}
+	obj := &s3TestObject{}
+	hasData := true
+	var buf bytes.Buffer
+	for idx, completed := range req.Parts {
+		part, ok := parts[completed.PartNumber]
+		if !ok || part.eTag != completed.ETag || (idx > 0 && completed.PartNumber <= req.Parts[idx-1].PartNumber) {
+			writeTestXML(w, http.StatusBadRequest, &s3TestError{Code: "InvalidPart"})
+			return
+		}
+		if idx < len(req.Parts)-1 && part.size < s3manager.MinUploadPartSize {
+			writeTestXML(w, http.StatusBadRequest, &s3TestError{Code: "EntityTooSmall"})
+			return
+		}
+		obj.size += part.size
+		if part.data == nil {
+			hasData = false
+		}
+		buf.Write(part.data)
+	}
+	if hasData {
+		obj.data = buf.Bytes()
+	}
+	obj.eTag = getTestETag([]byte(uploadID))
+	s.objects[key] = obj
+	delete(s.uploads, uploadID)
+	writeTestXML(w, http.StatusOK, &s3TestCompleteResult{Bucket: testBucket, Key: key, ETag: obj.eTag})
+}
+
+func (s *s3TestServer) addObject(key string, data []byte) {
+	s.Lock()
+	defer s.Unlock()
+
+	s.objects[key] = &s3TestObject{data: data, size: int64(len(data)), eTag: getTestETag(data)}
+}
+
+func (s *s3TestServer) getObjectData(key string) []byte {
+	s.Lock()
+	defer s.Unlock()
+
+	if obj, ok := s.objects[key]; ok {
+		return obj.data
+	}
+	return nil
+}
+
+func (s *s3TestServer) getObjectSize(key string) int64 {
+	s.Lock()
+	defer s.Unlock()
+
+	if obj, ok := s.objects[key]; ok {
+		return obj.size
+	}
+	return -1
+}
+
+func (s *s3TestServer) getCopyRanges() []string {
+	s.Lock()
+	defer s.Unlock()
+
+	ranges := s.copyRanges
+	s.copyRanges = nil
+	return ranges
+}
+
+func (s *s3TestServer) getNumUploads() int {
+	s.Lock()
+	defer s.Unlock()
+
+	return len(s.uploads)
+}
+
+func (s *s3TestServer) getAborted() []string {
+	s.Lock()
+	defer s.Unlock()
+
+	return s.aborted
+}
+
+func (s *s3TestServer) getUploadedParts() int {
+	s.Lock()
+	defer s.Unlock()
+
+	return s.uploadedParts
+}
+
+func (s *s3TestServer) waitForUploadedParts(num int) {
+	for i := 0; i < 200; i++ {
+		s.Lock()
+		uploaded := s.uploadedParts
+		s.Unlock()
+		if uploaded >= num {
+			return
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+func newS3TestFs(t *testing.T, s *s3TestServer) *S3Fs {
+	fs, err := NewS3Fs("", os.TempDir(), "", S3FsConfig{
+		S3FsConfig: sdk.S3FsConfig{
+			Bucket:            testBucket,
+			Region:            "us-east-1",
+			AccessKey:         "access_key",
+			AccessSecret:      kms.NewPlainSecret("access_secret"),
+			Endpoint:          s.server.URL,
+			ForcePathStyle:    true,
+			UploadPartSize:    5,
+			UploadConcurrency: 2,
+		},
+	})
+	require.NoError(t, err)
+	return fs.(*S3Fs)
+}
+
+func TestS3ResumeUpload(t *testing.T) {
+	s := newS3TestServer(t)
+	fs := newS3TestFs(t, s)
+
+	// an object of exactly 5MB is copied server side as the first part
+	initialData := getRandomTestData(t, int(s3manager.MinUploadPartSize))
+	newData := getRandomTestData(t, 1024*1024)
+	s.addObject(testFileName, initialData)
+	err := uploadTestData(fs, testFileName, os.O_WRONLY, newData, int64(len(initialData)))
+	assert.NoError(t, err)
+	assert.Equal(t, append(initialData, newData...), s.getObjectData(testFileName))
+	assert.Equal(t, []string{fmt.Sprintf("bytes=0-%v", s3manager.MinUploadPartSize-1)}, s.getCopyRanges())
+	// writes before the resume offset are not allowed
+	err = uploadTestData(fs, testFileName, os.O_WRONLY, newData, 10)
+	assert.Error(t, err)
+	s.getCopyRanges()
+	// smaller objects are uploaded again
+	initialData = getRandomTestData(t, int(s3manager.MinUploadPartSize-1))
+	s.addObject(testFileName, initialData)
+	err = uploadTestData(fs, testFileName, os.O_WRONLY|os.O_APPEND, newData, int64(len(initialData)))
+	assert.NoError(t, err)
+	assert.Equal(t, append(initialData, newData...), s.getObjectData(testFileName))
+	assert.Len(t, s.getCopyRanges(), 0)
+	// truncate
+	err = uploadTestData(fs, testFileName, os.O_WRONLY|os.O_TRUNC, newData, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, newData, s.getObjectData(testFileName))
+	// the existing object is not modified if the resumed upload fails
+	s.addObject(testFileName, initialData)
+	resumeObj, err := fs.headObject(testFileName)
+	require.NoError(t, err)
+	s.addObject(testFileName, newData)
+	err = fs.resumeUpload(context.Background(), bytes.NewReader(newData), testFileName, "", resumeObj)
+	assert.Error(t, err)
+	assert.Equal(t, newData, s.getObjectData(testFileName))
+	assert.Equal(t, 0, s.getNumUploads())
+}
+
+func TestS3InterruptedUpload(t *testing.T) {
+	store := newTestMultipartUploadStore()
+	SetMultipartUploadStore(store)
+	defer SetMultipartUploadStore(nil)
+
+	s := newS3TestServer(t)
+	fs := newS3TestFs(t, s)
+	partSize := int(fs.config.UploadPartSize)
+	data := getRandomTestData(t, 4*partSize+1024)
+	// the upload is interrupted after two parts, the third part is not completed
+	reader := &testErrReader{
+		r: bytes.NewReader(data[:2*partSize+1024]),
+		waitFn: func() {
+			s.waitForUploadedParts(2)
+		},
+	}
+	err := fs.uploadObject(context.Background(), reader, testFileName, "")
+	assert.Error(t, err)
+	assert.Equal(t, 1, s.getNumUploads())
+	assert.Equal(t, 1, store.count())
+	// the interrupted upload is not visible as a file
+	_, err = fs.Stat(testFileName)
+	assert.True(t, fs.IsNotExist(err))
+	assert.Equal(t, int64(2*partSize), getTestPendingUploadSize(t, fs, fs.getMultipartUploadKey(testFileName)))
+	// interrupt the upload again after a new part
+	var state s3MultipartUploadState
+	upload, err := getMultipartUpload(fs, fs.getMultipartUploadKey(testFileName), &state)
+	require.NoError(t, err)
+	require.NotNil(t, upload)
+	assert.Len(t, state.Parts, 2)
+	reader = &testErrReader{
+		r: bytes.NewReader(data[upload.Size : upload.Size+int64(partSize)+10]),
+	}
+	err = fs.continueMultipartUpload(context.Background(), reader, testFileName, &state)
+	assert.Error(t, err)
+	_, err = fs.Stat(testFileName)
+	assert.True(t, fs.IsNotExist(err))
+	pendingSize := getTestPendingUploadSize(t, fs, fs.getMultipartUploadKey(testFileName))
+	assert.Equal(t, int64(3*partSize), pendingSize)
+	// a resume at a different offset is not allowed
+	err = uploadTestData(fs, testFileName, os.O_WRONLY, data[:10], 0)
+	assert.Error(t, err)
+	// now complete the upload
+	err = uploadTestData(fs, testFileName, os.O_WRONLY, data[pendingSize:], pendingSize)
+	assert.NoError(t, err)
+	assert.Equal(t, data, s.getObjectData(testFileName))
+	assert.Equal(t, 0, store.count())
+	assert.Equal(t, 0, s.getNumUploads())
+	info, err := fs.Stat(testFileName)
+	require.NoError(t, err)
+	assert.Equal(t, int64(len(data)), info.Size())
+	// resuming an existing object discards the interrupted upload for the same path
+	uploadedParts := s.getUploadedParts()
+	reader = &testErrReader{
+		r: bytes.NewReader(data[:partSize+10]),
+		waitFn: func() {
+			s.waitForUploadedParts(uploadedParts + 1)
+		},
+	}
+	err = fs.uploadObject(context.Background(), reader, testFileName, "")
+	assert.Error(t, err)
+	assert.Equal(t, 1, store.count())
+	info, err = fs.Stat(testFileName)
+	require.NoError(t, err)
+	assert.Equal(t, int64(len(data)), info.Size())
+	err = uploadTestData(fs, testFileName, os.O_WRONLY, data[:10], info.Size())
+	assert.NoError(t, err)
+	assert.Equal(t, append(data, data[:10]...), s.getObjectData(testFileName))
+	assert.Equal(t, 0, store.count())
+	assert.Equal(t, 0, s.getNumUploads())
+	// truncate and remove discard the interrupted upload too
+	name := "other.dat"
+	for _, discardFn := range []func() error{
+		func() error {
+			return uploadTestData(fs, name, os.O_WRONLY|os.O_TRUNC, data[:10], 0)
+		},
+		func() error {
+			return fs.Remove(name, false)
+		},
+	} {
+		numAborted := len(s.getAborted())
+		uploadedParts := s.getUploadedParts()
+		reader = &testErrReader{
+			r: bytes.NewReader(data[:partSize+10]),
+			waitFn: func() {
+				s.waitForUploadedParts(uploadedParts + 1)
+			},
+		}
+		err = fs.uploadObject(context.Background(), reader, name, "")
+		assert.Error(t, err)
+		assert.Equal(t, 1, store.count())
+		err = discardFn()
+		assert.NoError(t, err)
+		assert.Equal(t, 0, store.count())
+		assert.Equal(t, 0, s.getNumUploads())
+		assert.Len(t, s.getAborted(), numAborted+1)
+	}
+	// without a store the parts are not kept
+	SetMultipartUploadStore(nil)
+	reader = &testErrReader{
+		r: bytes.NewReader(data[:partSize+10]),
+	}
+	err = fs.uploadObject(context.Background(), reader, name, "")
+	assert.Error(t, err)
+	assert.Equal(t, 0, s.getNumUploads())
+}
+
+func TestS3CombineFiles(t *testing.T) {
+	s := newS3TestServer(t)
+	fs := newS3TestFs(t, s)
+
+	sizes := []int{1024 * 1024, 6 * 1024 * 1024, 0, 2 * 1024 * 1024, 3 * 1024 * 1024, int(s3manager.MinUploadPartSize),
+		100 * 1024}
+	var sources []string
+	var expected []byte
+	for idx, size := range sizes {
+		name := fmt.Sprintf("source%v", idx)
+		data := getRandomTestData(t, size)
+		s.addObject(name, data)
+		sources = append(sources, name)
+		expected = append(expected, data...)
+	}
+	err := fs.CombineFiles("target", sources)
+	assert.NoError(t, err)
+	assert.Equal(t, expected, s.getObjectData("target"))
+	// only the source with 5MB is copied, the smaller sources are grouped in bigger parts
+	assert.Equal(t, []string{fmt.Sprintf("bytes=0-%v", s3manager.MinUploadPartSize-1)}, s.getCopyRanges())
+	// the first bytes of a big source complete the pending part, the remaining ones
+	// are copied
+	s.addObject("big", getRandomTestData(t, 12*1024*1024))
+	err = fs.CombineFiles("target", []string{"source0", "big", "source1", "source5"})
+	assert.NoError(t, err)
+	expected = append(append(s.getObjectData("source0"), s.getObjectData("big")...), s.getObjectData("source1")...)
+	expected = append(expected, s.getObjectData("source5")...)
+	assert.Equal(t, expected, s.getObjectData("target"))
+	assert.Equal(t, []string{
+		fmt.Sprintf("bytes=%v-%v", 4*1024*1024, 12*1024*1024-1),
+		fmt.Sprintf("bytes=0-%v", 6*1024*1024-1),
+		fmt.Sprintf("bytes=0-%v", s3manager.MinUploadPartSize-1),
+	}, s.getCopyRanges())
+	// empty sources
+	err = fs.CombineFiles("target", []string{"source2"})
+	assert.NoError(t, err)
+	assert.Len(t, s.getObjectData("target"), 0)
+	err = fs.CombineFiles("target", nil)
+	assert.Error(t, err)
+	err = fs.CombineFiles("target", []string{"missing"})
+	assert.Error(t, err)
+	assert.Equal(t, 0, s.getNumUploads())
+}
+
+func TestS3PartCopyBoundaries(t *testing.T) {
+	s := newS3TestServer(t)
+	fs := newS3TestFs(t, s)
+
+	const gb = int64(1024 * 1024 * 1024)
+	s.Lock()
+	s.objects["big"] = &s3TestObject{size: 15*gb + 10, eTag: getTestETag([]byte("big"))}
+	s.Unlock()
+	obj, err := fs.headObject("big")
+	require.NoError(t, err)
+
+	testCases := []struct {
+		start    int64
+		end      int64
+		numParts int
+	}{
+		{start: 0, end: s3MaxCopyPartSize - 1, numParts: 1},
+		{start: 0, end: s3MaxCopyPartSize, numParts: 1},
+		{start: 0, end: s3MaxCopyPartSize + 1, numParts: 2},
+		{start: 1, end: 2*s3MaxCopyPartSize + 1, numParts: 2},
+		{start: 0, end: 2*s3MaxCopyPartSize + 1, numParts: 3},
+		{start: 10, end: 15*gb + 10, numParts: 3},
+		{start: 0, end: 15*gb + 10, numParts: 4},
+	}
+	for _, tc := range testCases {
+		ctx := context.Background()
+		numParts, err := fs.multipartUpload(ctx, "target", "", func(uploadID *string) ([]*s3.CompletedPart, error) {
+			return fs.uploadPartsCopy(ctx, "target", uploadID, nil, "big", obj.ETag, tc.start, tc.end)
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, tc.numParts, numParts)
+		assert.Equal(t, tc.end-tc.start, s.getObjectSize("target"))
+		ranges := s.getCopyRanges()
+		require.Len(t, ranges, tc.numParts)
+		offset := tc.start
+		for _, copyRange := range ranges {
+			var first, last int64
+			_, err := fmt.Sscanf(copyRange, "bytes=%d-%d", &first, &last)
+			assert.NoError(t, err)
+			assert.Equal(t, offset, first)
+			assert.LessOrEqual(t, last-first+1, int64(s3MaxCopyPartSize))
+			offset = last + 1
+		}
+		assert.Equal(t, tc.end, offset)
+	}
+	// the parts are appended to the existing ones
+	ctx := context.Background()
+	parts := []*s3.CompletedPart{{ETag: aws.String("etag"), PartNumber: aws.Int64(1)}}
+	_, err = fs.multipartUpload(ctx, "target", "", func(uploadID *string) ([]*s3.CompletedPart, error) {
+		var err error
+		parts, err = fs.uploadPartsCopy(ctx, "target", uploadID, parts, "big", obj.ETag, 0, 0)
+		if err != nil {
+			return nil, err
+		}
+		assert.Len(t, parts, 1)
+		parts, err = fs.uploadPartsCopy(ctx, "target", uploadID, parts, "big", obj.ETag, 0, s3MaxCopyPartSize+1)
+		if err != nil {
+			return nil, err
+		}
+		assert.Len(t, parts, 3)
+		assert.Equal(t, int64(2), aws.Int64Value(parts[1].PartNumber))
+		assert.Equal(t, int64(3), aws.Int64Value(parts[2].PartNumber))
+		return parts[1:], nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(s3MaxCopyPartSize+1), s.getObjectSize("target"))
+	assert.Len(t, s.getCopyRanges(), 2)
+	assert.Equal(t, 0, s.getNumUploads())
+	// the source was modified
+	_, err = fs.multipartUpload(ctx, "target", "", func(uploadID *string) ([]*s3.CompletedPart, error) {
+		return fs.uploadPartsCopy(ctx, "target", uploadID, nil, "big", aws.String("modified"), 0, 10)
+	})
+	assert.Error(t, err)
+	assert.Equal(t, 0, s.getNumUploads())
+}
+
+type gcsTestObject struct {
+	data        []byte
+	generation  int64
+	contentType string
+}
+
+type gcsTestSession struct {
+	name        string
+	contentType string
+	data        []byte
+}
+
+type gcsTestComposeRequest struct {
+	Destination struct {
+		ContentType string `json:"contentType"`
+	} `json:"destination"`
+	SourceObjects []struct {
+		Name       string `json:"name"`
+		Generation string `json:"generation"`
+	} `json:"sourceObjects"`
+}
+
+type gcsTestObjectMetadata struct {
+	Name        string `json:"name"`
+	ContentType string `json:"contentType"`
+}
+
+// gcsTestServer is a minimal in memory implementation of the Google Cloud Storage
+// JSON API used by GCSFs and by the client library
+type gcsTestServer struct {
+	sync.Mutex
+	objects        map[string]*gcsTestObject
+	sessions       map[string]*gcsTestSession
+	generation     int64
+	sessionCounter int
+	// max bytes persisted for each chunk, 0 means no limit
+	persistLimit int
+	// if true the next chunk is persisted but an error is returned
+	failNextChunk  bool
+	composeSources []int
+	server         *httptest.Server
+}
+
+func newGCSTestServer(t *testing.T) *gcsTestServer {
+	s := &gcsTestServer{
+		objects:  make(map[string]*gcsTestObject),
+		sessions: make(map[string]*gcsTestSession),
+	}
+	s.server = httptest.NewServer(s)
+	t.Cleanup(s.server.Close)
+	return s
+}
+
+func (s *gcsTestServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.Lock()
+	defer s.Unlock()
+
+	objectsPath := fmt.Sprintf("/storage/v1/b/%v/o/", testBucket)
+	uploadPath := fmt.Sprintf("/upload/storage/v1/b/%v/o", testBucket)
+	query := r.URL.Query()
+
+	switch {
+	case r.URL.Path == uploadPath && r.Method == http.MethodPost && query.Get("uploadType") == "multipart":
+		s.uploadMultipart(w, r)
+	case r.URL.Path == uploadPath && r.Method == http.MethodPost && query.Get("uploadType") == "resumable":
+		var metadata gcsTestObjectMetadata
+		if err := json.NewDecoder(r.Body).Decode(&metadata); err != nil || metadata.Name == "" {
+			s.writeError(w, http.StatusBadRequest)
+			return
+		}
+		s.sessionCounter++
+		id := fmt.Sprintf("session%v", s.sessionCounter)
+		s.sessions[id] = &gcsTestSession{name: metadata.Name, contentType: metadata.ContentType}
+		w.Header().Set("Location", fmt.Sprintf("%v%v?uploadType=resumable&upload_id=%v", s.server.URL, uploadPath, id))
+		w.WriteHeader(http.StatusOK)
+	case r.URL.Path == uploadPath && r.Method == http.MethodPut:
+		s.uploadChunk(w, r, query.Get("upload_id"))
+	case r.URL.Path == uploadPath && r.Method == http.MethodDelete:
+		delete(s.sessions, query.Get("upload_id"))
+		w.WriteHeader(499)
+	case r.URL.Path == strings.TrimSuffix(objectsPath, "/") && r.Method == http.MethodGet:
+		s.listObjects(w, query.Get("prefix"))
+	case strings.HasPrefix(r.URL.Path, objectsPath) && strings.HasSuffix(r.URL.Path, "/compose") &&
+		r.Method == http.MethodPost:
+		s.compose(w, r, strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, objectsPath), "/compose"))
+	case strings.HasPrefix(r.URL.Path, objectsPath) && r.Method == http.MethodGet:
+		name := strings.TrimPrefix(r.URL.Path, objectsPath)
+		obj, ok := s.objects[name]
+		if !ok {
+			s.writeError(w, http.StatusNotFound)
+			return
+		}
+		s.writeObject(w, name, obj)
+	case strings.HasPrefix(r.URL.Path, objectsPath) && r.Method == http.MethodDelete:
+		name := strings.TrimPrefix(r.URL.Path, objectsPath)
+		if _, ok := s.objects[name]; !ok {
+			s.writeError(w, http.StatusNotFound)
+			return
+		}
+		delete(s.objects, name)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		s.writeError(w, http.StatusNotImplemented)
+	}
+}
+
+func (s *gcsTestServer) writeError(w http.ResponseWriter, status int) {
+	writeTestJSON(w, status, map[string]interface{}{
+		"error": map[string]interface{}{
+			"code":    status,
+			"message": http.StatusText(status),
+		},
+	})
+}
+
+func (s *gcsTestServer) listObjects(w http.ResponseWriter, prefix string) {
+	items := make([]map[string]interface{}, 0)
+	for name, obj := range s.objects {
+		if strings.HasPrefix(name, prefix) {
+			items = append(items, map[string]interface{}{
+				"kind":       "storage#object",
+				"bucket":     testBucket,
+				"name":       name,
+				"size":       strconv.Itoa(len(obj.data)),
+				"generation": strconv.FormatInt(obj.generation, 10),
+			})
+		}
+	}
+	writeTestJSON(w, http.StatusOK, map[string]interface{}{
+		"kind":  "storage#objects",
+		"items": items,
+	})
+}
+
+func (s *gcsTestServer) writeObject(w http.ResponseWriter, name string, obj *gcsTestObject) {
+	writeTestJSON(w, http.StatusOK, map[string]interface{}{
+		"kind":           "storage#object",
+		"bucket":         testBucket,
+		"name":           name,
+		"size":           strconv.Itoa(len(obj.data)),
+		"generation":     strconv.FormatInt(obj.generation, 10),
+		"metageneration": "1",
+		"contentType":    obj.contentType,
+		"etag":           strconv.FormatInt(obj.generation, 10),
+		"updated":        time.Now().UTC().Format(time.RFC3339Nano),
+	})
+}
+
+func (s *gcsTestServer) addObject(name string, data []byte, contentType string) *gcsTestObject {
+	s.generation++
+	obj := &gcsTestObject{data: data, generation: s.generation, contentType: contentType}
+	s.objects[name] = obj
+	return obj
+}
+
+func (s *gcsTestServer) uploadMultipart(w http.ResponseWriter, r *http.Request) {
+	_, params, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest)
+		return
+	}
+	reader := multipart.NewReader(r.Body, params["boundary"])
+	part, err := reader.NextPart()
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest)
+		return
+	}
+	var metadata gcsTestObjectMetadata
+	if err := json.NewDecoder(part).Decode(&metadata); err != nil || metadata.Name == "" {
+		s.writeError(w, http.StatusBadRequest)
+		return
+	}
+	part, err = reader.NextPart()
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest)
+		return
+	}
+	data, err := io.ReadAll(part)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest)
+		return
+	}
+	s.writeObject(w, metadata.Name, s.addObject(metadata.Name, data, metadata.ContentType))
+}
+
+func (s *gcsTestServer) uploadChunk(w http.ResponseWriter, r *http.Request, id string) {
+	session, ok := s.sessions[id]
+	if !ok {
+		s.writeError(w, http.StatusNotFound)
+		return
+	}
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest)
+		return
+	}
+	contentRange := r.Header.Get("Content-Range")
+	total := int64(-1)
+	if strings.HasPrefix(contentRange, "bytes */") {
+		if t := strings.TrimPrefix(contentRange, "bytes */"); t != "*" {
+			if total, err = strconv.ParseInt(t, 10, 64); err != nil {
+				s.writeError(w, http.StatusBadRequest)
+				return
+			}
+		}
+	} else {
+		var first, last int64
+		var t string
+		if _, err := fmt.Sscanf(contentRange, "bytes %d-%d/%s", &first, &last, &t); err != nil ||
+			first != int64(len(session.data)) || last-first+1 != int64(len(data)) {
+			s.writeError(w, http.StatusBadRequest)
+			return
+		}
+		if t != "*" {
+			if total, err = strconv.ParseInt(t, 10, 64); err != nil {
+				s.writeError(w, http.StatusBadRequest)
+				return
+			}
+		}
+		if total != last+1 {
+			// the chunks, except the last one, must be a multiple of 256KB
+			if len(data)%gcsUploadChunkUnit != 0 {
+				s.writeError(w, http.StatusBadRequest)
+				return
+			}
+			if s.persistLimit > 0 && len(data) > s.persistLimit {
+				data = data[:s.persistLimit]
+			}
+		}
+		session.data = append(session.data, data...)
+	}
+	if s.failNextChunk && len(data) > 0 {
+		s.failNextChunk = false
+		s.writeError(w, http.StatusServiceUnavailable)
+		return
+	}
+	if total >= 0 && total == int64(len(session.data)) {
+		delete(s.sessions, id)
+		s.writeObject(w, session.name, s.addObject(session.name, session.data, session.contentType))
+		return
+	}
+	if len(session.data) > 0 {
+		w.Header().Set("Range", fmt.Sprintf("bytes=0-%v", len(session.data)-1))
+	}
+	w.WriteHeader(http.StatusPermanentRedirect)
+}
+
+func (s *gcsTestServer) compose(w http.ResponseWriter, r *http.Request, name string) {
+	var req gcsTestComposeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.writeError(w, http.StatusBadRequest)
+		return
+	}
+	if len(req.SourceObjects) == 0 || len(req.SourceObjects) > gcsMaxComposeSources {
+		s.writeError(w, http.StatusBadRequest)
+		return
+	}
+	if generationMatch := r.URL.Query().Get("ifGenerationMatch"); generationMatch != "" {
+		var generation int64
+		if obj, ok := s.objects[name]; ok {
+			generation = obj.generation
+		}
+		if generationMatch != strconv.FormatInt(generation, 10) {
+			s.writeError(w, http.StatusPreconditionFailed)
+			return
+		}
+	}
+	var data []byte
+	for _, source := range req.SourceObjects {
+		obj, ok := s.objects[source.Name]
+		if !ok {
+			s.writeError(w, http.StatusNotFound)
+			return
+		}
+		if source.Generation != "" && source.Generation != strconv.FormatInt(obj.generation, 10) {
+			s.writeError(w, http.StatusNotFound)
+			return
+		}
+		data = append(data, obj.data...)
+	}
+	s.composeSources = append(s.composeSources, len(req.SourceObjects))
+	s.writeObject(w, name, s.addObject(name, data, req.Destination.ContentType))
+}
+
+func (s *gcsTestServer) setObject(name string, data []byte) {
+	s.Lock()
+	defer s.Unlock()
+
+	s.addObject(name, data, "")
+}
+
+func (s *gcsTestServer) getObjectData(name string) []byte {
+	s.Lock()
+	defer s.Unlock()
+
+	if obj, ok := s.objects[name]; ok {
+		return obj.data
+	}
+	return nil
+}
+
+func (s *gcsTestServer) getNumObjects() int {
+	s.Lock()
+	defer s.Unlock()
+
+	return len(s.objects)
+}
+
+func (s *gcsTestServer) getNumSessions() int {
+	s.Lock()
+	defer s.Unlock()
+
+	return len(s.sessions)
+}
+
+func (s *gcsTestServer) getComposeSources() []int {
+	s.Lock()
+	defer s.Unlock()
+
+	sources := s.composeSources
+	s.composeSources = nil
+	return sources
+}
+
+func (s *gcsTestServer) setPersistLimit(limit int, failNextChunk bool) {
+	s.Lock()
+	defer s.Unlock()
+
+	s.persistLimit = limit
+	s.failNextChunk = failNextChunk
+}
+
+func newGCSTestFs(t *testing.T, s *gcsTestServer) *GCSFs {
+	t.Setenv("STORAGE_EMULATOR_HOST", s.server.URL)
+	fs, err := NewGCSFs("", os.TempDir(), "", GCSFsConfig{
+		GCSFsConfig: sdk.GCSFsConfig{
+			Bucket:               testBucket,
+			AutomaticCredentials: 1,
+		},
+	})
+	require.NoError(t, err)
+	return fs.(*GCSFs)
+}
+
+func TestGCSResumeUpload(t *testing.T) {
+	s := newGCSTestServer(t)
+	fs := newGCSTestFs(t, s)
+
+	initialData := getRandomTestData(t, 1024*1024)
+	newData := getRandomTestData(t, 512*1024)
+	s.setObject(testFileName, initialData)
+	err := uploadTestData(fs, testFileName, os.O_WRONLY, newData, int64(len(initialData)))
+	assert.NoError(t, err)
+	assert.Equal(t, append(initialData, newData...), s.getObjectData(testFileName))
+	assert.Equal(t, []int{2}, s.getComposeSources())
+	// the temporary object is removed
+	assert.Equal(t, 1, s.getNumObjects())
+	// writes before the resume offset are not allowed
+	err = uploadTestData(fs, testFileName, os.O_WRONLY, newData, 10)
+	assert.Error(t, err)
+	// truncate
+	err = uploadTestData(fs, testFileName, os.O_WRONLY|os.O_TRUNC, newData, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, newData, s.getObjectData(testFileName))
+	// the existing object is not replaced if it was modified in the meantime
+	attrs, err := fs.headObject(testFileName)
+	require.NoError(t, err)
+	s.setObject(testFileName, initialData)
+	_, err = fs.resumeUpload(context.Background(), bytes.NewReader(newData), attrs, "")
+	assert.Error(t, err)
+	assert.Equal(t, initialData, s.getObjectData(testFileName))
+	assert.Equal(t, 1, s.getNumObjects())
+}
+
+func TestGCSInterruptedUpload(t *testing.T) {
+	store := newTestMultipartUploadStore()
+	SetMultipartUploadStore(store)
+	defer SetMultipartUploadStore(nil)
+
+	s := newGCSTestServer(t)
+	fs := newGCSTestFs(t, s)
+	data := getRandomTestData(t, 2*gcsUploadChunkSize+4*1024*1024)
+	// only a part of the first chunk is persisted, then the upload is interrupted
+	s.setPersistLimit(gcsUploadChunkSize/2, false)
+	reader := &testErrReader{
+		r: bytes.NewReader(data[:gcsUploadChunkSize+1024*1024]),
+	}
+	_, err := fs.uploadResumable(context.Background(), reader, testFileName, "", nil, &gcsMultipartUploadState{})
+	assert.ErrorIs(t, err, errTestRead)
+	assert.Equal(t, 1, store.count())
+	assert.Equal(t, 1, s.getNumSessions())
+	// the interrupted upload is not visible as a file
+	_, err = fs.Stat(testFileName)
+	assert.True(t, fs.IsNotExist(err))
+	assert.Equal(t, int64(gcsUploadChunkSize/2), getTestPendingUploadSize(t, fs, fs.getMultipartUploadKey(testFileName)))
+	// the next chunk is persisted but the upload fails, the saved size is lower than
+	// the persisted one and the data already persisted are skipped on resume
+	s.setPersistLimit(0, true)
+	var state gcsMultipartUploadState
+	upload, err := getMultipartUpload(fs, fs.getMultipartUploadKey(testFileName), &state)
+	require.NoError(t, err)
+	require.NotNil(t, upload)
+	reader = &testErrReader{
+		r: bytes.NewReader(data[upload.Size : upload.Size+gcsUploadChunkSize+10]),
+	}
+	_, err = fs.uploadResumable(context.Background(), reader, testFileName, "", upload, &state)
+	assert.Error(t, err)
+	_, err = fs.Stat(testFileName)
+	assert.True(t, fs.IsNotExist(err))
+	pendingSize := getTestPendingUploadSize(t, fs, fs.getMultipartUploadKey(testFileName))
+	assert.Equal(t, int64(gcsUploadChunkSize/2), pendingSize)
+	// a resume at a different offset is not allowed
+	err = uploadTestData(fs, testFileName, os.O_WRONLY, data[:10], 0)
+	assert.Error(t, err)
+	// now complete the upload
+	err = uploadTestData(fs, testFileName, os.O_WRONLY, data[pendingSize:], pendingSize)
+	assert.NoError(t, err)
+	assert.Equal(t, data, s.getObjectData(testFileName))
+	assert.Equal(t, 0, store.count())
+	assert.Equal(t, 0, s.getNumSessions())
+	info, err := fs.Stat(testFileName)
+	require.NoError(t, err)
+	assert.Equal(t, int64(len(data)), info.Size())
+	// truncate and remove discard the interrupted upload
+	name := "other.dat"
+	for _, discardFn := range []func() error{
+		func() error {
+			return uploadTestData(fs, name, os.O_WRONLY|os.O_TRUNC, data[:10], 0)
+		},
+		func() error {
+			return fs.Remove(name, false)
+		},
+	} {
+		reader = &testErrReader{
+			r: bytes.NewReader(data[:gcsUploadChunkSize+10]),
+		}
+		_, err = fs.uploadResumable(context.Background(), reader, name, "", nil, &gcsMultipartUploadState{})
+		assert.Error(t, err)
+		assert.Equal(t, 1, store.count())
+		err = discardFn()
+		assert.NoError(t, err)
+		assert.Equal(t, 0, store.count())
+		assert.Equal(t, 0, s.getNumSessions())
+	}
+	// nothing is saved if no data was persisted
+	reader = &testErrReader{
+		r: bytes.NewReader(data[:10]),
+	}
+	_, err = fs.uploadResumable(context.Background(), reader, name, "", nil, &gcsMultipartUploadState{})
+	assert.Error(t, err)
+	assert.Equal(t, 0, store.count())
+	// the upload session does not exist anymore
+	reader = &testErrReader{
+		r: bytes.NewReader(data[:gcsUploadChunkSize+10]),
+	}
+	_, err = fs.uploadResumable(context.Background(), reader, name, "", nil, &gcsMultipartUploadState{})
+	assert.Error(t, err)
+	assert.Equal(t, 1, store.count())
+	s.Lock()
+	s.sessions = make(map[string]*gcsTestSession)
+	s.Unlock()
+	pendingSize = getTestPendingUploadSize(t, fs, fs.getMultipartUploadKey(name))
+	err = uploadTestData(fs, name, os.O_WRONLY, data[pendingSize:], pendingSize)
+	assert.Error(t, err)
+	assert.Equal(t, 0, store.count())
+}
+
+func TestGCSCombineFiles(t *testing.T) {
+	s := newGCSTestServer(t)
+	fs := newGCSTestFs(t, s)
+
+	testCases := []struct {
+		numSources     int
+		composeSources []int
+	}{
+		{numSources: 1, composeSources: []int{1}},
+		{numSources: 32, composeSources: []int{32}},
+		// the last source is not composed alone
+		{numSources: 33, composeSources: []int{32, 2}},
+		{numSources: 65, composeSources: []int{32, 32, 3}},
+		{numSources: 70, composeSources: []int{32, 32, 6, 3}},
+		// 33 temporary objects are composed in the first pass
+		{numSources: 32*32 + 1, composeSources: append(getRepeatedInts(32, 32), 32, 2)},
+	}
+	for _, tc := range testCases {
+		s.Lock()
+		s.objects = make(map[string]*gcsTestObject)
+		s.Unlock()
+		var sources []string
+		var expected []byte
+		for i := 0; i < tc.numSources; i++ {
+			name := fmt.Sprintf("source%v", i)
+			data := []byte(fmt.Sprintf("data for source %v;", i))
+			s.setObject(name, data)
+			sources = append(sources, name)
+			expected = append(expected, data...)
+		}
+		err := fs.CombineFiles("target", sources)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, s.getObjectData("target"))
+		assert.Equal(t, tc.composeSources, s.getComposeSources(), "sources: %v", tc.numSources)
+		// the temporary objects are removed
+		assert.Equal(t, tc.numSources+1, s.getNumObjects())
+	}
+	err := fs.CombineFiles("target", []string{"source0", "missing"})
+	assert.Error(t, err)
+	err = fs.CombineFiles("target", nil)
+	assert.Error(t, err)
+}
+
+func getRepeatedInts(value, count int) []int {
+	result := make([]int, 0, count)
+	for i := 0; i < count; i++ {
+		result = append(result, value)
+	}
+	return result
+}
+
+type azTestBlock struct {
+	id   string
+	data []byte
+}
+
+type azTestBlob struct {
+	data        []byte
+	blocks      []azTestBlock
+	eTag        string
+	contentType string
+}
+
+type azTestBlockList struct {
+	XMLName         xml.Name          `xml:"BlockList"`
+	CommittedBlocks []azTestBlockItem `xml:"CommittedBlocks>Block"`
+	// always present in the responses
+	UncommittedBlocks struct{} `xml:"UncommittedBlocks"`
+}
+
+type azTestBlockItem struct {
+	Name string `xml:"Name"`
+	Size int    `xml:"Size"`
+}
+
+type azTestError struct {
+	XMLName xml.Name `xml:"Error"`
+	Code    string   `xml:"Code"`
+	Message string   `xml:"Message"`
+}
+
+type azTestBlobItem struct {
+	Name string `xml:"Name"`
+}
+
+type azTestListBlobsResult struct {
+	XMLName    xml.Name         `xml:"EnumerationResults"`
+	Prefix     string           `xml:"Prefix"`
+	Blobs      []azTestBlobItem `xml:"Blobs>Blob"`
+	NextMarker string           `xml:"NextMarker"`
+}
+
+// azTestServer is a minimal in memory implementation of the Azure Blob Storage
+// API used by AzureBlobFs
+type azTestServer struct {
+	sync.Mutex
+	blobs       map[string]*azTestBlob
+	uncommitted map[string]map[string][]byte
+	etagCounter int
+	// source ranges for the blocks staged from URL
+	stagedRanges []string
+	server       *httptest.Server
+}
+
+func newAzTestServer(t *testing.T) *azTestServer {
+	s := &azTestServer{
+		blobs:       make(map[string]*azTestBlob),
+		uncommitted: make(map[string]map[string][]byte),
+	}
+	s.server = httptest.NewServer(s)
+	t.Cleanup(s.server.Close)
+	return s
+}
+
+func (s *azTestServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.Lock()
+	defer s.Unlock()
+
+	containerPath := fmt.Sprintf("/%v/%v", testAzAccount, testBucket)
+	query := r.URL.Query()
+	if r.URL.Path == containerPath {
+		if r.Method == http.MethodGet && query.Get("comp") == "list" {
+			result := azTestListBlobsResult{Prefix: query.Get("prefix")}
+			for name := range s.blobs {
+				if strings.HasPrefix(name, result.Prefix) {
+					result.Blobs = append(result.Blobs, azTestBlobItem{Name: name})
+				}
+			}
+			writeTestXML(w, http.StatusOK, &result)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	name := strings.TrimPrefix(r.URL.Path, containerPath+"/")
+	blob, blobExists := s.blobs[name]
+	if !blobExists && (r.Method == http.MethodHead || r.Method == http.MethodDelete || r.Method == http.MethodGet) {
+		s.writeError(w, http.StatusNotFound, string(azblob.ServiceCodeBlobNotFound))
+		return
+	}
+
+	switch {
+	case r.Method == http.MethodHead:
+		s.writeBlobHeaders(w, blob, len(blob.data))
+		w.WriteHeader(http.StatusOK)
+	case r.Method == http.MethodGet && query.Get("comp") == "blocklist":
+		var result azTestBlockList
+		for _, block := range blob.blocks {
+			result.CommittedBlocks = append(result.CommittedBlocks, azTestBlockItem{Name: block.id, Size: len(block.data)})
+		}
+		writeTestXML(w, http.StatusOK, &result)
+	case r.Method == http.MethodGet:
+		s.writeBlobHeaders(w, blob, len(blob.data))
+		w.WriteHeader(http.StatusOK)
+		w.Write(blob.data) //nolint:errcheck
+	case r.Method == http.MethodDelete:
+		delete(s.blobs, name)
+		delete(s.uncommitted, name)
+		w.WriteHeader(http.StatusAccepted)
+	case r.Method == http.MethodPut && query.Get("comp") == "block":
+		s.stageBlock(w, r, name, query.Get("blockid"))
+	case r.Method == http.MethodPut && query.Get("comp") == "blocklist":
+		s.commitBlockList(w, r, name, blob)
+	default:
+		s.writeError(w, http.StatusBadRequest, "UnsupportedHttpVerb")
+	}
+}
+
+func (s *azTestServer) writeError(w http.ResponseWriter, status int, code string) {
+	w.Header().Set("x-ms-error-code", code)
+	writeTestXML(w, status, &azTestError{Code: code, Message: code})
+}
+
+func (s *azTestServer) writeBlobHeaders(w http.ResponseWriter, blob *azTestBlob, size int) {
+	w.Header().Set("Content-Length", strconv.Itoa(size))
+	w.Header().Set("Content-Type", blob.contentType)
+	w.Header().Set("ETag", blob.eTag)
+	w.Header().Set("Last-Modified", time.Now().UTC().Format(http.TimeFormat))
+	w.Header().Set("x-ms-blob-type", "BlockBlob")
+}
+
+func (s *azTestServer) stageBlock(w http.ResponseWriter, r *http.Request, name, blockID string) {
+	if _, err := base64.StdEncoding.DecodeString(blockID); err != nil || blockID == "" {
+		s.writeError(w, http.StatusBadRequest, "InvalidQueryParameterValue")
+		return
+	}
+	var data []byte
+	if copySource := r.Header.Get("x-ms-copy-source"); copySource != "" {
+		u, err := url.Parse(copySource)
+		if err != nil {
+			s.writeError(w, http.StatusBadRequest, "InvalidHeaderValue")
+			return
+		}
+		source, ok := s.blobs[strings.TrimPrefix(u.Path, fmt.Sprintf("/%v/%v/", testAzAccount, testBucket))]
+		if !ok {
+			s.writeError(w, http.StatusNotFound, "CannotVerifyCopySource")
+			return
+		}
+		if ifMatch := r.Header.Get("x-ms-source-if-match"); ifMatch != "" && ifMatch != source.eTag {
+			s.writeError(w, http.StatusPreconditionFailed, "SourceConditionNotMet")
+			return
+		}
+		sourceRange := r.Header.Get("x-ms-source-range")
+		var first, last int64
+		if _, err := fmt.Sscanf(sourceRange, "bytes=%d-%d", &first, &last); err != nil || first > last ||
+			last >= int64(len(source.data)) || last-first+1 > azureMaxStageBlockFromURLSize {
+			s.writeError(w, http.StatusBadRequest, "InvalidSourceRange")
+			return
+		}
+		s.stagedRanges = append(s.stagedRanges, sourceRange)
+		data = source.data[first : last+1]
+	} else {
+		var err error
+		data, err = io.ReadAll(r.Body)
+		if err != nil {
+			s.writeError(w, http.StatusBadRequest, "InvalidInput")
+			return
+		}
+	}
+	if _, ok := s.uncommitted[name]; !ok {
+		s.uncommitted[name] = make(map[string][]byte)
+	}
+	s.uncommitted[name][blockID] = data
+	w.WriteHeader(http.StatusCreated)
+}
+
+func (s *azTestServer) commitBlockList(w http.ResponseWriter, r *http.Request, name string, blob *azTestBlob) {
+	var req azblob.BlockLookupList
+	if err := xml.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.writeError(w, http.StatusBadRequest, "InvalidXmlDocument")
+		return
+	}
+	committed := make(map[string][]byte)
+	if blob != nil {
+		for _, block := range blob.blocks {
+			committed[block.id] = block.data
+		}
+	}
+	newBlob := &azTestBlob{contentType: r.Header.Get("x-ms-blob-content-type")}
+	for _, blockID := range req.Latest {
+		data, ok := s.uncommitted[name][blockID]
+		if !ok {
+			data, ok = committed[blockID]
+		}
+		if !ok || (len(newBlob.blocks) > 0 && len(blockID) != len(newBlob.blocks[0].id)) {
+			s.writeError(w, http.StatusBadRequest, "InvalidBlockList")
+			return
+		}
+		newBlob.blocks = append(newBlob.blocks, azTestBlock{id: blockID, data: data})
+		newBlob.data = append(newBlob.data, data...)
+	}
+	s.etagCounter++
+	newBlob.eTag = fmt.Sprintf("%#v", fmt.Sprintf("etag%v", s.etagCounter))
+	s.blobs[name] = newBlob
+	// the uncommitted blocks are discarded after a commit
+	delete(s.uncommitted, name)
+	w.Header().Set("ETag", newBlob.eTag)
+	w.Header().Set("Last-Modified", time.Now().UTC().Format(http.TimeFormat))
+	w.WriteHeader(http.StatusCreated)
+}
+
+// setBlob adds a blob with the specified data, if blockSize is greater than 0 the
+// data are committed as blocks
+func (s *azTestServer) setBlob(name string, data []byte, blockSize int) {
+	s.Lock()
+	defer s.Unlock()
+
+	blob := &azTestBlob{data: data}
+	if blockSize > 0 {
+		binaryBlockID := make([]byte, 8)
+		for offset := 0; offset < len(data); offset += blockSize {
+			end := offset + blockSize
+			if end > len(data) {
+				end = len(data)
+			}
+			binaryBlockID[0]++
+			blob.blocks = append(blob.blocks, azTestBlock{
+				id:   base64.StdEncoding.EncodeToString(binaryBlockID),
+				data: data[offset:end],
+			})
+		}
+	}
+	s.etagCounter++
+	blob.eTag = fmt.Sprintf("%#v", fmt.Sprintf("etag%v", s.etagCounter))
+	s.blobs[name] = blob
+}
+
+func (s *azTestServer) getBlob(name string) ([]byte, int) {
+	s.Lock()
+	defer s.Unlock()
+
+	if blob, ok := s.blobs[name]; ok {
+		return blob.data, len(blob.blocks)
+	}
+	return nil, 0
+}
+
+func (s *azTestServer) getNumUncommitted(name string) int {
+	s.Lock()
+	defer s.Unlock()
+
+	return len(s.uncommitted[name])
+}
+
+func (s *azTestServer) getStagedRanges() []string {
+	s.Lock()
+	defer s.Unlock()
+
+	ranges := s.stagedRanges
+	s.stagedRanges = nil
+	return ranges
+}
+
+func newAzTestFs(t *testing.T, s *azTestServer) *AzureBlobFs {
+	fs, err := NewAzBlobFs("", os.TempDir(), "", AzBlobFsConfig{
+		AzBlobFsConfig: sdk.AzBlobFsConfig{
+			Container:         testBucket,
+			AccountName:       testAzAccount,
+			AccountKey:        kms.NewPlainSecret(base64.StdEncoding.EncodeToString([]byte("account_key"))),
+			Endpoint:          s.server.URL,
+			UseEmulator:       true,
+			UploadPartSize:    1,
+			UploadConcurrency: 2,
+		},
+	})
+	require.NoError(t, err)
+	return fs.(*AzureBlobFs)
+}
+
+func TestAzureResumeUpload(t *testing.T) {
+	s := newAzTestServer(t)
+	fs := newAzTestFs(t, s)
+	partSize := int(fs.config.UploadPartSize)
+
+	// the committed blocks are kept and only the new data are staged
+	initialData := getRandomTestData(t, 2*partSize+100)
+	newData := getRandomTestData(t, partSize+10)
+	s.setBlob(testFileName, initialData, partSize)
+	err := uploadTestData(fs, testFileName, os.O_WRONLY, newData, int64(len(initialData)))
+	assert.NoError(t, err)
+	data, numBlocks := s.getBlob(testFileName)
+	assert.Equal(t, append(initialData, newData...), data)
+	assert.Equal(t, 5, numBlocks)
+	// writes before the resume offset are not allowed
+	err = uploadTestData(fs, testFileName, os.O_WRONLY, newData, 10)
+	assert.Error(t, err)
+	// blobs without committed blocks are uploaded again
+	s.setBlob(testFileName, initialData, 0)
+	err = uploadTestData(fs, testFileName, os.O_WRONLY|os.O_APPEND, newData, int64(len(initialData)))
+	assert.NoError(t, err)
+	data, numBlocks = s.getBlob(testFileName)
+	assert.Equal(t, append(initialData, newData...), data)
+	assert.Equal(t, 4, numBlocks)
+	// truncate
+	err = uploadTestData(fs, testFileName, os.O_WRONLY|os.O_TRUNC, newData, 0)
+	assert.NoError(t, err)
+	data, numBlocks = s.getBlob(testFileName)
+	assert.Equal(t, newData, data)
+	assert.Equal(t, 2, numBlocks)
+}
+
+func TestAzureInterruptedUpload(t *testing.T) {
+	store := newTestMultipartUploadStore()
+	SetMultipartUploadStore(store)
+	defer SetMultipartUploadStore(nil)
+
+	s := newAzTestServer(t)
+	fs := newAzTestFs(t, s)
+	partSize := int(fs.config.UploadPartSize)
+	data := getRandomTestData(t, 5*partSize+1024)
+	headers := azblob.BlobHTTPHeaders{}
+	blobBlockURL := fs.containerURL.NewBlockBlobURL(testFileName)
+	// the upload is interrupted after two blocks, the third block is not completed
+	reader := &testErrReader{
+		r: bytes.NewReader(data[:2*partSize+1024]),
+	}
+	err := fs.handleResumableUpload(context.Background(), reader, &blobBlockURL, &headers, testFileName, nil,
+		&azBlobMultipartUploadState{})
+	assert.ErrorIs(t, err, errTestRead)
+	assert.Equal(t, 1, store.count())
+	assert.Equal(t, 2, s.getNumUncommitted(testFileName))
+	// the interrupted upload is not visible as a file
+	_, err = fs.Stat(testFileName)
+	assert.True(t, fs.IsNotExist(err))
+	assert.Equal(t, int64(2*partSize), getTestPendingUploadSize(t, fs, fs.getMultipartUploadKey(testFileName)))
+	// interrupt the upload again after a new block
+	var state azBlobMultipartUploadState
+	upload, err := getMultipartUpload(fs, fs.getMultipartUploadKey(testFileName), &state)
+	require.NoError(t, err)
+	require.NotNil(t, upload)
+	assert.Len(t, state.Blocks, 2)
+	reader = &testErrReader{
+		r: bytes.NewReader(data[upload.Size : upload.Size+int64(partSize)+10]),
+	}
+	err = fs.handleResumableUpload(context.Background(), reader, &blobBlockURL, &headers, testFileName, upload,
+		&state)
+	assert.ErrorIs(t, err, errTestRead)
+	_, err = fs.Stat(testFileName)
+	assert.True(t, fs.IsNotExist(err))
+	pendingSize := getTestPendingUploadSize(t, fs, fs.getMultipartUploadKey(testFileName))
+	assert.Equal(t, int64(3*partSize), pendingSize)
+	// a resume at a different offset is not allowed
+	err = uploadTestData(fs, testFileName, os.O_WRONLY, data[:10], 0)
+	assert.Error(t, err)
+	// now complete the upload
+	err = uploadTestData(fs, testFileName, os.O_WRONLY, data[pendingSize:], pendingSize)
+	assert.NoError(t, err)
+	blobData, numBlocks := s.getBlob(testFileName)
+	assert.Equal(t, data, blobData)
+	assert.Equal(t, 6, numBlocks)
+	assert.Equal(t, 0, store.count())
+	info, err := fs.Stat(testFileName)
+	require.NoError(t, err)
+	assert.Equal(t, int64(len(data)), info.Size())
+	// truncate and remove discard the interrupted upload
+	name := "other.dat"
+	blobBlockURL = fs.containerURL.NewBlockBlobURL(name)
+	for _, discardFn := range []func() error{
+		func() error {
+			return uploadTestData(fs, name, os.O_WRONLY|os.O_TRUNC, data[:10], 0)
+		},
+		func() error {
+			return fs.Remove(name, false)
+		},
+	} {
+		reader = &testErrReader{
+			r: bytes.NewReader(data[:partSize+10]),
+		}
+		err = fs.handleResumableUpload(context.Background(), reader, &blobBlockURL, &headers, name, nil,
+			&azBlobMultipartUploadState{})
+		assert.Error(t, err)
+		assert.Equal(t, 1, store.count())
+		err = discardFn()
+		assert.NoError(t, err)
+		assert.Equal(t, 0, store.count())
+	}
+	// nothing is saved if no block was staged
+	reader = &testErrReader{
+		r: bytes.NewReader(data[:10]),
+	}
+	err = fs.handleResumableUpload(context.Background(), reader, &blobBlockURL, &headers, name, nil,
+		&azBlobMultipartUploadState{})
+	assert.Error(t, err)
+	assert.Equal(t, 0, store.count())
+}
+
+func TestAzureCombineFiles(t *testing.T) {
+	s := newAzTestServer(t)
+	fs := newAzTestFs(t, s)
+
+	var sources []string
+	var expected []byte
+	for idx, size := range []int{1024, 0, 2048, 100} {
+		name := fmt.Sprintf("source%v", idx)
+		data := getRandomTestData(t, size)
+		s.setBlob(name, data, 0)
+		sources = append(sources, name)
+		expected = append(expected, data...)
+	}
+	err := fs.CombineFiles("target", sources)
+	assert.NoError(t, err)
+	data, numBlocks := s.getBlob("target")
+	assert.Equal(t, expected, data)
+	assert.Equal(t, 3, numBlocks)
+	assert.Equal(t, []string{"bytes=0-1023", "bytes=0-2047", "bytes=0-99"}, s.getStagedRanges())
+	// if the first source is the target its committed blocks are kept
+	err = fs.CombineFiles("target", []string{"target", "source0"})
+	assert.NoError(t, err)
+	data, numBlocks = s.getBlob("target")
+	assert.Equal(t, append(expected, expected[:1024]...), data)
+	assert.Equal(t, 4, numBlocks)
+	assert.Equal(t, []string{"bytes=0-1023"}, s.getStagedRanges())
+	// the target is a source but not the first one
+	err = fs.CombineFiles("target", []string{"source2", "target"})
+	assert.NoError(t, err)
+	data, numBlocks = s.getBlob("target")
+	assert.Equal(t, append(expected[1024:3072], append(expected, expected[:1024]...)...), data)
+	assert.Equal(t, 2, numBlocks)
+	err = fs.CombineFiles("target", []string{"missing"})
+	assert.Error(t, err)
+	err = fs.CombineFiles("target", nil)
+	assert.Error(t, err)
+}
@@ -0,0 +1,114 @@
+package vfs
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/drakkan/sftpgo/v2/logger"
+	"github.com/drakkan/sftpgo/v2/util"
+)
+
+// MultipartUploadMaxAge defines how long an interrupted upload can be resumed.
+// Azure Blob Storage discards the uncommitted blocks and Google Cloud Storage
+// expires the resumable upload sessions after a week
+const MultipartUploadMaxAge = 6 * 24 * time.Hour
+
+var multipartUploadStore MultipartUploadStore
+
+// MultipartUpload defines the state of an interrupted upload to a Cloud Storage
+// backend. The uploaded data are kept by the storage backend and the state
+// allows to resume the upload from the last persisted byte, after a restart
+// or using a different SFTPGo instance too
+type MultipartUpload struct {
+	// Key identifies the target object, it is an hash of the storage
+	// backend, the bucket and the object name
+	Key string `json:"key"`
+	// Bytes persisted so far, the upload is resumed from this offset
+	Size int64 `json:"size"`
+	// Backend specific state, JSON encoded
+	State string `json:"state"`
+	// Creation time as unix timestamp in milliseconds
+	CreatedAt int64 `json:"created_at"`
+	// Last update as unix timestamp in milliseconds
+	UpdatedAt int64 `json:"updated_at"`
+}
+
+// IsExpired returns true if the upload cannot be resumed anymore at the given time
+func (u *MultipartUpload) IsExpired(now time.Time) bool {
+	return u.UpdatedAt <= util.GetTimeAsMsSinceEpoch(now.Add(-MultipartUploadMaxAge))
+}
+
+// MultipartUploadStore defines the interface to persist the interrupted uploads
+type MultipartUploadStore interface {
+	GetMultipartUpload(key string) (MultipartUpload, error)
+	SaveMultipartUpload(upload *MultipartUpload) error
+	DeleteMultipartUpload(key string) error
+}
+
+// SetMultipartUploadStore sets the store for the interrupted uploads.
+// If no store is set the interrupted uploads cannot be resumed
+func SetMultipartUploadStore(store MultipartUploadStore) {
+	multipartUploadStore = store
+}
+
+func getMultipartUploadKey(elems ...string) string {
+	h := sha256.Sum256([]byte(strings.Join(elems, "\x00")))
+	return hex.EncodeToString(h[:])
+}
+
+// getMultipartUpload returns the interrupted upload with the specified key, if
+// any, and decodes its backend specific state. Expired uploads are returned too
+func getMultipartUpload(fs Fs, key string, state interface{}) (*MultipartUpload, error) {
+	if multipartUploadStore == nil {
+		return nil, nil
+	}
+	upload, err := multipartUploadStore.GetMultipartUpload(key)
+	if err != nil {
+		if _, ok := err.(*util.RecordNotFoundError); ok {
+			return nil, nil
+		}
+		fsLog(fs, logger.LevelWarn, "unable to get multipart upload for key %#v: %v", key, err)
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(upload.State), state); err != nil {
+		fsLog(fs, logger.LevelWarn, "invalid state for multipart upload with key %#v: %v", key, err)
+		return nil, err
+	}
+	return &upload, nil
+}
+
+// saveMultipartUpload persists the state for an interrupted upload
+func saveMultipartUpload(fs Fs, key string, size int64, state interface{}) error {
+	if multipartUploadStore == nil {
+		return ErrVfsUnsupported
+	}
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	now := util.GetTimeAsMsSinceEpoch(time.Now())
+	err = multipartUploadStore.SaveMultipartUpload(&MultipartUpload{
+		Key:       key,
+		Size:      size,
+		State:     string(data),
+		CreatedAt: now,
+		UpdatedAt: now,
+	})
+	fsLog(fs, logger.LevelDebug, "multipart upload with key %#v saved, size: %v, err: %v", key, size, err)
+	return err
+}
+
+// deleteMultipartUpload removes the state for an interrupted upload
+func deleteMultipartUpload(fs Fs, key string) {
+	if multipartUploadStore == nil {
+		return
+	}
+	if err := multipartUploadStore.DeleteMultipartUpload(key); err != nil {
+		if _, ok := err.(*util.RecordNotFoundError); !ok {
+			fsLog(fs, logger.LevelWarn, "unable to delete multipart upload with key %#v: %v", key, err)
+		}
+	}
+}
@@ -0,0 +1,467 @@
+package vfs
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/eikenb/pipeat"
+
+	"github.com/drakkan/sftpgo/v2/logger"
+	"github.com/drakkan/sftpgo/v2/metric"
+	"github.com/drakkan/sftpgo/v2/sdk"
+	"github.com/drakkan/sftpgo/v2/util"
+)
+
+const (
+	readCacheLogSender   = "readCache"
+	readCacheDirName     = "sftpgo_read_cache"
+	readCacheEvictionLRU = "lru"
+	readCacheEvictionLFU = "lfu"
+)
+
+var (
+	validReadCacheEvictions = []string{readCacheEvictionLRU, readCacheEvictionLFU}
+	readCaches              = readCacheManager{
+		caches: make(map[string]*readCache),
+	}
+)
+
+// ReadCacheConfig defines the configuration for the local read cache
+type ReadCacheConfig struct {
+	sdk.ReadCacheConfig
+}
+
+// IsEnabled returns true if the read cache is enabled
+func (c *ReadCacheConfig) IsEnabled() bool {
+	return c.MaxSize > 0
+}
+
+func (c *ReadCacheConfig) isEqual(other *ReadCacheConfig) bool {
+	if c.MaxSize != other.MaxSize {
+		return false
+	}
+	if c.TTL != other.TTL {
+		return false
+	}
+	return c.Eviction == other.Eviction
+}
+
+// Validate returns an error if the configuration is not valid
+func (c *ReadCacheConfig) Validate() error {
+	if c.MaxSize < 0 {
+		return fmt.Errorf("invalid max size: %v", c.MaxSize)
+	}
+	if c.TTL < 0 {
+		return fmt.Errorf("invalid ttl: %v", c.TTL)
+	}
+	if !c.IsEnabled() {
+		c.TTL = 0
+		c.Eviction = ""
+		return nil
+	}
+	c.Eviction = strings.ToLower(strings.TrimSpace(c.Eviction))
+	if c.Eviction == "" {
+		c.Eviction = readCacheEvictionLRU
+	}
+	if !util.IsStringInSlice(c.Eviction, validReadCacheEvictions) {
+		return fmt.Errorf("invalid eviction policy %#v, valid values: %v", c.Eviction, validReadCacheEvictions)
+	}
+	return nil
+}
+
+// ReadCacheFs wraps a cloud based Fs and stores the downloaded files inside a
+// local disk cache. Subsequent downloads are served from the cache if the
+// cached contents still match the size, modification time and ETag reported
+// by the remote storage. Cached contents are invalidated if they are modified
+// through SFTPGo
+type ReadCacheFs struct {
+	Fs
+	cache        *readCache
+	localTempDir string
+}
+
+// NewReadCacheFs returns fs wrapped inside a ReadCacheFs if the read cache is
+// enabled for the given filesystem configuration, fs is returned unchanged otherwise.
+// The read cache is supported for S3, Google Cloud Storage and Azure Blob filesystems
+func NewReadCacheFs(fs Fs, fsConfig *Filesystem) Fs {
+	if !fsConfig.ReadCache.IsEnabled() {
+		return fs
+	}
+	switch fsConfig.Provider {
+	case sdk.S3FilesystemProvider, sdk.GCSFilesystemProvider, sdk.AzureBlobFilesystemProvider:
+	default:
+		return fs
+	}
+	cache, err := readCaches.get(getReadCacheNamespace(fsConfig), fsConfig.ReadCache)
+	if err != nil {
+		fsLog(fs, logger.LevelWarn, "unable to initialize the read cache, downloads will not be cached: %v", err)
+		return fs
+	}
+	localTempDir := tempPath
+	if localTempDir == "" {
+		localTempDir = filepath.Clean(os.TempDir())
+	}
+	return &ReadCacheFs{
+		Fs:           fs,
+		cache:        cache,
+		localTempDir: localTempDir,
+	}
+}
+
+// Open opens the named file for reading. The contents are served from the
+// local cache if available and still valid
+func (fs *ReadCacheFs) Open(name string, offset int64) (File, *pipeat.PipeReaderAt, func(), error) {
+	info, err := fs.Fs.Stat(name)
+	if err != nil || info.IsDir() || info.Size() > fs.cache.getMaxSize() {
+		metric.ReadCacheMiss()
+		return fs.Fs.Open(name, offset)
+	}
+	if cachedPath, ok := fs.cache.get(name, info); ok {
+		r, cancelFn, err := fs.openCached(name, cachedPath, offset)
+		if err == nil {
+			return nil, r, cancelFn, nil
+		}
+		fsLog(fs, logger.LevelWarn, "unable to open cached file for path %#v: %v", name, err)
+		fs.cache.invalidate(name)
+	}
+	metric.ReadCacheMiss()
+	if offset > 0 {
+		return fs.Fs.Open(name, offset)
+	}
+	return fs.openAndCache(name, info)
+}
+
+// Create invalidates the cached contents, if any, and creates or opens
+// the named file for writing
+func (fs *ReadCacheFs) Create(name string, flag int) (File, *PipeWriter, func(), error) {
+	fs.cache.invalidate(name)
+	return fs.Fs.Create(name, flag)
+}
+
+// Rename invalidates the cached contents for source and target, if any,
+// and renames (moves) source to target
+func (fs *ReadCacheFs) Rename(source, target string) error {
+	fs.cache.invalidate(source)
+	fs.cache.invalidate(target)
+	return fs.Fs.Rename(source, target)
+}
+
+// Remove invalidates the cached contents, if any, and removes the named file or (empty) directory
+func (fs *ReadCacheFs) Remove(name string, isDir bool) error {
+	fs.cache.invalidate(name)
+	return fs.Fs.Remove(name, isDir)
+}
+
+// Truncate invalidates the cached contents, if any, and changes the size of the named file
+func (fs *ReadCacheFs) Truncate(name string, size int64) error {
+	fs.cache.invalidate(name)
+	return fs.Fs.Truncate(name, size)
+}
+
+// CopyFile implements the FsFileCopier interface
+func (fs *ReadCacheFs) CopyFile(source, target string) error {
+	copier, ok := fs.Fs.(FsFileCopier)
+	if !ok {
+		return ErrVfsUnsupported
+	}
+	fs.cache.invalidate(target)
+	return copier.CopyFile(source, target)
+}
+
+// CombineFiles implements the FsFileCombiner interface
+func (fs *ReadCacheFs) CombineFiles(target string, sources []string) error {
+	combiner, ok := fs.Fs.(FsFileCombiner)
+	if !ok {
+		return ErrVfsUnsupported
+	}
+	fs.cache.invalidate(target)
+	return combiner.CombineFiles(target, sources)
+}
+
+func (fs *ReadCacheFs) openCached(name, cachedPath string, offset int64) (*pipeat.PipeReaderAt, func(), error) {
+	file, err := os.Open(cachedPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	if _, err := file.Seek(offset, io.SeekStart); err != nil {
+		file.Close()
+		return nil, nil, err
+	}
+	r, w, err := pipeat.PipeInDir(fs.localTempDir)
+	if err != nil {
+		file.Close()
+		return nil, nil, err
+	}
+	go func() {
+		n, err := io.Copy(w, file)
+		file.Close()
+		w.CloseWithError(err) //nolint:errcheck
+		fsLog(fs, logger.LevelDebug, "download served from the read cache, path: %#v size: %v, err: %v", name, n, err)
+		metric.ReadCacheHit(n)
+	}()
+	// closing the file will abort the copy
+	cancelFn := func() {
+		file.Close()
+	}
+	return r, cancelFn, nil
+}
+
+func (fs *ReadCacheFs) openAndCache(name string, info os.FileInfo) (File, *pipeat.PipeReaderAt, func(), error) {
+	file, reader, cancelFn, err := fs.Fs.Open(name, 0)
+	if err != nil || reader == nil {
+		return file, reader, cancelFn, err
+	}
+	cacheFile, err := fs.cache.createTempFile()
+	if err != nil {
+		fsLog(fs, logger.LevelWarn, "unable to create the cache file for path %#v: %v", name, err)
+		return file, reader, cancelFn, nil
+	}
+	r, w, err := pipeat.PipeInDir(fs.localTempDir)
+	if err != nil {
+		cacheFile.Close()
+		os.Remove(cacheFile.Name())
+		return file, reader, cancelFn, nil
+	}
+	go func() {
+		cacheWriter := &readCacheWriter{file: cacheFile}
+		n, err := io.Copy(w, io.TeeReader(reader, cacheWriter))
+		reader.Close()
+		errClose := cacheFile.Close()
+		if err == nil && cacheWriter.err == nil && errClose == nil && n == info.Size() {
+			fs.cache.add(name, cacheFile.Name(), info)
+		} else {
+			fsLog(fs, logger.LevelDebug, "download for path %#v not cached, size: %v, expected size: %v, err: %v, "+
+				"write err: %v, close err: %v", name, n, info.Size(), err, cacheWriter.err, errClose)
+			os.Remove(cacheFile.Name())
+		}
+		w.CloseWithError(err) //nolint:errcheck
+	}()
+	return file, r, cancelFn, nil
+}
+
+// readCacheWriter writes the downloaded contents to the cache file.
+// Write errors are recorded and not returned, this way a cache
+// error cannot interrupt the download
+type readCacheWriter struct {
+	file *os.File
+	err  error
+}
+
+func (w *readCacheWriter) Write(p []byte) (int, error) {
+	if w.err == nil {
+		_, w.err = w.file.Write(p)
+	}
+	return len(p), nil
+}
+
+type readCacheEntry struct {
+	path     string
+	size     int64
+	modTime  time.Time
+	etag     string
+	cachedAt time.Time
+	lastUsed time.Time
+	hits     int64
+}
+
+// readCache is a disk cache shared among all the filesystems pointing to the same remote storage
+type readCache struct {
+	sync.Mutex
+	dir      string
+	maxSize  int64
+	ttl      time.Duration
+	eviction string
+	size     int64
+	entries  map[string]*readCacheEntry
+}
+
+func (c *readCache) setConfig(config ReadCacheConfig) {
+	c.Lock()
+	defer c.Unlock()
+
+	c.maxSize = config.MaxSize * 1048576
+	c.ttl = time.Duration(config.TTL) * time.Second
+	c.eviction = config.Eviction
+	c.evict(0)
+}
+
+func (c *readCache) getMaxSize() int64 {
+	c.Lock()
+	defer c.Unlock()
+
+	return c.maxSize
+}
+
+func (c *readCache) createTempFile() (*os.File, error) {
+	return os.CreateTemp(c.dir, "download-*")
+}
+
+// get returns the path of the cached file for name, if it is still valid for the given file info
+func (c *readCache) get(name string, info os.FileInfo) (string, bool) {
+	c.Lock()
+	defer c.Unlock()
+
+	entry, ok := c.entries[name]
+	if !ok {
+		return "", false
+	}
+	now := time.Now()
+	if c.isExpired(entry, now) || entry.size != info.Size() || !entry.modTime.Equal(info.ModTime()) ||
+		entry.etag != getFileInfoETag(info) {
+		c.removeEntry(name, entry)
+		return "", false
+	}
+	entry.lastUsed = now
+	entry.hits++
+	return entry.path, true
+}
+
+// add adds the specified file to the cache, the file is removed if it cannot be cached
+func (c *readCache) add(name, filePath string, info os.FileInfo) {
+	c.Lock()
+	defer c.Unlock()
+
+	if entry, ok := c.entries[name]; ok {
+		c.removeEntry(name, entry)
+	}
+	if info.Size() > c.maxSize {
+		os.Remove(filePath)
+		return
+	}
+	c.evict(info.Size())
+	now := time.Now()
+	c.entries[name] = &readCacheEntry{
+		path:     filePath,
+		size:     info.Size(),
+		modTime:  info.ModTime(),
+		etag:     getFileInfoETag(info),
+		cachedAt: now,
+		lastUsed: now,
+	}
+	c.size += info.Size()
+}
+
+// invalidate removes the cached contents for name and, if name is a directory, for its contents
+func (c *readCache) invalidate(name string) {
+	c.Lock()
+	defer c.Unlock()
+
+	dirPrefix := strings.TrimSuffix(name, "/") + "/"
+	for key, entry := range c.entries {
+		if key == name || strings.HasPrefix(key, dirPrefix) {
+			c.removeEntry(key, entry)
+		}
+	}
+}
+
+// evict removes cached entries until there is room for the specified size.
+// Expired entries are removed first. The caller must hold the lock
+func (c *readCache) evict(size int64) {
+	now := time.Now()
+	for key, entry := range c.entries {
+		if c.isExpired(entry, now) {
+			c.removeEntry(key, entry)
+		}
+	}
+	for c.size+size > c.maxSize && len(c.entries) > 0 {
+		var victimKey string
+		var victim *readCacheEntry
+		for key, entry := range c.entries {
+			if victim == nil || c.isBetterVictim(entry, victim) {
+				victimKey = key
+				victim = entry
+			}
+		}
+		logger.Debug(readCacheLogSender, "", "evicting cached path %#v, size: %v", victimKey, victim.size)
+		c.removeEntry(victimKey, victim)
+	}
+}
+
+func (c *readCache) isBetterVictim(entry, victim *readCacheEntry) bool {
+	if c.eviction == readCacheEvictionLFU && entry.hits != victim.hits {
+		return entry.hits < victim.hits
+	}
+	return entry.lastUsed.Before(victim.lastUsed)
+}
+
+func (c *readCache) isExpired(entry *readCacheEntry, now time.Time) bool {
+	return c.ttl > 0 && now.Sub(entry.cachedAt) > c.ttl
+}
+
+// removeEntry removes the cached entry. The caller must hold the lock
+func (c *readCache) removeEntry(key string, entry *readCacheEntry) {
+	if err := os.Remove(entry.path); err != nil && !os.IsNotExist(err) {
+		logger.Warn(readCacheLogSender, "", "unable to remove cached file %#v: %v", entry.path, err)
+	}
+	c.size -= entry.size
+	delete(c.entries, key)
+}
+
+type readCacheManager struct {
+	sync.Mutex
+	caches map[string]*readCache
+}
+
+// get returns the cache for the specified namespace, creating it if needed.
+// The cache is updated to use the given configuration
+func (m *readCacheManager) get(namespace string, config ReadCacheConfig) (*readCache, error) {
+	m.Lock()
+	defer m.Unlock()
+
+	cache, ok := m.caches[namespace]
+	if !ok {
+		baseDir := tempPath
+		if baseDir == "" {
+			baseDir = os.TempDir()
+		}
+		dir := filepath.Join(baseDir, readCacheDirName, namespace)
+		// the cache index is in memory only, contents cached by previous runs cannot be used
+		if err := os.RemoveAll(dir); err != nil {
+			return nil, err
+		}
+		if err := os.MkdirAll(dir, 0700); err != nil {
+			return nil, err
+		}
+		cache = &readCache{
+			dir:     dir,
+			entries: make(map[string]*readCacheEntry),
+		}
+		m.caches[namespace] = cache
+	}
+	cache.setConfig(config)
+	return cache, nil
+}
+
+// getReadCacheNamespace returns an identifier for the remote storage, filesystems
+// pointing to the same remote storage share the same cache
+func getReadCacheNamespace(fsConfig *Filesystem) string {
+	parts := []string{fsConfig.Provider.Name()}
+	switch fsConfig.Provider {
+	case sdk.S3FilesystemProvider:
+		parts = append(parts, fsConfig.S3Config.Endpoint, fsConfig.S3Config.Region, fsConfig.S3Config.Bucket)
+	case sdk.GCSFilesystemProvider:
+		parts = append(parts, fsConfig.GCSConfig.Bucket)
+	case sdk.AzureBlobFilesystemProvider:
+		parts = append(parts, fsConfig.AzBlobConfig.Endpoint, fsConfig.AzBlobConfig.AccountName,
+			fsConfig.AzBlobConfig.Container)
+		if fsConfig.AzBlobConfig.SASURL != nil {
+			parts = append(parts, fsConfig.AzBlobConfig.SASURL.GetPayload())
+		}
+	}
+	h := sha256.Sum256([]byte(strings.Join(parts, "\x00")))
+	return hex.EncodeToString(h[:])
+}
+
+func getFileInfoETag(info os.FileInfo) string {
+	if fi, ok := info.(*FileInfo); ok {
+		return fi.etag
+	}
+	return ""
+}
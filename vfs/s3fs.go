@@ -4,8 +4,11 @@
 package vfs
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"mime"
 	"net/url"
 	"os"
@@ -30,8 +33,37 @@ import (
 	"github.com/drakkan/sftpgo/v2/version"
 )
 
-// using this mime type for directories improves compatibility with s3fs-fuse
-const s3DirMimeType = "application/x-directory"
+// s3ObjectSegment defines the range [start, end) of an object
+type s3ObjectSegment struct {
+	name  string
+	eTag  *string
+	start int64
+	end   int64
+}
+
+func (s *s3ObjectSegment) size() int64 {
+	return s.end - s.start
+}
+
+// s3MultipartUploadState defines the state of an interrupted multipart upload
+type s3MultipartUploadState struct {
+	UploadID string           `json:"upload_id"`
+	Parts    []s3UploadedPart `json:"parts"`
+}
+
+// s3UploadedPart defines a part uploaded within a multipart upload
+type s3UploadedPart struct {
+	Number int64  `json:"number"`
+	ETag   string `json:"etag"`
+	Size   int64  `json:"size"`
+}
+
+const (
+	// using this mime type for directories improves compatibility with s3fs-fuse
+	s3DirMimeType = "application/x-directory"
+	// max size for a part copied using UploadPartCopy
+	s3MaxCopyPartSize = 5 * 1024 * 1024 * 1024
+)
 
 // S3Fs is a Fs implementation for AWS S3 compatible object storages
 type S3Fs struct {
@@ -146,7 +178,9 @@ func (fs *S3Fs) Stat(name string) (os.FileInfo, error) {
 		// a "dir" has a trailing "/" so we cannot have a directory here
 		objSize := *obj.ContentLength
 		objectModTime := *obj.LastModified
-		return NewFileInfo(name, false, objSize, objectModTime, false), nil
+		info := NewFileInfo(name, false, objSize, objectModTime, false)
+		info.etag = aws.StringValue(obj.ETag)
+		return info, nil
 	}
 	if !fs.IsNotExist(err) {
 		return result, err
@@ -224,41 +258,502 @@ func (fs *S3Fs) Open(name string, offset int64) (File, *pipeat.PipeReaderAt, fun
 
 // Create creates or opens the named file for writing
 func (fs *S3Fs) Create(name string, flag int) (File, *PipeWriter, func(), error) {
+	var resumeObj *s3.HeadObjectOutput
+	if isUploadResumeRequested(flag) {
+		obj, err := fs.headObject(name)
+		if err == nil {
+			if aws.Int64Value(obj.ContentLength) > 0 {
+				resumeObj = obj
+			}
+		} else if !fs.IsNotExist(err) {
+			return nil, nil, nil, err
+		}
+	}
+	var pendingUpload *MultipartUpload
+	var pendingState s3MultipartUploadState
+	if flag != -1 {
+		pendingUpload, _ = getMultipartUpload(fs, fs.getMultipartUploadKey(name), &pendingState)
+		if pendingUpload != nil && (resumeObj != nil || !isUploadResumeRequested(flag) || pendingUpload.IsExpired(time.Now())) {
+			fs.discardMultipartUpload(name, &pendingState)
+			pendingUpload = nil
+		}
+	}
 	r, w, err := pipeat.PipeInDir(fs.localTempDir)
 	if err != nil {
 		return nil, nil, nil, err
 	}
-	p := NewPipeWriter(w)
+	var p *PipeWriter
+	if resumeObj != nil {
+		p = NewPipeWriterAtOffset(w, aws.Int64Value(resumeObj.ContentLength))
+	} else if pendingUpload != nil {
+		p = NewPipeWriterAtOffset(w, pendingUpload.Size)
+	} else {
+		p = NewPipeWriter(w)
+	}
 	ctx, cancelFn := context.WithCancel(context.Background())
-	uploader := s3manager.NewUploaderWithClient(fs.svc)
 	go func() {
 		defer cancelFn()
-		key := name
 		var contentType string
 		if flag == -1 {
 			contentType = s3DirMimeType
 		} else {
 			contentType = mime.TypeByExtension(path.Ext(name))
 		}
-		response, err := uploader.UploadWithContext(ctx, &s3manager.UploadInput{
-			Bucket:       aws.String(fs.config.Bucket),
-			Key:          aws.String(key),
-			Body:         r,
-			StorageClass: util.NilIfEmpty(fs.config.StorageClass),
-			ContentType:  util.NilIfEmpty(contentType),
-		}, func(u *s3manager.Uploader) {
-			u.Concurrency = fs.config.UploadConcurrency
-			u.PartSize = fs.config.UploadPartSize
-		})
+		var err error
+		if resumeObj != nil {
+			err = fs.resumeUpload(ctx, r, name, contentType, resumeObj)
+		} else if pendingUpload != nil {
+			err = fs.continueMultipartUpload(ctx, r, name, &pendingState)
+		} else {
+			err = fs.uploadObject(ctx, r, name, contentType)
+		}
 		r.CloseWithError(err) //nolint:errcheck
 		p.Done(err)
-		fsLog(fs, logger.LevelDebug, "upload completed, path: %#v, response: %v, readed bytes: %v, err: %+v",
-			name, response, r.GetReadedBytes(), err)
+		fsLog(fs, logger.LevelDebug, "upload completed, path: %#v, readed bytes: %v, err: %+v",
+			name, r.GetReadedBytes(), err)
 		metric.S3TransferCompleted(r.GetReadedBytes(), 0, err)
 	}()
 	return nil, p, cancelFn, nil
 }
 
+func (fs *S3Fs) uploadObject(ctx context.Context, r io.Reader, name, contentType string) error {
+	uploader := s3manager.NewUploaderWithClient(fs.svc)
+	_, err := uploader.UploadWithContext(ctx, &s3manager.UploadInput{
+		Bucket:       aws.String(fs.config.Bucket),
+		Key:          aws.String(name),
+		Body:         r,
+		StorageClass: util.NilIfEmpty(fs.config.StorageClass),
+		ContentType:  util.NilIfEmpty(contentType),
+	}, func(u *s3manager.Uploader) {
+		u.Concurrency = fs.config.UploadConcurrency
+		u.PartSize = fs.config.UploadPartSize
+		// the uploaded parts are saved on error, so the upload can be resumed
+		u.LeavePartsOnError = multipartUploadStore != nil
+	})
+	var failure s3manager.MultiUploadFailure
+	if errors.As(err, &failure) && multipartUploadStore != nil {
+		fs.saveInterruptedUpload(name, failure.UploadID(), nil)
+	}
+	return err
+}
+
+// continueMultipartUpload uploads the data read from r as new parts of an
+// interrupted multipart upload and then completes it. If the upload is
+// interrupted again the uploaded parts are saved, so it can be resumed later
+func (fs *S3Fs) continueMultipartUpload(ctx context.Context, r io.Reader, name string,
+	state *s3MultipartUploadState,
+) error {
+	parts := state.Parts
+	buf := make([]byte, fs.config.UploadPartSize)
+	for {
+		n, err := readFill(r, buf)
+		if err != nil && err != io.EOF {
+			fs.saveInterruptedUpload(name, state.UploadID, parts)
+			return err
+		}
+		if n > 0 {
+			partNumber := int64(len(parts) + 1)
+			part, errUpload := fs.uploadPart(ctx, name, aws.String(state.UploadID), partNumber, buf[:n])
+			if errUpload != nil {
+				fs.saveInterruptedUpload(name, state.UploadID, parts)
+				return errUpload
+			}
+			parts = append(parts, s3UploadedPart{
+				Number: partNumber,
+				ETag:   aws.StringValue(part.ETag),
+				Size:   int64(n),
+			})
+		}
+		if err == io.EOF {
+			break
+		}
+	}
+	completedParts := make([]*s3.CompletedPart, 0, len(parts))
+	for _, part := range parts {
+		completedParts = append(completedParts, &s3.CompletedPart{
+			ETag:       aws.String(part.ETag),
+			PartNumber: aws.Int64(part.Number),
+		})
+	}
+	_, err := fs.svc.CompleteMultipartUploadWithContext(ctx, &s3.CompleteMultipartUploadInput{
+		Bucket:   aws.String(fs.config.Bucket),
+		Key:      aws.String(name),
+		UploadId: aws.String(state.UploadID),
+		MultipartUpload: &s3.CompletedMultipartUpload{
+			Parts: completedParts,
+		},
+	})
+	fsLog(fs, logger.LevelDebug, "multipart upload continued, path: %#v, parts: %v, new parts: %v, err: %v",
+		name, len(parts), len(parts)-len(state.Parts), err)
+	if err != nil {
+		fs.saveInterruptedUpload(name, state.UploadID, parts)
+		return err
+	}
+	deleteMultipartUpload(fs, fs.getMultipartUploadKey(name))
+	return nil
+}
+
+// saveInterruptedUpload saves the state for an interrupted multipart upload, so
+// it can be resumed later. If the uploaded parts are not provided they are listed
+// from the storage backend. Only the consecutive parts starting from the first
+// one are kept and, since a part smaller than 5MB can only be the last one, the
+// parts following a smaller part are ignored too.
+// If there is nothing to resume the multipart upload is aborted
+func (fs *S3Fs) saveInterruptedUpload(name, uploadID string, parts []s3UploadedPart) {
+	if parts == nil {
+		var err error
+		parts, err = fs.listUploadedParts(name, uploadID)
+		if err != nil {
+			fsLog(fs, logger.LevelWarn, "unable to list the uploaded parts for %#v: %v", name, err)
+		}
+	}
+	state := s3MultipartUploadState{
+		UploadID: uploadID,
+	}
+	var size int64
+	for idx, part := range parts {
+		if part.Number != int64(idx+1) || part.Size < s3manager.MinUploadPartSize {
+			break
+		}
+		state.Parts = append(state.Parts, part)
+		size += part.Size
+	}
+	if len(state.Parts) > 0 {
+		if err := saveMultipartUpload(fs, fs.getMultipartUploadKey(name), size, &state); err == nil {
+			return
+		}
+	}
+	fs.abortMultipartUpload(name, aws.String(uploadID))
+}
+
+func (fs *S3Fs) listUploadedParts(name, uploadID string) ([]s3UploadedPart, error) {
+	ctx, cancelFn := context.WithDeadline(context.Background(), time.Now().Add(fs.ctxLongTimeout))
+	defer cancelFn()
+
+	var parts []s3UploadedPart
+	err := fs.svc.ListPartsPagesWithContext(ctx, &s3.ListPartsInput{
+		Bucket:   aws.String(fs.config.Bucket),
+		Key:      aws.String(name),
+		UploadId: aws.String(uploadID),
+	}, func(page *s3.ListPartsOutput, lastPage bool) bool {
+		for _, part := range page.Parts {
+			parts = append(parts, s3UploadedPart{
+				Number: aws.Int64Value(part.PartNumber),
+				ETag:   aws.StringValue(part.ETag),
+				Size:   aws.Int64Value(part.Size),
+			})
+		}
+		return true
+	})
+	return parts, err
+}
+
+// discardMultipartUpload aborts an interrupted multipart upload and removes its state
+func (fs *S3Fs) discardMultipartUpload(name string, state *s3MultipartUploadState) {
+	fs.abortMultipartUpload(name, aws.String(state.UploadID))
+	deleteMultipartUpload(fs, fs.getMultipartUploadKey(name))
+}
+
+func (fs *S3Fs) abortMultipartUpload(name string, uploadID *string) {
+	ctx, cancelFn := context.WithDeadline(context.Background(), time.Now().Add(fs.ctxTimeout))
+	defer cancelFn()
+
+	_, err := fs.svc.AbortMultipartUploadWithContext(ctx, &s3.AbortMultipartUploadInput{
+		Bucket:   aws.String(fs.config.Bucket),
+		Key:      aws.String(name),
+		UploadId: uploadID,
+	})
+	if err != nil {
+		fsLog(fs, logger.LevelWarn, "unable to abort multipart upload for %#v: %v", name, err)
+	}
+}
+
+func (fs *S3Fs) getMultipartUploadKey(name string) string {
+	return getMultipartUploadKey("s3", fs.config.Endpoint, fs.config.Bucket, name)
+}
+
+// resumeUpload appends the data read from r to an existing object.
+// A new multipart upload is started, the existing object is copied server side
+// as the first parts and then the new data are uploaded as the following parts.
+// S3 requires at least 5MB for each part except the last one, so smaller objects
+// are uploaded again.
+// If the upload fails the multipart upload is aborted and the existing object
+// is not modified
+func (fs *S3Fs) resumeUpload(ctx context.Context, r io.Reader, name, contentType string,
+	obj *s3.HeadObjectOutput,
+) error {
+	size := aws.Int64Value(obj.ContentLength)
+	if size < s3manager.MinUploadPartSize {
+		fsLog(fs, logger.LevelDebug, "resuming upload for %#v, size %v, the existing data will be uploaded again",
+			name, size)
+		resp, err := fs.svc.GetObjectWithContext(ctx, &s3.GetObjectInput{
+			Bucket:  aws.String(fs.config.Bucket),
+			Key:     aws.String(name),
+			IfMatch: obj.ETag,
+		})
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+
+		return fs.uploadObject(ctx, io.MultiReader(resp.Body, r), name, contentType)
+	}
+	parts, err := fs.multipartUpload(ctx, name, contentType, func(uploadID *string) ([]*s3.CompletedPart, error) {
+		return fs.uploadResumedParts(ctx, r, name, uploadID, obj)
+	})
+	fsLog(fs, logger.LevelDebug, "upload resumed, path: %#v, initial size: %v, parts: %v, err: %v",
+		name, size, parts, err)
+	return err
+}
+
+func (fs *S3Fs) uploadResumedParts(ctx context.Context, r io.Reader, name string, uploadID *string,
+	obj *s3.HeadObjectOutput,
+) ([]*s3.CompletedPart, error) {
+	parts, err := fs.uploadPartsCopy(ctx, name, uploadID, nil, name, obj.ETag, 0, aws.Int64Value(obj.ContentLength))
+	if err != nil {
+		return nil, err
+	}
+	buf := make([]byte, fs.config.UploadPartSize)
+	for {
+		n, err := readFill(r, buf)
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+		if n > 0 {
+			part, errUpload := fs.uploadPart(ctx, name, uploadID, int64(len(parts)+1), buf[:n])
+			if errUpload != nil {
+				return nil, errUpload
+			}
+			parts = append(parts, part)
+		}
+		if err == io.EOF {
+			return parts, nil
+		}
+	}
+}
+
+// CombineFiles implements the FsFileCombiner interface.
+// The sources are combined using a multipart upload and the parts are copied
+// server side. S3 requires at least 5MB for each part except the last one, so
+// the sources smaller than 5MB are downloaded and grouped in bigger parts
+func (fs *S3Fs) CombineFiles(target string, sources []string) error {
+	if len(sources) == 0 {
+		return errors.New("no source files to combine")
+	}
+	var segments []s3ObjectSegment
+	var totalSize int64
+	for _, source := range sources {
+		obj, err := fs.headObject(source)
+		if err != nil {
+			return err
+		}
+		size := aws.Int64Value(obj.ContentLength)
+		if size > 0 {
+			segments = append(segments, s3ObjectSegment{
+				name: source,
+				eTag: obj.ETag,
+				end:  size,
+			})
+			totalSize += size
+		}
+	}
+	ctx, cancelFn := context.WithCancel(context.Background())
+	defer cancelFn()
+
+	contentType := mime.TypeByExtension(path.Ext(target))
+	if totalSize == 0 {
+		err := fs.uploadObject(ctx, bytes.NewReader(nil), target, contentType)
+		metric.S3CopyObjectCompleted(err)
+		return err
+	}
+	parts, err := fs.multipartUpload(ctx, target, contentType, func(uploadID *string) ([]*s3.CompletedPart, error) {
+		return fs.uploadCombinedParts(ctx, target, uploadID, segments)
+	})
+	fsLog(fs, logger.LevelDebug, "combine completed, target: %#v, sources: %v, size: %v, parts: %v, err: %v",
+		target, len(sources), totalSize, parts, err)
+	metric.S3CopyObjectCompleted(err)
+	return err
+}
+
+// uploadCombinedParts uploads the parts for the specified segments. A segment
+// is copied server side if its size is at least 5MB, smaller segments are
+// downloaded and grouped until they reach this size
+func (fs *S3Fs) uploadCombinedParts(ctx context.Context, name string, uploadID *string, segments []s3ObjectSegment,
+) ([]*s3.CompletedPart, error) {
+	var parts []*s3.CompletedPart
+	var pending []s3ObjectSegment
+	var pendingSize int64
+
+	flushPending := func() error {
+		data, err := fs.downloadSegments(ctx, pending, pendingSize)
+		if err != nil {
+			return err
+		}
+		part, err := fs.uploadPart(ctx, name, uploadID, int64(len(parts)+1), data)
+		if err != nil {
+			return err
+		}
+		parts = append(parts, part)
+		pending = nil
+		pendingSize = 0
+		return nil
+	}
+
+	for _, segment := range segments {
+		if pendingSize > 0 && segment.size() >= s3manager.MinUploadPartSize {
+			// complete the pending part using the first bytes of this segment
+			head := segment
+			head.end = head.start + s3manager.MinUploadPartSize - pendingSize
+			pending = append(pending, head)
+			pendingSize += head.size()
+			if err := flushPending(); err != nil {
+				return nil, err
+			}
+			segment.start = head.end
+		}
+		if segment.size() < s3manager.MinUploadPartSize {
+			pending = append(pending, segment)
+			pendingSize += segment.size()
+			if pendingSize >= s3manager.MinUploadPartSize {
+				if err := flushPending(); err != nil {
+					return nil, err
+				}
+			}
+			continue
+		}
+		var err error
+		parts, err = fs.uploadPartsCopy(ctx, name, uploadID, parts, segment.name, segment.eTag, segment.start, segment.end)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if pendingSize > 0 {
+		if err := flushPending(); err != nil {
+			return nil, err
+		}
+	}
+	return parts, nil
+}
+
+// multipartUpload starts a multipart upload for the specified object, the parts
+// are uploaded using the uploadParts function and then the upload is completed.
+// The multipart upload is aborted on error. The number of parts is returned
+func (fs *S3Fs) multipartUpload(ctx context.Context, name, contentType string,
+	uploadParts func(uploadID *string) ([]*s3.CompletedPart, error),
+) (int, error) {
+	res, err := fs.svc.CreateMultipartUploadWithContext(ctx, &s3.CreateMultipartUploadInput{
+		Bucket:       aws.String(fs.config.Bucket),
+		Key:          aws.String(name),
+		StorageClass: util.NilIfEmpty(fs.config.StorageClass),
+		ContentType:  util.NilIfEmpty(contentType),
+	})
+	if err != nil {
+		return 0, err
+	}
+	parts, err := uploadParts(res.UploadId)
+	if err != nil {
+		fsLog(fs, logger.LevelDebug, "multipart upload for %#v failed, aborting: %v", name, err)
+		fs.abortMultipartUpload(name, res.UploadId)
+		return len(parts), err
+	}
+	_, err = fs.svc.CompleteMultipartUploadWithContext(ctx, &s3.CompleteMultipartUploadInput{
+		Bucket:   aws.String(fs.config.Bucket),
+		Key:      aws.String(name),
+		UploadId: res.UploadId,
+		MultipartUpload: &s3.CompletedMultipartUpload{
+			Parts: parts,
+		},
+	})
+	return len(parts), err
+}
+
+// uploadPartsCopy copies the specified range of the source object as new parts and
+// appends them to the given ones. A single copied part cannot exceed 5GB, so
+// bigger ranges are split in parts with the same size
+func (fs *S3Fs) uploadPartsCopy(ctx context.Context, name string, uploadID *string, parts []*s3.CompletedPart,
+	source string, eTag *string, start, end int64,
+) ([]*s3.CompletedPart, error) {
+	size := end - start
+	if size <= 0 {
+		return parts, nil
+	}
+	numCopyParts := (size + s3MaxCopyPartSize - 1) / s3MaxCopyPartSize
+	copyPartSize := (size + numCopyParts - 1) / numCopyParts
+	copySource := pathEscape(fs.Join(fs.config.Bucket, source))
+	for offset := start; offset < end; offset += copyPartSize {
+		last := offset + copyPartSize - 1
+		if last >= end {
+			last = end - 1
+		}
+		partNumber := int64(len(parts) + 1)
+		innerCtx, cancelFn := context.WithDeadline(ctx, time.Now().Add(fs.ctxTimeout))
+		resp, err := fs.svc.UploadPartCopyWithContext(innerCtx, &s3.UploadPartCopyInput{
+			Bucket:            aws.String(fs.config.Bucket),
+			Key:               aws.String(name),
+			UploadId:          uploadID,
+			PartNumber:        aws.Int64(partNumber),
+			CopySource:        aws.String(copySource),
+			CopySourceIfMatch: eTag,
+			CopySourceRange:   aws.String(fmt.Sprintf("bytes=%v-%v", offset, last)),
+		})
+		cancelFn()
+		if err != nil {
+			return nil, err
+		}
+		parts = append(parts, &s3.CompletedPart{
+			ETag:       resp.CopyPartResult.ETag,
+			PartNumber: aws.Int64(partNumber),
+		})
+	}
+	return parts, nil
+}
+
+func (fs *S3Fs) uploadPart(ctx context.Context, name string, uploadID *string, partNumber int64, data []byte,
+) (*s3.CompletedPart, error) {
+	resp, err := fs.svc.UploadPartWithContext(ctx, &s3.UploadPartInput{
+		Bucket:     aws.String(fs.config.Bucket),
+		Key:        aws.String(name),
+		UploadId:   uploadID,
+		PartNumber: aws.Int64(partNumber),
+		Body:       bytes.NewReader(data),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &s3.CompletedPart{
+		ETag:       resp.ETag,
+		PartNumber: aws.Int64(partNumber),
+	}, nil
+}
+
+// downloadSegments returns the contents for the specified object segments
+func (fs *S3Fs) downloadSegments(ctx context.Context, segments []s3ObjectSegment, size int64) ([]byte, error) {
+	buf := bytes.NewBuffer(make([]byte, 0, size))
+	for _, segment := range segments {
+		innerCtx, cancelFn := context.WithDeadline(ctx, time.Now().Add(fs.ctxTimeout))
+		resp, err := fs.svc.GetObjectWithContext(innerCtx, &s3.GetObjectInput{
+			Bucket:  aws.String(fs.config.Bucket),
+			Key:     aws.String(segment.name),
+			IfMatch: segment.eTag,
+			Range:   aws.String(fmt.Sprintf("bytes=%v-%v", segment.start, segment.end-1)),
+		})
+		if err != nil {
+			cancelFn()
+			return nil, err
+		}
+		n, err := io.Copy(buf, resp.Body)
+		resp.Body.Close()
+		cancelFn()
+		if err != nil {
+			return nil, err
+		}
+		if n != segment.size() {
+			return nil, fmt.Errorf("unexpected size for %#v, got: %v, expected: %v", segment.name, n, segment.size())
+		}
+	}
+	return buf.Bytes(), nil
+}
+
 // Rename renames (moves) source to target.
 // We don't support renaming non empty directories since we should
 // rename all the contents too and this could take long time: think
@@ -299,9 +794,32 @@ func (fs *S3Fs) Rename(source, target string) error {
 	} else {
 		contentType = mime.TypeByExtension(path.Ext(source))
 	}
+	if err := fs.copyObject(copySource, target, contentType); err != nil {
+		return err
+	}
+	return fs.Remove(source, fi.IsDir())
+}
+
+// CopyFile implements the FsFileCopier interface.
+// The file is copied using a server side CopyObject call
+func (fs *S3Fs) CopyFile(source, target string) error {
+	if source == target {
+		return nil
+	}
+	fi, err := fs.Stat(source)
+	if err != nil {
+		return err
+	}
+	if fi.IsDir() {
+		return fmt.Errorf("cannot copy %#v, it is a directory", source)
+	}
+	return fs.copyObject(fs.Join(fs.config.Bucket, source), target, mime.TypeByExtension(path.Ext(source)))
+}
+
+func (fs *S3Fs) copyObject(copySource, target, contentType string) error {
 	ctx, cancelFn := context.WithDeadline(context.Background(), time.Now().Add(fs.ctxTimeout))
 	defer cancelFn()
-	_, err = fs.svc.CopyObjectWithContext(ctx, &s3.CopyObjectInput{
+	_, err := fs.svc.CopyObjectWithContext(ctx, &s3.CopyObjectInput{
 		Bucket:       aws.String(fs.config.Bucket),
 		CopySource:   aws.String(pathEscape(copySource)),
 		Key:          aws.String(target),
@@ -317,10 +835,7 @@ func (fs *S3Fs) Rename(source, target string) error {
 		Key:    aws.String(target),
 	})
 	metric.S3CopyObjectCompleted(err)
-	if err != nil {
-		return err
-	}
-	return fs.Remove(source, fi.IsDir())
+	return err
 }
 
 // Remove removes the named file or (empty) directory.
@@ -336,6 +851,11 @@ func (fs *S3Fs) Remove(name string, isDir bool) error {
 		if !strings.HasSuffix(name, "/") {
 			name += "/"
 		}
+	} else {
+		var state s3MultipartUploadState
+		if upload, _ := getMultipartUpload(fs, fs.getMultipartUploadKey(name), &state); upload != nil {
+			fs.discardMultipartUpload(name, &state)
+		}
 	}
 	ctx, cancelFn := context.WithDeadline(context.Background(), time.Now().Add(fs.ctxTimeout))
 	defer cancelFn()
@@ -456,9 +976,10 @@ func (fs *S3Fs) ReadDir(dirname string) ([]os.FileInfo, error) {
 }
 
 // IsUploadResumeSupported returns true if resuming uploads is supported.
-// Resuming uploads is not supported on S3
+// Resumed uploads use a multipart upload that copies the existing object
+// server side and then appends the new data
 func (*S3Fs) IsUploadResumeSupported() bool {
-	return false
+	return true
 }
 
 // IsAtomicUploadSupported returns true if atomic upload is supported.
@@ -98,6 +98,23 @@ type Fs interface {
 	Close() error
 }
 
+// FsFileCopier is a Fs that implements the CopyFile method.
+// It is used for server side copies, the backends implementing this
+// interface can copy a file without downloading and uploading it again
+type FsFileCopier interface {
+	Fs
+	CopyFile(source, target string) error
+}
+
+// FsFileCombiner is a Fs that implements the CombineFiles method.
+// It is used to concatenate files server side, the sources are combined,
+// in the given order, into the target file without downloading them.
+// The sources are not removed
+type FsFileCombiner interface {
+	Fs
+	CombineFiles(target string, sources []string) error
+}
+
 // File defines an interface representing a SFTPGo file
 type File interface {
 	io.Reader
@@ -515,6 +532,8 @@ type PipeWriter struct {
 	writer *pipeat.PipeWriterAt
 	err    error
 	done   chan bool
+	// offset for the first byte written to the pipe, it is greater than 0 for resumed uploads
+	offset int64
 }
 
 // NewPipeWriter initializes a new PipeWriter
@@ -526,6 +545,20 @@ func NewPipeWriter(w *pipeat.PipeWriterAt) *PipeWriter {
 	}
 }
 
+// NewPipeWriterAtOffset initializes a new PipeWriter for an upload resumed at
+// the specified offset. Writes before the offset are not allowed
+func NewPipeWriterAtOffset(w *pipeat.PipeWriterAt, offset int64) *PipeWriter {
+	p := NewPipeWriter(w)
+	p.offset = offset
+	return p
+}
+
+// GetOffset returns the offset for the first byte written to the pipe.
+// It is greater than 0 if an interrupted upload was resumed
+func (p *PipeWriter) GetOffset() int64 {
+	return p.offset
+}
+
 // Close waits for the upload to end, closes the pipeat.PipeWriterAt and returns an error if any.
 func (p *PipeWriter) Close() error {
 	p.writer.Close() //nolint:errcheck // the returned error is always null
@@ -542,7 +575,10 @@ func (p *PipeWriter) Done(err error) {
 
 // WriteAt is a wrapper for pipeat WriteAt
 func (p *PipeWriter) WriteAt(data []byte, off int64) (int, error) {
-	return p.writer.WriteAt(data, off)
+	if off < p.offset {
+		return 0, fmt.Errorf("invalid write offset: %v, the upload was resumed at offset: %v", off, p.offset)
+	}
+	return p.writer.WriteAt(data, off-p.offset)
 }
 
 // Write is a wrapper for pipeat Write
@@ -550,6 +586,24 @@ func (p *PipeWriter) Write(data []byte) (int, error) {
 	return p.writer.Write(data)
 }
 
+// isUploadResumeRequested returns true if the flags passed to the Create method
+// of a Cloud Storage filesystem request to resume the upload for an existing file.
+// The protocol handlers use 0 or flags including os.O_TRUNC for new uploads
+// while -1 is used to create directories
+func isUploadResumeRequested(flag int) bool {
+	return flag > 0 && flag&os.O_TRUNC == 0
+}
+
+// readFill reads from r until buf is full or an error occurs, copied from rclone
+func readFill(r io.Reader, buf []byte) (n int, err error) {
+	var nn int
+	for n < len(buf) && err == nil {
+		nn, err = r.Read(buf[n:])
+		n += nn
+	}
+	return n, err
+}
+
 // IsDirectory checks if a path exists and is a directory
 func IsDirectory(fs Fs, path string) (bool, error) {
 	fileInfo, err := fs.Stat(path)
@@ -569,6 +623,16 @@ func IsCryptOsFs(fs Fs) bool {
 	return fs.Name() == cryptFsName
 }
 
+// IsEncryptedFs returns true if fs is a remote filesystem with client side encryption
+func IsEncryptedFs(fs Fs) bool {
+	switch fs.(type) {
+	case *EncryptedFs, *encryptedCopierFs:
+		return true
+	default:
+		return false
+	}
+}
+
 // IsSFTPFs returns true if fs is an SFTP filesystem
 func IsSFTPFs(fs Fs) bool {
 	return strings.HasPrefix(fs.Name(), sftpFsName)
@@ -598,6 +662,13 @@ func IsLocalOrSFTPFs(fs Fs) bool {
 	return IsLocalOsFs(fs) || IsSFTPFs(fs)
 }
 
+// HasPendingUploadResume returns true if fs can resume an upload for a path
+// that does not exist yet. Interrupted uploads to Cloud Storage backends are
+// not visible as files, they are resumed if Create is called with a resume flag
+func HasPendingUploadResume(fs Fs) bool {
+	return !IsLocalOrSFTPFs(fs) && fs.IsUploadResumeSupported()
+}
+
 // HasOpenRWSupport returns true if the fs can open a file
 // for reading and writing at the same time
 func HasOpenRWSupport(fs Fs) bool {
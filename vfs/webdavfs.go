@@ -0,0 +1,864 @@
+package vfs
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/eikenb/pipeat"
+	"github.com/pkg/sftp"
+	"github.com/rs/xid"
+
+	"github.com/drakkan/sftpgo/v2/kms"
+	"github.com/drakkan/sftpgo/v2/logger"
+	"github.com/drakkan/sftpgo/v2/sdk"
+	"github.com/drakkan/sftpgo/v2/util"
+)
+
+const (
+	// webDAVFsName is the name for the WebDAV Fs implementation
+	webDAVFsName        = "webdavfs"
+	webDAVDialTimeout   = 15 * time.Second
+	webDAVMaxIdleConns  = 4
+	webDAVPropfindProps = `<?xml version="1.0" encoding="utf-8"?>` +
+		`<D:propfind xmlns:D="DAV:"><D:prop><D:resourcetype/><D:getcontentlength/>` +
+		`<D:getlastmodified/><D:getcontenttype/></D:prop></D:propfind>`
+)
+
+var errWebDAVTransferAborted = errors.New("transfer aborted")
+
+// WebDAVFsConfig defines the configuration for WebDAV based filesystem
+type WebDAVFsConfig struct {
+	sdk.WebDAVFsConfig
+}
+
+// HideConfidentialData hides confidential data
+func (c *WebDAVFsConfig) HideConfidentialData() {
+	if c.Password != nil {
+		c.Password.Hide()
+	}
+	if c.BearerToken != nil {
+		c.BearerToken.Hide()
+	}
+}
+
+func (c *WebDAVFsConfig) isEqual(other *WebDAVFsConfig) bool {
+	if c.Endpoint != other.Endpoint {
+		return false
+	}
+	if c.Username != other.Username {
+		return false
+	}
+	if c.Prefix != other.Prefix {
+		return false
+	}
+	if c.SkipTLSVerify != other.SkipTLSVerify {
+		return false
+	}
+	c.setEmptyCredentialsIfNil()
+	other.setEmptyCredentialsIfNil()
+	if !c.Password.IsEqual(other.Password) {
+		return false
+	}
+	return c.BearerToken.IsEqual(other.BearerToken)
+}
+
+func (c *WebDAVFsConfig) setEmptyCredentialsIfNil() {
+	if c.Password == nil {
+		c.Password = kms.NewEmptySecret()
+	}
+	if c.BearerToken == nil {
+		c.BearerToken = kms.NewEmptySecret()
+	}
+}
+
+// Validate returns an error if the configuration is not valid
+func (c *WebDAVFsConfig) Validate() error {
+	c.setEmptyCredentialsIfNil()
+	if c.Endpoint == "" {
+		return errors.New("endpoint cannot be empty")
+	}
+	u, err := url.Parse(c.Endpoint)
+	if err != nil {
+		return fmt.Errorf("invalid endpoint: %v", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("invalid endpoint scheme %#v, valid values are http and https", u.Scheme)
+	}
+	if u.Host == "" {
+		return errors.New("invalid endpoint: the host is missing")
+	}
+	if c.Password.IsEncrypted() && !c.Password.IsValid() {
+		return errors.New("invalid encrypted password")
+	}
+	if !c.Password.IsEmpty() && !c.Password.IsValidInput() {
+		return errors.New("invalid password")
+	}
+	if c.BearerToken.IsEncrypted() && !c.BearerToken.IsValid() {
+		return errors.New("invalid encrypted bearer token")
+	}
+	if !c.BearerToken.IsEmpty() && !c.BearerToken.IsValidInput() {
+		return errors.New("invalid bearer token")
+	}
+	if !c.Password.IsEmpty() && c.Username == "" {
+		return errors.New("username cannot be empty if a password is set")
+	}
+	if !c.Password.IsEmpty() && !c.BearerToken.IsEmpty() {
+		return errors.New("password and bearer token cannot be used together")
+	}
+	if c.Prefix != "" {
+		c.Prefix = util.CleanPath(c.Prefix)
+	} else {
+		c.Prefix = "/"
+	}
+	return nil
+}
+
+// EncryptCredentials encrypts the password and the bearer token if they are in plain text
+func (c *WebDAVFsConfig) EncryptCredentials(additionalData string) error {
+	if c.Password.IsPlain() {
+		c.Password.SetAdditionalData(additionalData)
+		if err := c.Password.Encrypt(); err != nil {
+			return err
+		}
+	}
+	if c.BearerToken.IsPlain() {
+		c.BearerToken.SetAdditionalData(additionalData)
+		if err := c.BearerToken.Encrypt(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type webDAVMultistatus struct {
+	Responses []webDAVResponse `xml:"DAV: response"`
+}
+
+type webDAVResponse struct {
+	Href      string           `xml:"DAV: href"`
+	Propstats []webDAVPropstat `xml:"DAV: propstat"`
+}
+
+type webDAVPropstat struct {
+	Status string     `xml:"DAV: status"`
+	Prop   webDAVProp `xml:"DAV: prop"`
+}
+
+type webDAVProp struct {
+	ResourceType struct {
+		Collection *struct{} `xml:"DAV: collection"`
+	} `xml:"DAV: resourcetype"`
+	ContentLength string `xml:"DAV: getcontentlength"`
+	LastModified  string `xml:"DAV: getlastmodified"`
+	ContentType   string `xml:"DAV: getcontenttype"`
+}
+
+// webDAVEntry is a resource as returned by a PROPFIND request
+type webDAVEntry struct {
+	// the unescaped URL path
+	urlPath     string
+	isDir       bool
+	size        int64
+	modTime     time.Time
+	contentType string
+}
+
+// WebDAVFs is a Fs implementation for remote WebDAV shares.
+// Stat and ReadDir are mapped to PROPFIND requests, downloads use ranged
+// GET requests and uploads are streamed using PUT requests
+type WebDAVFs struct {
+	connectionID string
+	// if not empty this fs is mouted as virtual folder in the specified path
+	mountPath    string
+	localTempDir string
+	config       *WebDAVFsConfig
+	baseURL      *url.URL
+	httpClient   *http.Client
+}
+
+// NewWebDAVFs returns a WebDAVFs object that allows to interact with a WebDAV server
+func NewWebDAVFs(connectionID, mountPath, localTempDir string, config WebDAVFsConfig) (Fs, error) {
+	if localTempDir == "" {
+		if tempPath != "" {
+			localTempDir = tempPath
+		} else {
+			localTempDir = filepath.Clean(os.TempDir())
+		}
+	}
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	if !config.Password.IsEmpty() {
+		if err := config.Password.TryDecrypt(); err != nil {
+			return nil, err
+		}
+	}
+	if !config.BearerToken.IsEmpty() {
+		if err := config.BearerToken.TryDecrypt(); err != nil {
+			return nil, err
+		}
+	}
+	baseURL, err := url.Parse(config.Endpoint)
+	if err != nil {
+		return nil, err
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = (&net.Dialer{
+		Timeout:   webDAVDialTimeout,
+		KeepAlive: 30 * time.Second,
+	}).DialContext
+	transport.TLSHandshakeTimeout = webDAVDialTimeout
+	transport.MaxIdleConnsPerHost = webDAVMaxIdleConns
+	transport.TLSClientConfig = &tls.Config{
+		InsecureSkipVerify: config.SkipTLSVerify, //nolint:gosec
+		MinVersion:         tls.VersionTLS12,
+	}
+	return &WebDAVFs{
+		connectionID: connectionID,
+		mountPath:    mountPath,
+		localTempDir: localTempDir,
+		config:       &config,
+		baseURL:      baseURL,
+		httpClient: &http.Client{
+			Transport: transport,
+			// redirects are handled in doRequest, the standard client changes
+			// the method to GET following a 301 or 302 redirect
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+	}, nil
+}
+
+// Name returns the name for the Fs implementation
+func (fs *WebDAVFs) Name() string {
+	return fmt.Sprintf("%v %#v", webDAVFsName, fs.config.Endpoint)
+}
+
+// ConnectionID returns the connection ID associated to this Fs implementation
+func (fs *WebDAVFs) ConnectionID() string {
+	return fs.connectionID
+}
+
+// Stat returns a FileInfo describing the named file
+func (fs *WebDAVFs) Stat(name string) (os.FileInfo, error) {
+	entries, err := fs.propfind(name, false, "0")
+	if err != nil {
+		return nil, err
+	}
+	if len(entries) == 0 {
+		return nil, &os.PathError{Op: "stat", Path: name, Err: os.ErrNotExist}
+	}
+	entry := entries[0]
+	return NewFileInfo(name, entry.isDir, entry.size, entry.modTime, false), nil
+}
+
+// Lstat returns a FileInfo describing the named file
+func (fs *WebDAVFs) Lstat(name string) (os.FileInfo, error) {
+	return fs.Stat(name)
+}
+
+// Open opens the named file for reading
+func (fs *WebDAVFs) Open(name string, offset int64) (File, *pipeat.PipeReaderAt, func(), error) {
+	ctx, cancelFn := context.WithCancel(context.Background())
+	req, err := fs.newRequest(ctx, http.MethodGet, name, false, nil)
+	if err != nil {
+		cancelFn()
+		return nil, nil, nil, err
+	}
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%v-", offset))
+	}
+	resp, err := fs.httpClient.Do(req)
+	if err != nil {
+		cancelFn()
+		return nil, nil, nil, err
+	}
+	var toSkip int64
+	switch resp.StatusCode {
+	case http.StatusOK:
+		// the server ignored the range request
+		toSkip = offset
+	case http.StatusPartialContent:
+	case http.StatusRequestedRangeNotSatisfiable:
+		// the offset is equal to the file size, there is nothing to read
+		resp.Body.Close()
+		resp.Body = http.NoBody
+	default:
+		err = getWebDAVResponseError("open", name, resp)
+		cancelFn()
+		return nil, nil, nil, err
+	}
+	r, w, err := pipeat.PipeInDir(fs.localTempDir)
+	if err != nil {
+		resp.Body.Close()
+		cancelFn()
+		return nil, nil, nil, err
+	}
+	go func() {
+		defer cancelFn()
+		defer resp.Body.Close()
+
+		var n int64
+		var err error
+		if toSkip > 0 {
+			_, err = io.CopyN(io.Discard, resp.Body, toSkip)
+		}
+		if err == nil {
+			n, err = io.Copy(w, resp.Body)
+		}
+		w.CloseWithError(err) //nolint:errcheck
+		fsLog(fs, logger.LevelDebug, "download completed, path: %#v size: %v, err: %v", name, n, err)
+	}()
+
+	return nil, r, cancelFn, nil
+}
+
+// Create creates or opens the named file for writing
+func (fs *WebDAVFs) Create(name string, flag int) (File, *PipeWriter, func(), error) {
+	r, w, err := pipeat.PipeInDir(fs.localTempDir)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	ctx, cancelCtx := context.WithCancel(context.Background())
+	// the pipe reader is closed by us, the HTTP client must not close it
+	req, err := fs.newRequest(ctx, http.MethodPut, name, false, io.NopCloser(r))
+	if err != nil {
+		cancelCtx()
+		r.Close()
+		w.Close()
+		return nil, nil, nil, err
+	}
+	// the size is unknown, chunked transfer encoding will be used
+	req.ContentLength = -1
+	p := NewPipeWriter(w)
+	var closeOnce sync.Once
+	closeReader := func(err error) {
+		closeOnce.Do(func() {
+			r.CloseWithError(err) //nolint:errcheck
+		})
+	}
+	cancelFn := func() {
+		closeReader(errWebDAVTransferAborted)
+		cancelCtx()
+	}
+	go func() {
+		defer cancelCtx()
+
+		resp, err := fs.httpClient.Do(req)
+		if err == nil {
+			switch resp.StatusCode {
+			case http.StatusOK, http.StatusCreated, http.StatusNoContent:
+			default:
+				err = getWebDAVResponseError("create", name, resp)
+			}
+			resp.Body.Close()
+		}
+		closeReader(err)
+		p.Done(err)
+		fsLog(fs, logger.LevelDebug, "upload completed, path: %#v, readed bytes: %v, err: %v",
+			name, r.GetReadedBytes(), err)
+	}()
+
+	return nil, p, cancelFn, nil
+}
+
+// Rename renames (moves) source to target.
+// Directories are moved server side including their contents
+func (fs *WebDAVFs) Rename(source, target string) error {
+	resp, err := fs.doRequest("MOVE", source, false, nil, func(req *http.Request, isDir bool) {
+		req.Header.Set("Destination", fs.getURL(target, isDir))
+		req.Header.Set("Overwrite", "T")
+	})
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusCreated, http.StatusNoContent:
+		return nil
+	default:
+		return getWebDAVResponseError("rename", source, resp)
+	}
+}
+
+// Remove removes the named file or (empty) directory.
+func (fs *WebDAVFs) Remove(name string, isDir bool) error {
+	if isDir {
+		// the DELETE method removes collections including their contents
+		contents, err := fs.ReadDir(name)
+		if err != nil {
+			return err
+		}
+		if len(contents) > 0 {
+			return fmt.Errorf("cannot remove non empty directory: %#v", name)
+		}
+	}
+	resp, err := fs.doRequest(http.MethodDelete, name, isDir, nil, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusAccepted, http.StatusNoContent:
+		return nil
+	default:
+		return getWebDAVResponseError("remove", name, resp)
+	}
+}
+
+// Mkdir creates a new directory with the specified name and default permissions
+func (fs *WebDAVFs) Mkdir(name string) error {
+	resp, err := fs.doRequest("MKCOL", name, true, nil, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusCreated:
+		return nil
+	case http.StatusMethodNotAllowed:
+		// MKCOL is not allowed on existing resources
+		return &os.PathError{Op: "mkdir", Path: name, Err: os.ErrExist}
+	default:
+		return getWebDAVResponseError("mkdir", name, resp)
+	}
+}
+
+// MkdirAll creates a directory named path, along with any necessary parents,
+// and returns nil, or else returns an error.
+// If path is already a directory, MkdirAll does nothing and returns nil.
+func (fs *WebDAVFs) MkdirAll(name string, uid int, gid int) error {
+	dirPath := "/"
+	for _, dir := range strings.Split(path.Clean(name), "/") {
+		if dir == "" {
+			continue
+		}
+		dirPath = path.Join(dirPath, dir)
+		if err := fs.Mkdir(dirPath); err != nil && !os.IsExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// Symlink creates source as a symbolic link to target.
+func (*WebDAVFs) Symlink(source, target string) error {
+	return ErrVfsUnsupported
+}
+
+// Readlink returns the destination of the named symbolic link
+func (*WebDAVFs) Readlink(name string) (string, error) {
+	return "", ErrVfsUnsupported
+}
+
+// Chown changes the numeric uid and gid of the named file.
+func (*WebDAVFs) Chown(name string, uid int, gid int) error {
+	return ErrVfsUnsupported
+}
+
+// Chmod changes the mode of the named file to mode.
+func (*WebDAVFs) Chmod(name string, mode os.FileMode) error {
+	return ErrVfsUnsupported
+}
+
+// Chtimes changes the access and modification times of the named file.
+func (*WebDAVFs) Chtimes(name string, atime, mtime time.Time) error {
+	return ErrVfsUnsupported
+}
+
+// Truncate changes the size of the named file.
+// Truncate by path is not supported, while truncating an opened
+// file is handled inside base transfer
+func (*WebDAVFs) Truncate(name string, size int64) error {
+	return ErrVfsUnsupported
+}
+
+// ReadDir reads the directory named by dirname and returns
+// a list of directory entries.
+func (fs *WebDAVFs) ReadDir(dirname string) ([]os.FileInfo, error) {
+	entries, err := fs.propfind(dirname, true, "1")
+	if err != nil {
+		return nil, err
+	}
+	dirPath := path.Clean(fs.getURLPath(dirname))
+	var result []os.FileInfo
+	for _, entry := range entries {
+		entryPath := path.Clean(entry.urlPath)
+		// the response includes the requested collection too
+		if entryPath == dirPath {
+			continue
+		}
+		result = append(result, NewFileInfo(entryPath, entry.isDir, entry.size, entry.modTime, false))
+	}
+	return result, nil
+}
+
+// IsUploadResumeSupported returns true if resuming uploads is supported.
+// WebDAV has no standard way to append to an existing resource
+func (*WebDAVFs) IsUploadResumeSupported() bool {
+	return false
+}
+
+// IsAtomicUploadSupported returns true if atomic upload is supported.
+func (*WebDAVFs) IsAtomicUploadSupported() bool {
+	return true
+}
+
+// IsNotExist returns a boolean indicating whether the error is known to
+// report that a file or directory does not exist
+func (*WebDAVFs) IsNotExist(err error) bool {
+	return os.IsNotExist(err)
+}
+
+// IsPermission returns a boolean indicating whether the error is known to
+// report that permission is denied.
+func (*WebDAVFs) IsPermission(err error) bool {
+	if _, ok := err.(*pathResolutionError); ok {
+		return true
+	}
+	return os.IsPermission(err)
+}
+
+// IsNotSupported returns true if the error indicate an unsupported operation
+func (*WebDAVFs) IsNotSupported(err error) bool {
+	if err == nil {
+		return false
+	}
+	return errors.Is(err, ErrVfsUnsupported)
+}
+
+// CheckRootPath creates the specified local root directory if it does not exists
+func (fs *WebDAVFs) CheckRootPath(username string, uid int, gid int) bool {
+	// we need a local directory for temporary files
+	osFs := NewOsFs(fs.ConnectionID(), fs.localTempDir, "")
+	osFs.CheckRootPath(username, uid, gid)
+	if fs.config.Prefix == "/" {
+		return true
+	}
+	if err := fs.MkdirAll(fs.config.Prefix, uid, gid); err != nil {
+		fsLog(fs, logger.LevelDebug, "error creating root directory %#v for user %#v: %v", fs.config.Prefix, username, err)
+		return false
+	}
+	return true
+}
+
+// ScanRootDirContents returns the number of files contained in a directory and
+// their size
+func (fs *WebDAVFs) ScanRootDirContents() (int, int64, error) {
+	return fs.GetDirSize(fs.config.Prefix)
+}
+
+// GetAtomicUploadPath returns the path to use for an atomic upload
+func (*WebDAVFs) GetAtomicUploadPath(name string) string {
+	dir := path.Dir(name)
+	guid := xid.New().String()
+	return path.Join(dir, ".sftpgo-upload."+guid+"."+path.Base(name))
+}
+
+// GetRelativePath returns the path for a file relative to the WebDAV prefix if any.
+// This is the path as seen by SFTPGo users
+func (fs *WebDAVFs) GetRelativePath(name string) string {
+	rel := path.Clean(name)
+	if rel == "." {
+		rel = ""
+	}
+	if !path.IsAbs(rel) {
+		return "/" + rel
+	}
+	if fs.config.Prefix != "/" {
+		if !strings.HasPrefix(rel, fs.config.Prefix) {
+			rel = "/"
+		}
+		rel = path.Clean("/" + strings.TrimPrefix(rel, fs.config.Prefix))
+	}
+	if fs.mountPath != "" {
+		rel = path.Join(fs.mountPath, rel)
+	}
+	return rel
+}
+
+// Walk walks the file tree rooted at root, calling walkFn for each file or
+// directory in the tree, including root
+func (fs *WebDAVFs) Walk(root string, walkFn filepath.WalkFunc) error {
+	info, err := fs.Stat(root)
+	if err != nil {
+		return walkFn(root, nil, err)
+	}
+	err = fs.walk(root, info, walkFn)
+	if err == filepath.SkipDir {
+		return nil
+	}
+	return err
+}
+
+func (fs *WebDAVFs) walk(name string, info os.FileInfo, walkFn filepath.WalkFunc) error {
+	if !info.IsDir() {
+		return walkFn(name, info, nil)
+	}
+	contents, err := fs.ReadDir(name)
+	err1 := walkFn(name, info, err)
+	if err != nil || err1 != nil {
+		return err1
+	}
+	for _, fi := range contents {
+		err = fs.walk(path.Join(name, fi.Name()), fi, walkFn)
+		if err != nil {
+			if !fi.IsDir() || err != filepath.SkipDir {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Join joins any number of path elements into a single path
+func (*WebDAVFs) Join(elem ...string) string {
+	return path.Join(elem...)
+}
+
+// HasVirtualFolders returns true if folders are emulated
+func (*WebDAVFs) HasVirtualFolders() bool {
+	return false
+}
+
+// ResolvePath returns the matching filesystem path for the specified virtual path
+func (fs *WebDAVFs) ResolvePath(virtualPath string) (string, error) {
+	if fs.mountPath != "" {
+		virtualPath = strings.TrimPrefix(virtualPath, fs.mountPath)
+	}
+	// symlinks cannot be resolved using WebDAV, cleaning the virtual path
+	// before joining it with the prefix is enough to stay inside the prefix
+	virtualPath = path.Clean("/" + virtualPath)
+	return fs.Join(fs.config.Prefix, virtualPath), nil
+}
+
+// GetDirSize returns the number of files and the size for a folder
+// including any subfolders
+func (fs *WebDAVFs) GetDirSize(dirname string) (int, int64, error) {
+	numFiles := 0
+	size := int64(0)
+	isDir, err := IsDirectory(fs, dirname)
+	if err == nil && isDir {
+		err = fs.Walk(dirname, func(_ string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if info != nil && info.Mode().IsRegular() {
+				size += info.Size()
+				numFiles++
+			}
+			return err
+		})
+	}
+	return numFiles, size, err
+}
+
+// GetMimeType returns the content type.
+// The content type reported by the server is used if available
+func (fs *WebDAVFs) GetMimeType(name string) (string, error) {
+	entries, err := fs.propfind(name, false, "0")
+	if err != nil {
+		return "", err
+	}
+	if len(entries) > 0 && entries[0].contentType != "" {
+		return entries[0].contentType, nil
+	}
+	req, err := fs.newRequest(context.Background(), http.MethodGet, name, false, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Range", "bytes=0-511")
+	resp, err := fs.httpClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusPartialContent {
+		return "", getWebDAVResponseError("open", name, resp)
+	}
+	var buf [512]byte
+	n, err := io.ReadFull(resp.Body, buf[:])
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return "", err
+	}
+	return http.DetectContentType(buf[:n]), nil
+}
+
+// GetAvailableDiskSize return the available size for the specified path
+func (*WebDAVFs) GetAvailableDiskSize(dirName string) (*sftp.StatVFS, error) {
+	return nil, ErrStorageSizeUnavailable
+}
+
+// Close closes the idle HTTP connections
+func (fs *WebDAVFs) Close() error {
+	fs.httpClient.CloseIdleConnections()
+	return nil
+}
+
+// getURLPath returns the unescaped URL path for the specified fs path
+func (fs *WebDAVFs) getURLPath(name string) string {
+	return path.Join(fs.baseURL.Path, name)
+}
+
+// getURL returns the URL for the specified fs path, collections URLs
+// end with a slash
+func (fs *WebDAVFs) getURL(name string, isDir bool) string {
+	u := *fs.baseURL
+	u.Path = fs.getURLPath(name)
+	u.RawPath = ""
+	if isDir && !strings.HasSuffix(u.Path, "/") {
+		u.Path += "/"
+	}
+	return u.String()
+}
+
+func (fs *WebDAVFs) newRequest(ctx context.Context, method, name string, isDir bool, body io.Reader) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, fs.getURL(name, isDir), body)
+	if err != nil {
+		return nil, err
+	}
+	if !fs.config.BearerToken.IsEmpty() {
+		req.Header.Set("Authorization", "Bearer "+fs.config.BearerToken.GetPayload())
+	} else if fs.config.Username != "" {
+		req.SetBasicAuth(fs.config.Username, fs.config.Password.GetPayload())
+	}
+	return req, nil
+}
+
+// doRequest executes a request without a streaming body. If the server redirects
+// a resource URL to the collection URL the request is repeated using this URL.
+// setHeaders, if not nil, is called to add the request specific headers
+func (fs *WebDAVFs) doRequest(method, name string, isDir bool, body []byte,
+	setHeaders func(req *http.Request, isDir bool),
+) (*http.Response, error) {
+	for {
+		var reqBody io.Reader
+		if body != nil {
+			reqBody = bytes.NewReader(body)
+		}
+		req, err := fs.newRequest(context.Background(), method, name, isDir, reqBody)
+		if err != nil {
+			return nil, err
+		}
+		if setHeaders != nil {
+			setHeaders(req, isDir)
+		}
+		resp, err := fs.httpClient.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		switch resp.StatusCode {
+		case http.StatusMovedPermanently, http.StatusFound, http.StatusTemporaryRedirect, http.StatusPermanentRedirect:
+			if !isDir {
+				resp.Body.Close()
+				isDir = true
+				continue
+			}
+		}
+		return resp, nil
+	}
+}
+
+// propfind returns the resources matching the specified path and depth
+func (fs *WebDAVFs) propfind(name string, isDir bool, depth string) ([]webDAVEntry, error) {
+	resp, err := fs.doRequest("PROPFIND", name, isDir, []byte(webDAVPropfindProps), func(req *http.Request, _ bool) {
+		req.Header.Set("Depth", depth)
+		req.Header.Set("Content-Type", "application/xml; charset=utf-8")
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusMultiStatus {
+		return nil, getWebDAVResponseError("stat", name, resp)
+	}
+	var ms webDAVMultistatus
+	if err := xml.NewDecoder(resp.Body).Decode(&ms); err != nil {
+		return nil, fmt.Errorf("unable to parse PROPFIND response for %#v: %w", name, err)
+	}
+	entries := make([]webDAVEntry, 0, len(ms.Responses))
+	for _, r := range ms.Responses {
+		u, err := url.Parse(r.Href)
+		if err != nil {
+			fsLog(fs, logger.LevelDebug, "skipping invalid href %#v: %v", r.Href, err)
+			continue
+		}
+		entry := webDAVEntry{
+			urlPath: u.Path,
+		}
+		for _, ps := range r.Propstats {
+			if !isWebDAVStatusOK(ps.Status) {
+				continue
+			}
+			if ps.Prop.ResourceType.Collection != nil {
+				entry.isDir = true
+			}
+			if ps.Prop.ContentLength != "" {
+				entry.size, _ = strconv.ParseInt(strings.TrimSpace(ps.Prop.ContentLength), 10, 64)
+			}
+			if ps.Prop.LastModified != "" {
+				entry.modTime, _ = http.ParseTime(strings.TrimSpace(ps.Prop.LastModified))
+			}
+			if ps.Prop.ContentType != "" {
+				entry.contentType = ps.Prop.ContentType
+			}
+		}
+		if entry.isDir {
+			entry.size = 0
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// isWebDAVStatusOK returns true if the specified status line, for example
+// "HTTP/1.1 200 OK", reports a successful status
+func isWebDAVStatusOK(status string) bool {
+	fields := strings.Fields(status)
+	if len(fields) < 2 {
+		return false
+	}
+	code, err := strconv.Atoi(fields[1])
+	if err != nil {
+		return false
+	}
+	return code >= 200 && code < 300
+}
+
+// getWebDAVResponseError maps an unexpected HTTP response to an error
+func getWebDAVResponseError(op, name string, resp *http.Response) error {
+	var err error
+	switch resp.StatusCode {
+	case http.StatusNotFound, http.StatusGone, http.StatusConflict:
+		// 409 is returned for MKCOL, PUT and MOVE if the parent collection is missing
+		err = os.ErrNotExist
+	case http.StatusUnauthorized, http.StatusForbidden:
+		err = os.ErrPermission
+	case http.StatusMethodNotAllowed, http.StatusNotImplemented:
+		err = ErrVfsUnsupported
+	default:
+		err = fmt.Errorf("unexpected status code: %v", resp.Status)
+	}
+	return &os.PathError{Op: op, Path: name, Err: err}
+}
@@ -2,11 +2,15 @@ package webdavd
 
 import (
 	"context"
+	"encoding/xml"
 	"errors"
 	"io"
 	"mime"
+	"net/http"
 	"os"
 	"path"
+	"strconv"
+	"sync"
 	"sync/atomic"
 	"time"
 
@@ -19,7 +23,46 @@ import (
 	"github.com/drakkan/sftpgo/v2/vfs"
 )
 
-var errTransferAborted = errors.New("transfer aborted")
+var (
+	errTransferAborted = errors.New("transfer aborted")
+	// RFC 4331 quota properties, they are protected and cannot be modified
+	quotaAvailableBytesPropName = xml.Name{Space: "DAV:", Local: "quota-available-bytes"}
+	quotaUsedBytesPropName      = xml.Name{Space: "DAV:", Local: "quota-used-bytes"}
+)
+
+// quotaCache caches the quota usage within a request, a PROPFIND request
+// with depth 1 could ask the quota properties for many directories
+type quotaCache struct {
+	sync.Mutex
+	results map[string]vfs.QuotaCheckResult
+}
+
+func newQuotaCache() *quotaCache {
+	return &quotaCache{
+		results: make(map[string]vfs.QuotaCheckResult),
+	}
+}
+
+func (c *quotaCache) get(key string) (vfs.QuotaCheckResult, bool) {
+	if c == nil {
+		return vfs.QuotaCheckResult{}, false
+	}
+	c.Lock()
+	defer c.Unlock()
+
+	result, ok := c.results[key]
+	return result, ok
+}
+
+func (c *quotaCache) add(key string, result vfs.QuotaCheckResult) {
+	if c == nil {
+		return
+	}
+	c.Lock()
+	defer c.Unlock()
+
+	c.results[key] = result
+}
 
 type webDavFile struct {
 	*common.BaseTransfer
@@ -29,6 +72,7 @@ type webDavFile struct {
 	startOffset int64
 	isFinished  bool
 	readTryed   int32
+	quotas      *quotaCache
 }
 
 func newWebDavFile(baseTransfer *common.BaseTransfer, pipeWriter *vfs.PipeWriter, pipeReader *pipeat.PipeReaderAt) *webDavFile {
@@ -117,15 +161,11 @@ func (f *webDavFile) Stat() (os.FileInfo, error) {
 		}
 		return info, nil
 	}
-	info, err := f.Fs.Stat(f.GetFsPath())
-	if err != nil {
+	if err := f.updateStatInfo(); err != nil {
 		return nil, err
 	}
-	if vfs.IsCryptOsFs(f.Fs) {
-		info = f.Fs.(*vfs.CryptFs).ConvertFileInfo(info)
-	}
 	fi := &webDavFileInfo{
-		FileInfo:    info,
+		FileInfo:    f.info,
 		Fs:          f.Fs,
 		virtualPath: f.GetVirtualPath(),
 		fsPath:      f.GetFsPath(),
@@ -147,6 +187,11 @@ func (f *webDavFile) Read(p []byte) (n int, err error) {
 			f.Connection.Log(logger.LevelWarn, "reading file %#v is not allowed", f.GetVirtualPath())
 			return 0, f.Connection.GetPermissionDeniedError()
 		}
+		transferQuota := f.GetTransferQuota()
+		if !transferQuota.HasDownloadSpace() {
+			f.Connection.Log(logger.LevelInfo, "denying file read due to quota limits")
+			return 0, f.Connection.GetReadQuotaExceededError()
+		}
 		err := common.ExecutePreAction(&f.Connection.User, common.OperationPreDownload, f.GetFsPath(), f.GetVirtualPath(),
 			f.Connection.GetProtocol(), f.Connection.GetRemoteIP(), 0, 0)
 		if err != nil {
@@ -182,6 +227,9 @@ func (f *webDavFile) Read(p []byte) (n int, err error) {
 	n, err = f.reader.Read(p)
 	atomic.AddInt64(&f.BytesSent, int64(n))
 
+	if err == nil {
+		err = f.CheckRead()
+	}
 	if err != nil && err != io.EOF {
 		f.TransferError(err)
 		return
@@ -201,8 +249,8 @@ func (f *webDavFile) Write(p []byte) (n int, err error) {
 	n, err = f.writer.Write(p)
 	atomic.AddInt64(&f.BytesReceived, int64(n))
 
-	if f.MaxWriteSize > 0 && err == nil && atomic.LoadInt64(&f.BytesReceived) > f.MaxWriteSize {
-		err = common.ErrQuotaExceeded
+	if err == nil {
+		err = f.CheckWrite()
 	}
 	if err != nil {
 		f.TransferError(err)
@@ -343,3 +391,151 @@ func (f *webDavFile) isTransfer() bool {
 	}
 	return true
 }
+
+// DeadProps implements webdav.DeadPropsHolder interface.
+// The dead properties stored in the data provider are returned together
+// with the quota properties for directories
+func (f *webDavFile) DeadProps() (map[xml.Name]webdav.Property, error) {
+	props := make(map[xml.Name]webdav.Property)
+	if f.GetType() == common.TransferDownload {
+		if err := f.updateStatInfo(); err == nil && f.info.IsDir() {
+			f.addQuotaProps(props)
+		}
+	}
+	stored, err := dataprovider.GetWebDAVProperties(f.Connection.User.Username, f.GetVirtualPath())
+	if err != nil {
+		f.Connection.Log(logger.LevelWarn, "unable to get dead properties for path %#v: %v", f.GetVirtualPath(), err)
+		return props, nil
+	}
+	for _, p := range stored.Properties {
+		name := xml.Name{Space: p.Namespace, Local: p.Name}
+		props[name] = webdav.Property{
+			XMLName:  name,
+			Lang:     p.Lang,
+			InnerXML: []byte(p.InnerXML),
+		}
+	}
+	return props, nil
+}
+
+// Patch implements webdav.DeadPropsHolder interface.
+// Patching is atomic, either all or no patches succeed
+func (f *webDavFile) Patch(patches []webdav.Proppatch) ([]webdav.Propstat, error) {
+	if isProtectedPropPatched(patches) {
+		pstatForbidden := webdav.Propstat{
+			Status:   http.StatusForbidden,
+			XMLError: `<D:cannot-modify-protected-property xmlns:D="DAV:"/>`,
+		}
+		pstatFailedDep := webdav.Propstat{
+			Status: webdav.StatusFailedDependency,
+		}
+		for _, patch := range patches {
+			for _, p := range patch.Props {
+				if isProtectedProp(p.XMLName) {
+					pstatForbidden.Props = append(pstatForbidden.Props, webdav.Property{XMLName: p.XMLName})
+				} else {
+					pstatFailedDep.Props = append(pstatFailedDep.Props, webdav.Property{XMLName: p.XMLName})
+				}
+			}
+		}
+		return []webdav.Propstat{pstatForbidden, pstatFailedDep}, nil
+	}
+	pstat := webdav.Propstat{Status: http.StatusOK}
+	for _, patch := range patches {
+		for _, p := range patch.Props {
+			pstat.Props = append(pstat.Props, webdav.Property{XMLName: p.XMLName})
+		}
+	}
+	parentPath := path.Dir(f.GetVirtualPath())
+	if !f.Connection.User.HasPerm(dataprovider.PermUpload, parentPath) &&
+		!f.Connection.User.HasPerm(dataprovider.PermOverwrite, parentPath) {
+		pstat.Status = http.StatusForbidden
+		return []webdav.Propstat{pstat}, nil
+	}
+	stored, err := dataprovider.GetWebDAVProperties(f.Connection.User.Username, f.GetVirtualPath())
+	if err != nil {
+		f.Connection.Log(logger.LevelWarn, "unable to get dead properties for path %#v: %v", f.GetVirtualPath(), err)
+		return nil, err
+	}
+	for _, patch := range patches {
+		for _, p := range patch.Props {
+			stored.Properties = removeDeadProp(stored.Properties, p.XMLName)
+			if !patch.Remove {
+				stored.Properties = append(stored.Properties, dataprovider.WebDAVProperty{
+					Namespace: p.XMLName.Space,
+					Name:      p.XMLName.Local,
+					Lang:      p.Lang,
+					InnerXML:  string(p.InnerXML),
+				})
+			}
+		}
+	}
+	if err := dataprovider.SetWebDAVProperties(&stored); err != nil {
+		f.Connection.Log(logger.LevelWarn, "unable to save dead properties for path %#v: %v", f.GetVirtualPath(), err)
+		return nil, err
+	}
+	return []webdav.Propstat{pstat}, nil
+}
+
+// addQuotaProps adds the RFC 4331 quota properties. The quota is checked as for
+// a file inside the directory, the same way we do for the SFTP statvfs extension
+func (f *webDavFile) addQuotaProps(props map[xml.Name]webdav.Property) {
+	virtualPath := f.GetVirtualPath()
+	cacheKey := ""
+	if vfolder, err := f.Connection.User.GetVirtualFolderForPath(virtualPath); err == nil && !vfolder.IsIncludedInUserQuota() {
+		cacheKey = vfolder.Name
+	}
+	quotaResult, ok := f.quotas.get(cacheKey)
+	if !ok {
+		quotaResult = f.Connection.HasSpace(false, true, path.Join(virtualPath, "fakefile.txt"))
+		f.quotas.add(cacheKey, quotaResult)
+	}
+	if dataprovider.GetQuotaTracking() > 0 {
+		props[quotaUsedBytesPropName] = webdav.Property{
+			XMLName:  quotaUsedBytesPropName,
+			InnerXML: []byte(strconv.FormatInt(quotaResult.UsedSize, 10)),
+		}
+	}
+	if quotaResult.QuotaSize > 0 {
+		available := quotaResult.QuotaSize - quotaResult.UsedSize
+		if available < 0 {
+			available = 0
+		}
+		props[quotaAvailableBytesPropName] = webdav.Property{
+			XMLName:  quotaAvailableBytesPropName,
+			InnerXML: []byte(strconv.FormatInt(available, 10)),
+		}
+		return
+	}
+	if statvfs, err := f.Fs.GetAvailableDiskSize(f.GetFsPath()); err == nil {
+		props[quotaAvailableBytesPropName] = webdav.Property{
+			XMLName:  quotaAvailableBytesPropName,
+			InnerXML: []byte(strconv.FormatUint(statvfs.FreeSpace(), 10)),
+		}
+	}
+}
+
+func isProtectedProp(name xml.Name) bool {
+	return name == quotaAvailableBytesPropName || name == quotaUsedBytesPropName
+}
+
+func isProtectedPropPatched(patches []webdav.Proppatch) bool {
+	for _, patch := range patches {
+		for _, p := range patch.Props {
+			if isProtectedProp(p.XMLName) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func removeDeadProp(props []dataprovider.WebDAVProperty, name xml.Name) []dataprovider.WebDAVProperty {
+	result := props[:0]
+	for _, p := range props {
+		if p.Namespace != name.Space || p.Name != name.Local {
+			result = append(result, p)
+		}
+	}
+	return result
+}
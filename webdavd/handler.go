@@ -21,6 +21,7 @@ import (
 type Connection struct {
 	*common.BaseConnection
 	request *http.Request
+	quotas  *quotaCache
 }
 
 // GetClientVersion returns the connected client's version.
@@ -151,9 +152,11 @@ func (c *Connection) getFile(fs vfs.Fs, fsPath, virtualPath string) (webdav.File
 	}
 
 	baseTransfer := common.NewBaseTransfer(file, c.BaseConnection, cancelFn, fsPath, fsPath, virtualPath, common.TransferDownload,
-		0, 0, 0, false, fs)
+		0, 0, 0, false, fs, c.GetTransferQuota(virtualPath))
+	f := newWebDavFile(baseTransfer, nil, r)
+	f.quotas = c.quotas
 
-	return newWebDavFile(baseTransfer, nil, r), nil
+	return f, nil
 }
 
 func (c *Connection) putFile(fs vfs.Fs, fsPath, virtualPath string) (webdav.File, error) {
@@ -199,6 +202,11 @@ func (c *Connection) handleUploadToNewFile(fs vfs.Fs, resolvedPath, filePath, re
 		c.Log(logger.LevelInfo, "denying file write due to quota limits")
 		return nil, common.ErrQuotaExceeded
 	}
+	transferQuota := c.GetTransferQuota(requestPath)
+	if !transferQuota.HasUploadSpace() {
+		c.Log(logger.LevelInfo, "denying file write due to transfer quota limits")
+		return nil, common.ErrQuotaExceeded
+	}
 	if err := common.ExecutePreAction(&c.User, common.OperationPreUpload, resolvedPath, requestPath, c.GetProtocol(), c.GetRemoteIP(), 0, 0); err != nil {
 		c.Log(logger.LevelDebug, "upload for file %#v denied by pre action: %v", requestPath, err)
 		return nil, c.GetPermissionDeniedError()
@@ -215,7 +223,7 @@ func (c *Connection) handleUploadToNewFile(fs vfs.Fs, resolvedPath, filePath, re
 	maxWriteSize, _ := c.GetMaxWriteSize(quotaResult, false, 0, fs.IsUploadResumeSupported())
 
 	baseTransfer := common.NewBaseTransfer(file, c.BaseConnection, cancelFn, resolvedPath, filePath, requestPath,
-		common.TransferUpload, 0, 0, maxWriteSize, true, fs)
+		common.TransferUpload, 0, 0, maxWriteSize, true, fs, transferQuota)
 
 	return newWebDavFile(baseTransfer, w, nil), nil
 }
@@ -228,6 +236,11 @@ func (c *Connection) handleUploadToExistingFile(fs vfs.Fs, resolvedPath, filePat
 		c.Log(logger.LevelInfo, "denying file write due to quota limits")
 		return nil, common.ErrQuotaExceeded
 	}
+	transferQuota := c.GetTransferQuota(requestPath)
+	if !transferQuota.HasUploadSpace() {
+		c.Log(logger.LevelInfo, "denying file write due to transfer quota limits")
+		return nil, common.ErrQuotaExceeded
+	}
 	if err := common.ExecutePreAction(&c.User, common.OperationPreUpload, resolvedPath, requestPath, c.GetProtocol(), c.GetRemoteIP(),
 		fileSize, os.O_TRUNC); err != nil {
 		c.Log(logger.LevelDebug, "upload for file %#v denied by pre action: %v", requestPath, err)
@@ -270,7 +283,7 @@ func (c *Connection) handleUploadToExistingFile(fs vfs.Fs, resolvedPath, filePat
 	vfs.SetPathPermissions(fs, filePath, c.User.GetUID(), c.User.GetGID())
 
 	baseTransfer := common.NewBaseTransfer(file, c.BaseConnection, cancelFn, resolvedPath, filePath, requestPath,
-		common.TransferUpload, 0, initialSize, maxWriteSize, false, fs)
+		common.TransferUpload, 0, initialSize, maxWriteSize, false, fs, transferQuota)
 
 	return newWebDavFile(baseTransfer, w, nil), nil
 }
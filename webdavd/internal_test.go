@@ -4,6 +4,7 @@ import (
 	"context"
 	"crypto/tls"
 	"crypto/x509"
+	"encoding/xml"
 	"errors"
 	"fmt"
 	"io"
@@ -13,6 +14,7 @@ import (
 	"path"
 	"path/filepath"
 	"runtime"
+	"strings"
 	"testing"
 	"time"
 
@@ -695,7 +697,7 @@ func TestContentType(t *testing.T) {
 	testFilePath := filepath.Join(user.HomeDir, testFile)
 	ctx := context.Background()
 	baseTransfer := common.NewBaseTransfer(nil, connection.BaseConnection, nil, testFilePath, testFilePath, testFile,
-		common.TransferDownload, 0, 0, 0, false, fs)
+		common.TransferDownload, 0, 0, 0, false, fs, dataprovider.TransferQuota{})
 	fs = newMockOsFs(nil, false, fs.ConnectionID(), user.GetHomeDir(), nil)
 	err := os.WriteFile(testFilePath, []byte(""), os.ModePerm)
 	assert.NoError(t, err)
@@ -745,7 +747,7 @@ func TestTransferReadWriteErrors(t *testing.T) {
 	}
 	testFilePath := filepath.Join(user.HomeDir, testFile)
 	baseTransfer := common.NewBaseTransfer(nil, connection.BaseConnection, nil, testFilePath, testFilePath, testFile,
-		common.TransferUpload, 0, 0, 0, false, fs)
+		common.TransferUpload, 0, 0, 0, false, fs, dataprovider.TransferQuota{})
 	davFile := newWebDavFile(baseTransfer, nil, nil)
 	p := make([]byte, 1)
 	_, err := davFile.Read(p)
@@ -763,7 +765,7 @@ func TestTransferReadWriteErrors(t *testing.T) {
 	assert.NoError(t, err)
 
 	baseTransfer = common.NewBaseTransfer(nil, connection.BaseConnection, nil, testFilePath, testFilePath, testFile,
-		common.TransferDownload, 0, 0, 0, false, fs)
+		common.TransferDownload, 0, 0, 0, false, fs, dataprovider.TransferQuota{})
 	davFile = newWebDavFile(baseTransfer, nil, nil)
 	_, err = davFile.Read(p)
 	assert.True(t, os.IsNotExist(err))
@@ -771,7 +773,7 @@ func TestTransferReadWriteErrors(t *testing.T) {
 	assert.True(t, os.IsNotExist(err))
 
 	baseTransfer = common.NewBaseTransfer(nil, connection.BaseConnection, nil, testFilePath, testFilePath, testFile,
-		common.TransferDownload, 0, 0, 0, false, fs)
+		common.TransferDownload, 0, 0, 0, false, fs, dataprovider.TransferQuota{})
 	err = os.WriteFile(testFilePath, []byte(""), os.ModePerm)
 	assert.NoError(t, err)
 	f, err := os.Open(testFilePath)
@@ -796,7 +798,7 @@ func TestTransferReadWriteErrors(t *testing.T) {
 	assert.NoError(t, err)
 	mockFs := newMockOsFs(nil, false, fs.ConnectionID(), user.HomeDir, r)
 	baseTransfer = common.NewBaseTransfer(nil, connection.BaseConnection, nil, testFilePath, testFilePath, testFile,
-		common.TransferDownload, 0, 0, 0, false, mockFs)
+		common.TransferDownload, 0, 0, 0, false, mockFs, dataprovider.TransferQuota{})
 	davFile = newWebDavFile(baseTransfer, nil, nil)
 
 	writeContent := []byte("content\r\n")
@@ -816,7 +818,7 @@ func TestTransferReadWriteErrors(t *testing.T) {
 	assert.NoError(t, err)
 
 	baseTransfer = common.NewBaseTransfer(nil, connection.BaseConnection, nil, testFilePath, testFilePath, testFile,
-		common.TransferDownload, 0, 0, 0, false, fs)
+		common.TransferDownload, 0, 0, 0, false, fs, dataprovider.TransferQuota{})
 	davFile = newWebDavFile(baseTransfer, nil, nil)
 	davFile.writer = f
 	err = davFile.Close()
@@ -841,7 +843,7 @@ func TestTransferSeek(t *testing.T) {
 	testFilePath := filepath.Join(user.HomeDir, testFile)
 	testFileContents := []byte("content")
 	baseTransfer := common.NewBaseTransfer(nil, connection.BaseConnection, nil, testFilePath, testFilePath, testFile,
-		common.TransferUpload, 0, 0, 0, false, fs)
+		common.TransferUpload, 0, 0, 0, false, fs, dataprovider.TransferQuota{})
 	davFile := newWebDavFile(baseTransfer, nil, nil)
 	_, err := davFile.Seek(0, io.SeekStart)
 	assert.EqualError(t, err, common.ErrOpUnsupported.Error())
@@ -849,7 +851,7 @@ func TestTransferSeek(t *testing.T) {
 	assert.NoError(t, err)
 
 	baseTransfer = common.NewBaseTransfer(nil, connection.BaseConnection, nil, testFilePath, testFilePath, testFile,
-		common.TransferDownload, 0, 0, 0, false, fs)
+		common.TransferDownload, 0, 0, 0, false, fs, dataprovider.TransferQuota{})
 	davFile = newWebDavFile(baseTransfer, nil, nil)
 	_, err = davFile.Seek(0, io.SeekCurrent)
 	assert.True(t, os.IsNotExist(err))
@@ -863,14 +865,14 @@ func TestTransferSeek(t *testing.T) {
 		assert.NoError(t, err)
 	}
 	baseTransfer = common.NewBaseTransfer(f, connection.BaseConnection, nil, testFilePath, testFilePath, testFile,
-		common.TransferDownload, 0, 0, 0, false, fs)
+		common.TransferDownload, 0, 0, 0, false, fs, dataprovider.TransferQuota{})
 	davFile = newWebDavFile(baseTransfer, nil, nil)
 	_, err = davFile.Seek(0, io.SeekStart)
 	assert.Error(t, err)
 	davFile.Connection.RemoveTransfer(davFile.BaseTransfer)
 
 	baseTransfer = common.NewBaseTransfer(nil, connection.BaseConnection, nil, testFilePath, testFilePath, testFile,
-		common.TransferDownload, 0, 0, 0, false, fs)
+		common.TransferDownload, 0, 0, 0, false, fs, dataprovider.TransferQuota{})
 	davFile = newWebDavFile(baseTransfer, nil, nil)
 	res, err := davFile.Seek(0, io.SeekStart)
 	assert.NoError(t, err)
@@ -885,14 +887,14 @@ func TestTransferSeek(t *testing.T) {
 	assert.Nil(t, err)
 
 	baseTransfer = common.NewBaseTransfer(nil, connection.BaseConnection, nil, testFilePath+"1", testFilePath+"1", testFile,
-		common.TransferDownload, 0, 0, 0, false, fs)
+		common.TransferDownload, 0, 0, 0, false, fs, dataprovider.TransferQuota{})
 	davFile = newWebDavFile(baseTransfer, nil, nil)
 	_, err = davFile.Seek(0, io.SeekEnd)
 	assert.True(t, os.IsNotExist(err))
 	davFile.Connection.RemoveTransfer(davFile.BaseTransfer)
 
 	baseTransfer = common.NewBaseTransfer(nil, connection.BaseConnection, nil, testFilePath, testFilePath, testFile,
-		common.TransferDownload, 0, 0, 0, false, fs)
+		common.TransferDownload, 0, 0, 0, false, fs, dataprovider.TransferQuota{})
 	davFile = newWebDavFile(baseTransfer, nil, nil)
 	davFile.reader = f
 	davFile.Fs = newMockOsFs(nil, true, fs.ConnectionID(), user.GetHomeDir(), nil)
@@ -907,7 +909,7 @@ func TestTransferSeek(t *testing.T) {
 	assert.Equal(t, int64(5), res)
 
 	baseTransfer = common.NewBaseTransfer(nil, connection.BaseConnection, nil, testFilePath+"1", testFilePath+"1", testFile,
-		common.TransferDownload, 0, 0, 0, false, fs)
+		common.TransferDownload, 0, 0, 0, false, fs, dataprovider.TransferQuota{})
 
 	davFile = newWebDavFile(baseTransfer, nil, nil)
 	davFile.Fs = newMockOsFs(nil, true, fs.ConnectionID(), user.GetHomeDir(), nil)
@@ -1541,3 +1543,188 @@ func TestMisc(t *testing.T) {
 
 	certMgr = oldCertMgr
 }
+
+func TestProviderLockSystem(t *testing.T) {
+	username := "lock_user"
+	now := time.Now()
+	ls := getLockSystem(LockSystemProvider, username)
+	assert.IsType(t, &providerLockSystem{}, ls)
+	assert.IsType(t, webdav.NewMemLS(), getLockSystem(LockSystemMemory, username))
+
+	token, err := ls.Create(now, webdav.LockDetails{
+		Root:     "/dir",
+		Duration: time.Minute,
+		OwnerXML: "<owner>test</owner>",
+	})
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(token, "opaquelocktoken:"))
+	// a second lock system for the same user, as another instance would use, shares the locks
+	ls1 := newProviderLockSystem(username)
+	_, err = ls1.Create(now, webdav.LockDetails{Root: "/dir", Duration: time.Minute})
+	assert.ErrorIs(t, err, webdav.ErrLocked)
+	_, err = ls1.Create(now, webdav.LockDetails{Root: "/dir/sub/file", Duration: time.Minute, ZeroDepth: true})
+	assert.ErrorIs(t, err, webdav.ErrLocked)
+	_, err = ls1.Create(now, webdav.LockDetails{Root: "/", Duration: time.Minute})
+	assert.ErrorIs(t, err, webdav.ErrLocked)
+	// locks are per user
+	token1, err := newProviderLockSystem(username+"1").Create(now, webdav.LockDetails{Root: "/dir", Duration: -1})
+	assert.NoError(t, err)
+
+	_, err = ls1.Confirm(now, "/dir/file", "")
+	assert.ErrorIs(t, err, webdav.ErrConfirmationFailed)
+	_, err = ls1.Confirm(now, "/dir/file", "", webdav.Condition{Token: token1})
+	assert.ErrorIs(t, err, webdav.ErrConfirmationFailed)
+	release, err := ls1.Confirm(now, "/dir/file", "/dir/file1", webdav.Condition{Token: token})
+	assert.NoError(t, err)
+	// the lock is held
+	_, err = ls.Confirm(now, "/dir", "", webdav.Condition{Token: token})
+	assert.ErrorIs(t, err, webdav.ErrConfirmationFailed)
+	_, err = ls.Refresh(now, token, time.Minute)
+	assert.ErrorIs(t, err, webdav.ErrLocked)
+	err = ls.Unlock(now, token)
+	assert.ErrorIs(t, err, webdav.ErrLocked)
+	release()
+
+	details, err := ls1.Refresh(now, token, 2*time.Minute)
+	assert.NoError(t, err)
+	assert.Equal(t, "/dir", details.Root)
+	assert.Equal(t, 2*time.Minute, details.Duration)
+	assert.Equal(t, "<owner>test</owner>", details.OwnerXML)
+	_, err = ls1.Refresh(now, token1, time.Minute)
+	assert.ErrorIs(t, err, webdav.ErrNoSuchLock)
+	// the lock is expired
+	_, err = ls.Confirm(now.Add(3*time.Minute), "/dir", "", webdav.Condition{Token: token})
+	assert.ErrorIs(t, err, webdav.ErrConfirmationFailed)
+	err = ls.Unlock(now.Add(3*time.Minute), token)
+	assert.ErrorIs(t, err, webdav.ErrNoSuchLock)
+	// an expired lock for the same resource is replaced
+	token2, err := ls.Create(now.Add(3*time.Minute), webdav.LockDetails{Root: "/dir", Duration: time.Minute, ZeroDepth: true})
+	assert.NoError(t, err)
+	_, err = dataprovider.GetWebDAVLock(token)
+	assert.Error(t, err)
+	// zero depth locks don't apply to descendants
+	_, err = ls.Confirm(now.Add(3*time.Minute), "/dir/file", "", webdav.Condition{Token: token2})
+	assert.ErrorIs(t, err, webdav.ErrConfirmationFailed)
+	token3, err := ls.Create(now.Add(3*time.Minute), webdav.LockDetails{Root: "/dir/file", Duration: time.Minute})
+	assert.NoError(t, err)
+	locks, err := dataprovider.GetWebDAVLocks(username, now.Add(3*time.Minute))
+	assert.NoError(t, err)
+	assert.Len(t, locks, 2)
+
+	for _, tok := range []string{token2, token3} {
+		err = ls.Unlock(now, tok)
+		assert.NoError(t, err)
+		err = ls.Unlock(now, tok)
+		assert.ErrorIs(t, err, webdav.ErrNoSuchLock)
+	}
+	err = dataprovider.DeleteWebDAVLock(token1)
+	assert.NoError(t, err)
+	locks, err = dataprovider.GetWebDAVLocks(username, now)
+	assert.NoError(t, err)
+	assert.Len(t, locks, 0)
+}
+
+func TestCanCreateLock(t *testing.T) {
+	locks := []dataprovider.WebDAVLock{
+		{
+			Token:     "t1",
+			Root:      "/a/b",
+			ZeroDepth: true,
+		},
+	}
+	assert.False(t, canCreateLock(locks, "/a/b", true, ""))
+	assert.True(t, canCreateLock(locks, "/a/b", true, "t1"))
+	assert.True(t, canCreateLock(locks, "/a/b/c", false, ""))
+	assert.True(t, canCreateLock(locks, "/a", true, ""))
+	assert.False(t, canCreateLock(locks, "/a", false, ""))
+	assert.False(t, canCreateLock(locks, "/", false, ""))
+	assert.True(t, canCreateLock(locks, "/a/bc", false, ""))
+	locks[0].ZeroDepth = false
+	assert.False(t, canCreateLock(locks, "/a/b/c", true, ""))
+	assert.True(t, canCreateLock(locks, "/a/bc", true, ""))
+	assert.True(t, isLockDescendant("/a", "/"))
+	assert.False(t, isLockDescendant("/", "/"))
+}
+
+func TestDeadPropsHolder(t *testing.T) {
+	user := dataprovider.User{
+		BaseUser: sdk.BaseUser{
+			Username: "dead_props_user",
+			HomeDir:  filepath.Clean(os.TempDir()),
+		},
+	}
+	user.Permissions = make(map[string][]string)
+	user.Permissions["/"] = []string{dataprovider.PermListItems, dataprovider.PermDownload}
+	fs := vfs.NewOsFs("connID", user.HomeDir, "")
+	connection := &Connection{
+		BaseConnection: common.NewBaseConnection(fs.ConnectionID(), common.ProtocolWebDAV, "", "", user),
+	}
+	dirPath := filepath.Join(user.HomeDir, "dead_props_dir")
+	err := os.MkdirAll(dirPath, os.ModePerm)
+	assert.NoError(t, err)
+	f, err := connection.getFile(fs, dirPath, "/dead_props_dir")
+	assert.NoError(t, err)
+	davFile, ok := f.(*webDavFile)
+	if assert.True(t, ok) {
+		assert.Nil(t, davFile.quotas)
+		props, err := davFile.DeadProps()
+		assert.NoError(t, err)
+		assert.Contains(t, props, quotaAvailableBytesPropName)
+
+		propName := xml.Name{Space: "urn:test:", Local: "color"}
+		patches := []webdav.Proppatch{
+			{
+				Props: []webdav.Property{{XMLName: propName, InnerXML: []byte("blue")}},
+			},
+		}
+		pstats, err := davFile.Patch(patches)
+		assert.NoError(t, err)
+		if assert.Len(t, pstats, 1) {
+			assert.Equal(t, http.StatusForbidden, pstats[0].Status)
+		}
+		connection.User.Permissions["/"] = []string{dataprovider.PermAny}
+		pstats, err = davFile.Patch(patches)
+		assert.NoError(t, err)
+		if assert.Len(t, pstats, 1) {
+			assert.Equal(t, http.StatusOK, pstats[0].Status)
+		}
+		props, err = davFile.DeadProps()
+		assert.NoError(t, err)
+		if assert.Contains(t, props, propName) {
+			assert.Equal(t, []byte("blue"), props[propName].InnerXML)
+		}
+		patches[0].Remove = true
+		pstats, err = davFile.Patch(patches)
+		assert.NoError(t, err)
+		if assert.Len(t, pstats, 1) {
+			assert.Equal(t, http.StatusOK, pstats[0].Status)
+		}
+		props, err = davFile.DeadProps()
+		assert.NoError(t, err)
+		assert.NotContains(t, props, propName)
+	}
+	err = f.Close()
+	assert.NoError(t, err)
+	err = os.Remove(dirPath)
+	assert.NoError(t, err)
+
+	cache := newQuotaCache()
+	_, ok = cache.get("")
+	assert.False(t, ok)
+	cache.add("", vfs.QuotaCheckResult{QuotaSize: 10})
+	result, ok := cache.get("")
+	assert.True(t, ok)
+	assert.Equal(t, int64(10), result.QuotaSize)
+	cache = nil
+	cache.add("", vfs.QuotaCheckResult{})
+	_, ok = cache.get("")
+	assert.False(t, ok)
+
+	props := removeDeadProp([]dataprovider.WebDAVProperty{
+		{Namespace: "a", Name: "b"},
+		{Namespace: "c", Name: "b"},
+	}, xml.Name{Space: "a", Local: "b"})
+	if assert.Len(t, props, 1) {
+		assert.Equal(t, "c", props[0].Namespace)
+	}
+}
@@ -0,0 +1,280 @@
+package webdavd
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/webdav"
+
+	"github.com/drakkan/sftpgo/v2/dataprovider"
+	"github.com/drakkan/sftpgo/v2/logger"
+	"github.com/drakkan/sftpgo/v2/util"
+)
+
+// Supported lock systems
+const (
+	LockSystemMemory   = "memory"
+	LockSystemProvider = "provider"
+)
+
+var (
+	supportedLockSystems = []string{LockSystemMemory, LockSystemProvider}
+	heldLocks            = heldLockTokens{
+		tokens: make(map[string]bool),
+	}
+)
+
+// heldLockTokens tracks the lock tokens confirmed, and not yet released, by this instance.
+// A lock is held while a request using it is in progress
+type heldLockTokens struct {
+	sync.Mutex
+	tokens map[string]bool
+}
+
+func (h *heldLockTokens) isHeld(token string) bool {
+	h.Lock()
+	defer h.Unlock()
+
+	return h.tokens[token]
+}
+
+// hold marks the given tokens as held, it returns false if at least one of them is already held
+func (h *heldLockTokens) hold(tokens ...string) bool {
+	h.Lock()
+	defer h.Unlock()
+
+	for _, token := range tokens {
+		if h.tokens[token] {
+			return false
+		}
+	}
+	for _, token := range tokens {
+		h.tokens[token] = true
+	}
+	return true
+}
+
+func (h *heldLockTokens) release(tokens ...string) {
+	h.Lock()
+	defer h.Unlock()
+
+	for _, token := range tokens {
+		delete(h.tokens, token)
+	}
+}
+
+// providerLockSystem is a webdav.LockSystem that stores the locks in the data provider,
+// so they are shared between the SFTPGo instances using the same data provider.
+// The locking semantics are the same as the in memory lock system
+type providerLockSystem struct {
+	username string
+}
+
+func newProviderLockSystem(username string) webdav.LockSystem {
+	return &providerLockSystem{
+		username: username,
+	}
+}
+
+func (ls *providerLockSystem) Confirm(now time.Time, name0, name1 string, conditions ...webdav.Condition) (func(), error) {
+	var tokens []string
+
+	for _, name := range []string{name0, name1} {
+		if name == "" {
+			continue
+		}
+		lock, err := ls.lookup(now, util.CleanPath(name), conditions...)
+		if err != nil {
+			return nil, err
+		}
+		if lock == nil {
+			return nil, webdav.ErrConfirmationFailed
+		}
+		if !util.IsStringInSlice(lock.Token, tokens) {
+			tokens = append(tokens, lock.Token)
+		}
+	}
+	if !heldLocks.hold(tokens...) {
+		return nil, webdav.ErrConfirmationFailed
+	}
+
+	return func() {
+		heldLocks.release(tokens...)
+	}, nil
+}
+
+func (ls *providerLockSystem) Create(now time.Time, details webdav.LockDetails) (string, error) {
+	details.Root = util.CleanPath(details.Root)
+	locks, err := dataprovider.GetWebDAVLocks(ls.username, now)
+	if err != nil {
+		return "", err
+	}
+	if !canCreateLock(locks, details.Root, details.ZeroDepth, "") {
+		return "", webdav.ErrLocked
+	}
+	lock := dataprovider.WebDAVLock{
+		Token:     generateLockToken(),
+		Username:  ls.username,
+		Root:      details.Root,
+		OwnerXML:  details.OwnerXML,
+		ZeroDepth: details.ZeroDepth,
+	}
+	lock.Refresh(now, details.Duration)
+	if err := dataprovider.AddWebDAVLock(&lock); err != nil {
+		// another instance could have locked the same resource in the meantime
+		if locks, errGet := dataprovider.GetWebDAVLocks(ls.username, now); errGet == nil {
+			if !canCreateLock(locks, details.Root, details.ZeroDepth, "") {
+				return "", webdav.ErrLocked
+			}
+		}
+		logger.Warn(logSender, "", "unable to add WebDAV lock for user %#v, root %#v: %v", ls.username, lock.Root, err)
+		return "", err
+	}
+	// a conflicting lock for a parent or a child resource could have been added by
+	// another instance in the meantime, we check again and back off if so
+	locks, err = dataprovider.GetWebDAVLocks(ls.username, now)
+	if err == nil && !canCreateLock(locks, lock.Root, lock.ZeroDepth, lock.Token) {
+		dataprovider.DeleteWebDAVLock(lock.Token) //nolint:errcheck
+		return "", webdav.ErrLocked
+	}
+
+	return lock.Token, nil
+}
+
+func (ls *providerLockSystem) Refresh(now time.Time, token string, duration time.Duration) (webdav.LockDetails, error) {
+	lock, err := ls.getLock(now, token)
+	if err != nil {
+		return webdav.LockDetails{}, err
+	}
+	if heldLocks.isHeld(token) {
+		return webdav.LockDetails{}, webdav.ErrLocked
+	}
+	lock.Refresh(now, duration)
+	if err := dataprovider.UpdateWebDAVLock(&lock); err != nil {
+		if _, ok := err.(*util.RecordNotFoundError); ok {
+			return webdav.LockDetails{}, webdav.ErrNoSuchLock
+		}
+		return webdav.LockDetails{}, err
+	}
+
+	return getLockDetails(&lock), nil
+}
+
+func (ls *providerLockSystem) Unlock(now time.Time, token string) error {
+	if _, err := ls.getLock(now, token); err != nil {
+		return err
+	}
+	if heldLocks.isHeld(token) {
+		return webdav.ErrLocked
+	}
+	if err := dataprovider.DeleteWebDAVLock(token); err != nil {
+		if _, ok := err.(*util.RecordNotFoundError); ok {
+			return webdav.ErrNoSuchLock
+		}
+		return err
+	}
+	return nil
+}
+
+// lookup returns the first lock, identified by the given conditions, that applies to the given name.
+// A nil lock is returned if no lock matches
+func (ls *providerLockSystem) lookup(now time.Time, name string, conditions ...webdav.Condition) (*dataprovider.WebDAVLock, error) {
+	for _, c := range conditions {
+		if c.Token == "" {
+			continue
+		}
+		lock, err := ls.getLock(now, c.Token)
+		if err != nil {
+			if errors.Is(err, webdav.ErrNoSuchLock) {
+				continue
+			}
+			return nil, err
+		}
+		if heldLocks.isHeld(lock.Token) {
+			continue
+		}
+		if lock.Root == name {
+			return &lock, nil
+		}
+		if !lock.ZeroDepth && isLockDescendant(name, lock.Root) {
+			return &lock, nil
+		}
+	}
+	return nil, nil
+}
+
+// getLock returns the not expired lock with the given token for the lock system user
+func (ls *providerLockSystem) getLock(now time.Time, token string) (dataprovider.WebDAVLock, error) {
+	lock, err := dataprovider.GetWebDAVLock(token)
+	if err != nil {
+		if _, ok := err.(*util.RecordNotFoundError); ok {
+			return lock, webdav.ErrNoSuchLock
+		}
+		return lock, err
+	}
+	if lock.Username != ls.username || lock.IsExpired(now) {
+		return lock, webdav.ErrNoSuchLock
+	}
+	return lock, nil
+}
+
+// canCreateLock returns true if a lock for the given name can be created.
+// The lock with the excluded token, if any, is ignored
+func canCreateLock(locks []dataprovider.WebDAVLock, name string, zeroDepth bool, excludedToken string) bool {
+	for _, lock := range locks {
+		if lock.Token == excludedToken {
+			continue
+		}
+		if lock.Root == name {
+			return false
+		}
+		// an infinite depth lock is not allowed if a descendant is locked
+		if !zeroDepth && isLockDescendant(lock.Root, name) {
+			return false
+		}
+		// an ancestor with an infinite depth lock applies to the requested name
+		if !lock.ZeroDepth && isLockDescendant(name, lock.Root) {
+			return false
+		}
+	}
+	return true
+}
+
+// isLockDescendant returns true if name is a descendant of root, both paths must be cleaned
+func isLockDescendant(name, root string) bool {
+	if name == root {
+		return false
+	}
+	if root == "/" {
+		return true
+	}
+	return strings.HasPrefix(name, root+"/")
+}
+
+func getLockDetails(lock *dataprovider.WebDAVLock) webdav.LockDetails {
+	return webdav.LockDetails{
+		Root:      lock.Root,
+		Duration:  lock.GetDuration(),
+		OwnerXML:  lock.OwnerXML,
+		ZeroDepth: lock.ZeroDepth,
+	}
+}
+
+// generateLockToken returns a random lock token as absolute URI, as required by RFC 4918
+func generateLockToken() string {
+	b := util.GenerateRandomBytes(16)
+	// set version 4 and variant bits as for a random UUID
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("opaquelocktoken:%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
+
+func getLockSystem(lockSystem, username string) webdav.LockSystem {
+	if lockSystem == LockSystemProvider {
+		return newProviderLockSystem(username)
+	}
+	return webdav.NewMemLS()
+}
@@ -204,6 +204,7 @@ func (s *webDavServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		BaseConnection: common.NewBaseConnection(connectionID, common.ProtocolWebDAV, util.GetHTTPLocalAddress(r),
 			r.RemoteAddr, user),
 		request: r,
+		quotas:  newQuotaCache(),
 	}
 	common.Connections.Add(connection)
 	defer common.Connections.Remove(connection.GetID())
@@ -265,9 +266,14 @@ func (s *webDavServer) authenticate(r *http.Request, ip string) (dataprovider.Us
 				tlsCert = nil
 				loginMethod = dataprovider.LoginMethodPassword
 			}
-			if err := dataprovider.CheckCachedUserCredentials(cachedUser, password, loginMethod, common.ProtocolWebDAV, tlsCert); err == nil {
+			err = dataprovider.CheckCachedUserCredentials(cachedUser, password, loginMethod, common.ProtocolWebDAV, tlsCert)
+			if err == nil {
 				return cachedUser.User, true, cachedUser.LockSystem, loginMethod, nil
 			}
+			if errors.Is(err, dataprovider.ErrPasswordChangeRequired) {
+				updateLoginMetrics(&cachedUser.User, ip, loginMethod, err)
+				return user, false, nil, loginMethod, err
+			}
 			updateLoginMetrics(&cachedUser.User, ip, loginMethod, dataprovider.ErrInvalidCredentials)
 			return user, false, nil, loginMethod, dataprovider.ErrInvalidCredentials
 		}
@@ -277,9 +283,12 @@ func (s *webDavServer) authenticate(r *http.Request, ip string) (dataprovider.Us
 	if err != nil {
 		user.Username = username
 		updateLoginMetrics(&user, ip, loginMethod, err)
+		if errors.Is(err, dataprovider.ErrPasswordChangeRequired) {
+			return user, false, nil, loginMethod, err
+		}
 		return user, false, nil, loginMethod, dataprovider.ErrInvalidCredentials
 	}
-	lockSystem := webdav.NewMemLS()
+	lockSystem := getLockSystem(s.config.LockSystem, user.Username)
 	cachedUser = &dataprovider.CachedUser{
 		User:       user,
 		Password:   password,
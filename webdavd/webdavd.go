@@ -138,6 +138,12 @@ type Configuration struct {
 	Cors Cors `json:"cors" mapstructure:"cors"`
 	// Cache configuration
 	Cache Cache `json:"cache" mapstructure:"cache"`
+	// LockSystem defines where the WebDAV locks are stored. Supported values:
+	//	- "memory", locks are stored in memory, they are lost on restart and they are not
+	//	  shared between multiple SFTPGo instances
+	//	- "provider", locks are stored in the data provider, they are preserved across restarts
+	//	  and they are shared between the SFTPGo instances using the same data provider
+	LockSystem string `json:"lock_system" mapstructure:"lock_system"`
 }
 
 // GetStatus returns the server status
@@ -169,6 +175,12 @@ func (c *Configuration) Initialize(configDir string) error {
 	if !c.ShouldBind() {
 		return common.ErrNoBinding
 	}
+	if c.LockSystem == "" {
+		c.LockSystem = LockSystemMemory
+	}
+	if !util.IsStringInSlice(c.LockSystem, supportedLockSystems) {
+		return fmt.Errorf("unsupported lock system: %#v", c.LockSystem)
+	}
 
 	certificateFile := getConfigPath(c.CertificateFile, configDir)
 	certificateKeyFile := getConfigPath(c.CertificateKeyFile, configDir)
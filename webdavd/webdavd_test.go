@@ -808,6 +808,82 @@ func TestPropPatch(t *testing.T) {
 	assert.NoError(t, err)
 }
 
+func TestDeadProperties(t *testing.T) {
+	u := getTestUser()
+	u.QuotaSize = 1048576
+	user, _, err := httpdtest.AddUser(u, http.StatusCreated)
+	assert.NoError(t, err)
+	client := getWebDavClient(user, true, nil)
+	assert.NoError(t, checkBasicFunc(client))
+
+	testFilePath := filepath.Join(homeBasePath, testFileName)
+	testFileSize := int64(65535)
+	err = createTestFile(testFilePath, testFileSize)
+	assert.NoError(t, err)
+	err = uploadFile(testFilePath, testFileName, testFileSize, client)
+	assert.NoError(t, err)
+	httpClient := httpclient.GetHTTPClient()
+	doRequest := func(method, name, body string) (int, string) {
+		req, err := http.NewRequest(method, fmt.Sprintf("http://%v/%v", webDavServerAddr, name), bytes.NewReader([]byte(body)))
+		assert.NoError(t, err)
+		req.SetBasicAuth(u.Username, u.Password)
+		req.Header.Set("Depth", "0")
+		resp, err := httpClient.Do(req)
+		if !assert.NoError(t, err) {
+			return 0, ""
+		}
+		defer resp.Body.Close()
+		respBody, err := io.ReadAll(resp.Body)
+		assert.NoError(t, err)
+		return resp.StatusCode, string(respBody)
+	}
+	propatchBody := `<?xml version="1.0" encoding="utf-8" ?><D:propertyupdate xmlns:D="DAV:" xmlns:Z="urn:test:"><D:set><D:prop><Z:color>blue</Z:color></D:prop></D:set></D:propertyupdate>`
+	status, _ := doRequest("PROPPATCH", testFileName, propatchBody)
+	assert.Equal(t, http.StatusMultiStatus, status)
+	propfindBody := `<?xml version="1.0" encoding="utf-8" ?><D:propfind xmlns:D="DAV:" xmlns:Z="urn:test:"><D:prop><Z:color/></D:prop></D:propfind>`
+	status, body := doRequest("PROPFIND", testFileName, propfindBody)
+	assert.Equal(t, http.StatusMultiStatus, status)
+	assert.Contains(t, body, "blue")
+	assert.Contains(t, body, "200 OK")
+	// dead properties follow renames
+	testFileName1 := testFileName + "_renamed"
+	err = client.Rename(testFileName, testFileName1, false)
+	assert.NoError(t, err)
+	status, body = doRequest("PROPFIND", testFileName1, propfindBody)
+	assert.Equal(t, http.StatusMultiStatus, status)
+	assert.Contains(t, body, "blue")
+	props, err := dataprovider.GetWebDAVProperties(user.Username, "/"+testFileName)
+	assert.NoError(t, err)
+	assert.Len(t, props.Properties, 0)
+	// quota properties are reported for directories and cannot be modified
+	propfindBody = `<?xml version="1.0" encoding="utf-8" ?><D:propfind xmlns:D="DAV:"><D:prop><D:quota-available-bytes/><D:quota-used-bytes/></D:prop></D:propfind>`
+	status, body = doRequest("PROPFIND", "", propfindBody)
+	assert.Equal(t, http.StatusMultiStatus, status)
+	assert.Contains(t, body, fmt.Sprintf("%v</D:quota-available-bytes>", u.QuotaSize-testFileSize))
+	assert.Contains(t, body, fmt.Sprintf("%v</D:quota-used-bytes>", testFileSize))
+	propatchBody = `<?xml version="1.0" encoding="utf-8" ?><D:propertyupdate xmlns:D="DAV:" xmlns:Z="urn:test:"><D:set><D:prop><D:quota-used-bytes>0</D:quota-used-bytes><Z:color>red</Z:color></D:prop></D:set></D:propertyupdate>`
+	status, body = doRequest("PROPPATCH", "", propatchBody)
+	assert.Equal(t, http.StatusMultiStatus, status)
+	assert.Contains(t, body, "403 Forbidden")
+	assert.Contains(t, body, "424 Failed Dependency")
+	props, err = dataprovider.GetWebDAVProperties(user.Username, "/")
+	assert.NoError(t, err)
+	assert.Len(t, props.Properties, 0)
+	// dead properties are removed with the file
+	err = client.Remove(testFileName1)
+	assert.NoError(t, err)
+	props, err = dataprovider.GetWebDAVProperties(user.Username, "/"+testFileName1)
+	assert.NoError(t, err)
+	assert.Len(t, props.Properties, 0)
+
+	err = os.Remove(testFilePath)
+	assert.NoError(t, err)
+	_, err = httpdtest.RemoveUser(user, http.StatusOK)
+	assert.NoError(t, err)
+	err = os.RemoveAll(user.GetHomeDir())
+	assert.NoError(t, err)
+}
+
 func TestLoginInvalidPwd(t *testing.T) {
 	u := getTestUser()
 	user, _, err := httpdtest.AddUser(u, http.StatusCreated)
@@ -1745,6 +1821,217 @@ func TestSFTPBuffered(t *testing.T) {
 	assert.NoError(t, err)
 }
 
+func TestWebDAVFs(t *testing.T) {
+	u := getTestUser()
+	localUser, _, err := httpdtest.AddUser(u, http.StatusCreated)
+	assert.NoError(t, err)
+	for _, useTLS := range []bool{false, true} {
+		u = getTestWebDAVFsUser(useTLS)
+		u.QuotaFiles = 1000
+		webDAVFsUser, _, err := httpdtest.AddUser(u, http.StatusCreated)
+		assert.NoError(t, err)
+
+		client := getWebDavClient(webDAVFsUser, false, nil)
+		assert.NoError(t, checkBasicFunc(client))
+		testFilePath := filepath.Join(homeBasePath, testFileName)
+		testFileSize := int64(65535)
+		err = createTestFile(testFilePath, testFileSize)
+		assert.NoError(t, err)
+		err = uploadFile(testFilePath, testFileName, testFileSize, client)
+		assert.NoError(t, err)
+		// overwrite an existing file
+		err = uploadFile(testFilePath, testFileName, testFileSize, client)
+		assert.NoError(t, err)
+		// the file is stored inside the configured prefix
+		info, err := os.Stat(filepath.Join(localUser.GetHomeDir(), "webdavfs", testFileName))
+		if assert.NoError(t, err) {
+			assert.Equal(t, testFileSize, info.Size())
+		}
+		localDownloadPath := filepath.Join(homeBasePath, testDLFileName)
+		err = downloadFile(testFileName, localDownloadPath, testFileSize, client)
+		assert.NoError(t, err)
+		user, _, err := httpdtest.GetUserByUsername(webDAVFsUser.Username, http.StatusOK)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, user.UsedQuotaFiles)
+		assert.Equal(t, testFileSize, user.UsedQuotaSize)
+
+		err = client.Remove(testFileName)
+		assert.NoError(t, err)
+		fileContent := []byte("test file contents")
+		err = os.WriteFile(testFilePath, fileContent, os.ModePerm)
+		assert.NoError(t, err)
+		err = uploadFile(testFilePath, testFileName, int64(len(fileContent)), client)
+		assert.NoError(t, err)
+		// ranged downloads are mapped to ranged GET requests
+		remotePath := fmt.Sprintf("http://%v/%v", webDavServerAddr, testFileName)
+		req, err := http.NewRequest(http.MethodGet, remotePath, nil)
+		assert.NoError(t, err)
+		req.SetBasicAuth(webDAVFsUser.Username, defaultPassword)
+		req.Header.Set("Range", "bytes=5-")
+		resp, err := httpclient.GetHTTPClient().Do(req)
+		if assert.NoError(t, err) {
+			assert.Equal(t, http.StatusPartialContent, resp.StatusCode)
+			bodyBytes, err := io.ReadAll(resp.Body)
+			assert.NoError(t, err)
+			assert.Equal(t, "file contents", string(bodyBytes))
+			resp.Body.Close()
+		}
+
+		testDir := "testdir"
+		err = client.Mkdir(testDir, os.ModePerm)
+		assert.NoError(t, err)
+		err = client.MkdirAll(path.Join(testDir, "sub", "sub"), os.ModePerm)
+		assert.NoError(t, err)
+		err = client.Rename(testFileName, path.Join(testDir, testFileName), false)
+		assert.NoError(t, err)
+		_, err = client.Stat(testFileName)
+		assert.Error(t, err)
+		files, err := client.ReadDir(testDir)
+		assert.NoError(t, err)
+		if assert.Len(t, files, 2) {
+			for _, f := range files {
+				if f.Name() == testFileName {
+					assert.False(t, f.IsDir())
+					assert.Equal(t, int64(len(fileContent)), f.Size())
+				} else {
+					assert.Equal(t, "sub", f.Name())
+					assert.True(t, f.IsDir())
+				}
+			}
+		}
+		// directories are moved server side
+		err = client.Rename(testDir, testDir+"_renamed", false)
+		assert.NoError(t, err)
+		info, err = client.Stat(path.Join(testDir+"_renamed", "sub", "sub"))
+		if assert.NoError(t, err) {
+			assert.True(t, info.IsDir())
+		}
+		err = client.Remove(path.Join(testDir+"_renamed", "sub", "sub"))
+		assert.NoError(t, err)
+		_, err = client.Stat(path.Join(testDir+"_renamed", "sub", "sub"))
+		assert.Error(t, err)
+		err = client.RemoveAll(testDir + "_renamed")
+		assert.NoError(t, err)
+		user, _, err = httpdtest.GetUserByUsername(webDAVFsUser.Username, http.StatusOK)
+		assert.NoError(t, err)
+		assert.Equal(t, 0, user.UsedQuotaFiles)
+		assert.Equal(t, int64(0), user.UsedQuotaSize)
+		entries, err := os.ReadDir(filepath.Join(localUser.GetHomeDir(), "webdavfs"))
+		assert.NoError(t, err)
+		assert.Len(t, entries, 0)
+
+		err = os.Remove(testFilePath)
+		assert.NoError(t, err)
+		err = os.Remove(localDownloadPath)
+		assert.NoError(t, err)
+		_, err = httpdtest.RemoveUser(webDAVFsUser, http.StatusOK)
+		assert.NoError(t, err)
+		err = os.RemoveAll(webDAVFsUser.GetHomeDir())
+		assert.NoError(t, err)
+	}
+	// wrong credentials for the remote server
+	u = getTestWebDAVFsUser(false)
+	u.FsConfig.WebDAVConfig.Password = kms.NewPlainSecret("wrong password")
+	webDAVFsUser, _, err := httpdtest.AddUser(u, http.StatusCreated)
+	assert.NoError(t, err)
+	client := getWebDavClient(webDAVFsUser, false, nil)
+	assert.Error(t, checkBasicFunc(client))
+	_, err = httpdtest.RemoveUser(webDAVFsUser, http.StatusOK)
+	assert.NoError(t, err)
+	err = os.RemoveAll(webDAVFsUser.GetHomeDir())
+	assert.NoError(t, err)
+
+	_, err = httpdtest.RemoveUser(localUser, http.StatusOK)
+	assert.NoError(t, err)
+	err = os.RemoveAll(localUser.GetHomeDir())
+	assert.NoError(t, err)
+	assert.Len(t, common.Connections.GetStats(), 0)
+}
+
+func TestWebDAVFsVirtualFolder(t *testing.T) {
+	u := getTestUser()
+	localUser, _, err := httpdtest.AddUser(u, http.StatusCreated)
+	assert.NoError(t, err)
+	vdir := "/vdir"
+	folderName := "webdavfsfolder"
+	u = getTestUser()
+	u.Username += "_1"
+	u.VirtualFolders = append(u.VirtualFolders, vfs.VirtualFolder{
+		BaseVirtualFolder: vfs.BaseVirtualFolder{
+			Name: folderName,
+			FsConfig: vfs.Filesystem{
+				Provider: sdk.WebDAVFilesystemProvider,
+				WebDAVConfig: vfs.WebDAVFsConfig{
+					WebDAVFsConfig: sdk.WebDAVFsConfig{
+						Endpoint: fmt.Sprintf("http://%v/", webDavServerAddr),
+						Username: defaultUsername,
+						Password: kms.NewPlainSecret(defaultPassword),
+						Prefix:   "/folder",
+					},
+				},
+			},
+		},
+		VirtualPath: vdir,
+		QuotaSize:   -1,
+		QuotaFiles:  -1,
+	})
+	user, _, err := httpdtest.AddUser(u, http.StatusCreated)
+	assert.NoError(t, err)
+	client := getWebDavClient(user, false, nil)
+	assert.NoError(t, checkBasicFunc(client))
+	testFilePath := filepath.Join(homeBasePath, testFileName)
+	testFileSize := int64(65535)
+	err = createTestFile(testFilePath, testFileSize)
+	assert.NoError(t, err)
+	err = uploadFile(testFilePath, path.Join(vdir, testFileName), testFileSize, client)
+	assert.NoError(t, err)
+	info, err := os.Stat(filepath.Join(localUser.GetHomeDir(), "folder", testFileName))
+	if assert.NoError(t, err) {
+		assert.Equal(t, testFileSize, info.Size())
+	}
+	localDownloadPath := filepath.Join(homeBasePath, testDLFileName)
+	err = downloadFile(path.Join(vdir, testFileName), localDownloadPath, testFileSize, client)
+	assert.NoError(t, err)
+	folder, _, err := httpdtest.GetFolderByName(folderName, http.StatusOK)
+	assert.NoError(t, err)
+	assert.Equal(t, testFileSize, folder.UsedQuotaSize)
+	assert.Equal(t, 1, folder.UsedQuotaFiles)
+	user, _, err = httpdtest.GetUserByUsername(user.Username, http.StatusOK)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, user.UsedQuotaFiles)
+	assert.Equal(t, int64(0), user.UsedQuotaSize)
+	// renaming between different filesystems is not supported
+	err = client.Rename(path.Join(vdir, testFileName), testFileName, false)
+	assert.Error(t, err)
+	err = client.Rename(path.Join(vdir, testFileName), path.Join(vdir, testFileName+"_1"), false)
+	assert.NoError(t, err)
+	files, err := client.ReadDir(vdir)
+	assert.NoError(t, err)
+	if assert.Len(t, files, 1) {
+		assert.Equal(t, testFileName+"_1", files[0].Name())
+		assert.Equal(t, testFileSize, files[0].Size())
+	}
+	folder, _, err = httpdtest.GetFolderByName(folderName, http.StatusOK)
+	assert.NoError(t, err)
+	assert.Equal(t, testFileSize, folder.UsedQuotaSize)
+	assert.Equal(t, 1, folder.UsedQuotaFiles)
+
+	err = os.Remove(testFilePath)
+	assert.NoError(t, err)
+	err = os.Remove(localDownloadPath)
+	assert.NoError(t, err)
+	_, err = httpdtest.RemoveUser(user, http.StatusOK)
+	assert.NoError(t, err)
+	_, err = httpdtest.RemoveFolder(vfs.BaseVirtualFolder{Name: folderName}, http.StatusOK)
+	assert.NoError(t, err)
+	err = os.RemoveAll(user.GetHomeDir())
+	assert.NoError(t, err)
+	_, err = httpdtest.RemoveUser(localUser, http.StatusOK)
+	assert.NoError(t, err)
+	err = os.RemoveAll(localUser.GetHomeDir())
+	assert.NoError(t, err)
+}
+
 func TestBytesRangeRequests(t *testing.T) {
 	u := getTestUser()
 	u.Username = u.Username + "1"
@@ -2731,6 +3018,21 @@ func getTestSFTPUser() dataprovider.User {
 	return u
 }
 
+func getTestWebDAVFsUser(useTLS bool) dataprovider.User {
+	u := getTestUser()
+	u.Username = u.Username + "_webdavfs"
+	u.FsConfig.Provider = sdk.WebDAVFilesystemProvider
+	u.FsConfig.WebDAVConfig.Endpoint = fmt.Sprintf("http://%v/", webDavServerAddr)
+	if useTLS {
+		u.FsConfig.WebDAVConfig.Endpoint = fmt.Sprintf("https://%v/", webDavTLSServerAddr)
+		u.FsConfig.WebDAVConfig.SkipTLSVerify = true
+	}
+	u.FsConfig.WebDAVConfig.Username = defaultUsername
+	u.FsConfig.WebDAVConfig.Password = kms.NewPlainSecret(defaultPassword)
+	u.FsConfig.WebDAVConfig.Prefix = "/webdavfs"
+	return u
+}
+
 func getTestUserWithCryptFs() dataprovider.User {
 	user := getTestUser()
 	user.FsConfig.Provider = sdk.CryptedFilesystemProvider